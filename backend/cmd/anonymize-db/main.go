@@ -0,0 +1,49 @@
+// Command anonymize-db replaces the personal and secret data of a database in
+// place, so a copy of production can be used for debugging on staging. It
+// only counts the values it would replace unless -apply is given, and must
+// never be pointed at production itself.
+//
+// Usage:
+//
+//	go run ./cmd/anonymize-db [-apply]
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"scratch-lottery/internal/config"
+	"scratch-lottery/internal/repository"
+	"scratch-lottery/pkg/logger"
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Default().Fatal("Failed to load configuration: %v", err)
+	}
+	logger.ConfigureFromEnv()
+	log := logger.Default()
+
+	apply := flag.Bool("apply", false, "replace the data instead of only counting the values to replace")
+	flag.Parse()
+
+	db, err := repository.InitDB(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize database: %v", err)
+	}
+	defer func() {
+		_ = repository.CloseDB()
+	}()
+
+	result, err := repository.AnonymizeDatabase(db, *apply)
+	if err != nil {
+		log.Fatal("Failed to anonymize database: %v", err)
+	}
+	fmt.Print(result)
+	if !*apply {
+		log.Info("%d values would be anonymized; run with -apply to replace them", result.Total())
+		return
+	}
+	log.Info("Anonymized %d values", result.Total())
+}
@@ -0,0 +1,95 @@
+// Command clientgen generates the Go client of pkg/client and the TypeScript
+// client of the frontend from the checked-in OpenAPI document. With -fetch it
+// first refreshes the document from a running server with API_DOCS_ENABLED
+// set, so the clients follow the routes and request types of the server.
+//
+// Usage:
+//
+//	go generate ./pkg/client
+//	go run ./cmd/clientgen -spec api/openapi.json [-fetch http://localhost:8080/api/docs] [-go file] [-ts file]
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"scratch-lottery/internal/clientgen"
+	"scratch-lottery/pkg/logger"
+)
+
+func main() {
+	specPath := flag.String("spec", "api/openapi.json", "OpenAPI document the clients are generated from")
+	fetch := flag.String("fetch", "", "URL of /api/docs to refresh the document from first")
+	goOutput := flag.String("go", "", "file to write the Go client to")
+	goPackage := flag.String("package", "client", "package of the Go client")
+	tsOutput := flag.String("ts", "", "file to write the TypeScript client to")
+	flag.Parse()
+
+	logger.ConfigureFromEnv()
+	log := logger.Default()
+
+	if *fetch != "" {
+		if err := fetchSpec(*fetch, *specPath); err != nil {
+			log.Fatal("Failed to fetch the OpenAPI document: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(*specPath)
+	if err != nil {
+		log.Fatal("Failed to read the OpenAPI document: %v", err)
+	}
+	spec, err := clientgen.Parse(data)
+	if err != nil {
+		log.Fatal("%v", err)
+	}
+	source := filepath.Base(*specPath)
+
+	if *goOutput != "" {
+		code, err := clientgen.GenerateGo(spec, *goPackage, source)
+		if err != nil {
+			log.Fatal("Failed to generate the Go client: %v", err)
+		}
+		if err := os.WriteFile(*goOutput, code, 0o644); err != nil {
+			log.Fatal("Failed to write the Go client: %v", err)
+		}
+	}
+	if *tsOutput != "" {
+		code, err := clientgen.GenerateTypeScript(spec, source)
+		if err != nil {
+			log.Fatal("Failed to generate the TypeScript client: %v", err)
+		}
+		if err := os.WriteFile(*tsOutput, code, 0o644); err != nil {
+			log.Fatal("Failed to write the TypeScript client: %v", err)
+		}
+	}
+}
+
+// fetchSpec saves the document served at url to path, indented so changes
+// to it read well in a diff
+func fetchSpec(url, path string) error {
+	resp, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s answered %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, data, "", "  "); err != nil {
+		return fmt.Errorf("%s did not answer JSON: %w", url, err)
+	}
+	indented.WriteByte('\n')
+	return os.WriteFile(path, indented.Bytes(), 0o644)
+}
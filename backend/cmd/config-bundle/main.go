@@ -0,0 +1,115 @@
+// Command config-bundle exports the settings, exchange products and lottery
+// types of a tenant as a bundle, or applies a bundle to them, so a standby
+// deployment can be kept in step with production from version control.
+// Imports only print the changes unless -apply is given.
+//
+// Usage:
+//
+//	go run ./cmd/config-bundle export [-format json|yaml] [-o file] [-tenant 1]
+//	go run ./cmd/config-bundle import -f file [-apply] [-tenant 1]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"scratch-lottery/internal/config"
+	"scratch-lottery/internal/repository"
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/logger"
+)
+
+func main() {
+	if len(os.Args) < 2 || (os.Args[1] != "export" && os.Args[1] != "import") {
+		fmt.Fprintln(os.Stderr, "usage: config-bundle export|import [flags]")
+		os.Exit(2)
+	}
+	command := os.Args[1]
+
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Default().Fatal("Failed to load configuration: %v", err)
+	}
+	logger.ConfigureFromEnv()
+	log := logger.Default()
+
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	tenant := flags.Uint("tenant", repository.DefaultTenantID, "tenant whose configuration is exported or applied")
+	format := flags.String("format", "yaml", "export format, json or yaml")
+	output := flags.String("o", "", "file to export to instead of stdout")
+	input := flags.String("f", "", "bundle file to import, JSON or YAML")
+	apply := flags.Bool("apply", false, "apply the bundle instead of only printing the changes")
+	_ = flags.Parse(os.Args[2:])
+
+	db, err := repository.InitDB(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize database: %v", err)
+	}
+	defer func() {
+		_ = repository.CloseDB()
+	}()
+
+	walletService := service.NewWalletService(db)
+	bundleService := service.NewConfigBundleService(db,
+		service.NewLotteryService(db, cfg.EncryptionKey),
+		service.NewExchangeService(db, walletService),
+	).ForTenant(*tenant)
+
+	if command == "export" {
+		bundle, err := bundleService.Export()
+		if err != nil {
+			log.Fatal("Failed to export configuration: %v", err)
+		}
+		data, err := service.MarshalConfigBundle(bundle, *format)
+		if err != nil {
+			log.Fatal("Failed to encode configuration: %v", err)
+		}
+		if *output == "" {
+			_, _ = os.Stdout.Write(data)
+			return
+		}
+		if err := os.WriteFile(*output, data, 0o644); err != nil {
+			log.Fatal("Failed to write %s: %v", *output, err)
+		}
+		log.Info("Exported %d settings, %d products and %d lottery types to %s",
+			len(bundle.Settings), len(bundle.Products), len(bundle.LotteryTypes), *output)
+		return
+	}
+
+	if *input == "" {
+		log.Fatal("import needs a bundle file, given with -f")
+	}
+	data, err := os.ReadFile(*input)
+	if err != nil {
+		log.Fatal("Failed to read %s: %v", *input, err)
+	}
+	bundle, err := service.ParseConfigBundle(data)
+	if err != nil {
+		log.Fatal("Failed to parse %s: %v", *input, err)
+	}
+
+	var diff *service.ConfigBundleDiff
+	if *apply {
+		// Changes made from the command line are logged without an admin
+		diff, err = bundleService.Apply(0, bundle)
+	} else {
+		diff, err = bundleService.Diff(bundle)
+	}
+	if err != nil {
+		log.Fatal("Failed to import configuration: %v", err)
+	}
+	for _, change := range diff.Changes {
+		line := fmt.Sprintf("%s %s %q", change.Action, change.Kind, change.Name)
+		if len(change.Fields) > 0 {
+			line += ": " + strings.Join(change.Fields, ", ")
+		}
+		fmt.Println(line)
+	}
+	if *apply {
+		log.Info("Applied %d changes, %d entries unchanged", len(diff.Changes), diff.Unchanged)
+	} else {
+		log.Info("%d changes, %d entries unchanged; run again with -apply to apply them", len(diff.Changes), diff.Unchanged)
+	}
+}
@@ -0,0 +1,46 @@
+// Command partition-transactions converts the transactions table into a table
+// partitioned by month. It needs postgres and should run while the server is
+// stopped, since writes to transactions are blocked during the copy.
+//
+// Usage:
+//
+//	go run ./cmd/partition-transactions [-months-ahead 3] [-drop-legacy]
+package main
+
+import (
+	"flag"
+
+	"scratch-lottery/internal/config"
+	"scratch-lottery/internal/repository"
+	"scratch-lottery/pkg/logger"
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Default().Fatal("Failed to load configuration: %v", err)
+	}
+	logger.ConfigureFromEnv()
+	log := logger.Default()
+
+	monthsAhead := flag.Int("months-ahead", cfg.TransactionPartitionMonthsAhead, "number of future months to create partitions for")
+	dropLegacy := flag.Bool("drop-legacy", false, "drop the unpartitioned table after copying instead of keeping it as transactions_unpartitioned")
+	flag.Parse()
+
+	db, err := repository.InitDB(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize database: %v", err)
+	}
+	defer func() {
+		_ = repository.CloseDB()
+	}()
+
+	result, err := repository.PartitionTransactions(db, *monthsAhead, *dropLegacy)
+	if err != nil {
+		log.Fatal("Failed to partition transactions: %v", err)
+	}
+	log.Info("Copied %d transactions into %d monthly partitions", result.Rows, len(result.Partitions))
+	if result.LegacyKept {
+		log.Info("The old table was kept as transactions_unpartitioned; drop it once verified")
+	}
+}
@@ -1,7 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+	_ "time/tzdata" // BUSINESS_TIMEZONE must load on hosts without zoneinfo
 
 	"scratch-lottery/internal/cache"
 	"scratch-lottery/internal/config"
@@ -10,6 +17,8 @@ import (
 	"scratch-lottery/internal/repository"
 	"scratch-lottery/internal/service"
 	"scratch-lottery/pkg/auth"
+	"scratch-lottery/pkg/biztime"
+	"scratch-lottery/pkg/captcha"
 	"scratch-lottery/pkg/logger"
 
 	"github.com/gin-gonic/gin"
@@ -25,18 +34,32 @@ func main() {
 	// Configure logger after `.env` is loaded by config.Load()
 	logger.ConfigureFromEnv()
 	log := logger.Default()
+	defer func() {
+		_ = log.Sync()
+	}()
 
 	// Print startup banner
 	logger.PrintBanner("Scratch Lottery", "1.0.0", cfg.OAuthMode)
 	log.Info("Configuration loaded (mode: %s)", cfg.OAuthMode)
 
+	// Times are stored and compared in UTC whatever the server's zone; days,
+	// weeks and months are counted in the business time zone
+	businessZone, err := time.LoadLocation(cfg.BusinessTimezone)
+	if err != nil {
+		log.Fatal("Invalid BUSINESS_TIMEZONE: %v", err)
+	}
+	time.Local = time.UTC
+	biztime.SetLocation(businessZone)
+
 	// Initialize database
 	db, err := repository.InitDB(cfg)
 	if err != nil {
 		log.Fatal("Failed to initialize database: %v", err)
 	}
 	defer func() {
-		_ = repository.CloseDB()
+		if err := repository.CloseDB(); err != nil {
+			log.Warn("Failed to close database: %v", err)
+		}
 	}()
 	log.Info("Database connected (%s)", cfg.DBDriver)
 
@@ -55,6 +78,29 @@ func main() {
 		}
 	}
 
+	// Create the demo content on first boot if enabled
+	if cfg.DemoSeed {
+		if seeded, err := service.NewDemoService(db).ForTenant(repository.DefaultTenantID).Seed(); err != nil {
+			log.Warn("Failed to seed demo content: %v", err)
+		} else if seeded {
+			log.Info("Demo content seeded")
+		}
+	}
+
+	// Initialize the read-only reporting connection
+	reportDB, err := repository.InitReportDB(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize read-only database: %v", err)
+	}
+	if reportDB != nil {
+		defer func() {
+			if sqlDB, err := reportDB.DB(); err == nil {
+				_ = sqlDB.Close()
+			}
+		}()
+		log.Info("Read-only reporting connection ready")
+	}
+
 	// Initialize cache
 	memCache := cache.NewMemoryCache()
 	tokenBlacklist := cache.NewTokenBlacklist(memCache)
@@ -66,72 +112,370 @@ func main() {
 		cfg.JWTRefreshExpiry,
 	)
 
+	// Admin API realm
+	adminRealm, err := service.NewAdminRealm(jwtManager, cfg.AdminIPAllowlist, cfg.AdminTokenIssuer,
+		cfg.AdminTokenAudience, time.Duration(cfg.AdminTokenExpiry)*time.Minute)
+	if err != nil {
+		log.Fatal("Invalid ADMIN_IP_ALLOWLIST: %v", err)
+	}
+
 	// Initialize services
+	tenantService := service.NewTenantService(db)
 	authService := service.NewAuthService(db, jwtManager, tokenBlacklist, cfg.IsDevMode())
 	oauthService := service.NewOAuthService(db, cfg, jwtManager, tokenBlacklist, memCache)
+	sessionService := service.NewSessionService(db, tokenBlacklist, cfg.MaxSessionsPerUser, cfg.MaxSessionsPerIP,
+		time.Duration(jwtManager.GetRefreshExpiry())*time.Second)
+	authService.UseSessions(sessionService)
+	oauthService.UseSessions(sessionService)
+	notificationService := service.NewNotificationService(db)
+	authGuardService := service.NewAuthGuardService(db, notificationService, cfg.AuthMaxFailures,
+		time.Duration(cfg.AuthFailureWindow)*time.Minute,
+		time.Duration(cfg.AuthLockoutMinutes)*time.Minute)
 	walletService := service.NewWalletService(db)
+	walletService.UseBonusSpendPriority(cfg.BonusSpendPriority)
+	adminService := service.NewAdminService(db, walletService)
+	adminService.UseNotifications(notificationService)
+	streakService := service.NewStreakService(db, adminService)
 	lotteryService := service.NewLotteryService(db, cfg.EncryptionKey)
-	purchaseService := service.NewPurchaseService(db, lotteryService, walletService)
-	scratchService := service.NewScratchService(db, lotteryService, walletService)
+	if cfg.StockCounterInterval > 0 {
+		stockCounter := service.NewStockCounter(db, time.Duration(cfg.StockCounterInterval)*time.Second)
+		lotteryService.UseStockCounter(stockCounter)
+		stopStockCounter := stockCounter.Start()
+		defer stopStockCounter()
+	}
+	oddsHintService := service.NewOddsHintService(db, memCache, cfg.OddsHintMode, cfg.OddsHintCacheSeconds)
+	segmentService := service.NewSegmentService(db, notificationService)
+	campaignService := service.NewCampaignService(db, lotteryService, segmentService, notificationService)
+	checkinService := service.NewCheckinService(db, adminService, campaignService)
+	var stockReservationService *service.StockReservationService
+	if cfg.PurchaseReservationSeconds > 0 {
+		stockReservationService = service.NewStockReservationService(db,
+			time.Duration(cfg.PurchaseReservationSeconds)*time.Second)
+		stopStockReservationSweeper := stockReservationService.Start(time.Minute)
+		defer stopStockReservationSweeper()
+	}
+	purchaseService := service.NewPurchaseService(db, lotteryService, walletService, oddsHintService, campaignService, stockReservationService)
+	refundService := service.NewRefundService(db)
+	purchaseService.UseRefunds(refundService)
+	largeWinService := service.NewLargeWinService(db, adminService, cfg.EncryptionKey)
+	if reportDB != nil {
+		adminService.UseReportDB(reportDB)
+		largeWinService.UseReportDB(reportDB)
+	}
+	prizeFulfillmentService := service.NewPrizeFulfillmentService(db)
+	scratchService := service.NewScratchService(db, lotteryService, walletService, streakService, largeWinService)
 	exchangeService := service.NewExchangeService(db, walletService)
-	userService := service.NewUserService(db, walletService)
-
-	// Initialize admin service
-	adminService := service.NewAdminService(db, walletService)
+	userService := service.NewUserService(db, walletService, streakService)
+	oddsService := service.NewOddsService(db)
+	ticketHistoryService := service.NewTicketHistoryService(db)
+	ticketEventService := service.NewTicketEventService(db)
+	ticketTransferService := service.NewTicketTransferService(db, notificationService)
+	voucherService := service.NewVoucherService(db, lotteryService, cfg.VoucherClaimMaxFailures,
+		time.Duration(cfg.VoucherClaimWindow)*time.Minute)
+	userNoteService := service.NewUserNoteService(db)
+	supportService := service.NewSupportService(db, adminService)
+	supportService.UseNotifications(notificationService)
+	accountMergeService := service.NewAccountMergeService(db)
+	jackpotService := service.NewJackpotService(db)
+	demoService := service.NewDemoService(db)
+
+	// Push big wins, sell-outs and balance changes to live subscribers
+	liveEventBus := service.NewLiveEventBus(cfg.LiveBigWinMin, cfg.LiveMaxPerIP)
+	purchaseService.UseEvents(liveEventBus)
+	scratchService.UseEvents(liveEventBus)
+
+	// Complete onboarding checklist steps as users take their first actions
+	onboardingService := service.NewOnboardingService(db, adminService)
+	oauthService.UseOnboarding(onboardingService)
+	checkinService.UseOnboarding(onboardingService)
+	purchaseService.UseOnboarding(onboardingService)
+	scratchService.UseOnboarding(onboardingService)
+
+	incrementalScratchService := service.NewIncrementalScratchService(db, lotteryService, scratchService, service.NewScratchEventHub())
+	quickPlayService := service.NewQuickPlayService(purchaseService, scratchService)
+
+	// Initialize waiting room for high-demand lottery types
+	waitingRoomService := service.NewWaitingRoomService(db,
+		time.Duration(cfg.WaitingRoomWindow)*time.Minute,
+		time.Duration(cfg.WaitingRoomAdmissionTTL)*time.Second)
 
 	// Initialize payment service
-	paymentService := service.NewPaymentService(db, adminService, walletService)
+	paymentService := service.NewPaymentService(db, adminService, walletService, campaignService)
+	paymentSettingsService := service.NewPaymentSettingsService(db, adminService, paymentService)
+
+	// Initialize white-label branding
+	brandingService := service.NewBrandingService(adminService, cfg.BrandingAssetDir, cfg.BrandingMaxAssetKB)
+
+	// Initialize live ticket status for public verify pages
+	ticketWatchService := service.NewTicketWatchService(db, lotteryService, cfg.TicketWatchMaxPerIP)
+	if cfg.TicketWatchInterval > 0 {
+		stopTicketWatch := ticketWatchService.Start(time.Duration(cfg.TicketWatchInterval) * time.Second)
+		defer stopTicketWatch()
+	}
+
+	// Initialize per-admin dashboard layouts
+	dashboardService := service.NewDashboardService(db)
+	prizeTemplateService := service.NewPrizeTemplateService(db, lotteryService)
+
+	// Initialize inventory monitor
+	inventoryMonitorService := service.NewInventoryMonitorService(db, adminService, lotteryService,
+		time.Duration(cfg.InventoryVelocityWindow)*time.Hour)
+	if cfg.InventoryMonitorInterval > 0 {
+		stopInventoryMonitor := inventoryMonitorService.Start(time.Duration(cfg.InventoryMonitorInterval) * time.Minute)
+		defer stopInventoryMonitor()
+		log.Info("Inventory monitor started (every %d min)", cfg.InventoryMonitorInterval)
+	}
+
+	// Initialize prize pool replenishment
+	if cfg.PrizePoolReplenishInterval > 0 {
+		stopReplenisher := service.NewPrizePoolReplenishService(db).Start(time.Duration(cfg.PrizePoolReplenishInterval) * time.Second)
+		defer stopReplenisher()
+	}
+
+	// Initialize scheduled starts and ends of lottery types
+	if cfg.LotteryTypeScheduleInterval > 0 {
+		stopLotteryTypeSchedule := service.NewLotteryTypeScheduleService(db).Start(time.Duration(cfg.LotteryTypeScheduleInterval) * time.Second)
+		defer stopLotteryTypeSchedule()
+	}
+
+	// Initialize exchange gifts
+	exchangeGiftService := service.NewExchangeGiftService(db, exchangeService,
+		time.Duration(cfg.ExchangeGiftExpiryDays)*24*time.Hour)
+	if cfg.ExchangeGiftSweepInterval > 0 {
+		stopGiftSweeper := exchangeGiftService.Start(time.Duration(cfg.ExchangeGiftSweepInterval) * time.Minute)
+		defer stopGiftSweeper()
+	}
+
+	// Initialize two-phase exchange; expired reservations are released every minute
+	exchangeReservationService := service.NewExchangeReservationService(db, exchangeService,
+		time.Duration(cfg.ExchangeReservationMinutes)*time.Minute)
+	stopReservationSweeper := exchangeReservationService.Start(time.Minute)
+	defer stopReservationSweeper()
+
+	// Initialize daily wallet balance snapshots
+	walletSnapshotService := service.NewWalletSnapshotService(db)
+	if cfg.WalletSnapshotInterval > 0 {
+		stopSnapshotJob := walletSnapshotService.Start(time.Duration(cfg.WalletSnapshotInterval) * time.Minute)
+		defer stopSnapshotJob()
+	}
+
+	// Initialize user badge counters
+	badgeService := service.NewBadgeService(db)
+	if cfg.BadgeReconcileInterval > 0 {
+		stopBadgeReconciler := badgeService.Start(time.Duration(cfg.BadgeReconcileInterval) * time.Minute)
+		defer stopBadgeReconciler()
+	}
+
+	// Initialize wallet ledger reconciliation
+	walletReconciliationService := service.NewWalletReconciliationService(db, adminService, notificationService)
+	if cfg.WalletReconcileInterval > 0 {
+		stopWalletReconciler := walletReconciliationService.Start(time.Duration(cfg.WalletReconcileInterval) * time.Minute)
+		defer stopWalletReconciler()
+	}
+
+	// Initialize payment order reconciliation
+	paymentReconciliationService := service.NewPaymentReconciliationService(db, paymentService,
+		time.Duration(cfg.PaymentPendingTimeout)*time.Minute)
+	if cfg.PaymentReconcileInterval > 0 {
+		stopPaymentReconciler := paymentReconciliationService.Start(time.Duration(cfg.PaymentReconcileInterval) * time.Minute)
+		defer stopPaymentReconciler()
+	}
+
+	// Reset sandbox tenants to their starting balance in the background
+	sandboxService := service.NewSandboxService(db, cfg.SandboxStartingBalance)
+	if cfg.SandboxResetInterval > 0 {
+		stopSandboxReset := sandboxService.Start(time.Duration(cfg.SandboxResetInterval) * time.Minute)
+		defer stopSandboxReset()
+	}
+
+	// Post-deploy canary checks, run in a sandbox tenant of their own
+	canaryService := service.NewCanaryService(db, tenantService, sandboxService, lotteryService, purchaseService,
+		scratchService, exchangeService, paymentService, walletService)
+
+	// Re-evaluate user segments in the background
+	if cfg.SegmentEvaluationInterval > 0 {
+		stopSegmentJob := segmentService.Start(time.Duration(cfg.SegmentEvaluationInterval) * time.Minute)
+		defer stopSegmentJob()
+	}
+
+	// Keep monthly transaction partitions ahead; a no-op until the table is partitioned
+	transactionPartitionService := service.NewTransactionPartitionService(db, cfg.TransactionPartitionMonthsAhead)
+	if cfg.TransactionPartitionInterval > 0 {
+		stopPartitionJob := transactionPartitionService.Start(time.Duration(cfg.TransactionPartitionInterval) * time.Hour)
+		defer stopPartitionJob()
+	}
+
+	// Send weekly digests to users who opted in
+	digestService := service.NewDigestService(db, notificationService)
+	if cfg.DigestInterval > 0 {
+		stopDigestJob := digestService.Start(time.Duration(cfg.DigestInterval) * time.Minute)
+		defer stopDigestJob()
+	}
+
+	// Initialize wallet webhook dispatcher
+	walletWebhookService := service.NewWalletWebhookService(db, cfg.WalletWebhookMaxAttempts)
+	if cfg.WalletWebhookInterval > 0 {
+		stopWebhookDispatcher := walletWebhookService.Start(time.Duration(cfg.WalletWebhookInterval) * time.Second)
+		defer stopWebhookDispatcher()
+	}
+
+	// Aggregate API usage for the admin request analytics
+	requestAnalyticsService := service.NewRequestAnalyticsService(db, cfg.RequestAnalyticsRetentionDays)
+	if cfg.RequestAnalyticsInterval > 0 {
+		stopAnalyticsJob := requestAnalyticsService.Start(time.Duration(cfg.RequestAnalyticsInterval) * time.Second)
+		defer stopAnalyticsJob()
+	}
+
+	// Check component health for the public status page
+	statusService := service.NewStatusService(db, adminService,
+		time.Duration(cfg.StatusCheckInterval)*time.Second,
+		time.Duration(cfg.StatusCacheSeconds)*time.Second)
+	if cfg.StatusCheckInterval > 0 {
+		stopStatusChecks := statusService.Start(time.Duration(cfg.StatusCheckInterval) * time.Second)
+		defer stopStatusChecks()
+	}
+
+	// Initialize subsystem kill switches
+	killSwitchService := service.NewKillSwitchService(adminService, statusService,
+		time.Duration(cfg.KillSwitchCacheSeconds)*time.Second)
+	purchaseSwitch := middleware.KillSwitchMiddleware(killSwitchService, service.SubsystemPurchase)
+	scratchSwitch := middleware.KillSwitchMiddleware(killSwitchService, service.SubsystemScratch)
+	exchangeSwitch := middleware.KillSwitchMiddleware(killSwitchService, service.SubsystemExchange)
+	paymentSwitch := middleware.KillSwitchMiddleware(killSwitchService, service.SubsystemPayment)
 
 	// Initialize handlers
-	authHandler := handler.NewAuthHandler(authService)
-	oauthHandler := handler.NewOAuthHandler(oauthService)
+	authHandler := handler.NewAuthHandler(authService, authGuardService, adminRealm)
+	oauthHandler := handler.NewOAuthHandler(oauthService, authGuardService)
 	walletHandler := handler.NewWalletHandler(walletService)
-	lotteryHandler := handler.NewLotteryHandler(lotteryService, purchaseService, scratchService)
+	lotteryHandler := handler.NewLotteryHandler(lotteryService, purchaseService, scratchService, waitingRoomService, cfg.VerifyBatchMaxCodes)
 	exchangeHandler := handler.NewExchangeHandler(exchangeService)
 	userHandler := handler.NewUserHandler(userService)
 	adminHandler := handler.NewAdminHandler(adminService)
 	paymentHandler := handler.NewPaymentHandler(paymentService)
+	paymentSettingsHandler := handler.NewPaymentSettingsHandler(paymentSettingsService)
+	scratchStreamHandler := handler.NewScratchStreamHandler(incrementalScratchService)
+	quickPlayHandler := handler.NewQuickPlayHandler(quickPlayService, waitingRoomService)
+	oddsHandler := handler.NewOddsHandler(oddsService)
+	inventoryHandler := handler.NewInventoryHandler(inventoryMonitorService)
+	exchangeGiftHandler := handler.NewExchangeGiftHandler(exchangeGiftService)
+	walletWebhookHandler := handler.NewWalletWebhookHandler(walletWebhookService)
+	walletSnapshotHandler := handler.NewWalletSnapshotHandler(walletSnapshotService)
+	exchangeReservationHandler := handler.NewExchangeReservationHandler(exchangeReservationService)
+	waitingRoomHandler := handler.NewWaitingRoomHandler(waitingRoomService)
+	tenantHandler := handler.NewTenantHandler(tenantService)
+	brandingHandler := handler.NewBrandingHandler(brandingService, cfg.BrandingCacheSeconds)
+	notificationHandler := handler.NewNotificationHandler(notificationService, digestService)
+	authIncidentHandler := handler.NewAuthIncidentHandler(authGuardService)
+	loginHistoryHandler := handler.NewLoginHistoryHandler(authGuardService)
+	dashboardHandler := handler.NewDashboardHandler(dashboardService)
+	prizeTemplateHandler := handler.NewPrizeTemplateHandler(prizeTemplateService)
+	streakHandler := handler.NewStreakHandler(streakService)
+	largeWinHandler := handler.NewLargeWinHandler(largeWinService)
+	prizeFulfillmentHandler := handler.NewPrizeFulfillmentHandler(prizeFulfillmentService)
+	badgeHandler := handler.NewBadgeHandler(badgeService)
+	walletReconciliationHandler := handler.NewWalletReconciliationHandler(walletReconciliationService)
+	paymentReconciliationHandler := handler.NewPaymentReconciliationHandler(paymentReconciliationService)
+	segmentHandler := handler.NewSegmentHandler(segmentService)
+	campaignHandler := handler.NewCampaignHandler(campaignService)
+	checkinHandler := handler.NewCheckinHandler(checkinService)
+	onboardingHandler := handler.NewOnboardingHandler(onboardingService)
+	refundHandler := handler.NewRefundHandler(refundService)
+	disputeHandler := handler.NewTransactionDisputeHandler(service.NewTransactionDisputeService(db,
+		time.Duration(cfg.DisputeWindowDays)*24*time.Hour))
+	pricingHandler := handler.NewPricingHandler(service.NewPricingService(adminService))
+	configBundleHandler := handler.NewConfigBundleHandler(service.NewConfigBundleService(db, lotteryService, exchangeService))
+	ticketHistoryHandler := handler.NewTicketHistoryHandler(ticketHistoryService)
+	ticketEventHandler := handler.NewTicketEventHandler(ticketEventService)
+	ticketTransferHandler := handler.NewTicketTransferHandler(ticketTransferService)
+	userNoteHandler := handler.NewUserNoteHandler(userNoteService)
+	supportHandler := handler.NewSupportHandler(supportService)
+	accountMergeHandler := handler.NewAccountMergeHandler(accountMergeService)
+	jackpotHandler := handler.NewJackpotHandler(jackpotService)
+	sandboxHandler := handler.NewSandboxHandler(sandboxService)
+	canaryHandler := handler.NewCanaryHandler(canaryService)
+	demoHandler := handler.NewDemoHandler(demoService)
+	ticketWatchHandler := handler.NewTicketWatchHandler(ticketWatchService)
+	requestAnalyticsHandler := handler.NewRequestAnalyticsHandler(requestAnalyticsService)
+	statusHandler := handler.NewStatusHandler(statusService, cfg.StatusCacheSeconds)
+	killSwitchHandler := handler.NewKillSwitchHandler(killSwitchService)
+	voucherHandler := handler.NewVoucherHandler(voucherService)
+	liveEventHandler := handler.NewLiveEventHandler(liveEventBus)
+
+	// Rate limiter for partner batch verification
+	verifyBatchLimiter := middleware.NewRateLimiter(cfg.VerifyBatchRateLimit, time.Minute)
+
+	// Rate limiter for verify page subscriptions
+	ticketWatchLimiter := middleware.NewRateLimiter(cfg.TicketWatchRateLimit, time.Minute)
+
+	// Rate limiter for voucher claims
+	voucherClaimLimiter := middleware.NewRateLimiter(cfg.VoucherClaimRateLimit, time.Minute)
+
+	// Captcha required on routes open to abuse
+	captchaVerifier, err := captcha.New(cfg.CaptchaProvider, cfg.CaptchaSecret)
+	if err != nil {
+		log.Fatal("Failed to configure captcha: %v", err)
+	}
+	if captchaVerifier.Name() == captcha.ProviderNone && cfg.IsProdMode() {
+		log.Warn("CAPTCHA_PROVIDER is none, captcha protected routes accept every request")
+	}
+	requireCaptcha := middleware.CaptchaMiddleware(captchaVerifier)
 
 	// Set Gin mode based on environment
 	logger.SetGinMode(cfg.OAuthMode)
 
+	// Cacheable GET routes answer HEAD as well; net/http drops the body
+	getAndHead := []string{http.MethodGet, http.MethodHead}
+
 	// Create Gin router with custom logger
 	gin.DisableConsoleColor()
 	r := gin.New()
+	if err := middleware.TrustProxies(r, cfg.TrustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES: %v", err)
+	}
 	r.Use(logger.GinRequestID())
 	r.Use(logger.GinLogger())
+	if cfg.RequestAnalyticsInterval > 0 {
+		r.Use(middleware.RequestAnalyticsMiddleware(requestAnalyticsService))
+	}
 	r.Use(logger.GinRecovery())
 
-	// CORS middleware
-	r.Use(func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization")
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
-		}
-		c.Next()
-	})
-
-	// Health check endpoint
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status": "ok",
-			"mode":   cfg.OAuthMode,
-		})
-	})
+	// Request body limits, raised for the routes that legitimately take large bodies
+	r.Use(middleware.BodyLimitMiddleware(middleware.BodyLimits{
+		Default: int64(cfg.BodyMaxKB) * 1024,
+		Routes: map[string]int64{
+			"POST /api/admin/exchange/products/:id/import-keys": int64(cfg.CardKeyImportMaxKB) * 1024,
+			"POST /api/admin/settings/branding/logo":            int64(cfg.BrandingMaxAssetKB+64) * 1024, // room for the multipart envelope
+			"POST /api/admin/lottery/design-assets":             int64(cfg.BrandingMaxAssetKB+64) * 1024,
+		},
+	}))
+
+	// CORS middleware; wrong methods on known paths get 405 with an Allow
+	// header, and OPTIONS on them is answered with the same methods
+	r.HandleMethodNotAllowed = true
+	r.Use(middleware.CORSMiddleware(middleware.ParseOrigins(cfg.CORSAllowedOrigins)))
+	r.NoMethod(middleware.MethodNotAllowed)
+	r.NoRoute(middleware.RouteNotFound)
+
+	// Health check endpoints; readiness fails once shutdown begins
+	healthHandler := handler.NewHealthHandler(cfg.OAuthMode)
+	r.Match(getAndHead, "/health", healthHandler.Live)
+	r.Match(getAndHead, "/health/ready", healthHandler.Ready)
 
 	// OAuth callback at root level (compatible with /oauth/callback format)
-	r.GET("/oauth/callback", oauthHandler.LinuxdoCallback)
+	r.GET("/oauth/callback", middleware.TenantMiddleware(tenantService), oauthHandler.LinuxdoCallback)
 
-	// API routes
+	// API routes (tenant resolved from the X-Tenant header or the host)
 	api := r.Group("/api")
+	api.Use(middleware.TenantMiddleware(tenantService))
 	{
 		// System routes (public)
 		systemGroup := api.Group("/system")
 		{
 			systemGroup.GET("/payment-status", adminHandler.GetPaymentStatus)
+			systemGroup.Match(getAndHead, "/branding", brandingHandler.GetBranding)
+			systemGroup.Match(getAndHead, "/branding/assets/:name", brandingHandler.GetBrandingAsset)
+			systemGroup.Match(getAndHead, "/status", statusHandler.GetStatus)
+			systemGroup.GET("/announcements", killSwitchHandler.GetAnnouncements)
 		}
 
 		// Auth routes (public)
@@ -139,7 +483,7 @@ func main() {
 		{
 			authGroup.GET("/mode", authHandler.GetAuthMode)
 			authGroup.GET("/dev/users", authHandler.GetDevUsers)
-			authGroup.POST("/login/dev", authHandler.DevLogin)
+			authGroup.POST("/login/dev", requireCaptcha, authHandler.DevLogin)
 			authGroup.POST("/refresh", authHandler.RefreshToken)
 			authGroup.POST("/logout", authHandler.Logout)
 
@@ -149,21 +493,33 @@ func main() {
 
 			// Protected auth routes
 			authGroup.GET("/me", middleware.AuthMiddleware(authService), authHandler.GetCurrentUser)
+			authGroup.POST("/admin-session", middleware.AuthMiddleware(authService), authHandler.CreateAdminSession)
 		}
 
+		// Live events (WebSocket); signed in users also get their balance changes
+		api.GET("/ws", middleware.QueryTokenMiddleware(), middleware.OptionalAuthMiddleware(authService), liveEventHandler.Serve)
+
 		// Protected routes example
 		api.GET("/ping", func(c *gin.Context) {
 			c.JSON(200, gin.H{"message": "pong"})
 		})
 
+		// OpenAPI document of every route, for client generators
+		if cfg.APIDocsEnabled {
+			api.GET("/docs", handler.NewDocsHandler(r, "1.0").GetSpec)
+		}
+
 		// Wallet routes (protected)
 		walletGroup := api.Group("/wallet")
 		walletGroup.Use(middleware.AuthMiddleware(authService))
 		{
 			walletGroup.GET("", walletHandler.GetWallet)
 			walletGroup.GET("/balance", walletHandler.GetBalance)
+			walletGroup.GET("/balance/history", walletSnapshotHandler.GetBalanceHistory)
 			walletGroup.GET("/transactions", walletHandler.GetTransactions)
 			walletGroup.POST("/check-balance", walletHandler.CheckBalance)
+			walletGroup.POST("/transactions/:id/dispute", disputeHandler.OpenDispute)
+			walletGroup.GET("/disputes", disputeHandler.GetMyDisputes)
 		}
 
 		// Payment routes
@@ -172,11 +528,17 @@ func main() {
 			// Public callback endpoint (EPay will call this)
 			paymentGroup.POST("/callback", paymentHandler.PaymentCallback)
 			paymentGroup.GET("/callback", paymentHandler.PaymentCallback) // Some EPay implementations use GET
+			paymentGroup.POST("/callback/:gateway", paymentHandler.PaymentCallback)
+			paymentGroup.GET("/callback/:gateway", paymentHandler.PaymentCallback)
+
+			// Public recharge options for the recharge page
+			paymentGroup.GET("/recharge-options", paymentHandler.GetRechargeOptions)
 
 			// Protected routes
-			paymentGroup.POST("/recharge", middleware.AuthMiddleware(authService), paymentHandler.CreateRechargeOrder)
+			paymentGroup.POST("/recharge", paymentSwitch, middleware.AuthMiddleware(authService), paymentHandler.CreateRechargeOrder)
 			paymentGroup.GET("/orders", middleware.AuthMiddleware(authService), paymentHandler.GetUserOrders)
 			paymentGroup.GET("/orders/:order_no", middleware.AuthMiddleware(authService), paymentHandler.GetOrderStatus)
+			paymentGroup.GET("/orders/:order_no/events", middleware.AuthMiddleware(authService), paymentHandler.WatchOrder)
 		}
 
 		// Lottery routes (public for listing, some protected)
@@ -188,15 +550,49 @@ func main() {
 			lotteryGroup.GET("/types/:id/prize-levels", lotteryHandler.GetPrizeLevels)
 			lotteryGroup.GET("/types/:id/prize-pools", lotteryHandler.GetPrizePools)
 			lotteryGroup.GET("/types/:id/active-pool", lotteryHandler.GetActivePrizePool)
+			lotteryGroup.GET("/jackpot", jackpotHandler.GetJackpot)
+			lotteryGroup.GET("/types/:id/odds", oddsHandler.GetOddsDisclosure)
+			lotteryGroup.GET("/types/:id/odds/versions", oddsHandler.GetOddsDisclosureVersions)
+			lotteryGroup.GET("/design-schema", lotteryHandler.GetDesignConfigSchema)
 			lotteryGroup.GET("/verify/:code", lotteryHandler.VerifySecurityCode)
+			lotteryGroup.GET("/verify/:code/stream", middleware.RateLimitMiddleware(ticketWatchLimiter), ticketWatchHandler.WatchTicket)
+
+			// Partner routes (API key, rate limited)
+			lotteryGroup.POST("/verify/batch",
+				middleware.APIKeyMiddleware(middleware.ParseAPIKeys(cfg.RetailerAPIKeys)),
+				middleware.RateLimitMiddleware(verifyBatchLimiter),
+				lotteryHandler.VerifySecurityCodesBatch,
+			)
 
 			// Protected routes
-			lotteryGroup.POST("/purchase", middleware.AuthMiddleware(authService), lotteryHandler.PurchaseTickets)
-			lotteryGroup.POST("/purchase/preview", middleware.AuthMiddleware(authService), lotteryHandler.GetPurchasePreview)
+			lotteryGroup.POST("/purchase", purchaseSwitch, middleware.AuthMiddleware(authService), lotteryHandler.PurchaseTickets)
+			lotteryGroup.POST("/purchase/preview", purchaseSwitch, middleware.AuthMiddleware(authService), lotteryHandler.GetPurchasePreview)
+			lotteryGroup.POST("/quick-play", purchaseSwitch, scratchSwitch, middleware.AuthMiddleware(authService), quickPlayHandler.QuickPlay)
+			lotteryGroup.POST("/types/:id/queue", middleware.AuthMiddleware(authService), waitingRoomHandler.JoinQueue)
+			lotteryGroup.GET("/types/:id/queue", middleware.AuthMiddleware(authService), waitingRoomHandler.GetQueueStatus)
 			lotteryGroup.GET("/tickets", middleware.AuthMiddleware(authService), lotteryHandler.GetUserTickets)
 			lotteryGroup.GET("/tickets/:id", middleware.AuthMiddleware(authService), lotteryHandler.GetTicketByID)
 			lotteryGroup.GET("/tickets/:id/detail", middleware.AuthMiddleware(authService), lotteryHandler.GetTicketDetail)
-			lotteryGroup.POST("/scratch/:id", middleware.AuthMiddleware(authService), lotteryHandler.ScratchTicket)
+			lotteryGroup.GET("/tickets/:id/history", middleware.AuthMiddleware(authService), ticketHistoryHandler.GetUserHistory)
+			lotteryGroup.POST("/tickets/:id/gift", middleware.AuthMiddleware(authService), ticketTransferHandler.GiftTicket)
+			lotteryGroup.GET("/transfers", middleware.AuthMiddleware(authService), ticketTransferHandler.GetTransfers)
+			lotteryGroup.POST("/scratch/:id", scratchSwitch, middleware.AuthMiddleware(authService), lotteryHandler.ScratchTicket)
+			lotteryGroup.POST("/tickets/:id/claim", middleware.AuthMiddleware(authService), largeWinHandler.ClaimPrize)
+			lotteryGroup.GET("/claims", middleware.AuthMiddleware(authService), largeWinHandler.GetClaims)
+			claimVoucher := []gin.HandlerFunc{
+				middleware.AuthMiddleware(authService),
+				middleware.RateLimitMiddleware(voucherClaimLimiter),
+				requireCaptcha,
+				voucherHandler.ClaimVoucher,
+			}
+			lotteryGroup.POST("/vouchers/claim", claimVoucher...)
+			// Printed vouchers name the ticket claim endpoint; both share one rate limit
+			lotteryGroup.POST("/claim-ticket", claimVoucher...)
+
+			// Incremental scratching (area by area, streamed over SSE)
+			lotteryGroup.POST("/scratch/:id/areas/:index", scratchSwitch, middleware.AuthMiddleware(authService), scratchStreamHandler.ScratchArea)
+			lotteryGroup.POST("/scratch/:id/settle", scratchSwitch, middleware.AuthMiddleware(authService), scratchStreamHandler.SettleTicket)
+			lotteryGroup.GET("/scratch/:id/stream", middleware.AuthMiddleware(authService), scratchStreamHandler.StreamScratch)
 		}
 
 		// Exchange routes (public for listing, protected for redeem)
@@ -207,9 +603,28 @@ func main() {
 			exchangeGroup.GET("/products/:id", exchangeHandler.GetProductByID)
 
 			// Protected routes
-			exchangeGroup.POST("/redeem", middleware.AuthMiddleware(authService), exchangeHandler.Redeem)
+			exchangeGroup.POST("/redeem", exchangeSwitch, middleware.AuthMiddleware(authService), exchangeHandler.Redeem)
 			exchangeGroup.GET("/records", middleware.AuthMiddleware(authService), exchangeHandler.GetExchangeRecords)
 			exchangeGroup.GET("/records/:id", middleware.AuthMiddleware(authService), exchangeHandler.GetExchangeRecordByID)
+			exchangeGroup.POST("/records/:id/reveal", middleware.AuthMiddleware(authService), exchangeHandler.RevealCardKey)
+			exchangeGroup.GET("/records/:id/receipt", middleware.AuthMiddleware(authService), exchangeHandler.GetExchangeReceipt)
+			exchangeGroup.POST("/reserve", exchangeSwitch, middleware.AuthMiddleware(authService), exchangeReservationHandler.Reserve)
+			exchangeGroup.POST("/records/:id/confirm", exchangeSwitch, middleware.AuthMiddleware(authService), exchangeReservationHandler.Confirm)
+			exchangeGroup.POST("/records/:id/cancel", middleware.AuthMiddleware(authService), exchangeReservationHandler.Cancel)
+			exchangeGroup.POST("/gifts", exchangeSwitch, middleware.AuthMiddleware(authService), exchangeGiftHandler.SendGift)
+			exchangeGroup.GET("/gifts", middleware.AuthMiddleware(authService), exchangeGiftHandler.GetGifts)
+			exchangeGroup.POST("/gifts/:id/accept", middleware.AuthMiddleware(authService), exchangeGiftHandler.AcceptGift)
+			exchangeGroup.POST("/gifts/:id/decline", middleware.AuthMiddleware(authService), exchangeGiftHandler.DeclineGift)
+		}
+
+		// Canary checks for deploy pipelines (API key)
+		api.POST("/canary/run", middleware.APIKeyMiddleware(middleware.ParseAPIKeys(cfg.CanaryAPIKeys)), canaryHandler.RunCanary)
+
+		// Partner routes (API key)
+		partnerGroup := api.Group("/partner")
+		partnerGroup.Use(middleware.APIKeyMiddleware(middleware.ParseAPIKeys(cfg.RetailerAPIKeys)))
+		{
+			partnerGroup.GET("/usage", requestAnalyticsHandler.GetPartnerUsage)
 		}
 
 		// User routes (protected)
@@ -218,24 +633,65 @@ func main() {
 		{
 			userGroup.GET("/profile", userHandler.GetProfile)
 			userGroup.GET("/tickets", userHandler.GetTickets)
+			userGroup.GET("/purchases/summary", userHandler.GetPurchaseSummary)
 			userGroup.GET("/wins", userHandler.GetWins)
 			userGroup.GET("/statistics", userHandler.GetStatistics)
+			userGroup.GET("/badges", badgeHandler.GetBadges)
+			userGroup.GET("/login-history", loginHistoryHandler.GetLoginHistory)
+			userGroup.GET("/notifications", notificationHandler.GetNotifications)
+			userGroup.POST("/notifications/read-all", notificationHandler.MarkAllNotificationsRead)
+			userGroup.POST("/notifications/:id/read", notificationHandler.MarkNotificationRead)
+			userGroup.GET("/notification-preferences", notificationHandler.GetPreferences)
+			userGroup.PUT("/notification-preferences", notificationHandler.UpdatePreferences)
+			userGroup.GET("/digest", notificationHandler.GetDigest)
+			userGroup.POST("/support", supportHandler.CreateIssue)
+			userGroup.GET("/support", supportHandler.GetMyIssues)
+			userGroup.GET("/support/:id", supportHandler.GetMyIssue)
+			userGroup.POST("/checkin", checkinHandler.CheckIn)
+			userGroup.GET("/checkin/status", checkinHandler.GetStatus)
+			userGroup.POST("/check-in", checkinHandler.CheckIn) // kept for older clients
+			userGroup.GET("/onboarding", onboardingHandler.GetProgress)
+			userGroup.GET("/coupons", campaignHandler.GetCoupons)
 		}
 
 		// Admin routes (protected, admin only)
 		adminGroup := api.Group("/admin")
 		adminGroup.Use(middleware.AuthMiddleware(authService))
-		adminGroup.Use(middleware.AdminMiddleware())
+		adminGroup.Use(middleware.AdminMiddleware(adminRealm, authGuardService))
+		adminGroup.Use(middleware.FieldRedactionMiddleware(adminService))
 		{
+			// Bounds the shape of JSON sent to endpoints that store configuration
+			configGuard := middleware.JSONGuardMiddleware(cfg.ConfigJSONMaxDepth, cfg.ConfigJSONMaxFields)
+
 			// Dashboard
 			adminGroup.GET("/dashboard", adminHandler.GetDashboard)
+			adminGroup.GET("/dashboard/widgets", dashboardHandler.GetWidgetCatalog)
+			adminGroup.GET("/dashboard/layout", dashboardHandler.GetLayout)
+			adminGroup.PUT("/dashboard/layout", configGuard, dashboardHandler.SaveLayout)
+			adminGroup.DELETE("/dashboard/layout", dashboardHandler.ResetLayout)
 
 			// Lottery type management
-			adminGroup.POST("/lottery/types", lotteryHandler.CreateLotteryType)
-			adminGroup.PUT("/lottery/types/:id", lotteryHandler.UpdateLotteryType)
+			adminGroup.POST("/lottery/types", configGuard, lotteryHandler.CreateLotteryType)
+			adminGroup.PUT("/lottery/types/:id", configGuard, lotteryHandler.UpdateLotteryType)
 			adminGroup.DELETE("/lottery/types/:id", lotteryHandler.DeleteLotteryType)
-			adminGroup.PUT("/lottery/types/:id/prize-levels", lotteryHandler.UpdatePrizeLevels)
+			adminGroup.PUT("/lottery/types/:id/prize-levels", configGuard, lotteryHandler.UpdatePrizeLevels)
+			adminGroup.POST("/lottery/design-assets", brandingHandler.UploadDesignAsset)
 			adminGroup.POST("/lottery/types/:id/prize-pools", lotteryHandler.CreatePrizePool)
+			adminGroup.GET("/lottery/tickets/:id/history", ticketHistoryHandler.GetHistory)
+			adminGroup.GET("/lottery/tickets/:id/events", ticketEventHandler.GetEvents)
+			adminGroup.GET("/lottery/tickets/:id/integrity", ticketEventHandler.CheckIntegrity)
+			adminGroup.POST("/lottery/tickets/:id/reassign", ticketHistoryHandler.ReassignTicket)
+			adminGroup.POST("/lottery/types/:id/vouchers", voucherHandler.GenerateVouchers)
+			adminGroup.GET("/lottery/types/:id/vouchers/export", voucherHandler.ExportVouchers)
+
+			// Prize templates
+			adminGroup.GET("/lottery/prize-templates", prizeTemplateHandler.GetTemplates)
+			adminGroup.POST("/lottery/prize-templates", configGuard, prizeTemplateHandler.CreateTemplate)
+			adminGroup.GET("/lottery/prize-templates/:id", prizeTemplateHandler.GetTemplate)
+			adminGroup.PUT("/lottery/prize-templates/:id", configGuard, prizeTemplateHandler.UpdateTemplate)
+			adminGroup.DELETE("/lottery/prize-templates/:id", prizeTemplateHandler.DeleteTemplate)
+			adminGroup.POST("/lottery/prize-templates/:id/lottery-types", prizeTemplateHandler.CreateLotteryType)
+			adminGroup.POST("/lottery/prize-templates/:id/prize-pools", prizeTemplateHandler.CreatePrizePool)
 
 			// Exchange product management
 			adminGroup.GET("/exchange/products", exchangeHandler.GetAllProducts)
@@ -244,23 +700,170 @@ func main() {
 			adminGroup.DELETE("/exchange/products/:id", exchangeHandler.DeleteProduct)
 			adminGroup.POST("/exchange/products/:id/import-keys", exchangeHandler.ImportCardKeys)
 			adminGroup.GET("/exchange/products/:id/card-keys", exchangeHandler.GetCardKeys)
+			adminGroup.POST("/exchange/products/:id/recalculate-stock", exchangeHandler.RecalculateStock)
+			adminGroup.POST("/exchange/products/recalculate-stock", exchangeHandler.RepairAllStock)
+			adminGroup.GET("/exchange/price-suggestion", pricingHandler.SuggestPrice)
+			adminGroup.GET("/exchange/card-key-reveals", exchangeHandler.GetCardKeyReveals)
+			adminGroup.GET("/exchange/receipts/:code", exchangeHandler.VerifyExchangeReceipt)
+			adminGroup.GET("/exchange/prize-fulfillments", prizeFulfillmentHandler.GetFulfillments)
+			adminGroup.POST("/exchange/prize-fulfillments/:id/approve", prizeFulfillmentHandler.ApproveFulfillment)
 
 			// User management
 			adminGroup.GET("/users", adminHandler.GetUsers)
 			adminGroup.GET("/users/:id", adminHandler.GetUserByID)
 			adminGroup.PUT("/users/:id/points", adminHandler.AdjustUserPoints)
+			adminGroup.GET("/users/:id/balance/history", walletSnapshotHandler.GetUserBalanceHistory)
+			adminGroup.GET("/users/:id/login-history", loginHistoryHandler.GetUserLoginHistory)
 			adminGroup.PUT("/users/:id/role", adminHandler.UpdateUserRole)
+			adminGroup.GET("/users/:id/notes", userNoteHandler.GetNotes)
+			adminGroup.POST("/users/:id/notes", userNoteHandler.CreateNote)
+			adminGroup.PUT("/users/:id/notes/:noteId", userNoteHandler.UpdateNote)
+			adminGroup.DELETE("/users/:id/notes/:noteId", userNoteHandler.DeleteNote)
+
+			// Support issues
+			adminGroup.GET("/support/issues", supportHandler.ListIssues)
+			adminGroup.GET("/support/issues/:id", supportHandler.GetIssue)
+			adminGroup.PUT("/support/issues/:id", supportHandler.UpdateIssue)
+			adminGroup.GET("/support/sla", supportHandler.GetSLA)
+			adminGroup.PUT("/support/sla", supportHandler.UpdateSLA)
+
+			// Duplicate accounts
+			adminGroup.GET("/account-merges/candidates", accountMergeHandler.GetCandidates)
+			adminGroup.GET("/account-merges", accountMergeHandler.GetMerges)
+			adminGroup.POST("/account-merges", accountMergeHandler.Merge)
+			adminGroup.POST("/account-merges/:id/rollback", accountMergeHandler.Rollback)
+
+			// Demo content
+			adminGroup.GET("/demo", demoHandler.GetDemoContent)
+			adminGroup.DELETE("/demo", demoHandler.RemoveDemoContent)
+
+			// API request analytics
+			adminGroup.GET("/analytics/requests", requestAnalyticsHandler.GetRequestAnalytics)
+
+			// Wallet reconciliation
+			adminGroup.GET("/wallet-reconciliations", walletReconciliationHandler.GetReconciliations)
+			adminGroup.POST("/wallet-reconciliations/run", walletReconciliationHandler.RunReconciliation)
+			adminGroup.POST("/wallet-reconciliations/:id/resolve", walletReconciliationHandler.ResolveReconciliation)
+
+			// Payment reconciliation
+			adminGroup.GET("/payment-reconciliations", paymentReconciliationHandler.GetReconciliations)
+			adminGroup.POST("/payment-reconciliations/run", paymentReconciliationHandler.RunReconciliation)
+			adminGroup.POST("/payment-reconciliations/:id/resolve", paymentReconciliationHandler.ResolveReconciliation)
+
+			// User segments
+			adminGroup.GET("/segments", segmentHandler.GetSegments)
+			adminGroup.POST("/segments", configGuard, segmentHandler.CreateSegment)
+			adminGroup.GET("/segments/:id", segmentHandler.GetSegment)
+			adminGroup.PUT("/segments/:id", configGuard, segmentHandler.UpdateSegment)
+			adminGroup.DELETE("/segments/:id", segmentHandler.DeleteSegment)
+			adminGroup.POST("/segments/:id/evaluate", segmentHandler.EvaluateSegment)
+			adminGroup.GET("/segments/:id/members", segmentHandler.GetMembers)
+			adminGroup.POST("/segments/:id/notify", segmentHandler.NotifySegment)
+
+			// Campaigns
+			adminGroup.GET("/campaigns", campaignHandler.GetCampaigns)
+			adminGroup.POST("/campaigns", configGuard, campaignHandler.CreateCampaign)
+			adminGroup.GET("/campaigns/:id", campaignHandler.GetCampaign)
+			adminGroup.PUT("/campaigns/:id", configGuard, campaignHandler.UpdateCampaign)
+			adminGroup.DELETE("/campaigns/:id", campaignHandler.DeleteCampaign)
+			adminGroup.GET("/campaigns/:id/report", campaignHandler.GetReport)
 
 			// System settings
 			adminGroup.GET("/settings", adminHandler.GetSystemSettings)
-			adminGroup.PUT("/settings", adminHandler.UpdateSystemSettings)
+			adminGroup.PUT("/settings", configGuard, adminHandler.UpdateSystemSettings)
+			adminGroup.POST("/settings/payment/test", paymentSettingsHandler.TestPaymentSettings)
+			adminGroup.GET("/settings/payment/versions", paymentSettingsHandler.GetPaymentSettingsVersions)
+			adminGroup.POST("/settings/payment/versions/:version/rollback", paymentSettingsHandler.RollbackPaymentSettings)
+			adminGroup.GET("/settings/branding", brandingHandler.GetBrandingSettings)
+			adminGroup.PUT("/settings/branding", configGuard, brandingHandler.UpdateBrandingSettings)
+			adminGroup.POST("/settings/branding/logo", brandingHandler.UploadBrandingLogo)
+			adminGroup.GET("/settings/incident", statusHandler.GetIncident)
+			adminGroup.PUT("/settings/incident", configGuard, statusHandler.UpdateIncident)
+			adminGroup.GET("/settings/kill-switches", killSwitchHandler.GetKillSwitches)
+			adminGroup.PUT("/settings/kill-switches/:subsystem", killSwitchHandler.UpdateKillSwitch)
+			adminGroup.GET("/settings/streaks", streakHandler.GetStreakRules)
+			adminGroup.PUT("/settings/streaks", configGuard, streakHandler.UpdateStreakRules)
+			adminGroup.GET("/settings/jackpot", jackpotHandler.GetJackpot)
+			adminGroup.PUT("/settings/jackpot", configGuard, jackpotHandler.UpdateJackpotSettings)
+			adminGroup.GET("/settings/checkin", checkinHandler.GetRules)
+			adminGroup.PUT("/settings/checkin", configGuard, checkinHandler.UpdateRules)
+			adminGroup.GET("/settings/onboarding", onboardingHandler.GetConfig)
+			adminGroup.PUT("/settings/onboarding", configGuard, onboardingHandler.UpdateConfig)
+			adminGroup.GET("/settings/notification-batching", notificationHandler.GetBatchingConfig)
+			adminGroup.PUT("/settings/notification-batching", configGuard, notificationHandler.UpdateBatchingConfig)
+			adminGroup.GET("/settings/pricing", pricingHandler.GetConfig)
+			adminGroup.PUT("/settings/pricing", configGuard, pricingHandler.UpdateConfig)
+			adminGroup.GET("/settings/recharge", paymentHandler.GetRechargeRules)
+			adminGroup.PUT("/settings/recharge", configGuard, paymentHandler.UpdateRechargeRules)
+			adminGroup.GET("/settings/redaction", adminHandler.GetFieldRedactionPolicy)
+			adminGroup.PUT("/settings/redaction", configGuard, adminHandler.UpdateFieldRedactionPolicy)
+
+			// Sandbox tenants
+			adminGroup.POST("/sandbox/reset", sandboxHandler.ResetSandbox)
 
 			// Statistics
 			adminGroup.GET("/statistics", adminHandler.GetStatistics)
 			adminGroup.GET("/statistics/export", adminHandler.ExportStatistics)
+			adminGroup.GET("/statistics/forecast", adminHandler.GetSalesForecast)
+
+			// Large win reports
+			adminGroup.GET("/reports/large-wins", largeWinHandler.GetLargeWinReport)
+			adminGroup.GET("/reports/large-wins/export", largeWinHandler.ExportLargeWinReport)
+
+			// Prize claims of held large wins
+			adminGroup.GET("/claims", largeWinHandler.GetAdminClaims)
+			adminGroup.POST("/claims/:id/approve", largeWinHandler.ApproveClaim)
+			adminGroup.POST("/claims/:id/reject", largeWinHandler.RejectClaim)
+
+			// Refunds of purchases, failed purchases included
+			adminGroup.POST("/payment-orders/:order_no/sync", paymentHandler.SyncOrder)
+			adminGroup.POST("/payment-orders/:order_no/confirm", paymentHandler.ConfirmManualOrder)
+			adminGroup.GET("/refunds", refundHandler.GetRefunds)
+			adminGroup.POST("/refunds", refundHandler.RefundPurchase)
+			adminGroup.POST("/refunds/:id/approve", refundHandler.ApproveRefund)
+			adminGroup.POST("/refunds/:id/reject", refundHandler.RejectRefund)
+			adminGroup.GET("/disputes", disputeHandler.GetDisputes)
+			adminGroup.POST("/disputes/:id/uphold", disputeHandler.UpholdDispute)
+			adminGroup.POST("/disputes/:id/refund", disputeHandler.RefundDispute)
 
 			// Admin logs
 			adminGroup.GET("/logs", adminHandler.GetAdminLogs)
+			adminGroup.GET("/logs/export", adminHandler.ExportAdminLogs)
+			adminGroup.GET("/logs/verify", adminHandler.VerifyAdminLogs)
+
+			// Configuration as code
+			adminGroup.GET("/config-bundle", configBundleHandler.Export)
+			adminGroup.POST("/config-bundle/preview", configBundleHandler.Preview)
+			adminGroup.POST("/config-bundle/apply", configBundleHandler.Apply)
+
+			// Auth incidents
+			adminGroup.GET("/auth-incidents", authIncidentHandler.GetAuthIncidents)
+
+			// Deployment-wide features, limited to the default tenant's admins
+			platformGroup := adminGroup.Group("")
+			platformGroup.Use(middleware.DefaultTenantMiddleware())
+			{
+				// Inventory alerts
+				platformGroup.GET("/inventory/alerts", inventoryHandler.GetInventoryAlerts)
+				platformGroup.POST("/inventory/check", inventoryHandler.CheckInventory)
+
+				// Wallet webhooks
+				platformGroup.GET("/wallet-webhooks", walletWebhookHandler.GetWebhooks)
+				platformGroup.POST("/wallet-webhooks", walletWebhookHandler.CreateWebhook)
+				platformGroup.PUT("/wallet-webhooks/:id", walletWebhookHandler.UpdateWebhook)
+				platformGroup.DELETE("/wallet-webhooks/:id", walletWebhookHandler.DeleteWebhook)
+				platformGroup.GET("/wallet-webhooks/:id/deliveries", walletWebhookHandler.GetDeliveries)
+				platformGroup.POST("/wallet-webhooks/:id/replay", walletWebhookHandler.ReplayWebhook)
+
+				// Tenant management
+				platformGroup.GET("/tenants", tenantHandler.GetTenants)
+				platformGroup.POST("/tenants", tenantHandler.CreateTenant)
+				platformGroup.PUT("/tenants/:id", tenantHandler.UpdateTenant)
+				platformGroup.POST("/tenants/:id/admins", tenantHandler.AssignTenantAdmin)
+
+				// Canary checks
+				platformGroup.POST("/canary/run", canaryHandler.RunCanary)
+			}
 		}
 	}
 
@@ -270,7 +873,42 @@ func main() {
 	log.Info("Mode: %s | DB: %s | Cache: %s", cfg.OAuthMode, cfg.DBDriver, "memory")
 	fmt.Println("════════════════════════════════════════════════════════════════")
 
-	if err := r.Run(addr); err != nil {
-		log.Fatal("Failed to start server: %v", err)
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           r,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	// Wait for SIGINT or SIGTERM, then drain: fail the readiness probe so load
+	// balancers stop routing here, stop accepting connections and let the
+	// in-flight scratches and purchases finish. Background jobs, the database
+	// pools and the log file are closed by the deferred calls above.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	select {
+	case err := <-serveErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Failed to start server: %v", err)
+		}
+		return
+	case <-ctx.Done():
+	}
+	stop()
+
+	log.Info("Shutting down, draining in-flight requests")
+	healthHandler.Drain()
+	time.Sleep(time.Duration(cfg.ShutdownDrainDelay) * time.Second)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeout)*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		// Streams such as order watches outlive the timeout; cut them off
+		log.Warn("Requests still open after %ds, closing them: %v", cfg.ShutdownTimeout, err)
+		_ = srv.Close()
 	}
+	log.Info("Server stopped")
 }
@@ -5,7 +5,10 @@ import (
 	"time"
 )
 
-const tokenBlacklistPrefix = "token_blacklist:"
+const (
+	tokenBlacklistPrefix   = "token_blacklist:"
+	sessionBlacklistPrefix = "session_blacklist:"
+)
 
 // TokenBlacklist manages revoked tokens
 type TokenBlacklist struct {
@@ -34,3 +37,15 @@ func (b *TokenBlacklist) Remove(token string) error {
 	key := fmt.Sprintf("%s%s", tokenBlacklistPrefix, token)
 	return b.cache.Delete(key)
 }
+
+// AddSession revokes every token of a login session
+func (b *TokenBlacklist) AddSession(sessionID string, expiration time.Duration) error {
+	key := fmt.Sprintf("%s%s", sessionBlacklistPrefix, sessionID)
+	return b.cache.Set(key, true, expiration)
+}
+
+// IsSessionBlacklisted checks if a login session is revoked
+func (b *TokenBlacklist) IsSessionBlacklisted(sessionID string) bool {
+	key := fmt.Sprintf("%s%s", sessionBlacklistPrefix, sessionID)
+	return b.cache.Exists(key)
+}
@@ -0,0 +1,135 @@
+package clientgen
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+// The checked-in clients are generated from the checked-in document
+func TestGeneratedClientsUpToDate(t *testing.T) {
+	data, err := os.ReadFile("../../api/openapi.json")
+	if err != nil {
+		t.Fatalf("Failed to read the OpenAPI document: %v", err)
+	}
+	spec, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	goCode, err := GenerateGo(spec, "client", "openapi.json")
+	if err != nil {
+		t.Fatalf("GenerateGo failed: %v", err)
+	}
+	tsCode, err := GenerateTypeScript(spec, "openapi.json")
+	if err != nil {
+		t.Fatalf("GenerateTypeScript failed: %v", err)
+	}
+	for path, generated := range map[string][]byte{
+		"../../pkg/client/api.gen.go":            goCode,
+		"../../../frontend/src/api/generated.ts": tsCode,
+	} {
+		checkedIn, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", path, err)
+		}
+		if !bytes.Equal(checkedIn, generated) {
+			t.Errorf("%s is out of date with api/openapi.json, run go generate ./pkg/client", path)
+		}
+	}
+}
+
+func TestNames(t *testing.T) {
+	tests := []struct {
+		name       string
+		exported   string
+		unexported string
+	}{
+		{"order_no", "OrderNo", "orderNo"},
+		{"id", "ID", "id"},
+		{"user_ids", "UserIDs", "userIDs"},
+		{"GetAPIKeys", "GetAPIKeys", "getAPIKeys"},
+		{"APIKeyUsage", "APIKeyUsage", "apiKeyUsage"},
+		{"get_api_ping", "GetAPIPing", "getAPIPing"},
+		{"callback-url", "CallbackURL", "callbackURL"},
+	}
+	for _, tt := range tests {
+		if got := exportedName(tt.name); got != tt.exported {
+			t.Errorf("exportedName(%q) = %q, expected %q", tt.name, got, tt.exported)
+		}
+		if got := unexportedName(tt.name); got != tt.unexported {
+			t.Errorf("unexportedName(%q) = %q, expected %q", tt.name, got, tt.unexported)
+		}
+	}
+}
+
+// Operations of the same name are told apart by their handler, paths are
+// filled in, and query parameters and inline responses get types of their own
+func TestGenerate(t *testing.T) {
+	spec, err := Parse([]byte(`{
+		"paths": {
+			"/api/docs": {"get": {"operationId": "DocsHandler.GetSpec", "summary": "GetSpec"}},
+			"/api/admin/stats": {"get": {"operationId": "AdminHandler.GetStatistics", "summary": "Get statistics"}},
+			"/api/items/{code}": {"get": {
+				"operationId": "ItemHandler.GetStatistics",
+				"summary": "GetStatistics",
+				"parameters": [
+					{"name": "code", "in": "path", "required": true, "schema": {"type": "string"}},
+					{"name": "page", "in": "query", "schema": {"type": "integer", "minimum": 0}}
+				],
+				"responses": {"200": {"content": {"application/json": {"schema": {"allOf": [
+					{"$ref": "#/components/schemas/Response"},
+					{"type": "object", "properties": {"data": {"type": "object", "properties": {"count": {"type": "integer"}}}}}
+				]}}}}}
+			}}
+		},
+		"components": {"schemas": {
+			"Response": {"type": "object"},
+			"itemRequest": {"type": "object", "required": ["name"], "properties": {
+				"name": {"type": "string"},
+				"owner": {"allOf": [{"$ref": "#/components/schemas/itemRequest"}], "nullable": true}
+			}}
+		}}
+	}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	goCode, err := GenerateGo(spec, "client", "test.json")
+	if err != nil {
+		t.Fatalf("GenerateGo failed: %v", err)
+	}
+	for _, want := range []string{
+		"type ItemRequest struct",
+		"Name  string       `json:\"name\"`",
+		"Owner *ItemRequest `json:\"owner,omitempty\"`",
+		"func (c *Client) AdminGetStatistics(ctx context.Context) (json.RawMessage, error)",
+		"// ItemGetStatistics calls GET /items/{code}\n",
+		"func (c *Client) ItemGetStatistics(ctx context.Context, code string, params *ItemGetStatisticsParams) (*ItemGetStatisticsResult, error)",
+		`"/items/"+url.PathEscape(code)`,
+		"Page uint",
+	} {
+		if !strings.Contains(string(goCode), want) {
+			t.Errorf("Expected the Go client to contain %q:\n%s", want, goCode)
+		}
+	}
+	if strings.Contains(string(goCode), "/docs") {
+		t.Error("Expected the document itself left out")
+	}
+
+	tsCode, err := GenerateTypeScript(spec, "test.json")
+	if err != nil {
+		t.Fatalf("GenerateTypeScript failed: %v", err)
+	}
+	for _, want := range []string{
+		"export interface ItemRequest {\n  name: string;\n  owner?: ItemRequest | null;\n}",
+		"export interface ItemGetStatisticsResult {\n  count?: number;\n}",
+		"export function itemGetStatistics(code: string, params?: ItemGetStatisticsParams): Promise<ItemGetStatisticsResult>",
+		"withQuery(`/items/${encodeURIComponent(code)}`, params)",
+	} {
+		if !strings.Contains(string(tsCode), want) {
+			t.Errorf("Expected the TypeScript client to contain %q:\n%s", want, tsCode)
+		}
+	}
+}
@@ -0,0 +1,358 @@
+package clientgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"strings"
+	"unicode"
+)
+
+// GenerateGo returns the Go source of the types of the components and a
+// method per operation for package pkg, which provides the Client type and
+// its do method. source names the document in the generated header.
+func GenerateGo(spec *Spec, pkg, source string) ([]byte, error) {
+	endpoints, err := spec.endpoints()
+	if err != nil {
+		return nil, err
+	}
+	g := &goGenerator{declared: map[string]bool{}}
+	for _, name := range spec.componentNames() {
+		g.declared[goTypeName(name)] = true
+	}
+
+	var methods bytes.Buffer
+	for _, ep := range endpoints {
+		if err := g.method(&methods, ep); err != nil {
+			return nil, fmt.Errorf("%s %s: %w", ep.Method, ep.Path, err)
+		}
+	}
+	var types bytes.Buffer
+	for _, name := range spec.componentNames() {
+		if err := g.structType(&types, goTypeName(name), spec.Components.Schemas[name],
+			fmt.Sprintf("%s is the %s schema of the API", goTypeName(name), name)); err != nil {
+			return nil, fmt.Errorf("schema %s: %w", name, err)
+		}
+	}
+	// Inline objects are declared after the components, in the order found
+	for i := 0; i < len(g.inline); i++ {
+		inline := g.inline[i]
+		if err := g.structType(&types, inline.name, inline.schema, inline.doc); err != nil {
+			return nil, fmt.Errorf("%s: %w", inline.name, err)
+		}
+	}
+
+	body := types.String() + methods.String()
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by clientgen from %s. DO NOT EDIT.\n\npackage %s\n\n", source, pkg)
+	out.WriteString("import (\n")
+	used, err := usedPackages(body)
+	if err != nil {
+		return nil, err
+	}
+	for _, imp := range []string{"context", "encoding/json", "fmt", "net/http", "net/url", "time"} {
+		if used[imp[strings.LastIndex(imp, "/")+1:]] {
+			fmt.Fprintf(&out, "\t%q\n", imp)
+		}
+	}
+	out.WriteString(")\n\n")
+	out.WriteString(body)
+
+	formatted, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting the generated client: %w", err)
+	}
+	return formatted, nil
+}
+
+// usedPackages returns the names of the packages the declarations of the
+// generated code refer to
+func usedPackages(declarations string) (map[string]bool, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), "", "package client\n\n"+declarations, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, fmt.Errorf("parsing the generated client: %w", err)
+	}
+	used := map[string]bool{}
+	ast.Inspect(file, func(node ast.Node) bool {
+		if selector, ok := node.(*ast.SelectorExpr); ok {
+			if ident, ok := selector.X.(*ast.Ident); ok {
+				used[ident.Name] = true
+			}
+		}
+		return true
+	})
+	return used, nil
+}
+
+type goGenerator struct {
+	declared map[string]bool
+	inline   []inlineType
+}
+
+// inlineType is an object schema declared in place, which gets a type of
+// its own in Go
+type inlineType struct {
+	name   string
+	doc    string
+	schema *Schema
+}
+
+// goTypeName exports the name of a component, such as amountRequest
+func goTypeName(component string) string {
+	runes := []rune(component)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+// declare reserves the name of a generated type
+func (g *goGenerator) declare(name string) error {
+	if g.declared[name] {
+		return fmt.Errorf("the type %s is declared twice", name)
+	}
+	g.declared[name] = true
+	return nil
+}
+
+// goType returns the Go type of a schema. Inline objects are declared as a
+// type named hint.
+func (g *goGenerator) goType(schema *Schema, hint string) (string, error) {
+	if schema == nil {
+		return "any", nil
+	}
+	if schema.Ref != "" {
+		return goTypeName(refName(schema.Ref)), nil
+	}
+	if name, ok := nullableRef(schema); ok {
+		return "*" + goTypeName(name), nil
+	}
+
+	var base string
+	switch schema.Type {
+	case "boolean":
+		base = "bool"
+	case "integer":
+		base = "int"
+		if schema.Minimum != nil && *schema.Minimum >= 0 {
+			base = "uint"
+		}
+	case "number":
+		base = "float64"
+	case "string":
+		switch schema.Format {
+		case "date-time":
+			base = "time.Time"
+		case "byte":
+			return "[]byte", nil
+		default:
+			base = "string"
+		}
+	case "array":
+		items, err := g.goType(schema.Items, hint+"Item")
+		if err != nil {
+			return "", err
+		}
+		return "[]" + items, nil
+	case "object":
+		if schema.Properties != nil {
+			if err := g.declare(hint); err != nil {
+				return "", err
+			}
+			g.inline = append(g.inline, inlineType{name: hint, schema: schema, doc: hint + " is an object of the API"})
+			base = hint
+			break
+		}
+		values, err := g.goType(schema.AdditionalProperties, hint+"Value")
+		if err != nil {
+			return "", err
+		}
+		return "map[string]" + values, nil
+	default:
+		return "any", nil
+	}
+	if schema.Nullable {
+		return "*" + base, nil
+	}
+	return base, nil
+}
+
+func (g *goGenerator) structType(out *bytes.Buffer, name string, schema *Schema, doc string) error {
+	fmt.Fprintf(out, "// %s\ntype %s struct {\n", doc, name)
+	fields := map[string]bool{}
+	for _, property := range sortedProperties(schema) {
+		field := exportedName(property)
+		// Models embedding gorm.Model also send its untagged fields, such as
+		// CreatedAt next to created_at; the tagged one keeps its JSON name
+		if fields[field] {
+			field = goTypeName(property)
+		}
+		if field == "" || fields[field] {
+			return fmt.Errorf("the property %q has no Go name of its own", property)
+		}
+		fields[field] = true
+		fieldType, err := g.goType(schema.Properties[property], name+field)
+		if err != nil {
+			return err
+		}
+		tag := property
+		if !isRequired(schema, property) {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(out, "\t%s %s `json:%q`\n", field, fieldType, tag)
+	}
+	out.WriteString("}\n\n")
+	return nil
+}
+
+// method writes the Client method calling an endpoint, with the type of its
+// query parameters
+func (g *goGenerator) method(out *bytes.Buffer, ep endpoint) error {
+	args := []string{"ctx context.Context"}
+	for _, param := range ep.Params {
+		args = append(args, unexportedName(param.Name)+" "+goParamType(param.Schema))
+	}
+	query := "nil"
+	if len(ep.Query) > 0 {
+		paramsType := ep.Name + "Params"
+		if err := g.declare(paramsType); err != nil {
+			return err
+		}
+		g.queryType(out, paramsType, ep)
+		args = append(args, "params *"+paramsType)
+		query = "query"
+	}
+	body := "nil"
+	if ep.Body != nil {
+		bodyType, err := g.goType(ep.Body, ep.Name+"Body")
+		if err != nil {
+			return err
+		}
+		args = append(args, "body "+bodyType)
+		body = "body"
+	}
+
+	result, pointer := "json.RawMessage", false
+	if ep.Response != nil {
+		var err error
+		if result, err = g.goType(ep.Response, ep.Name+"Result"); err != nil {
+			return err
+		}
+		pointer = !strings.HasPrefix(result, "[]") && !strings.HasPrefix(result, "map[") &&
+			!strings.HasPrefix(result, "*") && result != "any" && g.declared[result]
+	}
+	returned, zero, value := result, goZero(result), "result"
+	if pointer {
+		returned, zero, value = "*"+result, "nil", "&result"
+	}
+
+	comment := fmt.Sprintf("%s calls %s %s", ep.Name, ep.Method, ep.Path)
+	if ep.Summary != "" {
+		comment += ": " + ep.Summary
+	}
+	fmt.Fprintf(out, "// %s\nfunc (c *Client) %s(%s) (%s, error) {\n", comment, ep.Name, strings.Join(args, ", "), returned)
+	if query != "nil" {
+		out.WriteString("\tvar query url.Values\n\tif params != nil {\n\t\tquery = params.values()\n\t}\n")
+	}
+	fmt.Fprintf(out, "\tvar result %s\n", result)
+	fmt.Fprintf(out, "\tif err := c.do(ctx, http.Method%s, %s, %s, %s, &result); err != nil {\n\t\treturn %s, err\n\t}\n",
+		methodConstant(ep.Method), goPath(ep), query, body, zero)
+	fmt.Fprintf(out, "\treturn %s, nil\n}\n\n", value)
+	return nil
+}
+
+// queryType writes the type of the query parameters of an endpoint
+func (g *goGenerator) queryType(out *bytes.Buffer, name string, ep endpoint) {
+	fmt.Fprintf(out, "// %s are the query parameters of %s. Zero values are left out.\ntype %s struct {\n", name, ep.Name, name)
+	for _, param := range ep.Query {
+		fmt.Fprintf(out, "\t%s %s\n", exportedName(param.Name), goParamType(param.Schema))
+	}
+	out.WriteString("}\n\n")
+
+	fmt.Fprintf(out, "func (p *%s) values() url.Values {\n\tquery := url.Values{}\n", name)
+	for _, param := range ep.Query {
+		field := "p." + exportedName(param.Name)
+		switch goParamType(param.Schema) {
+		case "bool":
+			fmt.Fprintf(out, "\tif %s {\n\t\tquery.Set(%q, \"true\")\n\t}\n", field, param.Name)
+		case "string":
+			fmt.Fprintf(out, "\tif %s != \"\" {\n\t\tquery.Set(%q, %s)\n\t}\n", field, param.Name, field)
+		default:
+			fmt.Fprintf(out, "\tif %s != 0 {\n\t\tquery.Set(%q, fmt.Sprint(%s))\n\t}\n", field, param.Name, field)
+		}
+	}
+	out.WriteString("\treturn query\n}\n\n")
+}
+
+// goParamType returns the Go type of a path or query parameter
+func goParamType(schema *Schema) string {
+	if schema == nil {
+		return "string"
+	}
+	switch schema.Type {
+	case "boolean":
+		return "bool"
+	case "integer":
+		if schema.Minimum != nil && *schema.Minimum >= 0 {
+			return "uint"
+		}
+		return "int"
+	case "number":
+		return "float64"
+	}
+	return "string"
+}
+
+// goPath returns the expression of an endpoint's path with its parameters
+// filled in
+func goPath(ep endpoint) string {
+	types := map[string]string{}
+	for _, param := range ep.Params {
+		types[param.Name] = goParamType(param.Schema)
+	}
+	var parts []string
+	rest := ep.Path
+	for {
+		start := strings.Index(rest, "{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(rest, "}")
+		if start > 0 {
+			parts = append(parts, fmt.Sprintf("%q", rest[:start]))
+		}
+		name := rest[start+1 : end]
+		if types[name] == "string" {
+			parts = append(parts, "url.PathEscape("+unexportedName(name)+")")
+		} else {
+			parts = append(parts, "fmt.Sprint("+unexportedName(name)+")")
+		}
+		rest = rest[end+1:]
+	}
+	if rest != "" {
+		parts = append(parts, fmt.Sprintf("%q", rest))
+	}
+	return strings.Join(parts, " + ")
+}
+
+// methodConstant returns the suffix of the net/http constant of a method,
+// such as Get for http.MethodGet
+func methodConstant(method string) string {
+	return method[:1] + strings.ToLower(method[1:])
+}
+
+// goZero returns the zero value of a Go type the generator uses
+func goZero(goType string) string {
+	switch goType {
+	case "bool":
+		return "false"
+	case "string":
+		return `""`
+	case "int", "uint", "float64":
+		return "0"
+	case "time.Time":
+		return "time.Time{}"
+	}
+	return "nil"
+}
@@ -0,0 +1,316 @@
+// Package clientgen generates the typed API clients from the OpenAPI
+// document the server serves at /api/docs: the types and methods of
+// pkg/client for Go, and a TypeScript module for the frontend. Both are
+// generated from the same checked-in document, so they describe the same
+// operations under the same names.
+package clientgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Spec is the part of an OpenAPI 3 document the generators read
+type Spec struct {
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components struct {
+		Schemas map[string]*Schema `json:"schemas"`
+	} `json:"components"`
+}
+
+// Operation is an operation of a path
+type Operation struct {
+	OperationID string      `json:"operationId"`
+	Summary     string      `json:"summary"`
+	Parameters  []Parameter `json:"parameters"`
+	RequestBody *struct {
+		Content map[string]struct {
+			Schema *Schema `json:"schema"`
+		} `json:"content"`
+	} `json:"requestBody"`
+	Responses map[string]struct {
+		Content map[string]struct {
+			Schema *Schema `json:"schema"`
+		} `json:"content"`
+	} `json:"responses"`
+}
+
+// Parameter is a path or query parameter
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// Schema is a JSON schema as pkg/openapi derives them from Go types
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Nullable             bool               `json:"nullable"`
+	Minimum              *float64           `json:"minimum"`
+	Items                *Schema            `json:"items"`
+	AdditionalProperties *Schema            `json:"additionalProperties"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AllOf                []*Schema          `json:"allOf"`
+}
+
+// envelope is the component every response is wrapped in; the clients
+// unwrap it themselves
+const envelope = "Response"
+
+// apiPrefix is left out of the paths, the clients being given the base URL
+// of the API
+const apiPrefix = "/api"
+
+// skippedPaths are not called as JSON operations: the document itself, the
+// live events socket and the notifications of payment gateways
+var skippedPaths = []string{"/api/docs", "/api/ws", "/api/payment/callback"}
+
+// Parse reads an OpenAPI document
+func Parse(data []byte) (*Spec, error) {
+	var spec Spec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("parsing the OpenAPI document: %w", err)
+	}
+	if len(spec.Paths) == 0 {
+		return nil, fmt.Errorf("the OpenAPI document has no paths")
+	}
+	return &spec, nil
+}
+
+// endpoint is an operation the clients call
+type endpoint struct {
+	Name     string // Exported Go name, such as GetWallet
+	Method   string
+	Path     string // Relative to the API base URL, with {name} parameters
+	Summary  string
+	Params   []Parameter // Path parameters in order
+	Query    []Parameter
+	Body     *Schema
+	Response *Schema // Data of the response envelope, nil when undescribed
+}
+
+var methodOrder = []string{"get", "post", "put", "patch", "delete"}
+
+// endpoints lists the operations of the document under /api, by path and
+// method, each under a unique name
+func (s *Spec) endpoints() ([]endpoint, error) {
+	paths := make([]string, 0, len(s.Paths))
+	for path := range s.Paths {
+		if skipped(path) {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var endpoints []endpoint
+	handlers := []string{}
+	for _, path := range paths {
+		for _, method := range methodOrder {
+			op := s.Paths[path][method]
+			if op == nil {
+				continue
+			}
+			handler, name := splitOperationID(op.OperationID)
+			ep := endpoint{
+				Name:   name,
+				Method: strings.ToUpper(method),
+				Path:   strings.TrimPrefix(path, apiPrefix),
+			}
+			// Undescribed operations are summarized by their id
+			if op.Summary != op.OperationID[strings.LastIndex(op.OperationID, ".")+1:] {
+				ep.Summary = op.Summary
+			}
+			for _, param := range op.Parameters {
+				switch param.In {
+				case "path":
+					ep.Params = append(ep.Params, param)
+				case "query":
+					ep.Query = append(ep.Query, param)
+				}
+			}
+			if op.RequestBody != nil {
+				ep.Body = op.RequestBody.Content["application/json"].Schema
+			}
+			ep.Response = responseData(op)
+			endpoints = append(endpoints, ep)
+			handlers = append(handlers, handler)
+		}
+	}
+
+	// Handler methods of the same name are told apart by their handler
+	counts := map[string]int{}
+	for _, ep := range endpoints {
+		counts[ep.Name]++
+	}
+	seen := map[string]bool{}
+	for i := range endpoints {
+		if counts[endpoints[i].Name] > 1 {
+			endpoints[i].Name = exportedName(strings.TrimSuffix(handlers[i], "Handler")) + endpoints[i].Name
+		}
+		if seen[endpoints[i].Name] {
+			return nil, fmt.Errorf("two operations are named %s", endpoints[i].Name)
+		}
+		seen[endpoints[i].Name] = true
+	}
+	return endpoints, nil
+}
+
+func skipped(path string) bool {
+	if !strings.HasPrefix(path, apiPrefix+"/") {
+		return true
+	}
+	for _, prefix := range skippedPaths {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// splitOperationID splits an operation id such as "WalletHandler.GetWallet"
+// into the handler and the exported name of the operation. Routes without a
+// handler method have ids such as "get_api_ping".
+func splitOperationID(id string) (handler, name string) {
+	if i := strings.LastIndex(id, "."); i >= 0 {
+		return id[:i], exportedName(id[i+1:])
+	}
+	return "", exportedName(id)
+}
+
+// responseData returns the schema of the data of a successful response, or
+// nil when the operation does not describe it
+func responseData(op *Operation) *Schema {
+	schema := op.Responses["200"].Content["application/json"].Schema
+	if schema == nil {
+		return nil
+	}
+	for _, part := range schema.AllOf {
+		if data := part.Properties["data"]; data != nil {
+			return data
+		}
+	}
+	return nil
+}
+
+// componentNames returns the names of the components the clients declare,
+// sorted
+func (s *Spec) componentNames() []string {
+	names := make([]string, 0, len(s.Components.Schemas))
+	for name := range s.Components.Schemas {
+		if name != envelope {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// refName returns the component a schema refers to
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+// nullableRef returns the component of a nullable reference, which
+// pkg/openapi writes as {allOf: [{$ref}], nullable: true}
+func nullableRef(schema *Schema) (string, bool) {
+	if len(schema.AllOf) == 1 && schema.AllOf[0].Ref != "" {
+		return refName(schema.AllOf[0].Ref), true
+	}
+	return "", false
+}
+
+// sortedProperties returns the property names of an object schema, sorted
+func sortedProperties(schema *Schema) []string {
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func isRequired(schema *Schema, name string) bool {
+	for _, required := range schema.Required {
+		if required == name {
+			return true
+		}
+	}
+	return false
+}
+
+// initialisms are written in capitals in Go names
+var initialisms = map[string]bool{
+	"API": true, "HTTP": true, "ID": true, "IDS": true, "IP": true, "JSON": true,
+	"PDF": true, "SQL": true, "URL": true, "UUID": true, "WS": true,
+}
+
+// exportedName turns a name such as "order_no" or "getItem" into an exported
+// Go name such as "OrderNo" or "GetItem"
+func exportedName(name string) string {
+	var b strings.Builder
+	for _, word := range words(name) {
+		if upper := strings.ToUpper(word); initialisms[upper] {
+			if upper == "IDS" {
+				upper = "IDs"
+			}
+			b.WriteString(upper)
+			continue
+		}
+		runes := []rune(word)
+		b.WriteRune(unicode.ToUpper(runes[0]))
+		b.WriteString(string(runes[1:]))
+	}
+	return b.String()
+}
+
+// unexportedName turns a name into a Go or TypeScript parameter name, such
+// as "orderNo"
+func unexportedName(name string) string {
+	exported := exportedName(name)
+	runes := []rune(exported)
+	i := 0
+	for i < len(runes) && unicode.IsUpper(runes[i]) {
+		i++
+	}
+	if i > 1 && i < len(runes) {
+		i-- // Keep the capital starting the next word, as in "idNumber"
+	}
+	return strings.ToLower(string(runes[:i])) + string(runes[i:])
+}
+
+// words splits a name at underscores, dashes and changes of case
+func words(name string) []string {
+	var words []string
+	var current []rune
+	runes := []rune(name)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if len(current) > 0 {
+				words = append(words, string(current))
+				current = nil
+			}
+			continue
+		}
+		if len(current) > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				words = append(words, string(current))
+				current = nil
+			}
+		}
+		current = append(current, r)
+	}
+	if len(current) > 0 {
+		words = append(words, string(current))
+	}
+	return words
+}
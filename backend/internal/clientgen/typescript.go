@@ -0,0 +1,190 @@
+package clientgen
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// GenerateTypeScript returns a TypeScript module declaring an interface per
+// component and a function per operation, which call the API through the
+// apiClient of the frontend's ./client module. source names the document in
+// the generated header.
+func GenerateTypeScript(spec *Spec, source string) ([]byte, error) {
+	endpoints, err := spec.endpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by clientgen from %s. DO NOT EDIT.\n\n", source)
+	out.WriteString("import { apiClient } from './client';\n\n")
+
+	for _, name := range spec.componentNames() {
+		schema := spec.Components.Schemas[name]
+		if len(schema.Properties) == 0 {
+			fmt.Fprintf(&out, "export type %s = %s;\n\n", goTypeName(name), tsType(schema, ""))
+			continue
+		}
+		fmt.Fprintf(&out, "export interface %s ", goTypeName(name))
+		tsObject(&out, schema, "")
+		out.WriteString("\n\n")
+	}
+
+	out.WriteString(`// withQuery appends the query parameters that are set to a path
+function withQuery(path: string, params?: object): string {
+  const search = new URLSearchParams();
+  for (const [key, value] of Object.entries(params ?? {})) {
+    if (value !== undefined && value !== null && value !== '') {
+      search.set(key, String(value));
+    }
+  }
+  const query = search.toString();
+  return query ? ` + "`${path}?${query}`" + ` : path;
+}
+`)
+
+	for _, ep := range endpoints {
+		tsFunction(&out, ep)
+	}
+	return out.Bytes(), nil
+}
+
+// tsFunction writes the function calling an endpoint, with the interface of
+// its query parameters
+func tsFunction(out *bytes.Buffer, ep endpoint) {
+	name := unexportedName(ep.Name)
+	var args []string
+	for _, param := range ep.Params {
+		args = append(args, unexportedName(param.Name)+": "+tsType(param.Schema, ""))
+	}
+
+	path := "`" + tsPath(ep) + "`"
+	if len(ep.Query) > 0 {
+		paramsType := ep.Name + "Params"
+		fmt.Fprintf(out, "\nexport interface %s {\n", paramsType)
+		for _, param := range ep.Query {
+			fmt.Fprintf(out, "  %s?: %s;\n", tsProperty(param.Name), tsType(param.Schema, "  "))
+		}
+		out.WriteString("}\n")
+		args = append(args, "params?: "+paramsType)
+		path = "withQuery(" + path + ", params)"
+	}
+
+	options := fmt.Sprintf("{ method: '%s' }", ep.Method)
+	if ep.Body != nil {
+		args = append(args, "body: "+tsType(ep.Body, ""))
+		options = fmt.Sprintf("{ method: '%s', body: JSON.stringify(body) }", ep.Method)
+	}
+
+	result := "unknown"
+	switch {
+	case ep.Response != nil && ep.Response.Type == "object" && ep.Response.Properties != nil:
+		// Inline objects are named as in the Go client
+		result = ep.Name + "Result"
+		fmt.Fprintf(out, "\nexport interface %s ", result)
+		tsObject(out, ep.Response, "")
+		out.WriteString("\n")
+	case ep.Response != nil:
+		result = tsType(ep.Response, "")
+	}
+
+	comment := fmt.Sprintf("%s %s", ep.Method, ep.Path)
+	if ep.Summary != "" {
+		comment += ": " + ep.Summary
+	}
+	fmt.Fprintf(out, "\n// %s\nexport function %s(%s): Promise<%s> {\n", comment, name, strings.Join(args, ", "), result)
+	fmt.Fprintf(out, "  return apiClient.request<%s>(%s, %s);\n}\n", result, path, options)
+}
+
+// tsPath returns the template literal of an endpoint's path with its
+// parameters filled in
+func tsPath(ep endpoint) string {
+	numeric := map[string]bool{}
+	for _, param := range ep.Params {
+		numeric[param.Name] = param.Schema != nil && (param.Schema.Type == "integer" || param.Schema.Type == "number")
+	}
+	return pathParam.ReplaceAllStringFunc(ep.Path, func(match string) string {
+		name := match[1 : len(match)-1]
+		if numeric[name] {
+			return "${" + unexportedName(name) + "}"
+		}
+		return "${encodeURIComponent(" + unexportedName(name) + ")}"
+	})
+}
+
+var pathParam = regexp.MustCompile(`\{[^}]+\}`)
+
+// tsType returns the TypeScript type of a schema; indent is the indentation
+// of the line inline objects start on
+func tsType(schema *Schema, indent string) string {
+	if schema == nil {
+		return "unknown"
+	}
+	if schema.Ref != "" {
+		return goTypeName(refName(schema.Ref))
+	}
+	if name, ok := nullableRef(schema); ok {
+		return goTypeName(name) + " | null"
+	}
+
+	var base string
+	switch schema.Type {
+	case "boolean":
+		base = "boolean"
+	case "integer", "number":
+		base = "number"
+	case "string":
+		base = "string"
+	case "array":
+		items := tsType(schema.Items, indent)
+		if strings.Contains(items, " | ") {
+			items = "(" + items + ")"
+		}
+		return items + "[]"
+	case "object":
+		if schema.Properties != nil {
+			var b bytes.Buffer
+			tsObject(&b, schema, indent)
+			base = b.String()
+			break
+		}
+		return "Record<string, " + tsType(schema.AdditionalProperties, indent) + ">"
+	default:
+		return "unknown"
+	}
+	if schema.Nullable {
+		return base + " | null"
+	}
+	return base
+}
+
+// tsObject writes the object type of a schema with its properties
+func tsObject(out *bytes.Buffer, schema *Schema, indent string) {
+	properties := sortedProperties(schema)
+	if len(properties) == 0 {
+		out.WriteString("Record<string, unknown>")
+		return
+	}
+	out.WriteString("{\n")
+	for _, property := range properties {
+		optional := "?"
+		if isRequired(schema, property) {
+			optional = ""
+		}
+		fmt.Fprintf(out, "%s  %s%s: %s;\n", indent, tsProperty(property), optional,
+			tsType(schema.Properties[property], indent+"  "))
+	}
+	out.WriteString(indent + "}")
+}
+
+var tsIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// tsProperty quotes the property names that are not identifiers
+func tsProperty(name string) string {
+	if tsIdentifier.MatchString(name) {
+		return name
+	}
+	return "'" + name + "'"
+}
@@ -10,8 +10,11 @@ import (
 // Config holds all configuration for the application
 type Config struct {
 	// Server settings
-	ServerPort string
-	ServerHost string
+	ServerPort         string
+	ServerHost         string
+	ShutdownDrainDelay int    // in seconds, how long the readiness probe fails before the server stops accepting requests
+	ShutdownTimeout    int    // in seconds, how long in-flight requests may take to finish on shutdown
+	BusinessTimezone   string // IANA time zone days, weeks and months are counted in
 
 	// Database settings
 	DBDriver   string // sqlite or postgres
@@ -22,6 +25,13 @@ type Config struct {
 	DBName     string
 	DBPath     string // SQLite file path
 
+	// Read-only database connection for statistics and exports
+	DBReadOnly         bool   // open a separate read-only connection for reporting
+	DBReadOnlyHost     string // defaults to DBHost
+	DBReadOnlyPort     string // defaults to DBPort
+	DBReadOnlyUser     string // postgres role granted only SELECT
+	DBReadOnlyPassword string
+
 	// JWT settings
 	JWTSecret          string
 	JWTAccessExpiry    int // in minutes
@@ -47,6 +57,145 @@ type Config struct {
 
 	// Encryption
 	EncryptionKey string
+
+	// CORS settings
+	CORSAllowedOrigins string // comma separated origins browsers may call the API from, "*" allows any
+	TrustedProxies     string // comma separated proxy IPs or CIDRs whose X-Forwarded-For is believed, none by default
+
+	// Retailer API settings
+	RetailerAPIKeys      string // comma separated API keys for partner kiosks
+	VerifyBatchMaxCodes  int    // max security codes per batch verification
+	VerifyBatchRateLimit int    // batch verification requests per minute per API key
+
+	// Waiting room settings
+	WaitingRoomWindow       int // in minutes, how long a lottery type stays protected after overflowing
+	WaitingRoomAdmissionTTL int // in seconds, how long an admitted user has to purchase
+
+	// Inventory monitor settings
+	InventoryMonitorInterval int // in minutes, 0 disables the monitor
+
+	// Stock counter settings
+	StockCounterInterval int // in seconds, how often cached stock counters are reconciled with the database; 0 reads stock from the database
+	InventoryVelocityWindow  int // in hours, look-back for sales velocity
+
+	// Prize pool replenishment settings
+	PrizePoolReplenishInterval int // in seconds, 0 disables opening new pools for sold out lottery types
+
+	// Lottery type schedule settings
+	LotteryTypeScheduleInterval int // in seconds, 0 disables scheduled starts and ends of lottery types
+
+	// Exchange gift settings
+	ExchangeGiftExpiryDays    int // days a recipient has to accept a gift
+	ExchangeGiftSweepInterval int // in minutes, 0 disables returning expired gifts in the background
+
+	// Two-phase exchange settings
+	ExchangeReservationMinutes int // how long a reservation holds points and a card key
+
+	// Transaction dispute settings
+	DisputeWindowDays int // days after a debit its user can dispute it, 0 for no limit
+
+	// Purchase preview settings
+	PurchaseReservationSeconds int // how long a preview holds tickets for the purchase, 0 disables reservations
+
+	// Wallet balance snapshot settings
+	WalletSnapshotInterval int // in minutes, 0 disables the daily balance snapshot job
+
+	// Wallet webhook settings
+	WalletWebhookInterval    int // in seconds, 0 disables the webhook dispatcher
+	WalletWebhookMaxAttempts int // delivery attempts before a delivery is marked failed
+
+	// Verify page subscription settings
+	TicketWatchInterval  int // in seconds, how often watched tickets are polled
+	TicketWatchRateLimit int // subscriptions per minute per client IP
+	TicketWatchMaxPerIP  int // concurrent subscriptions per client IP
+
+	// Live event settings
+	LiveBigWinMin int // prizes of at least this many points are pushed as big wins, 0 disables them
+	LiveMaxPerIP  int // concurrent live event connections per client IP
+
+	// Login protection settings
+	AuthMaxFailures    int // failed attempts of an account or IP before it is locked out
+	AuthFailureWindow  int // in minutes, window failures are counted in
+	AuthLockoutMinutes int // how long a lockout lasts
+	MaxSessionsPerUser int // active sessions an account holds before the oldest is evicted, 0 is unlimited
+	MaxSessionsPerIP   int // active sessions of other accounts an IP holds before logins from it are refused, 0 is unlimited
+
+	// Demo content settings
+	DemoSeed bool // create a demo lottery, products and admin on first boot
+
+	// API documentation settings
+	APIDocsEnabled bool // serve the OpenAPI document at /api/docs
+
+	// Admin API settings
+	AdminIPAllowlist   string // comma separated IPs and CIDR ranges allowed to reach /api/admin, empty allows any
+	AdminTokenIssuer   string // issuer of admin audience tokens
+	AdminTokenAudience string // audience of admin audience tokens, empty disables them
+	AdminTokenExpiry   int    // in minutes
+
+	// Branding settings
+	BrandingAssetDir     string // directory uploaded branding assets are stored in
+	BrandingMaxAssetKB   int    // maximum size of an uploaded branding asset
+	BrandingCacheSeconds int    // max-age of the public branding response
+
+	// Purchase preview odds settings
+	OddsHintMode         string // exact, banded or off
+	OddsHintCacheSeconds int    // how long the odds of a pool are cached
+
+	// Wallet settings
+	BonusSpendPriority string // bonus_first or points_first: which balance ticket purchases draw on first
+
+	// User badge settings
+	BadgeReconcileInterval int // in minutes, 0 disables reconciling badge counters in the background
+
+	// Wallet reconciliation settings
+	WalletReconcileInterval int // in minutes, 0 disables auditing wallets against their ledger in the background
+
+	// Payment reconciliation settings
+	PaymentReconcileInterval int // in minutes, 0 disables looking up stale pending orders at their gateway in the background
+	PaymentPendingTimeout    int // in minutes an order may stay pending before it is looked up, and expired if unpaid
+
+	// User segment settings
+	SegmentEvaluationInterval int // in minutes, 0 disables re-evaluating segment memberships in the background
+
+	// Transaction partition settings
+	TransactionPartitionInterval    int // in hours, 0 disables creating monthly transaction partitions ahead of time
+	TransactionPartitionMonthsAhead int // number of future months to keep partitions for
+
+	// Weekly digest settings
+	DigestInterval int // in minutes, 0 disables sending weekly digests
+
+	// Request analytics settings
+	RequestAnalyticsInterval      int // in seconds, how often recorded requests are flushed; 0 disables request analytics
+	RequestAnalyticsRetentionDays int // hourly request analytics older than this are deleted, 0 keeps them
+
+	// Status page settings
+	StatusCheckInterval int // in seconds, how often component health is checked; 0 disables health checks
+	StatusCacheSeconds  int // how long the public status is cached
+
+	// Kill switch settings
+	KillSwitchCacheSeconds int // how long subsystem kill switches are cached per instance
+
+	// Voucher claim settings
+	VoucherClaimMaxFailures int // failed voucher claims of a user before claiming is locked
+	VoucherClaimWindow      int // in minutes, how long failed voucher claims count
+	VoucherClaimRateLimit   int // voucher claims per minute per user
+
+	// Request body limits
+	BodyMaxKB           int // default maximum request body size, 0 disables the limit
+	CardKeyImportMaxKB  int // maximum body size of a card key import
+	ConfigJSONMaxDepth  int // maximum nesting of JSON bodies sent to admin config endpoints
+	ConfigJSONMaxFields int // maximum fields and array elements of JSON bodies sent to admin config endpoints
+
+	// Captcha settings
+	CaptchaProvider string // none, turnstile, hcaptcha or recaptcha
+	CaptchaSecret   string // secret key of the captcha provider
+
+	// Sandbox tenant settings
+	SandboxResetInterval   int // in minutes, 0 disables resetting sandbox tenants in the background
+	SandboxStartingBalance int // test points every sandbox wallet starts over with
+
+	// Canary settings
+	CanaryAPIKeys string // comma separated API keys deploy pipelines run the canary checks with
 }
 
 var cfg *Config
@@ -58,8 +207,11 @@ func Load() (*Config, error) {
 
 	cfg = &Config{
 		// Server
-		ServerPort: getEnv("SERVER_PORT", "8080"),
-		ServerHost: getEnv("SERVER_HOST", "0.0.0.0"),
+		ServerPort:         getEnv("SERVER_PORT", "8080"),
+		ServerHost:         getEnv("SERVER_HOST", "0.0.0.0"),
+		ShutdownDrainDelay: getEnvInt("SHUTDOWN_DRAIN_DELAY", 5),
+		ShutdownTimeout:    getEnvInt("SHUTDOWN_TIMEOUT", 30),
+		BusinessTimezone:   getEnv("BUSINESS_TIMEZONE", "Asia/Shanghai"),
 
 		// Database
 		DBDriver:   getEnv("DB_DRIVER", "sqlite"),
@@ -70,6 +222,13 @@ func Load() (*Config, error) {
 		DBName:     getEnv("DB_NAME", "scratch_lottery"),
 		DBPath:     getEnv("DB_PATH", "./data/lottery.db"),
 
+		// Read-only database
+		DBReadOnly:         getEnvBool("DB_READONLY", false),
+		DBReadOnlyHost:     getEnv("DB_READONLY_HOST", getEnv("DB_HOST", "localhost")),
+		DBReadOnlyPort:     getEnv("DB_READONLY_PORT", getEnv("DB_PORT", "5432")),
+		DBReadOnlyUser:     getEnv("DB_READONLY_USER", ""),
+		DBReadOnlyPassword: getEnv("DB_READONLY_PASSWORD", ""),
+
 		// JWT
 		JWTSecret:        getEnv("JWT_SECRET", "your-secret-key-change-in-production"),
 		JWTAccessExpiry:  getEnvInt("JWT_ACCESS_EXPIRY", 15),
@@ -95,6 +254,144 @@ func Load() (*Config, error) {
 
 		// Encryption
 		EncryptionKey: getEnv("ENCRYPTION_KEY", "32-byte-key-for-aes-encryption!"),
+
+		// Retailer API
+		CORSAllowedOrigins: getEnv("CORS_ALLOWED_ORIGINS", "*"),
+		TrustedProxies:     getEnv("TRUSTED_PROXIES", ""),
+
+		RetailerAPIKeys:      getEnv("RETAILER_API_KEYS", ""),
+		VerifyBatchMaxCodes:  getEnvInt("VERIFY_BATCH_MAX_CODES", 50),
+		VerifyBatchRateLimit: getEnvInt("VERIFY_BATCH_RATE_LIMIT", 10),
+
+		// Waiting room
+		WaitingRoomWindow:       getEnvInt("WAITING_ROOM_WINDOW", 10),
+		WaitingRoomAdmissionTTL: getEnvInt("WAITING_ROOM_ADMISSION_TTL", 120),
+
+		// Inventory monitor
+		InventoryMonitorInterval: getEnvInt("INVENTORY_MONITOR_INTERVAL", 5),
+
+		// Stock counters
+		StockCounterInterval: getEnvInt("STOCK_COUNTER_INTERVAL", 30),
+		InventoryVelocityWindow:  getEnvInt("INVENTORY_VELOCITY_WINDOW", 24),
+
+		// Prize pool replenishment
+		PrizePoolReplenishInterval: getEnvInt("PRIZE_POOL_REPLENISH_INTERVAL", 60),
+
+		// Lottery type schedules
+		LotteryTypeScheduleInterval: getEnvInt("LOTTERY_TYPE_SCHEDULE_INTERVAL", 30),
+
+		// Exchange gifts
+		ExchangeGiftExpiryDays:    getEnvInt("EXCHANGE_GIFT_EXPIRY_DAYS", 7),
+		ExchangeGiftSweepInterval: getEnvInt("EXCHANGE_GIFT_SWEEP_INTERVAL", 60),
+
+		// Two-phase exchange
+		ExchangeReservationMinutes: getEnvInt("EXCHANGE_RESERVATION_MINUTES", 15),
+
+		// Transaction disputes
+		DisputeWindowDays: getEnvInt("DISPUTE_WINDOW_DAYS", 30),
+
+		// Purchase preview
+		PurchaseReservationSeconds: getEnvInt("PURCHASE_RESERVATION_SECONDS", 120),
+
+		// Wallet balance snapshots
+		WalletSnapshotInterval: getEnvInt("WALLET_SNAPSHOT_INTERVAL", 60),
+
+		// Wallet webhooks
+		WalletWebhookInterval:    getEnvInt("WALLET_WEBHOOK_INTERVAL", 15),
+		WalletWebhookMaxAttempts: getEnvInt("WALLET_WEBHOOK_MAX_ATTEMPTS", 8),
+
+		// Verify page subscriptions
+		TicketWatchInterval:  getEnvInt("TICKET_WATCH_INTERVAL", 2),
+		TicketWatchRateLimit: getEnvInt("TICKET_WATCH_RATE_LIMIT", 10),
+		TicketWatchMaxPerIP:  getEnvInt("TICKET_WATCH_MAX_PER_IP", 5),
+
+		// Live events
+		LiveBigWinMin: getEnvInt("LIVE_BIG_WIN_MIN", 1000),
+		LiveMaxPerIP:  getEnvInt("LIVE_MAX_PER_IP", 5),
+
+		// Login protection
+		AuthMaxFailures:    getEnvInt("AUTH_MAX_FAILURES", 5),
+		AuthFailureWindow:  getEnvInt("AUTH_FAILURE_WINDOW", 15),
+		AuthLockoutMinutes: getEnvInt("AUTH_LOCKOUT_MINUTES", 15),
+		MaxSessionsPerUser: getEnvInt("MAX_SESSIONS_PER_USER", 5),
+		MaxSessionsPerIP:   getEnvInt("MAX_SESSIONS_PER_IP", 0),
+
+		// Demo content
+		DemoSeed: getEnvBool("DEMO_SEED", false),
+
+		// API documentation
+		APIDocsEnabled: getEnvBool("API_DOCS_ENABLED", false),
+
+		// Admin API
+		AdminIPAllowlist:   getEnv("ADMIN_IP_ALLOWLIST", ""),
+		AdminTokenIssuer:   getEnv("ADMIN_TOKEN_ISSUER", "scratch-lottery"),
+		AdminTokenAudience: getEnv("ADMIN_TOKEN_AUDIENCE", ""),
+		AdminTokenExpiry:   getEnvInt("ADMIN_TOKEN_EXPIRY", 60),
+
+		// Branding
+		BrandingAssetDir:     getEnv("BRANDING_ASSET_DIR", "./data/branding"),
+		BrandingMaxAssetKB:   getEnvInt("BRANDING_MAX_ASSET_KB", 512),
+		BrandingCacheSeconds: getEnvInt("BRANDING_CACHE_SECONDS", 300),
+
+		// Purchase preview odds
+		OddsHintMode:         getEnv("ODDS_HINT_MODE", "banded"),
+		OddsHintCacheSeconds: getEnvInt("ODDS_HINT_CACHE_SECONDS", 30),
+
+		// Wallet
+		BonusSpendPriority: getEnv("BONUS_SPEND_PRIORITY", "bonus_first"),
+
+		// User badges
+		BadgeReconcileInterval: getEnvInt("BADGE_RECONCILE_INTERVAL", 30),
+
+		// Wallet reconciliation
+		WalletReconcileInterval: getEnvInt("WALLET_RECONCILE_INTERVAL", 60),
+
+		// Payment reconciliation
+		PaymentReconcileInterval: getEnvInt("PAYMENT_RECONCILE_INTERVAL", 10),
+		PaymentPendingTimeout:    getEnvInt("PAYMENT_PENDING_TIMEOUT", 30),
+
+		// User segments
+		SegmentEvaluationInterval: getEnvInt("SEGMENT_EVALUATION_INTERVAL", 1440),
+
+		// Transaction partitions
+		TransactionPartitionInterval:    getEnvInt("TRANSACTION_PARTITION_INTERVAL", 24),
+		TransactionPartitionMonthsAhead: getEnvInt("TRANSACTION_PARTITION_MONTHS_AHEAD", 3),
+
+		// Weekly digests
+		DigestInterval: getEnvInt("DIGEST_INTERVAL", 60),
+
+		// Request analytics
+		RequestAnalyticsInterval:      getEnvInt("REQUEST_ANALYTICS_INTERVAL", 60),
+		RequestAnalyticsRetentionDays: getEnvInt("REQUEST_ANALYTICS_RETENTION_DAYS", 90),
+
+		// Status page
+		StatusCheckInterval: getEnvInt("STATUS_CHECK_INTERVAL", 60),
+		StatusCacheSeconds:  getEnvInt("STATUS_CACHE_SECONDS", 30),
+
+		// Kill switches
+		KillSwitchCacheSeconds: getEnvInt("KILL_SWITCH_CACHE_SECONDS", 5),
+
+		// Voucher claims
+		VoucherClaimMaxFailures: getEnvInt("VOUCHER_CLAIM_MAX_FAILURES", 5),
+		VoucherClaimWindow:      getEnvInt("VOUCHER_CLAIM_WINDOW", 60),
+		VoucherClaimRateLimit:   getEnvInt("VOUCHER_CLAIM_RATE_LIMIT", 10),
+
+		// Request body limits
+		BodyMaxKB:           getEnvInt("BODY_MAX_KB", 1024),
+		CardKeyImportMaxKB:  getEnvInt("CARD_KEY_IMPORT_MAX_KB", 20480),
+		ConfigJSONMaxDepth:  getEnvInt("CONFIG_JSON_MAX_DEPTH", 16),
+		ConfigJSONMaxFields: getEnvInt("CONFIG_JSON_MAX_FIELDS", 2000),
+
+		// Captcha
+		CaptchaProvider: getEnv("CAPTCHA_PROVIDER", "none"),
+		CaptchaSecret:   getEnv("CAPTCHA_SECRET", ""),
+
+		// Sandbox tenants
+		SandboxResetInterval:   getEnvInt("SANDBOX_RESET_INTERVAL", 1440),
+		SandboxStartingBalance: getEnvInt("SANDBOX_STARTING_BALANCE", 1000),
+
+		// Canary checks
+		CanaryAPIKeys: getEnv("CANARY_API_KEYS", ""),
 	}
 
 	return cfg, nil
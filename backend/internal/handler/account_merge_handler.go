@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"strconv"
+
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccountMergeHandler handles duplicate account detection and merges
+type AccountMergeHandler struct {
+	accountMergeService *service.AccountMergeService
+}
+
+// NewAccountMergeHandler creates a new account merge handler
+func NewAccountMergeHandler(accountMergeService *service.AccountMergeService) *AccountMergeHandler {
+	return &AccountMergeHandler{accountMergeService: accountMergeService}
+}
+
+// GetCandidates returns the pairs of accounts that look like duplicates
+// GET /api/admin/account-merges/candidates
+func (h *AccountMergeHandler) GetCandidates(c *gin.Context) {
+	candidates, err := h.accountMergeService.ForTenant(tenantID(c)).FindDuplicates()
+	if err != nil {
+		response.InternalError(c, "获取重复账户失败", err.Error())
+		return
+	}
+
+	response.Success(c, candidates)
+}
+
+// GetMerges returns the account merges, newest first
+// GET /api/admin/account-merges
+func (h *AccountMergeHandler) GetMerges(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	merges, err := h.accountMergeService.ForTenant(tenantID(c)).GetMerges(page, limit)
+	if err != nil {
+		response.InternalError(c, "获取合并记录失败", err.Error())
+		return
+	}
+
+	response.Success(c, merges)
+}
+
+// Merge merges a duplicate account into another
+// POST /api/admin/account-merges
+func (h *AccountMergeHandler) Merge(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	var req service.MergeAccountsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	merge, err := h.accountMergeService.ForTenant(tenantID(c)).Merge(adminID.(uint), req)
+	if err != nil {
+		h.handleError(c, err, "合并账户失败")
+		return
+	}
+
+	response.Created(c, merge)
+}
+
+// Rollback undoes an account merge
+// POST /api/admin/account-merges/:id/rollback
+func (h *AccountMergeHandler) Rollback(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	mergeID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的合并记录ID")
+		return
+	}
+
+	merge, err := h.accountMergeService.ForTenant(tenantID(c)).Rollback(adminID.(uint), uint(mergeID))
+	if err != nil {
+		h.handleError(c, err, "撤销合并失败")
+		return
+	}
+
+	response.Success(c, merge)
+}
+
+func (h *AccountMergeHandler) handleError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrUserNotFound:
+		response.NotFound(c, "用户不存在")
+	case service.ErrMergeNotFound:
+		response.NotFound(c, "合并记录不存在")
+	case service.ErrInvalidMerge:
+		response.BadRequest(c, "无法合并：不能合并同一账户、管理员账户或已合并的账户")
+	case service.ErrMergeRolledBack:
+		response.BadRequest(c, "该合并已撤销")
+	case service.ErrMergeRollbackBalance:
+		response.BadRequest(c, "目标账户余额不足，无法撤销合并")
+	default:
+		response.InternalError(c, message, err.Error())
+	}
+}
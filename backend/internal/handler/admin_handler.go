@@ -22,7 +22,7 @@ func NewAdminHandler(adminService *service.AdminService) *AdminHandler {
 // GetPaymentStatus returns whether payment is enabled (public endpoint)
 // GET /api/system/payment-status
 func (h *AdminHandler) GetPaymentStatus(c *gin.Context) {
-	enabled := h.adminService.IsPaymentEnabled()
+	enabled := h.adminService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).IsPaymentEnabled()
 	response.Success(c, gin.H{
 		"payment_enabled": enabled,
 	})
@@ -31,7 +31,7 @@ func (h *AdminHandler) GetPaymentStatus(c *gin.Context) {
 // GetDashboard returns dashboard statistics
 // GET /api/admin/dashboard
 func (h *AdminHandler) GetDashboard(c *gin.Context) {
-	stats, err := h.adminService.GetDashboardStats()
+	stats, err := h.adminService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).GetDashboardStats()
 	if err != nil {
 		response.InternalError(c, "获取统计数据失败", err.Error())
 		return
@@ -51,7 +51,7 @@ func (h *AdminHandler) GetUsers(c *gin.Context) {
 		return
 	}
 
-	result, err := h.adminService.GetUsers(query)
+	result, err := h.adminService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).GetUsers(query)
 	if err != nil {
 		response.InternalError(c, "获取用户列表失败", err.Error())
 		return
@@ -70,7 +70,7 @@ func (h *AdminHandler) GetUserByID(c *gin.Context) {
 		return
 	}
 
-	user, err := h.adminService.GetUserByID(uint(id))
+	user, err := h.adminService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).GetUserByID(uint(id))
 	if err != nil {
 		switch err {
 		case service.ErrUserNotFound:
@@ -106,7 +106,7 @@ func (h *AdminHandler) AdjustUserPoints(c *gin.Context) {
 		return
 	}
 
-	user, err := h.adminService.AdjustUserPoints(adminID.(uint), uint(id), req)
+	user, err := h.adminService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).AdjustUserPoints(adminID.(uint), uint(id), req)
 	if err != nil {
 		switch err {
 		case service.ErrUserNotFound:
@@ -146,7 +146,7 @@ func (h *AdminHandler) UpdateUserRole(c *gin.Context) {
 		return
 	}
 
-	user, err := h.adminService.UpdateUserRole(adminID.(uint), uint(id), req.Role)
+	user, err := h.adminService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).UpdateUserRole(adminID.(uint), uint(id), req.Role)
 	if err != nil {
 		switch err {
 		case service.ErrUserNotFound:
@@ -165,7 +165,7 @@ func (h *AdminHandler) UpdateUserRole(c *gin.Context) {
 // GetSystemSettings returns system settings
 // GET /api/admin/settings
 func (h *AdminHandler) GetSystemSettings(c *gin.Context) {
-	settings, err := h.adminService.GetSystemSettings()
+	settings, err := h.adminService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).GetSystemSettings()
 	if err != nil {
 		response.InternalError(c, "获取系统设置失败", err.Error())
 		return
@@ -189,8 +189,16 @@ func (h *AdminHandler) UpdateSystemSettings(c *gin.Context) {
 		return
 	}
 
-	settings, err := h.adminService.UpdateSystemSettings(adminID.(uint), req)
+	settings, err := h.adminService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).UpdateSystemSettings(adminID.(uint), req)
 	if err != nil {
+		if err == service.ErrInvalidReportThreshold {
+			response.BadRequest(c, "大额中奖门槛不能为负数")
+			return
+		}
+		if err == service.ErrUnknownPaymentGateway {
+			response.BadRequest(c, "未知的支付网关")
+			return
+		}
 		response.InternalError(c, "更新系统设置失败", err.Error())
 		return
 	}
@@ -198,6 +206,47 @@ func (h *AdminHandler) UpdateSystemSettings(c *gin.Context) {
 	response.Success(c, settings)
 }
 
+// GetFieldRedactionPolicy returns the response fields redacted for each staff role
+// GET /api/admin/settings/redaction
+func (h *AdminHandler) GetFieldRedactionPolicy(c *gin.Context) {
+	policy, err := h.adminService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).GetFieldRedactionPolicy()
+	if err != nil {
+		response.InternalError(c, "获取字段脱敏配置失败", err.Error())
+		return
+	}
+
+	response.Success(c, policy)
+}
+
+// UpdateFieldRedactionPolicy replaces the response fields redacted for each staff role
+// PUT /api/admin/settings/redaction
+func (h *AdminHandler) UpdateFieldRedactionPolicy(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	var req service.FieldRedactionPolicy
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	policy, err := h.adminService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).UpdateFieldRedactionPolicy(adminID.(uint), req)
+	if err != nil {
+		switch err {
+		case service.ErrInvalidFieldRedaction:
+			response.BadRequest(c, "无效的字段脱敏配置")
+		default:
+			response.InternalError(c, "更新字段脱敏配置失败", err.Error())
+		}
+		return
+	}
+
+	response.Success(c, policy)
+}
+
 // ==================== Admin Logs ====================
 
 // GetAdminLogs returns paginated admin logs
@@ -209,7 +258,7 @@ func (h *AdminHandler) GetAdminLogs(c *gin.Context) {
 		return
 	}
 
-	result, err := h.adminService.GetAdminLogs(query)
+	result, err := h.adminService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).GetAdminLogs(query)
 	if err != nil {
 		response.InternalError(c, "获取操作日志失败", err.Error())
 		return
@@ -218,6 +267,54 @@ func (h *AdminHandler) GetAdminLogs(c *gin.Context) {
 	response.Success(c, result)
 }
 
+// ExportAdminLogs exports the admin logs matching the listing filters as CSV or JSON
+// GET /api/admin/logs/export
+func (h *AdminHandler) ExportAdminLogs(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	var query service.AdminLogQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	format := c.DefaultQuery("format", service.AdminLogExportCSV)
+	data, err := h.adminService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).ExportAdminLogs(adminID.(uint), query, format)
+	if err != nil {
+		switch err {
+		case service.ErrInvalidExportFormat:
+			response.BadRequest(c, "不支持的导出格式")
+		default:
+			response.InternalError(c, "导出操作日志失败", err.Error())
+		}
+		return
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if format == service.AdminLogExportJSON {
+		contentType = "application/json; charset=utf-8"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", "attachment; filename=admin-logs."+format)
+	c.Data(200, contentType, data)
+}
+
+// VerifyAdminLogs checks the admin log hash chain for missing or altered logs
+// GET /api/admin/logs/verify
+func (h *AdminHandler) VerifyAdminLogs(c *gin.Context) {
+	result, err := h.adminService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).VerifyAdminLogs()
+	if err != nil {
+		response.InternalError(c, "校验操作日志失败", err.Error())
+		return
+	}
+
+	response.Success(c, result)
+}
+
 // ==================== Statistics ====================
 
 // GetStatistics returns comprehensive statistics
@@ -229,7 +326,7 @@ func (h *AdminHandler) GetStatistics(c *gin.Context) {
 		return
 	}
 
-	stats, err := h.adminService.GetStatistics(query)
+	stats, err := h.adminService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).GetStatistics(query)
 	if err != nil {
 		response.InternalError(c, "获取统计数据失败", err.Error())
 		return
@@ -238,6 +335,24 @@ func (h *AdminHandler) GetStatistics(c *gin.Context) {
 	response.Success(c, stats)
 }
 
+// GetSalesForecast returns sell-out and revenue forecasts per lottery type
+// GET /api/admin/statistics/forecast
+func (h *AdminHandler) GetSalesForecast(c *gin.Context) {
+	var query service.ForecastQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	forecast, err := h.adminService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).GetSalesForecast(query)
+	if err != nil {
+		response.InternalError(c, "获取销售预测失败", err.Error())
+		return
+	}
+
+	response.Success(c, forecast)
+}
+
 // ExportStatistics exports statistics as CSV
 // GET /api/admin/statistics/export
 func (h *AdminHandler) ExportStatistics(c *gin.Context) {
@@ -247,7 +362,7 @@ func (h *AdminHandler) ExportStatistics(c *gin.Context) {
 		return
 	}
 
-	csvData, err := h.adminService.ExportStatisticsCSV(query)
+	csvData, err := h.adminService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).ExportStatisticsCSV(query)
 	if err != nil {
 		response.InternalError(c, "导出统计数据失败", err.Error())
 		return
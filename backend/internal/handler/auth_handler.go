@@ -1,8 +1,12 @@
 package handler
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
+	"scratch-lottery/internal/model"
 	"scratch-lottery/internal/service"
 	"scratch-lottery/pkg/auth"
 	"scratch-lottery/pkg/response"
@@ -12,12 +16,31 @@ import (
 
 // AuthHandler handles authentication endpoints
 type AuthHandler struct {
-	authService *service.AuthService
+	authService      *service.AuthService
+	authGuardService *service.AuthGuardService
+	adminRealm       *service.AdminRealm
 }
 
 // NewAuthHandler creates a new auth handler
-func NewAuthHandler(authService *service.AuthService) *AuthHandler {
-	return &AuthHandler{authService: authService}
+func NewAuthHandler(authService *service.AuthService, authGuardService *service.AuthGuardService, adminRealm *service.AdminRealm) *AuthHandler {
+	return &AuthHandler{authService: authService, authGuardService: authGuardService, adminRealm: adminRealm}
+}
+
+// clientInfo returns the client of the request for auth incident tracking
+func clientInfo(c *gin.Context) service.ClientInfo {
+	return service.ClientInfo{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+}
+
+// respondLocked rejects a login attempt from a locked out account or IP
+func respondLocked(c *gin.Context, lockedUntil time.Time) {
+	retryAfter := int(time.Until(lockedUntil).Seconds()) + 1
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	response.TooManyRequests(c, fmt.Sprintf("登录失败次数过多，请于 %s 后重试", lockedUntil.Format("15:04")))
+}
+
+// respondTooManySessions rejects a login from an IP holding too many sessions
+func respondTooManySessions(c *gin.Context) {
+	response.TooManyRequests(c, "当前网络登录的账号过多，请稍后再试")
 }
 
 // DevLoginRequest represents the dev login request
@@ -58,19 +81,40 @@ func (h *AuthHandler) DevLogin(c *gin.Context) {
 		return
 	}
 
-	authResp, err := h.authService.DevLogin(req.UserID)
+	client := clientInfo(c)
+	guard := h.authGuardService.ForTenant(tenantID(c))
+	lockedUntil, err := guard.LockedUntil(req.UserID, client.IP)
+	if err != nil {
+		response.InternalError(c, "登录失败", err.Error())
+		return
+	}
+	if lockedUntil != nil {
+		respondLocked(c, *lockedUntil)
+		return
+	}
+
+	authResp, err := h.authService.ForTenant(tenantID(c)).DevLogin(req.UserID, client)
 	if err != nil {
 		switch err {
 		case service.ErrDevModeDisabled:
 			response.Error(c, http.StatusForbidden, response.ErrForbidden, "开发模式未启用")
 		case service.ErrInvalidDevUser:
+			_ = guard.RecordFailure(model.AuthIncidentLoginFailed, req.UserID, client, "unknown dev user")
 			response.BadRequest(c, "无效的开发用户ID")
+		case service.ErrTooManyIPSessions:
+			_ = guard.RecordFailure(model.AuthIncidentSessionLimit, req.UserID, client, "too many sessions from ip")
+			respondTooManySessions(c)
 		default:
 			response.InternalError(c, "登录失败", err.Error())
 		}
 		return
 	}
 
+	if err := guard.RecordLogin(authResp.User, client, model.LoginMethodDev); err != nil {
+		response.InternalError(c, "登录失败", err.Error())
+		return
+	}
+
 	response.Success(c, authResp)
 }
 
@@ -83,7 +127,7 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
-	authResp, err := h.authService.RefreshToken(req.RefreshToken)
+	authResp, err := h.authService.ForTenant(tenantID(c)).RefreshToken(req.RefreshToken)
 	if err != nil {
 		switch err {
 		case auth.ErrExpiredToken:
@@ -133,7 +177,7 @@ func (h *AuthHandler) GetCurrentUser(c *gin.Context) {
 		return
 	}
 
-	user, err := h.authService.GetUserByID(userID.(uint))
+	user, err := h.authService.ForTenant(tenantID(c)).GetUserByID(userID.(uint))
 	if err != nil {
 		response.NotFound(c, "用户不存在")
 		return
@@ -142,6 +186,46 @@ func (h *AuthHandler) GetCurrentUser(c *gin.Context) {
 	response.Success(c, user)
 }
 
+// CreateAdminSession issues an admin audience token to an admin. With an IP
+// allowlist configured it must be requested from an allowlisted IP.
+// POST /api/auth/admin-session
+func (h *AuthHandler) CreateAdminSession(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	user, err := h.authService.ForTenant(tenantID(c)).GetUserByID(userID.(uint))
+	if err != nil {
+		response.NotFound(c, "用户不存在")
+		return
+	}
+
+	session, err := h.adminRealm.IssueToken(user, c.ClientIP())
+	if err != nil {
+		reason := ""
+		switch err {
+		case service.ErrAdminRealmDisabled:
+			response.NotFound(c, "管理后台令牌未启用")
+		case service.ErrNotAdmin:
+			reason = service.AdminDenialNotAdmin
+			response.Forbidden(c, "需要管理员权限", reason)
+		case service.ErrAdminIPNotAllowed:
+			reason = service.AdminDenialIPNotAllowed
+			response.Error(c, http.StatusForbidden, response.ErrAdminIPDenied, "当前IP不允许申请管理后台令牌", reason)
+		default:
+			response.InternalError(c, "签发管理后台令牌失败", err.Error())
+		}
+		if reason != "" {
+			_ = h.authGuardService.ForTenant(tenantID(c)).RecordAdminDenial(user.ID, user.LinuxdoID, clientInfo(c), reason+" POST /api/auth/admin-session")
+		}
+		return
+	}
+
+	response.Success(c, session)
+}
+
 // GetAuthMode returns the current authentication mode
 // GET /api/auth/mode
 func (h *AuthHandler) GetAuthMode(c *gin.Context) {
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthIncidentHandler handles the admin view of authentication incidents
+type AuthIncidentHandler struct {
+	authGuardService *service.AuthGuardService
+}
+
+// NewAuthIncidentHandler creates a new auth incident handler
+func NewAuthIncidentHandler(authGuardService *service.AuthGuardService) *AuthIncidentHandler {
+	return &AuthIncidentHandler{authGuardService: authGuardService}
+}
+
+// GetAuthIncidents returns recent failed logins, lockouts and new-device logins
+// GET /api/admin/auth-incidents
+func (h *AuthIncidentHandler) GetAuthIncidents(c *gin.Context) {
+	var query service.AuthIncidentQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	result, err := h.authGuardService.ForTenant(tenantID(c)).GetIncidents(query)
+	if err != nil {
+		response.InternalError(c, "获取登录安全事件失败", err.Error())
+		return
+	}
+
+	response.Success(c, result)
+}
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BadgeHandler handles user badge endpoints
+type BadgeHandler struct {
+	badgeService *service.BadgeService
+}
+
+// NewBadgeHandler creates a new badge handler
+func NewBadgeHandler(badgeService *service.BadgeService) *BadgeHandler {
+	return &BadgeHandler{badgeService: badgeService}
+}
+
+// GetBadges returns the badge counts of the current user
+// GET /api/user/badges
+func (h *BadgeHandler) GetBadges(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "请先登录")
+		return
+	}
+
+	badges, err := h.badgeService.ForTenant(tenantID(c)).GetBadges(userID.(uint))
+	if err != nil {
+		response.InternalError(c, "获取角标计数失败", err.Error())
+		return
+	}
+
+	response.Success(c, badges)
+}
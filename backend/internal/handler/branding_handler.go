@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BrandingHandler handles white-label branding endpoints
+type BrandingHandler struct {
+	brandingService *service.BrandingService
+	cacheSeconds    int
+}
+
+// NewBrandingHandler creates a new branding handler. The public branding
+// response may be cached by clients for cacheSeconds.
+func NewBrandingHandler(brandingService *service.BrandingService, cacheSeconds int) *BrandingHandler {
+	return &BrandingHandler{brandingService: brandingService, cacheSeconds: cacheSeconds}
+}
+
+// GetBranding returns the branding of the current tenant
+// GET /api/system/branding
+func (h *BrandingHandler) GetBranding(c *gin.Context) {
+	tenant := tenantID(c)
+	branding, err := h.brandingService.ForTenant(tenant).GetBranding()
+	if err != nil {
+		response.InternalError(c, "获取品牌配置失败", err.Error())
+		return
+	}
+
+	// The branding only changes through the admin settings, so its update
+	// time identifies the version served to the client
+	etag := fmt.Sprintf(`"b%d-%d"`, tenant, branding.UpdatedAt.UnixNano())
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", h.cacheSeconds))
+	c.Header("Vary", "Host, X-Tenant")
+	c.Header("ETag", etag)
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	response.Success(c, branding)
+}
+
+// GetBrandingAsset serves an uploaded branding asset
+// GET /api/system/branding/assets/:name
+func (h *BrandingHandler) GetBrandingAsset(c *gin.Context) {
+	path, err := h.brandingService.AssetPath(c.Param("name"))
+	if err != nil {
+		response.NotFound(c, "素材不存在")
+		return
+	}
+
+	// Asset names are content hashes, so a name never changes content
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.File(path)
+}
+
+// GetBrandingSettings returns the branding for the admin settings page
+// GET /api/admin/settings/branding
+func (h *BrandingHandler) GetBrandingSettings(c *gin.Context) {
+	branding, err := h.brandingService.ForTenant(tenantID(c)).GetBranding()
+	if err != nil {
+		response.InternalError(c, "获取品牌配置失败", err.Error())
+		return
+	}
+
+	response.Success(c, branding)
+}
+
+// UpdateBrandingSettings updates the branding
+// PUT /api/admin/settings/branding
+func (h *BrandingHandler) UpdateBrandingSettings(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	var req service.UpdateBrandingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	branding, err := h.brandingService.ForTenant(tenantID(c)).UpdateBranding(adminID.(uint), req)
+	if err != nil {
+		h.handleError(c, err, "更新品牌配置失败")
+		return
+	}
+
+	response.Success(c, branding)
+}
+
+// UploadBrandingLogo uploads a new logo image (multipart field "file")
+// POST /api/admin/settings/branding/logo
+func (h *BrandingHandler) UploadBrandingLogo(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		response.BadRequest(c, "请上传Logo文件", err.Error())
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		response.BadRequest(c, "读取上传文件失败", err.Error())
+		return
+	}
+	defer file.Close()
+
+	branding, err := h.brandingService.ForTenant(tenantID(c)).UploadLogo(adminID.(uint), file)
+	if err != nil {
+		h.handleError(c, err, "上传Logo失败")
+		return
+	}
+
+	response.Success(c, branding)
+}
+
+// UploadDesignAsset uploads an image or sound for lottery type designs
+// (multipart field "file")
+// POST /api/admin/lottery/design-assets
+func (h *BrandingHandler) UploadDesignAsset(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		response.BadRequest(c, "请上传素材文件", err.Error())
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		response.BadRequest(c, "读取上传文件失败", err.Error())
+		return
+	}
+	defer file.Close()
+
+	asset, err := h.brandingService.ForTenant(tenantID(c)).UploadDesignAsset(adminID.(uint), file)
+	if err != nil {
+		if err == service.ErrBrandingAssetType {
+			response.BadRequest(c, "仅支持 PNG、JPEG、GIF 图片和 MP3、OGG、WAV 音频")
+			return
+		}
+		h.handleError(c, err, "上传素材失败")
+		return
+	}
+
+	response.Success(c, asset)
+}
+
+func (h *BrandingHandler) handleError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrInvalidSiteName:
+		response.BadRequest(c, "站点名称无效，最多64个字符且不能包含尖括号")
+	case service.ErrInvalidBrandingColor:
+		response.BadRequest(c, "颜色格式无效，应为 #RGB 或 #RRGGBB")
+	case service.ErrInvalidBrandingURL:
+		response.BadRequest(c, "链接无效，仅支持 http(s) 地址或站内路径")
+	case service.ErrInvalidBrandingEmail:
+		response.BadRequest(c, "客服邮箱格式无效")
+	case service.ErrBrandingAssetTooBig:
+		response.BadRequest(c, "文件过大")
+	case service.ErrBrandingAssetType:
+		response.BadRequest(c, "仅支持 PNG、JPEG、GIF 图片")
+	case service.ErrBrandingAssetInvalid:
+		response.BadRequest(c, "图片无效或尺寸超过 2048x2048")
+	default:
+		response.InternalError(c, message, err.Error())
+	}
+}
@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"strconv"
+
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CampaignHandler handles campaign, check-in and coupon endpoints
+type CampaignHandler struct {
+	campaignService *service.CampaignService
+}
+
+// NewCampaignHandler creates a new campaign handler
+func NewCampaignHandler(campaignService *service.CampaignService) *CampaignHandler {
+	return &CampaignHandler{campaignService: campaignService}
+}
+
+// GetCampaigns returns all campaigns
+// GET /api/admin/campaigns
+func (h *CampaignHandler) GetCampaigns(c *gin.Context) {
+	campaigns, err := h.campaignService.ForTenant(tenantID(c)).GetCampaigns()
+	if err != nil {
+		response.InternalError(c, "获取活动失败", err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{"campaigns": campaigns})
+}
+
+// GetCampaign returns a campaign by ID
+// GET /api/admin/campaigns/:id
+func (h *CampaignHandler) GetCampaign(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的活动ID")
+		return
+	}
+
+	campaign, err := h.campaignService.ForTenant(tenantID(c)).GetCampaign(uint(id))
+	if err != nil {
+		h.handleError(c, err, "获取活动失败")
+		return
+	}
+
+	response.Success(c, campaign)
+}
+
+// CreateCampaign creates a campaign
+// POST /api/admin/campaigns
+func (h *CampaignHandler) CreateCampaign(c *gin.Context) {
+	var req service.CampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	campaign, err := h.campaignService.ForTenant(tenantID(c)).CreateCampaign(req)
+	if err != nil {
+		h.handleError(c, err, "创建活动失败")
+		return
+	}
+
+	response.Created(c, campaign)
+}
+
+// UpdateCampaign replaces a campaign
+// PUT /api/admin/campaigns/:id
+func (h *CampaignHandler) UpdateCampaign(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的活动ID")
+		return
+	}
+
+	var req service.CampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	campaign, err := h.campaignService.ForTenant(tenantID(c)).UpdateCampaign(uint(id), req)
+	if err != nil {
+		h.handleError(c, err, "更新活动失败")
+		return
+	}
+
+	response.Success(c, campaign)
+}
+
+// DeleteCampaign deletes a campaign
+// DELETE /api/admin/campaigns/:id
+func (h *CampaignHandler) DeleteCampaign(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的活动ID")
+		return
+	}
+
+	if err := h.campaignService.ForTenant(tenantID(c)).DeleteCampaign(uint(id)); err != nil {
+		h.handleError(c, err, "删除活动失败")
+		return
+	}
+
+	response.Success(c, gin.H{"message": "活动已删除"})
+}
+
+// GetReport returns the performance of a campaign
+// GET /api/admin/campaigns/:id/report
+func (h *CampaignHandler) GetReport(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的活动ID")
+		return
+	}
+
+	report, err := h.campaignService.ForTenant(tenantID(c)).GetReport(uint(id))
+	if err != nil {
+		h.handleError(c, err, "获取活动报表失败")
+		return
+	}
+
+	response.Success(c, report)
+}
+
+// GetCoupons returns the coupons the current user can redeem
+// GET /api/user/coupons
+func (h *CampaignHandler) GetCoupons(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	coupons, err := h.campaignService.ForTenant(tenantID(c)).GetCoupons(userID.(uint))
+	if err != nil {
+		response.InternalError(c, "获取优惠券失败", err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{"coupons": coupons})
+}
+
+func (h *CampaignHandler) handleError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrCampaignNotFound:
+		response.NotFound(c, "活动不存在")
+	case service.ErrInvalidCampaign:
+		response.BadRequest(c, "无效的活动配置")
+	default:
+		response.InternalError(c, message, err.Error())
+	}
+}
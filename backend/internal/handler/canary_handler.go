@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"net/http"
+
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CanaryHandler handles post-deploy canary check endpoints
+type CanaryHandler struct {
+	canaryService *service.CanaryService
+}
+
+// NewCanaryHandler creates a new canary handler
+func NewCanaryHandler(canaryService *service.CanaryService) *CanaryHandler {
+	return &CanaryHandler{canaryService: canaryService}
+}
+
+// RunCanary runs the canary checks and returns the result of every step. A
+// failed run is answered with 503 and the report, so deploy pipelines can
+// gate on the status code.
+// POST /api/canary/run (API key)
+// POST /api/admin/canary/run
+func (h *CanaryHandler) RunCanary(c *gin.Context) {
+	// Runs authenticated by API key have no admin
+	var adminID uint
+	if userID, exists := c.Get("userID"); exists {
+		adminID = userID.(uint)
+	}
+
+	report, err := h.canaryService.Run(adminID)
+	if err != nil {
+		switch err {
+		case service.ErrCanaryRunning:
+			response.TooManyRequests(c, "自检正在运行")
+		default:
+			response.InternalError(c, "运行自检失败", err.Error())
+		}
+		return
+	}
+
+	if !report.Passed {
+		c.JSON(http.StatusServiceUnavailable, response.Response{
+			Code:    response.ErrServiceUnavailable,
+			Message: "自检未通过",
+			Data:    report,
+		})
+		return
+	}
+	response.Success(c, report)
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CheckinHandler handles daily check-in endpoints
+type CheckinHandler struct {
+	checkinService *service.CheckinService
+}
+
+// NewCheckinHandler creates a new check-in handler
+func NewCheckinHandler(checkinService *service.CheckinService) *CheckinHandler {
+	return &CheckinHandler{checkinService: checkinService}
+}
+
+// CheckIn records the daily check-in of the current user
+// POST /api/user/checkin
+func (h *CheckinHandler) CheckIn(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	result, err := h.checkinService.ForTenant(tenantID(c)).CheckIn(userID.(uint))
+	if err != nil {
+		switch err {
+		case service.ErrAlreadyCheckedIn:
+			response.BadRequest(c, "今天已经签到")
+		case service.ErrWalletNotFound:
+			response.NotFound(c, "钱包不存在")
+		default:
+			response.InternalError(c, "签到失败", err.Error())
+		}
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// GetStatus returns the check-in state of the current user
+// GET /api/user/checkin/status
+func (h *CheckinHandler) GetStatus(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	status, err := h.checkinService.ForTenant(tenantID(c)).GetStatus(userID.(uint))
+	if err != nil {
+		response.InternalError(c, "获取签到状态失败", err.Error())
+		return
+	}
+
+	response.Success(c, status)
+}
+
+// GetRules returns the check-in reward schedule
+// GET /api/admin/settings/checkin
+func (h *CheckinHandler) GetRules(c *gin.Context) {
+	rules, err := h.checkinService.ForTenant(tenantID(c)).GetRules()
+	if err != nil {
+		response.InternalError(c, "获取签到奖励规则失败", err.Error())
+		return
+	}
+
+	response.Success(c, rules)
+}
+
+// UpdateRules replaces the check-in reward schedule
+// PUT /api/admin/settings/checkin
+func (h *CheckinHandler) UpdateRules(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	var req service.CheckinRules
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	rules, err := h.checkinService.ForTenant(tenantID(c)).UpdateRules(adminID.(uint), req)
+	if err != nil {
+		switch err {
+		case service.ErrInvalidCheckinRule:
+			response.BadRequest(c, "无效的签到奖励规则")
+		default:
+			response.InternalError(c, "更新签到奖励规则失败", err.Error())
+		}
+		return
+	}
+
+	response.Success(c, rules)
+}
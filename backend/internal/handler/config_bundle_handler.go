@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"errors"
+	"io"
+
+	"scratch-lottery/internal/middleware"
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConfigBundleHandler handles configuration-as-code endpoints
+type ConfigBundleHandler struct {
+	bundleService *service.ConfigBundleService
+}
+
+// NewConfigBundleHandler creates a new configuration bundle handler
+func NewConfigBundleHandler(bundleService *service.ConfigBundleService) *ConfigBundleHandler {
+	return &ConfigBundleHandler{bundleService: bundleService}
+}
+
+// Export downloads the tenant's configuration bundle
+// GET /api/admin/config-bundle?format=json|yaml
+func (h *ConfigBundleHandler) Export(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "yaml" {
+		response.BadRequest(c, "导出格式须为 json 或 yaml")
+		return
+	}
+
+	bundle, err := h.bundleService.ForTenant(tenantID(c)).Export()
+	if err != nil {
+		response.InternalError(c, "导出配置失败", err.Error())
+		return
+	}
+	data, err := service.MarshalConfigBundle(bundle, format)
+	if err != nil {
+		response.InternalError(c, "导出配置失败", err.Error())
+		return
+	}
+
+	contentType := "application/json; charset=utf-8"
+	if format == "yaml" {
+		contentType = "application/yaml; charset=utf-8"
+	}
+	c.Header("Content-Disposition", "attachment; filename=config-bundle."+format)
+	c.Data(200, contentType, data)
+}
+
+// Preview lists what applying the bundle in the body (JSON or YAML) would change
+// POST /api/admin/config-bundle/preview
+func (h *ConfigBundleHandler) Preview(c *gin.Context) {
+	bundle, ok := h.readBundle(c)
+	if !ok {
+		return
+	}
+
+	diff, err := h.bundleService.ForTenant(tenantID(c)).Diff(bundle)
+	if err != nil {
+		h.bundleError(c, err, "预览配置变更失败")
+		return
+	}
+
+	response.Success(c, diff)
+}
+
+// Apply applies the bundle in the body (JSON or YAML) and returns what changed
+// POST /api/admin/config-bundle/apply
+func (h *ConfigBundleHandler) Apply(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	bundle, ok := h.readBundle(c)
+	if !ok {
+		return
+	}
+
+	diff, err := h.bundleService.ForTenant(tenantID(c)).Apply(adminID.(uint), bundle)
+	if err != nil {
+		h.bundleError(c, err, "应用配置失败")
+		return
+	}
+
+	response.Success(c, diff)
+}
+
+func (h *ConfigBundleHandler) readBundle(c *gin.Context) (*service.ConfigBundle, bool) {
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		if middleware.IsBodyTooLarge(err) {
+			response.RequestEntityTooLarge(c, "请求内容过大")
+		} else {
+			response.BadRequest(c, "读取请求体失败", err.Error())
+		}
+		return nil, false
+	}
+	bundle, err := service.ParseConfigBundle(data)
+	if err != nil {
+		response.BadRequest(c, "配置包格式无效", err.Error())
+		return nil, false
+	}
+	return bundle, true
+}
+
+func (h *ConfigBundleHandler) bundleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidConfigBundle):
+		response.BadRequest(c, "配置包无效", err.Error())
+	case errors.Is(err, service.ErrConfigBundleEntry):
+		response.BadRequest(c, "配置包中的条目无法应用，未做任何更改", err.Error())
+	default:
+		response.InternalError(c, message, err.Error())
+	}
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DashboardHandler handles the configurable admin dashboard
+type DashboardHandler struct {
+	dashboardService *service.DashboardService
+}
+
+// NewDashboardHandler creates a new dashboard handler
+func NewDashboardHandler(dashboardService *service.DashboardService) *DashboardHandler {
+	return &DashboardHandler{dashboardService: dashboardService}
+}
+
+// GetWidgetCatalog returns the widgets available on the dashboard
+// GET /api/admin/dashboard/widgets
+func (h *DashboardHandler) GetWidgetCatalog(c *gin.Context) {
+	response.Success(c, gin.H{
+		"widgets": h.dashboardService.ForTenant(tenantID(c)).GetCatalog(),
+	})
+}
+
+// GetLayout returns the current admin's dashboard layout
+// GET /api/admin/dashboard/layout
+func (h *DashboardHandler) GetLayout(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	layout, err := h.dashboardService.ForTenant(tenantID(c)).GetLayout(adminID.(uint))
+	if err != nil {
+		response.InternalError(c, "获取仪表盘布局失败", err.Error())
+		return
+	}
+
+	response.Success(c, layout)
+}
+
+// SaveLayout saves the current admin's dashboard layout
+// PUT /api/admin/dashboard/layout
+func (h *DashboardHandler) SaveLayout(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	var req service.SaveDashboardLayoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	layout, err := h.dashboardService.ForTenant(tenantID(c)).SaveLayout(adminID.(uint), req)
+	if err != nil {
+		switch err {
+		case service.ErrUnknownWidget:
+			response.BadRequest(c, "组件不存在")
+		case service.ErrDuplicateWidget:
+			response.BadRequest(c, "同一组件只能添加一次")
+		case service.ErrInvalidWidgetSize:
+			response.BadRequest(c, "组件尺寸无效")
+		case service.ErrInvalidWidgetSetting:
+			response.BadRequest(c, "组件设置无效")
+		case service.ErrTooManyWidgets:
+			response.BadRequest(c, "组件数量超过上限")
+		default:
+			response.InternalError(c, "保存仪表盘布局失败", err.Error())
+		}
+		return
+	}
+
+	response.Success(c, layout)
+}
+
+// ResetLayout restores the default dashboard layout for the current admin
+// DELETE /api/admin/dashboard/layout
+func (h *DashboardHandler) ResetLayout(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	layout, err := h.dashboardService.ForTenant(tenantID(c)).ResetLayout(adminID.(uint))
+	if err != nil {
+		response.InternalError(c, "重置仪表盘布局失败", err.Error())
+		return
+	}
+
+	response.Success(c, layout)
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DemoHandler handles the demo content created on first boot
+type DemoHandler struct {
+	demoService *service.DemoService
+}
+
+// NewDemoHandler creates a new demo handler
+func NewDemoHandler(demoService *service.DemoService) *DemoHandler {
+	return &DemoHandler{demoService: demoService}
+}
+
+// GetDemoContent returns how much demo content is present
+// GET /api/admin/demo
+func (h *DemoHandler) GetDemoContent(c *gin.Context) {
+	content, err := h.demoService.ForTenant(tenantID(c)).GetDemoContent()
+	if err != nil {
+		response.InternalError(c, "获取演示数据失败", err.Error())
+		return
+	}
+
+	response.Success(c, content)
+}
+
+// RemoveDemoContent deletes the demo lottery, products and admin
+// DELETE /api/admin/demo
+func (h *DemoHandler) RemoveDemoContent(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	removed, err := h.demoService.ForTenant(tenantID(c)).RemoveDemoContent(adminID.(uint))
+	if err != nil {
+		switch err {
+		case service.ErrDemoHasUnscratched:
+			response.BadRequest(c, "演示彩票仍有未刮开的彩票，暂不能移除")
+		default:
+			response.InternalError(c, "移除演示数据失败", err.Error())
+		}
+		return
+	}
+
+	response.Success(c, removed)
+}
@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DocsHandler serves the OpenAPI document of the API
+type DocsHandler struct {
+	router  *gin.Engine
+	version string
+
+	once sync.Once
+	spec []byte
+}
+
+// NewDocsHandler creates a new docs handler. The document lists the routes
+// registered on router when it is first requested.
+func NewDocsHandler(router *gin.Engine, version string) *DocsHandler {
+	return &DocsHandler{router: router, version: version}
+}
+
+// GetSpec returns the OpenAPI document
+// GET /api/docs
+func (h *DocsHandler) GetSpec(c *gin.Context) {
+	h.once.Do(func() {
+		h.spec, _ = json.Marshal(h.Build())
+	})
+	c.Data(http.StatusOK, "application/json; charset=utf-8", h.spec)
+}
+
+// Build builds the OpenAPI document of the routes registered on the router
+func (h *DocsHandler) Build() map[string]any {
+	routes := h.router.Routes()
+	operations := make(map[string]openapi.Operation, len(apiOperations))
+	for key, op := range apiOperations {
+		operations[key] = op
+	}
+	// Routes not described below are still listed; admin routes always
+	// need a token
+	for _, route := range routes {
+		key := route.Method + " " + route.Path
+		if _, described := operations[key]; !described && strings.HasPrefix(route.Path, "/api/admin/") {
+			operations[key] = openapi.Operation{Security: openapi.SecurityBearer}
+		}
+	}
+	return openapi.Build(openapi.Info{
+		Title:       "Scratch Lottery API",
+		Description: "Responses are wrapped in {code, message, data}. Send X-Tenant to select a tenant.",
+		Version:     h.version,
+	}, routes, operations)
+}
+
+// pageQuery is the pagination of list endpoints without filters
+type pageQuery struct {
+	Page  int `form:"page"`
+	Limit int `form:"limit"`
+}
+
+// receiptQuery selects the format of a receipt: json (default) or pdf
+type receiptQuery struct {
+	Format string `form:"format"`
+}
+
+// amountRequest is the body of the balance check
+type amountRequest struct {
+	Amount int `json:"amount" binding:"required"`
+}
+
+// walletQuery selects the version of the wallet response
+type walletQuery struct {
+	Version int `form:"version"`
+}
+
+// ticketListResponse is a page of the user's tickets
+type ticketListResponse struct {
+	Tickets    []service.TicketResponse `json:"tickets"`
+	Total      int64                    `json:"total"`
+	Page       int                      `json:"page"`
+	Limit      int                      `json:"limit"`
+	TotalPages int                      `json:"total_pages"`
+}
+
+// apiOperations describes the routes client generators need most: the
+// lottery, wallet, exchange, payment and admin APIs
+var apiOperations = map[string]openapi.Operation{
+	// Auth and check-in
+	"POST /api/auth/refresh":       {Summary: "Exchange a refresh token for a new token pair", Security: openapi.SecurityPublic, Body: RefreshRequest{}, Response: service.AuthResponse{}},
+	"GET /api/auth/me":             {Summary: "Get the signed in user", Security: openapi.SecurityBearer, Response: model.User{}},
+	"POST /api/user/checkin":       {Summary: "Check in for the day", Security: openapi.SecurityBearer, Response: service.CheckInResponse{}},
+	"GET /api/user/checkin/status": {Summary: "Get my check-in streak", Security: openapi.SecurityBearer, Response: service.CheckinStatus{}},
+
+	// Lottery
+	"GET /api/lottery/types":                  {Summary: "List lottery types", Security: openapi.SecurityPublic, Query: service.LotteryTypeListQuery{}, Response: service.LotteryTypeListResponse{}},
+	"GET /api/lottery/types/:id":              {Summary: "Get a lottery type", Security: openapi.SecurityPublic, Response: service.LotteryTypeDetailResponse{}},
+	"GET /api/lottery/types/:id/prize-levels": {Summary: "List the prize levels of a lottery type", Security: openapi.SecurityPublic, Response: []service.PrizeLevelResponse{}},
+	"GET /api/lottery/types/:id/prize-pools":  {Summary: "List the prize pools of a lottery type", Security: openapi.SecurityPublic, Response: []service.PrizePoolResponse{}},
+	"GET /api/lottery/types/:id/active-pool":  {Summary: "Get the active prize pool of a lottery type", Security: openapi.SecurityPublic, Response: service.PrizePoolResponse{}},
+	"GET /api/lottery/verify/:code":           {Summary: "Verify a ticket security code", Security: openapi.SecurityPublic, Response: service.VerifySecurityCodeResponse{}},
+	"POST /api/lottery/verify/batch":          {Summary: "Verify security codes in bulk (partners)", Security: openapi.SecurityAPIKey, Body: BatchVerifyRequest{}, Response: []service.BatchVerifyResult{}},
+	"GET /api/partner/usage":                  {Summary: "Get the API usage of my API key (partners)", Security: openapi.SecurityAPIKey, Query: service.APIKeyUsageQuery{}, Response: service.APIKeyUsageResponse{}},
+	"POST /api/canary/run":                    {Summary: "Run the post-deploy canary checks (deploy pipelines), 503 when a step fails", Security: openapi.SecurityAPIKey, Response: service.CanaryReport{}},
+	"POST /api/lottery/purchase":              {Summary: "Buy tickets", Security: openapi.SecurityBearer, Body: service.PurchaseRequest{}, Response: service.PurchaseResponse{}},
+	"POST /api/lottery/purchase/preview":      {Summary: "Preview the price of a purchase", Security: openapi.SecurityBearer, Body: service.PurchaseRequest{}, Response: map[string]any{}},
+	"POST /api/lottery/claim-ticket":          {Summary: "Claim a voucher ticket by its security code", Security: openapi.SecurityBearer, Body: service.ClaimVoucherRequest{}, Response: service.TicketResponse{}},
+	"POST /api/lottery/vouchers/claim":        {Summary: "Claim a voucher ticket by its security code", Security: openapi.SecurityBearer, Body: service.ClaimVoucherRequest{}, Response: service.TicketResponse{}},
+	"POST /api/lottery/quick-play":            {Summary: "Buy tickets and scratch them at once", Security: openapi.SecurityBearer, Body: service.PurchaseRequest{}, Response: service.QuickPlayResponse{}},
+	"GET /api/lottery/tickets":                {Summary: "List my tickets", Security: openapi.SecurityBearer, Query: pageQuery{}, Response: ticketListResponse{}},
+	"GET /api/lottery/tickets/:id":            {Summary: "Get a ticket", Security: openapi.SecurityBearer, Response: model.Ticket{}},
+	"GET /api/lottery/tickets/:id/detail":     {Summary: "Get a ticket with its scratch areas", Security: openapi.SecurityBearer, Response: service.TicketDetailResponse{}},
+	"POST /api/lottery/scratch/:id":           {Summary: "Scratch a ticket", Security: openapi.SecurityBearer, Body: ScratchConfirmRequest{}, Response: service.ScratchResponse{}},
+	"GET /api/user/tickets":                   {Summary: "List my tickets", Security: openapi.SecurityBearer, Query: service.TicketRecordQuery{}, Response: service.TicketRecordListResponse{}},
+	"GET /api/user/wins":                      {Summary: "List my wins", Security: openapi.SecurityBearer, Query: service.TicketRecordQuery{}, Response: service.WinRecordListResponse{}},
+	"GET /api/user/profile":                   {Summary: "Get my profile", Security: openapi.SecurityBearer, Response: service.UserProfileResponse{}},
+	"GET /api/user/statistics":                {Summary: "Get my statistics", Security: openapi.SecurityBearer, Response: service.UserStatisticsResponse{}},
+	"POST /api/user/support":                  {Summary: "Report an issue to support", Security: openapi.SecurityBearer, Body: service.CreateSupportIssueRequest{}, Response: service.SupportIssueResponse{}},
+	"GET /api/user/support":                   {Summary: "List my support issues", Security: openapi.SecurityBearer, Query: pageQuery{}, Response: service.SupportIssueListResponse{}},
+	"GET /api/user/support/:id":               {Summary: "Get a support issue of mine", Security: openapi.SecurityBearer, Response: service.SupportIssueResponse{}},
+
+	// Wallet
+	"GET /api/wallet":              {Summary: "Get my wallet; version 2 adds the breakdown of the balance", Security: openapi.SecurityBearer, Query: walletQuery{}, Response: service.WalletResponse{}},
+	"GET /api/wallet/transactions": {Summary: "List my wallet transactions", Security: openapi.SecurityBearer, Query: service.TransactionQuery{}, Response: service.TransactionListResponse{}},
+	"GET /api/wallet/balance": {Summary: "Get my balance", Security: openapi.SecurityBearer, Response: struct {
+		Balance int `json:"balance"`
+	}{}},
+	"POST /api/wallet/check-balance": {Summary: "Check whether my balance covers an amount", Security: openapi.SecurityBearer, Body: amountRequest{}, Response: struct {
+		Sufficient bool `json:"sufficient"`
+	}{}},
+	"POST /api/wallet/transactions/:id/dispute": {Summary: "Dispute a debit of my wallet", Security: openapi.SecurityBearer, Body: service.OpenDisputeRequest{}, Response: model.TransactionDispute{}},
+	"GET /api/wallet/disputes":                  {Summary: "List my transaction disputes", Security: openapi.SecurityBearer, Response: []model.TransactionDispute{}},
+
+	// Exchange
+	"GET /api/exchange/products":            {Summary: "List exchange products", Security: openapi.SecurityPublic, Query: service.ProductQuery{}, Response: service.ProductListResponse{}},
+	"GET /api/exchange/products/:id":        {Summary: "Get an exchange product", Security: openapi.SecurityPublic, Response: service.ProductResponse{}},
+	"POST /api/exchange/redeem":             {Summary: "Redeem a product with points", Security: openapi.SecurityBearer, Body: service.RedeemRequest{}, Response: service.RedeemResponse{}},
+	"GET /api/exchange/records":             {Summary: "List my exchange records, card keys masked", Security: openapi.SecurityBearer, Query: service.ExchangeRecordQuery{}, Response: service.ExchangeRecordListResponse{}},
+	"GET /api/exchange/records/:id":         {Summary: "Get an exchange record", Security: openapi.SecurityBearer, Response: service.ExchangeRecordResponse{}},
+	"POST /api/exchange/records/:id/reveal": {Summary: "Reveal the card key of an exchange record", Security: openapi.SecurityBearer, Response: service.CardKeyRevealResponse{}},
+	"GET /api/exchange/records/:id/receipt": {Summary: "Get the receipt of a redemption as JSON or a PDF", Security: openapi.SecurityBearer, Query: receiptQuery{}, Response: service.ExchangeReceipt{}},
+
+	// Payment
+	"GET /api/payment/recharge-options":   {Summary: "Get the recharge options", Security: openapi.SecurityPublic, Response: service.RechargeRules{}},
+	"POST /api/payment/recharge":          {Summary: "Create a recharge order", Security: openapi.SecurityBearer, Body: service.RechargeRequest{}, Response: service.RechargeResponse{}},
+	"GET /api/payment/orders":             {Summary: "List my recharge orders", Security: openapi.SecurityBearer, Query: pageQuery{}},
+	"GET /api/payment/orders/:order_no":   {Summary: "Get a recharge order", Security: openapi.SecurityBearer, Response: service.OrderResponse{}},
+	"POST /api/payment/callback":          {Summary: "Payment notification from the EPay gateway", Security: openapi.SecurityPublic, Body: service.PaymentCallbackRequest{}},
+	"GET /api/payment/callback":           {Summary: "Payment notification from the EPay gateway", Security: openapi.SecurityPublic, Query: service.PaymentCallbackRequest{}},
+	"POST /api/payment/callback/:gateway": {Summary: "Payment notification from the named payment gateway", Security: openapi.SecurityPublic},
+	"GET /api/payment/callback/:gateway":  {Summary: "Payment notification from the named payment gateway", Security: openapi.SecurityPublic},
+
+	// Admin
+	"GET /api/admin/dashboard":                            {Summary: "Get the dashboard statistics", Security: openapi.SecurityBearer, Response: service.DashboardStats{}},
+	"GET /api/admin/statistics":                           {Summary: "Get statistics and trends", Security: openapi.SecurityBearer, Query: service.StatisticsQuery{}, Response: service.StatisticsResponse{}},
+	"GET /api/admin/users":                                {Summary: "List users", Security: openapi.SecurityBearer, Query: service.UserListQuery{}, Response: service.UserListResponse{}},
+	"GET /api/admin/users/:id":                            {Summary: "Get a user", Security: openapi.SecurityBearer, Response: service.UserResponse{}},
+	"PUT /api/admin/users/:id/points":                     {Summary: "Adjust the points of a user", Security: openapi.SecurityBearer, Body: service.AdjustUserPointsRequest{}, Response: service.UserResponse{}},
+	"GET /api/admin/disputes":                             {Summary: "List the transaction dispute queue", Security: openapi.SecurityBearer, Query: service.AdminDisputeQuery{}, Response: service.AdminDisputeListResponse{}},
+	"POST /api/admin/disputes/:id/uphold":                 {Summary: "Close a dispute with the transaction standing", Security: openapi.SecurityBearer, Body: service.ResolveDisputeRequest{}, Response: model.TransactionDispute{}},
+	"POST /api/admin/disputes/:id/refund":                 {Summary: "Close a dispute by refunding the transaction", Security: openapi.SecurityBearer, Body: service.ResolveDisputeRequest{}, Response: model.TransactionDispute{}},
+	"POST /api/admin/payment-orders/:order_no/sync":       {Summary: "Ask the gateway of a pending or expired order whether it has been paid", Security: openapi.SecurityBearer, Response: service.OrderResponse{}},
+	"POST /api/admin/payment-orders/:order_no/confirm":    {Summary: "Mark a pending order of the manual gateway paid", Security: openapi.SecurityBearer, Body: service.ConfirmManualOrderRequest{}, Response: service.OrderResponse{}},
+	"GET /api/admin/payment-reconciliations":              {Summary: "List payment orders that disagreed with their gateway, with open counts by kind", Security: openapi.SecurityBearer, Query: service.PaymentReconciliationQuery{}, Response: service.PaymentReconciliationListResponse{}},
+	"POST /api/admin/payment-reconciliations/run":         {Summary: "Look up stale pending orders at their gateway now", Security: openapi.SecurityBearer, Response: service.PaymentReconcileResult{}},
+	"POST /api/admin/payment-reconciliations/:id/resolve": {Summary: "Close a payment reconciliation after review", Security: openapi.SecurityBearer, Body: service.ResolvePaymentReconciliationRequest{}, Response: service.PaymentReconciliationResponse{}},
+	"GET /api/admin/settings":                             {Summary: "Get the system settings", Security: openapi.SecurityBearer, Response: service.SystemSettings{}},
+	"PUT /api/admin/settings":                             {Summary: "Update the system settings", Security: openapi.SecurityBearer, Body: service.UpdateSystemSettingsRequest{}, Response: service.SystemSettings{}},
+	"GET /api/admin/logs":                                 {Summary: "List the admin operation log", Security: openapi.SecurityBearer, Query: service.AdminLogQuery{}, Response: service.AdminLogListResponse{}},
+	"POST /api/admin/lottery/types":                       {Summary: "Create a lottery type", Security: openapi.SecurityBearer, Body: service.CreateLotteryTypeRequest{}, Response: service.LotteryTypeDetailResponse{}},
+	"PUT /api/admin/lottery/types/:id":                    {Summary: "Update a lottery type", Security: openapi.SecurityBearer, Body: service.UpdateLotteryTypeRequest{}, Response: service.LotteryTypeDetailResponse{}},
+	"POST /api/admin/lottery/types/:id/prize-pools":       {Summary: "Create a prize pool", Security: openapi.SecurityBearer, Body: service.CreatePrizePoolRequest{}, Response: service.PrizePoolResponse{}},
+	"GET /api/admin/exchange/products":                    {Summary: "List all exchange products", Security: openapi.SecurityBearer, Query: service.ProductQuery{}, Response: service.ProductListResponse{}},
+	"POST /api/admin/exchange/products":                   {Summary: "Create an exchange product", Security: openapi.SecurityBearer, Body: service.CreateProductRequest{}, Response: service.ProductResponse{}},
+	"PUT /api/admin/exchange/products/:id":                {Summary: "Update an exchange product", Security: openapi.SecurityBearer, Body: service.UpdateProductRequest{}, Response: service.ProductResponse{}},
+	"POST /api/admin/exchange/products/:id/import-keys":   {Summary: "Import card keys", Security: openapi.SecurityBearer, Body: service.ImportCardKeysRequest{}},
+	"GET /api/admin/exchange/products/:id/card-keys":      {Summary: "List the card keys of a product with counts by status", Security: openapi.SecurityBearer, Query: service.CardKeyQuery{}, Response: service.CardKeyListResponse{}},
+	"GET /api/admin/exchange/card-key-reveals":            {Summary: "List card key reveals", Security: openapi.SecurityBearer, Query: service.CardKeyRevealQuery{}, Response: service.CardKeyRevealListResponse{}},
+	"GET /api/admin/exchange/receipts/:code":              {Summary: "Verify a receipt by its verification code", Security: openapi.SecurityBearer, Response: service.ExchangeReceipt{}},
+	"GET /api/admin/support/issues":                       {Summary: "List support issues, unresolved and earliest deadline first", Security: openapi.SecurityBearer, Query: service.SupportIssueQuery{}, Response: service.SupportIssueListResponse{}},
+	"PUT /api/admin/support/issues/:id":                   {Summary: "Triage a support issue", Security: openapi.SecurityBearer, Body: service.UpdateSupportIssueRequest{}, Response: service.SupportIssueResponse{}},
+	"POST /api/admin/canary/run":                          {Summary: "Run the post-deploy canary checks, 503 when a step fails", Security: openapi.SecurityBearer, Response: service.CanaryReport{}},
+}
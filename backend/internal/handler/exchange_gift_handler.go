@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExchangeGiftHandler handles exchange gift endpoints
+type ExchangeGiftHandler struct {
+	giftService *service.ExchangeGiftService
+}
+
+// NewExchangeGiftHandler creates a new exchange gift handler
+func NewExchangeGiftHandler(giftService *service.ExchangeGiftService) *ExchangeGiftHandler {
+	return &ExchangeGiftHandler{giftService: giftService}
+}
+
+// SendGift redeems a product as a gift for another user
+// POST /api/exchange/gifts
+func (h *ExchangeGiftHandler) SendGift(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	var req service.SendGiftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	result, err := h.giftService.ForTenant(tenantID(c)).SendGift(userID.(uint), req)
+	if err != nil {
+		switch err {
+		case service.ErrGiftToSelf:
+			response.BadRequest(c, "不能赠送给自己")
+		case service.ErrGiftRecipientNotFound:
+			response.NotFound(c, "受赠用户不存在")
+		case service.ErrProductNotFound:
+			response.NotFound(c, "商品不存在")
+		case service.ErrProductSoldOut, service.ErrNoAvailableCardKey:
+			response.Error(c, http.StatusOK, response.ErrProductSoldOut, "商品已兑完")
+		case service.ErrProductOffline:
+			response.Error(c, http.StatusOK, response.ErrProductNotFound, "商品已下架")
+		case service.ErrProductNotDropped:
+			response.Error(c, http.StatusOK, response.ErrProductNotDropped, "商品尚未开售")
+		case service.ErrInsufficientPoints:
+			response.Error(c, http.StatusOK, response.ErrInsufficientPoints, "积分不足")
+		case service.ErrWalletFrozen:
+			response.BadRequest(c, "钱包已冻结，等待管理员核查")
+		default:
+			response.InternalError(c, "赠送失败", err.Error())
+		}
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// GetGifts returns gifts received (default) or sent by the current user
+// GET /api/exchange/gifts
+func (h *ExchangeGiftHandler) GetGifts(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	var query service.GiftQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	result, err := h.giftService.ForTenant(tenantID(c)).GetGifts(userID.(uint), query)
+	if err != nil {
+		response.InternalError(c, "获取礼物列表失败", err.Error())
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// AcceptGift accepts a pending gift and reveals its card key
+// POST /api/exchange/gifts/:id/accept
+func (h *ExchangeGiftHandler) AcceptGift(c *gin.Context) {
+	h.respondToGift(c, h.giftService.ForTenant(tenantID(c)).AcceptGift)
+}
+
+// DeclineGift declines a pending gift, returning it to the giver
+// POST /api/exchange/gifts/:id/decline
+func (h *ExchangeGiftHandler) DeclineGift(c *gin.Context) {
+	h.respondToGift(c, h.giftService.ForTenant(tenantID(c)).DeclineGift)
+}
+
+func (h *ExchangeGiftHandler) respondToGift(c *gin.Context, action func(userID, giftID uint) (*service.GiftResponse, error)) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的礼物ID")
+		return
+	}
+
+	result, err := action(userID.(uint), uint(id))
+	if err != nil {
+		switch err {
+		case service.ErrGiftNotFound:
+			response.NotFound(c, "礼物不存在")
+		case service.ErrGiftNotPending:
+			response.BadRequest(c, "礼物已处理")
+		case service.ErrGiftExpired:
+			response.BadRequest(c, "礼物已过期，已退回赠送人")
+		case service.ErrExchangeDisputed:
+			response.Forbidden(c, "赠送人对该兑换提出了争议，暂时无法领取")
+		default:
+			response.InternalError(c, "处理礼物失败", err.Error())
+		}
+		return
+	}
+
+	response.Success(c, result)
+}
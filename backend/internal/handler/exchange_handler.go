@@ -1,9 +1,11 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
+	"scratch-lottery/internal/middleware"
 	"scratch-lottery/internal/service"
 	"scratch-lottery/pkg/response"
 
@@ -29,7 +31,7 @@ func (h *ExchangeHandler) GetProducts(c *gin.Context) {
 		return
 	}
 
-	result, err := h.exchangeService.GetProducts(query)
+	result, err := h.exchangeService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).GetProducts(query)
 	if err != nil {
 		response.InternalError(c, "获取商品列表失败", err.Error())
 		return
@@ -48,7 +50,7 @@ func (h *ExchangeHandler) GetProductByID(c *gin.Context) {
 		return
 	}
 
-	product, err := h.exchangeService.GetProductByID(uint(id))
+	product, err := h.exchangeService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).GetProductByID(uint(id))
 	if err != nil {
 		switch err {
 		case service.ErrProductNotFound:
@@ -78,7 +80,7 @@ func (h *ExchangeHandler) Redeem(c *gin.Context) {
 		return
 	}
 
-	result, err := h.exchangeService.Redeem(userID.(uint), req.ProductID)
+	result, err := h.exchangeService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).Redeem(userID.(uint), req.ProductID)
 	if err != nil {
 		switch err {
 		case service.ErrProductNotFound:
@@ -87,8 +89,12 @@ func (h *ExchangeHandler) Redeem(c *gin.Context) {
 			response.Error(c, http.StatusOK, response.ErrProductSoldOut, "商品已兑完")
 		case service.ErrProductOffline:
 			response.Error(c, http.StatusOK, response.ErrProductNotFound, "商品已下架")
+		case service.ErrProductNotDropped:
+			response.Error(c, http.StatusOK, response.ErrProductNotDropped, "商品尚未开售")
 		case service.ErrInsufficientPoints:
 			response.Error(c, http.StatusOK, response.ErrInsufficientPoints, "积分不足")
+		case service.ErrWalletFrozen:
+			response.BadRequest(c, "钱包已冻结，等待管理员核查")
 		case service.ErrNoAvailableCardKey:
 			response.Error(c, http.StatusOK, response.ErrProductSoldOut, "商品已兑完")
 		default:
@@ -115,7 +121,7 @@ func (h *ExchangeHandler) GetExchangeRecords(c *gin.Context) {
 		return
 	}
 
-	result, err := h.exchangeService.GetExchangeRecords(userID.(uint), query)
+	result, err := h.exchangeService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).GetExchangeRecords(userID.(uint), query)
 	if err != nil {
 		response.InternalError(c, "获取兑换记录失败", err.Error())
 		return
@@ -140,7 +146,7 @@ func (h *ExchangeHandler) GetExchangeRecordByID(c *gin.Context) {
 		return
 	}
 
-	record, err := h.exchangeService.GetExchangeRecordByID(userID.(uint), uint(id))
+	record, err := h.exchangeService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).GetExchangeRecordByID(userID.(uint), uint(id))
 	if err != nil {
 		response.NotFound(c, "兑换记录不存在")
 		return
@@ -149,6 +155,81 @@ func (h *ExchangeHandler) GetExchangeRecordByID(c *gin.Context) {
 	response.Success(c, record)
 }
 
+// RevealCardKey reveals the full card key of an exchange record to its redeemer; every reveal is audited
+// POST /api/exchange/records/:id/reveal
+func (h *ExchangeHandler) RevealCardKey(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的记录ID")
+		return
+	}
+
+	result, err := h.exchangeService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).RevealCardKey(userID.(uint), uint(id), c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		switch err {
+		case service.ErrExchangeRecordNotFound:
+			response.NotFound(c, "兑换记录不存在")
+		case service.ErrCardKeyUnavailable:
+			response.Forbidden(c, "卡密不可查看")
+		case service.ErrExchangeDisputed:
+			response.Forbidden(c, "该兑换存在交易争议，卡密已冻结")
+		default:
+			response.InternalError(c, "查看卡密失败", err.Error())
+		}
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// GetExchangeReceipt returns the receipt of a redemption as JSON or a PDF
+// GET /api/exchange/records/:id/receipt?format=json|pdf
+func (h *ExchangeHandler) GetExchangeReceipt(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的记录ID")
+		return
+	}
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "pdf" {
+		response.BadRequest(c, "收据格式须为 json 或 pdf")
+		return
+	}
+
+	receipt, err := h.exchangeService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).GetExchangeReceipt(userID.(uint), uint(id))
+	if err != nil {
+		switch err {
+		case service.ErrExchangeRecordNotFound:
+			response.NotFound(c, "兑换记录不存在")
+		case service.ErrReceiptUnavailable:
+			response.BadRequest(c, "仅已完成的积分兑换可开具收据")
+		default:
+			response.InternalError(c, "生成收据失败", err.Error())
+		}
+		return
+	}
+
+	if format == "pdf" {
+		c.Header("Content-Disposition", "attachment; filename=receipt-"+receipt.Code+".pdf")
+		c.Data(http.StatusOK, "application/pdf", service.RenderExchangeReceiptPDF(receipt))
+		return
+	}
+	response.Success(c, receipt)
+}
+
 // ==================== Admin Endpoints ====================
 
 // GetAllProducts returns all products (including offline) for admin
@@ -160,7 +241,7 @@ func (h *ExchangeHandler) GetAllProducts(c *gin.Context) {
 		return
 	}
 
-	result, err := h.exchangeService.GetAllProducts(query)
+	result, err := h.exchangeService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).GetAllProducts(query)
 	if err != nil {
 		response.InternalError(c, "获取商品列表失败", err.Error())
 		return
@@ -178,9 +259,14 @@ func (h *ExchangeHandler) CreateProduct(c *gin.Context) {
 		return
 	}
 
-	product, err := h.exchangeService.CreateProduct(req)
+	product, err := h.exchangeService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).CreateProduct(req)
 	if err != nil {
-		response.InternalError(c, "创建商品失败", err.Error())
+		switch err {
+		case service.ErrInvalidProductCost:
+			response.BadRequest(c, "无效的商品成本")
+		default:
+			response.InternalError(c, "创建商品失败", err.Error())
+		}
 		return
 	}
 
@@ -203,11 +289,13 @@ func (h *ExchangeHandler) UpdateProduct(c *gin.Context) {
 		return
 	}
 
-	product, err := h.exchangeService.UpdateProduct(uint(id), req)
+	product, err := h.exchangeService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).UpdateProduct(uint(id), req)
 	if err != nil {
 		switch err {
 		case service.ErrProductNotFound:
 			response.NotFound(c, "商品不存在")
+		case service.ErrInvalidProductCost:
+			response.BadRequest(c, "无效的商品成本")
 		default:
 			response.InternalError(c, "更新商品失败", err.Error())
 		}
@@ -227,7 +315,7 @@ func (h *ExchangeHandler) DeleteProduct(c *gin.Context) {
 		return
 	}
 
-	if err := h.exchangeService.DeleteProduct(uint(id)); err != nil {
+	if err := h.exchangeService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).DeleteProduct(uint(id)); err != nil {
 		switch err {
 		case service.ErrProductNotFound:
 			response.NotFound(c, "商品不存在")
@@ -240,7 +328,9 @@ func (h *ExchangeHandler) DeleteProduct(c *gin.Context) {
 	response.Success(c, gin.H{"message": "商品已删除"})
 }
 
-// ImportCardKeys imports card keys for a product
+// ImportCardKeys imports card keys for a product, either as a JSON list, as
+// a text/plain body with one key per line that is streamed into the
+// database, or as an uploaded CSV or XLSX file (multipart field "file")
 // POST /api/admin/exchange/products/:id/import-keys
 func (h *ExchangeHandler) ImportCardKeys(c *gin.Context) {
 	idStr := c.Param("id")
@@ -250,17 +340,34 @@ func (h *ExchangeHandler) ImportCardKeys(c *gin.Context) {
 		return
 	}
 
-	var req service.ImportCardKeysRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		response.BadRequest(c, "请求参数无效", err.Error())
+	exchangeService := h.exchangeService.ForTenant(tenantID(c)).WithRequest(c.Request.Context())
+	if c.ContentType() == "multipart/form-data" {
+		h.importCardKeysFile(c, exchangeService, uint(id))
 		return
 	}
-
-	imported, err := h.exchangeService.ImportCardKeys(uint(id), req.CardKeys)
+	var imported int
+	if c.ContentType() == "text/plain" {
+		imported, err = exchangeService.ImportCardKeysFrom(uint(id), c.Request.Body)
+	} else {
+		var req service.ImportCardKeysRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			if middleware.IsBodyTooLarge(err) {
+				response.RequestEntityTooLarge(c, "导入内容过大，请分批导入")
+				return
+			}
+			response.BadRequest(c, "请求参数无效", err.Error())
+			return
+		}
+		imported, err = exchangeService.ImportCardKeys(uint(id), req.CardKeys)
+	}
 	if err != nil {
-		switch err {
-		case service.ErrProductNotFound:
+		switch {
+		case errors.Is(err, service.ErrProductNotFound):
 			response.NotFound(c, "商品不存在")
+		case errors.Is(err, service.ErrCardKeyTooLong):
+			response.BadRequest(c, "卡密过长")
+		case middleware.IsBodyTooLarge(err):
+			response.RequestEntityTooLarge(c, "导入内容过大，请分批导入")
 		default:
 			response.InternalError(c, "导入卡密失败", err.Error())
 		}
@@ -273,7 +380,44 @@ func (h *ExchangeHandler) ImportCardKeys(c *gin.Context) {
 	})
 }
 
-// GetCardKeys returns card keys for a product (admin only)
+// importCardKeysFile imports the card keys of an uploaded file and reports
+// the skipped duplicates and refused rows
+func (h *ExchangeHandler) importCardKeysFile(c *gin.Context, exchangeService *service.ExchangeService, productID uint) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		if middleware.IsBodyTooLarge(err) {
+			response.RequestEntityTooLarge(c, "导入内容过大，请分批导入")
+			return
+		}
+		response.BadRequest(c, "请上传卡密文件", err.Error())
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		response.BadRequest(c, "读取上传文件失败", err.Error())
+		return
+	}
+	defer file.Close()
+
+	report, err := exchangeService.ImportCardKeysFile(productID, fileHeader.Filename, file, fileHeader.Size)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrProductNotFound):
+			response.NotFound(c, "商品不存在")
+		case errors.Is(err, service.ErrUnsupportedImportFile):
+			response.BadRequest(c, "仅支持 CSV 或 XLSX 文件")
+		case errors.Is(err, service.ErrInvalidImportFile):
+			response.BadRequest(c, "无法解析卡密文件")
+		default:
+			response.InternalError(c, "导入卡密失败", err.Error())
+		}
+		return
+	}
+
+	response.Success(c, report)
+}
+
+// GetCardKeys returns a page of the card keys of a product (admin only)
 // GET /api/admin/exchange/products/:id/card-keys
 func (h *ExchangeHandler) GetCardKeys(c *gin.Context) {
 	idStr := c.Param("id")
@@ -283,21 +427,107 @@ func (h *ExchangeHandler) GetCardKeys(c *gin.Context) {
 		return
 	}
 
-	status := c.Query("status")
+	var query service.CardKeyQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
 
-	cardKeys, err := h.exchangeService.GetCardKeysByProductID(uint(id), status)
+	cardKeys, err := h.exchangeService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).WithRedaction(fieldRedaction(c)).GetCardKeysByProductID(uint(id), query)
 	if err != nil {
 		switch err {
 		case service.ErrProductNotFound:
 			response.NotFound(c, "商品不存在")
+		case service.ErrInvalidCardKeyQuery:
+			response.BadRequest(c, "无效的卡密状态或排序方式")
 		default:
 			response.InternalError(c, "获取卡密列表失败", err.Error())
 		}
 		return
 	}
 
-	response.Success(c, gin.H{
-		"card_keys": cardKeys,
-		"total":     len(cardKeys),
-	})
+	response.Success(c, cardKeys)
+}
+
+// RecalculateStock resets a product's stock to its available card keys
+// POST /api/admin/exchange/products/:id/recalculate-stock
+func (h *ExchangeHandler) RecalculateStock(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的商品ID")
+		return
+	}
+
+	correction, err := h.exchangeService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).RecalculateProductStock(adminID.(uint), uint(id))
+	if err != nil {
+		switch err {
+		case service.ErrProductNotFound:
+			response.NotFound(c, "商品不存在")
+		default:
+			response.InternalError(c, "重新计算库存失败", err.Error())
+		}
+		return
+	}
+
+	response.Success(c, correction)
+}
+
+// RepairAllStock recalculates the stock of all products
+// POST /api/admin/exchange/products/recalculate-stock
+func (h *ExchangeHandler) RepairAllStock(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	result, err := h.exchangeService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).RecalculateAllStock(adminID.(uint))
+	if err != nil {
+		response.InternalError(c, "修复库存失败", err.Error())
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// GetCardKeyReveals returns the card key reveal audit log (admin only)
+// GET /api/admin/exchange/card-key-reveals
+func (h *ExchangeHandler) GetCardKeyReveals(c *gin.Context) {
+	var query service.CardKeyRevealQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	result, err := h.exchangeService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).GetCardKeyReveals(query)
+	if err != nil {
+		response.InternalError(c, "获取卡密查看记录失败", err.Error())
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// VerifyExchangeReceipt resolves a receipt verification code (admin only)
+// GET /api/admin/exchange/receipts/:code
+func (h *ExchangeHandler) VerifyExchangeReceipt(c *gin.Context) {
+	receipt, err := h.exchangeService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).VerifyExchangeReceipt(c.Param("code"))
+	if err != nil {
+		switch err {
+		case service.ErrReceiptNotFound:
+			response.NotFound(c, "收据不存在")
+		default:
+			response.InternalError(c, "核验收据失败", err.Error())
+		}
+		return
+	}
+
+	response.Success(c, receipt)
 }
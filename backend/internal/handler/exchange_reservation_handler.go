@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExchangeReservationHandler handles two-phase exchange endpoints
+type ExchangeReservationHandler struct {
+	reservationService *service.ExchangeReservationService
+}
+
+// NewExchangeReservationHandler creates a new exchange reservation handler
+func NewExchangeReservationHandler(reservationService *service.ExchangeReservationService) *ExchangeReservationHandler {
+	return &ExchangeReservationHandler{reservationService: reservationService}
+}
+
+// Reserve holds the points and a card key of a product for the current user
+// POST /api/exchange/reserve
+func (h *ExchangeReservationHandler) Reserve(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	var req service.RedeemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	result, err := h.reservationService.ForTenant(tenantID(c)).Reserve(userID.(uint), req.ProductID)
+	if err != nil {
+		switch err {
+		case service.ErrProductNotFound:
+			response.NotFound(c, "商品不存在")
+		case service.ErrProductSoldOut, service.ErrNoAvailableCardKey:
+			response.Error(c, http.StatusOK, response.ErrProductSoldOut, "商品已兑完")
+		case service.ErrProductOffline:
+			response.Error(c, http.StatusOK, response.ErrProductNotFound, "商品已下架")
+		case service.ErrProductNotDropped:
+			response.Error(c, http.StatusOK, response.ErrProductNotDropped, "商品尚未开售")
+		case service.ErrInsufficientPoints:
+			response.Error(c, http.StatusOK, response.ErrInsufficientPoints, "积分不足")
+		case service.ErrWalletFrozen:
+			response.BadRequest(c, "钱包已冻结，等待管理员核查")
+		default:
+			response.InternalError(c, "预订失败", err.Error())
+		}
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// Confirm completes a reservation and returns the card key
+// POST /api/exchange/records/:id/confirm
+func (h *ExchangeReservationHandler) Confirm(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的记录ID")
+		return
+	}
+
+	result, err := h.reservationService.ForTenant(tenantID(c)).Confirm(userID.(uint), uint(id))
+	if err != nil {
+		h.handleError(c, err, "确认兑换失败")
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// Cancel releases a reservation and refunds the held points
+// POST /api/exchange/records/:id/cancel
+func (h *ExchangeReservationHandler) Cancel(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的记录ID")
+		return
+	}
+
+	if err := h.reservationService.ForTenant(tenantID(c)).Cancel(userID.(uint), uint(id)); err != nil {
+		h.handleError(c, err, "取消预订失败")
+		return
+	}
+
+	response.Success(c, gin.H{"message": "预订已取消，积分已退回"})
+}
+
+func (h *ExchangeReservationHandler) handleError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrReservationNotFound:
+		response.NotFound(c, "预订不存在或已处理")
+	case service.ErrReservationExpired:
+		response.BadRequest(c, "预订已超时，积分已退回")
+	default:
+		response.InternalError(c, message, err.Error())
+	}
+}
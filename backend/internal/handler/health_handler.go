@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthHandler handles the liveness and readiness probes
+type HealthHandler struct {
+	mode     string
+	draining atomic.Bool
+}
+
+// NewHealthHandler creates a new health handler
+func NewHealthHandler(mode string) *HealthHandler {
+	return &HealthHandler{mode: mode}
+}
+
+// Drain marks the server as shutting down, so the readiness probe fails and
+// load balancers stop sending new requests while in-flight ones finish
+func (h *HealthHandler) Drain() {
+	h.draining.Store(true)
+}
+
+// Live reports that the process is up
+// GET /health
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+		"mode":   h.mode,
+	})
+}
+
+// Ready reports whether the server accepts new requests
+// GET /health/ready
+func (h *HealthHandler) Ready(c *gin.Context) {
+	if h.draining.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "shutting_down"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ready"})
+}
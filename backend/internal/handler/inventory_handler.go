@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InventoryHandler handles inventory alert endpoints
+type InventoryHandler struct {
+	inventoryMonitorService *service.InventoryMonitorService
+}
+
+// NewInventoryHandler creates a new inventory handler
+func NewInventoryHandler(inventoryMonitorService *service.InventoryMonitorService) *InventoryHandler {
+	return &InventoryHandler{inventoryMonitorService: inventoryMonitorService}
+}
+
+// GetInventoryAlerts returns low-stock alerts (admin only)
+// GET /api/admin/inventory/alerts
+func (h *InventoryHandler) GetInventoryAlerts(c *gin.Context) {
+	var query service.InventoryAlertQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	result, err := h.inventoryMonitorService.GetAlerts(query)
+	if err != nil {
+		response.InternalError(c, "获取库存预警失败", err.Error())
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// CheckInventory runs an inventory check immediately (admin only)
+// POST /api/admin/inventory/check
+func (h *InventoryHandler) CheckInventory(c *gin.Context) {
+	alerts, err := h.inventoryMonitorService.CheckInventory()
+	if err != nil {
+		response.InternalError(c, "库存检查失败", err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{
+		"new_alerts": alerts,
+		"count":      len(alerts),
+	})
+}
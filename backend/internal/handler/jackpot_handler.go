@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JackpotHandler handles progressive jackpot endpoints
+type JackpotHandler struct {
+	jackpotService *service.JackpotService
+}
+
+// NewJackpotHandler creates a new jackpot handler
+func NewJackpotHandler(jackpotService *service.JackpotService) *JackpotHandler {
+	return &JackpotHandler{jackpotService: jackpotService}
+}
+
+// GetJackpot returns the current jackpot
+// GET /api/lottery/jackpot
+// GET /api/admin/settings/jackpot
+func (h *JackpotHandler) GetJackpot(c *gin.Context) {
+	jackpot, err := h.jackpotService.ForTenant(tenantID(c)).GetJackpot()
+	if err != nil {
+		response.InternalError(c, "获取累积奖池失败", err.Error())
+		return
+	}
+
+	response.Success(c, jackpot)
+}
+
+// UpdateJackpotSettings sets the contribution rate and odds of the jackpot
+// PUT /api/admin/settings/jackpot
+func (h *JackpotHandler) UpdateJackpotSettings(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	var req service.UpdateJackpotSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	jackpot, err := h.jackpotService.ForTenant(tenantID(c)).UpdateSettings(adminID.(uint), req)
+	if err != nil {
+		switch err {
+		case service.ErrInvalidJackpotSettings:
+			response.BadRequest(c, "无效的累积奖池设置")
+		default:
+			response.InternalError(c, "更新累积奖池设置失败", err.Error())
+		}
+		return
+	}
+
+	response.Success(c, jackpot)
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// KillSwitchHandler handles subsystem kill switch and announcement endpoints
+type KillSwitchHandler struct {
+	killSwitchService *service.KillSwitchService
+}
+
+// NewKillSwitchHandler creates a new kill switch handler
+func NewKillSwitchHandler(killSwitchService *service.KillSwitchService) *KillSwitchHandler {
+	return &KillSwitchHandler{killSwitchService: killSwitchService}
+}
+
+// GetAnnouncements returns the incident banner and the disabled subsystems
+// GET /api/system/announcements
+func (h *KillSwitchHandler) GetAnnouncements(c *gin.Context) {
+	announcements, err := h.killSwitchService.ForTenant(tenantID(c)).Announcements()
+	if err != nil {
+		response.InternalError(c, "获取公告失败", err.Error())
+		return
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	response.Success(c, gin.H{"announcements": announcements})
+}
+
+// GetKillSwitches returns the state of every subsystem
+// GET /api/admin/settings/kill-switches
+func (h *KillSwitchHandler) GetKillSwitches(c *gin.Context) {
+	switches, err := h.killSwitchService.ForTenant(tenantID(c)).GetKillSwitches()
+	if err != nil {
+		response.InternalError(c, "获取功能开关失败", err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{"kill_switches": switches})
+}
+
+// UpdateKillSwitch switches a subsystem off or back on
+// PUT /api/admin/settings/kill-switches/:subsystem
+func (h *KillSwitchHandler) UpdateKillSwitch(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	var req service.UpdateKillSwitchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	sw, err := h.killSwitchService.ForTenant(tenantID(c)).UpdateKillSwitch(adminID.(uint), c.Param("subsystem"), req)
+	if err != nil {
+		switch err {
+		case service.ErrInvalidSubsystem:
+			response.NotFound(c, "功能模块不存在，可选 purchase、scratch、exchange 或 payment")
+		case service.ErrInvalidKillSwitch:
+			response.BadRequest(c, "提示信息最多500个字符")
+		default:
+			response.InternalError(c, "更新功能开关失败", err.Error())
+		}
+		return
+	}
+
+	response.Success(c, sw)
+}
@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LargeWinHandler handles large win reports and prize claims
+type LargeWinHandler struct {
+	largeWinService *service.LargeWinService
+}
+
+// NewLargeWinHandler creates a new large win handler
+func NewLargeWinHandler(largeWinService *service.LargeWinService) *LargeWinHandler {
+	return &LargeWinHandler{largeWinService: largeWinService}
+}
+
+// ClaimPrize confirms the winner's identity for a held large win
+// POST /api/lottery/tickets/:id/claim
+func (h *LargeWinHandler) ClaimPrize(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "请先登录")
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的彩票ID")
+		return
+	}
+
+	var req service.ClaimPrizeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	claim, err := h.largeWinService.ForTenant(tenantID(c)).ClaimPrize(userID.(uint), uint(id), req)
+	if err != nil {
+		switch err {
+		case service.ErrPrizeClaimNotFound:
+			response.NotFound(c, "该彩票无待兑奖记录")
+		case service.ErrPrizeAlreadyClaimed:
+			response.BadRequest(c, "奖金已兑领")
+		case service.ErrInvalidClaimIdentity:
+			response.BadRequest(c, "身份信息无效")
+		default:
+			response.InternalError(c, "兑奖失败", err.Error())
+		}
+		return
+	}
+
+	response.Success(c, claim)
+}
+
+// GetClaims returns the prize claims of the current user
+// GET /api/lottery/claims
+func (h *LargeWinHandler) GetClaims(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "请先登录")
+		return
+	}
+
+	claims, err := h.largeWinService.ForTenant(tenantID(c)).GetClaims(userID.(uint))
+	if err != nil {
+		response.InternalError(c, "获取兑奖记录失败", err.Error())
+		return
+	}
+
+	response.Success(c, claims)
+}
+
+// GetAdminClaims returns a page of prize claims, by default those waiting for approval
+// GET /api/admin/claims
+func (h *LargeWinHandler) GetAdminClaims(c *gin.Context) {
+	var query service.AdminPrizeClaimQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	claims, err := h.largeWinService.ForTenant(tenantID(c)).WithRedaction(fieldRedaction(c)).GetAdminClaims(query)
+	if err != nil {
+		response.InternalError(c, "获取兑奖申请失败", err.Error())
+		return
+	}
+
+	response.Success(c, claims)
+}
+
+// ApproveClaim approves a prize claim and pays out its prize
+// POST /api/admin/claims/:id/approve
+func (h *LargeWinHandler) ApproveClaim(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的兑奖申请ID")
+		return
+	}
+
+	claim, err := h.largeWinService.ForTenant(tenantID(c)).ApproveClaim(adminID.(uint), uint(id))
+	if err != nil {
+		h.reviewError(c, err, "审核兑奖申请失败")
+		return
+	}
+
+	response.Success(c, claim)
+}
+
+// RejectClaim rejects a prize claim, forfeiting its prize
+// POST /api/admin/claims/:id/reject
+func (h *LargeWinHandler) RejectClaim(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的兑奖申请ID")
+		return
+	}
+
+	var req service.RejectPrizeClaimRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	claim, err := h.largeWinService.ForTenant(tenantID(c)).RejectClaim(adminID.(uint), uint(id), req)
+	if err != nil {
+		h.reviewError(c, err, "审核兑奖申请失败")
+		return
+	}
+
+	response.Success(c, claim)
+}
+
+// GetLargeWinReport returns wins above the large win threshold within a period
+// GET /api/admin/reports/large-wins
+func (h *LargeWinHandler) GetLargeWinReport(c *gin.Context) {
+	var query service.LargeWinReportQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	report, err := h.largeWinService.ForTenant(tenantID(c)).WithRedaction(fieldRedaction(c)).GetReport(query)
+	if err != nil {
+		h.reportError(c, err, "获取大额中奖报表失败")
+		return
+	}
+
+	response.Success(c, report)
+}
+
+// ExportLargeWinReport exports the large win report as CSV with full winner identifiers
+// GET /api/admin/reports/large-wins/export
+func (h *LargeWinHandler) ExportLargeWinReport(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	var query service.LargeWinReportQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	csvData, err := h.largeWinService.ForTenant(tenantID(c)).WithRedaction(fieldRedaction(c)).ExportReportCSV(adminID.(uint), query)
+	if err != nil {
+		h.reportError(c, err, "导出大额中奖报表失败")
+		return
+	}
+
+	filename := fmt.Sprintf("large-wins-%s-%s.csv", query.StartDate, query.EndDate)
+	if query.StartDate == "" || query.EndDate == "" {
+		filename = "large-wins.csv"
+	}
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Data(200, "text/csv; charset=utf-8", csvData)
+}
+
+// reportError maps large win report errors to responses
+func (h *LargeWinHandler) reportError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrInvalidReportThreshold:
+		response.BadRequest(c, "未设置大额中奖门槛，请指定 min_amount")
+	case service.ErrInvalidReportPeriod:
+		response.BadRequest(c, "无效的报表时间范围")
+	default:
+		response.InternalError(c, message, err.Error())
+	}
+}
+
+// reviewError maps prize claim review errors to responses
+func (h *LargeWinHandler) reviewError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrPrizeClaimNotFound:
+		response.NotFound(c, "兑奖申请不存在")
+	case service.ErrPrizeClaimNotSubmitted:
+		response.BadRequest(c, "该兑奖申请不在待审核状态")
+	case service.ErrInvalidRejectReason:
+		response.BadRequest(c, "请填写拒绝原因（不超过255字）")
+	case service.ErrPayoutDisputed:
+		response.Forbidden(c, "该用户有未处理的购票争议，奖金暂缓发放")
+	default:
+		response.InternalError(c, message, err.Error())
+	}
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	liveEventWriteTimeout = 10 * time.Second
+	liveEventPongTimeout  = 60 * time.Second
+	liveEventPingInterval = liveEventPongTimeout * 9 / 10
+)
+
+// liveEventUpgrader accepts any origin: the API is served with open CORS and
+// authenticates with bearer tokens rather than cookies
+var liveEventUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+// LiveEventHandler handles the live event WebSocket
+type LiveEventHandler struct {
+	events *service.LiveEventBus
+}
+
+// NewLiveEventHandler creates a new live event handler
+func NewLiveEventHandler(events *service.LiveEventBus) *LiveEventHandler {
+	return &LiveEventHandler{events: events}
+}
+
+// Serve pushes live events over a WebSocket: big wins and sell-outs of the
+// tenant to everyone, and balance changes to signed in users
+// GET /api/ws
+func (h *LiveEventHandler) Serve(c *gin.Context) {
+	var userID uint
+	if id, exists := c.Get("userID"); exists {
+		userID = id.(uint)
+	}
+
+	events, cancel, err := h.events.ForTenant(tenantID(c)).Subscribe(userID, c.ClientIP())
+	if err != nil {
+		response.TooManyRequests(c, "连接数量过多，请关闭其他页面后重试")
+		return
+	}
+	defer cancel()
+
+	conn, err := liveEventUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already written the error response
+		return
+	}
+	defer conn.Close()
+
+	// Clients only send control frames; reading handles them and notices
+	// the connection closing
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		conn.SetReadLimit(512)
+		_ = conn.SetReadDeadline(time.Now().Add(liveEventPongTimeout))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(liveEventPongTimeout))
+		})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(liveEventPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			_ = conn.SetWriteDeadline(time.Now().Add(liveEventWriteTimeout))
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(liveEventWriteTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"strconv"
+
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LoginHistoryHandler handles the login history of users
+type LoginHistoryHandler struct {
+	authGuardService *service.AuthGuardService
+}
+
+// NewLoginHistoryHandler creates a new login history handler
+func NewLoginHistoryHandler(authGuardService *service.AuthGuardService) *LoginHistoryHandler {
+	return &LoginHistoryHandler{authGuardService: authGuardService}
+}
+
+// GetLoginHistory returns the recent logins of the current user
+// GET /api/user/login-history
+func (h *LoginHistoryHandler) GetLoginHistory(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "请先登录")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	history, err := h.authGuardService.ForTenant(tenantID(c)).GetLoginHistory(userID.(uint), page, limit)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Success(c, history)
+}
+
+// GetUserLoginHistory returns the recent logins of a user
+// GET /api/admin/users/:id/login-history
+func (h *LoginHistoryHandler) GetUserLoginHistory(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的用户ID")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	history, err := h.authGuardService.ForTenant(tenantID(c)).GetLoginHistory(uint(userID), page, limit)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Success(c, history)
+}
+
+func (h *LoginHistoryHandler) handleError(c *gin.Context, err error) {
+	switch err {
+	case service.ErrUserNotFound:
+		response.NotFound(c, "用户不存在")
+	default:
+		response.InternalError(c, "获取登录记录失败", err.Error())
+	}
+}
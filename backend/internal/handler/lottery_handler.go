@@ -1,7 +1,11 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
 	"strconv"
+	"time"
 
 	"scratch-lottery/internal/service"
 	"scratch-lottery/pkg/response"
@@ -14,14 +18,19 @@ type LotteryHandler struct {
 	lotteryService  *service.LotteryService
 	purchaseService *service.PurchaseService
 	scratchService  *service.ScratchService
+	waitingRoom     *service.WaitingRoomService
+
+	verifyBatchMaxCodes int
 }
 
 // NewLotteryHandler creates a new lottery handler
-func NewLotteryHandler(lotteryService *service.LotteryService, purchaseService *service.PurchaseService, scratchService *service.ScratchService) *LotteryHandler {
+func NewLotteryHandler(lotteryService *service.LotteryService, purchaseService *service.PurchaseService, scratchService *service.ScratchService, waitingRoom *service.WaitingRoomService, verifyBatchMaxCodes int) *LotteryHandler {
 	return &LotteryHandler{
-		lotteryService:  lotteryService,
-		purchaseService: purchaseService,
-		scratchService:  scratchService,
+		lotteryService:      lotteryService,
+		purchaseService:     purchaseService,
+		scratchService:      scratchService,
+		waitingRoom:         waitingRoom,
+		verifyBatchMaxCodes: verifyBatchMaxCodes,
 	}
 }
 
@@ -34,7 +43,7 @@ func (h *LotteryHandler) GetLotteryTypes(c *gin.Context) {
 		return
 	}
 
-	result, err := h.lotteryService.GetAllLotteryTypes(query)
+	result, err := h.lotteryService.ForTenant(tenantID(c)).GetAllLotteryTypes(query)
 	if err != nil {
 		response.InternalError(c, "获取彩票类型列表失败", err.Error())
 		return
@@ -53,7 +62,7 @@ func (h *LotteryHandler) GetLotteryTypeByID(c *gin.Context) {
 		return
 	}
 
-	lotteryType, err := h.lotteryService.GetLotteryTypeByID(uint(id))
+	lotteryType, err := h.lotteryService.ForTenant(tenantID(c)).GetLotteryTypeByID(uint(id))
 	if err != nil {
 		switch err {
 		case service.ErrLotteryTypeNotFound:
@@ -76,11 +85,23 @@ func (h *LotteryHandler) CreateLotteryType(c *gin.Context) {
 		return
 	}
 
-	lotteryType, err := h.lotteryService.CreateLotteryType(req)
+	lotteryType, err := h.lotteryService.ForTenant(tenantID(c)).CreateLotteryType(req)
 	if err != nil {
 		switch err {
 		case service.ErrInvalidPrizeConfig:
 			response.BadRequest(c, "无效的奖级配置")
+		case service.ErrInvalidLotteryTypeSchedule:
+			response.BadRequest(c, "无效的上架时间，下架时间须晚于当前时间和上架时间")
+		case service.ErrInvalidPrizePayout:
+			response.BadRequest(c, "无效的奖品发放方式，实物奖品须指定有效的兑换商品")
+		case service.ErrInvalidPrizeLevelDisplay:
+			response.BadRequest(c, "无效的奖级展示配置，图片须为 http(s) 链接或站内路径，颜色须为 #RGB 或 #RRGGBB，文案不超过 256 个字符")
+		case service.ErrPrizeTemplateNotFound:
+			response.BadRequest(c, "补池使用的奖级模板不存在")
+		case service.ErrInvalidNumberMatchConfig:
+			response.BadRequest(c, "无效的数字匹配配置，号码总数不超过 100 个，max_number 须大于中奖号码个数")
+		case service.ErrInvalidMultiplierConfig:
+			response.BadRequest(c, "无效的翻倍配置，倍数须为 1 至 50 且不重复，各奖级奖金须为票价乘以其中一个倍数")
 		default:
 			response.InternalError(c, "创建彩票类型失败", err.Error())
 		}
@@ -106,13 +127,23 @@ func (h *LotteryHandler) UpdateLotteryType(c *gin.Context) {
 		return
 	}
 
-	lotteryType, err := h.lotteryService.UpdateLotteryType(uint(id), req)
+	lotteryType, err := h.lotteryService.ForTenant(tenantID(c)).UpdateLotteryType(uint(id), req)
 	if err != nil {
 		switch err {
 		case service.ErrLotteryTypeNotFound:
 			response.NotFound(c, "彩票类型不存在")
 		case service.ErrInvalidPrizeConfig:
 			response.BadRequest(c, "无效的奖级配置")
+		case service.ErrInvalidLotteryTypeSchedule:
+			response.BadRequest(c, "无效的上架时间，下架时间须晚于当前时间和上架时间")
+		case service.ErrInvalidDesignConfig:
+			response.BadRequest(c, "无效的界面设计配置，颜色应为 #RGB 或 #RRGGBB，素材仅支持 http(s) 地址或站内路径")
+		case service.ErrPrizeTemplateNotFound:
+			response.BadRequest(c, "补池使用的奖级模板不存在")
+		case service.ErrInvalidNumberMatchConfig:
+			response.BadRequest(c, "无效的数字匹配配置，号码总数不超过 100 个，max_number 须大于中奖号码个数")
+		case service.ErrInvalidMultiplierConfig:
+			response.BadRequest(c, "无效的翻倍配置，倍数须为 1 至 50 且不重复，各奖级奖金须为票价乘以其中一个倍数")
 		default:
 			response.InternalError(c, "更新彩票类型失败", err.Error())
 		}
@@ -122,6 +153,12 @@ func (h *LotteryHandler) UpdateLotteryType(c *gin.Context) {
 	response.Success(c, lotteryType)
 }
 
+// GetDesignConfigSchema returns the JSON schema of lottery type design configs
+// GET /api/lottery/design-schema
+func (h *LotteryHandler) GetDesignConfigSchema(c *gin.Context) {
+	c.JSON(http.StatusOK, service.DesignConfigSchema())
+}
+
 // DeleteLotteryType deletes a lottery type (admin only)
 // DELETE /api/admin/lottery/types/:id
 func (h *LotteryHandler) DeleteLotteryType(c *gin.Context) {
@@ -132,10 +169,12 @@ func (h *LotteryHandler) DeleteLotteryType(c *gin.Context) {
 		return
 	}
 
-	if err := h.lotteryService.DeleteLotteryType(uint(id)); err != nil {
+	if err := h.lotteryService.ForTenant(tenantID(c)).DeleteLotteryType(uint(id)); err != nil {
 		switch err {
 		case service.ErrLotteryTypeNotFound:
 			response.NotFound(c, "彩票类型不存在")
+		case service.ErrLotteryTypeHasUnscratched:
+			response.BadRequest(c, "该彩票类型仍有未刮开的彩票，无法删除")
 		default:
 			response.InternalError(c, "删除彩票类型失败", err.Error())
 		}
@@ -155,7 +194,7 @@ func (h *LotteryHandler) GetPrizeLevels(c *gin.Context) {
 		return
 	}
 
-	prizeLevels, err := h.lotteryService.GetPrizeLevels(uint(id))
+	prizeLevels, err := h.lotteryService.ForTenant(tenantID(c)).GetPrizeLevels(uint(id))
 	if err != nil {
 		response.InternalError(c, "获取奖级配置失败", err.Error())
 		return
@@ -182,10 +221,16 @@ func (h *LotteryHandler) UpdatePrizeLevels(c *gin.Context) {
 		return
 	}
 
-	if err := h.lotteryService.UpdatePrizeLevels(uint(id), req.PrizeLevels); err != nil {
+	if err := h.lotteryService.ForTenant(tenantID(c)).UpdatePrizeLevels(uint(id), req.PrizeLevels); err != nil {
 		switch err {
 		case service.ErrLotteryTypeNotFound:
 			response.NotFound(c, "彩票类型不存在")
+		case service.ErrInvalidPrizePayout:
+			response.BadRequest(c, "无效的奖品发放方式，实物奖品须指定有效的兑换商品")
+		case service.ErrInvalidPrizeLevelDisplay:
+			response.BadRequest(c, "无效的奖级展示配置，图片须为 http(s) 链接或站内路径，颜色须为 #RGB 或 #RRGGBB，文案不超过 256 个字符")
+		case service.ErrPrizeLevelsInUse:
+			response.BadRequest(c, "该彩票类型有已售出彩票的奖池正在销售，无法修改奖级结构，仅可修改名称、发放方式和展示配置")
 		default:
 			response.InternalError(c, "更新奖级配置失败", err.Error())
 		}
@@ -212,7 +257,7 @@ func (h *LotteryHandler) CreatePrizePool(c *gin.Context) {
 	}
 	req.LotteryTypeID = uint(id)
 
-	prizePool, err := h.lotteryService.CreatePrizePool(req)
+	prizePool, err := h.lotteryService.ForTenant(tenantID(c)).CreatePrizePool(req)
 	if err != nil {
 		switch err {
 		case service.ErrLotteryTypeNotFound:
@@ -236,7 +281,7 @@ func (h *LotteryHandler) GetPrizePools(c *gin.Context) {
 		return
 	}
 
-	prizePools, err := h.lotteryService.GetPrizePools(uint(id))
+	prizePools, err := h.lotteryService.ForTenant(tenantID(c)).GetPrizePools(uint(id))
 	if err != nil {
 		response.InternalError(c, "获取奖组列表失败", err.Error())
 		return
@@ -255,7 +300,7 @@ func (h *LotteryHandler) GetActivePrizePool(c *gin.Context) {
 		return
 	}
 
-	prizePool, err := h.lotteryService.GetActivePrizePool(uint(id))
+	prizePool, err := h.lotteryService.ForTenant(tenantID(c)).GetActivePrizePool(uint(id))
 	if err != nil {
 		switch err {
 		case service.ErrPrizePoolNotFound:
@@ -285,6 +330,10 @@ func (h *LotteryHandler) PurchaseTickets(c *gin.Context) {
 		response.BadRequest(c, "请求参数无效", err.Error())
 		return
 	}
+	// Clients may send the request ID as an Idempotency-Key header instead
+	if req.RequestID == "" {
+		req.RequestID = c.GetHeader("Idempotency-Key")
+	}
 
 	// Validate quantity
 	if req.Quantity < 1 || req.Quantity > 10 {
@@ -292,7 +341,44 @@ func (h *LotteryHandler) PurchaseTickets(c *gin.Context) {
 		return
 	}
 
-	result, err := h.purchaseService.PurchaseTickets(userID.(uint), req)
+	// A retried request is answered before it queues again
+	replay, err := h.purchaseService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).ReplayPurchase(userID.(uint), req)
+	if err != nil {
+		switch err {
+		case service.ErrInvalidRequestID:
+			response.BadRequest(c, "请求ID过长")
+		case service.ErrRequestIDReused:
+			response.Error(c, http.StatusConflict, response.ErrPurchaseConflict, "请求ID已用于其他购买")
+		case service.ErrPurchaseInProgress:
+			response.Error(c, http.StatusConflict, response.ErrPurchaseConflict, "相同请求正在处理中，请稍后重试")
+		default:
+			response.InternalError(c, "购买失败", err.Error())
+		}
+		return
+	}
+	if replay != nil {
+		response.Success(c, replay)
+		return
+	}
+
+	// High-demand lottery types admit purchases through the waiting room
+	leave, err := h.waitingRoom.Enter(userID.(uint), req.LotteryTypeID, req.AdmissionToken)
+	if err != nil {
+		switch err {
+		case service.ErrLotteryTypeNotFound:
+			response.NotFound(c, "彩票类型不存在")
+		case service.ErrWaitingRoomRequired:
+			response.Error(c, http.StatusTooManyRequests, response.ErrWaitingRoom, "购买人数过多，请先排队")
+		case service.ErrWaitingRoomNotAdmitted:
+			response.Error(c, http.StatusTooManyRequests, response.ErrWaitingRoom, "排队中，请等待放行")
+		default:
+			response.InternalError(c, "购买失败", err.Error())
+		}
+		return
+	}
+	defer leave()
+
+	result, err := h.purchaseService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).PurchaseTickets(userID.(uint), req)
 	if err != nil {
 		switch err {
 		case service.ErrLotteryTypeNotFound:
@@ -301,8 +387,18 @@ func (h *LotteryHandler) PurchaseTickets(c *gin.Context) {
 			response.BadRequest(c, "彩票已售罄")
 		case service.ErrInsufficientBalance:
 			response.BadRequest(c, "余额不足")
+		case service.ErrWalletFrozen:
+			response.BadRequest(c, "钱包已冻结，等待管理员核查")
 		case service.ErrNoPrizePoolActive:
 			response.BadRequest(c, "暂无可用奖组")
+		case service.ErrCouponUnavailable:
+			response.BadRequest(c, "优惠券不存在、已使用或已过期")
+		case service.ErrInvalidRequestID:
+			response.BadRequest(c, "请求ID过长")
+		case service.ErrRequestIDReused:
+			response.Error(c, http.StatusConflict, response.ErrPurchaseConflict, "请求ID已用于其他购买")
+		case service.ErrPurchaseInProgress:
+			response.Error(c, http.StatusConflict, response.ErrPurchaseConflict, "相同请求正在处理中，请稍后重试")
 		default:
 			response.InternalError(c, "购买失败", err.Error())
 		}
@@ -327,7 +423,7 @@ func (h *LotteryHandler) GetPurchasePreview(c *gin.Context) {
 		return
 	}
 
-	preview, err := h.purchaseService.GetPurchasePreview(userID.(uint), req)
+	preview, err := h.purchaseService.ForTenant(tenantID(c)).GetPurchasePreview(userID.(uint), req)
 	if err != nil {
 		switch err {
 		case service.ErrLotteryTypeNotFound:
@@ -355,7 +451,7 @@ func (h *LotteryHandler) GetUserTickets(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
 
-	tickets, total, err := h.lotteryService.GetUserTickets(userID.(uint), page, limit)
+	tickets, total, err := h.lotteryService.ForTenant(tenantID(c)).GetUserTickets(userID.(uint), page, limit)
 	if err != nil {
 		response.InternalError(c, "获取彩票列表失败", err.Error())
 		return
@@ -391,7 +487,7 @@ func (h *LotteryHandler) GetTicketByID(c *gin.Context) {
 		return
 	}
 
-	ticket, err := h.lotteryService.GetTicketByID(uint(id))
+	ticket, err := h.lotteryService.ForTenant(tenantID(c)).GetTicketByID(uint(id))
 	if err != nil {
 		switch err {
 		case service.ErrTicketNotFound:
@@ -409,7 +505,7 @@ func (h *LotteryHandler) GetTicketByID(c *gin.Context) {
 	}
 
 	// Only show prize amount if scratched
-	showPrize := ticket.Status == "scratched" || ticket.Status == "claimed"
+	showPrize := ticket.Status.Revealed()
 	
 	resp := gin.H{
 		"id":              ticket.ID,
@@ -437,7 +533,7 @@ func (h *LotteryHandler) VerifySecurityCode(c *gin.Context) {
 		return
 	}
 
-	result, err := h.lotteryService.VerifySecurityCode(code)
+	result, err := h.lotteryService.ForTenant(tenantID(c)).VerifySecurityCode(code)
 	if err != nil {
 		switch err {
 		case service.ErrTicketNotFound:
@@ -453,6 +549,74 @@ func (h *LotteryHandler) VerifySecurityCode(c *gin.Context) {
 	response.Success(c, result)
 }
 
+// BatchVerifyRequest represents a batch security code verification request
+type BatchVerifyRequest struct {
+	Codes []string `json:"codes" binding:"required"`
+}
+
+// VerifySecurityCodesBatch verifies many security codes at once for partner kiosks
+// POST /api/lottery/verify/batch
+func (h *LotteryHandler) VerifySecurityCodesBatch(c *gin.Context) {
+	var req BatchVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	results, err := h.lotteryService.ForTenant(tenantID(c)).VerifySecurityCodes(req.Codes, h.verifyBatchMaxCodes)
+	if err != nil {
+		switch err {
+		case service.ErrEmptyVerifyBatch:
+			response.BadRequest(c, "保安码列表不能为空")
+		case service.ErrVerifyBatchTooLarge:
+			response.BadRequest(c, "保安码数量超出单次上限")
+		default:
+			response.InternalError(c, "查询失败", err.Error())
+		}
+		return
+	}
+
+	response.Success(c, gin.H{
+		"results": results,
+		"total":   len(results),
+	})
+}
+
+
+// ScratchConfirmRequest carries the confirmation token of the ticket detail,
+// required for lottery types that confirm scratches
+type ScratchConfirmRequest struct {
+	ConfirmToken string `json:"confirm_token"`
+}
+
+// respondScratchConfirmation rejects a scratch that was not confirmed and
+// reports whether err was a confirmation error
+func respondScratchConfirmation(c *gin.Context, err error) bool {
+	switch err {
+	case service.ErrScratchConfirmationRequired:
+		response.Error(c, http.StatusBadRequest, response.ErrScratchNotConfirmed, "请确认后再刮开")
+	case service.ErrScratchConfirmationInvalid:
+		response.Error(c, http.StatusBadRequest, response.ErrScratchNotConfirmed, "确认已失效，请重新打开彩票确认")
+	default:
+		return false
+	}
+	return true
+}
+
+// respondScratchTooSoon rejects a scratch made before the pacing of its
+// lottery type allows it, with the countdown in Retry-After, and reports
+// whether err was a pacing error
+func respondScratchTooSoon(c *gin.Context, err error) bool {
+	var tooSoon *service.ScratchTooSoonError
+	if !errors.As(err, &tooSoon) {
+		return false
+	}
+	retryAfter := max(tooSoon.RetryAfter(), 1)
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	response.Error(c, http.StatusTooManyRequests, response.ErrScratchTooSoon,
+		fmt.Sprintf("刮奖过快，请 %d 秒后再试", retryAfter), tooSoon.ScratchableAt.Format(time.RFC3339))
+	return true
+}
 
 // ScratchTicket scratches a ticket and reveals the result
 // POST /api/lottery/scratch/:id
@@ -470,8 +634,15 @@ func (h *LotteryHandler) ScratchTicket(c *gin.Context) {
 		return
 	}
 
-	result, err := h.scratchService.ScratchTicket(userID.(uint), uint(id))
+	// The body is optional, only confirmed lottery types need the token
+	var req ScratchConfirmRequest
+	_ = c.ShouldBindJSON(&req)
+
+	result, err := h.scratchService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).ScratchTicket(userID.(uint), uint(id), req.ConfirmToken)
 	if err != nil {
+		if respondScratchConfirmation(c, err) || respondScratchTooSoon(c, err) {
+			return
+		}
 		switch err {
 		case service.ErrTicketNotFound:
 			response.NotFound(c, "彩票不存在")
@@ -479,6 +650,10 @@ func (h *LotteryHandler) ScratchTicket(c *gin.Context) {
 			response.Forbidden(c, "无权操作此彩票")
 		case service.ErrTicketAlreadyScratched:
 			response.BadRequest(c, "彩票已刮开")
+		case service.ErrTicketVoided:
+			response.BadRequest(c, "彩票已作废")
+		case service.ErrNumberMatchMismatch, service.ErrMultiplierMismatch:
+			response.InternalError(c, "彩票数据校验失败，请联系客服")
 		default:
 			response.InternalError(c, "刮奖失败", err.Error())
 		}
@@ -504,7 +679,7 @@ func (h *LotteryHandler) GetTicketDetail(c *gin.Context) {
 		return
 	}
 
-	detail, err := h.scratchService.GetTicketDetail(userID.(uint), uint(id))
+	detail, err := h.scratchService.ForTenant(tenantID(c)).GetTicketDetail(userID.(uint), uint(id))
 	if err != nil {
 		switch err {
 		case service.ErrTicketNotFound:
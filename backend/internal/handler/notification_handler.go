@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationHandler handles the notification center of users
+type NotificationHandler struct {
+	notificationService *service.NotificationService
+	digestService       *service.DigestService
+}
+
+// NewNotificationHandler creates a new notification handler
+func NewNotificationHandler(notificationService *service.NotificationService, digestService *service.DigestService) *NotificationHandler {
+	return &NotificationHandler{notificationService: notificationService, digestService: digestService}
+}
+
+// GetNotifications returns the current user's notifications
+// GET /api/user/notifications
+func (h *NotificationHandler) GetNotifications(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	result, err := h.notificationService.ForTenant(tenantID(c)).GetNotifications(userID.(uint), page, limit)
+	if err != nil {
+		response.InternalError(c, "获取通知失败", err.Error())
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// MarkNotificationRead marks a notification as read
+// POST /api/user/notifications/:id/read
+func (h *NotificationHandler) MarkNotificationRead(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的通知ID")
+		return
+	}
+
+	if err := h.notificationService.ForTenant(tenantID(c)).MarkRead(userID.(uint), uint(id)); err != nil {
+		switch err {
+		case service.ErrNotificationNotFound:
+			response.NotFound(c, "通知不存在")
+		default:
+			response.InternalError(c, "更新通知失败", err.Error())
+		}
+		return
+	}
+
+	response.Success(c, gin.H{"message": "已标记为已读"})
+}
+
+// MarkAllNotificationsRead marks all notifications of the current user as read
+// POST /api/user/notifications/read-all
+func (h *NotificationHandler) MarkAllNotificationsRead(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	if err := h.notificationService.ForTenant(tenantID(c)).MarkAllRead(userID.(uint)); err != nil {
+		response.InternalError(c, "更新通知失败", err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{"message": "已全部标记为已读"})
+}
+
+// GetPreferences returns the current user's notification preferences
+// GET /api/user/notification-preferences
+func (h *NotificationHandler) GetPreferences(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	prefs, err := h.digestService.ForTenant(tenantID(c)).GetPreferences(userID.(uint))
+	if err != nil {
+		response.InternalError(c, "获取通知设置失败", err.Error())
+		return
+	}
+
+	response.Success(c, prefs)
+}
+
+// UpdatePreferences changes the current user's notification preferences
+// PUT /api/user/notification-preferences
+func (h *NotificationHandler) UpdatePreferences(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	var req service.UpdateNotificationPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	prefs, err := h.digestService.ForTenant(tenantID(c)).UpdatePreferences(userID.(uint), req)
+	if err != nil {
+		response.InternalError(c, "更新通知设置失败", err.Error())
+		return
+	}
+
+	response.Success(c, prefs)
+}
+
+// GetDigest returns the current user's weekly digest as it stands now
+// GET /api/user/digest
+func (h *NotificationHandler) GetDigest(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	digest, err := h.digestService.ForTenant(tenantID(c)).Build(userID.(uint), time.Now())
+	if err != nil {
+		response.InternalError(c, "获取摘要失败", err.Error())
+		return
+	}
+
+	response.Success(c, digest)
+}
+
+// GetBatchingConfig returns the notification batching windows
+// GET /api/admin/settings/notification-batching
+func (h *NotificationHandler) GetBatchingConfig(c *gin.Context) {
+	config, err := h.notificationService.ForTenant(tenantID(c)).GetBatchingConfig()
+	if err != nil {
+		response.InternalError(c, "获取通知合并配置失败", err.Error())
+		return
+	}
+
+	response.Success(c, config)
+}
+
+// UpdateBatchingConfig replaces the notification batching windows
+// PUT /api/admin/settings/notification-batching
+func (h *NotificationHandler) UpdateBatchingConfig(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	var req service.NotificationBatchingConfig
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	config, err := h.notificationService.ForTenant(tenantID(c)).UpdateBatchingConfig(adminID.(uint), req)
+	if err != nil {
+		switch err {
+		case service.ErrInvalidNotificationBatching:
+			response.BadRequest(c, "无效的通知合并配置")
+		default:
+			response.InternalError(c, "更新通知合并配置失败", err.Error())
+		}
+		return
+	}
+
+	response.Success(c, config)
+}
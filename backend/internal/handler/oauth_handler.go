@@ -4,7 +4,9 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"net/http"
+	"time"
 
+	"scratch-lottery/internal/model"
 	"scratch-lottery/internal/service"
 	"scratch-lottery/pkg/response"
 
@@ -13,12 +15,46 @@ import (
 
 // OAuthHandler handles OAuth2 endpoints
 type OAuthHandler struct {
-	oauthService *service.OAuthService
+	oauthService     *service.OAuthService
+	authGuardService *service.AuthGuardService
 }
 
 // NewOAuthHandler creates a new OAuth handler
-func NewOAuthHandler(oauthService *service.OAuthService) *OAuthHandler {
-	return &OAuthHandler{oauthService: oauthService}
+func NewOAuthHandler(oauthService *service.OAuthService, authGuardService *service.AuthGuardService) *OAuthHandler {
+	return &OAuthHandler{oauthService: oauthService, authGuardService: authGuardService}
+}
+
+// lockedUntil returns when the lockout of the requesting IP ends, or nil
+func (h *OAuthHandler) lockedUntil(c *gin.Context) *time.Time {
+	lockedUntil, _ := h.authGuardService.ForTenant(tenantID(c)).LockedUntil("", c.ClientIP())
+	return lockedUntil
+}
+
+// recordAnomaly records a failed or suspicious OAuth callback
+func (h *OAuthHandler) recordAnomaly(c *gin.Context, reason string) {
+	_ = h.authGuardService.ForTenant(tenantID(c)).RecordFailure(model.AuthIncidentOAuthAnomaly, "", clientInfo(c), reason)
+}
+
+// completeCallback exchanges the callback code for tokens, tracking failures
+// and the device of successful logins
+func (h *OAuthHandler) completeCallback(c *gin.Context, code, state string) (*service.AuthResponse, error) {
+	authResp, err := h.oauthService.HandleCallback(code, state, clientInfo(c))
+	if err != nil {
+		switch err {
+		case service.ErrOAuthDisabled:
+			// Not an anomaly, OAuth is simply off
+		case service.ErrTooManyIPSessions:
+			_ = h.authGuardService.ForTenant(tenantID(c)).RecordFailure(model.AuthIncidentSessionLimit, "", clientInfo(c), "too many sessions from ip")
+		default:
+			h.recordAnomaly(c, err.Error())
+		}
+		return nil, err
+	}
+
+	if err := h.authGuardService.ForTenant(authResp.User.TenantID).RecordLogin(authResp.User, clientInfo(c), model.LoginMethodOAuth); err != nil {
+		return nil, err
+	}
+	return authResp, nil
 }
 
 // generateState generates a random state string for OAuth
@@ -36,8 +72,13 @@ func (h *OAuthHandler) LinuxdoLogin(c *gin.Context) {
 		return
 	}
 
+	if lockedUntil := h.lockedUntil(c); lockedUntil != nil {
+		respondLocked(c, *lockedUntil)
+		return
+	}
+
 	state := generateState()
-	authURL, err := h.oauthService.GetAuthorizationURL(state)
+	authURL, err := h.oauthService.GetAuthorizationURL(state, tenantID(c))
 	if err != nil {
 		switch err {
 		case service.ErrOAuthDisabled:
@@ -59,20 +100,27 @@ func (h *OAuthHandler) LinuxdoCallback(c *gin.Context) {
 	state := c.Query("state")
 	errorParam := c.Query("error")
 
+	if lockedUntil := h.lockedUntil(c); lockedUntil != nil {
+		respondLocked(c, *lockedUntil)
+		return
+	}
+
 	// Check for OAuth error
 	if errorParam != "" {
 		errorDesc := c.Query("error_description")
+		h.recordAnomaly(c, "provider error: "+errorParam)
 		response.Error(c, http.StatusBadRequest, response.ErrOAuthFailed, "OAuth授权失败: "+errorDesc)
 		return
 	}
 
 	// Validate required parameters
 	if code == "" || state == "" {
+		h.recordAnomaly(c, "missing code or state")
 		response.BadRequest(c, "缺少必要的OAuth参数")
 		return
 	}
 
-	authResp, err := h.oauthService.HandleCallback(code, state)
+	authResp, err := h.completeCallback(c, code, state)
 	if err != nil {
 		switch err {
 		case service.ErrOAuthDisabled:
@@ -83,6 +131,8 @@ func (h *OAuthHandler) LinuxdoCallback(c *gin.Context) {
 			response.Error(c, http.StatusBadRequest, response.ErrOAuthFailed, "OAuth令牌交换失败")
 		case service.ErrOAuthUserInfo:
 			response.Error(c, http.StatusBadRequest, response.ErrOAuthFailed, "获取用户信息失败")
+		case service.ErrTooManyIPSessions:
+			respondTooManySessions(c)
 		default:
 			response.InternalError(c, "OAuth登录失败", err.Error())
 		}
@@ -106,19 +156,30 @@ func (h *OAuthHandler) OAuthCallbackRedirect(c *gin.Context) {
 		frontendURL = "/"
 	}
 
+	if h.lockedUntil(c) != nil {
+		c.Redirect(http.StatusTemporaryRedirect, frontendURL+"?error=locked")
+		return
+	}
+
 	// Check for OAuth error
 	if errorParam != "" {
+		h.recordAnomaly(c, "provider error: "+errorParam)
 		c.Redirect(http.StatusTemporaryRedirect, frontendURL+"?error=oauth_failed")
 		return
 	}
 
 	// Validate required parameters
 	if code == "" || state == "" {
+		h.recordAnomaly(c, "missing code or state")
 		c.Redirect(http.StatusTemporaryRedirect, frontendURL+"?error=missing_params")
 		return
 	}
 
-	authResp, err := h.oauthService.HandleCallback(code, state)
+	authResp, err := h.completeCallback(c, code, state)
+	if err == service.ErrTooManyIPSessions {
+		c.Redirect(http.StatusTemporaryRedirect, frontendURL+"?error=too_many_sessions")
+		return
+	}
 	if err != nil {
 		c.Redirect(http.StatusTemporaryRedirect, frontendURL+"?error=auth_failed")
 		return
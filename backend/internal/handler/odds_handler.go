@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"strconv"
+
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// oddsDisclosureTemplate renders an odds disclosure document as a printable HTML page
+var oddsDisclosureTemplate = template.Must(template.New("odds").Funcs(template.FuncMap{
+	"percent": func(v float64) string { return strconv.FormatFloat(v*100, 'f', 4, 64) + "%" },
+	"odds":    func(v float64) string { return "1 : " + strconv.FormatFloat(v, 'f', 2, 64) },
+}).Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>{{.LotteryTypeName}} 中奖概率公示 v{{.Version}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #999; padding: 4px 12px; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+</style>
+</head>
+<body>
+<h1>{{.LotteryTypeName}} 中奖概率公示</h1>
+<p>版本 v{{.Version}} · 生成时间 {{.GeneratedAt.Format "2006-01-02 15:04:05"}} · 配置摘要 <code>{{.ConfigHash}}</code></p>
+<ul>
+<li>票价：{{.TicketPrice}} 积分</li>
+<li>奖组总票数：{{.PoolSize}}</li>
+<li>中奖票总数：{{.TotalPrizes}}，奖金总额：{{.TotalPrizeValue}} 积分</li>
+<li>总体中奖概率：{{percent .OverallProbability}}（{{odds .OverallOddsOneIn}}）</li>
+<li>理论返奖率：{{percent .ExpectedReturnRate}}</li>
+</ul>
+<table>
+<thead><tr><th>奖级</th><th>奖金</th><th>数量</th><th>中奖概率</th><th>赔率</th></tr></thead>
+<tbody>
+{{range .Levels}}<tr><td>{{.Level}} · {{.Name}}</td><td>{{.PrizeAmount}}</td><td>{{.Quantity}}</td><td>{{percent .Probability}}</td><td>{{odds .OddsOneIn}}</td></tr>
+{{end}}</tbody>
+</table>
+</body>
+</html>
+`))
+
+// OddsHandler handles odds disclosure endpoints
+type OddsHandler struct {
+	oddsService *service.OddsService
+}
+
+// NewOddsHandler creates a new odds handler
+func NewOddsHandler(oddsService *service.OddsService) *OddsHandler {
+	return &OddsHandler{oddsService: oddsService}
+}
+
+// GetOddsDisclosure returns the odds disclosure of a lottery type as JSON or HTML.
+// Use ?version=N for a historical version and ?format=html for the printable page.
+// GET /api/lottery/types/:id/odds
+func (h *OddsHandler) GetOddsDisclosure(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的彩票类型ID")
+		return
+	}
+
+	var doc *service.OddsDisclosureDocument
+	if versionStr := c.Query("version"); versionStr != "" {
+		version, err := strconv.Atoi(versionStr)
+		if err != nil || version < 1 {
+			response.BadRequest(c, "无效的版本号")
+			return
+		}
+		doc, err = h.oddsService.ForTenant(tenantID(c)).GetOddsDisclosureVersion(uint(id), version)
+		if err != nil {
+			h.handleError(c, err)
+			return
+		}
+	} else {
+		doc, err = h.oddsService.ForTenant(tenantID(c)).GetOddsDisclosure(uint(id))
+		if err != nil {
+			h.handleError(c, err)
+			return
+		}
+	}
+
+	if c.Query("format") == "html" {
+		var buf bytes.Buffer
+		if err := oddsDisclosureTemplate.Execute(&buf, doc); err != nil {
+			response.InternalError(c, "生成概率公示失败", err.Error())
+			return
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
+		return
+	}
+
+	response.Success(c, doc)
+}
+
+// GetOddsDisclosureVersions lists the recorded odds disclosure versions
+// GET /api/lottery/types/:id/odds/versions
+func (h *OddsHandler) GetOddsDisclosureVersions(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的彩票类型ID")
+		return
+	}
+
+	versions, err := h.oddsService.ForTenant(tenantID(c)).GetOddsDisclosureVersions(uint(id))
+	if err != nil {
+		response.InternalError(c, "获取概率公示版本失败", err.Error())
+		return
+	}
+
+	response.Success(c, versions)
+}
+
+func (h *OddsHandler) handleError(c *gin.Context, err error) {
+	switch err {
+	case service.ErrLotteryTypeNotFound:
+		response.NotFound(c, "彩票类型不存在")
+	case service.ErrPrizePoolNotFound:
+		response.NotFound(c, "没有可用的奖组")
+	case service.ErrOddsVersionNotFound:
+		response.NotFound(c, "概率公示版本不存在")
+	default:
+		response.InternalError(c, "生成概率公示失败", err.Error())
+	}
+}
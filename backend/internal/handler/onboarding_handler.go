@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OnboardingHandler handles onboarding checklist endpoints
+type OnboardingHandler struct {
+	onboardingService *service.OnboardingService
+}
+
+// NewOnboardingHandler creates a new onboarding handler
+func NewOnboardingHandler(onboardingService *service.OnboardingService) *OnboardingHandler {
+	return &OnboardingHandler{onboardingService: onboardingService}
+}
+
+// GetProgress returns the onboarding checklist of the current user
+// GET /api/user/onboarding
+func (h *OnboardingHandler) GetProgress(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	progress, err := h.onboardingService.ForTenant(tenantID(c)).GetProgress(userID.(uint))
+	if err != nil {
+		response.InternalError(c, "获取新手任务失败", err.Error())
+		return
+	}
+
+	response.Success(c, progress)
+}
+
+// GetConfig returns the onboarding checklist configuration
+// GET /api/admin/settings/onboarding
+func (h *OnboardingHandler) GetConfig(c *gin.Context) {
+	config, err := h.onboardingService.ForTenant(tenantID(c)).GetConfig()
+	if err != nil {
+		response.InternalError(c, "获取新手任务配置失败", err.Error())
+		return
+	}
+
+	response.Success(c, config)
+}
+
+// UpdateConfig replaces the onboarding checklist configuration
+// PUT /api/admin/settings/onboarding
+func (h *OnboardingHandler) UpdateConfig(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	var req service.OnboardingConfig
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	config, err := h.onboardingService.ForTenant(tenantID(c)).UpdateConfig(adminID.(uint), req)
+	if err != nil {
+		switch err {
+		case service.ErrInvalidOnboardingStep:
+			response.BadRequest(c, "无效的新手任务配置")
+		default:
+			response.InternalError(c, "更新新手任务配置失败", err.Error())
+		}
+		return
+	}
+
+	response.Success(c, config)
+}
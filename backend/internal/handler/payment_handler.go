@@ -1,14 +1,21 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
 	"scratch-lottery/internal/service"
 	"scratch-lottery/pkg/response"
 
+	"github.com/gin-contrib/sse"
 	"github.com/gin-gonic/gin"
 )
 
+// orderWatchMaxDuration bounds how long an order subscription stays open;
+// clients reconnect or fall back to polling after it
+const orderWatchMaxDuration = 30 * time.Minute
+
 // PaymentHandler handles payment-related endpoints
 type PaymentHandler struct {
 	paymentService *service.PaymentService
@@ -34,7 +41,7 @@ func (h *PaymentHandler) CreateRechargeOrder(c *gin.Context) {
 		return
 	}
 
-	result, err := h.paymentService.CreateRechargeOrder(userID.(uint), req)
+	result, err := h.paymentService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).CreateRechargeOrder(userID.(uint), req)
 	if err != nil {
 		switch err {
 		case service.ErrPaymentDisabled:
@@ -42,7 +49,7 @@ func (h *PaymentHandler) CreateRechargeOrder(c *gin.Context) {
 		case service.ErrPaymentConfigError:
 			response.InternalError(c, "支付配置错误", "请联系管理员")
 		case service.ErrPaymentInvalidAmount:
-			response.BadRequest(c, "充值金额无效", "充值金额必须在1-10000元之间")
+			response.BadRequest(c, "充值金额无效", "请选择可用的充值面额或符合规则的金额")
 		default:
 			response.InternalError(c, "创建订单失败", err.Error())
 		}
@@ -52,27 +59,92 @@ func (h *PaymentHandler) CreateRechargeOrder(c *gin.Context) {
 	response.Success(c, result)
 }
 
-// PaymentCallback handles payment callback from EPay
-// POST /api/payment/callback
+// GetRechargeOptions returns the recharge amount rules for the recharge page
+// GET /api/payment/recharge-options
+func (h *PaymentHandler) GetRechargeOptions(c *gin.Context) {
+	rules, err := h.paymentService.ForTenant(tenantID(c)).GetRechargeRules()
+	if err != nil {
+		response.InternalError(c, "获取充值选项失败", err.Error())
+		return
+	}
+
+	response.Success(c, rules)
+}
+
+// GetRechargeRules returns the recharge amount rules
+// GET /api/admin/settings/recharge
+func (h *PaymentHandler) GetRechargeRules(c *gin.Context) {
+	rules, err := h.paymentService.ForTenant(tenantID(c)).GetRechargeRules()
+	if err != nil {
+		response.InternalError(c, "获取充值规则失败", err.Error())
+		return
+	}
+
+	response.Success(c, rules)
+}
+
+// UpdateRechargeRules replaces the recharge amount rules
+// PUT /api/admin/settings/recharge
+func (h *PaymentHandler) UpdateRechargeRules(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	var req service.RechargeRules
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	rules, err := h.paymentService.ForTenant(tenantID(c)).UpdateRechargeRules(adminID.(uint), req)
+	if err != nil {
+		switch err {
+		case service.ErrInvalidRechargeRules:
+			response.BadRequest(c, "无效的充值规则")
+		default:
+			response.InternalError(c, "更新充值规则失败", err.Error())
+		}
+		return
+	}
+
+	response.Success(c, rules)
+}
+
+// PaymentCallback handles payment notifications from a payment gateway
+// POST /api/payment/callback/:gateway
 func (h *PaymentHandler) PaymentCallback(c *gin.Context) {
-	var callback service.PaymentCallbackRequest
-	
-	// Try to bind from form data first (EPay typically uses form)
-	if err := c.ShouldBind(&callback); err != nil {
-		// Try JSON as fallback
-		if err := c.ShouldBindJSON(&callback); err != nil {
+	gateway := c.Param("gateway")
+	if gateway == "" {
+		gateway = service.PaymentGatewayEPay // The original callback URL
+	}
+
+	// EPay sends form data or a query string; accept JSON as a fallback
+	params := map[string]string{}
+	if err := c.Request.ParseForm(); err == nil {
+		for k, v := range c.Request.Form {
+			if len(v) > 0 {
+				params[k] = v[0]
+			}
+		}
+	}
+	if len(params) == 0 {
+		if err := c.ShouldBindJSON(&params); err != nil {
 			c.String(http.StatusOK, "fail")
 			return
 		}
 	}
 
-	err := h.paymentService.ProcessCallback(callback)
+	err := h.paymentService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).ProcessGatewayCallback(gateway, params)
 	if err != nil {
 		switch err {
 		case service.ErrInvalidSignature:
 			c.String(http.StatusOK, "fail")
 		case service.ErrOrderNotFound:
 			c.String(http.StatusOK, "fail")
+		case service.ErrPaymentAmountMismatch:
+			c.String(http.StatusOK, "fail")
 		case service.ErrOrderAlreadyPaid:
 			// Already processed, return success to prevent retry
 			c.String(http.StatusOK, "success")
@@ -82,10 +154,68 @@ func (h *PaymentHandler) PaymentCallback(c *gin.Context) {
 		return
 	}
 
-	// Return success to EPay
+	// Return success to the gateway
 	c.String(http.StatusOK, "success")
 }
 
+// SyncOrder asks the gateway of a pending order whether it has been paid
+// POST /api/admin/payment-orders/:order_no/sync
+func (h *PaymentHandler) SyncOrder(c *gin.Context) {
+	order, err := h.paymentService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).SyncOrder(c.Param("order_no"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrOrderNotFound):
+			response.NotFound(c, "订单不存在")
+		case errors.Is(err, service.ErrPaymentConfigError):
+			response.InternalError(c, "支付配置错误", "请检查支付网关设置")
+		case errors.Is(err, service.ErrUnknownPaymentGateway):
+			response.BadRequest(c, "订单的支付网关不可用", err.Error())
+		case errors.Is(err, service.ErrGatewayQueryFailed):
+			response.Error(c, http.StatusBadGateway, response.ErrPaymentFailed, "查询支付网关失败", err.Error())
+		case errors.Is(err, service.ErrPaymentAmountMismatch):
+			response.Error(c, http.StatusConflict, response.ErrPaymentFailed, "网关支付金额与订单不符")
+		default:
+			response.InternalError(c, "同步订单失败", err.Error())
+		}
+		return
+	}
+
+	response.Success(c, order)
+}
+
+// ConfirmManualOrder marks a pending manual order paid
+// POST /api/admin/payment-orders/:order_no/confirm
+func (h *PaymentHandler) ConfirmManualOrder(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	var req service.ConfirmManualOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	order, err := h.paymentService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).ConfirmManualOrder(adminID.(uint), c.Param("order_no"), req)
+	if err != nil {
+		switch err {
+		case service.ErrOrderNotFound:
+			response.NotFound(c, "订单不存在")
+		case service.ErrOrderNotManual:
+			response.BadRequest(c, "该订单不是人工支付订单")
+		case service.ErrOrderNotPending:
+			response.Error(c, http.StatusConflict, response.ErrPaymentFailed, "订单已处理")
+		default:
+			response.InternalError(c, "确认订单失败", err.Error())
+		}
+		return
+	}
+
+	response.Success(c, order)
+}
+
 // GetOrderStatus gets the status of a payment order
 // GET /api/payment/orders/:order_no
 func (h *PaymentHandler) GetOrderStatus(c *gin.Context) {
@@ -101,7 +231,7 @@ func (h *PaymentHandler) GetOrderStatus(c *gin.Context) {
 		return
 	}
 
-	order, err := h.paymentService.GetOrderByNo(orderNo)
+	order, err := h.paymentService.ForTenant(tenantID(c)).GetOrderByNo(orderNo)
 	if err != nil {
 		switch err {
 		case service.ErrOrderNotFound:
@@ -119,6 +249,75 @@ func (h *PaymentHandler) GetOrderStatus(c *gin.Context) {
 	response.Success(c, order)
 }
 
+// WatchOrder streams the status of one of the current user's orders over
+// SSE: once on connect and again on every change, closing once the order is
+// no longer pending
+// GET /api/payment/orders/:order_no/events
+func (h *PaymentHandler) WatchOrder(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	orderNo := c.Param("order_no")
+	if orderNo == "" {
+		response.BadRequest(c, "订单号不能为空")
+		return
+	}
+
+	current, updates, cancel, err := h.paymentService.ForTenant(tenantID(c)).WatchOrder(userID.(uint), orderNo)
+	if err != nil {
+		switch err {
+		case service.ErrOrderNotFound:
+			response.NotFound(c, "订单不存在")
+		case service.ErrTooManyOrderWatches:
+			response.TooManyRequests(c, "订阅数量过多，请关闭其他页面后重试")
+		default:
+			response.InternalError(c, "获取订单失败", err.Error())
+		}
+		return
+	}
+	defer cancel()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	send := func(order *service.OrderResponse) bool {
+		c.Render(-1, sse.Event{Event: "status", Data: order})
+		c.Writer.Flush()
+		return !service.OrderFinal(order.Status)
+	}
+
+	if !send(current) {
+		return
+	}
+
+	keepAlive := time.NewTicker(scratchStreamKeepAlive)
+	defer keepAlive.Stop()
+	deadline := time.NewTimer(orderWatchMaxDuration)
+	defer deadline.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-deadline.C:
+			return
+		case order, ok := <-updates:
+			if !ok || !send(order) {
+				return
+			}
+		case <-keepAlive.C:
+			_, _ = c.Writer.WriteString(": keep-alive\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
+
 // GetUserOrders gets the user's payment orders
 // GET /api/payment/orders
 func (h *PaymentHandler) GetUserOrders(c *gin.Context) {
@@ -137,7 +336,7 @@ func (h *PaymentHandler) GetUserOrders(c *gin.Context) {
 		return
 	}
 
-	orders, total, err := h.paymentService.GetUserOrders(userID.(uint), query.Page, query.Limit)
+	orders, total, err := h.paymentService.ForTenant(tenantID(c)).GetUserOrders(userID.(uint), query.Page, query.Limit)
 	if err != nil {
 		response.InternalError(c, "获取订单列表失败", err.Error())
 		return
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"strconv"
+
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PaymentReconciliationHandler handles payment orders that disagree with their gateway
+type PaymentReconciliationHandler struct {
+	reconciliationService *service.PaymentReconciliationService
+}
+
+// NewPaymentReconciliationHandler creates a new payment reconciliation handler
+func NewPaymentReconciliationHandler(reconciliationService *service.PaymentReconciliationService) *PaymentReconciliationHandler {
+	return &PaymentReconciliationHandler{reconciliationService: reconciliationService}
+}
+
+// GetReconciliations lists payment reconciliations
+// GET /api/admin/payment-reconciliations
+func (h *PaymentReconciliationHandler) GetReconciliations(c *gin.Context) {
+	var query service.PaymentReconciliationQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	result, err := h.reconciliationService.ForTenant(tenantID(c)).GetReconciliations(query)
+	if err != nil {
+		response.InternalError(c, "获取支付对账记录失败", err.Error())
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// RunReconciliation looks up the tenant's stale pending orders at their
+// gateway right away
+// POST /api/admin/payment-reconciliations/run
+func (h *PaymentReconciliationHandler) RunReconciliation(c *gin.Context) {
+	result, err := h.reconciliationService.ForTenant(tenantID(c)).Reconcile()
+	if err != nil {
+		response.InternalError(c, "支付对账失败", err.Error())
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// ResolveReconciliation closes a payment reconciliation after review
+// POST /api/admin/payment-reconciliations/:id/resolve
+func (h *PaymentReconciliationHandler) ResolveReconciliation(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的记录ID")
+		return
+	}
+
+	var req service.ResolvePaymentReconciliationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	reconciliation, err := h.reconciliationService.ForTenant(tenantID(c)).Resolve(adminID.(uint), uint(id), req)
+	if err != nil {
+		switch err {
+		case service.ErrPaymentReconciliationNotFound:
+			response.NotFound(c, "对账记录不存在")
+		case service.ErrPaymentReconciliationResolved:
+			response.BadRequest(c, "对账记录已处理")
+		default:
+			response.InternalError(c, "处理对账记录失败", err.Error())
+		}
+		return
+	}
+
+	response.Success(c, reconciliation)
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"strconv"
+
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PaymentSettingsHandler handles payment credential testing and settings history endpoints
+type PaymentSettingsHandler struct {
+	paymentSettingsService *service.PaymentSettingsService
+}
+
+// NewPaymentSettingsHandler creates a new payment settings handler
+func NewPaymentSettingsHandler(paymentSettingsService *service.PaymentSettingsService) *PaymentSettingsHandler {
+	return &PaymentSettingsHandler{paymentSettingsService: paymentSettingsService}
+}
+
+// TestPaymentSettings checks EPay credentials against the provider without saving them
+// POST /api/admin/settings/payment/test
+func (h *PaymentSettingsHandler) TestPaymentSettings(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	var req service.TestPaymentSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	result, err := h.paymentSettingsService.ForTenant(tenantID(c)).TestConnection(adminID.(uint), req)
+	if err != nil {
+		response.InternalError(c, "测试支付配置失败", err.Error())
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// GetPaymentSettingsVersions returns the payment settings history
+// GET /api/admin/settings/payment/versions
+func (h *PaymentSettingsHandler) GetPaymentSettingsVersions(c *gin.Context) {
+	versions, err := h.paymentSettingsService.ForTenant(tenantID(c)).GetVersions()
+	if err != nil {
+		response.InternalError(c, "获取支付配置历史失败", err.Error())
+		return
+	}
+
+	response.Success(c, versions)
+}
+
+// RollbackPaymentSettings restores a previous version of the payment settings
+// POST /api/admin/settings/payment/versions/:version/rollback
+func (h *PaymentSettingsHandler) RollbackPaymentSettings(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 1 {
+		response.BadRequest(c, "无效的版本号")
+		return
+	}
+
+	settings, err := h.paymentSettingsService.ForTenant(tenantID(c)).Rollback(adminID.(uint), version)
+	if err != nil {
+		switch err {
+		case service.ErrPaymentSettingsVersionNotFound:
+			response.NotFound(c, "支付配置版本不存在")
+		default:
+			response.InternalError(c, "回滚支付配置失败", err.Error())
+		}
+		return
+	}
+
+	response.Success(c, settings)
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"strconv"
+
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PricingHandler handles product pricing endpoints
+type PricingHandler struct {
+	pricingService *service.PricingService
+}
+
+// NewPricingHandler creates a new pricing handler
+func NewPricingHandler(pricingService *service.PricingService) *PricingHandler {
+	return &PricingHandler{pricingService: pricingService}
+}
+
+// SuggestPrice suggests a point price for a product cost
+// GET /api/admin/exchange/price-suggestion?cost=12.50&margin=20
+func (h *PricingHandler) SuggestPrice(c *gin.Context) {
+	var margin *int
+	if value := c.Query("margin"); value != "" {
+		percent, err := strconv.Atoi(value)
+		if err != nil {
+			response.BadRequest(c, "无效的目标毛利率")
+			return
+		}
+		margin = &percent
+	}
+
+	suggestion, err := h.pricingService.ForTenant(tenantID(c)).SuggestPrice(c.Query("cost"), margin)
+	if err != nil {
+		h.pricingError(c, err, "计算建议售价失败")
+		return
+	}
+
+	response.Success(c, suggestion)
+}
+
+// GetConfig returns the product pricing settings
+// GET /api/admin/settings/pricing
+func (h *PricingHandler) GetConfig(c *gin.Context) {
+	config, err := h.pricingService.ForTenant(tenantID(c)).GetConfig()
+	if err != nil {
+		response.InternalError(c, "获取定价配置失败", err.Error())
+		return
+	}
+
+	response.Success(c, config)
+}
+
+// UpdateConfig replaces the product pricing settings
+// PUT /api/admin/settings/pricing
+func (h *PricingHandler) UpdateConfig(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	var req service.PricingConfig
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	config, err := h.pricingService.ForTenant(tenantID(c)).UpdateConfig(adminID.(uint), req)
+	if err != nil {
+		h.pricingError(c, err, "更新定价配置失败")
+		return
+	}
+
+	response.Success(c, config)
+}
+
+func (h *PricingHandler) pricingError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrInvalidProductCost:
+		response.BadRequest(c, "无效的商品成本，请以元为单位填写，最多两位小数")
+	case service.ErrInvalidTargetMargin:
+		response.BadRequest(c, "目标毛利率须在 0 到 95 之间")
+	default:
+		response.InternalError(c, message, err.Error())
+	}
+}
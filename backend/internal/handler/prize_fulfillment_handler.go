@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"strconv"
+
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PrizeFulfillmentHandler handles prizes paid out as exchange products
+type PrizeFulfillmentHandler struct {
+	fulfillmentService *service.PrizeFulfillmentService
+}
+
+// NewPrizeFulfillmentHandler creates a new prize fulfillment handler
+func NewPrizeFulfillmentHandler(fulfillmentService *service.PrizeFulfillmentService) *PrizeFulfillmentHandler {
+	return &PrizeFulfillmentHandler{fulfillmentService: fulfillmentService}
+}
+
+// GetFulfillments lists prizes paid out as exchange products
+// GET /api/admin/exchange/prize-fulfillments
+func (h *PrizeFulfillmentHandler) GetFulfillments(c *gin.Context) {
+	var query service.PrizeFulfillmentQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	result, err := h.fulfillmentService.ForTenant(tenantID(c)).WithRedaction(fieldRedaction(c)).GetFulfillments(query)
+	if err != nil {
+		response.InternalError(c, "获取奖品发放记录失败", err.Error())
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// ApproveFulfillment delivers a pending prize product to its winner
+// POST /api/admin/exchange/prize-fulfillments/:id/approve
+func (h *PrizeFulfillmentHandler) ApproveFulfillment(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的记录ID")
+		return
+	}
+
+	var req service.ApproveFulfillmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	fulfillment, err := h.fulfillmentService.ForTenant(tenantID(c)).WithRedaction(fieldRedaction(c)).Approve(adminID.(uint), uint(id), req)
+	if err != nil {
+		switch err {
+		case service.ErrFulfillmentNotFound:
+			response.NotFound(c, "奖品发放记录不存在")
+		case service.ErrFulfillmentAlreadyDelivered:
+			response.BadRequest(c, "奖品已发放")
+		case service.ErrNoAvailableCardKey, service.ErrProductSoldOut:
+			response.BadRequest(c, "商品无可用卡密，请手动填写卡密")
+		case service.ErrPayoutDisputed:
+			response.Forbidden(c, "该用户有未处理的购票争议，奖品暂缓发放")
+		default:
+			response.InternalError(c, "发放奖品失败", err.Error())
+		}
+		return
+	}
+
+	response.Success(c, fulfillment)
+}
@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"strconv"
+
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PrizeTemplateHandler handles the prize template library
+type PrizeTemplateHandler struct {
+	prizeTemplateService *service.PrizeTemplateService
+}
+
+// NewPrizeTemplateHandler creates a new prize template handler
+func NewPrizeTemplateHandler(prizeTemplateService *service.PrizeTemplateService) *PrizeTemplateHandler {
+	return &PrizeTemplateHandler{prizeTemplateService: prizeTemplateService}
+}
+
+// GetTemplates returns all prize templates
+// GET /api/admin/lottery/prize-templates
+func (h *PrizeTemplateHandler) GetTemplates(c *gin.Context) {
+	templates, err := h.prizeTemplateService.ForTenant(tenantID(c)).GetTemplates()
+	if err != nil {
+		response.InternalError(c, "获取奖级模板失败", err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{"templates": templates})
+}
+
+// GetTemplate returns a prize template by ID
+// GET /api/admin/lottery/prize-templates/:id
+func (h *PrizeTemplateHandler) GetTemplate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的模板ID")
+		return
+	}
+
+	template, err := h.prizeTemplateService.ForTenant(tenantID(c)).GetTemplate(uint(id))
+	if err != nil {
+		h.handleError(c, err, "获取奖级模板失败")
+		return
+	}
+
+	response.Success(c, template)
+}
+
+// CreateTemplate creates a prize template
+// POST /api/admin/lottery/prize-templates
+func (h *PrizeTemplateHandler) CreateTemplate(c *gin.Context) {
+	var req service.PrizeTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	template, err := h.prizeTemplateService.ForTenant(tenantID(c)).CreateTemplate(req)
+	if err != nil {
+		h.handleError(c, err, "创建奖级模板失败")
+		return
+	}
+
+	response.Created(c, template)
+}
+
+// UpdateTemplate replaces a prize template
+// PUT /api/admin/lottery/prize-templates/:id
+func (h *PrizeTemplateHandler) UpdateTemplate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的模板ID")
+		return
+	}
+
+	var req service.PrizeTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	template, err := h.prizeTemplateService.ForTenant(tenantID(c)).UpdateTemplate(uint(id), req)
+	if err != nil {
+		h.handleError(c, err, "更新奖级模板失败")
+		return
+	}
+
+	response.Success(c, template)
+}
+
+// DeleteTemplate deletes a prize template
+// DELETE /api/admin/lottery/prize-templates/:id
+func (h *PrizeTemplateHandler) DeleteTemplate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的模板ID")
+		return
+	}
+
+	if err := h.prizeTemplateService.ForTenant(tenantID(c)).DeleteTemplate(uint(id)); err != nil {
+		h.handleError(c, err, "删除奖级模板失败")
+		return
+	}
+
+	response.Success(c, gin.H{"message": "奖级模板已删除"})
+}
+
+// CreateLotteryType creates a lottery type and its first prize pool from a template
+// POST /api/admin/lottery/prize-templates/:id/lottery-types
+func (h *PrizeTemplateHandler) CreateLotteryType(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的模板ID")
+		return
+	}
+
+	var req service.CreateLotteryTypeFromTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	instance, err := h.prizeTemplateService.ForTenant(tenantID(c)).CreateLotteryType(uint(id), req)
+	if err != nil {
+		h.handleError(c, err, "创建彩票类型失败")
+		return
+	}
+
+	response.Created(c, instance)
+}
+
+// CreatePrizePool applies a template to a lottery type and opens a new prize pool
+// POST /api/admin/lottery/prize-templates/:id/prize-pools
+func (h *PrizeTemplateHandler) CreatePrizePool(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的模板ID")
+		return
+	}
+
+	var req service.CreatePoolFromTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	instance, err := h.prizeTemplateService.ForTenant(tenantID(c)).CreatePrizePool(uint(id), req)
+	if err != nil {
+		h.handleError(c, err, "创建奖池失败")
+		return
+	}
+
+	response.Created(c, instance)
+}
+
+// handleError maps prize template errors to responses
+func (h *PrizeTemplateHandler) handleError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrPrizeTemplateNotFound:
+		response.NotFound(c, "奖级模板不存在")
+	case service.ErrLotteryTypeNotFound:
+		response.NotFound(c, "彩票类型不存在")
+	case service.ErrInvalidPrizeTemplate, service.ErrInvalidPrizeConfig:
+		response.BadRequest(c, "无效的奖级配置")
+	case service.ErrInvalidPrizePayout:
+		response.BadRequest(c, "无效的奖品发放方式，实物奖品须指定有效的兑换商品")
+	case service.ErrInvalidPrizeLevelDisplay:
+		response.BadRequest(c, "无效的奖级展示配置，图片须为 http(s) 链接或站内路径，颜色须为 #RGB 或 #RRGGBB，文案不超过 256 个字符")
+	case service.ErrPrizeLevelsInUse:
+		response.BadRequest(c, "该彩票类型有已售出彩票的奖池正在销售，无法替换奖级")
+	case service.ErrTemplateReturnRate:
+		response.BadRequest(c, "奖级总额超出模板返奖率")
+	case service.ErrTemplateTooManyWinners:
+		response.BadRequest(c, "中奖票数超过奖池票数")
+	case service.ErrInvalidTemplateScale:
+		response.BadRequest(c, "无效的缩放参数")
+	default:
+		response.InternalError(c, message, err.Error())
+	}
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"net/http"
+
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// QuickPlayHandler handles buying and scratching tickets in one request
+type QuickPlayHandler struct {
+	quickPlayService *service.QuickPlayService
+	waitingRoom      *service.WaitingRoomService
+}
+
+// NewQuickPlayHandler creates a new quick play handler
+func NewQuickPlayHandler(quickPlayService *service.QuickPlayService, waitingRoom *service.WaitingRoomService) *QuickPlayHandler {
+	return &QuickPlayHandler{quickPlayService: quickPlayService, waitingRoom: waitingRoom}
+}
+
+// QuickPlay buys tickets and scratches them at once
+// POST /api/lottery/quick-play
+func (h *QuickPlayHandler) QuickPlay(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "请先登录")
+		return
+	}
+
+	var req service.PurchaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+	// Clients may send the request ID as an Idempotency-Key header instead
+	if req.RequestID == "" {
+		req.RequestID = c.GetHeader("Idempotency-Key")
+	}
+
+	if req.Quantity < 1 || req.Quantity > 10 {
+		response.BadRequest(c, "购买数量必须在1-10之间")
+		return
+	}
+
+	quickPlay := h.quickPlayService.ForTenant(tenantID(c)).WithRequest(c.Request.Context())
+
+	// A retried request is answered before it queues again
+	replay, err := quickPlay.ReplayQuickPlay(userID.(uint), req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	if replay != nil {
+		response.Success(c, replay)
+		return
+	}
+
+	// High-demand lottery types admit purchases through the waiting room
+	leave, err := h.waitingRoom.Enter(userID.(uint), req.LotteryTypeID, req.AdmissionToken)
+	if err != nil {
+		switch err {
+		case service.ErrWaitingRoomRequired:
+			response.Error(c, http.StatusTooManyRequests, response.ErrWaitingRoom, "购买人数过多，请先排队")
+		case service.ErrWaitingRoomNotAdmitted:
+			response.Error(c, http.StatusTooManyRequests, response.ErrWaitingRoom, "排队中，请等待放行")
+		default:
+			h.handleError(c, err)
+		}
+		return
+	}
+	defer leave()
+
+	result, err := quickPlay.QuickPlay(userID.(uint), req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+func (h *QuickPlayHandler) handleError(c *gin.Context, err error) {
+	switch err {
+	case service.ErrLotteryTypeNotFound:
+		response.NotFound(c, "彩票类型不存在")
+	case service.ErrQuickPlayUnavailable:
+		response.BadRequest(c, "该彩票需确认或间隔刮奖，不支持一键刮奖")
+	case service.ErrLotteryTypeSoldOut:
+		response.BadRequest(c, "彩票已售罄")
+	case service.ErrInsufficientBalance:
+		response.BadRequest(c, "余额不足")
+	case service.ErrWalletFrozen:
+		response.BadRequest(c, "钱包已冻结，等待管理员核查")
+	case service.ErrNoPrizePoolActive:
+		response.BadRequest(c, "暂无可用奖组")
+	case service.ErrCouponUnavailable:
+		response.BadRequest(c, "优惠券不存在、已使用或已过期")
+	case service.ErrInvalidRequestID:
+		response.BadRequest(c, "请求ID过长")
+	case service.ErrRequestIDReused:
+		response.Error(c, http.StatusConflict, response.ErrPurchaseConflict, "请求ID已用于其他购买")
+	case service.ErrPurchaseInProgress:
+		response.Error(c, http.StatusConflict, response.ErrPurchaseConflict, "相同请求正在处理中，请稍后重试")
+	case service.ErrNumberMatchMismatch, service.ErrMultiplierMismatch:
+		response.InternalError(c, "彩票数据校验失败，请联系客服")
+	default:
+		response.InternalError(c, "一键刮奖失败", err.Error())
+	}
+}
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"strconv"
+
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RefundHandler handles purchase refund endpoints
+type RefundHandler struct {
+	refundService *service.RefundService
+}
+
+// NewRefundHandler creates a new refund handler
+func NewRefundHandler(refundService *service.RefundService) *RefundHandler {
+	return &RefundHandler{refundService: refundService}
+}
+
+// GetRefunds returns a page of refunds and failed purchases
+// GET /api/admin/refunds
+func (h *RefundHandler) GetRefunds(c *gin.Context) {
+	var query service.AdminRefundQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	refunds, err := h.refundService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).GetRefunds(query)
+	if err != nil {
+		response.InternalError(c, "获取退款记录失败", err.Error())
+		return
+	}
+
+	response.Success(c, refunds)
+}
+
+// RefundPurchase refunds a completed purchase to its buyer
+// POST /api/admin/refunds
+func (h *RefundHandler) RefundPurchase(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	var req service.RefundPurchaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	refund, err := h.refundService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).RefundPurchase(adminID.(uint), req)
+	if err != nil {
+		h.refundError(c, err, "退款失败")
+		return
+	}
+
+	response.Success(c, refund)
+}
+
+// ApproveRefund credits a pending refund
+// POST /api/admin/refunds/:id/approve
+func (h *RefundHandler) ApproveRefund(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的退款ID")
+		return
+	}
+
+	refund, err := h.refundService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).ApproveRefund(adminID.(uint), uint(id))
+	if err != nil {
+		h.refundError(c, err, "审核退款失败")
+		return
+	}
+
+	response.Success(c, refund)
+}
+
+// RejectRefund refuses a pending refund
+// POST /api/admin/refunds/:id/reject
+func (h *RefundHandler) RejectRefund(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的退款ID")
+		return
+	}
+
+	var req service.RejectRefundRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	refund, err := h.refundService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).RejectRefund(adminID.(uint), uint(id), req)
+	if err != nil {
+		h.refundError(c, err, "审核退款失败")
+		return
+	}
+
+	response.Success(c, refund)
+}
+
+func (h *RefundHandler) refundError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrRefundNotFound:
+		response.NotFound(c, "退款记录不存在")
+	case service.ErrPurchaseNotFound:
+		response.NotFound(c, "购票记录不存在")
+	case service.ErrWalletNotFound:
+		response.NotFound(c, "钱包不存在")
+	case service.ErrRefundNotPending:
+		response.BadRequest(c, "该退款不在待审核状态")
+	case service.ErrInvalidRefund:
+		response.BadRequest(c, "退款金额不能超过购票未退部分，原因不超过255字")
+	case service.ErrPurchasePlayed:
+		response.BadRequest(c, "该笔购票已有彩票刮开，不能退款")
+	default:
+		response.InternalError(c, message, err.Error())
+	}
+}
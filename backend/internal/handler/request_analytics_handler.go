@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestAnalyticsHandler handles the API usage analytics of admins and partners
+type RequestAnalyticsHandler struct {
+	analyticsService *service.RequestAnalyticsService
+}
+
+// NewRequestAnalyticsHandler creates a new request analytics handler
+func NewRequestAnalyticsHandler(analyticsService *service.RequestAnalyticsService) *RequestAnalyticsHandler {
+	return &RequestAnalyticsHandler{analyticsService: analyticsService}
+}
+
+// GetRequestAnalytics returns the busiest routes with error rates and p95
+// latency, an hourly timeline and the busiest users of a date range
+// GET /api/admin/analytics/requests
+func (h *RequestAnalyticsHandler) GetRequestAnalytics(c *gin.Context) {
+	var query service.RequestAnalyticsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	result, err := h.analyticsService.ForTenant(tenantID(c)).GetRequestAnalytics(query)
+	if err != nil {
+		response.InternalError(c, "获取接口统计失败", err.Error())
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// GetPartnerUsage returns the request counts, error rate and rate limit hits
+// of the calling partner's API key per route and per hour
+// GET /api/partner/usage
+func (h *RequestAnalyticsHandler) GetPartnerUsage(c *gin.Context) {
+	var query service.APIKeyUsageQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	result, err := h.analyticsService.GetAPIKeyUsage(c.GetString("apiKey"), query)
+	if err != nil {
+		response.InternalError(c, "获取接口用量失败", err.Error())
+		return
+	}
+
+	response.Success(c, result)
+}
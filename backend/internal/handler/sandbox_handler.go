@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SandboxHandler handles sandbox tenant endpoints
+type SandboxHandler struct {
+	sandboxService *service.SandboxService
+}
+
+// NewSandboxHandler creates a new sandbox handler
+func NewSandboxHandler(sandboxService *service.SandboxService) *SandboxHandler {
+	return &SandboxHandler{sandboxService: sandboxService}
+}
+
+// ResetSandbox wipes the activity of the current sandbox tenant and gives
+// every wallet the starting balance again
+// POST /api/admin/sandbox/reset
+func (h *SandboxHandler) ResetSandbox(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	result, err := h.sandboxService.Reset(adminID.(uint), tenantID(c))
+	if err != nil {
+		switch err {
+		case service.ErrNotSandboxTenant:
+			response.BadRequest(c, "当前租户不是沙盒租户")
+		default:
+			response.InternalError(c, "重置沙盒失败", err.Error())
+		}
+		return
+	}
+
+	response.Success(c, result)
+}
@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+// scratchStreamKeepAlive is the interval between SSE keep-alive comments
+const scratchStreamKeepAlive = 15 * time.Second
+
+// ScratchStreamHandler handles incremental (area by area) scratching endpoints
+type ScratchStreamHandler struct {
+	incrementalScratchService *service.IncrementalScratchService
+}
+
+// NewScratchStreamHandler creates a new scratch stream handler
+func NewScratchStreamHandler(incrementalScratchService *service.IncrementalScratchService) *ScratchStreamHandler {
+	return &ScratchStreamHandler{incrementalScratchService: incrementalScratchService}
+}
+
+// ScratchArea reveals a single area of a ticket
+// POST /api/lottery/scratch/:id/areas/:index
+func (h *ScratchStreamHandler) ScratchArea(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "请先登录")
+		return
+	}
+
+	ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的彩票ID")
+		return
+	}
+	areaIndex, err := strconv.Atoi(c.Param("index"))
+	if err != nil {
+		response.BadRequest(c, "无效的刮奖区域")
+		return
+	}
+
+	var req ScratchConfirmRequest
+	_ = c.ShouldBindJSON(&req)
+
+	event, err := h.incrementalScratchService.ScratchArea(userID.(uint), uint(ticketID), areaIndex, req.ConfirmToken)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Success(c, event)
+}
+
+// SettleTicket finishes an incrementally scratched ticket and credits any prize
+// POST /api/lottery/scratch/:id/settle
+func (h *ScratchStreamHandler) SettleTicket(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "请先登录")
+		return
+	}
+
+	ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的彩票ID")
+		return
+	}
+
+	var req ScratchConfirmRequest
+	_ = c.ShouldBindJSON(&req)
+
+	result, err := h.incrementalScratchService.Settle(userID.(uint), uint(ticketID), req.ConfirmToken)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// StreamScratch streams partial scratch results of a ticket session over SSE.
+// Reconnecting clients send Last-Event-ID (or ?last_event_id) to replay missed events.
+// GET /api/lottery/scratch/:id/stream
+func (h *ScratchStreamHandler) StreamScratch(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "请先登录")
+		return
+	}
+
+	ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的彩票ID")
+		return
+	}
+
+	lastSeq := 0
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	if lastEventID != "" {
+		if n, err := strconv.Atoi(lastEventID); err == nil && n > 0 {
+			lastSeq = n
+		}
+	}
+
+	// Subscribe before replaying so no event falls between replay and live stream
+	live, cancel := h.incrementalScratchService.Hub().Subscribe(uint(ticketID))
+	defer cancel()
+
+	replay, err := h.incrementalScratchService.GetScratchEvents(userID.(uint), uint(ticketID), lastSeq)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	send := func(event service.ScratchEvent) bool {
+		if event.Seq <= lastSeq {
+			return true
+		}
+		lastSeq = event.Seq
+		c.Render(-1, sse.Event{
+			Id:    strconv.Itoa(event.Seq),
+			Event: event.Type,
+			Data:  event,
+		})
+		c.Writer.Flush()
+		return event.Type != service.ScratchEventSettled
+	}
+
+	for _, event := range replay {
+		if !send(event) {
+			return
+		}
+	}
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(scratchStreamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-live:
+			if !ok || !send(event) {
+				return
+			}
+		case <-keepAlive.C:
+			_, _ = c.Writer.WriteString(": keep-alive\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
+
+func (h *ScratchStreamHandler) handleError(c *gin.Context, err error) {
+	if respondScratchConfirmation(c, err) || respondScratchTooSoon(c, err) {
+		return
+	}
+	switch err {
+	case service.ErrTicketNotFound:
+		response.NotFound(c, "彩票不存在")
+	case service.ErrTicketNotOwned:
+		response.Forbidden(c, "无权操作此彩票")
+	case service.ErrTicketAlreadyScratched:
+		response.BadRequest(c, "彩票已刮开")
+	case service.ErrTicketVoided:
+		response.BadRequest(c, "彩票已作废")
+	case service.ErrInvalidAreaIndex:
+		response.BadRequest(c, "无效的刮奖区域")
+	case service.ErrTicketHasNoAreas:
+		response.BadRequest(c, "该彩票不支持分区刮奖")
+	case service.ErrNumberMatchMismatch, service.ErrMultiplierMismatch:
+		response.InternalError(c, "彩票数据校验失败，请联系客服")
+	default:
+		response.InternalError(c, "刮奖失败", err.Error())
+	}
+}
@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"strconv"
+
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SegmentHandler handles user segment endpoints
+type SegmentHandler struct {
+	segmentService *service.SegmentService
+}
+
+// NewSegmentHandler creates a new segment handler
+func NewSegmentHandler(segmentService *service.SegmentService) *SegmentHandler {
+	return &SegmentHandler{segmentService: segmentService}
+}
+
+// GetSegments returns all user segments
+// GET /api/admin/segments
+func (h *SegmentHandler) GetSegments(c *gin.Context) {
+	segments, err := h.segmentService.ForTenant(tenantID(c)).GetSegments()
+	if err != nil {
+		response.InternalError(c, "获取用户分群失败", err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{"segments": segments})
+}
+
+// GetSegment returns a user segment by ID
+// GET /api/admin/segments/:id
+func (h *SegmentHandler) GetSegment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的分群ID")
+		return
+	}
+
+	segment, err := h.segmentService.ForTenant(tenantID(c)).GetSegment(uint(id))
+	if err != nil {
+		h.handleError(c, err, "获取用户分群失败")
+		return
+	}
+
+	response.Success(c, segment)
+}
+
+// CreateSegment creates a user segment and evaluates its members
+// POST /api/admin/segments
+func (h *SegmentHandler) CreateSegment(c *gin.Context) {
+	var req service.SegmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	segment, err := h.segmentService.ForTenant(tenantID(c)).CreateSegment(req)
+	if err != nil {
+		h.handleError(c, err, "创建用户分群失败")
+		return
+	}
+
+	response.Created(c, segment)
+}
+
+// UpdateSegment replaces a user segment and re-evaluates its members
+// PUT /api/admin/segments/:id
+func (h *SegmentHandler) UpdateSegment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的分群ID")
+		return
+	}
+
+	var req service.SegmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	segment, err := h.segmentService.ForTenant(tenantID(c)).UpdateSegment(uint(id), req)
+	if err != nil {
+		h.handleError(c, err, "更新用户分群失败")
+		return
+	}
+
+	response.Success(c, segment)
+}
+
+// DeleteSegment deletes a user segment
+// DELETE /api/admin/segments/:id
+func (h *SegmentHandler) DeleteSegment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的分群ID")
+		return
+	}
+
+	if err := h.segmentService.ForTenant(tenantID(c)).DeleteSegment(uint(id)); err != nil {
+		h.handleError(c, err, "删除用户分群失败")
+		return
+	}
+
+	response.Success(c, gin.H{"message": "用户分群已删除"})
+}
+
+// EvaluateSegment re-evaluates the members of a user segment now
+// POST /api/admin/segments/:id/evaluate
+func (h *SegmentHandler) EvaluateSegment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的分群ID")
+		return
+	}
+
+	segment, err := h.segmentService.ForTenant(tenantID(c)).Evaluate(uint(id))
+	if err != nil {
+		h.handleError(c, err, "计算分群成员失败")
+		return
+	}
+
+	response.Success(c, segment)
+}
+
+// GetMembers returns a page of the members of a user segment
+// GET /api/admin/segments/:id/members
+func (h *SegmentHandler) GetMembers(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的分群ID")
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	members, err := h.segmentService.ForTenant(tenantID(c)).GetMembers(uint(id), page, limit)
+	if err != nil {
+		h.handleError(c, err, "获取分群成员失败")
+		return
+	}
+
+	response.Success(c, members)
+}
+
+// NotifySegment sends a notification to every member of a user segment
+// POST /api/admin/segments/:id/notify
+func (h *SegmentHandler) NotifySegment(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的分群ID")
+		return
+	}
+
+	var req service.SegmentNotificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	notified, err := h.segmentService.ForTenant(tenantID(c)).NotifyMembers(adminID.(uint), uint(id), req)
+	if err != nil {
+		h.handleError(c, err, "发送分群通知失败")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"notified": notified,
+		"message":  "通知已发送",
+	})
+}
+
+func (h *SegmentHandler) handleError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrSegmentNotFound:
+		response.NotFound(c, "用户分群不存在")
+	case service.ErrInvalidSegmentRules:
+		response.BadRequest(c, "无效的分群规则")
+	case service.ErrInvalidSegmentNotification:
+		response.BadRequest(c, "通知标题不能超过128个字符，内容不能超过1024个字符")
+	default:
+		response.InternalError(c, message, err.Error())
+	}
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"fmt"
+
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StatusHandler handles status page endpoints
+type StatusHandler struct {
+	statusService *service.StatusService
+	cacheSeconds  int
+}
+
+// NewStatusHandler creates a new status handler. The public status may be
+// cached by clients for cacheSeconds.
+func NewStatusHandler(statusService *service.StatusService, cacheSeconds int) *StatusHandler {
+	return &StatusHandler{statusService: statusService, cacheSeconds: cacheSeconds}
+}
+
+// GetStatus returns component health, uptime and the incident banner
+// GET /api/system/status
+func (h *StatusHandler) GetStatus(c *gin.Context) {
+	status, err := h.statusService.ForTenant(tenantID(c)).GetStatus()
+	if err != nil {
+		response.InternalError(c, "获取系统状态失败", err.Error())
+		return
+	}
+
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", h.cacheSeconds))
+	c.Header("Vary", "Host, X-Tenant")
+	response.Success(c, status)
+}
+
+// GetIncident returns the incident banner of the status page
+// GET /api/admin/settings/incident
+func (h *StatusHandler) GetIncident(c *gin.Context) {
+	incident, err := h.statusService.ForTenant(tenantID(c)).GetIncident()
+	if err != nil {
+		response.InternalError(c, "获取公告失败", err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{"incident": incident})
+}
+
+// UpdateIncident sets or clears the incident banner of the status page
+// PUT /api/admin/settings/incident
+func (h *StatusHandler) UpdateIncident(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	var req service.UpdateStatusIncidentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	incident, err := h.statusService.ForTenant(tenantID(c)).UpdateIncident(adminID.(uint), req)
+	if err != nil {
+		if err == service.ErrInvalidIncident {
+			response.BadRequest(c, "公告最多500个字符，级别应为 info、minor 或 major")
+			return
+		}
+		response.InternalError(c, "更新公告失败", err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{"incident": incident})
+}
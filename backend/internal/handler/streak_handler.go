@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StreakHandler handles scratch streak endpoints
+type StreakHandler struct {
+	streakService *service.StreakService
+}
+
+// NewStreakHandler creates a new streak handler
+func NewStreakHandler(streakService *service.StreakService) *StreakHandler {
+	return &StreakHandler{streakService: streakService}
+}
+
+// GetStreakRules returns the streak reward rules
+// GET /api/admin/settings/streaks
+func (h *StreakHandler) GetStreakRules(c *gin.Context) {
+	rules, err := h.streakService.ForTenant(tenantID(c)).GetRules()
+	if err != nil {
+		response.InternalError(c, "获取连续刮奖奖励规则失败", err.Error())
+		return
+	}
+
+	response.Success(c, service.StreakRulesResponse{Rules: rules})
+}
+
+// UpdateStreakRules replaces the streak reward rules
+// PUT /api/admin/settings/streaks
+func (h *StreakHandler) UpdateStreakRules(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	var req service.UpdateStreakRulesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	rules, err := h.streakService.ForTenant(tenantID(c)).UpdateRules(adminID.(uint), req)
+	if err != nil {
+		switch err {
+		case service.ErrInvalidStreakRule:
+			response.BadRequest(c, "无效的连续刮奖奖励规则")
+		default:
+			response.InternalError(c, "更新连续刮奖奖励规则失败", err.Error())
+		}
+		return
+	}
+
+	response.Success(c, rules)
+}
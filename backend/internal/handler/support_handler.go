@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"strconv"
+
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SupportHandler handles the issues users report to support
+type SupportHandler struct {
+	supportService *service.SupportService
+}
+
+// NewSupportHandler creates a new support handler
+func NewSupportHandler(supportService *service.SupportService) *SupportHandler {
+	return &SupportHandler{supportService: supportService}
+}
+
+// CreateIssue files an issue for the current user
+// POST /api/user/support
+func (h *SupportHandler) CreateIssue(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	var req service.CreateSupportIssueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	issue, err := h.supportService.ForTenant(tenantID(c)).CreateIssue(userID.(uint), req)
+	if err != nil {
+		h.handleError(c, err, "提交问题失败")
+		return
+	}
+
+	response.Created(c, issue)
+}
+
+// GetMyIssues returns the issues of the current user, newest first
+// GET /api/user/support
+func (h *SupportHandler) GetMyIssues(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	issues, err := h.supportService.ForTenant(tenantID(c)).GetUserIssues(userID.(uint), page, limit)
+	if err != nil {
+		h.handleError(c, err, "获取问题列表失败")
+		return
+	}
+
+	response.Success(c, issues)
+}
+
+// GetMyIssue returns an issue of the current user
+// GET /api/user/support/:id
+func (h *SupportHandler) GetMyIssue(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	issueID, ok := parseSupportIssueID(c)
+	if !ok {
+		return
+	}
+
+	issue, err := h.supportService.ForTenant(tenantID(c)).GetUserIssue(userID.(uint), issueID)
+	if err != nil {
+		h.handleError(c, err, "获取问题失败")
+		return
+	}
+
+	response.Success(c, issue)
+}
+
+// ListIssues returns issues for triage, unresolved and earliest deadline first
+// GET /api/admin/support/issues
+func (h *SupportHandler) ListIssues(c *gin.Context) {
+	var query service.SupportIssueQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	issues, err := h.supportService.ForTenant(tenantID(c)).ListIssues(query)
+	if err != nil {
+		h.handleError(c, err, "获取问题列表失败")
+		return
+	}
+
+	response.Success(c, issues)
+}
+
+// GetIssue returns an issue
+// GET /api/admin/support/issues/:id
+func (h *SupportHandler) GetIssue(c *gin.Context) {
+	issueID, ok := parseSupportIssueID(c)
+	if !ok {
+		return
+	}
+
+	issue, err := h.supportService.ForTenant(tenantID(c)).GetIssue(issueID)
+	if err != nil {
+		h.handleError(c, err, "获取问题失败")
+		return
+	}
+
+	response.Success(c, issue)
+}
+
+// UpdateIssue changes the status, priority, assignee or links of an issue
+// PUT /api/admin/support/issues/:id
+func (h *SupportHandler) UpdateIssue(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	issueID, ok := parseSupportIssueID(c)
+	if !ok {
+		return
+	}
+
+	var req service.UpdateSupportIssueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	issue, err := h.supportService.ForTenant(tenantID(c)).UpdateIssue(adminID.(uint), issueID, req)
+	if err != nil {
+		h.handleError(c, err, "更新问题失败")
+		return
+	}
+
+	response.Success(c, issue)
+}
+
+// GetSLA returns the SLA hours of each priority
+// GET /api/admin/support/sla
+func (h *SupportHandler) GetSLA(c *gin.Context) {
+	sla, err := h.supportService.ForTenant(tenantID(c)).GetSLA()
+	if err != nil {
+		h.handleError(c, err, "获取 SLA 设置失败")
+		return
+	}
+
+	response.Success(c, sla)
+}
+
+// UpdateSLA replaces the SLA hours of each priority
+// PUT /api/admin/support/sla
+func (h *SupportHandler) UpdateSLA(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	var req service.SupportSLA
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	sla, err := h.supportService.ForTenant(tenantID(c)).UpdateSLA(adminID.(uint), req)
+	if err != nil {
+		h.handleError(c, err, "更新 SLA 设置失败")
+		return
+	}
+
+	response.Success(c, sla)
+}
+
+// parseSupportIssueID reads the issue ID of a support route
+func parseSupportIssueID(c *gin.Context) (uint, bool) {
+	issueID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的问题ID")
+		return 0, false
+	}
+	return uint(issueID), true
+}
+
+func (h *SupportHandler) handleError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrSupportIssueNotFound:
+		response.NotFound(c, "问题不存在")
+	case service.ErrInvalidSupportIssue:
+		response.BadRequest(c, "问题分类、状态或优先级无效")
+	case service.ErrSupportLinkNotFound:
+		response.BadRequest(c, "关联的订单、彩票或兑换记录不存在")
+	case service.ErrTooManySupportIssues:
+		response.TooManyRequests(c, "未解决的问题过多，请等待处理后再提交")
+	case service.ErrInvalidSupportSLA:
+		response.BadRequest(c, "SLA 时长须在 1 到 720 小时之间")
+	default:
+		response.InternalError(c, message, err.Error())
+	}
+}
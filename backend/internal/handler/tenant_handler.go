@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"strconv"
+
+	"scratch-lottery/internal/repository"
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tenantID returns the tenant resolved for the request by TenantMiddleware
+func tenantID(c *gin.Context) uint {
+	if value, exists := c.Get("tenantID"); exists {
+		if id, ok := value.(uint); ok && id != 0 {
+			return id
+		}
+	}
+	return repository.DefaultTenantID
+}
+
+// fieldRedaction returns the response redaction FieldRedactionMiddleware
+// resolved for the request, which redacts nothing outside the admin API
+func fieldRedaction(c *gin.Context) service.FieldRedaction {
+	if value, exists := c.Get("fieldRedaction"); exists {
+		if redaction, ok := value.(service.FieldRedaction); ok {
+			return redaction
+		}
+	}
+	return service.FieldRedaction{}
+}
+
+// TenantHandler handles tenant management for the deployment operator
+type TenantHandler struct {
+	tenantService *service.TenantService
+}
+
+// NewTenantHandler creates a new tenant handler
+func NewTenantHandler(tenantService *service.TenantService) *TenantHandler {
+	return &TenantHandler{tenantService: tenantService}
+}
+
+// GetTenants returns all tenants
+// GET /api/admin/tenants
+func (h *TenantHandler) GetTenants(c *gin.Context) {
+	tenants, err := h.tenantService.ListTenants()
+	if err != nil {
+		response.InternalError(c, "获取租户列表失败", err.Error())
+		return
+	}
+
+	response.Success(c, tenants)
+}
+
+// CreateTenant creates a new tenant
+// POST /api/admin/tenants
+func (h *TenantHandler) CreateTenant(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	var req service.TenantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	tenant, err := h.tenantService.CreateTenant(adminID.(uint), req)
+	if err != nil {
+		h.handleError(c, err, "创建租户失败")
+		return
+	}
+
+	response.Created(c, tenant)
+}
+
+// UpdateTenant updates a tenant
+// PUT /api/admin/tenants/:id
+func (h *TenantHandler) UpdateTenant(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的租户ID")
+		return
+	}
+
+	var req service.TenantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	tenant, err := h.tenantService.UpdateTenant(adminID.(uint), uint(id), req)
+	if err != nil {
+		h.handleError(c, err, "更新租户失败")
+		return
+	}
+
+	response.Success(c, tenant)
+}
+
+// AssignTenantAdmin makes a user admin of a tenant
+// POST /api/admin/tenants/:id/admins
+func (h *TenantHandler) AssignTenantAdmin(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的租户ID")
+		return
+	}
+
+	var req service.AssignTenantAdminRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	user, err := h.tenantService.AssignTenantAdmin(adminID.(uint), uint(id), req)
+	if err != nil {
+		h.handleError(c, err, "设置租户管理员失败")
+		return
+	}
+
+	response.Success(c, user)
+}
+
+func (h *TenantHandler) handleError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrTenantNotFound:
+		response.NotFound(c, "租户不存在")
+	case service.ErrTenantNameEmpty:
+		response.BadRequest(c, "租户名称不能为空")
+	case service.ErrInvalidTenantSlug:
+		response.BadRequest(c, "租户标识无效，仅支持小写字母、数字和连字符")
+	case service.ErrTenantSlugTaken:
+		response.BadRequest(c, "租户标识已被使用")
+	case service.ErrTenantDomainTaken:
+		response.BadRequest(c, "租户域名已被使用")
+	case service.ErrDefaultTenant:
+		response.BadRequest(c, "默认租户不能停用")
+	case service.ErrDefaultSandbox:
+		response.BadRequest(c, "默认租户不能设为沙盒")
+	default:
+		response.InternalError(c, message, err.Error())
+	}
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"strconv"
+
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TicketEventHandler handles ticket lifecycle event endpoints
+type TicketEventHandler struct {
+	ticketEventService *service.TicketEventService
+}
+
+// NewTicketEventHandler creates a new ticket event handler
+func NewTicketEventHandler(ticketEventService *service.TicketEventService) *TicketEventHandler {
+	return &TicketEventHandler{ticketEventService: ticketEventService}
+}
+
+// GetEvents returns the lifecycle event stream of a ticket
+// GET /api/admin/lottery/tickets/:id/events
+func (h *TicketEventHandler) GetEvents(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的彩票ID")
+		return
+	}
+
+	events, err := h.ticketEventService.ForTenant(tenantID(c)).GetEvents(uint(id))
+	if err != nil {
+		h.handleError(c, err, "获取彩票事件失败")
+		return
+	}
+
+	response.Success(c, events)
+}
+
+// CheckIntegrity rebuilds a ticket from its events and compares it with the
+// stored ticket and the wallet ledger
+// GET /api/admin/lottery/tickets/:id/integrity
+func (h *TicketEventHandler) CheckIntegrity(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的彩票ID")
+		return
+	}
+
+	report, err := h.ticketEventService.ForTenant(tenantID(c)).CheckIntegrity(uint(id))
+	if err != nil {
+		h.handleError(c, err, "校验彩票失败")
+		return
+	}
+
+	response.Success(c, report)
+}
+
+func (h *TicketEventHandler) handleError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrTicketNotFound:
+		response.NotFound(c, "彩票不存在")
+	default:
+		response.InternalError(c, message, err.Error())
+	}
+}
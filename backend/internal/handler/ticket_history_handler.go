@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"strconv"
+
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TicketHistoryHandler handles ticket ownership history endpoints
+type TicketHistoryHandler struct {
+	ticketHistoryService *service.TicketHistoryService
+}
+
+// NewTicketHistoryHandler creates a new ticket history handler
+func NewTicketHistoryHandler(ticketHistoryService *service.TicketHistoryService) *TicketHistoryHandler {
+	return &TicketHistoryHandler{ticketHistoryService: ticketHistoryService}
+}
+
+// GetHistory returns the full ownership history of a ticket
+// GET /api/admin/lottery/tickets/:id/history
+func (h *TicketHistoryHandler) GetHistory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的彩票ID")
+		return
+	}
+
+	history, err := h.ticketHistoryService.ForTenant(tenantID(c)).WithRedaction(fieldRedaction(c)).GetHistory(uint(id))
+	if err != nil {
+		h.handleError(c, err, "获取彩票流转记录失败")
+		return
+	}
+
+	response.Success(c, history)
+}
+
+// GetUserHistory returns the ownership history of one of the current user's tickets
+// GET /api/lottery/tickets/:id/history
+func (h *TicketHistoryHandler) GetUserHistory(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "请先登录")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的彩票ID")
+		return
+	}
+
+	entries, err := h.ticketHistoryService.ForTenant(tenantID(c)).GetUserHistory(userID.(uint), uint(id))
+	if err != nil {
+		h.handleError(c, err, "获取彩票流转记录失败")
+		return
+	}
+
+	response.Success(c, gin.H{"entries": entries})
+}
+
+// ReassignTicket moves an unscratched ticket to another user
+// POST /api/admin/lottery/tickets/:id/reassign
+func (h *TicketHistoryHandler) ReassignTicket(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的彩票ID")
+		return
+	}
+
+	var req service.ReassignTicketRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	history, err := h.ticketHistoryService.ForTenant(tenantID(c)).WithRedaction(fieldRedaction(c)).Reassign(adminID.(uint), uint(id), req)
+	if err != nil {
+		h.handleError(c, err, "转移彩票失败")
+		return
+	}
+
+	response.Success(c, history)
+}
+
+func (h *TicketHistoryHandler) handleError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrTicketNotFound:
+		response.NotFound(c, "彩票不存在")
+	case service.ErrTicketNotOwned:
+		response.Forbidden(c, "无权访问此彩票")
+	case service.ErrTicketNotReassignable:
+		response.BadRequest(c, "只能转移未刮开的彩票")
+	case service.ErrInvalidReassignRecipient:
+		response.BadRequest(c, "无效的接收用户")
+	default:
+		response.InternalError(c, message, err.Error())
+	}
+}
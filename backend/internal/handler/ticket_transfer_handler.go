@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"strconv"
+
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TicketTransferHandler handles ticket gift endpoints
+type TicketTransferHandler struct {
+	ticketTransferService *service.TicketTransferService
+}
+
+// NewTicketTransferHandler creates a new ticket transfer handler
+func NewTicketTransferHandler(ticketTransferService *service.TicketTransferService) *TicketTransferHandler {
+	return &TicketTransferHandler{ticketTransferService: ticketTransferService}
+}
+
+// GiftTicket gives an unscratched ticket of the current user to another user
+// POST /api/lottery/tickets/:id/gift
+func (h *TicketTransferHandler) GiftTicket(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "请先登录")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的彩票ID")
+		return
+	}
+
+	var req service.GiftTicketRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	transfer, err := h.ticketTransferService.ForTenant(tenantID(c)).GiftTicket(userID.(uint), uint(id), req)
+	if err != nil {
+		switch err {
+		case service.ErrTicketNotFound:
+			response.NotFound(c, "彩票不存在")
+		case service.ErrTicketNotOwned:
+			response.Forbidden(c, "无权操作此彩票")
+		case service.ErrTicketNotTransferable:
+			response.BadRequest(c, "只能赠送未刮开的彩票")
+		case service.ErrTransferRecipientNotFound:
+			response.NotFound(c, "受赠用户不存在")
+		case service.ErrTransferToSelf:
+			response.BadRequest(c, "不能赠送给自己")
+		default:
+			response.InternalError(c, "赠送彩票失败", err.Error())
+		}
+		return
+	}
+
+	response.Success(c, transfer)
+}
+
+// GetTransfers returns ticket gifts received (default) or sent by the current user
+// GET /api/lottery/transfers
+func (h *TicketTransferHandler) GetTransfers(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "请先登录")
+		return
+	}
+
+	var query service.TicketTransferQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	transfers, err := h.ticketTransferService.ForTenant(tenantID(c)).GetTransfers(userID.(uint), query)
+	if err != nil {
+		response.InternalError(c, "获取彩票赠送记录失败", err.Error())
+		return
+	}
+
+	response.Success(c, transfers)
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+// ticketWatchMaxDuration bounds how long a verify page subscription stays
+// open; clients reconnect to keep watching
+const ticketWatchMaxDuration = 30 * time.Minute
+
+// TicketWatchHandler handles live status subscriptions of public verify pages
+type TicketWatchHandler struct {
+	ticketWatchService *service.TicketWatchService
+}
+
+// NewTicketWatchHandler creates a new ticket watch handler
+func NewTicketWatchHandler(ticketWatchService *service.TicketWatchService) *TicketWatchHandler {
+	return &TicketWatchHandler{ticketWatchService: ticketWatchService}
+}
+
+// WatchTicket streams the verification view of a ticket over SSE: once on
+// connect and again when the ticket is scratched or claimed
+// GET /api/lottery/verify/:code/stream
+func (h *TicketWatchHandler) WatchTicket(c *gin.Context) {
+	code := c.Param("code")
+	if len(code) != service.SecurityCodeLength {
+		response.BadRequest(c, "无效的保安码格式")
+		return
+	}
+
+	current, updates, cancel, err := h.ticketWatchService.ForTenant(tenantID(c)).Watch(code, c.ClientIP())
+	if err != nil {
+		switch err {
+		case service.ErrTicketNotFound:
+			response.NotFound(c, "彩票不存在")
+		case service.ErrInvalidSecurityCode:
+			response.BadRequest(c, "无效的保安码格式")
+		case service.ErrTooManyTicketWatches:
+			response.TooManyRequests(c, "订阅数量过多，请关闭其他页面后重试")
+		default:
+			response.InternalError(c, "查询失败", err.Error())
+		}
+		return
+	}
+	defer cancel()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	send := func(view *service.VerifySecurityCodeResponse) bool {
+		c.Render(-1, sse.Event{Event: "status", Data: view})
+		c.Writer.Flush()
+		return view.Status != string(model.TicketStatusClaimed)
+	}
+
+	if !send(current) {
+		return
+	}
+
+	keepAlive := time.NewTicker(scratchStreamKeepAlive)
+	defer keepAlive.Stop()
+	deadline := time.NewTimer(ticketWatchMaxDuration)
+	defer deadline.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-deadline.C:
+			return
+		case view, ok := <-updates:
+			if !ok || !send(view) {
+				return
+			}
+		case <-keepAlive.C:
+			_, _ = c.Writer.WriteString(": keep-alive\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
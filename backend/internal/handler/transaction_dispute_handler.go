@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"strconv"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TransactionDisputeHandler handles wallet transaction dispute endpoints
+type TransactionDisputeHandler struct {
+	disputeService *service.TransactionDisputeService
+}
+
+// NewTransactionDisputeHandler creates a new transaction dispute handler
+func NewTransactionDisputeHandler(disputeService *service.TransactionDisputeService) *TransactionDisputeHandler {
+	return &TransactionDisputeHandler{disputeService: disputeService}
+}
+
+// OpenDispute disputes a debit of the current user's wallet
+// POST /api/wallet/transactions/:id/dispute
+func (h *TransactionDisputeHandler) OpenDispute(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的交易ID")
+		return
+	}
+
+	var req service.OpenDisputeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	dispute, err := h.disputeService.ForTenant(tenantID(c)).OpenDispute(userID.(uint), uint(id), req)
+	if err != nil {
+		h.disputeError(c, err, "提交争议失败")
+		return
+	}
+
+	response.Success(c, dispute)
+}
+
+// GetMyDisputes returns the disputes of the current user
+// GET /api/wallet/disputes
+func (h *TransactionDisputeHandler) GetMyDisputes(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	disputes, err := h.disputeService.ForTenant(tenantID(c)).GetUserDisputes(userID.(uint))
+	if err != nil {
+		response.InternalError(c, "获取争议记录失败", err.Error())
+		return
+	}
+
+	response.Success(c, disputes)
+}
+
+// GetDisputes returns a page of the dispute queue
+// GET /api/admin/disputes
+func (h *TransactionDisputeHandler) GetDisputes(c *gin.Context) {
+	var query service.AdminDisputeQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	disputes, err := h.disputeService.ForTenant(tenantID(c)).GetDisputes(query)
+	if err != nil {
+		response.InternalError(c, "获取争议记录失败", err.Error())
+		return
+	}
+
+	response.Success(c, disputes)
+}
+
+// UpholdDispute closes a dispute with the transaction standing
+// POST /api/admin/disputes/:id/uphold
+func (h *TransactionDisputeHandler) UpholdDispute(c *gin.Context) {
+	h.resolve(c, (*service.TransactionDisputeService).UpholdDispute)
+}
+
+// RefundDispute closes a dispute by refunding the transaction
+// POST /api/admin/disputes/:id/refund
+func (h *TransactionDisputeHandler) RefundDispute(c *gin.Context) {
+	h.resolve(c, (*service.TransactionDisputeService).RefundDispute)
+}
+
+// resolve binds a resolution and applies it to the dispute in the path
+func (h *TransactionDisputeHandler) resolve(c *gin.Context, apply func(*service.TransactionDisputeService, uint, uint, service.ResolveDisputeRequest) (*model.TransactionDispute, error)) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的争议ID")
+		return
+	}
+
+	var req service.ResolveDisputeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	dispute, err := apply(h.disputeService.ForTenant(tenantID(c)), adminID.(uint), uint(id), req)
+	if err != nil {
+		h.disputeError(c, err, "处理争议失败")
+		return
+	}
+
+	response.Success(c, dispute)
+}
+
+func (h *TransactionDisputeHandler) disputeError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrDisputeNotFound:
+		response.NotFound(c, "争议记录不存在")
+	case service.ErrTransactionNotFound:
+		response.NotFound(c, "交易记录不存在")
+	case service.ErrWalletNotFound:
+		response.NotFound(c, "钱包不存在")
+	case service.ErrDisputeNotOpen:
+		response.BadRequest(c, "该争议已处理")
+	case service.ErrTransactionNotDisputable:
+		response.BadRequest(c, "仅可对积分扣款或赠金购票提出争议")
+	case service.ErrTransactionAlreadyDisputed:
+		response.BadRequest(c, "该交易已提出过争议")
+	case service.ErrDisputeWindowClosed:
+		response.BadRequest(c, "该交易已超过可提出争议的期限")
+	case service.ErrInvalidDispute:
+		response.BadRequest(c, "原因不能为空且不超过255字")
+	case service.ErrInvalidRefund:
+		response.BadRequest(c, "该交易已全额退款")
+	case service.ErrPurchasePlayed:
+		response.BadRequest(c, "该笔购票已有彩票刮开，不能退款")
+	default:
+		response.InternalError(c, message, err.Error())
+	}
+}
@@ -28,7 +28,7 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 		return
 	}
 
-	profile, err := h.userService.GetUserProfile(userID.(uint))
+	profile, err := h.userService.ForTenant(tenantID(c)).GetUserProfile(userID.(uint))
 	if err != nil {
 		switch err {
 		case service.ErrUserNotFound:
@@ -51,7 +51,7 @@ func (h *UserHandler) GetStatistics(c *gin.Context) {
 		return
 	}
 
-	stats, err := h.userService.GetUserStatistics(userID.(uint))
+	stats, err := h.userService.ForTenant(tenantID(c)).GetUserStatistics(userID.(uint))
 	if err != nil {
 		response.InternalError(c, "获取统计数据失败", err.Error())
 		return
@@ -75,7 +75,7 @@ func (h *UserHandler) GetTickets(c *gin.Context) {
 		return
 	}
 
-	result, err := h.userService.GetUserTickets(userID.(uint), query)
+	result, err := h.userService.ForTenant(tenantID(c)).GetUserTickets(userID.(uint), query)
 	if err != nil {
 		response.InternalError(c, "获取购彩记录失败", err.Error())
 		return
@@ -84,6 +84,30 @@ func (h *UserHandler) GetTickets(c *gin.Context) {
 	response.Success(c, result)
 }
 
+// GetPurchaseSummary returns the current user's spend and winnings grouped by day and lottery type
+// GET /api/user/purchases/summary
+func (h *UserHandler) GetPurchaseSummary(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	var query service.PurchaseSummaryQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	result, err := h.userService.ForTenant(tenantID(c)).GetPurchaseSummary(userID.(uint), query)
+	if err != nil {
+		response.InternalError(c, "获取购彩汇总失败", err.Error())
+		return
+	}
+
+	response.Success(c, result)
+}
+
 // GetWins returns the current user's winning records
 // GET /api/user/wins
 func (h *UserHandler) GetWins(c *gin.Context) {
@@ -96,7 +120,7 @@ func (h *UserHandler) GetWins(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
 
-	result, err := h.userService.GetUserWins(userID.(uint), page, limit)
+	result, err := h.userService.ForTenant(tenantID(c)).GetUserWins(userID.(uint), page, limit)
 	if err != nil {
 		response.InternalError(c, "获取中奖记录失败", err.Error())
 		return
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"strconv"
+
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserNoteHandler handles the internal notes admins keep on users
+type UserNoteHandler struct {
+	userNoteService *service.UserNoteService
+}
+
+// NewUserNoteHandler creates a new user note handler
+func NewUserNoteHandler(userNoteService *service.UserNoteService) *UserNoteHandler {
+	return &UserNoteHandler{userNoteService: userNoteService}
+}
+
+// GetNotes returns the notes on a user, pinned first
+// GET /api/admin/users/:id/notes
+func (h *UserNoteHandler) GetNotes(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的用户ID")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	notes, err := h.userNoteService.ForTenant(tenantID(c)).GetNotes(uint(userID), page, limit)
+	if err != nil {
+		h.handleError(c, err, "获取备注失败")
+		return
+	}
+
+	response.Success(c, notes)
+}
+
+// CreateNote adds a note on a user
+// POST /api/admin/users/:id/notes
+func (h *UserNoteHandler) CreateNote(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的用户ID")
+		return
+	}
+
+	var req service.CreateUserNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	note, err := h.userNoteService.ForTenant(tenantID(c)).CreateNote(adminID.(uint), uint(userID), req)
+	if err != nil {
+		h.handleError(c, err, "添加备注失败")
+		return
+	}
+
+	response.Created(c, note)
+}
+
+// UpdateNote changes the content or pinned flag of a note
+// PUT /api/admin/users/:id/notes/:noteId
+func (h *UserNoteHandler) UpdateNote(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	userID, noteID, ok := parseUserNoteIDs(c)
+	if !ok {
+		return
+	}
+
+	var req service.UpdateUserNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	note, err := h.userNoteService.ForTenant(tenantID(c)).UpdateNote(adminID.(uint), userID, noteID, req)
+	if err != nil {
+		h.handleError(c, err, "更新备注失败")
+		return
+	}
+
+	response.Success(c, note)
+}
+
+// DeleteNote removes a note
+// DELETE /api/admin/users/:id/notes/:noteId
+func (h *UserNoteHandler) DeleteNote(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	userID, noteID, ok := parseUserNoteIDs(c)
+	if !ok {
+		return
+	}
+
+	if err := h.userNoteService.ForTenant(tenantID(c)).DeleteNote(adminID.(uint), userID, noteID); err != nil {
+		h.handleError(c, err, "删除备注失败")
+		return
+	}
+
+	response.Success(c, gin.H{"message": "备注已删除"})
+}
+
+// parseUserNoteIDs reads the user and note IDs of a note route
+func parseUserNoteIDs(c *gin.Context) (uint, uint, bool) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的用户ID")
+		return 0, 0, false
+	}
+	noteID, err := strconv.ParseUint(c.Param("noteId"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的备注ID")
+		return 0, 0, false
+	}
+	return uint(userID), uint(noteID), true
+}
+
+func (h *UserNoteHandler) handleError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrUserNotFound:
+		response.NotFound(c, "用户不存在")
+	case service.ErrUserNoteNotFound:
+		response.NotFound(c, "备注不存在")
+	case service.ErrUserNoteNotAuthor:
+		response.Forbidden(c, "只能修改或删除自己的备注")
+	default:
+		response.InternalError(c, message, err.Error())
+	}
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VoucherHandler handles offline voucher endpoints
+type VoucherHandler struct {
+	voucherService *service.VoucherService
+}
+
+// NewVoucherHandler creates a new voucher handler
+func NewVoucherHandler(voucherService *service.VoucherService) *VoucherHandler {
+	return &VoucherHandler{voucherService: voucherService}
+}
+
+// GenerateVouchers generates unassigned voucher tickets of a lottery type
+// POST /api/admin/lottery/types/:id/vouchers
+func (h *VoucherHandler) GenerateVouchers(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的彩票类型ID")
+		return
+	}
+
+	var req service.GenerateVouchersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	batch, err := h.voucherService.ForTenant(tenantID(c)).GenerateVouchers(adminID.(uint), uint(id), req)
+	if err != nil {
+		h.handleError(c, err, "生成兑换券失败")
+		return
+	}
+
+	response.Success(c, batch)
+}
+
+// ExportVouchers exports the security codes of the unclaimed vouchers as CSV
+// GET /api/admin/lottery/types/:id/vouchers/export
+func (h *VoucherHandler) ExportVouchers(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的彩票类型ID")
+		return
+	}
+
+	csvData, err := h.voucherService.ForTenant(tenantID(c)).ExportVouchersCSV(adminID.(uint), uint(id))
+	if err != nil {
+		h.handleError(c, err, "导出兑换券失败")
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=vouchers-%d.csv", id))
+	c.Data(200, "text/csv; charset=utf-8", csvData)
+}
+
+// ClaimVoucher moves a voucher ticket into the current user's account
+// POST /api/lottery/vouchers/claim
+// POST /api/lottery/claim-ticket
+func (h *VoucherHandler) ClaimVoucher(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "请先登录")
+		return
+	}
+
+	var req service.ClaimVoucherRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	vouchers := h.voucherService.ForTenant(tenantID(c))
+	ticket, err := vouchers.ClaimVoucher(userID.(uint), req, clientInfo(c))
+	if err == service.ErrVoucherClaimsLocked {
+		if lockedUntil, _ := vouchers.LockedUntil(userID.(uint)); lockedUntil != nil {
+			c.Header("Retry-After", strconv.Itoa(int(time.Until(*lockedUntil).Seconds())+1))
+		}
+		response.TooManyRequests(c, "兑换失败次数过多，请稍后再试")
+		return
+	}
+	if err != nil {
+		h.handleError(c, err, "兑换失败")
+		return
+	}
+
+	response.Success(c, ticket)
+}
+
+func (h *VoucherHandler) handleError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrLotteryTypeNotFound:
+		response.NotFound(c, "彩票类型不存在")
+	case service.ErrInvalidVoucherQuantity:
+		response.BadRequest(c, "无效的兑换券数量")
+	case service.ErrLotteryTypeSoldOut, service.ErrNoPrizePoolActive:
+		response.BadRequest(c, "奖池余量不足")
+	case service.ErrVoucherNotFound:
+		response.Error(c, http.StatusBadRequest, response.ErrInvalidSecurityCode, "兑换码无效或已被使用")
+	default:
+		response.InternalError(c, message, err.Error())
+	}
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"strconv"
+
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WaitingRoomHandler handles waiting room endpoints for high-demand lottery types
+type WaitingRoomHandler struct {
+	waitingRoom *service.WaitingRoomService
+}
+
+// NewWaitingRoomHandler creates a new waiting room handler
+func NewWaitingRoomHandler(waitingRoom *service.WaitingRoomService) *WaitingRoomHandler {
+	return &WaitingRoomHandler{waitingRoom: waitingRoom}
+}
+
+// JoinQueue places the current user in the waiting room of a lottery type
+// POST /api/lottery/types/:id/queue
+func (h *WaitingRoomHandler) JoinQueue(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "请先登录")
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的彩票类型ID")
+		return
+	}
+
+	status, err := h.waitingRoom.Join(userID.(uint), uint(id))
+	if err != nil {
+		switch err {
+		case service.ErrLotteryTypeNotFound:
+			response.NotFound(c, "彩票类型不存在")
+		default:
+			response.InternalError(c, "排队失败", err.Error())
+		}
+		return
+	}
+
+	response.Success(c, status)
+}
+
+// GetQueueStatus returns the current user's position, ETA and admission token
+// GET /api/lottery/types/:id/queue
+func (h *WaitingRoomHandler) GetQueueStatus(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "请先登录")
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的彩票类型ID")
+		return
+	}
+
+	status, err := h.waitingRoom.Status(userID.(uint), uint(id))
+	if err != nil {
+		switch err {
+		case service.ErrWaitingRoomNotQueued:
+			response.NotFound(c, "未在排队中")
+		default:
+			response.InternalError(c, "获取排队状态失败", err.Error())
+		}
+		return
+	}
+
+	response.Success(c, status)
+}
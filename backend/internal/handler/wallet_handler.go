@@ -2,6 +2,7 @@ package handler
 
 import (
 	"net/http"
+	"strconv"
 
 	"scratch-lottery/internal/service"
 	"scratch-lottery/pkg/response"
@@ -19,7 +20,8 @@ func NewWalletHandler(walletService *service.WalletService) *WalletHandler {
 	return &WalletHandler{walletService: walletService}
 }
 
-// GetWallet returns the current user's wallet information
+// GetWallet returns the current user's wallet information. Clients opt in
+// to the balance breakdown with version=2.
 // GET /api/wallet
 func (h *WalletHandler) GetWallet(c *gin.Context) {
 	userID, exists := c.Get("userID")
@@ -28,9 +30,21 @@ func (h *WalletHandler) GetWallet(c *gin.Context) {
 		return
 	}
 
-	wallet, err := h.walletService.GetWalletByUserID(userID.(uint))
+	version := service.WalletAPIVersion1
+	if versionStr := c.Query("version"); versionStr != "" {
+		parsed, err := strconv.Atoi(versionStr)
+		if err != nil {
+			response.BadRequest(c, "无效的版本号")
+			return
+		}
+		version = parsed
+	}
+
+	wallet, err := h.walletService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).GetWalletVersion(userID.(uint), version)
 	if err != nil {
 		switch err {
+		case service.ErrInvalidWalletVersion:
+			response.BadRequest(c, "无效的版本号")
 		case service.ErrWalletNotFound:
 			response.NotFound(c, "钱包不存在")
 		default:
@@ -57,7 +71,7 @@ func (h *WalletHandler) GetTransactions(c *gin.Context) {
 		return
 	}
 
-	result, err := h.walletService.GetTransactions(userID.(uint), query)
+	result, err := h.walletService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).GetTransactions(userID.(uint), query)
 	if err != nil {
 		switch err {
 		case service.ErrWalletNotFound:
@@ -80,7 +94,7 @@ func (h *WalletHandler) GetBalance(c *gin.Context) {
 		return
 	}
 
-	balance, err := h.walletService.GetBalance(userID.(uint))
+	balance, err := h.walletService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).GetBalance(userID.(uint))
 	if err != nil {
 		switch err {
 		case service.ErrWalletNotFound:
@@ -113,7 +127,7 @@ func (h *WalletHandler) CheckBalance(c *gin.Context) {
 		return
 	}
 
-	sufficient, err := h.walletService.HasSufficientBalance(userID.(uint), req.Amount)
+	sufficient, err := h.walletService.ForTenant(tenantID(c)).WithRequest(c.Request.Context()).HasSufficientBalance(userID.(uint), req.Amount)
 	if err != nil {
 		switch err {
 		case service.ErrWalletNotFound:
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"strconv"
+
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WalletReconciliationHandler handles wallets whose balance disagrees with their ledger
+type WalletReconciliationHandler struct {
+	reconciliationService *service.WalletReconciliationService
+}
+
+// NewWalletReconciliationHandler creates a new wallet reconciliation handler
+func NewWalletReconciliationHandler(reconciliationService *service.WalletReconciliationService) *WalletReconciliationHandler {
+	return &WalletReconciliationHandler{reconciliationService: reconciliationService}
+}
+
+// GetReconciliations lists wallet reconciliations
+// GET /api/admin/wallet-reconciliations
+func (h *WalletReconciliationHandler) GetReconciliations(c *gin.Context) {
+	var query service.ReconciliationQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	result, err := h.reconciliationService.ForTenant(tenantID(c)).GetReconciliations(query)
+	if err != nil {
+		response.InternalError(c, "获取对账记录失败", err.Error())
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// RunReconciliation audits the tenant's wallets against their ledger right away
+// POST /api/admin/wallet-reconciliations/run
+func (h *WalletReconciliationHandler) RunReconciliation(c *gin.Context) {
+	found, err := h.reconciliationService.ForTenant(tenantID(c)).Reconcile()
+	if err != nil {
+		response.InternalError(c, "钱包对账失败", err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{
+		"found": found,
+	})
+}
+
+// ResolveReconciliation closes a reconciliation after review
+// POST /api/admin/wallet-reconciliations/:id/resolve
+func (h *WalletReconciliationHandler) ResolveReconciliation(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的记录ID")
+		return
+	}
+
+	var req service.ResolveReconciliationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	reconciliation, err := h.reconciliationService.ForTenant(tenantID(c)).Resolve(adminID.(uint), uint(id), req)
+	if err != nil {
+		switch err {
+		case service.ErrReconciliationNotFound:
+			response.NotFound(c, "对账记录不存在")
+		case service.ErrReconciliationResolved:
+			response.BadRequest(c, "对账记录已处理")
+		default:
+			response.InternalError(c, "处理对账记录失败", err.Error())
+		}
+		return
+	}
+
+	response.Success(c, reconciliation)
+}
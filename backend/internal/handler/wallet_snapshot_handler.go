@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"strconv"
+
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WalletSnapshotHandler handles wallet balance history endpoints
+type WalletSnapshotHandler struct {
+	snapshotService *service.WalletSnapshotService
+}
+
+// NewWalletSnapshotHandler creates a new wallet snapshot handler
+func NewWalletSnapshotHandler(snapshotService *service.WalletSnapshotService) *WalletSnapshotHandler {
+	return &WalletSnapshotHandler{snapshotService: snapshotService}
+}
+
+// GetBalanceHistory returns the current user's daily balance series
+// GET /api/wallet/balance/history
+func (h *WalletSnapshotHandler) GetBalanceHistory(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	h.respondHistory(c, userID.(uint))
+}
+
+// GetUserBalanceHistory returns a user's daily balance series (admin only)
+// GET /api/admin/users/:id/balance/history
+func (h *WalletSnapshotHandler) GetUserBalanceHistory(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的用户ID")
+		return
+	}
+
+	h.respondHistory(c, uint(id))
+}
+
+func (h *WalletSnapshotHandler) respondHistory(c *gin.Context, userID uint) {
+	var query service.BalanceHistoryQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	result, err := h.snapshotService.ForTenant(tenantID(c)).GetBalanceHistory(userID, query)
+	if err != nil {
+		switch err {
+		case service.ErrWalletNotFound:
+			response.NotFound(c, "钱包不存在")
+		default:
+			response.InternalError(c, "获取余额历史失败", err.Error())
+		}
+		return
+	}
+
+	response.Success(c, result)
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"strconv"
+
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WalletWebhookHandler handles wallet webhook management endpoints (admin only)
+type WalletWebhookHandler struct {
+	webhookService *service.WalletWebhookService
+}
+
+// NewWalletWebhookHandler creates a new wallet webhook handler
+func NewWalletWebhookHandler(webhookService *service.WalletWebhookService) *WalletWebhookHandler {
+	return &WalletWebhookHandler{webhookService: webhookService}
+}
+
+// GetWebhooks returns all wallet webhooks
+// GET /api/admin/wallet-webhooks
+func (h *WalletWebhookHandler) GetWebhooks(c *gin.Context) {
+	webhooks, err := h.webhookService.GetWebhooks()
+	if err != nil {
+		response.InternalError(c, "获取Webhook列表失败", err.Error())
+		return
+	}
+
+	response.Success(c, webhooks)
+}
+
+// CreateWebhook creates a wallet webhook; the signing secret is only returned here
+// POST /api/admin/wallet-webhooks
+func (h *WalletWebhookHandler) CreateWebhook(c *gin.Context) {
+	var req service.CreateWalletWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	webhook, err := h.webhookService.CreateWebhook(req)
+	if err != nil {
+		h.handleError(c, err, "创建Webhook失败")
+		return
+	}
+
+	response.Created(c, webhook)
+}
+
+// UpdateWebhook updates a wallet webhook
+// PUT /api/admin/wallet-webhooks/:id
+func (h *WalletWebhookHandler) UpdateWebhook(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+
+	var req service.UpdateWalletWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	webhook, err := h.webhookService.UpdateWebhook(id, req)
+	if err != nil {
+		h.handleError(c, err, "更新Webhook失败")
+		return
+	}
+
+	response.Success(c, webhook)
+}
+
+// DeleteWebhook deletes a wallet webhook
+// DELETE /api/admin/wallet-webhooks/:id
+func (h *WalletWebhookHandler) DeleteWebhook(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.webhookService.DeleteWebhook(id); err != nil {
+		h.handleError(c, err, "删除Webhook失败")
+		return
+	}
+
+	response.Success(c, gin.H{"message": "Webhook已删除"})
+}
+
+// GetDeliveries returns the delivery log of a wallet webhook
+// GET /api/admin/wallet-webhooks/:id/deliveries
+func (h *WalletWebhookHandler) GetDeliveries(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+
+	var query service.WebhookDeliveryQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	result, err := h.webhookService.GetDeliveries(id, query)
+	if err != nil {
+		response.InternalError(c, "获取投递记录失败", err.Error())
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// ReplayWebhook re-queues the transactions of a time window for delivery
+// POST /api/admin/wallet-webhooks/:id/replay
+func (h *WalletWebhookHandler) ReplayWebhook(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+
+	var req service.ReplayWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	replayed, err := h.webhookService.Replay(id, req)
+	if err != nil {
+		h.handleError(c, err, "重放Webhook失败")
+		return
+	}
+
+	response.Success(c, gin.H{"replayed": replayed})
+}
+
+func (h *WalletWebhookHandler) handleError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrWebhookNotFound:
+		response.NotFound(c, "Webhook不存在")
+	case service.ErrInvalidTransactionType:
+		response.BadRequest(c, "无效的交易类型")
+	case service.ErrInvalidReplayWindow:
+		response.BadRequest(c, "无效的时间范围")
+	default:
+		response.InternalError(c, message, err.Error())
+	}
+}
+
+func parseWebhookID(c *gin.Context) (uint, bool) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的Webhook ID")
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHeader is the header partners use to send their API key
+const APIKeyHeader = "X-API-Key"
+
+// ParseAPIKeys parses a comma separated API key list (e.g. from RETAILER_API_KEYS)
+func ParseAPIKeys(s string) []string {
+	var keys []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			keys = append(keys, part)
+		}
+	}
+	return keys
+}
+
+// APIKeyMiddleware authenticates partner requests by a static API key.
+// The matched key is stored in the context as "apiKey" for rate limiting.
+// With no keys configured every request is rejected.
+func APIKeyMiddleware(keys []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided := c.GetHeader(APIKeyHeader)
+		if provided == "" {
+			response.Unauthorized(c, "缺少API密钥")
+			c.Abort()
+			return
+		}
+
+		for _, key := range keys {
+			if subtle.ConstantTimeCompare([]byte(provided), []byte(key)) == 1 {
+				c.Set("apiKey", key)
+				c.Next()
+				return
+			}
+		}
+
+		response.Unauthorized(c, "无效的API密钥")
+		c.Abort()
+	}
+}
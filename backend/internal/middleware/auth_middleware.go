@@ -1,10 +1,12 @@
 package middleware
 
 import (
+	"net/http"
 	"strings"
 
 	"scratch-lottery/internal/service"
 	"scratch-lottery/pkg/auth"
+	"scratch-lottery/pkg/logger"
 	"scratch-lottery/pkg/response"
 
 	"github.com/gin-gonic/gin"
@@ -43,6 +45,13 @@ func AuthMiddleware(authService *service.AuthService) gin.HandlerFunc {
 			return
 		}
 
+		// Tokens are only valid on the tenant they were issued for
+		if !matchesTenant(c, claims) {
+			response.Error(c, 401, response.ErrTokenInvalid, "令牌不属于当前租户")
+			c.Abort()
+			return
+		}
+
 		// Set user info in context
 		c.Set("userID", claims.UserID)
 		c.Set("linuxdoID", claims.LinuxdoID)
@@ -54,16 +63,71 @@ func AuthMiddleware(authService *service.AuthService) gin.HandlerFunc {
 	}
 }
 
-// AdminMiddleware ensures the user is an admin
-func AdminMiddleware() gin.HandlerFunc {
+// supportWritableRoutes are the admin routes support staff may change data
+// through: the triage of support issues
+var supportWritableRoutes = map[string]bool{
+	"/api/admin/support/issues/:id": true,
+}
+
+// AdminMiddleware ensures the user is staff: an admin, or support staff who
+// may only make read requests besides triaging support issues. Staff roles are scoped to the tenant of the
+// user, which AuthMiddleware has matched to the request.
+// The realm further restricts admins to allowlisted IPs or admin audience
+// tokens; refused requests are recorded as auth incidents by guard. Either
+// may be nil, in which case only the role is checked or nothing is recorded.
+func AdminMiddleware(realm *service.AdminRealm, guard *service.AuthGuardService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		role, exists := c.Get("role")
-		if !exists || role.(string) != "admin" {
-			response.Forbidden(c, "需要管理员权限")
-			c.Abort()
+		var claims *auth.Claims
+		if value, exists := c.Get("claims"); exists {
+			claims, _ = value.(*auth.Claims)
+		}
+
+		reason := ""
+		if realm != nil {
+			reason = realm.Check(claims, c.ClientIP())
+		} else if claims == nil || !service.IsStaffRole(claims.Role) {
+			reason = service.AdminDenialNotAdmin
+		}
+		if reason == "" && claims.Role == service.RoleSupport &&
+			c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead &&
+			!supportWritableRoutes[c.FullPath()] {
+			reason = service.AdminDenialReadOnly
+		}
+		if reason == "" {
+			c.Next()
 			return
 		}
-		c.Next()
+
+		if guard != nil {
+			recordAdminDenial(c, guard, claims, reason)
+		}
+		switch reason {
+		case service.AdminDenialIPNotAllowed:
+			response.Error(c, http.StatusForbidden, response.ErrAdminIPDenied, "当前IP不允许访问管理后台", reason)
+		case service.AdminDenialTokenRequired:
+			response.Error(c, http.StatusForbidden, response.ErrAdminTokenRequired, "需要管理后台令牌", reason)
+		case service.AdminDenialReadOnly:
+			response.Forbidden(c, "客服账号仅可查看", reason)
+		default:
+			response.Forbidden(c, "需要管理员权限", reason)
+		}
+		c.Abort()
+	}
+}
+
+// recordAdminDenial audits a refused admin request on the request's tenant
+func recordAdminDenial(c *gin.Context, guard *service.AuthGuardService, claims *auth.Claims, reason string) {
+	var userID uint
+	subject := ""
+	if claims != nil {
+		userID, subject = claims.UserID, claims.LinuxdoID
+	}
+	if tenantID, exists := c.Get("tenantID"); exists {
+		guard = guard.ForTenant(tenantID.(uint))
+	}
+	client := service.ClientInfo{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+	if err := guard.RecordAdminDenial(userID, subject, client, reason+" "+c.Request.Method+" "+c.FullPath()); err != nil {
+		logger.Default().Warn("Recording an admin denial failed: %v", err)
 	}
 }
 
@@ -84,7 +148,7 @@ func OptionalAuthMiddleware(authService *service.AuthService) gin.HandlerFunc {
 
 		tokenStr := parts[1]
 		claims, err := authService.ValidateAccessToken(tokenStr)
-		if err != nil {
+		if err != nil || !matchesTenant(c, claims) {
 			c.Next()
 			return
 		}
@@ -99,3 +163,15 @@ func OptionalAuthMiddleware(authService *service.AuthService) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// QueryTokenMiddleware lets clients that cannot set headers, such as browser
+// WebSocket handshakes, pass the access token as the access_token query
+// parameter. It must run before the auth middleware.
+func QueryTokenMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token := c.Query("access_token"); token != "" && c.GetHeader("Authorization") == "" {
+			c.Request.Header.Set("Authorization", "Bearer "+token)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"errors"
+
+	"scratch-lottery/pkg/captcha"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CaptchaHeader is the header clients send the solved captcha token in
+const CaptchaHeader = "X-Captcha-Token"
+
+// CaptchaMiddleware requires a valid captcha token on the routes it guards.
+// With the none provider every request passes.
+func CaptchaMiddleware(verifier captcha.Captcha) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := verifier.Verify(c.Request.Context(), c.GetHeader(CaptchaHeader), c.ClientIP())
+		switch {
+		case err == nil:
+			c.Next()
+			return
+		case errors.Is(err, captcha.ErrMissingToken):
+			response.Forbidden(c, "请完成人机验证")
+		case errors.Is(err, captcha.ErrVerifyFailed):
+			response.Forbidden(c, "人机验证失败")
+		default:
+			response.ServiceUnavailable(c, "人机验证服务不可用", err.Error())
+		}
+		c.Abort()
+	}
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// corsAllowedHeaders are the request headers browsers may send cross-origin
+const corsAllowedHeaders = "Origin, Content-Type, Authorization, X-API-Key, X-Tenant, X-Captcha-Token, X-Request-Id, Idempotency-Key"
+
+// corsExposedHeaders are the response headers browsers may read cross-origin
+const corsExposedHeaders = "X-Request-Id, X-Sandbox, Retry-After, Allow"
+
+// ParseOrigins parses a comma separated list of allowed origins. An empty
+// list allows every origin.
+func ParseOrigins(s string) []string {
+	var origins []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			origins = append(origins, part)
+		}
+	}
+	return origins
+}
+
+// CORSMiddleware answers cross-origin requests from the allowed origins
+// ("*" or an empty list allows any). The router must handle method-not-allowed
+// (HandleMethodNotAllowed), so an OPTIONS request to a known path arrives
+// with the methods of the path in its Allow header: it is answered with 204
+// and those methods. OPTIONS requests to unknown paths fall through to 404.
+func CORSMiddleware(allowedOrigins []string) gin.HandlerFunc {
+	anyOrigin := len(allowedOrigins) == 0
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		if origin == "*" {
+			anyOrigin = true
+		}
+		allowed[strings.TrimSuffix(origin, "/")] = true
+	}
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		switch {
+		case anyOrigin:
+			c.Header("Access-Control-Allow-Origin", "*")
+		case origin != "" && allowed[origin]:
+			c.Header("Access-Control-Allow-Origin", origin)
+			c.Header("Vary", "Origin")
+		}
+		c.Header("Access-Control-Allow-Headers", corsAllowedHeaders)
+		c.Header("Access-Control-Expose-Headers", corsExposedHeaders)
+
+		if c.Request.Method != http.MethodOptions {
+			c.Next()
+			return
+		}
+		methods := c.Writer.Header().Get("Allow")
+		if methods == "" {
+			c.Next()
+			return
+		}
+		methods = withOptions(methods)
+		c.Header("Allow", methods)
+		c.Header("Access-Control-Allow-Methods", methods)
+		c.Header("Access-Control-Max-Age", "600")
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
+
+// MethodNotAllowed answers requests whose path exists under other methods
+// only. The router has already listed those methods in the Allow header.
+func MethodNotAllowed(c *gin.Context) {
+	c.Header("Allow", withOptions(c.Writer.Header().Get("Allow")))
+	response.Error(c, http.StatusMethodNotAllowed, response.ErrMethodNotAllowed, "请求方法不允许")
+}
+
+// RouteNotFound answers requests to unknown paths
+func RouteNotFound(c *gin.Context) {
+	response.NotFound(c, "接口不存在")
+}
+
+// withOptions adds OPTIONS to a list of allowed methods, which every routed
+// path answers
+func withOptions(methods string) string {
+	if methods == "" || strings.Contains(methods, http.MethodOptions) {
+		return methods
+	}
+	return methods + ", " + http.MethodOptions
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FieldRedactionMiddleware resolves which response fields the staff member
+// may not see from the tenant's redaction policy and stores the redaction as
+// "fieldRedaction". It must run after AuthMiddleware.
+func FieldRedactionMiddleware(adminService *service.AdminService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scoped := adminService
+		if tenantID, exists := c.Get("tenantID"); exists {
+			scoped = adminService.ForTenant(tenantID.(uint))
+		}
+
+		redaction, err := scoped.FieldRedactionFor(c.GetString("role"))
+		if err != nil {
+			response.InternalError(c, "获取字段脱敏配置失败", err.Error())
+			c.Abort()
+			return
+		}
+
+		c.Set("fieldRedaction", redaction)
+		c.Next()
+	}
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"errors"
+
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// KillSwitchMiddleware refuses requests with 503 and the subsystem's banner
+// while the subsystem is switched off for the request's tenant
+func KillSwitchMiddleware(killSwitches *service.KillSwitchService, subsystem string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scoped := killSwitches
+		if tenantID, exists := c.Get("tenantID"); exists {
+			scoped = killSwitches.ForTenant(tenantID.(uint))
+		}
+
+		if err := scoped.Check(subsystem); err != nil {
+			var disabled *service.SubsystemDisabledError
+			if errors.As(err, &disabled) {
+				response.ServiceUnavailable(c, disabled.Message)
+			} else {
+				response.InternalError(c, "获取功能开关失败", err.Error())
+			}
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
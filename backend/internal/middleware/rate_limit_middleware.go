@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimiter is a fixed-window in-memory rate limiter
+type RateLimiter struct {
+	limit   int
+	window  time.Duration
+	windows map[string]*rateWindow
+	mutex   sync.Mutex
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// NewRateLimiter creates a rate limiter allowing limit requests per window for each key
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:   limit,
+		window:  window,
+		windows: make(map[string]*rateWindow),
+	}
+}
+
+// Allow records a request for key and reports whether it is within the limit,
+// along with the time until the current window resets
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	w, exists := l.windows[key]
+	if !exists || now.Sub(w.start) >= l.window {
+		// Drop expired windows so the map does not grow without bound
+		for k, old := range l.windows {
+			if now.Sub(old.start) >= l.window {
+				delete(l.windows, k)
+			}
+		}
+		w = &rateWindow{start: now}
+		l.windows[key] = w
+	}
+
+	reset := l.window - now.Sub(w.start)
+	if w.count >= l.limit {
+		return false, reset
+	}
+	w.count++
+	return true, reset
+}
+
+// RateLimitMiddleware limits requests per API key, or per user once
+// AuthMiddleware has run, and per client IP for other requests. A
+// non-positive limit disables rate limiting.
+func RateLimitMiddleware(limiter *RateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limiter == nil || limiter.limit <= 0 {
+			c.Next()
+			return
+		}
+
+		key := "ip:" + c.ClientIP()
+		if apiKey, exists := c.Get("apiKey"); exists {
+			key = "key:" + apiKey.(string)
+		} else if userID, exists := c.Get("userID"); exists {
+			key = "user:" + strconv.FormatUint(uint64(userID.(uint)), 10)
+		}
+
+		allowed, reset := limiter.Allow(key)
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(reset.Seconds())+1))
+			response.TooManyRequests(c, "请求过于频繁，请稍后再试")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Signed in requests are limited per user, so neither a new X-Forwarded-For
+// nor a new connection gets around the limit, while other users keep theirs
+func TestRateLimitKeysOnUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	limiter := NewRateLimiter(2, time.Minute)
+	r.POST("/api/lottery/vouchers/claim", func(c *gin.Context) {
+		var userID uint = 1
+		if c.GetHeader("X-User") == "2" {
+			userID = 2
+		}
+		c.Set("userID", userID)
+	}, RateLimitMiddleware(limiter), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	claim := func(user, remote, forwarded string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/lottery/vouchers/claim", nil)
+		req.RemoteAddr = remote
+		req.Header.Set("X-User", user)
+		req.Header.Set("X-Forwarded-For", forwarded)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for i, from := range [][2]string{
+		{"192.0.2.1:1", "203.0.113.1"},
+		{"192.0.2.2:1", "203.0.113.2"},
+		{"192.0.2.3:1", "203.0.113.3"},
+	} {
+		want := http.StatusOK
+		if i == 2 {
+			want = http.StatusTooManyRequests
+		}
+		if code := claim("1", from[0], from[1]); code != want {
+			t.Errorf("Claim %d of user 1: expected %d, got %d", i+1, want, code)
+		}
+	}
+	if code := claim("2", "192.0.2.1:1", ""); code != http.StatusOK {
+		t.Errorf("Expected another user not limited, got %d", code)
+	}
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"time"
+
+	"scratch-lottery/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestAnalyticsMiddleware counts every finished request by route, status,
+// latency and user for the admin request analytics, and by API key for the
+// partner usage. It must run outside the
+// recovery middleware to see the status of panicking requests.
+func RequestAnalyticsMiddleware(analytics *service.RequestAnalyticsService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		var tenant, user uint
+		if id, ok := c.Get("tenantID"); ok {
+			tenant = id.(uint)
+		}
+		if id, ok := c.Get("userID"); ok {
+			user = id.(uint)
+		}
+		analytics.Record(tenant, c.Request.Method, c.FullPath(), user, c.Writer.Status(), time.Since(start))
+		if key, ok := c.Get("apiKey"); ok {
+			analytics.RecordAPIKey(key.(string), c.Request.Method, c.FullPath(), c.Writer.Status())
+		}
+	}
+}
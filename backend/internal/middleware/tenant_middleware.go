@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"scratch-lottery/internal/repository"
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/auth"
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TenantHeader selects a tenant by slug, overriding the request host
+const TenantHeader = "X-Tenant"
+
+// SandboxHeader marks the responses of sandbox tenants, whose points are
+// test currency
+const SandboxHeader = "X-Sandbox"
+
+// TenantMiddleware resolves the tenant of the request from the X-Tenant
+// header or the host and stores its ID as "tenantID". Responses of sandbox
+// tenants carry the X-Sandbox header.
+func TenantMiddleware(tenantService *service.TenantService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenant, err := tenantService.Resolve(c.GetHeader(TenantHeader), c.Request.Host)
+		if err != nil {
+			switch err {
+			case service.ErrTenantNotFound:
+				response.NotFound(c, "租户不存在")
+			case service.ErrTenantDisabled:
+				response.Forbidden(c, "租户已停用")
+			default:
+				response.InternalError(c, "解析租户失败", err.Error())
+			}
+			c.Abort()
+			return
+		}
+
+		c.Set("tenantID", tenant.ID)
+		if tenant.Sandbox {
+			c.Header(SandboxHeader, "true")
+		}
+		c.Next()
+	}
+}
+
+// DefaultTenantMiddleware limits deployment-wide features to the default
+// tenant, whose admins operate the deployment
+func DefaultTenantMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID, exists := c.Get("tenantID")
+		if exists && tenantID.(uint) != repository.DefaultTenantID {
+			response.Forbidden(c, "仅平台运营方可用")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// claimsTenant returns the tenant a token was issued for
+func claimsTenant(claims *auth.Claims) uint {
+	if claims.TenantID == 0 {
+		return repository.DefaultTenantID
+	}
+	return claims.TenantID
+}
+
+// matchesTenant reports whether the token belongs to the request's tenant.
+// Requests without a resolved tenant adopt the token's tenant.
+func matchesTenant(c *gin.Context, claims *auth.Claims) bool {
+	tenantID, exists := c.Get("tenantID")
+	if !exists {
+		c.Set("tenantID", claimsTenant(claims))
+		return true
+	}
+	return tenantID.(uint) == claimsTenant(claims)
+}
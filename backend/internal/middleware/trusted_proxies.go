@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TrustProxies makes ClientIP take the address from X-Forwarded-For or
+// X-Real-IP only for requests from one of the comma separated proxy IPs or
+// CIDRs. With none, the default, the client IP is the peer address of the
+// connection: otherwise any client could pick the IP that the admin
+// allowlist, the session limits and the rate limits see.
+func TrustProxies(r *gin.Engine, proxies string) error {
+	var trusted []string
+	for _, part := range strings.Split(proxies, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			trusted = append(trusted, part)
+		}
+	}
+	return r.SetTrustedProxies(trusted)
+}
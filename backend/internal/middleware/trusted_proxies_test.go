@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"scratch-lottery/internal/service"
+	"scratch-lottery/pkg/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// A client outside the admin allowlist cannot get in by naming an allowed IP
+// in X-Forwarded-For, unless the request comes through a trusted proxy
+func TestAdminAllowlistIgnoresSpoofedForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	realm, err := service.NewAdminRealm(auth.NewJWTManager("test-secret", 15, 7), "203.0.113.7", "", "", 0)
+	if err != nil {
+		t.Fatalf("NewAdminRealm failed: %v", err)
+	}
+
+	for _, tc := range []struct {
+		proxies string
+		want    int
+	}{
+		{"", http.StatusForbidden},
+		{"198.51.100.0/24", http.StatusForbidden},
+		{"192.0.2.1", http.StatusOK},
+	} {
+		r := gin.New()
+		if err := TrustProxies(r, tc.proxies); err != nil {
+			t.Fatalf("TrustProxies(%q) failed: %v", tc.proxies, err)
+		}
+		r.GET("/api/admin/stats", func(c *gin.Context) {
+			c.Set("claims", &auth.Claims{UserID: 1, Role: "admin"})
+		}, AdminMiddleware(realm, nil), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/stats", nil)
+		req.RemoteAddr = "192.0.2.1:40000"
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Errorf("Trusting %q: expected %d, got %d", tc.proxies, tc.want, w.Code)
+		}
+	}
+
+	if err := TrustProxies(gin.New(), "not-an-ip"); err == nil {
+		t.Error("Expected an invalid proxy refused")
+	}
+}
+
+// The IP that logins count sessions under is the peer address whatever
+// X-Forwarded-For says, so a client can neither spread its logins over
+// made-up IPs nor use up the sessions of another IP
+func TestSessionIPIgnoresSpoofedForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, tc := range []struct {
+		proxies string
+		want    []string
+	}{
+		{"", []string{"192.0.2.1", "192.0.2.1"}},
+		{"192.0.2.0/24", []string{"203.0.113.7", "203.0.113.8"}},
+	} {
+		r := gin.New()
+		if err := TrustProxies(r, tc.proxies); err != nil {
+			t.Fatalf("TrustProxies(%q) failed: %v", tc.proxies, err)
+		}
+		var seen []string
+		r.POST("/api/auth/login/dev", func(c *gin.Context) {
+			seen = append(seen, c.ClientIP())
+		})
+		for _, forwarded := range []string{"203.0.113.7", "203.0.113.8"} {
+			req := httptest.NewRequest(http.MethodPost, "/api/auth/login/dev", nil)
+			req.RemoteAddr = "192.0.2.1:40000"
+			req.Header.Set("X-Forwarded-For", forwarded)
+			r.ServeHTTP(httptest.NewRecorder(), req)
+		}
+		if len(seen) != 2 || seen[0] != tc.want[0] || seen[1] != tc.want[1] {
+			t.Errorf("Trusting %q: expected the logins from %v, got %v", tc.proxies, tc.want, seen)
+		}
+	}
+}
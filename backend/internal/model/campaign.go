@@ -0,0 +1,122 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CampaignTrigger defines the user action a campaign rewards
+type CampaignTrigger string
+
+const (
+	CampaignTriggerRecharge      CampaignTrigger = "recharge"       // A recharge order is paid
+	CampaignTriggerFirstPurchase CampaignTrigger = "first_purchase" // A user buys tickets for the first time
+	CampaignTriggerCheckIn       CampaignTrigger = "check_in"       // A user checks in for the day
+)
+
+// CampaignRewardType defines what a campaign gives out
+type CampaignRewardType string
+
+const (
+	CampaignRewardPoints     CampaignRewardType = "points"
+	CampaignRewardCoupon     CampaignRewardType = "coupon"      // Discount on a later ticket purchase
+	CampaignRewardFreeTicket CampaignRewardType = "free_ticket" // Tickets of a lottery type at no cost
+	CampaignRewardBonus      CampaignRewardType = "bonus"       // Bonus credits, spent on tickets only
+)
+
+// Campaign rewards users for a trigger while it is scheduled, optionally
+// limited to the members of a segment. Budget and MaxRewards cap the total
+// cost in points and the number of rewards; Spent and RewardCount track them.
+type Campaign struct {
+	gorm.Model
+	TenantID            uint               `gorm:"index;default:1" json:"tenant_id"`
+	Name                string             `gorm:"size:64" json:"name"`
+	Description         string             `gorm:"size:256" json:"description"`
+	Trigger             CampaignTrigger    `gorm:"column:trigger_type;size:32;index" json:"trigger"` // TRIGGER is an SQL keyword
+	SegmentID           *uint              `gorm:"index" json:"segment_id,omitempty"`                // Nil targets all users
+	StartsAt            *time.Time         `json:"starts_at,omitempty"`
+	EndsAt              *time.Time         `json:"ends_at,omitempty"`
+	Enabled             bool               `json:"enabled"`
+	MinAmount           int                `json:"min_amount"` // Recharge campaigns: minimum order amount in minor currency units
+	RewardType          CampaignRewardType `gorm:"size:32" json:"reward_type"`
+	RewardPoints        int                `json:"reward_points"` // Points or bonus credits credited, or the discount of a coupon
+	RewardLotteryTypeID uint               `json:"reward_lottery_type_id,omitempty"`
+	RewardQuantity      int                `json:"reward_quantity,omitempty"`   // Free tickets per reward
+	CouponValidDays     int                `json:"coupon_valid_days,omitempty"` // 0 = coupons never expire
+	PerUserLimit        int                `json:"per_user_limit"`              // 0 = unlimited
+	Budget              int                `json:"budget"`                      // In points, 0 = unlimited
+	MaxRewards          int                `json:"max_rewards"`                 // 0 = unlimited
+	Spent               int                `json:"spent"`
+	RewardCount         int                `json:"reward_count"`
+}
+
+// CampaignRewardStatus defines the status of a campaign reward
+type CampaignRewardStatus string
+
+const (
+	CampaignRewardStatusIssued CampaignRewardStatus = "issued"
+	CampaignRewardStatusFailed CampaignRewardStatus = "failed" // Free tickets could not be generated; the budget was released
+)
+
+// CampaignReward records one reward of a campaign. EventKey identifies the
+// triggering event so the same event never pays twice.
+type CampaignReward struct {
+	ID          uint                 `gorm:"primarykey" json:"id"`
+	TenantID    uint                 `gorm:"index;default:1" json:"tenant_id"`
+	CampaignID  uint                 `gorm:"uniqueIndex:idx_campaign_rewards_event" json:"campaign_id"`
+	UserID      uint                 `gorm:"uniqueIndex:idx_campaign_rewards_event;index" json:"user_id"`
+	EventKey    string               `gorm:"uniqueIndex:idx_campaign_rewards_event;size:64" json:"event_key"`
+	ReferenceID uint                 `json:"reference_id,omitempty"` // Related payment order or ticket
+	RewardType  CampaignRewardType   `gorm:"size:32" json:"reward_type"`
+	Cost        int                  `json:"cost"` // In points, counted against the budget
+	CouponID    *uint                `json:"coupon_id,omitempty"`
+	Status      CampaignRewardStatus `gorm:"size:16;index" json:"status"`
+	CreatedAt   time.Time            `gorm:"index" json:"created_at"`
+}
+
+// Coupon takes Discount points off a later ticket purchase of its owner
+type Coupon struct {
+	gorm.Model
+	TenantID   uint       `gorm:"index;default:1" json:"tenant_id"`
+	UserID     uint       `gorm:"index" json:"user_id"`
+	CampaignID uint       `gorm:"index" json:"campaign_id"`
+	Discount   int        `json:"discount"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	UsedAt     *time.Time `json:"used_at,omitempty"`
+}
+
+// CheckIn records the daily check-in of a user. Days are calendar days in
+// server time, stored as YYYY-MM-DD.
+type CheckIn struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	TenantID   uint      `gorm:"index;default:1" json:"tenant_id"`
+	UserID     uint      `gorm:"uniqueIndex:idx_check_ins_user_date" json:"user_id"`
+	Date       string    `gorm:"uniqueIndex:idx_check_ins_user_date;size:10" json:"date"`
+	StreakDays int       `json:"streak_days"` // Consecutive days checked in, including this one
+	Points     int       `json:"points"`      // Daily points credited
+	Bonus      int       `json:"bonus"`       // Streak bonus credited
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// OnboardingStepKey identifies a step of the first-run checklist. Each step
+// is completed by the user action it names.
+type OnboardingStepKey string
+
+const (
+	OnboardingLinkAccount   OnboardingStepKey = "link_account"   // Signs in with a LinuxDO account
+	OnboardingFirstCheckIn  OnboardingStepKey = "first_check_in" // Claims points by checking in
+	OnboardingFirstPurchase OnboardingStepKey = "first_purchase" // Buys a ticket
+	OnboardingFirstScratch  OnboardingStepKey = "first_scratch"  // Scratches a ticket
+)
+
+// OnboardingStep records that a user completed a step of the first-run
+// checklist, and the reward points credited for it
+type OnboardingStep struct {
+	ID           uint              `gorm:"primarykey" json:"id"`
+	TenantID     uint              `gorm:"index;default:1" json:"tenant_id"`
+	UserID       uint              `gorm:"uniqueIndex:idx_onboarding_steps_user_step" json:"user_id"`
+	Step         OnboardingStepKey `gorm:"uniqueIndex:idx_onboarding_steps_user_step;size:32" json:"step"`
+	RewardPoints int               `json:"reward_points"` // 0 for steps found done before the checklist tracked them
+	CompletedAt  time.Time         `json:"completed_at"`
+}
@@ -18,13 +18,19 @@ const (
 // Product represents an exchangeable product
 type Product struct {
 	gorm.Model
-	Name        string        `gorm:"size:128" json:"name"`
-	Description string        `gorm:"type:text" json:"description"`
-	Image       string        `gorm:"size:512" json:"image"`
-	Price       int           `json:"price"` // Points required
-	Stock       int           `json:"stock"` // Available stock
-	Status      ProductStatus `gorm:"size:32;default:available" json:"status"`
-	CardKeys    []CardKey     `gorm:"foreignKey:ProductID" json:"card_keys,omitempty"`
+	TenantID          uint          `gorm:"index;default:1" json:"tenant_id"`
+	Name              string        `gorm:"size:128" json:"name"`
+	Description       string        `gorm:"type:text" json:"description"`
+	Image             string        `gorm:"size:512" json:"image"`
+	Price             int           `json:"price"` // Points required
+	Cost              int           `json:"-"`     // Real-money cost in fen, shown to admins only (0 = unknown)
+	Stock             int           `json:"stock"` // Available stock
+	Status            ProductStatus `gorm:"size:32;default:available" json:"status"`
+	LowStockThreshold int           `json:"low_stock_threshold"` // Alert when stock falls to this level (0 = disabled)
+	OneTimeReveal     bool          `json:"one_time_reveal"`     // Card key is shown once on redemption, then masked
+	DropAt            *time.Time    `json:"drop_at,omitempty"`   // Flash drop: stock hidden and redemption blocked until this time
+	Demo              bool          `gorm:"index" json:"demo"`   // Created by the demo bootstrap, removed with the demo content
+	CardKeys          []CardKey     `gorm:"foreignKey:ProductID" json:"card_keys,omitempty"`
 }
 
 // CardKeyStatus defines the status of a card key
@@ -33,6 +39,7 @@ type CardKeyStatus string
 const (
 	CardKeyStatusAvailable CardKeyStatus = "available"
 	CardKeyStatusRedeemed  CardKeyStatus = "redeemed"
+	CardKeyStatusReserved  CardKeyStatus = "reserved" // Held by a pending two-phase exchange
 )
 
 // CardKey represents a redeemable card key
@@ -45,14 +52,70 @@ type CardKey struct {
 	RedeemedAt *time.Time    `json:"redeemed_at,omitempty"`
 }
 
+// ExchangeRecordStatus defines the status of an exchange record
+type ExchangeRecordStatus string
+
+const (
+	ExchangeRecordStatusCompleted ExchangeRecordStatus = "completed"
+	ExchangeRecordStatusReserved  ExchangeRecordStatus = "reserved" // Points and card key held until confirmed
+	ExchangeRecordStatusReleased  ExchangeRecordStatus = "released" // Reservation cancelled or timed out, points refunded
+	ExchangeRecordStatusPending   ExchangeRecordStatus = "pending"  // Prize payout waiting for an admin to deliver a card key
+)
+
 // ExchangeRecord represents an exchange transaction
 type ExchangeRecord struct {
 	gorm.Model
-	UserID    uint    `gorm:"index" json:"user_id"`
-	ProductID uint    `gorm:"index" json:"product_id"`
-	CardKeyID uint    `gorm:"index" json:"card_key_id"`
-	Cost      int     `json:"cost"`
-	User      User    `gorm:"foreignKey:UserID" json:"user,omitempty"`
-	Product   Product `gorm:"foreignKey:ProductID" json:"product,omitempty"`
-	CardKey   CardKey `gorm:"foreignKey:CardKeyID" json:"card_key,omitempty"`
+	TenantID      uint                 `gorm:"index;default:1" json:"tenant_id"`
+	UserID        uint                 `gorm:"index" json:"user_id"`
+	ProductID     uint                 `gorm:"index" json:"product_id"`
+	CardKeyID     uint                 `gorm:"index" json:"card_key_id"`
+	Cost          int                  `json:"cost"`
+	Status        ExchangeRecordStatus `gorm:"size:32;default:completed;index" json:"status"`
+	ReservedUntil *time.Time           `json:"reserved_until,omitempty"`               // Deadline to confirm a reservation
+	GiftID        *uint                `gorm:"index" json:"gift_id,omitempty"`         // Set when the product was sent or received as a gift
+	TicketID      *uint                `gorm:"uniqueIndex" json:"ticket_id,omitempty"` // Set when the product pays out a prize of the ticket
+	ReceiptCode   *string              `gorm:"size:32;uniqueIndex" json:"-"`           // Verification code of the receipt, issued when first requested
+	User          User                 `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Product       Product              `gorm:"foreignKey:ProductID" json:"product,omitempty"`
+	CardKey       CardKey              `gorm:"foreignKey:CardKeyID" json:"card_key,omitempty"`
+	Gift          *ExchangeGift        `gorm:"foreignKey:GiftID" json:"gift,omitempty"`
+}
+
+// CardKeyReveal is an audit entry written each time a user re-reveals a
+// redeemed card key from their exchange records
+type CardKeyReveal struct {
+	gorm.Model
+	RecordID  uint   `gorm:"index" json:"record_id"`
+	UserID    uint   `gorm:"index" json:"user_id"`
+	CardKeyID uint   `json:"card_key_id"`
+	IPAddress string `gorm:"size:64" json:"ip_address"`
+	UserAgent string `gorm:"size:256" json:"user_agent"`
+	User      User   `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// ExchangeGiftStatus defines the status of an exchange gift
+type ExchangeGiftStatus string
+
+const (
+	ExchangeGiftStatusPending  ExchangeGiftStatus = "pending"
+	ExchangeGiftStatusAccepted ExchangeGiftStatus = "accepted"
+	ExchangeGiftStatusReturned ExchangeGiftStatus = "returned" // Declined or unclaimed before expiry
+)
+
+// ExchangeGift represents a redeemed product sent to another user.
+// The card key is only revealed to the recipient after acceptance.
+type ExchangeGift struct {
+	gorm.Model
+	GiverID     uint               `gorm:"index" json:"giver_id"`
+	RecipientID uint               `gorm:"index" json:"recipient_id"`
+	ProductID   uint               `gorm:"index" json:"product_id"`
+	CardKeyID   uint               `json:"card_key_id"`
+	Message     string             `gorm:"size:256" json:"message"`
+	Status      ExchangeGiftStatus `gorm:"size:32;default:pending;index" json:"status"`
+	ExpiresAt   time.Time          `gorm:"index" json:"expires_at"`
+	RespondedAt *time.Time         `json:"responded_at,omitempty"`
+	Giver       User               `gorm:"foreignKey:GiverID" json:"giver,omitempty"`
+	Recipient   User               `gorm:"foreignKey:RecipientID" json:"recipient,omitempty"`
+	Product     Product            `gorm:"foreignKey:ProductID" json:"product,omitempty"`
+	CardKey     CardKey            `gorm:"foreignKey:CardKeyID" json:"-"`
 }
@@ -29,6 +29,7 @@ const (
 // LotteryType represents a type of lottery game
 type LotteryType struct {
 	gorm.Model
+	TenantID     uint              `gorm:"index;default:1" json:"tenant_id"`
 	Name         string            `gorm:"size:128" json:"name"`
 	Description  string            `gorm:"type:text" json:"description"`
 	Price        int               `json:"price"`
@@ -38,19 +39,45 @@ type LotteryType struct {
 	RulesConfig  string            `gorm:"type:text" json:"rules_config"`  // JSON configuration for game rules
 	DesignConfig string            `gorm:"type:text" json:"design_config"` // JSON configuration for visual design
 	Status       LotteryTypeStatus `gorm:"size:32;default:available" json:"status"`
+	LowStockThreshold int          `json:"low_stock_threshold"` // Alert when stock falls to this level (0 = disabled)
+	WaitingRoomThreshold int       `json:"waiting_room_threshold"` // Concurrent purchases before the waiting room engages (0 = disabled)
+	PrizeLevelVersion int          `gorm:"default:1" json:"prize_level_version"` // Version of the prize levels new pools draw from
+	ConfirmScratch bool            `json:"confirm_scratch"` // Scratching needs the confirmation token of the ticket detail
+	ScratchDelaySeconds int        `json:"scratch_delay_seconds"`    // Minimum time between purchase and scratch (0 = disabled)
+	ScratchIntervalSeconds int     `json:"scratch_interval_seconds"` // Minimum time between consecutive scratches of a user (0 = disabled)
+	AutoReplenish bool             `json:"auto_replenish"`           // Open a new prize pool when the active one sells out
+	ReplenishTemplateID uint       `json:"replenish_template_id"`    // Prize template of replenished pools (0 = repeat the sold out pool)
+	ReplenishTickets int           `json:"replenish_tickets"`        // Size of template pools (0 = the template's base size)
+	ScheduledStartAt *time.Time    `gorm:"index" json:"scheduled_start_at,omitempty"` // Made available when reached, then cleared
+	ScheduledEndAt   *time.Time    `gorm:"index" json:"scheduled_end_at,omitempty"`   // Disabled when reached, then cleared
+	Demo         bool              `gorm:"index" json:"demo"` // Created by the demo bootstrap, removed with the demo content
 	PrizeLevels  []PrizeLevel      `gorm:"foreignKey:LotteryTypeID" json:"prize_levels,omitempty"`
 	PrizePools   []PrizePool       `gorm:"foreignKey:LotteryTypeID" json:"prize_pools,omitempty"`
 }
 
+// PrizePayoutType defines how the prize of a level is paid out
+type PrizePayoutType string
+
+const (
+	PrizePayoutPoints  PrizePayoutType = "points"  // credited to the wallet
+	PrizePayoutProduct PrizePayoutType = "product" // delivered as an exchange product
+)
+
 // PrizeLevel represents a prize level configuration
 type PrizeLevel struct {
 	gorm.Model
-	LotteryTypeID uint   `gorm:"index" json:"lottery_type_id"`
-	Level         int    `json:"level"`
-	Name          string `gorm:"size:64" json:"name"`
-	PrizeAmount   int    `json:"prize_amount"`
-	Quantity      int    `json:"quantity"`  // Total quantity in prize pool
-	Remaining     int    `json:"remaining"` // Remaining quantity
+	LotteryTypeID   uint            `gorm:"index" json:"lottery_type_id"`
+	Version         int             `gorm:"index;default:1" json:"version"` // Prize level set the level belongs to
+	Level           int             `json:"level"`
+	Name            string          `gorm:"size:64" json:"name"`
+	PrizeAmount     int             `json:"prize_amount"`
+	Quantity        int             `json:"quantity"`  // Total quantity in prize pool
+	Remaining       int             `json:"remaining"` // Remaining quantity
+	PayoutType      PrizePayoutType `gorm:"size:16;default:points" json:"payout_type"`
+	PayoutProductID uint            `json:"payout_product_id,omitempty"` // Product delivered for product payouts
+	Image           string          `gorm:"size:512" json:"image,omitempty"`       // Icon or image of the prize, http(s) URL or site path
+	Color           string          `gorm:"size:16" json:"color,omitempty"`        // Display color, #RGB or #RRGGBB
+	Description     string          `gorm:"size:256" json:"description,omitempty"` // Marketing copy shown in the prize table
 }
 
 // PrizePoolStatus defines the status of a prize pool
@@ -65,26 +92,52 @@ const (
 // PrizePool represents a batch of lottery tickets
 type PrizePool struct {
 	gorm.Model
-	LotteryTypeID uint            `gorm:"index" json:"lottery_type_id"`
-	TotalTickets  int             `json:"total_tickets"`
-	SoldTickets   int             `json:"sold_tickets"`
-	ClaimedPrizes int             `json:"claimed_prizes"`
-	ReturnRate    float64         `json:"return_rate"`
-	Status        PrizePoolStatus `gorm:"size:32;default:active" json:"status"`
+	LotteryTypeID     uint            `gorm:"index" json:"lottery_type_id"`
+	TotalTickets      int             `json:"total_tickets"`
+	SoldTickets       int             `json:"sold_tickets"`
+	ClaimedPrizes     int             `json:"claimed_prizes"`
+	ReturnRate        float64         `json:"return_rate"`
+	Status            PrizePoolStatus `gorm:"size:32;default:active" json:"status"`
+	PrizeLevelVersion int             `gorm:"default:1" json:"prize_level_version"` // Version of the prize levels the pool draws from
+}
+
+// PrizeTemplate is a reusable set of prize levels for a pool of BaseTickets
+// tickets sold at TicketPrice, with the return rate the levels are designed for
+type PrizeTemplate struct {
+	gorm.Model
+	TenantID    uint    `gorm:"index;default:1" json:"tenant_id"`
+	Name        string  `gorm:"size:128" json:"name"`
+	Description string  `gorm:"type:text" json:"description"`
+	TicketPrice int     `json:"ticket_price"`
+	BaseTickets int     `json:"base_tickets"`
+	ReturnRate  float64 `json:"return_rate"`
+	Levels      string  `gorm:"type:text" json:"levels"` // JSON array of prize levels
 }
 
 // TicketStatus defines the status of a ticket
 type TicketStatus string
 
 const (
-	TicketStatusUnscratched TicketStatus = "unscratched"
-	TicketStatusScratched   TicketStatus = "scratched"
-	TicketStatusClaimed     TicketStatus = "claimed"
+	TicketStatusUnscratched  TicketStatus = "unscratched"
+	TicketStatusScratching   TicketStatus = "scratching" // incremental scratch in progress
+	TicketStatusScratched    TicketStatus = "scratched"
+	TicketStatusPendingClaim TicketStatus = "pending_claim" // scratched, prize held until its claim is approved
+	TicketStatusClaimed      TicketStatus = "claimed"
+	TicketStatusVoided       TicketStatus = "voided" // purchase refunded before it was scratched
 )
 
+// RevealedTicketStatuses lists the statuses of tickets whose content is revealed
+var RevealedTicketStatuses = []TicketStatus{TicketStatusScratched, TicketStatusPendingClaim, TicketStatusClaimed}
+
+// Revealed reports whether a ticket in the status has been scratched
+func (s TicketStatus) Revealed() bool {
+	return s == TicketStatusScratched || s == TicketStatusPendingClaim || s == TicketStatusClaimed
+}
+
 // Ticket represents a lottery ticket
 type Ticket struct {
 	gorm.Model
+	TenantID         uint         `gorm:"index;default:1" json:"tenant_id"`
 	UserID           uint         `gorm:"index" json:"user_id"`
 	LotteryTypeID    uint         `gorm:"index" json:"lottery_type_id"`
 	PrizePoolID      uint         `gorm:"index" json:"prize_pool_id"`
@@ -92,8 +145,195 @@ type Ticket struct {
 	ContentEncrypted string       `gorm:"type:text" json:"-"` // AES encrypted content
 	PrizeAmount      int          `json:"prize_amount"`
 	Status           TicketStatus `gorm:"size:32;default:unscratched" json:"status"`
+	PurchaseID       uint         `gorm:"index" json:"purchase_id,omitempty"` // Transaction the charges of its purchase reference
 	PurchasedAt      time.Time    `json:"purchased_at"`
 	ScratchedAt      *time.Time   `json:"scratched_at,omitempty"`
 	User             User         `gorm:"foreignKey:UserID" json:"user,omitempty"`
 	LotteryType      LotteryType  `gorm:"foreignKey:LotteryTypeID" json:"lottery_type,omitempty"`
 }
+
+// TicketHistoryEvent defines how a ticket changed hands
+type TicketHistoryEvent string
+
+const (
+	TicketHistoryPurchase TicketHistoryEvent = "purchase"
+	TicketHistoryGift     TicketHistoryEvent = "gift"     // issued for free by a campaign
+	TicketHistoryReassign TicketHistoryEvent = "reassign" // moved to another user by an admin
+	TicketHistoryVoucher  TicketHistoryEvent = "voucher"  // generated unassigned for an offline voucher
+	TicketHistoryClaim    TicketHistoryEvent = "claim"    // voucher claimed by a user with its security code
+	TicketHistoryTransfer TicketHistoryEvent = "transfer" // sent to another user by its owner
+)
+
+// TicketHistory records an ownership change of a ticket. FromUserID is nil
+// when the ticket was issued.
+type TicketHistory struct {
+	ID          uint               `gorm:"primarykey" json:"id"`
+	TenantID    uint               `gorm:"index;default:1" json:"tenant_id"`
+	TicketID    uint               `gorm:"index" json:"ticket_id"`
+	Event       TicketHistoryEvent `gorm:"size:32" json:"event"`
+	FromUserID  *uint              `gorm:"index" json:"from_user_id,omitempty"`
+	ToUserID    uint               `gorm:"index" json:"to_user_id"`
+	ActorID     *uint              `json:"actor_id,omitempty"`     // Admin who made the change
+	ReferenceID uint               `json:"reference_id,omitempty"` // Campaign reward of a gift, or purchase of a purchased ticket
+	Reason      string             `gorm:"size:256" json:"reason,omitempty"`
+	CreatedAt   time.Time          `json:"created_at"`
+}
+
+// TicketEventType defines a step in the lifecycle of a ticket
+type TicketEventType string
+
+const (
+	TicketEventGenerated     TicketEventType = "generated"
+	TicketEventPurchased     TicketEventType = "purchased"
+	TicketEventViewed        TicketEventType = "viewed"         // detail opened by the owner
+	TicketEventScratched     TicketEventType = "scratched"      // Status tells whether the prize is held for a claim
+	TicketEventPrizeCredited TicketEventType = "prize_credited" // Amount points credited to the owner
+	TicketEventClaimed       TicketEventType = "claimed"        // held prize paid out after its claim
+	TicketEventVoided        TicketEventType = "voided"         // held prize forfeited by a rejected claim, or purchase refunded
+)
+
+// TicketEvent is an entry of the append-only event stream of a ticket.
+// Replaying the stream rebuilds the ticket status and the points it paid
+// out, which settles disputes about lost wins. Events are never updated or
+// deleted.
+type TicketEvent struct {
+	ID        uint            `gorm:"primarykey" json:"id"`
+	TenantID  uint            `gorm:"index;default:1" json:"tenant_id"`
+	TicketID  uint            `gorm:"index" json:"ticket_id"`
+	Type      TicketEventType `gorm:"size:32" json:"type"`
+	ActorID   *uint           `json:"actor_id,omitempty"`          // User or admin; nil for the system
+	Status    TicketStatus    `gorm:"size:32" json:"status,omitempty"` // Ticket status after the event, empty if unchanged
+	Amount    int             `json:"amount,omitempty"`
+	Details   string          `gorm:"size:256" json:"details,omitempty"`
+	CreatedAt time.Time       `gorm:"index" json:"created_at"`
+}
+
+// TicketTransfer records an unscratched ticket a user gave to another user
+type TicketTransfer struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	TenantID   uint      `gorm:"index;default:1" json:"tenant_id"`
+	TicketID   uint      `gorm:"index" json:"ticket_id"`
+	FromUserID uint      `gorm:"index" json:"from_user_id"`
+	ToUserID   uint      `gorm:"index" json:"to_user_id"`
+	Message    string    `gorm:"size:256" json:"message"`
+	CreatedAt  time.Time `json:"created_at"`
+	Ticket     Ticket    `gorm:"foreignKey:TicketID" json:"-"`
+	FromUser   User      `gorm:"foreignKey:FromUserID" json:"-"`
+	ToUser     User      `gorm:"foreignKey:ToUserID" json:"-"`
+}
+
+// StockReservation holds tickets of a lottery type for a user between the
+// purchase preview and the purchase. Reservations past ExpiresAt no longer
+// count against the stock and are removed by a sweeper.
+type StockReservation struct {
+	ID            uint      `gorm:"primarykey" json:"id"`
+	TenantID      uint      `gorm:"index;default:1" json:"tenant_id"`
+	UserID        uint      `gorm:"index" json:"user_id"`
+	LotteryTypeID uint      `gorm:"index" json:"lottery_type_id"`
+	Quantity      int       `json:"quantity"`
+	ExpiresAt     time.Time `gorm:"index" json:"expires_at"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// ScratchConfirmation is the confirmation token issued with the detail of
+// an unscratched ticket whose lottery type requires confirmed scratches. The
+// scratch request must echo it before ExpiresAt; it is used once.
+type ScratchConfirmation struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	TenantID  uint      `gorm:"index;default:1" json:"tenant_id"`
+	TicketID  uint      `gorm:"uniqueIndex" json:"ticket_id"`
+	UserID    uint      `gorm:"index" json:"user_id"`
+	Token     string    `gorm:"size:64" json:"-"`
+	ExpiresAt time.Time `gorm:"index" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PurchaseRequestRecord remembers a purchase made with a client supplied
+// request ID, so a retried request returns the original response instead of
+// buying again
+type PurchaseRequestRecord struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	TenantID    uint      `gorm:"uniqueIndex:idx_purchase_request;default:1" json:"tenant_id"`
+	UserID      uint      `gorm:"uniqueIndex:idx_purchase_request" json:"user_id"`
+	RequestID   string    `gorm:"size:64;uniqueIndex:idx_purchase_request" json:"request_id"`
+	Fingerprint string    `gorm:"size:64" json:"-"`   // Lottery type, quantity and coupon of the purchase
+	Completed   bool      `json:"completed"`          // False while the purchase is in progress
+	Response    string    `gorm:"type:text" json:"-"` // JSON purchase response
+	CreatedAt   time.Time `gorm:"index" json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// VoucherClaimFailure records a failed attempt to claim a voucher ticket by
+// its security code. Users with too many recent failures cannot claim.
+type VoucherClaimFailure struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	TenantID  uint      `gorm:"index;default:1" json:"tenant_id"`
+	UserID    uint      `gorm:"index" json:"user_id"`
+	IP        string    `gorm:"size:64" json:"ip"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// PrizeClaimStatus defines the status of a prize claim
+type PrizeClaimStatus string
+
+const (
+	PrizeClaimStatusPending   PrizeClaimStatus = "pending"   // waiting for the winner's identity
+	PrizeClaimStatusSubmitted PrizeClaimStatus = "submitted" // identity given, waiting for an admin's approval
+	PrizeClaimStatusConfirmed PrizeClaimStatus = "confirmed" // identity given and prize credited
+	PrizeClaimStatusRejected  PrizeClaimStatus = "rejected"  // refused by an admin, the prize is forfeited
+)
+
+// PrizeClaim holds a large win until its winner confirms their identity and,
+// when the operator requires it, an admin approves the payout
+type PrizeClaim struct {
+	gorm.Model
+	TenantID          uint             `gorm:"index;default:1" json:"tenant_id"`
+	TicketID          uint             `gorm:"uniqueIndex" json:"ticket_id"`
+	UserID            uint             `gorm:"index" json:"user_id"`
+	PrizeAmount       int              `json:"prize_amount"`
+	Status            PrizeClaimStatus `gorm:"size:16;index" json:"status"`
+	FullName          string           `gorm:"size:128" json:"full_name"`
+	IDNumberEncrypted string           `gorm:"type:text" json:"-"` // AES encrypted identity document number
+	Contact           string           `gorm:"size:128" json:"contact"`
+	ConfirmedAt       *time.Time       `json:"confirmed_at,omitempty"`
+	PayoutProductID   uint             `json:"payout_product_id,omitempty"` // Product delivered instead of points once confirmed
+	ApprovalRequired  bool             `json:"approval_required"`           // Paid out only once an admin approves the claim
+	ReviewedBy        uint             `json:"reviewed_by,omitempty"`
+	ReviewedAt        *time.Time       `json:"reviewed_at,omitempty"`
+	RejectReason      string           `gorm:"size:255" json:"reject_reason,omitempty"`
+}
+
+// TicketAreaScratch records a single area revealed during incremental scratching
+type TicketAreaScratch struct {
+	gorm.Model
+	TicketID    uint      `gorm:"uniqueIndex:idx_ticket_area;uniqueIndex:idx_ticket_seq" json:"ticket_id"`
+	AreaIndex   int       `gorm:"uniqueIndex:idx_ticket_area" json:"area_index"`
+	Seq         int       `gorm:"uniqueIndex:idx_ticket_seq" json:"seq"` // 1-based reveal order
+	Content     string    `gorm:"size:64" json:"content"`
+	Value       int       `json:"value"`
+	ScratchedAt time.Time `json:"scratched_at"`
+}
+
+// OddsDisclosure is a versioned odds disclosure document for a lottery type.
+// A new version is recorded whenever the prize configuration changes.
+type OddsDisclosure struct {
+	gorm.Model
+	LotteryTypeID uint   `gorm:"uniqueIndex:idx_odds_type_version" json:"lottery_type_id"`
+	Version       int    `gorm:"uniqueIndex:idx_odds_type_version" json:"version"`
+	ConfigHash    string `gorm:"size:64;index" json:"config_hash"` // SHA-256 of the configuration the document was generated from
+	Content       string `gorm:"type:text" json:"-"`               // JSON document
+}
+
+// Jackpot is the progressive jackpot of a tenant. Every purchase adds
+// ContributionPercent of its cost to Amount, and each ticket sold wins the
+// whole amount with a chance of one in Odds.
+type Jackpot struct {
+	gorm.Model
+	TenantID            uint       `gorm:"uniqueIndex;default:1" json:"tenant_id"`
+	Amount              int        `json:"amount"`
+	ContributionPercent int        `json:"contribution_percent"`
+	Odds                int        `json:"odds"` // 0 disables the jackpot outcome
+	LastWinnerID        uint       `json:"last_winner_id,omitempty"`
+	LastWonAmount       int        `json:"last_won_amount,omitempty"`
+	LastWonAt           *time.Time `json:"last_won_at,omitempty"`
+}
@@ -1,25 +1,62 @@
 package model
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
+// TenantStatus defines the status of a tenant
+type TenantStatus string
+
+const (
+	TenantStatusActive   TenantStatus = "active"
+	TenantStatusDisabled TenantStatus = "disabled"
+)
+
+// Tenant represents a lottery operator hosted on this deployment. Users,
+// wallets, lottery types, products and settings belong to exactly one tenant.
+type Tenant struct {
+	gorm.Model
+	Name   string       `gorm:"size:128" json:"name"`
+	Slug   string       `gorm:"uniqueIndex;size:64" json:"slug"` // Matched against the X-Tenant header
+	Domain string       `gorm:"index;size:255" json:"domain"`    // Matched against the request host
+	Status TenantStatus `gorm:"size:32;default:active" json:"status"`
+	// Sandbox tenants play with test points: recharges are simulated, their
+	// data is reset periodically and they are left out of statistics
+	Sandbox bool `gorm:"index" json:"sandbox"`
+}
+
 // SystemConfig represents system configuration
 type SystemConfig struct {
 	gorm.Model
-	Key   string `gorm:"uniqueIndex;size:64" json:"key"`
-	Value string `gorm:"type:text" json:"value"`
+	TenantID uint   `gorm:"uniqueIndex:idx_system_configs_tenant_key;default:1" json:"tenant_id"`
+	Key      string `gorm:"uniqueIndex:idx_system_configs_tenant_key;size:64" json:"key"`
+	Value    string `gorm:"type:text" json:"value"`
 }
 
-// AdminLog represents an admin action log
+// AdminLog represents an admin action log. The logs of a tenant form a hash
+// chain, so edited or deleted logs can be detected.
 type AdminLog struct {
 	gorm.Model
-	AdminID    uint   `gorm:"index" json:"admin_id"`
-	Action     string `gorm:"size:64" json:"action"`
-	TargetType string `gorm:"size:64" json:"target_type"`
-	TargetID   uint   `json:"target_id"`
-	Details    string `gorm:"type:text" json:"details"` // JSON
-	Admin      User   `gorm:"foreignKey:AdminID" json:"admin,omitempty"`
+	TenantID   uint    `gorm:"index;uniqueIndex:idx_admin_log_chain;default:1" json:"tenant_id"`
+	AdminID    uint    `gorm:"index" json:"admin_id"`
+	Action     string  `gorm:"size:64" json:"action"`
+	TargetType string  `gorm:"size:64" json:"target_type"`
+	TargetID   uint    `json:"target_id"`
+	Details    string  `gorm:"type:text" json:"details"` // JSON
+	Seq        *uint64 `gorm:"uniqueIndex:idx_admin_log_chain" json:"seq"` // Position in the tenant's hash chain, nil for logs written before chaining
+	PrevHash   string  `gorm:"size:64" json:"prev_hash"`                   // Hash of the previous log of the tenant, empty for the first
+	Hash       string  `gorm:"size:64" json:"hash"`                        // SHA-256 over the log's fields and PrevHash
+	Admin      User    `gorm:"foreignKey:AdminID" json:"admin,omitempty"`
+}
+
+// DashboardLayout is the admin dashboard layout saved by an admin
+type DashboardLayout struct {
+	gorm.Model
+	TenantID uint   `gorm:"index;default:1" json:"tenant_id"`
+	AdminID  uint   `gorm:"uniqueIndex" json:"admin_id"`
+	Widgets  string `gorm:"type:text" json:"widgets"` // JSON list of widget configs, in display order
 }
 
 // PaymentOrder represents a payment order
@@ -27,10 +64,182 @@ type PaymentOrder struct {
 	gorm.Model
 	UserID      uint   `gorm:"index" json:"user_id"`
 	OrderNo     string `gorm:"uniqueIndex;size:64" json:"order_no"`
-	Amount      int    `json:"amount"`      // Amount in cents
+	Amount      int    `json:"amount"`      // Amount in minor units of Currency
+	Currency    string `gorm:"size:3;default:CNY" json:"currency"`
 	Points      int    `json:"points"`      // Points to add
-	Status      string `gorm:"size:32;default:pending" json:"status"` // pending, paid, failed
+	Status      string `gorm:"size:32;default:pending" json:"status"` // pending, paid, failed, expired
 	PaymentType string `gorm:"size:32" json:"payment_type"`
 	TradeNo     string `gorm:"size:128" json:"trade_no,omitempty"` // Third-party trade number
+	Gateway     string `gorm:"size:32;default:epay" json:"gateway"` // Payment gateway the order is paid through
 	User        User   `gorm:"foreignKey:UserID" json:"user,omitempty"`
 }
+
+// PaymentTypeSandbox marks the simulated payments of sandbox tenants
+const PaymentTypeSandbox = "sandbox"
+
+// PaymentReconciliationKind defines what a payment reconciliation found
+type PaymentReconciliationKind string
+
+const (
+	PaymentReconciliationMissedCallback  PaymentReconciliationKind = "missed_callback"   // Paid at the gateway without a callback; credited by reconciliation
+	PaymentReconciliationAmountMismatch  PaymentReconciliationKind = "amount_mismatch"   // The gateway reports a different amount; the order is not credited
+	PaymentReconciliationQueryFailed     PaymentReconciliationKind = "query_failed"      // The gateway could not be asked; the order stays pending
+	PaymentReconciliationPaidAfterExpiry PaymentReconciliationKind = "paid_after_expiry" // Paid after the order had expired; credited anyway
+)
+
+// PaymentReconciliation records a payment order whose state at the gateway
+// disagreed with ours. An order has at most one open record of each kind,
+// which stays open until an admin resolves it.
+type PaymentReconciliation struct {
+	gorm.Model
+	TenantID   uint                      `gorm:"index;default:1" json:"tenant_id"`
+	OrderID    uint                      `gorm:"index" json:"order_id"`
+	OrderNo    string                    `gorm:"size:64" json:"order_no"`
+	UserID     uint                      `gorm:"index" json:"user_id"`
+	Gateway    string                    `gorm:"size:32" json:"gateway"`
+	Kind       PaymentReconciliationKind `gorm:"size:32;index" json:"kind"`
+	Amount     int                       `json:"amount"`                            // Amount of the order in minor units
+	Reported   string                    `gorm:"size:32" json:"reported,omitempty"` // Amount reported by the gateway, in major units
+	Detail     string                    `gorm:"size:512" json:"detail,omitempty"`
+	ResolvedAt *time.Time                `gorm:"index" json:"resolved_at,omitempty"`
+	ResolvedBy uint                      `json:"resolved_by,omitempty"`
+	Note       string                    `gorm:"size:512" json:"note,omitempty"`
+	User       User                      `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// Payment settings version actions
+const (
+	PaymentSettingsActionInitial  = "initial"  // Settings in place before the first recorded change
+	PaymentSettingsActionUpdate   = "update"
+	PaymentSettingsActionRollback = "rollback"
+)
+
+// PaymentSettingsVersion is a snapshot of the payment settings recorded every
+// time they change, so a bad change can be rolled back in one step
+type PaymentSettingsVersion struct {
+	gorm.Model
+	TenantID       uint   `gorm:"uniqueIndex:idx_payment_settings_tenant_version;default:1" json:"tenant_id"`
+	Version        int    `gorm:"uniqueIndex:idx_payment_settings_tenant_version" json:"version"`
+	PaymentEnabled bool   `json:"payment_enabled"`
+	MerchantID     string `gorm:"size:128" json:"epay_merchant_id"`
+	Secret         string `gorm:"size:256" json:"-"`
+	CallbackURL    string `gorm:"size:512" json:"epay_callback_url"`
+	GatewayURL     string `gorm:"size:512" json:"epay_gateway_url"`
+	AdminID        uint   `gorm:"index" json:"admin_id"`
+	Action         string `gorm:"size:32" json:"action"`
+	RolledBackTo   int    `json:"rolled_back_to,omitempty"` // Version restored by a rollback
+}
+
+// Inventory alert target types
+const (
+	InventoryTargetLotteryType = "lottery_type"
+	InventoryTargetProduct     = "product"
+)
+
+// InventoryAlert is raised when stock of a lottery type or product falls to its
+// low-stock threshold. It stays open until stock recovers above the threshold.
+type InventoryAlert struct {
+	gorm.Model
+	TargetType       string     `gorm:"size:32;index:idx_inventory_target" json:"target_type"`
+	TargetID         uint       `gorm:"index:idx_inventory_target" json:"target_id"`
+	TargetName       string     `gorm:"size:128" json:"target_name"`
+	Stock            int        `json:"stock"`
+	Threshold        int        `json:"threshold"`
+	VelocityPerHour  float64    `json:"velocity_per_hour"`            // Recent sales per hour
+	ProjectedSellOut *time.Time `json:"projected_sell_out,omitempty"` // Nil when there were no recent sales
+	WebhookStatus    string     `gorm:"size:32" json:"webhook_status"` // skipped, sent, failed
+	ResolvedAt       *time.Time `gorm:"index" json:"resolved_at,omitempty"`
+}
+
+// WalletWebhook is an outgoing webhook that mirrors wallet transactions to an
+// external system. Transactions after LastTransactionID are still to be enqueued.
+type WalletWebhook struct {
+	gorm.Model
+	Name              string `gorm:"size:64" json:"name"`
+	URL               string `gorm:"size:512" json:"url"`
+	Secret            string `gorm:"size:128" json:"-"`               // HMAC-SHA256 signing secret
+	Types             string `gorm:"size:256" json:"types"`           // Comma separated transaction types, empty = all
+	MinAmount         int    `json:"min_amount"`                      // Minimum absolute amount, 0 = all
+	Enabled           bool   `gorm:"default:true" json:"enabled"`
+	LastTransactionID uint   `json:"last_transaction_id"`
+}
+
+// WebhookDeliveryStatus defines the status of a webhook delivery
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryStatusPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryStatusDelivered WebhookDeliveryStatus = "delivered"
+	WebhookDeliveryStatusFailed    WebhookDeliveryStatus = "failed" // Gave up after the maximum attempts
+)
+
+// WebhookDelivery is an outbox entry for one transaction sent to one webhook
+type WebhookDelivery struct {
+	gorm.Model
+	WebhookID     uint                  `gorm:"uniqueIndex:idx_webhook_delivery" json:"webhook_id"`
+	TransactionID uint                  `gorm:"uniqueIndex:idx_webhook_delivery" json:"transaction_id"`
+	Payload       string                `gorm:"type:text" json:"payload"`
+	Status        WebhookDeliveryStatus `gorm:"size:32;index" json:"status"`
+	Attempts      int                   `json:"attempts"`
+	NextAttemptAt time.Time             `gorm:"index" json:"next_attempt_at"`
+	LastError     string                `gorm:"size:512" json:"last_error,omitempty"`
+	DeliveredAt   *time.Time            `json:"delivered_at,omitempty"`
+}
+
+// RequestStat aggregates the API requests to one route in one hour. Latencies
+// are counted in buckets, so percentiles can be estimated over any range of hours.
+type RequestStat struct {
+	ID             uint      `gorm:"primarykey" json:"id"`
+	TenantID       uint      `gorm:"uniqueIndex:idx_request_stat;default:1" json:"tenant_id"`
+	Hour           time.Time `gorm:"uniqueIndex:idx_request_stat;index" json:"hour"`
+	Method         string    `gorm:"uniqueIndex:idx_request_stat;size:16" json:"method"`
+	Route          string    `gorm:"uniqueIndex:idx_request_stat;size:255" json:"route"` // Route pattern, e.g. /api/lottery/tickets/:id
+	Requests       int64     `json:"requests"`
+	ClientErrors   int64     `json:"client_errors"` // 4xx responses
+	ServerErrors   int64     `json:"server_errors"` // 5xx responses
+	LatencyTotalMs int64     `json:"latency_total_ms"`
+	LatencyMaxMs   int64     `json:"latency_max_ms"`
+	LatencyLe10    int64     `json:"latency_le_10"` // Requests answered within 10ms
+	LatencyLe50    int64     `json:"latency_le_50"`
+	LatencyLe100   int64     `json:"latency_le_100"`
+	LatencyLe250   int64     `json:"latency_le_250"`
+	LatencyLe500   int64     `json:"latency_le_500"`
+	LatencyLe1000  int64     `json:"latency_le_1000"`
+	LatencyLe5000  int64     `json:"latency_le_5000"`
+	LatencyOver    int64     `json:"latency_over"` // Requests slower than 5s
+}
+
+// UserRequestStat counts the API requests of one signed-in user in one hour
+type UserRequestStat struct {
+	ID       uint      `gorm:"primarykey" json:"id"`
+	TenantID uint      `gorm:"uniqueIndex:idx_user_request_stat;default:1" json:"tenant_id"`
+	Hour     time.Time `gorm:"uniqueIndex:idx_user_request_stat;index" json:"hour"`
+	UserID   uint      `gorm:"uniqueIndex:idx_user_request_stat" json:"user_id"`
+	Requests int64     `json:"requests"`
+	Errors   int64     `json:"errors"` // 4xx and 5xx responses
+}
+
+// APIKeyRequestStat counts the requests of one partner API key to one route
+// in one hour. Keys are stored by fingerprint, never in full.
+type APIKeyRequestStat struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	Hour        time.Time `gorm:"uniqueIndex:idx_api_key_request_stat;index" json:"hour"`
+	KeyID       string    `gorm:"uniqueIndex:idx_api_key_request_stat;size:16" json:"key_id"` // Fingerprint of the API key
+	Method      string    `gorm:"uniqueIndex:idx_api_key_request_stat;size:16" json:"method"`
+	Route       string    `gorm:"uniqueIndex:idx_api_key_request_stat;size:255" json:"route"`
+	Requests    int64     `json:"requests"`
+	Errors      int64     `json:"errors"`       // 4xx and 5xx responses
+	RateLimited int64     `json:"rate_limited"` // 429 responses
+}
+
+// HealthCheck is the result of checking one component in one check slot.
+// Instances share the slots, so each slot is recorded once; slots without a
+// check count as downtime.
+type HealthCheck struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	Component string    `gorm:"uniqueIndex:idx_health_check;size:32" json:"component"`
+	Slot      time.Time `gorm:"uniqueIndex:idx_health_check;index" json:"slot"` // Check time truncated to the check interval
+	Healthy   bool      `json:"healthy"`
+	LatencyMs int64     `json:"latency_ms"`
+	Detail    string    `gorm:"size:255" json:"detail,omitempty"`
+}
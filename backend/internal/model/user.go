@@ -9,18 +9,24 @@ import (
 // User represents a user in the system
 type User struct {
 	gorm.Model
-	LinuxdoID string `gorm:"uniqueIndex;size:64" json:"linuxdo_id"`
-	Username  string `gorm:"size:128" json:"username"`
-	Avatar    string `gorm:"size:512" json:"avatar"`
-	Role      string `gorm:"size:32;default:user" json:"role"` // user, admin
-	Wallet    Wallet `gorm:"foreignKey:UserID" json:"wallet,omitempty"`
+	TenantID     uint   `gorm:"uniqueIndex:idx_users_tenant_linuxdo;default:1" json:"tenant_id"`
+	LinuxdoID    string `gorm:"uniqueIndex:idx_users_tenant_linuxdo;size:64" json:"linuxdo_id"`
+	Username     string `gorm:"size:128" json:"username"`
+	Avatar       string `gorm:"size:512" json:"avatar"`
+	Role         string `gorm:"size:32;default:user" json:"role"`      // user, admin
+	Demo         bool   `gorm:"index" json:"demo,omitempty"`           // Demo admin created by the demo bootstrap
+	MergedIntoID *uint  `gorm:"index" json:"merged_into_id,omitempty"` // Set once the account is merged into another as a duplicate
+	Wallet       Wallet `gorm:"foreignKey:UserID" json:"wallet,omitempty"`
 }
 
 // Wallet represents a user's wallet
 type Wallet struct {
 	gorm.Model
+	TenantID     uint          `gorm:"index;default:1" json:"tenant_id"`
 	UserID       uint          `gorm:"uniqueIndex" json:"user_id"`
-	Balance      int           `gorm:"default:50" json:"balance"` // Initial 50 points
+	Balance      int           `gorm:"default:50" json:"balance"`      // Initial 50 points
+	BonusBalance int           `gorm:"default:0" json:"bonus_balance"` // Bonus credits from promotions, spent on tickets only
+	FrozenAt     *time.Time    `json:"frozen_at,omitempty"`            // Set while debits are blocked pending review of a ledger discrepancy
 	Transactions []Transaction `gorm:"foreignKey:WalletID" json:"transactions,omitempty"`
 }
 
@@ -28,20 +34,375 @@ type Wallet struct {
 type TransactionType string
 
 const (
-	TransactionTypeInitial  TransactionType = "initial"
-	TransactionTypeRecharge TransactionType = "recharge"
-	TransactionTypePurchase TransactionType = "purchase"
-	TransactionTypeWin      TransactionType = "win"
-	TransactionTypeExchange TransactionType = "exchange"
+	TransactionTypeInitial       TransactionType = "initial"
+	TransactionTypeRecharge      TransactionType = "recharge"
+	TransactionTypePurchase      TransactionType = "purchase"
+	TransactionTypeWin           TransactionType = "win"
+	TransactionTypeExchange      TransactionType = "exchange"
+	TransactionTypeStreakBonus   TransactionType = "streak_bonus"
+	TransactionTypeCampaign      TransactionType = "campaign"
+	TransactionTypeCheckin       TransactionType = "checkin"
+	TransactionTypeOnboarding    TransactionType = "onboarding"
+	TransactionTypeRefund        TransactionType = "refund"
+	TransactionTypeBonusGrant    TransactionType = "bonus_grant"    // Bonus credits granted by a promotion
+	TransactionTypeBonusPurchase TransactionType = "bonus_purchase" // Bonus credits spent on tickets
+)
+
+// WalletCurrency is the balance of a wallet a transaction is booked on
+type WalletCurrency string
+
+const (
+	CurrencyPoints WalletCurrency = "points" // Wallet.Balance
+	CurrencyBonus  WalletCurrency = "bonus"  // Wallet.BonusBalance
 )
 
 // Transaction represents a wallet transaction
 type Transaction struct {
 	gorm.Model
+	TenantID    uint            `gorm:"index;default:1" json:"tenant_id"`
 	WalletID    uint            `gorm:"index" json:"wallet_id"`
 	Type        TransactionType `gorm:"size:32" json:"type"`
+	Currency    WalletCurrency  `gorm:"size:16;default:points;index" json:"currency"`
 	Amount      int             `json:"amount"` // Positive for credit, negative for debit
 	Description string          `gorm:"size:256" json:"description"`
-	ReferenceID uint            `json:"reference_id,omitempty"` // Related ticket or product ID
+	ReferenceID uint            `json:"reference_id,omitempty"` // Related ticket, purchase or exchange record ID
 	CreatedAt   time.Time       `json:"created_at"`
 }
+
+// RefundStatus defines the status of a refund
+type RefundStatus string
+
+const (
+	RefundStatusRolledBack RefundStatus = "rolled_back" // failed purchase whose charge was undone with it
+	RefundStatusPending    RefundStatus = "pending"     // charge kept, waiting for an admin to refund it
+	RefundStatusRefunded   RefundStatus = "refunded"    // points credited back
+	RefundStatusRejected   RefundStatus = "rejected"    // refused by an admin
+)
+
+// Refund gives the points of a purchase back to its buyer. Failed purchases
+// are recorded automatically; admins refund completed ones.
+type Refund struct {
+	gorm.Model
+	TenantID            uint           `gorm:"index;default:1" json:"tenant_id"`
+	UserID              uint           `gorm:"index" json:"user_id"`
+	LotteryTypeID       uint           `json:"lottery_type_id,omitempty"`
+	Quantity            int            `json:"quantity,omitempty"`
+	Amount              int            `json:"amount"`                                 // Points or bonus credits to give back
+	Currency            WalletCurrency `gorm:"size:16;default:points" json:"currency"` // Balance the refund is credited to, that of its purchase
+	PurchaseID          uint           `gorm:"index" json:"purchase_id,omitempty"`     // Purchase transaction refunded
+	RefundTransactionID uint           `json:"refund_transaction_id,omitempty"`        // Credit of the refund
+	Status              RefundStatus   `gorm:"size:16;index" json:"status"`
+	Reason              string         `gorm:"size:255" json:"reason,omitempty"`
+	Error               string         `gorm:"size:512" json:"error,omitempty"` // Why the purchase failed
+	ReviewedBy          uint           `json:"reviewed_by,omitempty"`
+	ReviewedAt          *time.Time     `json:"reviewed_at,omitempty"`
+}
+
+// TransactionDisputeStatus defines the status of a transaction dispute
+type TransactionDisputeStatus string
+
+const (
+	TransactionDisputeStatusOpen     TransactionDisputeStatus = "open"     // waiting for an admin
+	TransactionDisputeStatusUpheld   TransactionDisputeStatus = "upheld"   // the transaction stands
+	TransactionDisputeStatusRefunded TransactionDisputeStatus = "refunded" // points credited back
+)
+
+// TransactionDispute is a user's objection to a debit of their wallet, such
+// as an exchange they did not make. While open it freezes the card key of
+// the disputed exchange, or the user's prize payouts for a disputed ticket
+// purchase; a refunded exchange keeps its card key frozen.
+type TransactionDispute struct {
+	gorm.Model
+	TenantID         uint                     `gorm:"index;default:1" json:"tenant_id"`
+	UserID           uint                     `gorm:"index" json:"user_id"`
+	TransactionID    uint                     `gorm:"uniqueIndex" json:"transaction_id"`
+	TransactionType  TransactionType          `gorm:"size:32" json:"transaction_type"`
+	Amount           int                      `json:"amount"` // Points debited by the transaction
+	Reason           string                   `gorm:"size:255" json:"reason"`
+	Status           TransactionDisputeStatus `gorm:"size:16;index;default:open" json:"status"`
+	ExchangeRecordID uint                     `gorm:"index" json:"exchange_record_id,omitempty"` // Exchange whose card key is frozen
+	FreezesPayouts   bool                     `json:"freezes_payouts"`                           // Prize payouts of the user held while open
+	RefundID         uint                     `json:"refund_id,omitempty"`
+	Resolution       string                   `gorm:"size:255" json:"resolution,omitempty"`
+	ResolvedBy       uint                     `json:"resolved_by,omitempty"`
+	ResolvedAt       *time.Time               `json:"resolved_at,omitempty"`
+}
+
+// ScratchStreak tracks the consecutive days on which a user scratched a ticket.
+// Days are calendar days in server time, stored as YYYY-MM-DD.
+type ScratchStreak struct {
+	gorm.Model
+	TenantID        uint   `gorm:"index;default:1" json:"tenant_id"`
+	UserID          uint   `gorm:"uniqueIndex" json:"user_id"`
+	CurrentStreak   int    `json:"current_streak"`
+	LongestStreak   int    `json:"longest_streak"`
+	LastScratchDate string `gorm:"size:10" json:"last_scratch_date"`
+	TotalBonus      int    `json:"total_bonus"` // streak bonus points credited so far
+}
+
+// UserBadgeCounter keeps the badge counts of a user so they can be read
+// without counting. Counters are adjusted as events happen and periodically
+// reconciled against the source tables.
+type UserBadgeCounter struct {
+	gorm.Model
+	TenantID            uint      `gorm:"index;default:1" json:"tenant_id"`
+	UserID              uint      `gorm:"uniqueIndex" json:"user_id"`
+	UnscratchedTickets  int       `json:"unscratched_tickets"`
+	UnreadNotifications int       `json:"unread_notifications"`
+	PendingOrders       int       `json:"pending_orders"`
+	ReconciledAt        time.Time `json:"reconciled_at"`
+}
+
+// Wallet balance snapshot sources
+const (
+	BalanceSnapshotSourceScheduled = "scheduled" // Captured by the daily snapshot job
+	BalanceSnapshotSourceLedger    = "ledger"    // Recomputed from the transaction ledger on demand
+)
+
+// WalletBalanceSnapshot records a wallet's balance at the end of a day
+type WalletBalanceSnapshot struct {
+	gorm.Model
+	WalletID uint   `gorm:"uniqueIndex:idx_wallet_snapshot_date" json:"wallet_id"`
+	Date     string `gorm:"uniqueIndex:idx_wallet_snapshot_date;size:10" json:"date"` // Format: 2006-01-02
+	Balance  int    `json:"balance"`
+	Source   string `gorm:"size:32" json:"source"`
+}
+
+// WalletReconciliation records a wallet whose stored balance disagrees with the
+// sum of its transactions. It stays open until an admin resolves it.
+type WalletReconciliation struct {
+	gorm.Model
+	TenantID    uint           `gorm:"index;default:1" json:"tenant_id"`
+	WalletID    uint           `gorm:"index" json:"wallet_id"`
+	UserID      uint           `gorm:"index" json:"user_id"`
+	Currency    WalletCurrency `gorm:"size:16;default:points" json:"currency"` // Balance that disagreed: points or bonus credits
+	Balance     int            `json:"balance"`                                // Stored balance when last checked
+	LedgerTotal int            `json:"ledger_total"`                           // Sum of the wallet's transactions when last checked
+	Difference  int            `json:"difference"`                             // Balance minus ledger total
+	Frozen      bool           `json:"frozen"`                                 // The wallet was frozen when the discrepancy was found
+	ResolvedAt  *time.Time     `gorm:"index" json:"resolved_at,omitempty"`
+	ResolvedBy  uint           `json:"resolved_by,omitempty"`
+	Note        string         `gorm:"size:512" json:"note,omitempty"`
+	User        User           `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// AuthIncidentKind defines the kind of an authentication incident
+type AuthIncidentKind string
+
+const (
+	AuthIncidentLoginFailed  AuthIncidentKind = "login_failed"  // Login with an unknown or invalid account
+	AuthIncidentOAuthAnomaly AuthIncidentKind = "oauth_anomaly" // OAuth callback with an error, bad state or failed exchange
+	AuthIncidentLockout      AuthIncidentKind = "lockout"       // Account or IP locked after too many failures
+	AuthIncidentNewDevice    AuthIncidentKind = "new_device"    // Successful login from a device not seen before
+	AuthIncidentAdminDenied  AuthIncidentKind = "admin_denied"  // Request to the admin API refused
+	AuthIncidentSessionLimit AuthIncidentKind = "session_limit" // Login refused because its IP holds too many sessions
+)
+
+// AuthIncident records a failed or suspicious authentication attempt. Subject
+// is the account the attempt was made for, empty when it is not known yet.
+type AuthIncident struct {
+	gorm.Model
+	TenantID    uint             `gorm:"index;default:1" json:"tenant_id"`
+	Kind        AuthIncidentKind `gorm:"size:32;index" json:"kind"`
+	Subject     string           `gorm:"size:64;index" json:"subject"`
+	UserID      *uint            `gorm:"index" json:"user_id,omitempty"`
+	IP          string           `gorm:"size:64;index" json:"ip"`
+	UserAgent   string           `gorm:"size:256" json:"user_agent"`
+	Reason      string           `gorm:"size:256" json:"reason"`
+	LockedUntil *time.Time       `json:"locked_until,omitempty"` // Set on lockout incidents
+}
+
+// UserDevice is a device a user has logged in from, identified by a hash of
+// its user agent
+type UserDevice struct {
+	gorm.Model
+	TenantID    uint      `gorm:"index;default:1" json:"tenant_id"`
+	UserID      uint      `gorm:"uniqueIndex:idx_user_device" json:"user_id"`
+	Fingerprint string    `gorm:"uniqueIndex:idx_user_device;size:64" json:"-"`
+	UserAgent   string    `gorm:"size:256" json:"user_agent"`
+	LastIP      string    `gorm:"size:64" json:"last_ip"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+}
+
+// Login methods
+const (
+	LoginMethodOAuth = "oauth"
+	LoginMethodDev   = "dev"
+)
+
+// LoginRecord is a successful login of a user. The records are kept as the
+// login history users and support check for unfamiliar access.
+type LoginRecord struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	TenantID  uint      `gorm:"index;default:1" json:"tenant_id"`
+	UserID    uint      `gorm:"index" json:"user_id"`
+	Method    string    `gorm:"size:16" json:"method"`
+	IP        string    `gorm:"size:64" json:"ip"`
+	UserAgent string    `gorm:"size:256" json:"user_agent"`
+	NewDevice bool      `json:"new_device"` // First login from the device, other than the user's first device
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// Session revoke reasons
+const (
+	SessionRevokeLogout  = "logout"  // Ended by the user
+	SessionRevokeEvicted = "evicted" // Oldest session of a user over the session limit
+)
+
+// UserSession is a login of a user that is still refreshable. The tokens
+// issued for it carry its SessionID; a revoked session's tokens are refused.
+type UserSession struct {
+	ID           uint       `gorm:"primarykey" json:"id"`
+	TenantID     uint       `gorm:"index;default:1" json:"tenant_id"`
+	UserID       uint       `gorm:"index" json:"user_id"`
+	SessionID    string     `gorm:"size:64;uniqueIndex" json:"-"`
+	IP           string     `gorm:"size:64;index" json:"ip"`
+	UserAgent    string     `gorm:"size:256" json:"user_agent"`
+	ExpiresAt    time.Time  `gorm:"index" json:"expires_at"` // Pushed back on every refresh
+	LastUsedAt   time.Time  `json:"last_used_at"`
+	RevokedAt    *time.Time `gorm:"index" json:"revoked_at,omitempty"`
+	RevokeReason string     `gorm:"size:16" json:"revoke_reason,omitempty"`
+	CreatedAt    time.Time  `gorm:"index" json:"created_at"`
+}
+
+// Notification types
+const (
+	NotificationTypeNewDeviceLogin    = "new_device_login"
+	NotificationTypeWalletDiscrepancy = "wallet_discrepancy"
+	NotificationTypeCampaign          = "campaign"
+	NotificationTypeDigest            = "digest"
+	NotificationTypeTicketGift        = "ticket_gift"
+	NotificationTypePointsAdjusted    = "points_adjusted"
+	NotificationTypeSupportIssue      = "support_issue"
+)
+
+// Notification is a message shown to a user in their notification center
+type Notification struct {
+	gorm.Model
+	TenantID uint       `gorm:"index;default:1" json:"tenant_id"`
+	UserID   uint       `gorm:"index" json:"user_id"`
+	Type     string     `gorm:"size:32;index" json:"type"`
+	Title    string     `gorm:"size:128" json:"title"`
+	Content  string     `gorm:"size:1024" json:"content"`
+	ReadAt   *time.Time `json:"read_at,omitempty"`
+	// Batched notifications summarise several events of their type, newest
+	// first; BatchStartedAt is the time of the first
+	Count          int        `gorm:"default:1" json:"count"`
+	BatchStartedAt *time.Time `json:"batch_started_at,omitempty"`
+}
+
+// AccountMerge records the merge of a duplicate account into another. Moved
+// holds the IDs of the records moved from the source account as JSON, so the
+// merge can be rolled back.
+type AccountMerge struct {
+	gorm.Model
+	TenantID     uint       `gorm:"index;default:1" json:"tenant_id"`
+	SourceUserID uint       `gorm:"index" json:"source_user_id"`
+	TargetUserID uint       `gorm:"index" json:"target_user_id"`
+	AdminID      uint       `json:"admin_id"`
+	Reason       string     `gorm:"size:512" json:"reason"`
+	Balance      int        `json:"balance"`       // Points moved from the source wallet
+	BonusBalance int        `json:"bonus_balance"` // Bonus credits moved from the source wallet
+	Moved        string     `gorm:"type:text" json:"-"`
+	RolledBackAt *time.Time `json:"rolled_back_at,omitempty"`
+	RolledBackBy uint       `json:"rolled_back_by,omitempty"`
+}
+
+// UserNote is an internal note an admin keeps on a user, such as the record
+// of a support case. Notes are only ever shown to admins.
+type UserNote struct {
+	gorm.Model
+	TenantID uint   `gorm:"index;default:1" json:"tenant_id"`
+	UserID   uint   `gorm:"index" json:"user_id"`
+	AuthorID uint   `gorm:"index" json:"author_id"`
+	Content  string `gorm:"size:2000" json:"content"`
+	Pinned   bool   `json:"pinned"`
+}
+
+// SupportIssueStatus is the state of a support issue
+type SupportIssueStatus string
+
+const (
+	SupportIssueStatusOpen       SupportIssueStatus = "open"
+	SupportIssueStatusInProgress SupportIssueStatus = "in_progress"
+	SupportIssueStatusResolved   SupportIssueStatus = "resolved"
+	SupportIssueStatusClosed     SupportIssueStatus = "closed"
+)
+
+// SupportIssuePriority is how urgently a support issue is handled; it sets
+// the SLA deadline
+type SupportIssuePriority string
+
+const (
+	SupportIssuePriorityLow    SupportIssuePriority = "low"
+	SupportIssuePriorityNormal SupportIssuePriority = "normal"
+	SupportIssuePriorityHigh   SupportIssuePriority = "high"
+	SupportIssuePriorityUrgent SupportIssuePriority = "urgent"
+)
+
+// Support issue categories
+const (
+	SupportCategoryPaymentMissing = "payment_missing"
+	SupportCategoryTicketStuck    = "ticket_stuck"
+	SupportCategoryExchange       = "exchange"
+	SupportCategoryAccount        = "account"
+	SupportCategoryOther          = "other"
+)
+
+// SupportIssue is a problem reported by a user, such as a recharge that was
+// paid but not credited. It may link the payment order, lottery ticket and
+// exchange record concerned. DueAt is the SLA deadline for resolving it.
+type SupportIssue struct {
+	gorm.Model
+	TenantID         uint                 `gorm:"index;default:1" json:"tenant_id"`
+	UserID           uint                 `gorm:"index" json:"user_id"`
+	Category         string               `gorm:"size:32;index" json:"category"`
+	Subject          string               `gorm:"size:128" json:"subject"`
+	Description      string               `gorm:"size:2000" json:"description"`
+	Status           SupportIssueStatus   `gorm:"size:16;index;default:open" json:"status"`
+	Priority         SupportIssuePriority `gorm:"size:16;index;default:normal" json:"priority"`
+	AssigneeID       uint                 `gorm:"index" json:"assignee_id,omitempty"`
+	OrderNo          string               `gorm:"size:64;index" json:"order_no,omitempty"`
+	TicketID         uint                 `gorm:"index" json:"ticket_id,omitempty"`
+	ExchangeRecordID uint                 `gorm:"index" json:"exchange_record_id,omitempty"`
+	Resolution       string               `gorm:"size:2000" json:"resolution,omitempty"`
+	DueAt            time.Time            `gorm:"index" json:"due_at"`
+	FirstResponseAt  *time.Time           `json:"first_response_at,omitempty"`
+	ResolvedAt       *time.Time           `json:"resolved_at,omitempty"`
+}
+
+// NotificationPreference holds the notification choices of a user. Users
+// without a preference get the defaults: no weekly digest.
+type NotificationPreference struct {
+	ID           uint       `gorm:"primarykey" json:"id"`
+	TenantID     uint       `gorm:"index;default:1" json:"tenant_id"`
+	UserID       uint       `gorm:"uniqueIndex" json:"user_id"`
+	WeeklyDigest bool       `gorm:"index" json:"weekly_digest"`
+	LastDigestAt *time.Time `json:"last_digest_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// Segment is a group of users defined by rules on spending, activity and
+// balance, used to target campaigns and notifications. Memberships are
+// re-evaluated periodically.
+type Segment struct {
+	gorm.Model
+	TenantID    uint       `gorm:"index;default:1" json:"tenant_id"`
+	Name        string     `gorm:"size:64" json:"name"`
+	Description string     `gorm:"size:256" json:"description"`
+	Rules       string     `gorm:"type:text" json:"rules"` // JSON segment rules
+	Enabled     bool       `json:"enabled"`                // Disabled segments keep their members but are not re-evaluated
+	MemberCount int        `json:"member_count"`
+	EvaluatedAt *time.Time `json:"evaluated_at,omitempty"`
+}
+
+// UserSegment records that a user matched a segment at its last evaluation
+type UserSegment struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	TenantID  uint      `gorm:"index;default:1" json:"tenant_id"`
+	SegmentID uint      `gorm:"uniqueIndex:idx_user_segments_segment_user" json:"segment_id"`
+	UserID    uint      `gorm:"uniqueIndex:idx_user_segments_segment_user;index" json:"user_id"`
+	CreatedAt time.Time `json:"created_at"` // When the user joined the segment
+	User      User      `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"time"
+
+	"scratch-lottery/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// adminLogChainLock namespaces the postgres advisory locks that serialize
+// appending to a tenant's admin log chain
+const adminLogChainLock = 0x61646d6e // "admn"
+
+// AdminLogChainPlugin links every new admin log to the previous log of its
+// tenant: it assigns the next sequence number, the previous log's hash and
+// the log's own hash. Logs must be created one struct at a time, after the
+// tenant plugin has assigned their tenant.
+type AdminLogChainPlugin struct{}
+
+// Name implements gorm.Plugin
+func (AdminLogChainPlugin) Name() string {
+	return "admin_log_chain"
+}
+
+// Initialize implements gorm.Plugin
+func (AdminLogChainPlugin) Initialize(db *gorm.DB) error {
+	callback := db.Callback().Create().Before("gorm:create")
+	if db.Callback().Create().Get("tenant:create") != nil {
+		callback = callback.After("tenant:create")
+	}
+	return callback.Register("admin_log_chain:create", chainAdminLog)
+}
+
+// AdminLogHash returns the hash of an admin log over its chained fields and
+// the hash of its predecessor
+func AdminLogHash(log *model.AdminLog) string {
+	var seq uint64
+	if log.Seq != nil {
+		seq = *log.Seq
+	}
+	// A JSON array keeps the field boundaries unambiguous
+	payload, _ := json.Marshal([]interface{}{
+		log.TenantID, seq, log.AdminID, log.Action, log.TargetType, log.TargetID,
+		log.Details, log.CreatedAt.UnixMicro(), log.PrevHash,
+	})
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// chainAdminLog assigns the chain fields of a new admin log
+func chainAdminLog(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil || db.Statement.Schema.Table != "admin_logs" {
+		return
+	}
+	rv := db.Statement.ReflectValue
+	if rv.Kind() != reflect.Struct {
+		_ = db.AddError(errors.New("admin logs must be created one at a time"))
+		return
+	}
+	log, ok := rv.Addr().Interface().(*model.AdminLog)
+	if !ok {
+		return
+	}
+	if log.TenantID == 0 {
+		log.TenantID = DefaultTenantID
+	}
+	// Stored timestamps keep microseconds, so the hash must not depend on more
+	if log.CreatedAt.IsZero() {
+		log.CreatedAt = time.Now()
+	}
+	log.CreatedAt = log.CreatedAt.Truncate(time.Microsecond)
+
+	tx := db.Session(&gorm.Session{NewDB: true})
+	if db.Dialector.Name() == "postgres" {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?, ?)", adminLogChainLock, log.TenantID).Error; err != nil {
+			_ = db.AddError(err)
+			return
+		}
+	}
+
+	var prev model.AdminLog
+	err := tx.Unscoped().Select("seq", "hash").
+		Where("tenant_id = ? AND seq IS NOT NULL", log.TenantID).
+		Order("seq DESC").
+		Limit(1).
+		Find(&prev).Error
+	if err != nil {
+		_ = db.AddError(err)
+		return
+	}
+
+	seq := uint64(1)
+	if prev.Seq != nil {
+		seq = *prev.Seq + 1
+	}
+	log.Seq = &seq
+	log.PrevHash = prev.Hash
+	log.Hash = AdminLogHash(log)
+}
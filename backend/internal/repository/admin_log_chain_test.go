@@ -0,0 +1,69 @@
+package repository_test
+
+import (
+	"testing"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+func setupAdminLogChainTestDB(t *testing.T) *gorm.DB {
+	db := setupTenantTestDB(t)
+	if err := db.Use(repository.AdminLogChainPlugin{}); err != nil {
+		t.Fatalf("Failed to register admin log chain plugin: %v", err)
+	}
+	return db
+}
+
+func TestAdminLogChainLinksLogsPerTenant(t *testing.T) {
+	db := setupAdminLogChainTestDB(t)
+
+	first := repository.ScopeTenant(db, repository.DefaultTenantID)
+	second := repository.ScopeTenant(db, 2)
+	for i := 0; i < 3; i++ {
+		if err := first.Create(&model.AdminLog{AdminID: 1, Action: "update_config", Details: `{"i":1}`}).Error; err != nil {
+			t.Fatalf("Failed to create admin log: %v", err)
+		}
+	}
+	if err := second.Create(&model.AdminLog{AdminID: 2, Action: "update_config"}).Error; err != nil {
+		t.Fatalf("Failed to create admin log: %v", err)
+	}
+	// Unscoped sessions chain into the default tenant
+	if err := db.Create(&model.AdminLog{AdminID: 1, Action: "system"}).Error; err != nil {
+		t.Fatalf("Failed to create admin log: %v", err)
+	}
+
+	var logs []model.AdminLog
+	first.Order("seq ASC").Find(&logs)
+	if len(logs) != 4 {
+		t.Fatalf("Expected 4 logs of the default tenant, got %d", len(logs))
+	}
+	prevHash := ""
+	for i, log := range logs {
+		if log.Seq == nil || *log.Seq != uint64(i+1) {
+			t.Errorf("Expected seq %d, got %v", i+1, log.Seq)
+		}
+		if log.PrevHash != prevHash {
+			t.Errorf("Log %d does not link to its predecessor", i+1)
+		}
+		if log.Hash == "" || repository.AdminLogHash(&log) != log.Hash {
+			t.Errorf("Log %d does not match its stored hash", i+1)
+		}
+		prevHash = log.Hash
+	}
+
+	var other model.AdminLog
+	second.First(&other)
+	if other.Seq == nil || *other.Seq != 1 || other.PrevHash != "" {
+		t.Errorf("Expected tenant 2 to start its own chain, got %+v", other)
+	}
+
+	// Any change of a chained field changes the hash
+	edited := logs[1]
+	edited.Details = `{"i":2}`
+	if repository.AdminLogHash(&edited) == logs[1].Hash {
+		t.Errorf("Expected the edited log to hash differently")
+	}
+}
@@ -0,0 +1,260 @@
+package repository
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"scratch-lottery/pkg/redact"
+
+	"gorm.io/gorm"
+)
+
+// anonymizeKind is how the values of a column are replaced
+type anonymizeKind int
+
+const (
+	// anonymizePseudonym replaces a value with a prefixed keyed hash, so equal
+	// values stay equal across columns and unique values stay unique
+	anonymizePseudonym anonymizeKind = iota
+	// anonymizeScramble replaces letters and digits with others of the same
+	// kind, keeping the length and separators of codes and keys
+	anonymizeScramble
+	// anonymizeIP maps an address to one in 10.0.0.0/8
+	anonymizeIP
+	// anonymizeMask replaces every character of free text with '*'
+	anonymizeMask
+	// anonymizeClear empties the value
+	anonymizeClear
+)
+
+// anonymizeColumn is a column holding personal or secret data
+type anonymizeColumn struct {
+	Table  string
+	Column string
+	Kind   anonymizeKind
+	Prefix string // Pseudonym prefix
+}
+
+// anonymizedColumns lists the columns AnonymizeDatabase rewrites. Columns
+// sharing a pseudonym prefix reference each other and map identically.
+// Admin logs are left alone, since rewriting them would break their hash
+// chain; foreign keys, amounts, statuses and timestamps are never touched.
+var anonymizedColumns = []anonymizeColumn{
+	{Table: "users", Column: "linuxdo_id", Kind: anonymizePseudonym, Prefix: "anon_"},
+	{Table: "users", Column: "username", Kind: anonymizePseudonym, Prefix: "user_"},
+	{Table: "users", Column: "avatar", Kind: anonymizeClear},
+	{Table: "auth_incidents", Column: "subject", Kind: anonymizePseudonym, Prefix: "anon_"},
+	{Table: "auth_incidents", Column: "ip", Kind: anonymizeIP},
+	{Table: "login_records", Column: "ip", Kind: anonymizeIP},
+	{Table: "user_devices", Column: "last_ip", Kind: anonymizeIP},
+	{Table: "user_sessions", Column: "ip", Kind: anonymizeIP},
+	{Table: "card_key_reveals", Column: "ip_address", Kind: anonymizeIP},
+	{Table: "voucher_claim_failures", Column: "ip", Kind: anonymizeIP},
+	{Table: "card_keys", Column: "key_content", Kind: anonymizeScramble},
+	{Table: "payment_orders", Column: "order_no", Kind: anonymizePseudonym, Prefix: "PO"},
+	{Table: "payment_orders", Column: "trade_no", Kind: anonymizeScramble},
+	{Table: "payment_reconciliations", Column: "order_no", Kind: anonymizePseudonym, Prefix: "PO"},
+	{Table: "payment_reconciliations", Column: "note", Kind: anonymizeMask},
+	{Table: "support_issues", Column: "order_no", Kind: anonymizePseudonym, Prefix: "PO"},
+	{Table: "support_issues", Column: "description", Kind: anonymizeMask},
+	{Table: "support_issues", Column: "resolution", Kind: anonymizeMask},
+	{Table: "user_notes", Column: "content", Kind: anonymizeMask},
+	{Table: "transaction_disputes", Column: "reason", Kind: anonymizeMask},
+	{Table: "transaction_disputes", Column: "resolution", Kind: anonymizeMask},
+	{Table: "exchange_gifts", Column: "message", Kind: anonymizeMask},
+	{Table: "ticket_transfers", Column: "message", Kind: anonymizeMask},
+	{Table: "prize_claims", Column: "full_name", Kind: anonymizeMask},
+	{Table: "prize_claims", Column: "contact", Kind: anonymizeScramble},
+	{Table: "prize_claims", Column: "id_number_encrypted", Kind: anonymizeClear},
+	{Table: "payment_settings_versions", Column: "merchant_id", Kind: anonymizeScramble},
+	{Table: "payment_settings_versions", Column: "secret", Kind: anonymizeScramble},
+	{Table: "wallet_webhooks", Column: "secret", Kind: anonymizeScramble},
+}
+
+// anonymizeBatchSize is the number of rows read at a time
+const anonymizeBatchSize = 500
+
+// AnonymizeResult counts the values replaced, by "table.column"
+type AnonymizeResult struct {
+	Columns map[string]int64
+}
+
+// Total returns the number of values replaced
+func (r *AnonymizeResult) Total() int64 {
+	var total int64
+	for _, n := range r.Columns {
+		total += n
+	}
+	return total
+}
+
+// String lists the counts by column, sorted
+func (r *AnonymizeResult) String() string {
+	names := make([]string, 0, len(r.Columns))
+	for name := range r.Columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s: %d\n", name, r.Columns[name])
+	}
+	return b.String()
+}
+
+// AnonymizeDatabase replaces the personal and secret data of a database in
+// place, for staging copies of production: user identities, IP addresses,
+// card keys, payment order references, free text written by users and
+// stored secrets, including sensitive system settings. Ids and every other
+// column are kept, so relations and the shape of the data survive.
+//
+// Pseudonyms are keyed with a random key that is not kept, so they cannot be
+// reversed by hashing candidate values. Without apply only the values that
+// would be replaced are counted. The rewrite runs in one transaction.
+func AnonymizeDatabase(db *gorm.DB, apply bool) (*AnonymizeResult, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	a := anonymizer{key: key}
+
+	result := &AnonymizeResult{Columns: make(map[string]int64)}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, column := range anonymizedColumns {
+			if !tx.Migrator().HasTable(column.Table) {
+				continue
+			}
+			n, err := a.anonymizeRows(tx, column, nil, apply)
+			if err != nil {
+				return fmt.Errorf("anonymizing %s.%s: %w", column.Table, column.Column, err)
+			}
+			result.Columns[column.Table+"."+column.Column] = n
+		}
+
+		// Sensitive settings, such as payment keys
+		var keys []string
+		if err := tx.Table("system_configs").Distinct("key").Pluck("key", &keys).Error; err != nil {
+			return err
+		}
+		var sensitive []string
+		for _, k := range keys {
+			if redact.IsSensitive(k) {
+				sensitive = append(sensitive, k)
+			}
+		}
+		if len(sensitive) > 0 {
+			column := anonymizeColumn{Table: "system_configs", Column: "value", Kind: anonymizeScramble}
+			n, err := a.anonymizeRows(tx, column, func(query *gorm.DB) *gorm.DB {
+				return query.Where("key IN ?", sensitive)
+			}, apply)
+			if err != nil {
+				return fmt.Errorf("anonymizing system_configs.value: %w", err)
+			}
+			result.Columns["system_configs.value"] = n
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// anonymizeRows replaces the non-empty values of a column row by row in id
+// order, restricted by scope when given. Returns the number of values
+// replaced, or that would be without apply.
+func (a anonymizer) anonymizeRows(tx *gorm.DB, column anonymizeColumn, scope func(*gorm.DB) *gorm.DB, apply bool) (int64, error) {
+	var count int64
+	var lastID uint
+	for {
+		var rows []struct {
+			ID    uint
+			Value string
+		}
+		query := tx.Table(column.Table).
+			Select("id, "+column.Column+" AS value").
+			Where("id > ? AND "+column.Column+" IS NOT NULL AND "+column.Column+" <> ''", lastID)
+		if scope != nil {
+			query = scope(query)
+		}
+		if err := query.Order("id ASC").Limit(anonymizeBatchSize).Scan(&rows).Error; err != nil {
+			return count, err
+		}
+		if len(rows) == 0 {
+			return count, nil
+		}
+		for _, row := range rows {
+			if apply {
+				if err := tx.Table(column.Table).Where("id = ?", row.ID).Update(column.Column, a.replace(column, row.Value)).Error; err != nil {
+					return count, err
+				}
+			}
+			count++
+		}
+		lastID = rows[len(rows)-1].ID
+	}
+}
+
+// anonymizer derives replacement values from a secret key
+type anonymizer struct {
+	key []byte
+}
+
+// replace returns the replacement of a value of the column
+func (a anonymizer) replace(column anonymizeColumn, value string) string {
+	switch column.Kind {
+	case anonymizePseudonym:
+		return column.Prefix + hex.EncodeToString(a.digest(value, 0)[:8])
+	case anonymizeScramble:
+		return a.scramble(value)
+	case anonymizeIP:
+		sum := a.digest(value, 0)
+		return fmt.Sprintf("10.%d.%d.%d", sum[0], sum[1], sum[2])
+	case anonymizeMask:
+		return strings.Map(func(r rune) rune {
+			if unicode.IsSpace(r) {
+				return r
+			}
+			return '*'
+		}, value)
+	default:
+		return ""
+	}
+}
+
+// scramble replaces each letter and digit by one of the same case or kind
+// derived from the whole value, so equal values scramble identically
+func (a anonymizer) scramble(value string) string {
+	var stream []byte
+	for block := 0; len(stream) < len(value); block++ {
+		stream = append(stream, a.digest(value, block)...)
+	}
+	runes := []rune(value)
+	for i, r := range runes {
+		b := int(stream[i])
+		switch {
+		case r >= 'a' && r <= 'z':
+			runes[i] = rune('a' + b%26)
+		case r >= 'A' && r <= 'Z':
+			runes[i] = rune('A' + b%26)
+		case r >= '0' && r <= '9':
+			runes[i] = rune('0' + b%10)
+		}
+	}
+	return string(runes)
+}
+
+// digest returns a block of the keyed hash of a value
+func (a anonymizer) digest(value string, block int) []byte {
+	mac := hmac.New(sha256.New, a.key)
+	_ = binary.Write(mac, binary.BigEndian, uint32(block))
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,99 @@
+package repository_test
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+)
+
+func TestAnonymizeDatabase(t *testing.T) {
+	db := setupTenantTestDB(t)
+
+	users := []model.User{
+		{LinuxdoID: "10001", Username: "alice", Avatar: "https://example.com/a.png"},
+		{LinuxdoID: "10002", Username: "bob"},
+	}
+	for i := range users {
+		if err := db.Create(&users[i]).Error; err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+	db.Create(&model.AuthIncident{Kind: "login_failed", Subject: "10001", IP: "203.0.113.7"})
+	db.Create(&model.LoginRecord{UserID: users[0].ID, IP: "203.0.113.7"})
+	db.Create(&model.PaymentOrder{UserID: users[0].ID, OrderNo: "R20261016000001", Amount: 100, TradeNo: "T-8842"})
+	db.Create(&model.SupportIssue{UserID: users[0].ID, OrderNo: "R20261016000001", Description: "我的订单没到账"})
+	db.Create(&model.CardKey{ProductID: 1, KeyContent: "ABCD-1234-efgh"})
+	db.Create(&model.CardKey{ProductID: 1, KeyContent: "ABCD-1234-efgh"})
+	db.Create(&model.SystemConfig{Key: "epay_key", Value: "s3cret"})
+	db.Create(&model.SystemConfig{Key: "site_name", Value: "Lottery"})
+
+	// Without apply nothing changes
+	counted, err := repository.AnonymizeDatabase(db, false)
+	if err != nil {
+		t.Fatalf("AnonymizeDatabase failed: %v", err)
+	}
+	if counted.Columns["users.username"] != 2 || counted.Columns["users.avatar"] != 1 || counted.Columns["system_configs.value"] != 1 {
+		t.Errorf("Unexpected counts %v", counted.Columns)
+	}
+	var unchanged model.User
+	db.First(&unchanged, users[0].ID)
+	if unchanged.Username != "alice" {
+		t.Fatalf("Expected a dry run to change nothing, got %s", unchanged.Username)
+	}
+
+	applied, err := repository.AnonymizeDatabase(db, true)
+	if err != nil {
+		t.Fatalf("AnonymizeDatabase failed: %v", err)
+	}
+	if applied.Total() != counted.Total() {
+		t.Errorf("Expected %d values replaced, got %d", counted.Total(), applied.Total())
+	}
+
+	var anonymized []model.User
+	db.Order("id").Find(&anonymized)
+	if anonymized[0].ID != users[0].ID || anonymized[0].Username == "alice" || anonymized[0].LinuxdoID == "10001" || anonymized[0].Avatar != "" {
+		t.Errorf("Expected the identity replaced, got %+v", anonymized[0])
+	}
+	if anonymized[0].Username == anonymized[1].Username || anonymized[0].LinuxdoID == anonymized[1].LinuxdoID {
+		t.Error("Expected distinct users to stay distinct")
+	}
+
+	// References between columns survive
+	var incident model.AuthIncident
+	db.First(&incident)
+	if incident.Subject != anonymized[0].LinuxdoID || !strings.HasPrefix(incident.IP, "10.") {
+		t.Errorf("Expected the incident to reference the pseudonym, got %+v", incident)
+	}
+	var login model.LoginRecord
+	db.First(&login)
+	if login.IP != incident.IP || login.UserID != users[0].ID {
+		t.Errorf("Expected the same address mapped alike, got %s and %s", login.IP, incident.IP)
+	}
+	var order model.PaymentOrder
+	var issue model.SupportIssue
+	db.First(&order)
+	db.First(&issue)
+	if order.OrderNo == "R20261016000001" || issue.OrderNo != order.OrderNo || order.Amount != 100 {
+		t.Errorf("Expected the order number replaced consistently, got %s and %s", order.OrderNo, issue.OrderNo)
+	}
+	if !regexp.MustCompile(`^[A-Z]-\d{4}$`).MatchString(order.TradeNo) || issue.Description != "*******" {
+		t.Errorf("Unexpected trade number %s or description %s", order.TradeNo, issue.Description)
+	}
+
+	// Card keys keep their format, and duplicates stay duplicates
+	var keys []model.CardKey
+	db.Order("id").Find(&keys)
+	if keys[0].KeyContent == "ABCD-1234-efgh" || !regexp.MustCompile(`^[A-Z]{4}-\d{4}-[a-z]{4}$`).MatchString(keys[0].KeyContent) || keys[0].KeyContent != keys[1].KeyContent {
+		t.Errorf("Unexpected card keys %s and %s", keys[0].KeyContent, keys[1].KeyContent)
+	}
+
+	var secret, siteName model.SystemConfig
+	db.Where("key = ?", "epay_key").First(&secret)
+	db.Where("key = ?", "site_name").First(&siteName)
+	if secret.Value == "s3cret" || len(secret.Value) != len("s3cret") || siteName.Value != "Lottery" {
+		t.Errorf("Expected only the secret setting replaced, got %s and %s", secret.Value, siteName.Value)
+	}
+}
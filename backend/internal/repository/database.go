@@ -34,7 +34,7 @@ func InitDB(cfg *config.Config) (*gorm.DB, error) {
 		dialector = sqlite.Open(cfg.DBPath)
 	case "postgres":
 		dsn := fmt.Sprintf(
-			"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+			"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable TimeZone=UTC",
 			cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName,
 		)
 		dialector = postgres.Open(dsn)
@@ -48,6 +48,16 @@ func InitDB(cfg *config.Config) (*gorm.DB, error) {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	// Enforce tenant isolation for tenant-scoped sessions
+	if err := db.Use(TenantPlugin{}); err != nil {
+		return nil, fmt.Errorf("failed to register tenant plugin: %w", err)
+	}
+
+	// Chain admin logs so edits and deletions can be detected
+	if err := db.Use(AdminLogChainPlugin{}); err != nil {
+		return nil, fmt.Errorf("failed to register admin log chain plugin: %w", err)
+	}
+
 	return db, nil
 }
 
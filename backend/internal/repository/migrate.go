@@ -6,30 +6,128 @@ import (
 	"gorm.io/gorm"
 )
 
+// legacyUniqueIndexes were global unique indexes that became unique per tenant
+var legacyUniqueIndexes = []struct {
+	model interface{}
+	name  string
+}{
+	{&model.User{}, "idx_users_linuxdo_id"},
+	{&model.SystemConfig{}, "idx_system_configs_key"},
+	{&model.PaymentSettingsVersion{}, "idx_payment_settings_versions_version"},
+}
+
 // AutoMigrate runs database migrations for all models
 func AutoMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(
+	for _, index := range legacyUniqueIndexes {
+		if db.Migrator().HasIndex(index.model, index.name) {
+			if err := db.Migrator().DropIndex(index.model, index.name); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := db.AutoMigrate(
+		// Tenant related
+		&model.Tenant{},
+
 		// User related
 		&model.User{},
 		&model.Wallet{},
 		&model.Transaction{},
+		&model.Refund{},
+		&model.TransactionDispute{},
+		&model.WalletBalanceSnapshot{},
+		&model.WalletReconciliation{},
+		&model.PaymentReconciliation{},
+		&model.ScratchStreak{},
+		&model.UserBadgeCounter{},
+		&model.AuthIncident{},
+		&model.UserDevice{},
+		&model.LoginRecord{},
+		&model.UserSession{},
+		&model.Notification{},
+		&model.NotificationPreference{},
+		&model.UserNote{},
+		&model.SupportIssue{},
+		&model.AccountMerge{},
+		&model.Segment{},
+		&model.UserSegment{},
 
 		// Lottery related
 		&model.LotteryType{},
 		&model.PrizeLevel{},
 		&model.PrizePool{},
+		&model.PrizeTemplate{},
 		&model.Ticket{},
+		&model.TicketHistory{},
+		&model.TicketEvent{},
+		&model.TicketTransfer{},
+		&model.StockReservation{},
+		&model.ScratchConfirmation{},
+		&model.PurchaseRequestRecord{},
+		&model.VoucherClaimFailure{},
+		&model.TicketAreaScratch{},
+		&model.PrizeClaim{},
+		&model.OddsDisclosure{},
+		&model.Jackpot{},
 
 		// Exchange related
 		&model.Product{},
 		&model.CardKey{},
 		&model.ExchangeRecord{},
+		&model.ExchangeGift{},
+		&model.CardKeyReveal{},
+
+		// Campaign related
+		&model.Campaign{},
+		&model.CampaignReward{},
+		&model.Coupon{},
+		&model.CheckIn{},
+		&model.OnboardingStep{},
 
 		// System related
 		&model.SystemConfig{},
 		&model.AdminLog{},
+		&model.DashboardLayout{},
 		&model.PaymentOrder{},
-	)
+		&model.PaymentSettingsVersion{},
+		&model.InventoryAlert{},
+		&model.WalletWebhook{},
+		&model.WebhookDelivery{},
+		&model.RequestStat{},
+		&model.UserRequestStat{},
+		&model.APIKeyRequestStat{},
+		&model.HealthCheck{},
+	); err != nil {
+		return err
+	}
+
+	return ensureDefaultTenant(db)
+}
+
+// ensureDefaultTenant creates the default tenant that owns pre-existing data
+func ensureDefaultTenant(db *gorm.DB) error {
+	var count int64
+	if err := db.Unscoped().Model(&model.Tenant{}).Where("id = ?", DefaultTenantID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	tenant := model.Tenant{
+		Model:  gorm.Model{ID: DefaultTenantID},
+		Name:   "默认运营方",
+		Slug:   "default",
+		Status: model.TenantStatusActive,
+	}
+	if err := db.Create(&tenant).Error; err != nil {
+		return err
+	}
+	// The explicit ID does not advance the postgres sequence
+	if db.Dialector.Name() == "postgres" {
+		return db.Exec("SELECT setval(pg_get_serial_sequence('tenants', 'id'), (SELECT MAX(id) FROM tenants))").Error
+	}
+	return nil
 }
 
 // SeedDevData seeds development data for testing
@@ -0,0 +1,76 @@
+package repository_test
+
+import (
+	"testing"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+)
+
+func TestMigrateBindsLegacyPrizeLevelsToFirstVersion(t *testing.T) {
+	db := setupTenantTestDB(t)
+
+	// Recreate the schema from before prize levels were versioned
+	columns := []struct {
+		model  interface{}
+		column string
+	}{
+		{&model.LotteryType{}, "PrizeLevelVersion"},
+		{&model.PrizeLevel{}, "Version"},
+		{&model.PrizePool{}, "PrizeLevelVersion"},
+	}
+	for _, c := range columns {
+		if err := db.Migrator().DropColumn(c.model, c.column); err != nil {
+			t.Fatalf("Failed to drop %s: %v", c.column, err)
+		}
+	}
+	if err := db.Exec("INSERT INTO lottery_types (id, tenant_id, name, status) VALUES (1, 1, 'Legacy', 'available')").Error; err != nil {
+		t.Fatalf("Failed to insert lottery type: %v", err)
+	}
+	if err := db.Exec("INSERT INTO prize_levels (lottery_type_id, level, name, quantity, remaining) VALUES (1, 1, 'First', 5, 3)").Error; err != nil {
+		t.Fatalf("Failed to insert prize level: %v", err)
+	}
+	if err := db.Exec("INSERT INTO prize_pools (lottery_type_id, total_tickets, sold_tickets, status) VALUES (1, 10, 2, 'active')").Error; err != nil {
+		t.Fatalf("Failed to insert prize pool: %v", err)
+	}
+
+	if err := repository.AutoMigrate(db); err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+
+	var lotteryType model.LotteryType
+	var level model.PrizeLevel
+	var pool model.PrizePool
+	db.First(&lotteryType, 1)
+	db.Where("lottery_type_id = ?", 1).First(&level)
+	db.Where("lottery_type_id = ?", 1).First(&pool)
+	if lotteryType.PrizeLevelVersion != 1 || level.Version != 1 || pool.PrizeLevelVersion != 1 {
+		t.Errorf("Expected version 1 everywhere, got type %d, level %d, pool %d",
+			lotteryType.PrizeLevelVersion, level.Version, pool.PrizeLevelVersion)
+	}
+	if level.Remaining != 3 {
+		t.Errorf("Expected the remaining quantity to be kept, got %d", level.Remaining)
+	}
+}
+
+func TestMigrateMarksLegacyPaymentOrdersAsYuan(t *testing.T) {
+	db := setupTenantTestDB(t)
+
+	// Recreate the schema from before order amounts carried a currency
+	if err := db.Migrator().DropColumn(&model.PaymentOrder{}, "Currency"); err != nil {
+		t.Fatalf("Failed to drop currency: %v", err)
+	}
+	if err := db.Exec("INSERT INTO payment_orders (user_id, order_no, amount, points, status) VALUES (1, 'LEGACY', 1000, 100, 'paid')").Error; err != nil {
+		t.Fatalf("Failed to insert payment order: %v", err)
+	}
+
+	if err := repository.AutoMigrate(db); err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+
+	var order model.PaymentOrder
+	db.Where("order_no = ?", "LEGACY").First(&order)
+	if order.Currency != "CNY" || order.Amount != 1000 {
+		t.Errorf("Expected 1000 fen in CNY, got %d %q", order.Amount, order.Currency)
+	}
+}
@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"scratch-lottery/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// The transactions table can be partitioned by month on postgres. Partitions
+// are named after their month (transactions_p202610) and cover the month in
+// UTC; a default partition catches rows no monthly partition covers.
+const (
+	transactionsTable        = "transactions"
+	legacyTransactionsTable  = "transactions_unpartitioned"
+	defaultTransactionsTable = "transactions_default"
+)
+
+var ErrPartitioningUnsupported = errors.New("table partitioning requires postgres")
+
+// TransactionPartitionName returns the partition holding transactions created
+// in the month of t
+func TransactionPartitionName(t time.Time) string {
+	return fmt.Sprintf("%s_p%s", transactionsTable, t.UTC().Format("200601"))
+}
+
+// TransactionsBetween returns a query on transactions created between from
+// and to, both inclusive. A zero bound is left open. Bounding reads by date
+// lets postgres skip the partitions outside the range.
+func TransactionsBetween(db *gorm.DB, from, to time.Time) *gorm.DB {
+	query := db.Model(&model.Transaction{})
+	if !from.IsZero() {
+		query = query.Where("created_at >= ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("created_at <= ?", to)
+	}
+	return query
+}
+
+// TransactionsPartitioned reports whether the transactions table is
+// partitioned. It is always false outside postgres.
+func TransactionsPartitioned(db *gorm.DB) (bool, error) {
+	if db.Dialector.Name() != "postgres" {
+		return false, nil
+	}
+	var count int64
+	err := db.Raw(
+		"SELECT COUNT(*) FROM pg_partitioned_table WHERE partrelid = to_regclass(?)",
+		transactionsTable,
+	).Scan(&count).Error
+	return count > 0, err
+}
+
+// EnsureTransactionPartitions creates the monthly partitions from the month of
+// from through monthsAhead months after the current month. Existing
+// partitions are kept. Returns the names of the partitions created.
+func EnsureTransactionPartitions(db *gorm.DB, from time.Time, monthsAhead int) ([]string, error) {
+	if db.Dialector.Name() != "postgres" {
+		return nil, ErrPartitioningUnsupported
+	}
+
+	var created []string
+	month := monthStart(from)
+	last := monthStart(time.Now()).AddDate(0, monthsAhead, 0)
+	for ; !month.After(last); month = month.AddDate(0, 1, 0) {
+		name := TransactionPartitionName(month)
+		var exists bool
+		if err := db.Raw("SELECT to_regclass(?) IS NOT NULL", name).Scan(&exists).Error; err != nil {
+			return created, err
+		}
+		if exists {
+			continue
+		}
+		if err := db.Exec(fmt.Sprintf(
+			"CREATE TABLE %s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s')",
+			name, transactionsTable, month.Format(time.RFC3339), month.AddDate(0, 1, 0).Format(time.RFC3339),
+		)).Error; err != nil {
+			return created, fmt.Errorf("failed to create partition %s: %w", name, err)
+		}
+		created = append(created, name)
+	}
+	return created, nil
+}
+
+// PartitionTransactionsResult describes a conversion of the transactions table
+type PartitionTransactionsResult struct {
+	Rows       int64
+	Partitions []string
+	LegacyKept bool // the old table was kept as transactions_unpartitioned
+}
+
+// PartitionTransactions converts the unpartitioned transactions table into a
+// table partitioned by month, in one database transaction. The rows are
+// copied into monthly partitions from the oldest transaction through
+// monthsAhead months ahead; ids and their sequence are kept. The old table is
+// renamed to transactions_unpartitioned for verification, or dropped when
+// dropLegacy is set. Writes to transactions are blocked while it runs.
+func PartitionTransactions(db *gorm.DB, monthsAhead int, dropLegacy bool) (*PartitionTransactionsResult, error) {
+	if db.Dialector.Name() != "postgres" {
+		return nil, ErrPartitioningUnsupported
+	}
+	partitioned, err := TransactionsPartitioned(db)
+	if err != nil {
+		return nil, err
+	}
+	if partitioned {
+		return nil, errors.New("transactions table is already partitioned")
+	}
+
+	result := &PartitionTransactionsResult{LegacyKept: !dropLegacy}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(fmt.Sprintf("LOCK TABLE %s IN EXCLUSIVE MODE", transactionsTable)).Error; err != nil {
+			return err
+		}
+
+		var sequence string
+		if err := tx.Raw("SELECT COALESCE(pg_get_serial_sequence(?, 'id'), '')", transactionsTable).Scan(&sequence).Error; err != nil {
+			return err
+		}
+		var oldest sql.NullTime
+		if err := tx.Raw(fmt.Sprintf("SELECT MIN(created_at) FROM %s", transactionsTable)).Row().Scan(&oldest); err != nil {
+			return err
+		}
+
+		// Move the old table and its indexes out of the way, since index
+		// names are unique per schema
+		if err := tx.Exec(fmt.Sprintf("ALTER TABLE %s RENAME TO %s", transactionsTable, legacyTransactionsTable)).Error; err != nil {
+			return err
+		}
+		var indexes []string
+		if err := tx.Raw("SELECT indexname FROM pg_indexes WHERE schemaname = current_schema() AND tablename = ?", legacyTransactionsTable).Scan(&indexes).Error; err != nil {
+			return err
+		}
+		for _, index := range indexes {
+			if err := tx.Exec(fmt.Sprintf("ALTER INDEX %s RENAME TO %s", index, legacyIndexName(index))).Error; err != nil {
+				return err
+			}
+		}
+
+		// The partition key has to be part of the primary key
+		if err := tx.Exec(fmt.Sprintf(
+			"CREATE TABLE %s (LIKE %s INCLUDING DEFAULTS) PARTITION BY RANGE (created_at)",
+			transactionsTable, legacyTransactionsTable,
+		)).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD PRIMARY KEY (id, created_at)", transactionsTable)).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(fmt.Sprintf("CREATE TABLE %s PARTITION OF %s DEFAULT", defaultTransactionsTable, transactionsTable)).Error; err != nil {
+			return err
+		}
+
+		from := time.Now()
+		if oldest.Valid {
+			from = oldest.Time
+		}
+		partitions, err := EnsureTransactionPartitions(tx, from, monthsAhead)
+		if err != nil {
+			return err
+		}
+		result.Partitions = partitions
+
+		// Recreate the model's indexes on the new table
+		if err := tx.AutoMigrate(&model.Transaction{}); err != nil {
+			return err
+		}
+
+		copied := tx.Exec(fmt.Sprintf("INSERT INTO %s SELECT * FROM %s", transactionsTable, legacyTransactionsTable))
+		if copied.Error != nil {
+			return copied.Error
+		}
+		result.Rows = copied.RowsAffected
+
+		if sequence != "" {
+			if err := tx.Exec(fmt.Sprintf("ALTER SEQUENCE %s OWNED BY %s.id", sequence, transactionsTable)).Error; err != nil {
+				return err
+			}
+		}
+		if dropLegacy {
+			return tx.Exec(fmt.Sprintf("DROP TABLE %s", legacyTransactionsTable)).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// legacyIndexName renames an index of the old transactions table, keeping
+// within postgres' 63 character limit
+func legacyIndexName(index string) string {
+	name := "legacy_" + index
+	if len(name) > 63 {
+		name = name[:63]
+	}
+	return name
+}
+
+// monthStart returns the start of the month of t in UTC
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
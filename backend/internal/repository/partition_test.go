@@ -0,0 +1,66 @@
+package repository_test
+
+import (
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+)
+
+func TestTransactionPartitionName(t *testing.T) {
+	// Partitions cover UTC months
+	shanghai := time.FixedZone("CST", 8*3600)
+	cases := map[time.Time]string{
+		time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC): "transactions_p202610",
+		time.Date(2026, 1, 1, 7, 0, 0, 0, shanghai):    "transactions_p202512",
+		time.Date(2026, 12, 31, 23, 0, 0, 0, time.UTC): "transactions_p202612",
+	}
+	for at, want := range cases {
+		if got := repository.TransactionPartitionName(at); got != want {
+			t.Errorf("TransactionPartitionName(%v) = %s, want %s", at, got, want)
+		}
+	}
+}
+
+func TestTransactionsBetween(t *testing.T) {
+	db := setupTenantTestDB(t)
+
+	base := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	for day := 0; day < 5; day++ {
+		tx := model.Transaction{WalletID: 1, Type: model.TransactionTypeWin, Amount: 1, CreatedAt: base.AddDate(0, 0, day)}
+		if err := db.Create(&tx).Error; err != nil {
+			t.Fatalf("Failed to create transaction: %v", err)
+		}
+	}
+
+	cases := []struct {
+		from, to time.Time
+		want     int64
+	}{
+		{time.Time{}, time.Time{}, 5},
+		{base.AddDate(0, 0, 1), time.Time{}, 4},
+		{time.Time{}, base.AddDate(0, 0, 1), 2},
+		{base.AddDate(0, 0, 1), base.AddDate(0, 0, 3), 3},
+	}
+	for _, c := range cases {
+		var count int64
+		if err := repository.TransactionsBetween(db, c.from, c.to).Count(&count).Error; err != nil || count != c.want {
+			t.Errorf("TransactionsBetween(%v, %v) counted %d (err %v), want %d", c.from, c.to, count, err, c.want)
+		}
+	}
+}
+
+func TestPartitioningNeedsPostgres(t *testing.T) {
+	db := setupTenantTestDB(t)
+
+	if partitioned, err := repository.TransactionsPartitioned(db); err != nil || partitioned {
+		t.Errorf("Expected sqlite transactions to be unpartitioned, got %v (err %v)", partitioned, err)
+	}
+	if _, err := repository.PartitionTransactions(db, 3, false); err != repository.ErrPartitioningUnsupported {
+		t.Errorf("Expected ErrPartitioningUnsupported, got %v", err)
+	}
+	if _, err := repository.EnsureTransactionPartitions(db, time.Now(), 3); err != repository.ErrPartitioningUnsupported {
+		t.Errorf("Expected ErrPartitioningUnsupported, got %v", err)
+	}
+}
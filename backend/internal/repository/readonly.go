@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"scratch-lottery/internal/config"
+	"scratch-lottery/pkg/logger"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+var ErrReadOnlyConnection = errors.New("write attempted on a read-only database connection")
+
+// InitReportDB opens the read-only connection used by statistics and exports.
+// Returns nil when DB_READONLY is off; callers then report from the primary
+// connection. On postgres the connection logs in as DB_READONLY_USER, a role
+// that should only be granted SELECT, and every session is read-only; on
+// sqlite the database file is opened in read-only mode.
+func InitReportDB(cfg *config.Config) (*gorm.DB, error) {
+	if !cfg.DBReadOnly {
+		return nil, nil
+	}
+
+	var dialector gorm.Dialector
+	switch cfg.DBDriver {
+	case "sqlite":
+		dialector = sqlite.Open(fmt.Sprintf("file:%s?mode=ro", cfg.DBPath))
+	case "postgres":
+		if cfg.DBReadOnlyUser == "" {
+			return nil, errors.New("DB_READONLY_USER is required for the read-only connection")
+		}
+		dsn := fmt.Sprintf(
+			"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable default_transaction_read_only=on TimeZone=UTC",
+			cfg.DBReadOnlyHost, cfg.DBReadOnlyPort, cfg.DBReadOnlyUser, cfg.DBReadOnlyPassword, cfg.DBName,
+		)
+		dialector = postgres.Open(dsn)
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %s", cfg.DBDriver)
+	}
+
+	reportDB, err := gorm.Open(dialector, &gorm.Config{
+		Logger: logger.GormLoggerForMode(cfg.OAuthMode),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to read-only database: %w", err)
+	}
+	if err := reportDB.Use(TenantPlugin{}); err != nil {
+		return nil, fmt.Errorf("failed to register tenant plugin: %w", err)
+	}
+	if err := reportDB.Use(ReadOnlyPlugin{}); err != nil {
+		return nil, fmt.Errorf("failed to register read-only plugin: %w", err)
+	}
+	return reportDB, nil
+}
+
+// ReadOnlyPlugin rejects creates, updates, deletes and raw statements before
+// they reach the database. It backs up the database role, which remains the
+// actual guarantee.
+type ReadOnlyPlugin struct{}
+
+// Name implements gorm.Plugin
+func (ReadOnlyPlugin) Name() string {
+	return "readonly"
+}
+
+// Initialize implements gorm.Plugin
+func (ReadOnlyPlugin) Initialize(db *gorm.DB) error {
+	if err := db.Callback().Create().Before("gorm:create").Register("readonly:create", rejectWrite); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register("readonly:update", rejectWrite); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().Before("gorm:delete").Register("readonly:delete", rejectWrite); err != nil {
+		return err
+	}
+	return db.Callback().Raw().Before("gorm:raw").Register("readonly:raw", rejectWrite)
+}
+
+func rejectWrite(db *gorm.DB) {
+	_ = db.AddError(ErrReadOnlyConnection)
+}
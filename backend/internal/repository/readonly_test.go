@@ -0,0 +1,75 @@
+package repository_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"scratch-lottery/internal/config"
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestReportDBRejectsWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lottery.db")
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if err := db.Use(repository.TenantPlugin{}); err != nil {
+		t.Fatalf("Failed to register tenant plugin: %v", err)
+	}
+	if err := repository.AutoMigrate(db); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	db.Create(&model.User{LinuxdoID: "reader", Username: "Reader", Role: "user"})
+
+	cfg := &config.Config{DBDriver: "sqlite", DBPath: path}
+	if reportDB, err := repository.InitReportDB(cfg); err != nil || reportDB != nil {
+		t.Fatalf("Expected no report connection while disabled, got %v (err %v)", reportDB, err)
+	}
+
+	cfg.DBReadOnly = true
+	reportDB, err := repository.InitReportDB(cfg)
+	if err != nil || reportDB == nil {
+		t.Fatalf("Failed to open report connection: %v", err)
+	}
+
+	var users []model.User
+	if err := repository.ScopeTenant(reportDB, repository.DefaultTenantID).Find(&users).Error; err != nil || len(users) != 1 {
+		t.Fatalf("Expected to read one user, got %d (err %v)", len(users), err)
+	}
+	var otherUsers []model.User
+	if err := repository.ScopeTenant(reportDB, 2).Find(&otherUsers).Error; err != nil || len(otherUsers) != 0 {
+		t.Errorf("Expected tenant filtering on the report connection, got %d users (err %v)", len(otherUsers), err)
+	}
+
+	writes := map[string]error{
+		"create": reportDB.Create(&model.User{LinuxdoID: "writer", Username: "Writer"}).Error,
+		"update": reportDB.Model(&model.User{}).Where("id = ?", users[0].ID).Update("role", "admin").Error,
+		"delete": reportDB.Where("1 = 1").Delete(&model.User{}).Error,
+		"exec":   reportDB.Exec("UPDATE users SET role = 'admin'").Error,
+	}
+	for name, err := range writes {
+		if !errors.Is(err, repository.ErrReadOnlyConnection) {
+			t.Errorf("Expected %s to be rejected, got %v", name, err)
+		}
+	}
+
+	var admins int64
+	db.Model(&model.User{}).Where("role = ?", "admin").Count(&admins)
+	if admins != 0 {
+		t.Errorf("Expected no write to reach the database, found %d admins", admins)
+	}
+}
+
+func TestReportDBNeedsPostgresRole(t *testing.T) {
+	cfg := &config.Config{DBDriver: "postgres", DBReadOnly: true}
+	if _, err := repository.InitReportDB(cfg); err == nil {
+		t.Errorf("Expected an error without DB_READONLY_USER")
+	}
+}
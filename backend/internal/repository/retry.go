@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+
+	"scratch-lottery/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// Retry limits of Transaction
+const (
+	maxTransactionAttempts = 4
+	transactionRetryDelay  = 20 * time.Millisecond
+)
+
+// Database error codes a transaction can be retried on
+const (
+	sqlStateSerializationFailure = "40001" // postgres: could not serialize access
+	sqlStateDeadlockDetected     = "40P01" // postgres: deadlock detected
+	sqlStateLockNotAvailable     = "55P03" // postgres: lock timeout or NOWAIT lock not available
+	sqliteBusy                   = 5       // sqlite: database is locked by another connection
+	sqliteLocked                 = 6       // sqlite: a table is locked
+)
+
+// Transaction runs fn in a transaction on db like db.Transaction, running it
+// again when the database aborts it with a serialization failure, deadlock or
+// lock timeout. Retries are bounded and wait a jittered, growing delay; any
+// other error, and the last retryable one, is returned as is.
+//
+// fn may run more than once, so it must not depend on state it changed in
+// an earlier attempt. Inside an enclosing transaction fn runs once, since
+// only the outermost transaction can be retried.
+func Transaction(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	if committer, ok := db.Statement.ConnPool.(gorm.TxCommitter); ok && committer != nil {
+		return db.Transaction(fn)
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = db.Transaction(fn)
+		if err == nil || !IsRetryable(err) || attempt == maxTransactionAttempts {
+			return err
+		}
+		delay := transactionRetryDelay << (attempt - 1)
+		delay += time.Duration(rand.Int63n(int64(delay)))
+		logger.Default().Warn("Retrying transaction in %v after attempt %d failed: %v", delay, attempt, err)
+		time.Sleep(delay)
+	}
+}
+
+// IsRetryable reports whether err aborted a transaction that may succeed
+// when run again
+func IsRetryable(err error) bool {
+	var pgErr interface{ SQLState() string }
+	if errors.As(err, &pgErr) {
+		state := pgErr.SQLState()
+		return state == sqlStateSerializationFailure || state == sqlStateDeadlockDetected ||
+			state == sqlStateLockNotAvailable
+	}
+	var sqliteErr interface{ Code() int }
+	if errors.As(err, &sqliteErr) {
+		// Extended result codes keep the primary code in the low byte
+		code := sqliteErr.Code() & 0xff
+		return code == sqliteBusy || code == sqliteLocked
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package repository_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// sqlStateError mimics a postgres error carrying an SQLSTATE code
+type sqlStateError struct{ state string }
+
+func (e *sqlStateError) Error() string    { return "pg error " + e.state }
+func (e *sqlStateError) SQLState() string { return e.state }
+
+func TestTransactionRetriesSerializationFailures(t *testing.T) {
+	db := setupTenantTestDB(t)
+
+	// A serialization failure is retried and only the successful attempt commits
+	attempts := 0
+	err := repository.Transaction(db, func(tx *gorm.DB) error {
+		attempts++
+		if err := tx.Create(&model.User{LinuxdoID: fmt.Sprintf("retry_%d", attempts), Username: "Retry", Role: "user"}).Error; err != nil {
+			return err
+		}
+		if attempts < 3 {
+			return fmt.Errorf("update wallet: %w", &sqlStateError{state: "40001"})
+		}
+		return nil
+	})
+	if err != nil || attempts != 3 {
+		t.Fatalf("Expected success on the third attempt, got %d attempts (err %v)", attempts, err)
+	}
+	var users int64
+	db.Model(&model.User{}).Where("username = ?", "Retry").Count(&users)
+	if users != 1 {
+		t.Errorf("Expected only the last attempt to commit, got %d users", users)
+	}
+
+	// Retries are bounded
+	attempts = 0
+	deadlock := &sqlStateError{state: "40P01"}
+	err = repository.Transaction(db, func(tx *gorm.DB) error {
+		attempts++
+		return deadlock
+	})
+	if !errors.Is(err, deadlock) || attempts != 4 {
+		t.Errorf("Expected the deadlock after 4 attempts, got %d attempts (err %v)", attempts, err)
+	}
+
+	// A lock timeout is retried too
+	attempts = 0
+	err = repository.Transaction(db, func(tx *gorm.DB) error {
+		attempts++
+		if attempts < 2 {
+			return &sqlStateError{state: "55P03"}
+		}
+		return nil
+	})
+	if err != nil || attempts != 2 {
+		t.Errorf("Expected a lock timeout retried, got %d attempts (err %v)", attempts, err)
+	}
+
+	// Other errors are returned at once
+	attempts = 0
+	uniqueViolation := &sqlStateError{state: "23505"}
+	err = repository.Transaction(db, func(tx *gorm.DB) error {
+		attempts++
+		return uniqueViolation
+	})
+	if err != uniqueViolation || attempts != 1 {
+		t.Errorf("Expected no retry of a unique violation, got %d attempts (err %v)", attempts, err)
+	}
+
+	// Inside an enclosing transaction only the outermost one may retry
+	attempts = 0
+	err = db.Transaction(func(tx *gorm.DB) error {
+		return repository.Transaction(tx, func(tx *gorm.DB) error {
+			attempts++
+			return deadlock
+		})
+	})
+	if !errors.Is(err, deadlock) || attempts != 1 {
+		t.Errorf("Expected a nested transaction to run once, got %d attempts (err %v)", attempts, err)
+	}
+}
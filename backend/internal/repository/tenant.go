@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultTenantID is the tenant that owns all data created before multi-tenancy
+// and every request that does not resolve to another tenant
+const DefaultTenantID uint = 1
+
+// tenantFieldName is the model field that marks a table as tenant scoped
+const tenantFieldName = "TenantID"
+
+var ErrCrossTenantWrite = errors.New("record belongs to another tenant")
+
+type tenantContextKey struct{}
+
+// WithTenant returns a context that scopes database access to a tenant
+func WithTenant(ctx context.Context, tenantID uint) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenantID)
+}
+
+// TenantFromContext returns the tenant a context is scoped to
+func TenantFromContext(ctx context.Context) (uint, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	tenantID, ok := ctx.Value(tenantContextKey{}).(uint)
+	return tenantID, ok && tenantID != 0
+}
+
+// ScopeTenant returns a session of db restricted to a tenant. Every query,
+// update and delete on a tenant-scoped table (one with a TenantID field) is
+// filtered by the tenant, and created rows are assigned to it. A zero
+// tenantID returns db unchanged.
+func ScopeTenant(db *gorm.DB, tenantID uint) *gorm.DB {
+	if tenantID == 0 {
+		return db
+	}
+	ctx := context.Background()
+	if db.Statement != nil && db.Statement.Context != nil {
+		ctx = db.Statement.Context
+	}
+	return db.WithContext(WithTenant(ctx, tenantID))
+}
+
+// ScopeRequest returns a session of db carrying the values of a request
+// context, such as the request id its logs are tagged with. The tenant of db
+// is kept, while the cancellation of ctx is dropped so a client hanging up
+// does not abort a purchase or payment half way.
+func ScopeRequest(db *gorm.DB, ctx context.Context) *gorm.DB {
+	ctx = context.WithoutCancel(ctx)
+	if db.Statement != nil {
+		if tenantID, ok := TenantFromContext(db.Statement.Context); ok {
+			ctx = WithTenant(ctx, tenantID)
+		}
+	}
+	return db.WithContext(ctx)
+}
+
+// TenantPlugin enforces tenant isolation for sessions created with
+// ScopeTenant. Sessions without a tenant are not filtered; they are used by
+// background jobs that work across tenants.
+type TenantPlugin struct{}
+
+// Name implements gorm.Plugin
+func (TenantPlugin) Name() string {
+	return "tenant"
+}
+
+// Initialize implements gorm.Plugin
+func (TenantPlugin) Initialize(db *gorm.DB) error {
+	if err := db.Callback().Create().Before("gorm:create").Register("tenant:create", assignTenant); err != nil {
+		return err
+	}
+	if err := db.Callback().Query().Before("gorm:query").Register("tenant:query", filterTenant); err != nil {
+		return err
+	}
+	if err := db.Callback().Row().Before("gorm:row").Register("tenant:row", filterTenant); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register("tenant:update", filterTenant); err != nil {
+		return err
+	}
+	return db.Callback().Delete().Before("gorm:delete").Register("tenant:delete", filterTenant)
+}
+
+// filterTenant restricts the statement to rows of the session's tenant
+func filterTenant(db *gorm.DB) {
+	tenantID, ok := TenantFromContext(db.Statement.Context)
+	if !ok || db.Statement.Schema == nil {
+		return
+	}
+	field := db.Statement.Schema.LookUpField(tenantFieldName)
+	if field == nil {
+		return
+	}
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: tenantID},
+	}})
+}
+
+// assignTenant sets the tenant of new rows and refuses rows of another tenant
+func assignTenant(db *gorm.DB) {
+	tenantID, ok := TenantFromContext(db.Statement.Context)
+	if !ok || db.Statement.Schema == nil {
+		return
+	}
+	field := db.Statement.Schema.LookUpField(tenantFieldName)
+	if field == nil {
+		return
+	}
+
+	ctx := db.Statement.Context
+	assign := func(rv reflect.Value) {
+		value, zero := field.ValueOf(ctx, rv)
+		if zero {
+			if err := field.Set(ctx, rv, tenantID); err != nil {
+				_ = db.AddError(err)
+			}
+			return
+		}
+		if id, ok := value.(uint); !ok || id != tenantID {
+			_ = db.AddError(ErrCrossTenantWrite)
+		}
+	}
+
+	switch rv := db.Statement.ReflectValue; rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			elem := reflect.Indirect(rv.Index(i))
+			if elem.Kind() == reflect.Struct {
+				assign(elem)
+			}
+		}
+	case reflect.Struct:
+		assign(rv)
+	}
+}
@@ -0,0 +1,206 @@
+package repository_test
+
+import (
+	"errors"
+	"testing"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupTenantTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.Use(repository.TenantPlugin{}); err != nil {
+		t.Fatalf("Failed to register tenant plugin: %v", err)
+	}
+	if err := repository.AutoMigrate(db); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	if err := db.Create(&model.Tenant{Name: "Second", Slug: "second", Status: model.TenantStatusActive}).Error; err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	return db
+}
+
+func TestMigrateCreatesDefaultTenant(t *testing.T) {
+	db := setupTenantTestDB(t)
+
+	var tenant model.Tenant
+	if err := db.First(&tenant, repository.DefaultTenantID).Error; err != nil {
+		t.Fatalf("Default tenant missing: %v", err)
+	}
+	if tenant.Slug != "default" {
+		t.Errorf("Expected slug default, got %s", tenant.Slug)
+	}
+
+	// Migrating again must not fail or duplicate the tenant
+	if err := repository.AutoMigrate(db); err != nil {
+		t.Fatalf("Second migration failed: %v", err)
+	}
+	var count int64
+	db.Model(&model.Tenant{}).Count(&count)
+	if count != 2 {
+		t.Errorf("Expected 2 tenants, got %d", count)
+	}
+}
+
+func TestTenantCreateAssignsTenant(t *testing.T) {
+	db := setupTenantTestDB(t)
+	second := repository.ScopeTenant(db, 2)
+
+	product := model.Product{Name: "Card", Price: 10, Stock: 1}
+	if err := second.Create(&product).Error; err != nil {
+		t.Fatalf("Failed to create product: %v", err)
+	}
+	if product.TenantID != 2 {
+		t.Errorf("Expected tenant 2, got %d", product.TenantID)
+	}
+
+	// Unscoped sessions keep the column default
+	legacy := model.Product{Name: "Legacy", Price: 10, Stock: 1}
+	if err := db.Create(&legacy).Error; err != nil {
+		t.Fatalf("Failed to create product: %v", err)
+	}
+	if legacy.TenantID != repository.DefaultTenantID {
+		t.Errorf("Expected default tenant, got %d", legacy.TenantID)
+	}
+
+	// Batches are assigned row by row
+	configs := []model.SystemConfig{{Key: "site_name", Value: "A"}, {Key: "payment_enabled", Value: "true"}}
+	if err := second.Create(&configs).Error; err != nil {
+		t.Fatalf("Failed to create configs: %v", err)
+	}
+	for _, config := range configs {
+		if config.TenantID != 2 {
+			t.Errorf("Expected tenant 2 for %s, got %d", config.Key, config.TenantID)
+		}
+	}
+}
+
+func TestTenantCreateRejectsOtherTenant(t *testing.T) {
+	db := setupTenantTestDB(t)
+	second := repository.ScopeTenant(db, 2)
+
+	user := model.User{TenantID: repository.DefaultTenantID, LinuxdoID: "u1", Username: "u1"}
+	if err := second.Create(&user).Error; !errors.Is(err, repository.ErrCrossTenantWrite) {
+		t.Fatalf("Expected ErrCrossTenantWrite, got %v", err)
+	}
+
+	var count int64
+	db.Model(&model.User{}).Count(&count)
+	if count != 0 {
+		t.Errorf("Rejected user must not be stored, found %d", count)
+	}
+}
+
+func TestTenantQueriesAreIsolated(t *testing.T) {
+	db := setupTenantTestDB(t)
+	first := repository.ScopeTenant(db, repository.DefaultTenantID)
+	second := repository.ScopeTenant(db, 2)
+
+	// The same external identity and config key may exist once per tenant
+	for _, session := range []*gorm.DB{first, second} {
+		user := model.User{LinuxdoID: "shared", Username: "shared"}
+		if err := session.Create(&user).Error; err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+		if err := session.Create(&model.Wallet{UserID: user.ID, Balance: 100}).Error; err != nil {
+			t.Fatalf("Failed to create wallet: %v", err)
+		}
+		if err := session.Create(&model.SystemConfig{Key: "site_name", Value: "site"}).Error; err != nil {
+			t.Fatalf("Failed to create config: %v", err)
+		}
+	}
+	lotteryType := model.LotteryType{Name: "Second only", Price: 5}
+	if err := second.Create(&lotteryType).Error; err != nil {
+		t.Fatalf("Failed to create lottery type: %v", err)
+	}
+
+	var users []model.User
+	if err := first.Find(&users).Error; err != nil || len(users) != 1 || users[0].TenantID != repository.DefaultTenantID {
+		t.Fatalf("Expected one default tenant user, got %+v (err %v)", users, err)
+	}
+	var all int64
+	db.Model(&model.User{}).Count(&all)
+	if all != 2 {
+		t.Errorf("Unscoped session should see both users, got %d", all)
+	}
+
+	// Lookups by primary key do not cross tenants
+	if err := first.First(&model.LotteryType{}, lotteryType.ID).Error; !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Expected not found across tenants, got %v", err)
+	}
+	if err := second.First(&model.LotteryType{}, lotteryType.ID).Error; err != nil {
+		t.Errorf("Expected lottery type in its tenant, got %v", err)
+	}
+
+	// Preloads are filtered too
+	var user model.User
+	if err := second.Preload("Wallet").Where("linuxdo_id = ?", "shared").First(&user).Error; err != nil {
+		t.Fatalf("Failed to load user: %v", err)
+	}
+	if user.TenantID != 2 || user.Wallet.TenantID != 2 {
+		t.Errorf("Expected user and wallet of tenant 2, got %d/%d", user.TenantID, user.Wallet.TenantID)
+	}
+
+	// Updates and deletes only touch the session's tenant
+	if err := first.Model(&model.Wallet{}).Where("balance = ?", 100).Update("balance", 0).Error; err != nil {
+		t.Fatalf("Failed to update wallets: %v", err)
+	}
+	var secondWallet model.Wallet
+	second.First(&secondWallet)
+	if secondWallet.Balance != 100 {
+		t.Errorf("Update leaked into tenant 2: balance %d", secondWallet.Balance)
+	}
+	if err := first.Where("key = ?", "site_name").Delete(&model.SystemConfig{}).Error; err != nil {
+		t.Fatalf("Failed to delete config: %v", err)
+	}
+	var remaining []model.SystemConfig
+	db.Find(&remaining)
+	if len(remaining) != 1 || remaining[0].TenantID != 2 {
+		t.Errorf("Delete leaked into tenant 2: %+v", remaining)
+	}
+
+	// Updating a row of another tenant by primary key is a no-op
+	result := first.Model(&secondWallet).Update("balance", 1)
+	if result.Error != nil || result.RowsAffected != 0 {
+		t.Errorf("Expected no rows updated across tenants, got %d (err %v)", result.RowsAffected, result.Error)
+	}
+}
+
+func TestTenantScopeSurvivesTransactions(t *testing.T) {
+	db := setupTenantTestDB(t)
+	second := repository.ScopeTenant(db, 2)
+
+	if err := db.Create(&model.Product{Name: "Default", Price: 1, Stock: 1}).Error; err != nil {
+		t.Fatalf("Failed to create product: %v", err)
+	}
+
+	err := second.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&model.Product{}).Count(&count).Error; err != nil {
+			return err
+		}
+		if count != 0 {
+			t.Errorf("Transaction saw %d products of another tenant", count)
+		}
+		return tx.Create(&model.Product{Name: "Second", Price: 1, Stock: 1}).Error
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+
+	var product model.Product
+	if err := db.Where("name = ?", "Second").First(&product).Error; err != nil || product.TenantID != 2 {
+		t.Errorf("Expected product of tenant 2, got %+v (err %v)", product, err)
+	}
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
+)
+
+// Duplicate detection: accounts sharing a Linux.do ID spelling, a device or
+// a login IP are paired with their signals; an IP shared by many accounts and
+// accounts without a shared signal are not flagged.
+func TestAccountMergeFindDuplicates(t *testing.T) {
+	db := setupTenantTestDB(t)
+	if err := db.AutoMigrate(&model.AccountMerge{}, &model.UserDevice{}, &model.LoginRecord{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	users := []model.User{
+		{LinuxdoID: "00123", Username: "a"},
+		{LinuxdoID: "123", Username: "b"},
+		{LinuxdoID: "Alice", Username: "c"},
+		{LinuxdoID: "alice ", Username: "d"},
+		{LinuxdoID: "solo", Username: "e"},
+	}
+	if err := db.Create(&users).Error; err != nil {
+		t.Fatalf("Failed to create users: %v", err)
+	}
+	db.Create(&model.UserDevice{UserID: users[0].ID, Fingerprint: "fp-1"})
+	db.Create(&model.UserDevice{UserID: users[1].ID, Fingerprint: "fp-1"})
+	db.Create(&model.LoginRecord{UserID: users[2].ID, IP: "10.0.0.1", CreatedAt: time.Now()})
+	db.Create(&model.LoginRecord{UserID: users[2].ID, IP: "10.0.0.1", CreatedAt: time.Now()})
+	db.Create(&model.LoginRecord{UserID: users[4].ID, IP: "10.0.0.1", CreatedAt: time.Now()})
+
+	candidates, err := NewAccountMergeService(db).ForTenant(1).FindDuplicates()
+	if err != nil {
+		t.Fatalf("FindDuplicates failed: %v", err)
+	}
+	if len(candidates) != 3 {
+		t.Fatalf("Expected 3 candidates, got %+v", candidates)
+	}
+	first := candidates[0]
+	if first.Users[0].ID != users[0].ID || first.Users[1].ID != users[1].ID || len(first.Reasons) != 2 {
+		t.Errorf("Expected the ID and device pair first, got %+v", first)
+	}
+	if candidates[1].Users[0].ID != users[2].ID || candidates[1].Users[1].ID != users[3].ID || candidates[1].Reasons[0] != DuplicateReasonLinuxdoID {
+		t.Errorf("Expected the Linux.do ID pair, got %+v", candidates[1])
+	}
+	if candidates[2].Users[0].ID != users[2].ID || candidates[2].Users[1].ID != users[4].ID || candidates[2].Reasons[0] != DuplicateReasonIP {
+		t.Errorf("Expected the shared IP pair, got %+v", candidates[2])
+	}
+
+	// An IP shared by more accounts than a household is ignored
+	for i := 0; i < maxSharedIPUsers; i++ {
+		user := model.User{LinuxdoID: "cafe" + string(rune('a'+i))}
+		db.Create(&user)
+		db.Create(&model.LoginRecord{UserID: user.ID, IP: "10.0.0.1", CreatedAt: time.Now()})
+	}
+	candidates, err = NewAccountMergeService(db).ForTenant(1).FindDuplicates()
+	if err != nil || len(candidates) != 2 {
+		t.Errorf("Expected the shared IP to be dropped, got %+v (err %v)", candidates, err)
+	}
+}
+
+// Account merge: the source's balance, tickets, exchange records and
+// transactions move to the target, the source signs in as the target, the
+// merge is logged, and a rollback restores both accounts.
+func TestAccountMergeAndRollback(t *testing.T) {
+	db, _, _, targetID, ticketIDs := setupLargeWinTest(t, []int{0, 10})
+	if err := db.AutoMigrate(&model.AccountMerge{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	source := model.User{LinuxdoID: "large_winner_2", Username: "Alt", Role: "user"}
+	if err := db.Create(&source).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	sourceWallet := model.Wallet{UserID: source.ID, Balance: 30}
+	db.Create(&sourceWallet)
+	db.Create(&model.Transaction{WalletID: sourceWallet.ID, Type: model.TransactionTypeInitial, Amount: 30})
+	db.Model(&model.Ticket{}).Where("id = ?", ticketIDs[1]).Update("user_id", source.ID)
+	db.Create(&model.ExchangeRecord{UserID: source.ID, ProductID: 1, CardKeyID: 1, Cost: 5})
+
+	var targetBefore model.Wallet
+	db.Where("user_id = ?", targetID).First(&targetBefore)
+
+	merges := NewAccountMergeService(db).ForTenant(1)
+	merge, err := merges.Merge(9, MergeAccountsRequest{SourceUserID: source.ID, TargetUserID: targetID, Reason: "same person"})
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if merge.Balance != 30 {
+		t.Errorf("Expected 30 points merged, got %d", merge.Balance)
+	}
+
+	var target, sourceAfter model.Wallet
+	db.Where("user_id = ?", targetID).First(&target)
+	db.Where("user_id = ?", source.ID).First(&sourceAfter)
+	if target.Balance != targetBefore.Balance+30 || sourceAfter.Balance != 0 {
+		t.Errorf("Expected the balance to move, got target %d and source %d", target.Balance, sourceAfter.Balance)
+	}
+	var owned, records, transactions int64
+	db.Model(&model.Ticket{}).Where("user_id = ?", targetID).Count(&owned)
+	db.Model(&model.ExchangeRecord{}).Where("user_id = ?", targetID).Count(&records)
+	db.Model(&model.Transaction{}).Where("wallet_id = ?", sourceWallet.ID).Count(&transactions)
+	if owned != 2 || records != 1 || transactions != 0 {
+		t.Errorf("Expected every record to move, got %d tickets, %d records, %d transactions left", owned, records, transactions)
+	}
+	var logs int64
+	db.Model(&model.AdminLog{}).Where("action = ? AND target_id = ?", "merge_accounts", source.ID).Count(&logs)
+	if logs != 1 {
+		t.Errorf("Expected the merge to be logged, got %d logs", logs)
+	}
+
+	auth := &AuthService{db: db}
+	user, err := auth.findOrCreateUser(source.LinuxdoID, source.Username, "", "user")
+	if err != nil || user.ID != targetID {
+		t.Errorf("Expected the merged account to sign in as the target, got %+v (err %v)", user, err)
+	}
+
+	// Merged and staff accounts cannot be merged again
+	if _, err := merges.Merge(9, MergeAccountsRequest{SourceUserID: source.ID, TargetUserID: targetID}); err != ErrInvalidMerge {
+		t.Errorf("Expected ErrInvalidMerge for a merged account, got %v", err)
+	}
+	admin := model.User{LinuxdoID: "staff", Role: "admin"}
+	db.Create(&admin)
+	if _, err := merges.Merge(9, MergeAccountsRequest{SourceUserID: admin.ID, TargetUserID: targetID}); err != ErrInvalidMerge {
+		t.Errorf("Expected ErrInvalidMerge for a staff account, got %v", err)
+	}
+
+	if _, err := merges.Rollback(9, merge.ID); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	db.Where("user_id = ?", targetID).First(&target)
+	db.Where("user_id = ?", source.ID).First(&sourceAfter)
+	if target.Balance != targetBefore.Balance || sourceAfter.Balance != 30 {
+		t.Errorf("Expected the balances restored, got target %d and source %d", target.Balance, sourceAfter.Balance)
+	}
+	var ticket model.Ticket
+	db.First(&ticket, ticketIDs[1])
+	db.Model(&model.Transaction{}).Where("wallet_id = ?", sourceWallet.ID).Count(&transactions)
+	if ticket.UserID != source.ID || transactions != 1 {
+		t.Errorf("Expected the records back on the source, got ticket owner %d and %d transactions", ticket.UserID, transactions)
+	}
+	var restored model.User
+	db.First(&restored, source.ID)
+	if restored.MergedIntoID != nil {
+		t.Errorf("Expected the source to be active again")
+	}
+	if _, err := merges.Rollback(9, merge.ID); err != ErrMergeRolledBack {
+		t.Errorf("Expected ErrMergeRolledBack, got %v", err)
+	}
+}
@@ -0,0 +1,519 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// maxSharedIPUsers is the most users a login IP may be shared by to count as
+// a duplicate signal; beyond that it is likely a NAT or proxy
+const maxSharedIPUsers = 5
+
+// Duplicate account signals
+const (
+	DuplicateReasonLinuxdoID = "linuxdo_id" // Linux.do IDs differing only in case, spacing or leading zeros
+	DuplicateReasonDevice    = "device"     // the same device fingerprint
+	DuplicateReasonIP        = "ip"         // the same login IP
+)
+
+var (
+	ErrInvalidMerge         = errors.New("invalid account merge")
+	ErrMergeNotFound        = errors.New("account merge not found")
+	ErrMergeRolledBack      = errors.New("account merge already rolled back")
+	ErrMergeRollbackBalance = errors.New("target balance below the merged points")
+)
+
+// AccountMergeService detects duplicate accounts and merges them, keeping
+// enough of each merge to roll it back
+type AccountMergeService struct {
+	db *gorm.DB
+}
+
+// NewAccountMergeService creates a new account merge service
+func NewAccountMergeService(db *gorm.DB) *AccountMergeService {
+	return &AccountMergeService{db: db}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *AccountMergeService) ForTenant(tenantID uint) *AccountMergeService {
+	return &AccountMergeService{db: repository.ScopeTenant(s.db, tenantID)}
+}
+
+// DuplicateUser is an account of a duplicate candidate
+type DuplicateUser struct {
+	ID        uint      `json:"id"`
+	LinuxdoID string    `json:"linuxdo_id"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DuplicateCandidate is a pair of accounts that look like the same person,
+// the older account first
+type DuplicateCandidate struct {
+	Users   [2]DuplicateUser `json:"users"`
+	Reasons []string         `json:"reasons"`
+}
+
+// MergeAccountsRequest merges the source account into the target account
+type MergeAccountsRequest struct {
+	SourceUserID uint   `json:"source_user_id" binding:"required"`
+	TargetUserID uint   `json:"target_user_id" binding:"required"`
+	Reason       string `json:"reason" binding:"max=512"`
+}
+
+// AccountMergeListResponse represents a paginated list of merges, newest first
+type AccountMergeListResponse struct {
+	Merges     []model.AccountMerge `json:"merges"`
+	Total      int64                `json:"total"`
+	Page       int                  `json:"page"`
+	Limit      int                  `json:"limit"`
+	TotalPages int                  `json:"total_pages"`
+}
+
+// mergedRecords are the IDs of the records a merge moved to the target
+type mergedRecords struct {
+	Tickets         []uint `json:"tickets"`
+	ExchangeRecords []uint `json:"exchange_records"`
+	PrizeClaims     []uint `json:"prize_claims"`
+	Transactions    []uint `json:"transactions"`
+	Unscratched     int    `json:"unscratched"` // Unscratched tickets among the moved tickets
+}
+
+// FindDuplicates returns pairs of active accounts that share a signal, those
+// with the most signals first
+func (s *AccountMergeService) FindDuplicates() ([]DuplicateCandidate, error) {
+	var users []model.User
+	if err := s.db.Where("merged_into_id IS NULL").Order("id ASC").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]model.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+
+	type pair struct{ a, b uint }
+	reasons := make(map[pair][]string)
+	addGroup := func(userIDs []uint, reason string) {
+		sort.Slice(userIDs, func(i, j int) bool { return userIDs[i] < userIDs[j] })
+		for i := range userIDs {
+			for j := i + 1; j < len(userIDs); j++ {
+				key := pair{userIDs[i], userIDs[j]}
+				if _, ok := byID[key.a]; !ok {
+					continue
+				}
+				if _, ok := byID[key.b]; !ok {
+					continue
+				}
+				if existing := reasons[key]; len(existing) == 0 || existing[len(existing)-1] != reason {
+					reasons[key] = append(existing, reason)
+				}
+			}
+		}
+	}
+
+	linuxdoIDs := make(map[string][]uint)
+	for _, user := range users {
+		if key := normalizeLinuxdoID(user.LinuxdoID); key != "" {
+			linuxdoIDs[key] = append(linuxdoIDs[key], user.ID)
+		}
+	}
+	for _, userIDs := range linuxdoIDs {
+		addGroup(userIDs, DuplicateReasonLinuxdoID)
+	}
+
+	type shared struct {
+		SharedKey string
+		UserID    uint
+	}
+	groupBy := func(rows []shared, maxUsers int) [][]uint {
+		groups := make(map[string][]uint)
+		for _, row := range rows {
+			groups[row.SharedKey] = append(groups[row.SharedKey], row.UserID)
+		}
+		var result [][]uint
+		for _, userIDs := range groups {
+			if len(userIDs) > 1 && (maxUsers == 0 || len(userIDs) <= maxUsers) {
+				result = append(result, userIDs)
+			}
+		}
+		return result
+	}
+
+	var devices []shared
+	if err := s.db.Model(&model.UserDevice{}).
+		Select("DISTINCT fingerprint AS shared_key, user_id").
+		Where("fingerprint != ''").
+		Scan(&devices).Error; err != nil {
+		return nil, err
+	}
+	for _, userIDs := range groupBy(devices, 0) {
+		addGroup(userIDs, DuplicateReasonDevice)
+	}
+
+	var ips []shared
+	if err := s.db.Model(&model.LoginRecord{}).
+		Select("DISTINCT ip AS shared_key, user_id").
+		Where("ip != ''").
+		Scan(&ips).Error; err != nil {
+		return nil, err
+	}
+	for _, userIDs := range groupBy(ips, maxSharedIPUsers) {
+		addGroup(userIDs, DuplicateReasonIP)
+	}
+
+	candidates := make([]DuplicateCandidate, 0, len(reasons))
+	for key, pairReasons := range reasons {
+		candidates = append(candidates, DuplicateCandidate{
+			Users:   [2]DuplicateUser{toDuplicateUser(byID[key.a]), toDuplicateUser(byID[key.b])},
+			Reasons: pairReasons,
+		})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if len(candidates[i].Reasons) != len(candidates[j].Reasons) {
+			return len(candidates[i].Reasons) > len(candidates[j].Reasons)
+		}
+		if candidates[i].Users[0].ID != candidates[j].Users[0].ID {
+			return candidates[i].Users[0].ID < candidates[j].Users[0].ID
+		}
+		return candidates[i].Users[1].ID < candidates[j].Users[1].ID
+	})
+	return candidates, nil
+}
+
+// Merge moves the wallet balance, tickets, exchange records, prize claims and
+// transactions of the source account to the target account, marks the source
+// as merged so its logins land on the target, and logs the merge
+func (s *AccountMergeService) Merge(adminID uint, req MergeAccountsRequest) (*model.AccountMerge, error) {
+	if req.SourceUserID == req.TargetUserID {
+		return nil, ErrInvalidMerge
+	}
+	source, err := s.mergeableUser(req.SourceUserID)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.mergeableUser(req.TargetUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	var merge model.AccountMerge
+	err = repository.Transaction(s.db, func(tx *gorm.DB) error {
+		merge = model.AccountMerge{}
+
+		// Claim the source, so concurrent merges of the account fail
+		result := tx.Model(&model.User{}).
+			Where("id = ? AND merged_into_id IS NULL", source.ID).
+			Update("merged_into_id", target.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidMerge
+		}
+
+		var moved mergedRecords
+		if err := tx.Model(&model.Ticket{}).Where("user_id = ?", source.ID).Pluck("id", &moved.Tickets).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.ExchangeRecord{}).Where("user_id = ?", source.ID).Pluck("id", &moved.ExchangeRecords).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.PrizeClaim{}).Where("user_id = ?", source.ID).Pluck("id", &moved.PrizeClaims).Error; err != nil {
+			return err
+		}
+		var unscratched int64
+		if err := tx.Model(&model.Ticket{}).
+			Where("user_id = ? AND status IN ?", source.ID, unscratchedTicketStatuses).
+			Count(&unscratched).Error; err != nil {
+			return err
+		}
+		moved.Unscratched = int(unscratched)
+
+		var sourceWallet, targetWallet model.Wallet
+		if err := lockWallet(tx, source.ID, &sourceWallet); err != nil {
+			return err
+		}
+		if err := lockWallet(tx, target.ID, &targetWallet); err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Transaction{}).Where("wallet_id = ?", sourceWallet.ID).Pluck("id", &moved.Transactions).Error; err != nil {
+			return err
+		}
+
+		if err := moveRecords(tx, moved, source.ID, sourceWallet.ID, target.ID, targetWallet.ID); err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Wallet{}).Where("id = ?", sourceWallet.ID).
+			Updates(map[string]interface{}{"balance": 0, "bonus_balance": 0}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Wallet{}).Where("id = ?", targetWallet.ID).
+			Updates(map[string]interface{}{
+				"balance":       gorm.Expr("balance + ?", sourceWallet.Balance),
+				"bonus_balance": gorm.Expr("bonus_balance + ?", sourceWallet.BonusBalance),
+			}).Error; err != nil {
+			return err
+		}
+		if err := adjustBadge(tx, source.ID, badgeUnscratchedTickets, -moved.Unscratched); err != nil {
+			return err
+		}
+		if err := adjustBadge(tx, target.ID, badgeUnscratchedTickets, moved.Unscratched); err != nil {
+			return err
+		}
+
+		movedJSON, err := json.Marshal(moved)
+		if err != nil {
+			return err
+		}
+		merge = model.AccountMerge{
+			SourceUserID: source.ID,
+			TargetUserID: target.ID,
+			AdminID:      adminID,
+			Reason:       strings.TrimSpace(req.Reason),
+			Balance:      sourceWallet.Balance,
+			BonusBalance: sourceWallet.BonusBalance,
+			Moved:        string(movedJSON),
+		}
+		if err := tx.Create(&merge).Error; err != nil {
+			return err
+		}
+
+		details, _ := json.Marshal(map[string]interface{}{
+			"merge_id":         merge.ID,
+			"source_user_id":   source.ID,
+			"target_user_id":   target.ID,
+			"balance":          sourceWallet.Balance,
+			"bonus_balance":    sourceWallet.BonusBalance,
+			"tickets":          len(moved.Tickets),
+			"exchange_records": len(moved.ExchangeRecords),
+			"prize_claims":     len(moved.PrizeClaims),
+			"transactions":     len(moved.Transactions),
+			"reason":           merge.Reason,
+		})
+		adminLog := model.AdminLog{
+			AdminID:    adminID,
+			Action:     "merge_accounts",
+			TargetType: "user",
+			TargetID:   source.ID,
+			Details:    string(details),
+		}
+		return tx.Create(&adminLog).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &merge, nil
+}
+
+// GetMerges returns a page of the account merges, newest first
+func (s *AccountMergeService) GetMerges(page, limit int) (*AccountMergeListResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	var total int64
+	if err := s.db.Model(&model.AccountMerge{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+	var merges []model.AccountMerge
+	if err := s.db.Order("id DESC").Offset((page - 1) * limit).Limit(limit).Find(&merges).Error; err != nil {
+		return nil, err
+	}
+
+	totalPages := int(total) / limit
+	if int(total)%limit > 0 {
+		totalPages++
+	}
+	return &AccountMergeListResponse{
+		Merges:     merges,
+		Total:      total,
+		Page:       page,
+		Limit:      limit,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// Rollback undoes a merge: the moved records still owned by the target go
+// back to the source, the merged points are taken back from the target and
+// the source account is active again
+func (s *AccountMergeService) Rollback(adminID, mergeID uint) (*model.AccountMerge, error) {
+	var merge model.AccountMerge
+	if err := s.db.First(&merge, mergeID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMergeNotFound
+		}
+		return nil, err
+	}
+	if merge.RolledBackAt != nil {
+		return nil, ErrMergeRolledBack
+	}
+	var moved mergedRecords
+	if err := json.Unmarshal([]byte(merge.Moved), &moved); err != nil {
+		return nil, err
+	}
+
+	err := repository.Transaction(s.db, func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&model.AccountMerge{}).
+			Where("id = ? AND rolled_back_at IS NULL", merge.ID).
+			Updates(map[string]interface{}{"rolled_back_at": now, "rolled_back_by": adminID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrMergeRolledBack
+		}
+
+		var sourceWallet, targetWallet model.Wallet
+		if err := lockWallet(tx, merge.SourceUserID, &sourceWallet); err != nil {
+			return err
+		}
+		if err := lockWallet(tx, merge.TargetUserID, &targetWallet); err != nil {
+			return err
+		}
+		result = tx.Model(&model.Wallet{}).
+			Where("id = ? AND balance >= ? AND bonus_balance >= ?", targetWallet.ID, merge.Balance, merge.BonusBalance).
+			Updates(map[string]interface{}{
+				"balance":       gorm.Expr("balance - ?", merge.Balance),
+				"bonus_balance": gorm.Expr("bonus_balance - ?", merge.BonusBalance),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrMergeRollbackBalance
+		}
+		if err := tx.Model(&model.Wallet{}).Where("id = ?", sourceWallet.ID).
+			Updates(map[string]interface{}{
+				"balance":       gorm.Expr("balance + ?", merge.Balance),
+				"bonus_balance": gorm.Expr("bonus_balance + ?", merge.BonusBalance),
+			}).Error; err != nil {
+			return err
+		}
+
+		// Records the target has since given away stay where they are
+		var unscratched int64
+		if len(moved.Tickets) > 0 {
+			if err := tx.Model(&model.Ticket{}).
+				Where("id IN ? AND user_id = ? AND status IN ?", moved.Tickets, merge.TargetUserID, unscratchedTicketStatuses).
+				Count(&unscratched).Error; err != nil {
+				return err
+			}
+		}
+		if err := moveRecords(tx, moved, merge.TargetUserID, targetWallet.ID, merge.SourceUserID, sourceWallet.ID); err != nil {
+			return err
+		}
+		if err := adjustBadge(tx, merge.TargetUserID, badgeUnscratchedTickets, -int(unscratched)); err != nil {
+			return err
+		}
+		if err := adjustBadge(tx, merge.SourceUserID, badgeUnscratchedTickets, int(unscratched)); err != nil {
+			return err
+		}
+		if err := tx.Model(&model.User{}).Where("id = ?", merge.SourceUserID).
+			Update("merged_into_id", nil).Error; err != nil {
+			return err
+		}
+
+		merge.RolledBackAt = &now
+		merge.RolledBackBy = adminID
+		details, _ := json.Marshal(map[string]interface{}{
+			"merge_id":       merge.ID,
+			"source_user_id": merge.SourceUserID,
+			"target_user_id": merge.TargetUserID,
+			"balance":        merge.Balance,
+			"bonus_balance":  merge.BonusBalance,
+		})
+		adminLog := model.AdminLog{
+			AdminID:    adminID,
+			Action:     "rollback_account_merge",
+			TargetType: "user",
+			TargetID:   merge.SourceUserID,
+			Details:    string(details),
+		}
+		return tx.Create(&adminLog).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &merge, nil
+}
+
+// mergeableUser loads a user that may take part in a merge: a regular
+// account that is not merged itself
+func (s *AccountMergeService) mergeableUser(userID uint) (*model.User, error) {
+	var user model.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if IsStaffRole(user.Role) || user.MergedIntoID != nil {
+		return nil, ErrInvalidMerge
+	}
+	return &user, nil
+}
+
+// moveRecords hands the moved records still owned by one account over to
+// another, so a rollback only takes back what the target still owns
+func moveRecords(tx *gorm.DB, moved mergedRecords, fromUserID, fromWalletID, toUserID, toWalletID uint) error {
+	owned := []struct {
+		model interface{}
+		ids   []uint
+	}{
+		{&model.Ticket{}, moved.Tickets},
+		{&model.ExchangeRecord{}, moved.ExchangeRecords},
+		{&model.PrizeClaim{}, moved.PrizeClaims},
+	}
+	for _, records := range owned {
+		if len(records.ids) == 0 {
+			continue
+		}
+		if err := tx.Model(records.model).
+			Where("id IN ? AND user_id = ?", records.ids, fromUserID).
+			Update("user_id", toUserID).Error; err != nil {
+			return err
+		}
+	}
+	if len(moved.Transactions) > 0 {
+		if err := tx.Model(&model.Transaction{}).
+			Where("id IN ? AND wallet_id = ?", moved.Transactions, fromWalletID).
+			Update("wallet_id", toWalletID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// normalizeLinuxdoID folds the spellings of a Linux.do ID that identify the
+// same account: case, surrounding space and leading zeros of numeric IDs
+func normalizeLinuxdoID(linuxdoID string) string {
+	id := strings.ToLower(strings.TrimSpace(linuxdoID))
+	if id != "" && strings.Trim(id, "0123456789") == "" {
+		if trimmed := strings.TrimLeft(id, "0"); trimmed != "" {
+			return trimmed
+		}
+		return "0"
+	}
+	return id
+}
+
+func toDuplicateUser(user model.User) DuplicateUser {
+	return DuplicateUser{
+		ID:        user.ID,
+		LinuxdoID: user.LinuxdoID,
+		Username:  user.Username,
+		CreatedAt: user.CreatedAt,
+	}
+}
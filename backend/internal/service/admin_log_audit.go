@@ -0,0 +1,275 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+)
+
+// Admin log export formats
+const (
+	AdminLogExportCSV  = "csv"
+	AdminLogExportJSON = "json"
+)
+
+// Admin log chain issue kinds
+const (
+	AdminLogIssueGap        = "gap"         // Logs of the sequence are missing
+	AdminLogIssueBrokenLink = "broken_link" // The log does not link to the hash of its predecessor
+	AdminLogIssueEdited     = "edited"      // The log's fields do not match its hash
+	AdminLogIssueDeleted    = "deleted"     // The log was soft-deleted
+)
+
+// maxAdminLogChainIssues limits the issues reported by a verification
+const maxAdminLogChainIssues = 100
+
+// adminLogVerifyBatch is the number of logs verified per query
+const adminLogVerifyBatch = 500
+
+var (
+	ErrInvalidExportFormat = errors.New("invalid export format")
+)
+
+// AdminLogExportRecord is an admin log as exported. Details are kept as
+// stored, so the hash of each record can be recomputed from the export.
+type AdminLogExportRecord struct {
+	ID         uint      `json:"id"`
+	Seq        *uint64   `json:"seq"`
+	AdminID    uint      `json:"admin_id"`
+	AdminName  string    `json:"admin_name"`
+	Action     string    `json:"action"`
+	TargetType string    `json:"target_type"`
+	TargetID   uint      `json:"target_id"`
+	Details    string    `json:"details"`
+	CreatedAt  time.Time `json:"created_at"`
+	PrevHash   string    `json:"prev_hash"`
+	Hash       string    `json:"hash"`
+}
+
+// AdminLogExport is the JSON export of admin logs
+type AdminLogExport struct {
+	ExportedAt time.Time              `json:"exported_at"`
+	Filters    AdminLogQuery          `json:"filters"`
+	HeadSeq    uint64                 `json:"head_seq"`  // Latest log of the chain at export time
+	HeadHash   string                 `json:"head_hash"` // Keep it to detect later truncation of the chain
+	Logs       []AdminLogExportRecord `json:"logs"`
+}
+
+// AdminLogChainIssue is a problem found in the admin log chain
+type AdminLogChainIssue struct {
+	Kind   string `json:"kind"`
+	Seq    uint64 `json:"seq"`
+	LogID  uint   `json:"log_id,omitempty"` // Zero for missing logs
+	Detail string `json:"detail"`
+}
+
+// AdminLogVerificationResponse is the result of verifying the admin log chain
+type AdminLogVerificationResponse struct {
+	Valid      bool                 `json:"valid"`
+	Checked    int64                `json:"checked"`
+	Unchained  int64                `json:"unchained"` // Logs written before chaining, which cannot be verified
+	HeadSeq    uint64               `json:"head_seq"`
+	HeadHash   string               `json:"head_hash"`
+	Issues     []AdminLogChainIssue `json:"issues"` // At most 100
+	VerifiedAt time.Time            `json:"verified_at"`
+}
+
+// ExportAdminLogs exports the admin logs matching the listing filters, oldest
+// first, as CSV or JSON. The export itself is logged.
+func (s *AdminService) ExportAdminLogs(adminID uint, query AdminLogQuery, format string) ([]byte, error) {
+	if format != AdminLogExportCSV && format != AdminLogExportJSON {
+		return nil, ErrInvalidExportFormat
+	}
+	query.Page, query.Limit = 0, 0
+
+	var logs []model.AdminLog
+	if err := s.adminLogFilter(query).Preload("Admin").
+		Order("created_at ASC, id ASC").
+		Find(&logs).Error; err != nil {
+		return nil, err
+	}
+	head, err := s.adminLogChainHead()
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]AdminLogExportRecord, len(logs))
+	for i, log := range logs {
+		records[i] = AdminLogExportRecord{
+			ID:         log.ID,
+			Seq:        log.Seq,
+			AdminID:    log.AdminID,
+			AdminName:  log.Admin.Username,
+			Action:     log.Action,
+			TargetType: log.TargetType,
+			TargetID:   log.TargetID,
+			Details:    log.Details,
+			CreatedAt:  log.CreatedAt,
+			PrevHash:   log.PrevHash,
+			Hash:       log.Hash,
+		}
+	}
+
+	var data []byte
+	if format == AdminLogExportJSON {
+		export := AdminLogExport{
+			ExportedAt: time.Now(),
+			Filters:    query,
+			Logs:       records,
+		}
+		if head.Seq != nil {
+			export.HeadSeq, export.HeadHash = *head.Seq, head.Hash
+		}
+		if data, err = json.MarshalIndent(export, "", "  "); err != nil {
+			return nil, err
+		}
+	} else {
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		_ = w.Write([]string{"ID", "序号", "管理员ID", "管理员", "操作", "对象类型", "对象ID", "详情", "时间", "上一哈希", "哈希"})
+		for _, r := range records {
+			seq := ""
+			if r.Seq != nil {
+				seq = strconv.FormatUint(*r.Seq, 10)
+			}
+			_ = w.Write([]string{
+				strconv.FormatUint(uint64(r.ID), 10),
+				seq,
+				strconv.FormatUint(uint64(r.AdminID), 10),
+				r.AdminName,
+				r.Action,
+				r.TargetType,
+				strconv.FormatUint(uint64(r.TargetID), 10),
+				r.Details,
+				r.CreatedAt.Format(time.RFC3339Nano),
+				r.PrevHash,
+				r.Hash,
+			})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return nil, err
+		}
+		data = buf.Bytes()
+	}
+
+	details, _ := json.Marshal(map[string]interface{}{
+		"format":      format,
+		"admin_id":    query.AdminID,
+		"action":      query.Action,
+		"target_type": query.TargetType,
+		"records":     len(records),
+	})
+	adminLog := model.AdminLog{
+		AdminID:    adminID,
+		Action:     "export_admin_logs",
+		TargetType: "admin_log",
+		TargetID:   0,
+		Details:    string(details),
+	}
+	if err := s.db.Create(&adminLog).Error; err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// adminLogChainHead returns the latest chained admin log, with a nil Seq if
+// there is none
+func (s *AdminService) adminLogChainHead() (*model.AdminLog, error) {
+	var head model.AdminLog
+	if err := s.db.Unscoped().Select("seq", "hash").
+		Where("seq IS NOT NULL").
+		Order("seq DESC").
+		Limit(1).
+		Find(&head).Error; err != nil {
+		return nil, err
+	}
+	return &head, nil
+}
+
+// VerifyAdminLogs walks the admin log chain and reports missing, edited,
+// deleted and relinked logs. Logs removed from the end of the chain leave no
+// trace in it; compare the head with the head of an earlier export to catch
+// those.
+func (s *AdminService) VerifyAdminLogs() (*AdminLogVerificationResponse, error) {
+	resp := &AdminLogVerificationResponse{
+		Issues:     []AdminLogChainIssue{},
+		VerifiedAt: time.Now(),
+	}
+	if err := s.db.Model(&model.AdminLog{}).Unscoped().Where("seq IS NULL").Count(&resp.Unchained).Error; err != nil {
+		return nil, err
+	}
+
+	report := func(issue AdminLogChainIssue) {
+		if len(resp.Issues) < maxAdminLogChainIssues {
+			resp.Issues = append(resp.Issues, issue)
+		}
+	}
+
+	var lastSeq uint64
+	prevHash := ""
+	for {
+		var logs []model.AdminLog
+		if err := s.db.Unscoped().
+			Where("seq IS NOT NULL AND seq > ?", lastSeq).
+			Order("seq ASC").
+			Limit(adminLogVerifyBatch).
+			Find(&logs).Error; err != nil {
+			return nil, err
+		}
+
+		for i := range logs {
+			log := &logs[i]
+			seq := *log.Seq
+			if seq != lastSeq+1 {
+				report(AdminLogChainIssue{
+					Kind:   AdminLogIssueGap,
+					Seq:    lastSeq + 1,
+					Detail: fmt.Sprintf("logs %d to %d are missing", lastSeq+1, seq-1),
+				})
+			} else if log.PrevHash != prevHash {
+				report(AdminLogChainIssue{
+					Kind:   AdminLogIssueBrokenLink,
+					Seq:    seq,
+					LogID:  log.ID,
+					Detail: "previous hash does not match the previous log",
+				})
+			}
+			if repository.AdminLogHash(log) != log.Hash {
+				report(AdminLogChainIssue{
+					Kind:   AdminLogIssueEdited,
+					Seq:    seq,
+					LogID:  log.ID,
+					Detail: "log does not match its hash",
+				})
+			}
+			if log.DeletedAt.Valid {
+				report(AdminLogChainIssue{
+					Kind:   AdminLogIssueDeleted,
+					Seq:    seq,
+					LogID:  log.ID,
+					Detail: fmt.Sprintf("log was deleted at %s", log.DeletedAt.Time.Format(time.RFC3339)),
+				})
+			}
+
+			resp.Checked++
+			lastSeq, prevHash = seq, log.Hash
+		}
+
+		if len(logs) < adminLogVerifyBatch {
+			break
+		}
+	}
+
+	resp.HeadSeq, resp.HeadHash = lastSeq, prevHash
+	resp.Valid = len(resp.Issues) == 0
+	return resp, nil
+}
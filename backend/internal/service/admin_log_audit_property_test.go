@@ -0,0 +1,157 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+	"gorm.io/gorm"
+)
+
+// setupAdminLogAuditTest creates a tenant test database that chains admin logs
+func setupAdminLogAuditTest(t *testing.T) *gorm.DB {
+	db := setupTenantTestDB(t)
+	if err := db.Use(repository.AdminLogChainPlugin{}); err != nil {
+		t.Fatalf("Failed to register admin log chain plugin: %v", err)
+	}
+	return db
+}
+
+// Admin log chain: an untouched chain verifies, and editing, deleting or
+// soft-deleting any log is reported at that log.
+func TestAdminLogChainVerification(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	db := setupAdminLogAuditTest(t)
+	admin := NewAdminService(db, NewWalletService(db)).ForTenant(1)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("tampering is detected where it happened", prop.ForAll(
+		func(count int, target int, tamper int) bool {
+			defer db.Unscoped().Where("1 = 1").Delete(&model.AdminLog{})
+
+			scoped := repository.ScopeTenant(db, 1)
+			for i := 0; i < count; i++ {
+				if err := scoped.Create(&model.AdminLog{AdminID: 1, Action: "update_config", TargetType: "config", TargetID: uint(i)}).Error; err != nil {
+					t.Logf("Failed to create admin log: %v", err)
+					return false
+				}
+			}
+			// Logs of another tenant do not affect the chain
+			repository.ScopeTenant(db, 2).Create(&model.AdminLog{AdminID: 2, Action: "update_config"})
+
+			result, err := admin.VerifyAdminLogs()
+			if err != nil || !result.Valid || result.Checked != int64(count) || result.HeadSeq != uint64(count) {
+				t.Logf("Expected an intact chain of %d logs, got %+v (err %v)", count, result, err)
+				return false
+			}
+
+			seq := uint64(target%count + 1)
+			var kind string
+			switch tamper {
+			case 0:
+				kind = AdminLogIssueEdited
+				db.Model(&model.AdminLog{}).Where("tenant_id = 1 AND seq = ?", seq).Update("action", "tampered")
+			case 1:
+				kind = AdminLogIssueDeleted
+				db.Where("tenant_id = 1 AND seq = ?", seq).Delete(&model.AdminLog{})
+			default:
+				kind = AdminLogIssueGap
+				db.Unscoped().Where("tenant_id = 1 AND seq = ?", seq).Delete(&model.AdminLog{})
+			}
+
+			result, err = admin.VerifyAdminLogs()
+			if err != nil {
+				t.Logf("VerifyAdminLogs failed: %v", err)
+				return false
+			}
+			// Removing the last log leaves no gap, only a shorter chain
+			if kind == AdminLogIssueGap && seq == uint64(count) {
+				return result.Valid && result.HeadSeq == seq-1
+			}
+			if result.Valid || len(result.Issues) == 0 {
+				t.Logf("Expected tampering with log %d to be detected, got %+v", seq, result)
+				return false
+			}
+			if result.Issues[0].Kind != kind || result.Issues[0].Seq != seq {
+				t.Logf("Expected %s at %d, got %+v", kind, seq, result.Issues)
+				return false
+			}
+			return true
+		},
+		gen.IntRange(2, 12),
+		gen.IntRange(0, 100),
+		gen.IntRange(0, 2),
+	))
+
+	properties.TestingRun(t)
+}
+
+// Admin log export: filters apply, records carry their hashes, the JSON
+// export carries the chain head, and the export itself is logged.
+func TestAdminLogExport(t *testing.T) {
+	db := setupAdminLogAuditTest(t)
+	admin := NewAdminService(db, NewWalletService(db)).ForTenant(1)
+
+	scoped := repository.ScopeTenant(db, 1)
+	for i := 0; i < 3; i++ {
+		scoped.Create(&model.AdminLog{AdminID: 1, Action: "update_config", Details: `{"key":"a,b"}`})
+	}
+	scoped.Create(&model.AdminLog{AdminID: 1, Action: "adjust_balance"})
+
+	if _, err := admin.ExportAdminLogs(1, AdminLogQuery{}, "xml"); err != ErrInvalidExportFormat {
+		t.Errorf("Expected ErrInvalidExportFormat, got %v", err)
+	}
+
+	data, err := admin.ExportAdminLogs(1, AdminLogQuery{Action: "update_config"}, AdminLogExportCSV)
+	if err != nil {
+		t.Fatalf("ExportAdminLogs failed: %v", err)
+	}
+	rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read the CSV export: %v", err)
+	}
+	if len(rows) != 4 || rows[1][7] != `{"key":"a,b"}` || rows[3][1] != "3" || rows[3][10] == "" {
+		t.Errorf("Unexpected CSV export %v", rows)
+	}
+
+	data, err = admin.ExportAdminLogs(1, AdminLogQuery{}, AdminLogExportJSON)
+	if err != nil {
+		t.Fatalf("ExportAdminLogs failed: %v", err)
+	}
+	var export AdminLogExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		t.Fatalf("Failed to read the JSON export: %v", err)
+	}
+	// The CSV export was logged before this one
+	if len(export.Logs) != 5 || export.HeadSeq != 5 || export.HeadHash != export.Logs[4].Hash {
+		t.Errorf("Unexpected JSON export %+v", export)
+	}
+	for _, record := range export.Logs {
+		log := model.AdminLog{TenantID: 1, AdminID: record.AdminID, Action: record.Action, TargetType: record.TargetType,
+			TargetID: record.TargetID, Details: record.Details, Seq: record.Seq, PrevHash: record.PrevHash}
+		log.CreatedAt = record.CreatedAt
+		if repository.AdminLogHash(&log) != record.Hash {
+			t.Errorf("Exported log %d does not match its hash", record.ID)
+		}
+	}
+
+	var exports int64
+	db.Model(&model.AdminLog{}).Where("action = ?", "export_admin_logs").Count(&exports)
+	if exports != 2 {
+		t.Errorf("Expected 2 export logs, got %d", exports)
+	}
+	if result, _ := admin.VerifyAdminLogs(); !result.Valid || result.Checked != 6 {
+		t.Errorf("Expected the chain to stay intact through exports, got %+v", result)
+	}
+}
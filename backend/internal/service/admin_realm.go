@@ -0,0 +1,154 @@
+package service
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/pkg/auth"
+)
+
+// Reasons an admin request is refused, recorded on the audit incident and
+// returned as error details
+const (
+	AdminDenialNotAdmin      = "not_admin"
+	AdminDenialIPNotAllowed  = "ip_not_allowed"
+	AdminDenialTokenRequired = "admin_token_required"
+	AdminDenialReadOnly      = "support_read_only" // Support staff may only read
+)
+
+var (
+	ErrInvalidIPAllowlist = errors.New("invalid admin IP allowlist")
+	ErrAdminRealmDisabled = errors.New("admin token audience not configured")
+	ErrNotAdmin           = errors.New("user is not an admin")
+	ErrAdminIPNotAllowed  = errors.New("IP not in the admin allowlist")
+)
+
+// AdminRealm guards the admin API. A request from an admin passes when it
+// comes from an allowlisted network, or carries a token issued for the admin
+// audience. With neither configured any admin passes.
+type AdminRealm struct {
+	allowed    []*net.IPNet
+	issuer     string
+	audience   string
+	expiry     time.Duration
+	jwtManager *auth.JWTManager
+}
+
+// AdminSessionResponse is an access token for the admin audience
+type AdminSessionResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// NewAdminRealm creates the admin realm. allowlist is a comma separated list
+// of IPs and CIDR ranges (e.g. from ADMIN_IP_ALLOWLIST); an empty audience
+// disables admin tokens.
+func NewAdminRealm(jwtManager *auth.JWTManager, allowlist, issuer, audience string, expiry time.Duration) (*AdminRealm, error) {
+	allowed, err := ParseIPAllowlist(allowlist)
+	if err != nil {
+		return nil, err
+	}
+	if expiry <= 0 {
+		expiry = time.Hour
+	}
+	return &AdminRealm{
+		allowed:    allowed,
+		issuer:     issuer,
+		audience:   audience,
+		expiry:     expiry,
+		jwtManager: jwtManager,
+	}, nil
+}
+
+// ParseIPAllowlist parses a comma separated list of IPs and CIDR ranges
+func ParseIPAllowlist(s string) ([]*net.IPNet, error) {
+	var allowed []*net.IPNet
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if !strings.Contains(part, "/") {
+			ip := net.ParseIP(part)
+			if ip == nil {
+				return nil, ErrInvalidIPAllowlist
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			allowed = append(allowed, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(part)
+		if err != nil {
+			return nil, ErrInvalidIPAllowlist
+		}
+		allowed = append(allowed, network)
+	}
+	return allowed, nil
+}
+
+// Restricted reports whether the realm restricts admins by network or token
+func (r *AdminRealm) Restricted() bool {
+	return len(r.allowed) > 0 || r.audience != ""
+}
+
+// AllowsIP reports whether ip is within the allowlist
+func (r *AdminRealm) AllowsIP(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range r.allowed {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns why an admin request from ip with claims is refused, or ""
+// when it passes
+func (r *AdminRealm) Check(claims *auth.Claims, ip string) string {
+	if claims == nil || !IsStaffRole(claims.Role) {
+		return AdminDenialNotAdmin
+	}
+	if !r.Restricted() || r.AllowsIP(ip) {
+		return ""
+	}
+	if r.audience != "" && claims.HasAudience(r.issuer, r.audience) {
+		return ""
+	}
+	if r.audience != "" {
+		return AdminDenialTokenRequired
+	}
+	return AdminDenialIPNotAllowed
+}
+
+// IssueToken issues an admin audience token to an admin. When an allowlist
+// is configured the token can only be obtained from it, and then lets the
+// admin in from elsewhere until it expires.
+func (r *AdminRealm) IssueToken(user *model.User, ip string) (*AdminSessionResponse, error) {
+	if r.audience == "" {
+		return nil, ErrAdminRealmDisabled
+	}
+	if !IsStaffRole(user.Role) {
+		return nil, ErrNotAdmin
+	}
+	if len(r.allowed) > 0 && !r.AllowsIP(ip) {
+		return nil, ErrAdminIPNotAllowed
+	}
+
+	token, err := r.jwtManager.GenerateAudienceToken(user.TenantID, user.ID, user.LinuxdoID, user.Username, user.Role, r.issuer, r.audience, r.expiry)
+	if err != nil {
+		return nil, err
+	}
+	return &AdminSessionResponse{
+		AccessToken: token,
+		ExpiresIn:   int64(r.expiry.Seconds()),
+	}, nil
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/pkg/auth"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+)
+
+// Admin realm: an admin passes exactly when the realm is unrestricted, the
+// IP is allowlisted, or the token carries the admin audience; non-admins
+// never pass.
+func TestAdminRealmCheck(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	jwtManager := auth.NewJWTManager("test-secret", 15, 7)
+	admin := &model.User{LinuxdoID: "admin", Username: "Admin", Role: "admin"}
+	admin.ID = 1
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("admins pass by network or audience", prop.ForAll(
+		func(withAllowlist, withAudience, fromOffice, adminToken, isAdmin bool) bool {
+			allowlist, audience := "", ""
+			if withAllowlist {
+				allowlist = "10.1.0.0/16, 192.168.5.7"
+			}
+			if withAudience {
+				audience = "admin-api"
+			}
+			realm, err := NewAdminRealm(jwtManager, allowlist, "scratch-lottery", audience, time.Hour)
+			if err != nil {
+				t.Logf("NewAdminRealm failed: %v", err)
+				return false
+			}
+
+			ip := "203.0.113.9"
+			if fromOffice {
+				ip = "10.1.42.3"
+			}
+			role := "user"
+			if isAdmin {
+				role = "admin"
+			}
+
+			var token string
+			if adminToken {
+				token, err = jwtManager.GenerateAudienceToken(0, admin.ID, admin.LinuxdoID, admin.Username, role, "scratch-lottery", "admin-api", time.Hour)
+			} else {
+				token, _, err = jwtManager.GenerateTokenPair(admin.ID, admin.LinuxdoID, admin.Username, role)
+			}
+			if err != nil {
+				t.Logf("Generating a token failed: %v", err)
+				return false
+			}
+			claims, err := jwtManager.ValidateToken(token)
+			if err != nil {
+				t.Logf("ValidateToken failed: %v", err)
+				return false
+			}
+
+			passes := isAdmin && ((!withAllowlist && !withAudience) ||
+				(withAllowlist && fromOffice) ||
+				(withAudience && adminToken))
+			reason := realm.Check(claims, ip)
+			if passes != (reason == "") {
+				t.Logf("allowlist %v audience %v office %v token %v admin %v: got %q", withAllowlist, withAudience, fromOffice, adminToken, isAdmin, reason)
+				return false
+			}
+			return true
+		},
+		gen.Bool(),
+		gen.Bool(),
+		gen.Bool(),
+		gen.Bool(),
+		gen.Bool(),
+	))
+
+	properties.TestingRun(t)
+}
+
+// Admin realm: admin tokens are issued only to admins, only from the
+// allowlist when one is set, and only for the configured issuer.
+func TestAdminRealmIssueToken(t *testing.T) {
+	jwtManager := auth.NewJWTManager("test-secret", 15, 7)
+	admin := &model.User{LinuxdoID: "admin", Username: "Admin", Role: "admin"}
+	admin.ID = 1
+
+	if _, err := NewAdminRealm(jwtManager, "10.0.0.0/33", "scratch-lottery", "admin-api", time.Hour); err != ErrInvalidIPAllowlist {
+		t.Errorf("Expected ErrInvalidIPAllowlist, got %v", err)
+	}
+
+	disabled, _ := NewAdminRealm(jwtManager, "", "scratch-lottery", "", time.Hour)
+	if _, err := disabled.IssueToken(admin, "10.0.0.1"); err != ErrAdminRealmDisabled {
+		t.Errorf("Expected ErrAdminRealmDisabled, got %v", err)
+	}
+
+	realm, err := NewAdminRealm(jwtManager, "10.0.0.0/8,2001:db8::1", "scratch-lottery", "admin-api", time.Hour)
+	if err != nil {
+		t.Fatalf("NewAdminRealm failed: %v", err)
+	}
+	if _, err := realm.IssueToken(&model.User{Role: "user"}, "10.0.0.1"); err != ErrNotAdmin {
+		t.Errorf("Expected ErrNotAdmin, got %v", err)
+	}
+	if _, err := realm.IssueToken(admin, "203.0.113.9"); err != ErrAdminIPNotAllowed {
+		t.Errorf("Expected ErrAdminIPNotAllowed, got %v", err)
+	}
+	if !realm.AllowsIP("2001:db8::1") || realm.AllowsIP("2001:db8::2") {
+		t.Errorf("Expected only the listed IPv6 address to be allowed")
+	}
+
+	session, err := realm.IssueToken(admin, "10.9.8.7")
+	if err != nil {
+		t.Fatalf("IssueToken failed: %v", err)
+	}
+	claims, err := jwtManager.ValidateToken(session.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateToken failed: %v", err)
+	}
+	if claims.TokenType != auth.AccessToken || session.ExpiresIn != 3600 {
+		t.Errorf("Unexpected admin session %+v", claims)
+	}
+	if reason := realm.Check(claims, "203.0.113.9"); reason != "" {
+		t.Errorf("Expected the admin token to pass off the allowlist, got %q", reason)
+	}
+
+	// A token of another issuer does not count
+	other, _ := NewAdminRealm(jwtManager, "", "other-deployment", "admin-api", time.Hour)
+	if reason := other.Check(claims, "203.0.113.9"); reason != AdminDenialTokenRequired {
+		t.Errorf("Expected %q for a foreign issuer, got %q", AdminDenialTokenRequired, reason)
+	}
+}
+
+// Admin denials are audited per tenant and never lock the account out.
+func TestAdminDenialAudit(t *testing.T) {
+	db := setupAuthGuardTestDB(t)
+	guard := NewAuthGuardService(db, NewNotificationService(db), 2, time.Hour, time.Hour).ForTenant(2)
+
+	client := ClientInfo{IP: "203.0.113.9", UserAgent: "test"}
+	for i := 0; i < 5; i++ {
+		if err := guard.RecordAdminDenial(7, "admin", client, fmt.Sprintf("%s GET /api/admin/users", AdminDenialIPNotAllowed)); err != nil {
+			t.Fatalf("RecordAdminDenial failed: %v", err)
+		}
+	}
+
+	if lockedUntil, _ := guard.LockedUntil("admin", client.IP); lockedUntil != nil {
+		t.Errorf("Admin denials locked the account out until %v", lockedUntil)
+	}
+	incidents, err := guard.GetIncidents(AuthIncidentQuery{Kind: string(model.AuthIncidentAdminDenied)})
+	if err != nil {
+		t.Fatalf("GetIncidents failed: %v", err)
+	}
+	if incidents.Total != 5 || incidents.Incidents[0].UserID == nil || *incidents.Incidents[0].UserID != 7 {
+		t.Errorf("Expected 5 denials of user 7, got %+v", incidents)
+	}
+	if other, _ := guard.ForTenant(1).GetIncidents(AuthIncidentQuery{Kind: string(model.AuthIncidentAdminDenied)}); other.Total != 0 {
+		t.Errorf("Denials leaked into another tenant")
+	}
+}
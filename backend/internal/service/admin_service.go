@@ -1,12 +1,19 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"strconv"
 	"time"
 
 	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+	"scratch-lottery/pkg/biztime"
+	"scratch-lottery/pkg/money"
+	"scratch-lottery/pkg/redact"
 
 	"gorm.io/gorm"
 )
@@ -20,38 +27,88 @@ var (
 // AdminService handles admin-related business logic
 type AdminService struct {
 	db            *gorm.DB
+	reportDB      *gorm.DB // statistics and exports, see UseReportDB
 	walletService *WalletService
+	notifications *NotificationService // optional, tells users of point adjustments
 }
 
 // NewAdminService creates a new admin service
 func NewAdminService(db *gorm.DB, walletService *WalletService) *AdminService {
 	return &AdminService{
 		db:            db,
+		reportDB:      db,
 		walletService: walletService,
 	}
 }
 
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *AdminService) ForTenant(tenantID uint) *AdminService {
+	scoped := &AdminService{
+		db:            repository.ScopeTenant(s.db, tenantID),
+		reportDB:      repository.ScopeTenant(s.reportDB, tenantID),
+		walletService: s.walletService.ForTenant(tenantID),
+	}
+	if s.notifications != nil {
+		scoped.notifications = s.notifications.ForTenant(tenantID)
+	}
+	return scoped
+}
+
+// WithRequest returns a copy of the service whose logs carry the request id
+// of ctx
+func (s *AdminService) WithRequest(ctx context.Context) *AdminService {
+	scoped := *s
+	scoped.db = repository.ScopeRequest(s.db, ctx)
+	scoped.reportDB = repository.ScopeRequest(s.reportDB, ctx)
+	scoped.walletService = s.walletService.WithRequest(ctx)
+	return &scoped
+}
+
+// UseReportDB runs statistics, forecasts and their exports on db, typically
+// a connection under a read-only database role
+func (s *AdminService) UseReportDB(db *gorm.DB) {
+	s.reportDB = db
+}
+
+// UseNotifications notifies users when an admin adjusts their points
+func (s *AdminService) UseNotifications(notifications *NotificationService) {
+	s.notifications = notifications
+}
+
+// reporting returns a copy of the service that reads from the report database
+func (s *AdminService) reporting() *AdminService {
+	return &AdminService{
+		db:            s.reportDB,
+		reportDB:      s.reportDB,
+		walletService: s.walletService,
+	}
+}
+
 // ==================== Dashboard Statistics ====================
 
 // DashboardStats represents the dashboard statistics
 type DashboardStats struct {
-	TotalUsers       int64   `json:"total_users"`
-	NewUsersToday    int64   `json:"new_users_today"`
-	NewUsersWeek     int64   `json:"new_users_week"`
-	NewUsersMonth    int64   `json:"new_users_month"`
-	TotalTicketsSold int64   `json:"total_tickets_sold"`
-	TotalRevenue     int64   `json:"total_revenue"`
-	TotalPrizesPaid  int64   `json:"total_prizes_paid"`
-	TotalExchanges   int64   `json:"total_exchanges"`
-	ActivePrizePools int64   `json:"active_prize_pools"`
-	AvailableStock   int64   `json:"available_stock"`
+	TotalUsers           int64 `json:"total_users"`
+	NewUsersToday        int64 `json:"new_users_today"`
+	NewUsersWeek         int64 `json:"new_users_week"`
+	NewUsersMonth        int64 `json:"new_users_month"`
+	TotalTicketsSold     int64 `json:"total_tickets_sold"`
+	TotalRevenue         int64 `json:"total_revenue"`
+	TotalPrizesPaid      int64 `json:"total_prizes_paid"`
+	TotalExchanges       int64 `json:"total_exchanges"`
+	ActivePrizePools     int64 `json:"active_prize_pools"`
+	AvailableStock       int64 `json:"available_stock"`
+	OpenSupportIssues    int64 `json:"open_support_issues"`
+	OverdueSupportIssues int64 `json:"overdue_support_issues"` // Unresolved past their SLA deadline
 }
 
 // GetDashboardStats returns dashboard statistics
 func (s *AdminService) GetDashboardStats() (*DashboardStats, error) {
+	s = s.reporting()
+
 	stats := &DashboardStats{}
 	now := time.Now()
-	todayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	todayStart := biztime.DayStart(now)
 	weekStart := todayStart.AddDate(0, 0, -7)
 	monthStart := todayStart.AddDate(0, -1, 0)
 
@@ -80,13 +137,13 @@ func (s *AdminService) GetDashboardStats() (*DashboardStats, error) {
 		return nil, err
 	}
 
-	// Total revenue (sum of ticket prices)
+	// Total revenue (sum of ticket prices, in points and bonus credits)
 	var revenue struct {
 		Total int64
 	}
 	if err := s.db.Model(&model.Transaction{}).
 		Select("COALESCE(SUM(ABS(amount)), 0) as total").
-		Where("type = ?", model.TransactionTypePurchase).
+		Where("type IN ?", ticketSpendTypes).
 		Scan(&revenue).Error; err != nil {
 		return nil, err
 	}
@@ -128,6 +185,18 @@ func (s *AdminService) GetDashboardStats() (*DashboardStats, error) {
 	}
 	stats.AvailableStock = stock.Total
 
+	// Unresolved support issues and those past their SLA deadline
+	if err := s.db.Model(&model.SupportIssue{}).
+		Where("status IN ?", activeSupportStatuses).
+		Count(&stats.OpenSupportIssues).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(&model.SupportIssue{}).
+		Where("status IN ? AND due_at < ?", activeSupportStatuses, now.UTC()).
+		Count(&stats.OverdueSupportIssues).Error; err != nil {
+		return nil, err
+	}
+
 	return stats, nil
 }
 
@@ -160,6 +229,7 @@ type UserResponse struct {
 	Balance   int       `json:"balance"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	Notes     []UserNoteResponse `json:"notes,omitempty"` // Recent admin notes, user detail only
 }
 
 // GetUsers returns paginated user list
@@ -233,16 +303,21 @@ func (s *AdminService) GetUsers(query UserListQuery) (*UserListResponse, error)
 	}, nil
 }
 
-// GetUserByID returns a user by ID
+// GetUserByID returns a user by ID with the recent admin notes on them
 func (s *AdminService) GetUserByID(userID uint) (*UserResponse, error) {
 	var user model.User
-	if err := s.db.Preload("Wallet").First(&user, userID).Error; err != nil {
+	if err := s.db.First(&user, userID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
 
+	notes, err := NewUserNoteService(s.db).GetRecentNotes(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
 	return &UserResponse{
 		ID:        user.ID,
 		LinuxdoID: user.LinuxdoID,
@@ -252,6 +327,7 @@ func (s *AdminService) GetUserByID(userID uint) (*UserResponse, error) {
 		Balance:   user.Wallet.Balance,
 		CreatedAt: user.CreatedAt,
 		UpdatedAt: user.UpdatedAt,
+		Notes:     notes,
 	}, nil
 }
 
@@ -264,19 +340,13 @@ type AdjustUserPointsRequest struct {
 // AdjustUserPoints adjusts a user's points balance
 func (s *AdminService) AdjustUserPoints(adminID, userID uint, req AdjustUserPointsRequest) (*UserResponse, error) {
 	var user model.User
-	if err := s.db.Preload("Wallet").First(&user, userID).Error; err != nil {
+	if err := s.db.First(&user, userID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
 
-	// Check if adjustment would result in negative balance
-	newBalance := user.Wallet.Balance + req.Amount
-	if newBalance < 0 {
-		return nil, ErrInsufficientBalance
-	}
-
 	description := req.Description
 	if description == "" {
 		if req.Amount > 0 {
@@ -286,15 +356,28 @@ func (s *AdminService) AdjustUserPoints(adminID, userID uint, req AdjustUserPoin
 		}
 	}
 
-	err := s.db.Transaction(func(tx *gorm.DB) error {
+	err := repository.Transaction(s.db, func(tx *gorm.DB) error {
+		var wallet model.Wallet
+		if err := lockWallet(tx, userID, &wallet); err != nil {
+			return err
+		}
+
+		// Check if adjustment would result in negative balance
+		oldBalance := wallet.Balance
+		newBalance := oldBalance + req.Amount
+		if newBalance < 0 {
+			return ErrInsufficientBalance
+		}
+
 		// Update wallet balance
-		if err := tx.Model(&user.Wallet).Update("balance", newBalance).Error; err != nil {
+		if err := tx.Model(&wallet).Update("balance", newBalance).Error; err != nil {
 			return err
 		}
 
 		// Create transaction record
 		transaction := model.Transaction{
-			WalletID:    user.Wallet.ID,
+			TenantID:    wallet.TenantID,
+			WalletID:    wallet.ID,
 			Type:        model.TransactionTypeInitial, // Using initial type for admin adjustments
 			Amount:      req.Amount,
 			Description: description,
@@ -304,11 +387,24 @@ func (s *AdminService) AdjustUserPoints(adminID, userID uint, req AdjustUserPoin
 			return err
 		}
 
+		// Grants repeated within the batching window reach the user as one
+		// summary notification
+		if s.notifications != nil {
+			title := "积分到账"
+			if req.Amount < 0 {
+				title = "积分扣除"
+			}
+			content := fmt.Sprintf("%s：%+d 积分", description, req.Amount)
+			if _, err := s.notifications.notify(tx, userID, model.NotificationTypePointsAdjusted, title, content); err != nil {
+				return err
+			}
+		}
+
 		// Log admin action
 		details, _ := json.Marshal(map[string]interface{}{
 			"amount":      req.Amount,
 			"description": description,
-			"old_balance": user.Wallet.Balance,
+			"old_balance": oldBalance,
 			"new_balance": newBalance,
 		})
 		adminLog := model.AdminLog{
@@ -331,7 +427,7 @@ func (s *AdminService) AdjustUserPoints(adminID, userID uint, req AdjustUserPoin
 
 // UpdateUserRole updates a user's role
 func (s *AdminService) UpdateUserRole(adminID, userID uint, role string) (*UserResponse, error) {
-	if role != "user" && role != "admin" {
+	if role != "user" && !IsStaffRole(role) {
 		return nil, errors.New("invalid role")
 	}
 
@@ -381,6 +477,13 @@ type SystemSettings struct {
 	EPayMerchantID   string `json:"epay_merchant_id"`
 	EPaySecret       string `json:"epay_secret"`
 	EPayCallbackURL  string `json:"epay_callback_url"`
+	EPayGatewayURL   string `json:"epay_gateway_url"`
+	PaymentGateway   string `json:"payment_gateway"`
+	InventoryAlertWebhookURL string `json:"inventory_alert_webhook_url"`
+	LargeWinThreshold        int    `json:"large_win_threshold"`
+	LargeWinIdentityRequired bool   `json:"large_win_identity_required"`
+	LargeWinApprovalRequired bool   `json:"large_win_approval_required"`
+	WalletReconcileFreeze    bool   `json:"wallet_reconcile_freeze"`
 }
 
 // GetSystemSettings returns system settings
@@ -389,31 +492,63 @@ func (s *AdminService) GetSystemSettings() (*SystemSettings, error) {
 
 	// Get payment enabled setting
 	var paymentConfig model.SystemConfig
-	if err := s.db.Where("key = ?", "payment_enabled").First(&paymentConfig).Error; err == nil {
+	if err := s.configs().Where("key = ?", "payment_enabled").First(&paymentConfig).Error; err == nil {
 		settings.PaymentEnabled = paymentConfig.Value == "true"
 	}
 
 	// Get epay settings
 	var epayMerchant model.SystemConfig
-	if err := s.db.Where("key = ?", "epay_merchant_id").First(&epayMerchant).Error; err == nil {
+	if err := s.configs().Where("key = ?", "epay_merchant_id").First(&epayMerchant).Error; err == nil {
 		settings.EPayMerchantID = epayMerchant.Value
 	}
 
 	var epaySecret model.SystemConfig
-	if err := s.db.Where("key = ?", "epay_secret").First(&epaySecret).Error; err == nil {
+	if err := s.configs().Where("key = ?", "epay_secret").First(&epaySecret).Error; err == nil {
 		// Mask the secret for security
-		if len(epaySecret.Value) > 4 {
-			settings.EPaySecret = epaySecret.Value[:4] + "****"
-		} else if epaySecret.Value != "" {
-			settings.EPaySecret = "****"
-		}
+		settings.EPaySecret = redact.Secret(epaySecret.Value)
 	}
 
 	var epayCallback model.SystemConfig
-	if err := s.db.Where("key = ?", "epay_callback_url").First(&epayCallback).Error; err == nil {
+	if err := s.configs().Where("key = ?", "epay_callback_url").First(&epayCallback).Error; err == nil {
 		settings.EPayCallbackURL = epayCallback.Value
 	}
 
+	var epayGateway model.SystemConfig
+	if err := s.configs().Where("key = ?", ConfigKeyEPayGatewayURL).First(&epayGateway).Error; err == nil {
+		settings.EPayGatewayURL = epayGateway.Value
+	}
+
+	settings.PaymentGateway = PaymentGatewayEPay
+	var paymentGateway model.SystemConfig
+	if err := s.configs().Where("key = ?", ConfigKeyPaymentGateway).First(&paymentGateway).Error; err == nil && paymentGateway.Value != "" {
+		settings.PaymentGateway = paymentGateway.Value
+	}
+
+	var inventoryWebhook model.SystemConfig
+	if err := s.configs().Where("key = ?", ConfigKeyInventoryAlertWebhook).First(&inventoryWebhook).Error; err == nil {
+		settings.InventoryAlertWebhookURL = inventoryWebhook.Value
+	}
+
+	var largeWinThreshold model.SystemConfig
+	if err := s.configs().Where("key = ?", ConfigKeyLargeWinThreshold).First(&largeWinThreshold).Error; err == nil {
+		settings.LargeWinThreshold, _ = strconv.Atoi(largeWinThreshold.Value)
+	}
+
+	var largeWinIdentity model.SystemConfig
+	if err := s.configs().Where("key = ?", ConfigKeyLargeWinIdentityRequired).First(&largeWinIdentity).Error; err == nil {
+		settings.LargeWinIdentityRequired = largeWinIdentity.Value == "true"
+	}
+
+	var largeWinApproval model.SystemConfig
+	if err := s.configs().Where("key = ?", ConfigKeyLargeWinApprovalRequired).First(&largeWinApproval).Error; err == nil {
+		settings.LargeWinApprovalRequired = largeWinApproval.Value == "true"
+	}
+
+	var reconcileFreeze model.SystemConfig
+	if err := s.configs().Where("key = ?", ConfigKeyWalletReconcileFreeze).First(&reconcileFreeze).Error; err == nil {
+		settings.WalletReconcileFreeze = reconcileFreeze.Value == "true"
+	}
+
 	return settings, nil
 }
 
@@ -423,11 +558,38 @@ type UpdateSystemSettingsRequest struct {
 	EPayMerchantID  *string `json:"epay_merchant_id"`
 	EPaySecret      *string `json:"epay_secret"`
 	EPayCallbackURL *string `json:"epay_callback_url"`
+	EPayGatewayURL  *string `json:"epay_gateway_url"`
+	PaymentGateway  *string `json:"payment_gateway"` // Gateway new orders are paid through
+	InventoryAlertWebhookURL *string `json:"inventory_alert_webhook_url"`
+	LargeWinThreshold        *int    `json:"large_win_threshold"`
+	LargeWinIdentityRequired *bool   `json:"large_win_identity_required"`
+	LargeWinApprovalRequired *bool   `json:"large_win_approval_required"`
+	WalletReconcileFreeze    *bool   `json:"wallet_reconcile_freeze"`
+}
+
+// changesPayment reports whether the request touches any payment setting
+func (r *UpdateSystemSettingsRequest) changesPayment() bool {
+	return r.PaymentEnabled != nil || r.EPayMerchantID != nil || r.EPaySecret != nil ||
+		r.EPayCallbackURL != nil || r.EPayGatewayURL != nil
 }
 
 // UpdateSystemSettings updates system settings
 func (s *AdminService) UpdateSystemSettings(adminID uint, req UpdateSystemSettingsRequest) (*SystemSettings, error) {
-	err := s.db.Transaction(func(tx *gorm.DB) error {
+	if req.LargeWinThreshold != nil && *req.LargeWinThreshold < 0 {
+		return nil, ErrInvalidReportThreshold
+	}
+	if req.PaymentGateway != nil && !validPaymentGateway(*req.PaymentGateway) {
+		return nil, ErrUnknownPaymentGateway
+	}
+
+	err := s.configs().Transaction(func(tx *gorm.DB) error {
+		// Keep the settings in place before the first recorded change restorable
+		if req.changesPayment() {
+			if err := recordInitialPaymentSettings(tx, adminID); err != nil {
+				return err
+			}
+		}
+
 		if req.PaymentEnabled != nil {
 			if err := s.upsertConfig(tx, "payment_enabled", boolToString(*req.PaymentEnabled)); err != nil {
 				return err
@@ -440,7 +602,7 @@ func (s *AdminService) UpdateSystemSettings(adminID uint, req UpdateSystemSettin
 			}
 		}
 
-		if req.EPaySecret != nil && *req.EPaySecret != "" && !containsMask(*req.EPaySecret) {
+		if req.EPaySecret != nil && *req.EPaySecret != "" && !redact.IsMasked(*req.EPaySecret) {
 			if err := s.upsertConfig(tx, "epay_secret", *req.EPaySecret); err != nil {
 				return err
 			}
@@ -452,8 +614,56 @@ func (s *AdminService) UpdateSystemSettings(adminID uint, req UpdateSystemSettin
 			}
 		}
 
-		// Log admin action
-		details, _ := json.Marshal(req)
+		if req.EPayGatewayURL != nil {
+			if err := s.upsertConfig(tx, ConfigKeyEPayGatewayURL, *req.EPayGatewayURL); err != nil {
+				return err
+			}
+		}
+
+		if req.PaymentGateway != nil {
+			if err := s.upsertConfig(tx, ConfigKeyPaymentGateway, *req.PaymentGateway); err != nil {
+				return err
+			}
+		}
+
+		if req.changesPayment() {
+			if _, err := recordPaymentSettingsVersion(tx, adminID, model.PaymentSettingsActionUpdate, 0); err != nil {
+				return err
+			}
+		}
+
+		if req.InventoryAlertWebhookURL != nil {
+			if err := s.upsertConfig(tx, ConfigKeyInventoryAlertWebhook, *req.InventoryAlertWebhookURL); err != nil {
+				return err
+			}
+		}
+
+		if req.LargeWinThreshold != nil {
+			if err := s.upsertConfig(tx, ConfigKeyLargeWinThreshold, strconv.Itoa(*req.LargeWinThreshold)); err != nil {
+				return err
+			}
+		}
+
+		if req.LargeWinIdentityRequired != nil {
+			if err := s.upsertConfig(tx, ConfigKeyLargeWinIdentityRequired, boolToString(*req.LargeWinIdentityRequired)); err != nil {
+				return err
+			}
+		}
+
+		if req.LargeWinApprovalRequired != nil {
+			if err := s.upsertConfig(tx, ConfigKeyLargeWinApprovalRequired, boolToString(*req.LargeWinApprovalRequired)); err != nil {
+				return err
+			}
+		}
+
+		if req.WalletReconcileFreeze != nil {
+			if err := s.upsertConfig(tx, ConfigKeyWalletReconcileFreeze, boolToString(*req.WalletReconcileFreeze)); err != nil {
+				return err
+			}
+		}
+
+		// Log admin action (never persist the secret itself)
+		details, _ := json.Marshal(redact.Value("", req))
 		adminLog := model.AdminLog{
 			AdminID:    adminID,
 			Action:     "update_settings",
@@ -473,6 +683,11 @@ func (s *AdminService) UpdateSystemSettings(adminID uint, req UpdateSystemSettin
 
 // upsertConfig inserts or updates a system config
 func (s *AdminService) upsertConfig(tx *gorm.DB, key, value string) error {
+	return upsertSystemConfig(tx, key, value)
+}
+
+// upsertSystemConfig inserts or updates a system config of the tenant of tx
+func upsertSystemConfig(tx *gorm.DB, key, value string) error {
 	var config model.SystemConfig
 	err := tx.Where("key = ?", key).First(&config).Error
 	
@@ -490,7 +705,7 @@ func (s *AdminService) upsertConfig(tx *gorm.DB, key, value string) error {
 // IsPaymentEnabled checks if payment is enabled
 func (s *AdminService) IsPaymentEnabled() bool {
 	var config model.SystemConfig
-	if err := s.db.Where("key = ?", "payment_enabled").First(&config).Error; err != nil {
+	if err := s.configs().Where("key = ?", "payment_enabled").First(&config).Error; err != nil {
 		return false
 	}
 	return config.Value == "true"
@@ -501,6 +716,7 @@ type EPayConfig struct {
 	MerchantID  string `json:"merchant_id"`
 	Secret      string `json:"secret"`
 	CallbackURL string `json:"callback_url"`
+	GatewayURL  string `json:"gateway_url"`
 }
 
 // GetEPayConfig returns the EPay configuration (unmasked) for payment processing
@@ -509,27 +725,32 @@ func (s *AdminService) GetEPayConfig() (*EPayConfig, error) {
 	config := &EPayConfig{}
 
 	var merchantConfig model.SystemConfig
-	if err := s.db.Where("key = ?", "epay_merchant_id").First(&merchantConfig).Error; err == nil {
+	if err := s.configs().Where("key = ?", "epay_merchant_id").First(&merchantConfig).Error; err == nil {
 		config.MerchantID = merchantConfig.Value
 	}
 
 	var secretConfig model.SystemConfig
-	if err := s.db.Where("key = ?", "epay_secret").First(&secretConfig).Error; err == nil {
+	if err := s.configs().Where("key = ?", "epay_secret").First(&secretConfig).Error; err == nil {
 		config.Secret = secretConfig.Value
 	}
 
 	var callbackConfig model.SystemConfig
-	if err := s.db.Where("key = ?", "epay_callback_url").First(&callbackConfig).Error; err == nil {
+	if err := s.configs().Where("key = ?", "epay_callback_url").First(&callbackConfig).Error; err == nil {
 		config.CallbackURL = callbackConfig.Value
 	}
 
+	var gatewayConfig model.SystemConfig
+	if err := s.configs().Where("key = ?", ConfigKeyEPayGatewayURL).First(&gatewayConfig).Error; err == nil {
+		config.GatewayURL = gatewayConfig.Value
+	}
+
 	return config, nil
 }
 
 // GetConfigValue retrieves a single system config value by key
 func (s *AdminService) GetConfigValue(key string) (string, error) {
 	var config model.SystemConfig
-	if err := s.db.Where("key = ?", key).First(&config).Error; err != nil {
+	if err := s.configs().Where("key = ?", key).First(&config).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return "", ErrConfigNotFound
 		}
@@ -540,7 +761,16 @@ func (s *AdminService) GetConfigValue(key string) (string, error) {
 
 // SetConfigValue sets a single system config value
 func (s *AdminService) SetConfigValue(key, value string) error {
-	return s.upsertConfig(s.db, key, value)
+	return s.upsertConfig(s.configs(), key, value)
+}
+
+// configs returns the session used for system config. Settings belong to a
+// tenant, so sessions without one (background jobs) use the default tenant's.
+func (s *AdminService) configs() *gorm.DB {
+	if _, ok := repository.TenantFromContext(s.db.Statement.Context); ok {
+		return s.db
+	}
+	return repository.ScopeTenant(s.db, repository.DefaultTenantID)
 }
 
 // ==================== Admin Logs ====================
@@ -575,17 +805,8 @@ type AdminLogListResponse struct {
 	TotalPages int                `json:"total_pages"`
 }
 
-// GetAdminLogs returns paginated admin logs
-func (s *AdminService) GetAdminLogs(query AdminLogQuery) (*AdminLogListResponse, error) {
-	// Set defaults
-	if query.Page < 1 {
-		query.Page = 1
-	}
-	if query.Limit < 1 || query.Limit > 100 {
-		query.Limit = 20
-	}
-
-	// Build query
+// adminLogFilter returns the admin logs matching the query's filters
+func (s *AdminService) adminLogFilter(query AdminLogQuery) *gorm.DB {
 	dbQuery := s.db.Model(&model.AdminLog{})
 
 	if query.AdminID > 0 {
@@ -597,6 +818,21 @@ func (s *AdminService) GetAdminLogs(query AdminLogQuery) (*AdminLogListResponse,
 	if query.TargetType != "" {
 		dbQuery = dbQuery.Where("target_type = ?", query.TargetType)
 	}
+	return dbQuery
+}
+
+// GetAdminLogs returns paginated admin logs
+func (s *AdminService) GetAdminLogs(query AdminLogQuery) (*AdminLogListResponse, error) {
+	// Set defaults
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit < 1 || query.Limit > 100 {
+		query.Limit = 20
+	}
+
+	// Build query
+	dbQuery := s.adminLogFilter(query)
 
 	// Get total count
 	var total int64
@@ -659,18 +895,30 @@ type StatisticsQuery struct {
 }
 
 // CoreMetrics represents core platform metrics
+
 type CoreMetrics struct {
-	TotalUsers         int64   `json:"total_users"`
-	NewUsersToday      int64   `json:"new_users_today"`
-	NewUsersWeek       int64   `json:"new_users_week"`
-	NewUsersMonth      int64   `json:"new_users_month"`
-	TotalPointsInflow  int64   `json:"total_points_inflow"`  // Total recharge + initial
-	TotalPointsOutflow int64   `json:"total_points_outflow"` // Total purchase + exchange
-	TotalTicketsSold   int64   `json:"total_tickets_sold"`
-	TotalSalesAmount   int64   `json:"total_sales_amount"`
-	TotalPrizesPaid    int64   `json:"total_prizes_paid"`
-	ReturnRate         float64 `json:"return_rate"` // prizes / sales
-	TotalExchangeCost  int64   `json:"total_exchange_cost"`
+	TotalUsers         int64             `json:"total_users"`
+	NewUsersToday      int64             `json:"new_users_today"`
+	NewUsersWeek       int64             `json:"new_users_week"`
+	NewUsersMonth      int64             `json:"new_users_month"`
+	TotalPointsInflow  int64             `json:"total_points_inflow"`  // Total recharge + initial
+	TotalPointsOutflow int64             `json:"total_points_outflow"` // Total purchase + exchange
+	TotalTicketsSold   int64             `json:"total_tickets_sold"`
+	TotalSalesAmount   int64             `json:"total_sales_amount"` // Paid in points and bonus credits
+	TotalPrizesPaid    int64             `json:"total_prizes_paid"`
+	ReturnRate         float64           `json:"return_rate"` // prizes / sales in points and bonus credits
+	TotalExchangeCost  int64             `json:"total_exchange_cost"`
+	TotalRecharge      money.Money       `json:"total_recharge"` // Paid recharge orders, in fen
+	Currencies         []CurrencyMetrics `json:"currencies"`     // Ledger totals by wallet currency
+}
+
+// CurrencyMetrics sums the ledger and the wallets of one wallet currency
+
+type CurrencyMetrics struct {
+	Currency    model.WalletCurrency `json:"currency"`
+	Credited    int64                `json:"credited"`     // Everything credited: recharges, prizes and rewards, or bonus grants
+	TicketSales int64                `json:"ticket_sales"` // Spent on tickets
+	Outstanding int64                `json:"outstanding"`  // Balance of all wallets
 }
 
 // TrendDataPoint represents a single data point in trend data
@@ -728,27 +976,20 @@ type StatisticsResponse struct {
 
 // GetStatistics returns comprehensive statistics
 func (s *AdminService) GetStatistics(query StatisticsQuery) (*StatisticsResponse, error) {
-	// Parse dates
-	var startDate, endDate time.Time
-	var err error
-	
-	if query.StartDate != "" {
-		startDate, err = time.Parse("2006-01-02", query.StartDate)
-		if err != nil {
-			startDate = time.Now().AddDate(0, -1, 0) // Default to 1 month ago
-		}
-	} else {
-		startDate = time.Now().AddDate(0, -1, 0)
+	s = s.reporting()
+
+	// Parse dates as business days: from the start of the first to the end
+	// of the last
+	today := biztime.DayStart(time.Now())
+	startDate, err := biztime.ParseDate(query.StartDate)
+	if err != nil {
+		startDate = today.AddDate(0, -1, 0) // Default to 1 month ago
 	}
-	
-	if query.EndDate != "" {
-		endDate, err = time.Parse("2006-01-02", query.EndDate)
-		if err != nil {
-			endDate = time.Now()
-		}
-	} else {
-		endDate = time.Now()
+	endDate, err := biztime.ParseDate(query.EndDate)
+	if err != nil {
+		endDate = today
 	}
+	endDate = endDate.AddDate(0, 0, 1).Add(-time.Microsecond)
 	
 	// Set default period
 	if query.Period == "" {
@@ -813,7 +1054,7 @@ func (s *AdminService) GetStatistics(query StatisticsQuery) (*StatisticsResponse
 func (s *AdminService) getCoreMetrics() (*CoreMetrics, error) {
 	metrics := &CoreMetrics{}
 	now := time.Now()
-	todayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	todayStart := biztime.DayStart(now)
 	weekStart := todayStart.AddDate(0, 0, -7)
 	monthStart := todayStart.AddDate(0, -1, 0)
 
@@ -866,13 +1107,13 @@ func (s *AdminService) getCoreMetrics() (*CoreMetrics, error) {
 		return nil, err
 	}
 
-	// Total sales amount
+	// Total sales amount, in points and bonus credits
 	var sales struct {
 		Total int64
 	}
 	if err := s.db.Model(&model.Transaction{}).
 		Select("COALESCE(SUM(ABS(amount)), 0) as total").
-		Where("type = ?", model.TransactionTypePurchase).
+		Where("type IN ?", ticketSpendTypes).
 		Scan(&sales).Error; err != nil {
 		return nil, err
 	}
@@ -890,23 +1131,87 @@ func (s *AdminService) getCoreMetrics() (*CoreMetrics, error) {
 	}
 	metrics.TotalPrizesPaid = prizes.Total
 
-	// Calculate return rate
-	if metrics.TotalSalesAmount > 0 {
-		metrics.ReturnRate = float64(metrics.TotalPrizesPaid) / float64(metrics.TotalSalesAmount) * 100
+	currencies, err := s.currencyMetrics()
+	if err != nil {
+		return nil, err
+	}
+	metrics.Currencies = currencies
+
+	// Calculate return rate over tickets paid in either currency
+	sold := int64(0)
+	for _, currency := range currencies {
+		sold += currency.TicketSales
+	}
+	if sold > 0 {
+		metrics.ReturnRate = float64(metrics.TotalPrizesPaid) / float64(sold) * 100
 	}
 
-	// Total exchange cost
+	// Total exchange cost (net of refunded reservations)
 	var exchangeCost struct {
 		Total int64
 	}
 	if err := s.db.Model(&model.Transaction{}).
-		Select("COALESCE(SUM(ABS(amount)), 0) as total").
+		Select("COALESCE(SUM(-amount), 0) as total").
 		Where("type = ?", model.TransactionTypeExchange).
 		Scan(&exchangeCost).Error; err != nil {
 		return nil, err
 	}
 	metrics.TotalExchangeCost = exchangeCost.Total
 
+	// Total money recharged, leaving out the simulated payments of sandboxes
+	var recharge struct {
+		Total int64
+	}
+	if err := s.db.Model(&model.PaymentOrder{}).
+		Select("COALESCE(SUM(amount), 0) as total").
+		Where("status = ? AND currency = ? AND payment_type <> ?", "paid", money.CNY, model.PaymentTypeSandbox).
+		Scan(&recharge).Error; err != nil {
+		return nil, err
+	}
+	metrics.TotalRecharge = money.New(recharge.Total, money.CNY)
+
+	return metrics, nil
+}
+
+// currencyMetrics sums the ledger and the wallet balances by currency
+func (s *AdminService) currencyMetrics() ([]CurrencyMetrics, error) {
+	var ledger []struct {
+		Currency    model.WalletCurrency
+		Credited    int64
+		TicketSales int64
+	}
+	if err := s.db.Model(&model.Transaction{}).
+		Select("currency, "+
+			"COALESCE(SUM(CASE WHEN amount > 0 THEN amount ELSE 0 END), 0) AS credited, "+
+			"COALESCE(SUM(CASE WHEN type IN (?, ?) THEN -amount ELSE 0 END), 0) AS ticket_sales",
+			model.TransactionTypePurchase, model.TransactionTypeBonusPurchase).
+		Group("currency").
+		Scan(&ledger).Error; err != nil {
+		return nil, err
+	}
+
+	var wallets struct {
+		Points int64
+		Bonus  int64
+	}
+	if err := s.db.Model(&model.Wallet{}).
+		Select("COALESCE(SUM(balance), 0) AS points, COALESCE(SUM(bonus_balance), 0) AS bonus").
+		Scan(&wallets).Error; err != nil {
+		return nil, err
+	}
+
+	metrics := []CurrencyMetrics{
+		{Currency: model.CurrencyPoints, Outstanding: wallets.Points},
+		{Currency: model.CurrencyBonus, Outstanding: wallets.Bonus},
+	}
+	for _, row := range ledger {
+		for i := range metrics {
+			if metrics[i].Currency == row.Currency {
+				metrics[i].Credited = row.Credited
+				metrics[i].TicketSales = row.TicketSales
+			}
+		}
+	}
 	return metrics, nil
 }
 
@@ -918,22 +1223,7 @@ func (s *AdminService) getUserTrend(startDate, endDate time.Time, period string)
 	}
 
 	// Generate date labels
-	current := startDate
-	for !current.After(endDate) {
-		var label string
-		switch period {
-		case "week":
-			label = current.Format("2006-W02")
-			current = current.AddDate(0, 0, 7)
-		case "month":
-			label = current.Format("2006-01")
-			current = current.AddDate(0, 1, 0)
-		default: // day
-			label = current.Format("01-02")
-			current = current.AddDate(0, 0, 1)
-		}
-		trend.Labels = append(trend.Labels, label)
-	}
+	trend.Labels = trendKeys(startDate, endDate, period)
 
 	// Query user counts by date
 	type DateCount struct {
@@ -959,20 +1249,7 @@ func (s *AdminService) getUserTrend(startDate, endDate time.Time, period string)
 	}
 
 	// Fill in data for each label
-	current = startDate
-	for range trend.Labels {
-		var key string
-		switch period {
-		case "week":
-			key = current.Format("2006-W02")
-			current = current.AddDate(0, 0, 7)
-		case "month":
-			key = current.Format("2006-01")
-			current = current.AddDate(0, 1, 0)
-		default:
-			key = current.Format("01-02")
-			current = current.AddDate(0, 0, 1)
-		}
+	for _, key := range trend.Labels {
 		trend.Data = append(trend.Data, resultMap[key])
 	}
 
@@ -988,24 +1265,9 @@ func (s *AdminService) getSalesTrend(startDate, endDate time.Time, period string
 	}
 
 	// Generate date labels
-	current := startDate
-	for !current.After(endDate) {
-		var label string
-		switch period {
-		case "week":
-			label = current.Format("2006-W02")
-			current = current.AddDate(0, 0, 7)
-		case "month":
-			label = current.Format("2006-01")
-			current = current.AddDate(0, 1, 0)
-		default:
-			label = current.Format("01-02")
-			current = current.AddDate(0, 0, 1)
-		}
-		trend.Labels = append(trend.Labels, label)
-	}
+	trend.Labels = trendKeys(startDate, endDate, period)
 
-	// Query sales by date
+	// Query sales by date. A purchase paid from both balances counts once.
 	type DateSales struct {
 		Date   string
 		Amount int64
@@ -1014,9 +1276,10 @@ func (s *AdminService) getSalesTrend(startDate, endDate time.Time, period string
 	var results []DateSales
 
 	dateFormat := s.getDateFormat(period)
-	if err := s.db.Model(&model.Transaction{}).
-		Select(dateFormat + " as date, COALESCE(SUM(ABS(amount)), 0) as amount, COUNT(*) as count").
-		Where("type = ? AND created_at >= ? AND created_at <= ?", model.TransactionTypePurchase, startDate, endDate).
+	if err := repository.TransactionsBetween(s.db, startDate, endDate).
+		Select(dateFormat + " as date, COALESCE(SUM(ABS(amount)), 0) as amount, " +
+			"COALESCE(SUM(CASE WHEN " + splitChargeSQL + " THEN 0 ELSE 1 END), 0) as count").
+		Where("type IN ?", ticketSpendTypes).
 		Group("date").
 		Order("date").
 		Scan(&results).Error; err != nil {
@@ -1032,20 +1295,7 @@ func (s *AdminService) getSalesTrend(startDate, endDate time.Time, period string
 	}
 
 	// Fill in data
-	current = startDate
-	for range trend.Labels {
-		var key string
-		switch period {
-		case "week":
-			key = current.Format("2006-W02")
-			current = current.AddDate(0, 0, 7)
-		case "month":
-			key = current.Format("2006-01")
-			current = current.AddDate(0, 1, 0)
-		default:
-			key = current.Format("01-02")
-			current = current.AddDate(0, 0, 1)
-		}
+	for _, key := range trend.Labels {
 		trend.Data = append(trend.Data, amountMap[key])
 		trend.Data2 = append(trend.Data2, countMap[key])
 	}
@@ -1061,22 +1311,7 @@ func (s *AdminService) getPrizesTrend(startDate, endDate time.Time, period strin
 	}
 
 	// Generate date labels
-	current := startDate
-	for !current.After(endDate) {
-		var label string
-		switch period {
-		case "week":
-			label = current.Format("2006-W02")
-			current = current.AddDate(0, 0, 7)
-		case "month":
-			label = current.Format("2006-01")
-			current = current.AddDate(0, 1, 0)
-		default:
-			label = current.Format("01-02")
-			current = current.AddDate(0, 0, 1)
-		}
-		trend.Labels = append(trend.Labels, label)
-	}
+	trend.Labels = trendKeys(startDate, endDate, period)
 
 	// Query prizes by date
 	type DatePrize struct {
@@ -1086,9 +1321,9 @@ func (s *AdminService) getPrizesTrend(startDate, endDate time.Time, period strin
 	var results []DatePrize
 
 	dateFormat := s.getDateFormat(period)
-	if err := s.db.Model(&model.Transaction{}).
+	if err := repository.TransactionsBetween(s.db, startDate, endDate).
 		Select(dateFormat + " as date, COALESCE(SUM(amount), 0) as amount").
-		Where("type = ? AND created_at >= ? AND created_at <= ?", model.TransactionTypeWin, startDate, endDate).
+		Where("type = ?", model.TransactionTypeWin).
 		Group("date").
 		Order("date").
 		Scan(&results).Error; err != nil {
@@ -1102,20 +1337,7 @@ func (s *AdminService) getPrizesTrend(startDate, endDate time.Time, period strin
 	}
 
 	// Fill in data
-	current = startDate
-	for range trend.Labels {
-		var key string
-		switch period {
-		case "week":
-			key = current.Format("2006-W02")
-			current = current.AddDate(0, 0, 7)
-		case "month":
-			key = current.Format("2006-01")
-			current = current.AddDate(0, 1, 0)
-		default:
-			key = current.Format("01-02")
-			current = current.AddDate(0, 0, 1)
-		}
+	for _, key := range trend.Labels {
 		trend.Data = append(trend.Data, resultMap[key])
 	}
 
@@ -1152,7 +1374,7 @@ func (s *AdminService) getLotteryTypeStats() ([]LotteryTypeStats, error) {
 		}
 		if err := s.db.Model(&model.Ticket{}).
 			Select("COALESCE(SUM(prize_amount), 0) as total").
-			Where("lottery_type_id = ? AND status IN (?, ?)", lt.ID, model.TicketStatusScratched, model.TicketStatusClaimed).
+			Where("lottery_type_id = ? AND status IN ?", lt.ID, model.RevealedTicketStatuses).
 			Scan(&prizes).Error; err != nil {
 			return nil, err
 		}
@@ -1180,7 +1402,7 @@ func (s *AdminService) getPrizeDistribution() ([]PrizeDistribution, error) {
 
 	if err := s.db.Model(&model.Ticket{}).
 		Select("prize_amount, COUNT(*) as count, SUM(prize_amount) as total").
-		Where("status IN (?, ?) AND prize_amount > 0", model.TicketStatusScratched, model.TicketStatusClaimed).
+		Where("status IN ? AND prize_amount > 0", model.RevealedTicketStatuses).
 		Group("prize_amount").
 		Order("prize_amount DESC").
 		Scan(&results).Error; err != nil {
@@ -1217,7 +1439,7 @@ func (s *AdminService) getPrizeDistribution() ([]PrizeDistribution, error) {
 func (s *AdminService) getUserBehaviorStats() (*UserBehaviorStats, error) {
 	stats := &UserBehaviorStats{}
 	now := time.Now()
-	todayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	todayStart := biztime.DayStart(now)
 	weekStart := todayStart.AddDate(0, 0, -7)
 	monthStart := todayStart.AddDate(0, -1, 0)
 
@@ -1260,13 +1482,13 @@ func (s *AdminService) getUserBehaviorStats() (*UserBehaviorStats, error) {
 		stats.AvgPurchaseCount = float64(totalTickets) / float64(totalUsers)
 	}
 
-	// Average purchase amount per user
+	// Average purchase amount per user, in points and bonus credits
 	var totalAmount struct {
 		Total int64
 	}
 	if err := s.db.Model(&model.Transaction{}).
 		Select("COALESCE(SUM(ABS(amount)), 0) as total").
-		Where("type = ?", model.TransactionTypePurchase).
+		Where("type IN ?", ticketSpendTypes).
 		Scan(&totalAmount).Error; err != nil {
 		return nil, err
 	}
@@ -1320,17 +1542,40 @@ func (s *AdminService) getUserBehaviorStats() (*UserBehaviorStats, error) {
 	return stats, nil
 }
 
-// getDateFormat returns the SQL date format based on period
+// getDateFormat returns the SQL date format based on period. Rows are
+// bucketed by their business day, like trendKeys.
 func (s *AdminService) getDateFormat(period string) string {
 	// PostgreSQL uses to_char for date formatting
+	createdAt := fmt.Sprintf("(created_at AT TIME ZONE '%s')", biztime.SQLZone())
+	switch period {
+	case "week":
+		return "to_char(" + createdAt + ", 'IYYY-\"W\"IW')"
+	case "month":
+		return "to_char(" + createdAt + ", 'YYYY-MM')"
+	default:
+		return "to_char(" + createdAt + ", 'MM-DD')"
+	}
+}
+
+// trendKeys returns the keys of the period buckets from startDate to
+// endDate, as getDateFormat formats them
+func trendKeys(startDate, endDate time.Time, period string) []string {
+	keys := []string{}
 	switch period {
 	case "week":
-		return "to_char(created_at, 'IYYY-\"W\"IW')"
+		for current := biztime.WeekStart(startDate); !current.After(endDate); current = current.AddDate(0, 0, 7) {
+			keys = append(keys, biztime.WeekKey(current))
+		}
 	case "month":
-		return "to_char(created_at, 'YYYY-MM')"
+		for current := biztime.MonthStart(startDate); !current.After(endDate); current = current.AddDate(0, 1, 0) {
+			keys = append(keys, biztime.MonthKey(current))
+		}
 	default:
-		return "to_char(created_at, 'MM-DD')"
+		for current := biztime.DayStart(startDate); !current.After(endDate); current = current.AddDate(0, 0, 1) {
+			keys = append(keys, current.Format("01-02"))
+		}
 	}
+	return keys
 }
 
 // ExportStatisticsCSV exports statistics as CSV
@@ -1357,6 +1602,15 @@ func (s *AdminService) ExportStatisticsCSV(query StatisticsQuery) ([]byte, error
 	csv += "总奖金支出," + formatInt64(stats.CoreMetrics.TotalPrizesPaid) + "\n"
 	csv += "返奖率," + formatFloat64(stats.CoreMetrics.ReturnRate) + "%\n"
 	csv += "总兑换消耗," + formatInt64(stats.CoreMetrics.TotalExchangeCost) + "\n"
+	csv += "总充值金额（元）," + stats.CoreMetrics.TotalRecharge.String() + "\n"
+	csv += "\n"
+
+	// Currency section
+	csv += "分币种统计\n"
+	csv += "币种,入账,购票消耗,钱包余额\n"
+	for _, currency := range stats.CoreMetrics.Currencies {
+		csv += string(currency.Currency) + "," + formatInt64(currency.Credited) + "," + formatInt64(currency.TicketSales) + "," + formatInt64(currency.Outstanding) + "\n"
+	}
 	csv += "\n"
 
 	// Lottery type stats section
@@ -1377,6 +1631,153 @@ func (s *AdminService) ExportStatisticsCSV(query StatisticsQuery) ([]byte, error
 	return []byte(csv), nil
 }
 
+// ==================== Sales Forecast ====================
+
+// forecastConfidenceZ is the z-score of the forecast confidence bands (~95%)
+const forecastConfidenceZ = 1.96
+
+// ForecastQuery represents query parameters for the sales forecast
+type ForecastQuery struct {
+	Window int `form:"window"` // Moving-average window in days (3-30, default 7)
+}
+
+// LotteryTypeForecast represents the sales forecast of a lottery type's active pool
+type LotteryTypeForecast struct {
+	LotteryTypeID          uint       `json:"lottery_type_id"`
+	Name                   string     `json:"name"`
+	Price                  int        `json:"price"`
+	PrizePoolID            uint       `json:"prize_pool_id"`
+	RemainingTickets       int        `json:"remaining_tickets"`
+	DailySales             []int64    `json:"daily_sales"` // Oldest first, one entry per window day
+	AvgDailySales          float64    `json:"avg_daily_sales"`
+	StdDevDailySales       float64    `json:"stddev_daily_sales"`
+	SellOutDate            *time.Time `json:"sell_out_date,omitempty"`
+	SellOutDateEarliest    *time.Time `json:"sell_out_date_earliest,omitempty"`
+	SellOutDateLatest      *time.Time `json:"sell_out_date_latest,omitempty"` // Nil when the low band has no sales
+	ProjectedWeeklyRevenue float64    `json:"projected_weekly_revenue"`
+	WeeklyRevenueLow       float64    `json:"weekly_revenue_low"`
+	WeeklyRevenueHigh      float64    `json:"weekly_revenue_high"`
+}
+
+// SalesForecastResponse represents the sales forecast for all active lottery types
+type SalesForecastResponse struct {
+	GeneratedAt  time.Time             `json:"generated_at"`
+	WindowDays   int                   `json:"window_days"`
+	LotteryTypes []LotteryTypeForecast `json:"lottery_types"`
+}
+
+// GetSalesForecast estimates sell-out dates and weekly revenue per lottery type
+// using a simple moving average of daily ticket sales, with confidence bands
+// derived from the day-to-day variation over the window
+func (s *AdminService) GetSalesForecast(query ForecastQuery) (*SalesForecastResponse, error) {
+	s = s.reporting()
+
+	window := query.Window
+	if window < 3 || window > 30 {
+		window = 7
+	}
+
+	now := time.Now()
+	todayStart := biztime.DayStart(now)
+	windowStart := todayStart.AddDate(0, 0, -(window - 1))
+
+	var lotteryTypes []model.LotteryType
+	if err := s.db.Where("status != ?", model.LotteryTypeStatusDisabled).Order("id ASC").Find(&lotteryTypes).Error; err != nil {
+		return nil, err
+	}
+
+	forecasts := make([]LotteryTypeForecast, 0, len(lotteryTypes))
+	for _, lt := range lotteryTypes {
+		var pool model.PrizePool
+		if err := s.db.Where("lottery_type_id = ? AND status = ?", lt.ID, model.PrizePoolStatusActive).First(&pool).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return nil, err
+		}
+
+		// Bucket recent purchases by day (done in Go to stay database agnostic)
+		var purchasedAt []time.Time
+		if err := s.db.Model(&model.Ticket{}).
+			Where("lottery_type_id = ? AND purchased_at >= ?", lt.ID, windowStart).
+			Pluck("purchased_at", &purchasedAt).Error; err != nil {
+			return nil, err
+		}
+		daily := make([]int64, window)
+		for _, t := range purchasedAt {
+			day := int(math.Round(biztime.DayStart(t).Sub(windowStart).Hours() / 24))
+			if day >= 0 && day < window {
+				daily[day]++
+			}
+		}
+
+		forecast := LotteryTypeForecast{
+			LotteryTypeID:    lt.ID,
+			Name:             lt.Name,
+			Price:            lt.Price,
+			PrizePoolID:      pool.ID,
+			RemainingTickets: pool.TotalTickets - pool.SoldTickets,
+			DailySales:       daily,
+		}
+		mean, stddev := meanStdDev(daily)
+		forecast.AvgDailySales = mean
+		forecast.StdDevDailySales = stddev
+
+		// Band on the mean daily rate, clamped at zero
+		margin := forecastConfidenceZ * stddev / math.Sqrt(float64(window))
+		low := math.Max(mean-margin, 0)
+		high := mean + margin
+
+		forecast.ProjectedWeeklyRevenue = mean * 7 * float64(lt.Price)
+		forecast.WeeklyRevenueLow = low * 7 * float64(lt.Price)
+		forecast.WeeklyRevenueHigh = high * 7 * float64(lt.Price)
+
+		forecast.SellOutDate = projectSellOut(now, forecast.RemainingTickets, mean)
+		forecast.SellOutDateEarliest = projectSellOut(now, forecast.RemainingTickets, high)
+		forecast.SellOutDateLatest = projectSellOut(now, forecast.RemainingTickets, low)
+
+		forecasts = append(forecasts, forecast)
+	}
+
+	return &SalesForecastResponse{
+		GeneratedAt:  now,
+		WindowDays:   window,
+		LotteryTypes: forecasts,
+	}, nil
+}
+
+// projectSellOut returns when remaining tickets sell out at the given daily rate,
+// or nil if the rate is zero
+func projectSellOut(now time.Time, remaining int, dailyRate float64) *time.Time {
+	if dailyRate <= 0 {
+		return nil
+	}
+	if remaining <= 0 {
+		return &now
+	}
+	days := float64(remaining) / dailyRate
+	t := now.Add(time.Duration(days * 24 * float64(time.Hour)))
+	return &t
+}
+
+// meanStdDev returns the mean and population standard deviation of values
+func meanStdDev(values []int64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += float64(v)
+	}
+	mean := sum / float64(len(values))
+	var variance float64
+	for _, v := range values {
+		d := float64(v) - mean
+		variance += d * d
+	}
+	return mean, math.Sqrt(variance / float64(len(values)))
+}
+
 // Helper functions
 
 func boolToString(b bool) string {
@@ -1386,15 +1787,6 @@ func boolToString(b bool) string {
 	return "false"
 }
 
-func containsMask(s string) bool {
-	for _, c := range s {
-		if c == '*' {
-			return true
-		}
-	}
-	return false
-}
-
 func formatInt64(n int64) string {
 	return fmt.Sprintf("%d", n)
 }
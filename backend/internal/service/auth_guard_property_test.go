@@ -0,0 +1,197 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+	"gorm.io/gorm"
+)
+
+func setupAuthGuardTestDB(t *testing.T) *gorm.DB {
+	db := setupTenantTestDB(t)
+	if err := db.AutoMigrate(&model.AuthIncident{}, &model.UserDevice{}, &model.LoginRecord{}, &model.Notification{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	return db
+}
+
+// Lockout: an account or IP is locked exactly when its failures within the
+// window reach the limit, and failures of one account do not lock another.
+func TestAuthGuardLockout(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("account is locked iff failures reach the limit", prop.ForAll(
+		func(maxFailures, failures int) bool {
+			db := setupAuthGuardTestDB(t)
+			guard := NewAuthGuardService(db, NewNotificationService(db), maxFailures, time.Hour, time.Hour).ForTenant(2)
+
+			// Spread the failures over distinct IPs so only the account can lock
+			for i := 0; i < failures; i++ {
+				client := ClientInfo{IP: fmt.Sprintf("10.0.0.%d", i), UserAgent: "test"}
+				if err := guard.RecordFailure(model.AuthIncidentLoginFailed, "victim", client, "bad password"); err != nil {
+					t.Logf("RecordFailure failed: %v", err)
+					return false
+				}
+			}
+
+			lockedUntil, err := guard.LockedUntil("victim", "192.168.0.1")
+			if err != nil || (lockedUntil != nil) != (failures >= maxFailures) {
+				t.Logf("%d/%d failures: locked until %v (err %v)", failures, maxFailures, lockedUntil, err)
+				return false
+			}
+			if other, _ := guard.LockedUntil("bystander", "192.168.0.1"); other != nil {
+				t.Logf("Unrelated account was locked")
+				return false
+			}
+			// Lockouts are kept per tenant
+			if other, _ := guard.ForTenant(1).LockedUntil("victim", ""); other != nil {
+				t.Logf("Lockout leaked into another tenant")
+				return false
+			}
+			return true
+		},
+		gen.IntRange(1, 5),
+		gen.IntRange(0, 8),
+	))
+
+	properties.TestingRun(t)
+}
+
+func TestAuthGuardIPLockoutExpires(t *testing.T) {
+	db := setupAuthGuardTestDB(t)
+	guard := NewAuthGuardService(db, NewNotificationService(db), 3, time.Hour, 50*time.Millisecond)
+	client := ClientInfo{IP: "203.0.113.7", UserAgent: "curl"}
+
+	for i := 0; i < 3; i++ {
+		if err := guard.RecordFailure(model.AuthIncidentOAuthAnomaly, "", client, "state mismatch"); err != nil {
+			t.Fatalf("RecordFailure failed: %v", err)
+		}
+	}
+	if lockedUntil, _ := guard.LockedUntil("", client.IP); lockedUntil == nil {
+		t.Fatalf("Expected IP to be locked")
+	}
+	// An IP lockout applies to any account tried from it
+	if lockedUntil, _ := guard.LockedUntil("someone", client.IP); lockedUntil == nil {
+		t.Errorf("Expected IP lockout to apply to other accounts")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if lockedUntil, _ := guard.LockedUntil("", client.IP); lockedUntil != nil {
+		t.Fatalf("Expected lockout to expire, locked until %v", lockedUntil)
+	}
+
+	// Failures before the last lockout do not count again
+	if err := guard.RecordFailure(model.AuthIncidentOAuthAnomaly, "", client, "state mismatch"); err != nil {
+		t.Fatalf("RecordFailure failed: %v", err)
+	}
+	if lockedUntil, _ := guard.LockedUntil("", client.IP); lockedUntil != nil {
+		t.Errorf("A single failure after a lockout must not lock again")
+	}
+
+	incidents, err := guard.GetIncidents(AuthIncidentQuery{Kind: string(model.AuthIncidentLockout)})
+	if err != nil || incidents.Total != 1 {
+		t.Errorf("Expected one lockout incident, got %+v (err %v)", incidents, err)
+	}
+}
+
+func TestAuthGuardNewDeviceNotification(t *testing.T) {
+	db := setupAuthGuardTestDB(t)
+	notifications := NewNotificationService(db)
+	guard := NewAuthGuardService(db, notifications, 5, time.Hour, time.Hour).ForTenant(2)
+
+	user := model.User{TenantID: 2, LinuxdoID: "u1", Username: "u1"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	logins := []struct {
+		userAgent string
+		notified  int64
+	}{
+		{"Firefox", 0}, // first device is trusted
+		{"Firefox", 0},
+		{"Safari", 1},
+		{"Safari", 1},
+		{"Chrome", 2},
+	}
+	for i, login := range logins {
+		if err := guard.RecordLogin(&user, ClientInfo{IP: "198.51.100.1", UserAgent: login.userAgent}, model.LoginMethodOAuth); err != nil {
+			t.Fatalf("Login %d: RecordLogin failed: %v", i, err)
+		}
+		list, err := notifications.ForTenant(2).GetNotifications(user.ID, 1, 20)
+		if err != nil || list.Total != login.notified || list.Unread != login.notified {
+			t.Fatalf("Login %d: expected %d notifications, got %+v (err %v)", i, login.notified, list, err)
+		}
+	}
+
+	incidents, err := guard.GetIncidents(AuthIncidentQuery{UserID: user.ID})
+	if err != nil || incidents.Total != 2 || incidents.Incidents[0].Kind != model.AuthIncidentNewDevice {
+		t.Errorf("Expected two new device incidents, got %+v (err %v)", incidents, err)
+	}
+
+	list, _ := notifications.ForTenant(2).GetNotifications(user.ID, 1, 20)
+	if err := notifications.ForTenant(2).MarkRead(user.ID, list.Notifications[0].ID); err != nil {
+		t.Fatalf("MarkRead failed: %v", err)
+	}
+	if err := notifications.ForTenant(1).MarkRead(user.ID, list.Notifications[1].ID); err != ErrNotificationNotFound {
+		t.Errorf("Expected ErrNotificationNotFound across tenants, got %v", err)
+	}
+	if list, _ = notifications.ForTenant(2).GetNotifications(user.ID, 1, 20); list.Unread != 1 {
+		t.Errorf("Expected 1 unread notification, got %d", list.Unread)
+	}
+}
+
+// Login history: every login is listed newest first with its method, IP and
+// device, new devices are flagged, and users of other tenants are not found.
+func TestAuthGuardLoginHistory(t *testing.T) {
+	db := setupAuthGuardTestDB(t)
+	guard := NewAuthGuardService(db, NewNotificationService(db), 5, time.Hour, time.Hour).ForTenant(2)
+
+	user := model.User{TenantID: 2, LinuxdoID: "u1", Username: "u1"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	logins := []struct {
+		client ClientInfo
+		method string
+	}{
+		{ClientInfo{IP: "198.51.100.1", UserAgent: "Firefox"}, model.LoginMethodOAuth},
+		{ClientInfo{IP: "198.51.100.2", UserAgent: "Firefox"}, model.LoginMethodOAuth},
+		{ClientInfo{IP: "203.0.113.9", UserAgent: "Safari"}, model.LoginMethodDev},
+	}
+	for i, login := range logins {
+		if err := guard.RecordLogin(&user, login.client, login.method); err != nil {
+			t.Fatalf("Login %d: RecordLogin failed: %v", i, err)
+		}
+	}
+
+	history, err := guard.GetLoginHistory(user.ID, 1, 2)
+	if err != nil {
+		t.Fatalf("GetLoginHistory failed: %v", err)
+	}
+	if history.Total != 3 || history.TotalPages != 2 || len(history.Logins) != 2 {
+		t.Fatalf("Unexpected page %+v", history)
+	}
+	latest := history.Logins[0]
+	if latest.IP != "203.0.113.9" || latest.UserAgent != "Safari" || latest.Method != model.LoginMethodDev || !latest.NewDevice {
+		t.Errorf("Unexpected latest login %+v", latest)
+	}
+	if history.Logins[1].NewDevice {
+		t.Errorf("Expected a known device, got %+v", history.Logins[1])
+	}
+
+	if _, err := guard.ForTenant(1).GetLoginHistory(user.ID, 1, 20); err != ErrUserNotFound {
+		t.Errorf("Expected ErrUserNotFound across tenants, got %v", err)
+	}
+}
@@ -0,0 +1,366 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// authFailureKinds are the incidents that count towards a lockout
+var authFailureKinds = []model.AuthIncidentKind{model.AuthIncidentLoginFailed, model.AuthIncidentOAuthAnomaly}
+
+// ClientInfo identifies the client an authentication attempt came from
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// AuthGuardService tracks failed and suspicious authentication attempts.
+// An account or IP with too many failures within the window is locked out
+// for a while, and users are notified when they log in from a new device.
+type AuthGuardService struct {
+	db                  *gorm.DB
+	notificationService *NotificationService
+	maxFailures         int64
+	window              time.Duration
+	lockout             time.Duration
+}
+
+// NewAuthGuardService creates a new auth guard service. maxFailures failures
+// of an account or IP within window lock it out for the lockout duration.
+func NewAuthGuardService(db *gorm.DB, notificationService *NotificationService, maxFailures int, window, lockout time.Duration) *AuthGuardService {
+	if maxFailures < 1 {
+		maxFailures = 5
+	}
+	if window <= 0 {
+		window = 15 * time.Minute
+	}
+	if lockout <= 0 {
+		lockout = 15 * time.Minute
+	}
+	return &AuthGuardService{
+		db:                  db,
+		notificationService: notificationService,
+		maxFailures:         int64(maxFailures),
+		window:              window,
+		lockout:             lockout,
+	}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *AuthGuardService) ForTenant(tenantID uint) *AuthGuardService {
+	return &AuthGuardService{
+		db:                  repository.ScopeTenant(s.db, tenantID),
+		notificationService: s.notificationService.ForTenant(tenantID),
+		maxFailures:         s.maxFailures,
+		window:              s.window,
+		lockout:             s.lockout,
+	}
+}
+
+// LockedUntil returns when the lockout of an account or IP ends, or nil if
+// neither is locked. An empty subject only checks the IP.
+func (s *AuthGuardService) LockedUntil(subject, ip string) (*time.Time, error) {
+	var lock model.AuthIncident
+	err := s.db.Where("kind = ? AND locked_until > ?", model.AuthIncidentLockout, time.Now()).
+		Where("(subject <> '' AND subject = ?) OR (ip <> '' AND ip = ?)", truncate(subject, 64), ip).
+		Order("locked_until DESC").
+		First(&lock).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return lock.LockedUntil, nil
+}
+
+// RecordFailure records a failed authentication attempt and locks out the
+// account and IP once they reach the failure limit
+func (s *AuthGuardService) RecordFailure(kind model.AuthIncidentKind, subject string, client ClientInfo, reason string) error {
+	incident := model.AuthIncident{
+		Kind:      kind,
+		Subject:   truncate(subject, 64),
+		IP:        client.IP,
+		UserAgent: truncate(client.UserAgent, 256),
+		Reason:    truncate(reason, 256),
+	}
+	if err := s.db.Create(&incident).Error; err != nil {
+		return err
+	}
+
+	if incident.Subject != "" {
+		if err := s.lockIfExceeded("subject", incident.Subject, model.AuthIncident{Subject: incident.Subject}); err != nil {
+			return err
+		}
+	}
+	if incident.IP != "" {
+		if err := s.lockIfExceeded("ip", incident.IP, model.AuthIncident{IP: incident.IP}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordAdminDenial records a refused request to the admin API. Denials are
+// audited only and do not count towards a lockout.
+func (s *AuthGuardService) RecordAdminDenial(userID uint, subject string, client ClientInfo, reason string) error {
+	incident := model.AuthIncident{
+		Kind:      model.AuthIncidentAdminDenied,
+		Subject:   truncate(subject, 64),
+		IP:        client.IP,
+		UserAgent: truncate(client.UserAgent, 256),
+		Reason:    truncate(reason, 256),
+	}
+	if userID > 0 {
+		incident.UserID = &userID
+	}
+	return s.db.Create(&incident).Error
+}
+
+// lockIfExceeded counts the failures of an account or IP since the window
+// started or its last lockout, whichever is later, and records a lockout
+// when they reach the limit
+func (s *AuthGuardService) lockIfExceeded(column, value string, lock model.AuthIncident) error {
+	now := time.Now()
+	since := now.Add(-s.window)
+
+	var last model.AuthIncident
+	err := s.db.Where("kind = ? AND "+column+" = ?", model.AuthIncidentLockout, value).
+		Order("id DESC").First(&last).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if err == nil && last.CreatedAt.After(since) {
+		since = last.CreatedAt
+	}
+
+	var failures int64
+	if err := s.db.Model(&model.AuthIncident{}).
+		Where("kind IN ? AND "+column+" = ? AND created_at > ?", authFailureKinds, value, since).
+		Count(&failures).Error; err != nil {
+		return err
+	}
+	if failures < s.maxFailures {
+		return nil
+	}
+
+	lockedUntil := now.Add(s.lockout)
+	lock.Kind = model.AuthIncidentLockout
+	lock.Reason = fmt.Sprintf("%d failures within %s", failures, s.window)
+	lock.LockedUntil = &lockedUntil
+	return s.db.Create(&lock).Error
+}
+
+// RecordLogin records a successful login in the login history and remembers
+// its device. The first login from a new device, other than a user's very
+// first device, is recorded as an incident and the user is notified.
+func (s *AuthGuardService) RecordLogin(user *model.User, client ClientInfo, method string) error {
+	sum := sha256.Sum256([]byte(client.UserAgent))
+	fingerprint := hex.EncodeToString(sum[:])
+	now := time.Now()
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		newDevice, err := s.recordDevice(tx, user, client, fingerprint, now)
+		if err != nil {
+			return err
+		}
+		record := model.LoginRecord{
+			UserID:    user.ID,
+			Method:    method,
+			IP:        client.IP,
+			UserAgent: truncate(client.UserAgent, 256),
+			NewDevice: newDevice,
+		}
+		return tx.Create(&record).Error
+	})
+}
+
+// recordDevice remembers the device of a login and reports whether it is a
+// new device other than the user's first, which is recorded as an incident
+// and notified
+func (s *AuthGuardService) recordDevice(tx *gorm.DB, user *model.User, client ClientInfo, fingerprint string, now time.Time) (bool, error) {
+	var device model.UserDevice
+	err := tx.Where("user_id = ? AND fingerprint = ?", user.ID, fingerprint).First(&device).Error
+	if err == nil {
+		return false, tx.Model(&device).Updates(map[string]interface{}{"last_ip": client.IP, "last_seen_at": now}).Error
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
+
+	var known int64
+	if err := tx.Model(&model.UserDevice{}).Where("user_id = ?", user.ID).Count(&known).Error; err != nil {
+		return false, err
+	}
+
+	device = model.UserDevice{
+		UserID:      user.ID,
+		Fingerprint: fingerprint,
+		UserAgent:   truncate(client.UserAgent, 256),
+		LastIP:      client.IP,
+		LastSeenAt:  now,
+	}
+	if err := tx.Create(&device).Error; err != nil {
+		return false, err
+	}
+	if known == 0 {
+		return false, nil
+	}
+
+	userID := user.ID
+	incident := model.AuthIncident{
+		Kind:      model.AuthIncidentNewDevice,
+		Subject:   truncate(user.LinuxdoID, 64),
+		UserID:    &userID,
+		IP:        client.IP,
+		UserAgent: device.UserAgent,
+		Reason:    "login from a new device",
+	}
+	if err := tx.Create(&incident).Error; err != nil {
+		return false, err
+	}
+
+	content := fmt.Sprintf("您的账号于 %s 在新设备上登录（IP：%s，设备：%s）。如非本人操作，请及时联系管理员。",
+		now.Format("2006-01-02 15:04"), client.IP, device.UserAgent)
+	if _, err := s.notificationService.notify(tx, user.ID, model.NotificationTypeNewDeviceLogin, "新设备登录提醒", content); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// LoginHistoryResponse represents a page of a user's logins, newest first
+type LoginHistoryResponse struct {
+	Logins     []model.LoginRecord `json:"logins"`
+	Total      int64               `json:"total"`
+	Page       int                 `json:"page"`
+	Limit      int                 `json:"limit"`
+	TotalPages int                 `json:"total_pages"`
+}
+
+// GetLoginHistory returns the logins of a user of the tenant, newest first
+func (s *AuthGuardService) GetLoginHistory(userID uint, page, limit int) (*LoginHistoryResponse, error) {
+	var user model.User
+	if err := s.db.Select("id").First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	var total int64
+	if err := s.db.Model(&model.LoginRecord{}).Where("user_id = ?", userID).Count(&total).Error; err != nil {
+		return nil, err
+	}
+	var logins []model.LoginRecord
+	if err := s.db.Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&logins).Error; err != nil {
+		return nil, err
+	}
+
+	totalPages := int(total) / limit
+	if int(total)%limit > 0 {
+		totalPages++
+	}
+	return &LoginHistoryResponse{
+		Logins:     logins,
+		Total:      total,
+		Page:       page,
+		Limit:      limit,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// AuthIncidentQuery represents query parameters for auth incidents
+type AuthIncidentQuery struct {
+	Kind    string `form:"kind"`
+	Subject string `form:"subject"`
+	IP      string `form:"ip"`
+	UserID  uint   `form:"user_id"`
+	Page    int    `form:"page"`
+	Limit   int    `form:"limit"`
+}
+
+// AuthIncidentListResponse represents a paginated list of auth incidents
+type AuthIncidentListResponse struct {
+	Incidents  []model.AuthIncident `json:"incidents"`
+	Total      int64                `json:"total"`
+	Page       int                  `json:"page"`
+	Limit      int                  `json:"limit"`
+	TotalPages int                  `json:"total_pages"`
+}
+
+// GetIncidents returns recent auth incidents, newest first
+func (s *AuthGuardService) GetIncidents(query AuthIncidentQuery) (*AuthIncidentListResponse, error) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit < 1 || query.Limit > 100 {
+		query.Limit = 20
+	}
+
+	dbQuery := s.db.Model(&model.AuthIncident{})
+	if query.Kind != "" {
+		dbQuery = dbQuery.Where("kind = ?", query.Kind)
+	}
+	if query.Subject != "" {
+		dbQuery = dbQuery.Where("subject = ?", query.Subject)
+	}
+	if query.IP != "" {
+		dbQuery = dbQuery.Where("ip = ?", query.IP)
+	}
+	if query.UserID > 0 {
+		dbQuery = dbQuery.Where("user_id = ?", query.UserID)
+	}
+
+	var total int64
+	if err := dbQuery.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	var incidents []model.AuthIncident
+	if err := dbQuery.Order("created_at DESC, id DESC").
+		Offset((query.Page - 1) * query.Limit).
+		Limit(query.Limit).
+		Find(&incidents).Error; err != nil {
+		return nil, err
+	}
+
+	totalPages := int(total) / query.Limit
+	if int(total)%query.Limit > 0 {
+		totalPages++
+	}
+
+	return &AuthIncidentListResponse{
+		Incidents:  incidents,
+		Total:      total,
+		Page:       query.Page,
+		Limit:      query.Limit,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// truncate shortens s to at most n runes
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
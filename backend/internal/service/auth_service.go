@@ -6,6 +6,7 @@ import (
 
 	"scratch-lottery/internal/cache"
 	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
 	"scratch-lottery/pkg/auth"
 
 	"gorm.io/gorm"
@@ -47,6 +48,7 @@ type AuthService struct {
 	db            *gorm.DB
 	jwtManager    *auth.JWTManager
 	blacklist     *cache.TokenBlacklist
+	sessions      *SessionService
 	isDevMode     bool
 }
 
@@ -60,6 +62,26 @@ func NewAuthService(db *gorm.DB, jwtManager *auth.JWTManager, blacklist *cache.T
 	}
 }
 
+// UseSessions binds the tokens issued at login to sessions tracked by
+// sessions, enforcing its limits
+func (s *AuthService) UseSessions(sessions *SessionService) {
+	s.sessions = sessions
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *AuthService) ForTenant(tenantID uint) *AuthService {
+	scoped := &AuthService{
+		db:         repository.ScopeTenant(s.db, tenantID),
+		jwtManager: s.jwtManager,
+		blacklist:  s.blacklist,
+		isDevMode:  s.isDevMode,
+	}
+	if s.sessions != nil {
+		scoped.sessions = s.sessions.ForTenant(tenantID)
+	}
+	return scoped
+}
+
 // GetDevUsers returns the list of available dev users
 func (s *AuthService) GetDevUsers() []DevUser {
 	if !s.isDevMode {
@@ -68,8 +90,8 @@ func (s *AuthService) GetDevUsers() []DevUser {
 	return DevUsers
 }
 
-// DevLogin handles development mode login
-func (s *AuthService) DevLogin(devUserID string) (*AuthResponse, error) {
+// DevLogin handles development mode login from client
+func (s *AuthService) DevLogin(devUserID string, client ClientInfo) (*AuthResponse, error) {
 	if !s.isDevMode {
 		return nil, ErrDevModeDisabled
 	}
@@ -93,22 +115,7 @@ func (s *AuthService) DevLogin(devUserID string) (*AuthResponse, error) {
 	}
 
 	// Generate tokens
-	accessToken, refreshToken, err := s.jwtManager.GenerateTokenPair(
-		user.ID,
-		user.LinuxdoID,
-		user.Username,
-		user.Role,
-	)
-	if err != nil {
-		return nil, err
-	}
-
-	return &AuthResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		ExpiresIn:    s.jwtManager.GetAccessExpiry(),
-		User:         user,
-	}, nil
+	return issueTokens(s.jwtManager, s.sessions, user, client)
 }
 
 // RefreshToken refreshes the access token using a refresh token
@@ -135,6 +142,14 @@ func (s *AuthService) RefreshToken(refreshTokenStr string) (*AuthResponse, error
 		return nil, ErrUserNotFound
 	}
 
+	// Keep the session alive, unless it was evicted or ended. Tokens issued
+	// before sessions were tracked have none and are refreshed without one.
+	if claims.SessionID != "" && s.sessions != nil {
+		if err := s.sessions.Touch(claims.SessionID); err != nil {
+			return nil, err
+		}
+	}
+
 	// Blacklist the old refresh token
 	remainingTime := time.Until(claims.ExpiresAt.Time)
 	if remainingTime > 0 {
@@ -142,7 +157,9 @@ func (s *AuthService) RefreshToken(refreshTokenStr string) (*AuthResponse, error
 	}
 
 	// Generate new tokens
-	accessToken, newRefreshToken, err := s.jwtManager.GenerateTokenPair(
+	accessToken, newRefreshToken, err := s.jwtManager.GenerateSessionTokenPair(
+		claims.SessionID,
+		user.TenantID,
 		user.ID,
 		user.LinuxdoID,
 		user.Username,
@@ -160,8 +177,10 @@ func (s *AuthService) RefreshToken(refreshTokenStr string) (*AuthResponse, error
 	}, nil
 }
 
-// Logout invalidates the tokens
+// Logout invalidates the tokens and ends their session
 func (s *AuthService) Logout(accessToken, refreshToken string) error {
+	sessionID := ""
+
 	// Blacklist access token
 	if accessToken != "" {
 		claims, err := s.jwtManager.ValidateToken(accessToken)
@@ -170,6 +189,7 @@ func (s *AuthService) Logout(accessToken, refreshToken string) error {
 			if remainingTime > 0 {
 				_ = s.blacklist.Add(accessToken, remainingTime)
 			}
+			sessionID = claims.SessionID
 		}
 	}
 
@@ -181,9 +201,15 @@ func (s *AuthService) Logout(accessToken, refreshToken string) error {
 			if remainingTime > 0 {
 				_ = s.blacklist.Add(refreshToken, remainingTime)
 			}
+			if sessionID == "" {
+				sessionID = claims.SessionID
+			}
 		}
 	}
 
+	if sessionID != "" && s.sessions != nil {
+		return s.sessions.End(sessionID)
+	}
 	return nil
 }
 
@@ -205,6 +231,11 @@ func (s *AuthService) ValidateAccessToken(tokenStr string) (*auth.Claims, error)
 		return nil, auth.ErrInvalidToken
 	}
 
+	// Refuse tokens of an evicted or ended session
+	if claims.SessionID != "" && s.sessions != nil && s.sessions.IsRevoked(claims.SessionID) {
+		return nil, auth.ErrTokenBlacklisted
+	}
+
 	return claims, nil
 }
 
@@ -245,6 +276,7 @@ func (s *AuthService) findOrCreateUser(linuxdoID, username, avatar, role string)
 
 		// Create initial transaction
 		tx := model.Transaction{
+			TenantID:    wallet.TenantID,
 			WalletID:    wallet.ID,
 			Type:        model.TransactionTypeInitial,
 			Amount:      50,
@@ -261,8 +293,15 @@ func (s *AuthService) findOrCreateUser(linuxdoID, username, avatar, role string)
 	} else if err != nil {
 		return nil, err
 	} else {
+		// A merged duplicate signs in as the account it was merged into
+		userID := user.ID
+		if user.MergedIntoID != nil {
+			userID = *user.MergedIntoID
+			user = model.User{}
+		}
+
 		// Load wallet for existing user
-		if err := s.db.Preload("Wallet").First(&user, user.ID).Error; err != nil {
+		if err := s.db.Preload("Wallet").First(&user, userID).Error; err != nil {
 			return nil, err
 		}
 	}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"testing"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+)
+
+// Badge counters: once created, a user's counters follow ticket purchases and
+// scratches, notifications and their reads without recounting, and a
+// reconciliation corrects counts changed behind the services' back.
+func TestBadgeCountersFollowEvents(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("counters match the source tables", prop.ForAll(
+		func(ops []int) bool {
+			db := setupTenantTestDB(t)
+			if err := db.AutoMigrate(&model.Notification{}, &model.PaymentOrder{}); err != nil {
+				t.Logf("Failed to migrate: %v", err)
+				return false
+			}
+			lotteryService := NewLotteryService(db, testEncryptionKey)
+			walletService := NewWalletService(db)
+			scratchService := NewScratchService(db, lotteryService, walletService, nil, nil)
+			notifications := NewNotificationService(db)
+			badges := NewBadgeService(db)
+
+			user := model.User{LinuxdoID: "badge_user", Username: "Badge", Role: "user"}
+			db.Create(&user)
+			db.Create(&model.Wallet{UserID: user.ID})
+			lotteryType := model.LotteryType{Name: "Badge", Price: 10, GameType: model.GameTypeNumberMatch, Status: model.LotteryTypeStatusAvailable}
+			db.Create(&lotteryType)
+			db.Create(&model.PrizePool{LotteryTypeID: lotteryType.ID, TotalTickets: 100, Status: model.PrizePoolStatusActive})
+
+			// Events before the counter exists are picked up when it is first read
+			if _, err := lotteryService.GenerateTicket(user.ID, lotteryType.ID); err != nil {
+				t.Logf("GenerateTicket failed: %v", err)
+				return false
+			}
+			notifications.Notify(user.ID, "test", "Hello", "First")
+			counts, err := badges.GetBadges(user.ID)
+			if err != nil || counts.UnscratchedTickets != 1 || counts.UnreadNotifications != 1 {
+				t.Logf("Expected initial counts 1/1, got %+v (err %v)", counts, err)
+				return false
+			}
+
+			for _, op := range ops {
+				var err error
+				switch op {
+				case 0:
+					_, err = lotteryService.GenerateTicket(user.ID, lotteryType.ID)
+				case 1:
+					var ticket model.Ticket
+					if db.Where("user_id = ? AND status = ?", user.ID, model.TicketStatusUnscratched).First(&ticket).Error == nil {
+						_, err = scratchService.ScratchTicket(user.ID, ticket.ID, "")
+					}
+				case 2:
+					_, err = notifications.Notify(user.ID, "test", "Hello", "Again")
+				case 3:
+					var notification model.Notification
+					if db.Where("user_id = ?", user.ID).Order("id DESC").First(&notification).Error == nil {
+						err = notifications.MarkRead(user.ID, notification.ID)
+					}
+				case 4:
+					err = notifications.MarkAllRead(user.ID)
+				}
+				if err != nil {
+					t.Logf("Op %d failed: %v", op, err)
+					return false
+				}
+			}
+
+			exact, err := countBadges(db, []uint{user.ID})
+			if err != nil {
+				t.Logf("countBadges failed: %v", err)
+				return false
+			}
+			counts, _ = badges.GetBadges(user.ID)
+			if *counts != exact[user.ID] {
+				t.Logf("Expected counters %+v, got %+v", exact[user.ID], *counts)
+				return false
+			}
+
+			// Nothing to correct while counters follow the events
+			if corrected, err := badges.Reconcile(); err != nil || corrected != 0 {
+				t.Logf("Expected no corrections, got %d (err %v)", corrected, err)
+				return false
+			}
+
+			// An order written directly drifts the counter until reconciled
+			db.Create(&model.PaymentOrder{UserID: user.ID, OrderNo: "badge-order", Status: "pending"})
+			if corrected, err := badges.Reconcile(); err != nil || corrected != 1 {
+				t.Logf("Expected one correction, got %d (err %v)", corrected, err)
+				return false
+			}
+			counts, _ = badges.GetBadges(user.ID)
+			return counts.PendingOrders == 1
+		},
+		gen.SliceOfN(15, gen.IntRange(0, 4)),
+	))
+
+	properties.TestingRun(t)
+}
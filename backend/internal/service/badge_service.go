@@ -0,0 +1,203 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+	"scratch-lottery/pkg/logger"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Badge counter columns
+const (
+	badgeUnscratchedTickets  = "unscratched_tickets"
+	badgeUnreadNotifications = "unread_notifications"
+	badgePendingOrders       = "pending_orders"
+)
+
+// badgeReconcileBatch is the number of counters reconciled per query
+const badgeReconcileBatch = 200
+
+// unscratchedTicketStatuses are the statuses of tickets a user can still scratch
+var unscratchedTicketStatuses = []model.TicketStatus{model.TicketStatusUnscratched, model.TicketStatusScratching}
+
+// BadgeService serves the badge counts shown on every page. Counts are read
+// from per-user counters that are adjusted as tickets, notifications and
+// orders change, and reconciled against the source tables in the background.
+type BadgeService struct {
+	db *gorm.DB
+}
+
+// NewBadgeService creates a new badge service
+func NewBadgeService(db *gorm.DB) *BadgeService {
+	return &BadgeService{db: db}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *BadgeService) ForTenant(tenantID uint) *BadgeService {
+	return &BadgeService{db: repository.ScopeTenant(s.db, tenantID)}
+}
+
+// BadgeCounts represents the badge counts of a user
+type BadgeCounts struct {
+	UnscratchedTickets  int `json:"unscratched_tickets"`
+	UnreadNotifications int `json:"unread_notifications"`
+	PendingOrders       int `json:"pending_orders"`
+}
+
+// GetBadges returns the badge counts of a user. The counter of a user is
+// created from exact counts the first time it is read.
+func (s *BadgeService) GetBadges(userID uint) (*BadgeCounts, error) {
+	var counter model.UserBadgeCounter
+	err := s.db.Where("user_id = ?", userID).First(&counter).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		counts, err := countBadges(s.db, []uint{userID})
+		if err != nil {
+			return nil, err
+		}
+		exact := counts[userID]
+		counter = model.UserBadgeCounter{
+			UserID:              userID,
+			UnscratchedTickets:  exact.UnscratchedTickets,
+			UnreadNotifications: exact.UnreadNotifications,
+			PendingOrders:       exact.PendingOrders,
+			ReconciledAt:        time.Now(),
+		}
+		if err := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&counter).Error; err != nil {
+			return nil, err
+		}
+		return &exact, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// A counter can briefly drift below zero until the next reconciliation
+	return &BadgeCounts{
+		UnscratchedTickets:  max(counter.UnscratchedTickets, 0),
+		UnreadNotifications: max(counter.UnreadNotifications, 0),
+		PendingOrders:       max(counter.PendingOrders, 0),
+	}, nil
+}
+
+// Reconcile recounts every badge counter from the source tables and corrects
+// the ones that drifted. Returns the number of corrected counters.
+func (s *BadgeService) Reconcile() (int, error) {
+	corrected := 0
+	var counters []model.UserBadgeCounter
+	result := s.db.FindInBatches(&counters, badgeReconcileBatch, func(tx *gorm.DB, batch int) error {
+		userIDs := make([]uint, len(counters))
+		for i, counter := range counters {
+			userIDs[i] = counter.UserID
+		}
+		counts, err := countBadges(s.db, userIDs)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, counter := range counters {
+			exact := counts[counter.UserID]
+			updates := map[string]interface{}{"reconciled_at": now}
+			if counter.UnscratchedTickets != exact.UnscratchedTickets ||
+				counter.UnreadNotifications != exact.UnreadNotifications ||
+				counter.PendingOrders != exact.PendingOrders {
+				updates[badgeUnscratchedTickets] = exact.UnscratchedTickets
+				updates[badgeUnreadNotifications] = exact.UnreadNotifications
+				updates[badgePendingOrders] = exact.PendingOrders
+				corrected++
+			}
+			if err := s.db.Model(&model.UserBadgeCounter{}).Where("id = ?", counter.ID).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return corrected, result.Error
+}
+
+// Start reconciles the badge counters every interval until the returned stop
+// func is called
+func (s *BadgeService) Start(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			corrected, err := s.Reconcile()
+			if err != nil {
+				logger.Default().Warn("Reconciling badge counters failed: %v", err)
+			} else if corrected > 0 {
+				logger.Default().Info("Corrected %d drifted badge counters", corrected)
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// adjustBadge adds delta to a badge counter of a user within the caller's
+// transaction. Users without a counter are skipped; their counter is created
+// from exact counts when first read.
+func adjustBadge(tx *gorm.DB, userID uint, column string, delta int) error {
+	if delta == 0 {
+		return nil
+	}
+	return tx.Model(&model.UserBadgeCounter{}).
+		Where("user_id = ?", userID).
+		Update(column, gorm.Expr(column+" + ?", delta)).Error
+}
+
+// countBadges counts the badges of users from the source tables
+func countBadges(db *gorm.DB, userIDs []uint) (map[uint]BadgeCounts, error) {
+	type userCount struct {
+		UserID uint
+		Total  int
+	}
+	countBy := func(query *gorm.DB) ([]userCount, error) {
+		var rows []userCount
+		err := query.Select("user_id, COUNT(*) as total").Where("user_id IN ?", userIDs).Group("user_id").Scan(&rows).Error
+		return rows, err
+	}
+
+	counts := make(map[uint]BadgeCounts, len(userIDs))
+	tickets, err := countBy(db.Model(&model.Ticket{}).Where("status IN ?", unscratchedTicketStatuses))
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range tickets {
+		c := counts[row.UserID]
+		c.UnscratchedTickets = row.Total
+		counts[row.UserID] = c
+	}
+
+	notifications, err := countBy(db.Model(&model.Notification{}).Where("read_at IS NULL"))
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range notifications {
+		c := counts[row.UserID]
+		c.UnreadNotifications = row.Total
+		counts[row.UserID] = c
+	}
+
+	orders, err := countBy(db.Model(&model.PaymentOrder{}).Where("status = ?", "pending"))
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range orders {
+		c := counts[row.UserID]
+		c.PendingOrders = row.Total
+		counts[row.UserID] = c
+	}
+	return counts, nil
+}
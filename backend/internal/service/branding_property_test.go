@@ -0,0 +1,147 @@
+package service
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+)
+
+// Branding validation: only well-formed colors are stored, and stored values
+// are served back to the frontend normalized.
+func TestBrandingColorValidation(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("colors are accepted iff they are #RGB or #RRGGBB", prop.ForAll(
+		func(digits string, length int) bool {
+			db := setupTenantTestDB(t)
+			branding := NewBrandingService(NewAdminService(db, NewWalletService(db)), t.TempDir(), 64)
+
+			color := "#" + digits[:length]
+			valid := length == 3 || length == 6
+			result, err := branding.ForTenant(2).UpdateBranding(1, UpdateBrandingRequest{PrimaryColor: &color})
+			if valid != (err == nil) {
+				t.Logf("Color %q: expected valid=%v, got %v", color, valid, err)
+				return false
+			}
+			if !valid {
+				return err == ErrInvalidBrandingColor
+			}
+			if result.PrimaryColor != strings.ToLower(color) || result.UpdatedAt.IsZero() {
+				t.Logf("Expected color %q with update time, got %+v", color, result)
+				return false
+			}
+
+			// Other tenants keep the default
+			other, err := branding.ForTenant(1).GetBranding()
+			return err == nil && other.PrimaryColor == defaultBranding.PrimaryColor
+		},
+		gen.RegexMatch(`^[0-9a-fA-F]{8}$`),
+		gen.IntRange(0, 8),
+	))
+
+	properties.TestingRun(t)
+}
+
+func TestBrandingFieldValidation(t *testing.T) {
+	db := setupTenantTestDB(t)
+	branding := NewBrandingService(NewAdminService(db, NewWalletService(db)), t.TempDir(), 64)
+
+	str := func(s string) *string { return &s }
+	cases := []struct {
+		req UpdateBrandingRequest
+		err error
+	}{
+		{UpdateBrandingRequest{SiteName: str("Lucky <script>")}, ErrInvalidSiteName},
+		{UpdateBrandingRequest{SiteName: str(strings.Repeat("刮", 65))}, ErrInvalidSiteName},
+		{UpdateBrandingRequest{LogoURL: str("javascript:alert(1)")}, ErrInvalidBrandingURL},
+		{UpdateBrandingRequest{LogoURL: str("//evil.example.com/logo.png")}, ErrInvalidBrandingURL},
+		{UpdateBrandingRequest{SupportURL: str("ftp://example.com")}, ErrInvalidBrandingURL},
+		{UpdateBrandingRequest{SupportEmail: str("Support <help@example.com>")}, ErrInvalidBrandingEmail},
+		{UpdateBrandingRequest{SupportEmail: str("not-an-email")}, ErrInvalidBrandingEmail},
+		{UpdateBrandingRequest{
+			SiteName:     str("Lucky"),
+			LogoURL:      str("/logo.png"),
+			SupportURL:   str("https://help.example.com"),
+			SupportEmail: str("help@example.com"),
+		}, nil},
+	}
+	for i, tc := range cases {
+		if _, err := branding.UpdateBranding(1, tc.req); err != tc.err {
+			t.Errorf("Case %d: expected %v, got %v", i, tc.err, err)
+		}
+	}
+
+	// Empty values reset to the defaults
+	result, err := branding.UpdateBranding(1, UpdateBrandingRequest{SiteName: str(""), LogoURL: str("")})
+	if err != nil {
+		t.Fatalf("UpdateBranding failed: %v", err)
+	}
+	if result.SiteName != defaultBranding.SiteName || result.LogoURL != "" || result.SupportEmail != "help@example.com" {
+		t.Errorf("Unexpected branding after reset: %+v", result)
+	}
+}
+
+func TestBrandingLogoUpload(t *testing.T) {
+	db := setupTenantTestDB(t)
+	dir := t.TempDir()
+	branding := NewBrandingService(NewAdminService(db, NewWalletService(db)), dir, 1)
+
+	encode := func(width, height int) []byte {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+			t.Fatalf("Failed to encode png: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	logo := encode(16, 16)
+	result, err := branding.ForTenant(2).UploadLogo(1, bytes.NewReader(logo))
+	if err != nil {
+		t.Fatalf("UploadLogo failed: %v", err)
+	}
+	name := strings.TrimPrefix(result.LogoURL, BrandingAssetPath)
+	if !strings.HasSuffix(name, ".png") {
+		t.Fatalf("Unexpected logo url %s", result.LogoURL)
+	}
+	path, err := branding.AssetPath(name)
+	if err != nil || path != filepath.Join(dir, name) {
+		t.Fatalf("Expected stored asset, got %s (err %v)", path, err)
+	}
+	if stored, _ := os.ReadFile(path); !bytes.Equal(stored, logo) {
+		t.Errorf("Stored asset differs from upload")
+	}
+
+	svg := []byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`)
+	if _, err := branding.UploadLogo(1, bytes.NewReader(svg)); err != ErrBrandingAssetType {
+		t.Errorf("Expected ErrBrandingAssetType for svg, got %v", err)
+	}
+	truncated := append([]byte{}, logo[:16]...)
+	if _, err := branding.UploadLogo(1, bytes.NewReader(truncated)); err != ErrBrandingAssetInvalid {
+		t.Errorf("Expected ErrBrandingAssetInvalid for truncated png, got %v", err)
+	}
+	if _, err := branding.UploadLogo(1, bytes.NewReader(encode(4096, 1))); err != ErrBrandingAssetInvalid {
+		t.Errorf("Expected ErrBrandingAssetInvalid for oversized dimensions, got %v", err)
+	}
+	if _, err := branding.UploadLogo(1, bytes.NewReader(append(logo, make([]byte, 1024)...))); err != ErrBrandingAssetTooBig {
+		t.Errorf("Expected ErrBrandingAssetTooBig, got %v", err)
+	}
+
+	// Only generated names are served
+	for _, name := range []string{"../branding_property_test.go", "logo.png", strings.Repeat("0", 64) + ".png"} {
+		if _, err := branding.AssetPath(name); err != ErrBrandingAssetNotFound {
+			t.Errorf("Expected ErrBrandingAssetNotFound for %q, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"image"
+	_ "image/gif"  // register GIF decoder for asset validation
+	_ "image/jpeg" // register JPEG decoder for asset validation
+	_ "image/png"  // register PNG decoder for asset validation
+	"io"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"scratch-lottery/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// System config keys of the branding settings
+const (
+	ConfigKeySiteName       = "site_name"
+	ConfigKeyLogoURL        = "branding_logo_url"
+	ConfigKeyPrimaryColor   = "branding_primary_color"
+	ConfigKeyAccentColor    = "branding_accent_color"
+	ConfigKeySupportEmail   = "branding_support_email"
+	ConfigKeySupportURL     = "branding_support_url"
+	ConfigKeyBrandingUpdate = "branding_updated_at"
+)
+
+// BrandingAssetPath is the public path uploaded branding assets are served under
+const BrandingAssetPath = "/api/system/branding/assets/"
+
+// Limits of uploaded branding assets
+const (
+	maxBrandingAssetDimension = 2048
+	maxBrandingSiteNameLength = 64
+)
+
+var (
+	ErrInvalidBrandingColor  = errors.New("invalid branding color")
+	ErrInvalidBrandingURL    = errors.New("invalid branding url")
+	ErrInvalidBrandingEmail  = errors.New("invalid support email")
+	ErrInvalidSiteName       = errors.New("invalid site name")
+	ErrBrandingAssetTooBig   = errors.New("branding asset too large")
+	ErrBrandingAssetType     = errors.New("unsupported branding asset type")
+	ErrBrandingAssetInvalid  = errors.New("branding asset is not a valid image")
+	ErrBrandingAssetNotFound = errors.New("branding asset not found")
+)
+
+var (
+	brandingColorPattern = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+	brandingAssetPattern = regexp.MustCompile(`^[0-9a-f]{64}\.(?:png|jpg|gif|mp3|ogg|wav)$`)
+)
+
+// brandingAssetTypes maps the accepted sniffed content types to file extensions.
+// SVG is deliberately not accepted since it can carry scripts.
+var brandingAssetTypes = map[string]string{
+	"image/png":  "png",
+	"image/jpeg": "jpg",
+	"image/gif":  "gif",
+}
+
+// defaultBranding is served for settings that have not been configured
+var defaultBranding = Branding{
+	SiteName:     "刮刮乐彩票娱乐网站",
+	PrimaryColor: "#e11d48",
+	AccentColor:  "#f59e0b",
+}
+
+// BrandingService manages the white-label branding of a tenant: site name,
+// logo, theme colors and support contact
+type BrandingService struct {
+	adminService  *AdminService
+	assetDir      string
+	maxAssetBytes int64
+}
+
+// NewBrandingService creates a new branding service. Uploaded assets are
+// stored in assetDir and may be at most maxAssetKB kilobytes.
+func NewBrandingService(adminService *AdminService, assetDir string, maxAssetKB int) *BrandingService {
+	if maxAssetKB <= 0 {
+		maxAssetKB = 512
+	}
+	return &BrandingService{
+		adminService:  adminService,
+		assetDir:      assetDir,
+		maxAssetBytes: int64(maxAssetKB) * 1024,
+	}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *BrandingService) ForTenant(tenantID uint) *BrandingService {
+	return &BrandingService{
+		adminService:  s.adminService.ForTenant(tenantID),
+		assetDir:      s.assetDir,
+		maxAssetBytes: s.maxAssetBytes,
+	}
+}
+
+// Branding represents the white-label configuration served to the frontend
+type Branding struct {
+	SiteName     string    `json:"site_name"`
+	LogoURL      string    `json:"logo_url"`
+	PrimaryColor string    `json:"primary_color"`
+	AccentColor  string    `json:"accent_color"`
+	SupportEmail string    `json:"support_email"`
+	SupportURL   string    `json:"support_url"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// UpdateBrandingRequest represents a request to update branding. Omitted
+// fields are left unchanged; empty strings reset a field to its default.
+type UpdateBrandingRequest struct {
+	SiteName     *string `json:"site_name"`
+	LogoURL      *string `json:"logo_url"`
+	PrimaryColor *string `json:"primary_color"`
+	AccentColor  *string `json:"accent_color"`
+	SupportEmail *string `json:"support_email"`
+	SupportURL   *string `json:"support_url"`
+}
+
+// GetBranding returns the branding with defaults filled in
+func (s *BrandingService) GetBranding() (*Branding, error) {
+	values := make(map[string]string)
+	for _, key := range []string{
+		ConfigKeySiteName, ConfigKeyLogoURL, ConfigKeyPrimaryColor, ConfigKeyAccentColor,
+		ConfigKeySupportEmail, ConfigKeySupportURL, ConfigKeyBrandingUpdate,
+	} {
+		value, err := s.adminService.GetConfigValue(key)
+		if err != nil && !errors.Is(err, ErrConfigNotFound) {
+			return nil, err
+		}
+		values[key] = value
+	}
+
+	branding := &Branding{
+		SiteName:     orDefault(values[ConfigKeySiteName], defaultBranding.SiteName),
+		LogoURL:      values[ConfigKeyLogoURL],
+		PrimaryColor: orDefault(values[ConfigKeyPrimaryColor], defaultBranding.PrimaryColor),
+		AccentColor:  orDefault(values[ConfigKeyAccentColor], defaultBranding.AccentColor),
+		SupportEmail: values[ConfigKeySupportEmail],
+		SupportURL:   values[ConfigKeySupportURL],
+	}
+	if updatedAt, err := time.Parse(time.RFC3339, values[ConfigKeyBrandingUpdate]); err == nil {
+		branding.UpdatedAt = updatedAt
+	}
+	return branding, nil
+}
+
+// UpdateBranding validates and saves branding settings
+func (s *BrandingService) UpdateBranding(adminID uint, req UpdateBrandingRequest) (*Branding, error) {
+	values, err := validateBranding(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.save(adminID, "update_branding", values, req); err != nil {
+		return nil, err
+	}
+	return s.GetBranding()
+}
+
+// UploadLogo validates an uploaded logo image, stores it under a content
+// addressed name and makes it the logo of the tenant
+func (s *BrandingService) UploadLogo(adminID uint, r io.Reader) (*Branding, error) {
+	data, err := io.ReadAll(io.LimitReader(r, s.maxAssetBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > s.maxAssetBytes {
+		return nil, ErrBrandingAssetTooBig
+	}
+
+	ext, ok := brandingAssetTypes[http.DetectContentType(data)]
+	if !ok {
+		return nil, ErrBrandingAssetType
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || config.Width < 1 || config.Height < 1 {
+		return nil, ErrBrandingAssetInvalid
+	}
+	if config.Width > maxBrandingAssetDimension || config.Height > maxBrandingAssetDimension {
+		return nil, ErrBrandingAssetInvalid
+	}
+
+	sum := sha256.Sum256(data)
+	name := hex.EncodeToString(sum[:]) + "." + ext
+	if err := os.MkdirAll(s.assetDir, 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(s.assetDir, name), data, 0644); err != nil {
+		return nil, err
+	}
+
+	logoURL := BrandingAssetPath + name
+	details := map[string]interface{}{"logo_url": logoURL, "size": len(data), "width": config.Width, "height": config.Height}
+	if err := s.save(adminID, "upload_branding_logo", map[string]string{ConfigKeyLogoURL: logoURL}, details); err != nil {
+		return nil, err
+	}
+	return s.GetBranding()
+}
+
+// AssetPath returns the file path of an uploaded asset. Only names generated
+// by UploadLogo and UploadDesignAsset are accepted.
+func (s *BrandingService) AssetPath(name string) (string, error) {
+	if !brandingAssetPattern.MatchString(name) {
+		return "", ErrBrandingAssetNotFound
+	}
+	path := filepath.Join(s.assetDir, name)
+	if _, err := os.Stat(path); err != nil {
+		return "", ErrBrandingAssetNotFound
+	}
+	return path, nil
+}
+
+// save writes branding config values and the update time, and logs the change
+func (s *BrandingService) save(adminID uint, action string, values map[string]string, details interface{}) error {
+	values[ConfigKeyBrandingUpdate] = time.Now().UTC().Format(time.RFC3339Nano)
+	return s.adminService.configs().Transaction(func(tx *gorm.DB) error {
+		for key, value := range values {
+			if err := s.adminService.upsertConfig(tx, key, value); err != nil {
+				return err
+			}
+		}
+
+		detailsJSON, _ := json.Marshal(details)
+		adminLog := model.AdminLog{
+			AdminID:    adminID,
+			Action:     action,
+			TargetType: "system",
+			TargetID:   0,
+			Details:    string(detailsJSON),
+		}
+		return tx.Create(&adminLog).Error
+	})
+}
+
+// validateBranding checks the requested values and returns the config values to write
+func validateBranding(req UpdateBrandingRequest) (map[string]string, error) {
+	values := make(map[string]string)
+
+	if req.SiteName != nil {
+		name := strings.TrimSpace(*req.SiteName)
+		if len([]rune(name)) > maxBrandingSiteNameLength || strings.ContainsAny(name, "<>") {
+			return nil, ErrInvalidSiteName
+		}
+		values[ConfigKeySiteName] = name
+	}
+	for key, color := range map[string]*string{ConfigKeyPrimaryColor: req.PrimaryColor, ConfigKeyAccentColor: req.AccentColor} {
+		if color == nil {
+			continue
+		}
+		if *color != "" && !brandingColorPattern.MatchString(*color) {
+			return nil, ErrInvalidBrandingColor
+		}
+		values[key] = strings.ToLower(*color)
+	}
+	for key, link := range map[string]*string{ConfigKeyLogoURL: req.LogoURL, ConfigKeySupportURL: req.SupportURL} {
+		if link == nil {
+			continue
+		}
+		if *link != "" && !isBrandingURL(*link) {
+			return nil, ErrInvalidBrandingURL
+		}
+		values[key] = *link
+	}
+	if req.SupportEmail != nil {
+		if *req.SupportEmail != "" {
+			address, err := mail.ParseAddress(*req.SupportEmail)
+			if err != nil || address.Name != "" {
+				return nil, ErrInvalidBrandingEmail
+			}
+		}
+		values[ConfigKeySupportEmail] = *req.SupportEmail
+	}
+	return values, nil
+}
+
+// isBrandingURL accepts absolute http(s) URLs and site-relative paths
+func isBrandingURL(link string) bool {
+	if strings.HasPrefix(link, "/") && !strings.HasPrefix(link, "//") {
+		return !strings.ContainsAny(link, "\"'<> ")
+	}
+	u, err := url.Parse(link)
+	return err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != ""
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
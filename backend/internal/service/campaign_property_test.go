@@ -0,0 +1,317 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+	"gorm.io/gorm"
+)
+
+func setupCampaignTest(t *testing.T, users int) (*gorm.DB, *CampaignService, []uint) {
+	db := setupTenantTestDB(t)
+	if err := db.AutoMigrate(&model.Campaign{}, &model.CampaignReward{}, &model.Coupon{}, &model.CheckIn{},
+		&model.Segment{}, &model.UserSegment{}, &model.Notification{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	userIDs := make([]uint, users)
+	for i := range userIDs {
+		user := model.User{LinuxdoID: fmt.Sprintf("campaign_user_%d", i), Username: fmt.Sprintf("User %d", i), Role: "user"}
+		db.Create(&user)
+		db.Create(&model.Wallet{UserID: user.ID})
+		userIDs[i] = user.ID
+	}
+
+	notifications := NewNotificationService(db)
+	campaigns := NewCampaignService(db, NewLotteryService(db, testEncryptionKey), NewSegmentService(db, notifications), notifications)
+	return db, campaigns, userIDs
+}
+
+// campaignCheckins returns a check-in service passing check-ins to the campaigns
+func campaignCheckins(db *gorm.DB, campaigns *CampaignService) *CheckinService {
+	return NewCheckinService(db, NewAdminService(db, NewWalletService(db)), campaigns)
+}
+
+// Campaigns: check-in rewards stop once the budget or the reward cap is
+// reached, never overspend the budget, and pay each user once a day.
+func TestCampaignBudgetCaps(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("rewards stay within the caps", prop.ForAll(
+		func(users, points, budget, maxRewards int) bool {
+			db, campaigns, userIDs := setupCampaignTest(t, users)
+			checkins := campaignCheckins(db, campaigns)
+			campaign, err := campaigns.CreateCampaign(CampaignRequest{
+				Name:         "Daily check-in",
+				Trigger:      model.CampaignTriggerCheckIn,
+				RewardType:   model.CampaignRewardPoints,
+				RewardPoints: points,
+				Budget:       budget,
+				MaxRewards:   maxRewards,
+			})
+			if err != nil {
+				t.Logf("CreateCampaign failed: %v", err)
+				return false
+			}
+
+			expected := users
+			if budget > 0 {
+				expected = min(expected, budget/points)
+			}
+			if maxRewards > 0 {
+				expected = min(expected, maxRewards)
+			}
+
+			rewarded := 0
+			for _, userID := range userIDs {
+				result, err := checkins.CheckIn(userID)
+				if err != nil {
+					t.Logf("CheckIn failed: %v", err)
+					return false
+				}
+				rewarded += len(result.Rewards)
+				if _, err := checkins.CheckIn(userID); err != ErrAlreadyCheckedIn {
+					t.Logf("Expected ErrAlreadyCheckedIn, got %v", err)
+					return false
+				}
+			}
+
+			report, err := campaigns.GetReport(campaign.ID)
+			if err != nil {
+				t.Logf("GetReport failed: %v", err)
+				return false
+			}
+			if rewarded != expected || report.Rewards != int64(expected) || report.Spent != expected*points ||
+				(budget > 0 && report.Spent > budget) {
+				t.Logf("Expected %d rewards, got %d (report %+v)", expected, rewarded, report)
+				return false
+			}
+			if expected < users && report.Campaign.Status != "exhausted" {
+				t.Logf("Expected the campaign to be exhausted, got %s", report.Campaign.Status)
+				return false
+			}
+
+			var credited int64
+			db.Model(&model.Transaction{}).Where("type = ?", model.TransactionTypeCampaign).Select("COALESCE(SUM(amount), 0)").Scan(&credited)
+			return credited == int64(expected*points)
+		},
+		gen.IntRange(1, 8),
+		gen.IntRange(1, 20),
+		gen.IntRange(0, 100),
+		gen.IntRange(0, 6),
+	))
+
+	properties.TestingRun(t)
+}
+
+func TestCampaignRewards(t *testing.T) {
+	db, campaigns, userIDs := setupCampaignTest(t, 3)
+	checkins := campaignCheckins(db, campaigns)
+	lotteryTypeID := createOddsHintPool(t, db, 100, 0, nil)
+
+	invalid := []CampaignRequest{
+		{Name: "No reward", Trigger: model.CampaignTriggerCheckIn, RewardType: model.CampaignRewardPoints},
+		{Name: "Unknown trigger", Trigger: "login", RewardType: model.CampaignRewardPoints, RewardPoints: 5},
+		{Name: "Min amount", Trigger: model.CampaignTriggerCheckIn, RewardType: model.CampaignRewardPoints, RewardPoints: 5, MinAmount: 100},
+		{Name: "No tickets", Trigger: model.CampaignTriggerCheckIn, RewardType: model.CampaignRewardFreeTicket, RewardLotteryTypeID: lotteryTypeID},
+		{Name: "Unknown lottery", Trigger: model.CampaignTriggerCheckIn, RewardType: model.CampaignRewardFreeTicket, RewardLotteryTypeID: 999, RewardQuantity: 1},
+		{Name: "Unknown segment", Trigger: model.CampaignTriggerCheckIn, RewardType: model.CampaignRewardPoints, RewardPoints: 5, SegmentID: new(uint)},
+	}
+	for _, req := range invalid {
+		if _, err := campaigns.CreateCampaign(req); err != ErrInvalidCampaign {
+			t.Errorf("Expected ErrInvalidCampaign for %q, got %v", req.Name, err)
+		}
+	}
+
+	// A first purchase earns a coupon worth more than one ticket
+	couponCampaign, err := campaigns.CreateCampaign(CampaignRequest{
+		Name:            "Welcome",
+		Trigger:         model.CampaignTriggerFirstPurchase,
+		RewardType:      model.CampaignRewardCoupon,
+		RewardPoints:    15,
+		CouponValidDays: 7,
+	})
+	if err != nil {
+		t.Fatalf("CreateCampaign failed: %v", err)
+	}
+	purchases := NewPurchaseService(db, NewLotteryService(db, testEncryptionKey), NewWalletService(db), nil, campaigns, nil)
+	first, err := purchases.PurchaseTickets(userIDs[0], PurchaseRequest{LotteryTypeID: lotteryTypeID, Quantity: 1})
+	if err != nil || len(first.Rewards) != 1 || first.Rewards[0].CouponID == nil {
+		t.Fatalf("Expected a coupon for the first purchase, got %+v (err %v)", first, err)
+	}
+	couponID := *first.Rewards[0].CouponID
+	if coupons, _ := campaigns.GetCoupons(userIDs[0]); len(coupons) != 1 || coupons[0].Discount != 15 || coupons[0].CampaignName != "Welcome" {
+		t.Errorf("Unexpected coupons %+v", coupons)
+	}
+	if _, err := purchases.PurchaseTickets(userIDs[1], PurchaseRequest{LotteryTypeID: lotteryTypeID, Quantity: 1, CouponID: couponID}); err != ErrCouponUnavailable {
+		t.Errorf("Expected ErrCouponUnavailable for another user's coupon, got %v", err)
+	}
+
+	second, err := purchases.PurchaseTickets(userIDs[0], PurchaseRequest{LotteryTypeID: lotteryTypeID, Quantity: 2, CouponID: couponID})
+	if err != nil || second.Cost != 5 || second.Discount != 15 || second.Balance != 50-10-5 || len(second.Rewards) != 0 {
+		t.Fatalf("Expected the coupon to cover 15 points, got %+v (err %v)", second, err)
+	}
+	if _, err := purchases.PurchaseTickets(userIDs[0], PurchaseRequest{LotteryTypeID: lotteryTypeID, Quantity: 1, CouponID: couponID}); err != ErrCouponUnavailable {
+		t.Errorf("Expected ErrCouponUnavailable for a used coupon, got %v", err)
+	}
+
+	// Only the purchase after the reward counts as spend of rewarded users
+	report, err := campaigns.GetReport(couponCampaign.ID)
+	if err != nil || report.Rewards != 1 || report.CouponsIssued != 1 || report.CouponsRedeemed != 1 ||
+		report.RewardedUserSpend != 5 || len(report.Daily) != 1 || report.Daily[0].Cost != 15 {
+		t.Errorf("Unexpected report %+v (err %v)", report, err)
+	}
+
+	// Free tickets for checking in, limited to a segment
+	segment, err := campaigns.segmentService.CreateSegment(SegmentRequest{Name: "Buyers", Rules: SegmentRules{ActiveDays: 1}})
+	if err != nil || segment.MemberCount != 1 {
+		t.Fatalf("Expected one buyer in the segment, got %+v (err %v)", segment, err)
+	}
+	ticketCampaign, err := campaigns.CreateCampaign(CampaignRequest{
+		Name:                "Free ticket",
+		Trigger:             model.CampaignTriggerCheckIn,
+		SegmentID:           &segment.ID,
+		RewardType:          model.CampaignRewardFreeTicket,
+		RewardLotteryTypeID: lotteryTypeID,
+		RewardQuantity:      2,
+	})
+	if err != nil {
+		t.Fatalf("CreateCampaign failed: %v", err)
+	}
+	checkIn, err := checkins.CheckIn(userIDs[0])
+	if err != nil || len(checkIn.Rewards) != 1 || len(checkIn.Rewards[0].TicketIDs) != 2 {
+		t.Fatalf("Expected two free tickets, got %+v (err %v)", checkIn, err)
+	}
+	if checkIn, err := checkins.CheckIn(userIDs[2]); err != nil || len(checkIn.Rewards) != 0 {
+		t.Errorf("Expected no reward outside the segment, got %+v (err %v)", checkIn, err)
+	}
+	var tickets int64
+	db.Model(&model.Ticket{}).Where("user_id = ?", userIDs[0]).Count(&tickets)
+	if tickets != 3+2 {
+		t.Errorf("Expected 5 tickets, got %d", tickets)
+	}
+	if report, _ := campaigns.GetReport(ticketCampaign.ID); report.Spent != 20 {
+		t.Errorf("Expected free tickets to cost 20 points, got %d", report.Spent)
+	}
+
+	// Ended campaigns no longer reward
+	ended := time.Now().Add(-time.Hour)
+	started := ended.Add(-time.Hour)
+	updated, err := campaigns.UpdateCampaign(ticketCampaign.ID, CampaignRequest{
+		Name:                "Free ticket",
+		Trigger:             model.CampaignTriggerCheckIn,
+		StartsAt:            &started,
+		EndsAt:              &ended,
+		RewardType:          model.CampaignRewardFreeTicket,
+		RewardLotteryTypeID: lotteryTypeID,
+		RewardQuantity:      2,
+	})
+	if err != nil || updated.Status != "ended" || updated.Spent != 20 {
+		t.Fatalf("Expected an ended campaign keeping its spend, got %+v (err %v)", updated, err)
+	}
+	db.Where("1 = 1").Delete(&model.CheckIn{})
+	if checkIn, err := checkins.CheckIn(userIDs[0]); err != nil || len(checkIn.Rewards) != 0 {
+		t.Errorf("Expected no reward from an ended campaign, got %+v (err %v)", checkIn, err)
+	}
+
+	// Notifications tell users about their rewards, batched into one
+	var notifications []model.Notification
+	db.Where("user_id = ? AND type = ?", userIDs[0], model.NotificationTypeCampaign).Find(&notifications)
+	if len(notifications) != 1 || notifications[0].Count != 2 {
+		t.Errorf("Expected 1 campaign notification for 2 rewards, got %+v", notifications)
+	}
+}
+
+// Recharge campaigns reward orders above their minimum, once per order and
+// within the per-user limit, and stay within their tenant.
+func TestCampaignRechargeEvents(t *testing.T) {
+	_, campaigns, userIDs := setupCampaignTest(t, 1)
+	if _, err := campaigns.CreateCampaign(CampaignRequest{
+		Name:         "Recharge bonus",
+		Trigger:      model.CampaignTriggerRecharge,
+		MinAmount:    1000,
+		RewardType:   model.CampaignRewardPoints,
+		RewardPoints: 20,
+		PerUserLimit: 2,
+	}); err != nil {
+		t.Fatalf("CreateCampaign failed: %v", err)
+	}
+
+	recharge := func(orderID uint, amount int) int {
+		rewards, err := campaigns.HandleEvent(CampaignEvent{
+			Trigger:     model.CampaignTriggerRecharge,
+			UserID:      userIDs[0],
+			Key:         fmt.Sprintf("order:%d", orderID),
+			ReferenceID: orderID,
+			Amount:      amount,
+		})
+		if err != nil {
+			t.Fatalf("HandleEvent failed: %v", err)
+		}
+		return len(rewards)
+	}
+	if got := recharge(1, 999); got != 0 {
+		t.Errorf("Expected no reward below the minimum, got %d", got)
+	}
+	if got := recharge(2, 1000); got != 1 {
+		t.Errorf("Expected a reward, got %d", got)
+	}
+	if got := recharge(2, 1000); got != 0 {
+		t.Errorf("Expected the same order to pay once, got %d", got)
+	}
+	if got := recharge(3, 5000); got != 1 {
+		t.Errorf("Expected a reward, got %d", got)
+	}
+	if got := recharge(4, 5000); got != 0 {
+		t.Errorf("Expected the per-user limit to stop rewards, got %d", got)
+	}
+
+	if rewards, err := campaigns.ForTenant(2).HandleEvent(CampaignEvent{
+		Trigger: model.CampaignTriggerRecharge, UserID: userIDs[0], Key: "order:5", Amount: 5000,
+	}); err != nil || len(rewards) != 0 {
+		t.Errorf("Expected no campaigns in tenant 2, got %+v (err %v)", rewards, err)
+	}
+}
+
+// Tickets bought with campaign bonus credits count as spend of rewarded
+// users, whether paid in bonus credits alone or topped up with points
+func TestCampaignReportCountsBonusSpend(t *testing.T) {
+	db, campaigns, userIDs := setupCampaignTest(t, 1)
+	lotteryTypeID := createOddsHintPool(t, db, 100, 0, nil)
+	campaign, err := campaigns.CreateCampaign(CampaignRequest{
+		Name:         "Bonus check-in",
+		Trigger:      model.CampaignTriggerCheckIn,
+		RewardType:   model.CampaignRewardBonus,
+		RewardPoints: 30,
+	})
+	if err != nil {
+		t.Fatalf("CreateCampaign failed: %v", err)
+	}
+	if checkIn, err := campaignCheckins(db, campaigns).CheckIn(userIDs[0]); err != nil || len(checkIn.Rewards) != 1 {
+		t.Fatalf("Expected a bonus reward, got %+v (err %v)", checkIn, err)
+	}
+
+	purchases := NewPurchaseService(db, NewLotteryService(db, testEncryptionKey), NewWalletService(db), nil, campaigns, nil)
+	purchases.walletService.UseBonusSpendPriority(BonusSpendFirst)
+	// 20 in bonus credits, then 10 in bonus credits and 30 in points
+	for _, quantity := range []int{2, 4} {
+		if _, err := purchases.PurchaseTickets(userIDs[0], PurchaseRequest{LotteryTypeID: lotteryTypeID, Quantity: quantity}); err != nil {
+			t.Fatalf("PurchaseTickets failed: %v", err)
+		}
+	}
+
+	report, err := campaigns.GetReport(campaign.ID)
+	if err != nil || report.RewardedUserSpend != 60 {
+		t.Errorf("Expected 60 spent by rewarded users, got %+v (err %v)", report, err)
+	}
+}
@@ -0,0 +1,723 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+	"scratch-lottery/pkg/biztime"
+	"scratch-lottery/pkg/logger"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrCampaignNotFound  = errors.New("campaign not found")
+	ErrInvalidCampaign   = errors.New("invalid campaign")
+	ErrCouponUnavailable = errors.New("coupon not found, used or expired")
+)
+
+// errCampaignRewardSkipped rolls back a reward the user is no longer eligible
+// for: the event already paid, the per-user limit is reached or the budget is spent
+var errCampaignRewardSkipped = errors.New("campaign reward skipped")
+
+// Campaign reward limits
+const (
+	maxCampaignRewardPoints = 100000
+	maxCampaignFreeTickets  = 10
+)
+
+// CampaignEvent is a user action reported to the campaign engine
+type CampaignEvent struct {
+	Trigger     model.CampaignTrigger
+	UserID      uint
+	Key         string // Identifies the event, so it pays at most once per campaign
+	ReferenceID uint   // Related payment order or ticket
+	Amount      int    // Recharge amount in minor currency units
+}
+
+// CampaignService manages campaigns and rewards the users whose actions
+// match them. Services report events through HandleEvent once the action
+// is committed; a failing campaign never fails the action itself.
+type CampaignService struct {
+	db                  *gorm.DB
+	lotteryService      *LotteryService
+	segmentService      *SegmentService
+	notificationService *NotificationService
+}
+
+// NewCampaignService creates a new campaign service
+func NewCampaignService(db *gorm.DB, lotteryService *LotteryService, segmentService *SegmentService, notificationService *NotificationService) *CampaignService {
+	return &CampaignService{
+		db:                  db,
+		lotteryService:      lotteryService,
+		segmentService:      segmentService,
+		notificationService: notificationService,
+	}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *CampaignService) ForTenant(tenantID uint) *CampaignService {
+	return &CampaignService{
+		db:                  repository.ScopeTenant(s.db, tenantID),
+		lotteryService:      s.lotteryService.ForTenant(tenantID),
+		segmentService:      s.segmentService.ForTenant(tenantID),
+		notificationService: s.notificationService.ForTenant(tenantID),
+	}
+}
+
+// CampaignRequest represents the request to create or update a campaign
+type CampaignRequest struct {
+	Name                string                   `json:"name" binding:"required,max=64"`
+	Description         string                   `json:"description" binding:"max=256"`
+	Trigger             model.CampaignTrigger    `json:"trigger" binding:"required"`
+	SegmentID           *uint                    `json:"segment_id"`
+	StartsAt            *time.Time               `json:"starts_at"`
+	EndsAt              *time.Time               `json:"ends_at"`
+	Enabled             *bool                    `json:"enabled"` // defaults to true
+	MinAmount           int                      `json:"min_amount"`
+	RewardType          model.CampaignRewardType `json:"reward_type" binding:"required"`
+	RewardPoints        int                      `json:"reward_points"`
+	RewardLotteryTypeID uint                     `json:"reward_lottery_type_id"`
+	RewardQuantity      int                      `json:"reward_quantity"`
+	CouponValidDays     int                      `json:"coupon_valid_days"`
+	PerUserLimit        int                      `json:"per_user_limit"`
+	Budget              int                      `json:"budget"`
+	MaxRewards          int                      `json:"max_rewards"`
+}
+
+// CampaignResponse represents a campaign in API responses. Status is one of
+// disabled, scheduled, active, ended or exhausted.
+type CampaignResponse struct {
+	ID                  uint                     `json:"id"`
+	Name                string                   `json:"name"`
+	Description         string                   `json:"description"`
+	Trigger             model.CampaignTrigger    `json:"trigger"`
+	SegmentID           *uint                    `json:"segment_id,omitempty"`
+	StartsAt            *time.Time               `json:"starts_at,omitempty"`
+	EndsAt              *time.Time               `json:"ends_at,omitempty"`
+	Enabled             bool                     `json:"enabled"`
+	Status              string                   `json:"status"`
+	MinAmount           int                      `json:"min_amount"`
+	RewardType          model.CampaignRewardType `json:"reward_type"`
+	RewardPoints        int                      `json:"reward_points"`
+	RewardLotteryTypeID uint                     `json:"reward_lottery_type_id,omitempty"`
+	RewardQuantity      int                      `json:"reward_quantity,omitempty"`
+	CouponValidDays     int                      `json:"coupon_valid_days,omitempty"`
+	PerUserLimit        int                      `json:"per_user_limit"`
+	Budget              int                      `json:"budget"`
+	MaxRewards          int                      `json:"max_rewards"`
+	Spent               int                      `json:"spent"`
+	RewardCount         int                      `json:"reward_count"`
+	CreatedAt           time.Time                `json:"created_at"`
+	UpdatedAt           time.Time                `json:"updated_at"`
+}
+
+// CampaignRewardResponse represents a reward given to a user
+type CampaignRewardResponse struct {
+	CampaignID   uint                     `json:"campaign_id"`
+	CampaignName string                   `json:"campaign_name"`
+	RewardType   model.CampaignRewardType `json:"reward_type"`
+	Points       int                      `json:"points,omitempty"`
+	CouponID     *uint                    `json:"coupon_id,omitempty"`
+	TicketIDs    []uint                   `json:"ticket_ids,omitempty"`
+}
+
+// CampaignDailyStat summarizes the rewards of a campaign on one day
+type CampaignDailyStat struct {
+	Date    string `json:"date"`
+	Rewards int    `json:"rewards"`
+	Cost    int    `json:"cost"`
+}
+
+// CampaignReport summarizes the performance of a campaign. RewardedUserSpend
+// is the points rewarded users spent on tickets after their first reward.
+type CampaignReport struct {
+	Campaign          CampaignResponse    `json:"campaign"`
+	Rewards           int64               `json:"rewards"`
+	FailedRewards     int64               `json:"failed_rewards"`
+	UniqueUsers       int64               `json:"unique_users"`
+	Spent             int                 `json:"spent"`
+	RemainingBudget   *int                `json:"remaining_budget,omitempty"` // Nil when the budget is unlimited
+	CouponsIssued     int64               `json:"coupons_issued"`
+	CouponsRedeemed   int64               `json:"coupons_redeemed"`
+	RewardedUserSpend int                 `json:"rewarded_user_spend"`
+	Daily             []CampaignDailyStat `json:"daily"`
+}
+
+// CouponResponse represents a coupon a user can still redeem
+type CouponResponse struct {
+	ID           uint       `json:"id"`
+	CampaignID   uint       `json:"campaign_id"`
+	CampaignName string     `json:"campaign_name"`
+	Discount     int        `json:"discount"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// GetCampaigns returns all campaigns, newest first
+func (s *CampaignService) GetCampaigns() ([]CampaignResponse, error) {
+	var campaigns []model.Campaign
+	if err := s.db.Order("id DESC").Find(&campaigns).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	responses := make([]CampaignResponse, len(campaigns))
+	for i := range campaigns {
+		responses[i] = toCampaignResponse(&campaigns[i], now)
+	}
+	return responses, nil
+}
+
+// GetCampaign returns a campaign by ID
+func (s *CampaignService) GetCampaign(id uint) (*CampaignResponse, error) {
+	campaign, err := s.campaign(id)
+	if err != nil {
+		return nil, err
+	}
+	resp := toCampaignResponse(campaign, time.Now())
+	return &resp, nil
+}
+
+// CreateCampaign validates and saves a new campaign
+func (s *CampaignService) CreateCampaign(req CampaignRequest) (*CampaignResponse, error) {
+	campaign := model.Campaign{}
+	if err := s.applyCampaignRequest(&campaign, req); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(&campaign).Error; err != nil {
+		return nil, err
+	}
+	resp := toCampaignResponse(&campaign, time.Now())
+	return &resp, nil
+}
+
+// UpdateCampaign replaces the definition of a campaign. Spent budget and
+// rewards already given are kept.
+func (s *CampaignService) UpdateCampaign(id uint, req CampaignRequest) (*CampaignResponse, error) {
+	campaign, err := s.campaign(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyCampaignRequest(campaign, req); err != nil {
+		return nil, err
+	}
+	if err := s.db.Omit("spent", "reward_count").Save(campaign).Error; err != nil {
+		return nil, err
+	}
+	resp := toCampaignResponse(campaign, time.Now())
+	return &resp, nil
+}
+
+// DeleteCampaign soft deletes a campaign. Issued coupons stay redeemable.
+func (s *CampaignService) DeleteCampaign(id uint) error {
+	campaign, err := s.campaign(id)
+	if err != nil {
+		return err
+	}
+	return s.db.Delete(campaign).Error
+}
+
+// GetReport returns the performance of a campaign
+func (s *CampaignService) GetReport(id uint) (*CampaignReport, error) {
+	campaign, err := s.campaign(id)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &CampaignReport{
+		Campaign: toCampaignResponse(campaign, time.Now()),
+		Spent:    campaign.Spent,
+		Daily:    []CampaignDailyStat{},
+	}
+	if campaign.Budget > 0 {
+		remaining := max(campaign.Budget-campaign.Spent, 0)
+		report.RemainingBudget = &remaining
+	}
+
+	rewards := s.db.Model(&model.CampaignReward{}).Where("campaign_id = ?", campaign.ID)
+	issued := rewards.Session(&gorm.Session{}).Where("status = ?", model.CampaignRewardStatusIssued)
+	if err := issued.Session(&gorm.Session{}).Count(&report.Rewards).Error; err != nil {
+		return nil, err
+	}
+	if err := rewards.Session(&gorm.Session{}).Where("status = ?", model.CampaignRewardStatusFailed).
+		Count(&report.FailedRewards).Error; err != nil {
+		return nil, err
+	}
+	if err := issued.Session(&gorm.Session{}).Distinct("user_id").Count(&report.UniqueUsers).Error; err != nil {
+		return nil, err
+	}
+
+	coupons := s.db.Model(&model.Coupon{}).Where("campaign_id = ?", campaign.ID)
+	if err := coupons.Session(&gorm.Session{}).Count(&report.CouponsIssued).Error; err != nil {
+		return nil, err
+	}
+	if err := coupons.Session(&gorm.Session{}).Where("used_at IS NOT NULL").Count(&report.CouponsRedeemed).Error; err != nil {
+		return nil, err
+	}
+
+	// Days are bucketed here rather than in SQL to stay portable across drivers
+	var daily []model.CampaignReward
+	if err := issued.Session(&gorm.Session{}).Select("created_at", "cost").Order("created_at ASC").
+		Find(&daily).Error; err != nil {
+		return nil, err
+	}
+	for _, reward := range daily {
+		date := biztime.Date(reward.CreatedAt)
+		if n := len(report.Daily); n == 0 || report.Daily[n-1].Date != date {
+			report.Daily = append(report.Daily, CampaignDailyStat{Date: date})
+		}
+		report.Daily[len(report.Daily)-1].Rewards++
+		report.Daily[len(report.Daily)-1].Cost += reward.Cost
+	}
+
+	firstRewards := s.db.Model(&model.CampaignReward{}).
+		Select("user_id, MIN(created_at) AS first_at").
+		Where("campaign_id = ? AND status = ?", campaign.ID, model.CampaignRewardStatusIssued).
+		Group("user_id")
+	if err := s.db.Model(&model.Transaction{}).
+		Select("COALESCE(SUM(-transactions.amount), 0)").
+		Joins("JOIN wallets ON wallets.id = transactions.wallet_id").
+		Joins("JOIN (?) AS first_rewards ON first_rewards.user_id = wallets.user_id", firstRewards).
+		Where("transactions.type IN ? AND transactions.created_at >= first_rewards.first_at", ticketSpendTypes).
+		Scan(&report.RewardedUserSpend).Error; err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// HandleEvent rewards a user for every active campaign the event matches and
+// returns the rewards given. A campaign that fails is logged and skipped.
+func (s *CampaignService) HandleEvent(event CampaignEvent) ([]CampaignRewardResponse, error) {
+	now := time.Now()
+	query := s.db.Where("enabled = ? AND trigger_type = ?", true, event.Trigger).
+		Where("(starts_at IS NULL OR starts_at <= ?)", now).
+		Where("(ends_at IS NULL OR ends_at > ?)", now)
+	if event.Trigger == model.CampaignTriggerRecharge {
+		query = query.Where("min_amount <= ?", event.Amount)
+	}
+	var campaigns []model.Campaign
+	if err := query.Order("id ASC").Find(&campaigns).Error; err != nil {
+		return nil, err
+	}
+
+	rewards := []CampaignRewardResponse{}
+	for i := range campaigns {
+		reward, err := s.reward(&campaigns[i], event)
+		if err != nil {
+			logger.Default().Warn("Campaign %d failed to reward user %d: %v", campaigns[i].ID, event.UserID, err)
+			continue
+		}
+		if reward != nil {
+			rewards = append(rewards, *reward)
+		}
+	}
+	return rewards, nil
+}
+
+// GetCoupons returns the coupons a user can still redeem, soonest expiry first
+func (s *CampaignService) GetCoupons(userID uint) ([]CouponResponse, error) {
+	var coupons []model.Coupon
+	if err := s.db.Where("user_id = ? AND used_at IS NULL", userID).
+		Where("(expires_at IS NULL OR expires_at > ?)", time.Now()).
+		Order("expires_at IS NULL, expires_at ASC, id ASC").
+		Find(&coupons).Error; err != nil {
+		return nil, err
+	}
+
+	campaignIDs := make([]uint, 0, len(coupons))
+	for _, coupon := range coupons {
+		campaignIDs = append(campaignIDs, coupon.CampaignID)
+	}
+	var campaigns []model.Campaign
+	if len(campaignIDs) > 0 {
+		if err := s.db.Unscoped().Select("id", "name").Where("id IN ?", campaignIDs).Find(&campaigns).Error; err != nil {
+			return nil, err
+		}
+	}
+	names := make(map[uint]string, len(campaigns))
+	for _, campaign := range campaigns {
+		names[campaign.ID] = campaign.Name
+	}
+
+	responses := make([]CouponResponse, len(coupons))
+	for i, coupon := range coupons {
+		responses[i] = CouponResponse{
+			ID:           coupon.ID,
+			CampaignID:   coupon.CampaignID,
+			CampaignName: names[coupon.CampaignID],
+			Discount:     coupon.Discount,
+			ExpiresAt:    coupon.ExpiresAt,
+			CreatedAt:    coupon.CreatedAt,
+		}
+	}
+	return responses, nil
+}
+
+// reward gives the reward of a campaign to the user of an event. Returns nil
+// when the user is outside the audience or no longer eligible.
+func (s *CampaignService) reward(campaign *model.Campaign, event CampaignEvent) (*CampaignRewardResponse, error) {
+	if campaign.SegmentID != nil {
+		member, err := s.segmentService.IsMember(*campaign.SegmentID, event.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if !member {
+			return nil, nil
+		}
+	}
+
+	cost := campaign.RewardPoints
+	if campaign.RewardType == model.CampaignRewardFreeTicket {
+		lotteryType, err := s.lotteryService.GetLotteryTypeByID(campaign.RewardLotteryTypeID)
+		if err != nil {
+			return nil, err
+		}
+		if cost, err = purchaseCost(lotteryType.Price, campaign.RewardQuantity); err != nil {
+			return nil, err
+		}
+	}
+
+	reward := model.CampaignReward{
+		TenantID:    campaign.TenantID,
+		CampaignID:  campaign.ID,
+		UserID:      event.UserID,
+		EventKey:    event.Key,
+		ReferenceID: event.ReferenceID,
+		RewardType:  campaign.RewardType,
+		Cost:        cost,
+		Status:      model.CampaignRewardStatusIssued,
+	}
+	err := repository.Transaction(s.db, func(tx *gorm.DB) error {
+		reward.ID, reward.CouponID = 0, nil
+		if campaign.PerUserLimit > 0 {
+			var given int64
+			if err := tx.Model(&model.CampaignReward{}).
+				Where("campaign_id = ? AND user_id = ? AND status = ?", campaign.ID, event.UserID, model.CampaignRewardStatusIssued).
+				Count(&given).Error; err != nil {
+				return err
+			}
+			if given >= int64(campaign.PerUserLimit) {
+				return errCampaignRewardSkipped
+			}
+		}
+
+		// Reserve the cost, guarded against concurrent rewards exceeding the caps
+		result := tx.Model(&model.Campaign{}).
+			Where("id = ?", campaign.ID).
+			Where("(budget = 0 OR spent + ? <= budget)", cost).
+			Where("(max_rewards = 0 OR reward_count < max_rewards)").
+			Updates(map[string]interface{}{
+				"spent":        gorm.Expr("spent + ?", cost),
+				"reward_count": gorm.Expr("reward_count + 1"),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errCampaignRewardSkipped
+		}
+
+		result = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&reward)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errCampaignRewardSkipped
+		}
+
+		switch campaign.RewardType {
+		case model.CampaignRewardPoints:
+			return creditCampaignPoints(tx, campaign, event.UserID, reward.ID)
+		case model.CampaignRewardBonus:
+			return grantBonus(tx, event.UserID, campaign.RewardPoints, fmt.Sprintf("活动赠金: %s", campaign.Name), reward.ID)
+		case model.CampaignRewardCoupon:
+			coupon := model.Coupon{
+				TenantID:   campaign.TenantID,
+				UserID:     event.UserID,
+				CampaignID: campaign.ID,
+				Discount:   campaign.RewardPoints,
+			}
+			if campaign.CouponValidDays > 0 {
+				expiresAt := time.Now().AddDate(0, 0, campaign.CouponValidDays)
+				coupon.ExpiresAt = &expiresAt
+			}
+			if err := tx.Create(&coupon).Error; err != nil {
+				return err
+			}
+			reward.CouponID = &coupon.ID
+			return tx.Model(&model.CampaignReward{}).Where("id = ?", reward.ID).Update("coupon_id", coupon.ID).Error
+		}
+		return nil
+	})
+	if errors.Is(err, errCampaignRewardSkipped) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &CampaignRewardResponse{
+		CampaignID:   campaign.ID,
+		CampaignName: campaign.Name,
+		RewardType:   campaign.RewardType,
+		CouponID:     reward.CouponID,
+	}
+	var content string
+	switch campaign.RewardType {
+	case model.CampaignRewardPoints:
+		resp.Points = campaign.RewardPoints
+		content = fmt.Sprintf("恭喜获得 %d 积分", campaign.RewardPoints)
+	case model.CampaignRewardBonus:
+		resp.Points = campaign.RewardPoints
+		content = fmt.Sprintf("恭喜获得 %d 赠金，可用于购买彩票", campaign.RewardPoints)
+	case model.CampaignRewardCoupon:
+		resp.Points = campaign.RewardPoints
+		content = fmt.Sprintf("恭喜获得一张抵扣 %d 积分的购票优惠券", campaign.RewardPoints)
+	case model.CampaignRewardFreeTicket:
+		// Tickets are generated once the reward is committed, like purchases
+		ticketIDs, err := s.issueFreeTickets(campaign, &reward)
+		if len(ticketIDs) == 0 {
+			return nil, err
+		}
+		if err != nil {
+			logger.Default().Warn("Campaign %d issued %d of %d free tickets to user %d: %v",
+				campaign.ID, len(ticketIDs), campaign.RewardQuantity, event.UserID, err)
+		}
+		resp.TicketIDs = ticketIDs
+		content = fmt.Sprintf("恭喜获得 %d 张免费彩票", len(ticketIDs))
+	}
+
+	if _, err := s.notificationService.Notify(event.UserID, model.NotificationTypeCampaign, "活动奖励："+campaign.Name, content); err != nil {
+		logger.Default().Warn("Notifying user %d of campaign %d failed: %v", event.UserID, campaign.ID, err)
+	}
+	return resp, nil
+}
+
+// issueFreeTickets generates the free tickets of a reward. The cost of tickets
+// that could not be generated goes back to the budget; a reward without any
+// ticket is marked failed.
+func (s *CampaignService) issueFreeTickets(campaign *model.Campaign, reward *model.CampaignReward) ([]uint, error) {
+	ticketIDs := make([]uint, 0, campaign.RewardQuantity)
+	var err error
+	for i := 0; i < campaign.RewardQuantity; i++ {
+		var ticket *model.Ticket
+		if ticket, err = s.lotteryService.GenerateGiftTicket(reward.UserID, campaign.RewardLotteryTypeID, reward.ID); err != nil {
+			break
+		}
+		ticketIDs = append(ticketIDs, ticket.ID)
+	}
+	if err == nil {
+		return ticketIDs, nil
+	}
+
+	unused := reward.Cost * (campaign.RewardQuantity - len(ticketIDs)) / campaign.RewardQuantity
+	updates := map[string]interface{}{"cost": gorm.Expr("cost - ?", unused)}
+	campaignUpdates := map[string]interface{}{"spent": gorm.Expr("spent - ?", unused)}
+	if len(ticketIDs) == 0 {
+		updates["status"] = model.CampaignRewardStatusFailed
+		campaignUpdates["reward_count"] = gorm.Expr("reward_count - 1")
+	}
+	if releaseErr := repository.Transaction(s.db, func(tx *gorm.DB) error {
+		if err := tx.Model(&model.CampaignReward{}).Where("id = ?", reward.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.Campaign{}).Where("id = ?", campaign.ID).Updates(campaignUpdates).Error
+	}); releaseErr != nil {
+		logger.Default().Warn("Releasing the budget of campaign reward %d failed: %v", reward.ID, releaseErr)
+	}
+	return ticketIDs, err
+}
+
+// creditCampaignPoints credits the points of a campaign to a user within the
+// reward transaction
+func creditCampaignPoints(tx *gorm.DB, campaign *model.Campaign, userID, rewardID uint) error {
+	var wallet model.Wallet
+	if err := lockWallet(tx, userID, &wallet); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrWalletNotFound
+		}
+		return err
+	}
+	wallet.Balance += campaign.RewardPoints
+	if err := tx.Save(&wallet).Error; err != nil {
+		return err
+	}
+
+	transaction := model.Transaction{
+		TenantID:    wallet.TenantID,
+		WalletID:    wallet.ID,
+		Type:        model.TransactionTypeCampaign,
+		Amount:      campaign.RewardPoints,
+		Description: fmt.Sprintf("活动奖励: %s", campaign.Name),
+		ReferenceID: rewardID,
+	}
+	return tx.Create(&transaction).Error
+}
+
+// usableCoupon returns a coupon of a user that is neither used nor expired
+func usableCoupon(db *gorm.DB, userID, couponID uint) (*model.Coupon, error) {
+	var coupon model.Coupon
+	if err := db.Where("id = ? AND user_id = ?", couponID, userID).First(&coupon).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCouponUnavailable
+		}
+		return nil, err
+	}
+	if coupon.UsedAt != nil || (coupon.ExpiresAt != nil && !coupon.ExpiresAt.After(time.Now())) {
+		return nil, ErrCouponUnavailable
+	}
+	return &coupon, nil
+}
+
+// redeemCoupon marks a coupon as used, guarded against concurrent purchases
+func redeemCoupon(db *gorm.DB, couponID uint) error {
+	result := db.Model(&model.Coupon{}).Where("id = ? AND used_at IS NULL", couponID).Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrCouponUnavailable
+	}
+	return nil
+}
+
+func (s *CampaignService) campaign(id uint) (*model.Campaign, error) {
+	var campaign model.Campaign
+	if err := s.db.First(&campaign, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCampaignNotFound
+		}
+		return nil, err
+	}
+	return &campaign, nil
+}
+
+// applyCampaignRequest validates a campaign request and copies it onto a campaign
+func (s *CampaignService) applyCampaignRequest(campaign *model.Campaign, req CampaignRequest) error {
+	switch req.Trigger {
+	case model.CampaignTriggerRecharge, model.CampaignTriggerFirstPurchase, model.CampaignTriggerCheckIn:
+	default:
+		return ErrInvalidCampaign
+	}
+	if req.MinAmount < 0 || (req.MinAmount > 0 && req.Trigger != model.CampaignTriggerRecharge) {
+		return ErrInvalidCampaign
+	}
+	if req.PerUserLimit < 0 || req.Budget < 0 || req.MaxRewards < 0 || req.CouponValidDays < 0 {
+		return ErrInvalidCampaign
+	}
+	if req.StartsAt != nil && req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+		return ErrInvalidCampaign
+	}
+
+	switch req.RewardType {
+	case model.CampaignRewardPoints, model.CampaignRewardBonus, model.CampaignRewardCoupon:
+		if req.RewardPoints <= 0 || req.RewardPoints > maxCampaignRewardPoints ||
+			req.RewardLotteryTypeID != 0 || req.RewardQuantity != 0 {
+			return ErrInvalidCampaign
+		}
+		if req.RewardType != model.CampaignRewardCoupon && req.CouponValidDays != 0 {
+			return ErrInvalidCampaign
+		}
+	case model.CampaignRewardFreeTicket:
+		if req.RewardPoints != 0 || req.CouponValidDays != 0 ||
+			req.RewardQuantity <= 0 || req.RewardQuantity > maxCampaignFreeTickets {
+			return ErrInvalidCampaign
+		}
+		if _, err := s.lotteryService.GetLotteryTypeByID(req.RewardLotteryTypeID); err != nil {
+			if errors.Is(err, ErrLotteryTypeNotFound) {
+				return ErrInvalidCampaign
+			}
+			return err
+		}
+	default:
+		return ErrInvalidCampaign
+	}
+
+	if req.SegmentID != nil {
+		if _, err := s.segmentService.GetSegment(*req.SegmentID); err != nil {
+			if errors.Is(err, ErrSegmentNotFound) {
+				return ErrInvalidCampaign
+			}
+			return err
+		}
+	}
+
+	campaign.Name = req.Name
+	campaign.Description = req.Description
+	campaign.Trigger = req.Trigger
+	campaign.SegmentID = req.SegmentID
+	campaign.StartsAt = utcTime(req.StartsAt)
+	campaign.EndsAt = utcTime(req.EndsAt)
+	campaign.Enabled = req.Enabled == nil || *req.Enabled
+	campaign.MinAmount = req.MinAmount
+	campaign.RewardType = req.RewardType
+	campaign.RewardPoints = req.RewardPoints
+	campaign.RewardLotteryTypeID = req.RewardLotteryTypeID
+	campaign.RewardQuantity = req.RewardQuantity
+	campaign.CouponValidDays = req.CouponValidDays
+	campaign.PerUserLimit = req.PerUserLimit
+	campaign.Budget = req.Budget
+	campaign.MaxRewards = req.MaxRewards
+	return nil
+}
+
+// campaignStatus reports where a campaign stands at the given time
+func campaignStatus(campaign *model.Campaign, now time.Time) string {
+	switch {
+	case !campaign.Enabled:
+		return "disabled"
+	case campaign.EndsAt != nil && !campaign.EndsAt.After(now):
+		return "ended"
+	case campaign.MaxRewards > 0 && campaign.RewardCount >= campaign.MaxRewards,
+		campaign.Budget > 0 && campaign.Spent >= campaign.Budget,
+		campaign.Budget > 0 && campaign.RewardType != model.CampaignRewardFreeTicket &&
+			campaign.Budget-campaign.Spent < campaign.RewardPoints:
+		return "exhausted"
+	case campaign.StartsAt != nil && campaign.StartsAt.After(now):
+		return "scheduled"
+	default:
+		return "active"
+	}
+}
+
+func toCampaignResponse(campaign *model.Campaign, now time.Time) CampaignResponse {
+	return CampaignResponse{
+		ID:                  campaign.ID,
+		Name:                campaign.Name,
+		Description:         campaign.Description,
+		Trigger:             campaign.Trigger,
+		SegmentID:           campaign.SegmentID,
+		StartsAt:            campaign.StartsAt,
+		EndsAt:              campaign.EndsAt,
+		Enabled:             campaign.Enabled,
+		Status:              campaignStatus(campaign, now),
+		MinAmount:           campaign.MinAmount,
+		RewardType:          campaign.RewardType,
+		RewardPoints:        campaign.RewardPoints,
+		RewardLotteryTypeID: campaign.RewardLotteryTypeID,
+		RewardQuantity:      campaign.RewardQuantity,
+		CouponValidDays:     campaign.CouponValidDays,
+		PerUserLimit:        campaign.PerUserLimit,
+		Budget:              campaign.Budget,
+		MaxRewards:          campaign.MaxRewards,
+		Spent:               campaign.Spent,
+		RewardCount:         campaign.RewardCount,
+		CreatedAt:           campaign.CreatedAt,
+		UpdatedAt:           campaign.UpdatedAt,
+	}
+}
+
+// utcTime returns t in UTC, the zone times are stored in, so windows given
+// with any offset compare correctly
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
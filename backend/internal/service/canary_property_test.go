@@ -0,0 +1,96 @@
+package service
+
+import (
+	"testing"
+
+	"scratch-lottery/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// setupCanaryTest creates a canary service over a multi-tenant database
+func setupCanaryTest(t *testing.T) (*gorm.DB, *CanaryService) {
+	db := setupTenantTestDB(t)
+	if err := db.AutoMigrate(&model.PaymentOrder{}, &model.TicketAreaScratch{}, &model.ExchangeGift{},
+		&model.CardKeyReveal{}, &model.WalletBalanceSnapshot{}, &model.TicketHistory{}, &model.TicketTransfer{},
+		&model.StockReservation{}, &model.ScratchConfirmation{}, &model.PurchaseRequestRecord{},
+		&model.VoucherClaimFailure{}, &model.PrizeClaim{}, &model.CheckIn{}, &model.OnboardingStep{}, &model.Refund{}, &model.ScratchStreak{},
+		&model.CampaignReward{}, &model.Coupon{}, &model.Notification{}, &model.WalletReconciliation{}, &model.PaymentReconciliation{},
+		&model.UserBadgeCounter{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	walletService := NewWalletService(db)
+	lotteryService := NewLotteryService(db, testEncryptionKey)
+	canary := NewCanaryService(db, NewTenantService(db), NewSandboxService(db, 1000), lotteryService,
+		NewPurchaseService(db, lotteryService, walletService, nil, nil, nil),
+		NewScratchService(db, lotteryService, walletService, nil, nil),
+		NewExchangeService(db, walletService),
+		NewPaymentService(db, NewAdminService(db, walletService), walletService, nil),
+		walletService)
+	return db, canary
+}
+
+// The canary creates its sandbox tenant on the first run and passes every
+// step on repeated runs, without touching other tenants
+func TestCanaryRunPasses(t *testing.T) {
+	db, canary := setupCanaryTest(t)
+
+	for run := 1; run <= 2; run++ {
+		report, err := canary.Run(0)
+		if err != nil {
+			t.Fatalf("Run %d failed: %v", run, err)
+		}
+		if !report.Passed || len(report.Steps) != 6 {
+			t.Fatalf("Run %d: expected every step passed, got %+v", run, report.Steps)
+		}
+		for _, step := range report.Steps {
+			if step.Status != CanaryStepPassed {
+				t.Errorf("Run %d: step %s %s: %s", run, step.Name, step.Status, step.Error)
+			}
+		}
+	}
+
+	var tenant model.Tenant
+	if err := db.Where("slug = ?", CanaryTenantSlug).First(&tenant).Error; err != nil || !tenant.Sandbox {
+		t.Fatalf("Expected a canary sandbox tenant, got %+v (err %v)", tenant, err)
+	}
+	var elsewhere int64
+	db.Model(&model.Ticket{}).Where("tenant_id <> ?", tenant.ID).Count(&elsewhere)
+	if elsewhere != 0 {
+		t.Errorf("Expected no tickets outside the canary tenant, got %d", elsewhere)
+	}
+	var runs int64
+	db.Model(&model.AdminLog{}).Where("tenant_id = ? AND action = ?", 1, "run_canary").Count(&runs)
+	if runs != 2 {
+		t.Errorf("Expected both runs logged on the default tenant, got %d", runs)
+	}
+}
+
+// A canary tenant that is not a sandbox fails the setup, and the steps after
+// it are skipped
+func TestCanaryRefusesRealTenant(t *testing.T) {
+	db, canary := setupCanaryTest(t)
+	db.Create(&model.Tenant{Name: "Canary Lottery", Slug: CanaryTenantSlug, Status: model.TenantStatusActive})
+
+	report, err := canary.Run(7)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if report.Passed {
+		t.Fatal("Expected the run to fail")
+	}
+	if setup := report.Steps[0]; setup.Status != CanaryStepFailed || setup.Error != ErrCanaryTenantNotSandbox.Error() {
+		t.Errorf("Expected the setup failed, got %+v", setup)
+	}
+	for _, step := range report.Steps[1:] {
+		if step.Status != CanaryStepSkipped {
+			t.Errorf("Expected step %s skipped, got %s", step.Name, step.Status)
+		}
+	}
+	var users int64
+	db.Model(&model.User{}).Where("linuxdo_id = ?", "canary").Count(&users)
+	if users != 0 {
+		t.Errorf("Expected no canary user in a real tenant, got %d", users)
+	}
+}
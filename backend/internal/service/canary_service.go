@@ -0,0 +1,349 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// The canary runs in a sandbox tenant of its own, as a dedicated user, with a
+// lottery type and product of its own, so it never touches real players,
+// pools or stock and is left out of statistics, webhooks and alerts
+const (
+	CanaryTenantSlug = "canary"
+	canaryName       = "Canary"
+	canaryLinuxdoID  = "canary"
+	canaryPrice      = 10
+	canaryPrize      = 5  // every canary ticket wins this, so the payout is checked too
+	canaryTickets    = 10 // tickets of each canary pool
+	canaryRecharge   = 1  // yuan
+)
+
+// Canary step results
+const (
+	CanaryStepPassed  = "passed"
+	CanaryStepFailed  = "failed"
+	CanaryStepSkipped = "skipped" // not run, since an earlier step failed
+)
+
+var (
+	ErrCanaryRunning          = errors.New("canary checks are already running")
+	ErrCanaryTenantNotSandbox = errors.New("canary tenant is not a sandbox")
+)
+
+// CanaryStep is the result of one step of a canary run
+type CanaryStep struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// CanaryReport is the result of a canary run
+type CanaryReport struct {
+	Passed     bool         `json:"passed"`
+	TenantID   uint         `json:"tenant_id,omitempty"`
+	Steps      []CanaryStep `json:"steps"`
+	StartedAt  time.Time    `json:"started_at"`
+	DurationMs int64        `json:"duration_ms"`
+}
+
+// CanaryService runs the critical paths end to end after a deploy: creating
+// a pool, buying and scratching a ticket, redeeming a card key and paying a
+// recharge, and reports which of them work
+type CanaryService struct {
+	db        *gorm.DB
+	tenants   *TenantService
+	sandbox   *SandboxService
+	lotteries *LotteryService
+	purchases *PurchaseService
+	scratches *ScratchService
+	exchanges *ExchangeService
+	payments  *PaymentService
+	wallets   *WalletService
+	running   sync.Mutex
+}
+
+// NewCanaryService creates a new canary service
+func NewCanaryService(db *gorm.DB, tenants *TenantService, sandbox *SandboxService, lotteries *LotteryService,
+	purchases *PurchaseService, scratches *ScratchService, exchanges *ExchangeService, payments *PaymentService,
+	wallets *WalletService) *CanaryService {
+	return &CanaryService{
+		db:        db,
+		tenants:   tenants,
+		sandbox:   sandbox,
+		lotteries: lotteries,
+		purchases: purchases,
+		scratches: scratches,
+		exchanges: exchanges,
+		payments:  payments,
+		wallets:   wallets,
+	}
+}
+
+// canaryRun carries what the steps of one run create for the next ones
+type canaryRun struct {
+	s             *CanaryService
+	adminID       uint
+	tenantID      uint
+	userID        uint
+	productID     uint
+	lotteryTypeID uint
+	poolID        uint
+	ticketID      uint
+	balance       int // wallet balance after the last step that changed it
+}
+
+// Run runs the canary checks. A step only runs once the steps before it have
+// passed; the rest are reported as skipped. The run is logged on the default
+// tenant, by the system when adminID is 0.
+func (s *CanaryService) Run(adminID uint) (*CanaryReport, error) {
+	if !s.running.TryLock() {
+		return nil, ErrCanaryRunning
+	}
+	defer s.running.Unlock()
+
+	run := &canaryRun{s: s, adminID: adminID}
+	steps := []struct {
+		name string
+		run  func() error
+	}{
+		{"setup", run.setup},
+		{"create_pool", run.createPool},
+		{"purchase", run.purchase},
+		{"scratch", run.scratch},
+		{"redeem", run.redeem},
+		{"payment", run.payment},
+	}
+
+	report := CanaryReport{Passed: true, StartedAt: time.Now()}
+	for _, step := range steps {
+		result := CanaryStep{Name: step.name, Status: CanaryStepSkipped}
+		if report.Passed {
+			started := time.Now()
+			if err := step.run(); err != nil {
+				result.Status, result.Error = CanaryStepFailed, err.Error()
+				report.Passed = false
+			} else {
+				result.Status = CanaryStepPassed
+			}
+			result.DurationMs = time.Since(started).Milliseconds()
+		}
+		report.Steps = append(report.Steps, result)
+	}
+	report.TenantID = run.tenantID
+	report.DurationMs = time.Since(report.StartedAt).Milliseconds()
+
+	details, _ := json.Marshal(report)
+	adminLog := model.AdminLog{
+		AdminID:    adminID,
+		Action:     "run_canary",
+		TargetType: "tenant",
+		TargetID:   run.tenantID,
+		Details:    string(details),
+	}
+	if err := repository.ScopeTenant(s.db, repository.DefaultTenantID).Create(&adminLog).Error; err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// setup makes sure the canary tenant and user exist and resets the tenant,
+// which returns the canary wallet to the sandbox starting balance
+func (r *canaryRun) setup() error {
+	var tenant model.Tenant
+	err := r.s.db.Where("slug = ?", CanaryTenantSlug).First(&tenant).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		name, slug, sandbox := canaryName, CanaryTenantSlug, true
+		created, err := r.s.tenants.CreateTenant(r.adminID, TenantRequest{Name: &name, Slug: &slug, Sandbox: &sandbox})
+		if err != nil {
+			return err
+		}
+		tenant = *created
+	case err != nil:
+		return err
+	case !tenant.Sandbox:
+		return ErrCanaryTenantNotSandbox
+	}
+	r.tenantID = tenant.ID
+	db := repository.ScopeTenant(r.s.db, r.tenantID)
+
+	user := model.User{LinuxdoID: canaryLinuxdoID, Username: canaryName, Role: "user"}
+	if err := db.Where("linuxdo_id = ?", canaryLinuxdoID).FirstOrCreate(&user).Error; err != nil {
+		return err
+	}
+	r.userID = user.ID
+	wallet := model.Wallet{UserID: user.ID}
+	if err := db.Where("user_id = ?", user.ID).FirstOrCreate(&wallet).Error; err != nil {
+		return err
+	}
+
+	// Keys of earlier runs go, so the redeem step gets the key it imports
+	var product model.Product
+	if err := db.Where("name = ?", canaryName).Limit(1).Find(&product).Error; err != nil {
+		return err
+	}
+	if product.ID != 0 {
+		r.productID = product.ID
+		if err := r.s.db.Unscoped().Where("product_id = ?", product.ID).Delete(&model.CardKey{}).Error; err != nil {
+			return err
+		}
+	}
+
+	reset, err := r.s.sandbox.Reset(0, r.tenantID)
+	if err != nil {
+		return err
+	}
+	r.balance = reset.StartingBalance
+	return nil
+}
+
+// createPool makes sure the canary lottery type exists and replaces its pool
+// with a new one
+func (r *canaryRun) createPool() error {
+	lotteries := r.s.lotteries.ForTenant(r.tenantID)
+	var lotteryType model.LotteryType
+	if err := repository.ScopeTenant(r.s.db, r.tenantID).Where("name = ?", canaryName).
+		Limit(1).Find(&lotteryType).Error; err != nil {
+		return err
+	}
+	r.lotteryTypeID = lotteryType.ID
+	if lotteryType.ID == 0 {
+		created, err := lotteries.CreateLotteryType(CreateLotteryTypeRequest{
+			Name:        canaryName,
+			Description: "发布后自检专用",
+			Price:       canaryPrice,
+			MaxPrize:    canaryPrize,
+			PrizeLevels: []PrizeLevelInput{{Level: 1, Name: "自检奖", PrizeAmount: canaryPrize, Quantity: canaryTickets}},
+		})
+		if err != nil {
+			return err
+		}
+		r.lotteryTypeID = created.ID
+	}
+
+	if err := r.s.db.Model(&model.PrizePool{}).
+		Where("lottery_type_id = ? AND status <> ?", r.lotteryTypeID, model.PrizePoolStatusClosed).
+		Update("status", model.PrizePoolStatusClosed).Error; err != nil {
+		return err
+	}
+	pool, err := lotteries.CreatePrizePool(CreatePrizePoolRequest{LotteryTypeID: r.lotteryTypeID, TotalTickets: canaryTickets})
+	if err != nil {
+		return err
+	}
+	r.poolID = pool.ID
+	return nil
+}
+
+// purchase buys a ticket from the new pool
+func (r *canaryRun) purchase() error {
+	resp, err := r.s.purchases.ForTenant(r.tenantID).PurchaseTickets(r.userID, PurchaseRequest{
+		LotteryTypeID: r.lotteryTypeID,
+		Quantity:      1,
+	})
+	if err != nil {
+		return err
+	}
+	if len(resp.Tickets) != 1 {
+		return fmt.Errorf("bought %d tickets instead of 1", len(resp.Tickets))
+	}
+	if resp.Balance != r.balance-resp.Cost {
+		return fmt.Errorf("balance %d after paying %d, expected %d", resp.Balance, resp.Cost, r.balance-resp.Cost)
+	}
+	r.ticketID, r.balance = resp.Tickets[0].ID, resp.Balance
+
+	var ticket model.Ticket
+	if err := r.s.db.Select("prize_pool_id").First(&ticket, r.ticketID).Error; err != nil {
+		return err
+	}
+	if ticket.PrizePoolID != r.poolID {
+		return fmt.Errorf("ticket drawn from pool %d instead of the new pool %d", ticket.PrizePoolID, r.poolID)
+	}
+	return nil
+}
+
+// scratch scratches the ticket and checks its prize is paid out
+func (r *canaryRun) scratch() error {
+	resp, err := r.s.scratches.ForTenant(r.tenantID).ScratchTicket(r.userID, r.ticketID, "")
+	if err != nil {
+		return err
+	}
+	if resp.Status != model.TicketStatusScratched || resp.PrizeAmount != canaryPrize {
+		return fmt.Errorf("ticket %s with prize %d, expected scratched with prize %d", resp.Status, resp.PrizeAmount, canaryPrize)
+	}
+	expected := r.balance + resp.PrizeAmount + resp.StreakBonus
+	if resp.NewBalance != expected {
+		return fmt.Errorf("balance %d after the payout, expected %d", resp.NewBalance, expected)
+	}
+	r.balance = resp.NewBalance
+	return nil
+}
+
+// redeem imports a test card key into the canary product and redeems it
+func (r *canaryRun) redeem() error {
+	exchanges := r.s.exchanges.ForTenant(r.tenantID)
+	if r.productID == 0 {
+		product, err := exchanges.CreateProduct(CreateProductRequest{
+			Name:        canaryName,
+			Description: "发布后自检专用",
+			Price:       canaryPrice,
+		})
+		if err != nil {
+			return err
+		}
+		r.productID = product.ID
+	}
+
+	key := fmt.Sprintf("CANARY-%d", time.Now().UnixNano())
+	if _, err := exchanges.ImportCardKeys(r.productID, []string{key}); err != nil {
+		return err
+	}
+	resp, err := exchanges.Redeem(r.userID, r.productID)
+	if err != nil {
+		return err
+	}
+	if resp.CardKey != key {
+		return errors.New("redeemed a different card key than the one imported")
+	}
+	if resp.Balance != r.balance-resp.Cost {
+		return fmt.Errorf("balance %d after paying %d, expected %d", resp.Balance, resp.Cost, r.balance-resp.Cost)
+	}
+	r.balance = resp.Balance
+	return nil
+}
+
+// payment pays a simulated recharge and checks the points are credited
+func (r *canaryRun) payment() error {
+	payments := r.s.payments.ForTenant(r.tenantID)
+	recharge, err := payments.CreateRechargeOrder(r.userID, RechargeRequest{Amount: canaryRecharge})
+	if err != nil {
+		return err
+	}
+	if !recharge.Sandbox {
+		return errors.New("recharge was not simulated")
+	}
+	order, err := payments.GetOrderByNo(recharge.OrderNo)
+	if err != nil {
+		return err
+	}
+	if order.Status != "paid" {
+		return fmt.Errorf("order %s, expected paid", order.Status)
+	}
+	wallet, err := r.s.wallets.ForTenant(r.tenantID).GetWalletByUserID(r.userID)
+	if err != nil {
+		return err
+	}
+	if wallet.Balance != r.balance+recharge.Points {
+		return fmt.Errorf("balance %d after the recharge, expected %d", wallet.Balance, r.balance+recharge.Points)
+	}
+	r.balance = wallet.Balance
+	return nil
+}
@@ -0,0 +1,390 @@
+package service
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"scratch-lottery/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// maxCardKeyImportErrors is the number of row errors an import report lists
+const maxCardKeyImportErrors = 100
+
+// maxXLSXPartSize caps the uncompressed size of a part read from an XLSX
+// file, so a small upload cannot expand without bound
+const maxXLSXPartSize = 64 << 20
+
+var (
+	ErrUnsupportedImportFile = errors.New("unsupported card key import file")
+	ErrInvalidImportFile     = errors.New("invalid card key import file")
+)
+
+// cardKeyHeaders are first-row values taken for a column header, not a key
+var cardKeyHeaders = map[string]bool{"card_key": true, "card_keys": true, "key": true, "卡密": true}
+
+// CardKeyImportError reports a row of an import file that was not imported
+type CardKeyImportError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// CardKeyImportReport summarises the import of a card key file
+type CardKeyImportReport struct {
+	Imported   int                  `json:"imported"`
+	Duplicates int                  `json:"duplicates"`  // Keys already in stock or repeated in the file
+	ErrorCount int                  `json:"error_count"` // Rows refused
+	Errors     []CardKeyImportError `json:"errors"`      // The first refused rows
+}
+
+func (r *CardKeyImportReport) refuse(row int, message string) {
+	r.ErrorCount++
+	if len(r.Errors) < maxCardKeyImportErrors {
+		r.Errors = append(r.Errors, CardKeyImportError{Row: row, Error: message})
+	}
+}
+
+// cardKeyRow is a key read from the first column of an import file
+type cardKeyRow struct {
+	row int // 1-based row number in the file
+	key string
+}
+
+// ImportCardKeysFile imports the card keys in the first column of an
+// uploaded CSV or XLSX file, picked by the file name's extension. A header
+// row is skipped, keys the product already has or the file repeats are
+// skipped, and rows with an invalid key are reported instead of failing the
+// import.
+func (s *ExchangeService) ImportCardKeysFile(productID uint, filename string, file io.ReaderAt, size int64) (*CardKeyImportReport, error) {
+	var rows []cardKeyRow
+	var err error
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".csv", ".txt":
+		rows, err = readCSVCardKeys(io.NewSectionReader(file, 0, size))
+	case ".xlsx":
+		rows, err = readXLSXCardKeys(file, size)
+	default:
+		return nil, ErrUnsupportedImportFile
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) > 0 && cardKeyHeaders[strings.ToLower(rows[0].key)] {
+		rows = rows[1:]
+	}
+
+	var product model.Product
+	if err := s.db.First(&product, productID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrProductNotFound
+		}
+		return nil, err
+	}
+
+	report := &CardKeyImportReport{Errors: []CardKeyImportError{}}
+	seen := make(map[string]bool, len(rows))
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		batch := make([]cardKeyRow, 0, cardKeyImportBatch)
+		flush := func() error {
+			if len(batch) == 0 {
+				return nil
+			}
+			imported, err := insertNewCardKeys(tx, productID, batch)
+			if err != nil {
+				return err
+			}
+			report.Imported += imported
+			report.Duplicates += len(batch) - imported
+			batch = batch[:0]
+			return nil
+		}
+		for _, row := range rows {
+			switch {
+			case row.key == "":
+				continue
+			case len(row.key) > maxCardKeyLength:
+				report.refuse(row.row, "卡密过长")
+				continue
+			case seen[row.key]:
+				report.Duplicates++
+				continue
+			}
+			seen[row.key] = true
+			batch = append(batch, row)
+			if len(batch) == cardKeyImportBatch {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+		if report.Imported == 0 {
+			return nil
+		}
+
+		if err := tx.Model(&product).Update("stock", gorm.Expr("stock + ?", report.Imported)).Error; err != nil {
+			return err
+		}
+		if product.Status == model.ProductStatusSoldOut {
+			return tx.Model(&product).Update("status", model.ProductStatusAvailable).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// insertNewCardKeys inserts the keys of a batch the product does not have yet
+// in one statement and returns how many it inserted
+func insertNewCardKeys(tx *gorm.DB, productID uint, batch []cardKeyRow) (int, error) {
+	keys := make([]string, len(batch))
+	for i, row := range batch {
+		keys[i] = row.key
+	}
+	var existing []string
+	if err := tx.Model(&model.CardKey{}).
+		Where("product_id = ? AND key_content IN ?", productID, keys).
+		Pluck("key_content", &existing).Error; err != nil {
+		return 0, err
+	}
+	known := make(map[string]bool, len(existing))
+	for _, key := range existing {
+		known[key] = true
+	}
+
+	cardKeys := make([]model.CardKey, 0, len(batch))
+	for _, key := range keys {
+		if !known[key] {
+			cardKeys = append(cardKeys, model.CardKey{
+				ProductID:  productID,
+				KeyContent: key,
+				Status:     model.CardKeyStatusAvailable,
+			})
+		}
+	}
+	if len(cardKeys) == 0 {
+		return 0, nil
+	}
+	if err := tx.Create(&cardKeys).Error; err != nil {
+		return 0, err
+	}
+	return len(cardKeys), nil
+}
+
+// readCSVCardKeys reads the first column of a CSV file. Rows may have any
+// number of columns.
+func readCSVCardKeys(r io.Reader) ([]cardKeyRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	var rows []cardKeyRow
+	for n := 1; ; n++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, ErrInvalidImportFile
+		}
+		key := ""
+		if len(record) > 0 {
+			key = strings.TrimSpace(strings.TrimPrefix(record[0], "\ufeff"))
+		}
+		rows = append(rows, cardKeyRow{row: n, key: key})
+	}
+}
+
+// readXLSXCardKeys reads the first column of the first worksheet of an XLSX
+// workbook
+func readXLSXCardKeys(file io.ReaderAt, size int64) ([]cardKeyRow, error) {
+	archive, err := zip.NewReader(file, size)
+	if err != nil {
+		return nil, ErrInvalidImportFile
+	}
+	parts := make(map[string]*zip.File, len(archive.File))
+	for _, part := range archive.File {
+		parts[part.Name] = part
+	}
+
+	sheetPath, err := firstXLSXSheet(parts)
+	if err != nil {
+		return nil, err
+	}
+	var sharedStrings []string
+	if part := parts["xl/sharedStrings.xml"]; part != nil {
+		if sharedStrings, err = readXLSXSharedStrings(part); err != nil {
+			return nil, err
+		}
+	}
+	sheet := parts[sheetPath]
+	if sheet == nil {
+		return nil, ErrInvalidImportFile
+	}
+	return readXLSXSheet(sheet, sharedStrings)
+}
+
+// firstXLSXSheet returns the path of the first worksheet of a workbook
+func firstXLSXSheet(parts map[string]*zip.File) (string, error) {
+	var workbook struct {
+		Sheets []struct {
+			RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodeXLSXPart(parts["xl/workbook.xml"], &workbook); err != nil || len(workbook.Sheets) == 0 {
+		return "xl/worksheets/sheet1.xml", nil
+	}
+	if err := decodeXLSXPart(parts["xl/_rels/workbook.xml.rels"], &rels); err != nil {
+		return "xl/worksheets/sheet1.xml", nil
+	}
+	for _, rel := range rels.Relationships {
+		if rel.ID != workbook.Sheets[0].RelID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+	return "", ErrInvalidImportFile
+}
+
+// readXLSXSharedStrings reads the shared string table cells refer to by index
+func readXLSXSharedStrings(part *zip.File) ([]string, error) {
+	var table struct {
+		Items []struct {
+			Text string `xml:"t"`
+			Runs []struct {
+				Text string `xml:"t"`
+			} `xml:"r"`
+		} `xml:"si"`
+	}
+	if err := decodeXLSXPart(part, &table); err != nil {
+		return nil, err
+	}
+	sharedStrings := make([]string, len(table.Items))
+	for i, item := range table.Items {
+		text := item.Text
+		for _, run := range item.Runs {
+			text += run.Text
+		}
+		sharedStrings[i] = text
+	}
+	return sharedStrings, nil
+}
+
+// xlsxCell is a cell of a worksheet
+type xlsxCell struct {
+	Ref    string `xml:"r,attr"`
+	Type   string `xml:"t,attr"`
+	Value  string `xml:"v"`
+	Inline struct {
+		Text string `xml:"t"`
+		Runs []struct {
+			Text string `xml:"t"`
+		} `xml:"r"`
+	} `xml:"is"`
+}
+
+// readXLSXSheet reads the cells of column A of a worksheet, streaming it
+func readXLSXSheet(part *zip.File, sharedStrings []string) ([]cardKeyRow, error) {
+	reader, err := part.Open()
+	if err != nil {
+		return nil, ErrInvalidImportFile
+	}
+	defer reader.Close()
+
+	decoder := xml.NewDecoder(io.LimitReader(reader, maxXLSXPartSize))
+	var rows []cardKeyRow
+	row := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, ErrInvalidImportFile
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "row":
+			row++
+			for _, attr := range start.Attr {
+				if attr.Name.Local == "r" {
+					if n, err := strconv.Atoi(attr.Value); err == nil {
+						row = n
+					}
+				}
+			}
+		case "c":
+			var cell xlsxCell
+			if err := decoder.DecodeElement(&cell, &start); err != nil {
+				return nil, ErrInvalidImportFile
+			}
+			column := strings.TrimRight(cell.Ref, "0123456789")
+			if column != "A" && cell.Ref != "" {
+				continue
+			}
+			key, err := xlsxCellText(cell, sharedStrings)
+			if err != nil {
+				return nil, err
+			}
+			rows = append(rows, cardKeyRow{row: row, key: strings.TrimSpace(key)})
+		}
+	}
+}
+
+// xlsxCellText returns the text of a cell
+func xlsxCellText(cell xlsxCell, sharedStrings []string) (string, error) {
+	switch cell.Type {
+	case "s":
+		index, err := strconv.Atoi(cell.Value)
+		if err != nil || index < 0 || index >= len(sharedStrings) {
+			return "", ErrInvalidImportFile
+		}
+		return sharedStrings[index], nil
+	case "inlineStr":
+		text := cell.Inline.Text
+		for _, run := range cell.Inline.Runs {
+			text += run.Text
+		}
+		return text, nil
+	default:
+		return cell.Value, nil
+	}
+}
+
+// decodeXLSXPart decodes an XML part of an XLSX file into v
+func decodeXLSXPart(part *zip.File, v interface{}) error {
+	if part == nil {
+		return ErrInvalidImportFile
+	}
+	reader, err := part.Open()
+	if err != nil {
+		return ErrInvalidImportFile
+	}
+	defer reader.Close()
+	if err := xml.NewDecoder(io.LimitReader(reader, maxXLSXPartSize)).Decode(v); err != nil {
+		return ErrInvalidImportFile
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+)
+
+// buildXLSX writes a workbook whose first sheet has keys in column A and a
+// note in column B. Even rows use the shared string table, odd rows inline
+// strings.
+func buildXLSX(t *testing.T, keys []string) []byte {
+	var shared, sheet strings.Builder
+	sharedCount := 0
+	for i, key := range keys {
+		row := i + 1
+		if i%2 == 0 {
+			fmt.Fprintf(&shared, "<si><t>%s</t></si>", key)
+			fmt.Fprintf(&sheet, `<row r="%d"><c r="A%d" t="s"><v>%d</v></c><c r="B%d" t="inlineStr"><is><t>note</t></is></c></row>`, row, row, sharedCount, row)
+			sharedCount++
+		} else {
+			fmt.Fprintf(&sheet, `<row r="%d"><c r="A%d" t="inlineStr"><is><t>%s</t></is></c></row>`, row, row, key)
+		}
+	}
+
+	parts := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Keys" sheetId="1" r:id="rId7"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId7" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/keys.xml"/></Relationships>`,
+		"xl/sharedStrings.xml":   `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` + shared.String() + `</sst>`,
+		"xl/worksheets/keys.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` + sheet.String() + `</sheetData></worksheet>`,
+	}
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for name, content := range parts {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to build workbook: %v", err)
+		}
+		_, _ = w.Write([]byte(content))
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatalf("Failed to build workbook: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// File imports: CSV and XLSX uploads import every new key once, skip keys
+// the product already has or the file repeats, and report rows with keys
+// that are too long instead of failing the import.
+func TestCardKeyFileImportSkipsDuplicates(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("new keys are imported once", prop.ForAll(
+		func(count, existing, repeatEvery int, xlsx bool) bool {
+			db := setupExchangeTestDB(t)
+			exchangeService := NewExchangeService(db, NewWalletService(db))
+			product := model.Product{Name: "Uploaded", Price: 10, Status: model.ProductStatusSoldOut}
+			db.Create(&product)
+			existing = min(existing, count)
+			if existing > 0 {
+				keys := make([]string, existing)
+				for i := range keys {
+					keys[i] = fmt.Sprintf("KEY-%05d", i)
+				}
+				if _, err := exchangeService.ImportCardKeys(product.ID, keys); err != nil {
+					t.Logf("ImportCardKeys failed: %v", err)
+					return false
+				}
+			}
+
+			keys := []string{"卡密"}
+			repeats := 0
+			for i := 0; i < count; i++ {
+				keys = append(keys, fmt.Sprintf("KEY-%05d", i))
+				if i%repeatEvery == 0 {
+					keys = append(keys, fmt.Sprintf("KEY-%05d", i))
+					repeats++
+				}
+			}
+			tooLongRow := len(keys) + 1
+			keys = append(keys, strings.Repeat("x", maxCardKeyLength+1))
+
+			var data []byte
+			filename := "keys.csv"
+			if xlsx {
+				data, filename = buildXLSX(t, keys), "keys.XLSX"
+			} else {
+				data = []byte(strings.Join(keys, ",extra\r\n") + "\r\n")
+			}
+			report, err := exchangeService.ImportCardKeysFile(product.ID, filename, bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				t.Logf("ImportCardKeysFile failed: %v", err)
+				return false
+			}
+			if report.Imported != count-existing || report.Duplicates != existing+repeats ||
+				report.ErrorCount != 1 || report.Errors[0].Row != tooLongRow {
+				t.Logf("Unexpected report %+v for %d keys, %d existing, %d repeats", report, count, existing, repeats)
+				return false
+			}
+
+			var stored int64
+			db.Model(&model.CardKey{}).Where("product_id = ?", product.ID).Count(&stored)
+			db.First(&product, product.ID)
+			if int(stored) != count || product.Stock != count || (count > 0) != (product.Status == model.ProductStatusAvailable) {
+				t.Logf("Expected %d stored keys, got %d with product %+v", count, stored, product)
+				return false
+			}
+			return true
+		},
+		gen.IntRange(0, cardKeyImportBatch+7),
+		gen.IntRange(0, 30),
+		gen.IntRange(1, 40),
+		gen.Bool(),
+	))
+
+	properties.TestingRun(t)
+}
+
+func TestCardKeyFileImportRejectsOtherFiles(t *testing.T) {
+	db := setupExchangeTestDB(t)
+	exchangeService := NewExchangeService(db, NewWalletService(db))
+	product := model.Product{Name: "Uploaded", Price: 10}
+	db.Create(&product)
+
+	data := []byte("KEY-1\n")
+	if _, err := exchangeService.ImportCardKeysFile(product.ID, "keys.xls", bytes.NewReader(data), int64(len(data))); err != ErrUnsupportedImportFile {
+		t.Errorf("Expected ErrUnsupportedImportFile, got %v", err)
+	}
+	if _, err := exchangeService.ImportCardKeysFile(product.ID, "keys.xlsx", bytes.NewReader(data), int64(len(data))); err != ErrInvalidImportFile {
+		t.Errorf("Expected ErrInvalidImportFile, got %v", err)
+	}
+	if _, err := exchangeService.ImportCardKeysFile(product.ID+1, "keys.csv", bytes.NewReader(data), int64(len(data))); err != ErrProductNotFound {
+		t.Errorf("Expected ErrProductNotFound, got %v", err)
+	}
+}
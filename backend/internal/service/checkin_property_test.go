@@ -0,0 +1,111 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/pkg/biztime"
+)
+
+// Daily check-in: each check-in credits the daily points as a check-in
+// transaction, consecutive days grow the streak and pay its bonus, a second
+// check-in the same day is refused, and a missed day restarts the streak.
+func TestCheckinStreakRewards(t *testing.T) {
+	db, campaigns, userIDs := setupCampaignTest(t, 1)
+	checkins := campaignCheckins(db, campaigns)
+	userID := userIDs[0]
+
+	if _, err := checkins.UpdateRules(1, CheckinRules{DailyPoints: 5, StreakRules: []StreakRule{{Days: 3, Points: 30}, {Days: 2, Points: 2, Repeat: true}}}); err != nil {
+		t.Fatalf("UpdateRules failed: %v", err)
+	}
+	rules, err := checkins.GetRules()
+	if err != nil || rules.DailyPoints != 5 || len(rules.StreakRules) != 2 || rules.StreakRules[0].Days != 2 {
+		t.Fatalf("Expected the stored schedule ordered by days, got %+v (err %v)", rules, err)
+	}
+
+	// Yesterday's check-in continues into today
+	yesterday := time.Now().AddDate(0, 0, -1).Format(streakDateLayout)
+	db.Create(&model.CheckIn{UserID: userID, Date: yesterday, StreakDays: 2})
+	status, err := checkins.GetStatus(userID)
+	if err != nil || status.CheckedInToday || status.CurrentStreak != 2 || status.NextReward == nil || status.NextReward.Days != 3 || status.NextReward.Points != 30 {
+		t.Fatalf("Unexpected status before checking in: %+v (err %v)", status, err)
+	}
+
+	var wallet model.Wallet
+	db.Where("user_id = ?", userID).First(&wallet)
+	result, err := checkins.CheckIn(userID)
+	if err != nil {
+		t.Fatalf("CheckIn failed: %v", err)
+	}
+	if result.StreakDays != 3 || result.Points != 5 || result.Bonus != 30 || result.Balance != wallet.Balance+35 {
+		t.Errorf("Expected day 3 to pay 5 + 30 points, got %+v", result)
+	}
+	if _, err := checkins.CheckIn(userID); err != ErrAlreadyCheckedIn {
+		t.Errorf("Expected ErrAlreadyCheckedIn, got %v", err)
+	}
+
+	var credited int64
+	db.Model(&model.Transaction{}).Where("type = ?", model.TransactionTypeCheckin).Select("COALESCE(SUM(amount), 0)").Scan(&credited)
+	if credited != 35 {
+		t.Errorf("Expected 35 points in check-in transactions, got %d", credited)
+	}
+	status, err = checkins.GetStatus(userID)
+	if err != nil || !status.CheckedInToday || status.CurrentStreak != 3 || status.NextReward == nil || status.NextReward.Days != 4 || status.NextReward.Points != 2 {
+		t.Errorf("Unexpected status after checking in: %+v (err %v)", status, err)
+	}
+
+	// A missed day restarts the streak
+	db.Where("1 = 1").Delete(&model.CheckIn{})
+	db.Create(&model.CheckIn{UserID: userID, Date: time.Now().AddDate(0, 0, -2).Format(streakDateLayout), StreakDays: 7})
+	if result, err := checkins.CheckIn(userID); err != nil || result.StreakDays != 1 || result.Bonus != 0 {
+		t.Errorf("Expected a new streak without bonus, got %+v (err %v)", result, err)
+	}
+
+	if _, err := checkins.UpdateRules(1, CheckinRules{DailyPoints: 5, StreakRules: []StreakRule{{Days: 1, Points: 10}}}); err != ErrInvalidCheckinRule {
+		t.Errorf("Expected ErrInvalidCheckinRule, got %v", err)
+	}
+}
+
+// Check-in days follow the business time zone, not UTC
+func TestCheckinUsesBusinessDays(t *testing.T) {
+	zone := time.FixedZone("UTC+14", 14*60*60)
+	biztime.SetLocation(zone)
+	defer biztime.SetLocation(time.UTC)
+
+	db, campaigns, userIDs := setupCampaignTest(t, 1)
+	checkins := campaignCheckins(db, campaigns)
+	userID := userIDs[0]
+
+	yesterday := time.Now().In(zone).AddDate(0, 0, -1).Format(biztime.DateLayout)
+	db.Create(&model.CheckIn{UserID: userID, Date: yesterday, StreakDays: 4})
+	result, err := checkins.CheckIn(userID)
+	if err != nil || result.StreakDays != 5 {
+		t.Fatalf("Expected yesterday in the business zone to continue the streak, got %+v (err %v)", result, err)
+	}
+	var checkin model.CheckIn
+	db.Where("user_id = ?", userID).Order("id DESC").First(&checkin)
+	if today := time.Now().In(zone).Format(biztime.DateLayout); checkin.Date != today {
+		t.Errorf("Expected the check-in on %s, got %s", today, checkin.Date)
+	}
+}
+
+// Trend buckets are labelled in the business time zone, weeks by ISO week
+func TestTrendKeysUseBusinessTime(t *testing.T) {
+	zone := time.FixedZone("UTC+8", 8*60*60)
+	biztime.SetLocation(zone)
+	defer biztime.SetLocation(time.UTC)
+
+	start, _ := biztime.ParseDate("2024-12-30")
+	end := start.AddDate(0, 0, 2).Add(-time.Microsecond)
+	if keys := trendKeys(start, end, "day"); len(keys) != 2 || keys[0] != "12-30" || keys[1] != "12-31" {
+		t.Errorf("Unexpected day keys %v", keys)
+	}
+	if keys := trendKeys(start, end, "week"); len(keys) != 1 || keys[0] != "2025-W01" {
+		t.Errorf("Unexpected week keys %v", keys)
+	}
+	// 2024-12-31 20:00 UTC is already January in the business zone
+	if key := biztime.MonthKey(time.Date(2024, 12, 31, 20, 0, 0, 0, time.UTC)); key != "2025-01" {
+		t.Errorf("Expected 2025-01, got %s", key)
+	}
+}
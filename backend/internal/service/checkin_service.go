@@ -0,0 +1,293 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+	"scratch-lottery/pkg/biztime"
+	"scratch-lottery/pkg/logger"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ConfigKeyCheckinRules holds the daily check-in reward schedule as JSON
+const ConfigKeyCheckinRules = "checkin_rules"
+
+var (
+	ErrAlreadyCheckedIn   = errors.New("already checked in today")
+	ErrInvalidCheckinRule = errors.New("invalid check-in rule")
+)
+
+// CheckinRules is the reward schedule of daily check-ins: DailyPoints for
+// every check-in, plus the bonus of each streak rule a check-in streak reaches
+type CheckinRules struct {
+	DailyPoints int          `json:"daily_points"`
+	StreakRules []StreakRule `json:"streak_rules"`
+}
+
+// CheckInResponse represents the result of a daily check-in
+type CheckInResponse struct {
+	Date       string                   `json:"date"`
+	StreakDays int                      `json:"streak_days"`
+	Points     int                      `json:"points"`
+	Bonus      int                      `json:"bonus"`
+	Balance    int                      `json:"balance"`
+	Rewards    []CampaignRewardResponse `json:"rewards"` // Rewards of check-in campaigns
+}
+
+// CheckinStatus represents the check-in state of a user
+type CheckinStatus struct {
+	CheckedInToday  bool              `json:"checked_in_today"`
+	CurrentStreak   int               `json:"current_streak"`
+	LastCheckInDate string            `json:"last_check_in_date,omitempty"`
+	DailyPoints     int               `json:"daily_points"`
+	NextReward      *StreakNextReward `json:"next_reward,omitempty"`
+}
+
+// CheckinService records daily check-ins, credits the configured daily
+// points and streak bonuses, and passes check-ins to the campaign engine.
+// The schedule is kept in the system config of each tenant; without one,
+// check-ins only count towards streaks and campaigns.
+type CheckinService struct {
+	db              *gorm.DB
+	adminService    *AdminService
+	campaignService *CampaignService
+	onboarding      *OnboardingService // Optional
+}
+
+// NewCheckinService creates a new check-in service
+func NewCheckinService(db *gorm.DB, adminService *AdminService, campaignService *CampaignService) *CheckinService {
+	return &CheckinService{db: db, adminService: adminService, campaignService: campaignService}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *CheckinService) ForTenant(tenantID uint) *CheckinService {
+	scoped := &CheckinService{
+		db:              repository.ScopeTenant(s.db, tenantID),
+		adminService:    s.adminService.ForTenant(tenantID),
+		campaignService: s.campaignService.ForTenant(tenantID),
+	}
+	if s.onboarding != nil {
+		scoped.onboarding = s.onboarding.ForTenant(tenantID)
+	}
+	return scoped
+}
+
+// UseOnboarding completes the first check-in step of the onboarding checklist
+func (s *CheckinService) UseOnboarding(onboarding *OnboardingService) {
+	s.onboarding = onboarding
+}
+
+// GetRules returns the check-in reward schedule with streak rules ordered by days
+func (s *CheckinService) GetRules() (*CheckinRules, error) {
+	rules := &CheckinRules{StreakRules: []StreakRule{}}
+	value, err := s.adminService.GetConfigValue(ConfigKeyCheckinRules)
+	if errors.Is(err, ErrConfigNotFound) || value == "" {
+		return rules, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(value), rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// UpdateRules validates and replaces the check-in reward schedule
+func (s *CheckinService) UpdateRules(adminID uint, req CheckinRules) (*CheckinRules, error) {
+	if req.DailyPoints < 0 || req.DailyPoints > maxStreakPoints || len(req.StreakRules) > maxStreakRules {
+		return nil, ErrInvalidCheckinRule
+	}
+	rules := append([]StreakRule{}, req.StreakRules...)
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Days < rules[j].Days })
+	for i, rule := range rules {
+		if rule.Days < 2 || rule.Days > maxStreakDays || rule.Points <= 0 || rule.Points > maxStreakPoints {
+			return nil, ErrInvalidCheckinRule
+		}
+		if i > 0 && rules[i-1].Days == rule.Days {
+			return nil, ErrInvalidCheckinRule
+		}
+	}
+	schedule := &CheckinRules{DailyPoints: req.DailyPoints, StreakRules: rules}
+
+	scheduleJSON, err := json.Marshal(schedule)
+	if err != nil {
+		return nil, err
+	}
+	err = s.adminService.configs().Transaction(func(tx *gorm.DB) error {
+		if err := s.adminService.upsertConfig(tx, ConfigKeyCheckinRules, string(scheduleJSON)); err != nil {
+			return err
+		}
+		adminLog := model.AdminLog{
+			AdminID:    adminID,
+			Action:     "update_checkin_rules",
+			TargetType: "system",
+			TargetID:   0,
+			Details:    string(scheduleJSON),
+		}
+		return tx.Create(&adminLog).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// CheckIn records today's check-in of a user, credits the daily points and
+// any streak bonus the new streak reaches, and returns the rewards of the
+// check-in campaigns it matches. A user checks in once a day.
+func (s *CheckinService) CheckIn(userID uint) (*CheckInResponse, error) {
+	rules, err := s.GetRules()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	today := biztime.Date(now)
+	yesterday := biztime.Date(biztime.DayStart(now).AddDate(0, 0, -1))
+
+	var checkIn model.CheckIn
+	var balance int
+	err = repository.Transaction(s.db, func(tx *gorm.DB) error {
+		var previous model.CheckIn
+		err := tx.Where("user_id = ? AND date = ?", userID, yesterday).First(&previous).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		streak := 1
+		if err == nil {
+			// Check-ins from before streaks were kept count as a streak of one
+			streak = max(previous.StreakDays, 1) + 1
+		}
+
+		checkIn = model.CheckIn{
+			UserID:     userID,
+			Date:       today,
+			StreakDays: streak,
+			Points:     rules.DailyPoints,
+			Bonus:      streakBonus(rules.StreakRules, streak),
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&checkIn)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrAlreadyCheckedIn
+		}
+
+		var wallet model.Wallet
+		if err := lockWallet(tx, userID, &wallet); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrWalletNotFound
+			}
+			return err
+		}
+		balance = wallet.Balance
+		if checkIn.Points+checkIn.Bonus == 0 {
+			return nil
+		}
+
+		wallet.Balance += checkIn.Points + checkIn.Bonus
+		if err := tx.Save(&wallet).Error; err != nil {
+			return err
+		}
+		balance = wallet.Balance
+
+		credits := []struct {
+			amount      int
+			description string
+		}{
+			{checkIn.Points, "每日签到奖励"},
+			{checkIn.Bonus, fmt.Sprintf("连续签到%d天奖励", streak)},
+		}
+		for _, credit := range credits {
+			if credit.amount <= 0 {
+				continue
+			}
+			transaction := model.Transaction{
+				TenantID:    wallet.TenantID,
+				WalletID:    wallet.ID,
+				Type:        model.TransactionTypeCheckin,
+				Amount:      credit.amount,
+				Description: credit.description,
+				ReferenceID: checkIn.ID,
+			}
+			if err := tx.Create(&transaction).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &CheckInResponse{
+		Date:       today,
+		StreakDays: checkIn.StreakDays,
+		Points:     checkIn.Points,
+		Bonus:      checkIn.Bonus,
+		Balance:    balance,
+		Rewards:    []CampaignRewardResponse{},
+	}
+	completeOnboarding(s.onboarding, userID, model.OnboardingFirstCheckIn)
+	if s.campaignService != nil {
+		rewards, err := s.campaignService.HandleEvent(CampaignEvent{
+			Trigger:     model.CampaignTriggerCheckIn,
+			UserID:      userID,
+			Key:         "check_in:" + today,
+			ReferenceID: checkIn.ID,
+		})
+		if err != nil {
+			// The check-in stands; campaign errors never undo the original operation
+			logger.Default().Warn("Check-in campaigns of user %d failed: %v", userID, err)
+		}
+		if rewards != nil {
+			resp.Rewards = rewards
+		}
+	}
+	return resp, nil
+}
+
+// GetStatus returns the check-in state of a user. A streak not continued
+// yesterday or today is reported as broken.
+func (s *CheckinService) GetStatus(userID uint) (*CheckinStatus, error) {
+	rules, err := s.GetRules()
+	if err != nil {
+		return nil, err
+	}
+
+	var last model.CheckIn
+	if err := s.db.Where("user_id = ?", userID).Order("date DESC").First(&last).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	now := time.Now()
+	today := biztime.Date(now)
+	yesterday := biztime.Date(biztime.DayStart(now).AddDate(0, 0, -1))
+
+	status := &CheckinStatus{
+		CheckedInToday:  last.Date == today,
+		LastCheckInDate: last.Date,
+		DailyPoints:     rules.DailyPoints,
+	}
+	if last.Date == today || last.Date == yesterday {
+		status.CurrentStreak = max(last.StreakDays, 1)
+	}
+
+	// The next bonus is counted from the streak after the next check-in
+	for days := status.CurrentStreak + 1; days <= status.CurrentStreak+maxStreakDays; days++ {
+		if points := streakBonus(rules.StreakRules, days); points > 0 {
+			status.NextReward = &StreakNextReward{Days: days, Points: points, DaysLeft: days - status.CurrentStreak}
+			break
+		}
+	}
+	return status, nil
+}
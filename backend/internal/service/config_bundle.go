@@ -0,0 +1,600 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+	"scratch-lottery/pkg/money"
+	"scratch-lottery/pkg/redact"
+
+	"github.com/goccy/go-yaml"
+	"gorm.io/gorm"
+)
+
+// ConfigBundleVersion is the version of the configuration bundle format
+const ConfigBundleVersion = 1
+
+// Kinds of entries in a configuration bundle
+const (
+	ConfigBundleSetting     = "setting"
+	ConfigBundleProduct     = "product"
+	ConfigBundleLotteryType = "lottery_type"
+)
+
+var (
+	ErrInvalidConfigBundle = errors.New("invalid configuration bundle")
+	ErrConfigBundleEntry   = errors.New("configuration bundle entry cannot be applied")
+)
+
+// bundleExcludedConfigKeys are system settings that record the state of an
+// environment rather than configure it, so they are neither exported nor
+// imported. Secrets are left out as well.
+var bundleExcludedConfigKeys = map[string]bool{
+	ConfigKeyDemoSeeded:     true,
+	ConfigKeyBrandingUpdate: true,
+	ConfigKeyStatusIncident: true,
+	ConfigKeyKillSwitches:   true,
+	"epay_callback_url":     true, // Points at the environment itself
+}
+
+// bundledConfigKey reports whether a system setting belongs in bundles
+func bundledConfigKey(key string) bool {
+	return key != "" && !bundleExcludedConfigKeys[key] && !redact.IsSensitive(key)
+}
+
+// ConfigBundle is the configuration of a tenant as code: its system settings,
+// exchange products without their card keys, and lottery types with their
+// current prize levels. Entries are matched to a target environment by key
+// or name, never by id.
+type ConfigBundle struct {
+	Version      int                 `json:"version"`
+	ExportedAt   time.Time           `json:"exported_at"`
+	Settings     map[string]string   `json:"settings"`
+	Products     []BundleProduct     `json:"products"`
+	LotteryTypes []BundleLotteryType `json:"lottery_types"`
+}
+
+// BundleProduct is an exchange product in a configuration bundle
+type BundleProduct struct {
+	Name              string `json:"name"`
+	Description       string `json:"description,omitempty"`
+	Image             string `json:"image,omitempty"`
+	Price             int    `json:"price"`
+	Cost              string `json:"cost,omitempty"` // Yuan
+	LowStockThreshold int    `json:"low_stock_threshold,omitempty"`
+	OneTimeReveal     bool   `json:"one_time_reveal,omitempty"`
+	Offline           bool   `json:"offline,omitempty"`
+}
+
+// BundleLotteryType is a lottery type in a configuration bundle. Prize
+// templates are not bundled, so replenished pools repeat the sold out pool.
+type BundleLotteryType struct {
+	Name                   string             `json:"name"`
+	Description            string             `json:"description,omitempty"`
+	Price                  int                `json:"price"`
+	MaxPrize               int                `json:"max_prize"`
+	GameType               model.GameType     `json:"game_type"`
+	CoverImage             string             `json:"cover_image,omitempty"`
+	RulesConfig            any                `json:"rules_config,omitempty"`
+	DesignConfig           *DesignConfig      `json:"design_config,omitempty"`
+	Disabled               bool               `json:"disabled,omitempty"`
+	LowStockThreshold      int                `json:"low_stock_threshold,omitempty"`
+	WaitingRoomThreshold   int                `json:"waiting_room_threshold,omitempty"`
+	ConfirmScratch         bool               `json:"confirm_scratch,omitempty"`
+	ScratchDelaySeconds    int                `json:"scratch_delay_seconds,omitempty"`
+	ScratchIntervalSeconds int                `json:"scratch_interval_seconds,omitempty"`
+	AutoReplenish          bool               `json:"auto_replenish,omitempty"`
+	ReplenishTickets       int                `json:"replenish_tickets,omitempty"`
+	PrizeLevels            []BundlePrizeLevel `json:"prize_levels"`
+}
+
+// BundlePrizeLevel is a prize level in a configuration bundle. Product
+// payouts name their product.
+type BundlePrizeLevel struct {
+	Level         int                   `json:"level"`
+	Name          string                `json:"name"`
+	PrizeAmount   int                   `json:"prize_amount"`
+	Quantity      int                   `json:"quantity"`
+	PayoutType    model.PrizePayoutType `json:"payout_type,omitempty"`
+	PayoutProduct string                `json:"payout_product,omitempty"`
+	Image         string                `json:"image,omitempty"`
+	Color         string                `json:"color,omitempty"`
+	Description   string                `json:"description,omitempty"`
+}
+
+// ConfigBundleChange is an entry of a bundle that differs from the target
+type ConfigBundleChange struct {
+	Kind   string   `json:"kind"`
+	Name   string   `json:"name"`
+	Action string   `json:"action"`           // create or update
+	Fields []string `json:"fields,omitempty"` // Changed fields of updates
+}
+
+// ConfigBundleDiff lists what applying a bundle changes. Entries of the
+// target that are missing from the bundle are left alone.
+type ConfigBundleDiff struct {
+	Changes   []ConfigBundleChange `json:"changes"`
+	Unchanged int                  `json:"unchanged"`
+}
+
+// ConfigBundleService exports and applies configuration bundles
+type ConfigBundleService struct {
+	db              *gorm.DB
+	lotteryService  *LotteryService
+	exchangeService *ExchangeService
+}
+
+// NewConfigBundleService creates a new configuration bundle service
+func NewConfigBundleService(db *gorm.DB, lotteryService *LotteryService, exchangeService *ExchangeService) *ConfigBundleService {
+	return &ConfigBundleService{db: db, lotteryService: lotteryService, exchangeService: exchangeService}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *ConfigBundleService) ForTenant(tenantID uint) *ConfigBundleService {
+	return &ConfigBundleService{
+		db:              repository.ScopeTenant(s.db, tenantID),
+		lotteryService:  s.lotteryService.ForTenant(tenantID),
+		exchangeService: s.exchangeService.ForTenant(tenantID),
+	}
+}
+
+// Export returns the configuration of the tenant as a bundle
+func (s *ConfigBundleService) Export() (*ConfigBundle, error) {
+	return exportConfigBundle(s.db)
+}
+
+// MarshalConfigBundle encodes a bundle as JSON or, for format "yaml", YAML
+func MarshalConfigBundle(bundle *ConfigBundle, format string) ([]byte, error) {
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil || format != "yaml" {
+		return data, err
+	}
+	return yaml.JSONToYAML(data)
+}
+
+// ParseConfigBundle decodes a bundle from JSON or YAML
+func ParseConfigBundle(data []byte) (*ConfigBundle, error) {
+	trimmed := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(trimmed, "{") {
+		converted, err := yaml.YAMLToJSON(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidConfigBundle, err)
+		}
+		data = converted
+	}
+	bundle := &ConfigBundle{}
+	if err := json.Unmarshal(data, bundle); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfigBundle, err)
+	}
+	return bundle, nil
+}
+
+// Diff previews what applying a bundle would change
+func (s *ConfigBundleService) Diff(bundle *ConfigBundle) (*ConfigBundleDiff, error) {
+	if err := validateConfigBundle(bundle); err != nil {
+		return nil, err
+	}
+	current, err := exportConfigBundle(s.db)
+	if err != nil {
+		return nil, err
+	}
+	return diffConfigBundles(current, bundle), nil
+}
+
+// Apply brings the tenant's configuration in line with a bundle in one
+// transaction and returns what changed. Products are applied before lottery
+// types so prize levels can pay out products the bundle adds.
+func (s *ConfigBundleService) Apply(adminID uint, bundle *ConfigBundle) (*ConfigBundleDiff, error) {
+	if err := validateConfigBundle(bundle); err != nil {
+		return nil, err
+	}
+
+	var diff *ConfigBundleDiff
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		current, err := exportConfigBundle(tx)
+		if err != nil {
+			return err
+		}
+		diff = diffConfigBundles(current, bundle)
+		if len(diff.Changes) == 0 {
+			return nil
+		}
+
+		lotteries := s.lotteryService.withDB(tx)
+		exchange := s.exchangeService.withDB(tx)
+		products := make(map[string]BundleProduct, len(bundle.Products))
+		for _, product := range bundle.Products {
+			products[product.Name] = product
+		}
+		lotteryTypes := make(map[string]BundleLotteryType, len(bundle.LotteryTypes))
+		for _, lotteryType := range bundle.LotteryTypes {
+			lotteryTypes[lotteryType.Name] = lotteryType
+		}
+
+		for _, change := range diff.Changes {
+			switch change.Kind {
+			case ConfigBundleSetting:
+				err = upsertSystemConfig(tx, change.Name, bundle.Settings[change.Name])
+			case ConfigBundleProduct:
+				err = applyBundleProduct(tx, exchange, products[change.Name])
+			}
+			if err != nil {
+				return fmt.Errorf("%w: %s %q: %w", ErrConfigBundleEntry, change.Kind, change.Name, err)
+			}
+		}
+		for _, change := range diff.Changes {
+			if change.Kind == ConfigBundleLotteryType {
+				if err := applyBundleLotteryType(tx, lotteries, lotteryTypes[change.Name]); err != nil {
+					return fmt.Errorf("%w: %s %q: %w", ErrConfigBundleEntry, change.Kind, change.Name, err)
+				}
+			}
+		}
+
+		details, err := json.Marshal(diff)
+		if err != nil {
+			return err
+		}
+		adminLog := model.AdminLog{
+			AdminID:    adminID,
+			Action:     "apply_config_bundle",
+			TargetType: "system",
+			TargetID:   0,
+			Details:    string(details),
+		}
+		return tx.Create(&adminLog).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
+
+// validateConfigBundle checks the parts of a bundle that are not validated
+// when its entries are applied
+func validateConfigBundle(bundle *ConfigBundle) error {
+	if bundle == nil || bundle.Version != ConfigBundleVersion {
+		return fmt.Errorf("%w: unsupported version", ErrInvalidConfigBundle)
+	}
+	for key := range bundle.Settings {
+		if !bundledConfigKey(key) {
+			return fmt.Errorf("%w: setting %q cannot be imported", ErrInvalidConfigBundle, key)
+		}
+	}
+	products := map[string]bool{}
+	for _, product := range bundle.Products {
+		if product.Name == "" || products[product.Name] {
+			return fmt.Errorf("%w: product names must be unique and not empty", ErrInvalidConfigBundle)
+		}
+		products[product.Name] = true
+	}
+	lotteryTypes := map[string]bool{}
+	for _, lotteryType := range bundle.LotteryTypes {
+		if lotteryType.Name == "" || lotteryTypes[lotteryType.Name] {
+			return fmt.Errorf("%w: lottery type names must be unique and not empty", ErrInvalidConfigBundle)
+		}
+		lotteryTypes[lotteryType.Name] = true
+	}
+	return nil
+}
+
+// exportConfigBundle reads the bundle of the tenant of db
+func exportConfigBundle(db *gorm.DB) (*ConfigBundle, error) {
+	bundle := &ConfigBundle{
+		Version:      ConfigBundleVersion,
+		ExportedAt:   time.Now().UTC(),
+		Settings:     map[string]string{},
+		Products:     []BundleProduct{},
+		LotteryTypes: []BundleLotteryType{},
+	}
+
+	var settings []model.SystemConfig
+	if err := db.Order("key ASC").Find(&settings).Error; err != nil {
+		return nil, err
+	}
+	for _, setting := range settings {
+		if bundledConfigKey(setting.Key) {
+			bundle.Settings[setting.Key] = setting.Value
+		}
+	}
+
+	var products []model.Product
+	if err := db.Where("demo = ?", false).Order("id ASC").Find(&products).Error; err != nil {
+		return nil, err
+	}
+	productNames := make(map[uint]string, len(products))
+	seen := map[string]bool{}
+	for _, product := range products {
+		productNames[product.ID] = product.Name
+		if seen[product.Name] {
+			continue // Bundles match products by name; the oldest wins
+		}
+		seen[product.Name] = true
+		entry := BundleProduct{
+			Name:              product.Name,
+			Description:       product.Description,
+			Image:             product.Image,
+			Price:             product.Price,
+			LowStockThreshold: product.LowStockThreshold,
+			OneTimeReveal:     product.OneTimeReveal,
+			Offline:           product.Status == model.ProductStatusOffline,
+		}
+		if product.Cost > 0 {
+			entry.Cost = money.Format(int64(product.Cost), money.CNY)
+		}
+		bundle.Products = append(bundle.Products, entry)
+	}
+
+	var lotteryTypes []model.LotteryType
+	if err := db.Where("demo = ?", false).Order("id ASC").Find(&lotteryTypes).Error; err != nil {
+		return nil, err
+	}
+	seen = map[string]bool{}
+	for _, lotteryType := range lotteryTypes {
+		if seen[lotteryType.Name] {
+			continue
+		}
+		seen[lotteryType.Name] = true
+		entry := BundleLotteryType{
+			Name:                   lotteryType.Name,
+			Description:            lotteryType.Description,
+			Price:                  lotteryType.Price,
+			MaxPrize:               lotteryType.MaxPrize,
+			GameType:               lotteryType.GameType,
+			CoverImage:             lotteryType.CoverImage,
+			Disabled:               lotteryType.Status == model.LotteryTypeStatusDisabled,
+			LowStockThreshold:      lotteryType.LowStockThreshold,
+			WaitingRoomThreshold:   lotteryType.WaitingRoomThreshold,
+			ConfirmScratch:         lotteryType.ConfirmScratch,
+			ScratchDelaySeconds:    lotteryType.ScratchDelaySeconds,
+			ScratchIntervalSeconds: lotteryType.ScratchIntervalSeconds,
+			AutoReplenish:          lotteryType.AutoReplenish,
+			ReplenishTickets:       lotteryType.ReplenishTickets,
+			PrizeLevels:            []BundlePrizeLevel{},
+		}
+		if lotteryType.RulesConfig != "" {
+			if err := json.Unmarshal([]byte(lotteryType.RulesConfig), &entry.RulesConfig); err != nil {
+				return nil, err
+			}
+		}
+		if lotteryType.DesignConfig != "" {
+			entry.DesignConfig = &DesignConfig{}
+			if err := json.Unmarshal([]byte(lotteryType.DesignConfig), entry.DesignConfig); err != nil {
+				return nil, err
+			}
+		}
+
+		var levels []model.PrizeLevel
+		if err := db.Where("lottery_type_id = ? AND version = ?", lotteryType.ID, lotteryType.PrizeLevelVersion).
+			Order("level ASC").Find(&levels).Error; err != nil {
+			return nil, err
+		}
+		for _, level := range levels {
+			bundled := BundlePrizeLevel{
+				Level:       level.Level,
+				Name:        level.Name,
+				PrizeAmount: level.PrizeAmount,
+				Quantity:    level.Quantity,
+				Image:       level.Image,
+				Color:       level.Color,
+				Description: level.Description,
+			}
+			if level.PayoutType == model.PrizePayoutProduct {
+				bundled.PayoutType = level.PayoutType
+				bundled.PayoutProduct = productNames[level.PayoutProductID]
+			}
+			entry.PrizeLevels = append(entry.PrizeLevels, bundled)
+		}
+		bundle.LotteryTypes = append(bundle.LotteryTypes, entry)
+	}
+	return bundle, nil
+}
+
+// diffConfigBundles lists the entries of target that differ from current
+func diffConfigBundles(current, target *ConfigBundle) *ConfigBundleDiff {
+	diff := &ConfigBundleDiff{Changes: []ConfigBundleChange{}}
+	add := func(kind, name string, before, after any, exists bool) {
+		if !exists {
+			diff.Changes = append(diff.Changes, ConfigBundleChange{Kind: kind, Name: name, Action: "create"})
+			return
+		}
+		if fields := changedFields(before, after); len(fields) > 0 {
+			diff.Changes = append(diff.Changes, ConfigBundleChange{Kind: kind, Name: name, Action: "update", Fields: fields})
+			return
+		}
+		diff.Unchanged++
+	}
+
+	keys := make([]string, 0, len(target.Settings))
+	for key := range target.Settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value, exists := current.Settings[key]
+		add(ConfigBundleSetting, key, map[string]string{"value": value}, map[string]string{"value": target.Settings[key]}, exists)
+	}
+
+	products := make(map[string]BundleProduct, len(current.Products))
+	for _, product := range current.Products {
+		products[product.Name] = product
+	}
+	for _, product := range target.Products {
+		before, exists := products[product.Name]
+		add(ConfigBundleProduct, product.Name, before, product, exists)
+	}
+
+	lotteryTypes := make(map[string]BundleLotteryType, len(current.LotteryTypes))
+	for _, lotteryType := range current.LotteryTypes {
+		lotteryTypes[lotteryType.Name] = lotteryType
+	}
+	for _, lotteryType := range target.LotteryTypes {
+		before, exists := lotteryTypes[lotteryType.Name]
+		add(ConfigBundleLotteryType, lotteryType.Name, before, lotteryType, exists)
+	}
+	return diff
+}
+
+// changedFields compares two entries by their JSON fields
+func changedFields(before, after any) []string {
+	var a, b map[string]any
+	beforeJSON, _ := json.Marshal(before)
+	afterJSON, _ := json.Marshal(after)
+	_ = json.Unmarshal(beforeJSON, &a)
+	_ = json.Unmarshal(afterJSON, &b)
+
+	fields := []string{}
+	for key := range a {
+		if _, ok := b[key]; !ok {
+			fields = append(fields, key)
+		}
+	}
+	for key, value := range b {
+		if !reflect.DeepEqual(a[key], value) {
+			fields = append(fields, key)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// applyBundleProduct creates or updates the product of the same name
+func applyBundleProduct(tx *gorm.DB, exchange *ExchangeService, product BundleProduct) error {
+	var existing model.Product
+	err := tx.Where("name = ? AND demo = ?", product.Name, false).Order("id ASC").First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		created, err := exchange.CreateProduct(CreateProductRequest{
+			Name:              product.Name,
+			Description:       product.Description,
+			Image:             product.Image,
+			Price:             product.Price,
+			LowStockThreshold: product.LowStockThreshold,
+			OneTimeReveal:     product.OneTimeReveal,
+			Cost:              product.Cost,
+		})
+		if err != nil || !product.Offline {
+			return err
+		}
+		existing.ID = created.ID
+		existing.Status = created.Status
+	} else if err != nil {
+		return err
+	}
+
+	status := existing.Status
+	if product.Offline {
+		status = model.ProductStatusOffline
+	} else if status == model.ProductStatusOffline {
+		status = model.ProductStatusAvailable
+	}
+	_, err = exchange.UpdateProduct(existing.ID, UpdateProductRequest{
+		Name:              &product.Name,
+		Description:       &product.Description,
+		Image:             &product.Image,
+		Price:             &product.Price,
+		Status:            &status,
+		LowStockThreshold: &product.LowStockThreshold,
+		OneTimeReveal:     &product.OneTimeReveal,
+		Cost:              &product.Cost,
+	})
+	return err
+}
+
+// applyBundleLotteryType creates or updates the lottery type of the same
+// name. Changed prize levels follow the rules of UpdatePrizeLevels.
+func applyBundleLotteryType(tx *gorm.DB, lotteries *LotteryService, lotteryType BundleLotteryType) error {
+	levels := make([]PrizeLevelInput, len(lotteryType.PrizeLevels))
+	for i, level := range lotteryType.PrizeLevels {
+		levels[i] = PrizeLevelInput{
+			Level:       level.Level,
+			Name:        level.Name,
+			PrizeAmount: level.PrizeAmount,
+			Quantity:    level.Quantity,
+			PayoutType:  level.PayoutType,
+			Image:       level.Image,
+			Color:       level.Color,
+			Description: level.Description,
+		}
+		if level.PayoutProduct != "" {
+			var product model.Product
+			if err := tx.Where("name = ? AND demo = ?", level.PayoutProduct, false).Order("id ASC").First(&product).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return fmt.Errorf("%w: payout product %q not found", ErrInvalidConfigBundle, level.PayoutProduct)
+				}
+				return err
+			}
+			levels[i].PayoutProductID = product.ID
+		}
+	}
+
+	var existing model.LotteryType
+	err := tx.Where("name = ? AND demo = ?", lotteryType.Name, false).Order("id ASC").First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		created, err := lotteries.CreateLotteryType(CreateLotteryTypeRequest{
+			Name:                   lotteryType.Name,
+			Description:            lotteryType.Description,
+			Price:                  lotteryType.Price,
+			MaxPrize:               lotteryType.MaxPrize,
+			GameType:               lotteryType.GameType,
+			CoverImage:             lotteryType.CoverImage,
+			RulesConfig:            lotteryType.RulesConfig,
+			PrizeLevels:            levels,
+			LowStockThreshold:      lotteryType.LowStockThreshold,
+			WaitingRoomThreshold:   lotteryType.WaitingRoomThreshold,
+			ConfirmScratch:         lotteryType.ConfirmScratch,
+			ScratchDelaySeconds:    lotteryType.ScratchDelaySeconds,
+			ScratchIntervalSeconds: lotteryType.ScratchIntervalSeconds,
+			AutoReplenish:          lotteryType.AutoReplenish,
+			ReplenishTickets:       lotteryType.ReplenishTickets,
+		})
+		if err != nil {
+			return err
+		}
+		if lotteryType.DesignConfig == nil && !lotteryType.Disabled {
+			return nil
+		}
+		existing.ID = created.ID
+		existing.Status = model.LotteryTypeStatusAvailable
+		levels = nil // Already created
+	} else if err != nil {
+		return err
+	}
+
+	status := existing.Status
+	if lotteryType.Disabled {
+		status = model.LotteryTypeStatusDisabled
+	} else if status == model.LotteryTypeStatusDisabled {
+		status = model.LotteryTypeStatusAvailable
+	}
+	update := UpdateLotteryTypeRequest{
+		Name:                   &lotteryType.Name,
+		Description:            &lotteryType.Description,
+		Price:                  &lotteryType.Price,
+		MaxPrize:               &lotteryType.MaxPrize,
+		GameType:               &lotteryType.GameType,
+		CoverImage:             &lotteryType.CoverImage,
+		RulesConfig:            lotteryType.RulesConfig,
+		DesignConfig:           lotteryType.DesignConfig,
+		Status:                 &status,
+		LowStockThreshold:      &lotteryType.LowStockThreshold,
+		WaitingRoomThreshold:   &lotteryType.WaitingRoomThreshold,
+		ConfirmScratch:         &lotteryType.ConfirmScratch,
+		ScratchDelaySeconds:    &lotteryType.ScratchDelaySeconds,
+		ScratchIntervalSeconds: &lotteryType.ScratchIntervalSeconds,
+		AutoReplenish:          &lotteryType.AutoReplenish,
+		ReplenishTickets:       &lotteryType.ReplenishTickets,
+	}
+	if _, err := lotteries.UpdateLotteryType(existing.ID, update); err != nil {
+		return err
+	}
+	if levels == nil {
+		return nil
+	}
+	return lotteries.UpdatePrizeLevels(existing.ID, levels)
+}
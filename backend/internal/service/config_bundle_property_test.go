@@ -0,0 +1,166 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+	"gorm.io/gorm"
+)
+
+func newTestConfigBundleService(db *gorm.DB) *ConfigBundleService {
+	return NewConfigBundleService(db, NewLotteryService(db, "test-encryption-key"), NewExchangeService(db, NewWalletService(db))).
+		ForTenant(repository.DefaultTenantID)
+}
+
+// seedConfigBundleSource creates settings, products and a lottery type whose
+// top prize pays out the first product
+func seedConfigBundleSource(db *gorm.DB, prices []int) error {
+	for _, kv := range [][2]string{{ConfigKeySiteName, "Standby"}, {ConfigKeyDemoSeeded, "true"}, {"epay_key", "secret"}} {
+		if err := upsertSystemConfig(repository.ScopeTenant(db, repository.DefaultTenantID), kv[0], kv[1]); err != nil {
+			return err
+		}
+	}
+
+	exchange := NewExchangeService(db, NewWalletService(db)).ForTenant(repository.DefaultTenantID)
+	var payoutID uint
+	for i, price := range prices {
+		product, err := exchange.CreateProduct(CreateProductRequest{Name: fmt.Sprintf("Product %d", i), Price: price, Cost: "1.50"})
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			payoutID = product.ID
+		}
+	}
+
+	levels := []PrizeLevelInput{{Level: 2, Name: "Small", PrizeAmount: 5, Quantity: 10}}
+	if payoutID != 0 {
+		levels = append(levels, PrizeLevelInput{Level: 1, Name: "Top", PrizeAmount: 50, Quantity: 1, PayoutType: model.PrizePayoutProduct, PayoutProductID: payoutID})
+	}
+	_, err := NewLotteryService(db, "test-encryption-key").ForTenant(repository.DefaultTenantID).CreateLotteryType(CreateLotteryTypeRequest{
+		Name:        "Lucky",
+		Price:       10,
+		MaxPrize:    100,
+		GameType:    model.GameTypeAmountSum,
+		RulesConfig: map[string]any{"areas": 9},
+		PrizeLevels: levels,
+	})
+	return err
+}
+
+// Configuration bundles: applying the export of one environment to another
+// leaves nothing to change, through JSON and YAML alike, and leaves out
+// environment state and secrets.
+func TestConfigBundleRoundTrip(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("an applied export has no changes left", prop.ForAll(
+		func(prices []int, yamlFormat bool) bool {
+			source := setupTenantTestDB(t)
+			if err := seedConfigBundleSource(source, prices); err != nil {
+				t.Logf("Seeding failed: %v", err)
+				return false
+			}
+			exported, err := newTestConfigBundleService(source).Export()
+			if err != nil {
+				t.Logf("Export failed: %v", err)
+				return false
+			}
+			if _, ok := exported.Settings[ConfigKeyDemoSeeded]; ok {
+				t.Logf("Environment state was exported: %v", exported.Settings)
+				return false
+			}
+			if _, ok := exported.Settings["epay_key"]; ok {
+				t.Logf("A secret was exported: %v", exported.Settings)
+				return false
+			}
+
+			format := "json"
+			if yamlFormat {
+				format = "yaml"
+			}
+			data, err := MarshalConfigBundle(exported, format)
+			if err != nil {
+				t.Logf("Marshal failed: %v", err)
+				return false
+			}
+			bundle, err := ParseConfigBundle(data)
+			if err != nil {
+				t.Logf("Parse failed: %v\n%s", err, data)
+				return false
+			}
+
+			target := setupTenantTestDB(t)
+			applier := newTestConfigBundleService(target)
+			applied, err := applier.Apply(1, bundle)
+			if err != nil {
+				t.Logf("Apply failed: %v", err)
+				return false
+			}
+			if want := 1 + len(prices) + 1; len(applied.Changes) != want {
+				t.Logf("Expected %d creations, got %+v", want, applied.Changes)
+				return false
+			}
+			diff, err := applier.Diff(bundle)
+			if err != nil || len(diff.Changes) != 0 {
+				t.Logf("Expected no changes after applying, got %+v (err %v)", diff, err)
+				return false
+			}
+
+			var logs int64
+			target.Model(&model.AdminLog{}).Where("action = ?", "apply_config_bundle").Count(&logs)
+			return logs == 1
+		},
+		gen.SliceOfN(3, gen.IntRange(1, 1000)),
+		gen.Bool(),
+	))
+
+	properties.TestingRun(t)
+}
+
+// A bundle that cannot be applied in full changes nothing
+func TestConfigBundleApplyIsAtomic(t *testing.T) {
+	db := setupTenantTestDB(t)
+	bundles := newTestConfigBundleService(db)
+
+	bundle := &ConfigBundle{
+		Version:  ConfigBundleVersion,
+		Settings: map[string]string{ConfigKeySiteName: "Standby"},
+		Products: []BundleProduct{{Name: "Gift", Price: 50}},
+		LotteryTypes: []BundleLotteryType{{
+			Name:        "Lucky",
+			Price:       10,
+			MaxPrize:    100,
+			GameType:    model.GameTypeAmountSum,
+			PrizeLevels: []BundlePrizeLevel{{Level: 1, Name: "Top", PrizeAmount: 50, Quantity: 1, PayoutType: model.PrizePayoutProduct, PayoutProduct: "Missing"}},
+		}},
+	}
+	if _, err := bundles.Apply(1, bundle); !errors.Is(err, ErrConfigBundleEntry) {
+		t.Fatalf("Expected an entry error, got %v", err)
+	}
+	var products, settings int64
+	db.Model(&model.Product{}).Count(&products)
+	db.Model(&model.SystemConfig{}).Count(&settings)
+	if products != 0 || settings != 0 {
+		t.Errorf("Expected nothing applied, got %d products and %d settings", products, settings)
+	}
+
+	// Environment state and secrets are refused
+	for _, key := range []string{ConfigKeyKillSwitches, "epay_key"} {
+		bundle := &ConfigBundle{Version: ConfigBundleVersion, Settings: map[string]string{key: "x"}}
+		if _, err := bundles.Diff(bundle); !errors.Is(err, ErrInvalidConfigBundle) {
+			t.Errorf("Expected %s to be refused, got %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+)
+
+// Dashboard layouts: a saved layout loads back with the same widgets in the
+// same order, and each admin only sees their own layout.
+func TestDashboardLayoutRoundTrip(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("saved layouts load back in order", prop.ForAll(
+		func(order []int, limit int) bool {
+			db := setupTenantTestDB(t)
+			if err := db.AutoMigrate(&model.DashboardLayout{}); err != nil {
+				t.Logf("Failed to migrate: %v", err)
+				return false
+			}
+			dashboard := NewDashboardService(db).ForTenant(1)
+
+			// Pick distinct widgets from the catalog in a random order
+			catalog := dashboard.GetCatalog()
+			seen := make(map[int]bool)
+			var widgets []DashboardWidgetConfig
+			for _, i := range order {
+				i %= len(catalog)
+				if seen[i] {
+					continue
+				}
+				seen[i] = true
+				config := DashboardWidgetConfig{Widget: catalog[i].Key, Size: catalog[i].Sizes[0]}
+				for _, setting := range catalog[i].Settings {
+					if setting.Key == "limit" {
+						config.Settings = map[string]interface{}{"limit": float64(limit)}
+					}
+				}
+				widgets = append(widgets, config)
+			}
+
+			saved, err := dashboard.SaveLayout(7, SaveDashboardLayoutRequest{Widgets: widgets})
+			if err != nil {
+				t.Logf("SaveLayout failed: %v", err)
+				return false
+			}
+			loaded, err := dashboard.GetLayout(7)
+			if err != nil || loaded.IsDefault || len(loaded.Widgets) != len(widgets) {
+				t.Logf("Expected %d saved widgets, got %+v (err %v)", len(widgets), loaded, err)
+				return false
+			}
+
+			// Compare through JSON, as the layout is stored and served as JSON
+			savedJSON, _ := json.Marshal(saved.Widgets)
+			loadedJSON, _ := json.Marshal(loaded.Widgets)
+			if string(savedJSON) != string(loadedJSON) {
+				t.Logf("Layout changed on reload: %s vs %s", savedJSON, loadedJSON)
+				return false
+			}
+			for i, config := range loaded.Widgets {
+				if config.Widget != widgets[i].Widget || config.Size != widgets[i].Size {
+					t.Logf("Widget %d: expected %s, got %s", i, widgets[i].Widget, config.Widget)
+					return false
+				}
+				if _, ok := widgets[i].Settings["limit"]; ok && config.Settings["limit"] != float64(limit) {
+					t.Logf("Widget %s lost its limit setting: %v", config.Widget, config.Settings)
+					return false
+				}
+			}
+
+			other, err := dashboard.GetLayout(8)
+			return err == nil && other.IsDefault
+		},
+		gen.SliceOfN(10, gen.IntRange(0, 100)),
+		gen.IntRange(5, 50),
+	))
+
+	properties.TestingRun(t)
+}
+
+func TestDashboardLayoutValidation(t *testing.T) {
+	db := setupTenantTestDB(t)
+	if err := db.AutoMigrate(&model.DashboardLayout{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	dashboard := NewDashboardService(db)
+
+	many := make([]DashboardWidgetConfig, maxDashboardWidgets+1)
+	cases := []struct {
+		widgets []DashboardWidgetConfig
+		err     error
+	}{
+		{[]DashboardWidgetConfig{{Widget: "missing"}}, ErrUnknownWidget},
+		{[]DashboardWidgetConfig{{Widget: "overview"}, {Widget: "overview"}}, ErrDuplicateWidget},
+		{[]DashboardWidgetConfig{{Widget: "overview", Size: WidgetSizeSmall}}, ErrInvalidWidgetSize},
+		{[]DashboardWidgetConfig{{Widget: "admin_logs", Settings: map[string]interface{}{"limit": float64(500)}}}, ErrInvalidWidgetSetting},
+		{[]DashboardWidgetConfig{{Widget: "admin_logs", Settings: map[string]interface{}{"limit": 10.5}}}, ErrInvalidWidgetSetting},
+		{[]DashboardWidgetConfig{{Widget: "admin_logs", Settings: map[string]interface{}{"color": "red"}}}, ErrInvalidWidgetSetting},
+		{[]DashboardWidgetConfig{{Widget: "sales_trend", Settings: map[string]interface{}{"period": "year"}}}, ErrInvalidWidgetSetting},
+		{many, ErrTooManyWidgets},
+		{[]DashboardWidgetConfig{}, nil},
+	}
+	for i, tc := range cases {
+		if _, err := dashboard.SaveLayout(1, SaveDashboardLayoutRequest{Widgets: tc.widgets}); err != tc.err {
+			t.Errorf("Case %d: expected %v, got %v", i, tc.err, err)
+		}
+	}
+
+	// Defaults are filled in for omitted sizes and settings
+	layout, err := dashboard.SaveLayout(1, SaveDashboardLayoutRequest{Widgets: []DashboardWidgetConfig{{Widget: "sales_forecast"}}})
+	if err != nil {
+		t.Fatalf("SaveLayout failed: %v", err)
+	}
+	want := DashboardWidgetConfig{Widget: "sales_forecast", Size: WidgetSizeMedium, Settings: map[string]interface{}{"window": 7}}
+	if !reflect.DeepEqual(layout.Widgets, []DashboardWidgetConfig{want}) {
+		t.Errorf("Expected defaults filled in, got %+v", layout.Widgets)
+	}
+
+	// Platform-only widgets are not available to other tenants
+	if _, err := dashboard.ForTenant(2).SaveLayout(2, SaveDashboardLayoutRequest{Widgets: []DashboardWidgetConfig{{Widget: "inventory_alerts"}}}); err != ErrUnknownWidget {
+		t.Errorf("Expected ErrUnknownWidget for platform widget, got %v", err)
+	}
+	for _, config := range mustDefaultLayout(t, dashboard.ForTenant(2)) {
+		if config.Widget == "inventory_alerts" {
+			t.Errorf("Default layout of tenant 2 contains a platform widget")
+		}
+	}
+
+	// Resetting restores the default layout
+	reset, err := dashboard.ResetLayout(1)
+	if err != nil || !reset.IsDefault {
+		t.Fatalf("ResetLayout failed: %+v (err %v)", reset, err)
+	}
+	if _, err := dashboard.SaveLayout(1, SaveDashboardLayoutRequest{}); err != nil {
+		t.Errorf("Saving after reset failed: %v", err)
+	}
+}
+
+func mustDefaultLayout(t *testing.T, dashboard *DashboardService) []DashboardWidgetConfig {
+	layout, err := dashboard.GetLayout(99)
+	if err != nil || !layout.IsDefault {
+		t.Fatalf("Expected default layout, got %+v (err %v)", layout, err)
+	}
+	return layout.Widgets
+}
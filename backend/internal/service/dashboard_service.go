@@ -0,0 +1,388 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnknownWidget        = errors.New("unknown dashboard widget")
+	ErrDuplicateWidget      = errors.New("dashboard widget added twice")
+	ErrInvalidWidgetSize    = errors.New("invalid dashboard widget size")
+	ErrInvalidWidgetSetting = errors.New("invalid dashboard widget setting")
+	ErrTooManyWidgets       = errors.New("too many dashboard widgets")
+)
+
+// maxDashboardWidgets is the maximum number of widgets on a dashboard
+const maxDashboardWidgets = 24
+
+// Dashboard widget sizes
+const (
+	WidgetSizeSmall  = "small"
+	WidgetSizeMedium = "medium"
+	WidgetSizeLarge  = "large"
+)
+
+// Dashboard widget setting types
+const (
+	WidgetSettingNumber = "number"
+	WidgetSettingSelect = "select"
+)
+
+// WidgetSetting describes a setting of a dashboard widget
+type WidgetSetting struct {
+	Key     string      `json:"key"`
+	Label   string      `json:"label"`
+	Type    string      `json:"type"`
+	Default interface{} `json:"default"`
+	Min     int         `json:"min,omitempty"`     // number settings only
+	Max     int         `json:"max,omitempty"`     // number settings only
+	Options []string    `json:"options,omitempty"` // select settings only
+}
+
+// DashboardWidget describes a widget available on the admin dashboard and
+// the endpoint it loads its data from
+type DashboardWidget struct {
+	Key          string          `json:"key"`
+	Name         string          `json:"name"`
+	Description  string          `json:"description"`
+	Endpoint     string          `json:"endpoint"`
+	Sizes        []string        `json:"sizes"`
+	DefaultSize  string          `json:"default_size"`
+	Settings     []WidgetSetting `json:"settings"`
+	PlatformOnly bool            `json:"platform_only"` // only available to the default tenant
+}
+
+// dashboardWidgets is the catalog of available widgets
+var dashboardWidgets = []DashboardWidget{
+	{
+		Key:         "overview",
+		Name:        "核心指标",
+		Description: "用户、销量、收入、兑换和库存汇总",
+		Endpoint:    "/api/admin/dashboard",
+		Sizes:       []string{WidgetSizeMedium, WidgetSizeLarge},
+		DefaultSize: WidgetSizeLarge,
+		Settings:    []WidgetSetting{},
+	},
+	{
+		Key:         "sales_trend",
+		Name:        "销售趋势",
+		Description: "按日、周或月统计的销量与收入",
+		Endpoint:    "/api/admin/statistics",
+		Sizes:       []string{WidgetSizeMedium, WidgetSizeLarge},
+		DefaultSize: WidgetSizeLarge,
+		Settings: []WidgetSetting{
+			{Key: "period", Label: "统计周期", Type: WidgetSettingSelect, Default: "day", Options: []string{"day", "week", "month"}},
+			{Key: "range_days", Label: "统计天数", Type: WidgetSettingNumber, Default: 30, Min: 7, Max: 365},
+		},
+	},
+	{
+		Key:         "sales_forecast",
+		Name:        "售罄预测",
+		Description: "各彩票类型的售罄时间与收入预测",
+		Endpoint:    "/api/admin/statistics/forecast",
+		Sizes:       []string{WidgetSizeMedium, WidgetSizeLarge},
+		DefaultSize: WidgetSizeMedium,
+		Settings: []WidgetSetting{
+			{Key: "window", Label: "移动平均天数", Type: WidgetSettingNumber, Default: 7, Min: 3, Max: 30},
+		},
+	},
+	{
+		Key:         "recent_users",
+		Name:        "最新用户",
+		Description: "最近注册的用户",
+		Endpoint:    "/api/admin/users",
+		Sizes:       []string{WidgetSizeSmall, WidgetSizeMedium},
+		DefaultSize: WidgetSizeSmall,
+		Settings: []WidgetSetting{
+			{Key: "limit", Label: "显示条数", Type: WidgetSettingNumber, Default: 10, Min: 5, Max: 50},
+		},
+	},
+	{
+		Key:         "admin_logs",
+		Name:        "操作日志",
+		Description: "管理员最近的操作记录",
+		Endpoint:    "/api/admin/logs",
+		Sizes:       []string{WidgetSizeSmall, WidgetSizeMedium, WidgetSizeLarge},
+		DefaultSize: WidgetSizeMedium,
+		Settings: []WidgetSetting{
+			{Key: "limit", Label: "显示条数", Type: WidgetSettingNumber, Default: 10, Min: 5, Max: 50},
+		},
+	},
+	{
+		Key:         "auth_incidents",
+		Name:        "登录安全事件",
+		Description: "登录失败、锁定和新设备登录",
+		Endpoint:    "/api/admin/auth-incidents",
+		Sizes:       []string{WidgetSizeSmall, WidgetSizeMedium},
+		DefaultSize: WidgetSizeSmall,
+		Settings: []WidgetSetting{
+			{Key: "kind", Label: "事件类型", Type: WidgetSettingSelect, Default: "", Options: []string{
+				"", string(model.AuthIncidentLoginFailed), string(model.AuthIncidentOAuthAnomaly),
+				string(model.AuthIncidentLockout), string(model.AuthIncidentNewDevice),
+			}},
+			{Key: "limit", Label: "显示条数", Type: WidgetSettingNumber, Default: 10, Min: 5, Max: 50},
+		},
+	},
+	{
+		Key:         "support_issues",
+		Name:        "问题反馈",
+		Description: "待处理的用户问题，超出 SLA 期限的优先",
+		Endpoint:    "/api/admin/support/issues",
+		Sizes:       []string{WidgetSizeSmall, WidgetSizeMedium, WidgetSizeLarge},
+		DefaultSize: WidgetSizeMedium,
+		Settings: []WidgetSetting{
+			{Key: "overdue", Label: "仅显示超期", Type: WidgetSettingSelect, Default: "false", Options: []string{"false", "true"}},
+			{Key: "limit", Label: "显示条数", Type: WidgetSettingNumber, Default: 10, Min: 5, Max: 50},
+		},
+	},
+	{
+		Key:          "inventory_alerts",
+		Name:         "库存预警",
+		Description:  "库存低于阈值的彩票类型和商品",
+		Endpoint:     "/api/admin/inventory/alerts",
+		Sizes:        []string{WidgetSizeSmall, WidgetSizeMedium},
+		DefaultSize:  WidgetSizeSmall,
+		Settings:     []WidgetSetting{},
+		PlatformOnly: true,
+	},
+	{
+		Key:          "wallet_webhooks",
+		Name:         "钱包 Webhook",
+		Description:  "Webhook 投递状态",
+		Endpoint:     "/api/admin/wallet-webhooks",
+		Sizes:        []string{WidgetSizeSmall, WidgetSizeMedium},
+		DefaultSize:  WidgetSizeSmall,
+		Settings:     []WidgetSetting{},
+		PlatformOnly: true,
+	},
+}
+
+// defaultDashboardWidgets is the layout of admins that have not saved one
+var defaultDashboardWidgets = []string{"overview", "sales_trend", "sales_forecast", "inventory_alerts", "admin_logs"}
+
+// DashboardService handles per-admin dashboard layouts
+type DashboardService struct {
+	db       *gorm.DB
+	platform bool
+}
+
+// NewDashboardService creates a new dashboard service
+func NewDashboardService(db *gorm.DB) *DashboardService {
+	return &DashboardService{db: db, platform: true}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *DashboardService) ForTenant(tenantID uint) *DashboardService {
+	return &DashboardService{
+		db:       repository.ScopeTenant(s.db, tenantID),
+		platform: tenantID == repository.DefaultTenantID,
+	}
+}
+
+// DashboardWidgetConfig is a widget placed on a dashboard
+type DashboardWidgetConfig struct {
+	Widget   string                 `json:"widget"`
+	Size     string                 `json:"size"`
+	Settings map[string]interface{} `json:"settings"`
+}
+
+// DashboardLayoutResponse represents the dashboard layout of an admin
+type DashboardLayoutResponse struct {
+	Widgets   []DashboardWidgetConfig `json:"widgets"`
+	IsDefault bool                    `json:"is_default"`
+	UpdatedAt *time.Time              `json:"updated_at,omitempty"`
+}
+
+// SaveDashboardLayoutRequest represents a request to save a dashboard layout.
+// Widgets are shown in the given order.
+type SaveDashboardLayoutRequest struct {
+	Widgets []DashboardWidgetConfig `json:"widgets"`
+}
+
+// GetCatalog returns the widgets available to the admin
+func (s *DashboardService) GetCatalog() []DashboardWidget {
+	widgets := make([]DashboardWidget, 0, len(dashboardWidgets))
+	for _, widget := range dashboardWidgets {
+		if widget.PlatformOnly && !s.platform {
+			continue
+		}
+		widgets = append(widgets, widget)
+	}
+	return widgets
+}
+
+// GetLayout returns the saved layout of an admin, or the default layout.
+// Widgets no longer available are dropped and missing settings get their defaults.
+func (s *DashboardService) GetLayout(adminID uint) (*DashboardLayoutResponse, error) {
+	var layout model.DashboardLayout
+	err := s.db.Where("admin_id = ?", adminID).First(&layout).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &DashboardLayoutResponse{Widgets: s.defaultLayout(), IsDefault: true}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var saved []DashboardWidgetConfig
+	if err := json.Unmarshal([]byte(layout.Widgets), &saved); err != nil {
+		return nil, err
+	}
+	widgets := make([]DashboardWidgetConfig, 0, len(saved))
+	for _, config := range saved {
+		widget, ok := s.widget(config.Widget)
+		if !ok {
+			continue
+		}
+		widgets = append(widgets, withDefaults(widget, config))
+	}
+
+	updatedAt := layout.UpdatedAt
+	return &DashboardLayoutResponse{Widgets: widgets, UpdatedAt: &updatedAt}, nil
+}
+
+// SaveLayout validates and saves the dashboard layout of an admin
+func (s *DashboardService) SaveLayout(adminID uint, req SaveDashboardLayoutRequest) (*DashboardLayoutResponse, error) {
+	if len(req.Widgets) > maxDashboardWidgets {
+		return nil, ErrTooManyWidgets
+	}
+
+	seen := make(map[string]bool)
+	widgets := make([]DashboardWidgetConfig, 0, len(req.Widgets))
+	for _, config := range req.Widgets {
+		widget, ok := s.widget(config.Widget)
+		if !ok {
+			return nil, ErrUnknownWidget
+		}
+		if seen[widget.Key] {
+			return nil, ErrDuplicateWidget
+		}
+		seen[widget.Key] = true
+
+		if err := validateWidgetConfig(widget, config); err != nil {
+			return nil, err
+		}
+		widgets = append(widgets, withDefaults(widget, config))
+	}
+
+	widgetsJSON, err := json.Marshal(widgets)
+	if err != nil {
+		return nil, err
+	}
+
+	var layout model.DashboardLayout
+	err = s.db.Where("admin_id = ?", adminID).First(&layout).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		layout = model.DashboardLayout{AdminID: adminID, Widgets: string(widgetsJSON)}
+		err = s.db.Create(&layout).Error
+	} else if err == nil {
+		layout.Widgets = string(widgetsJSON)
+		err = s.db.Save(&layout).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &DashboardLayoutResponse{Widgets: widgets, UpdatedAt: &layout.UpdatedAt}, nil
+}
+
+// ResetLayout removes the saved layout of an admin so the default is used again
+func (s *DashboardService) ResetLayout(adminID uint) (*DashboardLayoutResponse, error) {
+	if err := s.db.Unscoped().Where("admin_id = ?", adminID).Delete(&model.DashboardLayout{}).Error; err != nil {
+		return nil, err
+	}
+	return &DashboardLayoutResponse{Widgets: s.defaultLayout(), IsDefault: true}, nil
+}
+
+// widget looks up an available widget by key
+func (s *DashboardService) widget(key string) (DashboardWidget, bool) {
+	for _, widget := range s.GetCatalog() {
+		if widget.Key == key {
+			return widget, true
+		}
+	}
+	return DashboardWidget{}, false
+}
+
+// defaultLayout returns the default widgets available to the admin
+func (s *DashboardService) defaultLayout() []DashboardWidgetConfig {
+	widgets := make([]DashboardWidgetConfig, 0, len(defaultDashboardWidgets))
+	for _, key := range defaultDashboardWidgets {
+		if widget, ok := s.widget(key); ok {
+			widgets = append(widgets, withDefaults(widget, DashboardWidgetConfig{Widget: key}))
+		}
+	}
+	return widgets
+}
+
+// validateWidgetConfig checks the size and settings of a placed widget
+func validateWidgetConfig(widget DashboardWidget, config DashboardWidgetConfig) error {
+	if config.Size != "" && !containsString(widget.Sizes, config.Size) {
+		return ErrInvalidWidgetSize
+	}
+
+	for key, value := range config.Settings {
+		var setting *WidgetSetting
+		for i := range widget.Settings {
+			if widget.Settings[i].Key == key {
+				setting = &widget.Settings[i]
+				break
+			}
+		}
+		if setting == nil || !validWidgetSetting(*setting, value) {
+			return ErrInvalidWidgetSetting
+		}
+	}
+	return nil
+}
+
+// validWidgetSetting checks a setting value against its definition. JSON
+// numbers are decoded as float64 and must be whole.
+func validWidgetSetting(setting WidgetSetting, value interface{}) bool {
+	switch setting.Type {
+	case WidgetSettingNumber:
+		number, ok := value.(float64)
+		return ok && number == math.Trunc(number) && number >= float64(setting.Min) && number <= float64(setting.Max)
+	case WidgetSettingSelect:
+		option, ok := value.(string)
+		return ok && containsString(setting.Options, option)
+	}
+	return false
+}
+
+// withDefaults fills in the default size and settings of a placed widget,
+// replacing values that are no longer valid
+func withDefaults(widget DashboardWidget, config DashboardWidgetConfig) DashboardWidgetConfig {
+	result := DashboardWidgetConfig{
+		Widget:   widget.Key,
+		Size:     config.Size,
+		Settings: make(map[string]interface{}, len(widget.Settings)),
+	}
+	if result.Size == "" || !containsString(widget.Sizes, result.Size) {
+		result.Size = widget.DefaultSize
+	}
+	for _, setting := range widget.Settings {
+		if value, ok := config.Settings[setting.Key]; ok && validWidgetSetting(setting, value) {
+			result.Settings[setting.Key] = value
+		} else {
+			result.Settings[setting.Key] = setting.Default
+		}
+	}
+	return result
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
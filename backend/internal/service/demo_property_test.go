@@ -0,0 +1,109 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+)
+
+// Demo content: the bootstrap runs once per tenant, skips tenants with
+// content of their own, and is not recreated after removal.
+func TestDemoSeedOnce(t *testing.T) {
+	db := setupTenantTestDB(t)
+	demo := NewDemoService(db).ForTenant(1)
+
+	seeded, err := demo.Seed()
+	if err != nil || !seeded {
+		t.Fatalf("Expected the demo content to be seeded, got %v (err %v)", seeded, err)
+	}
+	content, err := demo.GetDemoContent()
+	if err != nil {
+		t.Fatalf("GetDemoContent failed: %v", err)
+	}
+	if !content.Seeded || content.LotteryTypes != 1 || content.Products != 3 || content.Users != 1 {
+		t.Errorf("Unexpected demo content %+v", content)
+	}
+
+	if stock := NewLotteryService(db, testEncryptionKey).calculateStock(1); stock != 10000 {
+		t.Errorf("Expected 10000 demo tickets in stock, got %d", stock)
+	}
+	var cardKeys int64
+	db.Model(&model.CardKey{}).Where("key_content LIKE ?", "DEMO-%").Count(&cardKeys)
+	if cardKeys != 3*demoCardKeysPerProduct {
+		t.Errorf("Expected %d demo card keys, got %d", 3*demoCardKeysPerProduct, cardKeys)
+	}
+
+	if seeded, err := demo.Seed(); err != nil || seeded {
+		t.Errorf("Expected a second seed to do nothing, got %v (err %v)", seeded, err)
+	}
+	if content, _ := demo.GetDemoContent(); content.LotteryTypes != 1 {
+		t.Errorf("Expected a single demo lottery type, got %d", content.LotteryTypes)
+	}
+
+	// A tenant with its own products keeps its storefront as is
+	other := NewDemoService(db).ForTenant(2)
+	if err := repository.ScopeTenant(db, 2).Create(&model.Product{Name: "Own product", Price: 10}).Error; err != nil {
+		t.Fatalf("Failed to create product: %v", err)
+	}
+	if seeded, err := other.Seed(); err != nil || seeded {
+		t.Errorf("Expected tenant 2 to be skipped, got %v (err %v)", seeded, err)
+	}
+	if content, _ := other.GetDemoContent(); !content.Seeded || content.Products != 0 {
+		t.Errorf("Expected tenant 2 to be marked seeded without demo content, got %+v", content)
+	}
+}
+
+// Demo content: removal is refused while demo tickets are unscratched, then
+// deletes the demo content and is audited.
+func TestDemoRemove(t *testing.T) {
+	db := setupTenantTestDB(t)
+	demo := NewDemoService(db).ForTenant(1)
+	if _, err := demo.Seed(); err != nil {
+		t.Fatalf("Seed failed: %v", err)
+	}
+
+	var lotteryType model.LotteryType
+	db.Where("demo = ?", true).First(&lotteryType)
+	var pool model.PrizePool
+	db.Where("lottery_type_id = ?", lotteryType.ID).First(&pool)
+	ticket := model.Ticket{UserID: 1, LotteryTypeID: lotteryType.ID, PrizePoolID: pool.ID, SecurityCode: "DEMO000000000001", PurchasedAt: time.Now()}
+	db.Create(&ticket)
+
+	if _, err := demo.RemoveDemoContent(1); err != ErrDemoHasUnscratched {
+		t.Fatalf("Expected ErrDemoHasUnscratched, got %v", err)
+	}
+
+	db.Model(&ticket).Update("status", model.TicketStatusScratched)
+	removed, err := demo.RemoveDemoContent(1)
+	if err != nil {
+		t.Fatalf("RemoveDemoContent failed: %v", err)
+	}
+	if removed.LotteryTypes != 1 || removed.Products != 3 || removed.Users != 1 {
+		t.Errorf("Unexpected removed content %+v", removed)
+	}
+
+	content, _ := demo.GetDemoContent()
+	if content.LotteryTypes != 0 || content.Products != 0 || content.Users != 0 {
+		t.Errorf("Expected no demo content left, got %+v", content)
+	}
+	db.First(&pool, pool.ID)
+	if pool.Status != model.PrizePoolStatusClosed {
+		t.Errorf("Expected the demo pool to be closed, got %s", pool.Status)
+	}
+	var cardKeys int64
+	db.Model(&model.CardKey{}).Count(&cardKeys)
+	if cardKeys != 0 {
+		t.Errorf("Expected the demo card keys to be deleted, %d left", cardKeys)
+	}
+	var logs int64
+	db.Model(&model.AdminLog{}).Where("action = ?", "remove_demo_content").Count(&logs)
+	if logs != 1 {
+		t.Errorf("Expected 1 admin log, got %d", logs)
+	}
+
+	if seeded, err := demo.Seed(); err != nil || seeded {
+		t.Errorf("Expected removed demo content not to return, got %v (err %v)", seeded, err)
+	}
+}
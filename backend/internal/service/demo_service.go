@@ -0,0 +1,233 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// ConfigKeyDemoSeeded marks that the demo bootstrap ran, so demo content
+// removed by an admin is not created again on the next boot
+const ConfigKeyDemoSeeded = "demo_seeded"
+
+// demoCardKeysPerProduct is the card key stock of each demo product
+const demoCardKeysPerProduct = 20
+
+var (
+	ErrDemoHasUnscratched = errors.New("demo lottery has unscratched tickets")
+)
+
+// DemoService creates and removes the demo content a new deployment can boot
+// with: a lottery type with prize levels and an active pool, a few exchange
+// products with card keys, and a demo admin. All of it is tagged Demo.
+type DemoService struct {
+	db *gorm.DB
+}
+
+// DemoContentResponse counts the demo content present
+type DemoContentResponse struct {
+	Seeded       bool  `json:"seeded"`
+	LotteryTypes int64 `json:"lottery_types"`
+	Products     int64 `json:"products"`
+	Users        int64 `json:"users"`
+}
+
+// NewDemoService creates a new demo service
+func NewDemoService(db *gorm.DB) *DemoService {
+	return &DemoService{db: db}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *DemoService) ForTenant(tenantID uint) *DemoService {
+	return &DemoService{db: repository.ScopeTenant(s.db, tenantID)}
+}
+
+// Seed creates the demo content on first boot. It does nothing once it ran,
+// even if the demo content was removed since, and skips deployments that
+// already have lottery types or products of their own. It reports whether
+// content was created.
+func (s *DemoService) Seed() (bool, error) {
+	seeded := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var marker int64
+		if err := tx.Model(&model.SystemConfig{}).Where("key = ?", ConfigKeyDemoSeeded).Count(&marker).Error; err != nil {
+			return err
+		}
+		if marker > 0 {
+			return nil
+		}
+
+		var lotteryTypes, products int64
+		if err := tx.Model(&model.LotteryType{}).Count(&lotteryTypes).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Product{}).Count(&products).Error; err != nil {
+			return err
+		}
+		if lotteryTypes == 0 && products == 0 {
+			if err := seedDemoContent(tx); err != nil {
+				return err
+			}
+			seeded = true
+		}
+
+		return tx.Create(&model.SystemConfig{Key: ConfigKeyDemoSeeded, Value: time.Now().Format(time.RFC3339)}).Error
+	})
+	if err != nil {
+		return false, err
+	}
+	return seeded, nil
+}
+
+// seedDemoContent creates the demo lottery type, products and admin
+func seedDemoContent(tx *gorm.DB) error {
+	lotteryType := model.LotteryType{
+		Name:        "演示·好运连连",
+		Description: "演示彩票：刮开覆盖膜，如果在同一局游戏中刮出3个相同的图符，即中得该图符所对应的奖金。",
+		Price:       10,
+		MaxPrize:    5000,
+		GameType:    model.GameTypeSymbolMatch,
+		CoverImage:  "/images/lottery/baifabaizhong.png",
+		RulesConfig: `{"match_count": 3, "win_symbols": ["金元宝", "红包", "福字", "鞭炮"]}`,
+		Status:      model.LotteryTypeStatusAvailable,
+		Demo:        true,
+	}
+	if err := tx.Create(&lotteryType).Error; err != nil {
+		return err
+	}
+
+	// 10000 tickets at 10 points return half of the sales as prizes
+	prizeLevels := []model.PrizeLevel{
+		{LotteryTypeID: lotteryType.ID, Level: 1, Name: "特等奖", PrizeAmount: 5000, Quantity: 1, Remaining: 1},
+		{LotteryTypeID: lotteryType.ID, Level: 2, Name: "一等奖", PrizeAmount: 500, Quantity: 10, Remaining: 10},
+		{LotteryTypeID: lotteryType.ID, Level: 3, Name: "二等奖", PrizeAmount: 100, Quantity: 100, Remaining: 100},
+		{LotteryTypeID: lotteryType.ID, Level: 4, Name: "三等奖", PrizeAmount: 20, Quantity: 500, Remaining: 500},
+		{LotteryTypeID: lotteryType.ID, Level: 5, Name: "四等奖", PrizeAmount: 10, Quantity: 2000, Remaining: 2000},
+	}
+	if err := tx.Create(&prizeLevels).Error; err != nil {
+		return err
+	}
+	pool := model.PrizePool{
+		LotteryTypeID: lotteryType.ID,
+		TotalTickets:  10000,
+		ReturnRate:    0.50,
+		Status:        model.PrizePoolStatusActive,
+	}
+	if err := tx.Create(&pool).Error; err != nil {
+		return err
+	}
+
+	products := []model.Product{
+		{Name: "演示·视频会员月卡", Description: "演示商品，卡密仅供体验，不可实际使用。", Price: 300, Demo: true},
+		{Name: "演示·音乐会员季卡", Description: "演示商品，卡密仅供体验，不可实际使用。", Price: 500, Demo: true},
+		{Name: "演示·网盘扩容券", Description: "演示商品，卡密仅供体验，不可实际使用。", Price: 100, Demo: true},
+	}
+	for i := range products {
+		products[i].Status = model.ProductStatusAvailable
+		products[i].Stock = demoCardKeysPerProduct
+		if err := tx.Create(&products[i]).Error; err != nil {
+			return err
+		}
+		cardKeys := make([]model.CardKey, demoCardKeysPerProduct)
+		for j := range cardKeys {
+			cardKeys[j] = model.CardKey{
+				ProductID:  products[i].ID,
+				KeyContent: fmt.Sprintf("DEMO-%02d-%04d", i+1, j+1),
+				Status:     model.CardKeyStatusAvailable,
+			}
+		}
+		if err := tx.Create(&cardKeys).Error; err != nil {
+			return err
+		}
+	}
+
+	admin := model.User{LinuxdoID: "demo_admin", Username: "演示管理员", Role: "admin", Demo: true}
+	if err := tx.Create(&admin).Error; err != nil {
+		return err
+	}
+	return tx.Create(&model.Wallet{UserID: admin.ID, Balance: 0}).Error
+}
+
+// GetDemoContent counts the demo content present
+func (s *DemoService) GetDemoContent() (*DemoContentResponse, error) {
+	var content DemoContentResponse
+	var marker int64
+	if err := s.db.Model(&model.SystemConfig{}).Where("key = ?", ConfigKeyDemoSeeded).Count(&marker).Error; err != nil {
+		return nil, err
+	}
+	content.Seeded = marker > 0
+	if err := s.db.Model(&model.LotteryType{}).Where("demo = ?", true).Count(&content.LotteryTypes).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(&model.Product{}).Where("demo = ?", true).Count(&content.Products).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(&model.User{}).Where("demo = ?", true).Count(&content.Users).Error; err != nil {
+		return nil, err
+	}
+	return &content, nil
+}
+
+// RemoveDemoContent deletes the demo content. Demo lottery types are removed
+// like deleted lottery types: their pools close and tickets bought from them
+// keep showing them, so removal is refused while any of those tickets is
+// unscratched. Unredeemed card keys of demo products are deleted with them.
+func (s *DemoService) RemoveDemoContent(adminID uint) (*DemoContentResponse, error) {
+	removed, err := s.GetDemoContent()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		lotteryTypeIDs := tx.Model(&model.LotteryType{}).Select("id").Where("demo = ?", true)
+		var unscratched int64
+		if err := tx.Model(&model.Ticket{}).
+			Where("lottery_type_id IN (?) AND status IN ?", lotteryTypeIDs, unscratchedTicketStatuses).
+			Count(&unscratched).Error; err != nil {
+			return err
+		}
+		if unscratched > 0 {
+			return ErrDemoHasUnscratched
+		}
+		if err := tx.Model(&model.PrizePool{}).
+			Where("lottery_type_id IN (?) AND status = ?", lotteryTypeIDs, model.PrizePoolStatusActive).
+			Update("status", model.PrizePoolStatusClosed).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("demo = ?", true).Delete(&model.LotteryType{}).Error; err != nil {
+			return err
+		}
+
+		productIDs := tx.Model(&model.Product{}).Select("id").Where("demo = ?", true)
+		if err := tx.Where("product_id IN (?) AND status = ?", productIDs, model.CardKeyStatusAvailable).
+			Delete(&model.CardKey{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("demo = ?", true).Delete(&model.Product{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("demo = ?", true).Delete(&model.User{}).Error; err != nil {
+			return err
+		}
+
+		details, _ := json.Marshal(removed)
+		adminLog := model.AdminLog{
+			AdminID:    adminID,
+			Action:     "remove_demo_content",
+			TargetType: "system",
+			TargetID:   0,
+			Details:    string(details),
+		}
+		return tx.Create(&adminLog).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"image"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"scratch-lottery/internal/model"
+)
+
+// Design asset kinds
+const (
+	DesignAssetImage = "image"
+	DesignAssetSound = "sound"
+)
+
+// DesignAnimationPresets are the win animations frontends implement
+var DesignAnimationPresets = []string{"none", "confetti", "fireworks", "coins", "shake"}
+
+var (
+	ErrInvalidDesignConfig = errors.New("invalid design config")
+)
+
+// designSoundTypes maps the accepted sniffed audio content types to file
+// extensions
+var designSoundTypes = map[string]string{
+	"audio/mpeg":      "mp3",
+	"application/ogg": "ogg",
+	"audio/wave":      "wav",
+}
+
+// DesignConfig is the visual and audio design of a lottery type. Asset
+// fields hold http(s) URLs or site paths, such as those returned by the
+// design asset upload.
+type DesignConfig struct {
+	Background      string       `json:"background,omitempty"`       // #RGB/#RRGGBB color or image URL
+	ScratchTexture  string       `json:"scratch_texture,omitempty"`  // Image of the scratch-off coating
+	Sounds          DesignSounds `json:"sounds"`                     // Empty sounds are not played
+	AnimationPreset string       `json:"animation_preset,omitempty"` // One of DesignAnimationPresets, none when empty
+}
+
+// DesignSounds are the sounds played while scratching a ticket
+type DesignSounds struct {
+	Scratch string `json:"scratch,omitempty"`
+	Win     string `json:"win,omitempty"`
+	Lose    string `json:"lose,omitempty"`
+}
+
+// DesignAsset is an uploaded design asset
+type DesignAsset struct {
+	Kind string `json:"kind"`
+	URL  string `json:"url"`
+	Size int    `json:"size"`
+}
+
+// validate checks the colors, asset URLs and animation preset of a design
+func (d *DesignConfig) validate() error {
+	if d.Background != "" && !brandingColorPattern.MatchString(d.Background) && !isBrandingURL(d.Background) {
+		return ErrInvalidDesignConfig
+	}
+	for _, link := range []string{d.ScratchTexture, d.Sounds.Scratch, d.Sounds.Win, d.Sounds.Lose} {
+		if link != "" && !isBrandingURL(link) {
+			return ErrInvalidDesignConfig
+		}
+	}
+	if d.AnimationPreset != "" {
+		for _, preset := range DesignAnimationPresets {
+			if d.AnimationPreset == preset {
+				return nil
+			}
+		}
+		return ErrInvalidDesignConfig
+	}
+	return nil
+}
+
+// parseDesignConfig reads a stored design config. Designs stored before the
+// schema keep the fields it knows.
+func parseDesignConfig(value string) *DesignConfig {
+	if value == "" {
+		return nil
+	}
+	var design DesignConfig
+	if err := json.Unmarshal([]byte(value), &design); err != nil {
+		return nil
+	}
+	return &design
+}
+
+// DesignConfigSchema returns the JSON schema of DesignConfig for tooling
+func DesignConfigSchema() map[string]interface{} {
+	asset := func(description string) map[string]interface{} {
+		return map[string]interface{}{
+			"type":        "string",
+			"description": description + "; http(s) URL or site path",
+		}
+	}
+	return map[string]interface{}{
+		"$schema":              "https://json-schema.org/draft/2020-12/schema",
+		"title":                "DesignConfig",
+		"type":                 "object",
+		"additionalProperties": false,
+		"properties": map[string]interface{}{
+			"background": map[string]interface{}{
+				"type":        "string",
+				"description": "#RGB or #RRGGBB color, or background image; http(s) URL or site path",
+			},
+			"scratch_texture": asset("Image of the scratch-off coating"),
+			"sounds": map[string]interface{}{
+				"type":                 "object",
+				"additionalProperties": false,
+				"properties": map[string]interface{}{
+					"scratch": asset("Sound played while scratching"),
+					"win":     asset("Sound played when the ticket wins"),
+					"lose":    asset("Sound played when the ticket loses"),
+				},
+			},
+			"animation_preset": map[string]interface{}{
+				"type":        "string",
+				"enum":        DesignAnimationPresets,
+				"description": "Animation played when the ticket wins",
+			},
+		},
+	}
+}
+
+// UploadDesignAsset validates an uploaded lottery design image or sound and
+// stores it under a content addressed name next to the branding assets
+func (s *BrandingService) UploadDesignAsset(adminID uint, r io.Reader) (*DesignAsset, error) {
+	data, err := io.ReadAll(io.LimitReader(r, s.maxAssetBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > s.maxAssetBytes {
+		return nil, ErrBrandingAssetTooBig
+	}
+
+	contentType := http.DetectContentType(data)
+	kind := DesignAssetImage
+	ext, ok := brandingAssetTypes[contentType]
+	if ok {
+		config, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil || config.Width < 1 || config.Height < 1 ||
+			config.Width > maxBrandingAssetDimension || config.Height > maxBrandingAssetDimension {
+			return nil, ErrBrandingAssetInvalid
+		}
+	} else if ext, ok = designSoundTypes[contentType]; ok {
+		kind = DesignAssetSound
+	} else {
+		return nil, ErrBrandingAssetType
+	}
+
+	sum := sha256.Sum256(data)
+	name := hex.EncodeToString(sum[:]) + "." + ext
+	if err := os.MkdirAll(s.assetDir, 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(s.assetDir, name), data, 0644); err != nil {
+		return nil, err
+	}
+
+	asset := &DesignAsset{Kind: kind, URL: BrandingAssetPath + name, Size: len(data)}
+	details, _ := json.Marshal(asset)
+	adminLog := model.AdminLog{
+		AdminID:    adminID,
+		Action:     "upload_design_asset",
+		TargetType: "system",
+		TargetID:   0,
+		Details:    string(details),
+	}
+	if err := s.adminService.db.Create(&adminLog).Error; err != nil {
+		return nil, err
+	}
+	return asset, nil
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+)
+
+// Design config: updates with well-formed colors, asset URLs and presets are
+// stored and served back typed; anything else is refused.
+func TestDesignConfigValidation(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	db := setupTenantTestDB(t)
+	lotteryTypeID := createOddsHintPool(t, db, 10, 0, nil)
+	lotteries := NewLotteryService(db, testEncryptionKey)
+
+	backgrounds := []string{"", "#fff", "#12ab9F", "/api/system/branding/assets/bg.png", "https://cdn.example.com/bg.jpg", "red", "#12345", "javascript:alert(1)"}
+	links := []string{"", "/assets/scratch.mp3", "https://cdn.example.com/win.ogg", "//evil.example.com/x.mp3", "ftp://example.com/a.wav", "/a\"b.mp3"}
+	presets := []string{"", "none", "confetti", "fireworks", "explode"}
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("designs are stored iff every field is well-formed", prop.ForAll(
+		func(background, texture, sound, preset int) bool {
+			design := DesignConfig{
+				Background:      backgrounds[background],
+				ScratchTexture:  links[texture],
+				Sounds:          DesignSounds{Win: links[sound]},
+				AnimationPreset: presets[preset],
+			}
+			valid := background < 5 && texture < 3 && sound < 3 && preset < 4
+
+			var before model.LotteryType
+			db.First(&before, lotteryTypeID)
+			result, err := lotteries.UpdateLotteryType(lotteryTypeID, UpdateLotteryTypeRequest{DesignConfig: &design})
+			if !valid {
+				var after model.LotteryType
+				db.First(&after, lotteryTypeID)
+				if err != ErrInvalidDesignConfig || after.DesignConfig != before.DesignConfig {
+					t.Logf("Design %+v: expected refusal, got %v", design, err)
+					return false
+				}
+				return true
+			}
+			if err != nil {
+				t.Logf("Design %+v: expected success, got %v", design, err)
+				return false
+			}
+			if result.DesignConfig == nil || *result.DesignConfig != design {
+				t.Logf("Expected design %+v, got %+v", design, result.DesignConfig)
+				return false
+			}
+			return true
+		},
+		gen.IntRange(0, len(backgrounds)-1),
+		gen.IntRange(0, len(links)-1),
+		gen.IntRange(0, len(links)-1),
+		gen.IntRange(0, len(presets)-1),
+	))
+
+	properties.TestingRun(t)
+}
+
+// Design config: free-form designs stored before the schema are served with
+// the fields it knows.
+func TestDesignConfigLegacy(t *testing.T) {
+	db := setupTenantTestDB(t)
+	lotteryTypeID := createOddsHintPool(t, db, 10, 0, nil)
+	lotteries := NewLotteryService(db, testEncryptionKey)
+
+	db.Model(&model.LotteryType{}).Where("id = ?", lotteryTypeID).
+		Update("design_config", `{"background":"#000","theme":"gold","sounds":{"win":"/win.mp3"}}`)
+	detail, err := lotteries.GetLotteryTypeByID(lotteryTypeID)
+	if err != nil {
+		t.Fatalf("GetLotteryTypeByID failed: %v", err)
+	}
+	if detail.DesignConfig == nil || detail.DesignConfig.Background != "#000" || detail.DesignConfig.Sounds.Win != "/win.mp3" {
+		t.Errorf("Unexpected legacy design %+v", detail.DesignConfig)
+	}
+
+	db.Model(&model.LotteryType{}).Where("id = ?", lotteryTypeID).Update("design_config", `not json`)
+	if detail, err := lotteries.GetLotteryTypeByID(lotteryTypeID); err != nil || detail.DesignConfig != nil {
+		t.Errorf("Expected unreadable designs to be omitted, got %+v (err %v)", detail, err)
+	}
+
+	schema := DesignConfigSchema()
+	properties, _ := schema["properties"].(map[string]interface{})
+	for _, field := range []string{"background", "scratch_texture", "sounds", "animation_preset"} {
+		if _, ok := properties[field]; !ok {
+			t.Errorf("Schema is missing %s", field)
+		}
+	}
+}
+
+// Design assets: sounds and images are stored like branding assets and
+// served from the branding asset path.
+func TestDesignAssetUpload(t *testing.T) {
+	db := setupTenantTestDB(t)
+	branding := NewBrandingService(NewAdminService(db, NewWalletService(db)), t.TempDir(), 1)
+
+	wav := []byte("RIFF")
+	wav = binary.LittleEndian.AppendUint32(wav, 36)
+	wav = append(wav, []byte("WAVEfmt ")...)
+	wav = append(wav, make([]byte, 32)...)
+	asset, err := branding.UploadDesignAsset(1, bytes.NewReader(wav))
+	if err != nil {
+		t.Fatalf("UploadDesignAsset failed: %v", err)
+	}
+	if asset.Kind != DesignAssetSound || !strings.HasSuffix(asset.URL, ".wav") || asset.Size != len(wav) {
+		t.Errorf("Unexpected sound asset %+v", asset)
+	}
+	if _, err := branding.AssetPath(strings.TrimPrefix(asset.URL, BrandingAssetPath)); err != nil {
+		t.Errorf("Expected the sound to be served, got %v", err)
+	}
+
+	if _, err := branding.UploadDesignAsset(1, strings.NewReader("<svg></svg>")); err != ErrBrandingAssetType {
+		t.Errorf("Expected ErrBrandingAssetType, got %v", err)
+	}
+
+	var logs int64
+	db.Model(&model.AdminLog{}).Where("action = ?", "upload_design_asset").Count(&logs)
+	if logs != 1 {
+		t.Errorf("Expected 1 upload log, got %d", logs)
+	}
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+	"gorm.io/gorm"
+)
+
+func setupDigestTest(t *testing.T) (*gorm.DB, *DigestService) {
+	db := setupTenantTestDB(t)
+	if err := db.AutoMigrate(&model.Notification{}, &model.NotificationPreference{}, &model.ExchangeGift{}); err != nil {
+		t.Fatalf("Failed to migrate digest tables: %v", err)
+	}
+	return db, NewDigestService(db, NewNotificationService(db))
+}
+
+func createDigestUser(t *testing.T, db *gorm.DB, name string) (model.User, model.Wallet) {
+	user := model.User{LinuxdoID: name, Username: name, Role: "user"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	wallet := model.Wallet{UserID: user.ID}
+	if err := db.Create(&wallet).Error; err != nil {
+		t.Fatalf("Failed to create wallet: %v", err)
+	}
+	return user, wallet
+}
+
+func countDigests(db *gorm.DB, userID uint) int64 {
+	var count int64
+	db.Model(&model.Notification{}).Where("user_id = ? AND type = ?", userID, model.NotificationTypeDigest).Count(&count)
+	return count
+}
+
+// Weekly digest: the digest counts unscratched tickets, wins within the last
+// week and pending gifts expiring soon, and leaves out everything else.
+func TestDigestContents(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("digest matches the user's tickets, wins and gifts", prop.ForAll(
+		func(unscratched, scratched int, winDays []int, giftHours []int) bool {
+			db, digests := setupDigestTest(t)
+			user, wallet := createDigestUser(t, db, "digest_user")
+			now := time.Now()
+
+			for i := 0; i < unscratched+scratched; i++ {
+				status := model.TicketStatusUnscratched
+				if i >= unscratched {
+					status = model.TicketStatusScratched
+				}
+				db.Create(&model.Ticket{UserID: user.ID, SecurityCode: fmt.Sprintf("DIGEST%04d", i), Status: status, PurchasedAt: now})
+			}
+
+			expectedWon := 0
+			for i, days := range winDays {
+				amount := 10 * (i + 1)
+				db.Create(&model.Transaction{WalletID: wallet.ID, Type: model.TransactionTypeWin, Amount: amount, CreatedAt: now.AddDate(0, 0, -days).Add(-time.Minute)})
+				if days < 7 {
+					expectedWon += amount
+				}
+			}
+			// Other transaction types never count as winnings
+			db.Create(&model.Transaction{WalletID: wallet.ID, Type: model.TransactionTypeRecharge, Amount: 500, CreatedAt: now})
+
+			var expectedGifts int64
+			for _, hours := range giftHours {
+				db.Create(&model.ExchangeGift{RecipientID: user.ID, Status: model.ExchangeGiftStatusPending, ExpiresAt: now.Add(time.Duration(hours) * time.Hour)})
+				if hours > 0 && hours <= 72 {
+					expectedGifts++
+				}
+			}
+			db.Create(&model.ExchangeGift{RecipientID: user.ID, Status: model.ExchangeGiftStatusAccepted, ExpiresAt: now.Add(time.Hour)})
+
+			digest, err := digests.Build(user.ID, now)
+			if err != nil {
+				t.Logf("Build failed: %v", err)
+				return false
+			}
+			if digest.UnscratchedTickets != int64(unscratched) || digest.WonPoints != expectedWon || digest.ExpiringGifts != expectedGifts {
+				t.Logf("Got %+v, expected %d unscratched, %d won, %d gifts", digest, unscratched, expectedWon, expectedGifts)
+				return false
+			}
+			return true
+		},
+		gen.IntRange(0, 5),
+		gen.IntRange(0, 3),
+		gen.SliceOfN(3, gen.IntRange(0, 14)),
+		gen.SliceOfN(3, gen.IntRange(-24, 120)),
+	))
+
+	properties.TestingRun(t)
+}
+
+// Weekly digest: opted-in users get one digest per week on the notification
+// channel of their tenant, and nobody else gets any.
+func TestDigestDelivery(t *testing.T) {
+	db, digests := setupDigestTest(t)
+	subscriber, _ := createDigestUser(t, db, "subscriber")
+	optedOut, _ := createDigestUser(t, db, "opted_out")
+	idle, _ := createDigestUser(t, db, "idle")
+
+	yes, no := true, false
+	for _, userID := range []uint{subscriber.ID, idle.ID} {
+		if _, err := digests.UpdatePreferences(userID, UpdateNotificationPreferencesRequest{WeeklyDigest: &yes}); err != nil {
+			t.Fatalf("UpdatePreferences failed: %v", err)
+		}
+	}
+	if _, err := digests.UpdatePreferences(optedOut.ID, UpdateNotificationPreferencesRequest{WeeklyDigest: &yes}); err != nil {
+		t.Fatalf("UpdatePreferences failed: %v", err)
+	}
+	prefs, err := digests.UpdatePreferences(optedOut.ID, UpdateNotificationPreferencesRequest{WeeklyDigest: &no})
+	if err != nil {
+		t.Fatalf("UpdatePreferences failed: %v", err)
+	}
+	if prefs.WeeklyDigest {
+		t.Fatalf("Expected the digest to be turned off")
+	}
+
+	for i, userID := range []uint{subscriber.ID, optedOut.ID} {
+		db.Create(&model.Ticket{UserID: userID, SecurityCode: fmt.Sprintf("SEND%04d", i), Status: model.TicketStatusUnscratched, PurchasedAt: time.Now()})
+	}
+
+	now := time.Now()
+	sent, err := digests.SendDue(now)
+	if err != nil {
+		t.Fatalf("SendDue failed: %v", err)
+	}
+	if sent != 1 || countDigests(db, subscriber.ID) != 1 {
+		t.Fatalf("Expected one digest for the subscriber, sent %d", sent)
+	}
+	if countDigests(db, optedOut.ID) != 0 || countDigests(db, idle.ID) != 0 {
+		t.Errorf("Expected no digests for opted-out or idle users")
+	}
+
+	// Nothing more within the week
+	if sent, _ := digests.SendDue(now.Add(6 * 24 * time.Hour)); sent != 0 {
+		t.Errorf("Expected no digests within the week, sent %d", sent)
+	}
+	if sent, _ := digests.SendDue(now.Add(7 * 24 * time.Hour)); sent != 1 {
+		t.Errorf("Expected the next digest after a week, sent %d", sent)
+	}
+}
+
+// Weekly digest: digests of users of another tenant are delivered in that tenant.
+func TestDigestTenantDelivery(t *testing.T) {
+	db, digests := setupDigestTest(t)
+	user, _ := createDigestUser(t, db, "tenant_user")
+	db.Model(&user).Update("tenant_id", 2)
+
+	yes := true
+	if _, err := digests.ForTenant(2).UpdatePreferences(user.ID, UpdateNotificationPreferencesRequest{WeeklyDigest: &yes}); err != nil {
+		t.Fatalf("UpdatePreferences failed: %v", err)
+	}
+	db.Create(&model.Ticket{TenantID: 2, UserID: user.ID, SecurityCode: "TENANT0001", Status: model.TicketStatusUnscratched, PurchasedAt: time.Now()})
+
+	if sent, err := digests.SendDue(time.Now()); err != nil || sent != 1 {
+		t.Fatalf("Expected one digest, sent %d: %v", sent, err)
+	}
+	var notification model.Notification
+	if err := db.Where("user_id = ?", user.ID).First(&notification).Error; err != nil {
+		t.Fatalf("Digest notification not found: %v", err)
+	}
+	if notification.TenantID != 2 {
+		t.Errorf("Expected the digest in tenant 2, got tenant %d", notification.TenantID)
+	}
+}
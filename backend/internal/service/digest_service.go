@@ -0,0 +1,212 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+	"scratch-lottery/pkg/logger"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Digest timing
+const (
+	digestPeriod      = 7 * 24 * time.Hour // Between two digests of a user
+	digestExpiryAhead = 3 * 24 * time.Hour // Gifts expiring within this are called out
+)
+
+// DigestService sends opted-in users a weekly summary of their unscratched
+// tickets, recent winnings and gifts about to expire, delivered as an in-app
+// notification
+type DigestService struct {
+	db                  *gorm.DB
+	notificationService *NotificationService
+}
+
+// NewDigestService creates a new digest service
+func NewDigestService(db *gorm.DB, notificationService *NotificationService) *DigestService {
+	return &DigestService{db: db, notificationService: notificationService}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *DigestService) ForTenant(tenantID uint) *DigestService {
+	return &DigestService{
+		db:                  repository.ScopeTenant(s.db, tenantID),
+		notificationService: s.notificationService.ForTenant(tenantID),
+	}
+}
+
+// NotificationPreferencesResponse represents the notification choices of a user
+type NotificationPreferencesResponse struct {
+	WeeklyDigest bool       `json:"weekly_digest"`
+	LastDigestAt *time.Time `json:"last_digest_at,omitempty"`
+}
+
+// UpdateNotificationPreferencesRequest represents the request to change the
+// notification choices of a user
+type UpdateNotificationPreferencesRequest struct {
+	WeeklyDigest *bool `json:"weekly_digest" binding:"required"`
+}
+
+// Digest summarizes the tickets and winnings of a user over a period
+type Digest struct {
+	PeriodStart        time.Time `json:"period_start"`
+	PeriodEnd          time.Time `json:"period_end"`
+	UnscratchedTickets int64     `json:"unscratched_tickets"`
+	WonPoints          int       `json:"won_points"`
+	ExpiringGifts      int64     `json:"expiring_gifts"` // Pending gifts to the user that expire soon
+}
+
+// empty reports whether the digest has nothing to tell
+func (d *Digest) empty() bool {
+	return d.UnscratchedTickets == 0 && d.WonPoints == 0 && d.ExpiringGifts == 0
+}
+
+// content renders the digest as the text of a notification
+func (d *Digest) content() string {
+	parts := make([]string, 0, 3)
+	if d.UnscratchedTickets > 0 {
+		parts = append(parts, fmt.Sprintf("你有 %d 张未刮开的彩票", d.UnscratchedTickets))
+	}
+	if d.ExpiringGifts > 0 {
+		parts = append(parts, fmt.Sprintf("%d 份待领取的礼物即将过期", d.ExpiringGifts))
+	}
+	if d.WonPoints > 0 {
+		parts = append(parts, fmt.Sprintf("本周中奖 %d 积分", d.WonPoints))
+	}
+	return strings.Join(parts, "，") + "。"
+}
+
+// GetPreferences returns the notification choices of a user
+func (s *DigestService) GetPreferences(userID uint) (*NotificationPreferencesResponse, error) {
+	var pref model.NotificationPreference
+	if err := s.db.Where("user_id = ?", userID).First(&pref).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &NotificationPreferencesResponse{}, nil
+		}
+		return nil, err
+	}
+	return &NotificationPreferencesResponse{WeeklyDigest: pref.WeeklyDigest, LastDigestAt: pref.LastDigestAt}, nil
+}
+
+// UpdatePreferences changes the notification choices of a user
+func (s *DigestService) UpdatePreferences(userID uint, req UpdateNotificationPreferencesRequest) (*NotificationPreferencesResponse, error) {
+	pref := model.NotificationPreference{UserID: userID, WeeklyDigest: *req.WeeklyDigest}
+	if err := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"weekly_digest", "updated_at"}),
+	}).Create(&pref).Error; err != nil {
+		return nil, err
+	}
+	return s.GetPreferences(userID)
+}
+
+// Build returns the digest of a user for the period ending at now
+func (s *DigestService) Build(userID uint, now time.Time) (*Digest, error) {
+	digest := &Digest{PeriodStart: now.Add(-digestPeriod), PeriodEnd: now}
+
+	if err := s.db.Model(&model.Ticket{}).
+		Where("user_id = ? AND status = ?", userID, model.TicketStatusUnscratched).
+		Count(&digest.UnscratchedTickets).Error; err != nil {
+		return nil, err
+	}
+
+	var won int64
+	if err := repository.TransactionsBetween(s.db, digest.PeriodStart, now).
+		Where("wallet_id IN (?) AND type = ?", s.db.Model(&model.Wallet{}).Select("id").Where("user_id = ?", userID), model.TransactionTypeWin).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&won).Error; err != nil {
+		return nil, err
+	}
+	digest.WonPoints = int(won)
+
+	if err := s.db.Model(&model.ExchangeGift{}).
+		Where("recipient_id = ? AND status = ? AND expires_at > ? AND expires_at <= ?",
+			userID, model.ExchangeGiftStatusPending, now, now.Add(digestExpiryAhead)).
+		Count(&digest.ExpiringGifts).Error; err != nil {
+		return nil, err
+	}
+	return digest, nil
+}
+
+// SendDue sends the digest to every opted-in user whose last digest is a
+// period old, and returns how many notifications went out. Users with
+// nothing to report are skipped until the next period.
+func (s *DigestService) SendDue(now time.Time) (int, error) {
+	var prefs []model.NotificationPreference
+	if err := s.db.Where("weekly_digest = ? AND (last_digest_at IS NULL OR last_digest_at <= ?)", true, now.Add(-digestPeriod)).
+		Order("id ASC").
+		Find(&prefs).Error; err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for i := range prefs {
+		delivered, err := s.ForTenant(prefs[i].TenantID).send(&prefs[i], now)
+		if err != nil {
+			logger.Default().Warn("Sending the digest of user %d failed: %v", prefs[i].UserID, err)
+			continue
+		}
+		if delivered {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// Start sends due digests every interval until the returned stop func is called
+func (s *DigestService) Start(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				sent, err := s.SendDue(time.Now())
+				if err != nil {
+					logger.Default().Warn("Sending digests failed: %v", err)
+				} else if sent > 0 {
+					logger.Default().Info("Sent %d weekly digests", sent)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// send builds and delivers the digest of a preference, marking the period as
+// done. The mark is guarded so concurrent runs deliver a digest once.
+func (s *DigestService) send(pref *model.NotificationPreference, now time.Time) (bool, error) {
+	digest, err := s.Build(pref.UserID, now)
+	if err != nil {
+		return false, err
+	}
+
+	delivered := false
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.NotificationPreference{}).
+			Where("id = ? AND weekly_digest = ? AND (last_digest_at IS NULL OR last_digest_at <= ?)", pref.ID, true, now.Add(-digestPeriod)).
+			Update("last_digest_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 || digest.empty() {
+			return nil
+		}
+
+		if _, err := s.notificationService.notify(tx, pref.UserID, model.NotificationTypeDigest, "每周彩票摘要", digest.content()); err != nil {
+			return err
+		}
+		delivered = true
+		return nil
+	})
+	return delivered, err
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+)
+
+// Exchange gifts: the giver pays, the key stays hidden until the recipient
+// accepts, and unclaimed gifts go back to the giver exactly once.
+func TestExchangeGiftLifecycle(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	setup := func(price int) (*ExchangeGiftService, *ExchangeService, *WalletService, bool) {
+		db := setupExchangeTestDB(t)
+		if err := db.AutoMigrate(&model.ExchangeGift{}, &model.CardKeyReveal{}); err != nil {
+			t.Logf("Failed to migrate: %v", err)
+			return nil, nil, nil, false
+		}
+		if createTestUserWithBalance(db, 1, price*2) != nil ||
+			createTestUserWithBalance(db, 2, 0) != nil ||
+			createTestProductWithCardKeys(db, 1, price, 2) != nil {
+			return nil, nil, nil, false
+		}
+		walletService := NewWalletService(db)
+		exchangeService := NewExchangeService(db, walletService)
+		return NewExchangeGiftService(db, exchangeService, time.Hour), exchangeService, walletService, true
+	}
+
+	properties.Property("accepted gift reveals the key to the recipient only", prop.ForAll(
+		func(price int) bool {
+			giftService, exchangeService, walletService, ok := setup(price)
+			if !ok {
+				return false
+			}
+
+			sent, err := giftService.SendGift(1, SendGiftRequest{ProductID: 1, RecipientID: 2})
+			if err != nil {
+				t.Logf("SendGift failed: %v", err)
+				return false
+			}
+			if balance, _ := walletService.GetBalance(1); balance != price {
+				t.Logf("Giver should pay %d, balance %d", price, balance)
+				return false
+			}
+
+			// Pending: nobody sees the key
+			received, err := giftService.GetGifts(2, GiftQuery{})
+			if err != nil || len(received.Gifts) != 1 || received.Gifts[0].CardKey != "" {
+				t.Logf("Pending gift should hide the key: %+v (err %v)", received, err)
+				return false
+			}
+			giverRecord, err := exchangeService.GetExchangeRecordByID(1, sent.RecordID)
+			if err != nil || giverRecord.CardKey != "" || giverRecord.GiftStatus != model.ExchangeGiftStatusPending {
+				t.Logf("Giver record should hide the key: %+v (err %v)", giverRecord, err)
+				return false
+			}
+
+			accepted, err := giftService.AcceptGift(2, sent.GiftID)
+			if err != nil || accepted.CardKey == "" || accepted.Status != model.ExchangeGiftStatusAccepted {
+				t.Logf("AcceptGift: %+v (err %v)", accepted, err)
+				return false
+			}
+			if _, err := giftService.AcceptGift(2, sent.GiftID); err != ErrGiftNotPending {
+				t.Logf("Second accept should fail, got %v", err)
+				return false
+			}
+
+			// The key is now in the recipient's exchange records
+			records, err := exchangeService.GetExchangeRecords(2, ExchangeRecordQuery{})
+			if err != nil || len(records.Records) != 1 || !records.Records[0].KeyMasked || records.Records[0].Cost != 0 {
+				t.Logf("Recipient records: %+v (err %v)", records, err)
+				return false
+			}
+			if revealed, err := exchangeService.RevealCardKey(2, records.Records[0].ID, "127.0.0.1", "test"); err != nil || revealed.CardKey != accepted.CardKey {
+				t.Logf("Recipient reveal: %+v (err %v)", revealed, err)
+				return false
+			}
+
+			// Expiry sweep must not touch accepted gifts
+			returned, err := giftService.ReturnExpiredGifts()
+			return err == nil && returned == 0
+		},
+		gen.IntRange(1, 500),
+	))
+
+	properties.Property("unclaimed gift returns to the giver", prop.ForAll(
+		func(price int, decline bool) bool {
+			giftService, exchangeService, _, ok := setup(price)
+			if !ok {
+				return false
+			}
+
+			sent, err := giftService.SendGift(1, SendGiftRequest{ProductID: 1, RecipientID: 2})
+			if err != nil {
+				t.Logf("SendGift failed: %v", err)
+				return false
+			}
+
+			if decline {
+				if _, err := giftService.DeclineGift(2, sent.GiftID); err != nil {
+					t.Logf("DeclineGift failed: %v", err)
+					return false
+				}
+			} else {
+				giftService.db.Model(&model.ExchangeGift{}).Where("id = ?", sent.GiftID).
+					Update("expires_at", time.Now().Add(-time.Minute))
+				if _, err := giftService.AcceptGift(2, sent.GiftID); err != ErrGiftExpired {
+					t.Logf("Expected ErrGiftExpired, got %v", err)
+					return false
+				}
+			}
+
+			if returned, _ := giftService.ReturnExpiredGifts(); returned != 0 {
+				t.Log("Gift should only be returned once")
+				return false
+			}
+
+			giverRecord, err := exchangeService.GetExchangeRecordByID(1, sent.RecordID)
+			if err != nil || giverRecord.CardKey == "" || giverRecord.GiftStatus != model.ExchangeGiftStatusReturned {
+				t.Logf("Returned gift should reveal the key to the giver: %+v (err %v)", giverRecord, err)
+				return false
+			}
+			records, _ := exchangeService.GetExchangeRecords(2, ExchangeRecordQuery{})
+			return records.Total == 0
+		},
+		gen.IntRange(1, 500),
+		gen.Bool(),
+	))
+
+	properties.Property("cannot gift to yourself or unknown users", prop.ForAll(
+		func(recipientID uint) bool {
+			giftService, _, _, ok := setup(10)
+			if !ok {
+				return false
+			}
+			_, err := giftService.SendGift(1, SendGiftRequest{ProductID: 1, RecipientID: recipientID})
+			switch recipientID {
+			case 1:
+				return err == ErrGiftToSelf
+			case 2:
+				return err == nil
+			default:
+				return err == ErrGiftRecipientNotFound
+			}
+		},
+		gen.UIntRange(1, 5),
+	))
+
+	properties.TestingRun(t)
+}
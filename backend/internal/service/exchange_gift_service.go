@@ -0,0 +1,400 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+	"scratch-lottery/pkg/logger"
+	"scratch-lottery/pkg/redact"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrGiftNotFound          = errors.New("gift not found")
+	ErrGiftNotPending        = errors.New("gift already accepted or returned")
+	ErrGiftExpired           = errors.New("gift expired")
+	ErrGiftRecipientNotFound = errors.New("gift recipient not found")
+	ErrGiftToSelf            = errors.New("cannot send a gift to yourself")
+)
+
+// Gift list directions
+const (
+	GiftDirectionReceived = "received"
+	GiftDirectionSent     = "sent"
+)
+
+// ExchangeGiftService handles redeeming products as gifts for other users.
+// A gift's card key stays hidden until the recipient accepts it; gifts that
+// are declined or left unclaimed past their expiry return to the giver.
+type ExchangeGiftService struct {
+	db              *gorm.DB
+	exchangeService *ExchangeService
+	expiry          time.Duration
+}
+
+// NewExchangeGiftService creates a new exchange gift service.
+// expiry is how long a recipient has to accept a gift.
+func NewExchangeGiftService(db *gorm.DB, exchangeService *ExchangeService, expiry time.Duration) *ExchangeGiftService {
+	if expiry <= 0 {
+		expiry = 7 * 24 * time.Hour
+	}
+	return &ExchangeGiftService{
+		db:              db,
+		exchangeService: exchangeService,
+		expiry:          expiry,
+	}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *ExchangeGiftService) ForTenant(tenantID uint) *ExchangeGiftService {
+	return &ExchangeGiftService{
+		db:              repository.ScopeTenant(s.db, tenantID),
+		exchangeService: s.exchangeService.ForTenant(tenantID),
+		expiry:          s.expiry,
+	}
+}
+
+// SendGiftRequest represents a request to redeem a product as a gift
+type SendGiftRequest struct {
+	ProductID   uint   `json:"product_id" binding:"required"`
+	RecipientID uint   `json:"recipient_id" binding:"required"`
+	Message     string `json:"message" binding:"max=256"`
+}
+
+// SendGiftResponse represents the result of sending a gift
+type SendGiftResponse struct {
+	GiftID      uint      `json:"gift_id"`
+	RecordID    uint      `json:"record_id"`
+	ProductName string    `json:"product_name"`
+	Cost        int       `json:"cost"`
+	Balance     int       `json:"balance"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// GiftResponse represents a gift in the response
+type GiftResponse struct {
+	ID            uint                     `json:"id"`
+	ProductID     uint                     `json:"product_id"`
+	ProductName   string                   `json:"product_name"`
+	GiverID       uint                     `json:"giver_id"`
+	GiverName     string                   `json:"giver_name"`
+	RecipientID   uint                     `json:"recipient_id"`
+	RecipientName string                   `json:"recipient_name"`
+	Message       string                   `json:"message"`
+	Status        model.ExchangeGiftStatus `json:"status"`
+	CardKey       string                   `json:"card_key,omitempty"` // Recipient after acceptance, giver after return
+	ExpiresAt     time.Time                `json:"expires_at"`
+	RespondedAt   *time.Time               `json:"responded_at,omitempty"`
+	CreatedAt     time.Time                `json:"created_at"`
+}
+
+// GiftQuery represents query parameters for listing gifts
+type GiftQuery struct {
+	Direction string `form:"direction"` // received (default) or sent
+	Status    string `form:"status"`
+	Page      int    `form:"page"`
+	Limit     int    `form:"limit"`
+}
+
+// GiftListResponse represents paginated gifts
+type GiftListResponse struct {
+	Gifts      []GiftResponse `json:"gifts"`
+	Total      int64          `json:"total"`
+	Page       int            `json:"page"`
+	Limit      int            `json:"limit"`
+	TotalPages int            `json:"total_pages"`
+}
+
+// SendGift redeems a product on behalf of the giver and holds the card key for the recipient
+func (s *ExchangeGiftService) SendGift(giverID uint, req SendGiftRequest) (*SendGiftResponse, error) {
+	if req.RecipientID == giverID {
+		return nil, ErrGiftToSelf
+	}
+	var recipient model.User
+	if err := s.db.First(&recipient, req.RecipientID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrGiftRecipientNotFound
+		}
+		return nil, err
+	}
+
+	var gift model.ExchangeGift
+	result, err := s.exchangeService.redeemProduct(giverID, req.ProductID,
+		func(tx *gorm.DB, cardKey *model.CardKey, record *model.ExchangeRecord) error {
+			gift = model.ExchangeGift{
+				GiverID:     giverID,
+				RecipientID: recipient.ID,
+				ProductID:   req.ProductID,
+				CardKeyID:   cardKey.ID,
+				Message:     req.Message,
+				Status:      model.ExchangeGiftStatusPending,
+				ExpiresAt:   time.Now().Add(s.expiry),
+			}
+			if err := tx.Create(&gift).Error; err != nil {
+				return err
+			}
+			record.GiftID = &gift.ID
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return &SendGiftResponse{
+		GiftID:      gift.ID,
+		RecordID:    result.record.ID,
+		ProductName: result.product.Name,
+		Cost:        result.product.Price,
+		Balance:     result.balance,
+		ExpiresAt:   gift.ExpiresAt,
+	}, nil
+}
+
+// AcceptGift accepts a pending gift, transferring the card key to the recipient
+// and adding it to the recipient's exchange records
+func (s *ExchangeGiftService) AcceptGift(recipientID uint, giftID uint) (*GiftResponse, error) {
+	gift, err := s.getPendingGift(recipientID, giftID)
+	if err != nil {
+		return nil, err
+	}
+	// A gift the giver disputes waits until the dispute is resolved
+	var giverRecord model.ExchangeRecord
+	if err := s.db.Where("gift_id = ? AND user_id = ?", gift.ID, gift.GiverID).First(&giverRecord).Error; err == nil {
+		if frozen, err := exchangeRecordFrozen(s.db, giverRecord.ID); err != nil {
+			return nil, err
+		} else if frozen {
+			return nil, ErrExchangeDisputed
+		}
+	}
+
+	err = repository.Transaction(s.db, func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&model.ExchangeGift{}).
+			Where("id = ? AND status = ?", gift.ID, model.ExchangeGiftStatusPending).
+			Updates(map[string]interface{}{
+				"status":       model.ExchangeGiftStatusAccepted,
+				"responded_at": now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrGiftNotPending
+		}
+
+		if err := tx.Model(&model.CardKey{}).Where("id = ?", gift.CardKeyID).
+			Update("redeemed_by", recipientID).Error; err != nil {
+			return err
+		}
+
+		record := model.ExchangeRecord{
+			UserID:    recipientID,
+			ProductID: gift.ProductID,
+			CardKeyID: gift.CardKeyID,
+			Cost:      0,
+			GiftID:    &gift.ID,
+		}
+		return tx.Create(&record).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.getGift(recipientID, giftID)
+	if err != nil {
+		return nil, err
+	}
+	// Acceptance is the one time the key is shown in full, even for one-time reveal products
+	var cardKey model.CardKey
+	if err := s.db.First(&cardKey, gift.CardKeyID).Error; err != nil {
+		return nil, err
+	}
+	resp.CardKey = cardKey.KeyContent
+	return resp, nil
+}
+
+// DeclineGift declines a pending gift, returning it to the giver
+func (s *ExchangeGiftService) DeclineGift(recipientID uint, giftID uint) (*GiftResponse, error) {
+	gift, err := s.getPendingGift(recipientID, giftID)
+	if err != nil {
+		return nil, err
+	}
+
+	returned, err := s.returnGifts(s.db.Where("id = ?", gift.ID))
+	if err != nil {
+		return nil, err
+	}
+	if returned == 0 {
+		return nil, ErrGiftNotPending
+	}
+
+	return s.getGift(recipientID, giftID)
+}
+
+// ReturnExpiredGifts returns every pending gift past its expiry to its giver
+func (s *ExchangeGiftService) ReturnExpiredGifts() (int64, error) {
+	return s.returnGifts(s.db.Where("expires_at <= ?", time.Now()))
+}
+
+// Start returns expired gifts every interval until the returned stop func is called
+func (s *ExchangeGiftService) Start(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				returned, err := s.ReturnExpiredGifts()
+				if err != nil {
+					logger.Default().Warn("Returning expired gifts failed: %v", err)
+				} else if returned > 0 {
+					logger.Default().Info("Returned %d unclaimed gifts to their givers", returned)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// GetGifts returns the gifts received or sent by a user, newest first
+func (s *ExchangeGiftService) GetGifts(userID uint, query GiftQuery) (*GiftListResponse, error) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit < 1 || query.Limit > 100 {
+		query.Limit = 20
+	}
+
+	// Make sure lapsed gifts are shown as returned even if the sweeper has not run yet
+	if _, err := s.ReturnExpiredGifts(); err != nil {
+		return nil, err
+	}
+
+	dbQuery := s.db.Model(&model.ExchangeGift{})
+	if query.Direction == GiftDirectionSent {
+		dbQuery = dbQuery.Where("giver_id = ?", userID)
+	} else {
+		dbQuery = dbQuery.Where("recipient_id = ?", userID)
+	}
+	if query.Status != "" {
+		dbQuery = dbQuery.Where("status = ?", query.Status)
+	}
+
+	var total int64
+	if err := dbQuery.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	var gifts []model.ExchangeGift
+	offset := (query.Page - 1) * query.Limit
+	if err := dbQuery.Preload("Giver").
+		Preload("Recipient").
+		Preload("Product").
+		Preload("CardKey").
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(query.Limit).
+		Find(&gifts).Error; err != nil {
+		return nil, err
+	}
+
+	totalPages := int(total) / query.Limit
+	if int(total)%query.Limit > 0 {
+		totalPages++
+	}
+
+	responses := make([]GiftResponse, len(gifts))
+	for i := range gifts {
+		responses[i] = *toGiftResponse(userID, &gifts[i])
+	}
+
+	return &GiftListResponse{
+		Gifts:      responses,
+		Total:      total,
+		Page:       query.Page,
+		Limit:      query.Limit,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// getPendingGift loads a gift addressed to the recipient and checks it can still be answered.
+// A gift found past its expiry is returned to the giver on the spot.
+func (s *ExchangeGiftService) getPendingGift(recipientID uint, giftID uint) (*model.ExchangeGift, error) {
+	var gift model.ExchangeGift
+	if err := s.db.Where("id = ? AND recipient_id = ?", giftID, recipientID).First(&gift).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrGiftNotFound
+		}
+		return nil, err
+	}
+	if gift.Status != model.ExchangeGiftStatusPending {
+		return nil, ErrGiftNotPending
+	}
+	if !gift.ExpiresAt.After(time.Now()) {
+		if _, err := s.returnGifts(s.db.Where("id = ?", gift.ID)); err != nil {
+			return nil, err
+		}
+		return nil, ErrGiftExpired
+	}
+	return &gift, nil
+}
+
+// returnGifts marks the pending gifts matched by scope as returned
+func (s *ExchangeGiftService) returnGifts(scope *gorm.DB) (int64, error) {
+	result := scope.Model(&model.ExchangeGift{}).
+		Where("status = ?", model.ExchangeGiftStatusPending).
+		Updates(map[string]interface{}{
+			"status":       model.ExchangeGiftStatusReturned,
+			"responded_at": time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
+
+// getGift loads a gift visible to the user
+func (s *ExchangeGiftService) getGift(userID uint, giftID uint) (*GiftResponse, error) {
+	var gift model.ExchangeGift
+	if err := s.db.Where("id = ? AND (giver_id = ? OR recipient_id = ?)", giftID, userID, userID).
+		Preload("Giver").
+		Preload("Recipient").
+		Preload("Product").
+		Preload("CardKey").
+		First(&gift).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrGiftNotFound
+		}
+		return nil, err
+	}
+	return toGiftResponse(userID, &gift), nil
+}
+
+func toGiftResponse(viewerID uint, gift *model.ExchangeGift) *GiftResponse {
+	resp := &GiftResponse{
+		ID:            gift.ID,
+		ProductID:     gift.ProductID,
+		ProductName:   gift.Product.Name,
+		GiverID:       gift.GiverID,
+		GiverName:     gift.Giver.Username,
+		RecipientID:   gift.RecipientID,
+		RecipientName: gift.Recipient.Username,
+		Message:       gift.Message,
+		Status:        gift.Status,
+		ExpiresAt:     gift.ExpiresAt,
+		RespondedAt:   gift.RespondedAt,
+		CreatedAt:     gift.CreatedAt,
+	}
+	// The key belongs to whoever ends up holding the gift
+	if (viewerID == gift.RecipientID && gift.Status == model.ExchangeGiftStatusAccepted) ||
+		(viewerID == gift.GiverID && gift.Status == model.ExchangeGiftStatusReturned) {
+		resp.CardKey = gift.CardKey.KeyContent
+		if gift.Product.OneTimeReveal {
+			resp.CardKey = redact.Secret(resp.CardKey)
+		}
+	}
+	return resp
+}
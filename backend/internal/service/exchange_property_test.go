@@ -1,7 +1,12 @@
 package service
 
 import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"scratch-lottery/internal/model"
 
@@ -30,6 +35,7 @@ func setupExchangeTestDB(t *testing.T) *gorm.DB {
 		&model.Product{},
 		&model.CardKey{},
 		&model.ExchangeRecord{},
+		&model.TransactionDispute{},
 	)
 	if err != nil {
 		t.Fatalf("Failed to migrate test database: %v", err)
@@ -327,7 +333,7 @@ func TestProperty9_ExchangeTransactionConsistency(t *testing.T) {
 
 			// Verify transaction record exists with correct amount
 			var transaction model.Transaction
-			if err := db.Where("type = ? AND reference_id = ?", model.TransactionTypeExchange, productID).
+			if err := db.Where("type = ? AND reference_id = ?", model.TransactionTypeExchange, result.RecordID).
 				First(&transaction).Error; err != nil {
 				t.Logf("Failed to find transaction: %v", err)
 				return false
@@ -680,3 +686,395 @@ func TestProperty10_ProductStockAutoUpdate(t *testing.T) {
 
 	properties.TestingRun(t)
 }
+
+// One-time reveal: the key is returned in full on redemption, masked in the
+// exchange records afterwards, and every explicit re-reveal is audited.
+func TestOneTimeRevealCardKeyAudited(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("masked after redemption, audited on reveal", prop.ForAll(
+		func(oneTime bool, reveals int) bool {
+			db := setupExchangeTestDB(t)
+			if err := db.AutoMigrate(&model.CardKeyReveal{}); err != nil {
+				t.Logf("Failed to migrate: %v", err)
+				return false
+			}
+			if err := createTestUserWithBalance(db, 1, 100); err != nil {
+				return false
+			}
+			if err := createTestProductWithCardKeys(db, 1, 10, 1); err != nil {
+				return false
+			}
+			db.Model(&model.Product{}).Where("id = ?", 1).Update("one_time_reveal", oneTime)
+			exchangeService := NewExchangeService(db, NewWalletService(db))
+
+			redeemed, err := exchangeService.Redeem(1, 1)
+			if err != nil {
+				t.Logf("Redeem failed: %v", err)
+				return false
+			}
+
+			record, err := exchangeService.GetExchangeRecordByID(1, redeemed.RecordID)
+			if err != nil {
+				return false
+			}
+			if record.KeyMasked != oneTime || (record.CardKey == redeemed.CardKey) == oneTime {
+				t.Logf("oneTime=%v but record key %q masked=%v", oneTime, record.CardKey, record.KeyMasked)
+				return false
+			}
+			// List pages mask the key of every product
+			list, err := exchangeService.GetExchangeRecords(1, ExchangeRecordQuery{})
+			if err != nil || len(list.Records) != 1 || !list.Records[0].KeyMasked || list.Records[0].CardKey == redeemed.CardKey {
+				t.Logf("Expected a masked key in the list, got %+v (err %v)", list, err)
+				return false
+			}
+
+			for i := 0; i < reveals; i++ {
+				revealed, err := exchangeService.RevealCardKey(1, redeemed.RecordID, "127.0.0.1", "test")
+				if err != nil || revealed.CardKey != redeemed.CardKey {
+					t.Logf("RevealCardKey: %+v (err %v)", revealed, err)
+					return false
+				}
+			}
+
+			// Other users cannot reveal someone else's key
+			if _, err := exchangeService.RevealCardKey(2, redeemed.RecordID, "127.0.0.1", "test"); err != ErrExchangeRecordNotFound {
+				t.Logf("Expected ErrExchangeRecordNotFound, got %v", err)
+				return false
+			}
+
+			audit, err := exchangeService.GetCardKeyReveals(CardKeyRevealQuery{RecordID: redeemed.RecordID})
+			return err == nil && audit.Total == int64(reveals)
+		},
+		gen.Bool(),
+		gen.IntRange(0, 3),
+	))
+
+	properties.TestingRun(t)
+}
+
+// Flash drops: redemption is blocked and stock hidden until the drop time;
+// once open, a burst of concurrent redemptions never oversells and the
+// product closes when depleted.
+func TestFlashDropBurstRedemption(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("no redemption before the drop, no overselling after", prop.ForAll(
+		func(numKeys, numUsers int) bool {
+			db := setupExchangeTestDB(t)
+			sqlDB, _ := db.DB()
+			sqlDB.SetMaxOpenConns(1) // every :memory: connection is a separate database
+			if err := createTestProductWithCardKeys(db, 1, 10, numKeys); err != nil {
+				return false
+			}
+			for u := 1; u <= numUsers; u++ {
+				if err := createTestUserWithBalance(db, uint(u), 100); err != nil {
+					return false
+				}
+			}
+			exchangeService := NewExchangeService(db, NewWalletService(db))
+
+			dropAt := time.Now().Add(time.Hour)
+			db.Model(&model.Product{}).Where("id = ?", 1).Update("drop_at", dropAt)
+
+			if _, err := exchangeService.Redeem(1, 1); err != ErrProductNotDropped {
+				t.Logf("Expected ErrProductNotDropped, got %v", err)
+				return false
+			}
+			product, err := exchangeService.GetProductByID(1)
+			if err != nil || !product.DropPending || product.Stock != 0 || product.DropCountdown <= 0 {
+				t.Logf("Stock should be hidden before the drop: %+v (err %v)", product, err)
+				return false
+			}
+
+			// Open the drop and let every user redeem at once
+			db.Model(&model.Product{}).Where("id = ?", 1).Update("drop_at", time.Now().Add(-time.Second))
+			var wg sync.WaitGroup
+			var succeeded int32
+			for u := 1; u <= numUsers; u++ {
+				wg.Add(1)
+				go func(userID uint) {
+					defer wg.Done()
+					if _, err := exchangeService.Redeem(userID, 1); err == nil {
+						atomic.AddInt32(&succeeded, 1)
+					}
+				}(uint(u))
+			}
+			wg.Wait()
+
+			want := numKeys
+			if numUsers < want {
+				want = numUsers
+			}
+			var redeemedKeys int64
+			db.Model(&model.CardKey{}).Where("status = ?", model.CardKeyStatusRedeemed).Count(&redeemedKeys)
+			product, _ = exchangeService.GetProductByID(1)
+			if int(succeeded) != want || redeemedKeys != int64(want) || product.Stock != numKeys-want {
+				t.Logf("succeeded=%d redeemed=%d stock=%d, want %d", succeeded, redeemedKeys, product.Stock, want)
+				return false
+			}
+			return (product.Stock == 0) == (product.Status == model.ProductStatusSoldOut)
+		},
+		gen.IntRange(1, 10),
+		gen.IntRange(1, 15),
+	))
+
+	properties.TestingRun(t)
+}
+
+// Streamed card key imports: keys read line by line are stored in batches and
+// counted exactly like a JSON import, and an oversized key rolls back the
+// whole import.
+func TestStreamedCardKeyImport(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("streamed and listed imports store the same keys", prop.ForAll(
+		func(count, blankEvery int) bool {
+			db := setupExchangeTestDB(t)
+			exchangeService := NewExchangeService(db, NewWalletService(db))
+			product := model.Product{Name: "Streamed", Price: 10, Status: model.ProductStatusSoldOut}
+			db.Create(&product)
+
+			var body strings.Builder
+			for i := 0; i < count; i++ {
+				if i%blankEvery == 0 {
+					body.WriteString("\r\n")
+				}
+				fmt.Fprintf(&body, "  KEY-%05d  \r\n", i)
+			}
+			imported, err := exchangeService.ImportCardKeysFrom(product.ID, strings.NewReader(body.String()))
+			if err != nil || imported != count {
+				t.Logf("Expected %d imported keys, got %d (err %v)", count, imported, err)
+				return false
+			}
+
+			var stored int64
+			db.Model(&model.CardKey{}).Where("product_id = ? AND key_content LIKE ?", product.ID, "KEY-%").Count(&stored)
+			db.First(&product, product.ID)
+			if int(stored) != count || product.Stock != count || (count > 0) != (product.Status == model.ProductStatusAvailable) {
+				t.Logf("Expected %d stored keys, got %d with product %+v", count, stored, product)
+				return false
+			}
+
+			// A key longer than the column rolls back everything
+			tooLong := "OK-1\n" + strings.Repeat("x", maxCardKeyLength+1) + "\n"
+			if _, err := exchangeService.ImportCardKeysFrom(product.ID, strings.NewReader(tooLong)); err != ErrCardKeyTooLong {
+				t.Logf("Expected ErrCardKeyTooLong, got %v", err)
+				return false
+			}
+			if _, err := exchangeService.ImportCardKeys(product.ID, []string{"OK-2", strings.Repeat("x", maxCardKeyLength+1)}); err != ErrCardKeyTooLong {
+				t.Logf("Expected ErrCardKeyTooLong from a listed import, got %v", err)
+				return false
+			}
+			db.First(&product, product.ID)
+			return product.Stock == count
+		},
+		gen.IntRange(0, 2*cardKeyImportBatch+7),
+		gen.IntRange(1, 50),
+	))
+
+	properties.TestingRun(t)
+}
+
+// Stock recalculation: after stock drifts from the available card keys,
+// recalculating restores stock == available keys with a matching status,
+// logs each correction once and is a no-op when run again.
+func TestRecalculateProductStock(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("recalculated stock matches available card keys", prop.ForAll(
+		func(available, redeemed, drift int, offline bool) bool {
+			db := setupExchangeTestDB(t)
+			if err := db.AutoMigrate(&model.AdminLog{}); err != nil {
+				t.Fatalf("Failed to migrate: %v", err)
+			}
+			exchangeService := NewExchangeService(db, NewWalletService(db))
+
+			status := model.ProductStatusAvailable
+			if offline {
+				status = model.ProductStatusOffline
+			}
+			product := model.Product{Name: "Drifted", Price: 10, Stock: available + drift, Status: status}
+			db.Create(&product)
+			for i := 0; i < available+redeemed; i++ {
+				keyStatus := model.CardKeyStatusAvailable
+				if i >= available {
+					keyStatus = model.CardKeyStatusRedeemed
+				}
+				db.Create(&model.CardKey{ProductID: product.ID, KeyContent: fmt.Sprintf("KEY-%d", i), Status: keyStatus})
+			}
+			// A healthy product next to it is left alone
+			if err := createTestProductWithCardKeys(db, product.ID+1, 10, 3); err != nil {
+				t.Fatalf("Failed to create product: %v", err)
+			}
+
+			correction, err := exchangeService.RecalculateProductStock(1, product.ID)
+			if err != nil || correction.StockBefore != available+drift || correction.StockAfter != available {
+				t.Logf("Unexpected correction %+v (err %v)", correction, err)
+				return false
+			}
+
+			wantStatus := status
+			if !offline && available == 0 {
+				wantStatus = model.ProductStatusSoldOut
+			}
+			db.First(&product, product.ID)
+			if product.Stock != available || product.Status != wantStatus {
+				t.Logf("Expected stock %d and status %s, got %+v", available, wantStatus, product)
+				return false
+			}
+			corrected := drift != 0 || wantStatus != status
+			if correction.Corrected != corrected {
+				t.Logf("Expected corrected %v, got %+v", corrected, correction)
+				return false
+			}
+
+			// Running the bulk repair afterwards finds nothing to correct
+			repair, err := exchangeService.RecalculateAllStock(1)
+			if err != nil || repair.Checked != 2 || repair.Corrected != 0 {
+				t.Logf("Expected a clean bulk repair, got %+v (err %v)", repair, err)
+				return false
+			}
+
+			var logs int64
+			db.Model(&model.AdminLog{}).Where("action = ?", "recalculate_product_stock").Count(&logs)
+			return (logs == 1) == corrected && logs <= 1
+		},
+		gen.IntRange(0, 5),
+		gen.IntRange(0, 3),
+		gen.IntRange(-3, 3),
+		gen.Bool(),
+	))
+
+	properties.TestingRun(t)
+}
+
+func TestRepairAllStockRestocksSoldOutProducts(t *testing.T) {
+	db := setupExchangeTestDB(t)
+	if err := db.AutoMigrate(&model.AdminLog{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	exchangeService := NewExchangeService(db, NewWalletService(db))
+
+	soldOut := model.Product{Name: "Sold out", Price: 10, Status: model.ProductStatusSoldOut}
+	db.Create(&soldOut)
+	db.Create(&model.CardKey{ProductID: soldOut.ID, KeyContent: "LEFTOVER", Status: model.CardKeyStatusAvailable})
+
+	repair, err := exchangeService.RecalculateAllStock(1)
+	if err != nil || repair.Corrected != 1 || len(repair.Corrections) != 1 {
+		t.Fatalf("Expected one correction, got %+v (err %v)", repair, err)
+	}
+	correction := repair.Corrections[0]
+	if correction.StockAfter != 1 || correction.StatusBefore != model.ProductStatusSoldOut || correction.StatusAfter != model.ProductStatusAvailable {
+		t.Errorf("Unexpected correction %+v", correction)
+	}
+
+	if _, err := exchangeService.RecalculateProductStock(1, soldOut.ID+100); err != ErrProductNotFound {
+		t.Errorf("Expected ErrProductNotFound, got %v", err)
+	}
+}
+
+// Card key listings: the pages of a filtered listing hold every matching key
+// once in the requested order, and the summary counts all keys of the product
+func TestCardKeyListingPages(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("pages cover the matching keys", prop.ForAll(
+		func(statuses []int, limit int, redeemedFilter, oldest bool) bool {
+			db := setupExchangeTestDB(t)
+			exchangeService := NewExchangeService(db, NewWalletService(db))
+			product := model.Product{Name: "Keys", Price: 10, Status: model.ProductStatusAvailable}
+			db.Create(&product)
+
+			keyStatuses := []model.CardKeyStatus{model.CardKeyStatusAvailable, model.CardKeyStatusReserved, model.CardKeyStatusRedeemed}
+			var want CardKeySummary
+			var matching []uint
+			for i, s := range statuses {
+				key := model.CardKey{ProductID: product.ID, KeyContent: fmt.Sprintf("KEY-%d", i), Status: keyStatuses[s]}
+				if key.Status == model.CardKeyStatusRedeemed {
+					key.RedeemedBy = uint(i%2 + 1)
+				}
+				db.Create(&key)
+				switch key.Status {
+				case model.CardKeyStatusAvailable:
+					want.Available++
+				case model.CardKeyStatusReserved:
+					want.Reserved++
+				case model.CardKeyStatusRedeemed:
+					want.Redeemed++
+				}
+				want.Total++
+				if !redeemedFilter || key.RedeemedBy == 1 {
+					matching = append(matching, key.ID)
+				}
+			}
+			// Keys of another product are neither listed nor counted
+			db.Create(&model.CardKey{ProductID: product.ID + 1, KeyContent: "OTHER", Status: model.CardKeyStatusAvailable})
+
+			query := CardKeyQuery{Limit: limit, Sort: "newest"}
+			if redeemedFilter {
+				query.Status = string(model.CardKeyStatusRedeemed)
+				query.RedeemedBy = 1
+			}
+			if oldest {
+				query.Sort = "oldest"
+			} else {
+				for i, j := 0, len(matching)-1; i < j; i, j = i+1, j-1 {
+					matching[i], matching[j] = matching[j], matching[i]
+				}
+			}
+
+			var listed []uint
+			for query.Page = 1; ; query.Page++ {
+				page, err := exchangeService.GetCardKeysByProductID(product.ID, query)
+				if err != nil {
+					t.Logf("GetCardKeysByProductID failed: %v", err)
+					return false
+				}
+				if page.Summary != want || page.Total != int64(len(matching)) {
+					t.Logf("Expected summary %+v and %d matching, got %+v and %d", want, len(matching), page.Summary, page.Total)
+					return false
+				}
+				for _, key := range page.CardKeys {
+					listed = append(listed, key.ID)
+				}
+				if query.Page >= page.TotalPages {
+					break
+				}
+			}
+			if fmt.Sprint(listed) != fmt.Sprint(matching) {
+				t.Logf("Expected keys %v, got %v", matching, listed)
+				return false
+			}
+
+			_, err := exchangeService.GetCardKeysByProductID(product.ID, CardKeyQuery{Status: "lost"})
+			return err == ErrInvalidCardKeyQuery
+		},
+		gen.SliceOfN(12, gen.IntRange(0, 2)),
+		gen.IntRange(1, 5),
+		gen.Bool(),
+		gen.Bool(),
+	))
+
+	properties.TestingRun(t)
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/pkg/biztime"
+	"scratch-lottery/pkg/pdf"
+	"scratch-lottery/pkg/redact"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrReceiptUnavailable = errors.New("exchange record has no receipt")
+	ErrReceiptNotFound    = errors.New("receipt not found")
+)
+
+// receiptCodePrefix starts every receipt verification code
+const receiptCodePrefix = "RC"
+
+// ExchangeReceipt is the receipt of a redemption paid with points
+type ExchangeReceipt struct {
+	Code        string    `json:"code"` // Verification code, resolvable by admins
+	RecordID    uint      `json:"record_id"`
+	UserID      uint      `json:"user_id"`
+	Username    string    `json:"username"`
+	ProductID   uint      `json:"product_id"`
+	ProductName string    `json:"product_name"`
+	Cost        int       `json:"cost"`
+	CardKey     string    `json:"card_key"` // Masked, keeping both ends to match against the key
+	RedeemedAt  time.Time `json:"redeemed_at"`
+}
+
+// GetExchangeReceipt returns the receipt of an exchange record of the user.
+// Only completed redemptions paid with points have a receipt; its
+// verification code is issued on the first request and kept afterwards.
+func (s *ExchangeService) GetExchangeReceipt(userID, recordID uint) (*ExchangeReceipt, error) {
+	var record model.ExchangeRecord
+	if err := s.db.Where("id = ? AND user_id = ?", recordID, userID).
+		Preload("User").
+		Preload("Product", includeDeleted).
+		Preload("CardKey").
+		First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrExchangeRecordNotFound
+		}
+		return nil, err
+	}
+	if record.Status != model.ExchangeRecordStatusCompleted || record.Cost <= 0 {
+		return nil, ErrReceiptUnavailable
+	}
+
+	if record.ReceiptCode == nil {
+		code, err := newReceiptCode()
+		if err != nil {
+			return nil, err
+		}
+		// A concurrent request may have issued the code first
+		result := s.db.Model(&model.ExchangeRecord{}).
+			Where("id = ? AND receipt_code IS NULL", record.ID).
+			Update("receipt_code", code)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			if err := s.db.Model(&model.ExchangeRecord{}).Where("id = ?", record.ID).
+				Pluck("receipt_code", &code).Error; err != nil {
+				return nil, err
+			}
+		}
+		record.ReceiptCode = &code
+	}
+
+	return toExchangeReceipt(&record), nil
+}
+
+// VerifyExchangeReceipt resolves a receipt verification code (admin only)
+func (s *ExchangeService) VerifyExchangeReceipt(code string) (*ExchangeReceipt, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return nil, ErrReceiptNotFound
+	}
+	var record model.ExchangeRecord
+	if err := s.db.Where("receipt_code = ?", code).
+		Preload("User").
+		Preload("Product", includeDeleted).
+		Preload("CardKey").
+		First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReceiptNotFound
+		}
+		return nil, err
+	}
+	return toExchangeReceipt(&record), nil
+}
+
+// RenderExchangeReceiptPDF renders a receipt as a printable PDF
+func RenderExchangeReceiptPDF(receipt *ExchangeReceipt) []byte {
+	const timeLayout = "2006-01-02 15:04:05"
+	return pdf.Render("兑换收据 "+receipt.Code, []pdf.Line{
+		{Text: "兑换收据", Size: 18},
+		{Text: ""},
+		{Text: "收据验证码：" + receipt.Code},
+		{Text: fmt.Sprintf("兑换记录：#%d", receipt.RecordID)},
+		{Text: fmt.Sprintf("用户：%s（ID %d）", receipt.Username, receipt.UserID)},
+		{Text: fmt.Sprintf("商品：%s（ID %d）", receipt.ProductName, receipt.ProductID)},
+		{Text: fmt.Sprintf("消耗积分：%d", receipt.Cost)},
+		{Text: "卡密：" + receipt.CardKey},
+		{Text: "兑换时间：" + biztime.In(receipt.RedeemedAt).Format(timeLayout)},
+		{Text: ""},
+		{Text: "本收据可凭验证码向平台管理员核验。", Size: 9},
+	})
+}
+
+func toExchangeReceipt(record *model.ExchangeRecord) *ExchangeReceipt {
+	receipt := &ExchangeReceipt{
+		RecordID:    record.ID,
+		UserID:      record.UserID,
+		Username:    record.User.Username,
+		ProductID:   record.ProductID,
+		ProductName: record.Product.Name,
+		Cost:        record.Cost,
+		CardKey:     redact.Partial(record.CardKey.KeyContent, 4),
+		RedeemedAt:  record.CreatedAt,
+	}
+	if record.ReceiptCode != nil {
+		receipt.Code = *record.ReceiptCode
+	}
+	return receipt
+}
+
+// newReceiptCode returns a new random receipt verification code
+func newReceiptCode() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return receiptCodePrefix + strings.ToUpper(hex.EncodeToString(buf)), nil
+}
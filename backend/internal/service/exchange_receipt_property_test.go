@@ -0,0 +1,126 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+)
+
+// Exchange receipts: a redemption keeps one verification code however often
+// its receipt is requested, the code resolves to the same receipt for
+// admins, and the card key on it is masked.
+func TestExchangeReceiptVerification(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("receipts keep their code and resolve", prop.ForAll(
+		func(requests int) bool {
+			db := setupExchangeTestDB(t)
+			if err := createTestUserWithBalance(db, 1, 100); err != nil {
+				return false
+			}
+			if err := createTestProductWithCardKeys(db, 1, 10, 1); err != nil {
+				return false
+			}
+			db.Model(&model.CardKey{}).Where("product_id = ?", 1).Update("key_content", "ABCD-EFGH-IJKL-MNOP")
+			exchangeService := NewExchangeService(db, NewWalletService(db))
+
+			redeemed, err := exchangeService.Redeem(1, 1)
+			if err != nil {
+				t.Logf("Redeem failed: %v", err)
+				return false
+			}
+
+			var first *ExchangeReceipt
+			for i := 0; i < requests; i++ {
+				receipt, err := exchangeService.GetExchangeReceipt(1, redeemed.RecordID)
+				if err != nil {
+					t.Logf("GetExchangeReceipt failed: %v", err)
+					return false
+				}
+				if first == nil {
+					first = receipt
+				}
+				if receipt.Code != first.Code || !strings.HasPrefix(receipt.Code, receiptCodePrefix) {
+					t.Logf("Expected code %s kept, got %s", first.Code, receipt.Code)
+					return false
+				}
+			}
+			if first.Cost != 10 || first.ProductName != "Test Product" || first.CardKey != "ABCD****MNOP" {
+				t.Logf("Unexpected receipt %+v", first)
+				return false
+			}
+
+			verified, err := exchangeService.VerifyExchangeReceipt(" " + strings.ToLower(first.Code) + " ")
+			if err != nil || *verified != *first {
+				t.Logf("Expected the code to resolve to %+v, got %+v (err %v)", first, verified, err)
+				return false
+			}
+
+			// Other users cannot get the receipt
+			if _, err := exchangeService.GetExchangeReceipt(2, redeemed.RecordID); err != ErrExchangeRecordNotFound {
+				t.Logf("Expected ErrExchangeRecordNotFound, got %v", err)
+				return false
+			}
+			return true
+		},
+		gen.IntRange(1, 4),
+	))
+
+	properties.TestingRun(t)
+}
+
+// Only completed redemptions paid with points have receipts, unknown codes
+// do not resolve, and the PDF carries the code
+func TestExchangeReceiptAvailability(t *testing.T) {
+	db := setupExchangeTestDB(t)
+	if err := createTestUserWithBalance(db, 1, 100); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if err := createTestProductWithCardKeys(db, 1, 10, 2); err != nil {
+		t.Fatalf("Failed to create product: %v", err)
+	}
+	exchangeService := NewExchangeService(db, NewWalletService(db))
+
+	reserved := model.ExchangeRecord{UserID: 1, ProductID: 1, CardKeyID: 1, Cost: 10, Status: model.ExchangeRecordStatusReserved}
+	free := model.ExchangeRecord{UserID: 1, ProductID: 1, CardKeyID: 2, Cost: 0, Status: model.ExchangeRecordStatusCompleted}
+	db.Create(&reserved)
+	db.Create(&free)
+	for _, record := range []model.ExchangeRecord{reserved, free} {
+		if _, err := exchangeService.GetExchangeReceipt(1, record.ID); err != ErrReceiptUnavailable {
+			t.Errorf("Expected no receipt for %s record costing %d, got %v", record.Status, record.Cost, err)
+		}
+	}
+	if _, err := exchangeService.VerifyExchangeReceipt("RC0000000000000000"); err != ErrReceiptNotFound {
+		t.Errorf("Expected an unknown code not to resolve, got %v", err)
+	}
+
+	db.Model(&reserved).Update("status", model.ExchangeRecordStatusCompleted)
+	receipt, err := exchangeService.GetExchangeReceipt(1, reserved.ID)
+	if err != nil {
+		t.Fatalf("GetExchangeReceipt failed: %v", err)
+	}
+	doc := RenderExchangeReceiptPDF(receipt)
+	if !bytes.HasPrefix(doc, []byte("%PDF-")) || !bytes.Contains(doc, []byte(encodeReceiptCode(receipt.Code))) {
+		t.Error("Expected a PDF carrying the verification code")
+	}
+}
+
+// encodeReceiptCode encodes an ASCII code as the PDF writer sets text
+func encodeReceiptCode(code string) string {
+	var b strings.Builder
+	for _, r := range code {
+		fmt.Fprintf(&b, "%04X", r)
+	}
+	return b.String()
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+)
+
+// Two-phase exchange: a reservation holds points and stock without revealing
+// the key; confirming completes it, while cancelling or timing out restores
+// the balance and the stock exactly once.
+func TestExchangeReservation(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	// outcome: 0 = confirm, 1 = cancel, 2 = time out
+	properties.Property("reserve then confirm, cancel or time out", prop.ForAll(
+		func(price int, outcome int) bool {
+			db := setupExchangeTestDB(t)
+			if err := createTestUserWithBalance(db, 1, price); err != nil {
+				return false
+			}
+			if err := createTestProductWithCardKeys(db, 1, price, 1); err != nil {
+				return false
+			}
+			walletService := NewWalletService(db)
+			exchangeService := NewExchangeService(db, walletService)
+			reservations := NewExchangeReservationService(db, exchangeService, time.Hour)
+
+			reserved, err := reservations.Reserve(1, 1)
+			if err != nil {
+				t.Logf("Reserve failed: %v", err)
+				return false
+			}
+			if reserved.Balance != 0 {
+				t.Logf("Points should be held, balance %d", reserved.Balance)
+				return false
+			}
+
+			// Held stock cannot be redeemed by anyone else
+			if err := createTestUserWithBalance(db, 2, price); err != nil {
+				return false
+			}
+			if _, err := exchangeService.Redeem(2, 1); err != ErrProductSoldOut && err != ErrNoAvailableCardKey {
+				t.Logf("Expected sold out while reserved, got %v", err)
+				return false
+			}
+
+			record, err := exchangeService.GetExchangeRecordByID(1, reserved.RecordID)
+			if err != nil || record.Status != model.ExchangeRecordStatusReserved || record.CardKey != "" {
+				t.Logf("Reserved record should hide the key: %+v (err %v)", record, err)
+				return false
+			}
+
+			switch outcome {
+			case 0:
+				confirmed, err := reservations.Confirm(1, reserved.RecordID)
+				if err != nil || confirmed.CardKey == "" {
+					t.Logf("Confirm: %+v (err %v)", confirmed, err)
+					return false
+				}
+				record, _ = exchangeService.GetExchangeRecordByID(1, reserved.RecordID)
+				return record.Status == model.ExchangeRecordStatusCompleted && record.CardKey == confirmed.CardKey
+			case 1:
+				if err := reservations.Cancel(1, reserved.RecordID); err != nil {
+					t.Logf("Cancel failed: %v", err)
+					return false
+				}
+			default:
+				db.Model(&model.ExchangeRecord{}).Where("id = ?", reserved.RecordID).
+					Update("reserved_until", time.Now().Add(-time.Minute))
+				if released, err := reservations.ReleaseExpired(); err != nil || released != 1 {
+					t.Logf("ReleaseExpired: %d (err %v)", released, err)
+					return false
+				}
+			}
+
+			// Released exactly once: further actions fail and nothing more is refunded
+			if _, err := reservations.Confirm(1, reserved.RecordID); err != ErrReservationNotFound {
+				t.Logf("Expected ErrReservationNotFound, got %v", err)
+				return false
+			}
+			if released, _ := reservations.ReleaseExpired(); released != 0 {
+				return false
+			}
+			balance, _ := walletService.GetBalance(1)
+			if balance != price {
+				t.Logf("Balance should be refunded to %d, got %d", price, balance)
+				return false
+			}
+			_, err = exchangeService.Redeem(2, 1)
+			return err == nil
+		},
+		gen.IntRange(1, 500),
+		gen.IntRange(0, 2),
+	))
+
+	properties.TestingRun(t)
+}
@@ -0,0 +1,260 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+	"scratch-lottery/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrReservationNotFound = errors.New("reservation not found")
+	ErrReservationExpired  = errors.New("reservation expired")
+)
+
+// ExchangeReservationService handles two-phase exchanges: a reservation holds
+// the points and a card key for a limited time, and a confirmation completes
+// the redemption. Reservations that are cancelled or time out are released,
+// returning the card key to stock and refunding the points.
+type ExchangeReservationService struct {
+	db              *gorm.DB
+	exchangeService *ExchangeService
+	holdFor         time.Duration
+}
+
+// NewExchangeReservationService creates a new exchange reservation service.
+// holdFor is how long a reservation holds the points and card key.
+func NewExchangeReservationService(db *gorm.DB, exchangeService *ExchangeService, holdFor time.Duration) *ExchangeReservationService {
+	if holdFor <= 0 {
+		holdFor = 15 * time.Minute
+	}
+	return &ExchangeReservationService{
+		db:              db,
+		exchangeService: exchangeService,
+		holdFor:         holdFor,
+	}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *ExchangeReservationService) ForTenant(tenantID uint) *ExchangeReservationService {
+	return &ExchangeReservationService{
+		db:              repository.ScopeTenant(s.db, tenantID),
+		exchangeService: s.exchangeService.ForTenant(tenantID),
+		holdFor:         s.holdFor,
+	}
+}
+
+// ReserveResponse represents a created reservation
+type ReserveResponse struct {
+	RecordID      uint      `json:"record_id"`
+	ProductName   string    `json:"product_name"`
+	Cost          int       `json:"cost"`
+	Balance       int       `json:"balance"`
+	ReservedUntil time.Time `json:"reserved_until"`
+}
+
+// Reserve holds the product price and a card key for the user until confirmed
+func (s *ExchangeReservationService) Reserve(userID uint, productID uint) (*ReserveResponse, error) {
+	reservedUntil := time.Now().Add(s.holdFor)
+	result, err := s.exchangeService.redeemProduct(userID, productID,
+		func(tx *gorm.DB, cardKey *model.CardKey, record *model.ExchangeRecord) error {
+			if err := tx.Model(cardKey).Update("status", model.CardKeyStatusReserved).Error; err != nil {
+				return err
+			}
+			record.Status = model.ExchangeRecordStatusReserved
+			record.ReservedUntil = &reservedUntil
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReserveResponse{
+		RecordID:      result.record.ID,
+		ProductName:   result.product.Name,
+		Cost:          result.product.Price,
+		Balance:       result.balance,
+		ReservedUntil: reservedUntil,
+	}, nil
+}
+
+// Confirm completes a reservation and reveals the card key
+func (s *ExchangeReservationService) Confirm(userID uint, recordID uint) (*RedeemResponse, error) {
+	record, err := s.getReservation(userID, recordID)
+	if err != nil {
+		return nil, err
+	}
+	if !record.ReservedUntil.After(time.Now()) {
+		if err := s.release(record, "预订超时"); err != nil && err != ErrReservationNotFound {
+			return nil, err
+		}
+		return nil, ErrReservationExpired
+	}
+
+	var cardKey model.CardKey
+	err = repository.Transaction(s.db, func(tx *gorm.DB) error {
+		result := tx.Model(&model.ExchangeRecord{}).
+			Where("id = ? AND status = ?", record.ID, model.ExchangeRecordStatusReserved).
+			Updates(map[string]interface{}{
+				"status":         model.ExchangeRecordStatusCompleted,
+				"reserved_until": nil,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrReservationNotFound
+		}
+
+		now := time.Now()
+		if err := tx.Model(&model.CardKey{}).Where("id = ?", record.CardKeyID).
+			Updates(map[string]interface{}{
+				"status":      model.CardKeyStatusRedeemed,
+				"redeemed_at": now,
+			}).Error; err != nil {
+			return err
+		}
+		return tx.First(&cardKey, record.CardKeyID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	balance, err := s.exchangeService.walletService.GetBalance(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RedeemResponse{
+		CardKey:     cardKey.KeyContent,
+		ProductName: record.Product.Name,
+		Cost:        record.Cost,
+		Balance:     balance,
+		RecordID:    record.ID,
+	}, nil
+}
+
+// Cancel releases a reservation before it times out
+func (s *ExchangeReservationService) Cancel(userID uint, recordID uint) error {
+	record, err := s.getReservation(userID, recordID)
+	if err != nil {
+		return err
+	}
+	return s.release(record, "取消预订")
+}
+
+// ReleaseExpired releases every reservation past its deadline
+func (s *ExchangeReservationService) ReleaseExpired() (int, error) {
+	var records []model.ExchangeRecord
+	if err := s.db.Where("status = ? AND reserved_until <= ?", model.ExchangeRecordStatusReserved, time.Now()).
+		Preload("Product").
+		Find(&records).Error; err != nil {
+		return 0, err
+	}
+
+	released := 0
+	for i := range records {
+		if err := s.release(&records[i], "预订超时"); err != nil {
+			if err == ErrReservationNotFound {
+				continue // Confirmed or released concurrently
+			}
+			return released, err
+		}
+		released++
+	}
+	return released, nil
+}
+
+// Start releases expired reservations every interval until the returned stop func is called
+func (s *ExchangeReservationService) Start(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				released, err := s.ReleaseExpired()
+				if err != nil {
+					logger.Default().Warn("Releasing expired reservations failed: %v", err)
+				} else if released > 0 {
+					logger.Default().Info("Released %d expired exchange reservations", released)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// getReservation loads a reserved exchange record owned by the user
+func (s *ExchangeReservationService) getReservation(userID uint, recordID uint) (*model.ExchangeRecord, error) {
+	var record model.ExchangeRecord
+	if err := s.db.Where("id = ? AND user_id = ? AND status = ?", recordID, userID, model.ExchangeRecordStatusReserved).
+		Preload("Product").
+		First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReservationNotFound
+		}
+		return nil, err
+	}
+	return &record, nil
+}
+
+// release returns the card key to stock and refunds the held points
+func (s *ExchangeReservationService) release(record *model.ExchangeRecord, reason string) error {
+	return repository.Transaction(s.db, func(tx *gorm.DB) error {
+		result := tx.Model(&model.ExchangeRecord{}).
+			Where("id = ? AND status = ?", record.ID, model.ExchangeRecordStatusReserved).
+			Update("status", model.ExchangeRecordStatusReleased)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrReservationNotFound
+		}
+
+		// Return the card key to stock
+		if err := tx.Model(&model.CardKey{}).Where("id = ?", record.CardKeyID).
+			Updates(map[string]interface{}{
+				"status":      model.CardKeyStatusAvailable,
+				"redeemed_by": 0,
+				"redeemed_at": nil,
+			}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Product{}).Where("id = ?", record.ProductID).
+			Update("stock", gorm.Expr("stock + 1")).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Product{}).
+			Where("id = ? AND status = ?", record.ProductID, model.ProductStatusSoldOut).
+			Update("status", model.ProductStatusAvailable).Error; err != nil {
+			return err
+		}
+
+		// Refund the held points
+		var wallet model.Wallet
+		if err := tx.Where("user_id = ?", record.UserID).First(&wallet).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&wallet).Update("balance", gorm.Expr("balance + ?", record.Cost)).Error; err != nil {
+			return err
+		}
+		transaction := model.Transaction{
+			TenantID:    wallet.TenantID,
+			WalletID:    wallet.ID,
+			Type:        model.TransactionTypeExchange,
+			Amount:      record.Cost,
+			Description: fmt.Sprintf("%s，退回积分: %s", reason, record.Product.Name),
+			ReferenceID: record.ID,
+		}
+		return tx.Create(&transaction).Error
+	})
+}
@@ -1,11 +1,19 @@
 package service
 
 import (
+	"context"
+	"bufio"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"strings"
+	"sync"
 	"time"
 
 	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+	"scratch-lottery/pkg/redact"
 
 	"gorm.io/gorm"
 )
@@ -17,12 +25,25 @@ var (
 	ErrInsufficientPoints  = errors.New("insufficient points")
 	ErrNoAvailableCardKey  = errors.New("no available card key")
 	ErrCardKeyNotFound     = errors.New("card key not found")
+	ErrExchangeRecordNotFound = errors.New("exchange record not found")
+	ErrProductNotDropped      = errors.New("product drop has not started")
+	ErrCardKeyUnavailable     = errors.New("card key not available to this user")
+	ErrCardKeyTooLong         = errors.New("card key too long")
+	ErrInvalidCardKeyQuery    = errors.New("invalid card key status or sort")
 )
 
+// maxCardKeyLength is the maximum length of a card key's content
+const maxCardKeyLength = 512
+
+// cardKeyImportBatch is the number of card keys inserted per statement
+const cardKeyImportBatch = 500
+
 // ExchangeService handles exchange-related business logic
 type ExchangeService struct {
 	db            *gorm.DB
 	walletService *WalletService
+	productLocks  *sync.Map // product ID -> *sync.Mutex, queues flash drop redemptions
+	redaction     FieldRedaction
 }
 
 // NewExchangeService creates a new exchange service
@@ -30,20 +51,68 @@ func NewExchangeService(db *gorm.DB, walletService *WalletService) *ExchangeServ
 	return &ExchangeService{
 		db:            db,
 		walletService: walletService,
+		productLocks:  &sync.Map{},
+	}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *ExchangeService) ForTenant(tenantID uint) *ExchangeService {
+	return &ExchangeService{
+		db:            repository.ScopeTenant(s.db, tenantID),
+		walletService: s.walletService.ForTenant(tenantID),
+		productLocks:  s.productLocks,
+		redaction:     s.redaction,
+	}
+}
+
+// WithRequest returns a copy of the service whose logs carry the request id
+// of ctx
+func (s *ExchangeService) WithRequest(ctx context.Context) *ExchangeService {
+	scoped := *s
+	scoped.db = repository.ScopeRequest(s.db, ctx)
+	scoped.walletService = s.walletService.WithRequest(ctx)
+	return &scoped
+}
+
+// withDB returns a copy of the service working on db, such as a transaction
+func (s *ExchangeService) withDB(db *gorm.DB) *ExchangeService {
+	return &ExchangeService{
+		db:            db,
+		walletService: s.walletService.withDB(db),
+		productLocks:  s.productLocks,
+		redaction:     s.redaction,
+	}
+}
+
+// WithRedaction returns a copy of the service that redacts card keys and
+// exchange records in its responses
+func (s *ExchangeService) WithRedaction(redaction FieldRedaction) *ExchangeService {
+	return &ExchangeService{
+		db:            s.db,
+		walletService: s.walletService,
+		productLocks:  s.productLocks,
+		redaction:     redaction,
 	}
 }
 
 // ProductResponse represents a product in the response
 type ProductResponse struct {
-	ID          uint                 `json:"id"`
-	Name        string               `json:"name"`
-	Description string               `json:"description"`
-	Image       string               `json:"image"`
-	Price       int                  `json:"price"`
-	Stock       int                  `json:"stock"`
-	Status      model.ProductStatus  `json:"status"`
-	CreatedAt   time.Time            `json:"created_at"`
-	UpdatedAt   time.Time            `json:"updated_at"`
+	ID                uint                `json:"id"`
+	Name              string              `json:"name"`
+	Description       string              `json:"description"`
+	Image             string              `json:"image"`
+	Price             int                 `json:"price"`
+	Stock             int                 `json:"stock"`
+	Status            model.ProductStatus `json:"status"`
+	LowStockThreshold int                 `json:"low_stock_threshold"`
+	OneTimeReveal     bool                `json:"one_time_reveal"`
+	DropAt            *time.Time          `json:"drop_at,omitempty"`
+	DropPending       bool                `json:"drop_pending"`             // Drop has not started: stock is hidden
+	DropCountdown     int64               `json:"drop_countdown,omitempty"` // Seconds until the drop starts
+	Cost              string              `json:"cost,omitempty"`            // Admins only: real-money cost in yuan
+	PricingWarning    string              `json:"pricing_warning,omitempty"` // Admins only: set when the price is worth less than the cost
+	CreatedAt         time.Time           `json:"created_at"`
+	UpdatedAt         time.Time           `json:"updated_at"`
 }
 
 // ProductListResponse represents paginated product list
@@ -79,12 +148,77 @@ type RedeemResponse struct {
 
 // ExchangeRecordResponse represents an exchange record in the response
 type ExchangeRecordResponse struct {
-	ID          uint      `json:"id"`
-	ProductID   uint      `json:"product_id"`
-	ProductName string    `json:"product_name"`
-	CardKey     string    `json:"card_key"`
-	Cost        int       `json:"cost"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID            uint                       `json:"id"`
+	ProductID     uint                       `json:"product_id"`
+	ProductName   string                     `json:"product_name"`
+	CardKey       string                     `json:"card_key"`
+	Cost          int                        `json:"cost"`
+	Status        model.ExchangeRecordStatus `json:"status"`
+	ReservedUntil *time.Time                 `json:"reserved_until,omitempty"`
+	GiftID        *uint                      `json:"gift_id,omitempty"`
+	GiftStatus    model.ExchangeGiftStatus   `json:"gift_status,omitempty"`
+	KeyMasked     bool                       `json:"key_masked"` // Listed, or a one-time reveal product: use the reveal action to view the key
+	Disputed      bool                       `json:"disputed,omitempty"` // Card key frozen by a dispute of the exchange
+	CreatedAt     time.Time                  `json:"created_at"`
+}
+
+// CardKeyRevealResponse represents a re-revealed card key
+type CardKeyRevealResponse struct {
+	RecordID   uint      `json:"record_id"`
+	CardKey    string    `json:"card_key"`
+	RevealedAt time.Time `json:"revealed_at"`
+}
+
+// CardKeyRevealQuery represents query parameters for the card key reveal audit log
+type CardKeyRevealQuery struct {
+	UserID   uint `form:"user_id"`
+	RecordID uint `form:"record_id"`
+	Page     int  `form:"page"`
+	Limit    int  `form:"limit"`
+}
+
+// CardKeyRevealListResponse represents paginated card key reveal audit entries
+type CardKeyRevealListResponse struct {
+	Reveals    []model.CardKeyReveal `json:"reveals"`
+	Total      int64                 `json:"total"`
+	Page       int                   `json:"page"`
+	Limit      int                   `json:"limit"`
+	TotalPages int                   `json:"total_pages"`
+}
+
+// CardKeyQuery represents query parameters for the card keys of a product
+type CardKeyQuery struct {
+	Status     string `form:"status"`
+	RedeemedBy uint   `form:"redeemed_by"`
+	Sort       string `form:"sort"` // newest (default), oldest or redeemed (latest redemption first)
+	Page       int    `form:"page"`
+	Limit      int    `form:"limit"`
+}
+
+// CardKeySummary counts the card keys of a product by status
+type CardKeySummary struct {
+	Available int64 `json:"available"`
+	Reserved  int64 `json:"reserved"`
+	Redeemed  int64 `json:"redeemed"`
+	Total     int64 `json:"total"`
+}
+
+// CardKeyListResponse represents a page of the card keys of a product
+type CardKeyListResponse struct {
+	CardKeys   []model.CardKey `json:"card_keys"`
+	Total      int64           `json:"total"` // Card keys matching the filters
+	Page       int             `json:"page"`
+	Limit      int             `json:"limit"`
+	TotalPages int             `json:"total_pages"`
+	Summary    CardKeySummary  `json:"summary"` // All card keys of the product, regardless of the filters
+}
+
+// cardKeyOrders maps the sort options of card key listings to their order
+var cardKeyOrders = map[string]string{
+	"":         "id DESC",
+	"newest":   "id DESC",
+	"oldest":   "id ASC",
+	"redeemed": "redeemed_at IS NULL, redeemed_at DESC, id DESC",
 }
 
 // ExchangeRecordListResponse represents paginated exchange record list
@@ -104,19 +238,28 @@ type ExchangeRecordQuery struct {
 
 // CreateProductRequest represents a request to create a product
 type CreateProductRequest struct {
-	Name        string `json:"name" binding:"required"`
-	Description string `json:"description"`
-	Image       string `json:"image"`
-	Price       int    `json:"price" binding:"required,gt=0"`
+	Name              string     `json:"name" binding:"required"`
+	Description       string     `json:"description"`
+	Image             string     `json:"image"`
+	Price             int        `json:"price" binding:"required,gt=0"`
+	LowStockThreshold int        `json:"low_stock_threshold" binding:"gte=0"`
+	OneTimeReveal     bool       `json:"one_time_reveal"`
+	DropAt            *time.Time `json:"drop_at"`
+	Cost              string     `json:"cost"` // Real-money cost in yuan, such as "12.50"
 }
 
 // UpdateProductRequest represents a request to update a product
 type UpdateProductRequest struct {
-	Name        *string              `json:"name"`
-	Description *string              `json:"description"`
-	Image       *string              `json:"image"`
-	Price       *int                 `json:"price"`
-	Status      *model.ProductStatus `json:"status"`
+	Name              *string              `json:"name"`
+	Description       *string              `json:"description"`
+	Image             *string              `json:"image"`
+	Price             *int                 `json:"price"`
+	Status            *model.ProductStatus `json:"status"`
+	LowStockThreshold *int                 `json:"low_stock_threshold" binding:"omitempty,gte=0"`
+	OneTimeReveal     *bool                `json:"one_time_reveal"`
+	DropAt            *time.Time           `json:"drop_at"`
+	ClearDropAt       bool                 `json:"clear_drop_at"` // Remove the drop schedule
+	Cost              *string              `json:"cost"`          // Real-money cost in yuan, "" or "0" when unknown
 }
 
 // ImportCardKeysRequest represents a request to import card keys
@@ -127,22 +270,33 @@ type ImportCardKeysRequest struct {
 
 // ==================== Product CRUD Operations ====================
 
-// CreateProduct creates a new product
+// CreateProduct creates a new product. The response carries a pricing
+// warning when the price is worth less than the product's cost.
 func (s *ExchangeService) CreateProduct(req CreateProductRequest) (*ProductResponse, error) {
 	product := model.Product{
-		Name:        req.Name,
-		Description: req.Description,
-		Image:       req.Image,
-		Price:       req.Price,
-		Stock:       0,
-		Status:      model.ProductStatusAvailable,
+		Name:              req.Name,
+		Description:       req.Description,
+		Image:             req.Image,
+		Price:             req.Price,
+		Stock:             0,
+		Status:            model.ProductStatusAvailable,
+		LowStockThreshold: req.LowStockThreshold,
+		OneTimeReveal:     req.OneTimeReveal,
+		DropAt:            req.DropAt,
+	}
+	if req.Cost != "" {
+		cost, err := parseProductCost(req.Cost)
+		if err != nil {
+			return nil, err
+		}
+		product.Cost = int(cost.Amount)
 	}
 
 	if err := s.db.Create(&product).Error; err != nil {
 		return nil, err
 	}
 
-	return s.toProductResponse(&product), nil
+	return s.toAdminProductResponse(&product), nil
 }
 
 // GetProducts retrieves paginated products
@@ -189,8 +343,13 @@ func (s *ExchangeService) GetProducts(query ProductQuery) (*ProductListResponse,
 		totalPages++
 	}
 
+	responses := s.toProductResponses(products)
+	for i := range responses {
+		hideUndroppedStock(&responses[i])
+	}
+
 	return &ProductListResponse{
-		Products:   s.toProductResponses(products),
+		Products:   responses,
 		Total:      total,
 		Page:       query.Page,
 		Limit:      query.Limit,
@@ -236,8 +395,13 @@ func (s *ExchangeService) GetAllProducts(query ProductQuery) (*ProductListRespon
 		totalPages++
 	}
 
+	responses := make([]ProductResponse, len(products))
+	for i := range products {
+		responses[i] = *s.toAdminProductResponse(&products[i])
+	}
+
 	return &ProductListResponse{
-		Products:   s.toProductResponses(products),
+		Products:   responses,
 		Total:      total,
 		Page:       query.Page,
 		Limit:      query.Limit,
@@ -255,7 +419,9 @@ func (s *ExchangeService) GetProductByID(id uint) (*ProductResponse, error) {
 		}
 		return nil, err
 	}
-	return s.toProductResponse(&product), nil
+	resp := s.toProductResponse(&product)
+	hideUndroppedStock(resp)
+	return resp, nil
 }
 
 // UpdateProduct updates a product
@@ -284,12 +450,33 @@ func (s *ExchangeService) UpdateProduct(id uint, req UpdateProductRequest) (*Pro
 	if req.Status != nil {
 		product.Status = *req.Status
 	}
+	if req.LowStockThreshold != nil {
+		product.LowStockThreshold = *req.LowStockThreshold
+	}
+	if req.OneTimeReveal != nil {
+		product.OneTimeReveal = *req.OneTimeReveal
+	}
+	if req.DropAt != nil {
+		product.DropAt = req.DropAt
+	} else if req.ClearDropAt {
+		product.DropAt = nil
+	}
+	if req.Cost != nil {
+		product.Cost = 0
+		if *req.Cost != "" {
+			cost, err := parseProductCost(*req.Cost)
+			if err != nil {
+				return nil, err
+			}
+			product.Cost = int(cost.Amount)
+		}
+	}
 
 	if err := s.db.Save(&product).Error; err != nil {
 		return nil, err
 	}
 
-	return s.toProductResponse(&product), nil
+	return s.toAdminProductResponse(&product), nil
 }
 
 // DeleteProduct deletes a product (soft delete)
@@ -308,6 +495,36 @@ func (s *ExchangeService) DeleteProduct(id uint) error {
 
 // ImportCardKeys imports card keys for a product
 func (s *ExchangeService) ImportCardKeys(productID uint, cardKeys []string) (int, error) {
+	next := 0
+	return s.importCardKeys(productID, func() (string, bool, error) {
+		if next == len(cardKeys) {
+			return "", false, nil
+		}
+		next++
+		return cardKeys[next-1], true, nil
+	})
+}
+
+// ImportCardKeysFrom imports card keys read from r, one per line, without
+// holding the whole import in memory. Blank lines are skipped.
+func (s *ExchangeService) ImportCardKeysFrom(productID uint, r io.Reader) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxCardKeyLength+2) // room for a CRLF
+	return s.importCardKeys(productID, func() (string, bool, error) {
+		if !scanner.Scan() {
+			if errors.Is(scanner.Err(), bufio.ErrTooLong) {
+				return "", false, ErrCardKeyTooLong
+			}
+			return "", false, scanner.Err()
+		}
+		return strings.TrimSpace(scanner.Text()), true, nil
+	})
+}
+
+// importCardKeys adds the card keys returned by next to a product's stock in
+// one transaction, inserting them in batches. next reports false once there
+// are no more keys.
+func (s *ExchangeService) importCardKeys(productID uint, next func() (string, bool, error)) (int, error) {
 	var product model.Product
 	if err := s.db.First(&product, productID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -318,19 +535,45 @@ func (s *ExchangeService) ImportCardKeys(productID uint, cardKeys []string) (int
 
 	imported := 0
 	err := s.db.Transaction(func(tx *gorm.DB) error {
-		for _, key := range cardKeys {
+		batch := make([]model.CardKey, 0, cardKeyImportBatch)
+		flush := func() error {
+			if len(batch) == 0 {
+				return nil
+			}
+			if err := tx.Create(&batch).Error; err != nil {
+				return err
+			}
+			imported += len(batch)
+			batch = batch[:0]
+			return nil
+		}
+		for {
+			key, ok, err := next()
+			if err != nil {
+				return err
+			}
+			if !ok {
+				break
+			}
 			if key == "" {
 				continue
 			}
-			cardKey := model.CardKey{
+			if len(key) > maxCardKeyLength {
+				return ErrCardKeyTooLong
+			}
+			batch = append(batch, model.CardKey{
 				ProductID:  productID,
 				KeyContent: key,
 				Status:     model.CardKeyStatusAvailable,
+			})
+			if len(batch) == cardKeyImportBatch {
+				if err := flush(); err != nil {
+					return err
+				}
 			}
-			if err := tx.Create(&cardKey).Error; err != nil {
-				return err
-			}
-			imported++
+		}
+		if err := flush(); err != nil {
+			return err
 		}
 
 		// Update product stock
@@ -356,8 +599,25 @@ func (s *ExchangeService) ImportCardKeys(productID uint, cardKeys []string) (int
 }
 
 
-// GetCardKeysByProductID retrieves card keys for a product (admin only)
-func (s *ExchangeService) GetCardKeysByProductID(productID uint, status string) ([]model.CardKey, error) {
+// GetCardKeysByProductID retrieves a page of the card keys of a product with
+// a count of all its card keys by status (admin only)
+func (s *ExchangeService) GetCardKeysByProductID(productID uint, query CardKeyQuery) (*CardKeyListResponse, error) {
+	order, ok := cardKeyOrders[query.Sort]
+	if !ok {
+		return nil, ErrInvalidCardKeyQuery
+	}
+	switch model.CardKeyStatus(query.Status) {
+	case "", model.CardKeyStatusAvailable, model.CardKeyStatusReserved, model.CardKeyStatusRedeemed:
+	default:
+		return nil, ErrInvalidCardKeyQuery
+	}
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit < 1 || query.Limit > 100 {
+		query.Limit = 20
+	}
+
 	var product model.Product
 	if err := s.db.First(&product, productID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -366,44 +626,237 @@ func (s *ExchangeService) GetCardKeysByProductID(productID uint, status string)
 		return nil, err
 	}
 
-	dbQuery := s.db.Where("product_id = ?", productID)
-	if status != "" {
-		dbQuery = dbQuery.Where("status = ?", status)
+	summary, err := s.cardKeySummary(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	dbQuery := s.db.Model(&model.CardKey{}).Where("product_id = ?", productID)
+	if query.Status != "" {
+		dbQuery = dbQuery.Where("status = ?", query.Status)
+	}
+	if query.RedeemedBy > 0 {
+		dbQuery = dbQuery.Where("redeemed_by = ?", query.RedeemedBy)
+	}
+
+	var total int64
+	if err := dbQuery.Count(&total).Error; err != nil {
+		return nil, err
 	}
 
 	var cardKeys []model.CardKey
-	if err := dbQuery.Order("created_at DESC").Find(&cardKeys).Error; err != nil {
+	offset := (query.Page - 1) * query.Limit
+	if err := dbQuery.Order(order).
+		Offset(offset).
+		Limit(query.Limit).
+		Find(&cardKeys).Error; err != nil {
 		return nil, err
 	}
+	for i := range cardKeys {
+		cardKeys[i].KeyContent = s.redaction.CardKey(cardKeys[i].KeyContent)
+	}
 
-	return cardKeys, nil
+	totalPages := int(total) / query.Limit
+	if int(total)%query.Limit > 0 {
+		totalPages++
+	}
+
+	return &CardKeyListResponse{
+		CardKeys:   cardKeys,
+		Total:      total,
+		Page:       query.Page,
+		Limit:      query.Limit,
+		TotalPages: totalPages,
+		Summary:    summary,
+	}, nil
 }
 
-// UpdateProductStock updates the stock count based on available card keys
-func (s *ExchangeService) UpdateProductStock(productID uint) error {
-	var count int64
+// cardKeySummary counts the card keys of a product by status
+func (s *ExchangeService) cardKeySummary(productID uint) (CardKeySummary, error) {
+	var counts []struct {
+		Status model.CardKeyStatus
+		Count  int64
+	}
+	var summary CardKeySummary
 	if err := s.db.Model(&model.CardKey{}).
-		Where("product_id = ? AND status = ?", productID, model.CardKeyStatusAvailable).
-		Count(&count).Error; err != nil {
-		return err
+		Select("status, COUNT(*) AS count").
+		Where("product_id = ?", productID).
+		Group("status").
+		Scan(&counts).Error; err != nil {
+		return summary, err
+	}
+	for _, c := range counts {
+		switch c.Status {
+		case model.CardKeyStatusAvailable:
+			summary.Available = c.Count
+		case model.CardKeyStatusReserved:
+			summary.Reserved = c.Count
+		case model.CardKeyStatusRedeemed:
+			summary.Redeemed = c.Count
+		}
+		summary.Total += c.Count
 	}
+	return summary, nil
+}
 
-	updates := map[string]interface{}{
-		"stock": count,
+// StockCorrection is the result of recalculating a product's stock from its
+// available card keys
+type StockCorrection struct {
+	ProductID    uint                `json:"product_id"`
+	ProductName  string              `json:"product_name"`
+	StockBefore  int                 `json:"stock_before"`
+	StockAfter   int                 `json:"stock_after"`
+	StatusBefore model.ProductStatus `json:"status_before"`
+	StatusAfter  model.ProductStatus `json:"status_after"`
+	Corrected    bool                `json:"corrected"`
+}
+
+// StockRepairResponse is the result of recalculating the stock of all products
+type StockRepairResponse struct {
+	Checked     int               `json:"checked"`
+	Corrected   int               `json:"corrected"`
+	Corrections []StockCorrection `json:"corrections"` // Only products whose stock or status changed
+}
+
+// UpdateProductStock updates the stock count based on available card keys
+func (s *ExchangeService) UpdateProductStock(productID uint) error {
+	_, err := s.recalculateProductStock(0, productID)
+	return err
+}
+
+// RecalculateProductStock resets a product's stock to its number of available
+// card keys, fixing drift left by revoked keys or failed imports. Corrections
+// are recorded in the admin log.
+func (s *ExchangeService) RecalculateProductStock(adminID, productID uint) (*StockCorrection, error) {
+	return s.recalculateProductStock(adminID, productID)
+}
+
+// RecalculateAllStock recalculates the stock of every product
+func (s *ExchangeService) RecalculateAllStock(adminID uint) (*StockRepairResponse, error) {
+	var productIDs []uint
+	if err := s.db.Model(&model.Product{}).Order("id ASC").Pluck("id", &productIDs).Error; err != nil {
+		return nil, err
 	}
 
-	// Update status based on stock
-	if count == 0 {
-		updates["status"] = model.ProductStatusSoldOut
+	result := &StockRepairResponse{Corrections: []StockCorrection{}}
+	for _, productID := range productIDs {
+		correction, err := s.recalculateProductStock(adminID, productID)
+		if errors.Is(err, ErrProductNotFound) {
+			continue // deleted meanwhile
+		}
+		if err != nil {
+			return nil, err
+		}
+		result.Checked++
+		if correction.Corrected {
+			result.Corrected++
+			result.Corrections = append(result.Corrections, *correction)
+		}
 	}
+	return result, nil
+}
+
+// recalculateProductStock sets a product's stock to its available card keys
+// and its status to match: sold out without stock, available again with
+// stock. Offline products stay offline. A correction made by an admin
+// (adminID != 0) is logged.
+func (s *ExchangeService) recalculateProductStock(adminID, productID uint) (*StockCorrection, error) {
+	unlock := s.lockProduct(productID)
+	defer unlock()
 
-	return s.db.Model(&model.Product{}).Where("id = ?", productID).Updates(updates).Error
+	var correction StockCorrection
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var product model.Product
+		if err := tx.First(&product, productID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrProductNotFound
+			}
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&model.CardKey{}).
+			Where("product_id = ? AND status = ?", productID, model.CardKeyStatusAvailable).
+			Count(&count).Error; err != nil {
+			return err
+		}
+
+		status := product.Status
+		switch {
+		case count == 0 && status == model.ProductStatusAvailable:
+			status = model.ProductStatusSoldOut
+		case count > 0 && status == model.ProductStatusSoldOut:
+			status = model.ProductStatusAvailable
+		}
+
+		correction = StockCorrection{
+			ProductID:    product.ID,
+			ProductName:  product.Name,
+			StockBefore:  product.Stock,
+			StockAfter:   int(count),
+			StatusBefore: product.Status,
+			StatusAfter:  status,
+			Corrected:    product.Stock != int(count) || product.Status != status,
+		}
+		if !correction.Corrected {
+			return nil
+		}
+
+		if err := tx.Model(&model.Product{}).Where("id = ?", productID).Updates(map[string]interface{}{
+			"stock":  count,
+			"status": status,
+		}).Error; err != nil {
+			return err
+		}
+
+		if adminID == 0 {
+			return nil
+		}
+		details, _ := json.Marshal(correction)
+		adminLog := model.AdminLog{
+			AdminID:    adminID,
+			Action:     "recalculate_product_stock",
+			TargetType: "product",
+			TargetID:   productID,
+			Details:    string(details),
+		}
+		return tx.Create(&adminLog).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &correction, nil
 }
 
 // ==================== Exchange Operations ====================
 
 // Redeem redeems a product for a user
 func (s *ExchangeService) Redeem(userID uint, productID uint) (*RedeemResponse, error) {
+	result, err := s.redeemProduct(userID, productID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RedeemResponse{
+		CardKey:     result.cardKey.KeyContent,
+		ProductName: result.product.Name,
+		Cost:        result.product.Price,
+		Balance:     result.balance,
+		RecordID:    result.record.ID,
+	}, nil
+}
+
+// redeemResult holds the outcome of redeemProduct
+type redeemResult struct {
+	product model.Product
+	cardKey model.CardKey
+	record  model.ExchangeRecord
+	balance int
+}
+
+// redeemProduct charges the user and assigns a card key. beforeRecord, if not nil,
+// runs inside the transaction before the exchange record is created.
+func (s *ExchangeService) redeemProduct(userID uint, productID uint, beforeRecord func(tx *gorm.DB, cardKey *model.CardKey, record *model.ExchangeRecord) error) (*redeemResult, error) {
 	var product model.Product
 	if err := s.db.First(&product, productID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -416,6 +869,9 @@ func (s *ExchangeService) Redeem(userID uint, productID uint) (*RedeemResponse,
 	if product.Status == model.ProductStatusOffline {
 		return nil, ErrProductOffline
 	}
+	if product.DropAt != nil && time.Now().Before(*product.DropAt) {
+		return nil, ErrProductNotDropped
+	}
 	if product.Status == model.ProductStatusSoldOut || product.Stock <= 0 {
 		return nil, ErrProductSoldOut
 	}
@@ -429,52 +885,41 @@ func (s *ExchangeService) Redeem(userID uint, productID uint) (*RedeemResponse,
 		return nil, ErrInsufficientPoints
 	}
 
+	// Flash drops see bursts of redemptions the moment they open; queue them
+	// per product so they do not all contend for the same rows at once
+	if product.DropAt != nil {
+		unlock := s.lockProduct(productID)
+		defer unlock()
+	}
+
 	var record model.ExchangeRecord
 	var cardKey model.CardKey
 	var newBalance int
 
-	err = s.db.Transaction(func(tx *gorm.DB) error {
-		// Find and lock an available card key
-		if err := tx.Where("product_id = ? AND status = ?", productID, model.CardKeyStatusAvailable).
-			Order("created_at ASC").
-			First(&cardKey).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return ErrNoAvailableCardKey
-			}
+	err = repository.Transaction(s.db, func(tx *gorm.DB) error {
+		cardKey = model.CardKey{}
+		if err := claimCardKey(tx, productID, userID, &cardKey); err != nil {
 			return err
 		}
 
-		// Mark card key as redeemed
-		now := time.Now()
-		cardKey.Status = model.CardKeyStatusRedeemed
-		cardKey.RedeemedBy = userID
-		cardKey.RedeemedAt = &now
-		if err := tx.Save(&cardKey).Error; err != nil {
-			return err
+		// Deduct points from wallet, never below zero nor from a frozen wallet
+		result := tx.Model(&model.Wallet{}).
+			Where("user_id = ? AND balance >= ? AND frozen_at IS NULL", userID, product.Price).
+			Update("balance", gorm.Expr("balance - ?", product.Price))
+		if result.Error != nil {
+			return result.Error
 		}
-
-		// Deduct points from wallet
 		var wallet model.Wallet
 		if err := tx.Where("user_id = ?", userID).First(&wallet).Error; err != nil {
 			return err
 		}
-		wallet.Balance -= product.Price
-		newBalance = wallet.Balance
-		if err := tx.Save(&wallet).Error; err != nil {
-			return err
-		}
-
-		// Create transaction record
-		transaction := model.Transaction{
-			WalletID:    wallet.ID,
-			Type:        model.TransactionTypeExchange,
-			Amount:      -product.Price,
-			Description: fmt.Sprintf("兑换商品: %s", product.Name),
-			ReferenceID: productID,
-		}
-		if err := tx.Create(&transaction).Error; err != nil {
-			return err
+		if result.RowsAffected == 0 {
+			if wallet.FrozenAt != nil {
+				return ErrWalletFrozen
+			}
+			return ErrInsufficientPoints
 		}
+		newBalance = wallet.Balance
 
 		// Create exchange record
 		record = model.ExchangeRecord{
@@ -483,35 +928,105 @@ func (s *ExchangeService) Redeem(userID uint, productID uint) (*RedeemResponse,
 			CardKeyID: cardKey.ID,
 			Cost:      product.Price,
 		}
+		if beforeRecord != nil {
+			if err := beforeRecord(tx, &cardKey, &record); err != nil {
+				return err
+			}
+		}
 		if err := tx.Create(&record).Error; err != nil {
 			return err
 		}
 
-		// Update product stock
-		product.Stock--
-		if product.Stock <= 0 {
-			product.Status = model.ProductStatusSoldOut
+		// Create transaction record, which references the exchange record
+		transaction := model.Transaction{
+			TenantID:    wallet.TenantID,
+			WalletID:    wallet.ID,
+			Type:        model.TransactionTypeExchange,
+			Amount:      -product.Price,
+			Description: fmt.Sprintf("兑换商品: %s", product.Name),
+			ReferenceID: record.ID,
 		}
-		if err := tx.Save(&product).Error; err != nil {
+		if err := tx.Create(&transaction).Error; err != nil {
 			return err
 		}
 
-		return nil
+		if err := takeProductStock(tx, productID); err != nil {
+			return err
+		}
+		return tx.First(&product, productID).Error
 	})
 
 	if err != nil {
 		return nil, err
 	}
 
-	return &RedeemResponse{
-		CardKey:     cardKey.KeyContent,
-		ProductName: product.Name,
-		Cost:        product.Price,
-		Balance:     newBalance,
-		RecordID:    record.ID,
+	return &redeemResult{
+		product: product,
+		cardKey: cardKey,
+		record:  record,
+		balance: newBalance,
 	}, nil
 }
 
+// claimCardKey redeems an available card key of a product for a user. The
+// status guard makes the claim safe against concurrent redemptions picking
+// the same key.
+func claimCardKey(tx *gorm.DB, productID, userID uint, cardKey *model.CardKey) error {
+	now := time.Now()
+	for attempt := 0; attempt < 3; attempt++ {
+		if err := tx.Where("product_id = ? AND status = ?", productID, model.CardKeyStatusAvailable).
+			Order("created_at ASC").
+			First(cardKey).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNoAvailableCardKey
+			}
+			return err
+		}
+		result := tx.Model(&model.CardKey{}).
+			Where("id = ? AND status = ?", cardKey.ID, model.CardKeyStatusAvailable).
+			Updates(map[string]interface{}{
+				"status":      model.CardKeyStatusRedeemed,
+				"redeemed_by": userID,
+				"redeemed_at": now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 1 {
+			cardKey.Status = model.CardKeyStatusRedeemed
+			cardKey.RedeemedBy = userID
+			cardKey.RedeemedAt = &now
+			return nil
+		}
+	}
+	return ErrNoAvailableCardKey
+}
+
+// takeProductStock takes one unit of a product's stock, closing the product
+// once it is depleted
+func takeProductStock(tx *gorm.DB, productID uint) error {
+	result := tx.Model(&model.Product{}).
+		Where("id = ? AND stock > 0", productID).
+		Update("stock", gorm.Expr("stock - 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrProductSoldOut
+	}
+	return tx.Model(&model.Product{}).
+		Where("id = ? AND stock <= 0", productID).
+		Update("status", model.ProductStatusSoldOut).Error
+}
+
+// lockProduct serializes redemptions of a product within this process
+func (s *ExchangeService) lockProduct(productID uint) (unlock func()) {
+	v, _ := s.productLocks.LoadOrStore(productID, &sync.Mutex{})
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
 
 // GetExchangeRecords retrieves paginated exchange records for a user
 func (s *ExchangeService) GetExchangeRecords(userID uint, query ExchangeRecordQuery) (*ExchangeRecordListResponse, error) {
@@ -538,6 +1053,7 @@ func (s *ExchangeService) GetExchangeRecords(userID uint, query ExchangeRecordQu
 	if err := s.db.Where("user_id = ?", userID).
 		Preload("Product").
 		Preload("CardKey").
+		Preload("Gift").
 		Order("created_at DESC").
 		Offset(offset).
 		Limit(query.Limit).
@@ -566,32 +1082,146 @@ func (s *ExchangeService) GetExchangeRecordByID(userID uint, recordID uint) (*Ex
 	if err := s.db.Where("id = ? AND user_id = ?", recordID, userID).
 		Preload("Product").
 		Preload("CardKey").
+		Preload("Gift").
+		First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrExchangeRecordNotFound
+		}
+		return nil, err
+	}
+
+	resp := s.toExchangeRecordResponse(&record)
+	frozen, err := exchangeRecordFrozen(s.db, record.ID)
+	if err != nil {
+		return nil, err
+	}
+	if frozen {
+		resp.CardKey = ""
+		resp.Disputed = true
+	}
+	return resp, nil
+}
+
+// RevealCardKey returns the full card key of an exchange record and records
+// the reveal in the audit log
+func (s *ExchangeService) RevealCardKey(userID uint, recordID uint, ipAddress, userAgent string) (*CardKeyRevealResponse, error) {
+	var record model.ExchangeRecord
+	if err := s.db.Where("id = ? AND user_id = ?", recordID, userID).
+		Preload("CardKey").
+		Preload("Gift").
 		First(&record).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("exchange record not found")
+			return nil, ErrExchangeRecordNotFound
 		}
 		return nil, err
 	}
+	if !canViewCardKey(&record) || record.CardKey.KeyContent == "" {
+		return nil, ErrCardKeyUnavailable
+	}
+	if frozen, err := exchangeRecordFrozen(s.db, record.ID); err != nil {
+		return nil, err
+	} else if frozen {
+		return nil, ErrExchangeDisputed
+	}
+
+	if len(userAgent) > 256 {
+		userAgent = userAgent[:256]
+	}
+	reveal := model.CardKeyReveal{
+		RecordID:  record.ID,
+		UserID:    userID,
+		CardKeyID: record.CardKeyID,
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+	}
+	if err := s.db.Create(&reveal).Error; err != nil {
+		return nil, err
+	}
+
+	return &CardKeyRevealResponse{
+		RecordID:   record.ID,
+		CardKey:    record.CardKey.KeyContent,
+		RevealedAt: reveal.CreatedAt,
+	}, nil
+}
+
+// GetCardKeyReveals retrieves the card key reveal audit log (admin only)
+func (s *ExchangeService) GetCardKeyReveals(query CardKeyRevealQuery) (*CardKeyRevealListResponse, error) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit < 1 || query.Limit > 100 {
+		query.Limit = 20
+	}
+
+	dbQuery := s.db.Model(&model.CardKeyReveal{})
+	if query.UserID > 0 {
+		dbQuery = dbQuery.Where("user_id = ?", query.UserID)
+	}
+	if query.RecordID > 0 {
+		dbQuery = dbQuery.Where("record_id = ?", query.RecordID)
+	}
+
+	var total int64
+	if err := dbQuery.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	var reveals []model.CardKeyReveal
+	offset := (query.Page - 1) * query.Limit
+	if err := dbQuery.Preload("User").
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(query.Limit).
+		Find(&reveals).Error; err != nil {
+		return nil, err
+	}
 
-	return s.toExchangeRecordResponse(&record), nil
+	totalPages := int(total) / query.Limit
+	if int(total)%query.Limit > 0 {
+		totalPages++
+	}
+
+	return &CardKeyRevealListResponse{
+		Reveals:    reveals,
+		Total:      total,
+		Page:       query.Page,
+		Limit:      query.Limit,
+		TotalPages: totalPages,
+	}, nil
 }
 
 // ==================== Helper Functions ====================
 
 func (s *ExchangeService) toProductResponse(product *model.Product) *ProductResponse {
 	return &ProductResponse{
-		ID:          product.ID,
-		Name:        product.Name,
-		Description: product.Description,
-		Image:       product.Image,
-		Price:       product.Price,
-		Stock:       product.Stock,
-		Status:      product.Status,
-		CreatedAt:   product.CreatedAt,
-		UpdatedAt:   product.UpdatedAt,
+		ID:                product.ID,
+		Name:              product.Name,
+		Description:       product.Description,
+		Image:             product.Image,
+		Price:             product.Price,
+		Stock:             product.Stock,
+		Status:            product.Status,
+		LowStockThreshold: product.LowStockThreshold,
+		OneTimeReveal:     product.OneTimeReveal,
+		DropAt:            product.DropAt,
+		CreatedAt:         product.CreatedAt,
+		UpdatedAt:         product.UpdatedAt,
 	}
 }
 
+// hideUndroppedStock hides the stock of products whose drop has not started
+// and fills in the countdown, for user-facing product responses
+func hideUndroppedStock(p *ProductResponse) {
+	now := time.Now()
+	if p.DropAt == nil || !now.Before(*p.DropAt) {
+		return
+	}
+	p.DropPending = true
+	p.DropCountdown = int64(p.DropAt.Sub(now).Seconds()) + 1
+	p.Stock = 0
+}
+
 func (s *ExchangeService) toProductResponses(products []model.Product) []ProductResponse {
 	responses := make([]ProductResponse, len(products))
 	for i, p := range products {
@@ -601,20 +1231,52 @@ func (s *ExchangeService) toProductResponses(products []model.Product) []Product
 }
 
 func (s *ExchangeService) toExchangeRecordResponse(record *model.ExchangeRecord) *ExchangeRecordResponse {
-	return &ExchangeRecordResponse{
-		ID:          record.ID,
-		ProductID:   record.ProductID,
-		ProductName: record.Product.Name,
-		CardKey:     record.CardKey.KeyContent,
-		Cost:        record.Cost,
-		CreatedAt:   record.CreatedAt,
+	resp := &ExchangeRecordResponse{
+		ID:            record.ID,
+		ProductID:     record.ProductID,
+		ProductName:   record.Product.Name,
+		CardKey:       s.redaction.CardKey(record.CardKey.KeyContent),
+		Cost:          record.Cost,
+		Status:        record.Status,
+		ReservedUntil: record.ReservedUntil,
+		GiftID:        record.GiftID,
+		CreatedAt:     record.CreatedAt,
+	}
+	if record.Gift != nil {
+		resp.GiftStatus = record.Gift.Status
+	}
+	if !canViewCardKey(record) {
+		resp.CardKey = ""
+	} else if record.Product.OneTimeReveal && resp.CardKey != "" {
+		resp.CardKey = redact.Secret(resp.CardKey)
+		resp.KeyMasked = true
+	}
+	return resp
+}
+
+// canViewCardKey reports whether the record owner currently holds the card key.
+// Reservations hide the key until confirmed, and the giver of a gift only
+// holds it again once the gift has been returned.
+func canViewCardKey(record *model.ExchangeRecord) bool {
+	if record.Status == model.ExchangeRecordStatusReserved || record.Status == model.ExchangeRecordStatusReleased {
+		return false
 	}
+	if record.Gift != nil && record.UserID == record.Gift.GiverID {
+		return record.Gift.Status == model.ExchangeGiftStatusReturned
+	}
+	return true
 }
 
+// toExchangeRecordResponses converts records for list pages, which always
+// mask the card key; the redeemer views it through RevealCardKey
 func (s *ExchangeService) toExchangeRecordResponses(records []model.ExchangeRecord) []ExchangeRecordResponse {
 	responses := make([]ExchangeRecordResponse, len(records))
 	for i, r := range records {
 		responses[i] = *s.toExchangeRecordResponse(&r)
+		if responses[i].CardKey != "" && !responses[i].KeyMasked {
+			responses[i].CardKey = redact.Secret(responses[i].CardKey)
+			responses[i].KeyMasked = true
+		}
 	}
 	return responses
 }
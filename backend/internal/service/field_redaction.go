@@ -0,0 +1,171 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/pkg/redact"
+
+	"gorm.io/gorm"
+)
+
+// ConfigKeyFieldRedaction holds the field redaction policy as JSON
+const ConfigKeyFieldRedaction = "field_redaction"
+
+// RoleSupport is the staff role that may view, but not change, the admin API
+const RoleSupport = "support"
+
+// Redactable response fields
+const (
+	RedactSecurityCode = "security_code"
+	RedactCardKey      = "card_key"
+)
+
+// redactKeep is the number of characters kept visible at each end of a
+// redacted value, e.g. ABCD****WXYZ
+const redactKeep = 4
+
+var (
+	ErrInvalidFieldRedaction = errors.New("invalid field redaction policy")
+)
+
+// redactableFields are the fields a policy may redact
+var redactableFields = map[string]bool{
+	RedactSecurityCode: true,
+	RedactCardKey:      true,
+}
+
+// IsStaffRole reports whether a role may use the admin API
+func IsStaffRole(role string) bool {
+	return role == "admin" || role == RoleSupport
+}
+
+// FieldRedactionPolicy lists the fields redacted in responses to each staff
+// role
+type FieldRedactionPolicy struct {
+	Roles map[string][]string `json:"roles"`
+}
+
+// defaultFieldRedactionPolicy hides security codes and card keys from support
+func defaultFieldRedactionPolicy() *FieldRedactionPolicy {
+	return &FieldRedactionPolicy{Roles: map[string][]string{
+		"admin":     {},
+		RoleSupport: {RedactSecurityCode, RedactCardKey},
+	}}
+}
+
+// validate checks that the policy names only staff roles and known fields
+func (p *FieldRedactionPolicy) validate() error {
+	for role, fields := range p.Roles {
+		if !IsStaffRole(role) {
+			return ErrInvalidFieldRedaction
+		}
+		seen := make(map[string]bool, len(fields))
+		for _, field := range fields {
+			if !redactableFields[field] || seen[field] {
+				return ErrInvalidFieldRedaction
+			}
+			seen[field] = true
+		}
+	}
+	return nil
+}
+
+// FieldRedaction masks the fields a viewer may not see. The zero value
+// redacts nothing.
+type FieldRedaction struct {
+	fields map[string]bool
+}
+
+// Redacts reports whether a field is redacted
+func (r FieldRedaction) Redacts(field string) bool {
+	return r.fields[field]
+}
+
+// SecurityCode returns a ticket security code as the viewer may see it
+func (r FieldRedaction) SecurityCode(code string) string {
+	if !r.fields[RedactSecurityCode] {
+		return code
+	}
+	return redact.Partial(code, redactKeep)
+}
+
+// CardKey returns card key contents as the viewer may see them
+func (r FieldRedaction) CardKey(key string) string {
+	if !r.fields[RedactCardKey] || key == "" {
+		return key
+	}
+	return redact.Partial(key, redactKeep)
+}
+
+// GetFieldRedactionPolicy returns the field redaction policy. Without a
+// configured policy security codes and card keys are redacted for support.
+func (s *AdminService) GetFieldRedactionPolicy() (*FieldRedactionPolicy, error) {
+	value, err := s.GetConfigValue(ConfigKeyFieldRedaction)
+	if errors.Is(err, ErrConfigNotFound) || value == "" {
+		return defaultFieldRedactionPolicy(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var policy FieldRedactionPolicy
+	if err := json.Unmarshal([]byte(value), &policy); err != nil {
+		return nil, err
+	}
+	if policy.Roles == nil {
+		policy.Roles = map[string][]string{}
+	}
+	return &policy, nil
+}
+
+// UpdateFieldRedactionPolicy validates and replaces the field redaction policy
+func (s *AdminService) UpdateFieldRedactionPolicy(adminID uint, req FieldRedactionPolicy) (*FieldRedactionPolicy, error) {
+	policy := FieldRedactionPolicy{Roles: make(map[string][]string, len(req.Roles))}
+	for role, fields := range req.Roles {
+		policy.Roles[role] = append([]string{}, fields...)
+	}
+	if err := policy.validate(); err != nil {
+		return nil, err
+	}
+
+	policyJSON, err := json.Marshal(policy)
+	if err != nil {
+		return nil, err
+	}
+	err = s.configs().Transaction(func(tx *gorm.DB) error {
+		if err := s.upsertConfig(tx, ConfigKeyFieldRedaction, string(policyJSON)); err != nil {
+			return err
+		}
+		adminLog := model.AdminLog{
+			AdminID:    adminID,
+			Action:     "update_field_redaction",
+			TargetType: "system",
+			TargetID:   0,
+			Details:    string(policyJSON),
+		}
+		return tx.Create(&adminLog).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// FieldRedactionFor returns the redaction applied to responses for a role
+func (s *AdminService) FieldRedactionFor(role string) (FieldRedaction, error) {
+	policy, err := s.GetFieldRedactionPolicy()
+	if err != nil {
+		return FieldRedaction{}, err
+	}
+	fields := policy.Roles[role]
+	if len(fields) == 0 {
+		return FieldRedaction{}, nil
+	}
+	redaction := FieldRedaction{fields: make(map[string]bool, len(fields))}
+	for _, field := range fields {
+		redaction.fields[field] = true
+	}
+	return redaction, nil
+}
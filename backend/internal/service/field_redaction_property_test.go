@@ -0,0 +1,128 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+	"scratch-lottery/pkg/auth"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+)
+
+// Field redaction: a redacted value keeps only its first and last four
+// characters, and values are untouched when the field is not redacted.
+func TestFieldRedactionMasksMiddle(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	redaction := FieldRedaction{fields: map[string]bool{RedactSecurityCode: true}}
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("only the ends of a security code stay visible", prop.ForAll(
+		func(code string) bool {
+			if (FieldRedaction{}).SecurityCode(code) != code || redaction.CardKey(code) != code {
+				t.Logf("Expected %q to pass unredacted", code)
+				return false
+			}
+			masked := redaction.SecurityCode(code)
+			switch {
+			case code == "":
+				return masked == ""
+			case len(code) <= 2*redactKeep:
+				return masked == "****"
+			}
+			return masked == code[:redactKeep]+"****"+code[len(code)-redactKeep:]
+		},
+		gen.RegexMatch("[A-Z0-9]{0,24}"),
+	))
+
+	properties.TestingRun(t)
+}
+
+// Field redaction: support sees redacted security codes and card keys by
+// default, the policy is per tenant and validated, and support passes the
+// admin realm.
+func TestFieldRedactionPolicy(t *testing.T) {
+	db := setupTenantTestDB(t)
+	if err := db.AutoMigrate(&model.TicketHistory{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	admin := NewAdminService(db, NewWalletService(db)).ForTenant(1)
+
+	support, err := admin.FieldRedactionFor(RoleSupport)
+	if err != nil {
+		t.Fatalf("FieldRedactionFor failed: %v", err)
+	}
+	if !support.Redacts(RedactSecurityCode) || !support.Redacts(RedactCardKey) {
+		t.Errorf("Expected support to be redacted by default")
+	}
+	if full, _ := admin.FieldRedactionFor("admin"); full.Redacts(RedactSecurityCode) || full.Redacts(RedactCardKey) {
+		t.Errorf("Expected admins to see everything by default")
+	}
+
+	// Card keys and ticket histories come out redacted for support
+	product := model.Product{Name: "Card", Price: 10, Stock: 1, Status: model.ProductStatusAvailable}
+	scoped := repository.ScopeTenant(db, 1)
+	scoped.Create(&product)
+	scoped.Create(&model.CardKey{ProductID: product.ID, KeyContent: "KEY1-SECRET-VALUE-9876", Status: model.CardKeyStatusAvailable})
+	cardKeys, err := NewExchangeService(db, NewWalletService(db)).ForTenant(1).WithRedaction(support).GetCardKeysByProductID(product.ID, CardKeyQuery{})
+	if err != nil || len(cardKeys.CardKeys) != 1 || cardKeys.CardKeys[0].KeyContent != "KEY1****9876" {
+		t.Errorf("Expected a redacted card key, got %+v (err %v)", cardKeys, err)
+	}
+
+	lotteryType := model.LotteryType{Name: "Lucky", Price: 10, Status: model.LotteryTypeStatusAvailable}
+	scoped.Create(&lotteryType)
+	ticket := model.Ticket{UserID: 1, LotteryTypeID: lotteryType.ID, SecurityCode: "ABCD12345678WXYZ", PurchasedAt: time.Now()}
+	scoped.Create(&ticket)
+	history, err := NewTicketHistoryService(db).ForTenant(1).WithRedaction(support).GetHistory(ticket.ID)
+	if err != nil || history.SecurityCode != "ABCD****WXYZ" {
+		t.Errorf("Expected a redacted security code, got %+v (err %v)", history, err)
+	}
+
+	invalid := []FieldRedactionPolicy{
+		{Roles: map[string][]string{"user": {RedactCardKey}}},
+		{Roles: map[string][]string{RoleSupport: {"balance"}}},
+		{Roles: map[string][]string{RoleSupport: {RedactCardKey, RedactCardKey}}},
+	}
+	for _, policy := range invalid {
+		if _, err := admin.UpdateFieldRedactionPolicy(1, policy); err != ErrInvalidFieldRedaction {
+			t.Errorf("Expected ErrInvalidFieldRedaction for %+v, got %v", policy, err)
+		}
+	}
+
+	if _, err := admin.UpdateFieldRedactionPolicy(1, FieldRedactionPolicy{Roles: map[string][]string{
+		"admin":     {RedactCardKey},
+		RoleSupport: {RedactSecurityCode},
+	}}); err != nil {
+		t.Fatalf("UpdateFieldRedactionPolicy failed: %v", err)
+	}
+	if full, _ := admin.FieldRedactionFor("admin"); !full.Redacts(RedactCardKey) || full.Redacts(RedactSecurityCode) {
+		t.Errorf("Expected admins to have card keys redacted")
+	}
+	if support, _ := admin.FieldRedactionFor(RoleSupport); support.Redacts(RedactCardKey) {
+		t.Errorf("Expected support to see card keys after the update")
+	}
+	if other, _ := admin.ForTenant(2).FieldRedactionFor("admin"); other.Redacts(RedactCardKey) {
+		t.Errorf("The policy leaked into another tenant")
+	}
+	var logs int64
+	db.Model(&model.AdminLog{}).Where("action = ?", "update_field_redaction").Count(&logs)
+	if logs != 1 {
+		t.Errorf("Expected 1 admin log, got %d", logs)
+	}
+
+	// Support staff pass the admin realm like admins; writes are refused by the middleware
+	jwtManager := auth.NewJWTManager("test-secret", 15, 7)
+	realm, _ := NewAdminRealm(jwtManager, "", "scratch-lottery", "", time.Hour)
+	token, _, _ := jwtManager.GenerateTokenPair(5, "support", "Support", RoleSupport)
+	claims, _ := jwtManager.ValidateToken(token)
+	if reason := realm.Check(claims, "203.0.113.9"); reason != "" {
+		t.Errorf("Expected support to pass the realm, got %q", reason)
+	}
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+)
+
+// setupIncrementalScratchTest creates a user with a wallet and an unscratched ticket
+// with the given number of areas and prize amount
+func setupIncrementalScratchTest(t *testing.T, areaCount, prizeAmount int) (*IncrementalScratchService, *WalletService, uint, uint) {
+	db := setupLotteryTestDB(t)
+	if err := db.AutoMigrate(&model.TicketAreaScratch{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	lotteryService := NewLotteryService(db, testEncryptionKey)
+	walletService := NewWalletService(db)
+	scratchService := NewScratchService(db, lotteryService, walletService, nil, nil)
+	incrementalService := NewIncrementalScratchService(db, lotteryService, scratchService, NewScratchEventHub())
+
+	user := model.User{LinuxdoID: "incremental_user", Username: "Test", Role: "user"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if err := db.Create(&model.Wallet{UserID: user.ID}).Error; err != nil {
+		t.Fatalf("Failed to create wallet: %v", err)
+	}
+
+	lotteryType := model.LotteryType{Name: "Pattern", Price: 10, MaxPrize: 1000, GameType: model.GameTypePattern, Status: model.LotteryTypeStatusAvailable}
+	if err := db.Create(&lotteryType).Error; err != nil {
+		t.Fatalf("Failed to create lottery type: %v", err)
+	}
+	prizePool := model.PrizePool{LotteryTypeID: lotteryType.ID, TotalTickets: 10, Status: model.PrizePoolStatusActive}
+	if err := db.Create(&prizePool).Error; err != nil {
+		t.Fatalf("Failed to create prize pool: %v", err)
+	}
+
+	content := &TicketContent{PrizeAmount: prizeAmount, Areas: make([]AreaData, areaCount)}
+	for i := range content.Areas {
+		content.Areas[i] = AreaData{Index: i, Content: "p1", Value: i + 1}
+	}
+	encrypted, err := lotteryService.EncryptTicketContent(content)
+	if err != nil {
+		t.Fatalf("Failed to encrypt content: %v", err)
+	}
+	code, err := lotteryService.GenerateUniqueSecurityCode()
+	if err != nil {
+		t.Fatalf("Failed to generate security code: %v", err)
+	}
+
+	ticket := model.Ticket{
+		UserID:           user.ID,
+		LotteryTypeID:    lotteryType.ID,
+		PrizePoolID:      prizePool.ID,
+		SecurityCode:     code,
+		ContentEncrypted: encrypted,
+		PrizeAmount:      prizeAmount,
+		Status:           model.TicketStatusUnscratched,
+		PurchasedAt:      time.Now(),
+	}
+	if err := db.Create(&ticket).Error; err != nil {
+		t.Fatalf("Failed to create ticket: %v", err)
+	}
+
+	return incrementalService, walletService, user.ID, ticket.ID
+}
+
+// Incremental scratching: every area reveal is persisted and streamed, the ticket is
+// settled exactly once after the last area, and a reconnecting client can replay
+// the whole session.
+func TestIncrementalScratchSettlesOnce(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("all areas revealed credits the prize exactly once", prop.ForAll(
+		func(areaCount, prizeAmount int) bool {
+			service, walletService, userID, ticketID := setupIncrementalScratchTest(t, areaCount, prizeAmount)
+
+			initialBalance, err := walletService.GetBalance(userID)
+			if err != nil {
+				t.Logf("GetBalance failed: %v", err)
+				return false
+			}
+
+			live, cancel := service.Hub().Subscribe(ticketID)
+			defer cancel()
+
+			// Scratch every area twice (retries must be idempotent), in reverse order
+			for i := areaCount - 1; i >= 0; i-- {
+				for attempt := 0; attempt < 2; attempt++ {
+					event, err := service.ScratchArea(userID, ticketID, i, "")
+					if err != nil {
+						t.Logf("ScratchArea failed: %v", err)
+						return false
+					}
+					if event.AreaIndex != i || event.Value != i+1 {
+						t.Logf("Unexpected area event: %+v", event)
+						return false
+					}
+				}
+			}
+
+			balance, err := walletService.GetBalance(userID)
+			if err != nil || balance != initialBalance+prizeAmount {
+				t.Logf("Prize should be credited once: got %d, want %d (err %v)", balance, initialBalance+prizeAmount, err)
+				return false
+			}
+
+			// Live listeners saw one event per area plus the settlement
+			if len(live) != areaCount+1 {
+				t.Logf("Expected %d live events, got %d", areaCount+1, len(live))
+				return false
+			}
+
+			// A reconnect from the middle replays the rest of the session
+			events, err := service.GetScratchEvents(userID, ticketID, areaCount/2)
+			if err != nil {
+				t.Logf("GetScratchEvents failed: %v", err)
+				return false
+			}
+			if len(events) != areaCount-areaCount/2+1 || events[len(events)-1].Type != ScratchEventSettled {
+				t.Logf("Unexpected replay: %d events", len(events))
+				return false
+			}
+
+			// Further scratching or settling is rejected
+			if _, err := service.Settle(userID, ticketID, ""); err != ErrTicketAlreadyScratched {
+				t.Logf("Expected ErrTicketAlreadyScratched, got %v", err)
+				return false
+			}
+
+			return true
+		},
+		gen.IntRange(1, 20),
+		gen.IntRange(0, 500),
+	))
+
+	properties.Property("invalid area index is rejected", prop.ForAll(
+		func(areaCount, offset int) bool {
+			service, _, userID, ticketID := setupIncrementalScratchTest(t, areaCount, 0)
+			_, err := service.ScratchArea(userID, ticketID, areaCount+offset, "")
+			return err == ErrInvalidAreaIndex
+		},
+		gen.IntRange(1, 20),
+		gen.IntRange(0, 5),
+	))
+
+	properties.TestingRun(t)
+}
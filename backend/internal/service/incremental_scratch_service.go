@@ -0,0 +1,318 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidAreaIndex = errors.New("invalid scratch area index")
+	ErrTicketHasNoAreas = errors.New("ticket has no scratch areas")
+)
+
+// Scratch stream event types
+const (
+	ScratchEventArea    = "area"
+	ScratchEventSettled = "settled"
+)
+
+// ScratchEvent is a partial (or final) scratch result streamed to the ticket session
+type ScratchEvent struct {
+	Type           string           `json:"type"`
+	TicketID       uint             `json:"ticket_id"`
+	Seq            int              `json:"seq"`
+	AreaIndex      int              `json:"area_index"`
+	Content        string           `json:"content,omitempty"`
+	Value          int              `json:"value,omitempty"`
+	ScratchedCount int              `json:"scratched_count"`
+	TotalAreas     int              `json:"total_areas"`
+	Settlement     *ScratchResponse `json:"settlement,omitempty"`
+}
+
+// ScratchEventHub fans out scratch events to subscribers of a ticket session
+type ScratchEventHub struct {
+	subscribers map[uint]map[chan ScratchEvent]struct{}
+	mutex       sync.RWMutex
+}
+
+// NewScratchEventHub creates a new scratch event hub
+func NewScratchEventHub() *ScratchEventHub {
+	return &ScratchEventHub{
+		subscribers: make(map[uint]map[chan ScratchEvent]struct{}),
+	}
+}
+
+// Subscribe registers a listener for a ticket. The returned cancel func must be called
+// when the listener goes away.
+func (h *ScratchEventHub) Subscribe(ticketID uint) (<-chan ScratchEvent, func()) {
+	ch := make(chan ScratchEvent, 32)
+
+	h.mutex.Lock()
+	if h.subscribers[ticketID] == nil {
+		h.subscribers[ticketID] = make(map[chan ScratchEvent]struct{})
+	}
+	h.subscribers[ticketID][ch] = struct{}{}
+	h.mutex.Unlock()
+
+	cancel := func() {
+		h.mutex.Lock()
+		defer h.mutex.Unlock()
+		if subs, ok := h.subscribers[ticketID]; ok {
+			if _, ok := subs[ch]; ok {
+				delete(subs, ch)
+				close(ch)
+			}
+			if len(subs) == 0 {
+				delete(h.subscribers, ticketID)
+			}
+		}
+	}
+	return ch, cancel
+}
+
+// Publish sends an event to all listeners of a ticket.
+// Slow listeners drop events; they recover by reconnecting and replaying.
+func (h *ScratchEventHub) Publish(event ScratchEvent) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	for ch := range h.subscribers[event.TicketID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// IncrementalScratchService handles area-by-area scratching with streamed partial results
+type IncrementalScratchService struct {
+	db             *gorm.DB
+	lotteryService *LotteryService
+	scratchService *ScratchService
+	hub            *ScratchEventHub
+}
+
+// NewIncrementalScratchService creates a new incremental scratch service
+func NewIncrementalScratchService(db *gorm.DB, lotteryService *LotteryService, scratchService *ScratchService, hub *ScratchEventHub) *IncrementalScratchService {
+	return &IncrementalScratchService{
+		db:             db,
+		lotteryService: lotteryService,
+		scratchService: scratchService,
+		hub:            hub,
+	}
+}
+
+// Hub returns the event hub used for streaming
+func (s *IncrementalScratchService) Hub() *ScratchEventHub {
+	return s.hub
+}
+
+// ScratchArea reveals a single area of a ticket and persists it immediately.
+// Scratching an already revealed area is idempotent and returns the stored event.
+// Once every area has been revealed the ticket is settled and the prize credited.
+// The first area of a ticket that requires confirmed scratches needs the
+// confirmation token issued with the ticket detail, and is subject to the
+// scratch pacing of its lottery type.
+func (s *IncrementalScratchService) ScratchArea(userID, ticketID uint, areaIndex int, confirmToken string) (*ScratchEvent, error) {
+	ticket, err := s.lotteryService.GetTicketByID(ticketID)
+	if err != nil {
+		return nil, err
+	}
+	if ticket.UserID != userID {
+		return nil, ErrTicketNotOwned
+	}
+
+	content, err := s.lotteryService.DecryptTicketContent(ticket.ContentEncrypted)
+	if err != nil {
+		return nil, err
+	}
+	totalAreas := len(content.Areas)
+	if totalAreas == 0 {
+		return nil, ErrTicketHasNoAreas
+	}
+	if areaIndex < 0 || areaIndex >= totalAreas {
+		return nil, ErrInvalidAreaIndex
+	}
+
+	// Already revealed: return the persisted result (safe for client retries)
+	var existing model.TicketAreaScratch
+	err = s.db.Where("ticket_id = ? AND area_index = ?", ticketID, areaIndex).First(&existing).Error
+	if err == nil {
+		var count int64
+		if err := s.db.Model(&model.TicketAreaScratch{}).Where("ticket_id = ?", ticketID).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		event := toAreaEvent(&existing, int(count), totalAreas)
+		// Retry a settlement that failed after the last area was revealed
+		if int(count) >= totalAreas && ticket.Status == model.TicketStatusScratching {
+			if _, err := s.Settle(userID, ticketID, ""); err != nil && err != ErrTicketAlreadyScratched {
+				return nil, err
+			}
+		}
+		return &event, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if ticket.Status == model.TicketStatusVoided {
+		return nil, ErrTicketVoided
+	}
+	if ticket.Status != model.TicketStatusUnscratched && ticket.Status != model.TicketStatusScratching {
+		return nil, ErrTicketAlreadyScratched
+	}
+
+	area := content.Areas[areaIndex]
+	record := model.TicketAreaScratch{
+		TicketID:    ticketID,
+		AreaIndex:   areaIndex,
+		Content:     fmt.Sprint(area.Content),
+		Value:       area.Value,
+		ScratchedAt: time.Now(),
+	}
+
+	err = repository.Transaction(s.db, func(tx *gorm.DB) error {
+		record.ID = 0
+		if err := checkScratchPacing(tx, ticket, record.ScratchedAt); err != nil {
+			return err
+		}
+		if err := consumeScratchConfirmation(tx, ticket, confirmToken); err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&model.TicketAreaScratch{}).Where("ticket_id = ?", ticketID).Count(&count).Error; err != nil {
+			return err
+		}
+		record.Seq = int(count) + 1
+		if err := tx.Create(&record).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.Ticket{}).
+			Where("id = ? AND status = ?", ticketID, model.TicketStatusUnscratched).
+			Update("status", model.TicketStatusScratching).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	event := toAreaEvent(&record, record.Seq, totalAreas)
+	s.hub.Publish(event)
+
+	// All areas revealed: every winning condition is resolved, settle the ticket
+	if record.Seq >= totalAreas {
+		if _, err := s.Settle(userID, ticketID, ""); err != nil && err != ErrTicketAlreadyScratched {
+			return nil, err
+		}
+	}
+
+	return &event, nil
+}
+
+// Settle finalizes an incrementally scratched ticket, crediting any prize,
+// and publishes the settlement to the ticket session. Settling a ticket with no
+// revealed area scratches it at once, see ScratchService.ScratchTicket.
+func (s *IncrementalScratchService) Settle(userID, ticketID uint, confirmToken string) (*ScratchResponse, error) {
+	result, err := s.scratchService.ScratchTicket(userID, ticketID, confirmToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var count int64
+	s.db.Model(&model.TicketAreaScratch{}).Where("ticket_id = ?", ticketID).Count(&count)
+
+	total := 0
+	if result.Content != nil {
+		total = len(result.Content.Areas)
+	}
+	s.hub.Publish(ScratchEvent{
+		Type:           ScratchEventSettled,
+		TicketID:       ticketID,
+		Seq:            int(count) + 1,
+		ScratchedCount: int(count),
+		TotalAreas:     total,
+		Settlement:     result,
+	})
+
+	return result, nil
+}
+
+// GetScratchEvents returns the events of a ticket session after the given sequence
+// number, so reconnecting clients can replay what they missed
+func (s *IncrementalScratchService) GetScratchEvents(userID, ticketID uint, afterSeq int) ([]ScratchEvent, error) {
+	ticket, err := s.lotteryService.GetTicketByID(ticketID)
+	if err != nil {
+		return nil, err
+	}
+	if ticket.UserID != userID {
+		return nil, ErrTicketNotOwned
+	}
+
+	content, err := s.lotteryService.DecryptTicketContent(ticket.ContentEncrypted)
+	if err != nil {
+		return nil, err
+	}
+	totalAreas := len(content.Areas)
+
+	var records []model.TicketAreaScratch
+	if err := s.db.Where("ticket_id = ?", ticketID).Order("seq ASC").Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	events := make([]ScratchEvent, 0, len(records)+1)
+	for i := range records {
+		if records[i].Seq > afterSeq {
+			events = append(events, toAreaEvent(&records[i], records[i].Seq, totalAreas))
+		}
+	}
+
+	// Settled tickets end the session with the final result
+	if ticket.Status.Revealed() {
+		seq := len(records) + 1
+		if seq > afterSeq {
+			balance, err := s.scratchService.walletService.GetBalance(userID)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, ScratchEvent{
+				Type:           ScratchEventSettled,
+				TicketID:       ticketID,
+				Seq:            seq,
+				ScratchedCount: len(records),
+				TotalAreas:     totalAreas,
+				Settlement: &ScratchResponse{
+					TicketID:     ticket.ID,
+					SecurityCode: ticket.SecurityCode,
+					Status:       ticket.Status,
+					PrizeAmount:  ticket.PrizeAmount,
+					IsWin:        ticket.PrizeAmount > 0,
+					Content:      content,
+					NewBalance:   balance,
+					ScratchedAt:  ticket.ScratchedAt,
+				},
+			})
+		}
+	}
+
+	return events, nil
+}
+
+func toAreaEvent(record *model.TicketAreaScratch, scratchedCount, totalAreas int) ScratchEvent {
+	return ScratchEvent{
+		Type:           ScratchEventArea,
+		TicketID:       record.TicketID,
+		Seq:            record.Seq,
+		AreaIndex:      record.AreaIndex,
+		Content:        record.Content,
+		Value:          record.Value,
+		ScratchedCount: scratchedCount,
+		TotalAreas:     totalAreas,
+	}
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+)
+
+// Inventory alerts: an alert (and webhook) is raised exactly once when stock
+// falls to the threshold, and re-armed after stock recovers.
+func TestInventoryAlertRaisedOncePerCrossing(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	var webhookCalls int32
+	var lastPayload InventoryAlertWebhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&webhookCalls, 1)
+		_ = json.NewDecoder(r.Body).Decode(&lastPayload)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	properties.Property("one alert per threshold crossing with sell-out projection", prop.ForAll(
+		func(threshold, sold int) bool {
+			db := setupLotteryTestDB(t)
+			if err := db.AutoMigrate(&model.Product{}, &model.ExchangeRecord{}, &model.SystemConfig{}, &model.InventoryAlert{}, &model.Tenant{}); err != nil {
+				t.Logf("Failed to migrate: %v", err)
+				return false
+			}
+			atomic.StoreInt32(&webhookCalls, 0)
+
+			adminService := NewAdminService(db, NewWalletService(db))
+			if err := adminService.SetConfigValue(ConfigKeyInventoryAlertWebhook, server.URL); err != nil {
+				t.Logf("SetConfigValue failed: %v", err)
+				return false
+			}
+			monitor := NewInventoryMonitorService(db, adminService, NewLotteryService(db, testEncryptionKey), 24*time.Hour)
+
+			product := model.Product{Name: "Gift card", Price: 10, Stock: threshold + 1, Status: model.ProductStatusAvailable, LowStockThreshold: threshold}
+			if err := db.Create(&product).Error; err != nil {
+				t.Logf("Failed to create product: %v", err)
+				return false
+			}
+			for i := 0; i < sold; i++ {
+				db.Create(&model.ExchangeRecord{UserID: 1, ProductID: product.ID, Cost: 10})
+			}
+
+			// Above threshold: nothing raised
+			if alerts, err := monitor.CheckInventory(); err != nil || len(alerts) != 0 {
+				t.Logf("Expected no alerts above threshold, got %d (err %v)", len(alerts), err)
+				return false
+			}
+
+			// Falls to threshold: exactly one alert across repeated checks
+			db.Model(&product).Update("stock", threshold)
+			first, err := monitor.CheckInventory()
+			if err != nil || len(first) != 1 {
+				t.Logf("Expected one alert, got %d (err %v)", len(first), err)
+				return false
+			}
+			if again, _ := monitor.CheckInventory(); len(again) != 0 {
+				t.Log("Alert should not repeat while still open")
+				return false
+			}
+			if atomic.LoadInt32(&webhookCalls) != 1 || first[0].WebhookStatus != WebhookStatusSent || lastPayload.TargetID != product.ID {
+				t.Logf("Webhook should be sent once, calls=%d status=%s", webhookCalls, first[0].WebhookStatus)
+				return false
+			}
+
+			// Projection exists only when there were recent sales
+			if (first[0].ProjectedSellOut != nil) != (sold > 0) {
+				t.Logf("Unexpected projection for %d sales: %v", sold, first[0].ProjectedSellOut)
+				return false
+			}
+
+			// Recovery re-arms the alert
+			db.Model(&product).Update("stock", threshold+5)
+			monitor.CheckInventory()
+			db.Model(&product).Update("stock", threshold)
+			second, err := monitor.CheckInventory()
+			return err == nil && len(second) == 1 && second[0].ID != first[0].ID
+		},
+		gen.IntRange(1, 50),
+		gen.IntRange(0, 10),
+	))
+
+	properties.TestingRun(t)
+}
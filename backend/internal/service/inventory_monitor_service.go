@@ -0,0 +1,305 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// ConfigKeyInventoryAlertWebhook is the system config key of the inventory alert webhook URL
+const ConfigKeyInventoryAlertWebhook = "inventory_alert_webhook_url"
+
+// Webhook delivery states of an inventory alert
+const (
+	WebhookStatusSkipped = "skipped"
+	WebhookStatusSent    = "sent"
+	WebhookStatusFailed  = "failed"
+)
+
+// InventoryMonitorService watches lottery and product stock and raises
+// alerts when stock falls to the configured low-stock threshold
+type InventoryMonitorService struct {
+	db             *gorm.DB
+	adminService   *AdminService
+	lotteryService *LotteryService
+	velocityWindow time.Duration
+	httpClient     *http.Client
+}
+
+// NewInventoryMonitorService creates a new inventory monitor service.
+// velocityWindow is the look-back period used to compute recent sales velocity.
+func NewInventoryMonitorService(db *gorm.DB, adminService *AdminService, lotteryService *LotteryService, velocityWindow time.Duration) *InventoryMonitorService {
+	if velocityWindow <= 0 {
+		velocityWindow = 24 * time.Hour
+	}
+	return &InventoryMonitorService{
+		db:             db,
+		adminService:   adminService,
+		lotteryService: lotteryService,
+		velocityWindow: velocityWindow,
+		httpClient:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Start runs the monitor every interval until the returned stop func is called
+func (s *InventoryMonitorService) Start(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				alerts, err := s.CheckInventory()
+				if err != nil {
+					logger.Default().Warn("Inventory check failed: %v", err)
+				}
+				for i := range alerts {
+					logger.Default().Warn("Low stock: %s", describeInventoryAlert(&alerts[i]))
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// inventoryItem is a stock-tracked target (lottery type or product)
+type inventoryItem struct {
+	targetType string
+	targetID   uint
+	name       string
+	stock      int
+	threshold  int
+	sold       int64 // units sold within the velocity window
+}
+
+// CheckInventory runs one monitoring pass and returns the newly raised alerts.
+// Open alerts are resolved once stock recovers above the threshold, so each
+// crossing produces exactly one alert.
+func (s *InventoryMonitorService) CheckInventory() ([]model.InventoryAlert, error) {
+	items, err := s.collectItems()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var raised []model.InventoryAlert
+	for _, item := range items {
+		var open model.InventoryAlert
+		err := s.db.Where("target_type = ? AND target_id = ? AND resolved_at IS NULL", item.targetType, item.targetID).
+			First(&open).Error
+		hasOpen := err == nil
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return raised, err
+		}
+
+		if item.stock > item.threshold {
+			if hasOpen {
+				if err := s.db.Model(&open).Update("resolved_at", now).Error; err != nil {
+					return raised, err
+				}
+			}
+			continue
+		}
+		if hasOpen {
+			continue
+		}
+
+		alert := model.InventoryAlert{
+			TargetType: item.targetType,
+			TargetID:   item.targetID,
+			TargetName: item.name,
+			Stock:      item.stock,
+			Threshold:  item.threshold,
+		}
+		alert.VelocityPerHour = float64(item.sold) / s.velocityWindow.Hours()
+		if alert.VelocityPerHour > 0 {
+			hoursLeft := float64(item.stock) / alert.VelocityPerHour
+			sellOut := now.Add(time.Duration(hoursLeft * float64(time.Hour)))
+			alert.ProjectedSellOut = &sellOut
+		}
+		alert.WebhookStatus = s.sendWebhook(&alert)
+
+		if err := s.db.Create(&alert).Error; err != nil {
+			return raised, err
+		}
+		raised = append(raised, alert)
+	}
+
+	return raised, nil
+}
+
+// collectItems loads all lottery types and products with a low-stock
+// threshold, except those of sandbox tenants
+func (s *InventoryMonitorService) collectItems() ([]inventoryItem, error) {
+	since := time.Now().Add(-s.velocityWindow)
+	var items []inventoryItem
+
+	var lotteryTypes []model.LotteryType
+	if err := s.db.Where("low_stock_threshold > 0 AND status != ?", model.LotteryTypeStatusDisabled).
+		Where("tenant_id NOT IN (?)", sandboxTenants(s.db)).
+		Find(&lotteryTypes).Error; err != nil {
+		return nil, err
+	}
+	for _, lt := range lotteryTypes {
+		var sold int64
+		if err := s.db.Model(&model.Ticket{}).
+			Where("lottery_type_id = ? AND purchased_at >= ?", lt.ID, since).
+			Count(&sold).Error; err != nil {
+			return nil, err
+		}
+		items = append(items, inventoryItem{
+			targetType: model.InventoryTargetLotteryType,
+			targetID:   lt.ID,
+			name:       lt.Name,
+			stock:      s.lotteryService.calculateStock(lt.ID),
+			threshold:  lt.LowStockThreshold,
+			sold:       sold,
+		})
+	}
+
+	var products []model.Product
+	if err := s.db.Where("low_stock_threshold > 0 AND status != ?", model.ProductStatusOffline).
+		Where("tenant_id NOT IN (?)", sandboxTenants(s.db)).
+		Find(&products).Error; err != nil {
+		return nil, err
+	}
+	for _, p := range products {
+		var sold int64
+		if err := s.db.Model(&model.ExchangeRecord{}).
+			Where("product_id = ? AND created_at >= ?", p.ID, since).
+			Count(&sold).Error; err != nil {
+			return nil, err
+		}
+		items = append(items, inventoryItem{
+			targetType: model.InventoryTargetProduct,
+			targetID:   p.ID,
+			name:       p.Name,
+			stock:      p.Stock,
+			threshold:  p.LowStockThreshold,
+			sold:       sold,
+		})
+	}
+
+	return items, nil
+}
+
+// InventoryAlertWebhookPayload is the JSON body posted to the alert webhook
+type InventoryAlertWebhookPayload struct {
+	Event            string     `json:"event"`
+	TargetType       string     `json:"target_type"`
+	TargetID         uint       `json:"target_id"`
+	TargetName       string     `json:"target_name"`
+	Stock            int        `json:"stock"`
+	Threshold        int        `json:"threshold"`
+	VelocityPerHour  float64    `json:"velocity_per_hour"`
+	ProjectedSellOut *time.Time `json:"projected_sell_out,omitempty"`
+}
+
+// sendWebhook posts the alert to the configured webhook and returns the delivery status
+func (s *InventoryMonitorService) sendWebhook(alert *model.InventoryAlert) string {
+	url, err := s.adminService.GetConfigValue(ConfigKeyInventoryAlertWebhook)
+	if err != nil || url == "" {
+		return WebhookStatusSkipped
+	}
+
+	body, err := json.Marshal(InventoryAlertWebhookPayload{
+		Event:            "inventory.low_stock",
+		TargetType:       alert.TargetType,
+		TargetID:         alert.TargetID,
+		TargetName:       alert.TargetName,
+		Stock:            alert.Stock,
+		Threshold:        alert.Threshold,
+		VelocityPerHour:  alert.VelocityPerHour,
+		ProjectedSellOut: alert.ProjectedSellOut,
+	})
+	if err != nil {
+		return WebhookStatusFailed
+	}
+
+	resp, err := s.httpClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		logger.Default().Warn("Inventory alert webhook failed: %v", err)
+		return WebhookStatusFailed
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		logger.Default().Warn("Inventory alert webhook returned status %d", resp.StatusCode)
+		return WebhookStatusFailed
+	}
+	return WebhookStatusSent
+}
+
+// InventoryAlertQuery represents query parameters for listing inventory alerts
+type InventoryAlertQuery struct {
+	TargetType string `form:"target_type"`
+	OpenOnly   bool   `form:"open_only"`
+	Page       int    `form:"page"`
+	Limit      int    `form:"limit"`
+}
+
+// InventoryAlertListResponse represents paginated inventory alerts
+type InventoryAlertListResponse struct {
+	Alerts     []model.InventoryAlert `json:"alerts"`
+	Total      int64                  `json:"total"`
+	Page       int                    `json:"page"`
+	Limit      int                    `json:"limit"`
+	TotalPages int                    `json:"total_pages"`
+}
+
+// GetAlerts returns inventory alerts, newest first
+func (s *InventoryMonitorService) GetAlerts(query InventoryAlertQuery) (*InventoryAlertListResponse, error) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit < 1 || query.Limit > 100 {
+		query.Limit = 20
+	}
+
+	dbQuery := s.db.Model(&model.InventoryAlert{})
+	if query.TargetType != "" {
+		dbQuery = dbQuery.Where("target_type = ?", query.TargetType)
+	}
+	if query.OpenOnly {
+		dbQuery = dbQuery.Where("resolved_at IS NULL")
+	}
+
+	var total int64
+	if err := dbQuery.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	var alerts []model.InventoryAlert
+	offset := (query.Page - 1) * query.Limit
+	if err := dbQuery.Order("created_at DESC").Offset(offset).Limit(query.Limit).Find(&alerts).Error; err != nil {
+		return nil, err
+	}
+
+	totalPages := int(total) / query.Limit
+	if int(total)%query.Limit > 0 {
+		totalPages++
+	}
+
+	return &InventoryAlertListResponse{
+		Alerts:     alerts,
+		Total:      total,
+		Page:       query.Page,
+		Limit:      query.Limit,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// describeInventoryAlert describes an alert for logs
+func describeInventoryAlert(alert *model.InventoryAlert) string {
+	return fmt.Sprintf("%s #%d (%s) stock %d <= %d", alert.TargetType, alert.TargetID, alert.TargetName, alert.Stock, alert.Threshold)
+}
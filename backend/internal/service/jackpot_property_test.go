@@ -0,0 +1,96 @@
+package service
+
+import (
+	"testing"
+
+	"scratch-lottery/internal/model"
+)
+
+// Jackpot: settings outside the limits are refused, purchases feed the
+// jackpot of their tenant, a ticket hitting the jackpot outcome takes the
+// whole jackpot on top of its prize and pays it when scratched, and an empty
+// jackpot is never drawn.
+func TestJackpotContributionAndPayout(t *testing.T) {
+	db := setupTenantTestDB(t)
+	jackpots := NewJackpotService(db).ForTenant(1)
+
+	invalid := []UpdateJackpotSettingsRequest{
+		{ContributionPercent: 51, Odds: 1000},
+		{ContributionPercent: -1, Odds: 1000},
+		{ContributionPercent: 10, Odds: 5},
+	}
+	for _, req := range invalid {
+		if _, err := jackpots.UpdateSettings(1, req); err != ErrInvalidJackpotSettings {
+			t.Errorf("Expected ErrInvalidJackpotSettings for %+v, got %v", req, err)
+		}
+	}
+	if _, err := jackpots.UpdateSettings(1, UpdateJackpotSettingsRequest{ContributionPercent: 10}); err != nil {
+		t.Fatalf("UpdateSettings failed: %v", err)
+	}
+
+	lotteryService := NewLotteryService(db, testEncryptionKey).ForTenant(1)
+	lotteryType, err := lotteryService.CreateLotteryType(CreateLotteryTypeRequest{
+		Name:        "Jackpot",
+		Price:       10,
+		MaxPrize:    100,
+		PrizeLevels: []PrizeLevelInput{{Level: 1, Name: "一等奖", PrizeAmount: 20, Quantity: 1}},
+	})
+	if err != nil {
+		t.Fatalf("CreateLotteryType failed: %v", err)
+	}
+	if _, err := lotteryService.CreatePrizePool(CreatePrizePoolRequest{LotteryTypeID: lotteryType.ID, TotalTickets: 100}); err != nil {
+		t.Fatalf("CreatePrizePool failed: %v", err)
+	}
+	user := model.User{TenantID: 1, LinuxdoID: "jackpot", Username: "Jackpot"}
+	db.Create(&user)
+	db.Create(&model.Wallet{TenantID: 1, UserID: user.ID, Balance: 100})
+
+	walletService := NewWalletService(db)
+	purchases := NewPurchaseService(db, NewLotteryService(db, testEncryptionKey), walletService, nil, nil, nil).ForTenant(1)
+	if _, err := purchases.PurchaseTickets(user.ID, PurchaseRequest{LotteryTypeID: lotteryType.ID, Quantity: 3}); err != nil {
+		t.Fatalf("PurchaseTickets failed: %v", err)
+	}
+	jackpot, err := jackpots.GetJackpot()
+	if err != nil || jackpot.Amount != 3 || jackpot.Enabled {
+		t.Errorf("Expected 10%% of the purchase in the disabled jackpot, got %+v (err %v)", jackpot, err)
+	}
+	if other, err := NewJackpotService(db).ForTenant(2).GetJackpot(); err != nil || other.Amount != 0 {
+		t.Errorf("Expected the second tenant's jackpot to be empty, got %+v (err %v)", other, err)
+	}
+
+	// Odds of one make every ticket hit the jackpot outcome
+	db.Model(&model.Jackpot{}).Where("tenant_id = ?", 1).Update("odds", 1)
+	ticket, err := lotteryService.generateTicket(user.ID, lotteryType.ID, model.TicketHistoryPurchase, 0)
+	if err != nil {
+		t.Fatalf("generateTicket failed: %v", err)
+	}
+	content, err := lotteryService.DecryptTicketContent(ticket.ContentEncrypted)
+	if err != nil {
+		t.Fatalf("DecryptTicketContent failed: %v", err)
+	}
+	if content.Jackpot != 3 || ticket.PrizeAmount != content.PrizeAmount+3 {
+		t.Errorf("Expected the ticket to win the jackpot of 3, got %+v with prize %d", content, ticket.PrizeAmount)
+	}
+	jackpot, err = jackpots.GetJackpot()
+	if err != nil || jackpot.Amount != 0 || jackpot.LastWonAmount != 3 || jackpot.LastWonAt == nil {
+		t.Errorf("Expected the jackpot to be paid out, got %+v (err %v)", jackpot, err)
+	}
+
+	scratchService := NewScratchService(db, NewLotteryService(db, testEncryptionKey), walletService, nil, nil).ForTenant(1)
+	result, err := scratchService.ScratchTicket(user.ID, ticket.ID, "")
+	if err != nil {
+		t.Fatalf("ScratchTicket failed: %v", err)
+	}
+	if result.PrizeAmount != ticket.PrizeAmount || result.Content.Jackpot != 3 || result.NewBalance != 70+ticket.PrizeAmount {
+		t.Errorf("Expected the jackpot to be credited, got %+v", result)
+	}
+
+	// An empty jackpot is not drawn
+	ticket, err = lotteryService.generateTicket(user.ID, lotteryType.ID, model.TicketHistoryPurchase, 0)
+	if err != nil {
+		t.Fatalf("generateTicket failed: %v", err)
+	}
+	if content, err = lotteryService.DecryptTicketContent(ticket.ContentEncrypted); err != nil || content.Jackpot != 0 {
+		t.Errorf("Expected no jackpot from an empty jackpot, got %+v (err %v)", content, err)
+	}
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// Jackpot setting limits. Odds below the minimum would pay the jackpot out
+// before it has grown.
+const (
+	maxJackpotContributionPercent = 50
+	minJackpotOdds                = 100
+	maxJackpotOdds                = 100000000
+)
+
+var (
+	ErrInvalidJackpotSettings = errors.New("invalid jackpot settings")
+)
+
+// UpdateJackpotSettingsRequest represents the request to configure the jackpot
+type UpdateJackpotSettingsRequest struct {
+	ContributionPercent int `json:"contribution_percent"`
+	Odds                int `json:"odds"`
+}
+
+// JackpotResponse represents the jackpot of a tenant
+type JackpotResponse struct {
+	Amount              int        `json:"amount"`
+	ContributionPercent int        `json:"contribution_percent"`
+	Odds                int        `json:"odds"`
+	Enabled             bool       `json:"enabled"`
+	LastWonAmount       int        `json:"last_won_amount,omitempty"`
+	LastWonAt           *time.Time `json:"last_won_at,omitempty"`
+}
+
+// JackpotService manages the progressive jackpot. Purchases feed it and
+// ticket generation pays it out; see contributeJackpot and claimJackpot.
+type JackpotService struct {
+	db *gorm.DB
+}
+
+// NewJackpotService creates a new jackpot service
+func NewJackpotService(db *gorm.DB) *JackpotService {
+	return &JackpotService{db: db}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *JackpotService) ForTenant(tenantID uint) *JackpotService {
+	return &JackpotService{db: repository.ScopeTenant(s.db, tenantID)}
+}
+
+// GetJackpot returns the current jackpot. A tenant that never configured
+// one has an empty, disabled jackpot.
+func (s *JackpotService) GetJackpot() (*JackpotResponse, error) {
+	jackpot, err := loadJackpot(s.db)
+	if err != nil {
+		return nil, err
+	}
+	return toJackpotResponse(jackpot), nil
+}
+
+// UpdateSettings sets the contribution rate and the odds of the jackpot
+func (s *JackpotService) UpdateSettings(adminID uint, req UpdateJackpotSettingsRequest) (*JackpotResponse, error) {
+	if req.ContributionPercent < 0 || req.ContributionPercent > maxJackpotContributionPercent {
+		return nil, ErrInvalidJackpotSettings
+	}
+	if req.Odds != 0 && (req.Odds < minJackpotOdds || req.Odds > maxJackpotOdds) {
+		return nil, ErrInvalidJackpotSettings
+	}
+
+	details, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	var jackpot *model.Jackpot
+	err = repository.Transaction(jackpots(s.db), func(tx *gorm.DB) error {
+		var err error
+		if jackpot, err = loadJackpot(tx); err != nil {
+			return err
+		}
+		jackpot.ContributionPercent = req.ContributionPercent
+		jackpot.Odds = req.Odds
+		if err := tx.Save(jackpot).Error; err != nil {
+			return err
+		}
+		adminLog := model.AdminLog{
+			AdminID:    adminID,
+			Action:     "update_jackpot",
+			TargetType: "jackpot",
+			TargetID:   jackpot.ID,
+			Details:    string(details),
+		}
+		return tx.Create(&adminLog).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toJackpotResponse(jackpot), nil
+}
+
+// jackpots returns the session used for the jackpot. The jackpot belongs to a
+// tenant, so sessions without one use the default tenant's.
+func jackpots(db *gorm.DB) *gorm.DB {
+	if _, ok := repository.TenantFromContext(db.Statement.Context); ok {
+		return db
+	}
+	return repository.ScopeTenant(db, repository.DefaultTenantID)
+}
+
+// loadJackpot returns the jackpot of the session's tenant, or an unsaved
+// empty one if the tenant has none yet
+func loadJackpot(db *gorm.DB) (*model.Jackpot, error) {
+	var jackpot model.Jackpot
+	if err := jackpots(db).First(&jackpot).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &model.Jackpot{}, nil
+		}
+		return nil, err
+	}
+	return &jackpot, nil
+}
+
+// contributeJackpot adds the configured share of a purchase costing cost
+// points to the jackpot
+func contributeJackpot(tx *gorm.DB, cost int) error {
+	jackpot, err := loadJackpot(tx)
+	if err != nil || jackpot.ID == 0 {
+		return err
+	}
+	contribution := cost * jackpot.ContributionPercent / 100
+	if contribution <= 0 {
+		return nil
+	}
+	return jackpots(tx).Model(&model.Jackpot{}).
+		Where("id = ?", jackpot.ID).
+		Update("amount", gorm.Expr("amount + ?", contribution)).Error
+}
+
+// drawJackpot reports whether a ticket hits the jackpot outcome, which has a
+// chance of one in the configured odds while the jackpot holds points
+func drawJackpot(db *gorm.DB) (bool, error) {
+	jackpot, err := loadJackpot(db)
+	if err != nil {
+		return false, err
+	}
+	if jackpot.Odds <= 0 || jackpot.Amount <= 0 {
+		return false, nil
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(jackpot.Odds)))
+	if err != nil {
+		return false, err
+	}
+	return n.Int64() == 0, nil
+}
+
+// claimJackpot pays the jackpot out to a user and returns the points won.
+// Points contributed while the claim is under way stay in the jackpot.
+func claimJackpot(tx *gorm.DB, userID uint) (int, error) {
+	jackpot, err := loadJackpot(tx)
+	if err != nil || jackpot.ID == 0 || jackpot.Amount <= 0 {
+		return 0, err
+	}
+	now := time.Now()
+	result := jackpots(tx).Model(&model.Jackpot{}).
+		Where("id = ? AND amount >= ?", jackpot.ID, jackpot.Amount).
+		Updates(map[string]interface{}{
+			"amount":          gorm.Expr("amount - ?", jackpot.Amount),
+			"last_winner_id":  userID,
+			"last_won_amount": jackpot.Amount,
+			"last_won_at":     now,
+		})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return 0, nil
+	}
+	return jackpot.Amount, nil
+}
+
+func toJackpotResponse(jackpot *model.Jackpot) *JackpotResponse {
+	return &JackpotResponse{
+		Amount:              jackpot.Amount,
+		ContributionPercent: jackpot.ContributionPercent,
+		Odds:                jackpot.Odds,
+		Enabled:             jackpot.Odds > 0,
+		LastWonAmount:       jackpot.LastWonAmount,
+		LastWonAt:           jackpot.LastWonAt,
+	}
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
+)
+
+// Kill switches: a disabled subsystem is refused with its banner while the
+// others keep working, the change is logged and announced, and switching it
+// back on restores it.
+func TestKillSwitchToggle(t *testing.T) {
+	db, status := setupStatusTest(t)
+	killSwitches := NewKillSwitchService(status.adminService, status, time.Minute).ForTenant(2)
+
+	if _, err := killSwitches.UpdateKillSwitch(1, SubsystemPayment, UpdateKillSwitchRequest{Disabled: true, Message: "支付通道故障"}); err != nil {
+		t.Fatalf("UpdateKillSwitch failed: %v", err)
+	}
+	var disabled *SubsystemDisabledError
+	if err := killSwitches.Check(SubsystemPayment); !errors.Is(err, ErrSubsystemDisabled) || !errors.As(err, &disabled) || disabled.Message != "支付通道故障" {
+		t.Fatalf("Expected payments to be disabled with the banner, got %v", err)
+	}
+	for _, subsystem := range []string{SubsystemPurchase, SubsystemScratch, SubsystemExchange} {
+		if err := killSwitches.Check(subsystem); err != nil {
+			t.Errorf("Expected %s to stay enabled, got %v", subsystem, err)
+		}
+	}
+
+	var logs int64
+	db.Model(&model.AdminLog{}).Where("action = ? AND tenant_id = ?", "update_kill_switch", 2).Count(&logs)
+	if logs != 1 {
+		t.Errorf("Expected the switch to be logged, got %d logs", logs)
+	}
+	announcements, err := killSwitches.Announcements()
+	if err != nil || len(announcements) != 1 || announcements[0].Kind != AnnouncementKillSwitch || announcements[0].Subsystem != SubsystemPayment {
+		t.Fatalf("Expected a payment banner, got %+v (err %v)", announcements, err)
+	}
+
+	// Other tenants are not affected by a tenant's switch
+	if err := killSwitches.ForTenant(1).Check(SubsystemPayment); err != nil {
+		t.Errorf("Expected the default tenant to keep payments, got %v", err)
+	}
+
+	if _, err := killSwitches.UpdateKillSwitch(1, SubsystemPayment, UpdateKillSwitchRequest{Disabled: false}); err != nil {
+		t.Fatalf("UpdateKillSwitch failed: %v", err)
+	}
+	if err := killSwitches.Check(SubsystemPayment); err != nil {
+		t.Errorf("Expected payments to be back on, got %v", err)
+	}
+	if _, err := killSwitches.UpdateKillSwitch(1, "lottery", UpdateKillSwitchRequest{Disabled: true}); err != ErrInvalidSubsystem {
+		t.Errorf("Expected ErrInvalidSubsystem, got %v", err)
+	}
+}
+
+// Kill switches of the default tenant apply to every tenant, and switches are
+// read through the cache until it expires
+func TestKillSwitchGlobalAndCached(t *testing.T) {
+	_, status := setupStatusTest(t)
+	killSwitches := NewKillSwitchService(status.adminService, status, time.Minute)
+
+	if _, err := killSwitches.UpdateKillSwitch(1, SubsystemScratch, UpdateKillSwitchRequest{Disabled: true}); err != nil {
+		t.Fatalf("UpdateKillSwitch failed: %v", err)
+	}
+	tenant := killSwitches.ForTenant(2)
+	var disabled *SubsystemDisabledError
+	if err := tenant.Check(SubsystemScratch); !errors.As(err, &disabled) || disabled.Message == "" {
+		t.Fatalf("Expected the platform switch to disable scratching with the default banner, got %v", err)
+	}
+	switches, err := tenant.GetKillSwitches()
+	if err != nil || len(switches) != 4 || !switches[1].Disabled || !switches[1].Global {
+		t.Fatalf("Expected a global scratch switch, got %+v (err %v)", switches, err)
+	}
+
+	// Another instance sees the change only once its cache expires
+	other := NewKillSwitchService(status.adminService, status, time.Minute)
+	if err := other.Check(SubsystemPurchase); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if _, err := killSwitches.UpdateKillSwitch(1, SubsystemPurchase, UpdateKillSwitchRequest{Disabled: true}); err != nil {
+		t.Fatalf("UpdateKillSwitch failed: %v", err)
+	}
+	if err := other.Check(SubsystemPurchase); err != nil {
+		t.Errorf("Expected the cached switch until the cache expires, got %v", err)
+	}
+	uncached := NewKillSwitchService(status.adminService, status, 0)
+	if err := uncached.Check(SubsystemPurchase); !errors.Is(err, ErrSubsystemDisabled) {
+		t.Errorf("Expected the stored switch without a cache, got %v", err)
+	}
+}
@@ -0,0 +1,336 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// ConfigKeyKillSwitches holds the subsystem kill switches of a tenant as JSON
+const ConfigKeyKillSwitches = "kill_switches"
+
+// Subsystems that can be switched off during an incident
+const (
+	SubsystemPurchase = "purchase"
+	SubsystemScratch  = "scratch"
+	SubsystemExchange = "exchange"
+	SubsystemPayment  = "payment"
+)
+
+// killSwitchSubsystems lists the subsystems in display order with the banner
+// shown when no message is given
+var killSwitchSubsystems = []struct {
+	name    string
+	message string
+}{
+	{SubsystemPurchase, "购票功能临时维护中，请稍后再试"},
+	{SubsystemScratch, "刮奖功能临时维护中，请稍后再试"},
+	{SubsystemExchange, "兑换功能临时维护中，请稍后再试"},
+	{SubsystemPayment, "充值功能临时维护中，请稍后再试"},
+}
+
+// Announcement kinds
+const (
+	AnnouncementIncident   = "incident"    // the incident banner of the status page
+	AnnouncementKillSwitch = "kill_switch" // a subsystem is switched off
+)
+
+var (
+	ErrSubsystemDisabled = errors.New("subsystem temporarily disabled")
+	ErrInvalidSubsystem  = errors.New("invalid subsystem")
+	ErrInvalidKillSwitch = errors.New("invalid kill switch message")
+)
+
+// KillSwitch is the state of a subsystem. Disabled subsystems refuse new
+// operations with the message.
+type KillSwitch struct {
+	Subsystem string    `json:"subsystem"`
+	Disabled  bool      `json:"disabled"`
+	Message   string    `json:"message"`
+	Global    bool      `json:"global"` // Switched off by the platform for every tenant
+	UpdatedBy uint      `json:"updated_by,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// UpdateKillSwitchRequest switches a subsystem off or back on
+type UpdateKillSwitchRequest struct {
+	Disabled bool   `json:"disabled"`
+	Message  string `json:"message"`
+}
+
+// SubsystemDisabledError carries the banner of a disabled subsystem. It
+// matches ErrSubsystemDisabled with errors.Is.
+type SubsystemDisabledError struct {
+	Subsystem string
+	Message   string
+}
+
+func (e *SubsystemDisabledError) Error() string {
+	return e.Subsystem + " temporarily disabled"
+}
+
+// Is makes the error match ErrSubsystemDisabled
+func (e *SubsystemDisabledError) Is(target error) bool {
+	return target == ErrSubsystemDisabled
+}
+
+// Announcement is a banner shown to users
+type Announcement struct {
+	Kind      string    `json:"kind"`
+	Subsystem string    `json:"subsystem,omitempty"`
+	Message   string    `json:"message"`
+	Severity  string    `json:"severity"`
+	Since     time.Time `json:"since"`
+}
+
+// killSwitchCache holds the stored switches of each tenant
+type killSwitchCache struct {
+	mutex   sync.Mutex
+	entries map[uint]killSwitchEntry
+}
+
+type killSwitchEntry struct {
+	switches map[string]KillSwitch
+	loadedAt time.Time
+}
+
+// KillSwitchService turns purchases, scratching, exchanges and payments off
+// and on independently during incidents. Switches are read through a short
+// cache, so a change reaches other instances within the cache TTL. Switches
+// of the default tenant, whose admins operate the deployment, apply to every
+// tenant.
+type KillSwitchService struct {
+	adminService  *AdminService
+	statusService *StatusService
+	tenantID      uint
+	cacheTTL      time.Duration
+	cache         *killSwitchCache
+}
+
+// NewKillSwitchService creates a new kill switch service
+func NewKillSwitchService(adminService *AdminService, statusService *StatusService, cacheTTL time.Duration) *KillSwitchService {
+	return &KillSwitchService{
+		adminService:  adminService,
+		statusService: statusService,
+		tenantID:      repository.DefaultTenantID,
+		cacheTTL:      cacheTTL,
+		cache:         &killSwitchCache{entries: make(map[uint]killSwitchEntry)},
+	}
+}
+
+// ForTenant returns a copy of the service for a tenant. The cache is shared.
+func (s *KillSwitchService) ForTenant(tenantID uint) *KillSwitchService {
+	scoped := *s
+	scoped.tenantID = tenantID
+	return &scoped
+}
+
+// Check returns a *SubsystemDisabledError if the subsystem is switched off
+// for the tenant or the whole deployment
+func (s *KillSwitchService) Check(subsystem string) error {
+	switches, err := s.effective()
+	if err != nil {
+		return err
+	}
+	if sw := switches[subsystem]; sw.Disabled {
+		return &SubsystemDisabledError{Subsystem: subsystem, Message: sw.Message}
+	}
+	return nil
+}
+
+// GetKillSwitches returns the state of every subsystem for the tenant
+func (s *KillSwitchService) GetKillSwitches() ([]KillSwitch, error) {
+	switches, err := s.effective()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]KillSwitch, len(killSwitchSubsystems))
+	for i, subsystem := range killSwitchSubsystems {
+		result[i] = switches[subsystem.name]
+		result[i].Subsystem = subsystem.name
+	}
+	return result, nil
+}
+
+// UpdateKillSwitch switches a subsystem of the tenant off or back on and
+// logs the change. The change takes effect on this instance at once.
+func (s *KillSwitchService) UpdateKillSwitch(adminID uint, subsystem string, req UpdateKillSwitchRequest) (*KillSwitch, error) {
+	defaultMessage, ok := killSwitchMessage(subsystem)
+	if !ok {
+		return nil, ErrInvalidSubsystem
+	}
+	message := strings.TrimSpace(req.Message)
+	if len([]rune(message)) > maxIncidentMessageLength {
+		return nil, ErrInvalidKillSwitch
+	}
+	if message == "" {
+		message = defaultMessage
+	}
+
+	admin := s.adminService.ForTenant(s.tenantID)
+	switches, err := s.load(admin)
+	if err != nil {
+		return nil, err
+	}
+	sw := KillSwitch{
+		Subsystem: subsystem,
+		Disabled:  req.Disabled,
+		Message:   message,
+		UpdatedBy: adminID,
+		UpdatedAt: time.Now(),
+	}
+	if req.Disabled {
+		switches[subsystem] = sw
+	} else {
+		delete(switches, subsystem)
+	}
+	value, err := json.Marshal(switches)
+	if err != nil {
+		return nil, err
+	}
+
+	err = admin.configs().Transaction(func(tx *gorm.DB) error {
+		if err := admin.upsertConfig(tx, ConfigKeyKillSwitches, string(value)); err != nil {
+			return err
+		}
+		details, _ := json.Marshal(map[string]interface{}{
+			"subsystem": subsystem,
+			"disabled":  req.Disabled,
+			"message":   message,
+		})
+		adminLog := model.AdminLog{
+			AdminID:    adminID,
+			Action:     "update_kill_switch",
+			TargetType: "system",
+			TargetID:   0,
+			Details:    string(details),
+		}
+		return tx.Create(&adminLog).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.cache.mutex.Lock()
+	s.cache.entries[s.tenantID] = killSwitchEntry{switches: switches, loadedAt: time.Now()}
+	s.cache.mutex.Unlock()
+	return &sw, nil
+}
+
+// Announcements returns the banners for users of the tenant: the incident
+// banner of the status page followed by the disabled subsystems
+func (s *KillSwitchService) Announcements() ([]Announcement, error) {
+	announcements := []Announcement{}
+	if s.statusService != nil {
+		incident, err := s.statusService.ForTenant(s.tenantID).GetIncident()
+		if err != nil {
+			return nil, err
+		}
+		if incident != nil {
+			announcements = append(announcements, Announcement{
+				Kind:     AnnouncementIncident,
+				Message:  incident.Message,
+				Severity: incident.Severity,
+				Since:    incident.UpdatedAt,
+			})
+		}
+	}
+
+	switches, err := s.GetKillSwitches()
+	if err != nil {
+		return nil, err
+	}
+	for _, sw := range switches {
+		if !sw.Disabled {
+			continue
+		}
+		announcements = append(announcements, Announcement{
+			Kind:      AnnouncementKillSwitch,
+			Subsystem: sw.Subsystem,
+			Message:   sw.Message,
+			Severity:  IncidentSeverityMajor,
+			Since:     sw.UpdatedAt,
+		})
+	}
+	return announcements, nil
+}
+
+// effective returns the disabled subsystems of the tenant merged with those
+// disabled for the whole deployment
+func (s *KillSwitchService) effective() (map[string]KillSwitch, error) {
+	switches, err := s.cached(s.tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if s.tenantID == repository.DefaultTenantID {
+		return switches, nil
+	}
+
+	global, err := s.cached(repository.DefaultTenantID)
+	if err != nil {
+		return nil, err
+	}
+	merged := make(map[string]KillSwitch, len(switches)+len(global))
+	for name, sw := range switches {
+		merged[name] = sw
+	}
+	for name, sw := range global {
+		sw.Global = true
+		merged[name] = sw
+	}
+	return merged, nil
+}
+
+// cached returns the stored switches of a tenant, reloading them once the
+// cache TTL has passed. Callers must not modify the returned map.
+func (s *KillSwitchService) cached(tenantID uint) (map[string]KillSwitch, error) {
+	s.cache.mutex.Lock()
+	entry, ok := s.cache.entries[tenantID]
+	s.cache.mutex.Unlock()
+	if ok && time.Since(entry.loadedAt) < s.cacheTTL {
+		return entry.switches, nil
+	}
+
+	switches, err := s.load(s.adminService.ForTenant(tenantID))
+	if err != nil {
+		return nil, err
+	}
+	s.cache.mutex.Lock()
+	s.cache.entries[tenantID] = killSwitchEntry{switches: switches, loadedAt: time.Now()}
+	s.cache.mutex.Unlock()
+	return switches, nil
+}
+
+// load reads the disabled subsystems stored for a tenant
+func (s *KillSwitchService) load(admin *AdminService) (map[string]KillSwitch, error) {
+	switches := make(map[string]KillSwitch)
+	value, err := admin.GetConfigValue(ConfigKeyKillSwitches)
+	if errors.Is(err, ErrConfigNotFound) || (err == nil && value == "") {
+		return switches, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(value), &switches); err != nil {
+		return nil, err
+	}
+	return switches, nil
+}
+
+// killSwitchMessage returns the default banner of a subsystem, or false if
+// the subsystem is unknown
+func killSwitchMessage(subsystem string) (string, bool) {
+	for _, s := range killSwitchSubsystems {
+		if s.name == subsystem {
+			return s.message, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,292 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+
+	"github.com/glebarez/sqlite"
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setupLargeWinTest creates a user with a wallet and one unscratched ticket per prize amount
+func setupLargeWinTest(t *testing.T, prizes []int) (*gorm.DB, *ScratchService, *LargeWinService, uint, []uint) {
+	db := setupTenantTestDB(t)
+	if err := db.AutoMigrate(&model.PrizeClaim{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	lotteryService := NewLotteryService(db, testEncryptionKey)
+	walletService := NewWalletService(db)
+	largeWins := NewLargeWinService(db, NewAdminService(db, walletService), testEncryptionKey)
+	scratchService := NewScratchService(db, lotteryService, walletService, nil, largeWins)
+
+	user := model.User{LinuxdoID: "large_winner", Username: "Winner", Role: "user"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if err := db.Create(&model.Wallet{UserID: user.ID}).Error; err != nil {
+		t.Fatalf("Failed to create wallet: %v", err)
+	}
+
+	lotteryType := model.LotteryType{Name: "Jackpot", Price: 10, MaxPrize: 10000, GameType: model.GameTypeAmountSum, Status: model.LotteryTypeStatusAvailable}
+	if err := db.Create(&lotteryType).Error; err != nil {
+		t.Fatalf("Failed to create lottery type: %v", err)
+	}
+	prizePool := model.PrizePool{LotteryTypeID: lotteryType.ID, TotalTickets: len(prizes), Status: model.PrizePoolStatusActive}
+	if err := db.Create(&prizePool).Error; err != nil {
+		t.Fatalf("Failed to create prize pool: %v", err)
+	}
+
+	ticketIDs := make([]uint, len(prizes))
+	for i, prize := range prizes {
+		encrypted, err := lotteryService.EncryptTicketContent(&TicketContent{PrizeAmount: prize, Areas: []AreaData{{Index: 0, Value: prize}}})
+		if err != nil {
+			t.Fatalf("Failed to encrypt content: %v", err)
+		}
+		code, err := lotteryService.GenerateUniqueSecurityCode()
+		if err != nil {
+			t.Fatalf("Failed to generate security code: %v", err)
+		}
+		ticket := model.Ticket{
+			UserID:           user.ID,
+			LotteryTypeID:    lotteryType.ID,
+			PrizePoolID:      prizePool.ID,
+			SecurityCode:     code,
+			ContentEncrypted: encrypted,
+			PrizeAmount:      prize,
+			Status:           model.TicketStatusUnscratched,
+			PurchasedAt:      time.Now(),
+		}
+		if err := db.Create(&ticket).Error; err != nil {
+			t.Fatalf("Failed to create ticket: %v", err)
+		}
+		ticketIDs[i] = ticket.ID
+	}
+	return db, scratchService, largeWins, user.ID, ticketIDs
+}
+
+// Large wins: with identity confirmation required, prizes at or above the
+// threshold are held as pending claims instead of being credited, and are
+// credited once when the winner confirms their identity. The report lists
+// exactly the wins at or above the threshold with masked identity numbers.
+func TestLargeWinHoldAndReport(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("large wins are held until claimed and reported", prop.ForAll(
+		func(prizes []int) bool {
+			const threshold = 500
+			db, scratchService, largeWins, userID, ticketIDs := setupLargeWinTest(t, prizes)
+			thresholdSetting, required := threshold, true
+			settings, err := largeWins.adminService.UpdateSystemSettings(1, UpdateSystemSettingsRequest{
+				LargeWinThreshold:        &thresholdSetting,
+				LargeWinIdentityRequired: &required,
+			})
+			if err != nil || settings.LargeWinThreshold != threshold || !settings.LargeWinIdentityRequired {
+				t.Logf("Expected large win settings, got %+v (err %v)", settings, err)
+				return false
+			}
+
+			var wallet model.Wallet
+			db.Where("user_id = ?", userID).First(&wallet)
+			initialBalance := wallet.Balance
+
+			credited, held, reported := 0, 0, 0
+			for i, ticketID := range ticketIDs {
+				resp, err := scratchService.ScratchTicket(userID, ticketID, "")
+				if err != nil {
+					t.Logf("ScratchTicket failed: %v", err)
+					return false
+				}
+				large := prizes[i] >= threshold
+				if resp.ClaimRequired != large {
+					t.Logf("Prize %d: expected claim required %v", prizes[i], large)
+					return false
+				}
+				if large {
+					held += prizes[i]
+					reported++
+				} else {
+					credited += prizes[i]
+				}
+			}
+
+			db.First(&wallet, wallet.ID)
+			if wallet.Balance != initialBalance+credited {
+				t.Logf("Expected balance %d before claims, got %d", initialBalance+credited, wallet.Balance)
+				return false
+			}
+
+			claims, err := largeWins.GetClaims(userID)
+			if err != nil || len(claims) != reported {
+				t.Logf("Expected %d pending claims, got %d (err %v)", reported, len(claims), err)
+				return false
+			}
+			for _, claim := range claims {
+				req := ClaimPrizeRequest{FullName: "张三", IDNumber: "110101199001011234", Contact: "winner@example.com"}
+				if _, err := largeWins.ClaimPrize(userID, claim.TicketID, req); err != nil {
+					t.Logf("ClaimPrize failed: %v", err)
+					return false
+				}
+				if _, err := largeWins.ClaimPrize(userID, claim.TicketID, req); err != ErrPrizeAlreadyClaimed {
+					t.Logf("Expected ErrPrizeAlreadyClaimed on a second claim, got %v", err)
+					return false
+				}
+				var ticket model.Ticket
+				db.First(&ticket, claim.TicketID)
+				if ticket.Status != model.TicketStatusClaimed {
+					t.Logf("Expected claimed ticket, got %s", ticket.Status)
+					return false
+				}
+			}
+
+			db.First(&wallet, wallet.ID)
+			if wallet.Balance != initialBalance+credited+held {
+				t.Logf("Expected balance %d after claims, got %d", initialBalance+credited+held, wallet.Balance)
+				return false
+			}
+
+			report, err := largeWins.GetReport(LargeWinReportQuery{})
+			if err != nil || int(report.Total) != reported || report.MinAmount != threshold || int(report.TotalAmount) != held {
+				t.Logf("Expected %d reported wins totalling %d, got %+v (err %v)", reported, held, report, err)
+				return false
+			}
+			for _, record := range report.Records {
+				if record.ClaimStatus != string(model.PrizeClaimStatusConfirmed) || strings.Contains(record.IDNumber, "1990") {
+					t.Logf("Expected a confirmed claim with a masked ID, got %+v", record)
+					return false
+				}
+			}
+			return true
+		},
+		gen.SliceOfN(6, gen.OneConstOf(0, 10, 100, 499, 500, 2000)),
+	))
+
+	properties.TestingRun(t)
+}
+
+func TestLargeWinReportExport(t *testing.T) {
+	db, scratchService, largeWins, userID, ticketIDs := setupLargeWinTest(t, []int{1000, 50})
+
+	// Without identity confirmation large wins are credited right away and reported as paid
+	for _, ticketID := range ticketIDs {
+		resp, err := scratchService.ScratchTicket(userID, ticketID, "")
+		if err != nil || resp.ClaimRequired {
+			t.Fatalf("Expected a credited prize, got %+v (err %v)", resp, err)
+		}
+	}
+
+	// No threshold configured and none given
+	if _, err := largeWins.GetReport(LargeWinReportQuery{}); err != ErrInvalidReportThreshold {
+		t.Errorf("Expected ErrInvalidReportThreshold, got %v", err)
+	}
+	if _, err := largeWins.GetReport(LargeWinReportQuery{MinAmount: 100, StartDate: "2024-02-01", EndDate: "2024-01-01"}); err != ErrInvalidReportPeriod {
+		t.Errorf("Expected ErrInvalidReportPeriod, got %v", err)
+	}
+	negative := -1
+	if _, err := largeWins.adminService.UpdateSystemSettings(1, UpdateSystemSettingsRequest{LargeWinThreshold: &negative}); err != ErrInvalidReportThreshold {
+		t.Errorf("Expected ErrInvalidReportThreshold for a negative threshold, got %v", err)
+	}
+
+	report, err := largeWins.GetReport(LargeWinReportQuery{MinAmount: 100})
+	if err != nil || report.Total != 1 || report.Records[0].ClaimStatus != LargeWinStatusPaid || report.Records[0].Username != "Winner" {
+		t.Fatalf("Expected one paid win, got %+v (err %v)", report, err)
+	}
+
+	// Identity given on a claim is exported in full
+	db.Create(&model.PrizeClaim{TicketID: ticketIDs[0], UserID: userID, PrizeAmount: 1000, Status: model.PrizeClaimStatusPending})
+	if _, err := largeWins.ClaimPrize(userID, ticketIDs[0], ClaimPrizeRequest{FullName: " ", IDNumber: "110101199001011234"}); err != ErrInvalidClaimIdentity {
+		t.Errorf("Expected ErrInvalidClaimIdentity, got %v", err)
+	}
+	if _, err := largeWins.ClaimPrize(userID, ticketIDs[1], ClaimPrizeRequest{FullName: "张三", IDNumber: "110101199001011234"}); err != ErrPrizeClaimNotFound {
+		t.Errorf("Expected ErrPrizeClaimNotFound, got %v", err)
+	}
+	if _, err := largeWins.ClaimPrize(userID, ticketIDs[0], ClaimPrizeRequest{FullName: "张三", IDNumber: "110101199001011234"}); err != nil {
+		t.Fatalf("ClaimPrize failed: %v", err)
+	}
+
+	var stored model.PrizeClaim
+	db.Where("ticket_id = ?", ticketIDs[0]).First(&stored)
+	if stored.IDNumberEncrypted == "" || strings.Contains(stored.IDNumberEncrypted, "110101") {
+		t.Errorf("Expected an encrypted ID number, got %q", stored.IDNumberEncrypted)
+	}
+
+	csvData, err := largeWins.ExportReportCSV(1, LargeWinReportQuery{MinAmount: 100})
+	if err != nil {
+		t.Fatalf("ExportReportCSV failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(csvData)), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], "110101199001011234") || !strings.Contains(lines[1], "张三") {
+		t.Errorf("Expected one exported win with the full identity, got %q", csvData)
+	}
+
+	var logs int64
+	db.Model(&model.AdminLog{}).Where("action = ?", "export_large_wins").Count(&logs)
+	if logs != 1 {
+		t.Errorf("Expected the export to be logged, got %d logs", logs)
+	}
+
+	// Reports are kept per tenant
+	if report, _ := largeWins.ForTenant(2).GetReport(LargeWinReportQuery{MinAmount: 100}); report == nil || report.Total != 0 {
+		t.Errorf("Expected no wins for tenant 2, got %+v", report)
+	}
+}
+
+func TestLargeWinReportOnReadOnlyConnection(t *testing.T) {
+	db, scratchService, largeWins, userID, ticketIDs := setupLargeWinTest(t, []int{1000})
+	if _, err := scratchService.ScratchTicket(userID, ticketIDs[0], ""); err != nil {
+		t.Fatalf("ScratchTicket failed: %v", err)
+	}
+
+	// A second session on the same connection pool that refuses writes
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("Failed to get connection pool: %v", err)
+	}
+	reportDB, err := gorm.Open(sqlite.Dialector{Conn: sqlDB}, &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to open report connection: %v", err)
+	}
+	if err := reportDB.Use(repository.TenantPlugin{}); err != nil {
+		t.Fatalf("Failed to register tenant plugin: %v", err)
+	}
+	if err := reportDB.Use(repository.ReadOnlyPlugin{}); err != nil {
+		t.Fatalf("Failed to register read-only plugin: %v", err)
+	}
+	largeWins.UseReportDB(reportDB)
+	largeWins.adminService.UseReportDB(reportDB)
+
+	report, err := largeWins.ForTenant(1).GetReport(LargeWinReportQuery{MinAmount: 100})
+	if err != nil || report.Total != 1 {
+		t.Fatalf("Expected one win from the report connection, got %+v (err %v)", report, err)
+	}
+	if report, _ := largeWins.ForTenant(2).GetReport(LargeWinReportQuery{MinAmount: 100}); report == nil || report.Total != 0 {
+		t.Errorf("Expected the report connection to stay tenant scoped, got %+v", report)
+	}
+
+	// The export reads from the report connection but logs on the primary one
+	if _, err := largeWins.ExportReportCSV(1, LargeWinReportQuery{MinAmount: 100}); err != nil {
+		t.Fatalf("ExportReportCSV failed: %v", err)
+	}
+	var logs int64
+	db.Model(&model.AdminLog{}).Where("action = ?", "export_large_wins").Count(&logs)
+	if logs != 1 {
+		t.Errorf("Expected the export to be logged, got %d logs", logs)
+	}
+
+	stats, err := largeWins.adminService.ForTenant(1).GetDashboardStats()
+	if err != nil || stats.TotalUsers != 1 || stats.TotalTicketsSold != 1 {
+		t.Errorf("Expected dashboard stats from the report connection, got %+v (err %v)", stats, err)
+	}
+}
@@ -0,0 +1,847 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+	"scratch-lottery/pkg/biztime"
+	"scratch-lottery/pkg/crypto"
+	"scratch-lottery/pkg/redact"
+
+	"gorm.io/gorm"
+)
+
+// System config keys of large win reporting
+const (
+	ConfigKeyLargeWinThreshold        = "large_win_threshold"         // prize amount from which a win is large
+	ConfigKeyLargeWinIdentityRequired = "large_win_identity_required" // hold large wins until the winner's identity is confirmed
+	ConfigKeyLargeWinApprovalRequired = "large_win_approval_required" // hold large wins until an admin approves their claim
+)
+
+// Claim statuses of reported wins. Wins credited at scratch time are paid.
+const (
+	LargeWinStatusPaid = "paid"
+)
+
+// maxClaimNameLength is the maximum length of a claimant's name
+const maxClaimNameLength = 64
+
+// maxRejectReasonLength is the maximum length of a claim rejection reason
+const maxRejectReasonLength = 255
+
+var (
+	ErrPrizeClaimNotFound     = errors.New("prize claim not found")
+	ErrPrizeAlreadyClaimed    = errors.New("prize already claimed")
+	ErrPrizeClaimNotSubmitted = errors.New("prize claim not waiting for approval")
+	ErrInvalidRejectReason    = errors.New("invalid claim rejection reason")
+	ErrInvalidClaimIdentity   = errors.New("invalid claim identity")
+	ErrInvalidReportThreshold = errors.New("invalid large win threshold")
+	ErrInvalidReportPeriod    = errors.New("invalid report period")
+)
+
+// claimIDNumberPattern matches identity document numbers
+var claimIDNumberPattern = regexp.MustCompile(`^[0-9A-Za-z-]{6,32}$`)
+
+// LargeWinService reports wins above a threshold and, when the operator
+// requires it, holds such wins until the winner confirms their identity and
+// an admin approves the payout
+type LargeWinService struct {
+	db            *gorm.DB
+	reportDB      *gorm.DB // report reads, see UseReportDB
+	adminService  *AdminService
+	encryptionKey string
+	redaction     FieldRedaction
+}
+
+// NewLargeWinService creates a new large win service. Identity document
+// numbers are encrypted with encryptionKey.
+func NewLargeWinService(db *gorm.DB, adminService *AdminService, encryptionKey string) *LargeWinService {
+	return &LargeWinService{db: db, reportDB: db, adminService: adminService, encryptionKey: encryptionKey}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *LargeWinService) ForTenant(tenantID uint) *LargeWinService {
+	return &LargeWinService{
+		db:            repository.ScopeTenant(s.db, tenantID),
+		reportDB:      repository.ScopeTenant(s.reportDB, tenantID),
+		adminService:  s.adminService.ForTenant(tenantID),
+		encryptionKey: s.encryptionKey,
+		redaction:     s.redaction,
+	}
+}
+
+// WithRedaction returns a copy of the service that redacts its report and export
+func (s *LargeWinService) WithRedaction(redaction FieldRedaction) *LargeWinService {
+	return &LargeWinService{
+		db:            s.db,
+		reportDB:      s.reportDB,
+		adminService:  s.adminService,
+		encryptionKey: s.encryptionKey,
+		redaction:     redaction,
+	}
+}
+
+// UseReportDB reads the large win report and its export from db, typically
+// a connection under a read-only database role. The export's admin log entry
+// is still written to the primary database.
+func (s *LargeWinService) UseReportDB(db *gorm.DB) {
+	s.reportDB = db
+}
+
+// LargeWinPolicy is the large win configuration of a tenant
+type LargeWinPolicy struct {
+	Threshold        int  `json:"threshold"`
+	IdentityRequired bool `json:"identity_required"`
+	ApprovalRequired bool `json:"approval_required"`
+}
+
+// holds reports whether a prize must wait for a claim
+func (p LargeWinPolicy) holds(prizeAmount int) bool {
+	return (p.IdentityRequired || p.ApprovalRequired) && p.Threshold > 0 && prizeAmount >= p.Threshold
+}
+
+// ClaimPrizeRequest represents a winner's identity confirmation
+type ClaimPrizeRequest struct {
+	FullName string `json:"full_name" binding:"required"`
+	IDNumber string `json:"id_number" binding:"required"`
+	Contact  string `json:"contact"`
+}
+
+// PrizeClaimResponse represents a prize claim in API responses
+type PrizeClaimResponse struct {
+	ID           uint                   `json:"id"`
+	TicketID     uint                   `json:"ticket_id"`
+	PrizeAmount  int                    `json:"prize_amount"`
+	Status       model.PrizeClaimStatus `json:"status"`
+	FullName     string                 `json:"full_name,omitempty"`
+	CreatedAt    time.Time              `json:"created_at"`
+	ConfirmedAt  *time.Time             `json:"confirmed_at,omitempty"`
+	ReviewedAt   *time.Time             `json:"reviewed_at,omitempty"`
+	RejectReason string                 `json:"reject_reason,omitempty"`
+	NewBalance   *int                   `json:"new_balance,omitempty"`
+}
+
+// AdminPrizeClaimQuery represents query parameters for the admin claim list
+type AdminPrizeClaimQuery struct {
+	Status string `form:"status"` // Defaults to claims waiting for approval
+	Page   int    `form:"page"`
+	Limit  int    `form:"limit"`
+}
+
+// AdminPrizeClaimResponse represents a prize claim in the admin claim list.
+// The identity document number is masked.
+type AdminPrizeClaimResponse struct {
+	PrizeClaimResponse
+	UserID       uint   `json:"user_id"`
+	Username     string `json:"username"`
+	SecurityCode string `json:"security_code"`
+	IDNumber     string `json:"id_number,omitempty"`
+	Contact      string `json:"contact,omitempty"`
+	ReviewedBy   uint   `json:"reviewed_by,omitempty"`
+}
+
+// AdminPrizeClaimListResponse represents a page of prize claims
+type AdminPrizeClaimListResponse struct {
+	Claims     []AdminPrizeClaimResponse `json:"claims"`
+	Total      int64                     `json:"total"`
+	Page       int                       `json:"page"`
+	Limit      int                       `json:"limit"`
+	TotalPages int                       `json:"total_pages"`
+}
+
+// RejectPrizeClaimRequest represents an admin's refusal of a claim
+type RejectPrizeClaimRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// LargeWinReportQuery represents query parameters for the large win report.
+// MinAmount defaults to the configured threshold, the period to the last month.
+type LargeWinReportQuery struct {
+	MinAmount int    `form:"min_amount"`
+	StartDate string `form:"start_date"` // Format: 2006-01-02
+	EndDate   string `form:"end_date"`   // Format: 2006-01-02, inclusive
+	Page      int    `form:"page"`
+	Limit     int    `form:"limit"`
+}
+
+// LargeWinRecord is a reported win
+type LargeWinRecord struct {
+	TicketID        uint       `json:"ticket_id"`
+	SecurityCode    string     `json:"security_code"`
+	LotteryTypeName string     `json:"lottery_type_name"`
+	PrizeAmount     int        `json:"prize_amount"`
+	UserID          uint       `json:"user_id"`
+	Username        string     `json:"username"`
+	LinuxdoID       string     `json:"linuxdo_id"`
+	ScratchedAt     *time.Time `json:"scratched_at"`
+	ClaimStatus     string     `json:"claim_status"` // paid, or the status of the prize claim
+	FullName        string     `json:"full_name,omitempty"`
+	IDNumber        string     `json:"id_number,omitempty"` // masked in the JSON report
+	Contact         string     `json:"contact,omitempty"`
+	ConfirmedAt     *time.Time `json:"confirmed_at,omitempty"`
+}
+
+// LargeWinReportResponse represents a page of the large win report
+type LargeWinReportResponse struct {
+	Records     []LargeWinRecord `json:"records"`
+	MinAmount   int              `json:"min_amount"`
+	StartDate   string           `json:"start_date"`
+	EndDate     string           `json:"end_date"`
+	TotalAmount int64            `json:"total_amount"`
+	Total       int64            `json:"total"`
+	Page        int              `json:"page"`
+	Limit       int              `json:"limit"`
+	TotalPages  int              `json:"total_pages"`
+}
+
+// GetPolicy returns the large win configuration
+func (s *LargeWinService) GetPolicy() (LargeWinPolicy, error) {
+	var policy LargeWinPolicy
+	threshold, err := s.adminService.GetConfigValue(ConfigKeyLargeWinThreshold)
+	if err != nil && !errors.Is(err, ErrConfigNotFound) {
+		return policy, err
+	}
+	policy.Threshold, _ = strconv.Atoi(threshold)
+
+	required, err := s.adminService.GetConfigValue(ConfigKeyLargeWinIdentityRequired)
+	if err != nil && !errors.Is(err, ErrConfigNotFound) {
+		return policy, err
+	}
+	policy.IdentityRequired = required == "true"
+
+	approval, err := s.adminService.GetConfigValue(ConfigKeyLargeWinApprovalRequired)
+	if err != nil && !errors.Is(err, ErrConfigNotFound) {
+		return policy, err
+	}
+	policy.ApprovalRequired = approval == "true"
+	return policy, nil
+}
+
+// holdPrize records a pending claim for a won ticket inside the scratch
+// transaction. payoutProductID is the product paid out once the claim is
+// confirmed, or zero to credit points. With approvalRequired the claim is
+// paid out only once an admin approves it, even if the policy changes later.
+func (s *LargeWinService) holdPrize(tx *gorm.DB, ticket *model.Ticket, payoutProductID uint, approvalRequired bool) error {
+	claim := model.PrizeClaim{
+		TenantID:         ticket.TenantID,
+		TicketID:         ticket.ID,
+		UserID:           ticket.UserID,
+		PrizeAmount:      ticket.PrizeAmount,
+		Status:           model.PrizeClaimStatusPending,
+		PayoutProductID:  payoutProductID,
+		ApprovalRequired: approvalRequired,
+	}
+	return tx.Create(&claim).Error
+}
+
+// GetClaims returns the prize claims of a user, pending ones first
+func (s *LargeWinService) GetClaims(userID uint) ([]PrizeClaimResponse, error) {
+	var claims []model.PrizeClaim
+	if err := s.db.Where("user_id = ?", userID).
+		Order(fmt.Sprintf("CASE WHEN status = '%s' THEN 0 ELSE 1 END, created_at DESC", model.PrizeClaimStatusPending)).
+		Find(&claims).Error; err != nil {
+		return nil, err
+	}
+
+	responses := make([]PrizeClaimResponse, len(claims))
+	for i := range claims {
+		responses[i] = toPrizeClaimResponse(&claims[i])
+	}
+	return responses, nil
+}
+
+// ClaimPrize confirms the winner's identity for a held prize, pays out the
+// prize and marks the ticket claimed. Claims that require approval wait for
+// an admin instead, see ApproveClaim. Product payouts wait for an admin to
+// deliver the product.
+func (s *LargeWinService) ClaimPrize(userID, ticketID uint, req ClaimPrizeRequest) (*PrizeClaimResponse, error) {
+	fullName := strings.TrimSpace(req.FullName)
+	idNumber := strings.TrimSpace(req.IDNumber)
+	contact := strings.TrimSpace(req.Contact)
+	if fullName == "" || len([]rune(fullName)) > maxClaimNameLength || !claimIDNumberPattern.MatchString(idNumber) ||
+		len([]rune(contact)) > 128 {
+		return nil, ErrInvalidClaimIdentity
+	}
+
+	var claim model.PrizeClaim
+	if err := s.db.Where("ticket_id = ? AND user_id = ?", ticketID, userID).First(&claim).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPrizeClaimNotFound
+		}
+		return nil, err
+	}
+	if claim.Status != model.PrizeClaimStatusPending {
+		return nil, ErrPrizeAlreadyClaimed
+	}
+
+	aesCrypto, err := crypto.NewAESCrypto(s.encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	idNumberEncrypted, err := aesCrypto.Encrypt(idNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	var ticket model.Ticket
+	if err := s.db.Preload("LotteryType", includeDeleted).First(&ticket, ticketID).Error; err != nil {
+		return nil, err
+	}
+
+	// An open dispute of a ticket purchase holds the payout for an admin
+	held, err := payoutsFrozen(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	status := model.PrizeClaimStatusConfirmed
+	if claim.ApprovalRequired || held {
+		status = model.PrizeClaimStatusSubmitted
+	}
+	err = repository.Transaction(s.db, func(tx *gorm.DB) error {
+		// Confirm the claim, guarding against a concurrent confirmation
+		result := tx.Model(&model.PrizeClaim{}).
+			Where("id = ? AND status = ?", claim.ID, model.PrizeClaimStatusPending).
+			Updates(map[string]interface{}{
+				"status":              status,
+				"full_name":           fullName,
+				"id_number_encrypted": idNumberEncrypted,
+				"contact":             contact,
+				"confirmed_at":        now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrPrizeAlreadyClaimed
+		}
+		if status == model.PrizeClaimStatusSubmitted {
+			return nil
+		}
+		return payOutClaim(tx, &claim, &ticket, userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	claim.Status = status
+	claim.FullName = fullName
+	claim.ConfirmedAt = &now
+	resp := toPrizeClaimResponse(&claim)
+	var wallet model.Wallet
+	if err := s.db.Where("user_id = ?", userID).First(&wallet).Error; err == nil {
+		resp.NewBalance = &wallet.Balance
+	}
+	return &resp, nil
+}
+
+// GetAdminClaims returns a page of prize claims in a status, by default those
+// waiting for approval, oldest first
+func (s *LargeWinService) GetAdminClaims(query AdminPrizeClaimQuery) (*AdminPrizeClaimListResponse, error) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit < 1 || query.Limit > 100 {
+		query.Limit = 20
+	}
+	status := model.PrizeClaimStatus(query.Status)
+	if status == "" {
+		status = model.PrizeClaimStatusSubmitted
+	}
+
+	dbQuery := s.db.Model(&model.PrizeClaim{}).Where("status = ?", status)
+	var total int64
+	if err := dbQuery.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+	var claims []model.PrizeClaim
+	if err := dbQuery.Order("created_at ASC, id ASC").
+		Offset((query.Page - 1) * query.Limit).
+		Limit(query.Limit).
+		Find(&claims).Error; err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]uint, 0, len(claims))
+	ticketIDs := make([]uint, 0, len(claims))
+	for _, claim := range claims {
+		userIDs = append(userIDs, claim.UserID)
+		ticketIDs = append(ticketIDs, claim.TicketID)
+	}
+	usernames := make(map[uint]string)
+	securityCodes := make(map[uint]string)
+	if len(claims) > 0 {
+		var users []model.User
+		if err := s.db.Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			usernames[user.ID] = user.Username
+		}
+		var tickets []model.Ticket
+		if err := s.db.Select("id", "security_code").Where("id IN ?", ticketIDs).Find(&tickets).Error; err != nil {
+			return nil, err
+		}
+		for _, ticket := range tickets {
+			securityCodes[ticket.ID] = ticket.SecurityCode
+		}
+	}
+
+	aesCrypto, err := crypto.NewAESCrypto(s.encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]AdminPrizeClaimResponse, len(claims))
+	for i := range claims {
+		claim := &claims[i]
+		resp := AdminPrizeClaimResponse{
+			PrizeClaimResponse: toPrizeClaimResponse(claim),
+			UserID:             claim.UserID,
+			Username:           usernames[claim.UserID],
+			SecurityCode:       s.redaction.SecurityCode(securityCodes[claim.TicketID]),
+			Contact:            claim.Contact,
+			ReviewedBy:         claim.ReviewedBy,
+		}
+		if claim.IDNumberEncrypted != "" {
+			idNumber, err := aesCrypto.Decrypt(claim.IDNumberEncrypted)
+			if err != nil {
+				return nil, err
+			}
+			resp.IDNumber = redact.Secret(idNumber)
+		}
+		responses[i] = resp
+	}
+
+	return &AdminPrizeClaimListResponse{
+		Claims:     responses,
+		Total:      total,
+		Page:       query.Page,
+		Limit:      query.Limit,
+		TotalPages: int((total + int64(query.Limit) - 1) / int64(query.Limit)),
+	}, nil
+}
+
+// ApproveClaim approves a submitted claim, pays out its prize and marks the
+// ticket claimed. The approval is recorded in the admin log.
+func (s *LargeWinService) ApproveClaim(adminID, claimID uint) (*PrizeClaimResponse, error) {
+	claim, err := s.getSubmittedClaim(claimID)
+	if err != nil {
+		return nil, err
+	}
+	if held, err := payoutsFrozen(s.db, claim.UserID); err != nil {
+		return nil, err
+	} else if held {
+		return nil, ErrPayoutDisputed
+	}
+	var ticket model.Ticket
+	if err := s.db.Preload("LotteryType", includeDeleted).First(&ticket, claim.TicketID).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	err = repository.Transaction(s.db, func(tx *gorm.DB) error {
+		if err := s.reviewClaim(tx, claim, adminID, now, model.PrizeClaimStatusConfirmed, ""); err != nil {
+			return err
+		}
+		if err := payOutClaim(tx, claim, &ticket, adminID); err != nil {
+			return err
+		}
+		return s.logClaimReview(tx, adminID, claim, "approve_prize_claim", "")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	claim.Status = model.PrizeClaimStatusConfirmed
+	claim.ReviewedAt = &now
+	resp := toPrizeClaimResponse(claim)
+	return &resp, nil
+}
+
+// RejectClaim refuses a submitted claim. The prize is forfeited and the
+// ticket stays scratched; the rejection is recorded in the admin log.
+func (s *LargeWinService) RejectClaim(adminID, claimID uint, req RejectPrizeClaimRequest) (*PrizeClaimResponse, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" || len([]rune(reason)) > maxRejectReasonLength {
+		return nil, ErrInvalidRejectReason
+	}
+	claim, err := s.getSubmittedClaim(claimID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	err = repository.Transaction(s.db, func(tx *gorm.DB) error {
+		if err := s.reviewClaim(tx, claim, adminID, now, model.PrizeClaimStatusRejected, reason); err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Ticket{}).Where("id = ?", claim.TicketID).
+			Update("status", model.TicketStatusScratched).Error; err != nil {
+			return err
+		}
+		ticket := model.Ticket{Model: gorm.Model{ID: claim.TicketID}, TenantID: claim.TenantID}
+		if err := appendTicketEvent(tx, &ticket, model.TicketEventVoided, adminID, model.TicketStatusScratched, 0, reason); err != nil {
+			return err
+		}
+		return s.logClaimReview(tx, adminID, claim, "reject_prize_claim", reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	claim.Status = model.PrizeClaimStatusRejected
+	claim.ReviewedAt = &now
+	claim.RejectReason = reason
+	resp := toPrizeClaimResponse(claim)
+	return &resp, nil
+}
+
+// getSubmittedClaim loads a claim waiting for approval
+func (s *LargeWinService) getSubmittedClaim(claimID uint) (*model.PrizeClaim, error) {
+	var claim model.PrizeClaim
+	if err := s.db.First(&claim, claimID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPrizeClaimNotFound
+		}
+		return nil, err
+	}
+	if claim.Status != model.PrizeClaimStatusSubmitted {
+		return nil, ErrPrizeClaimNotSubmitted
+	}
+	return &claim, nil
+}
+
+// reviewClaim moves a submitted claim to its reviewed status, guarding
+// against a concurrent review
+func (s *LargeWinService) reviewClaim(tx *gorm.DB, claim *model.PrizeClaim, adminID uint, now time.Time, status model.PrizeClaimStatus, reason string) error {
+	result := tx.Model(&model.PrizeClaim{}).
+		Where("id = ? AND status = ?", claim.ID, model.PrizeClaimStatusSubmitted).
+		Updates(map[string]interface{}{
+			"status":        status,
+			"reviewed_by":   adminID,
+			"reviewed_at":   now,
+			"reject_reason": reason,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrPrizeClaimNotSubmitted
+	}
+	return nil
+}
+
+// logClaimReview records the review of a claim in the admin log
+func (s *LargeWinService) logClaimReview(tx *gorm.DB, adminID uint, claim *model.PrizeClaim, action, reason string) error {
+	details, _ := json.Marshal(map[string]interface{}{
+		"ticket_id":    claim.TicketID,
+		"user_id":      claim.UserID,
+		"prize_amount": claim.PrizeAmount,
+		"reason":       reason,
+	})
+	adminLog := model.AdminLog{
+		AdminID:    adminID,
+		Action:     action,
+		TargetType: "prize_claim",
+		TargetID:   claim.ID,
+		Details:    string(details),
+	}
+	return tx.Create(&adminLog).Error
+}
+
+// GetReport returns a page of wins at or above the minimum amount scratched
+// within the period. Identity document numbers are masked.
+func (s *LargeWinService) GetReport(query LargeWinReportQuery) (*LargeWinReportResponse, error) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit < 1 {
+		query.Limit = 20
+	}
+	if query.Limit > 100 {
+		query.Limit = 100
+	}
+
+	dbQuery, resp, err := s.reportQuery(&query)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := dbQuery.Session(&gorm.Session{}).Count(&resp.Total).Error; err != nil {
+		return nil, err
+	}
+	if err := dbQuery.Session(&gorm.Session{}).Select("COALESCE(SUM(prize_amount), 0)").Scan(&resp.TotalAmount).Error; err != nil {
+		return nil, err
+	}
+
+	var tickets []model.Ticket
+	if err := dbQuery.Preload("User").Preload("LotteryType", includeDeleted).
+		Order("scratched_at DESC, id DESC").
+		Offset((query.Page - 1) * query.Limit).
+		Limit(query.Limit).
+		Find(&tickets).Error; err != nil {
+		return nil, err
+	}
+
+	resp.Records, err = s.toLargeWinRecords(tickets, true)
+	if err != nil {
+		return nil, err
+	}
+	resp.Page = query.Page
+	resp.Limit = query.Limit
+	resp.TotalPages = int((resp.Total + int64(query.Limit) - 1) / int64(query.Limit))
+	return resp, nil
+}
+
+// ExportReportCSV exports all wins of the report as CSV with full winner
+// identifiers. Every export is recorded in the admin log.
+func (s *LargeWinService) ExportReportCSV(adminID uint, query LargeWinReportQuery) ([]byte, error) {
+	dbQuery, resp, err := s.reportQuery(&query)
+	if err != nil {
+		return nil, err
+	}
+
+	var tickets []model.Ticket
+	if err := dbQuery.Preload("User").Preload("LotteryType", includeDeleted).
+		Order("scratched_at ASC, id ASC").
+		Find(&tickets).Error; err != nil {
+		return nil, err
+	}
+	records, err := s.toLargeWinRecords(tickets, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	_ = w.Write([]string{"票据ID", "保安码", "彩票类型", "中奖金额", "用户ID", "用户名", "LinuxDO ID", "刮开时间", "兑奖状态", "姓名", "证件号码", "联系方式", "确认时间"})
+	for _, r := range records {
+		_ = w.Write([]string{
+			strconv.FormatUint(uint64(r.TicketID), 10),
+			r.SecurityCode,
+			r.LotteryTypeName,
+			strconv.Itoa(r.PrizeAmount),
+			strconv.FormatUint(uint64(r.UserID), 10),
+			r.Username,
+			r.LinuxdoID,
+			formatReportTime(r.ScratchedAt),
+			r.ClaimStatus,
+			r.FullName,
+			r.IDNumber,
+			r.Contact,
+			formatReportTime(r.ConfirmedAt),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+
+	details, _ := json.Marshal(map[string]interface{}{
+		"min_amount": resp.MinAmount,
+		"start_date": resp.StartDate,
+		"end_date":   resp.EndDate,
+		"records":    len(records),
+	})
+	adminLog := model.AdminLog{
+		AdminID:    adminID,
+		Action:     "export_large_wins",
+		TargetType: "report",
+		TargetID:   0,
+		Details:    string(details),
+	}
+	if err := s.db.Create(&adminLog).Error; err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// reportQuery resolves the report defaults and builds the ticket query
+func (s *LargeWinService) reportQuery(query *LargeWinReportQuery) (*gorm.DB, *LargeWinReportResponse, error) {
+	if query.MinAmount == 0 {
+		policy, err := s.GetPolicy()
+		if err != nil {
+			return nil, nil, err
+		}
+		query.MinAmount = policy.Threshold
+	}
+	if query.MinAmount <= 0 {
+		return nil, nil, ErrInvalidReportThreshold
+	}
+
+	now := biztime.Now()
+	startDate := now.AddDate(0, -1, 0)
+	endDate := now
+	if query.StartDate != "" {
+		parsed, err := biztime.ParseDate(query.StartDate)
+		if err != nil {
+			return nil, nil, ErrInvalidReportPeriod
+		}
+		startDate = parsed
+	}
+	if query.EndDate != "" {
+		parsed, err := biztime.ParseDate(query.EndDate)
+		if err != nil {
+			return nil, nil, ErrInvalidReportPeriod
+		}
+		endDate = parsed
+	}
+	startDate = biztime.DayStart(startDate)
+	endDate = biztime.DayStart(endDate)
+	if endDate.Before(startDate) {
+		return nil, nil, ErrInvalidReportPeriod
+	}
+
+	dbQuery := s.reportDB.Model(&model.Ticket{}).
+		Where("prize_amount >= ? AND status IN ? AND scratched_at >= ? AND scratched_at < ?",
+			query.MinAmount,
+			model.RevealedTicketStatuses,
+			startDate, endDate.AddDate(0, 0, 1))
+
+	resp := &LargeWinReportResponse{
+		Records:   []LargeWinRecord{},
+		MinAmount: query.MinAmount,
+		StartDate: startDate.Format("2006-01-02"),
+		EndDate:   endDate.Format("2006-01-02"),
+	}
+	return dbQuery, resp, nil
+}
+
+// toLargeWinRecords builds report records with the claims of the tickets
+func (s *LargeWinService) toLargeWinRecords(tickets []model.Ticket, mask bool) ([]LargeWinRecord, error) {
+	ticketIDs := make([]uint, len(tickets))
+	for i, ticket := range tickets {
+		ticketIDs[i] = ticket.ID
+	}
+	claims := make(map[uint]model.PrizeClaim)
+	if len(ticketIDs) > 0 {
+		var rows []model.PrizeClaim
+		if err := s.reportDB.Where("ticket_id IN ?", ticketIDs).Find(&rows).Error; err != nil {
+			return nil, err
+		}
+		for _, claim := range rows {
+			claims[claim.TicketID] = claim
+		}
+	}
+
+	aesCrypto, err := crypto.NewAESCrypto(s.encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]LargeWinRecord, len(tickets))
+	for i, ticket := range tickets {
+		record := LargeWinRecord{
+			TicketID:        ticket.ID,
+			SecurityCode:    s.redaction.SecurityCode(ticket.SecurityCode),
+			LotteryTypeName: ticket.LotteryType.Name,
+			PrizeAmount:     ticket.PrizeAmount,
+			UserID:          ticket.UserID,
+			Username:        ticket.User.Username,
+			LinuxdoID:       ticket.User.LinuxdoID,
+			ScratchedAt:     ticket.ScratchedAt,
+			ClaimStatus:     LargeWinStatusPaid,
+		}
+		if claim, ok := claims[ticket.ID]; ok {
+			record.ClaimStatus = string(claim.Status)
+			record.FullName = claim.FullName
+			record.Contact = claim.Contact
+			record.ConfirmedAt = claim.ConfirmedAt
+			if claim.IDNumberEncrypted != "" {
+				idNumber, err := aesCrypto.Decrypt(claim.IDNumberEncrypted)
+				if err != nil {
+					return nil, err
+				}
+				if mask {
+					idNumber = redact.Secret(idNumber)
+				}
+				record.IDNumber = idNumber
+			}
+		}
+		records[i] = record
+	}
+	return records, nil
+}
+
+// creditPrize credits the prize of a won ticket to its owner's wallet and
+// counts it as claimed in its prize pool
+func creditPrize(tx *gorm.DB, ticket *model.Ticket) error {
+	var wallet model.Wallet
+	if err := lockWallet(tx, ticket.UserID, &wallet); err != nil {
+		return err
+	}
+
+	wallet.Balance += ticket.PrizeAmount
+	if err := tx.Save(&wallet).Error; err != nil {
+		return err
+	}
+
+	transaction := model.Transaction{
+		TenantID:    wallet.TenantID,
+		WalletID:    wallet.ID,
+		Type:        model.TransactionTypeWin,
+		Amount:      ticket.PrizeAmount,
+		Description: fmt.Sprintf("彩票中奖: %s", ticket.LotteryType.Name),
+		ReferenceID: ticket.ID,
+	}
+	if err := tx.Create(&transaction).Error; err != nil {
+		return err
+	}
+
+	if err := appendTicketEvent(tx, ticket, model.TicketEventPrizeCredited, 0, "", ticket.PrizeAmount, ""); err != nil {
+		return err
+	}
+
+	return tx.Model(&model.PrizePool{}).Where("id = ?", ticket.PrizePoolID).
+		Update("claimed_prizes", gorm.Expr("claimed_prizes + 1")).Error
+}
+
+// payOutClaim pays out the prize of a confirmed claim and marks its ticket
+// claimed: the prize product waits for delivery, points are credited at once.
+// actorID is the winner confirming the claim or the admin approving it.
+func payOutClaim(tx *gorm.DB, claim *model.PrizeClaim, ticket *model.Ticket, actorID uint) error {
+	if err := tx.Model(&model.Ticket{}).Where("id = ?", claim.TicketID).
+		Update("status", model.TicketStatusClaimed).Error; err != nil {
+		return err
+	}
+	if err := appendTicketEvent(tx, ticket, model.TicketEventClaimed, actorID, model.TicketStatusClaimed, 0, ""); err != nil {
+		return err
+	}
+	if claim.PayoutProductID != 0 {
+		return createPrizeFulfillment(tx, ticket, claim.PayoutProductID)
+	}
+	return creditPrize(tx, ticket)
+}
+
+func toPrizeClaimResponse(claim *model.PrizeClaim) PrizeClaimResponse {
+	return PrizeClaimResponse{
+		ID:           claim.ID,
+		TicketID:     claim.TicketID,
+		PrizeAmount:  claim.PrizeAmount,
+		Status:       claim.Status,
+		FullName:     claim.FullName,
+		CreatedAt:    claim.CreatedAt,
+		ConfirmedAt:  claim.ConfirmedAt,
+		ReviewedAt:   claim.ReviewedAt,
+		RejectReason: claim.RejectReason,
+	}
+}
+
+// formatReportTime formats an optional report time
+func formatReportTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
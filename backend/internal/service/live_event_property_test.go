@@ -0,0 +1,102 @@
+package service
+
+import (
+	"testing"
+)
+
+// drainLiveEvents returns the events waiting on a subscription
+func drainLiveEvents(ch <-chan LiveEvent) []LiveEvent {
+	var events []LiveEvent
+	for {
+		select {
+		case event := <-ch:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+// Live events: a credited scratch pushes the new balance to the winner only,
+// a big win is pushed to every subscriber of the tenant with the winner's
+// name masked, and subscribers of other tenants get nothing.
+func TestLiveEventsOfScratches(t *testing.T) {
+	_, scratchService, _, userID, ticketIDs := setupLargeWinTest(t, []int{50, 5000})
+	bus := NewLiveEventBus(1000, 5)
+	scratchService.UseEvents(bus)
+
+	winner, cancelWinner, err := bus.Subscribe(userID, "198.51.100.1")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer cancelWinner()
+	watcher, cancelWatcher, err := bus.Subscribe(0, "198.51.100.2")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer cancelWatcher()
+	foreign, cancelForeign, err := bus.ForTenant(2).Subscribe(0, "198.51.100.3")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer cancelForeign()
+
+	resp, err := scratchService.ScratchTicket(userID, ticketIDs[0], "")
+	if err != nil {
+		t.Fatalf("ScratchTicket failed: %v", err)
+	}
+	events := drainLiveEvents(winner)
+	if len(events) != 1 || events[0].Type != LiveEventBalance || events[0].Data.(BalanceEvent).Balance != resp.NewBalance {
+		t.Fatalf("Expected a balance event of %d, got %+v", resp.NewBalance, events)
+	}
+	if events := drainLiveEvents(watcher); len(events) != 0 {
+		t.Fatalf("Expected no events for other users of a small win, got %+v", events)
+	}
+
+	if _, err := scratchService.ScratchTicket(userID, ticketIDs[1], ""); err != nil {
+		t.Fatalf("ScratchTicket failed: %v", err)
+	}
+	if events := drainLiveEvents(winner); len(events) != 2 || events[0].Type != LiveEventBalance || events[1].Type != LiveEventBigWin {
+		t.Fatalf("Expected a balance and a big win event, got %+v", events)
+	}
+	events = drainLiveEvents(watcher)
+	if len(events) != 1 || events[0].Type != LiveEventBigWin {
+		t.Fatalf("Expected a big win event, got %+v", events)
+	}
+	if win := events[0].Data.(BigWinEvent); win.PrizeAmount != 5000 || win.Winner != "W***r" || win.LotteryTypeName != "Jackpot" {
+		t.Errorf("Unexpected big win %+v", win)
+	}
+	if events := drainLiveEvents(foreign); len(events) != 0 {
+		t.Errorf("Expected no events across tenants, got %+v", events)
+	}
+}
+
+// Live subscriptions are limited per client and released when cancelled
+func TestLiveEventSubscriptionLimit(t *testing.T) {
+	bus := NewLiveEventBus(1000, 2)
+
+	var cancels []func()
+	for i := 0; i < 2; i++ {
+		_, cancel, err := bus.Subscribe(0, "198.51.100.1")
+		if err != nil {
+			t.Fatalf("Subscribe %d failed: %v", i, err)
+		}
+		cancels = append(cancels, cancel)
+	}
+	if _, _, err := bus.Subscribe(0, "198.51.100.1"); err != ErrTooManyLiveSubscriptions {
+		t.Fatalf("Expected ErrTooManyLiveSubscriptions, got %v", err)
+	}
+	if _, cancel, err := bus.Subscribe(0, "198.51.100.2"); err != nil {
+		t.Fatalf("Expected another client to subscribe, got %v", err)
+	} else {
+		cancel()
+	}
+
+	cancels[0]()
+	cancels[0]()
+	if _, cancel, err := bus.Subscribe(0, "198.51.100.1"); err != nil {
+		t.Fatalf("Expected a released subscription to be reusable, got %v", err)
+	} else {
+		cancel()
+	}
+}
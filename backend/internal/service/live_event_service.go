@@ -0,0 +1,221 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+	"scratch-lottery/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTooManyLiveSubscriptions = errors.New("too many live event subscriptions from this client")
+)
+
+// Live event types
+const (
+	LiveEventBigWin  = "big_win"  // a prize at or above the big win threshold was scratched
+	LiveEventSoldOut = "sold_out" // the active prize pool of a lottery type sold its last ticket
+	LiveEventBalance = "balance"  // the balance of the subscribed user changed
+)
+
+// LiveEvent is a real-time event pushed to live subscribers. Events with a
+// user are private to that user, others go to every subscriber of the tenant.
+type LiveEvent struct {
+	Type   string      `json:"type"`
+	UserID uint        `json:"-"`
+	Data   interface{} `json:"data"`
+	At     time.Time   `json:"at"`
+}
+
+// BigWinEvent is the payload of a big win for the live winners ticker
+type BigWinEvent struct {
+	LotteryTypeID   uint   `json:"lottery_type_id"`
+	LotteryTypeName string `json:"lottery_type_name"`
+	PrizeAmount     int    `json:"prize_amount"`
+	Winner          string `json:"winner"` // masked username
+}
+
+// SoldOutEvent is the payload of a lottery type selling out
+type SoldOutEvent struct {
+	LotteryTypeID   uint   `json:"lottery_type_id"`
+	LotteryTypeName string `json:"lottery_type_name"`
+}
+
+// BalanceEvent is the payload of a balance change
+type BalanceEvent struct {
+	Balance int    `json:"balance"`
+	Reason  string `json:"reason"` // purchase or scratch
+}
+
+// liveSubscriber is the audience of a subscription
+type liveSubscriber struct {
+	tenantID uint
+	userID   uint // 0 for anonymous subscribers, who only get public events
+}
+
+// liveEventHub keeps the live subscribers. It is shared by all tenant copies
+// of the bus; events only reach subscribers of their tenant.
+type liveEventHub struct {
+	subscribers map[chan LiveEvent]liveSubscriber
+	clients     map[string]int
+	mutex       sync.Mutex
+}
+
+// LiveEventBus fans out live events published by the scratch and purchase
+// services to live subscribers. Events are delivered within this instance.
+type LiveEventBus struct {
+	hub          *liveEventHub
+	tenantID     uint
+	bigWinMin    int
+	maxPerClient int
+}
+
+// NewLiveEventBus creates a new live event bus. Prizes of at least bigWinMin
+// points are announced as big wins, 0 disables big win events. A client (IP)
+// may hold at most maxPerClient subscriptions at a time.
+func NewLiveEventBus(bigWinMin, maxPerClient int) *LiveEventBus {
+	if maxPerClient < 1 {
+		maxPerClient = 5
+	}
+	return &LiveEventBus{
+		hub: &liveEventHub{
+			subscribers: make(map[chan LiveEvent]liveSubscriber),
+			clients:     make(map[string]int),
+		},
+		tenantID:     repository.DefaultTenantID,
+		bigWinMin:    bigWinMin,
+		maxPerClient: maxPerClient,
+	}
+}
+
+// ForTenant returns a copy of the bus that publishes to and subscribes on a tenant
+func (b *LiveEventBus) ForTenant(tenantID uint) *LiveEventBus {
+	scoped := *b
+	scoped.tenantID = tenantID
+	return &scoped
+}
+
+// Subscribe registers a listener for the public events of the tenant and,
+// with a user, the private events of that user. The returned cancel func must
+// be called when the listener goes away.
+func (b *LiveEventBus) Subscribe(userID uint, client string) (<-chan LiveEvent, func(), error) {
+	hub := b.hub
+
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	if hub.clients[client] >= b.maxPerClient {
+		return nil, nil, ErrTooManyLiveSubscriptions
+	}
+	hub.clients[client]++
+
+	ch := make(chan LiveEvent, 32)
+	hub.subscribers[ch] = liveSubscriber{tenantID: b.tenantID, userID: userID}
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			hub.mutex.Lock()
+			defer hub.mutex.Unlock()
+
+			if hub.clients[client]--; hub.clients[client] <= 0 {
+				delete(hub.clients, client)
+			}
+			if _, ok := hub.subscribers[ch]; ok {
+				delete(hub.subscribers, ch)
+				close(ch)
+			}
+		})
+	}
+	return ch, cancel, nil
+}
+
+// Publish sends an event to its audience in the tenant. Slow listeners miss
+// the event rather than block the publisher.
+func (b *LiveEventBus) Publish(event LiveEvent) {
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+
+	hub := b.hub
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	for ch, sub := range hub.subscribers {
+		if sub.tenantID != b.tenantID || (event.UserID != 0 && sub.userID != event.UserID) {
+			continue
+		}
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// publishScratch announces the balance of a scratch that credited points and,
+// for prizes at or above the threshold, a big win to the whole tenant
+func (b *LiveEventBus) publishScratch(db *gorm.DB, ticket *model.Ticket, credited bool, balance int) {
+	if credited {
+		b.Publish(LiveEvent{
+			Type:   LiveEventBalance,
+			UserID: ticket.UserID,
+			Data:   BalanceEvent{Balance: balance, Reason: "scratch"},
+		})
+	}
+	if b.bigWinMin <= 0 || ticket.PrizeAmount < b.bigWinMin {
+		return
+	}
+
+	var user model.User
+	if err := db.Select("id", "username").First(&user, ticket.UserID).Error; err != nil {
+		logger.Default().Warn("Loading the winner of ticket %d failed: %v", ticket.ID, err)
+		return
+	}
+	b.Publish(LiveEvent{
+		Type: LiveEventBigWin,
+		Data: BigWinEvent{
+			LotteryTypeID:   ticket.LotteryTypeID,
+			LotteryTypeName: ticket.LotteryType.Name,
+			PrizeAmount:     ticket.PrizeAmount,
+			Winner:          maskUsername(user.Username),
+		},
+	})
+}
+
+// publishPurchase announces the balance after a purchase and, when the
+// purchase took the last tickets, the lottery type selling out
+func (b *LiveEventBus) publishPurchase(lotteryTypeID uint, lotteryTypeName string, userID uint, balance, stock int) {
+	b.Publish(LiveEvent{
+		Type:   LiveEventBalance,
+		UserID: userID,
+		Data:   BalanceEvent{Balance: balance, Reason: "purchase"},
+	})
+	if stock > 0 {
+		return
+	}
+	b.Publish(LiveEvent{
+		Type: LiveEventSoldOut,
+		Data: SoldOutEvent{LotteryTypeID: lotteryTypeID, LotteryTypeName: lotteryTypeName},
+	})
+}
+
+// maskUsername keeps the first and last character of a username, e.g. W***r
+func maskUsername(name string) string {
+	switch n := utf8.RuneCountInString(name); {
+	case n == 0:
+		return "***"
+	case n <= 2:
+		first, _ := utf8.DecodeRuneInString(name)
+		return string(first) + "***"
+	default:
+		first, _ := utf8.DecodeRuneInString(name)
+		last, _ := utf8.DecodeLastRuneInString(name)
+		return string(first) + "***" + string(last)
+	}
+}
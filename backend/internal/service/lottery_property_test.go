@@ -24,6 +24,11 @@ func setupLotteryTestDB(t *testing.T) *gorm.DB {
 	if err != nil {
 		t.Fatalf("Failed to connect to test database: %v", err)
 	}
+	// In-memory databases live as long as their connection; property tests
+	// create one per run, so release them with the test
+	if sqlDB, err := db.DB(); err == nil {
+		t.Cleanup(func() { _ = sqlDB.Close() })
+	}
 
 	err = db.AutoMigrate(
 		&model.User{},
@@ -32,7 +37,14 @@ func setupLotteryTestDB(t *testing.T) *gorm.DB {
 		&model.LotteryType{},
 		&model.PrizeLevel{},
 		&model.PrizePool{},
+		&model.Jackpot{},
 		&model.Ticket{},
+		&model.TicketHistory{},
+		&model.TicketEvent{},
+		&model.StockReservation{},
+		&model.ScratchConfirmation{},
+		&model.UserBadgeCounter{},
+		&model.TransactionDispute{},
 	)
 	if err != nil {
 		t.Fatalf("Failed to migrate test database: %v", err)
@@ -580,7 +592,12 @@ func TestProperty3_PurchaseBalanceVerification(t *testing.T) {
 			&model.LotteryType{},
 			&model.PrizeLevel{},
 			&model.PrizePool{},
+			&model.Jackpot{},
 			&model.Ticket{},
+			&model.TicketHistory{},
+			&model.TicketEvent{},
+			&model.StockReservation{},
+			&model.UserBadgeCounter{},
 		)
 		if err != nil {
 			return nil, nil, 0, 0, err
@@ -588,7 +605,7 @@ func TestProperty3_PurchaseBalanceVerification(t *testing.T) {
 
 		walletService := NewWalletService(db)
 		lotteryService := NewLotteryService(db, testEncryptionKey)
-		purchaseService := NewPurchaseService(db, lotteryService, walletService)
+		purchaseService := NewPurchaseService(db, lotteryService, walletService, nil, nil, nil)
 
 		// Create user
 		user := model.User{LinuxdoID: "test_user", Username: "Test", Role: "user"}
@@ -909,7 +926,12 @@ func TestProperty4_PurchaseTransactionConsistency(t *testing.T) {
 			&model.LotteryType{},
 			&model.PrizeLevel{},
 			&model.PrizePool{},
+			&model.Jackpot{},
 			&model.Ticket{},
+			&model.TicketHistory{},
+			&model.TicketEvent{},
+			&model.StockReservation{},
+			&model.UserBadgeCounter{},
 		)
 		if err != nil {
 			return nil, nil, nil, 0, 0, err
@@ -917,7 +939,7 @@ func TestProperty4_PurchaseTransactionConsistency(t *testing.T) {
 
 		walletService := NewWalletService(db)
 		lotteryService := NewLotteryService(db, testEncryptionKey)
-		purchaseService := NewPurchaseService(db, lotteryService, walletService)
+		purchaseService := NewPurchaseService(db, lotteryService, walletService, nil, nil, nil)
 
 		// Create user
 		user := model.User{LinuxdoID: "test_user", Username: "Test", Role: "user"}
@@ -1253,7 +1275,12 @@ func TestProperty5_WinningPrizePayout(t *testing.T) {
 			&model.LotteryType{},
 			&model.PrizeLevel{},
 			&model.PrizePool{},
+			&model.Jackpot{},
 			&model.Ticket{},
+			&model.TicketHistory{},
+			&model.TicketEvent{},
+			&model.StockReservation{},
+			&model.UserBadgeCounter{},
 		)
 		if err != nil {
 			return nil, nil, nil, 0, 0, err
@@ -1261,7 +1288,7 @@ func TestProperty5_WinningPrizePayout(t *testing.T) {
 
 		walletService := NewWalletService(db)
 		lotteryService := NewLotteryService(db, testEncryptionKey)
-		scratchService := NewScratchService(db, lotteryService, walletService)
+		scratchService := NewScratchService(db, lotteryService, walletService, nil, nil)
 
 		// Create user
 		user := model.User{LinuxdoID: "test_user", Username: "Test", Role: "user"}
@@ -1350,7 +1377,7 @@ func TestProperty5_WinningPrizePayout(t *testing.T) {
 			}
 
 			// Scratch the ticket
-			resp, err := scratchService.ScratchTicket(userID, ticketID)
+			resp, err := scratchService.ScratchTicket(userID, ticketID, "")
 			if err != nil {
 				t.Logf("Scratch failed: %v", err)
 				return false
@@ -1417,7 +1444,7 @@ func TestProperty5_WinningPrizePayout(t *testing.T) {
 			}
 
 			// Scratch the ticket
-			resp, err := scratchService.ScratchTicket(userID, ticketID)
+			resp, err := scratchService.ScratchTicket(userID, ticketID, "")
 			if err != nil {
 				t.Logf("Scratch failed: %v", err)
 				return false
@@ -1480,7 +1507,7 @@ func TestProperty5_WinningPrizePayout(t *testing.T) {
 			db.Model(&model.Transaction{}).Where("type = ?", model.TransactionTypeWin).Count(&countBefore)
 
 			// Scratch the ticket
-			_, err = scratchService.ScratchTicket(userID, ticketID)
+			_, err = scratchService.ScratchTicket(userID, ticketID, "")
 			if err != nil {
 				t.Logf("Scratch failed: %v", err)
 				return false
@@ -1549,7 +1576,7 @@ func TestProperty5_WinningPrizePayout(t *testing.T) {
 			}
 
 			// Scratch the ticket
-			resp, err := scratchService.ScratchTicket(userID, ticketID)
+			resp, err := scratchService.ScratchTicket(userID, ticketID, "")
 			if err != nil {
 				t.Logf("Scratch failed: %v", err)
 				return false
@@ -1601,7 +1628,7 @@ func TestProperty5_WinningPrizePayout(t *testing.T) {
 			}
 
 			// First scratch
-			_, err = scratchService.ScratchTicket(userID, ticketID)
+			_, err = scratchService.ScratchTicket(userID, ticketID, "")
 			if err != nil {
 				t.Logf("First scratch failed: %v", err)
 				return false
@@ -1615,7 +1642,7 @@ func TestProperty5_WinningPrizePayout(t *testing.T) {
 			}
 
 			// Try to scratch again
-			_, err = scratchService.ScratchTicket(userID, ticketID)
+			_, err = scratchService.ScratchTicket(userID, ticketID, "")
 			if err == nil {
 				t.Log("Second scratch should have failed")
 				return false
@@ -1668,7 +1695,7 @@ func TestProperty5_WinningPrizePayout(t *testing.T) {
 			}
 
 			// Try to scratch with other user
-			_, err = scratchService.ScratchTicket(otherUser.ID, ticketID)
+			_, err = scratchService.ScratchTicket(otherUser.ID, ticketID, "")
 			if err == nil {
 				t.Log("Scratch by other user should have failed")
 				return false
@@ -1728,7 +1755,12 @@ func TestProperty7_SecurityCodeQueryInfoHiding(t *testing.T) {
 			&model.LotteryType{},
 			&model.PrizeLevel{},
 			&model.PrizePool{},
+			&model.Jackpot{},
 			&model.Ticket{},
+			&model.TicketHistory{},
+			&model.TicketEvent{},
+			&model.StockReservation{},
+			&model.UserBadgeCounter{},
 		)
 		if err != nil {
 			return nil, nil, "", err
@@ -1924,7 +1956,12 @@ func TestProperty7_SecurityCodeQueryInfoHiding(t *testing.T) {
 				&model.LotteryType{},
 				&model.PrizeLevel{},
 				&model.PrizePool{},
+				&model.Jackpot{},
 				&model.Ticket{},
+				&model.TicketHistory{},
+				&model.TicketEvent{},
+				&model.StockReservation{},
+				&model.UserBadgeCounter{},
 			)
 			if err != nil {
 				t.Logf("Failed to migrate: %v", err)
@@ -2076,6 +2113,92 @@ func TestProperty7_SecurityCodeQueryInfoHiding(t *testing.T) {
 		gen.IntRange(0, 1000),
 	))
 
+	// Property: Batch verification applies the same hiding rules per code
+	properties.Property("batch verification hides prize info like single verification", prop.ForAll(
+		func(prizeAmount int, scratched bool) bool {
+			_, lotteryService, securityCode, err := setupVerifyTest(prizeAmount, scratched)
+			if err != nil {
+				t.Logf("Setup failed: %v", err)
+				return false
+			}
+
+			unknownCode, err := lotteryService.GenerateSecurityCode()
+			if err != nil {
+				t.Logf("Failed to generate code: %v", err)
+				return false
+			}
+
+			results, err := lotteryService.VerifySecurityCodes([]string{securityCode, "short", unknownCode}, 10)
+			if err != nil {
+				t.Logf("VerifySecurityCodes failed: %v", err)
+				return false
+			}
+			if len(results) != 3 {
+				t.Logf("Expected 3 results, got %d", len(results))
+				return false
+			}
+
+			if results[1].Result != VerifyResultInvalidCode || results[1].Ticket != nil {
+				t.Logf("Malformed code should be invalid_code, got: %s", results[1].Result)
+				return false
+			}
+			if results[2].Result != VerifyResultNotFound || results[2].Ticket != nil {
+				t.Logf("Unknown code should be not_found, got: %s", results[2].Result)
+				return false
+			}
+
+			single, err := lotteryService.VerifySecurityCode(securityCode)
+			if err != nil {
+				t.Logf("VerifySecurityCode failed: %v", err)
+				return false
+			}
+			batch := results[0].Ticket
+			if results[0].Result != VerifyResultOK || batch == nil {
+				t.Logf("Existing code should be ok, got: %s", results[0].Result)
+				return false
+			}
+			if (batch.PrizeAmount == nil) != (single.PrizeAmount == nil) || (batch.PrizeAmount == nil) != !scratched {
+				t.Log("Batch prize visibility should match single verification")
+				return false
+			}
+			if batch.PrizeAmount != nil && *batch.PrizeAmount != prizeAmount {
+				t.Logf("Prize amount mismatch: got %d, want %d", *batch.PrizeAmount, prizeAmount)
+				return false
+			}
+
+			return true
+		},
+		gen.IntRange(0, 1000),
+		gen.Bool(),
+	))
+
+	// Property: Batches over the limit are rejected as a whole
+	properties.Property("oversized batch is rejected", prop.ForAll(
+		func(extra int) bool {
+			_, lotteryService, securityCode, err := setupVerifyTest(100, false)
+			if err != nil {
+				t.Logf("Setup failed: %v", err)
+				return false
+			}
+
+			limit := 5
+			codes := make([]string, limit+extra)
+			for i := range codes {
+				codes[i] = securityCode
+			}
+
+			_, err = lotteryService.VerifySecurityCodes(codes, limit)
+			if err != ErrVerifyBatchTooLarge {
+				t.Logf("Expected ErrVerifyBatchTooLarge, got: %v", err)
+				return false
+			}
+
+			_, err = lotteryService.VerifySecurityCodes(nil, limit)
+			return err == ErrEmptyVerifyBatch
+		},
+		gen.IntRange(1, 20),
+	))
+
 	properties.TestingRun(t)
 }
 
@@ -2108,7 +2231,12 @@ func TestProperty12_PrizePoolTicketQuantityConsistency(t *testing.T) {
 			&model.LotteryType{},
 			&model.PrizeLevel{},
 			&model.PrizePool{},
+			&model.Jackpot{},
 			&model.Ticket{},
+			&model.TicketHistory{},
+			&model.TicketEvent{},
+			&model.StockReservation{},
+			&model.UserBadgeCounter{},
 		)
 		if err != nil {
 			return nil, nil, 0, err
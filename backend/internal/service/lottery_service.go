@@ -1,40 +1,71 @@
 package service
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
 	"time"
+	"unicode/utf8"
 
 	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
 	"scratch-lottery/pkg/crypto"
+	"scratch-lottery/pkg/logger"
+	"scratch-lottery/pkg/money"
 
 	"gorm.io/gorm"
 )
 
+
 var (
-	ErrLotteryTypeNotFound = errors.New("lottery type not found")
-	ErrLotteryTypeSoldOut  = errors.New("lottery type sold out")
-	ErrPrizePoolNotFound   = errors.New("prize pool not found")
-	ErrInvalidPrizeConfig  = errors.New("invalid prize configuration")
-	ErrTicketNotFound      = errors.New("ticket not found")
-	ErrSecurityCodeExists  = errors.New("security code already exists")
-	ErrNoPrizePoolActive   = errors.New("no active prize pool")
-	ErrEncryptionFailed    = errors.New("encryption failed")
-	ErrInvalidSecurityCode = errors.New("invalid security code format")
+	ErrLotteryTypeNotFound       = errors.New("lottery type not found")
+	ErrLotteryTypeSoldOut        = errors.New("lottery type sold out")
+	ErrPrizePoolNotFound         = errors.New("prize pool not found")
+	ErrInvalidPrizeConfig        = errors.New("invalid prize configuration")
+	ErrTicketNotFound            = errors.New("ticket not found")
+	ErrSecurityCodeExists        = errors.New("security code already exists")
+	ErrNoPrizePoolActive         = errors.New("no active prize pool")
+	ErrEncryptionFailed          = errors.New("encryption failed")
+	ErrInvalidSecurityCode       = errors.New("invalid security code format")
+	ErrEmptyVerifyBatch          = errors.New("no security codes to verify")
+	ErrVerifyBatchTooLarge       = errors.New("too many security codes in batch")
+	ErrLotteryTypeHasUnscratched = errors.New("lottery type has unscratched tickets")
+	ErrInvalidPrizePayout        = errors.New("invalid prize payout")
+	ErrPrizeLevelsInUse          = errors.New("prize levels in use by a selling prize pool")
+	ErrInvalidPrizeLevelDisplay  = errors.New("invalid prize level image, color or description")
+	ErrInvalidLotteryTypeSchedule = errors.New("scheduled end must be in the future and after the scheduled start")
 )
 
 // LotteryService handles lottery-related business logic
 type LotteryService struct {
 	db            *gorm.DB
 	encryptionKey string
+	numberMatch   *NumberMatchService
+	multiplier    *MultiplierService
+	stock         *StockCounter
 }
 
 // NewLotteryService creates a new lottery service
 func NewLotteryService(db *gorm.DB, encryptionKey string) *LotteryService {
-	return &LotteryService{db: db, encryptionKey: encryptionKey}
+	return &LotteryService{db: db, encryptionKey: encryptionKey, numberMatch: NewNumberMatchService(), multiplier: NewMultiplierService()}
+}
+
+// UseStockCounter serves stock reads from counter instead of the database
+func (s *LotteryService) UseStockCounter(counter *StockCounter) {
+	s.stock = counter
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *LotteryService) ForTenant(tenantID uint) *LotteryService {
+	return &LotteryService{db: repository.ScopeTenant(s.db, tenantID), encryptionKey: s.encryptionKey, numberMatch: s.numberMatch, multiplier: s.multiplier, stock: s.stock}
+}
+
+// withDB returns a copy of the service working on db, such as a transaction
+func (s *LotteryService) withDB(db *gorm.DB) *LotteryService {
+	return &LotteryService{db: db, encryptionKey: s.encryptionKey, numberMatch: s.numberMatch, multiplier: s.multiplier, stock: s.stock}
 }
 
 // LotteryTypeResponse represents a lottery type in API responses
@@ -48,6 +79,17 @@ type LotteryTypeResponse struct {
 	CoverImage  string                    `json:"cover_image"`
 	Status      model.LotteryTypeStatus   `json:"status"`
 	Stock       int                       `json:"stock"`
+	LowStockThreshold int               `json:"low_stock_threshold"`
+	WaitingRoomThreshold int            `json:"waiting_room_threshold"`
+	ConfirmScratch bool                 `json:"confirm_scratch"`
+	ScratchDelaySeconds int             `json:"scratch_delay_seconds"`
+	ScratchIntervalSeconds int          `json:"scratch_interval_seconds"`
+	AutoReplenish bool                  `json:"auto_replenish"`
+	ReplenishTemplateID uint            `json:"replenish_template_id"`
+	ReplenishTickets int                `json:"replenish_tickets"`
+	ScheduledStartAt *time.Time         `json:"scheduled_start_at,omitempty"`
+	ScheduledEndAt *time.Time           `json:"scheduled_end_at,omitempty"`
+	Archived    bool                      `json:"archived,omitempty"` // deleted, kept for the history of its tickets
 	CreatedAt   time.Time                 `json:"created_at"`
 	UpdatedAt   time.Time                 `json:"updated_at"`
 }
@@ -57,31 +99,37 @@ type LotteryTypeDetailResponse struct {
 	LotteryTypeResponse
 	Rules        string               `json:"rules"`
 	RulesConfig  interface{}          `json:"rules_config,omitempty"`
-	DesignConfig interface{}          `json:"design_config,omitempty"`
+	DesignConfig *DesignConfig        `json:"design_config,omitempty"`
 	PrizeLevels  []PrizeLevelResponse `json:"prize_levels"`
 	WinSymbols   []string             `json:"win_symbols,omitempty"`
 }
 
 // PrizeLevelResponse represents a prize level in API responses
 type PrizeLevelResponse struct {
-	ID          uint   `json:"id"`
-	Level       int    `json:"level"`
-	Name        string `json:"name"`
-	PrizeAmount int    `json:"prize_amount"`
-	Quantity    int    `json:"quantity"`
-	Remaining   int    `json:"remaining"`
+	ID              uint                  `json:"id"`
+	Level           int                   `json:"level"`
+	Name            string                `json:"name"`
+	PrizeAmount     int                   `json:"prize_amount"`
+	Quantity        int                   `json:"quantity"`
+	Remaining       int                   `json:"remaining"`
+	PayoutType      model.PrizePayoutType `json:"payout_type"`
+	PayoutProductID uint                  `json:"payout_product_id,omitempty"`
+	Image           string                `json:"image,omitempty"`
+	Color           string                `json:"color,omitempty"`
+	Description     string                `json:"description,omitempty"`
 }
 
 // PrizePoolResponse represents a prize pool in API responses
 type PrizePoolResponse struct {
-	ID            uint                  `json:"id"`
-	LotteryTypeID uint                  `json:"lottery_type_id"`
-	TotalTickets  int                   `json:"total_tickets"`
-	SoldTickets   int                   `json:"sold_tickets"`
-	ClaimedPrizes int                   `json:"claimed_prizes"`
-	ReturnRate    float64               `json:"return_rate"`
-	Status        model.PrizePoolStatus `json:"status"`
-	CreatedAt     time.Time             `json:"created_at"`
+	ID                uint                  `json:"id"`
+	LotteryTypeID     uint                  `json:"lottery_type_id"`
+	TotalTickets      int                   `json:"total_tickets"`
+	SoldTickets       int                   `json:"sold_tickets"`
+	ClaimedPrizes     int                   `json:"claimed_prizes"`
+	ReturnRate        float64               `json:"return_rate"`
+	Status            model.PrizePoolStatus `json:"status"`
+	PrizeLevelVersion int                   `json:"prize_level_version"`
+	CreatedAt         time.Time             `json:"created_at"`
 }
 
 // CreateLotteryTypeRequest represents the request to create a lottery type
@@ -94,6 +142,16 @@ type CreateLotteryTypeRequest struct {
 	CoverImage  string           `json:"cover_image"`
 	RulesConfig interface{}      `json:"rules_config"`
 	PrizeLevels []PrizeLevelInput `json:"prize_levels"`
+	LowStockThreshold int         `json:"low_stock_threshold" binding:"gte=0"`
+	WaitingRoomThreshold int      `json:"waiting_room_threshold" binding:"gte=0"`
+	ConfirmScratch bool           `json:"confirm_scratch"`
+	ScratchDelaySeconds int       `json:"scratch_delay_seconds" binding:"gte=0,lte=86400"`
+	ScratchIntervalSeconds int    `json:"scratch_interval_seconds" binding:"gte=0,lte=86400"`
+	AutoReplenish bool            `json:"auto_replenish"`
+	ReplenishTemplateID uint      `json:"replenish_template_id"`
+	ReplenishTickets int          `json:"replenish_tickets" binding:"gte=0"`
+	ScheduledStartAt *time.Time   `json:"scheduled_start_at"` // Launch time; the type stays disabled until then
+	ScheduledEndAt   *time.Time   `json:"scheduled_end_at"`   // End of sale; the type is disabled then
 }
 
 // UpdateLotteryTypeRequest represents the request to update a lottery type
@@ -105,16 +163,32 @@ type UpdateLotteryTypeRequest struct {
 	GameType     *model.GameType           `json:"game_type"`
 	CoverImage   *string                   `json:"cover_image"`
 	RulesConfig  interface{}               `json:"rules_config"`
-	DesignConfig interface{}               `json:"design_config"`
+	DesignConfig *DesignConfig             `json:"design_config"`
 	Status       *model.LotteryTypeStatus  `json:"status"`
+	LowStockThreshold *int                 `json:"low_stock_threshold" binding:"omitempty,gte=0"`
+	WaitingRoomThreshold *int              `json:"waiting_room_threshold" binding:"omitempty,gte=0"`
+	ConfirmScratch *bool                   `json:"confirm_scratch"`
+	ScratchDelaySeconds *int               `json:"scratch_delay_seconds" binding:"omitempty,gte=0,lte=86400"`
+	ScratchIntervalSeconds *int            `json:"scratch_interval_seconds" binding:"omitempty,gte=0,lte=86400"`
+	AutoReplenish *bool                    `json:"auto_replenish"`
+	ReplenishTemplateID *uint              `json:"replenish_template_id"`
+	ReplenishTickets *int                  `json:"replenish_tickets" binding:"omitempty,gte=0"`
+	ScheduledStartAt *time.Time            `json:"scheduled_start_at"`
+	ScheduledEndAt *time.Time              `json:"scheduled_end_at"`
+	ClearSchedule bool                     `json:"clear_schedule"` // Remove the scheduled start and end
 }
 
 // PrizeLevelInput represents input for creating prize levels
 type PrizeLevelInput struct {
-	Level       int    `json:"level" binding:"required"`
-	Name        string `json:"name" binding:"required"`
-	PrizeAmount int    `json:"prize_amount" binding:"required,gte=0"`
-	Quantity    int    `json:"quantity" binding:"required,gt=0"`
+	Level           int                   `json:"level" binding:"required"`
+	Name            string                `json:"name" binding:"required"`
+	PrizeAmount     int                   `json:"prize_amount" binding:"required,gte=0"`
+	Quantity        int                   `json:"quantity" binding:"required,gt=0"`
+	PayoutType      model.PrizePayoutType `json:"payout_type"`       // points (default) or product
+	PayoutProductID uint                  `json:"payout_product_id"` // exchange product of product payouts
+	Image           string                `json:"image"`               // optional icon or image, http(s) URL or site path
+	Color           string                `json:"color"`               // optional display color, #RGB or #RRGGBB
+	Description     string                `json:"description"`         // optional marketing copy, up to 256 characters
 }
 
 // CreatePrizePoolRequest represents the request to create a prize pool
@@ -209,7 +283,7 @@ func (s *LotteryService) GetAllLotteryTypes(query LotteryTypeListQuery) (*Lotter
 func (s *LotteryService) GetLotteryTypeByID(id uint) (*LotteryTypeDetailResponse, error) {
 	var lotteryType model.LotteryType
 	if err := s.db.Preload("PrizeLevels", func(db *gorm.DB) *gorm.DB {
-		return db.Order("level ASC")
+		return currentPrizeLevels(db).Order("level ASC")
 	}).First(&lotteryType, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrLotteryTypeNotFound
@@ -231,6 +305,20 @@ func (s *LotteryService) CreateLotteryType(req CreateLotteryTypeRequest) (*Lotte
 		}
 		rulesConfigJSON = string(data)
 	}
+	switch req.GameType {
+	case model.GameTypeNumberMatch:
+		if _, err := s.numberMatch.ParseConfig(rulesConfigJSON); err != nil {
+			return nil, err
+		}
+	case model.GameTypeMultiplier:
+		config, err := s.multiplier.ParseConfig(rulesConfigJSON)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.multiplier.ValidatePrizeLevels(config, req.Price, req.PrizeLevels); err != nil {
+			return nil, err
+		}
+	}
 
 	lotteryType := model.LotteryType{
 		Name:        req.Name,
@@ -241,6 +329,23 @@ func (s *LotteryService) CreateLotteryType(req CreateLotteryTypeRequest) (*Lotte
 		CoverImage:  req.CoverImage,
 		RulesConfig: rulesConfigJSON,
 		Status:      model.LotteryTypeStatusAvailable,
+		LowStockThreshold: req.LowStockThreshold,
+		WaitingRoomThreshold: req.WaitingRoomThreshold,
+		ConfirmScratch: req.ConfirmScratch,
+		ScratchDelaySeconds: req.ScratchDelaySeconds,
+		ScratchIntervalSeconds: req.ScratchIntervalSeconds,
+		AutoReplenish: req.AutoReplenish,
+		ReplenishTemplateID: req.ReplenishTemplateID,
+		ReplenishTickets: req.ReplenishTickets,
+		ScheduledStartAt: req.ScheduledStartAt,
+		ScheduledEndAt: req.ScheduledEndAt,
+		PrizeLevelVersion: 1,
+	}
+	if err := checkReplenishTemplate(s.db, lotteryType.ReplenishTemplateID); err != nil {
+		return nil, err
+	}
+	if err := applyLotteryTypeSchedule(&lotteryType, time.Now()); err != nil {
+		return nil, err
 	}
 
 	err := s.db.Transaction(func(tx *gorm.DB) error {
@@ -250,15 +355,7 @@ func (s *LotteryService) CreateLotteryType(req CreateLotteryTypeRequest) (*Lotte
 
 		// Create prize levels if provided
 		for _, pl := range req.PrizeLevels {
-			prizeLevel := model.PrizeLevel{
-				LotteryTypeID: lotteryType.ID,
-				Level:         pl.Level,
-				Name:          pl.Name,
-				PrizeAmount:   pl.PrizeAmount,
-				Quantity:      pl.Quantity,
-				Remaining:     pl.Quantity,
-			}
-			if err := tx.Create(&prizeLevel).Error; err != nil {
+			if err := createPrizeLevel(tx, lotteryType.ID, lotteryType.PrizeLevelVersion, pl); err != nil {
 				return err
 			}
 		}
@@ -309,16 +406,71 @@ func (s *LotteryService) UpdateLotteryType(id uint, req UpdateLotteryTypeRequest
 		}
 		lotteryType.RulesConfig = string(data)
 	}
+	switch lotteryType.GameType {
+	case model.GameTypeNumberMatch:
+		if _, err := s.numberMatch.ParseConfig(lotteryType.RulesConfig); err != nil {
+			return nil, err
+		}
+	case model.GameTypeMultiplier:
+		if _, err := s.multiplier.ParseConfig(lotteryType.RulesConfig); err != nil {
+			return nil, err
+		}
+	}
 	if req.DesignConfig != nil {
+		if err := req.DesignConfig.validate(); err != nil {
+			return nil, err
+		}
 		data, err := json.Marshal(req.DesignConfig)
 		if err != nil {
-			return nil, ErrInvalidPrizeConfig
+			return nil, ErrInvalidDesignConfig
 		}
 		lotteryType.DesignConfig = string(data)
 	}
 	if req.Status != nil {
 		lotteryType.Status = *req.Status
 	}
+	if req.LowStockThreshold != nil {
+		lotteryType.LowStockThreshold = *req.LowStockThreshold
+	}
+	if req.WaitingRoomThreshold != nil {
+		lotteryType.WaitingRoomThreshold = *req.WaitingRoomThreshold
+	}
+	if req.ConfirmScratch != nil {
+		lotteryType.ConfirmScratch = *req.ConfirmScratch
+	}
+	if req.ScratchDelaySeconds != nil {
+		lotteryType.ScratchDelaySeconds = *req.ScratchDelaySeconds
+	}
+	if req.ScratchIntervalSeconds != nil {
+		lotteryType.ScratchIntervalSeconds = *req.ScratchIntervalSeconds
+	}
+	if req.AutoReplenish != nil {
+		lotteryType.AutoReplenish = *req.AutoReplenish
+	}
+	if req.ReplenishTemplateID != nil {
+		if err := checkReplenishTemplate(s.db, *req.ReplenishTemplateID); err != nil {
+			return nil, err
+		}
+		lotteryType.ReplenishTemplateID = *req.ReplenishTemplateID
+	}
+	if req.ReplenishTickets != nil {
+		lotteryType.ReplenishTickets = *req.ReplenishTickets
+	}
+	if req.ClearSchedule {
+		lotteryType.ScheduledStartAt = nil
+		lotteryType.ScheduledEndAt = nil
+	}
+	if req.ScheduledStartAt != nil {
+		lotteryType.ScheduledStartAt = req.ScheduledStartAt
+	}
+	if req.ScheduledEndAt != nil {
+		lotteryType.ScheduledEndAt = req.ScheduledEndAt
+	}
+	if req.ScheduledStartAt != nil || req.ScheduledEndAt != nil {
+		if err := applyLotteryTypeSchedule(&lotteryType, time.Now()); err != nil {
+			return nil, err
+		}
+	}
 
 	if err := s.db.Save(&lotteryType).Error; err != nil {
 		return nil, err
@@ -327,7 +479,10 @@ func (s *LotteryService) UpdateLotteryType(id uint, req UpdateLotteryTypeRequest
 	return s.GetLotteryTypeByID(id)
 }
 
-// DeleteLotteryType soft deletes a lottery type
+// DeleteLotteryType soft deletes a lottery type. Deletion is refused while
+// tickets of the type are still waiting to be scratched; its open prize pools
+// are closed. The deleted type stays readable through its tickets, so
+// verification and ticket history keep showing it.
 func (s *LotteryService) DeleteLotteryType(id uint) error {
 	var lotteryType model.LotteryType
 	if err := s.db.First(&lotteryType, id).Error; err != nil {
@@ -337,13 +492,37 @@ func (s *LotteryService) DeleteLotteryType(id uint) error {
 		return err
 	}
 
-	return s.db.Delete(&lotteryType).Error
+	defer s.forgetStock(id)
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var unscratched int64
+		if err := tx.Model(&model.Ticket{}).
+			Where("lottery_type_id = ? AND status IN ?", id, unscratchedTicketStatuses).
+			Count(&unscratched).Error; err != nil {
+			return err
+		}
+		if unscratched > 0 {
+			return ErrLotteryTypeHasUnscratched
+		}
+
+		if err := tx.Model(&model.PrizePool{}).
+			Where("lottery_type_id = ? AND status = ?", id, model.PrizePoolStatusActive).
+			Update("status", model.PrizePoolStatusClosed).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&lotteryType).Error
+	})
+}
+
+// includeDeleted is a preload condition that keeps soft deleted rows, so
+// tickets still show the lottery type they were bought from after it is deleted
+func includeDeleted(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
 }
 
 // GetPrizeLevels returns prize levels for a lottery type
 func (s *LotteryService) GetPrizeLevels(lotteryTypeID uint) ([]PrizeLevelResponse, error) {
 	var prizeLevels []model.PrizeLevel
-	if err := s.db.Where("lottery_type_id = ?", lotteryTypeID).
+	if err := currentPrizeLevels(s.db).Where("lottery_type_id = ?", lotteryTypeID).
 		Order("level ASC").
 		Find(&prizeLevels).Error; err != nil {
 		return nil, err
@@ -357,7 +536,9 @@ func (s *LotteryService) GetPrizeLevels(lotteryTypeID uint) ([]PrizeLevelRespons
 	return responses, nil
 }
 
-// UpdatePrizeLevels updates prize levels for a lottery type
+// UpdatePrizeLevels updates prize levels for a lottery type. Names, payouts
+// and display fields are changed in place. Any other change starts a new version of the levels,
+// which is refused while a pool that already sold tickets is active.
 func (s *LotteryService) UpdatePrizeLevels(lotteryTypeID uint, levels []PrizeLevelInput) error {
 	// Verify lottery type exists
 	var lotteryType model.LotteryType
@@ -369,23 +550,47 @@ func (s *LotteryService) UpdatePrizeLevels(lotteryTypeID uint, levels []PrizeLev
 	}
 
 	return s.db.Transaction(func(tx *gorm.DB) error {
-		// Delete existing prize levels
-		if err := tx.Where("lottery_type_id = ?", lotteryTypeID).
-			Delete(&model.PrizeLevel{}).Error; err != nil {
+		var current []model.PrizeLevel
+		if err := tx.Where("lottery_type_id = ? AND version = ?", lotteryType.ID, lotteryType.PrizeLevelVersion).
+			Find(&current).Error; err != nil {
 			return err
 		}
 
-		// Create new prize levels
-		for _, pl := range levels {
-			prizeLevel := model.PrizeLevel{
-				LotteryTypeID: lotteryTypeID,
-				Level:         pl.Level,
-				Name:          pl.Name,
-				PrizeAmount:   pl.PrizeAmount,
-				Quantity:      pl.Quantity,
-				Remaining:     pl.Quantity,
+		// Names, payouts and display fields do not change the draw, so pools
+		// keep their levels
+		if sameLevelStructure(current, levels) {
+			byLevel := make(map[int]model.PrizeLevel, len(current))
+			for _, pl := range current {
+				byLevel[pl.Level] = pl
 			}
-			if err := tx.Create(&prizeLevel).Error; err != nil {
+			for _, input := range levels {
+				payoutType, payoutProductID, err := resolvePrizePayout(tx, input)
+				if err != nil {
+					return err
+				}
+				if err := input.validateDisplay(); err != nil {
+					return err
+				}
+				if err := tx.Model(&model.PrizeLevel{}).Where("id = ?", byLevel[input.Level].ID).
+					Updates(map[string]interface{}{
+						"name":              input.Name,
+						"payout_type":       payoutType,
+						"payout_product_id": payoutProductID,
+						"image":             input.Image,
+						"color":             input.Color,
+						"description":       input.Description,
+					}).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		if err := startPrizeLevelVersion(tx, &lotteryType); err != nil {
+			return err
+		}
+		for _, pl := range levels {
+			if err := createPrizeLevel(tx, lotteryTypeID, lotteryType.PrizeLevelVersion, pl); err != nil {
 				return err
 			}
 		}
@@ -394,6 +599,159 @@ func (s *LotteryService) UpdatePrizeLevels(lotteryTypeID uint, levels []PrizeLev
 	})
 }
 
+// sameLevelStructure reports whether prize level inputs keep the levels,
+// amounts and quantities of the current prize levels
+func sameLevelStructure(current []model.PrizeLevel, levels []PrizeLevelInput) bool {
+	if len(current) != len(levels) {
+		return false
+	}
+	byLevel := make(map[int]model.PrizeLevel, len(current))
+	for _, pl := range current {
+		byLevel[pl.Level] = pl
+	}
+	seen := make(map[int]bool, len(levels))
+	for _, input := range levels {
+		pl, ok := byLevel[input.Level]
+		if !ok || seen[input.Level] || pl.PrizeAmount != input.PrizeAmount || pl.Quantity != input.Quantity {
+			return false
+		}
+		seen[input.Level] = true
+	}
+	return true
+}
+
+// startPrizeLevelVersion starts a new, empty version of the prize levels of a
+// lottery type within the caller's transaction. Pools that sold tickets keep
+// drawing from the version they were opened with, so a new version is refused
+// while such a pool is active. Active pools without sales move to the new version.
+func startPrizeLevelVersion(tx *gorm.DB, lotteryType *model.LotteryType) error {
+	var selling int64
+	if err := tx.Model(&model.PrizePool{}).
+		Where("lottery_type_id = ? AND status = ? AND sold_tickets > 0", lotteryType.ID, model.PrizePoolStatusActive).
+		Count(&selling).Error; err != nil {
+		return err
+	}
+	if selling > 0 {
+		return ErrPrizeLevelsInUse
+	}
+
+	version := lotteryType.PrizeLevelVersion + 1
+	if err := tx.Model(&model.LotteryType{}).Where("id = ?", lotteryType.ID).
+		Update("prize_level_version", version).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&model.PrizePool{}).
+		Where("lottery_type_id = ? AND status = ?", lotteryType.ID, model.PrizePoolStatusActive).
+		Update("prize_level_version", version).Error; err != nil {
+		return err
+	}
+	lotteryType.PrizeLevelVersion = version
+	return nil
+}
+
+// currentPrizeLevels restricts a prize level query to the current version of
+// the prize levels of each lottery type
+func currentPrizeLevels(db *gorm.DB) *gorm.DB {
+	return db.Where("prize_levels.version = (SELECT prize_level_version FROM lottery_types WHERE lottery_types.id = prize_levels.lottery_type_id)")
+}
+
+// poolPrizeLevels restricts a prize level query to the levels a prize pool draws from
+func poolPrizeLevels(db *gorm.DB, pool *model.PrizePool) *gorm.DB {
+	return db.Where("lottery_type_id = ? AND version = ?", pool.LotteryTypeID, pool.PrizeLevelVersion)
+}
+
+// createPrizeLevel creates a prize level in a version of the prize levels of
+// a lottery type
+func createPrizeLevel(tx *gorm.DB, lotteryTypeID uint, version int, input PrizeLevelInput) error {
+	payoutType, payoutProductID, err := resolvePrizePayout(tx, input)
+	if err != nil {
+		return err
+	}
+	if err := input.validateDisplay(); err != nil {
+		return err
+	}
+	prizeLevel := model.PrizeLevel{
+		LotteryTypeID:   lotteryTypeID,
+		Version:         version,
+		Level:           input.Level,
+		Name:            input.Name,
+		PrizeAmount:     input.PrizeAmount,
+		Quantity:        input.Quantity,
+		Remaining:       input.Quantity,
+		PayoutType:      payoutType,
+		PayoutProductID: payoutProductID,
+		Image:           input.Image,
+		Color:           input.Color,
+		Description:     input.Description,
+	}
+	return tx.Create(&prizeLevel).Error
+}
+
+// maxPrizeLevelDescriptionLength bounds the marketing copy of a prize level
+const maxPrizeLevelDescriptionLength = 256
+
+// validateDisplay checks the image, color and marketing copy of a prize level
+// input, which are all optional
+func (input PrizeLevelInput) validateDisplay() error {
+	if input.Image != "" && (len(input.Image) > 512 || !isBrandingURL(input.Image)) {
+		return ErrInvalidPrizeLevelDisplay
+	}
+	if input.Color != "" && !brandingColorPattern.MatchString(input.Color) {
+		return ErrInvalidPrizeLevelDisplay
+	}
+	if utf8.RuneCountInString(input.Description) > maxPrizeLevelDescriptionLength {
+		return ErrInvalidPrizeLevelDisplay
+	}
+	return nil
+}
+
+// resolvePrizePayout validates the payout of a prize level input. Product
+// payouts must name an exchange product of the tenant.
+func resolvePrizePayout(tx *gorm.DB, input PrizeLevelInput) (model.PrizePayoutType, uint, error) {
+	switch input.PayoutType {
+	case "", model.PrizePayoutPoints:
+		return model.PrizePayoutPoints, 0, nil
+	case model.PrizePayoutProduct:
+		if input.PayoutProductID == 0 || input.PrizeAmount <= 0 {
+			return "", 0, ErrInvalidPrizePayout
+		}
+		var product model.Product
+		if err := tx.First(&product, input.PayoutProductID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return "", 0, ErrInvalidPrizePayout
+			}
+			return "", 0, err
+		}
+		return model.PrizePayoutProduct, product.ID, nil
+	default:
+		return "", 0, ErrInvalidPrizePayout
+	}
+}
+
+// prizePayoutProduct returns the exchange product a prize level of a pool
+// pays out, or zero when the level pays points
+func prizePayoutProduct(db *gorm.DB, prizePoolID uint, level int) (uint, error) {
+	if level <= 0 {
+		return 0, nil
+	}
+	var prizePool model.PrizePool
+	if err := db.First(&prizePool, prizePoolID).Error; err != nil {
+		return 0, err
+	}
+	var prizeLevel model.PrizeLevel
+	err := poolPrizeLevels(db, &prizePool).Where("level = ?", level).First(&prizeLevel).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if prizeLevel.PayoutType != model.PrizePayoutProduct {
+		return 0, nil
+	}
+	return prizeLevel.PayoutProductID, nil
+}
+
 // CreatePrizePool creates a new prize pool for a lottery type
 func (s *LotteryService) CreatePrizePool(req CreatePrizePoolRequest) (*PrizePoolResponse, error) {
 	// Verify lottery type exists
@@ -406,17 +764,19 @@ func (s *LotteryService) CreatePrizePool(req CreatePrizePoolRequest) (*PrizePool
 	}
 
 	prizePool := model.PrizePool{
-		LotteryTypeID: req.LotteryTypeID,
-		TotalTickets:  req.TotalTickets,
-		SoldTickets:   0,
-		ClaimedPrizes: 0,
-		ReturnRate:    req.ReturnRate,
-		Status:        model.PrizePoolStatusActive,
+		LotteryTypeID:     req.LotteryTypeID,
+		TotalTickets:      req.TotalTickets,
+		SoldTickets:       0,
+		ClaimedPrizes:     0,
+		ReturnRate:        req.ReturnRate,
+		Status:            model.PrizePoolStatusActive,
+		PrizeLevelVersion: lotteryType.PrizeLevelVersion,
 	}
 
 	if err := s.db.Create(&prizePool).Error; err != nil {
 		return nil, err
 	}
+	s.forgetStock(req.LotteryTypeID)
 
 	return s.toPrizePoolResponse(&prizePool), nil
 }
@@ -452,14 +812,59 @@ func (s *LotteryService) GetPrizePools(lotteryTypeID uint) ([]PrizePoolResponse,
 	return responses, nil
 }
 
-// calculateStock calculates available stock for a lottery type
+// calculateStock calculates available stock for a lottery type, net of the
+// tickets held by live stock reservations. With a stock counter the result
+// may lag behind other instances until the counter is reconciled; checks
+// that must be exact use availableStock.
 func (s *LotteryService) calculateStock(lotteryTypeID uint) int {
+	if s.stock != nil {
+		return s.stock.Stock(lotteryTypeID)
+	}
+	return availableStock(s.db, lotteryTypeID, 0)
+}
+
+// recordSale counts tickets sold by a committed purchase on the stock counter
+func (s *LotteryService) recordSale(lotteryTypeID uint, quantity int) {
+	if s.stock != nil {
+		s.stock.Sell(lotteryTypeID, quantity)
+	}
+}
+
+// forgetStock drops the stock counter of a lottery type after its pools changed
+func (s *LotteryService) forgetStock(lotteryTypeID uint) {
+	if s.stock != nil {
+		s.stock.Forget(lotteryTypeID)
+	}
+}
+
+// calculateStockFor calculates the stock available to a user, counting the
+// tickets held by the user's own reservation as available
+func (s *LotteryService) calculateStockFor(userID, lotteryTypeID uint) int {
+	return availableStock(s.db, lotteryTypeID, userID)
+}
+
+// availableStock returns the unsold tickets of the active pool of a lottery
+// type minus those held by unexpired reservations of users other than userID
+func availableStock(db *gorm.DB, lotteryTypeID, userID uint) int {
 	var prizePool model.PrizePool
-	if err := s.db.Where("lottery_type_id = ? AND status = ?", lotteryTypeID, model.PrizePoolStatusActive).
+	if err := db.Where("lottery_type_id = ? AND status = ?", lotteryTypeID, model.PrizePoolStatusActive).
 		First(&prizePool).Error; err != nil {
 		return 0
 	}
-	return prizePool.TotalTickets - prizePool.SoldTickets
+
+	var reserved int64
+	if err := db.Model(&model.StockReservation{}).
+		Where("lottery_type_id = ? AND user_id <> ? AND expires_at > ?", lotteryTypeID, userID, time.Now()).
+		Select("COALESCE(SUM(quantity), 0)").
+		Scan(&reserved).Error; err != nil {
+		return 0
+	}
+
+	stock := prizePool.TotalTickets - prizePool.SoldTickets - int(reserved)
+	if stock < 0 {
+		return 0
+	}
+	return stock
 }
 
 // Helper functions
@@ -475,6 +880,17 @@ func (s *LotteryService) toLotteryTypeResponse(lt *model.LotteryType, stock int)
 		CoverImage:  lt.CoverImage,
 		Status:      lt.Status,
 		Stock:       stock,
+		LowStockThreshold: lt.LowStockThreshold,
+		WaitingRoomThreshold: lt.WaitingRoomThreshold,
+		ConfirmScratch: lt.ConfirmScratch,
+		ScratchDelaySeconds: lt.ScratchDelaySeconds,
+		ScratchIntervalSeconds: lt.ScratchIntervalSeconds,
+		AutoReplenish: lt.AutoReplenish,
+		ReplenishTemplateID: lt.ReplenishTemplateID,
+		ReplenishTickets: lt.ReplenishTickets,
+		ScheduledStartAt: lt.ScheduledStartAt,
+		ScheduledEndAt: lt.ScheduledEndAt,
+		Archived:    lt.DeletedAt.Valid,
 		CreatedAt:   lt.CreatedAt,
 		UpdatedAt:   lt.UpdatedAt,
 	}
@@ -490,10 +906,7 @@ func (s *LotteryService) toLotteryTypeDetailResponse(lt *model.LotteryType) *Lot
 	}
 
 	// Parse design config
-	var designConfig interface{}
-	if lt.DesignConfig != "" {
-		_ = json.Unmarshal([]byte(lt.DesignConfig), &designConfig)
-	}
+	designConfig := parseDesignConfig(lt.DesignConfig)
 
 	// Parse win symbols from rules config
 	var winSymbols []string
@@ -512,14 +925,7 @@ func (s *LotteryService) toLotteryTypeDetailResponse(lt *model.LotteryType) *Lot
 	// Convert prize levels
 	prizeLevels := make([]PrizeLevelResponse, len(lt.PrizeLevels))
 	for i, pl := range lt.PrizeLevels {
-		prizeLevels[i] = PrizeLevelResponse{
-			ID:          pl.ID,
-			Level:       pl.Level,
-			Name:        pl.Name,
-			PrizeAmount: pl.PrizeAmount,
-			Quantity:    pl.Quantity,
-			Remaining:   pl.Remaining,
-		}
+		prizeLevels[i] = s.toPrizeLevelResponse(&pl)
 	}
 
 	return &LotteryTypeDetailResponse{
@@ -533,6 +939,16 @@ func (s *LotteryService) toLotteryTypeDetailResponse(lt *model.LotteryType) *Lot
 			CoverImage:  lt.CoverImage,
 			Status:      lt.Status,
 			Stock:       stock,
+			LowStockThreshold: lt.LowStockThreshold,
+			WaitingRoomThreshold: lt.WaitingRoomThreshold,
+			ConfirmScratch: lt.ConfirmScratch,
+			ScratchDelaySeconds: lt.ScratchDelaySeconds,
+			ScratchIntervalSeconds: lt.ScratchIntervalSeconds,
+			AutoReplenish: lt.AutoReplenish,
+			ReplenishTemplateID: lt.ReplenishTemplateID,
+			ReplenishTickets: lt.ReplenishTickets,
+			ScheduledStartAt: lt.ScheduledStartAt,
+			ScheduledEndAt: lt.ScheduledEndAt,
 			CreatedAt:   lt.CreatedAt,
 			UpdatedAt:   lt.UpdatedAt,
 		},
@@ -545,26 +961,36 @@ func (s *LotteryService) toLotteryTypeDetailResponse(lt *model.LotteryType) *Lot
 }
 
 func (s *LotteryService) toPrizeLevelResponse(pl *model.PrizeLevel) PrizeLevelResponse {
+	payoutType := pl.PayoutType
+	if payoutType == "" {
+		payoutType = model.PrizePayoutPoints
+	}
 	return PrizeLevelResponse{
-		ID:          pl.ID,
-		Level:       pl.Level,
-		Name:        pl.Name,
-		PrizeAmount: pl.PrizeAmount,
-		Quantity:    pl.Quantity,
-		Remaining:   pl.Remaining,
+		ID:              pl.ID,
+		Level:           pl.Level,
+		Name:            pl.Name,
+		PrizeAmount:     pl.PrizeAmount,
+		Quantity:        pl.Quantity,
+		Remaining:       pl.Remaining,
+		PayoutType:      payoutType,
+		PayoutProductID: pl.PayoutProductID,
+		Image:           pl.Image,
+		Color:           pl.Color,
+		Description:     pl.Description,
 	}
 }
 
 func (s *LotteryService) toPrizePoolResponse(pp *model.PrizePool) *PrizePoolResponse {
 	return &PrizePoolResponse{
-		ID:            pp.ID,
-		LotteryTypeID: pp.LotteryTypeID,
-		TotalTickets:  pp.TotalTickets,
-		SoldTickets:   pp.SoldTickets,
-		ClaimedPrizes: pp.ClaimedPrizes,
-		ReturnRate:    pp.ReturnRate,
-		Status:        pp.Status,
-		CreatedAt:     pp.CreatedAt,
+		ID:                pp.ID,
+		LotteryTypeID:     pp.LotteryTypeID,
+		TotalTickets:      pp.TotalTickets,
+		SoldTickets:       pp.SoldTickets,
+		ClaimedPrizes:     pp.ClaimedPrizes,
+		ReturnRate:        pp.ReturnRate,
+		Status:            pp.Status,
+		PrizeLevelVersion: pp.PrizeLevelVersion,
+		CreatedAt:         pp.CreatedAt,
 	}
 }
 
@@ -589,26 +1015,41 @@ type TicketContent struct {
 	WinSymbols  []string    `json:"win_symbols,omitempty"`
 	Areas       []AreaData  `json:"areas,omitempty"`
 	GameData    interface{} `json:"game_data,omitempty"`
+	Jackpot     int         `json:"jackpot,omitempty"` // Jackpot won on top of PrizeAmount
+
+	jackpotDrawn bool // Whether the ticket hit the jackpot outcome
 }
 
 // AreaData represents data for each scratch area
 type AreaData struct {
-	Index   int         `json:"index"`
-	Content interface{} `json:"content"`
-	Value   int         `json:"value,omitempty"`
+	Index      int         `json:"index"`
+	Content    interface{} `json:"content"`
+	Value      int         `json:"value,omitempty"`
+	Kind       string      `json:"kind,omitempty"`       // Role of the area in games that have several, such as number match
+	Prize      int         `json:"prize,omitempty"`      // Prize printed on the area, won only if the area matches
+	Multiplier int         `json:"multiplier,omitempty"` // Multiple of the printed prize won by a multiplier area
 }
 
 // PurchaseRequest represents a ticket purchase request
 type PurchaseRequest struct {
 	LotteryTypeID uint `json:"lottery_type_id" binding:"required"`
 	Quantity      int  `json:"quantity" binding:"required,min=1,max=10"`
+	AdmissionToken string `json:"admission_token"` // Waiting room token, required while the lottery type is protected
+	CouponID      uint `json:"coupon_id"`         // Optional coupon taken off the cost
+	Reserve       bool `json:"reserve"`           // Preview only: hold the tickets until the purchase
+	RequestID     string `json:"request_id"`      // Optional idempotency key; a retry with the same ID returns the original response
 }
 
 // PurchaseResponse represents the response after purchasing tickets
 type PurchaseResponse struct {
-	Tickets []TicketResponse `json:"tickets"`
-	Cost    int              `json:"cost"`
-	Balance int              `json:"balance"`
+	Tickets      []TicketResponse         `json:"tickets"`
+	Cost         int                      `json:"cost"`
+	Discount     int                      `json:"discount,omitempty"`
+	Balance      int                      `json:"balance"`
+	BonusSpent   int                      `json:"bonus_spent,omitempty"` // Part of the cost paid with bonus credits
+	BonusBalance int                      `json:"bonus_balance"`
+	Rewards      []CampaignRewardResponse `json:"rewards,omitempty"`  // Campaign rewards for a first purchase
+	Replayed     bool                     `json:"replayed,omitempty"` // The response of an earlier request with the same request ID
 }
 
 // SecurityCodeCharset defines the characters used for security codes
@@ -710,9 +1151,9 @@ func (s *LotteryService) DeterminePrizeResult(prizePoolID uint) (*TicketContent,
 		return nil, ErrPrizePoolNotFound
 	}
 
-	// Get prize levels for this lottery type
+	// Get the prize levels the pool draws from
 	var prizeLevels []model.PrizeLevel
-	if err := s.db.Where("lottery_type_id = ?", prizePool.LotteryTypeID).
+	if err := poolPrizeLevels(s.db, &prizePool).
 		Order("level ASC").
 		Find(&prizeLevels).Error; err != nil {
 		return nil, err
@@ -764,6 +1205,11 @@ func (s *LotteryService) DeterminePrizeResult(prizePoolID uint) (*TicketContent,
 		content.PrizeAmount = 0
 	}
 
+	// Rarely the ticket wins the progressive jackpot on top of its prize
+	if content.jackpotDrawn, err = drawJackpot(s.db); err != nil {
+		return nil, err
+	}
+
 	return content, nil
 }
 
@@ -900,6 +1346,7 @@ func (s *LotteryService) DeterminePrizeResultForPatternLottery(prizePoolID uint,
 		GameData: map[string]interface{}{
 			"pattern_content": patternContent,
 		},
+		jackpotDrawn: baseContent.jackpotDrawn,
 	}
 
 	return content, nil
@@ -940,8 +1387,32 @@ type PatternAreaData struct {
 	IsSpecial bool   `json:"is_special"`
 }
 
-// GenerateTicket generates a new ticket for a user
+// GenerateTicket generates a new ticket bought by a user
 func (s *LotteryService) GenerateTicket(userID, lotteryTypeID uint) (*model.Ticket, error) {
+	return s.generateTicket(userID, lotteryTypeID, model.TicketHistoryPurchase, 0)
+}
+
+// GeneratePurchasedTicket generates a new ticket for a user paid by the
+// purchase purchaseID, the transaction the purchase's charges reference
+func (s *LotteryService) GeneratePurchasedTicket(userID, lotteryTypeID, purchaseID uint) (*model.Ticket, error) {
+	return s.generateTicket(userID, lotteryTypeID, model.TicketHistoryPurchase, purchaseID)
+}
+
+// GenerateGiftTicket generates a new ticket given to a user for free by the
+// campaign reward referenceID
+func (s *LotteryService) GenerateGiftTicket(userID, lotteryTypeID, referenceID uint) (*model.Ticket, error) {
+	return s.generateTicket(userID, lotteryTypeID, model.TicketHistoryGift, referenceID)
+}
+
+// GenerateVoucherTicket generates an unassigned ticket to be sold offline as
+// a voucher and claimed later with its security code
+func (s *LotteryService) GenerateVoucherTicket(lotteryTypeID uint) (*model.Ticket, error) {
+	return s.generateTicket(0, lotteryTypeID, model.TicketHistoryVoucher, 0)
+}
+
+// generateTicket generates a new ticket for a user, or an unassigned one for
+// user 0, and records how it was issued
+func (s *LotteryService) generateTicket(userID, lotteryTypeID uint, event model.TicketHistoryEvent, referenceID uint) (*model.Ticket, error) {
 	// Get active prize pool
 	var prizePool model.PrizePool
 	if err := s.db.Where("lottery_type_id = ? AND status = ?", lotteryTypeID, model.PrizePoolStatusActive).
@@ -971,10 +1442,17 @@ func (s *LotteryService) GenerateTicket(userID, lotteryTypeID uint) (*model.Tick
 
 	// Determine prize result based on game type
 	var content *TicketContent
-	if lotteryType.GameType == model.GameTypePattern {
+	switch lotteryType.GameType {
+	case model.GameTypePattern:
 		// Use pattern lottery logic
 		content, err = s.DeterminePrizeResultForPatternLottery(prizePool.ID, lotteryTypeID)
-	} else {
+	case model.GameTypeNumberMatch:
+		// Lay out the numbers around the prize
+		content, err = s.determineNumberMatchResult(&prizePool, &lotteryType)
+	case model.GameTypeMultiplier:
+		// Reveal the prize as a multiple of the price
+		content, err = s.determineMultiplierResult(&prizePool, &lotteryType)
+	default:
 		// Use standard lottery logic
 		content, err = s.DeterminePrizeResult(prizePool.ID)
 	}
@@ -982,40 +1460,82 @@ func (s *LotteryService) GenerateTicket(userID, lotteryTypeID uint) (*model.Tick
 		return nil, err
 	}
 
-	// Encrypt content
-	encryptedContent, err := s.EncryptTicketContent(content)
-	if err != nil {
-		return nil, err
-	}
-
 	// Create ticket
 	ticket := &model.Ticket{
-		UserID:           userID,
-		LotteryTypeID:    lotteryTypeID,
-		PrizePoolID:      prizePool.ID,
-		SecurityCode:     securityCode,
-		ContentEncrypted: encryptedContent,
-		PrizeAmount:      content.PrizeAmount,
-		Status:           model.TicketStatusUnscratched,
-		PurchasedAt:      time.Now(),
+		UserID:        userID,
+		LotteryTypeID: lotteryTypeID,
+		PrizePoolID:   prizePool.ID,
+		SecurityCode:  securityCode,
+		Status:        model.TicketStatusUnscratched,
+		PurchasedAt:   time.Now(),
+	}
+	if event == model.TicketHistoryPurchase {
+		ticket.PurchaseID = referenceID
 	}
 
 	// Use transaction to ensure consistency
-	err = s.db.Transaction(func(tx *gorm.DB) error {
-		// Create ticket
-		if err := tx.Create(ticket).Error; err != nil {
+	err = repository.Transaction(s.db, func(tx *gorm.DB) error {
+		// Pay out the jackpot to a ticket that hit it; voucher tickets
+		// without an owner yet do not take part
+		content.Jackpot = 0
+		if content.jackpotDrawn && userID != 0 {
+			won, err := claimJackpot(tx, userID)
+			if err != nil {
+				return err
+			}
+			content.Jackpot = won
+		}
+		ticket.PrizeAmount = content.PrizeAmount + content.Jackpot
+
+		// Encrypt content
+		encryptedContent, err := s.EncryptTicketContent(content)
+		if err != nil {
 			return err
 		}
+		ticket.ContentEncrypted = encryptedContent
 
-		// Update prize pool sold count
-		if err := tx.Model(&prizePool).Update("sold_tickets", gorm.Expr("sold_tickets + 1")).Error; err != nil {
+		// Create ticket; a retried attempt inserts it afresh
+		ticket.ID = 0
+		if err := tx.Create(ticket).Error; err != nil {
+			return err
+		}
+		history := model.TicketHistory{
+			TicketID:    ticket.ID,
+			Event:       event,
+			ToUserID:    userID,
+			ReferenceID: referenceID,
+		}
+		if err := tx.Create(&history).Error; err != nil {
+			return err
+		}
+		if err := appendTicketEvent(tx, ticket, model.TicketEventGenerated, userID, model.TicketStatusUnscratched, 0, ""); err != nil {
+			return err
+		}
+		if event == model.TicketHistoryPurchase {
+			if err := appendTicketEvent(tx, ticket, model.TicketEventPurchased, userID, "", 0, ""); err != nil {
+				return err
+			}
+		}
+		if err := adjustBadge(tx, userID, badgeUnscratchedTickets, 1); err != nil {
 			return err
 		}
 
+		// Update prize pool sold count, guarded against concurrent buyers
+		// taking the last tickets meanwhile
+		result := tx.Model(&model.PrizePool{}).
+			Where("id = ? AND sold_tickets + ? <= total_tickets", prizePool.ID, 1).
+			Update("sold_tickets", gorm.Expr("sold_tickets + ?", 1))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrLotteryTypeSoldOut
+		}
+
 		// Update prize level remaining count if won
 		if content.PrizeLevel > 0 {
-			if err := tx.Model(&model.PrizeLevel{}).
-				Where("lottery_type_id = ? AND level = ?", lotteryTypeID, content.PrizeLevel).
+			if err := poolPrizeLevels(tx.Model(&model.PrizeLevel{}), &prizePool).
+				Where("level = ?", content.PrizeLevel).
 				Update("remaining", gorm.Expr("remaining - 1")).Error; err != nil {
 				return err
 			}
@@ -1045,7 +1565,7 @@ func (s *LotteryService) GenerateTicket(userID, lotteryTypeID uint) (*model.Tick
 // GetTicketByID retrieves a ticket by ID
 func (s *LotteryService) GetTicketByID(ticketID uint) (*model.Ticket, error) {
 	var ticket model.Ticket
-	if err := s.db.Preload("LotteryType").First(&ticket, ticketID).Error; err != nil {
+	if err := s.db.Preload("LotteryType", includeDeleted).First(&ticket, ticketID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrTicketNotFound
 		}
@@ -1057,7 +1577,7 @@ func (s *LotteryService) GetTicketByID(ticketID uint) (*model.Ticket, error) {
 // GetTicketBySecurityCode retrieves a ticket by security code
 func (s *LotteryService) GetTicketBySecurityCode(code string) (*model.Ticket, error) {
 	var ticket model.Ticket
-	if err := s.db.Preload("LotteryType").Where("security_code = ?", code).First(&ticket).Error; err != nil {
+	if err := s.db.Preload("LotteryType", includeDeleted).Where("security_code = ?", code).First(&ticket).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrTicketNotFound
 		}
@@ -1091,7 +1611,12 @@ func (s *LotteryService) VerifySecurityCode(code string) (*VerifySecurityCodeRes
 		return nil, err
 	}
 
-	// Build response - hide prize info if not scratched (Requirement 7.4)
+	return buildVerifyResponse(ticket), nil
+}
+
+// buildVerifyResponse builds the public verification view of a ticket,
+// hiding prize info if the ticket has not been scratched (Requirement 7.4)
+func buildVerifyResponse(ticket *model.Ticket) *VerifySecurityCodeResponse {
 	resp := &VerifySecurityCodeResponse{
 		SecurityCode: ticket.SecurityCode,
 		LotteryType:  ticket.LotteryType.Name,
@@ -1100,12 +1625,82 @@ func (s *LotteryService) VerifySecurityCode(code string) (*VerifySecurityCodeRes
 	}
 
 	// Only show prize if scratched or claimed (Requirement 7.4)
-	if ticket.Status == model.TicketStatusScratched || ticket.Status == model.TicketStatusClaimed {
+	if ticket.Status.Revealed() {
 		resp.PrizeAmount = &ticket.PrizeAmount
 		resp.ScratchedAt = ticket.ScratchedAt
 	}
 
-	return resp, nil
+	return resp
+}
+
+// Batch verification result codes
+const (
+	VerifyResultOK          = "ok"
+	VerifyResultNotFound    = "not_found"
+	VerifyResultInvalidCode = "invalid_code"
+)
+
+// DefaultVerifyBatchMaxCodes is the default maximum number of codes per batch verification
+const DefaultVerifyBatchMaxCodes = 50
+
+// BatchVerifyResult represents the verification result of a single code in a batch
+type BatchVerifyResult struct {
+	Code   string                      `json:"code"`
+	Result string                      `json:"result"`
+	Ticket *VerifySecurityCodeResponse `json:"ticket,omitempty"`
+}
+
+// VerifySecurityCodes verifies many security codes at once.
+// Results are returned in request order and follow the same hiding rules as
+// VerifySecurityCode; invalid or unknown codes are reported per code instead of
+// failing the whole batch.
+func (s *LotteryService) VerifySecurityCodes(codes []string, maxCodes int) ([]BatchVerifyResult, error) {
+	if maxCodes <= 0 {
+		maxCodes = DefaultVerifyBatchMaxCodes
+	}
+	if len(codes) == 0 {
+		return nil, ErrEmptyVerifyBatch
+	}
+	if len(codes) > maxCodes {
+		return nil, ErrVerifyBatchTooLarge
+	}
+
+	// Collect well-formed codes for a single lookup
+	lookup := make([]string, 0, len(codes))
+	for _, code := range codes {
+		if len(code) == SecurityCodeLength {
+			lookup = append(lookup, code)
+		}
+	}
+
+	tickets := make(map[string]*model.Ticket, len(lookup))
+	if len(lookup) > 0 {
+		var found []model.Ticket
+		if err := s.db.Preload("LotteryType", includeDeleted).Where("security_code IN ?", lookup).Find(&found).Error; err != nil {
+			return nil, err
+		}
+		for i := range found {
+			tickets[found[i].SecurityCode] = &found[i]
+		}
+	}
+
+	results := make([]BatchVerifyResult, len(codes))
+	for i, code := range codes {
+		results[i].Code = code
+		if len(code) != SecurityCodeLength {
+			results[i].Result = VerifyResultInvalidCode
+			continue
+		}
+		ticket, ok := tickets[code]
+		if !ok {
+			results[i].Result = VerifyResultNotFound
+			continue
+		}
+		results[i].Result = VerifyResultOK
+		results[i].Ticket = buildVerifyResponse(ticket)
+	}
+
+	return results, nil
 }
 
 // IsTicketScratched checks if a ticket has been scratched
@@ -1133,7 +1728,7 @@ func (s *LotteryService) GetUserTickets(userID uint, page, limit int) ([]TicketR
 
 	var tickets []model.Ticket
 	offset := (page - 1) * limit
-	if err := s.db.Preload("LotteryType").
+	if err := s.db.Preload("LotteryType", includeDeleted).
 		Where("user_id = ?", userID).
 		Order("purchased_at DESC").
 		Offset(offset).
@@ -1144,7 +1739,7 @@ func (s *LotteryService) GetUserTickets(userID uint, page, limit int) ([]TicketR
 
 	responses := make([]TicketResponse, len(tickets))
 	for i, t := range tickets {
-		responses[i] = s.toTicketResponse(&t, t.Status == model.TicketStatusScratched || t.Status == model.TicketStatusClaimed)
+		responses[i] = s.toTicketResponse(&t, t.Status.Revealed())
 	}
 
 	return responses, total, nil
@@ -1178,22 +1773,117 @@ func (s *LotteryService) toTicketResponse(ticket *model.Ticket, showPrize bool)
 
 // PurchaseService handles ticket purchase operations
 type PurchaseService struct {
-	db             *gorm.DB
-	lotteryService *LotteryService
-	walletService  *WalletService
+	db              *gorm.DB
+	lotteryService  *LotteryService
+	walletService   *WalletService
+	oddsHintService *OddsHintService
+	campaignService *CampaignService
+	reservations    *StockReservationService
+	events          *LiveEventBus
+	onboarding      *OnboardingService
+	refunds         *RefundService
 }
 
-// NewPurchaseService creates a new purchase service
-func NewPurchaseService(db *gorm.DB, lotteryService *LotteryService, walletService *WalletService) *PurchaseService {
+// NewPurchaseService creates a new purchase service. oddsHintService may be
+// nil, in which case previews carry no odds hints; campaignService may be
+// nil, in which case first purchases are not rewarded; reservations may be
+// nil, in which case previews never hold stock.
+func NewPurchaseService(db *gorm.DB, lotteryService *LotteryService, walletService *WalletService, oddsHintService *OddsHintService, campaignService *CampaignService, reservations *StockReservationService) *PurchaseService {
 	return &PurchaseService{
-		db:             db,
-		lotteryService: lotteryService,
-		walletService:  walletService,
+		db:              db,
+		lotteryService:  lotteryService,
+		walletService:   walletService,
+		oddsHintService: oddsHintService,
+		campaignService: campaignService,
+		reservations:    reservations,
+	}
+}
+
+// UseEvents publishes balance changes and sell-outs of purchases to events
+func (s *PurchaseService) UseEvents(events *LiveEventBus) {
+	s.events = events
+}
+
+// UseOnboarding completes the first purchase step of the onboarding checklist
+func (s *PurchaseService) UseOnboarding(onboarding *OnboardingService) {
+	s.onboarding = onboarding
+}
+
+// UseRefunds records failed purchases with refunds, which refunds charges
+// kept without tickets
+func (s *PurchaseService) UseRefunds(refunds *RefundService) {
+	s.refunds = refunds
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *PurchaseService) ForTenant(tenantID uint) *PurchaseService {
+	scoped := &PurchaseService{
+		db:             repository.ScopeTenant(s.db, tenantID),
+		lotteryService: s.lotteryService.ForTenant(tenantID),
+		walletService:  s.walletService.ForTenant(tenantID),
+	}
+	if s.oddsHintService != nil {
+		scoped.oddsHintService = s.oddsHintService.ForTenant(tenantID)
+	}
+	if s.campaignService != nil {
+		scoped.campaignService = s.campaignService.ForTenant(tenantID)
+	}
+	if s.reservations != nil {
+		scoped.reservations = s.reservations.ForTenant(tenantID)
+	}
+	if s.events != nil {
+		scoped.events = s.events.ForTenant(tenantID)
+	}
+	if s.onboarding != nil {
+		scoped.onboarding = s.onboarding.ForTenant(tenantID)
+	}
+	if s.refunds != nil {
+		scoped.refunds = s.refunds.ForTenant(tenantID)
+	}
+	return scoped
+}
+
+// WithRequest returns a copy of the service whose logs carry the request id
+// of ctx
+func (s *PurchaseService) WithRequest(ctx context.Context) *PurchaseService {
+	scoped := *s
+	scoped.db = repository.ScopeRequest(s.db, ctx)
+	return &scoped
+}
+
+// purchaseCost returns the price of quantity tickets in points
+func purchaseCost(price, quantity int) (int, error) {
+	cost, err := money.New(int64(price), money.Points).Mul(int64(quantity))
+	if err != nil {
+		return 0, ErrInvalidAmount
 	}
+	return int(cost.Amount), nil
 }
 
-// PurchaseTickets purchases tickets for a user
+// PurchaseTickets purchases tickets for a user. A purchase with a request ID
+// is made once; repeating the request returns the original response.
 func (s *PurchaseService) PurchaseTickets(userID uint, req PurchaseRequest) (*PurchaseResponse, error) {
+	if req.RequestID == "" {
+		return s.purchaseTickets(userID, req, nil)
+	}
+
+	replay, err := s.beginPurchaseRequest(userID, req)
+	if err != nil || replay != nil {
+		return replay, err
+	}
+	resp, err := s.purchaseTickets(userID, req, nil)
+	if err != nil {
+		s.abandonPurchaseRequest(userID, req.RequestID)
+		return nil, err
+	}
+	s.completePurchaseRequest(userID, req.RequestID, resp)
+	return resp, nil
+}
+
+// purchaseTickets makes a purchase. settle, when given, runs in the
+// transaction of the purchase with the tickets bought, so its failure undoes
+// the purchase.
+func (s *PurchaseService) purchaseTickets(userID uint, req PurchaseRequest, settle func(tx *gorm.DB, tickets []*model.Ticket) error) (*PurchaseResponse, error) {
 	// Get lottery type to check price
 	lotteryType, err := s.lotteryService.GetLotteryTypeByID(req.LotteryTypeID)
 	if err != nil {
@@ -1209,10 +1899,24 @@ func (s *PurchaseService) PurchaseTickets(userID uint, req PurchaseRequest) (*Pu
 	}
 
 	// Calculate total cost
-	totalCost := lotteryType.Price * req.Quantity
+	totalCost, err := purchaseCost(lotteryType.Price, req.Quantity)
+	if err != nil {
+		return nil, err
+	}
 
-	// Check user balance
-	balance, err := s.walletService.GetBalance(userID)
+	// Take the coupon off the cost; a coupon worth more than the purchase is used up
+	discount := 0
+	if req.CouponID != 0 {
+		coupon, err := usableCoupon(s.db, userID, req.CouponID)
+		if err != nil {
+			return nil, err
+		}
+		discount = min(coupon.Discount, totalCost)
+		totalCost -= discount
+	}
+
+	// Check user balance; bonus credits pay for tickets too
+	balance, err := s.walletService.GetTicketBalance(userID)
 	if err != nil {
 		return nil, err
 	}
@@ -1221,43 +1925,158 @@ func (s *PurchaseService) PurchaseTickets(userID uint, req PurchaseRequest) (*Pu
 		return nil, ErrInsufficientBalance
 	}
 
-	// Check stock availability
-	if lotteryType.Stock < req.Quantity {
-		return nil, ErrLotteryTypeSoldOut
+	// Redeem the coupon, deduct the cost and generate the tickets in one
+	// transaction, so a failure part way through refunds the purchase
+	description := fmt.Sprintf("购买彩票: %s x%d", lotteryType.Name, req.Quantity)
+	if discount > 0 {
+		description += fmt.Sprintf("（优惠券抵扣 %d）", discount)
+	}
+	tickets := make([]TicketResponse, 0, req.Quantity)
+	var payment TicketPayment
+	err = repository.Transaction(s.db, func(tx *gorm.DB) error {
+		tickets, payment = tickets[:0], TicketPayment{}
+
+		// Check stock availability; tickets the user reserved in the preview
+		// count as available. The sold count is only raised while it stays
+		// within the pool, so concurrent buyers cannot oversell it.
+		if availableStock(tx, req.LotteryTypeID, userID) < req.Quantity {
+			return ErrLotteryTypeSoldOut
+		}
+		if req.CouponID != 0 {
+			if err := redeemCoupon(tx, req.CouponID); err != nil {
+				return err
+			}
+		}
+		if totalCost > 0 {
+			paid, err := s.walletService.withDB(tx).PayForTickets(userID, totalCost, description)
+			if err != nil {
+				return err
+			}
+			payment = *paid
+			if err := contributeJackpot(tx, totalCost); err != nil {
+				return err
+			}
+		}
+
+		lotteries := s.lotteryService.withDB(tx)
+		generated := make([]*model.Ticket, 0, req.Quantity)
+		for i := 0; i < req.Quantity; i++ {
+			ticket, err := lotteries.GeneratePurchasedTicket(userID, req.LotteryTypeID, payment.PurchaseID)
+			if err != nil {
+				return err
+			}
+			generated = append(generated, ticket)
+			tickets = append(tickets, lotteries.toTicketResponse(ticket, false))
+		}
+		if settle != nil {
+			return settle(tx, generated)
+		}
+		return nil
+	})
+	if err != nil {
+		s.recordFailedPurchase(FailedPurchase{
+			UserID:        userID,
+			LotteryTypeID: req.LotteryTypeID,
+			Quantity:      req.Quantity,
+			Cost:          totalCost,
+			PurchaseID:    payment.PurchaseID,
+			Err:           err,
+		})
+		return nil, err
 	}
+	s.lotteryService.recordSale(req.LotteryTypeID, req.Quantity)
 
-	// Purchase tickets - deduct balance first, then generate tickets
-	var tickets []TicketResponse
-	var newBalance int
+	// The reservation of the preview has served its purpose
+	if s.reservations != nil {
+		if err := s.reservations.Consume(userID, req.LotteryTypeID); err != nil {
+			logger.FromContext(s.db.Statement.Context).Warn("Consuming the stock reservation of user %d failed: %v", userID, err)
+		}
+	}
 
-	// First deduct the total cost
-	description := fmt.Sprintf("购买彩票: %s x%d", lotteryType.Name, req.Quantity)
-	if err := s.walletService.Deduct(userID, totalCost, model.TransactionTypePurchase, description, 0); err != nil {
+	// Get updated balance
+	newBalance, newBonus, err := s.walletService.GetBalances(userID)
+	if err != nil {
 		return nil, err
 	}
 
-	// Generate tickets
-	for i := 0; i < req.Quantity; i++ {
-		ticket, err := s.lotteryService.GenerateTicket(userID, req.LotteryTypeID)
+	resp := &PurchaseResponse{
+		Tickets:      tickets,
+		Cost:         totalCost,
+		Discount:     discount,
+		Balance:      newBalance,
+		BonusSpent:   payment.Bonus,
+		BonusBalance: newBonus,
+	}
+
+	if s.events != nil {
+		s.events.publishPurchase(lotteryType.ID, lotteryType.Name, userID, newBalance, s.lotteryService.calculateStock(req.LotteryTypeID))
+	}
+	completeOnboarding(s.onboarding, userID, model.OnboardingFirstPurchase)
+
+	// Reward the first purchase of a user
+	if s.campaignService != nil && totalCost > 0 {
+		first, err := s.isFirstPurchase(userID, payment)
 		if err != nil {
-			// If ticket generation fails, we should refund - but for simplicity, we'll just return error
-			// In production, this should be handled with proper compensation
-			return nil, err
+			logger.FromContext(s.db.Statement.Context).Warn("Checking the first purchase of user %d failed: %v", userID, err)
+		} else if first {
+			rewards, err := s.campaignService.HandleEvent(CampaignEvent{
+				Trigger:     model.CampaignTriggerFirstPurchase,
+				UserID:      userID,
+				Key:         "first_purchase",
+				ReferenceID: tickets[0].ID,
+			})
+			if err != nil {
+				logger.FromContext(s.db.Statement.Context).Warn("Rewarding the first purchase of user %d failed: %v", userID, err)
+			}
+			resp.Rewards = rewards
 		}
-		tickets = append(tickets, s.lotteryService.toTicketResponse(ticket, false))
 	}
 
-	// Get updated balance
-	newBalance, err = s.walletService.GetBalance(userID)
+	return resp, nil
+}
+
+// recordFailedPurchase passes a failed purchase to refunds. Purchases refused
+// for the buyer's balance, coupon or the stock are not failures.
+func (s *PurchaseService) recordFailedPurchase(failed FailedPurchase) {
+	if s.refunds == nil {
+		return
+	}
+	switch failed.Err {
+	case ErrInsufficientBalance, ErrWalletFrozen, ErrWalletNotFound, ErrInvalidAmount,
+		ErrCouponUnavailable, ErrLotteryTypeSoldOut, ErrNoPrizePoolActive:
+		return
+	}
+	refunds, err := s.refunds.RecordFailedPurchase(failed)
 	if err != nil {
-		return nil, err
+		logger.FromContext(s.db.Statement.Context).Warn("Recording the failed purchase of user %d failed: %v", failed.UserID, err)
+		return
+	}
+	for _, refund := range refunds {
+		if refund.Status == model.RefundStatusRefunded {
+			logger.FromContext(s.db.Statement.Context).Warn("Refunded %d %s of a failed purchase of user %d", refund.Amount, refund.Currency, failed.UserID)
+		}
 	}
+}
 
-	return &PurchaseResponse{
-		Tickets: tickets,
-		Cost:    totalCost,
-		Balance: newBalance,
-	}, nil
+// isFirstPurchase reports whether the purchase just booked, paid as payment,
+// is the only purchase of a user. A purchase paid from both balances books
+// two transactions.
+func (s *PurchaseService) isFirstPurchase(userID uint, payment TicketPayment) (bool, error) {
+	booked := int64(0)
+	for _, part := range []int{payment.Points, payment.Bonus} {
+		if part > 0 {
+			booked++
+		}
+	}
+	var count int64
+	if err := s.db.Model(&model.Transaction{}).
+		Joins("JOIN wallets ON wallets.id = transactions.wallet_id").
+		Where("wallets.user_id = ? AND transactions.type IN ?", userID,
+			[]model.TransactionType{model.TransactionTypePurchase, model.TransactionTypeBonusPurchase}).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count == booked, nil
 }
 
 // ValidatePurchase validates if a purchase can be made without actually making it
@@ -1277,10 +2096,13 @@ func (s *PurchaseService) ValidatePurchase(userID uint, req PurchaseRequest) err
 	}
 
 	// Calculate total cost
-	totalCost := lotteryType.Price * req.Quantity
+	totalCost, err := purchaseCost(lotteryType.Price, req.Quantity)
+	if err != nil {
+		return err
+	}
 
 	// Check balance
-	balance, err := s.walletService.GetBalance(userID)
+	balance, err := s.walletService.GetTicketBalance(userID)
 	if err != nil {
 		return err
 	}
@@ -1290,7 +2112,7 @@ func (s *PurchaseService) ValidatePurchase(userID uint, req PurchaseRequest) err
 	}
 
 	// Check stock
-	if lotteryType.Stock < req.Quantity {
+	if s.lotteryService.calculateStockFor(userID, req.LotteryTypeID) < req.Quantity {
 		return ErrLotteryTypeSoldOut
 	}
 
@@ -1306,23 +2128,59 @@ func (s *PurchaseService) GetPurchasePreview(userID uint, req PurchaseRequest) (
 	}
 
 	// Calculate total cost
-	totalCost := lotteryType.Price * req.Quantity
+	totalCost, err := purchaseCost(lotteryType.Price, req.Quantity)
+	if err != nil {
+		return nil, err
+	}
 
 	// Get balance
-	balance, err := s.walletService.GetBalance(userID)
+	balance, bonus, err := s.walletService.GetBalances(userID)
 	if err != nil {
 		return nil, err
 	}
 
-	return map[string]interface{}{
+	stock := s.lotteryService.calculateStockFor(userID, req.LotteryTypeID)
+	canPurchase := balance+bonus >= totalCost && stock >= req.Quantity
+	payment := s.walletService.SplitTicketPayment(balance, bonus, totalCost)
+
+	// Hold the tickets until the purchase when asked to
+	var reservation *StockReservationResponse
+	if req.Reserve && canPurchase && s.reservations != nil {
+		reservation, err = s.reservations.Reserve(userID, req.LotteryTypeID, req.Quantity)
+		if errors.Is(err, ErrLotteryTypeSoldOut) {
+			canPurchase = false
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	preview := map[string]interface{}{
 		"lottery_type":    lotteryType,
 		"quantity":        req.Quantity,
 		"unit_price":      lotteryType.Price,
 		"total_cost":      totalCost,
 		"current_balance": balance,
-		"balance_after":   balance - totalCost,
-		"can_purchase":    balance >= totalCost && lotteryType.Stock >= req.Quantity,
-	}, nil
+		"balance_after":   balance - payment.Points,
+		"bonus_balance":   bonus,
+		"bonus_after":     bonus - payment.Bonus,
+		"can_purchase":    canPurchase,
+	}
+	if reservation != nil {
+		preview["reservation"] = reservation
+	}
+
+	// Add the current odds of the active pool
+	if s.oddsHintService != nil {
+		hint, err := s.oddsHintService.GetOddsHint(req.LotteryTypeID)
+		if err != nil {
+			return nil, err
+		}
+		if hint != nil {
+			preview["odds_hint"] = hint
+		}
+	}
+
+	return preview, nil
 }
 
 // ScratchService handles ticket scratching operations
@@ -1330,27 +2188,79 @@ type ScratchService struct {
 	db             *gorm.DB
 	lotteryService *LotteryService
 	walletService  *WalletService
+	streakService  *StreakService
+	largeWinService *LargeWinService
+	events          *LiveEventBus
+	onboarding      *OnboardingService
 }
 
-// NewScratchService creates a new scratch service
-func NewScratchService(db *gorm.DB, lotteryService *LotteryService, walletService *WalletService) *ScratchService {
+// NewScratchService creates a new scratch service. streakService may be nil,
+// in which case scratches do not count towards streaks, and largeWinService
+// may be nil, in which case every prize is credited when scratched.
+func NewScratchService(db *gorm.DB, lotteryService *LotteryService, walletService *WalletService, streakService *StreakService, largeWinService *LargeWinService) *ScratchService {
 	return &ScratchService{
-		db:             db,
-		lotteryService: lotteryService,
-		walletService:  walletService,
+		db:              db,
+		lotteryService:  lotteryService,
+		walletService:   walletService,
+		streakService:   streakService,
+		largeWinService: largeWinService,
+	}
+}
+
+// UseEvents publishes balance changes and big wins of scratches to events
+func (s *ScratchService) UseEvents(events *LiveEventBus) {
+	s.events = events
+}
+
+// UseOnboarding completes the first scratch step of the onboarding checklist
+func (s *ScratchService) UseOnboarding(onboarding *OnboardingService) {
+	s.onboarding = onboarding
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *ScratchService) ForTenant(tenantID uint) *ScratchService {
+	scoped := &ScratchService{
+		db:             repository.ScopeTenant(s.db, tenantID),
+		lotteryService: s.lotteryService.ForTenant(tenantID),
+		walletService:  s.walletService.ForTenant(tenantID),
+	}
+	if s.streakService != nil {
+		scoped.streakService = s.streakService.ForTenant(tenantID)
+	}
+	if s.largeWinService != nil {
+		scoped.largeWinService = s.largeWinService.ForTenant(tenantID)
+	}
+	if s.events != nil {
+		scoped.events = s.events.ForTenant(tenantID)
 	}
+	if s.onboarding != nil {
+		scoped.onboarding = s.onboarding.ForTenant(tenantID)
+	}
+	return scoped
+}
+
+// WithRequest returns a copy of the service whose logs carry the request id
+// of ctx
+func (s *ScratchService) WithRequest(ctx context.Context) *ScratchService {
+	scoped := *s
+	scoped.db = repository.ScopeRequest(s.db, ctx)
+	return &scoped
 }
 
 // ScratchResponse represents the response after scratching a ticket
+
 type ScratchResponse struct {
-	TicketID     uint                `json:"ticket_id"`
-	SecurityCode string              `json:"security_code"`
-	Status       model.TicketStatus  `json:"status"`
-	PrizeAmount  int                 `json:"prize_amount"`
-	IsWin        bool                `json:"is_win"`
-	Content      *TicketContent      `json:"content,omitempty"`
-	NewBalance   int                 `json:"new_balance"`
-	ScratchedAt  *time.Time          `json:"scratched_at"`
+	TicketID           uint               `json:"ticket_id"`
+	SecurityCode       string             `json:"security_code"`
+	Status             model.TicketStatus `json:"status"`
+	PrizeAmount        int                `json:"prize_amount"`
+	IsWin              bool               `json:"is_win"`
+	Content            *TicketContent     `json:"content,omitempty"`
+	NewBalance         int                `json:"new_balance"`
+	ScratchedAt        *time.Time         `json:"scratched_at"`
+	StreakBonus        int                `json:"streak_bonus,omitempty"`        // points credited for a scratch streak
+	ClaimRequired      bool               `json:"claim_required,omitempty"`      // prize held until its claim is confirmed or approved
+	FulfillmentPending bool               `json:"fulfillment_pending,omitempty"` // prize product waiting for an admin to deliver it
 }
 
 // TicketDetailResponse represents detailed ticket information
@@ -1365,15 +2275,21 @@ type TicketDetailResponse struct {
 	PurchasedAt   time.Time            `json:"purchased_at"`
 	ScratchedAt   *time.Time           `json:"scratched_at,omitempty"`
 	LotteryType   *LotteryTypeResponse `json:"lottery_type,omitempty"`
+	Confirmation  *ScratchConfirmationResponse `json:"confirmation,omitempty"` // to echo when scratching, for lottery types that require it
+	ScratchableAt *time.Time           `json:"scratchable_at,omitempty"` // earliest scratch allowed by the lottery type's pacing, for a countdown
 }
 
 var (
 	ErrTicketAlreadyScratched = errors.New("ticket already scratched")
 	ErrTicketNotOwned         = errors.New("ticket not owned by user")
+	ErrTicketVoided           = errors.New("ticket voided")
 )
 
-// ScratchTicket scratches a ticket and awards prize if won
-func (s *ScratchService) ScratchTicket(userID, ticketID uint) (*ScratchResponse, error) {
+// ScratchTicket scratches a ticket and awards prize if won. Tickets of lottery
+// types that require confirmed scratches need the confirmation token issued
+// with the ticket detail, and tickets of paced lottery types are rejected
+// with a ScratchTooSoonError until their pacing allows the scratch.
+func (s *ScratchService) ScratchTicket(userID, ticketID uint, confirmToken string) (*ScratchResponse, error) {
 	// Get ticket
 	ticket, err := s.lotteryService.GetTicketByID(ticketID)
 	if err != nil {
@@ -1385,65 +2301,35 @@ func (s *ScratchService) ScratchTicket(userID, ticketID uint) (*ScratchResponse,
 		return nil, ErrTicketNotOwned
 	}
 
-	// Check if already scratched
-	if ticket.Status != model.TicketStatusUnscratched {
+	if ticket.Status == model.TicketStatusVoided {
+		return nil, ErrTicketVoided
+	}
+	// Check if already scratched (a ticket being scratched area by area can still be settled)
+	if ticket.Status != model.TicketStatusUnscratched && ticket.Status != model.TicketStatusScratching {
 		return nil, ErrTicketAlreadyScratched
 	}
 
-	// Decrypt ticket content
-	content, err := s.lotteryService.DecryptTicketContent(ticket.ContentEncrypted)
+	policy, err := s.loadScratchPolicy()
+	if err != nil {
+		return nil, err
+	}
+	plan, err := s.planScratch(s.db, ticket, policy)
 	if err != nil {
 		return nil, err
 	}
 
 	// Update ticket status and award prize in a transaction
 	now := time.Now()
-	var newBalance int
-
-	err = s.db.Transaction(func(tx *gorm.DB) error {
-		// Update ticket status
-		if err := tx.Model(&model.Ticket{}).Where("id = ?", ticketID).Updates(map[string]interface{}{
-			"status":       model.TicketStatusScratched,
-			"scratched_at": now,
-		}).Error; err != nil {
+	var streakBonus int
+	err = repository.Transaction(s.db, func(tx *gorm.DB) error {
+		if err := checkScratchPacing(tx, ticket, now); err != nil {
 			return err
 		}
-
-		// Award prize if won - do all operations within the same transaction
-		if ticket.PrizeAmount > 0 {
-			// Get wallet
-			var wallet model.Wallet
-			if err := tx.Where("user_id = ?", userID).First(&wallet).Error; err != nil {
-				return err
-			}
-
-			// Update wallet balance
-			wallet.Balance += ticket.PrizeAmount
-			if err := tx.Save(&wallet).Error; err != nil {
-				return err
-			}
-
-			// Create transaction record
-			description := fmt.Sprintf("彩票中奖: %s", ticket.LotteryType.Name)
-			transaction := model.Transaction{
-				WalletID:    wallet.ID,
-				Type:        model.TransactionTypeWin,
-				Amount:      ticket.PrizeAmount,
-				Description: description,
-				ReferenceID: ticketID,
-			}
-			if err := tx.Create(&transaction).Error; err != nil {
-				return err
-			}
-
-			// Update prize pool claimed count
-			if err := tx.Model(&model.PrizePool{}).Where("id = ?", ticket.PrizePoolID).
-				Update("claimed_prizes", gorm.Expr("claimed_prizes + 1")).Error; err != nil {
-				return err
-			}
+		if err := consumeScratchConfirmation(tx, ticket, confirmToken); err != nil {
+			return err
 		}
-
-		return nil
+		streakBonus, err = s.settleScratch(tx, plan, policy, now)
+		return err
 	})
 
 	if err != nil {
@@ -1451,21 +2337,162 @@ func (s *ScratchService) ScratchTicket(userID, ticketID uint) (*ScratchResponse,
 	}
 
 	// Get updated balance
-	newBalance, err = s.walletService.GetBalance(userID)
+	newBalance, err := s.walletService.GetBalance(userID)
 	if err != nil {
 		return nil, err
 	}
 
+	if s.events != nil {
+		s.events.publishScratch(s.db, ticket, plan.credited() || streakBonus > 0, newBalance)
+	}
+	completeOnboarding(s.onboarding, userID, model.OnboardingFirstScratch)
+
+	return plan.response(newBalance, streakBonus, now), nil
+}
+
+// scratchPolicy holds the rules scratches are settled by, loaded before the
+// transaction of a scratch
+type scratchPolicy struct {
+	streakRules []StreakRule
+	largeWin    LargeWinPolicy
+}
+
+// loadScratchPolicy loads the streak rules and large win policy
+func (s *ScratchService) loadScratchPolicy() (scratchPolicy, error) {
+	var policy scratchPolicy
+	var err error
+	if s.streakService != nil {
+		if policy.streakRules, err = s.streakService.GetRules(); err != nil {
+			return policy, err
+		}
+	}
+	if s.largeWinService != nil {
+		if policy.largeWin, err = s.largeWinService.GetPolicy(); err != nil {
+			return policy, err
+		}
+	}
+	return policy, nil
+}
+
+// scratchPlan is how a ticket is settled when it is scratched
+type scratchPlan struct {
+	ticket             *model.Ticket
+	content            *TicketContent
+	payoutProductID    uint
+	claimRequired      bool // large win held until its claim is confirmed or approved
+	fulfillmentPending bool // prize product waiting for an admin to deliver it
+	status             model.TicketStatus
+}
+
+// credited reports whether the scratch pays its prize into the wallet
+func (p *scratchPlan) credited() bool {
+	return p.ticket.PrizeAmount > 0 && !p.claimRequired && !p.fulfillmentPending
+}
+
+// response returns the scratch response of the settled ticket
+func (p *scratchPlan) response(balance, streakBonus int, scratchedAt time.Time) *ScratchResponse {
 	return &ScratchResponse{
-		TicketID:     ticketID,
-		SecurityCode: ticket.SecurityCode,
-		Status:       model.TicketStatusScratched,
-		PrizeAmount:  ticket.PrizeAmount,
-		IsWin:        ticket.PrizeAmount > 0,
-		Content:      content,
-		NewBalance:   newBalance,
-		ScratchedAt:  &now,
-	}, nil
+		TicketID:           p.ticket.ID,
+		SecurityCode:       p.ticket.SecurityCode,
+		Status:             p.status,
+		PrizeAmount:        p.ticket.PrizeAmount,
+		IsWin:              p.ticket.PrizeAmount > 0,
+		Content:            p.content,
+		NewBalance:         balance,
+		ScratchedAt:        &scratchedAt,
+		StreakBonus:        streakBonus,
+		ClaimRequired:      p.claimRequired,
+		FulfillmentPending: p.fulfillmentPending,
+	}
+}
+
+// planScratch decrypts and checks the content of a ticket and decides how its
+// prize is paid. The payout of the prize level is looked up in db.
+func (s *ScratchService) planScratch(db *gorm.DB, ticket *model.Ticket, policy scratchPolicy) (*scratchPlan, error) {
+	content, err := s.lotteryService.DecryptTicketContent(ticket.ContentEncrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	// Refuse a ticket whose numbers or multiplier do not add up to its
+	// prize; a jackpot is paid on top of what the areas reveal
+	switch ticket.LotteryType.GameType {
+	case model.GameTypeNumberMatch:
+		if err := s.lotteryService.numberMatch.Validate(content, ticket.PrizeAmount-content.Jackpot); err != nil {
+			return nil, err
+		}
+	case model.GameTypeMultiplier:
+		if err := s.lotteryService.multiplier.Validate(content, ticket.PrizeAmount-content.Jackpot); err != nil {
+			return nil, err
+		}
+	}
+
+	plan := &scratchPlan{ticket: ticket, content: content, status: model.TicketStatusScratched}
+	if ticket.PrizeAmount > 0 {
+		if plan.payoutProductID, err = prizePayoutProduct(db, ticket.PrizePoolID, content.PrizeLevel); err != nil {
+			return nil, err
+		}
+	}
+
+	// Held large wins leave the ticket pending its claim
+	plan.claimRequired = ticket.PrizeAmount > 0 && policy.largeWin.holds(ticket.PrizeAmount)
+	plan.fulfillmentPending = ticket.PrizeAmount > 0 && !plan.claimRequired && plan.payoutProductID != 0
+	if plan.claimRequired {
+		plan.status = model.TicketStatusPendingClaim
+	}
+	return plan, nil
+}
+
+// settleScratch marks the ticket of plan scratched and pays its prize within
+// tx. It returns the streak bonus credited for the scratch.
+func (s *ScratchService) settleScratch(tx *gorm.DB, plan *scratchPlan, policy scratchPolicy, now time.Time) (int, error) {
+	ticket := plan.ticket
+
+	// Update ticket status, guarding against a concurrent settlement
+	result := tx.Model(&model.Ticket{}).
+		Where("id = ? AND status IN ?", ticket.ID, []model.TicketStatus{model.TicketStatusUnscratched, model.TicketStatusScratching}).
+		Updates(map[string]interface{}{
+			"status":       plan.status,
+			"scratched_at": now,
+		})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return 0, ErrTicketAlreadyScratched
+	}
+	if err := appendTicketEvent(tx, ticket, model.TicketEventScratched, ticket.UserID, plan.status, 0, ""); err != nil {
+		return 0, err
+	}
+	if err := adjustBadge(tx, ticket.UserID, badgeUnscratchedTickets, -1); err != nil {
+		return 0, err
+	}
+
+	// Award prize if won - do all operations within the same transaction.
+	// Large wins wait for the winner's identity when the operator requires
+	// it, and product payouts wait for an admin to deliver the product.
+	if ticket.PrizeAmount > 0 {
+		switch {
+		case plan.claimRequired:
+			if err := s.largeWinService.holdPrize(tx, ticket, plan.payoutProductID, policy.largeWin.ApprovalRequired); err != nil {
+				return 0, err
+			}
+		case plan.fulfillmentPending:
+			if err := createPrizeFulfillment(tx, ticket, plan.payoutProductID); err != nil {
+				return 0, err
+			}
+		default:
+			if err := creditPrize(tx, ticket); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	// Count the scratch towards the user's daily streak
+	if s.streakService != nil {
+		return s.streakService.recordScratch(tx, policy.streakRules, ticket.UserID, ticket.ID, now)
+	}
+	return 0, nil
 }
 
 // GetTicketDetail returns detailed ticket information for the owner
@@ -1480,6 +2507,9 @@ func (s *ScratchService) GetTicketDetail(userID, ticketID uint) (*TicketDetailRe
 	if ticket.UserID != userID {
 		return nil, ErrTicketNotOwned
 	}
+	if err := recordTicketView(s.db, ticket, userID); err != nil {
+		return nil, err
+	}
 
 	resp := &TicketDetailResponse{
 		ID:            ticket.ID,
@@ -1499,7 +2529,7 @@ func (s *ScratchService) GetTicketDetail(userID, ticketID uint) (*TicketDetailRe
 	}
 
 	// Only show prize and content if scratched
-	if ticket.Status == model.TicketStatusScratched || ticket.Status == model.TicketStatusClaimed {
+	if ticket.Status.Revealed() {
 		resp.PrizeAmount = ticket.PrizeAmount
 
 		// Decrypt and include content
@@ -1509,6 +2539,18 @@ func (s *ScratchService) GetTicketDetail(userID, ticketID uint) (*TicketDetailRe
 		}
 	}
 
+	// Expensive games are only scratched with the token of a shown detail
+	if needsScratchConfirmation(ticket) {
+		if resp.Confirmation, err = issueScratchConfirmation(s.db, ticket); err != nil {
+			return nil, err
+		}
+	}
+
+	// Paced games show when the ticket may be scratched
+	if resp.ScratchableAt, err = scratchableAt(s.db, ticket, time.Now()); err != nil {
+		return nil, err
+	}
+
 	return resp, nil
 }
 
@@ -1543,7 +2585,7 @@ func (s *ScratchService) GetTicketForScratch(userID, ticketID uint) (*TicketDeta
 	}
 
 	// If already scratched, include prize info
-	if ticket.Status == model.TicketStatusScratched || ticket.Status == model.TicketStatusClaimed {
+	if ticket.Status.Revealed() {
 		resp.PrizeAmount = ticket.PrizeAmount
 		
 		// Decrypt and include content
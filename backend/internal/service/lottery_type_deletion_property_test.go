@@ -0,0 +1,141 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+)
+
+// Lottery type deletion: a type cannot be deleted while any of its tickets
+// is unscratched. Once deleted, its open pools are closed, it disappears from
+// the catalogue, and verification, ticket history, wins and ticket details
+// keep showing it as archived.
+func TestLotteryTypeDeletionLifecycle(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("deleted types stay visible through their tickets", prop.ForAll(
+		func(scratched []bool) bool {
+			db := setupLotteryTestDB(t)
+			lotteryService := NewLotteryService(db, testEncryptionKey)
+			walletService := NewWalletService(db)
+			scratchService := NewScratchService(db, lotteryService, walletService, nil, nil)
+			userService := NewUserService(db, walletService, nil)
+
+			user := model.User{LinuxdoID: "deletion_user", Username: "Test", Role: "user"}
+			db.Create(&user)
+			db.Create(&model.Wallet{UserID: user.ID})
+			lotteryType := model.LotteryType{Name: "Retired", Price: 10, MaxPrize: 100, GameType: model.GameTypeNumberMatch, Status: model.LotteryTypeStatusAvailable}
+			db.Create(&lotteryType)
+			prizePool := model.PrizePool{LotteryTypeID: lotteryType.ID, TotalTickets: 10, Status: model.PrizePoolStatusActive}
+			db.Create(&prizePool)
+
+			encrypted, err := lotteryService.EncryptTicketContent(&TicketContent{PrizeAmount: 20})
+			if err != nil {
+				t.Logf("Failed to encrypt content: %v", err)
+				return false
+			}
+			pending := 0 // unscratched tickets left
+			tickets := make([]model.Ticket, len(scratched))
+			for i, done := range scratched {
+				code, _ := lotteryService.GenerateUniqueSecurityCode()
+				tickets[i] = model.Ticket{
+					UserID:           user.ID,
+					LotteryTypeID:    lotteryType.ID,
+					PrizePoolID:      prizePool.ID,
+					SecurityCode:     code,
+					ContentEncrypted: encrypted,
+					PrizeAmount:      20,
+					Status:           model.TicketStatusUnscratched,
+					PurchasedAt:      time.Now(),
+				}
+				if done {
+					now := time.Now()
+					tickets[i].Status = model.TicketStatusScratched
+					tickets[i].ScratchedAt = &now
+				} else {
+					pending++
+				}
+				db.Create(&tickets[i])
+			}
+
+			// Deletion waits for every ticket to be scratched
+			for _, ticket := range tickets {
+				if ticket.Status != model.TicketStatusUnscratched {
+					continue
+				}
+				if err := lotteryService.DeleteLotteryType(lotteryType.ID); err != ErrLotteryTypeHasUnscratched {
+					t.Logf("Expected ErrLotteryTypeHasUnscratched with %d pending tickets, got %v", pending, err)
+					return false
+				}
+				if _, err := scratchService.ScratchTicket(user.ID, ticket.ID, ""); err != nil {
+					t.Logf("ScratchTicket failed: %v", err)
+					return false
+				}
+				pending--
+			}
+			if err := lotteryService.DeleteLotteryType(lotteryType.ID); err != nil {
+				t.Logf("DeleteLotteryType failed: %v", err)
+				return false
+			}
+
+			db.First(&prizePool, prizePool.ID)
+			if prizePool.Status != model.PrizePoolStatusClosed {
+				t.Logf("Expected the pool to be closed, got %s", prizePool.Status)
+				return false
+			}
+			if _, err := lotteryService.GetLotteryTypeByID(lotteryType.ID); err != ErrLotteryTypeNotFound {
+				t.Logf("Expected deleted type to be gone from the catalogue, got %v", err)
+				return false
+			}
+
+			for _, ticket := range tickets {
+				verified, err := lotteryService.VerifySecurityCode(ticket.SecurityCode)
+				if err != nil || verified.LotteryType != lotteryType.Name {
+					t.Logf("Expected verification to show %q, got %+v (err %v)", lotteryType.Name, verified, err)
+					return false
+				}
+				detail, err := scratchService.GetTicketDetail(user.ID, ticket.ID)
+				if err != nil || detail.LotteryType == nil || detail.LotteryType.Name != lotteryType.Name || !detail.LotteryType.Archived {
+					t.Logf("Expected an archived type in the ticket detail, got %+v (err %v)", detail, err)
+					return false
+				}
+			}
+
+			history, err := userService.GetUserTickets(user.ID, TicketRecordQuery{})
+			if err != nil || len(history.Tickets) != len(tickets) {
+				t.Logf("Expected %d tickets in history, got %+v (err %v)", len(tickets), history, err)
+				return false
+			}
+			for _, record := range history.Tickets {
+				if record.LotteryName != lotteryType.Name {
+					t.Logf("Expected history to show %q, got %+v", lotteryType.Name, record)
+					return false
+				}
+			}
+			wins, err := userService.GetUserWins(user.ID, 1, 50)
+			if err != nil || len(wins.Wins) != len(tickets) {
+				t.Logf("Expected %d wins, got %+v (err %v)", len(tickets), wins, err)
+				return false
+			}
+			for _, win := range wins.Wins {
+				if win.LotteryName != lotteryType.Name {
+					t.Logf("Expected wins to show %q, got %+v", lotteryType.Name, win)
+					return false
+				}
+			}
+			return true
+		},
+		gen.SliceOfN(4, gen.Bool()),
+	))
+
+	properties.TestingRun(t)
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+)
+
+// Lottery type schedules: a type with a future start stays disabled until its
+// start, is available until its end and disabled after it, and every reached
+// schedule is applied once and cleared, whichever passes run in between.
+func TestLotteryTypeScheduleWindow(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("status follows the sale window", prop.ForAll(
+		func(startIn, length int, passes []int) bool {
+			db := setupTenantTestDB(t)
+			lotteryService := NewLotteryService(db, testEncryptionKey).ForTenant(1)
+			scheduler := NewLotteryTypeScheduleService(db)
+
+			now := time.Now()
+			start := now.Add(time.Duration(startIn) * time.Hour)
+			end := start.Add(time.Duration(length) * time.Hour)
+			created, err := lotteryService.CreateLotteryType(CreateLotteryTypeRequest{
+				Name: "Scheduled", Price: 10, MaxPrize: 100, GameType: model.GameTypeAmountSum,
+				RulesConfig: map[string]any{"areas": 9}, ScheduledStartAt: &start, ScheduledEndAt: &end,
+			})
+			if err != nil {
+				t.Logf("CreateLotteryType failed: %v", err)
+				return false
+			}
+			if created.Status != model.LotteryTypeStatusDisabled {
+				t.Logf("Expected a disabled type before its start, got %s", created.Status)
+				return false
+			}
+
+			// Passes run at increasing hours after now
+			at := now
+			launched := false
+			for _, step := range passes {
+				at = at.Add(time.Duration(step) * time.Hour)
+				if _, err := scheduler.Apply(at); err != nil {
+					t.Logf("Apply failed: %v", err)
+					return false
+				}
+
+				var lotteryType model.LotteryType
+				db.First(&lotteryType, created.ID)
+				want := model.LotteryTypeStatusDisabled
+				if !at.Before(start) && at.Before(end) {
+					want = model.LotteryTypeStatusAvailable
+				}
+				if want == model.LotteryTypeStatusAvailable {
+					launched = true
+				}
+				if lotteryType.Status != want {
+					t.Logf("At +%v expected %s, got %s", at.Sub(now), want, lotteryType.Status)
+					return false
+				}
+				if (lotteryType.ScheduledStartAt == nil) != !at.Before(start) || (lotteryType.ScheduledEndAt == nil) != !at.Before(end) {
+					t.Logf("At +%v expected reached schedules cleared, got %v and %v", at.Sub(now), lotteryType.ScheduledStartAt, lotteryType.ScheduledEndAt)
+					return false
+				}
+			}
+
+			// Each status change is logged once; a type whose window fell
+			// between two passes is never launched
+			expected := int64(0)
+			if launched {
+				expected = 1
+				if !at.Before(end) {
+					expected = 2
+				}
+			}
+			var logs int64
+			db.Model(&model.AdminLog{}).Where("action = ? AND target_id = ?", "schedule_lottery_type", created.ID).Count(&logs)
+			if logs != expected {
+				t.Logf("Expected %d schedule logs, got %d", expected, logs)
+				return false
+			}
+			return true
+		},
+		gen.IntRange(1, 5),
+		gen.IntRange(1, 5),
+		gen.SliceOfN(4, gen.IntRange(0, 4)),
+	))
+
+	properties.TestingRun(t)
+}
+
+// Schedules set by admins are checked, a reached start launches at once,
+// sold out types stay sold out at their start, and the schedule can be cleared
+func TestLotteryTypeScheduleUpdates(t *testing.T) {
+	db := setupTenantTestDB(t)
+	lotteryService := NewLotteryService(db, testEncryptionKey).ForTenant(1)
+	created, err := lotteryService.CreateLotteryType(CreateLotteryTypeRequest{
+		Name: "Manual", Price: 10, MaxPrize: 100, GameType: model.GameTypeAmountSum, RulesConfig: map[string]any{"areas": 9},
+	})
+	if err != nil {
+		t.Fatalf("CreateLotteryType failed: %v", err)
+	}
+
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	if _, err := lotteryService.UpdateLotteryType(created.ID, UpdateLotteryTypeRequest{ScheduledEndAt: &past}); err != ErrInvalidLotteryTypeSchedule {
+		t.Errorf("Expected an end in the past to be refused, got %v", err)
+	}
+	later := future.Add(time.Hour)
+	if _, err := lotteryService.UpdateLotteryType(created.ID, UpdateLotteryTypeRequest{ScheduledStartAt: &later, ScheduledEndAt: &future}); err != ErrInvalidLotteryTypeSchedule {
+		t.Errorf("Expected an end before the start to be refused, got %v", err)
+	}
+
+	updated, err := lotteryService.UpdateLotteryType(created.ID, UpdateLotteryTypeRequest{ScheduledStartAt: &future})
+	if err != nil || updated.Status != model.LotteryTypeStatusDisabled || updated.ScheduledStartAt == nil {
+		t.Fatalf("Expected a scheduled launch, got %+v (err %v)", updated, err)
+	}
+	updated, err = lotteryService.UpdateLotteryType(created.ID, UpdateLotteryTypeRequest{ScheduledStartAt: &past})
+	if err != nil || updated.Status != model.LotteryTypeStatusAvailable || updated.ScheduledStartAt != nil {
+		t.Fatalf("Expected a reached start to launch at once, got %+v (err %v)", updated, err)
+	}
+
+	if _, err := lotteryService.UpdateLotteryType(created.ID, UpdateLotteryTypeRequest{ScheduledEndAt: &future}); err != nil {
+		t.Fatalf("UpdateLotteryType failed: %v", err)
+	}
+	updated, err = lotteryService.UpdateLotteryType(created.ID, UpdateLotteryTypeRequest{ClearSchedule: true})
+	if err != nil || updated.ScheduledEndAt != nil {
+		t.Fatalf("Expected the schedule cleared, got %+v (err %v)", updated, err)
+	}
+
+	// A sold out type is not made available by its start
+	db.Model(&model.LotteryType{}).Where("id = ?", created.ID).
+		Updates(map[string]interface{}{"status": model.LotteryTypeStatusSoldOut, "scheduled_start_at": past})
+	changes, err := NewLotteryTypeScheduleService(db).Apply(time.Now())
+	if err != nil || len(changes) != 1 || changes[0].StatusAfter != model.LotteryTypeStatusSoldOut {
+		t.Errorf("Expected the start reached without a status change, got %+v (err %v)", changes, err)
+	}
+}
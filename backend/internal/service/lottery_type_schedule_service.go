@@ -0,0 +1,167 @@
+package service
+
+import (
+	"encoding/json"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+	"scratch-lottery/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// LotteryTypeScheduleService makes lottery types available at their scheduled
+// start and disables them at their scheduled end. Each schedule is cleared
+// once reached, so admins can still change the status by hand in between. It
+// works across tenants.
+type LotteryTypeScheduleService struct {
+	db *gorm.DB
+}
+
+// NewLotteryTypeScheduleService creates a new lottery type schedule service
+func NewLotteryTypeScheduleService(db *gorm.DB) *LotteryTypeScheduleService {
+	return &LotteryTypeScheduleService{db: db}
+}
+
+// LotteryTypeScheduleChange is a schedule of a lottery type that was reached
+type LotteryTypeScheduleChange struct {
+	LotteryTypeID uint                    `json:"lottery_type_id"`
+	Schedule      string                  `json:"schedule"` // start or end
+	StatusBefore  model.LotteryTypeStatus `json:"status_before"`
+	StatusAfter   model.LotteryTypeStatus `json:"status_after"`
+}
+
+// Start runs a schedule pass every interval until the returned stop func is called
+func (s *LotteryTypeScheduleService) Start(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				changes, err := s.Apply(time.Now())
+				if err != nil {
+					logger.Default().Warn("Applying lottery type schedules failed: %v", err)
+				}
+				for _, change := range changes {
+					if change.StatusBefore != change.StatusAfter {
+						logger.Default().Info("Scheduled %s of lottery type %d: %s -> %s",
+							change.Schedule, change.LotteryTypeID, change.StatusBefore, change.StatusAfter)
+					}
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// Apply runs one pass at now and returns the schedules it reached. Starts
+// make disabled lottery types available unless their end has passed too;
+// sold out types stay sold out. Ends disable the lottery type.
+func (s *LotteryTypeScheduleService) Apply(now time.Time) ([]LotteryTypeScheduleChange, error) {
+	var changes []LotteryTypeScheduleChange
+
+	var starting []model.LotteryType
+	if err := s.db.Where("scheduled_start_at <= ?", now).Find(&starting).Error; err != nil {
+		return nil, err
+	}
+	for i := range starting {
+		lotteryType := &starting[i]
+		status := lotteryType.Status
+		if status == model.LotteryTypeStatusDisabled && (lotteryType.ScheduledEndAt == nil || lotteryType.ScheduledEndAt.After(now)) {
+			status = model.LotteryTypeStatusAvailable
+		}
+		change, err := s.reach(lotteryType, "start", status, now)
+		if err != nil {
+			logger.Default().Warn("Starting lottery type %d failed: %v", lotteryType.ID, err)
+			continue
+		}
+		if change != nil {
+			changes = append(changes, *change)
+		}
+	}
+
+	var ending []model.LotteryType
+	if err := s.db.Where("scheduled_end_at <= ?", now).Find(&ending).Error; err != nil {
+		return changes, err
+	}
+	for i := range ending {
+		change, err := s.reach(&ending[i], "end", model.LotteryTypeStatusDisabled, now)
+		if err != nil {
+			logger.Default().Warn("Ending lottery type %d failed: %v", ending[i].ID, err)
+			continue
+		}
+		if change != nil {
+			changes = append(changes, *change)
+		}
+	}
+	return changes, nil
+}
+
+// reach clears a schedule of a lottery type and sets its status, logging
+// status changes. It returns nil when another instance reached it first.
+func (s *LotteryTypeScheduleService) reach(lotteryType *model.LotteryType, schedule string, status model.LotteryTypeStatus, now time.Time) (*LotteryTypeScheduleChange, error) {
+	column := "scheduled_" + schedule + "_at"
+	change := &LotteryTypeScheduleChange{
+		LotteryTypeID: lotteryType.ID,
+		Schedule:      schedule,
+		StatusBefore:  lotteryType.Status,
+		StatusAfter:   status,
+	}
+
+	err := repository.ScopeTenant(s.db, lotteryType.TenantID).Transaction(func(tx *gorm.DB) error {
+		// Claim the schedule, so concurrent instances reach it once
+		result := tx.Model(&model.LotteryType{}).
+			Where("id = ? AND "+column+" <= ?", lotteryType.ID, now).
+			Updates(map[string]interface{}{column: nil, "status": status})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			change = nil
+			return nil
+		}
+		if change.StatusBefore == change.StatusAfter {
+			return nil
+		}
+
+		details, _ := json.Marshal(change)
+		return tx.Create(&model.AdminLog{
+			AdminID:    0,
+			Action:     "schedule_lottery_type",
+			TargetType: "lottery_type",
+			TargetID:   lotteryType.ID,
+			Details:    string(details),
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// applyLotteryTypeSchedule checks the schedule an admin set on a lottery type.
+// A future start keeps the type disabled until then, and a start already
+// reached makes it available at once.
+func applyLotteryTypeSchedule(lotteryType *model.LotteryType, now time.Time) error {
+	start, end := lotteryType.ScheduledStartAt, lotteryType.ScheduledEndAt
+	if end != nil && (!end.After(now) || (start != nil && !end.After(*start))) {
+		return ErrInvalidLotteryTypeSchedule
+	}
+	if start == nil {
+		return nil
+	}
+	if start.After(now) {
+		lotteryType.Status = model.LotteryTypeStatusDisabled
+		return nil
+	}
+	lotteryType.ScheduledStartAt = nil
+	if lotteryType.Status == model.LotteryTypeStatusDisabled {
+		lotteryType.Status = model.LotteryTypeStatusAvailable
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"testing"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+)
+
+// Multiplier layout: for any price and configured multiplier a winning ticket
+// reveals the multiplier that turns the price into its prize, a losing ticket
+// reveals none, and both validate against their prize.
+func TestMultiplierLayout(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+	multipliers := NewMultiplierService()
+	config := MultiplierConfig{Multipliers: defaultMultipliers}
+
+	properties.Property("the revealed multiplier matches the prize", prop.ForAll(
+		func(price, index int, win bool) bool {
+			prize := 0
+			if win {
+				prize = price * defaultMultipliers[index]
+			}
+			content := &TicketContent{PrizeAmount: prize}
+			multipliers.Generate(config, content, price)
+			if len(content.Areas) != 1 {
+				return false
+			}
+			area := content.Areas[0]
+			if win && (area.Multiplier != defaultMultipliers[index] || area.Value != prize || area.Prize != price) {
+				return false
+			}
+			if !win && (area.Multiplier != 0 || area.Value != 0) {
+				return false
+			}
+			return multipliers.Validate(content, prize) == nil
+		},
+		gen.IntRange(1, 100),
+		gen.IntRange(0, len(defaultMultipliers)-1),
+		gen.Bool(),
+	))
+
+	properties.TestingRun(t)
+}
+
+// Multiplier tickets: creating a type checks the multipliers and that every
+// prize is a multiple of the price, scratching reveals the multiplier, and a
+// ticket altered to reveal a higher multiplier is refused.
+func TestMultiplierTicketScratch(t *testing.T) {
+	db := setupLotteryTestDB(t)
+	lotteryService := NewLotteryService(db, testEncryptionKey)
+	scratchService := NewScratchService(db, lotteryService, NewWalletService(db), nil, nil)
+
+	invalid := []CreateLotteryTypeRequest{
+		{RulesConfig: map[string][]int{"multipliers": {2, 51}}, PrizeLevels: []PrizeLevelInput{{Level: 1, Name: "一等奖", PrizeAmount: 20, Quantity: 1}}},
+		{RulesConfig: map[string][]int{"multipliers": {2, 5}}, PrizeLevels: []PrizeLevelInput{{Level: 1, Name: "一等奖", PrizeAmount: 30, Quantity: 1}}},
+	}
+	for _, req := range invalid {
+		req.Name, req.Price, req.MaxPrize, req.GameType = "Invalid", 10, 100, model.GameTypeMultiplier
+		if _, err := lotteryService.CreateLotteryType(req); err != ErrInvalidMultiplierConfig {
+			t.Errorf("Expected ErrInvalidMultiplierConfig for %+v, got %v", req.RulesConfig, err)
+		}
+	}
+
+	lotteryType, err := lotteryService.CreateLotteryType(CreateLotteryTypeRequest{
+		Name:        "Multiplier",
+		Price:       10,
+		MaxPrize:    500,
+		GameType:    model.GameTypeMultiplier,
+		RulesConfig: map[string][]int{"multipliers": {2, 5, 50}},
+		PrizeLevels: []PrizeLevelInput{{Level: 1, Name: "一等奖", PrizeAmount: 50, Quantity: 1}},
+	})
+	if err != nil {
+		t.Fatalf("CreateLotteryType failed: %v", err)
+	}
+	if _, err := lotteryService.CreatePrizePool(CreatePrizePoolRequest{LotteryTypeID: lotteryType.ID, TotalTickets: 1}); err != nil {
+		t.Fatalf("CreatePrizePool failed: %v", err)
+	}
+	user := model.User{LinuxdoID: "multiplier", Username: "Multiplier"}
+	db.Create(&user)
+	db.Create(&model.Wallet{UserID: user.ID})
+
+	ticket, err := lotteryService.generateTicket(user.ID, lotteryType.ID, model.TicketHistoryPurchase, 0)
+	if err != nil {
+		t.Fatalf("generateTicket failed: %v", err)
+	}
+	result, err := scratchService.ScratchTicket(user.ID, ticket.ID, "")
+	if err != nil {
+		t.Fatalf("ScratchTicket failed: %v", err)
+	}
+	if result.PrizeAmount != 50 || len(result.Content.Areas) != 1 || result.Content.Areas[0].Multiplier != 5 || result.Content.Areas[0].Content != "5X" {
+		t.Errorf("Expected the ticket to reveal 5X for 50 points, got %+v", result)
+	}
+
+	// The revealed multiplier must match the prize the ticket pays
+	content := &TicketContent{PrizeAmount: 20}
+	NewMultiplierService().Generate(MultiplierConfig{Multipliers: []int{2}}, content, 10)
+	content.Areas[0].Multiplier = 50
+	content.Areas[0].Value = 500
+	encrypted, err := lotteryService.EncryptTicketContent(content)
+	if err != nil {
+		t.Fatalf("EncryptTicketContent failed: %v", err)
+	}
+	tampered := model.Ticket{UserID: user.ID, LotteryTypeID: lotteryType.ID, SecurityCode: "TAMPERED0002", ContentEncrypted: encrypted, PrizeAmount: 20, Status: model.TicketStatusUnscratched}
+	db.Create(&tampered)
+	if _, err := scratchService.ScratchTicket(user.ID, tampered.ID, ""); err != ErrMultiplierMismatch {
+		t.Errorf("Expected ErrMultiplierMismatch, got %v", err)
+	}
+}
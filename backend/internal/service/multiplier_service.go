@@ -0,0 +1,151 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"scratch-lottery/internal/model"
+)
+
+// Multipliers a multiplier ticket may reveal
+const (
+	minMultiplier = 1
+	maxMultiplier = 50
+)
+
+// MultiplierAreaKind is the kind of the area of a multiplier ticket
+const MultiplierAreaKind = "multiplier"
+
+// multiplierLosingSymbol is revealed by a losing multiplier ticket
+const multiplierLosingSymbol = "谢谢参与"
+
+// defaultMultipliers are used when the rules config lists none
+var defaultMultipliers = []int{1, 2, 3, 5, 10, 20, 50}
+
+var (
+	ErrInvalidMultiplierConfig = errors.New("invalid multiplier configuration")
+	ErrMultiplierMismatch      = errors.New("ticket multiplier does not match the prize")
+)
+
+// MultiplierConfig is the rules config of a multiplier lottery type: the
+// multipliers of the ticket price a ticket may reveal
+type MultiplierConfig struct {
+	Multipliers []int `json:"multipliers"`
+}
+
+// MultiplierService reveals the prize of multiplier tickets as a multiple of
+// the ticket price and checks it again when a ticket is scratched
+type MultiplierService struct{}
+
+// NewMultiplierService creates a new multiplier service
+func NewMultiplierService() *MultiplierService {
+	return &MultiplierService{}
+}
+
+// ParseConfig reads the rules config of a lottery type, filling in the
+// default multipliers
+func (s *MultiplierService) ParseConfig(rulesConfig string) (MultiplierConfig, error) {
+	var config MultiplierConfig
+	if rulesConfig != "" {
+		if err := json.Unmarshal([]byte(rulesConfig), &config); err != nil {
+			return config, ErrInvalidMultiplierConfig
+		}
+	}
+	if len(config.Multipliers) == 0 {
+		config.Multipliers = defaultMultipliers
+	}
+	seen := make(map[int]bool, len(config.Multipliers))
+	for _, multiplier := range config.Multipliers {
+		if multiplier < minMultiplier || multiplier > maxMultiplier || seen[multiplier] {
+			return config, ErrInvalidMultiplierConfig
+		}
+		seen[multiplier] = true
+	}
+	return config, nil
+}
+
+// ValidatePrizeLevels checks that every prize of a lottery type is the price
+// times one of the configured multipliers
+func (s *MultiplierService) ValidatePrizeLevels(config MultiplierConfig, price int, levels []PrizeLevelInput) error {
+	for _, level := range levels {
+		if _, ok := config.multiplierFor(price, level.PrizeAmount); !ok {
+			return ErrInvalidMultiplierConfig
+		}
+	}
+	return nil
+}
+
+// Generate fills the multiplier area of a ticket. A winning ticket reveals
+// the multiplier that turns the price into its prize, a losing one reveals
+// no multiplier. Prizes that are no multiple of the price, such as those of
+// lottery types created before the game type was checked, leave the ticket
+// without an area.
+func (s *MultiplierService) Generate(config MultiplierConfig, content *TicketContent, price int) {
+	area := AreaData{Index: 0, Kind: MultiplierAreaKind, Content: multiplierLosingSymbol, Prize: price}
+	if content.PrizeAmount > 0 {
+		multiplier, ok := config.multiplierFor(price, content.PrizeAmount)
+		if !ok {
+			return
+		}
+		area.Content = fmt.Sprintf("%dX", multiplier)
+		area.Multiplier = multiplier
+		area.Value = content.PrizeAmount
+	}
+	content.Areas = []AreaData{area}
+}
+
+// Validate recomputes the prize of a ticket from its revealed multiplier and
+// returns ErrMultiplierMismatch if it differs from prizeAmount. Tickets
+// without a multiplier area are accepted.
+func (s *MultiplierService) Validate(content *TicketContent, prizeAmount int) error {
+	won, found := 0, false
+	for _, area := range content.Areas {
+		if area.Kind != MultiplierAreaKind {
+			continue
+		}
+		found = true
+		if area.Multiplier == 0 {
+			if area.Value != 0 {
+				return ErrMultiplierMismatch
+			}
+			continue
+		}
+		if area.Multiplier < minMultiplier || area.Multiplier > maxMultiplier || area.Value != area.Prize*area.Multiplier {
+			return ErrMultiplierMismatch
+		}
+		won += area.Value
+	}
+	if found && (won != prizeAmount || content.PrizeAmount != prizeAmount) {
+		return ErrMultiplierMismatch
+	}
+	return nil
+}
+
+// multiplierFor returns the configured multiplier that turns price into prize
+func (c MultiplierConfig) multiplierFor(price, prize int) (int, bool) {
+	if price <= 0 || prize%price != 0 {
+		return 0, false
+	}
+	for _, multiplier := range c.Multipliers {
+		if price*multiplier == prize {
+			return multiplier, true
+		}
+	}
+	return 0, false
+}
+
+// determineMultiplierResult determines the prize of a multiplier ticket and
+// reveals it as a multiple of the ticket price
+func (s *LotteryService) determineMultiplierResult(prizePool *model.PrizePool, lotteryType *model.LotteryType) (*TicketContent, error) {
+	config, err := s.multiplier.ParseConfig(lotteryType.RulesConfig)
+	if err != nil {
+		return nil, err
+	}
+	content, err := s.DeterminePrizeResult(prizePool.ID)
+	if err != nil {
+		return nil, err
+	}
+	s.multiplier.Generate(config, content, lotteryType.Price)
+	return content, nil
+}
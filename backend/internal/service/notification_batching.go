@@ -0,0 +1,177 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// ConfigKeyNotificationBatching holds the notification batching windows as JSON
+const ConfigKeyNotificationBatching = "notification_batching"
+
+const (
+	maxNotificationBatchWindow = 24 * 60 * 60 // Seconds
+	maxNotificationTitle       = 128
+	maxNotificationContent     = 1024
+)
+
+var ErrInvalidNotificationBatching = errors.New("invalid notification batching")
+
+// batchableNotificationTypes are the notification types batching can be
+// configured for. Security notices are always sent one by one.
+var batchableNotificationTypes = map[string]bool{
+	model.NotificationTypeCampaign:          true,
+	model.NotificationTypeTicketGift:        true,
+	model.NotificationTypePointsAdjusted:    true,
+	model.NotificationTypeWalletDiscrepancy: true,
+}
+
+// defaultNotificationBatchWindows batch the notifications bulk admin
+// operations send
+var defaultNotificationBatchWindows = map[string]int{
+	model.NotificationTypeCampaign:       600,
+	model.NotificationTypePointsAdjusted: 600,
+}
+
+// NotificationBatchingConfig holds how long notifications of each type are
+// collected into one summary, in seconds. Types left out or set to 0 are sent
+// one by one.
+type NotificationBatchingConfig struct {
+	Windows map[string]int `json:"windows"`
+}
+
+// GetBatchingConfig returns the notification batching windows
+func (s *NotificationService) GetBatchingConfig() (*NotificationBatchingConfig, error) {
+	return notificationBatching(s.db)
+}
+
+// UpdateBatchingConfig validates and replaces the notification batching windows
+func (s *NotificationService) UpdateBatchingConfig(adminID uint, req NotificationBatchingConfig) (*NotificationBatchingConfig, error) {
+	config := &NotificationBatchingConfig{Windows: map[string]int{}}
+	for notificationType, window := range req.Windows {
+		if !batchableNotificationTypes[notificationType] || window < 0 || window > maxNotificationBatchWindow {
+			return nil, ErrInvalidNotificationBatching
+		}
+		config.Windows[notificationType] = window
+	}
+
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	err = notificationConfigs(s.db).Transaction(func(tx *gorm.DB) error {
+		if err := upsertSystemConfig(tx, ConfigKeyNotificationBatching, string(configJSON)); err != nil {
+			return err
+		}
+		adminLog := model.AdminLog{
+			AdminID:    adminID,
+			Action:     "update_notification_batching",
+			TargetType: "system",
+			TargetID:   0,
+			Details:    string(configJSON),
+		}
+		return tx.Create(&adminLog).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// notificationBatching reads the batching windows of the tenant of db
+func notificationBatching(db *gorm.DB) (*NotificationBatchingConfig, error) {
+	var setting model.SystemConfig
+	err := notificationConfigs(db).Where("key = ?", ConfigKeyNotificationBatching).First(&setting).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &NotificationBatchingConfig{Windows: defaultNotificationBatchWindows}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	config := &NotificationBatchingConfig{}
+	if err := json.Unmarshal([]byte(setting.Value), config); err != nil {
+		return nil, err
+	}
+	if config.Windows == nil {
+		config.Windows = map[string]int{}
+	}
+	return config, nil
+}
+
+// notificationConfigs returns the session system config is read with.
+// Notifications sent without a tenant (background jobs) use the default
+// tenant's settings, like AdminService.
+func notificationConfigs(db *gorm.DB) *gorm.DB {
+	if _, ok := repository.TenantFromContext(db.Statement.Context); ok {
+		return db
+	}
+	return repository.ScopeTenant(db, repository.DefaultTenantID)
+}
+
+// batchNotification folds an event into the user's unread notification of
+// the same type when that one started less than the type's window ago. The
+// summary replaces it as a new notification, so it is listed at the time of
+// its latest event. Returns nil when the event starts a notification of its
+// own.
+func batchNotification(tx *gorm.DB, userID uint, notificationType, title, content string) (*model.Notification, error) {
+	if !batchableNotificationTypes[notificationType] {
+		return nil, nil
+	}
+	config, err := notificationBatching(tx)
+	if err != nil {
+		return nil, err
+	}
+	window := config.Windows[notificationType]
+	if window <= 0 {
+		return nil, nil
+	}
+
+	now := time.Now()
+	var previous model.Notification
+	err = tx.Where("user_id = ? AND type = ? AND read_at IS NULL", userID, notificationType).
+		Order("created_at DESC, id DESC").
+		First(&previous).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	startedAt := previous.CreatedAt
+	if previous.BatchStartedAt != nil {
+		startedAt = *previous.BatchStartedAt
+	}
+	if now.Sub(startedAt) >= time.Duration(window)*time.Second {
+		return nil, nil
+	}
+
+	// The notification may have been read or batched since; then the event
+	// starts a new one
+	result := tx.Unscoped().Where("id = ? AND read_at IS NULL", previous.ID).Delete(&model.Notification{})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	count := max(previous.Count, 1) + 1
+	summary := model.Notification{
+		UserID:         userID,
+		Type:           notificationType,
+		Title:          truncate(title, maxNotificationTitle-12) + fmt.Sprintf("（共 %d 条）", count),
+		Content:        truncate(content+"\n"+previous.Content, maxNotificationContent),
+		Count:          count,
+		BatchStartedAt: &startedAt,
+	}
+	if err := tx.Create(&summary).Error; err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
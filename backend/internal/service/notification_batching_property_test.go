@@ -0,0 +1,167 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+	"gorm.io/gorm"
+)
+
+// setupNotificationBatchingTest creates a user with a wallet
+func setupNotificationBatchingTest(t *testing.T) (*gorm.DB, *NotificationService, uint) {
+	db := setupTenantTestDB(t)
+	if err := db.AutoMigrate(&model.Notification{}, &model.PaymentOrder{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	user := model.User{LinuxdoID: "batch_user", Username: "Batch", Role: "user"}
+	db.Create(&user)
+	db.Create(&model.Wallet{UserID: user.ID})
+	return db, NewNotificationService(db), user.ID
+}
+
+// Notification batching: events of a batched type collapse into the user's
+// unread summary of that type, which is listed at its latest event with the
+// events newest first. Other types and events after a read are listed one
+// by one, and the unread badge counts notifications, not events.
+func TestNotificationBatchingKeepsOrder(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	types := []string{model.NotificationTypePointsAdjusted, model.NotificationTypeCampaign, model.NotificationTypeNewDeviceLogin}
+
+	type expected struct {
+		notificationType string
+		contents         []string // Newest first
+		read             bool
+	}
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("summaries follow their latest event", prop.ForAll(
+		func(ops []int) bool {
+			db, notifications, userID := setupNotificationBatchingTest(t)
+			badges := NewBadgeService(db)
+			if _, err := badges.GetBadges(userID); err != nil {
+				t.Logf("GetBadges failed: %v", err)
+				return false
+			}
+
+			var want []expected // Oldest first
+			for i, op := range ops {
+				if op == len(types) {
+					if err := notifications.MarkAllRead(userID); err != nil {
+						t.Logf("MarkAllRead failed: %v", err)
+						return false
+					}
+					for j := range want {
+						want[j].read = true
+					}
+					continue
+				}
+				notificationType := types[op]
+				content := fmt.Sprintf("event-%d", i)
+				if _, err := notifications.Notify(userID, notificationType, "Title", content); err != nil {
+					t.Logf("Notify failed: %v", err)
+					return false
+				}
+				contents := []string{content}
+				if notificationType != model.NotificationTypeNewDeviceLogin {
+					for j := len(want) - 1; j >= 0; j-- {
+						if want[j].notificationType == notificationType && !want[j].read {
+							contents = append(contents, want[j].contents...)
+							want = append(want[:j], want[j+1:]...)
+							break
+						}
+					}
+				}
+				want = append(want, expected{notificationType: notificationType, contents: contents})
+			}
+
+			list, err := notifications.GetNotifications(userID, 1, 100)
+			if err != nil || len(list.Notifications) != len(want) {
+				t.Logf("Expected %d notifications, got %+v (err %v)", len(want), list, err)
+				return false
+			}
+			unread := 0
+			for i, notification := range list.Notifications {
+				w := want[len(want)-1-i]
+				if notification.Type != w.notificationType || notification.Count != len(w.contents) ||
+					notification.Content != strings.Join(w.contents, "\n") || (notification.ReadAt != nil) != w.read {
+					t.Logf("Notification %d: expected %+v, got %+v", i, w, notification)
+					return false
+				}
+				if !w.read {
+					unread++
+				}
+			}
+			counts, err := badges.GetBadges(userID)
+			if err != nil || counts.UnreadNotifications != unread {
+				t.Logf("Expected %d unread, got %+v (err %v)", unread, counts, err)
+				return false
+			}
+			return true
+		},
+		gen.SliceOfN(20, gen.IntRange(0, len(types))),
+	))
+
+	properties.TestingRun(t)
+}
+
+// A summary stops collecting once its window has passed, and types can be
+// configured per tenant
+func TestNotificationBatchingWindows(t *testing.T) {
+	db, notifications, userID := setupNotificationBatchingTest(t)
+
+	first, _ := notifications.Notify(userID, model.NotificationTypeCampaign, "活动奖励", "a")
+	second, err := notifications.Notify(userID, model.NotificationTypeCampaign, "活动奖励", "b")
+	if err != nil || second.Count != 2 || second.Title != "活动奖励（共 2 条）" || !second.BatchStartedAt.Equal(first.CreatedAt) {
+		t.Fatalf("Expected a summary of 2, got %+v (err %v)", second, err)
+	}
+
+	// The window runs from the first event of the summary
+	db.Model(&model.Notification{}).Where("id = ?", second.ID).Update("batch_started_at", time.Now().Add(-11*time.Minute))
+	if third, err := notifications.Notify(userID, model.NotificationTypeCampaign, "活动奖励", "c"); err != nil || third.Count != 1 {
+		t.Errorf("Expected a new notification after the window, got %+v (err %v)", third, err)
+	}
+
+	if _, err := notifications.UpdateBatchingConfig(1, NotificationBatchingConfig{Windows: map[string]int{model.NotificationTypeNewDeviceLogin: 60}}); err != ErrInvalidNotificationBatching {
+		t.Errorf("Expected security notices not to batch, got %v", err)
+	}
+	if _, err := notifications.UpdateBatchingConfig(1, NotificationBatchingConfig{Windows: map[string]int{model.NotificationTypeCampaign: 0}}); err != nil {
+		t.Fatalf("UpdateBatchingConfig failed: %v", err)
+	}
+	if fourth, err := notifications.Notify(userID, model.NotificationTypeCampaign, "活动奖励", "d"); err != nil || fourth.Count != 1 {
+		t.Errorf("Expected batching disabled, got %+v (err %v)", fourth, err)
+	}
+	config, err := notifications.ForTenant(2).GetBatchingConfig()
+	if err != nil || config.Windows[model.NotificationTypeCampaign] != defaultNotificationBatchWindows[model.NotificationTypeCampaign] {
+		t.Errorf("Expected the other tenant to keep the defaults, got %+v (err %v)", config, err)
+	}
+}
+
+// A bulk grant reaches each user as one notification
+func TestBulkPointGrantIsBatched(t *testing.T) {
+	db, notifications, userID := setupNotificationBatchingTest(t)
+	adminService := NewAdminService(db, NewWalletService(db))
+	adminService.UseNotifications(notifications)
+
+	for i := 1; i <= 30; i++ {
+		if _, err := adminService.AdjustUserPoints(1, userID, AdjustUserPointsRequest{Amount: i, Description: "活动补偿"}); err != nil {
+			t.Fatalf("AdjustUserPoints failed: %v", err)
+		}
+	}
+
+	var batched []model.Notification
+	db.Where("user_id = ?", userID).Find(&batched)
+	if len(batched) != 1 || batched[0].Count != 30 || !strings.HasPrefix(batched[0].Content, "活动补偿：+30 积分\n活动补偿：+29 积分") {
+		t.Errorf("Expected one summary of 30 grants, got %+v", batched)
+	}
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotificationNotFound = errors.New("notification not found")
+)
+
+// NotificationService handles the in-app notifications of users
+type NotificationService struct {
+	db *gorm.DB
+}
+
+// NewNotificationService creates a new notification service
+func NewNotificationService(db *gorm.DB) *NotificationService {
+	return &NotificationService{db: db}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *NotificationService) ForTenant(tenantID uint) *NotificationService {
+	return &NotificationService{db: repository.ScopeTenant(s.db, tenantID)}
+}
+
+// NotificationListResponse represents a paginated list of notifications
+type NotificationListResponse struct {
+	Notifications []model.Notification `json:"notifications"`
+	Total         int64                `json:"total"`
+	Unread        int64                `json:"unread"`
+	Page          int                  `json:"page"`
+	Limit         int                  `json:"limit"`
+	TotalPages    int                  `json:"total_pages"`
+}
+
+// Notify sends a notification to a user
+func (s *NotificationService) Notify(userID uint, notificationType, title, content string) (*model.Notification, error) {
+	var notification *model.Notification
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		notification, err = s.notify(tx, userID, notificationType, title, content)
+		return err
+	})
+	return notification, err
+}
+
+// notify creates a notification within the given session, so callers can
+// send it as part of their own transaction. Events of a batched type are
+// folded into the user's recent unread notification of that type.
+func (s *NotificationService) notify(tx *gorm.DB, userID uint, notificationType, title, content string) (*model.Notification, error) {
+	summary, err := batchNotification(tx, userID, notificationType, title, content)
+	if err != nil || summary != nil {
+		return summary, err
+	}
+
+	notification := model.Notification{
+		UserID:  userID,
+		Type:    notificationType,
+		Title:   title,
+		Content: content,
+	}
+	if err := tx.Create(&notification).Error; err != nil {
+		return nil, err
+	}
+	if err := adjustBadge(tx, userID, badgeUnreadNotifications, 1); err != nil {
+		return nil, err
+	}
+	return &notification, nil
+}
+
+// GetNotifications returns the notifications of a user, newest first
+func (s *NotificationService) GetNotifications(userID uint, page, limit int) (*NotificationListResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	var total, unread int64
+	if err := s.db.Model(&model.Notification{}).Where("user_id = ?", userID).Count(&total).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(&model.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&unread).Error; err != nil {
+		return nil, err
+	}
+
+	var notifications []model.Notification
+	if err := s.db.Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&notifications).Error; err != nil {
+		return nil, err
+	}
+
+	totalPages := int(total) / limit
+	if int(total)%limit > 0 {
+		totalPages++
+	}
+
+	return &NotificationListResponse{
+		Notifications: notifications,
+		Total:         total,
+		Unread:        unread,
+		Page:          page,
+		Limit:         limit,
+		TotalPages:    totalPages,
+	}, nil
+}
+
+// MarkRead marks a notification of a user as read
+func (s *NotificationService) MarkRead(userID, notificationID uint) error {
+	var notification model.Notification
+	if err := s.db.Where("id = ? AND user_id = ?", notificationID, userID).First(&notification).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotificationNotFound
+		}
+		return err
+	}
+	if notification.ReadAt != nil {
+		return nil
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.Notification{}).
+			Where("id = ? AND read_at IS NULL", notification.ID).
+			Update("read_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		return adjustBadge(tx, userID, badgeUnreadNotifications, -int(result.RowsAffected))
+	})
+}
+
+// MarkAllRead marks all notifications of a user as read
+func (s *NotificationService) MarkAllRead(userID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.Notification{}).
+			Where("user_id = ? AND read_at IS NULL", userID).
+			Update("read_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		return adjustBadge(tx, userID, badgeUnreadNotifications, -int(result.RowsAffected))
+	})
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"testing"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+)
+
+// Number match layout: for any prize and ticket size the generated numbers
+// hold exactly one match printed with the prize on a winning ticket and none
+// on a losing one, and they validate against the prize.
+func TestNumberMatchLayout(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+	numberMatch := NewNumberMatchService()
+
+	properties.Property("numbers match the predetermined prize", prop.ForAll(
+		func(prize, winningNumbers, playerNumbers int) bool {
+			config := NumberMatchConfig{WinningNumbers: winningNumbers, PlayerNumbers: playerNumbers, MaxNumber: winningNumbers + 5}
+			content := &TicketContent{PrizeAmount: prize}
+			if err := numberMatch.Generate(config, content, []int{10, 50, 100}); err != nil {
+				return false
+			}
+			if len(content.Areas) != winningNumbers+playerNumbers {
+				return false
+			}
+
+			winning := map[int]bool{}
+			matches := 0
+			for _, area := range content.Areas {
+				number := area.Content.(int)
+				if number < 1 || number > config.MaxNumber {
+					return false
+				}
+				switch area.Kind {
+				case NumberAreaWinning:
+					winning[number] = true
+				case NumberAreaPlayer:
+					if winning[number] {
+						matches++
+						if area.Prize != prize || area.Value != prize {
+							return false
+						}
+					}
+				}
+			}
+			if (prize > 0 && matches != 1) || (prize == 0 && matches != 0) {
+				return false
+			}
+			return numberMatch.Validate(content, prize) == nil
+		},
+		gen.OneConstOf(0, 10, 500),
+		gen.IntRange(1, 5),
+		gen.IntRange(1, 12),
+	))
+
+	properties.TestingRun(t)
+}
+
+// Number match tickets: purchased tickets carry their numbers, scratching
+// returns them with the prize, and a ticket whose numbers were altered to
+// win is refused.
+func TestNumberMatchTicketScratch(t *testing.T) {
+	db := setupLotteryTestDB(t)
+	lotteryService := NewLotteryService(db, testEncryptionKey)
+	walletService := NewWalletService(db)
+	scratchService := NewScratchService(db, lotteryService, walletService, nil, nil)
+
+	if _, err := lotteryService.CreateLotteryType(CreateLotteryTypeRequest{
+		Name:        "Bad numbers",
+		Price:       10,
+		MaxPrize:    100,
+		GameType:    model.GameTypeNumberMatch,
+		RulesConfig: map[string]int{"winning_numbers": 5, "max_number": 5},
+		PrizeLevels: []PrizeLevelInput{{Level: 1, Name: "一等奖", PrizeAmount: 100, Quantity: 1}},
+	}); err != ErrInvalidNumberMatchConfig {
+		t.Fatalf("Expected ErrInvalidNumberMatchConfig, got %v", err)
+	}
+
+	lotteryType, err := lotteryService.CreateLotteryType(CreateLotteryTypeRequest{
+		Name:        "Numbers",
+		Price:       10,
+		MaxPrize:    100,
+		GameType:    model.GameTypeNumberMatch,
+		RulesConfig: map[string]int{"winning_numbers": 2, "player_numbers": 6},
+		PrizeLevels: []PrizeLevelInput{{Level: 1, Name: "一等奖", PrizeAmount: 100, Quantity: 1}},
+	})
+	if err != nil {
+		t.Fatalf("CreateLotteryType failed: %v", err)
+	}
+	if _, err := lotteryService.CreatePrizePool(CreatePrizePoolRequest{LotteryTypeID: lotteryType.ID, TotalTickets: 2}); err != nil {
+		t.Fatalf("CreatePrizePool failed: %v", err)
+	}
+	user := model.User{LinuxdoID: "numbers", Username: "Numbers"}
+	db.Create(&user)
+	db.Create(&model.Wallet{UserID: user.ID})
+
+	var won int
+	for i := 0; i < 2; i++ {
+		ticket, err := lotteryService.generateTicket(user.ID, lotteryType.ID, model.TicketHistoryPurchase, 0)
+		if err != nil {
+			t.Fatalf("generateTicket failed: %v", err)
+		}
+		result, err := scratchService.ScratchTicket(user.ID, ticket.ID, "")
+		if err != nil {
+			t.Fatalf("ScratchTicket failed: %v", err)
+		}
+		if len(result.Content.Areas) != 8 || result.Content.Areas[0].Kind != NumberAreaWinning || result.Content.Areas[2].Kind != NumberAreaPlayer {
+			t.Fatalf("Expected 2 winning and 6 player numbers, got %+v", result.Content.Areas)
+		}
+		won += result.PrizeAmount
+	}
+	if won != 100 {
+		t.Errorf("Expected the pool's single prize to be won, got %d", won)
+	}
+
+	// A losing ticket altered to show a match is refused
+	content := &TicketContent{}
+	if err := lotteryService.numberMatch.Generate(NumberMatchConfig{WinningNumbers: 1, PlayerNumbers: 3, MaxNumber: 10}, content, nil); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	content.Areas[1].Content = content.Areas[0].Content
+	content.Areas[1].Prize = 100
+	encrypted, err := lotteryService.EncryptTicketContent(content)
+	if err != nil {
+		t.Fatalf("EncryptTicketContent failed: %v", err)
+	}
+	tampered := model.Ticket{UserID: user.ID, LotteryTypeID: lotteryType.ID, SecurityCode: "TAMPERED0001", ContentEncrypted: encrypted, Status: model.TicketStatusUnscratched}
+	db.Create(&tampered)
+	if _, err := scratchService.ScratchTicket(user.ID, tampered.ID, ""); err != ErrNumberMatchMismatch {
+		t.Errorf("Expected ErrNumberMatchMismatch, got %v", err)
+	}
+}
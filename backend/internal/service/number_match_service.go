@@ -0,0 +1,227 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"math/big"
+
+	"scratch-lottery/internal/model"
+)
+
+// Kinds of the scratch areas of a number match ticket
+const (
+	NumberAreaWinning = "winning" // a winning number
+	NumberAreaPlayer  = "player"  // one of the player's numbers with the prize printed beside it
+)
+
+// Defaults of a number match ticket
+const (
+	defaultWinningNumbers = 3
+	defaultPlayerNumbers  = 10
+	defaultMaxNumber      = 30
+)
+
+var (
+	ErrInvalidNumberMatchConfig = errors.New("invalid number match configuration")
+	ErrNumberMatchMismatch      = errors.New("ticket numbers do not match the prize")
+)
+
+// NumberMatchConfig is the rules config of a number match lottery type.
+// Numbers are drawn from 1 to MaxNumber; a player number equal to any of the
+// winning numbers wins the prize printed beside it.
+type NumberMatchConfig struct {
+	WinningNumbers int `json:"winning_numbers"`
+	PlayerNumbers  int `json:"player_numbers"`
+	MaxNumber      int `json:"max_number"`
+}
+
+// NumberMatchService lays out the numbers of number match tickets around
+// their predetermined prize and checks them again when a ticket is scratched
+type NumberMatchService struct{}
+
+// NewNumberMatchService creates a new number match service
+func NewNumberMatchService() *NumberMatchService {
+	return &NumberMatchService{}
+}
+
+// ParseConfig reads the rules config of a lottery type, filling in defaults
+func (s *NumberMatchService) ParseConfig(rulesConfig string) (NumberMatchConfig, error) {
+	var config NumberMatchConfig
+	if rulesConfig != "" {
+		if err := json.Unmarshal([]byte(rulesConfig), &config); err != nil {
+			return config, ErrInvalidNumberMatchConfig
+		}
+	}
+	if config.WinningNumbers == 0 {
+		config.WinningNumbers = defaultWinningNumbers
+	}
+	if config.PlayerNumbers == 0 {
+		config.PlayerNumbers = defaultPlayerNumbers
+	}
+	if config.MaxNumber == 0 {
+		config.MaxNumber = defaultMaxNumber
+	}
+	// At most 100 areas, and numbers left over for the losing player numbers
+	if config.WinningNumbers < 1 || config.PlayerNumbers < 1 || config.WinningNumbers+config.PlayerNumbers > 100 ||
+		config.MaxNumber <= config.WinningNumbers || config.MaxNumber > 1000 {
+		return config, ErrInvalidNumberMatchConfig
+	}
+	return config, nil
+}
+
+// Generate fills the areas of a ticket: the winning numbers first, then the
+// player's numbers. A winning ticket has exactly one player number matching a
+// winning number, printed with the ticket's prize; the other player numbers
+// miss and show decoy prizes drawn from prizes.
+func (s *NumberMatchService) Generate(config NumberMatchConfig, content *TicketContent, prizes []int) error {
+	numbers, err := randomPermutation(config.MaxNumber)
+	if err != nil {
+		return err
+	}
+	winning := numbers[:config.WinningNumbers]
+	misses := numbers[config.WinningNumbers:]
+
+	matchIndex := -1
+	if content.PrizeAmount > 0 {
+		n, err := randomInt(config.PlayerNumbers)
+		if err != nil {
+			return err
+		}
+		matchIndex = n
+	}
+
+	areas := make([]AreaData, 0, config.WinningNumbers+config.PlayerNumbers)
+	for _, number := range winning {
+		areas = append(areas, AreaData{Index: len(areas), Kind: NumberAreaWinning, Content: number})
+	}
+	for i := 0; i < config.PlayerNumbers; i++ {
+		area := AreaData{Index: len(areas), Kind: NumberAreaPlayer}
+		if i == matchIndex {
+			n, err := randomInt(len(winning))
+			if err != nil {
+				return err
+			}
+			area.Content = winning[n]
+			area.Prize = content.PrizeAmount
+			area.Value = content.PrizeAmount
+		} else {
+			// Player numbers may repeat, as on printed tickets
+			n, err := randomInt(len(misses))
+			if err != nil {
+				return err
+			}
+			area.Content = misses[n]
+			if len(prizes) > 0 {
+				p, err := randomInt(len(prizes))
+				if err != nil {
+					return err
+				}
+				area.Prize = prizes[p]
+			}
+		}
+		areas = append(areas, area)
+	}
+	content.Areas = areas
+	return nil
+}
+
+// Validate recomputes the prize of a ticket from its numbers and returns
+// ErrNumberMatchMismatch if it differs from prizeAmount. Tickets issued
+// before numbers were generated have no winning numbers and are accepted.
+func (s *NumberMatchService) Validate(content *TicketContent, prizeAmount int) error {
+	winning := make(map[int]bool)
+	for _, area := range content.Areas {
+		if area.Kind == NumberAreaWinning {
+			number, ok := areaNumber(area.Content)
+			if !ok {
+				return ErrNumberMatchMismatch
+			}
+			winning[number] = true
+		}
+	}
+	if len(winning) == 0 {
+		return nil
+	}
+
+	won := 0
+	for _, area := range content.Areas {
+		if area.Kind != NumberAreaPlayer {
+			continue
+		}
+		number, ok := areaNumber(area.Content)
+		if !ok {
+			return ErrNumberMatchMismatch
+		}
+		matched := winning[number]
+		if matched {
+			won += area.Prize
+		}
+		if (matched && area.Value != area.Prize) || (!matched && area.Value != 0) {
+			return ErrNumberMatchMismatch
+		}
+	}
+	if won != prizeAmount || content.PrizeAmount != prizeAmount {
+		return ErrNumberMatchMismatch
+	}
+	return nil
+}
+
+// determineNumberMatchResult determines the prize of a number match ticket
+// and lays out its numbers, with decoy prizes taken from the pool's levels
+func (s *LotteryService) determineNumberMatchResult(prizePool *model.PrizePool, lotteryType *model.LotteryType) (*TicketContent, error) {
+	config, err := s.numberMatch.ParseConfig(lotteryType.RulesConfig)
+	if err != nil {
+		return nil, err
+	}
+	content, err := s.DeterminePrizeResult(prizePool.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var prizes []int
+	if err := poolPrizeLevels(s.db.Model(&model.PrizeLevel{}), prizePool).Pluck("prize_amount", &prizes).Error; err != nil {
+		return nil, err
+	}
+	if err := s.numberMatch.Generate(config, content, prizes); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// areaNumber reads the number of an area, which is a float64 once the
+// content has been decrypted
+func areaNumber(content interface{}) (int, bool) {
+	switch v := content.(type) {
+	case int:
+		return v, true
+	case float64:
+		return int(v), v == float64(int(v))
+	}
+	return 0, false
+}
+
+// randomPermutation returns the numbers 1 to n in random order
+func randomPermutation(n int) ([]int, error) {
+	numbers := make([]int, n)
+	for i := range numbers {
+		numbers[i] = i + 1
+	}
+	for i := n - 1; i > 0; i-- {
+		j, err := randomInt(i + 1)
+		if err != nil {
+			return nil, err
+		}
+		numbers[i], numbers[j] = numbers[j], numbers[i]
+	}
+	return numbers, nil
+}
+
+// randomInt returns a uniform random int in [0, n)
+func randomInt(n int) (int, error) {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(v.Int64()), nil
+}
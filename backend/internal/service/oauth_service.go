@@ -12,6 +12,7 @@ import (
 	"scratch-lottery/internal/cache"
 	"scratch-lottery/internal/config"
 	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
 	"scratch-lottery/pkg/auth"
 
 	"gorm.io/gorm"
@@ -48,6 +49,8 @@ type OAuthService struct {
 	jwtManager  *auth.JWTManager
 	blacklist   *cache.TokenBlacklist
 	stateCache  cache.Cache
+	onboarding  *OnboardingService
+	sessions    *SessionService
 }
 
 // NewOAuthService creates a new OAuth service
@@ -61,14 +64,27 @@ func NewOAuthService(db *gorm.DB, cfg *config.Config, jwtManager *auth.JWTManage
 	}
 }
 
-// GetAuthorizationURL returns the OAuth2 authorization URL
-func (s *OAuthService) GetAuthorizationURL(state string) (string, error) {
+// UseOnboarding completes the account linking step of the onboarding
+// checklist on every LinuxDO sign-in
+func (s *OAuthService) UseOnboarding(onboarding *OnboardingService) {
+	s.onboarding = onboarding
+}
+
+// UseSessions binds the tokens issued on sign-in to sessions tracked by
+// sessions, enforcing its limits
+func (s *OAuthService) UseSessions(sessions *SessionService) {
+	s.sessions = sessions
+}
+
+// GetAuthorizationURL returns the OAuth2 authorization URL. The tenant the
+// login started on is kept with the state, since the callback URL is shared.
+func (s *OAuthService) GetAuthorizationURL(state string, tenantID uint) (string, error) {
 	if s.cfg.IsDevMode() {
 		return "", ErrOAuthDisabled
 	}
 
 	// Store state in cache for validation
-	_ = s.stateCache.Set("oauth_state:"+state, true, 600) // 10 minutes
+	_ = s.stateCache.Set("oauth_state:"+state, tenantID, 600) // 10 minutes
 
 	params := url.Values{}
 	params.Set("client_id", s.cfg.LinuxdoClientID)
@@ -80,17 +96,22 @@ func (s *OAuthService) GetAuthorizationURL(state string) (string, error) {
 	return fmt.Sprintf("%s?%s", linuxdoAuthorizeURL, params.Encode()), nil
 }
 
-// HandleCallback handles the OAuth2 callback
-func (s *OAuthService) HandleCallback(code, state string) (*AuthResponse, error) {
+// HandleCallback handles the OAuth2 callback of a sign-in from client
+func (s *OAuthService) HandleCallback(code, state string, client ClientInfo) (*AuthResponse, error) {
 	if s.cfg.IsDevMode() {
 		return nil, ErrOAuthDisabled
 	}
 
 	// Validate state
-	if !s.stateCache.Exists("oauth_state:" + state) {
+	stateValue, exists := s.stateCache.Get("oauth_state:" + state)
+	if !exists {
 		return nil, ErrOAuthStateMismatch
 	}
 	_ = s.stateCache.Delete("oauth_state:" + state)
+	tenantID, _ := stateValue.(uint)
+	if tenantID == 0 {
+		tenantID = repository.DefaultTenantID
+	}
 
 	// Exchange code for token
 	accessToken, err := s.exchangeCodeForToken(code)
@@ -104,29 +125,19 @@ func (s *OAuthService) HandleCallback(code, state string) (*AuthResponse, error)
 		return nil, err
 	}
 
-	// Find or create user
-	user, err := s.findOrCreateUser(userInfo)
+	// Find or create user within the tenant
+	scoped := *s
+	scoped.db = repository.ScopeTenant(s.db, tenantID)
+	user, err := scoped.findOrCreateUser(userInfo)
 	if err != nil {
 		return nil, err
 	}
-
-	// Generate JWT tokens
-	jwtAccessToken, refreshToken, err := s.jwtManager.GenerateTokenPair(
-		user.ID,
-		user.LinuxdoID,
-		user.Username,
-		user.Role,
-	)
-	if err != nil {
-		return nil, err
+	if s.onboarding != nil {
+		completeOnboarding(s.onboarding.ForTenant(tenantID), user.ID, model.OnboardingLinkAccount)
 	}
 
-	return &AuthResponse{
-		AccessToken:  jwtAccessToken,
-		RefreshToken: refreshToken,
-		ExpiresIn:    s.jwtManager.GetAccessExpiry(),
-		User:         user,
-	}, nil
+	// Generate JWT tokens
+	return issueTokens(s.jwtManager, s.sessions, user, client)
 }
 
 // exchangeCodeForToken exchanges the authorization code for an access token
@@ -244,6 +255,7 @@ func (s *OAuthService) findOrCreateUser(userInfo *LinuxdoUserInfo) (*model.User,
 
 		// Create initial transaction
 		tx := model.Transaction{
+			TenantID:    wallet.TenantID,
 			WalletID:    wallet.ID,
 			Type:        model.TransactionTypeInitial,
 			Amount:      50,
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"math"
+	"testing"
+
+	"scratch-lottery/internal/cache"
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+	"gorm.io/gorm"
+)
+
+// createOddsHintPool creates a lottery type with an active pool of which sold
+// tickets are gone and the given prizes are left
+func createOddsHintPool(t *testing.T, db *gorm.DB, totalTickets, sold int, remaining []int) uint {
+	lotteryType := model.LotteryType{Name: "Odds", Price: 10, Status: model.LotteryTypeStatusAvailable}
+	if err := db.Create(&lotteryType).Error; err != nil {
+		t.Fatalf("Failed to create lottery type: %v", err)
+	}
+	for i, left := range remaining {
+		level := model.PrizeLevel{LotteryTypeID: lotteryType.ID, Level: i + 1, Name: "Prize", PrizeAmount: 100 * (len(remaining) - i), Quantity: left + 1, Remaining: left}
+		if err := db.Create(&level).Error; err != nil {
+			t.Fatalf("Failed to create prize level: %v", err)
+		}
+	}
+	pool := model.PrizePool{LotteryTypeID: lotteryType.ID, TotalTickets: totalTickets, SoldTickets: sold, Status: model.PrizePoolStatusActive}
+	if err := db.Create(&pool).Error; err != nil {
+		t.Fatalf("Failed to create prize pool: %v", err)
+	}
+	return lotteryType.ID
+}
+
+// Odds hints: exact hints are remaining winners over remaining tickets, and
+// banded hints show the same bands without any counts.
+func TestOddsHintMatchesPool(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("hints reflect the remaining pool", prop.ForAll(
+		func(totalTickets, soldPercent int, remaining []int) bool {
+			db := setupLotteryTestDB(t)
+			sold := totalTickets * soldPercent / 100
+			lotteryTypeID := createOddsHintPool(t, db, totalTickets, sold, remaining)
+
+			exact, err := NewOddsHintService(db, nil, OddsHintExact, 0).GetOddsHint(lotteryTypeID)
+			if err != nil || exact == nil {
+				t.Logf("GetOddsHint failed: %v", err)
+				return false
+			}
+			banded, err := NewOddsHintService(db, nil, OddsHintBanded, 0).GetOddsHint(lotteryTypeID)
+			if err != nil || banded == nil {
+				t.Logf("GetOddsHint failed: %v", err)
+				return false
+			}
+
+			left := totalTickets - sold
+			winners := 0
+			for i, level := range exact.Levels {
+				winners += remaining[i]
+				want := math.Min(1, float64(remaining[i])/float64(left))
+				if *level.Remaining != remaining[i] || math.Abs(*level.Probability-want) > 1e-12 {
+					t.Logf("Level %d: expected %d left (p=%f), got %+v", level.Level, remaining[i], want, level)
+					return false
+				}
+				if level.Band != oddsBand(want) || banded.Levels[i].Band != level.Band {
+					t.Logf("Level %d: bands %s/%s do not match p=%f", level.Level, level.Band, banded.Levels[i].Band, want)
+					return false
+				}
+				if banded.Levels[i].Remaining != nil || banded.Levels[i].Probability != nil || banded.Levels[i].OddsOneIn != nil {
+					t.Logf("Banded hint exposes counts: %+v", banded.Levels[i])
+					return false
+				}
+			}
+			if *exact.RemainingTickets != left || *exact.RemainingWinners != winners || exact.OverallBand != banded.OverallBand {
+				t.Logf("Unexpected totals %+v vs %+v", exact, banded)
+				return false
+			}
+			return banded.RemainingTickets == nil && banded.OverallProbability == nil
+		},
+		gen.IntRange(1000, 100000),
+		gen.IntRange(0, 99),
+		gen.SliceOfN(4, gen.IntRange(0, 500)),
+	))
+
+	properties.TestingRun(t)
+}
+
+func TestOddsHintCacheAndPreview(t *testing.T) {
+	db := setupTenantTestDB(t)
+	lotteryTypeID := createOddsHintPool(t, db, 1000, 0, []int{1, 10})
+	hints := NewOddsHintService(db, cache.NewMemoryCache(), OddsHintExact, 60)
+
+	first, err := hints.GetOddsHint(lotteryTypeID)
+	if err != nil || first == nil {
+		t.Fatalf("GetOddsHint failed: %v", err)
+	}
+
+	// Later sales are not seen until the cached hint expires
+	db.Model(&model.PrizePool{}).Where("lottery_type_id = ?", lotteryTypeID).Update("sold_tickets", 500)
+	cached, _ := hints.GetOddsHint(lotteryTypeID)
+	if *cached.RemainingTickets != 1000 {
+		t.Errorf("Expected cached hint, got %d remaining tickets", *cached.RemainingTickets)
+	}
+	if fresh, _ := NewOddsHintService(db, cache.NewMemoryCache(), OddsHintExact, 60).GetOddsHint(lotteryTypeID); *fresh.RemainingTickets != 500 {
+		t.Errorf("Expected 500 remaining tickets, got %d", *fresh.RemainingTickets)
+	}
+
+	// Tenants do not share cached hints
+	if _, err := hints.ForTenant(2).GetOddsHint(lotteryTypeID); err != ErrLotteryTypeNotFound {
+		t.Errorf("Expected ErrLotteryTypeNotFound across tenants, got %v", err)
+	}
+
+	// Hints can be turned off
+	if hint, err := NewOddsHintService(db, nil, OddsHintOff, 0).GetOddsHint(lotteryTypeID); hint != nil || err != nil {
+		t.Errorf("Expected no hint when off, got %+v (err %v)", hint, err)
+	}
+
+	// Previews carry the hint
+	user := model.User{LinuxdoID: "odds", Username: "odds"}
+	db.Create(&user)
+	db.Create(&model.Wallet{UserID: user.ID, Balance: 100})
+	purchases := NewPurchaseService(db, NewLotteryService(db, testEncryptionKey), NewWalletService(db),
+		NewOddsHintService(db, nil, OddsHintBanded, 0), nil, nil)
+	preview, err := purchases.GetPurchasePreview(user.ID, PurchaseRequest{LotteryTypeID: lotteryTypeID, Quantity: 1})
+	if err != nil {
+		t.Fatalf("GetPurchasePreview failed: %v", err)
+	}
+	hint, ok := preview["odds_hint"].(*OddsHint)
+	if !ok || hint.Mode != OddsHintBanded || len(hint.Levels) != 2 || hint.Levels[0].Band != OddsBandLow {
+		t.Errorf("Expected banded odds hint in preview, got %+v", preview["odds_hint"])
+	}
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"scratch-lottery/internal/cache"
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// Odds hint modes
+const (
+	OddsHintExact  = "exact"  // remaining winners, probabilities and odds
+	OddsHintBanded = "banded" // only a coarse band per level
+	OddsHintOff    = "off"    // no hints in purchase previews
+)
+
+// Odds bands, from the best to the worst chance of winning
+const (
+	OddsBandHigh    = "high"     // 1 in 10 or better
+	OddsBandMedium  = "medium"   // 1 in 100 or better
+	OddsBandLow     = "low"      // 1 in 10000 or better
+	OddsBandVeryLow = "very_low" // worse than 1 in 10000
+	OddsBandNone    = "none"     // no prizes left
+)
+
+// OddsHintService computes the real-time odds of the active prize pool of a
+// lottery type: remaining winners per level over the remaining tickets.
+// Hints are cached for a short time so previews do not query the pool each time.
+type OddsHintService struct {
+	db       *gorm.DB
+	cache    cache.Cache
+	mode     string
+	ttl      time.Duration
+	tenantID uint
+}
+
+// NewOddsHintService creates a new odds hint service. Unknown modes fall back
+// to banded hints.
+func NewOddsHintService(db *gorm.DB, c cache.Cache, mode string, cacheSeconds int) *OddsHintService {
+	if mode != OddsHintExact && mode != OddsHintOff {
+		mode = OddsHintBanded
+	}
+	return &OddsHintService{db: db, cache: c, mode: mode, ttl: time.Duration(cacheSeconds) * time.Second}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *OddsHintService) ForTenant(tenantID uint) *OddsHintService {
+	return &OddsHintService{
+		db:       repository.ScopeTenant(s.db, tenantID),
+		cache:    s.cache,
+		mode:     s.mode,
+		ttl:      s.ttl,
+		tenantID: tenantID,
+	}
+}
+
+// OddsHintLevel is the current chance of winning one prize level.
+// Counts and probabilities are only set in exact mode.
+type OddsHintLevel struct {
+	Level       int      `json:"level"`
+	Name        string   `json:"name"`
+	PrizeAmount int      `json:"prize_amount"`
+	Band        string   `json:"band"`
+	Remaining   *int     `json:"remaining,omitempty"`
+	Probability *float64 `json:"probability,omitempty"`
+	OddsOneIn   *float64 `json:"odds_one_in,omitempty"`
+}
+
+// OddsHint is the current chance of winning with the next ticket of a lottery type
+type OddsHint struct {
+	Mode               string          `json:"mode"`
+	PrizePoolID        uint            `json:"prize_pool_id"`
+	OverallBand        string          `json:"overall_band"`
+	RemainingTickets   *int            `json:"remaining_tickets,omitempty"`
+	RemainingWinners   *int            `json:"remaining_winners,omitempty"`
+	OverallProbability *float64        `json:"overall_probability,omitempty"`
+	Levels             []OddsHintLevel `json:"levels"`
+	UpdatedAt          time.Time       `json:"updated_at"`
+}
+
+// GetOddsHint returns the odds hint of a lottery type, or nil when hints are
+// disabled or the lottery type has no active prize pool
+func (s *OddsHintService) GetOddsHint(lotteryTypeID uint) (*OddsHint, error) {
+	if s.mode == OddsHintOff {
+		return nil, nil
+	}
+
+	key := fmt.Sprintf("odds_hint:%d:%d", s.tenantID, lotteryTypeID)
+	if s.cache != nil {
+		if cached, ok := s.cache.Get(key); ok {
+			hint, _ := cached.(*OddsHint)
+			return hint, nil
+		}
+	}
+
+	hint, err := s.computeOddsHint(lotteryTypeID)
+	if err != nil {
+		return nil, err
+	}
+	if s.cache != nil && s.ttl > 0 {
+		_ = s.cache.Set(key, hint, s.ttl)
+	}
+	return hint, nil
+}
+
+// computeOddsHint reads the active prize pool and its levels
+func (s *OddsHintService) computeOddsHint(lotteryTypeID uint) (*OddsHint, error) {
+	var lotteryType model.LotteryType
+	if err := s.db.Select("id").First(&lotteryType, lotteryTypeID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLotteryTypeNotFound
+		}
+		return nil, err
+	}
+
+	var prizePool model.PrizePool
+	err := s.db.Where("lottery_type_id = ? AND status = ?", lotteryTypeID, model.PrizePoolStatusActive).
+		First(&prizePool).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var prizeLevels []model.PrizeLevel
+	if err := poolPrizeLevels(s.db, &prizePool).Where("prize_amount > 0").
+		Order("level ASC").
+		Find(&prizeLevels).Error; err != nil {
+		return nil, err
+	}
+
+	remainingTickets := prizePool.TotalTickets - prizePool.SoldTickets
+	if remainingTickets < 0 {
+		remainingTickets = 0
+	}
+	exact := s.mode == OddsHintExact
+
+	hint := &OddsHint{
+		Mode:        s.mode,
+		PrizePoolID: prizePool.ID,
+		Levels:      make([]OddsHintLevel, 0, len(prizeLevels)),
+		UpdatedAt:   time.Now(),
+	}
+	winners := 0
+	for _, pl := range prizeLevels {
+		remaining := pl.Remaining
+		if remaining < 0 {
+			remaining = 0
+		}
+		winners += remaining
+		probability := winningProbability(remaining, remainingTickets)
+
+		level := OddsHintLevel{
+			Level:       pl.Level,
+			Name:        pl.Name,
+			PrizeAmount: pl.PrizeAmount,
+			Band:        oddsBand(probability),
+		}
+		if exact {
+			level.Remaining = &remaining
+			level.Probability = &probability
+			if remaining > 0 {
+				odds := roundOdds(1 / probability)
+				level.OddsOneIn = &odds
+			}
+		}
+		hint.Levels = append(hint.Levels, level)
+	}
+
+	overall := winningProbability(winners, remainingTickets)
+	hint.OverallBand = oddsBand(overall)
+	if exact {
+		hint.RemainingTickets = &remainingTickets
+		hint.RemainingWinners = &winners
+		hint.OverallProbability = &overall
+	}
+	return hint, nil
+}
+
+// winningProbability is the chance that the next ticket is one of the winners
+func winningProbability(winners, tickets int) float64 {
+	if winners <= 0 || tickets <= 0 {
+		return 0
+	}
+	if winners >= tickets {
+		return 1
+	}
+	return float64(winners) / float64(tickets)
+}
+
+// oddsBand maps a probability to its band
+func oddsBand(probability float64) string {
+	switch {
+	case probability <= 0:
+		return OddsBandNone
+	case probability >= 0.1:
+		return OddsBandHigh
+	case probability >= 0.01:
+		return OddsBandMedium
+	case probability >= 0.0001:
+		return OddsBandLow
+	default:
+		return OddsBandVeryLow
+	}
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrOddsVersionNotFound = errors.New("odds disclosure version not found")
+)
+
+// OddsService generates versioned odds disclosure documents
+type OddsService struct {
+	db *gorm.DB
+}
+
+// NewOddsService creates a new odds service
+func NewOddsService(db *gorm.DB) *OddsService {
+	return &OddsService{db: db}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *OddsService) ForTenant(tenantID uint) *OddsService {
+	return &OddsService{db: repository.ScopeTenant(s.db, tenantID)}
+}
+
+// OddsLevel describes the odds of a single prize level
+type OddsLevel struct {
+	Level       int     `json:"level"`
+	Name        string  `json:"name"`
+	PrizeAmount int     `json:"prize_amount"`
+	Quantity    int     `json:"quantity"`
+	Probability float64 `json:"probability"`
+	OddsOneIn   float64 `json:"odds_one_in"` // 1 in N tickets
+}
+
+// OddsDisclosureDocument is the structured odds sheet for a lottery type
+type OddsDisclosureDocument struct {
+	LotteryTypeID      uint        `json:"lottery_type_id"`
+	LotteryTypeName    string      `json:"lottery_type_name"`
+	GameType           string      `json:"game_type"`
+	TicketPrice        int         `json:"ticket_price"`
+	PoolSize           int         `json:"pool_size"`
+	TotalPrizes        int         `json:"total_prizes"`
+	TotalPrizeValue    int         `json:"total_prize_value"`
+	OverallProbability float64     `json:"overall_probability"`
+	OverallOddsOneIn   float64     `json:"overall_odds_one_in"`
+	ExpectedReturnRate float64     `json:"expected_return_rate"` // total prize value / total ticket sales
+	Levels             []OddsLevel `json:"levels"`
+	Version            int         `json:"version"`
+	ConfigHash         string      `json:"config_hash"`
+	GeneratedAt        time.Time   `json:"generated_at"`
+}
+
+// OddsVersionSummary describes a recorded disclosure version
+type OddsVersionSummary struct {
+	Version     int       `json:"version"`
+	ConfigHash  string    `json:"config_hash"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// GetOddsDisclosure returns the current odds disclosure for a lottery type,
+// recording a new version if the prize configuration changed since the last one
+func (s *OddsService) GetOddsDisclosure(lotteryTypeID uint) (*OddsDisclosureDocument, error) {
+	doc, err := s.buildDocument(lotteryTypeID)
+	if err != nil {
+		return nil, err
+	}
+
+	var latest model.OddsDisclosure
+	err = s.db.Where("lottery_type_id = ?", lotteryTypeID).Order("version DESC").First(&latest).Error
+	if err == nil && latest.ConfigHash == doc.ConfigHash {
+		return decodeOddsDisclosure(&latest)
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// Configuration changed (or first request): record a new version
+	doc.Version = latest.Version + 1
+	doc.GeneratedAt = time.Now()
+	content, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	record := model.OddsDisclosure{
+		LotteryTypeID: lotteryTypeID,
+		Version:       doc.Version,
+		ConfigHash:    doc.ConfigHash,
+		Content:       string(content),
+	}
+	if err := s.db.Create(&record).Error; err != nil {
+		// A concurrent request may have recorded the same version first
+		var existing model.OddsDisclosure
+		if s.db.Where("lottery_type_id = ? AND version = ?", lotteryTypeID, doc.Version).First(&existing).Error == nil &&
+			existing.ConfigHash == doc.ConfigHash {
+			return decodeOddsDisclosure(&existing)
+		}
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// GetOddsDisclosureVersion returns a previously recorded disclosure version
+func (s *OddsService) GetOddsDisclosureVersion(lotteryTypeID uint, version int) (*OddsDisclosureDocument, error) {
+	var record model.OddsDisclosure
+	if err := s.db.Where("lottery_type_id = ? AND version = ?", lotteryTypeID, version).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOddsVersionNotFound
+		}
+		return nil, err
+	}
+	return decodeOddsDisclosure(&record)
+}
+
+// GetOddsDisclosureVersions lists all recorded disclosure versions, newest first
+func (s *OddsService) GetOddsDisclosureVersions(lotteryTypeID uint) ([]OddsVersionSummary, error) {
+	var records []model.OddsDisclosure
+	if err := s.db.Select("version", "config_hash", "created_at").
+		Where("lottery_type_id = ?", lotteryTypeID).
+		Order("version DESC").
+		Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	versions := make([]OddsVersionSummary, len(records))
+	for i, r := range records {
+		versions[i] = OddsVersionSummary{
+			Version:     r.Version,
+			ConfigHash:  r.ConfigHash,
+			GeneratedAt: r.CreatedAt,
+		}
+	}
+	return versions, nil
+}
+
+// buildDocument computes the odds from the configured prize levels and pool size
+func (s *OddsService) buildDocument(lotteryTypeID uint) (*OddsDisclosureDocument, error) {
+	var lotteryType model.LotteryType
+	if err := s.db.First(&lotteryType, lotteryTypeID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLotteryTypeNotFound
+		}
+		return nil, err
+	}
+
+	// Prefer the active pool, otherwise the most recent one
+	var prizePool model.PrizePool
+	err := s.db.Where("lottery_type_id = ? AND status = ?", lotteryTypeID, model.PrizePoolStatusActive).First(&prizePool).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = s.db.Where("lottery_type_id = ?", lotteryTypeID).Order("created_at DESC").First(&prizePool).Error
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPrizePoolNotFound
+		}
+		return nil, err
+	}
+	if prizePool.TotalTickets <= 0 {
+		return nil, ErrPrizePoolNotFound
+	}
+
+	var prizeLevels []model.PrizeLevel
+	if err := poolPrizeLevels(s.db, &prizePool).Order("level ASC").Find(&prizeLevels).Error; err != nil {
+		return nil, err
+	}
+
+	poolSize := prizePool.TotalTickets
+	doc := &OddsDisclosureDocument{
+		LotteryTypeID:   lotteryType.ID,
+		LotteryTypeName: lotteryType.Name,
+		GameType:        string(lotteryType.GameType),
+		TicketPrice:     lotteryType.Price,
+		PoolSize:        poolSize,
+		Levels:          make([]OddsLevel, 0, len(prizeLevels)),
+	}
+
+	for _, pl := range prizeLevels {
+		if pl.Quantity <= 0 || pl.PrizeAmount <= 0 {
+			continue
+		}
+		level := OddsLevel{
+			Level:       pl.Level,
+			Name:        pl.Name,
+			PrizeAmount: pl.PrizeAmount,
+			Quantity:    pl.Quantity,
+			Probability: float64(pl.Quantity) / float64(poolSize),
+			OddsOneIn:   roundOdds(float64(poolSize) / float64(pl.Quantity)),
+		}
+		doc.Levels = append(doc.Levels, level)
+		doc.TotalPrizes += pl.Quantity
+		doc.TotalPrizeValue += pl.Quantity * pl.PrizeAmount
+	}
+
+	if doc.TotalPrizes > 0 {
+		doc.OverallProbability = float64(doc.TotalPrizes) / float64(poolSize)
+		doc.OverallOddsOneIn = roundOdds(float64(poolSize) / float64(doc.TotalPrizes))
+	}
+	if lotteryType.Price > 0 {
+		doc.ExpectedReturnRate = float64(doc.TotalPrizeValue) / float64(poolSize*lotteryType.Price)
+	}
+
+	hash, err := oddsConfigHash(doc)
+	if err != nil {
+		return nil, err
+	}
+	doc.ConfigHash = hash
+
+	return doc, nil
+}
+
+// oddsConfigHash hashes the inputs that determine the odds, so any change
+// to prices, pool size or prize levels yields a new disclosure version
+func oddsConfigHash(doc *OddsDisclosureDocument) (string, error) {
+	data, err := json.Marshal(struct {
+		Name     string      `json:"name"`
+		GameType string      `json:"game_type"`
+		Price    int         `json:"price"`
+		PoolSize int         `json:"pool_size"`
+		Levels   []OddsLevel `json:"levels"`
+	}{doc.LotteryTypeName, doc.GameType, doc.TicketPrice, doc.PoolSize, doc.Levels})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func decodeOddsDisclosure(record *model.OddsDisclosure) (*OddsDisclosureDocument, error) {
+	var doc OddsDisclosureDocument
+	if err := json.Unmarshal([]byte(record.Content), &doc); err != nil {
+		return nil, fmt.Errorf("decode odds disclosure v%d: %w", record.Version, err)
+	}
+	return &doc, nil
+}
+
+func roundOdds(v float64) float64 {
+	return float64(int64(v*100+0.5)) / 100
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"testing"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+)
+
+// Onboarding: however often a step is completed, its reward is credited
+// once, disabled steps stay out of the checklist, and check-ins from before
+// the checklist count as done without a reward.
+func TestOnboardingRewardsEachStepOnce(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("each enabled step pays its reward once", prop.ForAll(
+		func(points int, repeats int) bool {
+			db, campaigns, userIDs := setupCampaignTest(t, 1)
+			if err := db.AutoMigrate(&model.OnboardingStep{}, &model.AdminLog{}, &model.SystemConfig{}); err != nil {
+				t.Logf("Failed to migrate: %v", err)
+				return false
+			}
+			userID := userIDs[0]
+			onboarding := NewOnboardingService(db, NewAdminService(db, NewWalletService(db)))
+			checkins := campaignCheckins(db, campaigns)
+			checkins.UseOnboarding(onboarding)
+
+			_, err := onboarding.UpdateConfig(1, OnboardingConfig{Steps: []OnboardingStepConfig{
+				{Key: model.OnboardingFirstPurchase, RewardPoints: points, Enabled: true},
+				{Key: model.OnboardingFirstCheckIn, RewardPoints: points, Enabled: true},
+			}})
+			if err != nil {
+				t.Logf("UpdateConfig failed: %v", err)
+				return false
+			}
+
+			// A check-in made before the checklist is backfilled unrewarded
+			db.Create(&model.CheckIn{UserID: userID, Date: "2020-01-01", StreakDays: 1})
+			for i := 0; i < repeats; i++ {
+				if err := onboarding.Complete(userID, model.OnboardingFirstPurchase); err != nil {
+					t.Logf("Complete failed: %v", err)
+					return false
+				}
+				if err := onboarding.Complete(userID, model.OnboardingLinkAccount); err != nil {
+					t.Logf("Complete of a disabled step failed: %v", err)
+					return false
+				}
+			}
+
+			progress, err := onboarding.GetProgress(userID)
+			if err != nil || progress.Total != 2 || progress.Completed != 2 || !progress.Finished {
+				t.Logf("Unexpected progress %+v (err %v)", progress, err)
+				return false
+			}
+			if progress.Steps[0].Key != model.OnboardingFirstPurchase || progress.Steps[1].RewardPoints != 0 {
+				t.Logf("Unexpected steps %+v", progress.Steps)
+				return false
+			}
+
+			// The backfilled step is done; checking in now pays nothing more
+			if _, err := checkins.CheckIn(userID); err != nil {
+				t.Logf("CheckIn failed: %v", err)
+				return false
+			}
+			var credited int64
+			db.Model(&model.Transaction{}).Where("type = ?", model.TransactionTypeOnboarding).
+				Select("COALESCE(SUM(amount), 0)").Scan(&credited)
+			var steps int64
+			db.Model(&model.OnboardingStep{}).Where("user_id = ?", userID).Count(&steps)
+			if int(credited) != points || steps != 2 {
+				t.Logf("Credited %d points for %d steps, expected %d for 2", credited, steps, points)
+				return false
+			}
+			return true
+		},
+		gen.IntRange(1, 100),
+		gen.IntRange(1, 4),
+	))
+
+	properties.TestingRun(t)
+}
+
+func TestOnboardingRejectsInvalidSteps(t *testing.T) {
+	db, _, _ := setupCampaignTest(t, 0)
+	if err := db.AutoMigrate(&model.AdminLog{}, &model.SystemConfig{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	onboarding := NewOnboardingService(db, NewAdminService(db, NewWalletService(db)))
+
+	invalid := []OnboardingConfig{
+		{Steps: []OnboardingStepConfig{{Key: "first_win", Enabled: true}}},
+		{Steps: []OnboardingStepConfig{{Key: model.OnboardingFirstScratch}, {Key: model.OnboardingFirstScratch}}},
+		{Steps: []OnboardingStepConfig{{Key: model.OnboardingFirstScratch, RewardPoints: -1}}},
+	}
+	for _, config := range invalid {
+		if _, err := onboarding.UpdateConfig(1, config); err != ErrInvalidOnboardingStep {
+			t.Errorf("Expected ErrInvalidOnboardingStep for %+v, got %v", config, err)
+		}
+	}
+
+	config, err := onboarding.GetConfig()
+	if err != nil || len(config.Steps) != 4 || !config.Steps[0].Enabled || config.Steps[0].Title == "" {
+		t.Errorf("Expected the default checklist, got %+v (err %v)", config, err)
+	}
+}
@@ -0,0 +1,312 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+	"unicode/utf8"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+	"scratch-lottery/pkg/logger"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ConfigKeyOnboarding holds the first-run checklist as JSON
+const ConfigKeyOnboarding = "onboarding_steps"
+
+const (
+	maxOnboardingTitle       = 64
+	maxOnboardingDescription = 256
+)
+
+var ErrInvalidOnboardingStep = errors.New("invalid onboarding step")
+
+// onboardingStepOrder lists every checklist step in the default order
+var onboardingStepOrder = []model.OnboardingStepKey{
+	model.OnboardingLinkAccount,
+	model.OnboardingFirstCheckIn,
+	model.OnboardingFirstPurchase,
+	model.OnboardingFirstScratch,
+}
+
+// defaultOnboardingTitles are the titles of steps an admin has not renamed
+var defaultOnboardingTitles = map[model.OnboardingStepKey]string{
+	model.OnboardingLinkAccount:   "关联 LinuxDO 账号",
+	model.OnboardingFirstCheckIn:  "签到领取积分",
+	model.OnboardingFirstPurchase: "购买第一张彩票",
+	model.OnboardingFirstScratch:  "刮开第一张彩票",
+}
+
+// OnboardingStepConfig configures a step of the first-run checklist
+type OnboardingStepConfig struct {
+	Key          model.OnboardingStepKey `json:"key"`
+	Title        string                  `json:"title"`
+	Description  string                  `json:"description"`
+	RewardPoints int                     `json:"reward_points"`
+	Enabled      bool                    `json:"enabled"`
+}
+
+// OnboardingConfig is the first-run checklist of a tenant, steps in display order
+type OnboardingConfig struct {
+	Steps []OnboardingStepConfig `json:"steps"`
+}
+
+// OnboardingProgressStep is a checklist step as shown to a user
+type OnboardingProgressStep struct {
+	Key          model.OnboardingStepKey `json:"key"`
+	Title        string                  `json:"title"`
+	Description  string                  `json:"description"`
+	RewardPoints int                     `json:"reward_points"`
+	Completed    bool                    `json:"completed"`
+	CompletedAt  *time.Time              `json:"completed_at,omitempty"`
+}
+
+// OnboardingProgress is the first-run checklist of a user
+type OnboardingProgress struct {
+	Steps     []OnboardingProgressStep `json:"steps"`
+	Completed int                      `json:"completed"`
+	Total     int                      `json:"total"`
+	Finished  bool                     `json:"finished"`
+}
+
+// OnboardingService tracks the first-run checklist of users. Services report
+// the actions that complete a step once they are committed; the first
+// completion of an enabled step credits its reward points. The checklist is
+// kept in the system config of each tenant; without one every step is
+// enabled and unrewarded.
+type OnboardingService struct {
+	db           *gorm.DB
+	adminService *AdminService
+}
+
+// NewOnboardingService creates a new onboarding service
+func NewOnboardingService(db *gorm.DB, adminService *AdminService) *OnboardingService {
+	return &OnboardingService{db: db, adminService: adminService}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *OnboardingService) ForTenant(tenantID uint) *OnboardingService {
+	return &OnboardingService{
+		db:           repository.ScopeTenant(s.db, tenantID),
+		adminService: s.adminService.ForTenant(tenantID),
+	}
+}
+
+// GetConfig returns the checklist with every step, disabled ones included
+func (s *OnboardingService) GetConfig() (*OnboardingConfig, error) {
+	config := &OnboardingConfig{}
+	value, err := s.adminService.GetConfigValue(ConfigKeyOnboarding)
+	if err != nil && !errors.Is(err, ErrConfigNotFound) {
+		return nil, err
+	}
+	if value != "" {
+		if err := json.Unmarshal([]byte(value), config); err != nil {
+			return nil, err
+		}
+	}
+	if len(config.Steps) == 0 {
+		for _, key := range onboardingStepOrder {
+			config.Steps = append(config.Steps, OnboardingStepConfig{Key: key, Title: defaultOnboardingTitles[key], Enabled: true})
+		}
+	}
+	return config, nil
+}
+
+// UpdateConfig validates and replaces the checklist. Steps are shown in the
+// order given; steps left out are disabled.
+func (s *OnboardingService) UpdateConfig(adminID uint, req OnboardingConfig) (*OnboardingConfig, error) {
+	config := &OnboardingConfig{Steps: []OnboardingStepConfig{}}
+	seen := make(map[model.OnboardingStepKey]bool)
+	for _, step := range req.Steps {
+		if _, known := defaultOnboardingTitles[step.Key]; !known || seen[step.Key] {
+			return nil, ErrInvalidOnboardingStep
+		}
+		if step.RewardPoints < 0 || step.RewardPoints > maxStreakPoints ||
+			utf8.RuneCountInString(step.Title) > maxOnboardingTitle ||
+			utf8.RuneCountInString(step.Description) > maxOnboardingDescription {
+			return nil, ErrInvalidOnboardingStep
+		}
+		if step.Title == "" {
+			step.Title = defaultOnboardingTitles[step.Key]
+		}
+		seen[step.Key] = true
+		config.Steps = append(config.Steps, step)
+	}
+	for _, key := range onboardingStepOrder {
+		if !seen[key] {
+			config.Steps = append(config.Steps, OnboardingStepConfig{Key: key, Title: defaultOnboardingTitles[key]})
+		}
+	}
+
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	err = s.adminService.configs().Transaction(func(tx *gorm.DB) error {
+		if err := s.adminService.upsertConfig(tx, ConfigKeyOnboarding, string(configJSON)); err != nil {
+			return err
+		}
+		adminLog := model.AdminLog{
+			AdminID:    adminID,
+			Action:     "update_onboarding",
+			TargetType: "system",
+			TargetID:   0,
+			Details:    string(configJSON),
+		}
+		return tx.Create(&adminLog).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// GetProgress returns the enabled checklist steps of a user. Steps the user
+// had already done before the checklist tracked them are marked completed,
+// without a reward.
+func (s *OnboardingService) GetProgress(userID uint) (*OnboardingProgress, error) {
+	config, err := s.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	done, err := s.completedSteps(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	progress := &OnboardingProgress{Steps: []OnboardingProgressStep{}}
+	for _, step := range config.Steps {
+		if !step.Enabled {
+			continue
+		}
+		record, ok := done[step.Key]
+		if !ok {
+			if record, ok, err = s.backfill(userID, step.Key); err != nil {
+				return nil, err
+			}
+		}
+		item := OnboardingProgressStep{
+			Key:          step.Key,
+			Title:        step.Title,
+			Description:  step.Description,
+			RewardPoints: step.RewardPoints,
+			Completed:    ok,
+		}
+		if ok {
+			completedAt := record.CompletedAt
+			item.CompletedAt = &completedAt
+			item.RewardPoints = record.RewardPoints
+			progress.Completed++
+		}
+		progress.Steps = append(progress.Steps, item)
+	}
+	progress.Total = len(progress.Steps)
+	progress.Finished = progress.Completed == progress.Total
+	return progress, nil
+}
+
+// Complete records that a user completed a step and credits its reward the
+// first time. Disabled steps are not recorded.
+func (s *OnboardingService) Complete(userID uint, key model.OnboardingStepKey) error {
+	var recorded int64
+	if err := s.db.Model(&model.OnboardingStep{}).Where("user_id = ? AND step = ?", userID, key).
+		Count(&recorded).Error; err != nil || recorded > 0 {
+		return err
+	}
+	config, err := s.GetConfig()
+	if err != nil {
+		return err
+	}
+	var step *OnboardingStepConfig
+	for i := range config.Steps {
+		if config.Steps[i].Key == key && config.Steps[i].Enabled {
+			step = &config.Steps[i]
+		}
+	}
+	if step == nil {
+		return nil
+	}
+
+	return repository.Transaction(s.db, func(tx *gorm.DB) error {
+		record := model.OnboardingStep{UserID: userID, Step: key, RewardPoints: step.RewardPoints, CompletedAt: time.Now()}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+		if result.Error != nil || result.RowsAffected == 0 || step.RewardPoints == 0 {
+			return result.Error
+		}
+
+		var wallet model.Wallet
+		if err := lockWallet(tx, userID, &wallet); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrWalletNotFound
+			}
+			return err
+		}
+		wallet.Balance += step.RewardPoints
+		if err := tx.Save(&wallet).Error; err != nil {
+			return err
+		}
+		transaction := model.Transaction{
+			TenantID:    wallet.TenantID,
+			WalletID:    wallet.ID,
+			Type:        model.TransactionTypeOnboarding,
+			Amount:      step.RewardPoints,
+			Description: "新手任务奖励：" + step.Title,
+			ReferenceID: record.ID,
+		}
+		return tx.Create(&transaction).Error
+	})
+}
+
+// completedSteps returns the recorded steps of a user by key
+func (s *OnboardingService) completedSteps(userID uint) (map[model.OnboardingStepKey]model.OnboardingStep, error) {
+	var records []model.OnboardingStep
+	if err := s.db.Where("user_id = ?", userID).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	done := make(map[model.OnboardingStepKey]model.OnboardingStep, len(records))
+	for _, record := range records {
+		done[record.Step] = record
+	}
+	return done, nil
+}
+
+// backfill records a step without a reward when the user's history shows it
+// was done before the checklist tracked it. Linking the account leaves no
+// history; it completes on the next LinuxDO sign-in.
+func (s *OnboardingService) backfill(userID uint, key model.OnboardingStepKey) (model.OnboardingStep, bool, error) {
+	var query *gorm.DB
+	switch key {
+	case model.OnboardingFirstCheckIn:
+		query = s.db.Model(&model.CheckIn{}).Where("user_id = ?", userID)
+	case model.OnboardingFirstPurchase:
+		query = s.db.Model(&model.Ticket{}).Where("user_id = ?", userID)
+	case model.OnboardingFirstScratch:
+		query = s.db.Model(&model.Ticket{}).Where("user_id = ? AND scratched_at IS NOT NULL", userID)
+	default:
+		return model.OnboardingStep{}, false, nil
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil || count == 0 {
+		return model.OnboardingStep{}, false, err
+	}
+
+	record := model.OnboardingStep{UserID: userID, Step: key, CompletedAt: time.Now()}
+	if err := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&record).Error; err != nil {
+		return model.OnboardingStep{}, false, err
+	}
+	return record, true, nil
+}
+
+// completeOnboarding reports a completed step to onboarding, which may be
+// nil. Onboarding errors never undo the action that completed the step.
+func completeOnboarding(onboarding *OnboardingService, userID uint, key model.OnboardingStepKey) {
+	if onboarding == nil {
+		return
+	}
+	if err := onboarding.Complete(userID, key); err != nil {
+		logger.FromContext(onboarding.db.Statement.Context).Warn("Completing onboarding step %s of user %d failed: %v", key, userID, err)
+	}
+}
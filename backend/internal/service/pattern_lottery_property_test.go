@@ -29,7 +29,11 @@ func setupPatternLotteryTestDB(t *testing.T) *gorm.DB {
 		&model.LotteryType{},
 		&model.PrizeLevel{},
 		&model.PrizePool{},
+		&model.Jackpot{},
 		&model.Ticket{},
+		&model.TicketHistory{},
+		&model.TicketEvent{},
+		&model.StockReservation{},
 	)
 	if err != nil {
 		t.Fatalf("Failed to migrate test database: %v", err)
@@ -399,7 +399,7 @@ func (s *PatternLotteryService) GeneratePatternTicket(userID, lotteryTypeID uint
 func (s *PatternLotteryService) ScratchPatternArea(ticketID uint, areaIndex int) (*PatternScratchResult, error) {
 	// Get ticket
 	var ticket model.Ticket
-	if err := s.db.Preload("LotteryType").First(&ticket, ticketID).Error; err != nil {
+	if err := s.db.Preload("LotteryType", includeDeleted).First(&ticket, ticketID).Error; err != nil {
 		return nil, err
 	}
 
@@ -433,7 +433,7 @@ func (s *PatternLotteryService) ScratchPatternArea(ticketID uint, areaIndex int)
 func (s *PatternLotteryService) GetPatternTicketAreas(ticketID uint) ([]PatternAreaData, *PatternConfig, error) {
 	// Get ticket
 	var ticket model.Ticket
-	if err := s.db.Preload("LotteryType").First(&ticket, ticketID).Error; err != nil {
+	if err := s.db.Preload("LotteryType", includeDeleted).First(&ticket, ticketID).Error; err != nil {
 		return nil, nil, err
 	}
 
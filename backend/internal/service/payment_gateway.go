@@ -0,0 +1,260 @@
+package service
+
+import (
+	"crypto/md5"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/pkg/money"
+)
+
+// Payment gateway names, selected by the payment_gateway setting
+const (
+	PaymentGatewayEPay   = "epay"
+	PaymentGatewayManual = "manual" // Paid offline and confirmed by an admin
+)
+
+// ConfigKeyPaymentGateway selects the gateway new recharge orders are paid
+// through; orders keep the gateway they were created with
+const ConfigKeyPaymentGateway = "payment_gateway"
+
+var (
+	ErrUnknownPaymentGateway = errors.New("unknown payment gateway")
+	ErrGatewayQueryFailed    = errors.New("payment gateway query failed")
+)
+
+// PaymentGateway is a payment provider recharge orders are paid through
+type PaymentGateway interface {
+	// Name returns the gateway name stored on its orders
+	Name() string
+	// CreateOrder returns the URL the user pays the order at, or "" when
+	// the order is paid some other way
+	CreateOrder(order *model.PaymentOrder) (string, error)
+	// VerifyCallback checks the parameters of a payment notification and
+	// returns the payment it reports, or ErrInvalidSignature
+	VerifyCallback(params map[string]string) (*GatewayPayment, error)
+	// QueryOrder asks the provider for the state of an order
+	QueryOrder(order *model.PaymentOrder) (*GatewayPayment, error)
+}
+
+// GatewayPayment is the state of an order as reported by its gateway
+type GatewayPayment struct {
+	OrderNo     string
+	TradeNo     string // Trade number of the provider
+	PaymentType string // e.g. alipay, wxpay
+	Money       string // Amount paid in major units, as reported
+	Paid        bool
+}
+
+// validPaymentGateway reports whether name is a known gateway
+func validPaymentGateway(name string) bool {
+	return name == PaymentGatewayEPay || name == PaymentGatewayManual
+}
+
+// newPaymentGateway creates the named gateway with the settings of a
+// tenant. New providers are added here.
+func newPaymentGateway(name string, settings *AdminService) (PaymentGateway, error) {
+	switch name {
+	case "", PaymentGatewayEPay:
+		config, err := settings.GetEPayConfig()
+		if err != nil {
+			return nil, err
+		}
+		return newEPayGateway(config)
+	case PaymentGatewayManual:
+		return manualGateway{}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPaymentGateway, name)
+	}
+}
+
+// epayQueryTimeout bounds order queries to the EPay gateway
+const epayQueryTimeout = 10 * time.Second
+
+// epayGateway pays orders through an EPay compatible gateway
+type epayGateway struct {
+	config     *EPayConfig
+	httpClient *http.Client
+}
+
+// newEPayGateway creates the EPay gateway. Without a merchant ID and secret
+// it refuses to run, since callbacks signed with an empty secret could be
+// forged by anyone.
+func newEPayGateway(config *EPayConfig) (*epayGateway, error) {
+	if config.MerchantID == "" || config.Secret == "" {
+		return nil, ErrPaymentConfigError
+	}
+	return &epayGateway{config: config, httpClient: &http.Client{Timeout: epayQueryTimeout}}, nil
+}
+
+// Name returns the gateway name
+func (g *epayGateway) Name() string { return PaymentGatewayEPay }
+
+// CreateOrder returns the signed EPay payment URL of an order
+func (g *epayGateway) CreateOrder(order *model.PaymentOrder) (string, error) {
+	// Get callback URL from config or use default
+	notifyURL := g.config.CallbackURL
+	if notifyURL == "" {
+		notifyURL = "http://localhost:8080/api/payment/callback"
+	}
+
+	params := map[string]string{
+		"pid":          g.config.MerchantID,
+		"type":         "alipay", // Default to alipay, can be made configurable
+		"out_trade_no": order.OrderNo,
+		"notify_url":   notifyURL,
+		"return_url":   notifyURL, // Can be different for user redirect
+		"name":         "积分充值",
+		"money":        money.New(int64(order.Amount), orderCurrency(order)).String(),
+	}
+	params["sign"] = epaySign(params, g.config.Secret)
+	params["sign_type"] = "MD5"
+
+	u, err := url.Parse(epayGatewayURL(g.config) + "/submit.php")
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	for k, v := range params {
+		q.Set(k, v)
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// VerifyCallback checks an EPay notification: the MD5 signature over every
+// parameter it carries, compared in constant time, and the merchant ID.
+func (g *epayGateway) VerifyCallback(params map[string]string) (*GatewayPayment, error) {
+	if signType := params["sign_type"]; signType != "" && !strings.EqualFold(signType, "MD5") {
+		return nil, ErrInvalidSignature
+	}
+	if !epayVerifySign(params, g.config.Secret) {
+		return nil, ErrInvalidSignature
+	}
+	if subtle.ConstantTimeCompare([]byte(params["pid"]), []byte(g.config.MerchantID)) != 1 {
+		return nil, ErrInvalidSignature
+	}
+	return &GatewayPayment{
+		OrderNo:     params["out_trade_no"],
+		TradeNo:     params["trade_no"],
+		PaymentType: params["type"],
+		Money:       params["money"],
+		Paid:        params["trade_status"] == "TRADE_SUCCESS",
+	}, nil
+}
+
+// epayOrderResponse is the answer of the EPay order query
+type epayOrderResponse struct {
+	Code       json.Number `json:"code"`
+	Msg        string      `json:"msg"`
+	PID        json.Number `json:"pid"`
+	TradeNo    string      `json:"trade_no"`
+	OutTradeNo string      `json:"out_trade_no"`
+	Type       string      `json:"type"`
+	Money      string      `json:"money"`
+	Status     json.Number `json:"status"` // 1 once paid
+}
+
+// QueryOrder asks the EPay gateway for the state of an order
+func (g *epayGateway) QueryOrder(order *model.PaymentOrder) (*GatewayPayment, error) {
+	query := url.Values{}
+	query.Set("act", "order")
+	query.Set("pid", g.config.MerchantID)
+	query.Set("key", g.config.Secret)
+	query.Set("out_trade_no", order.OrderNo)
+
+	resp, err := g.httpClient.Get(epayGatewayURL(g.config) + "/api.php?" + query.Encode())
+	if err != nil {
+		// The error carries the URL, which carries the key
+		return nil, ErrGatewayQueryFailed
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%w: HTTP %d", ErrGatewayQueryFailed, resp.StatusCode)
+	}
+
+	var body epayOrderResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("%w: invalid response", ErrGatewayQueryFailed)
+	}
+	if code, _ := body.Code.Int64(); code != 1 {
+		return nil, fmt.Errorf("%w: %s", ErrGatewayQueryFailed, body.Msg)
+	}
+	if body.OutTradeNo != order.OrderNo || (body.PID != "" && body.PID.String() != g.config.MerchantID) {
+		return nil, fmt.Errorf("%w: response for another order", ErrGatewayQueryFailed)
+	}
+	status, _ := body.Status.Int64()
+	return &GatewayPayment{
+		OrderNo:     body.OutTradeNo,
+		TradeNo:     body.TradeNo,
+		PaymentType: body.Type,
+		Money:       body.Money,
+		Paid:        status == 1,
+	}, nil
+}
+
+// epaySign calculates the EPay MD5 signature: the non-empty parameters other
+// than sign and sign_type, sorted by key, joined as a query string and
+// followed by the secret
+func epaySign(params map[string]string, secret string) string {
+	keys := make([]string, 0, len(params))
+	for k, v := range params {
+		if v != "" && k != "sign" && k != "sign_type" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + "=" + params[k]
+	}
+	hash := md5.Sum([]byte(strings.Join(parts, "&") + secret))
+	return hex.EncodeToString(hash[:])
+}
+
+// epayVerifySign reports whether params carry a valid signature
+func epayVerifySign(params map[string]string, secret string) bool {
+	if secret == "" {
+		return false
+	}
+	expected := epaySign(params, secret)
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(strings.ToLower(params["sign"]))) == 1
+}
+
+// manualGateway is for payments made offline, such as bank transfers: the
+// order has no payment page and is marked paid by an admin once the money
+// has arrived
+type manualGateway struct{}
+
+// Name returns the gateway name
+func (manualGateway) Name() string { return PaymentGatewayManual }
+
+// CreateOrder returns no payment URL
+func (manualGateway) CreateOrder(order *model.PaymentOrder) (string, error) { return "", nil }
+
+// VerifyCallback refuses every notification, since nobody else can report a
+// manual payment
+func (manualGateway) VerifyCallback(params map[string]string) (*GatewayPayment, error) {
+	return nil, ErrInvalidSignature
+}
+
+// QueryOrder reports the order as recorded
+func (manualGateway) QueryOrder(order *model.PaymentOrder) (*GatewayPayment, error) {
+	return &GatewayPayment{
+		OrderNo:     order.OrderNo,
+		TradeNo:     order.TradeNo,
+		PaymentType: order.PaymentType,
+		Money:       money.New(int64(order.Amount), orderCurrency(order)).String(),
+		Paid:        order.Status == "paid",
+	}, nil
+}
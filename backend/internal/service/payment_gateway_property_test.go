@@ -0,0 +1,230 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"scratch-lottery/internal/model"
+)
+
+// setupPaymentGatewayTest creates a payment service with EPay configured and
+// a user with a wallet
+func setupPaymentGatewayTest(t *testing.T) (*PaymentService, *AdminService, model.User) {
+	db := setupTenantTestDB(t)
+	if err := db.AutoMigrate(&model.PaymentOrder{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	walletService := NewWalletService(db)
+	adminService := NewAdminService(db, walletService)
+	paymentService := NewPaymentService(db, adminService, walletService, nil)
+
+	enabled, merchant, secret := true, "10001", "test_secret_key"
+	if _, err := adminService.UpdateSystemSettings(1, UpdateSystemSettingsRequest{
+		PaymentEnabled: &enabled,
+		EPayMerchantID: &merchant,
+		EPaySecret:     &secret,
+	}); err != nil {
+		t.Fatalf("UpdateSystemSettings failed: %v", err)
+	}
+
+	user := model.User{LinuxdoID: "gateway_user", Username: "Payer", Role: "user"}
+	db.Create(&user)
+	db.Create(&model.Wallet{UserID: user.ID})
+	return paymentService, adminService, user
+}
+
+// signedEPayCallback returns the parameters of a successful EPay
+// notification for an order, signed with the test secret
+func signedEPayCallback(orderNo, amount string, extra map[string]string) map[string]string {
+	params := map[string]string{
+		"pid":          "10001",
+		"trade_no":     "T1",
+		"out_trade_no": orderNo,
+		"type":         "alipay",
+		"name":         "积分充值",
+		"money":        amount,
+		"trade_status": "TRADE_SUCCESS",
+	}
+	for k, v := range extra {
+		params[k] = v
+	}
+	params["sign"] = epaySign(params, "test_secret_key")
+	params["sign_type"] = "MD5"
+	return params
+}
+
+// EPay callbacks are verified over every parameter they carry, and forged or
+// misdirected ones are refused
+func TestEPayGatewayCallbackVerification(t *testing.T) {
+	paymentService, _, user := setupPaymentGatewayTest(t)
+	order, err := paymentService.CreateRechargeOrder(user.ID, RechargeRequest{Amount: 10})
+	if err != nil {
+		t.Fatalf("CreateRechargeOrder failed: %v", err)
+	}
+	if order.Gateway != PaymentGatewayEPay {
+		t.Fatalf("Expected an EPay order, got %q", order.Gateway)
+	}
+
+	// Parameters beyond the known ones are signed too
+	tampered := signedEPayCallback(order.OrderNo, "10.00", map[string]string{"param": "a"})
+	tampered["param"] = "b"
+	wrongPID := signedEPayCallback(order.OrderNo, "10.00", map[string]string{"pid": "10002"})
+	sha256Type := signedEPayCallback(order.OrderNo, "10.00", nil)
+	sha256Type["sign_type"] = "SHA256"
+	for name, params := range map[string]map[string]string{
+		"tampered extra param": tampered,
+		"other merchant":       wrongPID,
+		"other sign type":      sha256Type,
+	} {
+		if err := paymentService.ProcessGatewayCallback(PaymentGatewayEPay, params); err != ErrInvalidSignature {
+			t.Errorf("%s: expected ErrInvalidSignature, got %v", name, err)
+		}
+	}
+
+	// A valid EPay callback cannot complete an order on another gateway's URL
+	valid := signedEPayCallback(order.OrderNo, "10.00", map[string]string{"param": "a"})
+	if err := paymentService.ProcessGatewayCallback(PaymentGatewayManual, valid); err != ErrInvalidSignature {
+		t.Errorf("Expected the manual gateway to refuse callbacks, got %v", err)
+	}
+	if err := paymentService.ProcessGatewayCallback("paypal", valid); err == nil {
+		t.Error("Expected an unknown gateway to be refused")
+	}
+
+	if err := paymentService.ProcessGatewayCallback(PaymentGatewayEPay, valid); err != nil {
+		t.Fatalf("ProcessGatewayCallback failed: %v", err)
+	}
+	status, _ := paymentService.GetOrderByNo(order.OrderNo)
+	if status.Status != "paid" || status.TradeNo != "T1" {
+		t.Errorf("Expected the order paid, got %+v", status)
+	}
+
+	// Without a secret nothing verifies
+	if epayVerifySign(map[string]string{"pid": "10001", "sign": epaySign(map[string]string{"pid": "10001"}, "")}, "") {
+		t.Error("Expected an empty secret to verify nothing")
+	}
+}
+
+// Manual orders have no payment page, cannot be completed by a callback and
+// are marked paid by an admin
+func TestManualPaymentGateway(t *testing.T) {
+	paymentService, adminService, user := setupPaymentGatewayTest(t)
+
+	unknown := "paypal"
+	if _, err := adminService.UpdateSystemSettings(1, UpdateSystemSettingsRequest{PaymentGateway: &unknown}); err != ErrUnknownPaymentGateway {
+		t.Fatalf("Expected ErrUnknownPaymentGateway, got %v", err)
+	}
+	epayOrder, err := paymentService.CreateRechargeOrder(user.ID, RechargeRequest{Amount: 10})
+	if err != nil {
+		t.Fatalf("CreateRechargeOrder failed: %v", err)
+	}
+
+	manual := PaymentGatewayManual
+	if _, err := adminService.UpdateSystemSettings(1, UpdateSystemSettingsRequest{PaymentGateway: &manual}); err != nil {
+		t.Fatalf("UpdateSystemSettings failed: %v", err)
+	}
+	if settings, _ := adminService.GetSystemSettings(); settings.PaymentGateway != PaymentGatewayManual {
+		t.Errorf("Expected the manual gateway selected, got %q", settings.PaymentGateway)
+	}
+
+	order, err := paymentService.CreateRechargeOrder(user.ID, RechargeRequest{Amount: 20})
+	if err != nil {
+		t.Fatalf("CreateRechargeOrder failed: %v", err)
+	}
+	if order.Gateway != PaymentGatewayManual || order.PaymentURL != "" {
+		t.Fatalf("Expected a manual order without payment URL, got %+v", order)
+	}
+
+	// Orders keep their gateway: EPay cannot complete the manual order, while
+	// the earlier EPay order is still paid through EPay
+	if err := paymentService.ProcessGatewayCallback(PaymentGatewayEPay, signedEPayCallback(order.OrderNo, "20.00", nil)); err != ErrOrderNotFound {
+		t.Errorf("Expected ErrOrderNotFound, got %v", err)
+	}
+	if _, err := paymentService.ConfirmManualOrder(1, epayOrder.OrderNo, ConfirmManualOrderRequest{TradeNo: "BANK-1"}); err != ErrOrderNotManual {
+		t.Errorf("Expected ErrOrderNotManual, got %v", err)
+	}
+	if err := paymentService.ProcessGatewayCallback(PaymentGatewayEPay, signedEPayCallback(epayOrder.OrderNo, "10.00", nil)); err != nil {
+		t.Errorf("Expected the EPay order still paid through EPay, got %v", err)
+	}
+
+	confirmed, err := paymentService.ConfirmManualOrder(1, order.OrderNo, ConfirmManualOrderRequest{TradeNo: "BANK-1", Note: "转账已到账"})
+	if err != nil {
+		t.Fatalf("ConfirmManualOrder failed: %v", err)
+	}
+	if confirmed.Status != "paid" || confirmed.PaymentType != PaymentGatewayManual || confirmed.TradeNo != "BANK-1" {
+		t.Errorf("Unexpected confirmed order %+v", confirmed)
+	}
+	if _, err := paymentService.ConfirmManualOrder(1, order.OrderNo, ConfirmManualOrderRequest{TradeNo: "BANK-1"}); err != ErrOrderNotPending {
+		t.Errorf("Expected ErrOrderNotPending, got %v", err)
+	}
+
+	var wallet model.Wallet
+	paymentService.db.Where("user_id = ?", user.ID).First(&wallet)
+	if wallet.Balance != 50+100+200 {
+		t.Errorf("Expected balance %d, got %d", 50+100+200, wallet.Balance)
+	}
+	var logs int64
+	paymentService.db.Model(&model.AdminLog{}).Where("action = ?", "confirm_manual_payment").Count(&logs)
+	if logs != 1 {
+		t.Errorf("Expected one admin log, got %d", logs)
+	}
+}
+
+// Syncing asks EPay for the state of a pending order and completes it once
+// the gateway reports it paid with the right amount
+func TestEPayGatewaySyncOrder(t *testing.T) {
+	paymentService, adminService, user := setupPaymentGatewayTest(t)
+
+	reported := map[string]interface{}{"code": 1, "pid": 10001, "trade_no": "T9", "type": "wxpay", "money": "30.00", "status": 0}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != "/api.php" || q.Get("act") != "order" || q.Get("key") != "test_secret_key" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		body := map[string]interface{}{"out_trade_no": q.Get("out_trade_no")}
+		for k, v := range reported {
+			body[k] = v
+		}
+		json.NewEncoder(w).Encode(body)
+	}))
+	defer server.Close()
+	gatewayURL := server.URL
+	if _, err := adminService.UpdateSystemSettings(1, UpdateSystemSettingsRequest{EPayGatewayURL: &gatewayURL}); err != nil {
+		t.Fatalf("UpdateSystemSettings failed: %v", err)
+	}
+
+	order, err := paymentService.CreateRechargeOrder(user.ID, RechargeRequest{Amount: 30})
+	if err != nil {
+		t.Fatalf("CreateRechargeOrder failed: %v", err)
+	}
+
+	// Not paid yet
+	synced, err := paymentService.SyncOrder(order.OrderNo)
+	if err != nil || synced.Status != "pending" {
+		t.Fatalf("Expected the order still pending, got %+v (err %v)", synced, err)
+	}
+
+	// Paid a different amount
+	reported["status"], reported["money"] = 1, "3.00"
+	if _, err := paymentService.SyncOrder(order.OrderNo); err != ErrPaymentAmountMismatch {
+		t.Errorf("Expected ErrPaymentAmountMismatch, got %v", err)
+	}
+
+	reported["money"] = "30.00"
+	synced, err = paymentService.SyncOrder(order.OrderNo)
+	if err != nil || synced.Status != "paid" || synced.TradeNo != "T9" || synced.PaymentType != "wxpay" {
+		t.Fatalf("Expected the order paid, got %+v (err %v)", synced, err)
+	}
+
+	// Syncing again leaves the paid order alone
+	if synced, err = paymentService.SyncOrder(order.OrderNo); err != nil || synced.Status != "paid" {
+		t.Errorf("Expected the paid order returned, got %+v (err %v)", synced, err)
+	}
+	var wallet model.Wallet
+	paymentService.db.Where("user_id = ?", user.ID).First(&wallet)
+	if wallet.Balance != 50+300 {
+		t.Errorf("Expected balance %d, got %d", 50+300, wallet.Balance)
+	}
+}
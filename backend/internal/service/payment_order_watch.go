@@ -0,0 +1,105 @@
+package service
+
+import (
+	"errors"
+	"sync"
+
+	"scratch-lottery/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// maxOrderWatchesPerUser bounds the open order subscriptions of a user
+const maxOrderWatchesPerUser = 5
+
+var (
+	ErrTooManyOrderWatches = errors.New("too many order watches from this user")
+)
+
+// paymentOrderHub keeps the listeners of watched payment orders. It is shared
+// by all tenant copies of the payment service, as order numbers are unique.
+type paymentOrderHub struct {
+	listeners map[string]map[chan *OrderResponse]struct{}
+	users     map[uint]int
+	mutex     sync.Mutex
+}
+
+func newPaymentOrderHub() *paymentOrderHub {
+	return &paymentOrderHub{
+		listeners: make(map[string]map[chan *OrderResponse]struct{}),
+		users:     make(map[uint]int),
+	}
+}
+
+// publish sends an order's new state to its listeners. Slow listeners miss
+// the update rather than block the caller.
+func (h *paymentOrderHub) publish(order *OrderResponse) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for ch := range h.listeners[order.OrderNo] {
+		select {
+		case ch <- order:
+		default:
+		}
+	}
+}
+
+// OrderFinal reports whether an order status no longer changes
+func OrderFinal(status string) bool {
+	return status != "pending"
+}
+
+// WatchOrder starts watching a payment order of the user. It returns the
+// current state of the order and a channel receiving later status changes.
+// Orders of other users are reported as not found. The returned cancel func
+// must be called when the listener goes away.
+func (s *PaymentService) WatchOrder(userID uint, orderNo string) (*OrderResponse, <-chan *OrderResponse, func(), error) {
+	hub := s.orderHub
+
+	hub.mutex.Lock()
+	if hub.users[userID] >= maxOrderWatchesPerUser {
+		hub.mutex.Unlock()
+		return nil, nil, nil, ErrTooManyOrderWatches
+	}
+	hub.users[userID]++
+	ch := make(chan *OrderResponse, 4)
+	if hub.listeners[orderNo] == nil {
+		hub.listeners[orderNo] = make(map[chan *OrderResponse]struct{})
+	}
+	hub.listeners[orderNo][ch] = struct{}{}
+	hub.mutex.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			hub.mutex.Lock()
+			defer hub.mutex.Unlock()
+
+			if hub.users[userID]--; hub.users[userID] <= 0 {
+				delete(hub.users, userID)
+			}
+			if listeners, ok := hub.listeners[orderNo]; ok {
+				if _, ok := listeners[ch]; ok {
+					delete(listeners, ch)
+					close(ch)
+				}
+				if len(listeners) == 0 {
+					delete(hub.listeners, orderNo)
+				}
+			}
+		})
+	}
+
+	// Listening before loading the order, so a callback landing in between is
+	// not missed
+	var order model.PaymentOrder
+	if err := s.db.Where("order_no = ? AND user_id = ?", orderNo, userID).First(&order).Error; err != nil {
+		cancel()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, nil, ErrOrderNotFound
+		}
+		return nil, nil, nil, err
+	}
+	return s.toOrderResponse(&order), ch, cancel, nil
+}
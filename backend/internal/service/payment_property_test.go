@@ -2,7 +2,11 @@ package service
 
 import (
 	"fmt"
+	"strings"
 	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
 
 	"github.com/leanovate/gopter"
 	"github.com/leanovate/gopter/gen"
@@ -465,3 +469,183 @@ func TestSignatureWithEmptyParams(t *testing.T) {
 		t.Errorf("Signature length should be 32, got %d", len(sign))
 	}
 }
+
+// Recharge orders are priced in exact fen and a callback only credits points
+// when the paid amount matches the order to the fen.
+func TestPaymentCallbackAmountMatchesOrder(t *testing.T) {
+	db := setupTenantTestDB(t)
+	if err := db.AutoMigrate(&model.PaymentOrder{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	walletService := NewWalletService(db)
+	adminService := NewAdminService(db, walletService)
+	paymentService := NewPaymentService(db, adminService, walletService, nil)
+
+	enabled, merchant, secret := true, "10001", "test_secret_key"
+	if _, err := adminService.UpdateSystemSettings(1, UpdateSystemSettingsRequest{
+		PaymentEnabled: &enabled,
+		EPayMerchantID: &merchant,
+		EPaySecret:     &secret,
+	}); err != nil {
+		t.Fatalf("UpdateSystemSettings failed: %v", err)
+	}
+
+	user := model.User{LinuxdoID: "recharge_user", Username: "Payer", Role: "user"}
+	db.Create(&user)
+	db.Create(&model.Wallet{UserID: user.ID})
+
+	order, err := paymentService.CreateRechargeOrder(user.ID, RechargeRequest{Amount: 12})
+	if err != nil {
+		t.Fatalf("CreateRechargeOrder failed: %v", err)
+	}
+	if order.Points != 120 || !strings.Contains(order.PaymentURL, "money=12.00") {
+		t.Fatalf("Unexpected order %+v", order)
+	}
+
+	callback := func(amount string) PaymentCallbackRequest {
+		params := map[string]string{
+			"pid":          merchant,
+			"trade_no":     "T1",
+			"out_trade_no": order.OrderNo,
+			"type":         "alipay",
+			"name":         "积分充值",
+			"money":        amount,
+			"trade_status": "TRADE_SUCCESS",
+		}
+		return PaymentCallbackRequest{
+			PID:         merchant,
+			TradeNo:     "T1",
+			OutTradeNo:  order.OrderNo,
+			Type:        "alipay",
+			Name:        "积分充值",
+			Money:       amount,
+			TradeStatus: "TRADE_SUCCESS",
+			Sign:        paymentService.CalculateSign(params, secret),
+			SignType:    "MD5",
+		}
+	}
+
+	for _, amount := range []string{"11.99", "12.01", "0.12", "12.001", ""} {
+		if err := paymentService.ProcessCallback(callback(amount)); err != ErrPaymentAmountMismatch {
+			t.Errorf("Expected ErrPaymentAmountMismatch for %q, got %v", amount, err)
+		}
+	}
+	if err := paymentService.ProcessCallback(callback("12.00")); err != nil {
+		t.Fatalf("ProcessCallback failed: %v", err)
+	}
+
+	var wallet model.Wallet
+	db.Where("user_id = ?", user.ID).First(&wallet)
+	if wallet.Balance != 50+120 {
+		t.Errorf("Expected balance %d, got %d", 50+120, wallet.Balance)
+	}
+	var transaction model.Transaction
+	db.Where("wallet_id = ? AND type = ?", wallet.ID, model.TransactionTypeRecharge).First(&transaction)
+	if transaction.Description != "充值 12.00 元，获得 120 积分" {
+		t.Errorf("Unexpected description %q", transaction.Description)
+	}
+}
+
+// Order watches: the owner of an order sees it paid the moment the callback
+// is booked, and nobody else can watch it.
+func TestPaymentOrderWatch(t *testing.T) {
+	db := setupTenantTestDB(t)
+	if err := db.AutoMigrate(&model.PaymentOrder{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	walletService := NewWalletService(db)
+	adminService := NewAdminService(db, walletService)
+	paymentService := NewPaymentService(db, adminService, walletService, nil)
+
+	enabled, merchant, secret := true, "10001", "test_secret_key"
+	if _, err := adminService.UpdateSystemSettings(1, UpdateSystemSettingsRequest{
+		PaymentEnabled: &enabled,
+		EPayMerchantID: &merchant,
+		EPaySecret:     &secret,
+	}); err != nil {
+		t.Fatalf("UpdateSystemSettings failed: %v", err)
+	}
+
+	owner := model.User{LinuxdoID: "watch_owner", Username: "Owner", Role: "user"}
+	other := model.User{LinuxdoID: "watch_other", Username: "Other", Role: "user"}
+	db.Create(&owner)
+	db.Create(&other)
+	db.Create(&model.Wallet{UserID: owner.ID})
+
+	order, err := paymentService.CreateRechargeOrder(owner.ID, RechargeRequest{Amount: 5})
+	if err != nil {
+		t.Fatalf("CreateRechargeOrder failed: %v", err)
+	}
+
+	if _, _, _, err := paymentService.WatchOrder(other.ID, order.OrderNo); err != ErrOrderNotFound {
+		t.Errorf("Expected ErrOrderNotFound for another user, got %v", err)
+	}
+
+	// The watch is opened on a tenant copy; the callback arrives on the shared service
+	current, updates, cancel, err := paymentService.ForTenant(1).WatchOrder(owner.ID, order.OrderNo)
+	if err != nil {
+		t.Fatalf("WatchOrder failed: %v", err)
+	}
+	defer cancel()
+	if current.Status != "pending" || OrderFinal(current.Status) {
+		t.Fatalf("Expected a pending order, got %q", current.Status)
+	}
+
+	params := map[string]string{
+		"pid":          merchant,
+		"trade_no":     "T2",
+		"out_trade_no": order.OrderNo,
+		"type":         "alipay",
+		"name":         "积分充值",
+		"money":        "5.00",
+		"trade_status": "TRADE_SUCCESS",
+	}
+	if err := paymentService.ProcessCallback(PaymentCallbackRequest{
+		PID:         merchant,
+		TradeNo:     "T2",
+		OutTradeNo:  order.OrderNo,
+		Type:        "alipay",
+		Name:        "积分充值",
+		Money:       "5.00",
+		TradeStatus: "TRADE_SUCCESS",
+		Sign:        paymentService.CalculateSign(params, secret),
+		SignType:    "MD5",
+	}); err != nil {
+		t.Fatalf("ProcessCallback failed: %v", err)
+	}
+
+	select {
+	case update := <-updates:
+		if update.Status != "paid" || !OrderFinal(update.Status) || update.TradeNo != "T2" {
+			t.Errorf("Expected the paid order, got %+v", update)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected a status update after the callback")
+	}
+
+	cancel()
+	if _, ok := <-updates; ok {
+		t.Error("Expected cancel to close the updates channel")
+	}
+
+	// Open watches per user are bounded
+	cancels := make([]func(), 0, maxOrderWatchesPerUser)
+	for i := 0; i < maxOrderWatchesPerUser; i++ {
+		_, _, cancel, err := paymentService.WatchOrder(owner.ID, order.OrderNo)
+		if err != nil {
+			t.Fatalf("WatchOrder %d failed: %v", i, err)
+		}
+		cancels = append(cancels, cancel)
+	}
+	if _, _, _, err := paymentService.WatchOrder(owner.ID, order.OrderNo); err != ErrTooManyOrderWatches {
+		t.Errorf("Expected ErrTooManyOrderWatches, got %v", err)
+	}
+	for _, cancel := range cancels {
+		cancel()
+	}
+	if _, _, cancel, err := paymentService.WatchOrder(owner.ID, order.OrderNo); err != nil {
+		t.Errorf("Expected watching to work again after cancelling, got %v", err)
+	} else {
+		cancel()
+	}
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
+)
+
+// Stale pending orders are looked up at their gateway: paid ones are
+// credited, unpaid ones expire, and disagreements are recorded once for the
+// admins to resolve
+func TestPaymentReconciliation(t *testing.T) {
+	paymentService, adminService, user := setupPaymentGatewayTest(t)
+	db := paymentService.db
+	if err := db.AutoMigrate(&model.PaymentReconciliation{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	// The gateway reports orders by number; unknown orders fail the query
+	reported := make(map[string]map[string]interface{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		orderNo := r.URL.Query().Get("out_trade_no")
+		body := map[string]interface{}{"code": 0, "msg": "订单不存在"}
+		if state, ok := reported[orderNo]; ok {
+			body = map[string]interface{}{"code": 1, "pid": 10001, "trade_no": "T-" + orderNo, "type": "alipay", "out_trade_no": orderNo}
+			for k, v := range state {
+				body[k] = v
+			}
+		}
+		json.NewEncoder(w).Encode(body)
+	}))
+	defer server.Close()
+	gatewayURL := server.URL
+	if _, err := adminService.UpdateSystemSettings(1, UpdateSystemSettingsRequest{EPayGatewayURL: &gatewayURL}); err != nil {
+		t.Fatalf("UpdateSystemSettings failed: %v", err)
+	}
+
+	createOrder := func(amount int) string {
+		order, err := paymentService.CreateRechargeOrder(user.ID, RechargeRequest{Amount: amount})
+		if err != nil {
+			t.Fatalf("CreateRechargeOrder failed: %v", err)
+		}
+		return order.OrderNo
+	}
+	paid, unpaid, mismatched, unknown := createOrder(10), createOrder(20), createOrder(30), createOrder(40)
+	reported[paid] = map[string]interface{}{"status": 1, "money": "10.00"}
+	reported[unpaid] = map[string]interface{}{"status": 0, "money": "20.00"}
+	reported[mismatched] = map[string]interface{}{"status": 1, "money": "3.00"}
+	manual := PaymentGatewayManual
+	if _, err := adminService.UpdateSystemSettings(1, UpdateSystemSettingsRequest{PaymentGateway: &manual}); err != nil {
+		t.Fatalf("UpdateSystemSettings failed: %v", err)
+	}
+	manualOrder := createOrder(50)
+	epay := PaymentGatewayEPay
+	if _, err := adminService.UpdateSystemSettings(1, UpdateSystemSettingsRequest{PaymentGateway: &epay}); err != nil {
+		t.Fatalf("UpdateSystemSettings failed: %v", err)
+	}
+	db.Model(&model.PaymentOrder{}).Where("1 = 1").Update("created_at", time.Now().Add(-time.Hour))
+	fresh := createOrder(60)
+	reported[fresh] = map[string]interface{}{"status": 1, "money": "60.00"}
+
+	reconciler := NewPaymentReconciliationService(db, paymentService, 30*time.Minute)
+	if result, err := reconciler.ForTenant(2).Reconcile(); err != nil || result.Checked != 0 {
+		t.Errorf("Expected no orders of another tenant checked, got %+v (err %v)", result, err)
+	}
+
+	result, err := reconciler.Reconcile()
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if result.Checked != 4 || result.Paid != 1 || result.Expired != 1 || result.Reconciliations != 3 {
+		t.Errorf("Unexpected result %+v", result)
+	}
+	for orderNo, status := range map[string]string{
+		paid: "paid", unpaid: "expired", mismatched: "pending", unknown: "pending", manualOrder: "pending", fresh: "pending",
+	} {
+		if order, _ := paymentService.GetOrderByNo(orderNo); order.Status != status {
+			t.Errorf("Expected order %s %s, got %s", orderNo, status, order.Status)
+		}
+	}
+	if balance := walletBalance(db, user.ID); balance != 50+100 {
+		t.Errorf("Expected only the paid order credited, got %d", balance)
+	}
+
+	// A second run refreshes the open records instead of adding more
+	if result, err = reconciler.Reconcile(); err != nil || result.Checked != 2 || result.Reconciliations != 0 {
+		t.Errorf("Expected the two orders left pending checked again without new records, got %+v (err %v)", result, err)
+	}
+
+	// The expired order is still credited when its payment arrives
+	if err := paymentService.ProcessGatewayCallback(PaymentGatewayEPay, signedEPayCallback(unpaid, "20.00", nil)); err != nil {
+		t.Fatalf("ProcessGatewayCallback failed: %v", err)
+	}
+	if balance := walletBalance(db, user.ID); balance != 50+100+200 {
+		t.Errorf("Expected the late payment credited, got %d", balance)
+	}
+
+	report, err := reconciler.ForTenant(1).GetReconciliations(PaymentReconciliationQuery{})
+	if err != nil {
+		t.Fatalf("GetReconciliations failed: %v", err)
+	}
+	if report.Total != 4 || report.Open[model.PaymentReconciliationMissedCallback] != 1 ||
+		report.Open[model.PaymentReconciliationAmountMismatch] != 1 || report.Open[model.PaymentReconciliationQueryFailed] != 1 ||
+		report.Open[model.PaymentReconciliationPaidAfterExpiry] != 1 {
+		t.Fatalf("Unexpected report %+v", report)
+	}
+	mismatches, _ := reconciler.ForTenant(1).GetReconciliations(PaymentReconciliationQuery{Kind: string(model.PaymentReconciliationAmountMismatch)})
+	if mismatches.Total != 1 || mismatches.Reconciliations[0].OrderNo != mismatched ||
+		mismatches.Reconciliations[0].Amount != "30.00" || mismatches.Reconciliations[0].Reported != "3.00" {
+		t.Fatalf("Unexpected mismatch %+v", mismatches.Reconciliations)
+	}
+
+	id := mismatches.Reconciliations[0].ID
+	if _, err := reconciler.ForTenant(2).Resolve(7, id, ResolvePaymentReconciliationRequest{}); err != ErrPaymentReconciliationNotFound {
+		t.Errorf("Expected ErrPaymentReconciliationNotFound from another tenant, got %v", err)
+	}
+	resolved, err := reconciler.ForTenant(1).Resolve(7, id, ResolvePaymentReconciliationRequest{Note: "已联系网关退款"})
+	if err != nil || resolved.ResolvedAt == nil || resolved.ResolvedBy != 7 {
+		t.Fatalf("Expected the record resolved, got %+v (err %v)", resolved, err)
+	}
+	if _, err := reconciler.ForTenant(1).Resolve(7, id, ResolvePaymentReconciliationRequest{}); err != ErrPaymentReconciliationResolved {
+		t.Errorf("Expected ErrPaymentReconciliationResolved, got %v", err)
+	}
+	var logs int64
+	db.Model(&model.AdminLog{}).Where("action = ?", "resolve_payment_reconciliation").Count(&logs)
+	if logs != 1 {
+		t.Errorf("Expected one admin log, got %d", logs)
+	}
+}
+
+// An order completed twice from copies read while it was pending, as by a
+// late callback racing the reconciler, is credited and counted down once
+func TestPaymentOrderCompletedOnce(t *testing.T) {
+	paymentService, _, user := setupPaymentGatewayTest(t)
+	db := paymentService.db
+	db.Create(&model.UserBadgeCounter{UserID: user.ID})
+	initial := walletBalance(db, user.ID)
+	for i := 0; i < 2; i++ {
+		if _, err := paymentService.CreateRechargeOrder(user.ID, RechargeRequest{Amount: 10}); err != nil {
+			t.Fatalf("CreateRechargeOrder failed: %v", err)
+		}
+	}
+
+	var first, second model.PaymentOrder
+	db.Order("id").First(&first)
+	db.First(&second, first.ID)
+	if err := paymentService.completeOrder(&first, "alipay", "T1"); err != nil {
+		t.Fatalf("completeOrder failed: %v", err)
+	}
+	if err := paymentService.completeOrder(&second, "alipay", "T1"); err != ErrOrderAlreadyPaid {
+		t.Errorf("Expected ErrOrderAlreadyPaid, got %v", err)
+	}
+	if second.Status != "pending" {
+		t.Errorf("Expected the refused copy left as read, got %s", second.Status)
+	}
+
+	if balance := walletBalance(db, user.ID); balance != initial+100 {
+		t.Errorf("Expected the order credited once, got %d from %d", balance, initial)
+	}
+	var credits int64
+	db.Model(&model.Transaction{}).Where("type = ? AND reference_id = ?", model.TransactionTypeRecharge, first.ID).Count(&credits)
+	if credits != 1 {
+		t.Errorf("Expected one recharge transaction, got %d", credits)
+	}
+	var counter model.UserBadgeCounter
+	db.Where("user_id = ?", user.ID).First(&counter)
+	if counter.PendingOrders != 1 {
+		t.Errorf("Expected one pending order left on the badge, got %d", counter.PendingOrders)
+	}
+}
@@ -0,0 +1,415 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+	"scratch-lottery/pkg/logger"
+	"scratch-lottery/pkg/money"
+
+	"gorm.io/gorm"
+)
+
+// paymentReconcileBatch is the number of pending orders checked per query
+const paymentReconcileBatch = 100
+
+var (
+	ErrPaymentReconciliationNotFound = errors.New("payment reconciliation not found")
+	ErrPaymentReconciliationResolved = errors.New("payment reconciliation already resolved")
+)
+
+// PaymentReconciliationService settles payment orders left pending because
+// their callback never arrived. Orders pending longer than the timeout are
+// looked up at their gateway: paid ones are credited, unpaid ones expire, and
+// whatever disagrees with our records is kept for an admin to review.
+type PaymentReconciliationService struct {
+	db             *gorm.DB
+	paymentService *PaymentService
+	pendingTimeout time.Duration
+}
+
+// NewPaymentReconciliationService creates a new payment reconciliation
+// service that checks orders pending longer than pendingTimeout
+func NewPaymentReconciliationService(db *gorm.DB, paymentService *PaymentService, pendingTimeout time.Duration) *PaymentReconciliationService {
+	return &PaymentReconciliationService{db: db, paymentService: paymentService, pendingTimeout: pendingTimeout}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *PaymentReconciliationService) ForTenant(tenantID uint) *PaymentReconciliationService {
+	return &PaymentReconciliationService{
+		db:             repository.ScopeTenant(s.db, tenantID),
+		paymentService: s.paymentService,
+		pendingTimeout: s.pendingTimeout,
+	}
+}
+
+// PaymentReconcileResult counts what a reconciliation run did
+type PaymentReconcileResult struct {
+	Checked         int `json:"checked"`
+	Paid            int `json:"paid"`            // credited after the gateway reported them paid
+	Expired         int `json:"expired"`         // not paid at the gateway
+	Reconciliations int `json:"reconciliations"` // newly recorded discrepancies
+}
+
+// PaymentReconciliationQuery represents query parameters for payment reconciliations
+type PaymentReconciliationQuery struct {
+	Status string `form:"status"` // open (default) or resolved
+	Kind   string `form:"kind"`
+	Page   int    `form:"page"`
+	Limit  int    `form:"limit"`
+}
+
+// PaymentReconciliationResponse represents a payment order whose state at the
+// gateway disagreed with ours
+type PaymentReconciliationResponse struct {
+	ID         uint                            `json:"id"`
+	OrderID    uint                            `json:"order_id"`
+	OrderNo    string                          `json:"order_no"`
+	UserID     uint                            `json:"user_id"`
+	Username   string                          `json:"username"`
+	Gateway    string                          `json:"gateway"`
+	Kind       model.PaymentReconciliationKind `json:"kind"`
+	Amount     string                          `json:"amount"` // in yuan
+	Reported   string                          `json:"reported,omitempty"`
+	Detail     string                          `json:"detail,omitempty"`
+	ResolvedAt *time.Time                      `json:"resolved_at,omitempty"`
+	ResolvedBy uint                            `json:"resolved_by,omitempty"`
+	Note       string                          `json:"note,omitempty"`
+	CreatedAt  time.Time                       `json:"created_at"`
+	UpdatedAt  time.Time                       `json:"updated_at"`
+}
+
+// PaymentReconciliationListResponse represents paginated payment
+// reconciliations with the number of open ones of each kind
+type PaymentReconciliationListResponse struct {
+	Reconciliations []PaymentReconciliationResponse           `json:"reconciliations"`
+	Open            map[model.PaymentReconciliationKind]int64 `json:"open"`
+	Total           int64                                     `json:"total"`
+	Page            int                                       `json:"page"`
+	Limit           int                                       `json:"limit"`
+	TotalPages      int                                       `json:"total_pages"`
+}
+
+// ResolvePaymentReconciliationRequest represents a request to close a payment reconciliation
+type ResolvePaymentReconciliationRequest struct {
+	Note string `json:"note" binding:"max=512"`
+}
+
+// pendingPaymentOrder is a pending order with the tenant of its user
+type pendingPaymentOrder struct {
+	model.PaymentOrder
+	TenantID uint
+}
+
+// Reconcile looks up the orders pending longer than the timeout at their
+// gateway. Orders of the manual gateway are left to the admins confirming
+// them, and orders whose gateway cannot be asked stay pending for the next
+// run.
+func (s *PaymentReconciliationService) Reconcile() (*PaymentReconcileResult, error) {
+	result := &PaymentReconcileResult{}
+	cutoff := time.Now().Add(-s.pendingTimeout)
+	var lastID uint
+	for {
+		query := s.db.Model(&model.PaymentOrder{}).
+			Select("payment_orders.*, users.tenant_id").
+			Joins("JOIN users ON users.id = payment_orders.user_id").
+			Where("payment_orders.id > ? AND payment_orders.status = ? AND payment_orders.created_at < ?", lastID, "pending", cutoff).
+			Where("payment_orders.gateway <> ?", PaymentGatewayManual)
+		if tenantID, ok := repository.TenantFromContext(s.db.Statement.Context); ok {
+			query = query.Where("users.tenant_id = ?", tenantID)
+		}
+		var orders []pendingPaymentOrder
+		if err := query.Order("payment_orders.id ASC").Limit(paymentReconcileBatch).Scan(&orders).Error; err != nil {
+			return result, err
+		}
+		if len(orders) == 0 {
+			return result, nil
+		}
+		lastID = orders[len(orders)-1].ID
+
+		for i := range orders {
+			result.Checked++
+			if err := s.reconcileOrder(&orders[i], result); err != nil {
+				return result, err
+			}
+		}
+	}
+}
+
+// reconcileOrder settles one pending order by what its gateway reports
+func (s *PaymentReconciliationService) reconcileOrder(pending *pendingPaymentOrder, result *PaymentReconcileResult) error {
+	order := &pending.PaymentOrder
+	payments := s.paymentService.ForTenant(pending.TenantID)
+	record := func(kind model.PaymentReconciliationKind, reported, detail string) error {
+		created, err := recordPaymentReconciliation(s.db, pending.TenantID, order, kind, reported, detail)
+		if created {
+			result.Reconciliations++
+		}
+		return err
+	}
+
+	gateway, err := newPaymentGateway(order.Gateway, payments.adminService)
+	if err != nil {
+		return record(model.PaymentReconciliationQueryFailed, "", err.Error())
+	}
+	payment, err := gateway.QueryOrder(order)
+	if err != nil {
+		return record(model.PaymentReconciliationQueryFailed, "", err.Error())
+	}
+
+	if !payment.Paid {
+		expired, err := payments.expireOrder(order)
+		if expired {
+			result.Expired++
+		}
+		return err
+	}
+	if err := checkPaidAmount(order, payment); err != nil {
+		return record(model.PaymentReconciliationAmountMismatch, payment.Money, "")
+	}
+	if err := payments.completeOrder(order, payment.PaymentType, payment.TradeNo); err != nil {
+		if errors.Is(err, ErrOrderAlreadyPaid) {
+			return nil // A callback or an admin got there first
+		}
+		return err
+	}
+	result.Paid++
+	return record(model.PaymentReconciliationMissedCallback, payment.Money, "")
+}
+
+// expireOrder marks a pending order expired and tells the pages watching it.
+// Reports false when the order was no longer pending.
+func (s *PaymentService) expireOrder(order *model.PaymentOrder) (bool, error) {
+	expired := false
+	err := repository.Transaction(s.db, func(tx *gorm.DB) error {
+		result := tx.Model(&model.PaymentOrder{}).Where("id = ? AND status = ?", order.ID, "pending").
+			Update("status", "expired")
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		expired = true
+		return adjustBadge(tx, order.UserID, badgePendingOrders, -1)
+	})
+	if err != nil || !expired {
+		return false, err
+	}
+
+	order.Status = "expired"
+	s.orderHub.publish(s.toOrderResponse(order))
+	return true, nil
+}
+
+// recordPaymentReconciliation records a discrepancy of an order, or refreshes
+// the open record of the same kind. Reports whether a new record was created.
+func recordPaymentReconciliation(db *gorm.DB, tenantID uint, order *model.PaymentOrder, kind model.PaymentReconciliationKind, reported, detail string) (bool, error) {
+	db = repository.ScopeTenant(db, tenantID)
+	var open model.PaymentReconciliation
+	err := db.Where("order_id = ? AND kind = ? AND resolved_at IS NULL", order.ID, kind).First(&open).Error
+	if err == nil {
+		return false, db.Model(&open).Updates(map[string]interface{}{"reported": reported, "detail": detail}).Error
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
+
+	if len(detail) > 512 {
+		detail = detail[:512]
+	}
+	reconciliation := model.PaymentReconciliation{
+		OrderID:  order.ID,
+		OrderNo:  order.OrderNo,
+		UserID:   order.UserID,
+		Gateway:  order.Gateway,
+		Kind:     kind,
+		Amount:   order.Amount,
+		Reported: reported,
+		Detail:   detail,
+	}
+	if err := db.Create(&reconciliation).Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// recordOrderDiscrepancy records a discrepancy found while handling an order
+// outside of a reconciliation run, under the tenant of the order's user.
+// Failures are logged; they must not fail the payment being handled.
+func (s *PaymentService) recordOrderDiscrepancy(order *model.PaymentOrder, kind model.PaymentReconciliationKind, reported, detail string) {
+	var user model.User
+	err := s.db.Unscoped().Select("tenant_id").First(&user, order.UserID).Error
+	if err == nil {
+		_, err = recordPaymentReconciliation(s.db, user.TenantID, order, kind, reported, detail)
+	}
+	if err != nil {
+		logger.FromContext(s.db.Statement.Context).Warn("Recording %s of payment order %s failed: %v", kind, order.OrderNo, err)
+	}
+}
+
+// Start reconciles the pending orders every interval until the returned stop
+// func is called
+func (s *PaymentReconciliationService) Start(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			result, err := s.Reconcile()
+			if err != nil {
+				logger.Default().Warn("Reconciling payment orders failed: %v", err)
+			} else if result.Reconciliations > 0 {
+				logger.Default().Warn("Found %d payment orders that disagree with their gateway", result.Reconciliations)
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// GetReconciliations lists payment reconciliations (admin only)
+func (s *PaymentReconciliationService) GetReconciliations(query PaymentReconciliationQuery) (*PaymentReconciliationListResponse, error) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit < 1 || query.Limit > 100 {
+		query.Limit = 20
+	}
+
+	dbQuery := s.db.Model(&model.PaymentReconciliation{})
+	if query.Status == ReconciliationStatusResolved {
+		dbQuery = dbQuery.Where("resolved_at IS NOT NULL")
+	} else {
+		dbQuery = dbQuery.Where("resolved_at IS NULL")
+	}
+	if query.Kind != "" {
+		dbQuery = dbQuery.Where("kind = ?", query.Kind)
+	}
+
+	var total int64
+	if err := dbQuery.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	var records []model.PaymentReconciliation
+	offset := (query.Page - 1) * query.Limit
+	if err := dbQuery.Preload("User").
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(query.Limit).
+		Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	var counts []struct {
+		Kind  model.PaymentReconciliationKind
+		Count int64
+	}
+	if err := s.db.Model(&model.PaymentReconciliation{}).Select("kind, COUNT(*) AS count").
+		Where("resolved_at IS NULL").Group("kind").Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	open := make(map[model.PaymentReconciliationKind]int64, len(counts))
+	for _, count := range counts {
+		open[count.Kind] = count.Count
+	}
+
+	reconciliations := make([]PaymentReconciliationResponse, len(records))
+	for i := range records {
+		reconciliations[i] = toPaymentReconciliationResponse(&records[i])
+	}
+
+	totalPages := int(total) / query.Limit
+	if int(total)%query.Limit > 0 {
+		totalPages++
+	}
+
+	return &PaymentReconciliationListResponse{
+		Reconciliations: reconciliations,
+		Open:            open,
+		Total:           total,
+		Page:            query.Page,
+		Limit:           query.Limit,
+		TotalPages:      totalPages,
+	}, nil
+}
+
+// Resolve closes a payment reconciliation after an admin reviewed the order
+func (s *PaymentReconciliationService) Resolve(adminID, id uint, req ResolvePaymentReconciliationRequest) (*PaymentReconciliationResponse, error) {
+	var record model.PaymentReconciliation
+	if err := s.db.First(&record, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPaymentReconciliationNotFound
+		}
+		return nil, err
+	}
+	if record.ResolvedAt != nil {
+		return nil, ErrPaymentReconciliationResolved
+	}
+
+	err := repository.Transaction(s.db, func(tx *gorm.DB) error {
+		// Close the record, guarding against a concurrent resolution
+		result := tx.Model(&model.PaymentReconciliation{}).
+			Where("id = ? AND resolved_at IS NULL", record.ID).
+			Updates(map[string]interface{}{
+				"resolved_at": time.Now(),
+				"resolved_by": adminID,
+				"note":        req.Note,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrPaymentReconciliationResolved
+		}
+
+		details, _ := json.Marshal(map[string]interface{}{
+			"order_no": record.OrderNo,
+			"kind":     record.Kind,
+			"note":     req.Note,
+		})
+		adminLog := model.AdminLog{
+			AdminID:    adminID,
+			Action:     "resolve_payment_reconciliation",
+			TargetType: "payment_order",
+			TargetID:   record.OrderID,
+			Details:    string(details),
+		}
+		return tx.Create(&adminLog).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Preload("User").First(&record, record.ID).Error; err != nil {
+		return nil, err
+	}
+	resp := toPaymentReconciliationResponse(&record)
+	return &resp, nil
+}
+
+func toPaymentReconciliationResponse(record *model.PaymentReconciliation) PaymentReconciliationResponse {
+	return PaymentReconciliationResponse{
+		ID:         record.ID,
+		OrderID:    record.OrderID,
+		OrderNo:    record.OrderNo,
+		UserID:     record.UserID,
+		Username:   record.User.Username,
+		Gateway:    record.Gateway,
+		Kind:       record.Kind,
+		Amount:     money.Format(int64(record.Amount), money.CNY),
+		Reported:   record.Reported,
+		Detail:     record.Detail,
+		ResolvedAt: record.ResolvedAt,
+		ResolvedBy: record.ResolvedBy,
+		Note:       record.Note,
+		CreatedAt:  record.CreatedAt,
+		UpdatedAt:  record.UpdatedAt,
+	}
+}
@@ -1,16 +1,17 @@
 package service
 
 import (
-	"crypto/md5"
-	"encoding/hex"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"net/url"
-	"sort"
 	"strings"
 	"time"
 
 	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+	"scratch-lottery/pkg/logger"
+	"scratch-lottery/pkg/money"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -23,22 +24,56 @@ var (
 	ErrOrderNotFound      = errors.New("order not found")
 	ErrOrderAlreadyPaid   = errors.New("order already paid")
 	ErrPaymentInvalidAmount = errors.New("invalid payment amount")
+	ErrPaymentAmountMismatch = errors.New("payment amount mismatch")
+	ErrOrderNotPending       = errors.New("order is not pending")
+	ErrOrderNotManual        = errors.New("order is not paid manually")
 )
 
+// rechargePointsRate is the number of points credited per yuan recharged.
+// Fractions of a point are rounded down.
+var rechargePointsRate = money.Rate{Num: 10, Den: 1}
+
 // PaymentService handles payment-related business logic
 type PaymentService struct {
-	db            *gorm.DB
-	adminService  *AdminService
-	walletService *WalletService
+	db              *gorm.DB
+	adminService    *AdminService
+	walletService   *WalletService
+	campaignService *CampaignService
+	orderHub        *paymentOrderHub
 }
 
-// NewPaymentService creates a new payment service
-func NewPaymentService(db *gorm.DB, adminService *AdminService, walletService *WalletService) *PaymentService {
+// NewPaymentService creates a new payment service. campaignService may be
+// nil, in which case recharges are not rewarded.
+func NewPaymentService(db *gorm.DB, adminService *AdminService, walletService *WalletService, campaignService *CampaignService) *PaymentService {
 	return &PaymentService{
-		db:            db,
-		adminService:  adminService,
-		walletService: walletService,
+		db:              db,
+		adminService:    adminService,
+		walletService:   walletService,
+		campaignService: campaignService,
+		orderHub:        newPaymentOrderHub(),
+	}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *PaymentService) ForTenant(tenantID uint) *PaymentService {
+	scoped := &PaymentService{
+		db:            repository.ScopeTenant(s.db, tenantID),
+		adminService:  s.adminService.ForTenant(tenantID),
+		walletService: s.walletService.ForTenant(tenantID),
+		orderHub:      s.orderHub,
+	}
+	if s.campaignService != nil {
+		scoped.campaignService = s.campaignService.ForTenant(tenantID)
 	}
+	return scoped
+}
+
+// WithRequest returns a copy of the service whose logs carry the request id
+// of ctx
+func (s *PaymentService) WithRequest(ctx context.Context) *PaymentService {
+	scoped := *s
+	scoped.db = repository.ScopeRequest(s.db, ctx)
+	return &scoped
 }
 
 // RechargeRequest represents a recharge request
@@ -52,6 +87,8 @@ type RechargeResponse struct {
 	PaymentURL string `json:"payment_url"`
 	Amount     int    `json:"amount"`
 	Points     int    `json:"points"`
+	Gateway    string `json:"gateway,omitempty"` // Payment gateway; manual orders have no PaymentURL
+	Sandbox    bool   `json:"sandbox,omitempty"` // Paid at once with test points, PaymentURL is empty
 }
 
 // PaymentCallbackRequest represents a payment callback from EPay
@@ -67,6 +104,27 @@ type PaymentCallbackRequest struct {
 	SignType    string `form:"sign_type" json:"sign_type"`       // Signature type (MD5)
 }
 
+// params returns the callback as the parameters EPay signed
+func (c PaymentCallbackRequest) params() map[string]string {
+	return map[string]string{
+		"pid":          c.PID,
+		"trade_no":     c.TradeNo,
+		"out_trade_no": c.OutTradeNo,
+		"type":         c.Type,
+		"name":         c.Name,
+		"money":        c.Money,
+		"trade_status": c.TradeStatus,
+		"sign":         c.Sign,
+		"sign_type":    c.SignType,
+	}
+}
+
+// ConfirmManualOrderRequest represents an admin confirming an offline payment
+type ConfirmManualOrderRequest struct {
+	TradeNo string `json:"trade_no" binding:"required,max=128"` // Reference of the transfer
+	Note    string `json:"note" binding:"max=256"`
+}
+
 // OrderResponse represents an order in responses
 type OrderResponse struct {
 	ID          uint      `json:"id"`
@@ -76,53 +134,93 @@ type OrderResponse struct {
 	Status      string    `json:"status"`
 	PaymentType string    `json:"payment_type,omitempty"`
 	TradeNo     string    `json:"trade_no,omitempty"`
+	Gateway     string    `json:"gateway,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
 // CreateRechargeOrder creates a new recharge order and returns payment URL
 func (s *PaymentService) CreateRechargeOrder(userID uint, req RechargeRequest) (*RechargeResponse, error) {
-	// Check if payment is enabled
-	if !s.adminService.IsPaymentEnabled() {
-		return nil, ErrPaymentDisabled
+	// Sandbox tenants simulate the payment instead of going through EPay
+	sandbox, err := inSandbox(s.db)
+	if err != nil {
+		return nil, err
 	}
 
-	// Validate amount (minimum 1 yuan, maximum 10000 yuan)
-	if req.Amount < 1 || req.Amount > 10000 {
-		return nil, ErrPaymentInvalidAmount
+	// Check if payment is enabled
+	if !sandbox && !s.adminService.IsPaymentEnabled() {
+		return nil, ErrPaymentDisabled
 	}
 
-	// Get EPay configuration
-	epayConfig, err := s.adminService.GetEPayConfig()
+	// Validate amount against the configured denominations and custom amount rules
+	rules, err := s.GetRechargeRules()
 	if err != nil {
 		return nil, err
 	}
+	if !rules.allows(req.Amount) {
+		return nil, ErrPaymentInvalidAmount
+	}
 
-	if epayConfig.MerchantID == "" || epayConfig.Secret == "" {
-		return nil, ErrPaymentConfigError
+	// Get the gateway new orders are paid through
+	var gateway PaymentGateway
+	if !sandbox {
+		if gateway, err = s.currentGateway(); err != nil {
+			return nil, err
+		}
 	}
 
 	// Calculate points (1 yuan = 10 points)
-	points := req.Amount * 10
+	amount, err := money.FromMajor(int64(req.Amount), money.CNY)
+	if err != nil {
+		return nil, ErrPaymentInvalidAmount
+	}
+	points, err := amount.Convert(money.Points, rechargePointsRate, money.RoundDown)
+	if err != nil {
+		return nil, err
+	}
 
 	// Generate unique order number
 	orderNo := s.generateOrderNo()
 
 	// Create order in database
 	order := model.PaymentOrder{
-		UserID:  userID,
-		OrderNo: orderNo,
-		Amount:  req.Amount * 100, // Store in cents
-		Points:  points,
-		Status:  "pending",
+		UserID:   userID,
+		OrderNo:  orderNo,
+		Amount:   int(amount.Amount), // Store in fen
+		Currency: string(amount.Currency),
+		Points:   int(points.Amount),
+		Status:   "pending",
+		Gateway:  model.PaymentTypeSandbox,
+	}
+	if gateway != nil {
+		order.Gateway = gateway.Name()
 	}
 
-	if err := s.db.Create(&order).Error; err != nil {
+	err = repository.Transaction(s.db, func(tx *gorm.DB) error {
+		order.ID = 0
+		if err := tx.Create(&order).Error; err != nil {
+			return err
+		}
+		return adjustBadge(tx, userID, badgePendingOrders, 1)
+	})
+	if err != nil {
 		return nil, err
 	}
 
+	if sandbox {
+		if err := s.completeOrder(&order, model.PaymentTypeSandbox, "SANDBOX-"+orderNo); err != nil {
+			return nil, err
+		}
+		return &RechargeResponse{
+			OrderNo: orderNo,
+			Amount:  req.Amount,
+			Points:  order.Points,
+			Sandbox: true,
+		}, nil
+	}
+
 	// Build payment URL
-	paymentURL, err := s.buildPaymentURL(epayConfig, orderNo, req.Amount)
+	paymentURL, err := gateway.CreateOrder(&order)
 	if err != nil {
 		return nil, err
 	}
@@ -131,34 +229,52 @@ func (s *PaymentService) CreateRechargeOrder(userID uint, req RechargeRequest) (
 		OrderNo:    orderNo,
 		PaymentURL: paymentURL,
 		Amount:     req.Amount,
-		Points:     points,
+		Points:     order.Points,
+		Gateway:    order.Gateway,
 	}, nil
 }
 
+// currentGateway returns the gateway selected for new orders
+func (s *PaymentService) currentGateway() (PaymentGateway, error) {
+	name, err := s.adminService.GetConfigValue(ConfigKeyPaymentGateway)
+	if err != nil && !errors.Is(err, ErrConfigNotFound) {
+		return nil, err
+	}
+	gateway, err := newPaymentGateway(name, s.adminService)
+	if errors.Is(err, ErrUnknownPaymentGateway) {
+		return nil, ErrPaymentConfigError
+	}
+	return gateway, err
+}
+
 // ProcessCallback processes payment callback from EPay
 func (s *PaymentService) ProcessCallback(callback PaymentCallbackRequest) error {
-	// Get EPay configuration
-	epayConfig, err := s.adminService.GetEPayConfig()
+	return s.ProcessGatewayCallback(PaymentGatewayEPay, callback.params())
+}
+
+// ProcessGatewayCallback processes a payment notification sent by the named
+// gateway. Only orders created for that gateway are completed, and only when
+// the paid amount matches the order exactly.
+func (s *PaymentService) ProcessGatewayCallback(name string, params map[string]string) error {
+	gateway, err := newPaymentGateway(name, s.adminService)
 	if err != nil {
 		return err
 	}
 
 	// Verify signature
-	if !s.VerifySignature(callback, epayConfig.Secret) {
-		return ErrInvalidSignature
+	payment, err := gateway.VerifyCallback(params)
+	if err != nil {
+		return err
 	}
 
 	// Check trade status
-	if callback.TradeStatus != "TRADE_SUCCESS" {
+	if !payment.Paid {
 		return nil // Not a successful payment, ignore
 	}
 
 	// Find order
-	var order model.PaymentOrder
-	if err := s.db.Where("order_no = ?", callback.OutTradeNo).First(&order).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return ErrOrderNotFound
-		}
+	order, err := s.gatewayOrder(payment.OrderNo, gateway.Name())
+	if err != nil {
 		return err
 	}
 
@@ -167,22 +283,158 @@ func (s *PaymentService) ProcessCallback(callback PaymentCallbackRequest) error
 		return ErrOrderAlreadyPaid
 	}
 
-	// Update order and add points in transaction
-	err = s.db.Transaction(func(tx *gorm.DB) error {
-		// Update order status
-		order.Status = "paid"
-		order.PaymentType = callback.Type
-		order.TradeNo = callback.TradeNo
-		if err := tx.Save(&order).Error; err != nil {
-			return err
+	if err := checkPaidAmount(order, payment); err != nil {
+		s.recordOrderDiscrepancy(order, model.PaymentReconciliationAmountMismatch, payment.Money, "")
+		return err
+	}
+	return s.completeOrder(order, payment.PaymentType, payment.TradeNo)
+}
+
+// SyncOrder asks the gateway of a pending or expired order whether it has
+// been paid, for notifications that never arrived, and completes the order
+// if so
+func (s *PaymentService) SyncOrder(orderNo string) (*OrderResponse, error) {
+	var order model.PaymentOrder
+	if err := s.db.Where("order_no = ?", orderNo).First(&order).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, err
+	}
+	if order.Status != "pending" && order.Status != "expired" {
+		return s.toOrderResponse(&order), nil
+	}
+
+	gateway, err := newPaymentGateway(order.Gateway, s.adminService)
+	if err != nil {
+		return nil, err
+	}
+	payment, err := gateway.QueryOrder(&order)
+	if err != nil {
+		return nil, err
+	}
+	if payment.Paid {
+		if err := checkPaidAmount(&order, payment); err != nil {
+			s.recordOrderDiscrepancy(&order, model.PaymentReconciliationAmountMismatch, payment.Money, "")
+			return nil, err
+		}
+		err := s.completeOrder(&order, payment.PaymentType, payment.TradeNo)
+		if errors.Is(err, ErrOrderAlreadyPaid) {
+			// Paid meanwhile by a callback or the reconciler
+			if err := s.db.First(&order, order.ID).Error; err != nil {
+				return nil, err
+			}
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return s.toOrderResponse(&order), nil
+}
+
+// ConfirmManualOrder marks a pending order of the manual gateway paid once
+// an admin has seen the money arrive
+func (s *PaymentService) ConfirmManualOrder(adminID uint, orderNo string, req ConfirmManualOrderRequest) (*OrderResponse, error) {
+	order, err := s.gatewayOrder(orderNo, PaymentGatewayManual)
+	if errors.Is(err, ErrOrderNotFound) {
+		var exists int64
+		if s.db.Model(&model.PaymentOrder{}).Where("order_no = ?", orderNo).Count(&exists); exists > 0 {
+			return nil, ErrOrderNotManual
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != "pending" {
+		return nil, ErrOrderNotPending
+	}
+
+	if err := s.completeOrder(order, PaymentGatewayManual, strings.TrimSpace(req.TradeNo)); err != nil {
+		if errors.Is(err, ErrOrderAlreadyPaid) {
+			return nil, ErrOrderNotPending
+		}
+		return nil, err
+	}
+
+	details, _ := json.Marshal(map[string]interface{}{
+		"order_no": order.OrderNo,
+		"amount":   order.Amount,
+		"points":   order.Points,
+		"trade_no": order.TradeNo,
+		"note":     req.Note,
+	})
+	if err := s.db.Create(&model.AdminLog{
+		AdminID:    adminID,
+		Action:     "confirm_manual_payment",
+		TargetType: "payment_order",
+		TargetID:   order.ID,
+		Details:    string(details),
+	}).Error; err != nil {
+		return nil, err
+	}
+	return s.toOrderResponse(order), nil
+}
+
+// gatewayOrder finds an order paid through the named gateway. Orders of
+// other gateways are not found, so one gateway cannot complete another's.
+func (s *PaymentService) gatewayOrder(orderNo, gateway string) (*model.PaymentOrder, error) {
+	var order model.PaymentOrder
+	if err := s.db.Where("order_no = ?", orderNo).First(&order).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, err
+	}
+	if order.Gateway != gateway && !(order.Gateway == "" && gateway == PaymentGatewayEPay) {
+		return nil, ErrOrderNotFound
+	}
+	return &order, nil
+}
+
+// checkPaidAmount checks that the paid amount matches the order exactly
+func checkPaidAmount(order *model.PaymentOrder, payment *GatewayPayment) error {
+	paid, err := money.Parse(payment.Money, orderCurrency(order))
+	if err != nil || paid.Amount != int64(order.Amount) {
+		return ErrPaymentAmountMismatch
+	}
+	return nil
+}
+
+// completeOrder marks an order paid, credits its points, tells the pages
+// watching it and rewards the recharge. Paying an order that had expired is
+// recorded for the admins to review. Callers check the status on a copy read
+// before the transaction, so the order is only paid if it is still pending
+// or expired when updated; a completion racing another one, such as a late
+// callback and the reconciler, gets ErrOrderAlreadyPaid and credits nothing.
+func (s *PaymentService) completeOrder(order *model.PaymentOrder, paymentType, tradeNo string) error {
+	expired := false
+	err := repository.Transaction(s.db, func(tx *gorm.DB) error {
+		expired = false
+		paid := map[string]interface{}{"status": "paid", "payment_type": paymentType, "trade_no": tradeNo}
+		result := tx.Model(&model.PaymentOrder{}).Where("id = ? AND status = ?", order.ID, "pending").Updates(paid)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			if err := adjustBadge(tx, order.UserID, badgePendingOrders, -1); err != nil {
+				return err
+			}
+		} else {
+			result = tx.Model(&model.PaymentOrder{}).Where("id = ? AND status = ?", order.ID, "expired").Updates(paid)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrOrderAlreadyPaid
+			}
+			expired = true
 		}
 
 		// Add points to user wallet
-		description := fmt.Sprintf("充值 %d 元，获得 %d 积分", order.Amount/100, order.Points)
+		description := fmt.Sprintf("充值 %s 元，获得 %d 积分", money.Format(int64(order.Amount), orderCurrency(order)), order.Points)
 		
 		// Get wallet
 		var wallet model.Wallet
-		if err := tx.Where("user_id = ?", order.UserID).First(&wallet).Error; err != nil {
+		if err := lockWallet(tx, order.UserID, &wallet); err != nil {
 			return err
 		}
 
@@ -194,6 +446,7 @@ func (s *PaymentService) ProcessCallback(callback PaymentCallbackRequest) error
 
 		// Create transaction record
 		transaction := model.Transaction{
+			TenantID:    wallet.TenantID,
 			WalletID:    wallet.ID,
 			Type:        model.TransactionTypeRecharge,
 			Amount:      order.Points,
@@ -202,8 +455,32 @@ func (s *PaymentService) ProcessCallback(callback PaymentCallbackRequest) error
 		}
 		return tx.Create(&transaction).Error
 	})
+	if err != nil {
+		return err
+	}
+	order.Status = "paid"
+	order.PaymentType = paymentType
+	order.TradeNo = tradeNo
+	if expired {
+		s.recordOrderDiscrepancy(order, model.PaymentReconciliationPaidAfterExpiry, "", "")
+	}
 
-	return err
+	// Tell the pages watching the order that it is paid
+	s.orderHub.publish(s.toOrderResponse(order))
+
+	// Reward the recharge once it is booked
+	if s.campaignService != nil {
+		if _, err := s.campaignService.HandleEvent(CampaignEvent{
+			Trigger:     model.CampaignTriggerRecharge,
+			UserID:      order.UserID,
+			Key:         fmt.Sprintf("order:%d", order.ID),
+			ReferenceID: order.ID,
+			Amount:      order.Amount,
+		}); err != nil {
+			logger.FromContext(s.db.Statement.Context).Warn("Rewarding recharge order %s failed: %v", order.OrderNo, err)
+		}
+	}
+	return nil
 }
 
 // GetOrderByNo retrieves an order by order number
@@ -253,90 +530,12 @@ func (s *PaymentService) GetUserOrders(userID uint, page, limit int) ([]OrderRes
 
 // VerifySignature verifies the EPay callback signature
 func (s *PaymentService) VerifySignature(callback PaymentCallbackRequest, secret string) bool {
-	// Build params map (exclude sign and sign_type)
-	params := map[string]string{
-		"pid":          callback.PID,
-		"trade_no":     callback.TradeNo,
-		"out_trade_no": callback.OutTradeNo,
-		"type":         callback.Type,
-		"name":         callback.Name,
-		"money":        callback.Money,
-		"trade_status": callback.TradeStatus,
-	}
-
-	// Calculate expected signature
-	expectedSign := s.CalculateSign(params, secret)
-
-	return expectedSign == callback.Sign
+	return epayVerifySign(callback.params(), secret)
 }
 
 // CalculateSign calculates MD5 signature for EPay
 func (s *PaymentService) CalculateSign(params map[string]string, secret string) string {
-	// Sort keys
-	keys := make([]string, 0, len(params))
-	for k := range params {
-		keys = append(keys, k)
-	}
-	sort.Strings(keys)
-
-	// Build query string
-	var parts []string
-	for _, k := range keys {
-		v := params[k]
-		if v != "" {
-			parts = append(parts, fmt.Sprintf("%s=%s", k, v))
-		}
-	}
-	queryString := strings.Join(parts, "&")
-
-	// Append secret
-	signStr := queryString + secret
-
-	// Calculate MD5
-	hash := md5.Sum([]byte(signStr))
-	return hex.EncodeToString(hash[:])
-}
-
-// buildPaymentURL builds the EPay payment URL
-func (s *PaymentService) buildPaymentURL(config *EPayConfig, orderNo string, amount int) (string, error) {
-	// EPay API endpoint (this is a common EPay API format)
-	baseURL := "https://pay.example.com/submit.php" // This should be configurable
-
-	// Get callback URL from config or use default
-	notifyURL := config.CallbackURL
-	if notifyURL == "" {
-		notifyURL = "http://localhost:8080/api/payment/callback"
-	}
-
-	// Build params
-	params := map[string]string{
-		"pid":          config.MerchantID,
-		"type":         "alipay", // Default to alipay, can be made configurable
-		"out_trade_no": orderNo,
-		"notify_url":   notifyURL,
-		"return_url":   notifyURL, // Can be different for user redirect
-		"name":         "积分充值",
-		"money":        fmt.Sprintf("%.2f", float64(amount)),
-	}
-
-	// Calculate signature
-	sign := s.CalculateSign(params, config.Secret)
-	params["sign"] = sign
-	params["sign_type"] = "MD5"
-
-	// Build URL
-	u, err := url.Parse(baseURL)
-	if err != nil {
-		return "", err
-	}
-
-	q := u.Query()
-	for k, v := range params {
-		q.Set(k, v)
-	}
-	u.RawQuery = q.Encode()
-
-	return u.String(), nil
+	return epaySign(params, secret)
 }
 
 // generateOrderNo generates a unique order number
@@ -349,6 +548,15 @@ func (s *PaymentService) generateOrderNo() string {
 	return fmt.Sprintf("%s%s", timestamp, suffix)
 }
 
+// orderCurrency returns the currency of an order's amount. Orders created
+// before the currency was recorded are in yuan.
+func orderCurrency(order *model.PaymentOrder) money.Currency {
+	if order.Currency == "" {
+		return money.CNY
+	}
+	return money.Currency(order.Currency)
+}
+
 // toOrderResponse converts a PaymentOrder to OrderResponse
 func (s *PaymentService) toOrderResponse(order *model.PaymentOrder) *OrderResponse {
 	return &OrderResponse{
@@ -359,6 +567,7 @@ func (s *PaymentService) toOrderResponse(order *model.PaymentOrder) *OrderRespon
 		Status:      order.Status,
 		PaymentType: order.PaymentType,
 		TradeNo:     order.TradeNo,
+		Gateway:     order.Gateway,
 		CreatedAt:   order.CreatedAt,
 		UpdatedAt:   order.UpdatedAt,
 	}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+)
+
+// Payment settings: the connectivity test only succeeds when the gateway
+// accepts the signature, every change is versioned, and a rollback restores
+// the exact earlier settings as a new version.
+func TestPaymentSettingsTestAndRollback(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	const gatewaySecret = "provider-secret"
+	paymentService := &PaymentService{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		params := map[string]string{"act": q.Get("act"), "pid": q.Get("pid"), "timestamp": q.Get("timestamp")}
+		if q.Get("sign") != paymentService.CalculateSign(params, gatewaySecret) {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": -1, "msg": "签名错误"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": 1, "pid": q.Get("pid")})
+	}))
+	defer server.Close()
+
+	properties.Property("test validates credentials and rollback restores a version", prop.ForAll(
+		func(oldSecret string, rollbackTo int) bool {
+			db := setupLotteryTestDB(t)
+			if err := db.AutoMigrate(&model.SystemConfig{}, &model.AdminLog{}, &model.PaymentSettingsVersion{}); err != nil {
+				t.Logf("Failed to migrate: %v", err)
+				return false
+			}
+			adminService := NewAdminService(db, NewWalletService(db))
+			settingsService := NewPaymentSettingsService(db, adminService, paymentService)
+
+			// Existing settings predate the history
+			_ = adminService.SetConfigValue("epay_merchant_id", "1001")
+			_ = adminService.SetConfigValue("epay_secret", oldSecret)
+			_ = adminService.SetConfigValue(ConfigKeyEPayGatewayURL, server.URL)
+
+			// The saved secret is wrong; the candidate one is accepted
+			result, err := settingsService.TestConnection(1, TestPaymentSettingsRequest{})
+			if err != nil || result.Success {
+				t.Logf("Old secret should fail: %+v (err %v)", result, err)
+				return false
+			}
+			newSecret := gatewaySecret
+			result, err = settingsService.TestConnection(1, TestPaymentSettingsRequest{EPaySecret: &newSecret})
+			if err != nil || !result.Success {
+				t.Logf("New secret should pass: %+v (err %v)", result, err)
+				return false
+			}
+			if config, _ := adminService.GetEPayConfig(); config.Secret != oldSecret {
+				t.Logf("Testing must not save the secret")
+				return false
+			}
+
+			if _, err := adminService.UpdateSystemSettings(1, UpdateSystemSettingsRequest{EPaySecret: &newSecret}); err != nil {
+				return false
+			}
+			merchantID := "2002"
+			if _, err := adminService.UpdateSystemSettings(1, UpdateSystemSettingsRequest{EPayMerchantID: &merchantID}); err != nil {
+				return false
+			}
+
+			// initial + two updates
+			versions, err := settingsService.GetVersions()
+			if err != nil || len(versions) != 3 || !versions[0].Current || versions[0].EPaySecret == newSecret {
+				t.Logf("Unexpected versions: %+v (err %v)", versions, err)
+				return false
+			}
+
+			if _, err := settingsService.Rollback(1, rollbackTo); err != nil {
+				t.Logf("Rollback failed: %v", err)
+				return false
+			}
+			expected := map[int][2]string{1: {"1001", oldSecret}, 2: {"1001", newSecret}, 3: {"2002", newSecret}}[rollbackTo]
+			config, _ := adminService.GetEPayConfig()
+			if config.MerchantID != expected[0] || config.Secret != expected[1] || config.GatewayURL != server.URL {
+				t.Logf("Rollback to %d restored %+v", rollbackTo, config)
+				return false
+			}
+
+			versions, _ = settingsService.GetVersions()
+			if len(versions) != 4 || versions[0].Action != model.PaymentSettingsActionRollback || versions[0].RolledBackTo != rollbackTo {
+				t.Logf("Rollback should be recorded as a new version: %+v", versions[0])
+				return false
+			}
+			if _, err := settingsService.Rollback(1, 99); err != ErrPaymentSettingsVersionNotFound {
+				return false
+			}
+
+			// The secret never reaches the admin log
+			var logs []model.AdminLog
+			db.Find(&logs)
+			for _, log := range logs {
+				if strings.Contains(log.Details, gatewaySecret) {
+					t.Logf("Secret leaked into admin log: %s", log.Details)
+					return false
+				}
+			}
+			return true
+		},
+		gen.Identifier().Map(func(s string) string { return "old-" + s }),
+		gen.IntRange(1, 3),
+	))
+
+	properties.TestingRun(t)
+}
@@ -0,0 +1,331 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+	"scratch-lottery/pkg/redact"
+
+	"gorm.io/gorm"
+)
+
+// ConfigKeyEPayGatewayURL is the system config key of the EPay gateway base URL
+const ConfigKeyEPayGatewayURL = "epay_gateway_url"
+
+// defaultEPayGatewayURL is used when no gateway URL has been configured
+const defaultEPayGatewayURL = "https://pay.example.com"
+
+var ErrPaymentSettingsVersionNotFound = errors.New("payment settings version not found")
+
+// PaymentSettingsService validates EPay credentials against the provider and
+// keeps a version history of payment settings with one-step rollback
+type PaymentSettingsService struct {
+	db             *gorm.DB
+	adminService   *AdminService
+	paymentService *PaymentService
+	httpClient     *http.Client
+}
+
+// NewPaymentSettingsService creates a new payment settings service
+func NewPaymentSettingsService(db *gorm.DB, adminService *AdminService, paymentService *PaymentService) *PaymentSettingsService {
+	return &PaymentSettingsService{
+		db:             db,
+		adminService:   adminService,
+		paymentService: paymentService,
+		httpClient:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *PaymentSettingsService) ForTenant(tenantID uint) *PaymentSettingsService {
+	return &PaymentSettingsService{
+		db:             repository.ScopeTenant(s.db, tenantID),
+		adminService:   s.adminService.ForTenant(tenantID),
+		paymentService: s.paymentService.ForTenant(tenantID),
+		httpClient:     s.httpClient,
+	}
+}
+
+// TestPaymentSettingsRequest holds candidate credentials to test before saving.
+// Omitted (or masked) fields fall back to the saved settings.
+type TestPaymentSettingsRequest struct {
+	EPayMerchantID *string `json:"epay_merchant_id"`
+	EPaySecret     *string `json:"epay_secret"`
+	EPayGatewayURL *string `json:"epay_gateway_url"`
+}
+
+// PaymentSettingsTestResult represents the outcome of a connectivity test
+type PaymentSettingsTestResult struct {
+	Success      bool   `json:"success"`
+	Message      string `json:"message"`
+	GatewayURL   string `json:"gateway_url"`
+	HTTPStatus   int    `json:"http_status,omitempty"`
+	ProviderCode int    `json:"provider_code"`
+	LatencyMs    int64  `json:"latency_ms"`
+}
+
+// PaymentSettingsVersionResponse represents a payment settings version in responses
+type PaymentSettingsVersionResponse struct {
+	Version         int       `json:"version"`
+	PaymentEnabled  bool      `json:"payment_enabled"`
+	EPayMerchantID  string    `json:"epay_merchant_id"`
+	EPaySecret      string    `json:"epay_secret"` // Masked
+	EPayCallbackURL string    `json:"epay_callback_url"`
+	EPayGatewayURL  string    `json:"epay_gateway_url"`
+	AdminID         uint      `json:"admin_id"`
+	Action          string    `json:"action"`
+	RolledBackTo    int       `json:"rolled_back_to,omitempty"`
+	Current         bool      `json:"current"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// epayProviderResponse is the JSON body returned by the EPay merchant query API
+type epayProviderResponse struct {
+	Code json.Number `json:"code"`
+	Msg  string      `json:"msg"`
+	PID  json.Number `json:"pid"`
+}
+
+// TestConnection sends a signed merchant query to the EPay gateway to check
+// that the credentials are accepted. Nothing is saved.
+func (s *PaymentSettingsService) TestConnection(adminID uint, req TestPaymentSettingsRequest) (*PaymentSettingsTestResult, error) {
+	config, err := s.adminService.GetEPayConfig()
+	if err != nil {
+		return nil, err
+	}
+	if req.EPayMerchantID != nil {
+		config.MerchantID = *req.EPayMerchantID
+	}
+	if req.EPaySecret != nil && *req.EPaySecret != "" && !redact.IsMasked(*req.EPaySecret) {
+		config.Secret = *req.EPaySecret
+	}
+	if req.EPayGatewayURL != nil {
+		config.GatewayURL = *req.EPayGatewayURL
+	}
+
+	result := &PaymentSettingsTestResult{GatewayURL: epayGatewayURL(config)}
+	if config.MerchantID == "" || config.Secret == "" {
+		result.Message = "商户ID或密钥未配置"
+	} else {
+		s.probe(config, result)
+	}
+
+	// Log admin action (never persist the secret itself)
+	details, _ := json.Marshal(redact.Value("", map[string]interface{}{
+		"request": req,
+		"result":  result,
+	}))
+	adminLog := model.AdminLog{
+		AdminID:    adminID,
+		Action:     "test_payment_settings",
+		TargetType: "system",
+		TargetID:   0,
+		Details:    string(details),
+	}
+	if err := s.db.Create(&adminLog).Error; err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// probe performs the signed query and fills in the result
+func (s *PaymentSettingsService) probe(config *EPayConfig, result *PaymentSettingsTestResult) {
+	params := map[string]string{
+		"act":       "query",
+		"pid":       config.MerchantID,
+		"timestamp": strconv.FormatInt(time.Now().Unix(), 10),
+	}
+	params["sign"] = s.paymentService.CalculateSign(params, config.Secret)
+	params["sign_type"] = "MD5"
+
+	query := url.Values{}
+	for k, v := range params {
+		query.Set(k, v)
+	}
+	testURL := result.GatewayURL + "/api.php?" + query.Encode()
+
+	start := time.Now()
+	resp, err := s.httpClient.Get(testURL)
+	result.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Message = fmt.Sprintf("无法连接支付网关: %s", redact.Query(err.Error()))
+		return
+	}
+	defer resp.Body.Close()
+	result.HTTPStatus = resp.StatusCode
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		result.Message = fmt.Sprintf("支付网关返回 HTTP %d", resp.StatusCode)
+		return
+	}
+
+	var body epayProviderResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		result.Message = "支付网关响应格式无效"
+		return
+	}
+	code, _ := body.Code.Int64()
+	result.ProviderCode = int(code)
+	if code != 1 {
+		result.Message = "支付网关拒绝凭据"
+		if body.Msg != "" {
+			result.Message += ": " + body.Msg
+		}
+		return
+	}
+	if body.PID != "" && body.PID.String() != config.MerchantID {
+		result.Message = "支付网关返回的商户ID不匹配"
+		return
+	}
+
+	result.Success = true
+	result.Message = "连接成功，凭据有效"
+}
+
+// GetVersions returns the payment settings history, newest first
+func (s *PaymentSettingsService) GetVersions() ([]PaymentSettingsVersionResponse, error) {
+	var versions []model.PaymentSettingsVersion
+	if err := s.db.Order("version DESC").Find(&versions).Error; err != nil {
+		return nil, err
+	}
+
+	responses := make([]PaymentSettingsVersionResponse, len(versions))
+	for i, v := range versions {
+		responses[i] = PaymentSettingsVersionResponse{
+			Version:         v.Version,
+			PaymentEnabled:  v.PaymentEnabled,
+			EPayMerchantID:  v.MerchantID,
+			EPaySecret:      redact.Secret(v.Secret),
+			EPayCallbackURL: v.CallbackURL,
+			EPayGatewayURL:  v.GatewayURL,
+			AdminID:         v.AdminID,
+			Action:          v.Action,
+			RolledBackTo:    v.RolledBackTo,
+			Current:         i == 0,
+			CreatedAt:       v.CreatedAt,
+		}
+	}
+	return responses, nil
+}
+
+// Rollback restores the payment settings of a previous version. The rollback
+// itself is recorded as a new version.
+func (s *PaymentSettingsService) Rollback(adminID uint, version int) (*SystemSettings, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var target model.PaymentSettingsVersion
+		if err := tx.Where("version = ?", version).First(&target).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrPaymentSettingsVersionNotFound
+			}
+			return err
+		}
+
+		values := map[string]string{
+			"payment_enabled":       boolToString(target.PaymentEnabled),
+			"epay_merchant_id":      target.MerchantID,
+			"epay_secret":           target.Secret,
+			"epay_callback_url":     target.CallbackURL,
+			ConfigKeyEPayGatewayURL: target.GatewayURL,
+		}
+		for key, value := range values {
+			if err := s.adminService.upsertConfig(tx, key, value); err != nil {
+				return err
+			}
+		}
+
+		if _, err := recordPaymentSettingsVersion(tx, adminID, model.PaymentSettingsActionRollback, version); err != nil {
+			return err
+		}
+
+		details, _ := json.Marshal(map[string]interface{}{"version": version})
+		adminLog := model.AdminLog{
+			AdminID:    adminID,
+			Action:     "rollback_payment_settings",
+			TargetType: "system",
+			TargetID:   uint(version),
+			Details:    string(details),
+		}
+		return tx.Create(&adminLog).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.adminService.GetSystemSettings()
+}
+
+// recordInitialPaymentSettings snapshots the current payment settings as the
+// first version if no version has been recorded yet
+func recordInitialPaymentSettings(tx *gorm.DB, adminID uint) error {
+	var count int64
+	if err := tx.Model(&model.PaymentSettingsVersion{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	_, err := recordPaymentSettingsVersion(tx, adminID, model.PaymentSettingsActionInitial, 0)
+	return err
+}
+
+// recordPaymentSettingsVersion snapshots the current payment settings as a new version
+func recordPaymentSettingsVersion(tx *gorm.DB, adminID uint, action string, rolledBackTo int) (*model.PaymentSettingsVersion, error) {
+	var configs []model.SystemConfig
+	if err := tx.Where("key IN ?", []string{
+		"payment_enabled", "epay_merchant_id", "epay_secret", "epay_callback_url", ConfigKeyEPayGatewayURL,
+	}).Find(&configs).Error; err != nil {
+		return nil, err
+	}
+
+	var latest struct {
+		Version int
+	}
+	if err := tx.Model(&model.PaymentSettingsVersion{}).
+		Select("COALESCE(MAX(version), 0) as version").
+		Scan(&latest).Error; err != nil {
+		return nil, err
+	}
+
+	version := model.PaymentSettingsVersion{
+		Version:      latest.Version + 1,
+		AdminID:      adminID,
+		Action:       action,
+		RolledBackTo: rolledBackTo,
+	}
+	for _, config := range configs {
+		switch config.Key {
+		case "payment_enabled":
+			version.PaymentEnabled = config.Value == "true"
+		case "epay_merchant_id":
+			version.MerchantID = config.Value
+		case "epay_secret":
+			version.Secret = config.Value
+		case "epay_callback_url":
+			version.CallbackURL = config.Value
+		case ConfigKeyEPayGatewayURL:
+			version.GatewayURL = config.Value
+		}
+	}
+
+	if err := tx.Create(&version).Error; err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// epayGatewayURL returns the configured gateway base URL without a trailing slash
+func epayGatewayURL(config *EPayConfig) string {
+	if config.GatewayURL == "" {
+		return defaultEPayGatewayURL
+	}
+	return strings.TrimRight(config.GatewayURL, "/")
+}
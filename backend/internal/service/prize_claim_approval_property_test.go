@@ -0,0 +1,86 @@
+package service
+
+import (
+	"testing"
+
+	"scratch-lottery/internal/model"
+)
+
+// Prize claim approval: with approval required, a held win leaves its ticket
+// pending its claim, the submitted claim waits in the admin queue without
+// crediting the prize, and approval credits it once while rejection forfeits
+// it. Both reviews are logged and a claim is reviewed only once.
+func TestPrizeClaimApproval(t *testing.T) {
+	db, scratchService, largeWins, userID, ticketIDs := setupLargeWinTest(t, []int{800, 900})
+	threshold, approval := 500, true
+	settings, err := largeWins.adminService.UpdateSystemSettings(1, UpdateSystemSettingsRequest{
+		LargeWinThreshold:        &threshold,
+		LargeWinApprovalRequired: &approval,
+	})
+	if err != nil || !settings.LargeWinApprovalRequired {
+		t.Fatalf("Expected approval to be required, got %+v (err %v)", settings, err)
+	}
+
+	var wallet model.Wallet
+	db.Where("user_id = ?", userID).First(&wallet)
+	initialBalance := wallet.Balance
+
+	for _, ticketID := range ticketIDs {
+		resp, err := scratchService.ScratchTicket(userID, ticketID, "")
+		if err != nil || !resp.ClaimRequired || resp.Status != model.TicketStatusPendingClaim {
+			t.Fatalf("Expected the win to be held pending its claim, got %+v (err %v)", resp, err)
+		}
+		req := ClaimPrizeRequest{FullName: "张三", IDNumber: "110101199001011234"}
+		claim, err := largeWins.ClaimPrize(userID, ticketID, req)
+		if err != nil || claim.Status != model.PrizeClaimStatusSubmitted {
+			t.Fatalf("Expected the claim to wait for approval, got %+v (err %v)", claim, err)
+		}
+	}
+	db.First(&wallet, wallet.ID)
+	if wallet.Balance != initialBalance {
+		t.Fatalf("Expected no credit before approval, got balance %d", wallet.Balance)
+	}
+
+	queue, err := largeWins.GetAdminClaims(AdminPrizeClaimQuery{})
+	if err != nil || queue.Total != 2 || queue.Claims[0].Username != "Winner" || queue.Claims[0].IDNumber == "110101199001011234" {
+		t.Fatalf("Expected two masked claims in the queue, got %+v (err %v)", queue, err)
+	}
+	approved, rejected := queue.Claims[0], queue.Claims[1]
+
+	if _, err := largeWins.ApproveClaim(1, approved.ID); err != nil {
+		t.Fatalf("ApproveClaim failed: %v", err)
+	}
+	if _, err := largeWins.ApproveClaim(1, approved.ID); err != ErrPrizeClaimNotSubmitted {
+		t.Errorf("Expected ErrPrizeClaimNotSubmitted on a second approval, got %v", err)
+	}
+	if _, err := largeWins.RejectClaim(1, rejected.ID, RejectPrizeClaimRequest{Reason: " "}); err != ErrInvalidRejectReason {
+		t.Errorf("Expected ErrInvalidRejectReason, got %v", err)
+	}
+	resp, err := largeWins.RejectClaim(1, rejected.ID, RejectPrizeClaimRequest{Reason: "身份信息不符"})
+	if err != nil || resp.Status != model.PrizeClaimStatusRejected || resp.RejectReason != "身份信息不符" {
+		t.Fatalf("Expected the claim to be rejected, got %+v (err %v)", resp, err)
+	}
+
+	db.First(&wallet, wallet.ID)
+	if wallet.Balance != initialBalance+approved.PrizeAmount {
+		t.Errorf("Expected only the approved prize to be credited, got balance %d", wallet.Balance)
+	}
+	var tickets []model.Ticket
+	db.Where("id IN ?", []uint{approved.TicketID, rejected.TicketID}).Order("id ASC").Find(&tickets)
+	statuses := map[uint]model.TicketStatus{}
+	for _, ticket := range tickets {
+		statuses[ticket.ID] = ticket.Status
+	}
+	if statuses[approved.TicketID] != model.TicketStatusClaimed || statuses[rejected.TicketID] != model.TicketStatusScratched {
+		t.Errorf("Unexpected ticket statuses %v", statuses)
+	}
+
+	var logs int64
+	db.Model(&model.AdminLog{}).Where("action IN ? AND target_type = ?", []string{"approve_prize_claim", "reject_prize_claim"}, "prize_claim").Count(&logs)
+	if logs != 2 {
+		t.Errorf("Expected both reviews to be logged, got %d", logs)
+	}
+	if queue, err := largeWins.GetAdminClaims(AdminPrizeClaimQuery{}); err != nil || queue.Total != 0 {
+		t.Errorf("Expected an empty queue, got %+v (err %v)", queue, err)
+	}
+}
@@ -0,0 +1,246 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+	"gorm.io/gorm"
+)
+
+// setupPrizeFulfillmentTest creates a user, a product with card keys and a
+// lottery type whose first prize level pays out the product and whose second
+// pays points, with one unscratched ticket per given prize level
+func setupPrizeFulfillmentTest(t *testing.T, keys int, levels []int) (*gorm.DB, *ScratchService, *LargeWinService, *model.Product, uint, []uint) {
+	db := setupTenantTestDB(t)
+	if err := db.AutoMigrate(&model.PrizeClaim{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	lotteryService := NewLotteryService(db, testEncryptionKey)
+	walletService := NewWalletService(db)
+	exchangeService := NewExchangeService(db, walletService)
+	largeWins := NewLargeWinService(db, NewAdminService(db, walletService), testEncryptionKey)
+	scratchService := NewScratchService(db, lotteryService, walletService, nil, largeWins)
+
+	user := model.User{LinuxdoID: "gift_card_winner", Username: "Winner", Role: "user"}
+	db.Create(&user)
+	db.Create(&model.Wallet{UserID: user.ID})
+
+	product := model.Product{Name: "Gift Card", Price: 500, Status: model.ProductStatusSoldOut}
+	db.Create(&product)
+	cardKeys := make([]string, keys)
+	for i := range cardKeys {
+		cardKeys[i] = fmt.Sprintf("GIFT-%04d", i)
+	}
+	if _, err := exchangeService.ImportCardKeys(product.ID, cardKeys); err != nil {
+		t.Fatalf("Failed to import card keys: %v", err)
+	}
+
+	lotteryType := model.LotteryType{Name: "Gift", Price: 10, MaxPrize: 1000, GameType: model.GameTypeNumberMatch, Status: model.LotteryTypeStatusAvailable}
+	db.Create(&lotteryType)
+	inputs := []PrizeLevelInput{
+		{Level: 1, Name: "Gift card", PrizeAmount: 1000, Quantity: 10, PayoutType: model.PrizePayoutProduct, PayoutProductID: product.ID},
+		{Level: 2, Name: "Points", PrizeAmount: 20, Quantity: 10},
+	}
+	for _, input := range inputs {
+		if err := createPrizeLevel(db, lotteryType.ID, 1, input); err != nil {
+			t.Fatalf("Failed to create prize level: %v", err)
+		}
+	}
+	prizePool := model.PrizePool{LotteryTypeID: lotteryType.ID, TotalTickets: len(levels), Status: model.PrizePoolStatusActive}
+	db.Create(&prizePool)
+
+	prizes := map[int]int{0: 0, 1: 1000, 2: 20}
+	ticketIDs := make([]uint, len(levels))
+	for i, level := range levels {
+		encrypted, err := lotteryService.EncryptTicketContent(&TicketContent{PrizeLevel: level, PrizeAmount: prizes[level]})
+		if err != nil {
+			t.Fatalf("Failed to encrypt content: %v", err)
+		}
+		code, _ := lotteryService.GenerateUniqueSecurityCode()
+		ticket := model.Ticket{
+			UserID:           user.ID,
+			LotteryTypeID:    lotteryType.ID,
+			PrizePoolID:      prizePool.ID,
+			SecurityCode:     code,
+			ContentEncrypted: encrypted,
+			PrizeAmount:      prizes[level],
+			Status:           model.TicketStatusUnscratched,
+			PurchasedAt:      time.Now(),
+		}
+		db.Create(&ticket)
+		ticketIDs[i] = ticket.ID
+	}
+
+	db.First(&product, product.ID)
+	return db, scratchService, largeWins, &product, user.ID, ticketIDs
+}
+
+// Product payouts: winning a prize level paid as a product creates a pending
+// exchange record instead of crediting points. Approval delivers a card key
+// from stock, or a hand-entered one once stock runs out, exactly once.
+func TestPrizeFulfillmentLifecycle(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("product prizes are fulfilled instead of credited", prop.ForAll(
+		func(levels []int, keys int) bool {
+			db, scratchService, _, product, userID, ticketIDs := setupPrizeFulfillmentTest(t, keys, levels)
+			fulfillments := NewPrizeFulfillmentService(db)
+
+			var wallet model.Wallet
+			db.Where("user_id = ?", userID).First(&wallet)
+			expectedBalance := wallet.Balance
+
+			productWins := 0
+			for i, ticketID := range ticketIDs {
+				resp, err := scratchService.ScratchTicket(userID, ticketID, "")
+				if err != nil {
+					t.Logf("ScratchTicket failed: %v", err)
+					return false
+				}
+				if resp.FulfillmentPending != (levels[i] == 1) || resp.ClaimRequired {
+					t.Logf("Level %d: unexpected response %+v", levels[i], resp)
+					return false
+				}
+				if levels[i] == 1 {
+					productWins++
+				} else {
+					expectedBalance += resp.PrizeAmount
+				}
+			}
+
+			db.First(&wallet, wallet.ID)
+			if wallet.Balance != expectedBalance {
+				t.Logf("Expected balance %d, got %d", expectedBalance, wallet.Balance)
+				return false
+			}
+
+			pending, err := fulfillments.GetFulfillments(PrizeFulfillmentQuery{Limit: 100})
+			if err != nil || int(pending.Total) != productWins {
+				t.Logf("Expected %d pending fulfillments, got %+v (err %v)", productWins, pending, err)
+				return false
+			}
+
+			for i, fulfillment := range pending.Fulfillments {
+				if fulfillment.ProductID != product.ID || fulfillment.PrizeAmount != 1000 || fulfillment.Username != "Winner" {
+					t.Logf("Unexpected fulfillment %+v", fulfillment)
+					return false
+				}
+				req := ApproveFulfillmentRequest{}
+				if i >= keys {
+					if _, err := fulfillments.Approve(1, fulfillment.RecordID, req); err != ErrNoAvailableCardKey {
+						t.Logf("Expected ErrNoAvailableCardKey once stock runs out, got %v", err)
+						return false
+					}
+					req.KeyContent = fmt.Sprintf("MANUAL-%d", i)
+				}
+				if _, err := fulfillments.Approve(1, fulfillment.RecordID, req); err != nil {
+					t.Logf("Approve failed: %v", err)
+					return false
+				}
+				if _, err := fulfillments.Approve(1, fulfillment.RecordID, req); err != ErrFulfillmentAlreadyDelivered {
+					t.Logf("Expected ErrFulfillmentAlreadyDelivered on a second approval, got %v", err)
+					return false
+				}
+
+				var record model.ExchangeRecord
+				db.Preload("CardKey").First(&record, fulfillment.RecordID)
+				if record.Status != model.ExchangeRecordStatusCompleted || record.CardKey.KeyContent == "" || record.CardKey.RedeemedBy != userID {
+					t.Logf("Expected a delivered card key, got %+v", record)
+					return false
+				}
+			}
+
+			db.First(product, product.ID)
+			if product.Stock != keys-min(keys, productWins) {
+				t.Logf("Expected stock %d, got %d", keys-min(keys, productWins), product.Stock)
+				return false
+			}
+
+			db.First(&wallet, wallet.ID)
+			var prizePool model.PrizePool
+			db.First(&prizePool)
+			wins := 0
+			for _, level := range levels {
+				if level > 0 {
+					wins++
+				}
+			}
+			return wallet.Balance == expectedBalance && prizePool.ClaimedPrizes == wins
+		},
+		gen.SliceOfN(6, gen.IntRange(0, 2)),
+		gen.IntRange(0, 3),
+	))
+
+	properties.TestingRun(t)
+}
+
+func TestPrizeFulfillmentAfterLargeWinClaim(t *testing.T) {
+	db, scratchService, largeWins, product, userID, ticketIDs := setupPrizeFulfillmentTest(t, 1, []int{1})
+
+	// A product payout needs an existing product and a prize
+	if err := createPrizeLevel(db, 1, 1, PrizeLevelInput{Level: 3, Name: "Missing", PrizeAmount: 10, Quantity: 1, PayoutType: model.PrizePayoutProduct, PayoutProductID: 999}); err != ErrInvalidPrizePayout {
+		t.Errorf("Expected ErrInvalidPrizePayout for a missing product, got %v", err)
+	}
+	if err := createPrizeLevel(db, 1, 1, PrizeLevelInput{Level: 3, Name: "Unknown", PrizeAmount: 10, Quantity: 1, PayoutType: "cash"}); err != ErrInvalidPrizePayout {
+		t.Errorf("Expected ErrInvalidPrizePayout for an unknown payout type, got %v", err)
+	}
+
+	threshold, required := 500, true
+	if _, err := largeWins.adminService.UpdateSystemSettings(1, UpdateSystemSettingsRequest{
+		LargeWinThreshold:        &threshold,
+		LargeWinIdentityRequired: &required,
+	}); err != nil {
+		t.Fatalf("UpdateSystemSettings failed: %v", err)
+	}
+
+	// A held large win pays out the product once the winner confirms their identity
+	resp, err := scratchService.ScratchTicket(userID, ticketIDs[0], "")
+	if err != nil || !resp.ClaimRequired || resp.FulfillmentPending {
+		t.Fatalf("Expected a held prize, got %+v (err %v)", resp, err)
+	}
+	fulfillments := NewPrizeFulfillmentService(db)
+	if pending, _ := fulfillments.GetFulfillments(PrizeFulfillmentQuery{}); pending == nil || pending.Total != 0 {
+		t.Errorf("Expected no fulfillment before the claim, got %+v", pending)
+	}
+
+	var wallet model.Wallet
+	db.Where("user_id = ?", userID).First(&wallet)
+	balance := wallet.Balance
+	if _, err := largeWins.ClaimPrize(userID, ticketIDs[0], ClaimPrizeRequest{FullName: "张三", IDNumber: "110101199001011234"}); err != nil {
+		t.Fatalf("ClaimPrize failed: %v", err)
+	}
+	db.First(&wallet, wallet.ID)
+	if wallet.Balance != balance {
+		t.Errorf("Expected no points for a product payout, got balance %d (was %d)", wallet.Balance, balance)
+	}
+
+	pending, err := fulfillments.GetFulfillments(PrizeFulfillmentQuery{})
+	if err != nil || pending.Total != 1 || pending.Fulfillments[0].ProductID != product.ID {
+		t.Fatalf("Expected one pending fulfillment after the claim, got %+v (err %v)", pending, err)
+	}
+	if _, err := fulfillments.Approve(1, pending.Fulfillments[0].RecordID, ApproveFulfillmentRequest{}); err != nil {
+		t.Fatalf("Approve failed: %v", err)
+	}
+
+	var logs int64
+	db.Model(&model.AdminLog{}).Where("action = ?", "approve_prize_fulfillment").Count(&logs)
+	if logs != 1 {
+		t.Errorf("Expected the approval to be logged, got %d logs", logs)
+	}
+
+	// Fulfillments are kept per tenant
+	if _, err := fulfillments.ForTenant(2).Approve(1, pending.Fulfillments[0].RecordID, ApproveFulfillmentRequest{}); err != ErrFulfillmentNotFound {
+		t.Errorf("Expected ErrFulfillmentNotFound for tenant 2, got %v", err)
+	}
+}
@@ -0,0 +1,273 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrFulfillmentNotFound         = errors.New("prize fulfillment not found")
+	ErrFulfillmentAlreadyDelivered = errors.New("prize fulfillment already delivered")
+)
+
+// PrizeFulfillmentService handles prizes paid out as exchange products. Winning
+// a prize level with a product payout creates a pending exchange record for the
+// winner instead of crediting points, and an admin approves it by delivering a
+// card key, either from the product's stock or entered by hand.
+type PrizeFulfillmentService struct {
+	db        *gorm.DB
+	redaction FieldRedaction
+}
+
+// NewPrizeFulfillmentService creates a new prize fulfillment service
+func NewPrizeFulfillmentService(db *gorm.DB) *PrizeFulfillmentService {
+	return &PrizeFulfillmentService{db: db}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *PrizeFulfillmentService) ForTenant(tenantID uint) *PrizeFulfillmentService {
+	return &PrizeFulfillmentService{db: repository.ScopeTenant(s.db, tenantID), redaction: s.redaction}
+}
+
+// WithRedaction returns a copy of the service that redacts its responses
+func (s *PrizeFulfillmentService) WithRedaction(redaction FieldRedaction) *PrizeFulfillmentService {
+	return &PrizeFulfillmentService{db: s.db, redaction: redaction}
+}
+
+// PrizeFulfillmentQuery represents query parameters for prize fulfillments
+type PrizeFulfillmentQuery struct {
+	Status string `form:"status"` // Defaults to pending
+	Page   int    `form:"page"`
+	Limit  int    `form:"limit"`
+}
+
+// PrizeFulfillmentResponse represents a prize paid out as an exchange product
+type PrizeFulfillmentResponse struct {
+	RecordID     uint                       `json:"record_id"`
+	TicketID     uint                       `json:"ticket_id"`
+	SecurityCode string                     `json:"security_code"`
+	PrizeAmount  int                        `json:"prize_amount"`
+	UserID       uint                       `json:"user_id"`
+	Username     string                     `json:"username"`
+	ProductID    uint                       `json:"product_id"`
+	ProductName  string                     `json:"product_name"`
+	Status       model.ExchangeRecordStatus `json:"status"`
+	CreatedAt    time.Time                  `json:"created_at"`
+}
+
+// PrizeFulfillmentListResponse represents paginated prize fulfillments
+type PrizeFulfillmentListResponse struct {
+	Fulfillments []PrizeFulfillmentResponse `json:"fulfillments"`
+	Total        int64                      `json:"total"`
+	Page         int                        `json:"page"`
+	Limit        int                        `json:"limit"`
+	TotalPages   int                        `json:"total_pages"`
+}
+
+// ApproveFulfillmentRequest represents a request to deliver a prize product.
+// Without a key content a card key is taken from the product's stock.
+type ApproveFulfillmentRequest struct {
+	KeyContent string `json:"key_content" binding:"max=512"`
+}
+
+// GetFulfillments lists prizes paid out as exchange products (admin only)
+func (s *PrizeFulfillmentService) GetFulfillments(query PrizeFulfillmentQuery) (*PrizeFulfillmentListResponse, error) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit < 1 || query.Limit > 100 {
+		query.Limit = 20
+	}
+	if query.Status == "" {
+		query.Status = string(model.ExchangeRecordStatusPending)
+	}
+
+	dbQuery := s.db.Model(&model.ExchangeRecord{}).
+		Where("ticket_id IS NOT NULL AND status = ?", query.Status)
+
+	var total int64
+	if err := dbQuery.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	var records []model.ExchangeRecord
+	offset := (query.Page - 1) * query.Limit
+	if err := dbQuery.Preload("User").Preload("Product", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).
+		Order("created_at ASC").
+		Offset(offset).
+		Limit(query.Limit).
+		Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	ticketIDs := make([]uint, len(records))
+	for i, record := range records {
+		ticketIDs[i] = *record.TicketID
+	}
+	var tickets []model.Ticket
+	if err := s.db.Where("id IN ?", ticketIDs).Find(&tickets).Error; err != nil {
+		return nil, err
+	}
+	ticketsByID := make(map[uint]model.Ticket, len(tickets))
+	for _, ticket := range tickets {
+		ticketsByID[ticket.ID] = ticket
+	}
+
+	fulfillments := make([]PrizeFulfillmentResponse, len(records))
+	for i, record := range records {
+		ticket := ticketsByID[*record.TicketID]
+		fulfillments[i] = PrizeFulfillmentResponse{
+			RecordID:     record.ID,
+			TicketID:     *record.TicketID,
+			SecurityCode: s.redaction.SecurityCode(ticket.SecurityCode),
+			PrizeAmount:  ticket.PrizeAmount,
+			UserID:       record.UserID,
+			Username:     record.User.Username,
+			ProductID:    record.ProductID,
+			ProductName:  record.Product.Name,
+			Status:       record.Status,
+			CreatedAt:    record.CreatedAt,
+		}
+	}
+
+	totalPages := int(total) / query.Limit
+	if int(total)%query.Limit > 0 {
+		totalPages++
+	}
+
+	return &PrizeFulfillmentListResponse{
+		Fulfillments: fulfillments,
+		Total:        total,
+		Page:         query.Page,
+		Limit:        query.Limit,
+		TotalPages:   totalPages,
+	}, nil
+}
+
+// Approve delivers a pending prize product to its winner and counts the prize
+// as claimed in its prize pool
+func (s *PrizeFulfillmentService) Approve(adminID, recordID uint, req ApproveFulfillmentRequest) (*PrizeFulfillmentResponse, error) {
+	var record model.ExchangeRecord
+	if err := s.db.Where("id = ? AND ticket_id IS NOT NULL", recordID).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFulfillmentNotFound
+		}
+		return nil, err
+	}
+	if record.Status != model.ExchangeRecordStatusPending {
+		return nil, ErrFulfillmentAlreadyDelivered
+	}
+	if held, err := payoutsFrozen(s.db, record.UserID); err != nil {
+		return nil, err
+	} else if held {
+		return nil, ErrPayoutDisputed
+	}
+
+	var ticket model.Ticket
+	if err := s.db.First(&ticket, *record.TicketID).Error; err != nil {
+		return nil, err
+	}
+
+	keyContent := strings.TrimSpace(req.KeyContent)
+	err := repository.Transaction(s.db, func(tx *gorm.DB) error {
+		// Complete the record, guarding against a concurrent approval
+		result := tx.Model(&model.ExchangeRecord{}).
+			Where("id = ? AND status = ?", record.ID, model.ExchangeRecordStatusPending).
+			Update("status", model.ExchangeRecordStatusCompleted)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrFulfillmentAlreadyDelivered
+		}
+
+		var cardKey model.CardKey
+		if keyContent != "" {
+			now := time.Now()
+			cardKey = model.CardKey{
+				ProductID:  record.ProductID,
+				KeyContent: keyContent,
+				Status:     model.CardKeyStatusRedeemed,
+				RedeemedBy: record.UserID,
+				RedeemedAt: &now,
+			}
+			if err := tx.Create(&cardKey).Error; err != nil {
+				return err
+			}
+		} else {
+			if err := claimCardKey(tx, record.ProductID, record.UserID, &cardKey); err != nil {
+				return err
+			}
+			if err := takeProductStock(tx, record.ProductID); err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&model.ExchangeRecord{}).Where("id = ?", record.ID).
+			Update("card_key_id", cardKey.ID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&model.PrizePool{}).Where("id = ?", ticket.PrizePoolID).
+			Update("claimed_prizes", gorm.Expr("claimed_prizes + 1")).Error; err != nil {
+			return err
+		}
+
+		details, _ := json.Marshal(map[string]interface{}{
+			"ticket_id":  ticket.ID,
+			"product_id": record.ProductID,
+			"manual_key": keyContent != "",
+		})
+		adminLog := model.AdminLog{
+			AdminID:    adminID,
+			Action:     "approve_prize_fulfillment",
+			TargetType: "exchange_record",
+			TargetID:   record.ID,
+			Details:    string(details),
+		}
+		return tx.Create(&adminLog).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Preload("User").Preload("Product", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).First(&record, record.ID).Error; err != nil {
+		return nil, err
+	}
+	return &PrizeFulfillmentResponse{
+		RecordID:     record.ID,
+		TicketID:     ticket.ID,
+		SecurityCode: s.redaction.SecurityCode(ticket.SecurityCode),
+		PrizeAmount:  ticket.PrizeAmount,
+		UserID:       record.UserID,
+		Username:     record.User.Username,
+		ProductID:    record.ProductID,
+		ProductName:  record.Product.Name,
+		Status:       record.Status,
+		CreatedAt:    record.CreatedAt,
+	}, nil
+}
+
+// createPrizeFulfillment pays out the prize of a won ticket as a pending
+// exchange record of a product within the caller's transaction
+func createPrizeFulfillment(tx *gorm.DB, ticket *model.Ticket, productID uint) error {
+	ticketID := ticket.ID
+	record := model.ExchangeRecord{
+		TenantID:  ticket.TenantID,
+		UserID:    ticket.UserID,
+		ProductID: productID,
+		Status:    model.ExchangeRecordStatusPending,
+		TicketID:  &ticketID,
+	}
+	return tx.Create(&record).Error
+}
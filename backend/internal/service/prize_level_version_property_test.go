@@ -0,0 +1,206 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+)
+
+// Prize level versions: structural prize level changes are refused while a
+// pool that sold tickets is active and otherwise start a new version, renames
+// apply in place, and every version's remaining quantities match the winning
+// tickets sold from the pools bound to it.
+func TestPrizeLevelVersionsFollowPools(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("pools keep drawing from their own prize levels", prop.ForAll(
+		func(ops []int) bool {
+			db := setupLotteryTestDB(t)
+			lotteryService := NewLotteryService(db, testEncryptionKey)
+
+			user := model.User{LinuxdoID: "version_user", Username: "Test", Role: "user"}
+			db.Create(&user)
+			db.Create(&model.Wallet{UserID: user.ID})
+
+			// A type created before versioning: levels and pool take the default version
+			lotteryType := model.LotteryType{Name: "Versioned", Price: 10, MaxPrize: 100, GameType: model.GameTypeNumberMatch, Status: model.LotteryTypeStatusAvailable}
+			db.Create(&lotteryType)
+			db.Create(&model.PrizeLevel{LotteryTypeID: lotteryType.ID, Level: 1, Name: "First", PrizeAmount: 100, Quantity: 2, Remaining: 2})
+			db.Create(&model.PrizeLevel{LotteryTypeID: lotteryType.ID, Level: 2, Name: "Second", PrizeAmount: 20, Quantity: 4, Remaining: 4})
+			db.Create(&model.PrizePool{LotteryTypeID: lotteryType.ID, TotalTickets: 8, Status: model.PrizePoolStatusActive})
+
+			for step, op := range ops {
+				var active model.PrizePool
+				hasActive := db.Where("lottery_type_id = ? AND status = ?", lotteryType.ID, model.PrizePoolStatusActive).First(&active).Error == nil
+				db.First(&lotteryType, lotteryType.ID)
+
+				switch op {
+				case 0:
+					if hasActive {
+						if _, err := lotteryService.GenerateTicket(user.ID, lotteryType.ID); err != nil {
+							t.Logf("GenerateTicket failed: %v", err)
+							return false
+						}
+					}
+				case 1:
+					levels := []PrizeLevelInput{
+						{Level: 1, Name: "First", PrizeAmount: 100, Quantity: 1 + step%3},
+						{Level: 2, Name: "Second", PrizeAmount: 20, Quantity: 3},
+					}
+					var current []model.PrizeLevel
+					db.Where("lottery_type_id = ? AND version = ?", lotteryType.ID, lotteryType.PrizeLevelVersion).Find(&current)
+					structural := !sameLevelStructure(current, levels)
+					err := lotteryService.UpdatePrizeLevels(lotteryType.ID, levels)
+					if refused := structural && hasActive && active.SoldTickets > 0; refused != (err == ErrPrizeLevelsInUse) {
+						t.Logf("Expected refusal %v, got %v", refused, err)
+						return false
+					}
+					if err != nil && err != ErrPrizeLevelsInUse {
+						t.Logf("UpdatePrizeLevels failed: %v", err)
+						return false
+					}
+				case 2:
+					current, err := lotteryService.GetPrizeLevels(lotteryType.ID)
+					if err != nil {
+						t.Logf("GetPrizeLevels failed: %v", err)
+						return false
+					}
+					levels := make([]PrizeLevelInput, len(current))
+					for i, pl := range current {
+						levels[i] = PrizeLevelInput{Level: pl.Level, Name: fmt.Sprintf("Renamed %d", step), PrizeAmount: pl.PrizeAmount, Quantity: pl.Quantity}
+					}
+					if err := lotteryService.UpdatePrizeLevels(lotteryType.ID, levels); err != nil {
+						t.Logf("Renaming prize levels failed: %v", err)
+						return false
+					}
+					var updated model.LotteryType
+					db.First(&updated, lotteryType.ID)
+					if updated.PrizeLevelVersion != lotteryType.PrizeLevelVersion {
+						t.Logf("Expected a rename to keep version %d, got %d", lotteryType.PrizeLevelVersion, updated.PrizeLevelVersion)
+						return false
+					}
+				case 3:
+					if hasActive {
+						db.Model(&active).Update("status", model.PrizePoolStatusClosed)
+					}
+					pool, err := lotteryService.CreatePrizePool(CreatePrizePoolRequest{LotteryTypeID: lotteryType.ID, TotalTickets: 8, ReturnRate: 0.5})
+					if err != nil || pool.PrizeLevelVersion != lotteryType.PrizeLevelVersion {
+						t.Logf("Expected a new pool on version %d, got %+v (err %v)", lotteryType.PrizeLevelVersion, pool, err)
+						return false
+					}
+				}
+			}
+
+			// Winning tickets sold from each version's pools
+			var pools []model.PrizePool
+			db.Where("lottery_type_id = ?", lotteryType.ID).Find(&pools)
+			versionOf := make(map[uint]int, len(pools))
+			for _, pool := range pools {
+				versionOf[pool.ID] = pool.PrizeLevelVersion
+			}
+			type versionLevel struct{ version, level int }
+			won := make(map[versionLevel]int)
+			var tickets []model.Ticket
+			db.Where("lottery_type_id = ?", lotteryType.ID).Find(&tickets)
+			for _, ticket := range tickets {
+				content, err := lotteryService.DecryptTicketContent(ticket.ContentEncrypted)
+				if err != nil {
+					t.Logf("Failed to decrypt ticket: %v", err)
+					return false
+				}
+				if content.PrizeLevel > 0 {
+					won[versionLevel{versionOf[ticket.PrizePoolID], content.PrizeLevel}]++
+				}
+			}
+
+			var levels []model.PrizeLevel
+			db.Where("lottery_type_id = ?", lotteryType.ID).Find(&levels)
+			for _, pl := range levels {
+				if pl.Quantity-pl.Remaining != won[versionLevel{pl.Version, pl.Level}] {
+					t.Logf("Version %d level %d: %d of %d left with %d won", pl.Version, pl.Level, pl.Remaining, pl.Quantity, won[versionLevel{pl.Version, pl.Level}])
+					return false
+				}
+			}
+
+			// The catalogue shows the current version only
+			db.First(&lotteryType, lotteryType.ID)
+			current, err := lotteryService.GetPrizeLevels(lotteryType.ID)
+			if err != nil || len(current) != 2 {
+				t.Logf("Expected the 2 current prize levels, got %+v (err %v)", current, err)
+				return false
+			}
+			detail, err := lotteryService.GetLotteryTypeByID(lotteryType.ID)
+			return err == nil && len(detail.PrizeLevels) == 2
+		},
+		gen.SliceOfN(20, gen.IntRange(0, 3)),
+	))
+
+	properties.TestingRun(t)
+}
+
+// Prize level images, colors and copy show in the lottery type detail, change
+// in place without a new version, and are checked before anything is saved
+func TestPrizeLevelDisplay(t *testing.T) {
+	db := setupLotteryTestDB(t)
+	lotteryService := NewLotteryService(db, testEncryptionKey)
+
+	levels := []PrizeLevelInput{
+		{Level: 1, Name: "Top", PrizeAmount: 50, Quantity: 1, Image: "/uploads/design/top.png", Color: "#f59e0b", Description: "Grand prize"},
+		{Level: 2, Name: "Small", PrizeAmount: 5, Quantity: 10},
+	}
+	created, err := lotteryService.CreateLotteryType(CreateLotteryTypeRequest{
+		Name: "Shiny", Price: 10, MaxPrize: 100, GameType: model.GameTypeAmountSum,
+		RulesConfig: map[string]any{"areas": 9}, PrizeLevels: levels,
+	})
+	if err != nil {
+		t.Fatalf("CreateLotteryType failed: %v", err)
+	}
+	detail, err := lotteryService.GetLotteryTypeByID(created.ID)
+	if err != nil || len(detail.PrizeLevels) != 2 {
+		t.Fatalf("GetLotteryTypeByID failed: %+v (err %v)", detail, err)
+	}
+	top := detail.PrizeLevels[0]
+	if top.Image != levels[0].Image || top.Color != levels[0].Color || top.Description != levels[0].Description {
+		t.Errorf("Expected the display fields of level 1, got %+v", top)
+	}
+
+	var before model.LotteryType
+	db.First(&before, created.ID)
+	levels[1].Image, levels[1].Color, levels[1].Description = "https://cdn.example.com/small.png", "#0f0", "Win often"
+	if err := lotteryService.UpdatePrizeLevels(created.ID, levels); err != nil {
+		t.Fatalf("UpdatePrizeLevels failed: %v", err)
+	}
+	var after model.LotteryType
+	db.First(&after, created.ID)
+	current, _ := lotteryService.GetPrizeLevels(created.ID)
+	if after.PrizeLevelVersion != before.PrizeLevelVersion || current[1].Color != "#0f0" || current[1].Description != "Win often" {
+		t.Errorf("Expected an in-place display update on version %d, got version %d and %+v", before.PrizeLevelVersion, after.PrizeLevelVersion, current[1])
+	}
+
+	invalid := []PrizeLevelInput{
+		{Image: "javascript:alert(1)"},
+		{Color: "red"},
+		{Description: strings.Repeat("奖", maxPrizeLevelDescriptionLength+1)},
+	}
+	for _, display := range invalid {
+		changed := append([]PrizeLevelInput(nil), levels...)
+		changed[0].Image, changed[0].Color, changed[0].Description = display.Image, display.Color, display.Description
+		if err := lotteryService.UpdatePrizeLevels(created.ID, changed); err != ErrInvalidPrizeLevelDisplay {
+			t.Errorf("Expected %+v to be refused, got %v", display, err)
+		}
+	}
+	current, _ = lotteryService.GetPrizeLevels(created.ID)
+	if current[0].Color != levels[0].Color {
+		t.Errorf("Expected refused updates to change nothing, got %+v", current[0])
+	}
+}
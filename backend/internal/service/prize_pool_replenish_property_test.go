@@ -0,0 +1,125 @@
+package service
+
+import (
+	"testing"
+
+	"scratch-lottery/internal/model"
+)
+
+// Prize pool replenishment: a sold out pool of an auto-replenished lottery
+// type is followed by exactly one new pool from the configured template, in a
+// new prize level version and logged in the tenant; closed pools and types
+// without auto-replenishment are left alone.
+func TestPrizePoolReplenishFromTemplate(t *testing.T) {
+	db := setupPrizeTemplateTestDB(t)
+	lotteryService := NewLotteryService(db, testEncryptionKey)
+	templates := NewPrizeTemplateService(db, lotteryService).ForTenant(2)
+	template, err := templates.CreateTemplate(testPrizeTemplate())
+	if err != nil {
+		t.Fatalf("CreateTemplate failed: %v", err)
+	}
+	instance, err := templates.CreateLotteryType(template.ID, CreateLotteryTypeFromTemplateRequest{
+		Name:     "Replenished",
+		GameType: model.GameTypeNumberMatch,
+	})
+	if err != nil {
+		t.Fatalf("CreateLotteryType failed: %v", err)
+	}
+	lotteryTypeID := instance.LotteryType.ID
+
+	enabled, tickets := true, 20000
+	if _, err := lotteryService.ForTenant(2).UpdateLotteryType(lotteryTypeID, UpdateLotteryTypeRequest{
+		AutoReplenish:       &enabled,
+		ReplenishTemplateID: &template.ID,
+		ReplenishTickets:    &tickets,
+	}); err != nil {
+		t.Fatalf("UpdateLotteryType failed: %v", err)
+	}
+	replenisher := NewPrizePoolReplenishService(db)
+
+	// Nothing is due while the pool is still selling
+	if pools, err := replenisher.Replenish(); err != nil || len(pools) != 0 {
+		t.Fatalf("Expected no replenishment of an active pool, got %+v (err %v)", pools, err)
+	}
+
+	db.Model(&model.PrizePool{}).Where("id = ?", instance.PrizePool.ID).Update("status", model.PrizePoolStatusSoldOut)
+	pools, err := replenisher.Replenish()
+	if err != nil || len(pools) != 1 {
+		t.Fatalf("Expected one replenished pool, got %+v (err %v)", pools, err)
+	}
+	pool := pools[0]
+	if pool.LotteryTypeID != lotteryTypeID || pool.TotalTickets != tickets || pool.Status != model.PrizePoolStatusActive || pool.PrizeLevelVersion != 2 {
+		t.Errorf("Unexpected replenished pool %+v", pool)
+	}
+	var levels []model.PrizeLevel
+	poolPrizeLevels(db, &pool).Order("level ASC").Find(&levels)
+	if len(levels) != 3 || levels[0].Quantity != 2 || levels[1].Quantity != 40 || levels[2].Remaining != 3000 {
+		t.Errorf("Expected the template levels at double size, got %+v", levels)
+	}
+
+	var log model.AdminLog
+	if err := db.Where("action = ? AND target_id = ?", "auto_replenish_prize_pool", pool.ID).First(&log).Error; err != nil {
+		t.Fatalf("Expected the replenishment to be logged: %v", err)
+	}
+	if log.TenantID != 2 || log.AdminID != 0 || log.TargetType != "prize_pool" {
+		t.Errorf("Unexpected admin log %+v", log)
+	}
+
+	// The new pool is active, so a second pass opens nothing
+	if pools, err := replenisher.Replenish(); err != nil || len(pools) != 0 {
+		t.Fatalf("Expected a single replenishment, got %+v (err %v)", pools, err)
+	}
+
+	// A closed pool is not replaced
+	db.Model(&model.PrizePool{}).Where("id = ?", pool.ID).Update("status", model.PrizePoolStatusClosed)
+	if pools, err := replenisher.Replenish(); err != nil || len(pools) != 0 {
+		t.Errorf("Expected no replenishment of a closed pool, got %+v (err %v)", pools, err)
+	}
+}
+
+// Prize pool replenishment without a template repeats the sold out pool with
+// its levels at full quantity
+func TestPrizePoolReplenishRepeatsPool(t *testing.T) {
+	db := setupPrizeTemplateTestDB(t)
+	lotteryService := NewLotteryService(db, testEncryptionKey)
+	lotteryType, err := lotteryService.CreateLotteryType(CreateLotteryTypeRequest{
+		Name:          "Repeat",
+		Price:         10,
+		MaxPrize:      100,
+		GameType:      model.GameTypeNumberMatch,
+		PrizeLevels:   []PrizeLevelInput{{Level: 1, Name: "一等奖", PrizeAmount: 100, Quantity: 2}},
+		AutoReplenish: true,
+	})
+	if err != nil {
+		t.Fatalf("CreateLotteryType failed: %v", err)
+	}
+	previous, err := lotteryService.CreatePrizePool(CreatePrizePoolRequest{LotteryTypeID: lotteryType.ID, TotalTickets: 20, ReturnRate: 0.5})
+	if err != nil {
+		t.Fatalf("CreatePrizePool failed: %v", err)
+	}
+	db.Model(&model.PrizePool{}).Where("id = ?", previous.ID).Update("status", model.PrizePoolStatusSoldOut)
+	db.Model(&model.PrizeLevel{}).Where("lottery_type_id = ?", lotteryType.ID).Update("remaining", 0)
+
+	pools, err := NewPrizePoolReplenishService(db).Replenish()
+	if err != nil || len(pools) != 1 {
+		t.Fatalf("Expected one replenished pool, got %+v (err %v)", pools, err)
+	}
+	if pools[0].TotalTickets != 20 || pools[0].ReturnRate != 0.5 {
+		t.Errorf("Expected the sold out pool to be repeated, got %+v", pools[0])
+	}
+	var levels []model.PrizeLevel
+	poolPrizeLevels(db, &pools[0]).Find(&levels)
+	if len(levels) != 1 || levels[0].PrizeAmount != 100 || levels[0].Remaining != 2 {
+		t.Errorf("Expected the levels at full quantity, got %+v", levels)
+	}
+
+	if _, err := lotteryService.CreateLotteryType(CreateLotteryTypeRequest{
+		Name:                "Missing template",
+		Price:               10,
+		MaxPrize:            100,
+		GameType:            model.GameTypeNumberMatch,
+		ReplenishTemplateID: 999,
+	}); err != ErrPrizeTemplateNotFound {
+		t.Errorf("Expected ErrPrizeTemplateNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+	"scratch-lottery/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// errReplenishClaimed means another instance replenished the lottery type first
+var errReplenishClaimed = errors.New("prize pool already replenished")
+
+// PrizePoolReplenishService opens a new prize pool for lottery types with
+// auto-replenishment once their active pool sells out. It works across tenants.
+type PrizePoolReplenishService struct {
+	db *gorm.DB
+}
+
+// NewPrizePoolReplenishService creates a new prize pool replenish service
+func NewPrizePoolReplenishService(db *gorm.DB) *PrizePoolReplenishService {
+	return &PrizePoolReplenishService{db: db}
+}
+
+// Start runs a replenish pass every interval until the returned stop func is called
+func (s *PrizePoolReplenishService) Start(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				pools, err := s.Replenish()
+				if err != nil {
+					logger.Default().Warn("Prize pool replenishment failed: %v", err)
+				}
+				for _, pool := range pools {
+					logger.Default().Info("Replenished lottery type %d with prize pool %d", pool.LotteryTypeID, pool.ID)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// Replenish runs one pass and returns the prize pools it opened. A lottery
+// type is replenished when it has no active pool and its latest pool sold out;
+// closed pools are left alone, since an admin closed them on purpose. A type
+// whose template no longer fits is skipped and logged, the others go on.
+func (s *PrizePoolReplenishService) Replenish() ([]model.PrizePool, error) {
+	var lotteryTypes []model.LotteryType
+	if err := s.db.Where("auto_replenish = ? AND status != ?", true, model.LotteryTypeStatusDisabled).
+		Find(&lotteryTypes).Error; err != nil {
+		return nil, err
+	}
+
+	var opened []model.PrizePool
+	for i := range lotteryTypes {
+		pool, err := s.replenish(&lotteryTypes[i])
+		if err != nil {
+			if !errors.Is(err, errReplenishClaimed) {
+				logger.Default().Warn("Replenishing lottery type %d failed: %v", lotteryTypes[i].ID, err)
+			}
+			continue
+		}
+		if pool != nil {
+			opened = append(opened, *pool)
+		}
+	}
+	return opened, nil
+}
+
+// replenish opens the next pool of a lottery type if its latest pool sold
+// out, returning nil when nothing is due
+func (s *PrizePoolReplenishService) replenish(lotteryType *model.LotteryType) (*model.PrizePool, error) {
+	db := repository.ScopeTenant(s.db, lotteryType.TenantID)
+
+	var latest model.PrizePool
+	if err := db.Where("lottery_type_id = ?", lotteryType.ID).
+		Order("id DESC").
+		First(&latest).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if latest.Status != model.PrizePoolStatusSoldOut {
+		return nil, nil
+	}
+
+	var active int64
+	if err := db.Model(&model.PrizePool{}).
+		Where("lottery_type_id = ? AND status = ?", lotteryType.ID, model.PrizePoolStatusActive).
+		Count(&active).Error; err != nil {
+		return nil, err
+	}
+	if active > 0 {
+		return nil, nil
+	}
+
+	totalTickets, levels, returnRate, err := s.nextPoolLevels(db, lotteryType, &latest)
+	if err != nil {
+		return nil, err
+	}
+
+	var prizePool *model.PrizePool
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// Claim the replenishment by bumping the prize level version, so
+		// concurrent instances open one pool between them
+		version := lotteryType.PrizeLevelVersion + 1
+		result := tx.Model(&model.LotteryType{}).
+			Where("id = ? AND prize_level_version = ?", lotteryType.ID, lotteryType.PrizeLevelVersion).
+			Update("prize_level_version", version)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errReplenishClaimed
+		}
+		lotteryType.PrizeLevelVersion = version
+
+		pool, err := openTemplatePool(tx, lotteryType, levels, totalTickets, returnRate)
+		if err != nil {
+			return err
+		}
+		prizePool = pool
+
+		details, _ := json.Marshal(map[string]interface{}{
+			"lottery_type_id":  lotteryType.ID,
+			"previous_pool_id": latest.ID,
+			"template_id":      lotteryType.ReplenishTemplateID,
+			"total_tickets":    totalTickets,
+		})
+		adminLog := model.AdminLog{
+			AdminID:    0,
+			Action:     "auto_replenish_prize_pool",
+			TargetType: "prize_pool",
+			TargetID:   pool.ID,
+			Details:    string(details),
+		}
+		return tx.Create(&adminLog).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return prizePool, nil
+}
+
+// nextPoolLevels returns the size, prize levels and return rate of the next
+// pool: the configured template scaled to the replenish size, or else the
+// levels of the sold out pool at their full quantities
+func (s *PrizePoolReplenishService) nextPoolLevels(db *gorm.DB, lotteryType *model.LotteryType, previous *model.PrizePool) (int, []PrizeLevelInput, float64, error) {
+	if lotteryType.ReplenishTemplateID != 0 {
+		var template model.PrizeTemplate
+		if err := db.First(&template, lotteryType.ReplenishTemplateID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return 0, nil, 0, ErrPrizeTemplateNotFound
+			}
+			return 0, nil, 0, err
+		}
+		totalTickets, levels, err := scaleTemplate(&template, InstantiateTemplateRequest{TotalTickets: lotteryType.ReplenishTickets}, lotteryType.Price)
+		if err != nil {
+			return 0, nil, 0, err
+		}
+		return totalTickets, levels, template.ReturnRate, nil
+	}
+
+	var prizeLevels []model.PrizeLevel
+	if err := poolPrizeLevels(db, previous).Order("level ASC").Find(&prizeLevels).Error; err != nil {
+		return 0, nil, 0, err
+	}
+	levels := make([]PrizeLevelInput, len(prizeLevels))
+	for i, level := range prizeLevels {
+		levels[i] = PrizeLevelInput{
+			Level:           level.Level,
+			Name:            level.Name,
+			PrizeAmount:     level.PrizeAmount,
+			Quantity:        level.Quantity,
+			PayoutType:      level.PayoutType,
+			PayoutProductID: level.PayoutProductID,
+			Image:           level.Image,
+			Color:           level.Color,
+			Description:     level.Description,
+		}
+	}
+	return previous.TotalTickets, levels, previous.ReturnRate, nil
+}
+
+// checkReplenishTemplate checks that the replenish template of a lottery type
+// exists in the tenant; 0 repeats the sold out pool
+func checkReplenishTemplate(db *gorm.DB, templateID uint) error {
+	if templateID == 0 {
+		return nil
+	}
+	var count int64
+	if err := db.Model(&model.PrizeTemplate{}).Where("id = ?", templateID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrPrizeTemplateNotFound
+	}
+	return nil
+}
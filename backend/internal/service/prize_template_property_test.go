@@ -0,0 +1,182 @@
+package service
+
+import (
+	"math"
+	"testing"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+	"gorm.io/gorm"
+)
+
+func setupPrizeTemplateTestDB(t *testing.T) *gorm.DB {
+	db := setupTenantTestDB(t)
+	if err := db.AutoMigrate(&model.PrizeTemplate{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	return db
+}
+
+// testPrizeTemplate is a template of 10000 tickets at price 10 paying out 60%
+func testPrizeTemplate() PrizeTemplateRequest {
+	return PrizeTemplateRequest{
+		Name:        "Standard",
+		TicketPrice: 10,
+		BaseTickets: 10000,
+		ReturnRate:  0.6,
+		Levels: []PrizeLevelInput{
+			{Level: 2, Name: "二等奖", PrizeAmount: 1000, Quantity: 20},
+			{Level: 1, Name: "一等奖", PrizeAmount: 10000, Quantity: 1},
+			{Level: 3, Name: "三等奖", PrizeAmount: 20, Quantity: 1500},
+		},
+	}
+}
+
+// Template scaling: a pool created from a template has the requested size,
+// level quantities proportional to it, and never pays out more than the
+// template return rate.
+func TestPrizeTemplateScaling(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("scaled pools keep proportions and the return rate", prop.ForAll(
+		func(totalTickets int) bool {
+			db := setupPrizeTemplateTestDB(t)
+			templates := NewPrizeTemplateService(db, NewLotteryService(db, testEncryptionKey)).ForTenant(2)
+			req := testPrizeTemplate()
+			template, err := templates.CreateTemplate(req)
+			if err != nil {
+				t.Logf("CreateTemplate failed: %v", err)
+				return false
+			}
+
+			instance, err := templates.CreateLotteryType(template.ID, CreateLotteryTypeFromTemplateRequest{
+				InstantiateTemplateRequest: InstantiateTemplateRequest{TotalTickets: totalTickets},
+				Name:                       "Scaled",
+				GameType:                   model.GameTypeNumberMatch,
+			})
+			if err == ErrTemplateReturnRate || err == ErrTemplateTooManyWinners {
+				// Rounding tiny levels up to one prize may break the constraints,
+				// in which case nothing is created
+				var count int64
+				db.Model(&model.LotteryType{}).Count(&count)
+				return count == 0
+			}
+			if err != nil {
+				t.Logf("CreateLotteryType failed: %v", err)
+				return false
+			}
+
+			if instance.PrizePool.TotalTickets != totalTickets || instance.PrizePool.ReturnRate != req.ReturnRate {
+				t.Logf("Unexpected pool %+v", instance.PrizePool)
+				return false
+			}
+			if instance.LotteryType.Price != req.TicketPrice || instance.LotteryType.MaxPrize != 10000 {
+				t.Logf("Unexpected lottery type %+v", instance.LotteryType.LotteryTypeResponse)
+				return false
+			}
+
+			factor := float64(totalTickets) / float64(req.BaseTickets)
+			var prizeValue, winners int
+			for i, level := range instance.LotteryType.PrizeLevels {
+				base := template.Levels[i]
+				exact := float64(base.Quantity) * factor
+				if level.Level != base.Level || level.Quantity < 1 || (math.Abs(float64(level.Quantity)-exact) > 0.5 && level.Quantity != 1) {
+					t.Logf("Level %d: expected about %.1f prizes, got %d", base.Level, exact, level.Quantity)
+					return false
+				}
+				prizeValue += level.Quantity * level.PrizeAmount
+				winners += level.Quantity
+			}
+			if winners > totalTickets || float64(prizeValue) > float64(totalTickets*req.TicketPrice)*req.ReturnRate+1e-6 {
+				t.Logf("Pool of %d tickets pays %d to %d winners", totalTickets, prizeValue, winners)
+				return false
+			}
+			return true
+		},
+		gen.IntRange(100, 100000),
+	))
+
+	properties.TestingRun(t)
+}
+
+func TestPrizeTemplateValidation(t *testing.T) {
+	db := setupPrizeTemplateTestDB(t)
+	templates := NewPrizeTemplateService(db, NewLotteryService(db, testEncryptionKey))
+
+	tooGenerous := testPrizeTemplate()
+	tooGenerous.ReturnRate = 0.5
+	duplicate := testPrizeTemplate()
+	duplicate.Levels[0].Level = 1
+	overRate := testPrizeTemplate()
+	overRate.ReturnRate = 1.5
+	crowded := testPrizeTemplate()
+	crowded.Levels[2].Quantity = 20000
+
+	cases := []struct {
+		req PrizeTemplateRequest
+		err error
+	}{
+		{tooGenerous, ErrTemplateReturnRate},
+		{duplicate, ErrInvalidPrizeTemplate},
+		{overRate, ErrInvalidPrizeTemplate},
+		{crowded, ErrTemplateTooManyWinners},
+	}
+	for i, tc := range cases {
+		if _, err := templates.CreateTemplate(tc.req); err != tc.err {
+			t.Errorf("Case %d: expected %v, got %v", i, tc.err, err)
+		}
+	}
+
+	template, err := templates.CreateTemplate(testPrizeTemplate())
+	if err != nil {
+		t.Fatalf("CreateTemplate failed: %v", err)
+	}
+	if template.Levels[0].Level != 1 || math.Abs(template.ExpectedReturnRate-0.6) > 1e-9 {
+		t.Errorf("Expected sorted levels paying 60%%, got %+v", template)
+	}
+
+	// Templates are kept per tenant
+	if _, err := templates.ForTenant(2).GetTemplate(template.ID); err != ErrPrizeTemplateNotFound {
+		t.Errorf("Expected ErrPrizeTemplateNotFound across tenants, got %v", err)
+	}
+
+	// A cheaper lottery type cannot carry the same prizes
+	cheap := model.LotteryType{Name: "Cheap", Price: 5, GameType: model.GameTypeNumberMatch}
+	if err := db.Create(&cheap).Error; err != nil {
+		t.Fatalf("Failed to create lottery type: %v", err)
+	}
+	if _, err := templates.CreatePrizePool(template.ID, CreatePoolFromTemplateRequest{LotteryTypeID: cheap.ID}); err != ErrTemplateReturnRate {
+		t.Errorf("Expected ErrTemplateReturnRate for a cheaper lottery type, got %v", err)
+	}
+
+	// Applying a template replaces the prize levels of the lottery type
+	regular := model.LotteryType{Name: "Regular", Price: 10, GameType: model.GameTypeNumberMatch}
+	if err := db.Create(&regular).Error; err != nil {
+		t.Fatalf("Failed to create lottery type: %v", err)
+	}
+	db.Create(&model.PrizeLevel{LotteryTypeID: regular.ID, Level: 9, Name: "Old", PrizeAmount: 1, Quantity: 1})
+	instance, err := templates.CreatePrizePool(template.ID, CreatePoolFromTemplateRequest{
+		InstantiateTemplateRequest: InstantiateTemplateRequest{Scale: 5},
+		LotteryTypeID:              regular.ID,
+	})
+	if err != nil {
+		t.Fatalf("CreatePrizePool failed: %v", err)
+	}
+	if instance.PrizePool.TotalTickets != 50000 || len(instance.LotteryType.PrizeLevels) != 3 || instance.LotteryType.PrizeLevels[2].Quantity != 7500 {
+		t.Errorf("Expected a pool of 50000 tickets with scaled levels, got %+v", instance)
+	}
+
+	if err := templates.DeleteTemplate(template.ID); err != nil {
+		t.Fatalf("DeleteTemplate failed: %v", err)
+	}
+	if _, err := templates.GetTemplate(template.ID); err != ErrPrizeTemplateNotFound {
+		t.Errorf("Expected deleted template to be gone, got %v", err)
+	}
+}
@@ -0,0 +1,453 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrPrizeTemplateNotFound  = errors.New("prize template not found")
+	ErrInvalidPrizeTemplate   = errors.New("invalid prize template")
+	ErrTemplateReturnRate     = errors.New("prize levels exceed the template return rate")
+	ErrTemplateTooManyWinners = errors.New("prize levels have more winners than tickets")
+	ErrInvalidTemplateScale   = errors.New("invalid template scale")
+)
+
+// returnRateTolerance absorbs float rounding when comparing return rates
+const returnRateTolerance = 1e-9
+
+// PrizeTemplateService manages reusable prize level templates and creates
+// lottery types and prize pools from them
+type PrizeTemplateService struct {
+	db             *gorm.DB
+	lotteryService *LotteryService
+}
+
+// NewPrizeTemplateService creates a new prize template service
+func NewPrizeTemplateService(db *gorm.DB, lotteryService *LotteryService) *PrizeTemplateService {
+	return &PrizeTemplateService{db: db, lotteryService: lotteryService}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *PrizeTemplateService) ForTenant(tenantID uint) *PrizeTemplateService {
+	return &PrizeTemplateService{
+		db:             repository.ScopeTenant(s.db, tenantID),
+		lotteryService: s.lotteryService.ForTenant(tenantID),
+	}
+}
+
+// PrizeTemplateRequest represents the request to create or update a prize template.
+// Levels are designed for a pool of BaseTickets tickets sold at TicketPrice.
+type PrizeTemplateRequest struct {
+	Name        string            `json:"name" binding:"required"`
+	Description string            `json:"description"`
+	TicketPrice int               `json:"ticket_price" binding:"required,gt=0"`
+	BaseTickets int               `json:"base_tickets" binding:"required,gt=0"`
+	ReturnRate  float64           `json:"return_rate" binding:"required"`
+	Levels      []PrizeLevelInput `json:"levels" binding:"required,dive"`
+}
+
+// PrizeTemplateResponse represents a prize template in API responses
+type PrizeTemplateResponse struct {
+	ID                 uint              `json:"id"`
+	Name               string            `json:"name"`
+	Description        string            `json:"description"`
+	TicketPrice        int               `json:"ticket_price"`
+	BaseTickets        int               `json:"base_tickets"`
+	ReturnRate         float64           `json:"return_rate"`
+	ExpectedReturnRate float64           `json:"expected_return_rate"` // prize value of the levels / ticket sales
+	TotalWinners       int               `json:"total_winners"`
+	Levels             []PrizeLevelInput `json:"levels"`
+	CreatedAt          time.Time         `json:"created_at"`
+	UpdatedAt          time.Time         `json:"updated_at"`
+}
+
+// InstantiateTemplateRequest represents the scaling of a template to a pool size.
+// TotalTickets takes precedence over Scale; without either the template is used as is.
+type InstantiateTemplateRequest struct {
+	TotalTickets int     `json:"total_tickets" binding:"gte=0"`
+	Scale        float64 `json:"scale" binding:"gte=0"`
+}
+
+// CreateLotteryTypeFromTemplateRequest represents the request to create a lottery
+// type with the scaled levels of a template and its first prize pool
+type CreateLotteryTypeFromTemplateRequest struct {
+	InstantiateTemplateRequest
+	Name                 string         `json:"name" binding:"required"`
+	Description          string         `json:"description"`
+	Price                int            `json:"price" binding:"gte=0"`     // defaults to the template ticket price
+	MaxPrize             int            `json:"max_prize" binding:"gte=0"` // defaults to the highest prize
+	GameType             model.GameType `json:"game_type" binding:"required"`
+	CoverImage           string         `json:"cover_image"`
+	RulesConfig          interface{}    `json:"rules_config"`
+	LowStockThreshold    int            `json:"low_stock_threshold" binding:"gte=0"`
+	WaitingRoomThreshold int            `json:"waiting_room_threshold" binding:"gte=0"`
+}
+
+// CreatePoolFromTemplateRequest represents the request to apply the scaled levels
+// of a template to an existing lottery type and open a new prize pool
+type CreatePoolFromTemplateRequest struct {
+	InstantiateTemplateRequest
+	LotteryTypeID uint `json:"lottery_type_id" binding:"required"`
+}
+
+// TemplateInstanceResponse represents a lottery type and prize pool created from a template
+type TemplateInstanceResponse struct {
+	LotteryType *LotteryTypeDetailResponse `json:"lottery_type"`
+	PrizePool   *PrizePoolResponse         `json:"prize_pool"`
+}
+
+// GetTemplates returns all prize templates
+func (s *PrizeTemplateService) GetTemplates() ([]PrizeTemplateResponse, error) {
+	var templates []model.PrizeTemplate
+	if err := s.db.Order("name ASC").Find(&templates).Error; err != nil {
+		return nil, err
+	}
+
+	responses := make([]PrizeTemplateResponse, 0, len(templates))
+	for i := range templates {
+		resp, err := toPrizeTemplateResponse(&templates[i])
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, *resp)
+	}
+	return responses, nil
+}
+
+// GetTemplate returns a prize template by ID
+func (s *PrizeTemplateService) GetTemplate(id uint) (*PrizeTemplateResponse, error) {
+	template, err := s.template(id)
+	if err != nil {
+		return nil, err
+	}
+	return toPrizeTemplateResponse(template)
+}
+
+// CreateTemplate validates and saves a new prize template
+func (s *PrizeTemplateService) CreateTemplate(req PrizeTemplateRequest) (*PrizeTemplateResponse, error) {
+	template := model.PrizeTemplate{}
+	if err := applyTemplateRequest(&template, req); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(&template).Error; err != nil {
+		return nil, err
+	}
+	return toPrizeTemplateResponse(&template)
+}
+
+// UpdateTemplate validates and replaces a prize template
+func (s *PrizeTemplateService) UpdateTemplate(id uint, req PrizeTemplateRequest) (*PrizeTemplateResponse, error) {
+	template, err := s.template(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyTemplateRequest(template, req); err != nil {
+		return nil, err
+	}
+	if err := s.db.Save(template).Error; err != nil {
+		return nil, err
+	}
+	return toPrizeTemplateResponse(template)
+}
+
+// DeleteTemplate soft deletes a prize template. Lottery types created from it are kept.
+func (s *PrizeTemplateService) DeleteTemplate(id uint) error {
+	template, err := s.template(id)
+	if err != nil {
+		return err
+	}
+	return s.db.Delete(template).Error
+}
+
+// CreateLotteryType creates a lottery type with the scaled levels of a template
+// and opens its first prize pool
+func (s *PrizeTemplateService) CreateLotteryType(templateID uint, req CreateLotteryTypeFromTemplateRequest) (*TemplateInstanceResponse, error) {
+	template, err := s.template(templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	price := req.Price
+	if price == 0 {
+		price = template.TicketPrice
+	}
+	totalTickets, levels, err := scaleTemplate(template, req.InstantiateTemplateRequest, price)
+	if err != nil {
+		return nil, err
+	}
+
+	maxPrize := req.MaxPrize
+	if maxPrize == 0 {
+		for _, level := range levels {
+			if level.PrizeAmount > maxPrize {
+				maxPrize = level.PrizeAmount
+			}
+		}
+	}
+
+	var rulesConfigJSON string
+	if req.RulesConfig != nil {
+		data, err := json.Marshal(req.RulesConfig)
+		if err != nil {
+			return nil, ErrInvalidPrizeConfig
+		}
+		rulesConfigJSON = string(data)
+	}
+
+	lotteryType := model.LotteryType{
+		Name:                 req.Name,
+		Description:          req.Description,
+		Price:                price,
+		MaxPrize:             maxPrize,
+		GameType:             req.GameType,
+		CoverImage:           req.CoverImage,
+		RulesConfig:          rulesConfigJSON,
+		Status:               model.LotteryTypeStatusAvailable,
+		LowStockThreshold:    req.LowStockThreshold,
+		WaitingRoomThreshold: req.WaitingRoomThreshold,
+		PrizeLevelVersion:    1,
+	}
+
+	var prizePool model.PrizePool
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&lotteryType).Error; err != nil {
+			return err
+		}
+		pool, err := openTemplatePool(tx, &lotteryType, levels, totalTickets, template.ReturnRate)
+		if err != nil {
+			return err
+		}
+		prizePool = *pool
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	detail, err := s.lotteryService.GetLotteryTypeByID(lotteryType.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &TemplateInstanceResponse{LotteryType: detail, PrizePool: s.lotteryService.toPrizePoolResponse(&prizePool)}, nil
+}
+
+// CreatePrizePool replaces the prize levels of a lottery type with the scaled
+// levels of a template and opens a new prize pool. The scaled levels are checked
+// against the price of the lottery type, and start a new version of its prize
+// levels, which is refused while a pool that already sold tickets is active.
+func (s *PrizeTemplateService) CreatePrizePool(templateID uint, req CreatePoolFromTemplateRequest) (*TemplateInstanceResponse, error) {
+	template, err := s.template(templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	var lotteryType model.LotteryType
+	if err := s.db.First(&lotteryType, req.LotteryTypeID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLotteryTypeNotFound
+		}
+		return nil, err
+	}
+
+	totalTickets, levels, err := scaleTemplate(template, req.InstantiateTemplateRequest, lotteryType.Price)
+	if err != nil {
+		return nil, err
+	}
+
+	var prizePool model.PrizePool
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := startPrizeLevelVersion(tx, &lotteryType); err != nil {
+			return err
+		}
+		pool, err := openTemplatePool(tx, &lotteryType, levels, totalTickets, template.ReturnRate)
+		if err != nil {
+			return err
+		}
+		prizePool = *pool
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	detail, err := s.lotteryService.GetLotteryTypeByID(lotteryType.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &TemplateInstanceResponse{LotteryType: detail, PrizePool: s.lotteryService.toPrizePoolResponse(&prizePool)}, nil
+}
+
+// template loads a prize template by ID
+func (s *PrizeTemplateService) template(id uint) (*model.PrizeTemplate, error) {
+	var template model.PrizeTemplate
+	if err := s.db.First(&template, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPrizeTemplateNotFound
+		}
+		return nil, err
+	}
+	return &template, nil
+}
+
+// openTemplatePool creates the prize levels of the current version of a lottery
+// type and an active prize pool drawing from them
+func openTemplatePool(tx *gorm.DB, lotteryType *model.LotteryType, levels []PrizeLevelInput, totalTickets int, returnRate float64) (*model.PrizePool, error) {
+	for _, level := range levels {
+		if err := createPrizeLevel(tx, lotteryType.ID, lotteryType.PrizeLevelVersion, level); err != nil {
+			return nil, err
+		}
+	}
+
+	prizePool := model.PrizePool{
+		LotteryTypeID:     lotteryType.ID,
+		TotalTickets:      totalTickets,
+		ReturnRate:        returnRate,
+		Status:            model.PrizePoolStatusActive,
+		PrizeLevelVersion: lotteryType.PrizeLevelVersion,
+	}
+	if err := tx.Create(&prizePool).Error; err != nil {
+		return nil, err
+	}
+	return &prizePool, nil
+}
+
+// applyTemplateRequest validates a template request and copies it into the template
+func applyTemplateRequest(template *model.PrizeTemplate, req PrizeTemplateRequest) error {
+	if req.Name == "" || req.TicketPrice <= 0 || req.BaseTickets <= 0 || len(req.Levels) == 0 {
+		return ErrInvalidPrizeTemplate
+	}
+	if req.ReturnRate <= 0 || req.ReturnRate > 1 {
+		return ErrInvalidPrizeTemplate
+	}
+
+	levels := append([]PrizeLevelInput(nil), req.Levels...)
+	sort.Slice(levels, func(i, j int) bool { return levels[i].Level < levels[j].Level })
+	for i, level := range levels {
+		if level.Name == "" || level.PrizeAmount < 0 || level.Quantity <= 0 {
+			return ErrInvalidPrizeTemplate
+		}
+		if i > 0 && levels[i-1].Level == level.Level {
+			return ErrInvalidPrizeTemplate
+		}
+		if err := level.validateDisplay(); err != nil {
+			return err
+		}
+	}
+	if err := checkTemplateLevels(levels, req.BaseTickets, req.TicketPrice, req.ReturnRate); err != nil {
+		return err
+	}
+
+	levelsJSON, err := json.Marshal(levels)
+	if err != nil {
+		return err
+	}
+
+	template.Name = req.Name
+	template.Description = req.Description
+	template.TicketPrice = req.TicketPrice
+	template.BaseTickets = req.BaseTickets
+	template.ReturnRate = req.ReturnRate
+	template.Levels = string(levelsJSON)
+	return nil
+}
+
+// scaleTemplate scales the quantities of a template to a pool size and checks
+// the result against the template return rate at the given ticket price.
+// Every level keeps at least one prize.
+func scaleTemplate(template *model.PrizeTemplate, req InstantiateTemplateRequest, price int) (int, []PrizeLevelInput, error) {
+	levels, err := templateLevels(template)
+	if err != nil {
+		return 0, nil, err
+	}
+	if price <= 0 {
+		return 0, nil, ErrInvalidTemplateScale
+	}
+
+	totalTickets := template.BaseTickets
+	switch {
+	case req.TotalTickets > 0:
+		totalTickets = req.TotalTickets
+	case req.Scale > 0:
+		totalTickets = int(math.Round(float64(template.BaseTickets) * req.Scale))
+	}
+	if totalTickets <= 0 {
+		return 0, nil, ErrInvalidTemplateScale
+	}
+
+	factor := float64(totalTickets) / float64(template.BaseTickets)
+	for i := range levels {
+		quantity := int(math.Round(float64(levels[i].Quantity) * factor))
+		if quantity < 1 {
+			quantity = 1
+		}
+		levels[i].Quantity = quantity
+	}
+
+	if err := checkTemplateLevels(levels, totalTickets, price, template.ReturnRate); err != nil {
+		return 0, nil, err
+	}
+	return totalTickets, levels, nil
+}
+
+// checkTemplateLevels checks that levels fit in a pool and pay out no more
+// than the return rate of its ticket sales
+func checkTemplateLevels(levels []PrizeLevelInput, totalTickets, price int, returnRate float64) error {
+	winners, prizeValue := templateTotals(levels)
+	if winners > totalTickets {
+		return ErrTemplateTooManyWinners
+	}
+	if float64(prizeValue)/(float64(totalTickets)*float64(price)) > returnRate+returnRateTolerance {
+		return ErrTemplateReturnRate
+	}
+	return nil
+}
+
+// templateTotals returns the number of winning tickets and the total prize value of levels
+func templateTotals(levels []PrizeLevelInput) (int, int64) {
+	var winners int
+	var prizeValue int64
+	for _, level := range levels {
+		winners += level.Quantity
+		prizeValue += int64(level.Quantity) * int64(level.PrizeAmount)
+	}
+	return winners, prizeValue
+}
+
+// templateLevels decodes the stored levels of a template
+func templateLevels(template *model.PrizeTemplate) ([]PrizeLevelInput, error) {
+	var levels []PrizeLevelInput
+	if err := json.Unmarshal([]byte(template.Levels), &levels); err != nil {
+		return nil, err
+	}
+	return levels, nil
+}
+
+func toPrizeTemplateResponse(template *model.PrizeTemplate) (*PrizeTemplateResponse, error) {
+	levels, err := templateLevels(template)
+	if err != nil {
+		return nil, err
+	}
+	winners, prizeValue := templateTotals(levels)
+	return &PrizeTemplateResponse{
+		ID:                 template.ID,
+		Name:               template.Name,
+		Description:        template.Description,
+		TicketPrice:        template.TicketPrice,
+		BaseTickets:        template.BaseTickets,
+		ReturnRate:         template.ReturnRate,
+		ExpectedReturnRate: float64(prizeValue) / (float64(template.BaseTickets) * float64(template.TicketPrice)),
+		TotalWinners:       winners,
+		Levels:             levels,
+		CreatedAt:          template.CreatedAt,
+		UpdatedAt:          template.UpdatedAt,
+	}, nil
+}
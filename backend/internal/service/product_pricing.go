@@ -0,0 +1,190 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/pkg/money"
+
+	"gorm.io/gorm"
+)
+
+// ConfigKeyProductPricing holds the product pricing settings as JSON
+const ConfigKeyProductPricing = "product_pricing"
+
+const (
+	defaultTargetMarginPercent = 20
+	maxTargetMarginPercent     = 95
+	maxProductCost             = 1_000_000_000 // Fen, 10 million yuan
+)
+
+var (
+	ErrInvalidProductCost  = errors.New("invalid product cost")
+	ErrInvalidTargetMargin = errors.New("invalid target margin")
+)
+
+// pointsValueRate converts points back to yuan at the recharge rate
+var pointsValueRate = money.Rate{Num: rechargePointsRate.Den, Den: rechargePointsRate.Num}
+
+// PricingConfig holds the product pricing settings of a tenant
+type PricingConfig struct {
+	TargetMarginPercent int `json:"target_margin_percent"` // Share of a product's price kept after its cost
+}
+
+// PriceSuggestion is a point price suggested for a product cost
+type PriceSuggestion struct {
+	Cost                string `json:"cost"`            // Yuan
+	PointsPerYuan       string `json:"points_per_yuan"` // Recharge rate
+	TargetMarginPercent int    `json:"target_margin_percent"`
+	BreakEvenPrice      int    `json:"break_even_price"` // Lowest price that covers the cost
+	SuggestedPrice      int    `json:"suggested_price"`  // Lowest price that keeps the target margin
+	SuggestedValue      string `json:"suggested_value"`  // Yuan the suggested price is worth
+}
+
+// PricingService suggests point prices for exchange products. Points are
+// valued at the recharge rate, so a product's price covers its cost when the
+// yuan its points were bought for do.
+type PricingService struct {
+	adminService *AdminService
+}
+
+// NewPricingService creates a new pricing service
+func NewPricingService(adminService *AdminService) *PricingService {
+	return &PricingService{adminService: adminService}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *PricingService) ForTenant(tenantID uint) *PricingService {
+	return &PricingService{adminService: s.adminService.ForTenant(tenantID)}
+}
+
+// GetConfig returns the pricing settings, with the default target margin
+// when none is set
+func (s *PricingService) GetConfig() (*PricingConfig, error) {
+	config := &PricingConfig{TargetMarginPercent: defaultTargetMarginPercent}
+	value, err := s.adminService.GetConfigValue(ConfigKeyProductPricing)
+	if err != nil && !errors.Is(err, ErrConfigNotFound) {
+		return nil, err
+	}
+	if value != "" {
+		if err := json.Unmarshal([]byte(value), config); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+// UpdateConfig validates and replaces the pricing settings
+func (s *PricingService) UpdateConfig(adminID uint, req PricingConfig) (*PricingConfig, error) {
+	if req.TargetMarginPercent < 0 || req.TargetMarginPercent > maxTargetMarginPercent {
+		return nil, ErrInvalidTargetMargin
+	}
+
+	configJSON, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	err = s.adminService.configs().Transaction(func(tx *gorm.DB) error {
+		if err := s.adminService.upsertConfig(tx, ConfigKeyProductPricing, string(configJSON)); err != nil {
+			return err
+		}
+		adminLog := model.AdminLog{
+			AdminID:    adminID,
+			Action:     "update_pricing",
+			TargetType: "system",
+			TargetID:   0,
+			Details:    string(configJSON),
+		}
+		return tx.Create(&adminLog).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// SuggestPrice suggests a point price for a cost in yuan, such as "12.50".
+// Without a margin the configured target margin is used. Prices are rounded
+// up, so the suggested price never falls short of the margin.
+func (s *PricingService) SuggestPrice(cost string, marginPercent *int) (*PriceSuggestion, error) {
+	amount, err := parseProductCost(cost)
+	if err != nil {
+		return nil, err
+	}
+	margin := 0
+	if marginPercent != nil {
+		margin = *marginPercent
+	} else {
+		config, err := s.GetConfig()
+		if err != nil {
+			return nil, err
+		}
+		margin = config.TargetMarginPercent
+	}
+	if margin < 0 || margin > maxTargetMarginPercent {
+		return nil, ErrInvalidTargetMargin
+	}
+
+	breakEven, err := amount.Convert(money.Points, rechargePointsRate, money.RoundUp)
+	if err != nil {
+		return nil, ErrInvalidProductCost
+	}
+	// The price keeps margin% of its value: value * (100 - margin) / 100 >= cost
+	target, err := amount.Scale(100, int64(100-margin), money.RoundUp)
+	if err != nil {
+		return nil, ErrInvalidProductCost
+	}
+	suggested, err := target.Convert(money.Points, rechargePointsRate, money.RoundUp)
+	if err != nil {
+		return nil, ErrInvalidProductCost
+	}
+	value, err := suggested.Convert(money.CNY, pointsValueRate, money.RoundDown)
+	if err != nil {
+		return nil, ErrInvalidProductCost
+	}
+
+	return &PriceSuggestion{
+		Cost:                amount.String(),
+		PointsPerYuan:       strconv.FormatInt(rechargePointsRate.Num, 10) + "/" + strconv.FormatInt(rechargePointsRate.Den, 10),
+		TargetMarginPercent: margin,
+		BreakEvenPrice:      int(breakEven.Amount),
+		SuggestedPrice:      int(suggested.Amount),
+		SuggestedValue:      value.String(),
+	}, nil
+}
+
+// parseProductCost reads a non-negative cost in yuan, such as "12.50"
+func parseProductCost(cost string) (money.Money, error) {
+	amount, err := money.Parse(cost, money.CNY)
+	if err != nil || amount.Amount < 0 || amount.Amount > maxProductCost {
+		return money.Money{}, ErrInvalidProductCost
+	}
+	return amount, nil
+}
+
+// pricingWarning explains why a price loses money on a product's cost, or
+// returns "" when the price covers it or the cost is unknown
+func pricingWarning(price, cost int) string {
+	if cost <= 0 {
+		return ""
+	}
+	value, err := money.New(int64(price), money.Points).Convert(money.CNY, pointsValueRate, money.RoundDown)
+	if err != nil || value.Amount >= int64(cost) {
+		return ""
+	}
+	return fmt.Sprintf("售价 %d 积分按充值比例约合 %s 元，低于成本 %s 元，毛利为负", price, value.String(), money.Format(int64(cost), money.CNY))
+}
+
+// toAdminProductResponse adds the cost and pricing warning of a product for
+// admins
+func (s *ExchangeService) toAdminProductResponse(product *model.Product) *ProductResponse {
+	resp := s.toProductResponse(product)
+	if product.Cost > 0 {
+		resp.Cost = money.Format(int64(product.Cost), money.CNY)
+	}
+	resp.PricingWarning = pricingWarning(product.Price, product.Cost)
+	return resp
+}
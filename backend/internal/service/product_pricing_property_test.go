@@ -0,0 +1,131 @@
+package service
+
+import (
+	"testing"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/pkg/money"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+)
+
+// Price suggestions: the suggested price is the lowest whose recharge value
+// keeps the target margin of the cost, and the break-even price the lowest
+// that covers it. Neither draws a pricing warning; one point less than the
+// break-even price does.
+func TestPriceSuggestionKeepsTheMargin(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	db := setupExchangeTestDB(t)
+	if err := db.AutoMigrate(&model.AdminLog{}, &model.SystemConfig{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	pricing := NewPricingService(NewAdminService(db, NewWalletService(db)))
+
+	// value returns the fen a price in points is worth at the recharge rate
+	value := func(price int) int64 {
+		worth, _ := money.New(int64(price), money.Points).Convert(money.CNY, pointsValueRate, money.RoundDown)
+		return worth.Amount
+	}
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("suggested price is the lowest keeping the margin", prop.ForAll(
+		func(cost int64, margin int) bool {
+			suggestion, err := pricing.SuggestPrice(money.Format(cost, money.CNY), &margin)
+			if err != nil {
+				t.Logf("SuggestPrice failed: %v", err)
+				return false
+			}
+			keeps := func(price int) bool {
+				return value(price)*int64(100-margin) >= cost*100
+			}
+			if !keeps(suggestion.SuggestedPrice) || (suggestion.SuggestedPrice > 0 && keeps(suggestion.SuggestedPrice-1)) {
+				t.Logf("Cost %d at %d%%: suggested %d", cost, margin, suggestion.SuggestedPrice)
+				return false
+			}
+			breakEven := suggestion.BreakEvenPrice
+			if value(breakEven) < cost || (breakEven > 0 && value(breakEven-1) >= cost) || breakEven > suggestion.SuggestedPrice {
+				t.Logf("Cost %d: break-even %d", cost, breakEven)
+				return false
+			}
+			if pricingWarning(suggestion.SuggestedPrice, int(cost)) != "" || pricingWarning(breakEven, int(cost)) != "" {
+				t.Logf("Cost %d: unexpected warning for %d or %d", cost, suggestion.SuggestedPrice, breakEven)
+				return false
+			}
+			return breakEven == 0 || pricingWarning(breakEven-1, int(cost)) != ""
+		},
+		gen.Int64Range(0, 10_000_000),
+		gen.IntRange(0, maxTargetMarginPercent),
+	))
+
+	properties.TestingRun(t)
+}
+
+// The configured margin applies when none is given, and invalid costs and
+// margins are refused
+func TestPriceSuggestionSettings(t *testing.T) {
+	db := setupExchangeTestDB(t)
+	if err := db.AutoMigrate(&model.AdminLog{}, &model.SystemConfig{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	pricing := NewPricingService(NewAdminService(db, NewWalletService(db)))
+
+	if suggestion, err := pricing.SuggestPrice("12.50", nil); err != nil || suggestion.TargetMarginPercent != defaultTargetMarginPercent || suggestion.SuggestedPrice != 157 {
+		t.Errorf("Expected 157 points at the default margin, got %+v (err %v)", suggestion, err)
+	}
+	if _, err := pricing.UpdateConfig(1, PricingConfig{TargetMarginPercent: 96}); err != ErrInvalidTargetMargin {
+		t.Errorf("Expected ErrInvalidTargetMargin, got %v", err)
+	}
+	if _, err := pricing.UpdateConfig(1, PricingConfig{TargetMarginPercent: 50}); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	if suggestion, err := pricing.SuggestPrice("12.50", nil); err != nil || suggestion.SuggestedPrice != 250 || suggestion.BreakEvenPrice != 125 {
+		t.Errorf("Expected 250 points at a 50%% margin, got %+v (err %v)", suggestion, err)
+	}
+	for _, cost := range []string{"", "-1", "1.005", "abc", "10000000.01"} {
+		if _, err := pricing.SuggestPrice(cost, nil); err != ErrInvalidProductCost {
+			t.Errorf("Expected ErrInvalidProductCost for %q, got %v", cost, err)
+		}
+	}
+}
+
+// Products priced below their cost are created and updated with a warning
+// for admins, and the cost never reaches users
+func TestProductPricingWarning(t *testing.T) {
+	db := setupExchangeTestDB(t)
+	exchangeService := NewExchangeService(db, NewWalletService(db))
+
+	product, err := exchangeService.CreateProduct(CreateProductRequest{Name: "Gift card", Price: 99, Cost: "10.00"})
+	if err != nil || product.Cost != "10.00" || product.PricingWarning == "" {
+		t.Fatalf("Expected a pricing warning, got %+v (err %v)", product, err)
+	}
+	price := 100
+	if product, err = exchangeService.UpdateProduct(product.ID, UpdateProductRequest{Price: &price}); err != nil || product.PricingWarning != "" {
+		t.Errorf("Expected no warning at break-even, got %+v (err %v)", product, err)
+	}
+	cost := "abc"
+	if _, err := exchangeService.UpdateProduct(product.ID, UpdateProductRequest{Cost: &cost}); err != ErrInvalidProductCost {
+		t.Errorf("Expected ErrInvalidProductCost, got %v", err)
+	}
+	cost = ""
+	if product, err = exchangeService.UpdateProduct(product.ID, UpdateProductRequest{Cost: &cost}); err != nil || product.Cost != "" {
+		t.Errorf("Expected the cost cleared, got %+v (err %v)", product, err)
+	}
+
+	if _, err := exchangeService.CreateProduct(CreateProductRequest{Name: "Cheap", Price: 1, Cost: "5"}); err != nil {
+		t.Fatalf("CreateProduct failed: %v", err)
+	}
+	admin, err := exchangeService.GetAllProducts(ProductQuery{})
+	if err != nil || len(admin.Products) != 2 || admin.Products[0].PricingWarning == "" {
+		t.Errorf("Expected the admin list to warn, got %+v (err %v)", admin, err)
+	}
+	user, err := exchangeService.GetProducts(ProductQuery{})
+	if err != nil || user.Products[0].Cost != "" || user.Products[0].PricingWarning != "" {
+		t.Errorf("Expected no cost for users, got %+v (err %v)", user, err)
+	}
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/pkg/logger"
+
+	"gorm.io/gorm/clause"
+)
+
+// maxPurchaseRequestIDLength bounds client supplied purchase request IDs
+const maxPurchaseRequestIDLength = 64
+
+var (
+	ErrInvalidRequestID   = errors.New("invalid purchase request id")
+	ErrRequestIDReused    = errors.New("purchase request id already used for a different purchase")
+	ErrPurchaseInProgress = errors.New("purchase with this request id is in progress")
+)
+
+// purchaseFingerprint identifies what a purchase request buys, so a request
+// ID cannot be replayed for a different purchase
+func purchaseFingerprint(req PurchaseRequest) string {
+	return fmt.Sprintf("%d:%d:%d", req.LotteryTypeID, req.Quantity, req.CouponID)
+}
+
+// ReplayPurchase returns the stored response of a completed purchase with
+// the request ID of req, or nil when no purchase used the ID
+func (s *PurchaseService) ReplayPurchase(userID uint, req PurchaseRequest) (*PurchaseResponse, error) {
+	if req.RequestID == "" {
+		return nil, nil
+	}
+	var resp PurchaseResponse
+	found, err := s.replayRequest(userID, req.RequestID, purchaseFingerprint(req), &resp)
+	if err != nil || !found {
+		return nil, err
+	}
+	resp.Replayed = true
+	return &resp, nil
+}
+
+// replayRequest loads the stored response of a completed request with the
+// request ID into resp. It reports false when no request used the ID.
+func (s *PurchaseService) replayRequest(userID uint, requestID, fingerprint string, resp any) (bool, error) {
+	if len(requestID) > maxPurchaseRequestIDLength {
+		return false, ErrInvalidRequestID
+	}
+
+	var existing model.PurchaseRequestRecord
+	if err := s.db.Where("user_id = ? AND request_id = ?", userID, requestID).
+		Limit(1).Find(&existing).Error; err != nil {
+		return false, err
+	}
+	if existing.ID == 0 {
+		return false, nil
+	}
+	if existing.Fingerprint != fingerprint {
+		return false, ErrRequestIDReused
+	}
+	if !existing.Completed {
+		return false, ErrPurchaseInProgress
+	}
+
+	if err := json.Unmarshal([]byte(existing.Response), resp); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// beginPurchaseRequest claims the request ID of a purchase. It returns the
+// stored response when a purchase with the ID has completed, and nil when
+// the caller should make the purchase.
+func (s *PurchaseService) beginPurchaseRequest(userID uint, req PurchaseRequest) (*PurchaseResponse, error) {
+	var resp PurchaseResponse
+	replayed, err := s.claimRequest(userID, req.RequestID, purchaseFingerprint(req), &resp)
+	if err != nil || !replayed {
+		return nil, err
+	}
+	resp.Replayed = true
+	return &resp, nil
+}
+
+// claimRequest claims a request ID. It loads the stored response into resp
+// and reports true when a request with the ID has completed, and reports
+// false when the caller should make the request.
+func (s *PurchaseService) claimRequest(userID uint, requestID, fingerprint string, resp any) (bool, error) {
+	if len(requestID) > maxPurchaseRequestIDLength {
+		return false, ErrInvalidRequestID
+	}
+
+	record := model.PurchaseRequestRecord{
+		UserID:      userID,
+		RequestID:   requestID,
+		Fingerprint: fingerprint,
+	}
+	result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return false, nil
+	}
+	found, err := s.replayRequest(userID, requestID, fingerprint, resp)
+	if err == nil && !found {
+		// The claim was released by a failed attempt in between
+		return false, ErrPurchaseInProgress
+	}
+	return found, err
+}
+
+// completePurchaseRequest stores the response of a purchase for replays
+func (s *PurchaseService) completePurchaseRequest(userID uint, requestID string, resp any) {
+	responseJSON, err := json.Marshal(resp)
+	if err == nil {
+		err = s.db.Model(&model.PurchaseRequestRecord{}).
+			Where("user_id = ? AND request_id = ?", userID, requestID).
+			Updates(map[string]interface{}{"completed": true, "response": string(responseJSON)}).Error
+	}
+	if err != nil {
+		logger.FromContext(s.db.Statement.Context).Warn("Storing the response of purchase request %q of user %d failed: %v", requestID, userID, err)
+	}
+}
+
+// abandonPurchaseRequest releases the request ID of a failed purchase, so the
+// request can be retried
+func (s *PurchaseService) abandonPurchaseRequest(userID uint, requestID string) {
+	if err := s.db.Where("user_id = ? AND request_id = ? AND completed = ?", userID, requestID, false).
+		Delete(&model.PurchaseRequestRecord{}).Error; err != nil {
+		logger.FromContext(s.db.Statement.Context).Warn("Releasing purchase request %q of user %d failed: %v", requestID, userID, err)
+	}
+}
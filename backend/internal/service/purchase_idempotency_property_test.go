@@ -0,0 +1,107 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+)
+
+// Idempotent purchases: repeating a purchase with the same request ID buys
+// once and returns the original tickets every time.
+func TestPurchaseIdempotency(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("repeated requests buy once", prop.ForAll(
+		func(quantity, repeats int) bool {
+			db, purchases, _, lotteryTypeID, userIDs := setupStockReservationTest(t, 20, 1)
+			if err := db.AutoMigrate(&model.PurchaseRequestRecord{}); err != nil {
+				t.Fatalf("Failed to migrate: %v", err)
+			}
+			userID := userIDs[0]
+			req := PurchaseRequest{LotteryTypeID: lotteryTypeID, Quantity: quantity, RequestID: fmt.Sprintf("req-%d", quantity)}
+
+			first, err := purchases.PurchaseTickets(userID, req)
+			if err != nil {
+				t.Logf("PurchaseTickets failed: %v", err)
+				return false
+			}
+			for i := 0; i < repeats; i++ {
+				again, err := purchases.PurchaseTickets(userID, req)
+				if err != nil {
+					t.Logf("Repeated PurchaseTickets failed: %v", err)
+					return false
+				}
+				if !again.Replayed || len(again.Tickets) != len(first.Tickets) || again.Balance != first.Balance {
+					t.Logf("Expected the original response, got %+v", again)
+					return false
+				}
+				for j := range first.Tickets {
+					if again.Tickets[j].ID != first.Tickets[j].ID {
+						t.Logf("Ticket %d differs: %d, expected %d", j, again.Tickets[j].ID, first.Tickets[j].ID)
+						return false
+					}
+				}
+			}
+
+			var tickets int64
+			db.Model(&model.Ticket{}).Where("user_id = ?", userID).Count(&tickets)
+			balance, _ := purchases.walletService.GetBalance(userID)
+			if tickets != int64(quantity) || balance != first.Balance {
+				t.Logf("Expected %d tickets and balance %d, got %d and %d", quantity, first.Balance, tickets, balance)
+				return false
+			}
+			return !first.Replayed
+		},
+		gen.IntRange(1, 5),
+		gen.IntRange(1, 4),
+	))
+
+	properties.TestingRun(t)
+}
+
+// Idempotent purchases: a request ID cannot be reused for another purchase,
+// and a failed purchase releases its ID for the retry.
+func TestPurchaseRequestIDReuse(t *testing.T) {
+	db, purchases, _, lotteryTypeID, userIDs := setupStockReservationTest(t, 20, 2)
+	if err := db.AutoMigrate(&model.PurchaseRequestRecord{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	req := PurchaseRequest{LotteryTypeID: lotteryTypeID, Quantity: 1, RequestID: "retry-me"}
+
+	if _, err := purchases.PurchaseTickets(userIDs[0], req); err != nil {
+		t.Fatalf("PurchaseTickets failed: %v", err)
+	}
+	other := req
+	other.Quantity = 2
+	if _, err := purchases.PurchaseTickets(userIDs[0], other); err != ErrRequestIDReused {
+		t.Errorf("Expected the request ID to be refused for another purchase, got %v", err)
+	}
+	if resp, err := purchases.PurchaseTickets(userIDs[1], req); err != nil || resp.Replayed {
+		t.Errorf("Expected request IDs to be per user, got %+v (err %v)", resp, err)
+	}
+
+	db.Model(&model.Wallet{}).Where("user_id = ?", userIDs[1]).Update("balance", 0)
+	broke := PurchaseRequest{LotteryTypeID: lotteryTypeID, Quantity: 1, RequestID: "no-money"}
+	if _, err := purchases.PurchaseTickets(userIDs[1], broke); err != ErrInsufficientBalance {
+		t.Fatalf("Expected insufficient balance, got %v", err)
+	}
+	db.Model(&model.Wallet{}).Where("user_id = ?", userIDs[1]).Update("balance", 1000)
+	if resp, err := purchases.PurchaseTickets(userIDs[1], broke); err != nil || resp.Replayed {
+		t.Errorf("Expected the retry of a failed purchase to buy, got %+v (err %v)", resp, err)
+	}
+
+	long := req
+	long.RequestID = fmt.Sprintf("%065d", 0)
+	if _, err := purchases.PurchaseTickets(userIDs[0], long); err != ErrInvalidRequestID {
+		t.Errorf("Expected overlong request IDs to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+)
+
+// Purchase summary: the per-day and per-game breakdowns both add up to the
+// totals, every day of the range is present, and unrevealed prizes are hidden.
+func TestPurchaseSummaryAddsUp(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("breakdowns sum to totals", prop.ForAll(
+		func(days []int, days2 int) bool {
+			db := setupLotteryTestDB(t)
+			userService := NewUserService(db, NewWalletService(db), nil)
+
+			user := model.User{LinuxdoID: "summary_user", Username: "Test", Role: "user"}
+			db.Create(&user)
+			gameA := model.LotteryType{Name: "A", Price: 10, MaxPrize: 100, Status: model.LotteryTypeStatusAvailable}
+			gameB := model.LotteryType{Name: "B", Price: 25, MaxPrize: 100, Status: model.LotteryTypeStatusAvailable}
+			db.Create(&gameA)
+			db.Create(&gameB)
+
+			today := time.Now()
+			wantSpent, wantWinnings := 0, 0
+			for i, ago := range days {
+				game := gameA
+				status := model.TicketStatusScratched
+				if i%2 == 1 {
+					game = gameB
+					status = model.TicketStatusUnscratched
+				}
+				ticket := model.Ticket{
+					UserID:        user.ID,
+					LotteryTypeID: game.ID,
+					SecurityCode:  fmt.Sprintf("SUMMARY%04d", i),
+					PrizeAmount:   7,
+					Status:        status,
+					PurchasedAt:   today.AddDate(0, 0, -ago),
+				}
+				if err := db.Create(&ticket).Error; err != nil {
+					t.Logf("Failed to create ticket: %v", err)
+					return false
+				}
+				if ago < days2 {
+					wantSpent += game.Price
+					if status == model.TicketStatusScratched {
+						wantWinnings += 7
+					}
+				}
+			}
+
+			summary, err := userService.GetPurchaseSummary(user.ID, PurchaseSummaryQuery{
+				StartDate: today.AddDate(0, 0, -(days2 - 1)).Format("2006-01-02"),
+				EndDate:   today.Format("2006-01-02"),
+			})
+			if err != nil {
+				t.Logf("GetPurchaseSummary failed: %v", err)
+				return false
+			}
+
+			if len(summary.ByDay) != days2 {
+				t.Logf("Expected %d days, got %d", days2, len(summary.ByDay))
+				return false
+			}
+			if summary.Totals.Spent != wantSpent || summary.Totals.Winnings != wantWinnings {
+				t.Logf("Totals %+v, want spent %d winnings %d", summary.Totals, wantSpent, wantWinnings)
+				return false
+			}
+
+			var dayTotal, gameTotal PurchaseSummaryTotals
+			for _, d := range summary.ByDay {
+				addSummaryTotals(&dayTotal, d.PurchaseSummaryTotals)
+			}
+			for _, g := range summary.ByLotteryType {
+				addSummaryTotals(&gameTotal, g.PurchaseSummaryTotals)
+			}
+			return dayTotal == summary.Totals && gameTotal == summary.Totals
+		},
+		gen.SliceOf(gen.IntRange(0, 40)),
+		gen.IntRange(1, 30),
+	))
+
+	properties.TestingRun(t)
+}
+
+func addSummaryTotals(dst *PurchaseSummaryTotals, src PurchaseSummaryTotals) {
+	dst.Tickets += src.Tickets
+	dst.Spent += src.Spent
+	dst.Winnings += src.Winnings
+	dst.Net = dst.Winnings - dst.Spent
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+	"gorm.io/gorm"
+)
+
+// Atomic purchases: when generating any ticket of a purchase fails, the
+// whole purchase is rolled back - balance, coupon, tickets, prize pool
+// counters and wallet transactions are as before.
+func TestPurchaseRollsBackOnFailure(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	db, purchases, _, lotteryTypeID, userIDs := setupStockReservationTest(t, 1000, 1)
+	if err := db.AutoMigrate(&model.Coupon{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	userID := userIDs[0]
+	db.Model(&model.Wallet{}).Where("user_id = ?", userID).Update("balance", 100000)
+
+	// Fail the creation of the ticket numbered failAt within a purchase
+	failAt, created := 0, 0
+	errInjected := errors.New("injected ticket failure")
+	if err := db.Callback().Create().Before("gorm:create").Register("test:fail_ticket", func(tx *gorm.DB) {
+		if tx.Statement.Schema == nil || tx.Statement.Schema.Table != "tickets" || failAt == 0 {
+			return
+		}
+		created++
+		if created == failAt {
+			_ = tx.AddError(errInjected)
+		}
+	}); err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+
+	type state struct {
+		balance, tickets, transactions, sold, histories int64
+		couponUsed                                      bool
+	}
+	snapshot := func(couponID uint) state {
+		var st state
+		var wallet model.Wallet
+		db.Where("user_id = ?", userID).First(&wallet)
+		st.balance = int64(wallet.Balance)
+		db.Model(&model.Ticket{}).Count(&st.tickets)
+		db.Model(&model.Transaction{}).Count(&st.transactions)
+		db.Model(&model.TicketHistory{}).Count(&st.histories)
+		var pool model.PrizePool
+		db.Where("lottery_type_id = ?", lotteryTypeID).First(&pool)
+		st.sold = int64(pool.SoldTickets)
+		var coupon model.Coupon
+		db.First(&coupon, couponID)
+		st.couponUsed = coupon.UsedAt != nil
+		return st
+	}
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("failed purchases leave no trace", prop.ForAll(
+		func(quantity, fail int, withCoupon bool) bool {
+			fail = fail%quantity + 1
+			var couponID uint
+			if withCoupon {
+				coupon := model.Coupon{UserID: userID, Discount: 5}
+				db.Create(&coupon)
+				couponID = coupon.ID
+			}
+
+			before := snapshot(couponID)
+			failAt, created = fail, 0
+			_, err := purchases.PurchaseTickets(userID, PurchaseRequest{LotteryTypeID: lotteryTypeID, Quantity: quantity, CouponID: couponID})
+			failAt = 0
+			if !errors.Is(err, errInjected) {
+				t.Logf("Expected the injected failure, got %v", err)
+				return false
+			}
+			if after := snapshot(couponID); after != before {
+				t.Logf("Failed purchase changed state: %+v -> %+v", before, after)
+				return false
+			}
+
+			// The same purchase goes through once nothing fails
+			resp, err := purchases.PurchaseTickets(userID, PurchaseRequest{LotteryTypeID: lotteryTypeID, Quantity: 1, CouponID: couponID})
+			if err != nil {
+				t.Logf("Purchase after the failure failed: %v", err)
+				return false
+			}
+			after := snapshot(couponID)
+			return len(resp.Tickets) == 1 && after.sold == before.sold+1 && after.couponUsed == withCoupon &&
+				after.balance == before.balance-int64(resp.Cost)
+		},
+		gen.IntRange(1, 5),
+		gen.IntRange(0, 4),
+		gen.Bool(),
+	))
+
+	properties.TestingRun(t)
+}
+
+// Overselling: a purchase that read the pool before a concurrent buyer took
+// its last tickets is refused as sold out when it raises the sold count, and
+// rolled back
+func TestPurchaseCannotOversell(t *testing.T) {
+	db, purchases, _, lotteryTypeID, userIDs := setupStockReservationTest(t, 3, 1)
+	userID := userIDs[0]
+
+	// Sell out the pool right after the purchase created its first ticket
+	sellOut := true
+	if err := db.Callback().Create().After("gorm:create").Register("test:sell_out", func(tx *gorm.DB) {
+		if tx.Statement.Schema == nil || tx.Statement.Schema.Table != "tickets" || !sellOut {
+			return
+		}
+		sellOut = false
+		tx.Session(&gorm.Session{NewDB: true}).Exec("UPDATE prize_pools SET sold_tickets = total_tickets WHERE lottery_type_id = ?", lotteryTypeID)
+	}); err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+
+	_, err := purchases.PurchaseTickets(userID, PurchaseRequest{LotteryTypeID: lotteryTypeID, Quantity: 2})
+	if !errors.Is(err, ErrLotteryTypeSoldOut) {
+		t.Fatalf("Expected ErrLotteryTypeSoldOut, got %v", err)
+	}
+
+	var pool model.PrizePool
+	db.Where("lottery_type_id = ?", lotteryTypeID).First(&pool)
+	var tickets int64
+	db.Model(&model.Ticket{}).Count(&tickets)
+	if pool.SoldTickets != 0 || tickets != 0 {
+		t.Errorf("Expected the purchase rolled back, got %d sold and %d tickets", pool.SoldTickets, tickets)
+	}
+	if balance := walletBalance(db, userID); balance != 1000 {
+		t.Errorf("Expected the balance untouched, got %d", balance)
+	}
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"scratch-lottery/internal/model"
+
+	"gorm.io/gorm"
+)
+
+var ErrQuickPlayUnavailable = errors.New("lottery type requires confirmed or paced scratches")
+
+// QuickPlayService buys tickets and scratches them at once, in the
+// transaction of the purchase, so a failure leaves neither charge nor ticket
+type QuickPlayService struct {
+	purchases *PurchaseService
+	scratches *ScratchService
+}
+
+// NewQuickPlayService creates a new quick play service
+func NewQuickPlayService(purchases *PurchaseService, scratches *ScratchService) *QuickPlayService {
+	return &QuickPlayService{purchases: purchases, scratches: scratches}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *QuickPlayService) ForTenant(tenantID uint) *QuickPlayService {
+	return &QuickPlayService{
+		purchases: s.purchases.ForTenant(tenantID),
+		scratches: s.scratches.ForTenant(tenantID),
+	}
+}
+
+// WithRequest returns a copy of the service whose logs carry the request id
+// of ctx
+func (s *QuickPlayService) WithRequest(ctx context.Context) *QuickPlayService {
+	return &QuickPlayService{
+		purchases: s.purchases.WithRequest(ctx),
+		scratches: s.scratches.WithRequest(ctx),
+	}
+}
+
+// QuickPlayResponse represents the tickets of a quick play and their results
+type QuickPlayResponse struct {
+	Results     []ScratchResponse        `json:"results"`
+	Cost        int                      `json:"cost"`
+	Discount    int                      `json:"discount,omitempty"`
+	Wins        int                      `json:"wins"`                   // winning tickets
+	TotalPrize  int                      `json:"total_prize"`            // prizes of the winning tickets, held ones included
+	Credited    int                      `json:"credited"`               // prizes paid into the wallet
+	StreakBonus int                      `json:"streak_bonus,omitempty"` // points credited for a scratch streak
+	Balance     int                      `json:"balance"`
+	BonusSpent  int                      `json:"bonus_spent,omitempty"` // Part of the cost paid with bonus credits
+	Rewards     []CampaignRewardResponse `json:"rewards,omitempty"`     // Campaign rewards for a first purchase
+	Replayed    bool                     `json:"replayed,omitempty"`    // The response of an earlier request with the same request ID
+}
+
+// quickPlayFingerprint identifies what a quick play buys. It differs from
+// the fingerprint of the same purchase, so a request ID of a purchase cannot
+// be replayed as a quick play or the other way round.
+func quickPlayFingerprint(req PurchaseRequest) string {
+	return "quick:" + purchaseFingerprint(req)
+}
+
+// ReplayQuickPlay returns the stored response of a completed quick play with
+// the request ID of req, or nil when no request used the ID
+func (s *QuickPlayService) ReplayQuickPlay(userID uint, req PurchaseRequest) (*QuickPlayResponse, error) {
+	if req.RequestID == "" {
+		return nil, nil
+	}
+	var resp QuickPlayResponse
+	found, err := s.purchases.replayRequest(userID, req.RequestID, quickPlayFingerprint(req), &resp)
+	if err != nil || !found {
+		return nil, err
+	}
+	resp.Replayed = true
+	return &resp, nil
+}
+
+// QuickPlay buys tickets for a user and scratches them. Purchases are limited
+// as usual, and lottery types whose scratches need a confirmation or are
+// paced cannot be played at once. A quick play with a request ID is made
+// once; repeating the request returns the original response.
+func (s *QuickPlayService) QuickPlay(userID uint, req PurchaseRequest) (*QuickPlayResponse, error) {
+	if req.RequestID == "" {
+		return s.quickPlay(userID, req)
+	}
+
+	var replay QuickPlayResponse
+	replayed, err := s.purchases.claimRequest(userID, req.RequestID, quickPlayFingerprint(req), &replay)
+	if err != nil {
+		return nil, err
+	}
+	if replayed {
+		replay.Replayed = true
+		return &replay, nil
+	}
+	resp, err := s.quickPlay(userID, req)
+	if err != nil {
+		s.purchases.abandonPurchaseRequest(userID, req.RequestID)
+		return nil, err
+	}
+	s.purchases.completePurchaseRequest(userID, req.RequestID, resp)
+	return resp, nil
+}
+
+func (s *QuickPlayService) quickPlay(userID uint, req PurchaseRequest) (*QuickPlayResponse, error) {
+	var lotteryType model.LotteryType
+	if err := s.purchases.db.First(&lotteryType, req.LotteryTypeID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLotteryTypeNotFound
+		}
+		return nil, err
+	}
+	if lotteryType.ConfirmScratch || lotteryType.ScratchDelaySeconds > 0 || lotteryType.ScratchIntervalSeconds > 0 {
+		return nil, ErrQuickPlayUnavailable
+	}
+
+	policy, err := s.scratches.loadScratchPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	// Scratch the tickets in the transaction of the purchase
+	now := time.Now()
+	var plans []*scratchPlan
+	var bonuses []int
+	purchase, err := s.purchases.purchaseTickets(userID, req, func(tx *gorm.DB, tickets []*model.Ticket) error {
+		plans, bonuses = plans[:0], bonuses[:0]
+		for _, ticket := range tickets {
+			ticket.LotteryType = lotteryType
+			plan, err := s.scratches.planScratch(tx, ticket, policy)
+			if err != nil {
+				return err
+			}
+			bonus, err := s.scratches.settleScratch(tx, plan, policy, now)
+			if err != nil {
+				return err
+			}
+			plans = append(plans, plan)
+			bonuses = append(bonuses, bonus)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &QuickPlayResponse{
+		Results:    make([]ScratchResponse, 0, len(plans)),
+		Cost:       purchase.Cost,
+		Discount:   purchase.Discount,
+		Balance:    purchase.Balance,
+		BonusSpent: purchase.BonusSpent,
+		Rewards:    purchase.Rewards,
+	}
+	for i, plan := range plans {
+		resp.Results = append(resp.Results, *plan.response(purchase.Balance, bonuses[i], now))
+		resp.StreakBonus += bonuses[i]
+		if plan.ticket.PrizeAmount > 0 {
+			resp.Wins++
+			resp.TotalPrize += plan.ticket.PrizeAmount
+		}
+		if plan.credited() {
+			resp.Credited += plan.ticket.PrizeAmount
+		}
+		if s.scratches.events != nil {
+			s.scratches.events.publishScratch(s.scratches.db, plan.ticket, plan.credited() || bonuses[i] > 0, purchase.Balance)
+		}
+	}
+	completeOnboarding(s.scratches.onboarding, userID, model.OnboardingFirstScratch)
+
+	return resp, nil
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+	"gorm.io/gorm"
+)
+
+func setupQuickPlayTest(t *testing.T) (*gorm.DB, *QuickPlayService, uint, uint) {
+	db, purchases, _, lotteryTypeID, userIDs := setupStockReservationTest(t, 40, 1)
+	if err := db.AutoMigrate(&model.PurchaseRequestRecord{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	// Half of the tickets win
+	db.Create(&model.PrizeLevel{LotteryTypeID: lotteryTypeID, Level: 1, Name: "Prize", PrizeAmount: 15, Quantity: 20, Remaining: 20})
+	scratches := NewScratchService(db, purchases.lotteryService, purchases.walletService, nil, nil)
+	return db, NewQuickPlayService(purchases, scratches), lotteryTypeID, userIDs[0]
+}
+
+// Quick play: every ticket bought is scratched at once, the balance is the
+// starting balance less the cost plus the prizes credited, and repeating the
+// request with the same request ID returns the original results.
+func TestQuickPlaySettlesEveryTicket(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("bought tickets are scratched and paid", prop.ForAll(
+		func(quantity, repeats int) bool {
+			db, quickPlay, lotteryTypeID, userID := setupQuickPlayTest(t)
+			start, _ := quickPlay.purchases.walletService.GetBalance(userID)
+			req := PurchaseRequest{LotteryTypeID: lotteryTypeID, Quantity: quantity, RequestID: fmt.Sprintf("quick-%d", quantity)}
+
+			first, err := quickPlay.QuickPlay(userID, req)
+			if err != nil {
+				t.Logf("QuickPlay failed: %v", err)
+				return false
+			}
+			if len(first.Results) != quantity || first.Cost != 10*quantity {
+				t.Logf("Expected %d results costing %d, got %+v", quantity, 10*quantity, first)
+				return false
+			}
+			wins, prizes := 0, 0
+			for _, result := range first.Results {
+				if result.Status != model.TicketStatusScratched {
+					t.Logf("Ticket %d left %s", result.TicketID, result.Status)
+					return false
+				}
+				if result.IsWin {
+					wins++
+					prizes += result.PrizeAmount
+				}
+			}
+			if wins != first.Wins || prizes != first.TotalPrize || prizes != first.Credited {
+				t.Logf("Expected %d wins of %d, got %+v", wins, prizes, first)
+				return false
+			}
+			if first.Balance != start-first.Cost+first.Credited {
+				t.Logf("Balance %d, expected %d - %d + %d", first.Balance, start, first.Cost, first.Credited)
+				return false
+			}
+
+			for i := 0; i < repeats; i++ {
+				again, err := quickPlay.QuickPlay(userID, req)
+				if err != nil || !again.Replayed || again.Balance != first.Balance || len(again.Results) != quantity {
+					t.Logf("Expected the original response, got %+v (err %v)", again, err)
+					return false
+				}
+				for j := range first.Results {
+					if again.Results[j].TicketID != first.Results[j].TicketID || again.Results[j].PrizeAmount != first.Results[j].PrizeAmount {
+						t.Logf("Result %d differs: %+v, expected %+v", j, again.Results[j], first.Results[j])
+						return false
+					}
+				}
+			}
+
+			var unscratched, tickets int64
+			db.Model(&model.Ticket{}).Where("user_id = ?", userID).Count(&tickets)
+			db.Model(&model.Ticket{}).Where("user_id = ? AND status = ?", userID, model.TicketStatusUnscratched).Count(&unscratched)
+			balance, _ := quickPlay.purchases.walletService.GetBalance(userID)
+			if tickets != int64(quantity) || unscratched != 0 || balance != first.Balance {
+				t.Logf("Expected %d scratched tickets and balance %d, got %d (%d unscratched) and %d",
+					quantity, first.Balance, tickets, unscratched, balance)
+				return false
+			}
+			return true
+		},
+		gen.IntRange(1, 10),
+		gen.IntRange(0, 3),
+	))
+
+	properties.TestingRun(t)
+}
+
+// A quick play whose scratch fails buys nothing, and lottery types that need
+// confirmed or paced scratches cannot be played at once
+func TestQuickPlayRollsBackOnFailure(t *testing.T) {
+	db, quickPlay, lotteryTypeID, userID := setupQuickPlayTest(t)
+
+	// Fail recording the scratch of the third ticket
+	scratched := 0
+	if err := db.Callback().Create().Before("gorm:create").Register("test:fail_scratch", func(tx *gorm.DB) {
+		event, ok := tx.Statement.Dest.(*model.TicketEvent)
+		if !ok || event.Type != model.TicketEventScratched {
+			return
+		}
+		scratched++
+		if scratched == 3 {
+			_ = tx.AddError(errors.New("injected scratch failure"))
+		}
+	}); err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+
+	req := PurchaseRequest{LotteryTypeID: lotteryTypeID, Quantity: 5, RequestID: "quick"}
+	if _, err := quickPlay.QuickPlay(userID, req); err == nil {
+		t.Fatal("Expected the quick play to fail")
+	}
+	var tickets, transactions int64
+	db.Model(&model.Ticket{}).Count(&tickets)
+	db.Model(&model.Transaction{}).Count(&transactions)
+	balance, _ := quickPlay.purchases.walletService.GetBalance(userID)
+	if tickets != 0 || transactions != 0 || balance != 1000 {
+		t.Errorf("Expected nothing bought, got %d tickets, %d transactions and balance %d", tickets, transactions, balance)
+	}
+
+	// The request ID is released for a retry, but not shared with purchases
+	resp, err := quickPlay.QuickPlay(userID, req)
+	if err != nil || len(resp.Results) != 5 {
+		t.Fatalf("Expected the retry to succeed, got %+v (err %v)", resp, err)
+	}
+	if _, err := quickPlay.purchases.PurchaseTickets(userID, req); !errors.Is(err, ErrRequestIDReused) {
+		t.Errorf("Expected the request ID refused for a purchase, got %v", err)
+	}
+
+	db.Model(&model.LotteryType{}).Where("id = ?", lotteryTypeID).Update("confirm_scratch", true)
+	if _, err := quickPlay.QuickPlay(userID, PurchaseRequest{LotteryTypeID: lotteryTypeID, Quantity: 1}); !errors.Is(err, ErrQuickPlayUnavailable) {
+		t.Errorf("Expected confirmed scratches to rule out quick play, got %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"scratch-lottery/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// ConfigKeyRechargeRules holds the recharge amount rules as JSON
+const ConfigKeyRechargeRules = "recharge_rules"
+
+// Recharge amount limits, in yuan
+const (
+	minRechargeAmount     = 1
+	maxRechargeAmount     = 10000
+	maxRechargePresetsLen = 12
+)
+
+var (
+	ErrInvalidRechargeRules = errors.New("invalid recharge rules")
+)
+
+// RechargeRules define the amounts a user may recharge, in yuan: any of the
+// preset denominations, and when custom amounts are allowed, any multiple of
+// Step between MinAmount and MaxAmount
+type RechargeRules struct {
+	Denominations []int `json:"denominations"`
+	AllowCustom   bool  `json:"allow_custom"`
+	MinAmount     int   `json:"min_amount"`
+	MaxAmount     int   `json:"max_amount"`
+	Step          int   `json:"step"`
+}
+
+// defaultRechargeRules allow any whole amount within the hard limits
+func defaultRechargeRules() *RechargeRules {
+	return &RechargeRules{
+		Denominations: []int{},
+		AllowCustom:   true,
+		MinAmount:     minRechargeAmount,
+		MaxAmount:     maxRechargeAmount,
+		Step:          1,
+	}
+}
+
+// allows reports whether a recharge of amount yuan is valid under the rules
+func (r *RechargeRules) allows(amount int) bool {
+	for _, denomination := range r.Denominations {
+		if amount == denomination {
+			return true
+		}
+	}
+	return r.AllowCustom && amount >= r.MinAmount && amount <= r.MaxAmount && amount%r.Step == 0
+}
+
+// validate checks the rules against the hard limits and sorts the denominations
+func (r *RechargeRules) validate() error {
+	if len(r.Denominations) > maxRechargePresetsLen {
+		return ErrInvalidRechargeRules
+	}
+	sort.Ints(r.Denominations)
+	for i, denomination := range r.Denominations {
+		if denomination < minRechargeAmount || denomination > maxRechargeAmount {
+			return ErrInvalidRechargeRules
+		}
+		if i > 0 && r.Denominations[i-1] == denomination {
+			return ErrInvalidRechargeRules
+		}
+	}
+	if !r.AllowCustom {
+		if len(r.Denominations) == 0 {
+			return ErrInvalidRechargeRules
+		}
+		return nil
+	}
+	if r.MinAmount < minRechargeAmount || r.MaxAmount > maxRechargeAmount || r.MinAmount > r.MaxAmount || r.Step < 1 {
+		return ErrInvalidRechargeRules
+	}
+	// At least one custom amount must be possible
+	if (r.MinAmount+r.Step-1)/r.Step*r.Step > r.MaxAmount {
+		return ErrInvalidRechargeRules
+	}
+	return nil
+}
+
+// GetRechargeRules returns the recharge amount rules. Without configured
+// rules any whole amount from 1 to 10000 yuan is allowed.
+func (s *PaymentService) GetRechargeRules() (*RechargeRules, error) {
+	value, err := s.adminService.GetConfigValue(ConfigKeyRechargeRules)
+	if errors.Is(err, ErrConfigNotFound) || value == "" {
+		return defaultRechargeRules(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var rules RechargeRules
+	if err := json.Unmarshal([]byte(value), &rules); err != nil {
+		return nil, err
+	}
+	if rules.Denominations == nil {
+		rules.Denominations = []int{}
+	}
+	return &rules, nil
+}
+
+// UpdateRechargeRules validates and replaces the recharge amount rules
+func (s *PaymentService) UpdateRechargeRules(adminID uint, req RechargeRules) (*RechargeRules, error) {
+	rules := req
+	rules.Denominations = append([]int{}, req.Denominations...)
+	if err := rules.validate(); err != nil {
+		return nil, err
+	}
+
+	rulesJSON, err := json.Marshal(rules)
+	if err != nil {
+		return nil, err
+	}
+	err = s.adminService.configs().Transaction(func(tx *gorm.DB) error {
+		if err := s.adminService.upsertConfig(tx, ConfigKeyRechargeRules, string(rulesJSON)); err != nil {
+			return err
+		}
+		adminLog := model.AdminLog{
+			AdminID:    adminID,
+			Action:     "update_recharge_rules",
+			TargetType: "system",
+			TargetID:   0,
+			Details:    string(rulesJSON),
+		}
+		return tx.Create(&adminLog).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &rules, nil
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"testing"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+	"gorm.io/gorm"
+)
+
+func setupRechargeRulesTest(t *testing.T) (*gorm.DB, *PaymentService, uint) {
+	db := setupTenantTestDB(t)
+	if err := db.AutoMigrate(&model.PaymentOrder{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	walletService := NewWalletService(db)
+	adminService := NewAdminService(db, walletService)
+
+	enabled, merchant, secret := true, "10001", "test_secret_key"
+	if _, err := adminService.UpdateSystemSettings(1, UpdateSystemSettingsRequest{
+		PaymentEnabled: &enabled,
+		EPayMerchantID: &merchant,
+		EPaySecret:     &secret,
+	}); err != nil {
+		t.Fatalf("UpdateSystemSettings failed: %v", err)
+	}
+
+	user := model.User{LinuxdoID: "recharge_rules_user", Username: "Payer", Role: "user"}
+	db.Create(&user)
+	db.Create(&model.Wallet{UserID: user.ID})
+	return db, NewPaymentService(db, adminService, walletService, nil), user.ID
+}
+
+// Recharge rules: an order is created exactly when its amount is one of the
+// denominations, or a custom amount on the step within the range while
+// custom amounts are allowed.
+func TestRechargeRulesEnforced(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("orders follow the rules", prop.ForAll(
+		func(denominations []int, allowCustom bool, minAmount, span, step int, amounts []int) bool {
+			_, payments, userID := setupRechargeRulesTest(t)
+			rules, err := payments.UpdateRechargeRules(1, RechargeRules{
+				Denominations: denominations,
+				AllowCustom:   allowCustom,
+				MinAmount:     minAmount,
+				MaxAmount:     minAmount + span,
+				Step:          step,
+			})
+			if err == ErrInvalidRechargeRules {
+				return true
+			}
+			if err != nil {
+				t.Logf("UpdateRechargeRules failed: %v", err)
+				return false
+			}
+
+			for _, amount := range append(amounts, denominations...) {
+				expected := false
+				for _, denomination := range denominations {
+					expected = expected || amount == denomination
+				}
+				expected = expected || (allowCustom && amount >= minAmount && amount <= minAmount+span && amount%step == 0)
+
+				_, err := payments.CreateRechargeOrder(userID, RechargeRequest{Amount: amount})
+				if expected && err != nil {
+					t.Logf("Amount %d under %+v: expected an order, got %v", amount, rules, err)
+					return false
+				}
+				if !expected && err != ErrPaymentInvalidAmount {
+					t.Logf("Amount %d under %+v: expected ErrPaymentInvalidAmount, got %v", amount, rules, err)
+					return false
+				}
+			}
+			return true
+		},
+		gen.SliceOfN(3, gen.IntRange(1, 500)),
+		gen.Bool(),
+		gen.IntRange(1, 200),
+		gen.IntRange(0, 300),
+		gen.IntRange(1, 50),
+		gen.SliceOfN(5, gen.IntRange(-10, 600)),
+	))
+
+	properties.TestingRun(t)
+}
+
+// Recharge rules: without configured rules any whole amount from 1 to 10000
+// yuan is accepted, and invalid rules are refused.
+func TestRechargeRulesDefaultsAndValidation(t *testing.T) {
+	db, payments, userID := setupRechargeRulesTest(t)
+
+	rules, err := payments.GetRechargeRules()
+	if err != nil {
+		t.Fatalf("GetRechargeRules failed: %v", err)
+	}
+	if !rules.AllowCustom || rules.MinAmount != 1 || rules.MaxAmount != 10000 || rules.Step != 1 {
+		t.Errorf("Unexpected default rules %+v", rules)
+	}
+	for amount, valid := range map[int]bool{0: false, 1: true, 37: true, 10000: true, 10001: false} {
+		_, err := payments.CreateRechargeOrder(userID, RechargeRequest{Amount: amount})
+		if valid != (err == nil) {
+			t.Errorf("Amount %d: valid %v, got %v", amount, valid, err)
+		}
+	}
+
+	invalid := []RechargeRules{
+		{AllowCustom: false}, // Nothing to recharge
+		{Denominations: []int{10, 10}, AllowCustom: false},                        // Duplicate denomination
+		{Denominations: []int{0}, AllowCustom: false},                             // Below the hard limit
+		{Denominations: []int{20000}, AllowCustom: false},                         // Above the hard limit
+		{AllowCustom: true, MinAmount: 50, MaxAmount: 10, Step: 1},                // Empty range
+		{AllowCustom: true, MinAmount: 1, MaxAmount: 20000, Step: 1},              // Above the hard limit
+		{AllowCustom: true, MinAmount: 1, MaxAmount: 100, Step: 0},                // No step
+		{AllowCustom: true, MinAmount: 11, MaxAmount: 19, Step: 10},               // No multiple of the step in range
+		{Denominations: make([]int, maxRechargePresetsLen+1), AllowCustom: false}, // Too many denominations
+	}
+	for _, rules := range invalid {
+		if _, err := payments.UpdateRechargeRules(1, rules); err != ErrInvalidRechargeRules {
+			t.Errorf("Expected ErrInvalidRechargeRules for %+v, got %v", rules, err)
+		}
+	}
+
+	updated, err := payments.UpdateRechargeRules(1, RechargeRules{Denominations: []int{100, 10, 50}})
+	if err != nil {
+		t.Fatalf("UpdateRechargeRules failed: %v", err)
+	}
+	if len(updated.Denominations) != 3 || updated.Denominations[0] != 10 || updated.Denominations[2] != 100 {
+		t.Errorf("Expected sorted denominations, got %v", updated.Denominations)
+	}
+	var logs int64
+	db.Model(&model.AdminLog{}).Where("action = ?", "update_recharge_rules").Count(&logs)
+	if logs != 1 {
+		t.Errorf("Expected 1 admin log, got %d", logs)
+	}
+
+	// Rules are kept per tenant
+	other, err := payments.ForTenant(2).GetRechargeRules()
+	if err != nil {
+		t.Fatalf("GetRechargeRules failed: %v", err)
+	}
+	if len(other.Denominations) != 0 || !other.AllowCustom {
+		t.Errorf("Expected tenant 2 to keep the default rules, got %+v", other)
+	}
+}
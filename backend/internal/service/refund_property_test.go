@@ -0,0 +1,308 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+	"gorm.io/gorm"
+)
+
+// setupRefundTest creates a lottery with one buyer whose purchases record
+// their failures with a refund service
+func setupRefundTest(t *testing.T) (*gorm.DB, *PurchaseService, *RefundService, uint, uint) {
+	db, purchases, _, lotteryTypeID, userIDs := setupStockReservationTest(t, 1000, 1)
+	if err := db.AutoMigrate(&model.Refund{}, &model.AdminLog{}, &model.Coupon{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	refunds := NewRefundService(db)
+	purchases.UseRefunds(refunds)
+	return db, purchases, refunds, lotteryTypeID, userIDs[0]
+}
+
+func walletBalance(db *gorm.DB, userID uint) int {
+	var wallet model.Wallet
+	db.Where("user_id = ?", userID).First(&wallet)
+	return wallet.Balance
+}
+
+// A purchase failing part way is recorded as rolled back: its charge was
+// undone with it, so nothing is credited. Refused purchases are not failures.
+func TestFailedPurchaseIsRecorded(t *testing.T) {
+	db, purchases, _, lotteryTypeID, userID := setupRefundTest(t)
+
+	errInjected := errors.New("injected ticket failure")
+	if err := db.Callback().Create().Before("gorm:create").Register("test:fail_ticket", func(tx *gorm.DB) {
+		if tx.Statement.Schema != nil && tx.Statement.Schema.Table == "tickets" {
+			_ = tx.AddError(errInjected)
+		}
+	}); err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+
+	before := walletBalance(db, userID)
+	if _, err := purchases.PurchaseTickets(userID, PurchaseRequest{LotteryTypeID: lotteryTypeID, Quantity: 2}); !errors.Is(err, errInjected) {
+		t.Fatalf("Expected the injected failure, got %v", err)
+	}
+	if _, err := purchases.PurchaseTickets(userID, PurchaseRequest{LotteryTypeID: lotteryTypeID, Quantity: 100000}); err != ErrInsufficientBalance {
+		t.Fatalf("Expected ErrInsufficientBalance, got %v", err)
+	}
+
+	var refunds []model.Refund
+	db.Find(&refunds)
+	if len(refunds) != 1 || refunds[0].Status != model.RefundStatusRolledBack || refunds[0].Quantity != 2 || refunds[0].Error != errInjected.Error() {
+		t.Fatalf("Expected one rolled back failure, got %+v", refunds)
+	}
+	if balance := walletBalance(db, userID); balance != before {
+		t.Errorf("Expected the balance to stay %d, got %d", before, balance)
+	}
+}
+
+// A failed purchase whose charge was committed without tickets is refunded
+// once
+func TestKeptChargeOfFailedPurchaseIsRefunded(t *testing.T) {
+	db, _, refunds, lotteryTypeID, userID := setupRefundTest(t)
+	var wallet model.Wallet
+	db.Where("user_id = ?", userID).First(&wallet)
+	before := wallet.Balance
+
+	db.Model(&wallet).Update("balance", before-30)
+	charge := model.Transaction{WalletID: wallet.ID, Type: model.TransactionTypePurchase, Amount: -30}
+	db.Create(&charge)
+	db.Model(&charge).Update("reference_id", charge.ID)
+
+	failed := FailedPurchase{UserID: userID, LotteryTypeID: lotteryTypeID, Quantity: 3, Cost: 30, PurchaseID: charge.ID, Err: errors.New("commit failed")}
+	recorded, err := refunds.RecordFailedPurchase(failed)
+	if err != nil || len(recorded) != 1 || recorded[0].Status != model.RefundStatusRefunded ||
+		recorded[0].PurchaseID == 0 || recorded[0].RefundTransactionID == 0 {
+		t.Fatalf("Expected the kept charge refunded, got %+v (err %v)", recorded, err)
+	}
+	if balance := walletBalance(db, userID); balance != before {
+		t.Errorf("Expected the balance back at %d, got %d", before, balance)
+	}
+
+	// The charge is refunded once
+	if recorded, err := refunds.RecordFailedPurchase(failed); err != nil || len(recorded) != 1 || recorded[0].Status != model.RefundStatusRolledBack {
+		t.Errorf("Expected a second failure not to refund again, got %+v (err %v)", recorded, err)
+	}
+	if balance := walletBalance(db, userID); balance != before {
+		t.Errorf("Expected the balance to stay %d, got %d", before, balance)
+	}
+}
+
+// A failed purchase split between points and bonus credits is refunded from
+// both of its charges, each to the balance it was paid from
+func TestKeptSplitChargeIsRefunded(t *testing.T) {
+	db, _, refunds, lotteryTypeID, userID := setupRefundTest(t)
+	var wallet model.Wallet
+	db.Where("user_id = ?", userID).First(&wallet)
+	before := wallet.Balance
+
+	db.Model(&wallet).Updates(map[string]interface{}{"balance": before - 18, "bonus_balance": 0})
+	bonus := model.Transaction{WalletID: wallet.ID, Type: model.TransactionTypeBonusPurchase, Currency: model.CurrencyBonus, Amount: -12}
+	db.Create(&bonus)
+	db.Model(&bonus).Update("reference_id", bonus.ID)
+	db.Create(&model.Transaction{WalletID: wallet.ID, Type: model.TransactionTypePurchase, Currency: model.CurrencyPoints, Amount: -18, ReferenceID: bonus.ID})
+	// An identical purchase of its own is left alone
+	db.Create(&model.Transaction{WalletID: wallet.ID, Type: model.TransactionTypePurchase, Currency: model.CurrencyPoints, Amount: -30})
+
+	failed := FailedPurchase{UserID: userID, LotteryTypeID: lotteryTypeID, Quantity: 3, Cost: 30, PurchaseID: bonus.ID, Err: errors.New("commit failed")}
+	recorded, err := refunds.RecordFailedPurchase(failed)
+	if err != nil || len(recorded) != 2 {
+		t.Fatalf("Expected both charges refunded, got %+v (err %v)", recorded, err)
+	}
+	for _, refund := range recorded {
+		if refund.Status != model.RefundStatusRefunded {
+			t.Errorf("Expected refund of %d %s refunded, got %s", refund.Amount, refund.Currency, refund.Status)
+		}
+	}
+	db.Where("user_id = ?", userID).First(&wallet)
+	if wallet.Balance != before || wallet.BonusBalance != 12 {
+		t.Errorf("Expected %d points and 12 bonus back, got %d and %d", before, wallet.Balance, wallet.BonusBalance)
+	}
+	var bonusRefunds int64
+	db.Model(&model.Transaction{}).Where("type = ? AND currency = ? AND amount = ?", model.TransactionTypeRefund, model.CurrencyBonus, 12).Count(&bonusRefunds)
+	if bonusRefunds != 1 {
+		t.Errorf("Expected one bonus refund transaction, got %d", bonusRefunds)
+	}
+}
+
+// Admins refund the bonus part of a purchase as bonus credits
+func TestRefundBonusPurchase(t *testing.T) {
+	db, purchases, refunds, lotteryTypeID, userID := setupRefundTest(t)
+	db.Model(&model.Wallet{}).Where("user_id = ?", userID).Update("bonus_balance", 15)
+	if _, err := purchases.PurchaseTickets(userID, PurchaseRequest{LotteryTypeID: lotteryTypeID, Quantity: 2}); err != nil {
+		t.Fatalf("PurchaseTickets failed: %v", err)
+	}
+	var charge model.Transaction
+	if err := db.Where("type = ?", model.TransactionTypeBonusPurchase).First(&charge).Error; err != nil {
+		t.Fatalf("Expected a bonus purchase: %v", err)
+	}
+	var wallet model.Wallet
+	db.Where("user_id = ?", userID).First(&wallet)
+
+	refund, err := refunds.RefundPurchase(1, RefundPurchaseRequest{PurchaseID: charge.ID, Reason: "客诉补偿"})
+	if err != nil || refund.Currency != model.CurrencyBonus || refund.Amount != -charge.Amount {
+		t.Fatalf("Expected the bonus part refunded, got %+v (err %v)", refund, err)
+	}
+	var after model.Wallet
+	db.Where("user_id = ?", userID).First(&after)
+	if after.Balance != wallet.Balance || after.BonusBalance != wallet.BonusBalance-charge.Amount {
+		t.Errorf("Expected only the bonus balance credited, got %+v -> %+v", wallet, after)
+	}
+}
+
+// Refunding a purchase voids its tickets, which can no longer be scratched,
+// and a purchase with a scratched ticket is not refunded
+func TestRefundPurchaseVoidsTickets(t *testing.T) {
+	db, purchases, refunds, lotteryTypeID, userID := setupRefundTest(t)
+	var bought []*PurchaseResponse
+	for i := 0; i < 2; i++ {
+		purchase, err := purchases.PurchaseTickets(userID, PurchaseRequest{LotteryTypeID: lotteryTypeID, Quantity: 2})
+		if err != nil {
+			t.Fatalf("PurchaseTickets failed: %v", err)
+		}
+		bought = append(bought, purchase)
+	}
+	var charges []model.Transaction
+	db.Where("type = ?", model.TransactionTypePurchase).Order("id").Find(&charges)
+	if len(charges) != 2 {
+		t.Fatalf("Expected 2 charges, got %d", len(charges))
+	}
+
+	scratches := NewScratchService(db, purchases.lotteryService, purchases.walletService, nil, nil)
+	if _, err := scratches.ScratchTicket(userID, bought[1].Tickets[0].ID, ""); err != nil {
+		t.Fatalf("ScratchTicket failed: %v", err)
+	}
+	before := walletBalance(db, userID)
+	if _, err := refunds.RefundPurchase(1, RefundPurchaseRequest{PurchaseID: charges[1].ID, Reason: "客诉补偿"}); err != ErrPurchasePlayed {
+		t.Errorf("Expected ErrPurchasePlayed refunding a scratched purchase, got %v", err)
+	}
+	if balance := walletBalance(db, userID); balance != before {
+		t.Errorf("Expected the balance to stay %d, got %d", before, balance)
+	}
+	var ticket model.Ticket
+	db.First(&ticket, bought[1].Tickets[1].ID)
+	if ticket.Status != model.TicketStatusUnscratched {
+		t.Errorf("Expected the other ticket of the refused purchase unscratched, got %s", ticket.Status)
+	}
+
+	if _, err := refunds.RefundPurchase(1, RefundPurchaseRequest{PurchaseID: charges[0].ID, Amount: 5, Reason: "客诉补偿"}); err != nil {
+		t.Fatalf("RefundPurchase failed: %v", err)
+	}
+	var voided []model.Ticket
+	db.Where("purchase_id = ? AND status = ?", charges[0].ID, model.TicketStatusVoided).Find(&voided)
+	if len(voided) != 2 {
+		t.Errorf("Expected both tickets of the refunded purchase voided, got %d", len(voided))
+	}
+	var events int64
+	db.Model(&model.TicketEvent{}).Where("type = ? AND status = ?", model.TicketEventVoided, model.TicketStatusVoided).Count(&events)
+	if events != 2 {
+		t.Errorf("Expected 2 voided events, got %d", events)
+	}
+	if _, err := scratches.ScratchTicket(userID, bought[0].Tickets[0].ID, ""); err != ErrTicketVoided {
+		t.Errorf("Expected ErrTicketVoided scratching a refunded ticket, got %v", err)
+	}
+
+	// The rest of a partly refunded purchase can still be refunded
+	if _, err := refunds.RefundPurchase(1, RefundPurchaseRequest{PurchaseID: charges[0].ID, Reason: "客诉补偿"}); err != nil {
+		t.Errorf("Expected the rest of the purchase refunded, got %v", err)
+	}
+}
+
+// Admin refunds: the refunds of a purchase never add up to more than its
+// charge, and the balance grows by exactly what was refunded.
+func TestRefundsNeverExceedThePurchase(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("refunds stay within the charge", prop.ForAll(
+		func(quantity int, amounts []int) bool {
+			db, purchases, refunds, lotteryTypeID, userID := setupRefundTest(t)
+			if _, err := purchases.PurchaseTickets(userID, PurchaseRequest{LotteryTypeID: lotteryTypeID, Quantity: quantity}); err != nil {
+				t.Logf("PurchaseTickets failed: %v", err)
+				return false
+			}
+			var charge model.Transaction
+			db.Where("type = ?", model.TransactionTypePurchase).First(&charge)
+			before := walletBalance(db, userID)
+
+			refunded := 0
+			for _, amount := range amounts {
+				refund, err := refunds.RefundPurchase(1, RefundPurchaseRequest{PurchaseID: charge.ID, Amount: amount, Reason: "客诉补偿"})
+				remaining := -charge.Amount - refunded
+				expected := amount
+				if amount == 0 {
+					expected = remaining
+				}
+				if expected <= 0 || expected > remaining {
+					if err != ErrInvalidRefund {
+						t.Logf("Refunding %d of %d left: expected ErrInvalidRefund, got %v", amount, remaining, err)
+						return false
+					}
+					continue
+				}
+				if err != nil || refund.Status != model.RefundStatusRefunded || refund.Amount != expected {
+					t.Logf("Refunding %d of %d left: got %+v (err %v)", amount, remaining, refund, err)
+					return false
+				}
+				refunded += expected
+			}
+
+			var logs int64
+			db.Model(&model.AdminLog{}).Where("action = ?", "refund_purchase").Count(&logs)
+			balance := walletBalance(db, userID)
+			if balance != before+refunded || refunded > -charge.Amount {
+				t.Logf("Balance %d after refunding %d from %d", balance, refunded, before)
+				return false
+			}
+			return logs > 0 || refunded == 0
+		},
+		gen.IntRange(1, 5),
+		gen.SliceOfN(4, gen.IntRange(0, 40)),
+	))
+
+	properties.TestingRun(t)
+}
+
+// Pending refunds are credited on approval or closed on rejection, once
+func TestPendingRefundReview(t *testing.T) {
+	db, _, refunds, _, userID := setupRefundTest(t)
+	before := walletBalance(db, userID)
+
+	approved := model.Refund{UserID: userID, Amount: 25, Status: model.RefundStatusPending}
+	rejected := model.Refund{UserID: userID, Amount: 40, Status: model.RefundStatusPending}
+	db.Create(&approved)
+	db.Create(&rejected)
+
+	if refund, err := refunds.ApproveRefund(7, approved.ID); err != nil || refund.Status != model.RefundStatusRefunded || refund.ReviewedBy != 7 {
+		t.Fatalf("ApproveRefund: got %+v (err %v)", refund, err)
+	}
+	if _, err := refunds.ApproveRefund(7, approved.ID); err != ErrRefundNotPending {
+		t.Errorf("Expected ErrRefundNotPending approving twice, got %v", err)
+	}
+	if _, err := refunds.RejectRefund(7, rejected.ID, RejectRefundRequest{Reason: " "}); err != ErrInvalidRefund {
+		t.Errorf("Expected ErrInvalidRefund without a reason, got %v", err)
+	}
+	if refund, err := refunds.RejectRefund(7, rejected.ID, RejectRefundRequest{Reason: "重复申请"}); err != nil || refund.Status != model.RefundStatusRejected {
+		t.Fatalf("RejectRefund: got %+v (err %v)", refund, err)
+	}
+	if _, err := refunds.ApproveRefund(7, rejected.ID); err != ErrRefundNotPending {
+		t.Errorf("Expected ErrRefundNotPending approving a rejected refund, got %v", err)
+	}
+	if balance := walletBalance(db, userID); balance != before+25 {
+		t.Errorf("Expected a balance of %d, got %d", before+25, balance)
+	}
+
+	list, err := refunds.GetRefunds(AdminRefundQuery{Status: string(model.RefundStatusRefunded)})
+	if err != nil || list.Total != 1 || list.Refunds[0].ID != approved.ID {
+		t.Errorf("Expected the approved refund listed, got %+v (err %v)", list, err)
+	}
+}
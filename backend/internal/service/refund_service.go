@@ -0,0 +1,514 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// maxRefundReasonLength is the maximum length of a refund or rejection reason
+const maxRefundReasonLength = 255
+
+// maxRefundErrorLength is the maximum length of a recorded purchase failure
+const maxRefundErrorLength = 512
+
+var (
+	ErrRefundNotFound   = errors.New("refund not found")
+	ErrRefundNotPending = errors.New("refund not waiting for review")
+	ErrInvalidRefund    = errors.New("invalid refund")
+	ErrPurchaseNotFound = errors.New("purchase not found")
+	ErrPurchasePlayed   = errors.New("tickets of the purchase already scratched")
+)
+
+// RefundService gives the points and bonus credits of purchases back to
+// their buyers, each to the balance it was paid from. Failed purchases are
+// recorded for review: the charge of a purchase is made in the same
+// transaction as its tickets, so it is normally undone with them, but a
+// commit reported as failed may still have been applied. When the charge of a
+// failed purchase turns out to have stuck without tickets it is refunded at
+// once. Admins refund completed purchases and review refunds that could not
+// be credited.
+type RefundService struct {
+	db *gorm.DB
+}
+
+// NewRefundService creates a new refund service
+func NewRefundService(db *gorm.DB) *RefundService {
+	return &RefundService{db: db}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *RefundService) ForTenant(tenantID uint) *RefundService {
+	return &RefundService{db: repository.ScopeTenant(s.db, tenantID)}
+}
+
+// WithRequest returns a copy of the service whose logs carry the request id
+// of ctx
+func (s *RefundService) WithRequest(ctx context.Context) *RefundService {
+	scoped := *s
+	scoped.db = repository.ScopeRequest(s.db, ctx)
+	return &scoped
+}
+
+// FailedPurchase describes a purchase whose transaction failed
+type FailedPurchase struct {
+	UserID        uint
+	LotteryTypeID uint
+	Quantity      int
+	Cost          int  // Points and bonus credits the purchase charged
+	PurchaseID    uint // Transaction the purchase's charges reference, 0 if it charged nothing
+	Err           error
+}
+
+// RefundPurchaseRequest represents an admin's refund of a purchase. Amount
+// defaults to what has not been refunded yet.
+type RefundPurchaseRequest struct {
+	PurchaseID uint   `json:"purchase_id" binding:"required"` // Purchase or bonus purchase transaction
+	Amount     int    `json:"amount"`
+	Reason     string `json:"reason" binding:"required"`
+}
+
+// RejectRefundRequest represents an admin's refusal of a pending refund
+type RejectRefundRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// AdminRefundQuery represents query parameters for the admin refund list
+type AdminRefundQuery struct {
+	Status string `form:"status"` // All statuses when empty
+	UserID uint   `form:"user_id"`
+	Page   int    `form:"page"`
+	Limit  int    `form:"limit"`
+}
+
+// AdminRefundListResponse represents a page of refunds
+type AdminRefundListResponse struct {
+	Refunds    []model.Refund `json:"refunds"`
+	Total      int64          `json:"total"`
+	Page       int            `json:"page"`
+	Limit      int            `json:"limit"`
+	TotalPages int            `json:"total_pages"`
+}
+
+// RecordFailedPurchase records a purchase whose transaction failed. When its
+// charge was kept although no tickets were issued, the charge is refunded,
+// one refund for each balance it was paid from; refunds that cannot be
+// credited are left pending for an admin. A failure whose charge was rolled
+// back is recorded as a single rolled back refund.
+func (s *RefundService) RecordFailedPurchase(failed FailedPurchase) ([]model.Refund, error) {
+	message := ""
+	if failed.Err != nil {
+		message = failed.Err.Error()
+		if len([]rune(message)) > maxRefundErrorLength {
+			message = string([]rune(message)[:maxRefundErrorLength])
+		}
+	}
+
+	charges, err := s.keptCharges(failed)
+	if err != nil {
+		return nil, err
+	}
+	if len(charges) == 0 {
+		refund := model.Refund{
+			UserID:        failed.UserID,
+			LotteryTypeID: failed.LotteryTypeID,
+			Quantity:      failed.Quantity,
+			Amount:        failed.Cost,
+			Currency:      model.CurrencyPoints,
+			Status:        model.RefundStatusRolledBack,
+			Error:         message,
+		}
+		if err := s.db.Create(&refund).Error; err != nil {
+			return nil, err
+		}
+		return []model.Refund{refund}, nil
+	}
+
+	refunds := make([]model.Refund, len(charges))
+	for i, charge := range charges {
+		refunds[i] = model.Refund{
+			UserID:        failed.UserID,
+			LotteryTypeID: failed.LotteryTypeID,
+			Quantity:      failed.Quantity,
+			Amount:        -charge.Amount,
+			Currency:      chargeCurrency(&charge),
+			PurchaseID:    charge.ID,
+			Status:        model.RefundStatusPending,
+			Reason:        "购票失败自动退款",
+			Error:         message,
+		}
+	}
+	if err := s.db.Create(&refunds).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	err = repository.Transaction(s.db, func(tx *gorm.DB) error {
+		for i := range refunds {
+			if err := creditRefund(tx, &refunds[i], 0, now, "购票退款"); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		// Left pending; an admin approves them once the cause is fixed
+		for i := range refunds {
+			refunds[i].Status = model.RefundStatusPending
+			refunds[i].RefundTransactionID = 0
+			refunds[i].ReviewedAt = nil
+		}
+		return refunds, err
+	}
+	return refunds, nil
+}
+
+// chargeCurrency returns the balance a purchase charge was paid from
+func chargeCurrency(charge *model.Transaction) model.WalletCurrency {
+	if charge.Type == model.TransactionTypeBonusPurchase || charge.Currency == model.CurrencyBonus {
+		return model.CurrencyBonus
+	}
+	return model.CurrencyPoints
+}
+
+// keptCharges returns the charges of a failed purchase when they were
+// committed without any tickets, or nil. A purchase split between points and
+// bonus credits is charged as a purchase and a bonus purchase transaction,
+// which both reference the purchase and together make up its cost.
+func (s *RefundService) keptCharges(failed FailedPurchase) ([]model.Transaction, error) {
+	if failed.Cost <= 0 || failed.PurchaseID == 0 {
+		return nil, nil
+	}
+	var wallet model.Wallet
+	if err := s.db.Where("user_id = ?", failed.UserID).First(&wallet).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var charges []model.Transaction
+	if err := s.db.Where("wallet_id = ? AND type IN ? AND amount < 0 AND reference_id = ?",
+		wallet.ID, ticketSpendTypes, failed.PurchaseID).
+		Where("id NOT IN (?)", s.db.Model(&model.Refund{}).Select("purchase_id").Where("purchase_id <> 0")).
+		Order("id ASC").Find(&charges).Error; err != nil {
+		return nil, err
+	}
+	if len(charges) == 0 {
+		return nil, nil
+	}
+
+	var tickets int64
+	if err := s.db.Model(&model.Ticket{}).Where("purchase_id = ?", failed.PurchaseID).Count(&tickets).Error; err != nil {
+		return nil, err
+	}
+	if tickets > 0 {
+		return nil, nil
+	}
+	return charges, nil
+}
+
+// voidPurchaseTickets voids the tickets of the purchase purchaseID within tx,
+// so a refunded purchase cannot be scratched any more. It fails with
+// ErrPurchasePlayed when a ticket of the purchase has been scratched.
+func voidPurchaseTickets(tx *gorm.DB, purchaseID, actorID uint, reason string) error {
+	if purchaseID == 0 {
+		return nil
+	}
+	var tickets []model.Ticket
+	if err := tx.Where("purchase_id = ? AND status <> ?", purchaseID, model.TicketStatusVoided).Find(&tickets).Error; err != nil {
+		return err
+	}
+	for _, ticket := range tickets {
+		if ticket.Status != model.TicketStatusUnscratched {
+			return ErrPurchasePlayed
+		}
+	}
+	for i := range tickets {
+		ticket := &tickets[i]
+		// A ticket scratched meanwhile keeps the purchase from being refunded
+		result := tx.Model(&model.Ticket{}).Where("id = ? AND status = ?", ticket.ID, model.TicketStatusUnscratched).
+			Update("status", model.TicketStatusVoided)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrPurchasePlayed
+		}
+		if err := adjustBadge(tx, ticket.UserID, badgeUnscratchedTickets, -1); err != nil {
+			return err
+		}
+		if err := appendTicketEvent(tx, ticket, model.TicketEventVoided, actorID, model.TicketStatusVoided, 0, reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RefundPurchase refunds a completed purchase to its buyer, the points part
+// of it as points and the bonus purchase part as bonus credits. The refunds
+// of a purchase never exceed its charge; the refund is recorded in the admin
+// log. The tickets of the purchase are voided with the refund, so purchases
+// with a scratched ticket are not refunded.
+func (s *RefundService) RefundPurchase(adminID uint, req RefundPurchaseRequest) (*model.Refund, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" || len([]rune(reason)) > maxRefundReasonLength || req.Amount < 0 {
+		return nil, ErrInvalidRefund
+	}
+
+	var charge model.Transaction
+	if err := s.db.Where("type IN ? AND amount < 0", []model.TransactionType{model.TransactionTypePurchase, model.TransactionTypeBonusPurchase}).
+		First(&charge, req.PurchaseID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPurchaseNotFound
+		}
+		return nil, err
+	}
+	var wallet model.Wallet
+	if err := s.db.First(&wallet, charge.WalletID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWalletNotFound
+		}
+		return nil, err
+	}
+
+	var refund *model.Refund
+	now := time.Now()
+	err := repository.Transaction(s.db, func(tx *gorm.DB) error {
+		// The wallet lock serialises refunds of the buyer's purchases
+		var locked model.Wallet
+		if err := lockWallet(tx, wallet.UserID, &locked); err != nil {
+			return err
+		}
+		var refunded struct{ Total int }
+		if err := tx.Model(&model.Refund{}).Select("COALESCE(SUM(amount), 0) as total").
+			Where("purchase_id = ? AND status IN ?", charge.ID, []model.RefundStatus{model.RefundStatusPending, model.RefundStatusRefunded}).
+			Scan(&refunded).Error; err != nil {
+			return err
+		}
+		remaining := -charge.Amount - refunded.Total
+		amount := req.Amount
+		if amount == 0 {
+			amount = remaining
+		}
+		if amount <= 0 || amount > remaining {
+			return ErrInvalidRefund
+		}
+		if err := voidPurchaseTickets(tx, charge.ReferenceID, adminID, reason); err != nil {
+			return err
+		}
+
+		refund = &model.Refund{
+			UserID:     wallet.UserID,
+			PurchaseID: charge.ID,
+			Amount:     amount,
+			Currency:   chargeCurrency(&charge),
+			Status:     model.RefundStatusPending,
+			Reason:     reason,
+		}
+		if err := tx.Create(refund).Error; err != nil {
+			return err
+		}
+		if err := creditRefund(tx, refund, adminID, now, "购票退款"); err != nil {
+			return err
+		}
+		return logRefundReview(tx, adminID, refund, "refund_purchase", reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+// ApproveRefund credits a pending refund and records the approval in the
+// admin log
+func (s *RefundService) ApproveRefund(adminID, refundID uint) (*model.Refund, error) {
+	refund, err := s.getPendingRefund(refundID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	err = repository.Transaction(s.db, func(tx *gorm.DB) error {
+		if err := creditRefund(tx, refund, adminID, now, "购票退款"); err != nil {
+			return err
+		}
+		return logRefundReview(tx, adminID, refund, "approve_refund", "")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+// RejectRefund refuses a pending refund and records the rejection in the
+// admin log
+func (s *RefundService) RejectRefund(adminID, refundID uint, req RejectRefundRequest) (*model.Refund, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" || len([]rune(reason)) > maxRefundReasonLength {
+		return nil, ErrInvalidRefund
+	}
+	refund, err := s.getPendingRefund(refundID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	err = repository.Transaction(s.db, func(tx *gorm.DB) error {
+		result := tx.Model(&model.Refund{}).
+			Where("id = ? AND status = ?", refund.ID, model.RefundStatusPending).
+			Updates(map[string]interface{}{
+				"status":      model.RefundStatusRejected,
+				"reason":      reason,
+				"reviewed_by": adminID,
+				"reviewed_at": now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRefundNotPending
+		}
+		return logRefundReview(tx, adminID, refund, "reject_refund", reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+	refund.Status = model.RefundStatusRejected
+	refund.Reason = reason
+	refund.ReviewedBy = adminID
+	refund.ReviewedAt = &now
+	return refund, nil
+}
+
+// GetRefunds returns a page of refunds, newest first
+func (s *RefundService) GetRefunds(query AdminRefundQuery) (*AdminRefundListResponse, error) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit < 1 || query.Limit > 100 {
+		query.Limit = 20
+	}
+
+	dbQuery := s.db.Model(&model.Refund{})
+	if query.Status != "" {
+		dbQuery = dbQuery.Where("status = ?", query.Status)
+	}
+	if query.UserID != 0 {
+		dbQuery = dbQuery.Where("user_id = ?", query.UserID)
+	}
+	var total int64
+	if err := dbQuery.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+	refunds := []model.Refund{}
+	if err := dbQuery.Order("created_at DESC, id DESC").
+		Offset((query.Page - 1) * query.Limit).
+		Limit(query.Limit).
+		Find(&refunds).Error; err != nil {
+		return nil, err
+	}
+
+	return &AdminRefundListResponse{
+		Refunds:    refunds,
+		Total:      total,
+		Page:       query.Page,
+		Limit:      query.Limit,
+		TotalPages: int((total + int64(query.Limit) - 1) / int64(query.Limit)),
+	}, nil
+}
+
+// getPendingRefund loads a refund waiting for review
+func (s *RefundService) getPendingRefund(refundID uint) (*model.Refund, error) {
+	var refund model.Refund
+	if err := s.db.First(&refund, refundID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRefundNotFound
+		}
+		return nil, err
+	}
+	if refund.Status != model.RefundStatusPending {
+		return nil, ErrRefundNotPending
+	}
+	return &refund, nil
+}
+
+// creditRefund credits a pending refund to its buyer's wallet as a refund
+// transaction with the given description and marks it refunded, guarding
+// against a concurrent review. Bonus refunds go to the bonus balance.
+// Automatic refunds have no reviewer.
+func creditRefund(tx *gorm.DB, refund *model.Refund, adminID uint, now time.Time, description string) error {
+	var wallet model.Wallet
+	if err := lockWallet(tx, refund.UserID, &wallet); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrWalletNotFound
+		}
+		return err
+	}
+	currency := model.CurrencyPoints
+	if refund.Currency == model.CurrencyBonus {
+		currency = model.CurrencyBonus
+		wallet.BonusBalance += refund.Amount
+	} else {
+		wallet.Balance += refund.Amount
+	}
+	if err := tx.Save(&wallet).Error; err != nil {
+		return err
+	}
+	transaction := model.Transaction{
+		TenantID:    wallet.TenantID,
+		WalletID:    wallet.ID,
+		Type:        model.TransactionTypeRefund,
+		Currency:    currency,
+		Amount:      refund.Amount,
+		Description: description,
+		ReferenceID: refund.ID,
+	}
+	if err := tx.Create(&transaction).Error; err != nil {
+		return err
+	}
+
+	result := tx.Model(&model.Refund{}).
+		Where("id = ? AND status = ?", refund.ID, model.RefundStatusPending).
+		Updates(map[string]interface{}{
+			"status":                model.RefundStatusRefunded,
+			"refund_transaction_id": transaction.ID,
+			"reviewed_by":           adminID,
+			"reviewed_at":           now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRefundNotPending
+	}
+	refund.Status = model.RefundStatusRefunded
+	refund.RefundTransactionID = transaction.ID
+	refund.ReviewedBy = adminID
+	refund.ReviewedAt = &now
+	return nil
+}
+
+// logRefundReview records an admin's handling of a refund in the admin log
+func logRefundReview(tx *gorm.DB, adminID uint, refund *model.Refund, action, reason string) error {
+	details, _ := json.Marshal(map[string]interface{}{
+		"user_id":     refund.UserID,
+		"purchase_id": refund.PurchaseID,
+		"amount":      refund.Amount,
+		"currency":    refund.Currency,
+		"reason":      reason,
+	})
+	adminLog := model.AdminLog{
+		AdminID:    adminID,
+		Action:     action,
+		TargetType: "refund",
+		TargetID:   refund.ID,
+		Details:    string(details),
+	}
+	return tx.Create(&adminLog).Error
+}
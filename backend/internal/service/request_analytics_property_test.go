@@ -0,0 +1,228 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+	"gorm.io/gorm"
+)
+
+// setupRequestAnalyticsTest creates a tenant test database with the request
+// analytics tables
+func setupRequestAnalyticsTest(t *testing.T) (*gorm.DB, *RequestAnalyticsService) {
+	db := setupTenantTestDB(t)
+	if err := db.AutoMigrate(&model.RequestStat{}, &model.UserRequestStat{}, &model.APIKeyRequestStat{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	return db, NewRequestAnalyticsService(db, 90)
+}
+
+// Request analytics: requests flushed in several batches add up to the same
+// counts, error rate and p95 bucket as recorded.
+func TestRequestAnalyticsFlushAddsUp(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	db, analytics := setupRequestAnalyticsTest(t)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("flushed counts match recorded requests", prop.ForAll(
+		func(latencies []int, statuses []int, batch int) bool {
+			defer func() {
+				db.Where("1 = 1").Delete(&model.RequestStat{})
+				db.Where("1 = 1").Delete(&model.UserRequestStat{})
+			}()
+
+			var errors, clientErrors int64
+			recorded := &requestAggregate{}
+			for i, latency := range latencies {
+				status := statuses[i%len(statuses)]
+				if status >= 400 {
+					errors++
+				}
+				if status >= 400 && status < 500 {
+					clientErrors++
+				}
+				recorded.Requests++
+				recorded.Buckets[latencyBucket(int64(latency))]++
+				if int64(latency) > recorded.LatencyMaxMs {
+					recorded.LatencyMaxMs = int64(latency)
+				}
+				analytics.Record(1, "GET", "/api/lottery/types", 7, status, time.Duration(latency)*time.Millisecond)
+				if (i+1)%batch == 0 {
+					if _, err := analytics.Flush(); err != nil {
+						t.Logf("Flush failed: %v", err)
+						return false
+					}
+				}
+			}
+			if _, err := analytics.Flush(); err != nil {
+				t.Logf("Flush failed: %v", err)
+				return false
+			}
+
+			result, err := analytics.ForTenant(1).GetRequestAnalytics(RequestAnalyticsQuery{})
+			if err != nil {
+				t.Logf("GetRequestAnalytics failed: %v", err)
+				return false
+			}
+			if len(result.Routes) != 1 || len(result.Users) != 1 {
+				t.Logf("Expected one route and one user, got %+v", result)
+				return false
+			}
+			route := result.Routes[0]
+			if route.Requests != int64(len(latencies)) || route.ClientErrors != clientErrors ||
+				route.ClientErrors+route.ServerErrors != errors {
+				t.Logf("Expected %d requests with %d errors, got %+v", len(latencies), errors, route)
+				return false
+			}
+			if route.P95LatencyMs != p95Latency(recorded) || route.MaxLatencyMs != recorded.LatencyMaxMs {
+				t.Logf("Expected p95 %d and max %d, got %+v", p95Latency(recorded), recorded.LatencyMaxMs, route)
+				return false
+			}
+			if result.Users[0].UserID != 7 || result.Users[0].Requests != int64(len(latencies)) || result.Users[0].Errors != errors {
+				t.Logf("Unexpected user volume %+v", result.Users[0])
+				return false
+			}
+			return true
+		},
+		gen.SliceOfN(40, gen.IntRange(0, 8000)),
+		gen.SliceOfN(5, gen.OneConstOf(200, 201, 400, 404, 500)),
+		gen.IntRange(1, 40),
+	))
+
+	properties.TestingRun(t)
+}
+
+// Request analytics: p95 is the upper bound of the bucket holding the 95th
+// percentile, or the slowest request beyond the last bound.
+func TestRequestAnalyticsP95(t *testing.T) {
+	agg := &requestAggregate{}
+	for i := 0; i < 95; i++ {
+		agg.Buckets[latencyBucket(8)]++
+	}
+	for i := 0; i < 5; i++ {
+		agg.Buckets[latencyBucket(300)]++
+	}
+	if p95 := p95Latency(agg); p95 != 10 {
+		t.Errorf("Expected a p95 of 10ms, got %d", p95)
+	}
+
+	agg.Buckets[latencyBucket(8)]--
+	if p95 := p95Latency(agg); p95 != 500 {
+		t.Errorf("Expected a p95 of 500ms, got %d", p95)
+	}
+
+	slow := &requestAggregate{LatencyMaxMs: 9000}
+	slow.Buckets[latencyBucket(9000)] = 3
+	if p95 := p95Latency(slow); p95 != 9000 {
+		t.Errorf("Expected the slowest request beyond the last bound, got %d", p95)
+	}
+	if p95 := p95Latency(&requestAggregate{}); p95 != 0 {
+		t.Errorf("Expected 0 without requests, got %d", p95)
+	}
+}
+
+// Request analytics: unmatched routes are ignored, tenants see only their own
+// requests, and routes are ranked by volume.
+func TestRequestAnalyticsTenantsAndRanking(t *testing.T) {
+	db, analytics := setupRequestAnalyticsTest(t)
+
+	for i := 0; i < 3; i++ {
+		analytics.Record(1, "GET", "/api/lottery/types", 0, 200, time.Millisecond)
+	}
+	analytics.Record(1, "POST", "/api/lottery/purchase", 0, 500, time.Millisecond)
+	analytics.Record(2, "GET", "/api/exchange/products", 3, 200, time.Millisecond)
+	analytics.Record(1, "GET", "", 0, 404, time.Millisecond)
+	if flushed, err := analytics.Flush(); err != nil || flushed != 5 {
+		t.Fatalf("Expected 5 requests flushed, got %d (err %v)", flushed, err)
+	}
+
+	var rows int64
+	db.Model(&model.RequestStat{}).Count(&rows)
+	if rows != 3 {
+		t.Errorf("Expected 3 hourly rows, got %d", rows)
+	}
+
+	first, err := analytics.ForTenant(1).GetRequestAnalytics(RequestAnalyticsQuery{Route: "/api/lottery/purchase"})
+	if err != nil {
+		t.Fatalf("GetRequestAnalytics failed: %v", err)
+	}
+	if first.Requests != 4 || len(first.Routes) != 2 || first.Routes[0].Route != "/api/lottery/types" || len(first.Users) != 0 {
+		t.Errorf("Unexpected analytics of tenant 1 %+v", first)
+	}
+	if first.ErrorRate != 0.25 || first.Routes[1].ServerErrors != 1 {
+		t.Errorf("Expected one server error in four requests, got %+v", first)
+	}
+	if len(first.Timeline) != 1 || first.Timeline[0].Requests != 1 || first.Timeline[0].Errors != 1 {
+		t.Errorf("Expected the timeline of the purchase route only, got %+v", first.Timeline)
+	}
+
+	second, err := analytics.ForTenant(2).GetRequestAnalytics(RequestAnalyticsQuery{})
+	if err != nil {
+		t.Fatalf("GetRequestAnalytics failed: %v", err)
+	}
+	if second.Requests != 1 || len(second.Users) != 1 || second.Users[0].UserID != 3 {
+		t.Errorf("Unexpected analytics of tenant 2 %+v", second)
+	}
+
+	if deleted, err := analytics.Prune(time.Now().Add(time.Hour)); err != nil || deleted != 4 {
+		t.Errorf("Expected 4 rows pruned, got %d (err %v)", deleted, err)
+	}
+}
+
+// Partner usage: each API key sees only its own requests, errors and rate
+// limit hits, and keys are stored by fingerprint only.
+func TestRequestAnalyticsAPIKeyUsage(t *testing.T) {
+	db, analytics := setupRequestAnalyticsTest(t)
+
+	for _, status := range []int{200, 200, 400, 429, 429} {
+		analytics.RecordAPIKey("partner-a", "POST", "/api/lottery/verify/batch", status)
+	}
+	analytics.RecordAPIKey("partner-a", "GET", "/api/partner/usage", 200)
+	analytics.RecordAPIKey("partner-b", "POST", "/api/lottery/verify/batch", 200)
+	analytics.RecordAPIKey("", "POST", "/api/lottery/verify/batch", 200)
+	if _, err := analytics.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	// A second flush adds to the stored hour
+	analytics.RecordAPIKey("partner-a", "POST", "/api/lottery/verify/batch", 500)
+	if _, err := analytics.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	var leaked int64
+	db.Model(&model.APIKeyRequestStat{}).Where("key_id LIKE ?", "partner%").Count(&leaked)
+	if leaked != 0 {
+		t.Errorf("Expected keys stored by fingerprint, found %d rows with the key", leaked)
+	}
+
+	usage, err := analytics.GetAPIKeyUsage("partner-a", APIKeyUsageQuery{})
+	if err != nil {
+		t.Fatalf("GetAPIKeyUsage failed: %v", err)
+	}
+	if usage.KeyID != APIKeyID("partner-a") || usage.Requests != 7 || usage.Errors != 4 || usage.RateLimited != 2 {
+		t.Errorf("Unexpected usage of partner-a %+v", usage)
+	}
+	if len(usage.Routes) != 2 || usage.Routes[0].Route != "/api/lottery/verify/batch" || usage.Routes[0].Requests != 6 || usage.Routes[0].ErrorRate != 4.0/6 {
+		t.Errorf("Expected the batch route first with 4 errors in 6 requests, got %+v", usage.Routes)
+	}
+	if len(usage.Timeline) != 1 || usage.Timeline[0].Requests != 7 || usage.Timeline[0].RateLimited != 2 {
+		t.Errorf("Expected one hour with 7 requests, got %+v", usage.Timeline)
+	}
+
+	other, err := analytics.GetAPIKeyUsage("partner-b", APIKeyUsageQuery{})
+	if err != nil || other.Requests != 1 || other.Errors != 0 {
+		t.Errorf("Expected only the request of partner-b, got %+v (err %v)", other, err)
+	}
+	if deleted, err := analytics.Prune(time.Now().Add(time.Hour)); err != nil || deleted != 3 {
+		t.Errorf("Expected 3 rows pruned, got %d (err %v)", deleted, err)
+	}
+}
@@ -0,0 +1,769 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+	"scratch-lottery/pkg/biztime"
+	"scratch-lottery/pkg/logger"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxRequestAnalyticsDays limits the selectable request analytics range
+const maxRequestAnalyticsDays = 93
+
+// defaultRequestAnalyticsLimit is the number of routes and users reported by default
+const defaultRequestAnalyticsLimit = 20
+
+// latencyBucketBounds are the upper bounds in ms of the latency buckets; the
+// last bucket counts everything slower
+var latencyBucketBounds = [...]int64{10, 50, 100, 250, 500, 1000, 5000}
+
+// latencyBuckets counts requests per latency bucket
+type latencyBuckets [len(latencyBucketBounds) + 1]int64
+
+// requestStatKey identifies an hourly route aggregate
+type requestStatKey struct {
+	TenantID uint
+	Hour     time.Time
+	Method   string
+	Route    string
+}
+
+// userStatKey identifies an hourly user aggregate
+type userStatKey struct {
+	TenantID uint
+	Hour     time.Time
+	UserID   uint
+}
+
+// requestAggregate accumulates the requests of a route until they are flushed
+type requestAggregate struct {
+	Requests       int64
+	ClientErrors   int64
+	ServerErrors   int64
+	LatencyTotalMs int64
+	LatencyMaxMs   int64
+	Buckets        latencyBuckets
+}
+
+func (a *requestAggregate) merge(other *requestAggregate) {
+	a.Requests += other.Requests
+	a.ClientErrors += other.ClientErrors
+	a.ServerErrors += other.ServerErrors
+	a.LatencyTotalMs += other.LatencyTotalMs
+	if other.LatencyMaxMs > a.LatencyMaxMs {
+		a.LatencyMaxMs = other.LatencyMaxMs
+	}
+	for i := range a.Buckets {
+		a.Buckets[i] += other.Buckets[i]
+	}
+}
+
+// apiKeyStatKey identifies an hourly API key aggregate
+type apiKeyStatKey struct {
+	Hour   time.Time
+	KeyID  string
+	Method string
+	Route  string
+}
+
+// apiKeyAggregate accumulates the requests of an API key until they are flushed
+type apiKeyAggregate struct {
+	Requests    int64
+	Errors      int64
+	RateLimited int64
+}
+
+func (a *apiKeyAggregate) merge(other *apiKeyAggregate) {
+	a.Requests += other.Requests
+	a.Errors += other.Errors
+	a.RateLimited += other.RateLimited
+}
+
+// userAggregate accumulates the requests of a user until they are flushed
+type userAggregate struct {
+	Requests int64
+	Errors   int64
+}
+
+// requestRecorder holds the aggregates recorded since the last flush. It is
+// shared by all tenant copies of the service.
+type requestRecorder struct {
+	mu       sync.Mutex
+	requests map[requestStatKey]*requestAggregate
+	users    map[userStatKey]*userAggregate
+	keys     map[apiKeyStatKey]*apiKeyAggregate
+}
+
+func newRequestRecorder() *requestRecorder {
+	return &requestRecorder{
+		requests: make(map[requestStatKey]*requestAggregate),
+		users:    make(map[userStatKey]*userAggregate),
+		keys:     make(map[apiKeyStatKey]*apiKeyAggregate),
+	}
+}
+
+// take returns the pending aggregates and starts new ones
+func (r *requestRecorder) take() (map[requestStatKey]*requestAggregate, map[userStatKey]*userAggregate, map[apiKeyStatKey]*apiKeyAggregate) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	requests, users, keys := r.requests, r.users, r.keys
+	r.requests = make(map[requestStatKey]*requestAggregate)
+	r.users = make(map[userStatKey]*userAggregate)
+	r.keys = make(map[apiKeyStatKey]*apiKeyAggregate)
+	return requests, users, keys
+}
+
+// restore puts aggregates that failed to flush back, so the next flush retries them
+func (r *requestRecorder) restore(requests map[requestStatKey]*requestAggregate, users map[userStatKey]*userAggregate, keys map[apiKeyStatKey]*apiKeyAggregate) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, agg := range requests {
+		if pending, ok := r.requests[key]; ok {
+			pending.merge(agg)
+		} else {
+			r.requests[key] = agg
+		}
+	}
+	for key, agg := range users {
+		if pending, ok := r.users[key]; ok {
+			pending.Requests += agg.Requests
+			pending.Errors += agg.Errors
+		} else {
+			r.users[key] = agg
+		}
+	}
+	for key, agg := range keys {
+		if pending, ok := r.keys[key]; ok {
+			pending.merge(agg)
+		} else {
+			r.keys[key] = agg
+		}
+	}
+}
+
+// RequestAnalyticsService aggregates API usage per route, per user and per
+// partner API key into hourly rows, so admins can see top endpoints, error
+// rates and latency without an external APM, and partners can see their own
+// usage. Requests are counted in memory and flushed in the
+// background; the flush adds to existing rows, so several instances can
+// share the tables.
+type RequestAnalyticsService struct {
+	db            *gorm.DB
+	recorder      *requestRecorder
+	retentionDays int
+}
+
+// NewRequestAnalyticsService creates a new request analytics service. Hourly
+// rows older than retentionDays are pruned by the background job; 0 keeps
+// them forever.
+func NewRequestAnalyticsService(db *gorm.DB, retentionDays int) *RequestAnalyticsService {
+	return &RequestAnalyticsService{db: db, recorder: newRequestRecorder(), retentionDays: retentionDays}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *RequestAnalyticsService) ForTenant(tenantID uint) *RequestAnalyticsService {
+	return &RequestAnalyticsService{
+		db:            repository.ScopeTenant(s.db, tenantID),
+		recorder:      s.recorder,
+		retentionDays: s.retentionDays,
+	}
+}
+
+// RequestAnalyticsQuery represents query parameters for request analytics
+type RequestAnalyticsQuery struct {
+	StartDate string `form:"start_date"` // Format: 2006-01-02, default 6 days before end
+	EndDate   string `form:"end_date"`   // Format: 2006-01-02, default today
+	Method    string `form:"method"`     // Restricts the timeline to one method
+	Route     string `form:"route"`      // Restricts the timeline to one route pattern
+	Limit     int    `form:"limit"`      // Number of routes and users, default 20
+}
+
+// RouteAnalytics summarizes the requests to one route
+type RouteAnalytics struct {
+	Method       string  `json:"method"`
+	Route        string  `json:"route"`
+	Requests     int64   `json:"requests"`
+	ClientErrors int64   `json:"client_errors"`
+	ServerErrors int64   `json:"server_errors"`
+	ErrorRate    float64 `json:"error_rate"` // Share of 4xx and 5xx responses
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+	P95LatencyMs int64   `json:"p95_latency_ms"` // Upper bound of the latency bucket holding the 95th percentile
+	MaxLatencyMs int64   `json:"max_latency_ms"`
+}
+
+// RequestAnalyticsPoint summarizes the requests of one hour
+type RequestAnalyticsPoint struct {
+	Hour         time.Time `json:"hour"`
+	Requests     int64     `json:"requests"`
+	Errors       int64     `json:"errors"`
+	P95LatencyMs int64     `json:"p95_latency_ms"`
+}
+
+// UserRequestAnalytics is the call volume of one user
+type UserRequestAnalytics struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+	Requests int64  `json:"requests"`
+	Errors   int64  `json:"errors"`
+}
+
+// RequestAnalyticsResponse represents the request analytics of a date range
+type RequestAnalyticsResponse struct {
+	StartDate    string                  `json:"start_date"`
+	EndDate      string                  `json:"end_date"`
+	Requests     int64                   `json:"requests"`
+	Errors       int64                   `json:"errors"`
+	ErrorRate    float64                 `json:"error_rate"`
+	P95LatencyMs int64                   `json:"p95_latency_ms"`
+	Routes       []RouteAnalytics        `json:"routes"`   // Busiest first
+	Timeline     []RequestAnalyticsPoint `json:"timeline"` // Hourly, hours without requests omitted
+	Users        []UserRequestAnalytics  `json:"users"`    // Busiest first
+}
+
+// Record counts a finished request. Requests that matched no route are not
+// recorded, so scanners cannot flood the tables with arbitrary paths.
+func (s *RequestAnalyticsService) Record(tenantID uint, method, route string, userID uint, status int, latency time.Duration) {
+	if route == "" {
+		return
+	}
+	if tenantID == 0 {
+		tenantID = repository.DefaultTenantID
+	}
+	hour := time.Now().Truncate(time.Hour)
+	latencyMs := latency.Milliseconds()
+
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+
+	key := requestStatKey{TenantID: tenantID, Hour: hour, Method: method, Route: route}
+	agg, ok := s.recorder.requests[key]
+	if !ok {
+		agg = &requestAggregate{}
+		s.recorder.requests[key] = agg
+	}
+	agg.Requests++
+	switch {
+	case status >= 500:
+		agg.ServerErrors++
+	case status >= 400:
+		agg.ClientErrors++
+	}
+	agg.LatencyTotalMs += latencyMs
+	if latencyMs > agg.LatencyMaxMs {
+		agg.LatencyMaxMs = latencyMs
+	}
+	agg.Buckets[latencyBucket(latencyMs)]++
+
+	if userID == 0 {
+		return
+	}
+	userKey := userStatKey{TenantID: tenantID, Hour: hour, UserID: userID}
+	user, ok := s.recorder.users[userKey]
+	if !ok {
+		user = &userAggregate{}
+		s.recorder.users[userKey] = user
+	}
+	user.Requests++
+	if status >= 400 {
+		user.Errors++
+	}
+}
+
+// APIKeyID returns the fingerprint identifying an API key in the usage
+// statistics, so the key itself is never stored
+func APIKeyID(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:8])
+}
+
+// RecordAPIKey counts a finished request authenticated by a partner API key.
+// 429 responses are counted as rate limit hits.
+func (s *RequestAnalyticsService) RecordAPIKey(apiKey, method, route string, status int) {
+	if route == "" || apiKey == "" {
+		return
+	}
+	key := apiKeyStatKey{Hour: time.Now().Truncate(time.Hour), KeyID: APIKeyID(apiKey), Method: method, Route: route}
+
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+
+	agg, ok := s.recorder.keys[key]
+	if !ok {
+		agg = &apiKeyAggregate{}
+		s.recorder.keys[key] = agg
+	}
+	agg.Requests++
+	if status >= 400 {
+		agg.Errors++
+	}
+	if status == http.StatusTooManyRequests {
+		agg.RateLimited++
+	}
+}
+
+// latencyBucket returns the bucket a latency falls into
+func latencyBucket(latencyMs int64) int {
+	for i, bound := range latencyBucketBounds {
+		if latencyMs <= bound {
+			return i
+		}
+	}
+	return len(latencyBucketBounds)
+}
+
+// Flush adds the requests recorded since the last flush to the hourly rows.
+// On failure the requests are kept for the next flush. Returns the number of
+// requests flushed.
+func (s *RequestAnalyticsService) Flush() (int64, error) {
+	requests, users, keys := s.recorder.take()
+	if len(requests) == 0 && len(users) == 0 && len(keys) == 0 {
+		return 0, nil
+	}
+
+	var flushed int64
+	rows := make([]model.RequestStat, 0, len(requests))
+	for key, agg := range requests {
+		rows = append(rows, model.RequestStat{
+			TenantID:       key.TenantID,
+			Hour:           key.Hour,
+			Method:         key.Method,
+			Route:          key.Route,
+			Requests:       agg.Requests,
+			ClientErrors:   agg.ClientErrors,
+			ServerErrors:   agg.ServerErrors,
+			LatencyTotalMs: agg.LatencyTotalMs,
+			LatencyMaxMs:   agg.LatencyMaxMs,
+			LatencyLe10:    agg.Buckets[0],
+			LatencyLe50:    agg.Buckets[1],
+			LatencyLe100:   agg.Buckets[2],
+			LatencyLe250:   agg.Buckets[3],
+			LatencyLe500:   agg.Buckets[4],
+			LatencyLe1000:  agg.Buckets[5],
+			LatencyLe5000:  agg.Buckets[6],
+			LatencyOver:    agg.Buckets[7],
+		})
+		flushed += agg.Requests
+	}
+	userRows := make([]model.UserRequestStat, 0, len(users))
+	for key, agg := range users {
+		userRows = append(userRows, model.UserRequestStat{
+			TenantID: key.TenantID,
+			Hour:     key.Hour,
+			UserID:   key.UserID,
+			Requests: agg.Requests,
+			Errors:   agg.Errors,
+		})
+	}
+
+	keyRows := make([]model.APIKeyRequestStat, 0, len(keys))
+	for key, agg := range keys {
+		keyRows = append(keyRows, model.APIKeyRequestStat{
+			Hour:        key.Hour,
+			KeyID:       key.KeyID,
+			Method:      key.Method,
+			Route:       key.Route,
+			Requests:    agg.Requests,
+			Errors:      agg.Errors,
+			RateLimited: agg.RateLimited,
+		})
+	}
+
+	assignments := addExcluded("request_stats",
+		"requests", "client_errors", "server_errors", "latency_total_ms",
+		"latency_le10", "latency_le50", "latency_le100", "latency_le250",
+		"latency_le500", "latency_le1000", "latency_le5000", "latency_over")
+	assignments["latency_max_ms"] = gorm.Expr("CASE WHEN excluded.latency_max_ms > request_stats.latency_max_ms " +
+		"THEN excluded.latency_max_ms ELSE request_stats.latency_max_ms END")
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if len(rows) > 0 {
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "tenant_id"}, {Name: "hour"}, {Name: "method"}, {Name: "route"}},
+				DoUpdates: clause.Assignments(assignments),
+			}).CreateInBatches(&rows, 200).Error; err != nil {
+				return err
+			}
+		}
+		if len(userRows) > 0 {
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "tenant_id"}, {Name: "hour"}, {Name: "user_id"}},
+				DoUpdates: clause.Assignments(addExcluded("user_request_stats", "requests", "errors")),
+			}).CreateInBatches(&userRows, 200).Error; err != nil {
+				return err
+			}
+		}
+		if len(keyRows) > 0 {
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "hour"}, {Name: "key_id"}, {Name: "method"}, {Name: "route"}},
+				DoUpdates: clause.Assignments(addExcluded("api_key_request_stats", "requests", "errors", "rate_limited")),
+			}).CreateInBatches(&keyRows, 200).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.recorder.restore(requests, users, keys)
+		return 0, err
+	}
+	return flushed, nil
+}
+
+// addExcluded returns upsert assignments adding the conflicting row's counts
+// to the stored ones
+func addExcluded(table string, columns ...string) map[string]interface{} {
+	assignments := make(map[string]interface{}, len(columns))
+	for _, column := range columns {
+		assignments[column] = gorm.Expr(table + "." + column + " + excluded." + column)
+	}
+	return assignments
+}
+
+// Prune deletes hourly rows older than the given time. Returns the number of
+// rows deleted.
+func (s *RequestAnalyticsService) Prune(before time.Time) (int64, error) {
+	result := s.db.Where("hour < ?", before).Delete(&model.RequestStat{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	deleted := result.RowsAffected
+	for _, stat := range []interface{}{&model.UserRequestStat{}, &model.APIKeyRequestStat{}} {
+		result = s.db.Where("hour < ?", before).Delete(stat)
+		if result.Error != nil {
+			return deleted, result.Error
+		}
+		deleted += result.RowsAffected
+	}
+	return deleted, nil
+}
+
+// Start flushes the recorded requests every interval until the returned stop
+// func is called. Expired rows are pruned along the way.
+func (s *RequestAnalyticsService) Start(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if _, err := s.Flush(); err != nil {
+					logger.Default().Warn("Flushing request analytics failed: %v", err)
+				}
+				if s.retentionDays > 0 {
+					if _, err := s.Prune(time.Now().AddDate(0, 0, -s.retentionDays)); err != nil {
+						logger.Default().Warn("Pruning request analytics failed: %v", err)
+					}
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// GetRequestAnalytics returns the busiest routes with their error rates and
+// latency, an hourly timeline and the busiest users of the requested range.
+// Requests not flushed yet are not included.
+func (s *RequestAnalyticsService) GetRequestAnalytics(query RequestAnalyticsQuery) (*RequestAnalyticsResponse, error) {
+	startDate, endDate := requestAnalyticsRange(query.StartDate, query.EndDate)
+	limit := query.Limit
+	if limit <= 0 || limit > 100 {
+		limit = defaultRequestAnalyticsLimit
+	}
+	from, to := startDate, endDate.AddDate(0, 0, 1)
+
+	var stats []model.RequestStat
+	if err := s.db.Where("hour >= ? AND hour < ?", from, to).Order("hour ASC").Find(&stats).Error; err != nil {
+		return nil, err
+	}
+
+	resp := &RequestAnalyticsResponse{
+		StartDate: startDate.Format("2006-01-02"),
+		EndDate:   endDate.Format("2006-01-02"),
+		Routes:    []RouteAnalytics{},
+		Timeline:  []RequestAnalyticsPoint{},
+		Users:     []UserRequestAnalytics{},
+	}
+
+	type routeKey struct{ Method, Route string }
+	total := &requestAggregate{}
+	byRoute := make(map[routeKey]*requestAggregate)
+	var timeline []*requestAggregate
+	var hours []time.Time
+	for i := range stats {
+		agg := statAggregate(&stats[i])
+		total.merge(agg)
+
+		key := routeKey{Method: stats[i].Method, Route: stats[i].Route}
+		if byRoute[key] == nil {
+			byRoute[key] = &requestAggregate{}
+		}
+		byRoute[key].merge(agg)
+
+		if (query.Method != "" && query.Method != key.Method) || (query.Route != "" && query.Route != key.Route) {
+			continue
+		}
+		// Rows are ordered by hour, so each hour only extends the last point
+		if len(hours) == 0 || !hours[len(hours)-1].Equal(stats[i].Hour) {
+			hours = append(hours, stats[i].Hour)
+			timeline = append(timeline, &requestAggregate{})
+		}
+		timeline[len(timeline)-1].merge(agg)
+	}
+
+	resp.Requests = total.Requests
+	resp.Errors = total.ClientErrors + total.ServerErrors
+	resp.ErrorRate = errorRate(total)
+	resp.P95LatencyMs = p95Latency(total)
+
+	for key, agg := range byRoute {
+		route := RouteAnalytics{
+			Method:       key.Method,
+			Route:        key.Route,
+			Requests:     agg.Requests,
+			ClientErrors: agg.ClientErrors,
+			ServerErrors: agg.ServerErrors,
+			ErrorRate:    errorRate(agg),
+			P95LatencyMs: p95Latency(agg),
+			MaxLatencyMs: agg.LatencyMaxMs,
+		}
+		if agg.Requests > 0 {
+			route.AvgLatencyMs = float64(agg.LatencyTotalMs) / float64(agg.Requests)
+		}
+		resp.Routes = append(resp.Routes, route)
+	}
+	sort.Slice(resp.Routes, func(i, j int) bool {
+		if resp.Routes[i].Requests != resp.Routes[j].Requests {
+			return resp.Routes[i].Requests > resp.Routes[j].Requests
+		}
+		if resp.Routes[i].Route != resp.Routes[j].Route {
+			return resp.Routes[i].Route < resp.Routes[j].Route
+		}
+		return resp.Routes[i].Method < resp.Routes[j].Method
+	})
+	if len(resp.Routes) > limit {
+		resp.Routes = resp.Routes[:limit]
+	}
+
+	for i, agg := range timeline {
+		resp.Timeline = append(resp.Timeline, RequestAnalyticsPoint{
+			Hour:         hours[i],
+			Requests:     agg.Requests,
+			Errors:       agg.ClientErrors + agg.ServerErrors,
+			P95LatencyMs: p95Latency(agg),
+		})
+	}
+
+	if err := s.db.Model(&model.UserRequestStat{}).
+		Select("user_request_stats.user_id, users.username, SUM(user_request_stats.requests) as requests, SUM(user_request_stats.errors) as errors").
+		Joins("LEFT JOIN users ON users.id = user_request_stats.user_id").
+		Where("user_request_stats.hour >= ? AND user_request_stats.hour < ?", from, to).
+		Group("user_request_stats.user_id, users.username").
+		Order("requests DESC, user_request_stats.user_id ASC").
+		Limit(limit).
+		Scan(&resp.Users).Error; err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// APIKeyUsageQuery represents query parameters for the usage of an API key
+type APIKeyUsageQuery struct {
+	StartDate string `form:"start_date"` // Format: 2006-01-02, default 6 days before end
+	EndDate   string `form:"end_date"`   // Format: 2006-01-02, default today
+}
+
+// APIKeyRouteUsage summarizes the requests of an API key to one route
+type APIKeyRouteUsage struct {
+	Method      string  `json:"method"`
+	Route       string  `json:"route"`
+	Requests    int64   `json:"requests"`
+	Errors      int64   `json:"errors"`
+	ErrorRate   float64 `json:"error_rate"`
+	RateLimited int64   `json:"rate_limited"`
+}
+
+// APIKeyUsagePoint summarizes the requests of an API key in one hour
+type APIKeyUsagePoint struct {
+	Hour        time.Time `json:"hour"`
+	Requests    int64     `json:"requests"`
+	Errors      int64     `json:"errors"`
+	RateLimited int64     `json:"rate_limited"`
+}
+
+// APIKeyUsageResponse represents the usage of an API key in a date range
+type APIKeyUsageResponse struct {
+	KeyID       string             `json:"key_id"` // Fingerprint of the key
+	StartDate   string             `json:"start_date"`
+	EndDate     string             `json:"end_date"`
+	Requests    int64              `json:"requests"`
+	Errors      int64              `json:"errors"`
+	ErrorRate   float64            `json:"error_rate"`
+	RateLimited int64              `json:"rate_limited"`
+	Routes      []APIKeyRouteUsage `json:"routes"`   // Busiest first
+	Timeline    []APIKeyUsagePoint `json:"timeline"` // Hourly, hours without requests omitted
+}
+
+// GetAPIKeyUsage returns the request counts, error rate and rate limit hits
+// of one API key per route and per hour. Requests not flushed yet are not
+// included.
+func (s *RequestAnalyticsService) GetAPIKeyUsage(apiKey string, query APIKeyUsageQuery) (*APIKeyUsageResponse, error) {
+	startDate, endDate := requestAnalyticsRange(query.StartDate, query.EndDate)
+	keyID := APIKeyID(apiKey)
+
+	var stats []model.APIKeyRequestStat
+	if err := s.db.Where("key_id = ? AND hour >= ? AND hour < ?", keyID, startDate, endDate.AddDate(0, 0, 1)).
+		Order("hour ASC").Find(&stats).Error; err != nil {
+		return nil, err
+	}
+
+	resp := &APIKeyUsageResponse{
+		KeyID:     keyID,
+		StartDate: startDate.Format("2006-01-02"),
+		EndDate:   endDate.Format("2006-01-02"),
+		Routes:    []APIKeyRouteUsage{},
+		Timeline:  []APIKeyUsagePoint{},
+	}
+
+	type routeKey struct{ Method, Route string }
+	total := &apiKeyAggregate{}
+	byRoute := make(map[routeKey]*apiKeyAggregate)
+	for _, stat := range stats {
+		agg := &apiKeyAggregate{Requests: stat.Requests, Errors: stat.Errors, RateLimited: stat.RateLimited}
+		total.merge(agg)
+
+		key := routeKey{Method: stat.Method, Route: stat.Route}
+		if byRoute[key] == nil {
+			byRoute[key] = &apiKeyAggregate{}
+		}
+		byRoute[key].merge(agg)
+
+		// Rows are ordered by hour, so each hour only extends the last point
+		if n := len(resp.Timeline); n == 0 || !resp.Timeline[n-1].Hour.Equal(stat.Hour) {
+			resp.Timeline = append(resp.Timeline, APIKeyUsagePoint{Hour: stat.Hour})
+		}
+		point := &resp.Timeline[len(resp.Timeline)-1]
+		point.Requests += agg.Requests
+		point.Errors += agg.Errors
+		point.RateLimited += agg.RateLimited
+	}
+
+	resp.Requests = total.Requests
+	resp.Errors = total.Errors
+	resp.ErrorRate = keyErrorRate(total)
+	resp.RateLimited = total.RateLimited
+
+	for key, agg := range byRoute {
+		resp.Routes = append(resp.Routes, APIKeyRouteUsage{
+			Method:      key.Method,
+			Route:       key.Route,
+			Requests:    agg.Requests,
+			Errors:      agg.Errors,
+			ErrorRate:   keyErrorRate(agg),
+			RateLimited: agg.RateLimited,
+		})
+	}
+	sort.Slice(resp.Routes, func(i, j int) bool {
+		if resp.Routes[i].Requests != resp.Routes[j].Requests {
+			return resp.Routes[i].Requests > resp.Routes[j].Requests
+		}
+		if resp.Routes[i].Route != resp.Routes[j].Route {
+			return resp.Routes[i].Route < resp.Routes[j].Route
+		}
+		return resp.Routes[i].Method < resp.Routes[j].Method
+	})
+
+	return resp, nil
+}
+
+// requestAnalyticsRange parses a requested date range, defaulting to the
+// last 7 days and limited to maxRequestAnalyticsDays
+func requestAnalyticsRange(start, end string) (startDate, endDate time.Time) {
+	endDate = biztime.DayStart(time.Now())
+	if end != "" {
+		if t, err := biztime.ParseDate(end); err == nil {
+			endDate = t
+		}
+	}
+	startDate = endDate.AddDate(0, 0, -6)
+	if start != "" {
+		if t, err := biztime.ParseDate(start); err == nil {
+			startDate = t
+		}
+	}
+	if startDate.After(endDate) {
+		startDate, endDate = endDate, startDate
+	}
+	if endDate.Sub(startDate) > maxRequestAnalyticsDays*24*time.Hour {
+		startDate = endDate.AddDate(0, 0, -(maxRequestAnalyticsDays - 1))
+	}
+	return startDate, endDate
+}
+
+// statAggregate converts a stored hourly row back into an aggregate
+func statAggregate(stat *model.RequestStat) *requestAggregate {
+	return &requestAggregate{
+		Requests:       stat.Requests,
+		ClientErrors:   stat.ClientErrors,
+		ServerErrors:   stat.ServerErrors,
+		LatencyTotalMs: stat.LatencyTotalMs,
+		LatencyMaxMs:   stat.LatencyMaxMs,
+		Buckets: latencyBuckets{
+			stat.LatencyLe10, stat.LatencyLe50, stat.LatencyLe100, stat.LatencyLe250,
+			stat.LatencyLe500, stat.LatencyLe1000, stat.LatencyLe5000, stat.LatencyOver,
+		},
+	}
+}
+
+// errorRate returns the share of 4xx and 5xx responses
+func errorRate(agg *requestAggregate) float64 {
+	if agg.Requests == 0 {
+		return 0
+	}
+	return float64(agg.ClientErrors+agg.ServerErrors) / float64(agg.Requests)
+}
+
+// keyErrorRate returns the share of 4xx and 5xx responses of an API key
+func keyErrorRate(agg *apiKeyAggregate) float64 {
+	if agg.Requests == 0 {
+		return 0
+	}
+	return float64(agg.Errors) / float64(agg.Requests)
+}
+
+// p95Latency estimates the 95th percentile latency as the upper bound of the
+// bucket holding it. Beyond the last bound the slowest request is reported.
+func p95Latency(agg *requestAggregate) int64 {
+	var count int64
+	for _, n := range agg.Buckets {
+		count += n
+	}
+	if count == 0 {
+		return 0
+	}
+	rank := (count*95 + 99) / 100 // ceil(0.95 * count)
+	var seen int64
+	for i, n := range agg.Buckets {
+		seen += n
+		if seen >= rank {
+			if i < len(latencyBucketBounds) {
+				return latencyBucketBounds[i]
+			}
+			break
+		}
+	}
+	return agg.LatencyMaxMs
+}
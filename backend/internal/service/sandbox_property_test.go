@@ -0,0 +1,102 @@
+package service
+
+import (
+	"testing"
+
+	"scratch-lottery/internal/model"
+)
+
+// Sandbox tenants: the default tenant cannot be a sandbox, recharges of a
+// sandbox are paid at once with test points and left out of the recharge
+// total, and a reset wipes the tickets and ledger and gives every wallet the
+// starting balance again. Other tenants cannot be reset.
+func TestSandboxRechargeAndReset(t *testing.T) {
+	db := setupTenantTestDB(t)
+	if err := db.AutoMigrate(&model.PaymentOrder{}, &model.TicketAreaScratch{}, &model.ExchangeGift{},
+		&model.CardKeyReveal{}, &model.WalletBalanceSnapshot{}, &model.TicketHistory{}, &model.TicketTransfer{},
+		&model.StockReservation{}, &model.ScratchConfirmation{}, &model.PurchaseRequestRecord{},
+		&model.VoucherClaimFailure{}, &model.PrizeClaim{}, &model.CheckIn{}, &model.OnboardingStep{}, &model.Refund{}, &model.ScratchStreak{},
+		&model.CampaignReward{}, &model.Coupon{}, &model.Notification{}, &model.WalletReconciliation{}, &model.PaymentReconciliation{},
+		&model.UserBadgeCounter{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	sandbox := true
+	tenants := NewTenantService(db)
+	if _, err := tenants.UpdateTenant(1, 1, TenantRequest{Sandbox: &sandbox}); err != ErrDefaultSandbox {
+		t.Errorf("Expected ErrDefaultSandbox, got %v", err)
+	}
+	if _, err := tenants.UpdateTenant(1, 2, TenantRequest{Sandbox: &sandbox}); err != nil {
+		t.Fatalf("UpdateTenant failed: %v", err)
+	}
+
+	user := model.User{TenantID: 2, LinuxdoID: "sandbox", Username: "Sandbox"}
+	db.Create(&user)
+	db.Create(&model.Wallet{TenantID: 2, UserID: user.ID, Balance: 50})
+
+	walletService := NewWalletService(db)
+	adminService := NewAdminService(db, walletService)
+	payments := NewPaymentService(db, adminService, walletService, nil).ForTenant(2)
+	recharge, err := payments.CreateRechargeOrder(user.ID, RechargeRequest{Amount: 10})
+	if err != nil {
+		t.Fatalf("CreateRechargeOrder failed: %v", err)
+	}
+	if !recharge.Sandbox || recharge.PaymentURL != "" {
+		t.Errorf("Expected a simulated recharge, got %+v", recharge)
+	}
+	order, err := payments.GetOrderByNo(recharge.OrderNo)
+	if err != nil || order.Status != "paid" || order.PaymentType != model.PaymentTypeSandbox {
+		t.Errorf("Expected the order paid in the sandbox, got %+v (err %v)", order, err)
+	}
+	wallet, err := walletService.ForTenant(2).GetWalletByUserID(user.ID)
+	if err != nil || wallet.Balance != 150 || !wallet.Sandbox {
+		t.Errorf("Expected a sandbox wallet of 150 points, got %+v (err %v)", wallet, err)
+	}
+	metrics, err := adminService.getCoreMetrics()
+	if err != nil || metrics.TotalRecharge.Amount != 0 {
+		t.Errorf("Expected sandbox recharges left out of the total, got %+v (err %v)", metrics, err)
+	}
+
+	lotteryService := NewLotteryService(db, testEncryptionKey).ForTenant(2)
+	lotteryType, err := lotteryService.CreateLotteryType(CreateLotteryTypeRequest{
+		Name:        "Sandbox",
+		Price:       10,
+		MaxPrize:    100,
+		PrizeLevels: []PrizeLevelInput{{Level: 1, Name: "一等奖", PrizeAmount: 20, Quantity: 1}},
+	})
+	if err != nil {
+		t.Fatalf("CreateLotteryType failed: %v", err)
+	}
+	if _, err := lotteryService.CreatePrizePool(CreatePrizePoolRequest{LotteryTypeID: lotteryType.ID, TotalTickets: 10}); err != nil {
+		t.Fatalf("CreatePrizePool failed: %v", err)
+	}
+	purchases := NewPurchaseService(db, NewLotteryService(db, testEncryptionKey), walletService, nil, nil, nil).ForTenant(2)
+	if _, err := purchases.PurchaseTickets(user.ID, PurchaseRequest{LotteryTypeID: lotteryType.ID, Quantity: 3}); err != nil {
+		t.Fatalf("PurchaseTickets failed: %v", err)
+	}
+
+	sandboxService := NewSandboxService(db, 1000)
+	if _, err := sandboxService.Reset(1, 1); err != ErrNotSandboxTenant {
+		t.Errorf("Expected ErrNotSandboxTenant, got %v", err)
+	}
+	reset, err := sandboxService.ResetAll()
+	if err != nil || reset != 1 {
+		t.Fatalf("Expected one sandbox reset, got %d (err %v)", reset, err)
+	}
+
+	var tickets, orders int64
+	db.Model(&model.Ticket{}).Where("tenant_id = ?", 2).Count(&tickets)
+	db.Model(&model.PaymentOrder{}).Where("user_id = ?", user.ID).Count(&orders)
+	if tickets != 0 || orders != 0 {
+		t.Errorf("Expected the sandbox activity wiped, got %d tickets and %d orders", tickets, orders)
+	}
+	var pool model.PrizePool
+	db.Where("lottery_type_id = ?", lotteryType.ID).First(&pool)
+	if pool.SoldTickets != 0 || pool.Status != model.PrizePoolStatusActive {
+		t.Errorf("Expected the prize pool restocked, got %+v", pool)
+	}
+	wallet, err = walletService.ForTenant(2).GetWalletByUserID(user.ID)
+	if err != nil || wallet.Balance != 1000 || len(wallet.Transactions) != 1 {
+		t.Errorf("Expected the starting balance as the only transaction, got %+v (err %v)", wallet, err)
+	}
+}
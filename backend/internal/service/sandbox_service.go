@@ -0,0 +1,292 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+	"scratch-lottery/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// sandboxResetDescription describes the test points granted by a reset
+const sandboxResetDescription = "沙盒测试币（重置）"
+
+var (
+	ErrNotSandboxTenant = errors.New("tenant is not a sandbox")
+)
+
+// SandboxResetResponse counts what a sandbox reset removed and restored
+type SandboxResetResponse struct {
+	TenantID        uint      `json:"tenant_id"`
+	Tickets         int64     `json:"tickets"`
+	Transactions    int64     `json:"transactions"`
+	PaymentOrders   int64     `json:"payment_orders"`
+	ExchangeRecords int64     `json:"exchange_records"`
+	Wallets         int64     `json:"wallets"`
+	StartingBalance int       `json:"starting_balance"`
+	ResetAt         time.Time `json:"reset_at"`
+}
+
+// SandboxService resets the data of sandbox tenants, which sales demos play
+// in with test points. Recharges of a sandbox are simulated, and its activity
+// is left out of deployment-wide statistics, webhooks and alerts.
+type SandboxService struct {
+	db              *gorm.DB
+	startingBalance int
+}
+
+// NewSandboxService creates a new sandbox service. A reset gives every
+// wallet startingBalance test points.
+func NewSandboxService(db *gorm.DB, startingBalance int) *SandboxService {
+	return &SandboxService{db: db, startingBalance: startingBalance}
+}
+
+// Start resets every sandbox tenant every interval until the returned stop
+// func is called
+func (s *SandboxService) Start(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				reset, err := s.ResetAll()
+				if err != nil {
+					logger.Default().Warn("Resetting sandbox tenants failed: %v", err)
+				} else if reset > 0 {
+					logger.Default().Info("Reset %d sandbox tenants", reset)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// ResetAll resets every sandbox tenant and returns how many were reset
+func (s *SandboxService) ResetAll() (int, error) {
+	var tenantIDs []uint
+	if err := sandboxTenants(s.db).Pluck("id", &tenantIDs).Error; err != nil {
+		return 0, err
+	}
+	for i, tenantID := range tenantIDs {
+		if _, err := s.Reset(0, tenantID); err != nil {
+			return i, err
+		}
+	}
+	return len(tenantIDs), nil
+}
+
+// Reset wipes the activity of a sandbox tenant: tickets, ledgers, orders,
+// exchanges, rewards and notifications. Prize pools and card keys are
+// restocked and every wallet starts over with the starting balance. Users,
+// lottery types, products and settings are kept. A reset made by an admin
+// (adminID != 0) is logged.
+func (s *SandboxService) Reset(adminID, tenantID uint) (*SandboxResetResponse, error) {
+	sandbox, err := isSandboxTenant(s.db, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if !sandbox {
+		return nil, ErrNotSandboxTenant
+	}
+
+	var result SandboxResetResponse
+	err = repository.Transaction(s.db, func(tx *gorm.DB) error {
+		result = SandboxResetResponse{TenantID: tenantID, StartingBalance: s.startingBalance, ResetAt: time.Now()}
+		inTenant := func(model interface{}) *gorm.DB {
+			return tx.Model(model).Select("id").Where("tenant_id = ?", tenantID)
+		}
+		users := inTenant(&model.User{})
+		tickets := inTenant(&model.Ticket{})
+		wallets := inTenant(&model.Wallet{})
+
+		// Records without a tenant of their own go with their user, ticket or wallet
+		deleted := tx.Unscoped().Where("user_id IN (?)", users).Delete(&model.PaymentOrder{})
+		if deleted.Error != nil {
+			return deleted.Error
+		}
+		result.PaymentOrders = deleted.RowsAffected
+		for _, related := range []struct {
+			query string
+			of    *gorm.DB
+			model interface{}
+		}{
+			{"ticket_id IN (?)", tickets, &model.TicketAreaScratch{}},
+			{"giver_id IN (?)", users, &model.ExchangeGift{}},
+			{"user_id IN (?)", users, &model.CardKeyReveal{}},
+			{"wallet_id IN (?)", wallets, &model.WalletBalanceSnapshot{}},
+		} {
+			if err := tx.Unscoped().Where(related.query, related.of).Delete(related.model).Error; err != nil {
+				return err
+			}
+		}
+
+		// Card keys handed out by the sandbox return to stock
+		products := inTenant(&model.Product{})
+		if err := tx.Model(&model.CardKey{}).
+			Where("product_id IN (?) AND status <> ?", products, model.CardKeyStatusAvailable).
+			Updates(map[string]interface{}{"status": model.CardKeyStatusAvailable, "redeemed_by": 0, "redeemed_at": nil}).Error; err != nil {
+			return err
+		}
+
+		if deleted = tx.Unscoped().Where("tenant_id = ?", tenantID).Delete(&model.Ticket{}); deleted.Error != nil {
+			return deleted.Error
+		}
+		result.Tickets = deleted.RowsAffected
+		if deleted = tx.Unscoped().Where("tenant_id = ?", tenantID).Delete(&model.Transaction{}); deleted.Error != nil {
+			return deleted.Error
+		}
+		result.Transactions = deleted.RowsAffected
+		if deleted = tx.Unscoped().Where("tenant_id = ?", tenantID).Delete(&model.ExchangeRecord{}); deleted.Error != nil {
+			return deleted.Error
+		}
+		result.ExchangeRecords = deleted.RowsAffected
+		for _, activity := range []interface{}{
+			&model.TicketHistory{}, &model.TicketEvent{}, &model.TicketTransfer{}, &model.StockReservation{},
+			&model.ScratchConfirmation{}, &model.PurchaseRequestRecord{}, &model.VoucherClaimFailure{},
+			&model.PrizeClaim{}, &model.CheckIn{}, &model.OnboardingStep{}, &model.Refund{}, &model.ScratchStreak{}, &model.CampaignReward{},
+			&model.Coupon{}, &model.Notification{}, &model.WalletReconciliation{}, &model.PaymentReconciliation{},
+		} {
+			if err := tx.Unscoped().Where("tenant_id = ?", tenantID).Delete(activity).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := restockSandbox(tx, tenantID); err != nil {
+			return err
+		}
+
+		// Every wallet starts over with the starting balance of test points and no bonus credits
+		var walletRows []model.Wallet
+		if err := tx.Where("tenant_id = ?", tenantID).Find(&walletRows).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Wallet{}).Where("tenant_id = ?", tenantID).
+			Updates(map[string]interface{}{"balance": s.startingBalance, "bonus_balance": 0, "frozen_at": nil}).Error; err != nil {
+			return err
+		}
+		for _, wallet := range walletRows {
+			grant := model.Transaction{
+				TenantID:    tenantID,
+				WalletID:    wallet.ID,
+				Type:        model.TransactionTypeInitial,
+				Amount:      s.startingBalance,
+				Description: sandboxResetDescription,
+			}
+			if err := tx.Create(&grant).Error; err != nil {
+				return err
+			}
+		}
+		result.Wallets = int64(len(walletRows))
+
+		if err := tx.Model(&model.UserBadgeCounter{}).Where("tenant_id = ?", tenantID).
+			Updates(map[string]interface{}{"unscratched_tickets": 0, "unread_notifications": 0, "pending_orders": 0}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Jackpot{}).Where("tenant_id = ?", tenantID).
+			Updates(map[string]interface{}{"amount": 0, "last_winner_id": 0, "last_won_amount": 0, "last_won_at": nil}).Error; err != nil {
+			return err
+		}
+
+		if adminID == 0 {
+			return nil
+		}
+		adminLog := model.AdminLog{
+			TenantID:   tenantID,
+			AdminID:    adminID,
+			Action:     "reset_sandbox",
+			TargetType: "tenant",
+			TargetID:   tenantID,
+		}
+		return repository.ScopeTenant(tx, tenantID).Create(&adminLog).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// restockSandbox refills the prize pools and products of a sandbox tenant.
+// The newest open pool of each lottery type is reopened with all its tickets
+// and prizes, older open pools are closed, and product stock is recounted
+// from the card keys.
+func restockSandbox(tx *gorm.DB, tenantID uint) error {
+	lotteryTypes := tx.Model(&model.LotteryType{}).Select("id").Where("tenant_id = ?", tenantID)
+	if err := tx.Model(&model.PrizeLevel{}).Where("lottery_type_id IN (?)", lotteryTypes).
+		Update("remaining", gorm.Expr("quantity")).Error; err != nil {
+		return err
+	}
+
+	var pools []model.PrizePool
+	if err := tx.Where("lottery_type_id IN (?) AND status <> ?", lotteryTypes, model.PrizePoolStatusClosed).
+		Order("id DESC").Find(&pools).Error; err != nil {
+		return err
+	}
+	reopened := make(map[uint]bool)
+	for _, pool := range pools {
+		updates := map[string]interface{}{"status": model.PrizePoolStatusClosed}
+		if !reopened[pool.LotteryTypeID] {
+			reopened[pool.LotteryTypeID] = true
+			updates = map[string]interface{}{"status": model.PrizePoolStatusActive, "sold_tickets": 0, "claimed_prizes": 0}
+		}
+		if err := tx.Model(&model.PrizePool{}).Where("id = ?", pool.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Model(&model.LotteryType{}).
+		Where("tenant_id = ? AND status = ?", tenantID, model.LotteryTypeStatusSoldOut).
+		Update("status", model.LotteryTypeStatusAvailable).Error; err != nil {
+		return err
+	}
+
+	var products []model.Product
+	if err := tx.Where("tenant_id = ?", tenantID).Find(&products).Error; err != nil {
+		return err
+	}
+	for _, product := range products {
+		var count int64
+		if err := tx.Model(&model.CardKey{}).
+			Where("product_id = ? AND status = ?", product.ID, model.CardKeyStatusAvailable).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		updates := map[string]interface{}{"stock": count}
+		if count > 0 && product.Status == model.ProductStatusSoldOut {
+			updates["status"] = model.ProductStatusAvailable
+		}
+		if err := tx.Model(&product).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sandboxTenants selects the sandbox tenants, for use as a subquery that
+// keeps their activity out of deployment-wide statistics
+func sandboxTenants(db *gorm.DB) *gorm.DB {
+	return db.Model(&model.Tenant{}).Select("id").Where("sandbox = ?", true)
+}
+
+// isSandboxTenant reports whether a tenant is a sandbox
+func isSandboxTenant(db *gorm.DB, tenantID uint) (bool, error) {
+	var count int64
+	if err := db.Model(&model.Tenant{}).Where("id = ? AND sandbox = ?", tenantID, true).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// inSandbox reports whether a session is scoped to a sandbox tenant
+func inSandbox(db *gorm.DB) (bool, error) {
+	tenantID, ok := repository.TenantFromContext(db.Statement.Context)
+	if !ok {
+		return false, nil
+	}
+	return isSandboxTenant(db, tenantID)
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"scratch-lottery/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// scratchConfirmWindow is how long a scratch confirmation token is valid
+const scratchConfirmWindow = 2 * time.Minute
+
+var (
+	ErrScratchConfirmationRequired = errors.New("scratch confirmation required")
+	ErrScratchConfirmationInvalid  = errors.New("scratch confirmation invalid or expired")
+)
+
+// ScratchConfirmationResponse is the token the scratch request must echo
+type ScratchConfirmationResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// needsScratchConfirmation reports whether scratching the ticket needs a
+// confirmation token. Tickets already being scratched area by area were
+// confirmed with their first area.
+func needsScratchConfirmation(ticket *model.Ticket) bool {
+	return ticket.LotteryType.ConfirmScratch && ticket.Status == model.TicketStatusUnscratched
+}
+
+// issueScratchConfirmation issues a new confirmation token for a ticket,
+// replacing any earlier one
+func issueScratchConfirmation(db *gorm.DB, ticket *model.Ticket) (*ScratchConfirmationResponse, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	confirmation := model.ScratchConfirmation{
+		TicketID:  ticket.ID,
+		UserID:    ticket.UserID,
+		Token:     hex.EncodeToString(buf),
+		ExpiresAt: time.Now().Add(scratchConfirmWindow),
+	}
+	if err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "ticket_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"user_id", "token", "expires_at"}),
+	}).Create(&confirmation).Error; err != nil {
+		return nil, err
+	}
+	return &ScratchConfirmationResponse{Token: confirmation.Token, ExpiresAt: confirmation.ExpiresAt}, nil
+}
+
+// consumeScratchConfirmation checks the token echoed for a ticket that needs
+// confirmation and uses it up. Tickets that need none pass with any token.
+func consumeScratchConfirmation(db *gorm.DB, ticket *model.Ticket, token string) error {
+	if !needsScratchConfirmation(ticket) {
+		return nil
+	}
+	if token == "" {
+		return ErrScratchConfirmationRequired
+	}
+
+	var confirmation model.ScratchConfirmation
+	err := db.Where("ticket_id = ? AND user_id = ? AND expires_at > ?", ticket.ID, ticket.UserID, time.Now()).
+		First(&confirmation).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrScratchConfirmationInvalid
+	}
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare([]byte(confirmation.Token), []byte(token)) != 1 {
+		return ErrScratchConfirmationInvalid
+	}
+
+	// Guarded on the token, so a concurrent request cannot use it twice
+	result := db.Where("id = ? AND token = ?", confirmation.ID, confirmation.Token).Delete(&model.ScratchConfirmation{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrScratchConfirmationInvalid
+	}
+	return nil
+}
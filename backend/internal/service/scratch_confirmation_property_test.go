@@ -0,0 +1,113 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
+)
+
+// setupScratchConfirmationTest creates an unscratched ticket with three
+// areas, of a lottery type that requires confirmed scratches when confirm is set
+func setupScratchConfirmationTest(t *testing.T, confirm bool) (*ScratchService, *IncrementalScratchService, uint, uint) {
+	incremental, _, userID, ticketID := setupIncrementalScratchTest(t, 3, 100)
+	db := incremental.db
+	if err := db.Model(&model.LotteryType{}).Where("1 = 1").Update("confirm_scratch", confirm).Error; err != nil {
+		t.Fatalf("Failed to update lottery type: %v", err)
+	}
+	return NewScratchService(db, NewLotteryService(db, testEncryptionKey), NewWalletService(db), nil, nil), incremental, userID, ticketID
+}
+
+// Scratch confirmation: a confirmed lottery type scratches only with the
+// latest unexpired token of the ticket detail, and each token works once.
+func TestScratchConfirmationRequired(t *testing.T) {
+	scratches, _, userID, ticketID := setupScratchConfirmationTest(t, true)
+
+	if _, err := scratches.ScratchTicket(userID, ticketID, ""); err != ErrScratchConfirmationRequired {
+		t.Fatalf("Expected ErrScratchConfirmationRequired, got %v", err)
+	}
+	if _, err := scratches.ScratchTicket(userID, ticketID, "guessed"); err != ErrScratchConfirmationInvalid {
+		t.Fatalf("Expected ErrScratchConfirmationInvalid before any detail, got %v", err)
+	}
+
+	first, err := scratches.GetTicketDetail(userID, ticketID)
+	if err != nil || first.Confirmation == nil {
+		t.Fatalf("Expected a confirmation with the detail, got %+v (err %v)", first, err)
+	}
+	if first.LotteryType == nil || !first.LotteryType.ConfirmScratch {
+		t.Errorf("Expected the lottery type to show confirm_scratch")
+	}
+
+	// Viewing the detail again replaces the token
+	second, err := scratches.GetTicketDetail(userID, ticketID)
+	if err != nil || second.Confirmation == nil || second.Confirmation.Token == first.Confirmation.Token {
+		t.Fatalf("Expected a new token, got %+v (err %v)", second.Confirmation, err)
+	}
+	if _, err := scratches.ScratchTicket(userID, ticketID, first.Confirmation.Token); err != ErrScratchConfirmationInvalid {
+		t.Errorf("Expected the replaced token to be refused, got %v", err)
+	}
+
+	// An expired token is refused
+	scratches.db.Model(&model.ScratchConfirmation{}).Where("ticket_id = ?", ticketID).
+		Update("expires_at", time.Now().Add(-time.Second))
+	if _, err := scratches.ScratchTicket(userID, ticketID, second.Confirmation.Token); err != ErrScratchConfirmationInvalid {
+		t.Errorf("Expected the expired token to be refused, got %v", err)
+	}
+
+	third, err := scratches.GetTicketDetail(userID, ticketID)
+	if err != nil {
+		t.Fatalf("GetTicketDetail failed: %v", err)
+	}
+	resp, err := scratches.ScratchTicket(userID, ticketID, third.Confirmation.Token)
+	if err != nil {
+		t.Fatalf("Expected the confirmed scratch to succeed, got %v", err)
+	}
+	if resp.PrizeAmount != 100 {
+		t.Errorf("Expected a prize of 100, got %d", resp.PrizeAmount)
+	}
+
+	var left int64
+	scratches.db.Model(&model.ScratchConfirmation{}).Count(&left)
+	if left != 0 {
+		t.Errorf("Expected the token to be used up, %d left", left)
+	}
+	detail, _ := scratches.GetTicketDetail(userID, ticketID)
+	if detail.Confirmation != nil {
+		t.Errorf("Expected no confirmation for a scratched ticket")
+	}
+}
+
+// Scratch confirmation: incremental scratching confirms with the first area,
+// and lottery types without the setting scratch without a token.
+func TestScratchConfirmationIncremental(t *testing.T) {
+	scratches, incremental, userID, ticketID := setupScratchConfirmationTest(t, true)
+
+	if _, err := incremental.ScratchArea(userID, ticketID, 0, ""); err != ErrScratchConfirmationRequired {
+		t.Fatalf("Expected ErrScratchConfirmationRequired, got %v", err)
+	}
+	if _, err := incremental.Settle(userID, ticketID, ""); err != ErrScratchConfirmationRequired {
+		t.Fatalf("Expected settling an unscratched ticket to need confirmation, got %v", err)
+	}
+
+	detail, err := scratches.GetTicketDetail(userID, ticketID)
+	if err != nil {
+		t.Fatalf("GetTicketDetail failed: %v", err)
+	}
+	if _, err := incremental.ScratchArea(userID, ticketID, 0, detail.Confirmation.Token); err != nil {
+		t.Fatalf("Expected the confirmed first area to be revealed, got %v", err)
+	}
+	for i := 1; i < 3; i++ {
+		if _, err := incremental.ScratchArea(userID, ticketID, i, ""); err != nil {
+			t.Fatalf("Expected area %d to be revealed without a token, got %v", i, err)
+		}
+	}
+
+	plain, _, plainUserID, plainTicketID := setupScratchConfirmationTest(t, false)
+	detail, err = plain.GetTicketDetail(plainUserID, plainTicketID)
+	if err != nil || detail.Confirmation != nil {
+		t.Fatalf("Expected no confirmation without the setting, got %+v (err %v)", detail, err)
+	}
+	if _, err := plain.ScratchTicket(plainUserID, plainTicketID, ""); err != nil {
+		t.Errorf("Expected the scratch to succeed without the setting, got %v", err)
+	}
+}
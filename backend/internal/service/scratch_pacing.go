@@ -0,0 +1,115 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"scratch-lottery/internal/model"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrScratchTooSoon = errors.New("ticket scratched too soon")
+)
+
+// ScratchTooSoonError reports when a paced ticket may be scratched. It
+// matches ErrScratchTooSoon with errors.Is.
+type ScratchTooSoonError struct {
+	ScratchableAt time.Time
+}
+
+func (e *ScratchTooSoonError) Error() string {
+	return fmt.Sprintf("ticket scratched too soon, scratchable at %s", e.ScratchableAt.Format(time.RFC3339))
+}
+
+// Is makes the error match ErrScratchTooSoon
+func (e *ScratchTooSoonError) Is(target error) bool {
+	return target == ErrScratchTooSoon
+}
+
+// RetryAfter returns how long until the ticket may be scratched, in whole seconds rounded up
+func (e *ScratchTooSoonError) RetryAfter() int {
+	return int((time.Until(e.ScratchableAt) + time.Second - 1) / time.Second)
+}
+
+// scratchableAt returns when a user may start scratching a ticket under the
+// pacing of its lottery type: a minimum delay after the purchase and a
+// minimum interval after the user's previous scratch of the lottery type. It
+// returns nil when the ticket may be scratched at now, including tickets
+// already being scratched area by area.
+func scratchableAt(db *gorm.DB, ticket *model.Ticket, now time.Time) (*time.Time, error) {
+	lt := &ticket.LotteryType
+	if ticket.Status != model.TicketStatusUnscratched || (lt.ScratchDelaySeconds <= 0 && lt.ScratchIntervalSeconds <= 0) {
+		return nil, nil
+	}
+
+	var at time.Time
+	if lt.ScratchDelaySeconds > 0 {
+		at = ticket.PurchasedAt.Add(time.Duration(lt.ScratchDelaySeconds) * time.Second)
+	}
+
+	if lt.ScratchIntervalSeconds > 0 {
+		last, err := lastScratchAt(db, ticket.UserID, ticket.LotteryTypeID)
+		if err != nil {
+			return nil, err
+		}
+		if last != nil {
+			if next := last.Add(time.Duration(lt.ScratchIntervalSeconds) * time.Second); next.After(at) {
+				at = next
+			}
+		}
+	}
+
+	if !at.After(now) {
+		return nil, nil
+	}
+	return &at, nil
+}
+
+// checkScratchPacing rejects starting to scratch a ticket before its lottery
+// type's pacing allows it
+func checkScratchPacing(db *gorm.DB, ticket *model.Ticket, now time.Time) error {
+	at, err := scratchableAt(db, ticket, now)
+	if err != nil {
+		return err
+	}
+	if at != nil {
+		return &ScratchTooSoonError{ScratchableAt: *at}
+	}
+	return nil
+}
+
+// lastScratchAt returns when the user last started scratching a ticket of a
+// lottery type: the latest scratched ticket or first revealed area
+func lastScratchAt(db *gorm.DB, userID, lotteryTypeID uint) (*time.Time, error) {
+	var tickets []model.Ticket
+	if err := db.Select("scratched_at").
+		Where("user_id = ? AND lottery_type_id = ? AND scratched_at IS NOT NULL", userID, lotteryTypeID).
+		Order("scratched_at DESC").
+		Limit(1).
+		Find(&tickets).Error; err != nil {
+		return nil, err
+	}
+
+	var areas []model.TicketAreaScratch
+	if err := db.Model(&model.TicketAreaScratch{}).
+		Select("ticket_area_scratches.scratched_at").
+		Joins("JOIN tickets ON tickets.id = ticket_area_scratches.ticket_id").
+		Where("tickets.user_id = ? AND tickets.lottery_type_id = ? AND ticket_area_scratches.seq = 1", userID, lotteryTypeID).
+		Order("ticket_area_scratches.scratched_at DESC").
+		Limit(1).
+		Find(&areas).Error; err != nil {
+		return nil, err
+	}
+
+	var last *time.Time
+	if len(tickets) > 0 {
+		last = tickets[0].ScratchedAt
+	}
+	if len(areas) > 0 && (last == nil || areas[0].ScratchedAt.After(*last)) {
+		last = &areas[0].ScratchedAt
+	}
+	return last, nil
+}
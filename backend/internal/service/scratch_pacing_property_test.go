@@ -0,0 +1,86 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
+)
+
+// Scratch pacing: a ticket is refused until the delay after its purchase and
+// the interval after the user's previous scratch have passed, the error and
+// the ticket detail tell when it may be scratched, and unpaced lottery types
+// scratch at once.
+func TestScratchPacing(t *testing.T) {
+	db, scratches, _, userID, ticketIDs := setupLargeWinTest(t, []int{0, 10, 20})
+	if err := db.AutoMigrate(&model.TicketAreaScratch{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	if err := db.Model(&model.LotteryType{}).Where("1 = 1").
+		Updates(map[string]interface{}{"scratch_delay_seconds": 60, "scratch_interval_seconds": 300}).Error; err != nil {
+		t.Fatalf("Failed to update lottery type: %v", err)
+	}
+
+	_, err := scratches.ScratchTicket(userID, ticketIDs[0], "")
+	var tooSoon *ScratchTooSoonError
+	if !errors.Is(err, ErrScratchTooSoon) || !errors.As(err, &tooSoon) {
+		t.Fatalf("Expected ErrScratchTooSoon right after the purchase, got %v", err)
+	}
+	if retry := tooSoon.RetryAfter(); retry < 55 || retry > 60 {
+		t.Errorf("Expected to retry in about 60s, got %ds", retry)
+	}
+	detail, err := scratches.GetTicketDetail(userID, ticketIDs[0])
+	if err != nil || detail.ScratchableAt == nil || !detail.ScratchableAt.Equal(tooSoon.ScratchableAt) {
+		t.Fatalf("Expected the detail to show %v, got %+v (err %v)", tooSoon.ScratchableAt, detail, err)
+	}
+
+	// Once the delay has passed the first scratch goes through
+	db.Model(&model.Ticket{}).Where("1 = 1").Update("purchased_at", time.Now().Add(-2*time.Minute))
+	if _, err := scratches.ScratchTicket(userID, ticketIDs[0], ""); err != nil {
+		t.Fatalf("Expected the scratch after the delay to succeed, got %v", err)
+	}
+
+	// The next scratch waits for the interval after the previous one
+	_, err = scratches.ScratchTicket(userID, ticketIDs[1], "")
+	if !errors.As(err, &tooSoon) {
+		t.Fatalf("Expected ErrScratchTooSoon within the interval, got %v", err)
+	}
+	if retry := tooSoon.RetryAfter(); retry < 295 || retry > 300 {
+		t.Errorf("Expected to retry in about 300s, got %ds", retry)
+	}
+	db.Model(&model.Ticket{}).Where("id = ?", ticketIDs[0]).Update("scratched_at", time.Now().Add(-10*time.Minute))
+	if _, err := scratches.ScratchTicket(userID, ticketIDs[1], ""); err != nil {
+		t.Fatalf("Expected the scratch after the interval to succeed, got %v", err)
+	}
+
+	// Without pacing the last ticket scratches right away
+	db.Model(&model.LotteryType{}).Where("1 = 1").
+		Updates(map[string]interface{}{"scratch_delay_seconds": 0, "scratch_interval_seconds": 0})
+	detail, err = scratches.GetTicketDetail(userID, ticketIDs[2])
+	if err != nil || detail.ScratchableAt != nil {
+		t.Fatalf("Expected no countdown without pacing, got %+v (err %v)", detail, err)
+	}
+	if _, err := scratches.ScratchTicket(userID, ticketIDs[2], ""); err != nil {
+		t.Errorf("Expected the unpaced scratch to succeed, got %v", err)
+	}
+}
+
+// Scratch pacing: incremental scratching is paced on its first area only
+func TestScratchPacingIncremental(t *testing.T) {
+	incremental, _, userID, ticketID := setupIncrementalScratchTest(t, 3, 100)
+	db := incremental.db
+	db.Model(&model.LotteryType{}).Where("1 = 1").Update("scratch_delay_seconds", 60)
+
+	if _, err := incremental.ScratchArea(userID, ticketID, 0, ""); !errors.Is(err, ErrScratchTooSoon) {
+		t.Fatalf("Expected ErrScratchTooSoon for the first area, got %v", err)
+	}
+
+	db.Model(&model.Ticket{}).Where("id = ?", ticketID).Update("purchased_at", time.Now().Add(-2*time.Minute))
+	db.Model(&model.LotteryType{}).Where("1 = 1").Update("scratch_interval_seconds", 300)
+	for i := 0; i < 3; i++ {
+		if _, err := incremental.ScratchArea(userID, ticketID, i, ""); err != nil {
+			t.Fatalf("Expected area %d to be revealed, got %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+	"gorm.io/gorm"
+)
+
+// segmentTestUser describes the spending of a generated user: a purchase
+// DaysAgo days ago, or none when Spend is zero
+type segmentTestUser struct {
+	Spend   int
+	DaysAgo int
+}
+
+func setupSegmentTest(t *testing.T, users []segmentTestUser) (*gorm.DB, *SegmentService, []uint) {
+	db := setupTenantTestDB(t)
+	if err := db.AutoMigrate(&model.Segment{}, &model.UserSegment{}, &model.Notification{}, &model.UserBadgeCounter{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	now := time.Now()
+	userIDs := make([]uint, len(users))
+	for i, u := range users {
+		user := model.User{LinuxdoID: fmt.Sprintf("segment_user_%d", i), Username: fmt.Sprintf("User %d", i), Role: "user"}
+		db.Create(&user)
+		wallet := model.Wallet{UserID: user.ID}
+		db.Create(&wallet)
+		if u.Spend > 0 {
+			db.Create(&model.Transaction{
+				WalletID:  wallet.ID,
+				Type:      model.TransactionTypePurchase,
+				Amount:    -u.Spend,
+				CreatedAt: now.AddDate(0, 0, -u.DaysAgo),
+			})
+		}
+		userIDs[i] = user.ID
+	}
+	// Admins are never segment members
+	admin := model.User{LinuxdoID: "segment_admin", Username: "Admin", Role: "admin"}
+	db.Create(&admin)
+	db.Create(&model.Wallet{UserID: admin.ID})
+
+	return db, NewSegmentService(db, NewNotificationService(db)), userIDs
+}
+
+// User segments: "high spenders inactive for 30 days" contains exactly the
+// users who spent at least the threshold within 90 days but nothing in the
+// last 30, and re-evaluation follows changes in behaviour.
+func TestSegmentEvaluation(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("members match the rules", prop.ForAll(
+		func(spends []int, daysAgo []int, threshold int) bool {
+			users := make([]segmentTestUser, len(spends))
+			for i := range spends {
+				users[i] = segmentTestUser{Spend: spends[i], DaysAgo: daysAgo[i]}
+			}
+			db, segments, userIDs := setupSegmentTest(t, users)
+
+			segment, err := segments.ForTenant(1).CreateSegment(SegmentRequest{
+				Name: "Lapsed high spenders",
+				Rules: SegmentRules{
+					MinSpend:        &threshold,
+					SpendWindowDays: 90,
+					InactiveDays:    30,
+				},
+			})
+			if err != nil {
+				t.Logf("CreateSegment failed: %v", err)
+				return false
+			}
+
+			expected := map[uint]bool{}
+			for i, u := range users {
+				if u.Spend >= threshold && u.Spend > 0 && u.DaysAgo < 90 && u.DaysAgo >= 30 {
+					expected[userIDs[i]] = true
+				}
+			}
+			members, err := segments.MemberIDs(segment.ID)
+			if err != nil || len(members) != len(expected) || segment.MemberCount != len(expected) || !segment.Enabled {
+				t.Logf("Expected %d members, got %v / %+v (err %v)", len(expected), members, segment, err)
+				return false
+			}
+			for _, userID := range members {
+				if !expected[userID] {
+					t.Logf("User %d should not be a member", userID)
+					return false
+				}
+			}
+
+			// A member who spends again today leaves the segment on the next evaluation
+			if len(members) > 0 {
+				var wallet model.Wallet
+				db.Where("user_id = ?", members[0]).First(&wallet)
+				db.Create(&model.Transaction{WalletID: wallet.ID, Type: model.TransactionTypePurchase, Amount: -1})
+				if evaluated, err := segments.EvaluateAll(); err != nil || evaluated != 1 {
+					t.Logf("EvaluateAll failed: %d (err %v)", evaluated, err)
+					return false
+				}
+				if member, _ := segments.IsMember(segment.ID, members[0]); member {
+					t.Logf("User %d should have left the segment", members[0])
+					return false
+				}
+				if member, _ := segments.IsMember(segment.ID, members[len(members)-1]); len(members) > 1 && !member {
+					t.Logf("User %d should still be a member", members[len(members)-1])
+					return false
+				}
+			}
+
+			// Segments are kept per tenant
+			if _, err := segments.ForTenant(2).GetSegment(segment.ID); err != ErrSegmentNotFound {
+				t.Logf("Expected ErrSegmentNotFound for tenant 2, got %v", err)
+				return false
+			}
+			return true
+		},
+		gen.SliceOfN(6, gen.OneConstOf(0, 50, 200, 1000)),
+		gen.SliceOfN(6, gen.IntRange(0, 120)),
+		gen.OneConstOf(100, 500),
+	))
+
+	properties.TestingRun(t)
+}
+
+func TestSegmentRulesAndNotifications(t *testing.T) {
+	db, segments, userIDs := setupSegmentTest(t, []segmentTestUser{{Spend: 20, DaysAgo: 1}, {}, {}})
+
+	invalid := []SegmentRules{
+		{},
+		{InactiveDays: -1},
+		{SpendWindowDays: 30},
+		{ActiveDays: 30, InactiveDays: 30},
+		{MinBalance: intPtr(100), MaxBalance: intPtr(10)},
+	}
+	for _, rules := range invalid {
+		if _, err := segments.CreateSegment(SegmentRequest{Name: "Invalid", Rules: rules}); err != ErrInvalidSegmentRules {
+			t.Errorf("Expected ErrInvalidSegmentRules for %+v, got %v", rules, err)
+		}
+	}
+
+	// New users with the default balance who are active this week
+	disabled := false
+	segment, err := segments.CreateSegment(SegmentRequest{
+		Name:    "Active newcomers",
+		Rules:   SegmentRules{ActiveDays: 7, JoinedWithinDays: 30, MaxBalance: intPtr(50)},
+		Enabled: &disabled,
+	})
+	if err != nil || segment.MemberCount != 1 || segment.Enabled {
+		t.Fatalf("Expected one member in a disabled segment, got %+v (err %v)", segment, err)
+	}
+	// Disabled segments are not re-evaluated in the background
+	if evaluated, err := segments.EvaluateAll(); err != nil || evaluated != 0 {
+		t.Errorf("Expected no segment to be evaluated, got %d (err %v)", evaluated, err)
+	}
+	if ids, _ := segments.UserSegmentIDs(userIDs[0]); len(ids) != 1 || ids[0] != segment.ID {
+		t.Errorf("Expected user to be in segment %d, got %v", segment.ID, ids)
+	}
+
+	if _, err := segments.NotifyMembers(1, segment.ID, SegmentNotificationRequest{Title: "Hi"}); err != ErrInvalidSegmentNotification {
+		t.Errorf("Expected ErrInvalidSegmentNotification, got %v", err)
+	}
+	notified, err := segments.NotifyMembers(1, segment.ID, SegmentNotificationRequest{Title: "Welcome", Content: "Try our new tickets"})
+	if err != nil || notified != 1 {
+		t.Fatalf("Expected one notification, got %d (err %v)", notified, err)
+	}
+	var notifications int64
+	db.Model(&model.Notification{}).Where("user_id = ? AND type = ?", userIDs[0], model.NotificationTypeCampaign).Count(&notifications)
+	if notifications != 1 {
+		t.Errorf("Expected the member to be notified, got %d notifications", notifications)
+	}
+	var logs int64
+	db.Model(&model.AdminLog{}).Where("action = ?", "notify_segment").Count(&logs)
+	if logs != 1 {
+		t.Errorf("Expected the campaign to be logged, got %d logs", logs)
+	}
+
+	members, err := segments.GetMembers(segment.ID, 1, 20)
+	if err != nil || members.Total != 1 || members.Members[0].Username != "User 0" {
+		t.Errorf("Unexpected members %+v (err %v)", members, err)
+	}
+
+	if err := segments.DeleteSegment(segment.ID); err != nil {
+		t.Fatalf("DeleteSegment failed: %v", err)
+	}
+	var memberships int64
+	db.Model(&model.UserSegment{}).Count(&memberships)
+	if memberships != 0 {
+		t.Errorf("Expected memberships to be removed, got %d", memberships)
+	}
+}
+
+// Tickets paid in bonus credits count as spending, alone or topped up with points
+func TestSegmentCountsBonusSpend(t *testing.T) {
+	db, segments, userIDs := setupSegmentTest(t, []segmentTestUser{{Spend: 20, DaysAgo: 1}, {}, {}, {}})
+	charges := map[uint][]model.Transaction{
+		userIDs[1]: {{Type: model.TransactionTypeBonusPurchase, Currency: model.CurrencyBonus, Amount: -20}},
+		userIDs[2]: {
+			{Type: model.TransactionTypePurchase, Currency: model.CurrencyPoints, Amount: -5},
+			{Type: model.TransactionTypeBonusPurchase, Currency: model.CurrencyBonus, Amount: -15},
+		},
+		userIDs[3]: {{Type: model.TransactionTypeBonusPurchase, Currency: model.CurrencyBonus, Amount: -10}},
+	}
+	for userID, transactions := range charges {
+		var wallet model.Wallet
+		db.Where("user_id = ?", userID).First(&wallet)
+		for _, transaction := range transactions {
+			transaction.WalletID = wallet.ID
+			db.Create(&transaction)
+		}
+	}
+
+	segment, err := segments.CreateSegment(SegmentRequest{
+		Name:  "Active spenders",
+		Rules: SegmentRules{MinSpend: intPtr(20), SpendWindowDays: 30, ActiveDays: 7},
+	})
+	if err != nil || segment.MemberCount != 3 {
+		t.Fatalf("Expected three members, got %+v (err %v)", segment, err)
+	}
+	if ids, _ := segments.UserSegmentIDs(userIDs[3]); len(ids) != 0 {
+		t.Errorf("Expected the user spending 10 left out, got %v", ids)
+	}
+}
+
+func intPtr(v int) *int {
+	return &v
+}
@@ -0,0 +1,582 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+	"scratch-lottery/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSegmentNotFound            = errors.New("segment not found")
+	ErrInvalidSegmentRules        = errors.New("invalid segment rules")
+	ErrInvalidSegmentNotification = errors.New("invalid segment notification")
+)
+
+// segmentMembershipBatch is the number of memberships written or removed per statement
+const segmentMembershipBatch = 500
+
+// segmentSpendTypes are the transactions that count as spending: tickets paid
+// in points or bonus credits, and exchanges. Refunds of cancelled exchanges
+// are booked with the same type and reduce the spend.
+var segmentSpendTypes = []model.TransactionType{model.TransactionTypePurchase, model.TransactionTypeBonusPurchase, model.TransactionTypeExchange}
+
+// SegmentService manages user segments, evaluates their rules into
+// memberships and lets campaigns target them
+type SegmentService struct {
+	db                  *gorm.DB
+	notificationService *NotificationService
+}
+
+// NewSegmentService creates a new segment service
+func NewSegmentService(db *gorm.DB, notificationService *NotificationService) *SegmentService {
+	return &SegmentService{db: db, notificationService: notificationService}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *SegmentService) ForTenant(tenantID uint) *SegmentService {
+	return &SegmentService{
+		db:                  repository.ScopeTenant(s.db, tenantID),
+		notificationService: s.notificationService.ForTenant(tenantID),
+	}
+}
+
+// SegmentRules select the users of a segment. Every rule that is set must
+// match. Spending is the net amount of points spent on tickets and exchanges;
+// activity is the last time a user spent points. Spend ranges also serve as
+// tiers, e.g. one segment per range of monthly spend.
+type SegmentRules struct {
+	MinSpend         *int `json:"min_spend,omitempty"`
+	MaxSpend         *int `json:"max_spend,omitempty"`
+	SpendWindowDays  int  `json:"spend_window_days,omitempty"` // spending of the last N days, 0 for all time
+	ActiveDays       int  `json:"active_days,omitempty"`       // spent within the last N days
+	InactiveDays     int  `json:"inactive_days,omitempty"`     // spent nothing within the last N days
+	MinBalance       *int `json:"min_balance,omitempty"`
+	MaxBalance       *int `json:"max_balance,omitempty"`
+	JoinedWithinDays int  `json:"joined_within_days,omitempty"` // registered within the last N days
+}
+
+// SegmentRequest represents the request to create or update a segment
+type SegmentRequest struct {
+	Name        string       `json:"name" binding:"required,max=64"`
+	Description string       `json:"description" binding:"max=256"`
+	Rules       SegmentRules `json:"rules"`
+	Enabled     *bool        `json:"enabled"` // defaults to true
+}
+
+// SegmentResponse represents a segment in API responses
+type SegmentResponse struct {
+	ID          uint         `json:"id"`
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Rules       SegmentRules `json:"rules"`
+	Enabled     bool         `json:"enabled"`
+	MemberCount int          `json:"member_count"`
+	EvaluatedAt *time.Time   `json:"evaluated_at,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+// SegmentMemberResponse represents a member of a segment
+type SegmentMemberResponse struct {
+	UserID   uint      `json:"user_id"`
+	Username string    `json:"username"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// SegmentMemberListResponse represents a paginated list of segment members
+type SegmentMemberListResponse struct {
+	Members    []SegmentMemberResponse `json:"members"`
+	Total      int64                   `json:"total"`
+	Page       int                     `json:"page"`
+	Limit      int                     `json:"limit"`
+	TotalPages int                     `json:"total_pages"`
+}
+
+// SegmentNotificationRequest represents a notification sent to all members of a segment
+type SegmentNotificationRequest struct {
+	Title   string `json:"title" binding:"required"`
+	Content string `json:"content" binding:"required"`
+}
+
+// GetSegments returns all segments
+func (s *SegmentService) GetSegments() ([]SegmentResponse, error) {
+	var segments []model.Segment
+	if err := s.db.Order("name ASC").Find(&segments).Error; err != nil {
+		return nil, err
+	}
+
+	responses := make([]SegmentResponse, 0, len(segments))
+	for i := range segments {
+		resp, err := toSegmentResponse(&segments[i])
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, *resp)
+	}
+	return responses, nil
+}
+
+// GetSegment returns a segment by ID
+func (s *SegmentService) GetSegment(id uint) (*SegmentResponse, error) {
+	segment, err := s.segment(id)
+	if err != nil {
+		return nil, err
+	}
+	return toSegmentResponse(segment)
+}
+
+// CreateSegment saves a new segment and evaluates its members
+func (s *SegmentService) CreateSegment(req SegmentRequest) (*SegmentResponse, error) {
+	segment := model.Segment{Enabled: true}
+	if err := applySegmentRequest(&segment, req); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(&segment).Error; err != nil {
+		return nil, err
+	}
+	if err := s.evaluate(&segment); err != nil {
+		return nil, err
+	}
+	return toSegmentResponse(&segment)
+}
+
+// UpdateSegment replaces the definition of a segment and re-evaluates its members
+func (s *SegmentService) UpdateSegment(id uint, req SegmentRequest) (*SegmentResponse, error) {
+	segment, err := s.segment(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applySegmentRequest(segment, req); err != nil {
+		return nil, err
+	}
+	if err := s.db.Save(segment).Error; err != nil {
+		return nil, err
+	}
+	if err := s.evaluate(segment); err != nil {
+		return nil, err
+	}
+	return toSegmentResponse(segment)
+}
+
+// DeleteSegment soft deletes a segment and removes its memberships
+func (s *SegmentService) DeleteSegment(id uint) error {
+	segment, err := s.segment(id)
+	if err != nil {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("segment_id = ?", segment.ID).Delete(&model.UserSegment{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(segment).Error
+	})
+}
+
+// Evaluate re-evaluates the members of a segment, whether or not it is enabled
+func (s *SegmentService) Evaluate(id uint) (*SegmentResponse, error) {
+	segment, err := s.segment(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.evaluate(segment); err != nil {
+		return nil, err
+	}
+	return toSegmentResponse(segment)
+}
+
+// EvaluateAll re-evaluates every enabled segment. A segment that fails is
+// logged and skipped. Returns the number of segments evaluated.
+func (s *SegmentService) EvaluateAll() (int, error) {
+	var segments []model.Segment
+	if err := s.db.Where("enabled = ?", true).Order("id ASC").Find(&segments).Error; err != nil {
+		return 0, err
+	}
+
+	evaluated := 0
+	for i := range segments {
+		if err := s.evaluate(&segments[i]); err != nil {
+			logger.Default().Warn("Evaluating segment %d failed: %v", segments[i].ID, err)
+			continue
+		}
+		evaluated++
+	}
+	return evaluated, nil
+}
+
+// Start evaluates all enabled segments every interval until stop is called
+func (s *SegmentService) Start(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			evaluated, err := s.EvaluateAll()
+			if err != nil {
+				logger.Default().Warn("Evaluating segments failed: %v", err)
+			} else if evaluated > 0 {
+				logger.Default().Info("Evaluated %d user segments", evaluated)
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// GetMembers returns a page of the members of a segment, newest first
+func (s *SegmentService) GetMembers(id uint, page, limit int) (*SegmentMemberListResponse, error) {
+	segment, err := s.segment(id)
+	if err != nil {
+		return nil, err
+	}
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	dbQuery := s.db.Model(&model.UserSegment{}).Where("segment_id = ?", segment.ID)
+	var total int64
+	if err := dbQuery.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	var memberships []model.UserSegment
+	if err := dbQuery.Preload("User").
+		Order("created_at DESC, id DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&memberships).Error; err != nil {
+		return nil, err
+	}
+
+	members := make([]SegmentMemberResponse, len(memberships))
+	for i, membership := range memberships {
+		members[i] = SegmentMemberResponse{
+			UserID:   membership.UserID,
+			Username: membership.User.Username,
+			JoinedAt: membership.CreatedAt,
+		}
+	}
+	return &SegmentMemberListResponse{
+		Members:    members,
+		Total:      total,
+		Page:       page,
+		Limit:      limit,
+		TotalPages: int((total + int64(limit) - 1) / int64(limit)),
+	}, nil
+}
+
+// MemberIDs returns the IDs of the users in a segment, for campaigns that
+// target it
+func (s *SegmentService) MemberIDs(segmentID uint) ([]uint, error) {
+	segment, err := s.segment(segmentID)
+	if err != nil {
+		return nil, err
+	}
+	var userIDs []uint
+	if err := s.db.Model(&model.UserSegment{}).Where("segment_id = ?", segment.ID).
+		Order("user_id ASC").Pluck("user_id", &userIDs).Error; err != nil {
+		return nil, err
+	}
+	return userIDs, nil
+}
+
+// IsMember reports whether a user is in a segment, for offers restricted to it
+func (s *SegmentService) IsMember(segmentID, userID uint) (bool, error) {
+	var count int64
+	if err := s.db.Model(&model.UserSegment{}).
+		Where("segment_id = ? AND user_id = ?", segmentID, userID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// UserSegmentIDs returns the segments a user is in
+func (s *SegmentService) UserSegmentIDs(userID uint) ([]uint, error) {
+	var segmentIDs []uint
+	if err := s.db.Model(&model.UserSegment{}).Where("user_id = ?", userID).
+		Order("segment_id ASC").Pluck("segment_id", &segmentIDs).Error; err != nil {
+		return nil, err
+	}
+	return segmentIDs, nil
+}
+
+// NotifyMembers sends a notification to every member of a segment and
+// records the campaign in the admin log. Returns the number of users notified.
+func (s *SegmentService) NotifyMembers(adminID, segmentID uint, req SegmentNotificationRequest) (int, error) {
+	if req.Title == "" || req.Content == "" || len([]rune(req.Title)) > 128 || len([]rune(req.Content)) > 1024 {
+		return 0, ErrInvalidSegmentNotification
+	}
+	userIDs, err := s.MemberIDs(segmentID)
+	if err != nil {
+		return 0, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		for _, userID := range userIDs {
+			if _, err := s.notificationService.notify(tx, userID, model.NotificationTypeCampaign, req.Title, req.Content); err != nil {
+				return err
+			}
+		}
+
+		details, _ := json.Marshal(map[string]interface{}{
+			"title":      req.Title,
+			"recipients": len(userIDs),
+		})
+		adminLog := model.AdminLog{
+			AdminID:    adminID,
+			Action:     "notify_segment",
+			TargetType: "segment",
+			TargetID:   segmentID,
+			Details:    string(details),
+		}
+		return tx.Create(&adminLog).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(userIDs), nil
+}
+
+// evaluate replaces the memberships of a segment with the users matching its
+// rules. Users who still match keep their original join time.
+func (s *SegmentService) evaluate(segment *model.Segment) error {
+	rules, err := segmentRules(segment)
+	if err != nil {
+		return err
+	}
+	db := repository.ScopeTenant(s.db, segment.TenantID)
+	now := time.Now()
+
+	matched, err := matchSegmentRules(db, rules, now)
+	if err != nil {
+		return err
+	}
+
+	var current []uint
+	if err := db.Model(&model.UserSegment{}).Where("segment_id = ?", segment.ID).Pluck("user_id", &current).Error; err != nil {
+		return err
+	}
+	isCurrent := make(map[uint]bool, len(current))
+	for _, userID := range current {
+		isCurrent[userID] = true
+	}
+
+	var joined []model.UserSegment
+	for _, userID := range matched {
+		if isCurrent[userID] {
+			delete(isCurrent, userID)
+			continue
+		}
+		joined = append(joined, model.UserSegment{SegmentID: segment.ID, UserID: userID})
+	}
+	left := make([]uint, 0, len(isCurrent))
+	for userID := range isCurrent {
+		left = append(left, userID)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for start := 0; start < len(left); start += segmentMembershipBatch {
+			end := min(start+segmentMembershipBatch, len(left))
+			if err := tx.Where("segment_id = ? AND user_id IN ?", segment.ID, left[start:end]).
+				Delete(&model.UserSegment{}).Error; err != nil {
+				return err
+			}
+		}
+		if len(joined) > 0 {
+			if err := tx.CreateInBatches(&joined, segmentMembershipBatch).Error; err != nil {
+				return err
+			}
+		}
+
+		segment.MemberCount = len(matched)
+		segment.EvaluatedAt = &now
+		return tx.Model(&model.Segment{}).Where("id = ?", segment.ID).Updates(map[string]interface{}{
+			"member_count": segment.MemberCount,
+			"evaluated_at": now,
+		}).Error
+	})
+}
+
+// matchSegmentRules returns the IDs of the regular users matching the rules
+func matchSegmentRules(db *gorm.DB, rules SegmentRules, now time.Time) ([]uint, error) {
+	var candidates []struct {
+		UserID   uint
+		WalletID uint
+	}
+	query := db.Model(&model.Wallet{}).
+		Select("wallets.user_id, wallets.id AS wallet_id").
+		Joins("JOIN users ON users.id = wallets.user_id AND users.deleted_at IS NULL AND users.role = ?", "user")
+	if rules.MinBalance != nil {
+		query = query.Where("wallets.balance >= ?", *rules.MinBalance)
+	}
+	if rules.MaxBalance != nil {
+		query = query.Where("wallets.balance <= ?", *rules.MaxBalance)
+	}
+	if rules.JoinedWithinDays > 0 {
+		query = query.Where("users.created_at >= ?", now.AddDate(0, 0, -rules.JoinedWithinDays))
+	}
+	if err := query.Order("wallets.user_id ASC").Scan(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	var spend map[uint]int
+	if rules.MinSpend != nil || rules.MaxSpend != nil {
+		var from time.Time
+		if rules.SpendWindowDays > 0 {
+			from = now.AddDate(0, 0, -rules.SpendWindowDays)
+		}
+		var totals []struct {
+			WalletID uint
+			Total    int
+		}
+		if err := repository.TransactionsBetween(db, from, time.Time{}).
+			Select("wallet_id, COALESCE(SUM(-amount), 0) AS total").
+			Where("type IN ?", segmentSpendTypes).
+			Group("wallet_id").
+			Scan(&totals).Error; err != nil {
+			return nil, err
+		}
+		spend = make(map[uint]int, len(totals))
+		for _, total := range totals {
+			spend[total.WalletID] = total.Total
+		}
+	}
+
+	activeSince := func(days int) (map[uint]bool, error) {
+		var walletIDs []uint
+		if err := repository.TransactionsBetween(db, now.AddDate(0, 0, -days), time.Time{}).
+			Where("type IN ? AND amount < 0", segmentSpendTypes).
+			Distinct().Pluck("wallet_id", &walletIDs).Error; err != nil {
+			return nil, err
+		}
+		active := make(map[uint]bool, len(walletIDs))
+		for _, walletID := range walletIDs {
+			active[walletID] = true
+		}
+		return active, nil
+	}
+	var activeRecently, activeLately map[uint]bool
+	var err error
+	if rules.ActiveDays > 0 {
+		if activeRecently, err = activeSince(rules.ActiveDays); err != nil {
+			return nil, err
+		}
+	}
+	if rules.InactiveDays > 0 {
+		if activeLately, err = activeSince(rules.InactiveDays); err != nil {
+			return nil, err
+		}
+	}
+
+	matched := make([]uint, 0, len(candidates))
+	for _, c := range candidates {
+		if rules.MinSpend != nil && spend[c.WalletID] < *rules.MinSpend {
+			continue
+		}
+		if rules.MaxSpend != nil && spend[c.WalletID] > *rules.MaxSpend {
+			continue
+		}
+		if activeRecently != nil && !activeRecently[c.WalletID] {
+			continue
+		}
+		if activeLately != nil && activeLately[c.WalletID] {
+			continue
+		}
+		matched = append(matched, c.UserID)
+	}
+	return matched, nil
+}
+
+func (s *SegmentService) segment(id uint) (*model.Segment, error) {
+	var segment model.Segment
+	if err := s.db.First(&segment, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSegmentNotFound
+		}
+		return nil, err
+	}
+	return &segment, nil
+}
+
+// applySegmentRequest validates a segment request and copies it onto a segment
+func applySegmentRequest(segment *model.Segment, req SegmentRequest) error {
+	rules := req.Rules
+	if req.Name == "" || !validSegmentRules(rules) {
+		return ErrInvalidSegmentRules
+	}
+	rulesJSON, err := json.Marshal(rules)
+	if err != nil {
+		return err
+	}
+
+	segment.Name = req.Name
+	segment.Description = req.Description
+	segment.Rules = string(rulesJSON)
+	if req.Enabled != nil {
+		segment.Enabled = *req.Enabled
+	}
+	return nil
+}
+
+// validSegmentRules reports whether rules select anyone in a meaningful way:
+// at least one rule is set, no window is negative and no range is empty
+func validSegmentRules(rules SegmentRules) bool {
+	if rules.SpendWindowDays < 0 || rules.ActiveDays < 0 || rules.InactiveDays < 0 || rules.JoinedWithinDays < 0 {
+		return false
+	}
+	if rules.MinSpend != nil && rules.MaxSpend != nil && *rules.MinSpend > *rules.MaxSpend {
+		return false
+	}
+	if rules.MinBalance != nil && rules.MaxBalance != nil && *rules.MinBalance > *rules.MaxBalance {
+		return false
+	}
+	// Active within the last N days but not within the last M days needs N > M
+	if rules.ActiveDays > 0 && rules.InactiveDays > 0 && rules.ActiveDays <= rules.InactiveDays {
+		return false
+	}
+	if rules.SpendWindowDays > 0 && rules.MinSpend == nil && rules.MaxSpend == nil {
+		return false
+	}
+	return rules.MinSpend != nil || rules.MaxSpend != nil || rules.ActiveDays > 0 || rules.InactiveDays > 0 ||
+		rules.MinBalance != nil || rules.MaxBalance != nil || rules.JoinedWithinDays > 0
+}
+
+func segmentRules(segment *model.Segment) (SegmentRules, error) {
+	var rules SegmentRules
+	if err := json.Unmarshal([]byte(segment.Rules), &rules); err != nil {
+		return rules, ErrInvalidSegmentRules
+	}
+	return rules, nil
+}
+
+func toSegmentResponse(segment *model.Segment) (*SegmentResponse, error) {
+	rules, err := segmentRules(segment)
+	if err != nil {
+		return nil, err
+	}
+	return &SegmentResponse{
+		ID:          segment.ID,
+		Name:        segment.Name,
+		Description: segment.Description,
+		Rules:       rules,
+		Enabled:     segment.Enabled,
+		MemberCount: segment.MemberCount,
+		EvaluatedAt: segment.EvaluatedAt,
+		CreatedAt:   segment.CreatedAt,
+		UpdatedAt:   segment.UpdatedAt,
+	}, nil
+}
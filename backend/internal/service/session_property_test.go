@@ -0,0 +1,108 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/cache"
+	"scratch-lottery/internal/model"
+	"scratch-lottery/pkg/auth"
+)
+
+// setupSessionTest creates a dev mode auth service tracking sessions with
+// the given limits
+func setupSessionTest(t *testing.T, maxPerUser, maxPerIP int) (*AuthService, *SessionService) {
+	db := setupTenantTestDB(t)
+	if err := db.AutoMigrate(&model.UserSession{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	blacklist := cache.NewTokenBlacklist(cache.NewMemoryCache())
+	authService := NewAuthService(db, auth.NewJWTManager("test-secret", 15, 7), blacklist, true)
+	sessions := NewSessionService(db, blacklist, maxPerUser, maxPerIP, time.Hour)
+	authService.UseSessions(sessions)
+	return authService, sessions
+}
+
+// An account over the session limit loses its oldest session: the access
+// token is refused right away and the refresh token even after a restart
+func TestSessionEvictsOldest(t *testing.T) {
+	authService, sessions := setupSessionTest(t, 2, 0)
+	svc := authService.ForTenant(2)
+	client := ClientInfo{IP: "10.0.0.1", UserAgent: "test"}
+
+	var logins []*AuthResponse
+	for i := 0; i < 3; i++ {
+		resp, err := svc.DevLogin("dev_user", client)
+		if err != nil {
+			t.Fatalf("DevLogin failed: %v", err)
+		}
+		logins = append(logins, resp)
+	}
+
+	if _, err := svc.ValidateAccessToken(logins[0].AccessToken); err != auth.ErrTokenBlacklisted {
+		t.Errorf("Expected the oldest session's access token revoked, got %v", err)
+	}
+	for _, resp := range logins[1:] {
+		if _, err := svc.ValidateAccessToken(resp.AccessToken); err != nil {
+			t.Errorf("Expected the newer sessions kept, got %v", err)
+		}
+	}
+
+	// Without the cached blacklist the database still refuses the refresh
+	restarted := NewAuthService(sessions.db, authService.jwtManager, cache.NewTokenBlacklist(cache.NewMemoryCache()), true)
+	restarted.UseSessions(NewSessionService(sessions.db, restarted.blacklist, 2, 0, time.Hour))
+	if _, err := restarted.ForTenant(2).RefreshToken(logins[0].RefreshToken); err != auth.ErrTokenBlacklisted {
+		t.Errorf("Expected the evicted session's refresh refused, got %v", err)
+	}
+
+	// Refreshing keeps the session, and logging out ends it
+	refreshed, err := svc.RefreshToken(logins[1].RefreshToken)
+	if err != nil {
+		t.Fatalf("RefreshToken failed: %v", err)
+	}
+	claims, err := svc.ValidateAccessToken(refreshed.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken failed: %v", err)
+	}
+	original, _ := svc.ValidateAccessToken(logins[1].AccessToken)
+	if claims.SessionID == "" || claims.SessionID != original.SessionID {
+		t.Errorf("Expected the refreshed tokens in the same session, got %q and %q", claims.SessionID, original.SessionID)
+	}
+	if err := authService.Logout(refreshed.AccessToken, refreshed.RefreshToken); err != nil {
+		t.Fatalf("Logout failed: %v", err)
+	}
+	if _, err := svc.ValidateAccessToken(logins[1].AccessToken); err != auth.ErrTokenBlacklisted {
+		t.Errorf("Expected every token of the ended session refused, got %v", err)
+	}
+
+	var active int64
+	sessions.db.Model(&model.UserSession{}).Where("revoked_at IS NULL").Count(&active)
+	if active != 1 {
+		t.Errorf("Expected one active session left, got %d", active)
+	}
+}
+
+// An IP holding too many sessions of other accounts refuses new logins,
+// while its own accounts and other tenants can still log in
+func TestSessionIPLimit(t *testing.T) {
+	authService, _ := setupSessionTest(t, 0, 1)
+	svc := authService.ForTenant(2)
+	farm := ClientInfo{IP: "10.0.0.2", UserAgent: "test"}
+
+	if _, err := svc.DevLogin("dev_user", farm); err != nil {
+		t.Fatalf("DevLogin failed: %v", err)
+	}
+	if _, err := svc.DevLogin("dev_user2", farm); err != ErrTooManyIPSessions {
+		t.Errorf("Expected ErrTooManyIPSessions, got %v", err)
+	}
+	if _, err := svc.DevLogin("dev_user", farm); err != nil {
+		t.Errorf("Expected the same account allowed again, got %v", err)
+	}
+	if _, err := svc.DevLogin("dev_user2", ClientInfo{IP: "10.0.0.3", UserAgent: "test"}); err != nil {
+		t.Errorf("Expected another IP allowed, got %v", err)
+	}
+	if _, err := authService.ForTenant(1).DevLogin("dev_user2", farm); err != nil {
+		t.Errorf("Expected sessions of another tenant not counted, got %v", err)
+	}
+}
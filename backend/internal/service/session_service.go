@@ -0,0 +1,209 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"scratch-lottery/internal/cache"
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+	"scratch-lottery/pkg/auth"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTooManyIPSessions = errors.New("too many sessions from this ip")
+)
+
+// SessionService tracks the login sessions of users. A user holds at most
+// maxPerUser sessions, the oldest being evicted when a new one starts, and
+// an IP at most maxPerIP sessions of other users, which slows down farms
+// logging in many stuffed accounts. A limit of 0 turns it off.
+//
+// Revoked sessions are put on the token blacklist so their access tokens
+// are refused right away; their refresh tokens are also checked against the
+// database, so revocations outlive a restart.
+type SessionService struct {
+	db         *gorm.DB
+	blacklist  *cache.TokenBlacklist
+	maxPerUser int
+	maxPerIP   int64
+	expiry     time.Duration
+}
+
+// NewSessionService creates a new session service. Sessions expire when
+// they are not refreshed within expiry, the lifetime of a refresh token.
+func NewSessionService(db *gorm.DB, blacklist *cache.TokenBlacklist, maxPerUser, maxPerIP int, expiry time.Duration) *SessionService {
+	if maxPerUser < 0 {
+		maxPerUser = 0
+	}
+	if maxPerIP < 0 {
+		maxPerIP = 0
+	}
+	return &SessionService{
+		db:         db,
+		blacklist:  blacklist,
+		maxPerUser: maxPerUser,
+		maxPerIP:   int64(maxPerIP),
+		expiry:     expiry,
+	}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *SessionService) ForTenant(tenantID uint) *SessionService {
+	return &SessionService{
+		db:         repository.ScopeTenant(s.db, tenantID),
+		blacklist:  s.blacklist,
+		maxPerUser: s.maxPerUser,
+		maxPerIP:   s.maxPerIP,
+		expiry:     s.expiry,
+	}
+}
+
+// activeSessions restricts a query to the sessions that are neither revoked nor expired
+func activeSessions(db *gorm.DB, now time.Time) *gorm.DB {
+	return db.Where("revoked_at IS NULL AND expires_at > ?", now)
+}
+
+// Start starts a session for a user logging in from client, evicting the
+// user's oldest sessions over the limit. The login is refused with
+// ErrTooManyIPSessions when the IP already holds too many sessions of other
+// users.
+func (s *SessionService) Start(user *model.User, client ClientInfo) (*model.UserSession, error) {
+	sessionID, err := generateSessionID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := model.UserSession{
+		UserID:     user.ID,
+		SessionID:  sessionID,
+		IP:         client.IP,
+		UserAgent:  truncate(client.UserAgent, 256),
+		ExpiresAt:  now.Add(s.expiry),
+		LastUsedAt: now,
+	}
+	var evicted []string
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if s.maxPerIP > 0 && client.IP != "" {
+			var fromIP int64
+			if err := activeSessions(tx.Model(&model.UserSession{}), now).
+				Where("ip = ? AND user_id <> ?", client.IP, user.ID).
+				Count(&fromIP).Error; err != nil {
+				return err
+			}
+			if fromIP >= s.maxPerIP {
+				return ErrTooManyIPSessions
+			}
+		}
+
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+		if s.maxPerUser == 0 {
+			return nil
+		}
+
+		var oldest []model.UserSession
+		if err := activeSessions(tx, now).Where("user_id = ?", user.ID).
+			Order("created_at DESC, id DESC").
+			Offset(s.maxPerUser).
+			Find(&oldest).Error; err != nil {
+			return err
+		}
+		for _, old := range oldest {
+			if err := tx.Model(&old).Updates(map[string]interface{}{
+				"revoked_at":    now,
+				"revoke_reason": model.SessionRevokeEvicted,
+			}).Error; err != nil {
+				return err
+			}
+			evicted = append(evicted, old.SessionID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sessionID := range evicted {
+		_ = s.blacklist.AddSession(sessionID, s.expiry)
+	}
+	return &session, nil
+}
+
+// Touch keeps a session alive when its tokens are refreshed. A revoked,
+// expired or unknown session is refused with auth.ErrTokenBlacklisted.
+func (s *SessionService) Touch(sessionID string) error {
+	now := time.Now()
+	result := activeSessions(s.db.Model(&model.UserSession{}), now).
+		Where("session_id = ?", sessionID).
+		Updates(map[string]interface{}{"last_used_at": now, "expires_at": now.Add(s.expiry)})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return auth.ErrTokenBlacklisted
+	}
+	return nil
+}
+
+// End revokes a session on logout
+func (s *SessionService) End(sessionID string) error {
+	now := time.Now()
+	if err := activeSessions(s.db.Model(&model.UserSession{}), now).
+		Where("session_id = ?", sessionID).
+		Updates(map[string]interface{}{"revoked_at": now, "revoke_reason": model.SessionRevokeLogout}).Error; err != nil {
+		return err
+	}
+	return s.blacklist.AddSession(sessionID, s.expiry)
+}
+
+// IsRevoked reports whether the tokens of a session are refused
+func (s *SessionService) IsRevoked(sessionID string) bool {
+	return s.blacklist.IsSessionBlacklisted(sessionID)
+}
+
+// issueTokens generates the tokens of a login. When sessions are tracked
+// the tokens are bound to a new session of the user's tenant.
+func issueTokens(jwtManager *auth.JWTManager, sessions *SessionService, user *model.User, client ClientInfo) (*AuthResponse, error) {
+	sessionID := ""
+	if sessions != nil {
+		session, err := sessions.ForTenant(user.TenantID).Start(user, client)
+		if err != nil {
+			return nil, err
+		}
+		sessionID = session.SessionID
+	}
+
+	accessToken, refreshToken, err := jwtManager.GenerateSessionTokenPair(
+		sessionID,
+		user.TenantID,
+		user.ID,
+		user.LinuxdoID,
+		user.Username,
+		user.Role,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    jwtManager.GetAccessExpiry(),
+		User:         user,
+	}, nil
+}
+
+func generateSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
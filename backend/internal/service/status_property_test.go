@@ -0,0 +1,188 @@
+package service
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+	"gorm.io/gorm"
+)
+
+// setupStatusTest creates a tenant test database with the health check table
+func setupStatusTest(t *testing.T) (*gorm.DB, *StatusService) {
+	db := setupTenantTestDB(t)
+	if err := db.AutoMigrate(&model.HealthCheck{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	return db, NewStatusService(db, NewAdminService(db, NewWalletService(db)), time.Minute, 0)
+}
+
+// componentStatus returns the named component of a status page
+func componentStatus(status *SystemStatus, name string) ComponentStatus {
+	for _, component := range status.Components {
+		if component.Name == name {
+			return component
+		}
+	}
+	return ComponentStatus{}
+}
+
+// Status page: uptime is the share of slots since the first check that have
+// a healthy check; missing slots count as downtime.
+func TestStatusUptime(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	db, status := setupStatusTest(t)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("uptime counts healthy slots", prop.ForAll(
+		func(slots []int) bool {
+			defer db.Where("1 = 1").Delete(&model.HealthCheck{})
+
+			// 0 is a missing slot, 1 an unhealthy and 2 a healthy check; the
+			// first slot is always checked
+			slots[0] = slots[0]%2 + 1
+			current := time.Now().Truncate(time.Minute)
+			healthy := 0
+			for i, state := range slots {
+				if state == 0 {
+					continue
+				}
+				if state == 2 {
+					healthy++
+				}
+				slot := current.Add(-time.Duration(len(slots)-i) * time.Minute)
+				db.Create(&model.HealthCheck{Component: StatusComponentDatabase, Slot: slot, Healthy: state == 2})
+			}
+
+			uptimes, err := status.uptimes(time.Now())
+			if err != nil {
+				t.Logf("uptimes failed: %v", err)
+				return false
+			}
+			expected := math.Round(float64(healthy)/float64(len(slots))*10000) / 100
+			for _, window := range statusUptimeWindows {
+				if got := uptimes[StatusComponentDatabase][window.name]; got != expected {
+					t.Logf("Window %s: expected %.2f%%, got %.2f%%", window.name, expected, got)
+					return false
+				}
+			}
+			return true
+		},
+		gen.SliceOfN(30, gen.IntRange(0, 2)),
+	))
+
+	properties.TestingRun(t)
+}
+
+// Status page: checks are recorded once per slot, payments are checked when
+// enabled, and a failing component degrades the overall status.
+func TestStatusChecks(t *testing.T) {
+	db, status := setupStatusTest(t)
+
+	if err := status.Check(); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if err := status.Check(); err != nil {
+		t.Fatalf("Second check failed: %v", err)
+	}
+	var checks int64
+	db.Model(&model.HealthCheck{}).Count(&checks)
+	if checks != 2 {
+		t.Fatalf("Expected api and database checks once per slot, got %d", checks)
+	}
+
+	page, err := status.GetStatus()
+	if err != nil {
+		t.Fatalf("GetStatus failed: %v", err)
+	}
+	if page.Status != SystemOperational || componentStatus(page, StatusComponentDatabase).Status != ComponentOperational ||
+		componentStatus(page, StatusComponentPayments).Status != ComponentDisabled {
+		t.Errorf("Unexpected status %+v", page)
+	}
+
+	gatewayStatus := http.StatusOK
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(gatewayStatus)
+	}))
+	defer gateway.Close()
+	db.Create(&model.SystemConfig{Key: "payment_enabled", Value: "true"})
+	db.Create(&model.SystemConfig{Key: ConfigKeyEPayGatewayURL, Value: gateway.URL})
+
+	for _, tc := range []struct {
+		gatewayStatus int
+		component     string
+		system        string
+	}{
+		{http.StatusOK, ComponentOperational, SystemOperational},
+		{http.StatusServiceUnavailable, ComponentDown, SystemDegraded},
+	} {
+		gatewayStatus = tc.gatewayStatus
+		db.Where("1 = 1").Delete(&model.HealthCheck{})
+		if err := status.Check(); err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		page, err := status.buildStatus(time.Now())
+		if err != nil {
+			t.Fatalf("buildStatus failed: %v", err)
+		}
+		if got := componentStatus(page, StatusComponentPayments).Status; got != tc.component || page.Status != tc.system {
+			t.Errorf("Gateway %d: expected payments %s and system %s, got %s and %s",
+				tc.gatewayStatus, tc.component, tc.system, got, page.Status)
+		}
+	}
+
+	// The database going down is an outage
+	db.Where("component = ?", StatusComponentDatabase).Delete(&model.HealthCheck{})
+	db.Create(&model.HealthCheck{Component: StatusComponentDatabase, Slot: time.Now().Truncate(time.Minute), Detail: "timeout"})
+	page, err = status.buildStatus(time.Now())
+	if err != nil {
+		t.Fatalf("buildStatus failed: %v", err)
+	}
+	if page.Status != SystemOutage {
+		t.Errorf("Expected an outage, got %s", page.Status)
+	}
+}
+
+// Status page: the incident banner is per tenant, is shown right away and is
+// removed with an empty message.
+func TestStatusIncident(t *testing.T) {
+	_, status := setupStatusTest(t)
+	status.cacheTTL = time.Hour
+
+	if page, err := status.ForTenant(2).GetStatus(); err != nil || page.Incident != nil {
+		t.Fatalf("Expected no incident, got %+v (err %v)", page, err)
+	}
+	if _, err := status.ForTenant(2).UpdateIncident(1, UpdateStatusIncidentRequest{Message: "维护中", Severity: "critical"}); err != ErrInvalidIncident {
+		t.Errorf("Expected an invalid severity to be refused, got %v", err)
+	}
+	incident, err := status.ForTenant(2).UpdateIncident(1, UpdateStatusIncidentRequest{Message: " 支付维护中 ", Severity: IncidentSeverityMinor})
+	if err != nil || incident.Message != "支付维护中" {
+		t.Fatalf("UpdateIncident failed: %+v (err %v)", incident, err)
+	}
+
+	page, err := status.ForTenant(2).GetStatus()
+	if err != nil || page.Incident == nil || page.Incident.Severity != IncidentSeverityMinor {
+		t.Errorf("Expected the incident on the cached page, got %+v (err %v)", page, err)
+	}
+	if other, err := status.ForTenant(1).GetStatus(); err != nil || other.Incident != nil {
+		t.Errorf("Expected other tenants without the incident, got %+v (err %v)", other, err)
+	}
+
+	if incident, err := status.ForTenant(2).UpdateIncident(1, UpdateStatusIncidentRequest{}); err != nil || incident != nil {
+		t.Fatalf("Expected the incident to be removed, got %+v (err %v)", incident, err)
+	}
+	if page, err := status.ForTenant(2).GetStatus(); err != nil || page.Incident != nil {
+		t.Errorf("Expected no incident after removal, got %+v (err %v)", page, err)
+	}
+}
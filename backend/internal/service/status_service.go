@@ -0,0 +1,460 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+	"scratch-lottery/pkg/logger"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ConfigKeyStatusIncident holds the incident banner of the status page as JSON
+const ConfigKeyStatusIncident = "status_incident"
+
+// Status page components
+const (
+	StatusComponentAPI      = "api"
+	StatusComponentDatabase = "database"
+	StatusComponentPayments = "payments"
+)
+
+// Component states
+const (
+	ComponentOperational = "operational"
+	ComponentDown        = "down"
+	ComponentUnknown     = "unknown"  // Not checked recently
+	ComponentDisabled    = "disabled" // Payments are turned off
+)
+
+// Overall system states
+const (
+	SystemOperational = "operational"
+	SystemDegraded    = "degraded" // A component other than the database is down
+	SystemOutage      = "outage"   // The database is down
+)
+
+// Incident banner severities
+const (
+	IncidentSeverityInfo  = "info"
+	IncidentSeverityMinor = "minor"
+	IncidentSeverityMajor = "major"
+)
+
+// statusHistoryDays is how long health checks are kept, the longest uptime window
+const statusHistoryDays = 90
+
+// Limits of health checks
+const (
+	healthCheckTimeout       = 5 * time.Second
+	maxIncidentMessageLength = 500
+)
+
+// statusUptimeWindows are the windows uptime percentages are reported for
+var statusUptimeWindows = []struct {
+	name     string
+	duration time.Duration
+}{
+	{"24h", 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+	{"30d", 30 * 24 * time.Hour},
+	{"90d", statusHistoryDays * 24 * time.Hour},
+}
+
+var (
+	ErrInvalidIncident = errors.New("invalid status incident")
+)
+
+// StatusIncident is the banner an admin shows on the status page
+type StatusIncident struct {
+	Message   string    `json:"message"`
+	Severity  string    `json:"severity"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// UpdateStatusIncidentRequest sets the incident banner; an empty message
+// removes it
+type UpdateStatusIncidentRequest struct {
+	Message  string `json:"message"`
+	Severity string `json:"severity"`
+}
+
+// ComponentStatus is the health of one component. Uptime percentages are
+// keyed by window and omitted for windows without checks.
+type ComponentStatus struct {
+	Name      string             `json:"name"`
+	Status    string             `json:"status"`
+	LatencyMs int64              `json:"latency_ms"`
+	CheckedAt *time.Time         `json:"checked_at,omitempty"`
+	Uptime    map[string]float64 `json:"uptime"`
+}
+
+// SystemStatus is the data of the public status page
+type SystemStatus struct {
+	Status        string            `json:"status"`
+	Incident      *StatusIncident   `json:"incident"`
+	Components    []ComponentStatus `json:"components"`
+	CheckInterval int               `json:"check_interval"` // Seconds between health checks
+	GeneratedAt   time.Time         `json:"generated_at"`
+}
+
+// statusCache holds the generated status page of each tenant
+type statusCache struct {
+	mutex   sync.Mutex
+	entries map[uint]*SystemStatus
+}
+
+// StatusService checks the health of the API, the database and the payment
+// gateway, and summarizes the history for a public status page
+type StatusService struct {
+	db           *gorm.DB
+	adminService *AdminService
+	tenantID     uint
+	interval     time.Duration
+	cacheTTL     time.Duration
+	client       *http.Client
+	cache        *statusCache
+}
+
+// NewStatusService creates a new status service. Components are checked
+// every interval and generated status pages are cached for cacheTTL.
+func NewStatusService(db *gorm.DB, adminService *AdminService, interval, cacheTTL time.Duration) *StatusService {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &StatusService{
+		db:           db,
+		adminService: adminService,
+		tenantID:     repository.DefaultTenantID,
+		interval:     interval,
+		cacheTTL:     cacheTTL,
+		client:       &http.Client{Timeout: healthCheckTimeout},
+		cache:        &statusCache{entries: make(map[uint]*SystemStatus)},
+	}
+}
+
+// ForTenant returns a copy of the service whose status page carries the
+// incident banner and payment setting of a tenant. Health checks are shared.
+func (s *StatusService) ForTenant(tenantID uint) *StatusService {
+	scoped := *s
+	scoped.adminService = s.adminService.ForTenant(tenantID)
+	scoped.tenantID = tenantID
+	return &scoped
+}
+
+// Check checks every component and records the results in the current slot.
+// Slots already recorded by another instance are kept.
+func (s *StatusService) Check() error {
+	now := time.Now()
+	slot := now.Truncate(s.interval)
+
+	checks := []model.HealthCheck{{Component: StatusComponentAPI, Healthy: true}}
+	checks = append(checks, s.checkDatabase())
+	if payments, ok := s.checkPayments(); ok {
+		checks = append(checks, payments)
+	}
+	for i := range checks {
+		checks[i].Slot = slot
+	}
+	return s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&checks).Error
+}
+
+// checkDatabase pings the database
+func (s *StatusService) checkDatabase() model.HealthCheck {
+	check := model.HealthCheck{Component: StatusComponentDatabase}
+	sqlDB, err := s.db.DB()
+	if err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+		defer cancel()
+		started := time.Now()
+		err = sqlDB.PingContext(ctx)
+		check.LatencyMs = time.Since(started).Milliseconds()
+	}
+	if err != nil {
+		check.Detail = truncateDetail(err.Error())
+		return check
+	}
+	check.Healthy = true
+	return check
+}
+
+// checkPayments requests the payment gateway of the default tenant. Any
+// answer below 500 counts as reachable. It reports false when payments are
+// disabled, which is not checked.
+func (s *StatusService) checkPayments() (model.HealthCheck, bool) {
+	check := model.HealthCheck{Component: StatusComponentPayments}
+	admin := s.adminService.ForTenant(repository.DefaultTenantID)
+	if !admin.IsPaymentEnabled() {
+		return check, false
+	}
+	config, err := admin.GetEPayConfig()
+	if err != nil {
+		check.Detail = truncateDetail(err.Error())
+		return check, true
+	}
+	if config.GatewayURL == "" {
+		check.Detail = "payment gateway not configured"
+		return check, true
+	}
+
+	started := time.Now()
+	resp, err := s.client.Get(config.GatewayURL)
+	check.LatencyMs = time.Since(started).Milliseconds()
+	if err != nil {
+		check.Detail = truncateDetail(err.Error())
+		return check, true
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		check.Detail = resp.Status
+		return check, true
+	}
+	check.Healthy = true
+	return check, true
+}
+
+// Prune deletes health checks before a time
+func (s *StatusService) Prune(before time.Time) (int64, error) {
+	result := s.db.Where("slot < ?", before).Delete(&model.HealthCheck{})
+	return result.RowsAffected, result.Error
+}
+
+// Start checks the components every interval until the returned stop func
+// is called, and prunes checks older than the longest uptime window
+func (s *StatusService) Start(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := s.Check(); err != nil {
+					logger.Default().Warn("Recording health checks failed: %v", err)
+				}
+				if _, err := s.Prune(time.Now().AddDate(0, 0, -statusHistoryDays)); err != nil {
+					logger.Default().Warn("Pruning health checks failed: %v", err)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// GetStatus returns the status page of the tenant, cached for the cache TTL
+func (s *StatusService) GetStatus() (*SystemStatus, error) {
+	now := time.Now()
+	s.cache.mutex.Lock()
+	cached := s.cache.entries[s.tenantID]
+	s.cache.mutex.Unlock()
+	if cached != nil && now.Sub(cached.GeneratedAt) < s.cacheTTL {
+		return cached, nil
+	}
+
+	status, err := s.buildStatus(now)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.mutex.Lock()
+	s.cache.entries[s.tenantID] = status
+	s.cache.mutex.Unlock()
+	return status, nil
+}
+
+// buildStatus summarizes the latest checks and the uptime of each component
+func (s *StatusService) buildStatus(now time.Time) (*SystemStatus, error) {
+	incident, err := s.GetIncident()
+	if err != nil {
+		return nil, err
+	}
+
+	// The latest check of each component, if it is recent
+	var recent []model.HealthCheck
+	if err := s.db.Where("slot >= ?", now.Add(-2*s.interval)).
+		Order("slot DESC").
+		Find(&recent).Error; err != nil {
+		return nil, err
+	}
+	latest := make(map[string]model.HealthCheck)
+	for _, check := range recent {
+		if _, ok := latest[check.Component]; !ok {
+			latest[check.Component] = check
+		}
+	}
+
+	uptimes, err := s.uptimes(now)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &SystemStatus{
+		Status:        SystemOperational,
+		Incident:      incident,
+		CheckInterval: int(s.interval / time.Second),
+		GeneratedAt:   now,
+	}
+	paymentsEnabled := s.adminService.IsPaymentEnabled()
+	for _, name := range []string{StatusComponentAPI, StatusComponentDatabase, StatusComponentPayments} {
+		component := ComponentStatus{Name: name, Status: ComponentUnknown, Uptime: uptimes[name]}
+		if component.Uptime == nil {
+			component.Uptime = map[string]float64{}
+		}
+		if check, ok := latest[name]; ok {
+			checkedAt := check.Slot
+			component.CheckedAt = &checkedAt
+			component.LatencyMs = check.LatencyMs
+			component.Status = ComponentDown
+			if check.Healthy {
+				component.Status = ComponentOperational
+			}
+		}
+		if name == StatusComponentPayments && !paymentsEnabled {
+			component = ComponentStatus{Name: name, Status: ComponentDisabled, Uptime: map[string]float64{}}
+		}
+
+		if component.Status == ComponentDown {
+			if name == StatusComponentDatabase {
+				status.Status = SystemOutage
+			} else if status.Status == SystemOperational {
+				status.Status = SystemDegraded
+			}
+		}
+		status.Components = append(status.Components, component)
+	}
+	return status, nil
+}
+
+// uptimes returns the uptime percentage of each component in each window.
+// A window starts at the first check inside it; slots without a healthy
+// check count as downtime.
+func (s *StatusService) uptimes(now time.Time) (map[string]map[string]float64, error) {
+	current := now.Truncate(s.interval)
+	uptimes := make(map[string]map[string]float64)
+	for _, window := range statusUptimeWindows {
+		since := now.Add(-window.duration)
+		var rows []struct {
+			Component string
+			Checks    int64
+			Healthy   int64
+		}
+		if err := s.db.Model(&model.HealthCheck{}).
+			Select("component, COUNT(*) AS checks, SUM(CASE WHEN healthy THEN 1 ELSE 0 END) AS healthy").
+			Where("slot >= ? AND slot <= ?", since, now).
+			Group("component").
+			Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			var first model.HealthCheck
+			if err := s.db.Where("component = ? AND slot >= ?", row.Component, since).
+				Order("slot ASC").
+				Limit(1).
+				Find(&first).Error; err != nil {
+				return nil, err
+			}
+
+			// Slots since the first check, not counting the current slot
+			// until it is recorded
+			expected := int64(current.Sub(first.Slot) / s.interval)
+			if row.Checks > expected {
+				expected = row.Checks
+			}
+			if expected == 0 {
+				continue
+			}
+			if uptimes[row.Component] == nil {
+				uptimes[row.Component] = make(map[string]float64)
+			}
+			uptimes[row.Component][window.name] = math.Round(float64(row.Healthy)/float64(expected)*10000) / 100
+		}
+	}
+	return uptimes, nil
+}
+
+// GetIncident returns the incident banner of the tenant, or nil if there is none
+func (s *StatusService) GetIncident() (*StatusIncident, error) {
+	value, err := s.adminService.GetConfigValue(ConfigKeyStatusIncident)
+	if errors.Is(err, ErrConfigNotFound) || value == "" {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var incident StatusIncident
+	if err := json.Unmarshal([]byte(value), &incident); err != nil {
+		return nil, err
+	}
+	if incident.Message == "" {
+		return nil, nil
+	}
+	return &incident, nil
+}
+
+// UpdateIncident sets or, with an empty message, removes the incident banner
+func (s *StatusService) UpdateIncident(adminID uint, req UpdateStatusIncidentRequest) (*StatusIncident, error) {
+	message := strings.TrimSpace(req.Message)
+	if len([]rune(message)) > maxIncidentMessageLength {
+		return nil, ErrInvalidIncident
+	}
+	severity := req.Severity
+	if severity == "" {
+		severity = IncidentSeverityInfo
+	}
+	if severity != IncidentSeverityInfo && severity != IncidentSeverityMinor && severity != IncidentSeverityMajor {
+		return nil, ErrInvalidIncident
+	}
+
+	var incident *StatusIncident
+	value := ""
+	if message != "" {
+		incident = &StatusIncident{Message: message, Severity: severity, UpdatedAt: time.Now()}
+		data, err := json.Marshal(incident)
+		if err != nil {
+			return nil, err
+		}
+		value = string(data)
+	}
+
+	err := s.adminService.configs().Transaction(func(tx *gorm.DB) error {
+		if err := s.adminService.upsertConfig(tx, ConfigKeyStatusIncident, value); err != nil {
+			return err
+		}
+		details, _ := json.Marshal(map[string]interface{}{"message": message, "severity": severity})
+		adminLog := model.AdminLog{
+			AdminID:    adminID,
+			Action:     "update_status_incident",
+			TargetType: "system",
+			TargetID:   0,
+			Details:    string(details),
+		}
+		return tx.Create(&adminLog).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Show the change right away instead of after the cache expires
+	s.cache.mutex.Lock()
+	delete(s.cache.entries, s.tenantID)
+	s.cache.mutex.Unlock()
+	return incident, nil
+}
+
+// truncateDetail shortens error messages to fit a health check
+func truncateDetail(detail string) string {
+	if len(detail) > 255 {
+		return detail[:255]
+	}
+	return detail
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// StockCounter keeps the stock of the active prize pool of every lottery type
+// in memory, so storefront listings and ticket responses do not query the
+// pool and the reservations on every read. Purchases on this instance bump
+// the sold counter once they commit; everything else (purchases on other
+// instances, gifts, vouchers, new pools and reservations) is picked up when
+// the counters are reconciled with the database. Counters older than twice
+// the reconcile interval are reloaded on read, in case reconciliation stalls.
+type StockCounter struct {
+	db       *gorm.DB
+	interval time.Duration
+
+	mutex   sync.Mutex
+	entries map[uint]*stockEntry // By lottery type ID
+}
+
+// stockEntry is the stock of the active pool of a lottery type. Sold is the
+// only part that changes between reloads.
+type stockEntry struct {
+	total    int64
+	sold     atomic.Int64
+	reserved int64 // Tickets held by reservations when loaded
+	loadedAt time.Time
+}
+
+func (e *stockEntry) stock() int {
+	stock := e.total - e.sold.Load() - e.reserved
+	if stock < 0 {
+		return 0
+	}
+	return int(stock)
+}
+
+// NewStockCounter creates a new stock counter reconciled every interval
+func NewStockCounter(db *gorm.DB, interval time.Duration) *StockCounter {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	return &StockCounter{db: db, interval: interval, entries: make(map[uint]*stockEntry)}
+}
+
+// Start reconciles the counters with the database every interval and
+// returns a function stopping it
+func (c *StockCounter) Start() (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				drifted, err := c.Reconcile()
+				if err != nil {
+					logger.Default().Warn("Reconciling stock counters failed: %v", err)
+				} else if drifted > 0 {
+					logger.Default().Debug("Corrected %d stock counters", drifted)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// Stock returns the available stock of a lottery type: the unsold tickets of
+// its active pool minus those held by reservations
+func (c *StockCounter) Stock(lotteryTypeID uint) int {
+	entry := c.entry(lotteryTypeID)
+	if entry == nil {
+		return availableStock(c.db, lotteryTypeID, 0)
+	}
+	return entry.stock()
+}
+
+// Sell counts quantity tickets of a lottery type as sold. It is called once
+// the purchase has committed.
+func (c *StockCounter) Sell(lotteryTypeID uint, quantity int) {
+	c.mutex.Lock()
+	entry := c.entries[lotteryTypeID]
+	c.mutex.Unlock()
+	if entry != nil {
+		entry.sold.Add(int64(quantity))
+	}
+}
+
+// Forget drops the counter of a lottery type, so the next read loads it
+// afresh; used when a pool is opened or closed
+func (c *StockCounter) Forget(lotteryTypeID uint) {
+	c.mutex.Lock()
+	delete(c.entries, lotteryTypeID)
+	c.mutex.Unlock()
+}
+
+// Reconcile reloads every counter from the database and returns how many of
+// them showed another stock than the database
+func (c *StockCounter) Reconcile() (int, error) {
+	c.mutex.Lock()
+	ids := make([]uint, 0, len(c.entries))
+	for id := range c.entries {
+		ids = append(ids, id)
+	}
+	c.mutex.Unlock()
+
+	drifted := 0
+	for _, id := range ids {
+		fresh, err := c.load(id)
+		if err != nil {
+			return drifted, err
+		}
+		c.mutex.Lock()
+		if old := c.entries[id]; old != nil {
+			if old.stock() != fresh.stock() {
+				drifted++
+			}
+			c.entries[id] = fresh
+		}
+		c.mutex.Unlock()
+	}
+	return drifted, nil
+}
+
+// entry returns the counter of a lottery type, loading it when it is missing
+// or stale. It returns nil when the database cannot be read.
+func (c *StockCounter) entry(lotteryTypeID uint) *stockEntry {
+	c.mutex.Lock()
+	entry := c.entries[lotteryTypeID]
+	c.mutex.Unlock()
+	if entry != nil && time.Since(entry.loadedAt) < 2*c.interval {
+		return entry
+	}
+
+	entry, err := c.load(lotteryTypeID)
+	if err != nil {
+		return nil
+	}
+	c.mutex.Lock()
+	c.entries[lotteryTypeID] = entry
+	c.mutex.Unlock()
+	return entry
+}
+
+// load reads the stock of a lottery type from the database. A lottery type
+// without an active pool has no stock.
+func (c *StockCounter) load(lotteryTypeID uint) (*stockEntry, error) {
+	entry := &stockEntry{loadedAt: time.Now()}
+
+	var pools []model.PrizePool
+	if err := c.db.Where("lottery_type_id = ? AND status = ?", lotteryTypeID, model.PrizePoolStatusActive).
+		Limit(1).Find(&pools).Error; err != nil {
+		return nil, err
+	}
+	if len(pools) == 0 {
+		return entry, nil
+	}
+	entry.total = int64(pools[0].TotalTickets)
+	entry.sold.Store(int64(pools[0].SoldTickets))
+
+	if err := c.db.Model(&model.StockReservation{}).
+		Where("lottery_type_id = ? AND expires_at > ?", lotteryTypeID, time.Now()).
+		Select("COALESCE(SUM(quantity), 0)").
+		Scan(&entry.reserved).Error; err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
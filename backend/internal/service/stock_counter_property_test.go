@@ -0,0 +1,95 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+)
+
+// Stock counters: purchases on this instance keep the counter equal to the
+// stock in the database without reloading it, changes made elsewhere show
+// up once the counter is reconciled, and a new prize pool is picked up at once.
+func TestStockCounterTracksPurchases(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("counter follows purchases and reconciles outside sales", prop.ForAll(
+		func(totalTickets int, quantities []int, soldElsewhere int) bool {
+			db, purchases, _, lotteryTypeID, userIDs := setupStockReservationTest(t, totalTickets, 1)
+			counter := NewStockCounter(db, time.Hour)
+			purchases.lotteryService.UseStockCounter(counter)
+			lotteries := purchases.lotteryService
+
+			if stock := lotteries.calculateStock(lotteryTypeID); stock != totalTickets {
+				t.Logf("Initial stock %d, expected %d", stock, totalTickets)
+				return false
+			}
+			for _, quantity := range quantities {
+				_, err := purchases.PurchaseTickets(userIDs[0], PurchaseRequest{LotteryTypeID: lotteryTypeID, Quantity: quantity})
+				if err != nil && err != ErrLotteryTypeSoldOut && err != ErrInsufficientBalance {
+					t.Logf("PurchaseTickets failed: %v", err)
+					return false
+				}
+				if cached, exact := lotteries.calculateStock(lotteryTypeID), availableStock(db, lotteryTypeID, 0); cached != exact {
+					t.Logf("Counter shows %d, database %d", cached, exact)
+					return false
+				}
+			}
+
+			// Sales of another instance are not seen until reconciliation
+			before := lotteries.calculateStock(lotteryTypeID)
+			sold := soldElsewhere
+			if sold > before {
+				sold = before
+			}
+			db.Model(&model.PrizePool{}).Where("lottery_type_id = ?", lotteryTypeID).
+				Update("sold_tickets", totalTickets-before+sold)
+			if stock := lotteries.calculateStock(lotteryTypeID); stock != before {
+				t.Logf("Counter changed to %d before reconciliation", stock)
+				return false
+			}
+			drifted, err := counter.Reconcile()
+			if err != nil || (sold > 0) != (drifted == 1) {
+				t.Logf("Reconcile corrected %d counters (err %v) after %d outside sales", drifted, err, sold)
+				return false
+			}
+			if stock := lotteries.calculateStock(lotteryTypeID); stock != before-sold {
+				t.Logf("Reconciled stock %d, expected %d", stock, before-sold)
+				return false
+			}
+			return true
+		},
+		gen.IntRange(1, 20),
+		gen.SliceOfN(4, gen.IntRange(1, 6)),
+		gen.IntRange(0, 5),
+	))
+
+	properties.TestingRun(t)
+}
+
+func TestStockCounterForgetsReplacedPools(t *testing.T) {
+	db, purchases, _, lotteryTypeID, _ := setupStockReservationTest(t, 5, 0)
+	counter := NewStockCounter(db, time.Hour)
+	lotteries := purchases.lotteryService
+	lotteries.UseStockCounter(counter)
+
+	if stock := lotteries.calculateStock(lotteryTypeID); stock != 5 {
+		t.Fatalf("Expected a stock of 5, got %d", stock)
+	}
+	db.Model(&model.PrizePool{}).Where("lottery_type_id = ?", lotteryTypeID).
+		Update("status", model.PrizePoolStatusClosed)
+	if _, err := lotteries.CreatePrizePool(CreatePrizePoolRequest{LotteryTypeID: lotteryTypeID, TotalTickets: 40}); err != nil {
+		t.Fatalf("CreatePrizePool failed: %v", err)
+	}
+	if stock := lotteries.calculateStock(lotteryTypeID); stock != 40 {
+		t.Errorf("Expected the new pool's stock of 40, got %d", stock)
+	}
+}
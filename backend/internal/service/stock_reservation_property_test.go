@@ -0,0 +1,166 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+	"gorm.io/gorm"
+)
+
+func setupStockReservationTest(t *testing.T, totalTickets, users int) (*gorm.DB, *PurchaseService, *StockReservationService, uint, []uint) {
+	db := setupTenantTestDB(t)
+	lotteryTypeID := createOddsHintPool(t, db, totalTickets, 0, nil)
+
+	userIDs := make([]uint, users)
+	for i := range userIDs {
+		user := model.User{LinuxdoID: fmt.Sprintf("reserve_user_%d", i), Username: fmt.Sprintf("User %d", i), Role: "user"}
+		db.Create(&user)
+		db.Create(&model.Wallet{UserID: user.ID, Balance: 1000})
+		userIDs[i] = user.ID
+	}
+	reservations := NewStockReservationService(db, time.Minute)
+	purchases := NewPurchaseService(db, NewLotteryService(db, testEncryptionKey), NewWalletService(db), nil, nil, reservations)
+	return db, purchases, reservations, lotteryTypeID, userIDs
+}
+
+// Stock reservations: live reservations of other users reduce the stock a
+// user sees, the user's own reservation does not, and reservations beyond
+// the stock are refused.
+func TestStockReservationMath(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("stock is net of other users' live reservations", prop.ForAll(
+		func(totalTickets int, quantities []int) bool {
+			_, purchases, reservations, lotteryTypeID, userIDs := setupStockReservationTest(t, totalTickets, len(quantities))
+			lotteries := purchases.lotteryService
+
+			held := make([]int, len(quantities))
+			reserved := 0
+			for i, quantity := range quantities {
+				_, err := reservations.Reserve(userIDs[i], lotteryTypeID, quantity)
+				if reserved+quantity > totalTickets {
+					if err != ErrLotteryTypeSoldOut {
+						t.Logf("Reserving %d of %d left: expected sold out, got %v", quantity, totalTickets-reserved, err)
+						return false
+					}
+					continue
+				}
+				if err != nil {
+					t.Logf("Reserve failed: %v", err)
+					return false
+				}
+				held[i] = quantity
+				reserved += quantity
+			}
+
+			if stock := lotteries.calculateStock(lotteryTypeID); stock != totalTickets-reserved {
+				t.Logf("Stock %d, expected %d", stock, totalTickets-reserved)
+				return false
+			}
+			for i := range quantities {
+				if stock := lotteries.calculateStockFor(userIDs[i], lotteryTypeID); stock != totalTickets-reserved+held[i] {
+					t.Logf("Stock for user %d is %d, expected %d", i, stock, totalTickets-reserved+held[i])
+					return false
+				}
+			}
+			return true
+		},
+		gen.IntRange(1, 20),
+		gen.SliceOfN(4, gen.IntRange(1, 10)),
+	))
+
+	properties.Property("a new reservation replaces the previous one", prop.ForAll(
+		func(first, second int) bool {
+			_, purchases, reservations, lotteryTypeID, userIDs := setupStockReservationTest(t, 10, 1)
+			if _, err := reservations.Reserve(userIDs[0], lotteryTypeID, first); err != nil {
+				t.Logf("First reserve failed: %v", err)
+				return false
+			}
+			if _, err := reservations.Reserve(userIDs[0], lotteryTypeID, second); err != nil {
+				t.Logf("Second reserve failed: %v", err)
+				return false
+			}
+			return purchases.lotteryService.calculateStock(lotteryTypeID) == 10-second
+		},
+		gen.IntRange(1, 10),
+		gen.IntRange(1, 10),
+	))
+
+	properties.TestingRun(t)
+}
+
+// Stock reservations: a preview reservation keeps the last tickets for its
+// holder until the purchase consumes it or it expires.
+func TestStockReservationPurchase(t *testing.T) {
+	t.Run("reserved tickets go to the holder", func(t *testing.T) {
+		db, purchases, _, lotteryTypeID, userIDs := setupStockReservationTest(t, 2, 2)
+		holder, other := userIDs[0], userIDs[1]
+
+		preview, err := purchases.GetPurchasePreview(holder, PurchaseRequest{LotteryTypeID: lotteryTypeID, Quantity: 2, Reserve: true})
+		if err != nil {
+			t.Fatalf("GetPurchasePreview failed: %v", err)
+		}
+		if preview["can_purchase"] != true || preview["reservation"] == nil {
+			t.Fatalf("Expected a purchasable preview with a reservation, got %v", preview)
+		}
+
+		otherPreview, err := purchases.GetPurchasePreview(other, PurchaseRequest{LotteryTypeID: lotteryTypeID, Quantity: 1, Reserve: true})
+		if err != nil {
+			t.Fatalf("GetPurchasePreview failed: %v", err)
+		}
+		if otherPreview["can_purchase"] != false || otherPreview["reservation"] != nil {
+			t.Errorf("Expected no stock for other users, got %v", otherPreview)
+		}
+		if _, err := purchases.PurchaseTickets(other, PurchaseRequest{LotteryTypeID: lotteryTypeID, Quantity: 1}); err != ErrLotteryTypeSoldOut {
+			t.Errorf("Expected other users to find the tickets sold out, got %v", err)
+		}
+
+		resp, err := purchases.PurchaseTickets(holder, PurchaseRequest{LotteryTypeID: lotteryTypeID, Quantity: 2})
+		if err != nil {
+			t.Fatalf("PurchaseTickets failed: %v", err)
+		}
+		if len(resp.Tickets) != 2 {
+			t.Errorf("Expected 2 tickets, got %d", len(resp.Tickets))
+		}
+
+		var count int64
+		db.Model(&model.StockReservation{}).Count(&count)
+		if count != 0 {
+			t.Errorf("Expected the purchase to consume the reservation, %d left", count)
+		}
+	})
+
+	t.Run("expired reservations release the stock", func(t *testing.T) {
+		db, purchases, reservations, lotteryTypeID, userIDs := setupStockReservationTest(t, 2, 2)
+		if _, err := reservations.Reserve(userIDs[0], lotteryTypeID, 2); err != nil {
+			t.Fatalf("Reserve failed: %v", err)
+		}
+		db.Model(&model.StockReservation{}).Where("user_id = ?", userIDs[0]).
+			Update("expires_at", time.Now().Add(-time.Second))
+
+		if stock := purchases.lotteryService.calculateStock(lotteryTypeID); stock != 2 {
+			t.Errorf("Expected expired reservations not to hold stock, got %d", stock)
+		}
+		if _, err := purchases.PurchaseTickets(userIDs[1], PurchaseRequest{LotteryTypeID: lotteryTypeID, Quantity: 1}); err != nil {
+			t.Errorf("Expected the purchase to succeed after expiry, got %v", err)
+		}
+
+		released, err := reservations.ReleaseExpired()
+		if err != nil {
+			t.Fatalf("ReleaseExpired failed: %v", err)
+		}
+		if released != 1 {
+			t.Errorf("Expected 1 released reservation, got %d", released)
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+	"scratch-lottery/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// StockReservationService holds lottery tickets for a user between the
+// purchase preview and the purchase, so the last tickets cannot be sold to
+// someone else meanwhile. A user holds at most one reservation per lottery
+// type; it is consumed by the purchase or lapses after the hold time.
+type StockReservationService struct {
+	db      *gorm.DB
+	holdFor time.Duration
+}
+
+// NewStockReservationService creates a new stock reservation service.
+// holdFor is how long a reservation holds the tickets.
+func NewStockReservationService(db *gorm.DB, holdFor time.Duration) *StockReservationService {
+	if holdFor <= 0 {
+		holdFor = 2 * time.Minute
+	}
+	return &StockReservationService{db: db, holdFor: holdFor}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *StockReservationService) ForTenant(tenantID uint) *StockReservationService {
+	return &StockReservationService{
+		db:      repository.ScopeTenant(s.db, tenantID),
+		holdFor: s.holdFor,
+	}
+}
+
+// StockReservationResponse represents the tickets held for a user
+type StockReservationResponse struct {
+	ID            uint      `json:"id"`
+	LotteryTypeID uint      `json:"lottery_type_id"`
+	Quantity      int       `json:"quantity"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+// Reserve holds quantity tickets of a lottery type for the user, replacing
+// any reservation the user already holds for it. It fails with
+// ErrLotteryTypeSoldOut when other users' purchases and reservations leave
+// fewer tickets.
+func (s *StockReservationService) Reserve(userID, lotteryTypeID uint, quantity int) (*StockReservationResponse, error) {
+	reservation := model.StockReservation{
+		UserID:        userID,
+		LotteryTypeID: lotteryTypeID,
+		Quantity:      quantity,
+		ExpiresAt:     time.Now().Add(s.holdFor),
+	}
+	err := repository.Transaction(s.db, func(tx *gorm.DB) error {
+		reservation.ID = 0
+		if err := s.release(tx, userID, lotteryTypeID); err != nil {
+			return err
+		}
+		if availableStock(tx, lotteryTypeID, userID) < quantity {
+			return ErrLotteryTypeSoldOut
+		}
+		return tx.Create(&reservation).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &StockReservationResponse{
+		ID:            reservation.ID,
+		LotteryTypeID: reservation.LotteryTypeID,
+		Quantity:      reservation.Quantity,
+		ExpiresAt:     reservation.ExpiresAt,
+	}, nil
+}
+
+// Consume removes the reservation of the user for a lottery type once the
+// purchase it held the tickets for went through
+func (s *StockReservationService) Consume(userID, lotteryTypeID uint) error {
+	return s.release(s.db, userID, lotteryTypeID)
+}
+
+// ReleaseExpired removes every reservation past its deadline. Expired
+// reservations already stopped counting against the stock; this only keeps
+// the table small.
+func (s *StockReservationService) ReleaseExpired() (int, error) {
+	result := s.db.Where("expires_at <= ?", time.Now()).Delete(&model.StockReservation{})
+	return int(result.RowsAffected), result.Error
+}
+
+// Start releases expired reservations every interval until the returned stop func is called
+func (s *StockReservationService) Start(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				released, err := s.ReleaseExpired()
+				if err != nil {
+					logger.Default().Warn("Releasing expired stock reservations failed: %v", err)
+				} else if released > 0 {
+					logger.Default().Info("Released %d expired stock reservations", released)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// release removes the reservations of the user for a lottery type
+func (s *StockReservationService) release(tx *gorm.DB, userID, lotteryTypeID uint) error {
+	return tx.Where("user_id = ? AND lottery_type_id = ?", userID, lotteryTypeID).
+		Delete(&model.StockReservation{}).Error
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+	"gorm.io/gorm"
+)
+
+func setupStreakTestDB(t *testing.T) (*gorm.DB, *StreakService, uint) {
+	db := setupTenantTestDB(t)
+	if err := db.AutoMigrate(&model.ScratchStreak{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	user := model.User{LinuxdoID: "streaker", Username: "streaker"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if err := db.Create(&model.Wallet{UserID: user.ID}).Error; err != nil {
+		t.Fatalf("Failed to create wallet: %v", err)
+	}
+	walletService := NewWalletService(db)
+	return db, NewStreakService(db, NewAdminService(db, walletService)), user.ID
+}
+
+// Streak bonuses: scratching on consecutive days grows the streak, a missed
+// day restarts it, extra scratches on the same day change nothing, and every
+// rule the streak reaches is credited exactly once with its own transaction type.
+func TestStreakBonusCredits(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("bonuses follow the streak", prop.ForAll(
+		func(gaps []int) bool {
+			db, streaks, userID := setupStreakTestDB(t)
+			rules := []StreakRule{{Days: 3, Points: 10}, {Days: 2, Points: 1, Repeat: true}}
+			if _, err := streaks.UpdateRules(1, UpdateStreakRulesRequest{Rules: rules}); err != nil {
+				t.Logf("UpdateRules failed: %v", err)
+				return false
+			}
+			loaded, err := streaks.GetRules()
+			if err != nil || len(loaded) != 2 || loaded[0].Days != 2 {
+				t.Logf("Expected rules ordered by days, got %+v (err %v)", loaded, err)
+				return false
+			}
+
+			var wallet model.Wallet
+			db.Where("user_id = ?", userID).First(&wallet)
+			initialBalance := wallet.Balance
+
+			// gaps: 0 scratches again the same day, 1 the next day, 2+ skips days
+			day := time.Date(2024, 3, 1, 12, 0, 0, 0, time.Local)
+			streak, longest, expected := 0, 0, 0
+			for i, gap := range gaps {
+				day = day.AddDate(0, 0, gap)
+				if i == 0 || gap > 1 {
+					streak = 1
+				} else if gap == 1 {
+					streak++
+				}
+				if streak > longest {
+					longest = streak
+				}
+				want := 0
+				if i == 0 || gap > 0 {
+					want = streakBonus(loaded, streak)
+				}
+				expected += want
+
+				var bonus int
+				err := db.Transaction(func(tx *gorm.DB) error {
+					var err error
+					bonus, err = streaks.recordScratch(tx, loaded, userID, uint(i+1), day)
+					return err
+				})
+				if err != nil || bonus != want {
+					t.Logf("Scratch %d (gap %d, streak %d): expected bonus %d, got %d (err %v)", i, gap, streak, want, bonus, err)
+					return false
+				}
+			}
+
+			var record model.ScratchStreak
+			db.Where("user_id = ?", userID).First(&record)
+			if record.CurrentStreak != streak || record.LongestStreak != longest || record.TotalBonus != expected {
+				t.Logf("Expected streak %d/%d with %d bonus, got %+v", streak, longest, expected, record)
+				return false
+			}
+
+			db.First(&wallet, wallet.ID)
+			var credited int64
+			db.Model(&model.Transaction{}).Where("wallet_id = ? AND type = ?", wallet.ID, model.TransactionTypeStreakBonus).
+				Select("COALESCE(SUM(amount), 0)").Scan(&credited)
+			if wallet.Balance != initialBalance+expected || int(credited) != expected {
+				t.Logf("Expected %d credited, balance %d and %d in transactions", expected, wallet.Balance, credited)
+				return false
+			}
+			return true
+		},
+		gen.SliceOfN(12, gen.IntRange(0, 2)),
+	))
+
+	properties.TestingRun(t)
+}
+
+func TestStreakRulesAndStatus(t *testing.T) {
+	db, streaks, userID := setupStreakTestDB(t)
+
+	cases := [][]StreakRule{
+		{{Days: 1, Points: 5}},
+		{{Days: 3, Points: 0}},
+		{{Days: 3, Points: 5}, {Days: 3, Points: 7}},
+		{{Days: maxStreakDays + 1, Points: 5}},
+	}
+	for i, rules := range cases {
+		if _, err := streaks.UpdateRules(1, UpdateStreakRulesRequest{Rules: rules}); err != ErrInvalidStreakRule {
+			t.Errorf("Case %d: expected ErrInvalidStreakRule, got %v", i, err)
+		}
+	}
+	if _, err := streaks.UpdateRules(1, UpdateStreakRulesRequest{Rules: []StreakRule{{Days: 5, Points: 50}}}); err != nil {
+		t.Fatalf("UpdateRules failed: %v", err)
+	}
+
+	// Rules are kept per tenant
+	if rules, _ := streaks.ForTenant(2).GetRules(); len(rules) != 0 {
+		t.Errorf("Expected no rules for tenant 2, got %+v", rules)
+	}
+
+	status, err := streaks.GetStatus(userID)
+	if err != nil || status.CurrentStreak != 0 || status.NextReward == nil || status.NextReward.DaysLeft != 5 {
+		t.Fatalf("Expected a fresh streak 5 days from a reward, got %+v (err %v)", status, err)
+	}
+
+	// A streak continued yesterday is still current; an older one is broken
+	db.Create(&model.ScratchStreak{UserID: userID, CurrentStreak: 3, LongestStreak: 4,
+		LastScratchDate: time.Now().AddDate(0, 0, -1).Format(streakDateLayout)})
+	status, _ = streaks.GetStatus(userID)
+	if status.CurrentStreak != 3 || status.ScratchedToday || status.NextReward.DaysLeft != 2 {
+		t.Errorf("Expected a 3 day streak 2 days from a reward, got %+v", status)
+	}
+	db.Model(&model.ScratchStreak{}).Where("user_id = ?", userID).
+		Update("last_scratch_date", time.Now().AddDate(0, 0, -2).Format(streakDateLayout))
+	status, _ = streaks.GetStatus(userID)
+	if status.CurrentStreak != 0 || status.LongestStreak != 4 {
+		t.Errorf("Expected a broken streak, got %+v", status)
+	}
+
+	// Profiles carry the streak
+	profile, err := NewUserService(db, NewWalletService(db), streaks).GetUserProfile(userID)
+	if err != nil || profile.Streak == nil || profile.Streak.LongestStreak != 4 {
+		t.Errorf("Expected streak in profile, got %+v (err %v)", profile, err)
+	}
+}
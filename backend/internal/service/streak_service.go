@@ -0,0 +1,282 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+	"scratch-lottery/pkg/biztime"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ConfigKeyStreakRules holds the streak reward rules as JSON
+const ConfigKeyStreakRules = "streak_rules"
+
+// streakDateLayout is the layout of streak days, which are business dates
+const streakDateLayout = biztime.DateLayout
+
+// Streak rule limits
+const (
+	maxStreakRules  = 10
+	maxStreakDays   = 365
+	maxStreakPoints = 100000
+)
+
+var (
+	ErrInvalidStreakRule = errors.New("invalid streak rule")
+)
+
+// StreakRule rewards a streak reaching Days consecutive days with Points.
+// Repeating rules pay again at every multiple of Days.
+type StreakRule struct {
+	Days   int  `json:"days"`
+	Points int  `json:"points"`
+	Repeat bool `json:"repeat"`
+}
+
+// matches reports whether the rule pays out when a streak reaches days
+func (r StreakRule) matches(days int) bool {
+	if r.Repeat {
+		return days%r.Days == 0
+	}
+	return days == r.Days
+}
+
+// UpdateStreakRulesRequest represents the request to replace the streak rules
+type UpdateStreakRulesRequest struct {
+	Rules []StreakRule `json:"rules"`
+}
+
+// StreakRulesResponse represents the configured streak rules
+type StreakRulesResponse struct {
+	Rules []StreakRule `json:"rules"`
+}
+
+// StreakNextReward is the next bonus a continued streak earns
+type StreakNextReward struct {
+	Days     int `json:"days"`
+	Points   int `json:"points"`
+	DaysLeft int `json:"days_left"`
+}
+
+// StreakStatus represents the scratch streak of a user
+type StreakStatus struct {
+	CurrentStreak   int               `json:"current_streak"`
+	LongestStreak   int               `json:"longest_streak"`
+	LastScratchDate string            `json:"last_scratch_date,omitempty"`
+	ScratchedToday  bool              `json:"scratched_today"`
+	TotalBonus      int               `json:"total_bonus"`
+	NextReward      *StreakNextReward `json:"next_reward,omitempty"`
+}
+
+// StreakService tracks daily scratch streaks and credits streak bonuses.
+// Rules are kept in the system config of each tenant.
+type StreakService struct {
+	db           *gorm.DB
+	adminService *AdminService
+}
+
+// NewStreakService creates a new streak service
+func NewStreakService(db *gorm.DB, adminService *AdminService) *StreakService {
+	return &StreakService{db: db, adminService: adminService}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *StreakService) ForTenant(tenantID uint) *StreakService {
+	return &StreakService{
+		db:           repository.ScopeTenant(s.db, tenantID),
+		adminService: s.adminService.ForTenant(tenantID),
+	}
+}
+
+// GetRules returns the streak reward rules ordered by days. No rules means
+// streaks are tracked without bonuses.
+func (s *StreakService) GetRules() ([]StreakRule, error) {
+	value, err := s.adminService.GetConfigValue(ConfigKeyStreakRules)
+	if errors.Is(err, ErrConfigNotFound) || value == "" {
+		return []StreakRule{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []StreakRule
+	if err := json.Unmarshal([]byte(value), &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// UpdateRules validates and replaces the streak reward rules
+func (s *StreakService) UpdateRules(adminID uint, req UpdateStreakRulesRequest) (*StreakRulesResponse, error) {
+	if len(req.Rules) > maxStreakRules {
+		return nil, ErrInvalidStreakRule
+	}
+	rules := append([]StreakRule{}, req.Rules...)
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Days < rules[j].Days })
+	for i, rule := range rules {
+		if rule.Days < 2 || rule.Days > maxStreakDays || rule.Points <= 0 || rule.Points > maxStreakPoints {
+			return nil, ErrInvalidStreakRule
+		}
+		if i > 0 && rules[i-1].Days == rule.Days {
+			return nil, ErrInvalidStreakRule
+		}
+	}
+
+	rulesJSON, err := json.Marshal(rules)
+	if err != nil {
+		return nil, err
+	}
+	err = s.adminService.configs().Transaction(func(tx *gorm.DB) error {
+		if err := s.adminService.upsertConfig(tx, ConfigKeyStreakRules, string(rulesJSON)); err != nil {
+			return err
+		}
+		adminLog := model.AdminLog{
+			AdminID:    adminID,
+			Action:     "update_streak_rules",
+			TargetType: "system",
+			TargetID:   0,
+			Details:    string(rulesJSON),
+		}
+		return tx.Create(&adminLog).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &StreakRulesResponse{Rules: rules}, nil
+}
+
+// GetStatus returns the scratch streak of a user. A streak not continued
+// yesterday or today is reported as broken.
+func (s *StreakService) GetStatus(userID uint) (*StreakStatus, error) {
+	rules, err := s.GetRules()
+	if err != nil {
+		return nil, err
+	}
+
+	var streak model.ScratchStreak
+	if err := s.db.Where("user_id = ?", userID).First(&streak).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	now := time.Now()
+	today := biztime.Date(now)
+	yesterday := biztime.Date(biztime.DayStart(now).AddDate(0, 0, -1))
+
+	status := &StreakStatus{
+		LongestStreak:   streak.LongestStreak,
+		LastScratchDate: streak.LastScratchDate,
+		ScratchedToday:  streak.LastScratchDate == today,
+		TotalBonus:      streak.TotalBonus,
+	}
+	if streak.LastScratchDate == today || streak.LastScratchDate == yesterday {
+		status.CurrentStreak = streak.CurrentStreak
+	}
+
+	// The next bonus is counted from the streak after the next day's scratch
+	for days := status.CurrentStreak + 1; days <= status.CurrentStreak+maxStreakDays; days++ {
+		if points := streakBonus(rules, days); points > 0 {
+			status.NextReward = &StreakNextReward{Days: days, Points: points, DaysLeft: days - status.CurrentStreak}
+			break
+		}
+	}
+	return status, nil
+}
+
+// recordScratch advances the streak of a user for a scratch at the given time
+// and credits the bonus of any rule the new streak reaches. It runs inside the
+// scratch transaction and returns the credited bonus. Only the first scratch of
+// a day moves the streak, guarded against concurrent scratches.
+func (s *StreakService) recordScratch(tx *gorm.DB, rules []StreakRule, userID, ticketID uint, at time.Time) (int, error) {
+	today := biztime.Date(at)
+	yesterday := biztime.Date(biztime.DayStart(at).AddDate(0, 0, -1))
+
+	var streak model.ScratchStreak
+	err := tx.Where("user_id = ?", userID).First(&streak).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, err
+	}
+	exists := err == nil
+	if exists && streak.LastScratchDate == today {
+		return 0, nil
+	}
+
+	current := 1
+	if exists && streak.LastScratchDate == yesterday {
+		current = streak.CurrentStreak + 1
+	}
+	longest := streak.LongestStreak
+	if current > longest {
+		longest = current
+	}
+	bonus := streakBonus(rules, current)
+
+	var result *gorm.DB
+	if exists {
+		result = tx.Model(&model.ScratchStreak{}).
+			Where("id = ? AND last_scratch_date = ?", streak.ID, streak.LastScratchDate).
+			Updates(map[string]interface{}{
+				"current_streak":    current,
+				"longest_streak":    longest,
+				"last_scratch_date": today,
+				"total_bonus":       gorm.Expr("total_bonus + ?", bonus),
+			})
+	} else {
+		streak = model.ScratchStreak{
+			UserID:          userID,
+			CurrentStreak:   current,
+			LongestStreak:   longest,
+			LastScratchDate: today,
+			TotalBonus:      bonus,
+		}
+		result = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&streak)
+	}
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		// Another scratch moved the streak first
+		return 0, nil
+	}
+
+	if bonus > 0 {
+		var wallet model.Wallet
+		if err := lockWallet(tx, userID, &wallet); err != nil {
+			return 0, err
+		}
+		wallet.Balance += bonus
+		if err := tx.Save(&wallet).Error; err != nil {
+			return 0, err
+		}
+
+		transaction := model.Transaction{
+			TenantID:    wallet.TenantID,
+			WalletID:    wallet.ID,
+			Type:        model.TransactionTypeStreakBonus,
+			Amount:      bonus,
+			Description: fmt.Sprintf("连续刮奖%d天奖励", current),
+			ReferenceID: ticketID,
+		}
+		if err := tx.Create(&transaction).Error; err != nil {
+			return 0, err
+		}
+	}
+	return bonus, nil
+}
+
+// streakBonus returns the points all rules pay when a streak reaches days
+func streakBonus(rules []StreakRule, days int) int {
+	points := 0
+	for _, rule := range rules {
+		if rule.Days > 0 && rule.matches(days) {
+			points += rule.Points
+		}
+	}
+	return points
+}
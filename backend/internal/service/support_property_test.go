@@ -0,0 +1,193 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+	"gorm.io/gorm"
+)
+
+func setupSupportTest(t *testing.T) (*gorm.DB, *SupportService, uint) {
+	db := setupTenantTestDB(t)
+	if err := db.AutoMigrate(&model.Notification{}, &model.PaymentOrder{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	user := model.User{LinuxdoID: "reporter", Username: "reporter", Role: "user"}
+	db.Create(&user)
+	db.Create(&model.Wallet{UserID: user.ID})
+
+	adminService := NewAdminService(db, NewWalletService(db))
+	support := NewSupportService(db, adminService)
+	support.UseNotifications(NewNotificationService(db))
+	return db, support.ForTenant(1), user.ID
+}
+
+var supportPriorities = []model.SupportIssuePriority{
+	model.SupportIssuePriorityLow, model.SupportIssuePriorityNormal,
+	model.SupportIssuePriorityHigh, model.SupportIssuePriorityUrgent,
+}
+
+// Support SLA: an issue is due the SLA hours of its priority after it was
+// filed, and unresolved issues past that deadline are counted as overdue on
+// the dashboard and listed by the overdue filter.
+func TestSupportIssueSLA(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("deadlines follow the SLA and overdue issues are counted", prop.ForAll(
+		func(priorities []int, ages []int, resolved []bool) bool {
+			db, support, userID := setupSupportTest(t)
+			sla, _ := support.GetSLA()
+
+			expectedOverdue := int64(0)
+			expectedOpen := int64(0)
+			for i, p := range priorities {
+				// Users may only have a few unresolved issues, so file each
+				// for a user of its own
+				reporter := model.User{LinuxdoID: fmt.Sprintf("reporter_%d", i), Username: "r", Role: "user"}
+				db.Create(&reporter)
+
+				issue, err := support.CreateIssue(reporter.ID, CreateSupportIssueRequest{
+					Category: model.SupportCategoryOther, Subject: "Help", Description: "Something broke",
+				})
+				if err != nil {
+					t.Logf("CreateIssue failed: %v", err)
+					return false
+				}
+				priority := supportPriorities[p]
+				if _, err := support.UpdateIssue(userID, issue.ID, UpdateSupportIssueRequest{Priority: &priority}); err != nil {
+					t.Logf("UpdateIssue failed: %v", err)
+					return false
+				}
+
+				// File the issue ages[i] hours ago
+				filed := time.Now().UTC().Add(-time.Duration(ages[i]) * time.Hour)
+				db.Model(&model.SupportIssue{}).Where("id = ?", issue.ID).Update("created_at", filed)
+				status := model.SupportIssueStatusInProgress
+				if resolved[i] {
+					status = model.SupportIssueStatusResolved
+				}
+				// Changing the priority back and forth recomputes the deadline
+				// from the backdated filing time
+				other := model.SupportIssuePriorityNormal
+				if priority == other {
+					other = model.SupportIssuePriorityLow
+				}
+				support.UpdateIssue(userID, issue.ID, UpdateSupportIssueRequest{Priority: &other})
+				updated, err := support.UpdateIssue(userID, issue.ID, UpdateSupportIssueRequest{Priority: &priority, Status: &status})
+				if err != nil {
+					t.Logf("UpdateIssue failed: %v", err)
+					return false
+				}
+
+				due := filed.Add(time.Duration(sla.Hours(priority)) * time.Hour)
+				if updated.DueAt.Sub(due).Abs() > time.Second {
+					t.Logf("Issue %d of priority %s due %v, expected %v", issue.ID, priority, updated.DueAt, due)
+					return false
+				}
+				overdue := !resolved[i] && ages[i] >= sla.Hours(priority)
+				if updated.Overdue != overdue {
+					t.Logf("Issue %d overdue %v, expected %v", issue.ID, updated.Overdue, overdue)
+					return false
+				}
+				if !resolved[i] {
+					expectedOpen++
+				}
+				if overdue {
+					expectedOverdue++
+				}
+			}
+
+			stats, err := NewAdminService(db, NewWalletService(db)).ForTenant(1).GetDashboardStats()
+			if err != nil {
+				t.Logf("GetDashboardStats failed: %v", err)
+				return false
+			}
+			if stats.OpenSupportIssues != expectedOpen || stats.OverdueSupportIssues != expectedOverdue {
+				t.Logf("Expected %d open and %d overdue issues, got %d and %d",
+					expectedOpen, expectedOverdue, stats.OpenSupportIssues, stats.OverdueSupportIssues)
+				return false
+			}
+			list, err := support.ListIssues(SupportIssueQuery{Overdue: true, Limit: 100})
+			if err != nil || list.Total != expectedOverdue {
+				t.Logf("Expected %d overdue issues listed, got %+v (err %v)", expectedOverdue, list, err)
+				return false
+			}
+			for _, issue := range list.Issues {
+				if !issue.Overdue {
+					t.Logf("Issue %d listed as overdue is not", issue.ID)
+					return false
+				}
+			}
+			return true
+		},
+		gen.SliceOfN(4, gen.IntRange(0, 3)),
+		gen.SliceOfN(4, gen.IntRange(0, 100)),
+		gen.SliceOfN(4, gen.Bool()),
+	))
+
+	properties.TestingRun(t)
+}
+
+// Issues only link the reporter's own orders, tickets and exchange records,
+// users are limited in unresolved issues, and status changes notify them
+func TestSupportIssueTriage(t *testing.T) {
+	db, support, userID := setupSupportTest(t)
+	other := model.User{LinuxdoID: "other", Username: "other", Role: "user"}
+	db.Create(&other)
+	db.Create(&model.PaymentOrder{UserID: userID, OrderNo: "R1", Amount: 100, Points: 100})
+	db.Create(&model.PaymentOrder{UserID: other.ID, OrderNo: "R2", Amount: 100, Points: 100})
+
+	req := CreateSupportIssueRequest{Category: model.SupportCategoryPaymentMissing, Subject: "Recharge", Description: "Paid but no points", OrderNo: "R2"}
+	if _, err := support.CreateIssue(userID, req); !errors.Is(err, ErrSupportLinkNotFound) {
+		t.Fatalf("Expected another user's order to be refused, got %v", err)
+	}
+	req.OrderNo = "R1"
+	issue, err := support.CreateIssue(userID, req)
+	if err != nil {
+		t.Fatalf("CreateIssue failed: %v", err)
+	}
+	if issue.Priority != model.SupportIssuePriorityHigh || issue.Status != model.SupportIssueStatusOpen {
+		t.Errorf("Expected an open issue of high priority, got %s %s", issue.Status, issue.Priority)
+	}
+	if _, err := support.GetUserIssue(other.ID, issue.ID); !errors.Is(err, ErrSupportIssueNotFound) {
+		t.Errorf("Expected the issue hidden from other users, got %v", err)
+	}
+
+	for i := 1; i < maxOpenSupportIssues; i++ {
+		if _, err := support.CreateIssue(userID, CreateSupportIssueRequest{Category: model.SupportCategoryOther, Subject: "S", Description: "D"}); err != nil {
+			t.Fatalf("CreateIssue %d failed: %v", i, err)
+		}
+	}
+	if _, err := support.CreateIssue(userID, CreateSupportIssueRequest{Category: model.SupportCategoryOther, Subject: "S", Description: "D"}); !errors.Is(err, ErrTooManySupportIssues) {
+		t.Errorf("Expected the open issue limit, got %v", err)
+	}
+
+	resolved := model.SupportIssueStatusResolved
+	updated, err := support.UpdateIssue(1, issue.ID, UpdateSupportIssueRequest{Status: &resolved})
+	if err != nil {
+		t.Fatalf("UpdateIssue failed: %v", err)
+	}
+	if updated.FirstResponseAt == nil || updated.ResolvedAt == nil || updated.Overdue {
+		t.Errorf("Expected a responded, resolved issue, got %+v", updated)
+	}
+	var notifications int64
+	db.Model(&model.Notification{}).Where("user_id = ? AND type = ?", userID, model.NotificationTypeSupportIssue).Count(&notifications)
+	if notifications != 1 {
+		t.Errorf("Expected the user notified once, got %d", notifications)
+	}
+	// Resolving frees a slot
+	if _, err := support.CreateIssue(userID, CreateSupportIssueRequest{Category: model.SupportCategoryOther, Subject: "S", Description: "D"}); err != nil {
+		t.Errorf("Expected a new issue after resolving one, got %v", err)
+	}
+}
@@ -0,0 +1,497 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// ConfigKeySupportSLA holds the SLA hours of each support issue priority as JSON
+const ConfigKeySupportSLA = "support_sla"
+
+// maxOpenSupportIssues is the number of unresolved issues a user may have
+const maxOpenSupportIssues = 5
+
+var (
+	ErrSupportIssueNotFound = errors.New("support issue not found")
+	ErrInvalidSupportIssue  = errors.New("invalid support issue")
+	ErrSupportLinkNotFound  = errors.New("linked order, ticket or exchange record not found")
+	ErrTooManySupportIssues = errors.New("too many unresolved support issues")
+	ErrInvalidSupportSLA    = errors.New("invalid support SLA")
+)
+
+// supportCategories are the categories users file issues under
+var supportCategories = map[string]bool{
+	model.SupportCategoryPaymentMissing: true,
+	model.SupportCategoryTicketStuck:    true,
+	model.SupportCategoryExchange:       true,
+	model.SupportCategoryAccount:        true,
+	model.SupportCategoryOther:          true,
+}
+
+// activeSupportStatuses are the statuses of issues still waiting on support
+var activeSupportStatuses = []model.SupportIssueStatus{model.SupportIssueStatusOpen, model.SupportIssueStatusInProgress}
+
+// SupportSLA is the number of hours support has to resolve an issue of each
+// priority
+type SupportSLA struct {
+	Urgent int `json:"urgent"`
+	High   int `json:"high"`
+	Normal int `json:"normal"`
+	Low    int `json:"low"`
+}
+
+// DefaultSupportSLA applies until admins configure one
+var DefaultSupportSLA = SupportSLA{Urgent: 2, High: 8, Normal: 24, Low: 72}
+
+// Hours returns the SLA hours of a priority
+func (sla SupportSLA) Hours(priority model.SupportIssuePriority) int {
+	switch priority {
+	case model.SupportIssuePriorityUrgent:
+		return sla.Urgent
+	case model.SupportIssuePriorityHigh:
+		return sla.High
+	case model.SupportIssuePriorityLow:
+		return sla.Low
+	default:
+		return sla.Normal
+	}
+}
+
+// SupportService handles the issues users report to support and their SLA
+type SupportService struct {
+	db            *gorm.DB
+	adminService  *AdminService
+	notifications *NotificationService // Optional, tells users of status changes
+}
+
+// NewSupportService creates a new support service
+func NewSupportService(db *gorm.DB, adminService *AdminService) *SupportService {
+	return &SupportService{db: db, adminService: adminService}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *SupportService) ForTenant(tenantID uint) *SupportService {
+	scoped := &SupportService{
+		db:           repository.ScopeTenant(s.db, tenantID),
+		adminService: s.adminService.ForTenant(tenantID),
+	}
+	if s.notifications != nil {
+		scoped.notifications = s.notifications.ForTenant(tenantID)
+	}
+	return scoped
+}
+
+// UseNotifications notifies users when support changes the status of their issues
+func (s *SupportService) UseNotifications(notifications *NotificationService) {
+	s.notifications = notifications
+}
+
+// CreateSupportIssueRequest represents an issue submitted by a user
+type CreateSupportIssueRequest struct {
+	Category         string `json:"category" binding:"required"`
+	Subject          string `json:"subject" binding:"required,max=128"`
+	Description      string `json:"description" binding:"required,max=2000"`
+	OrderNo          string `json:"order_no" binding:"max=64"`
+	TicketID         uint   `json:"ticket_id"`
+	ExchangeRecordID uint   `json:"exchange_record_id"`
+}
+
+// UpdateSupportIssueRequest represents the triage of an issue by an admin.
+// Links set to empty or zero are removed.
+type UpdateSupportIssueRequest struct {
+	Status           *model.SupportIssueStatus   `json:"status"`
+	Priority         *model.SupportIssuePriority `json:"priority"`
+	AssigneeID       *uint                       `json:"assignee_id"`
+	Resolution       *string                     `json:"resolution" binding:"omitempty,max=2000"`
+	OrderNo          *string                     `json:"order_no" binding:"omitempty,max=64"`
+	TicketID         *uint                       `json:"ticket_id"`
+	ExchangeRecordID *uint                       `json:"exchange_record_id"`
+}
+
+// SupportIssueQuery represents the filters of the admin issue list
+type SupportIssueQuery struct {
+	Status   string `form:"status"`
+	Priority string `form:"priority"`
+	Category string `form:"category"`
+	UserID   uint   `form:"user_id"`
+	Overdue  bool   `form:"overdue"`
+	Page     int    `form:"page"`
+	Limit    int    `form:"limit"`
+}
+
+// SupportIssueResponse represents an issue. Overdue issues are unresolved
+// past their deadline; SLABreached also covers issues resolved late.
+type SupportIssueResponse struct {
+	model.SupportIssue
+	Username    string `json:"username,omitempty"`
+	Overdue     bool   `json:"overdue"`
+	SLABreached bool   `json:"sla_breached"`
+}
+
+// SupportIssueListResponse represents a paginated list of issues
+type SupportIssueListResponse struct {
+	Issues     []SupportIssueResponse `json:"issues"`
+	Total      int64                  `json:"total"`
+	Page       int                    `json:"page"`
+	Limit      int                    `json:"limit"`
+	TotalPages int                    `json:"total_pages"`
+}
+
+// GetSLA returns the SLA hours of each priority
+func (s *SupportService) GetSLA() (*SupportSLA, error) {
+	sla := DefaultSupportSLA
+	value, err := s.adminService.GetConfigValue(ConfigKeySupportSLA)
+	if errors.Is(err, ErrConfigNotFound) || value == "" {
+		return &sla, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(value), &sla); err != nil {
+		return nil, err
+	}
+	return &sla, nil
+}
+
+// UpdateSLA replaces the SLA hours. Deadlines of existing issues are kept
+// until their priority changes.
+func (s *SupportService) UpdateSLA(adminID uint, req SupportSLA) (*SupportSLA, error) {
+	for _, hours := range []int{req.Urgent, req.High, req.Normal, req.Low} {
+		if hours <= 0 || hours > 24*30 {
+			return nil, ErrInvalidSupportSLA
+		}
+	}
+	slaJSON, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	err = s.adminService.configs().Transaction(func(tx *gorm.DB) error {
+		if err := s.adminService.upsertConfig(tx, ConfigKeySupportSLA, string(slaJSON)); err != nil {
+			return err
+		}
+		adminLog := model.AdminLog{
+			AdminID:    adminID,
+			Action:     "update_support_sla",
+			TargetType: "system",
+			TargetID:   0,
+			Details:    string(slaJSON),
+		}
+		return tx.Create(&adminLog).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// CreateIssue files an issue for a user. Missing payments are handled with
+// high priority, everything else with normal priority until triaged.
+func (s *SupportService) CreateIssue(userID uint, req CreateSupportIssueRequest) (*SupportIssueResponse, error) {
+	if !supportCategories[req.Category] {
+		return nil, ErrInvalidSupportIssue
+	}
+	var open int64
+	if err := s.db.Model(&model.SupportIssue{}).
+		Where("user_id = ? AND status IN ?", userID, activeSupportStatuses).
+		Count(&open).Error; err != nil {
+		return nil, err
+	}
+	if open >= maxOpenSupportIssues {
+		return nil, ErrTooManySupportIssues
+	}
+
+	issue := model.SupportIssue{
+		UserID:           userID,
+		Category:         req.Category,
+		Subject:          req.Subject,
+		Description:      req.Description,
+		Status:           model.SupportIssueStatusOpen,
+		Priority:         model.SupportIssuePriorityNormal,
+		OrderNo:          req.OrderNo,
+		TicketID:         req.TicketID,
+		ExchangeRecordID: req.ExchangeRecordID,
+	}
+	if req.Category == model.SupportCategoryPaymentMissing {
+		issue.Priority = model.SupportIssuePriorityHigh
+	}
+	if err := s.checkLinks(&issue); err != nil {
+		return nil, err
+	}
+	sla, err := s.GetSLA()
+	if err != nil {
+		return nil, err
+	}
+	issue.DueAt = time.Now().UTC().Add(time.Duration(sla.Hours(issue.Priority)) * time.Hour)
+
+	if err := s.db.Create(&issue).Error; err != nil {
+		return nil, err
+	}
+	return s.toResponse(issue, ""), nil
+}
+
+// GetUserIssues returns a page of a user's issues, newest first
+func (s *SupportService) GetUserIssues(userID uint, page, limit int) (*SupportIssueListResponse, error) {
+	return s.list(SupportIssueQuery{UserID: userID, Page: page, Limit: limit}, "created_at DESC, id DESC")
+}
+
+// GetUserIssue returns an issue of a user
+func (s *SupportService) GetUserIssue(userID, issueID uint) (*SupportIssueResponse, error) {
+	issue, err := s.issue(issueID)
+	if err != nil {
+		return nil, err
+	}
+	if issue.UserID != userID {
+		return nil, ErrSupportIssueNotFound
+	}
+	return s.toResponse(*issue, ""), nil
+}
+
+// ListIssues returns a page of issues for triage: unresolved issues first,
+// earliest deadline first
+func (s *SupportService) ListIssues(query SupportIssueQuery) (*SupportIssueListResponse, error) {
+	return s.list(query, "CASE WHEN status IN ('open', 'in_progress') THEN 0 ELSE 1 END, due_at ASC, id ASC")
+}
+
+// GetIssue returns an issue
+func (s *SupportService) GetIssue(issueID uint) (*SupportIssueResponse, error) {
+	issue, err := s.issue(issueID)
+	if err != nil {
+		return nil, err
+	}
+	var user model.User
+	s.db.Unscoped().Select("id", "username").First(&user, issue.UserID)
+	return s.toResponse(*issue, user.Username), nil
+}
+
+// UpdateIssue triages an issue. The first update marks the first response;
+// a new priority moves the deadline to the SLA of that priority counted from
+// when the issue was filed. The user is notified of status changes.
+func (s *SupportService) UpdateIssue(adminID, issueID uint, req UpdateSupportIssueRequest) (*SupportIssueResponse, error) {
+	issue, err := s.issue(issueID)
+	if err != nil {
+		return nil, err
+	}
+	previousStatus := issue.Status
+	now := time.Now().UTC()
+
+	if req.Status != nil {
+		switch *req.Status {
+		case model.SupportIssueStatusOpen, model.SupportIssueStatusInProgress:
+			issue.ResolvedAt = nil
+		case model.SupportIssueStatusResolved, model.SupportIssueStatusClosed:
+			if issue.ResolvedAt == nil {
+				issue.ResolvedAt = &now
+			}
+		default:
+			return nil, ErrInvalidSupportIssue
+		}
+		issue.Status = *req.Status
+	}
+	if req.Priority != nil && *req.Priority != issue.Priority {
+		sla, err := s.GetSLA()
+		if err != nil {
+			return nil, err
+		}
+		switch *req.Priority {
+		case model.SupportIssuePriorityLow, model.SupportIssuePriorityNormal,
+			model.SupportIssuePriorityHigh, model.SupportIssuePriorityUrgent:
+		default:
+			return nil, ErrInvalidSupportIssue
+		}
+		issue.Priority = *req.Priority
+		issue.DueAt = issue.CreatedAt.UTC().Add(time.Duration(sla.Hours(issue.Priority)) * time.Hour)
+	}
+	if req.AssigneeID != nil {
+		issue.AssigneeID = *req.AssigneeID
+	}
+	if req.Resolution != nil {
+		issue.Resolution = *req.Resolution
+	}
+	if req.OrderNo != nil {
+		issue.OrderNo = *req.OrderNo
+	}
+	if req.TicketID != nil {
+		issue.TicketID = *req.TicketID
+	}
+	if req.ExchangeRecordID != nil {
+		issue.ExchangeRecordID = *req.ExchangeRecordID
+	}
+	if err := s.checkLinks(issue); err != nil {
+		return nil, err
+	}
+	if issue.FirstResponseAt == nil {
+		issue.FirstResponseAt = &now
+	}
+
+	details, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(issue).Error; err != nil {
+			return err
+		}
+		adminLog := model.AdminLog{
+			AdminID:    adminID,
+			Action:     "update_support_issue",
+			TargetType: "support_issue",
+			TargetID:   issue.ID,
+			Details:    string(details),
+		}
+		if err := tx.Create(&adminLog).Error; err != nil {
+			return err
+		}
+		if s.notifications == nil || issue.Status == previousStatus {
+			return nil
+		}
+		title := "问题反馈状态已更新"
+		content := fmt.Sprintf("您反馈的问题「%s」状态已更新为：%s", issue.Subject, supportStatusLabel(issue.Status))
+		_, err := s.notifications.notify(tx, issue.UserID, model.NotificationTypeSupportIssue, title, content)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetIssue(issue.ID)
+}
+
+func (s *SupportService) list(query SupportIssueQuery, order string) (*SupportIssueListResponse, error) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit < 1 || query.Limit > 100 {
+		query.Limit = 20
+	}
+
+	db := s.db.Model(&model.SupportIssue{})
+	if query.UserID != 0 {
+		db = db.Where("user_id = ?", query.UserID)
+	}
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+	if query.Priority != "" {
+		db = db.Where("priority = ?", query.Priority)
+	}
+	if query.Category != "" {
+		db = db.Where("category = ?", query.Category)
+	}
+	if query.Overdue {
+		db = db.Where("status IN ? AND due_at < ?", activeSupportStatuses, time.Now().UTC())
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, err
+	}
+	var issues []model.SupportIssue
+	if err := db.Order(order).Offset((query.Page - 1) * query.Limit).Limit(query.Limit).Find(&issues).Error; err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]uint, len(issues))
+	for i, issue := range issues {
+		userIDs[i] = issue.UserID
+	}
+	var users []model.User
+	if len(userIDs) > 0 {
+		if err := s.db.Unscoped().Select("id", "username").Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+			return nil, err
+		}
+	}
+	names := make(map[uint]string, len(users))
+	for _, user := range users {
+		names[user.ID] = user.Username
+	}
+
+	responses := make([]SupportIssueResponse, len(issues))
+	for i, issue := range issues {
+		responses[i] = *s.toResponse(issue, names[issue.UserID])
+	}
+	totalPages := int(total) / query.Limit
+	if int(total)%query.Limit > 0 {
+		totalPages++
+	}
+	return &SupportIssueListResponse{
+		Issues:     responses,
+		Total:      total,
+		Page:       query.Page,
+		Limit:      query.Limit,
+		TotalPages: totalPages,
+	}, nil
+}
+
+func (s *SupportService) issue(issueID uint) (*model.SupportIssue, error) {
+	var issue model.SupportIssue
+	if err := s.db.First(&issue, issueID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSupportIssueNotFound
+		}
+		return nil, err
+	}
+	return &issue, nil
+}
+
+// checkLinks checks that the order, ticket and exchange record linked to an
+// issue belong to the user who filed it
+func (s *SupportService) checkLinks(issue *model.SupportIssue) error {
+	check := func(db *gorm.DB) error {
+		var count int64
+		if err := db.Where("user_id = ?", issue.UserID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrSupportLinkNotFound
+		}
+		return nil
+	}
+	if issue.OrderNo != "" {
+		if err := check(s.db.Model(&model.PaymentOrder{}).Where("order_no = ?", issue.OrderNo)); err != nil {
+			return err
+		}
+	}
+	if issue.TicketID != 0 {
+		if err := check(s.db.Model(&model.Ticket{}).Where("id = ?", issue.TicketID)); err != nil {
+			return err
+		}
+	}
+	if issue.ExchangeRecordID != 0 {
+		if err := check(s.db.Model(&model.ExchangeRecord{}).Where("id = ?", issue.ExchangeRecordID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SupportService) toResponse(issue model.SupportIssue, username string) *SupportIssueResponse {
+	resolved := issue.Status == model.SupportIssueStatusResolved || issue.Status == model.SupportIssueStatusClosed
+	overdue := !resolved && time.Now().After(issue.DueAt)
+	breached := overdue || (issue.ResolvedAt != nil && issue.ResolvedAt.After(issue.DueAt))
+	return &SupportIssueResponse{
+		SupportIssue: issue,
+		Username:     username,
+		Overdue:      overdue,
+		SLABreached:  breached,
+	}
+}
+
+// supportStatusLabel names a status in user notifications
+func supportStatusLabel(status model.SupportIssueStatus) string {
+	switch status {
+	case model.SupportIssueStatusInProgress:
+		return "处理中"
+	case model.SupportIssueStatusResolved:
+		return "已解决"
+	case model.SupportIssueStatusClosed:
+		return "已关闭"
+	default:
+		return "待处理"
+	}
+}
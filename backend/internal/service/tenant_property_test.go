@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+	"scratch-lottery/pkg/logger"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+	"gorm.io/gorm"
+)
+
+// setupTenantTestDB returns a lottery test database with tenant isolation
+// enforced and two tenants: the default one and tenant 2
+func setupTenantTestDB(t *testing.T) *gorm.DB {
+	db := setupLotteryTestDB(t)
+	if err := db.Use(repository.TenantPlugin{}); err != nil {
+		t.Fatalf("Failed to register tenant plugin: %v", err)
+	}
+	if err := db.AutoMigrate(&model.Tenant{}, &model.Product{}, &model.CardKey{}, &model.ExchangeRecord{},
+		&model.SystemConfig{}, &model.AdminLog{}, &model.PaymentSettingsVersion{}, &model.UserNote{}, &model.SupportIssue{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	tenants := []model.Tenant{
+		{Name: "Default", Slug: "default", Status: model.TenantStatusActive},
+		{Name: "Second", Slug: "second", Domain: "second.example.com", Status: model.TenantStatusActive},
+	}
+	if err := db.Create(&tenants).Error; err != nil {
+		t.Fatalf("Failed to create tenants: %v", err)
+	}
+	return db
+}
+
+// Tenant isolation: products, users and settings of one tenant are invisible
+// to services scoped to another tenant.
+func TestTenantIsolation(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("scoped services only see their own tenant", prop.ForAll(
+		func(owners []bool) bool {
+			db := setupTenantTestDB(t)
+			walletService := NewWalletService(db)
+			exchange := NewExchangeService(db, walletService)
+			admin := NewAdminService(db, walletService)
+
+			// Each product belongs to tenant 2 when its flag is set
+			productTenant := make(map[uint]uint)
+			counts := map[uint]int64{}
+			for i, second := range owners {
+				tenantID := repository.DefaultTenantID
+				if second {
+					tenantID = 2
+				}
+				product, err := exchange.ForTenant(tenantID).CreateProduct(CreateProductRequest{Name: "P", Price: i + 1})
+				if err != nil {
+					t.Logf("CreateProduct failed: %v", err)
+					return false
+				}
+				productTenant[product.ID] = tenantID
+				counts[tenantID]++
+			}
+
+			for _, tenantID := range []uint{repository.DefaultTenantID, 2} {
+				scoped := exchange.ForTenant(tenantID)
+				list, err := scoped.GetProducts(ProductQuery{Status: string(model.ProductStatusAvailable), Limit: 100})
+				if err != nil || list.Total != counts[tenantID] {
+					t.Logf("Tenant %d: expected %d products, got %+v (err %v)", tenantID, counts[tenantID], list, err)
+					return false
+				}
+				for productID, owner := range productTenant {
+					_, err := scoped.GetProductByID(productID)
+					if (owner == tenantID) != (err == nil) {
+						t.Logf("Tenant %d reading product %d of tenant %d: %v", tenantID, productID, owner, err)
+						return false
+					}
+				}
+			}
+
+			// A user of tenant 2 cannot redeem a default tenant product
+			user := model.User{TenantID: 2, LinuxdoID: "buyer", Username: "buyer"}
+			if err := repository.ScopeTenant(db, 2).Create(&user).Error; err != nil {
+				return false
+			}
+			if err := repository.ScopeTenant(db, 2).Create(&model.Wallet{UserID: user.ID, Balance: 1000}).Error; err != nil {
+				return false
+			}
+			for productID, owner := range productTenant {
+				if owner != 2 {
+					if _, err := exchange.ForTenant(2).Redeem(user.ID, productID); err != ErrProductNotFound {
+						t.Logf("Expected ErrProductNotFound redeeming across tenants, got %v", err)
+						return false
+					}
+				}
+			}
+
+			// Admins only list users of their tenant
+			users, err := admin.ForTenant(repository.DefaultTenantID).GetUsers(UserListQuery{})
+			if err != nil || users.Total != 0 {
+				t.Logf("Default tenant admin saw %+v (err %v)", users, err)
+				return false
+			}
+			if _, err := admin.ForTenant(repository.DefaultTenantID).GetUserByID(user.ID); err == nil {
+				t.Logf("Default tenant admin read a user of tenant 2")
+				return false
+			}
+
+			// Settings are kept per tenant
+			enabled := len(owners)%2 == 0
+			if _, err := admin.ForTenant(2).UpdateSystemSettings(1, UpdateSystemSettingsRequest{PaymentEnabled: &enabled}); err != nil {
+				t.Logf("UpdateSystemSettings failed: %v", err)
+				return false
+			}
+			if admin.ForTenant(2).IsPaymentEnabled() != enabled || admin.ForTenant(repository.DefaultTenantID).IsPaymentEnabled() {
+				t.Logf("Payment setting leaked across tenants")
+				return false
+			}
+			return true
+		},
+		gen.SliceOfN(6, gen.Bool()),
+	))
+
+	properties.TestingRun(t)
+}
+
+// Tenant resolution: the X-Tenant header wins over the host, unknown hosts
+// fall back to the default tenant and disabled tenants are refused.
+func TestTenantResolution(t *testing.T) {
+	db := setupTenantTestDB(t)
+	tenants := NewTenantService(db)
+
+	cases := []struct {
+		slug, host string
+		want       uint
+		err        error
+	}{
+		{"", "localhost:8080", repository.DefaultTenantID, nil},
+		{"", "Second.Example.com:443", 2, nil},
+		{"second", "localhost", 2, nil},
+		{"default", "second.example.com", repository.DefaultTenantID, nil},
+		{"missing", "second.example.com", 0, ErrTenantNotFound},
+	}
+	for _, tc := range cases {
+		tenant, err := tenants.Resolve(tc.slug, tc.host)
+		if err != tc.err {
+			t.Errorf("Resolve(%q, %q): expected error %v, got %v", tc.slug, tc.host, tc.err, err)
+			continue
+		}
+		if err == nil && tenant.ID != tc.want {
+			t.Errorf("Resolve(%q, %q): expected tenant %d, got %d", tc.slug, tc.host, tc.want, tenant.ID)
+		}
+	}
+
+	disabled := model.TenantStatusDisabled
+	if _, err := tenants.UpdateTenant(1, 2, TenantRequest{Status: &disabled}); err != nil {
+		t.Fatalf("UpdateTenant failed: %v", err)
+	}
+	if _, err := tenants.Resolve("second", ""); err != ErrTenantDisabled {
+		t.Errorf("Expected ErrTenantDisabled, got %v", err)
+	}
+	if _, err := tenants.UpdateTenant(1, repository.DefaultTenantID, TenantRequest{Status: &disabled}); err != ErrDefaultTenant {
+		t.Errorf("Expected ErrDefaultTenant, got %v", err)
+	}
+
+	// Tenant admins are created inside their tenant
+	admin, err := tenants.AssignTenantAdmin(1, 2, AssignTenantAdminRequest{LinuxdoID: "owner"})
+	if err != nil {
+		t.Fatalf("AssignTenantAdmin failed: %v", err)
+	}
+	if admin.TenantID != 2 || admin.Role != "admin" {
+		t.Errorf("Expected admin of tenant 2, got tenant %d role %s", admin.TenantID, admin.Role)
+	}
+}
+
+// Services scoped to a request keep their tenant, and the sessions of the
+// services they call carry the request id as well
+func TestWithRequestKeepsTenant(t *testing.T) {
+	db := setupTenantTestDB(t)
+	walletService := NewWalletService(db)
+	ctx := logger.ContextWithRequestID(context.Background(), "req-1")
+
+	admin := NewAdminService(db, walletService).ForTenant(2).WithRequest(ctx)
+	exchange := NewExchangeService(db, walletService).ForTenant(2).WithRequest(ctx)
+	sessions := map[string]*gorm.DB{
+		"admin":           admin.db,
+		"admin reports":   admin.reportDB,
+		"admin wallet":    admin.walletService.db,
+		"exchange":        exchange.db,
+		"exchange wallet": exchange.walletService.db,
+		"refund":          NewRefundService(db).ForTenant(2).WithRequest(ctx).db,
+	}
+	for name, session := range sessions {
+		if rid := logger.RequestIDFromContext(session.Statement.Context); rid != "req-1" {
+			t.Errorf("Expected the %s session to carry the request id, got %q", name, rid)
+		}
+		if tenantID, ok := repository.TenantFromContext(session.Statement.Context); !ok || tenantID != 2 {
+			t.Errorf("Expected the %s session scoped to tenant 2, got %d", name, tenantID)
+		}
+	}
+}
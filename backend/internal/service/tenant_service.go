@@ -0,0 +1,314 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTenantNotFound    = errors.New("tenant not found")
+	ErrTenantDisabled    = errors.New("tenant disabled")
+	ErrTenantSlugTaken   = errors.New("tenant slug already in use")
+	ErrTenantDomainTaken = errors.New("tenant domain already in use")
+	ErrInvalidTenantSlug = errors.New("invalid tenant slug")
+	ErrTenantNameEmpty   = errors.New("tenant name is required")
+	ErrDefaultTenant     = errors.New("default tenant cannot be disabled")
+	ErrDefaultSandbox    = errors.New("default tenant cannot be a sandbox")
+)
+
+// tenantCacheTTL bounds how long a tenant lookup is served from memory
+const tenantCacheTTL = time.Minute
+
+var tenantSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,62}$`)
+
+// TenantService manages the lottery operators hosted on this deployment and
+// resolves which tenant a request belongs to
+type TenantService struct {
+	db       *gorm.DB
+	bySlug   map[string]*model.Tenant
+	byDomain map[string]*model.Tenant
+	fallback *model.Tenant // default tenant
+	loadedAt time.Time
+	mutex    sync.RWMutex
+}
+
+// NewTenantService creates a new tenant service
+func NewTenantService(db *gorm.DB) *TenantService {
+	return &TenantService{db: db}
+}
+
+// TenantRequest represents a request to create or update a tenant
+type TenantRequest struct {
+	Name    *string             `json:"name"`
+	Slug    *string             `json:"slug"`
+	Domain  *string             `json:"domain"`
+	Status  *model.TenantStatus `json:"status"`
+	Sandbox *bool               `json:"sandbox"`
+}
+
+// AssignTenantAdminRequest represents a request to make a user admin of a tenant
+type AssignTenantAdminRequest struct {
+	LinuxdoID string `json:"linuxdo_id" binding:"required"`
+	Username  string `json:"username"`
+}
+
+// Resolve returns the tenant of a request. The X-Tenant slug takes precedence
+// over the host; requests matching neither belong to the default tenant.
+func (s *TenantService) Resolve(slug, host string) (*model.Tenant, error) {
+	if err := s.ensureLoaded(); err != nil {
+		return nil, err
+	}
+
+	s.mutex.RLock()
+	var tenant *model.Tenant
+	if slug != "" {
+		tenant = s.bySlug[strings.ToLower(slug)]
+	} else if tenant = s.byDomain[normalizeDomain(host)]; tenant == nil {
+		tenant = s.fallback
+	}
+	s.mutex.RUnlock()
+
+	if tenant == nil {
+		return nil, ErrTenantNotFound
+	}
+	if tenant.Status == model.TenantStatusDisabled {
+		return nil, ErrTenantDisabled
+	}
+	return tenant, nil
+}
+
+// ListTenants returns all tenants
+func (s *TenantService) ListTenants() ([]model.Tenant, error) {
+	var tenants []model.Tenant
+	if err := s.db.Order("id ASC").Find(&tenants).Error; err != nil {
+		return nil, err
+	}
+	return tenants, nil
+}
+
+// CreateTenant creates a new tenant
+func (s *TenantService) CreateTenant(adminID uint, req TenantRequest) (*model.Tenant, error) {
+	if req.Name == nil || strings.TrimSpace(*req.Name) == "" {
+		return nil, ErrTenantNameEmpty
+	}
+	if req.Slug == nil {
+		return nil, ErrInvalidTenantSlug
+	}
+	tenant := model.Tenant{
+		Name:   *req.Name,
+		Slug:   strings.ToLower(*req.Slug),
+		Status: model.TenantStatusActive,
+	}
+	if req.Domain != nil {
+		tenant.Domain = normalizeDomain(*req.Domain)
+	}
+	if req.Status != nil {
+		tenant.Status = *req.Status
+	}
+	if req.Sandbox != nil {
+		tenant.Sandbox = *req.Sandbox
+	}
+	if err := s.validate(&tenant); err != nil {
+		return nil, err
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&tenant).Error; err != nil {
+			return err
+		}
+		return s.logAction(tx, adminID, "create_tenant", tenant.ID, req)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidate()
+	return &tenant, nil
+}
+
+// UpdateTenant updates a tenant
+func (s *TenantService) UpdateTenant(adminID, tenantID uint, req TenantRequest) (*model.Tenant, error) {
+	var tenant model.Tenant
+	if err := s.db.First(&tenant, tenantID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTenantNotFound
+		}
+		return nil, err
+	}
+
+	if req.Name != nil {
+		tenant.Name = *req.Name
+	}
+	if req.Slug != nil {
+		tenant.Slug = strings.ToLower(*req.Slug)
+	}
+	if req.Domain != nil {
+		tenant.Domain = normalizeDomain(*req.Domain)
+	}
+	if req.Status != nil {
+		if tenant.ID == repository.DefaultTenantID && *req.Status != model.TenantStatusActive {
+			return nil, ErrDefaultTenant
+		}
+		tenant.Status = *req.Status
+	}
+	if req.Sandbox != nil {
+		if tenant.ID == repository.DefaultTenantID && *req.Sandbox {
+			return nil, ErrDefaultSandbox
+		}
+		tenant.Sandbox = *req.Sandbox
+	}
+	if err := s.validate(&tenant); err != nil {
+		return nil, err
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&tenant).Error; err != nil {
+			return err
+		}
+		return s.logAction(tx, adminID, "update_tenant", tenant.ID, req)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidate()
+	return &tenant, nil
+}
+
+// AssignTenantAdmin makes a user admin of a tenant, creating the user and
+// wallet in that tenant if they do not exist yet
+func (s *TenantService) AssignTenantAdmin(adminID, tenantID uint, req AssignTenantAdminRequest) (*model.User, error) {
+	var tenant model.Tenant
+	if err := s.db.First(&tenant, tenantID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTenantNotFound
+		}
+		return nil, err
+	}
+
+	var user model.User
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		scoped := repository.ScopeTenant(tx, tenant.ID)
+		err := scoped.Where("linuxdo_id = ?", req.LinuxdoID).First(&user).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			username := req.Username
+			if username == "" {
+				username = req.LinuxdoID
+			}
+			user = model.User{TenantID: tenant.ID, LinuxdoID: req.LinuxdoID, Username: username, Role: "admin"}
+			if err := scoped.Create(&user).Error; err != nil {
+				return err
+			}
+			wallet := model.Wallet{TenantID: tenant.ID, UserID: user.ID, Balance: 0}
+			if err := scoped.Create(&wallet).Error; err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
+		} else if err := scoped.Model(&user).Update("role", "admin").Error; err != nil {
+			return err
+		}
+		return s.logAction(tx, adminID, "assign_tenant_admin", tenant.ID, req)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// validate checks the slug format and that slug and domain are unique
+func (s *TenantService) validate(tenant *model.Tenant) error {
+	if !tenantSlugPattern.MatchString(tenant.Slug) {
+		return ErrInvalidTenantSlug
+	}
+
+	var count int64
+	if err := s.db.Model(&model.Tenant{}).Where("slug = ? AND id <> ?", tenant.Slug, tenant.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrTenantSlugTaken
+	}
+
+	if tenant.Domain != "" {
+		if err := s.db.Model(&model.Tenant{}).Where("domain = ? AND id <> ?", tenant.Domain, tenant.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrTenantDomainTaken
+		}
+	}
+	return nil
+}
+
+func (s *TenantService) logAction(tx *gorm.DB, adminID uint, action string, tenantID uint, req interface{}) error {
+	details, _ := json.Marshal(req)
+	adminLog := model.AdminLog{
+		AdminID:    adminID,
+		Action:     action,
+		TargetType: "tenant",
+		TargetID:   tenantID,
+		Details:    string(details),
+	}
+	return repository.ScopeTenant(tx, repository.DefaultTenantID).Create(&adminLog).Error
+}
+
+// ensureLoaded refreshes the tenant lookup tables once they are stale
+func (s *TenantService) ensureLoaded() error {
+	s.mutex.RLock()
+	fresh := s.bySlug != nil && time.Since(s.loadedAt) < tenantCacheTTL
+	s.mutex.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	var tenants []model.Tenant
+	if err := s.db.Find(&tenants).Error; err != nil {
+		return err
+	}
+	bySlug := make(map[string]*model.Tenant, len(tenants))
+	byDomain := make(map[string]*model.Tenant, len(tenants))
+	var fallback *model.Tenant
+	for i := range tenants {
+		tenant := &tenants[i]
+		if tenant.ID == repository.DefaultTenantID {
+			fallback = tenant
+		}
+		bySlug[tenant.Slug] = tenant
+		if tenant.Domain != "" {
+			byDomain[tenant.Domain] = tenant
+		}
+	}
+
+	s.mutex.Lock()
+	s.bySlug = bySlug
+	s.byDomain = byDomain
+	s.fallback = fallback
+	s.loadedAt = time.Now()
+	s.mutex.Unlock()
+	return nil
+}
+
+func (s *TenantService) invalidate() {
+	s.mutex.Lock()
+	s.bySlug = nil
+	s.mutex.Unlock()
+}
+
+// normalizeDomain lowercases a host and strips the port
+func normalizeDomain(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if i := strings.LastIndex(host, ":"); i >= 0 && !strings.HasSuffix(host, "]") {
+		host = host[:i]
+	}
+	return host
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"testing"
+
+	"scratch-lottery/internal/model"
+)
+
+// Ticket events: buying, viewing and scratching a winning ticket appends
+// generated, purchased, viewed, scratched and prize_credited in order, with
+// repeated views collapsed. Replaying the stream matches the ticket and the
+// ledger until either is tampered with.
+func TestTicketEventStreamAndIntegrity(t *testing.T) {
+	db := setupTenantTestDB(t)
+	lotteryService := NewLotteryService(db, testEncryptionKey).ForTenant(1)
+	lotteryType, err := lotteryService.CreateLotteryType(CreateLotteryTypeRequest{
+		Name:        "Events",
+		Price:       10,
+		MaxPrize:    100,
+		PrizeLevels: []PrizeLevelInput{{Level: 1, Name: "一等奖", PrizeAmount: 20, Quantity: 5}},
+	})
+	if err != nil {
+		t.Fatalf("CreateLotteryType failed: %v", err)
+	}
+	if _, err := lotteryService.CreatePrizePool(CreatePrizePoolRequest{LotteryTypeID: lotteryType.ID, TotalTickets: 5}); err != nil {
+		t.Fatalf("CreatePrizePool failed: %v", err)
+	}
+	user := model.User{TenantID: 1, LinuxdoID: "events", Username: "Events"}
+	db.Create(&user)
+	db.Create(&model.Wallet{TenantID: 1, UserID: user.ID, Balance: 100})
+
+	walletService := NewWalletService(db)
+	purchases := NewPurchaseService(db, NewLotteryService(db, testEncryptionKey), walletService, nil, nil, nil).ForTenant(1)
+	purchase, err := purchases.PurchaseTickets(user.ID, PurchaseRequest{LotteryTypeID: lotteryType.ID, Quantity: 1})
+	if err != nil {
+		t.Fatalf("PurchaseTickets failed: %v", err)
+	}
+	ticketID := purchase.Tickets[0].ID
+
+	scratchService := NewScratchService(db, NewLotteryService(db, testEncryptionKey), walletService, nil, nil).ForTenant(1)
+	for i := 0; i < 2; i++ {
+		if _, err := scratchService.GetTicketDetail(user.ID, ticketID); err != nil {
+			t.Fatalf("GetTicketDetail failed: %v", err)
+		}
+	}
+	if _, err := scratchService.ScratchTicket(user.ID, ticketID, ""); err != nil {
+		t.Fatalf("ScratchTicket failed: %v", err)
+	}
+
+	events := NewTicketEventService(db).ForTenant(1)
+	stream, err := events.GetEvents(ticketID)
+	if err != nil {
+		t.Fatalf("GetEvents failed: %v", err)
+	}
+	expected := []model.TicketEventType{
+		model.TicketEventGenerated, model.TicketEventPurchased, model.TicketEventViewed,
+		model.TicketEventScratched, model.TicketEventPrizeCredited,
+	}
+	if len(stream.Events) != len(expected) {
+		t.Fatalf("Expected %d events, got %+v", len(expected), stream.Events)
+	}
+	for i, event := range stream.Events {
+		if event.Type != expected[i] {
+			t.Errorf("Event %d: expected %s, got %s", i, expected[i], event.Type)
+		}
+	}
+	if last := stream.Events[len(stream.Events)-1]; last.Amount != 20 || last.ActorID != nil {
+		t.Errorf("Expected the system to credit 20 points, got %+v", last)
+	}
+
+	report, err := events.CheckIntegrity(ticketID)
+	if err != nil || !report.Consistent || !report.Recorded || report.Credited != 20 || report.LedgerCredited != 20 {
+		t.Fatalf("Expected a consistent ticket, got %+v (err %v)", report, err)
+	}
+
+	// A lost credit and a rolled back status are both reported
+	db.Where("type = ? AND reference_id = ?", model.TransactionTypeWin, ticketID).Delete(&model.Transaction{})
+	db.Model(&model.Ticket{}).Where("id = ?", ticketID).Update("status", model.TicketStatusUnscratched)
+	report, err = events.CheckIntegrity(ticketID)
+	if err != nil || report.Consistent || len(report.Issues) != 2 ||
+		report.Issues[0] != TicketIssueStatusMismatch || report.Issues[1] != TicketIssueCreditMismatch {
+		t.Errorf("Expected status and credit mismatches, got %+v (err %v)", report, err)
+	}
+
+	if _, err := NewTicketEventService(db).ForTenant(2).GetEvents(ticketID); err != ErrTicketNotFound {
+		t.Errorf("Expected another tenant not to see the ticket, got %v", err)
+	}
+}
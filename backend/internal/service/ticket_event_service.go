@@ -0,0 +1,203 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// Integrity issues found by replaying the event stream of a ticket
+const (
+	TicketIssueStatusMismatch   = "status_mismatch"   // the stored status differs from the replayed one
+	TicketIssueCreditMismatch   = "credit_mismatch"   // the ledger paid out other points than the events
+	TicketIssueUnrevealedCredit = "unrevealed_credit" // points were credited before the ticket was scratched
+)
+
+// TicketEventService exposes the lifecycle event stream of tickets to admins
+// and checks tickets against it
+type TicketEventService struct {
+	db *gorm.DB
+}
+
+// NewTicketEventService creates a new ticket event service
+func NewTicketEventService(db *gorm.DB) *TicketEventService {
+	return &TicketEventService{db: db}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *TicketEventService) ForTenant(tenantID uint) *TicketEventService {
+	return &TicketEventService{db: repository.ScopeTenant(s.db, tenantID)}
+}
+
+// TicketEventsResponse represents the event stream of a ticket, oldest first
+type TicketEventsResponse struct {
+	TicketID     uint                `json:"ticket_id"`
+	SecurityCode string              `json:"security_code"`
+	OwnerID      uint                `json:"owner_id"`
+	Events       []model.TicketEvent `json:"events"`
+}
+
+// TicketIntegrityResponse compares a ticket with the state rebuilt from its
+// event stream and with the points the ledger paid out for it
+type TicketIntegrityResponse struct {
+	TicketID       uint               `json:"ticket_id"`
+	Recorded       bool               `json:"recorded"` // False for tickets older than the event stream
+	Status         model.TicketStatus `json:"status"`
+	ReplayedStatus model.TicketStatus `json:"replayed_status,omitempty"`
+	PrizeAmount    int                `json:"prize_amount"`
+	Credited       int                `json:"credited"`        // Points the events credited
+	LedgerCredited int                `json:"ledger_credited"` // Win transactions referencing the ticket
+	Consistent     bool               `json:"consistent"`
+	Issues         []string           `json:"issues,omitempty"`
+	CheckedAt      time.Time          `json:"checked_at"`
+}
+
+// GetEvents returns the event stream of a ticket
+func (s *TicketEventService) GetEvents(ticketID uint) (*TicketEventsResponse, error) {
+	ticket, err := s.ticket(ticketID)
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.events(ticketID)
+	if err != nil {
+		return nil, err
+	}
+	return &TicketEventsResponse{
+		TicketID:     ticket.ID,
+		SecurityCode: ticket.SecurityCode,
+		OwnerID:      ticket.UserID,
+		Events:       events,
+	}, nil
+}
+
+// CheckIntegrity replays the event stream of a ticket and compares the
+// rebuilt status and payout with the ticket and the wallet ledger
+func (s *TicketEventService) CheckIntegrity(ticketID uint) (*TicketIntegrityResponse, error) {
+	ticket, err := s.ticket(ticketID)
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.events(ticketID)
+	if err != nil {
+		return nil, err
+	}
+
+	var ledger struct {
+		Total int
+	}
+	if err := s.db.Model(&model.Transaction{}).
+		Select("COALESCE(SUM(amount), 0) as total").
+		Where("type = ? AND reference_id = ?", model.TransactionTypeWin, ticket.ID).
+		Scan(&ledger).Error; err != nil {
+		return nil, err
+	}
+
+	replayed := replayTicketEvents(events)
+	resp := &TicketIntegrityResponse{
+		TicketID:       ticket.ID,
+		Recorded:       len(events) > 0,
+		Status:         ticket.Status,
+		ReplayedStatus: replayed.status,
+		PrizeAmount:    ticket.PrizeAmount,
+		Credited:       replayed.credited,
+		LedgerCredited: ledger.Total,
+		CheckedAt:      time.Now(),
+	}
+	if resp.Recorded {
+		// An incremental scratch in progress is still unscratched to the stream
+		status := ticket.Status
+		if status == model.TicketStatusScratching {
+			status = model.TicketStatusUnscratched
+		}
+		if status != replayed.status {
+			resp.Issues = append(resp.Issues, TicketIssueStatusMismatch)
+		}
+		if replayed.credited != ledger.Total {
+			resp.Issues = append(resp.Issues, TicketIssueCreditMismatch)
+		}
+		if replayed.creditedUnrevealed {
+			resp.Issues = append(resp.Issues, TicketIssueUnrevealedCredit)
+		}
+	}
+	resp.Consistent = len(resp.Issues) == 0
+	return resp, nil
+}
+
+func (s *TicketEventService) ticket(ticketID uint) (*model.Ticket, error) {
+	var ticket model.Ticket
+	if err := s.db.First(&ticket, ticketID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTicketNotFound
+		}
+		return nil, err
+	}
+	return &ticket, nil
+}
+
+func (s *TicketEventService) events(ticketID uint) ([]model.TicketEvent, error) {
+	var events []model.TicketEvent
+	if err := s.db.Where("ticket_id = ?", ticketID).Order("id ASC").Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// replayedTicket is the state of a ticket rebuilt from its events
+type replayedTicket struct {
+	status             model.TicketStatus
+	credited           int
+	creditedUnrevealed bool
+}
+
+// replayTicketEvents folds an event stream, oldest first, into the state of
+// its ticket
+func replayTicketEvents(events []model.TicketEvent) replayedTicket {
+	var state replayedTicket
+	for _, event := range events {
+		if event.Type == model.TicketEventPrizeCredited {
+			if !state.status.Revealed() {
+				state.creditedUnrevealed = true
+			}
+			state.credited += event.Amount
+		}
+		if event.Status != "" {
+			state.status = event.Status
+		}
+	}
+	return state
+}
+
+// appendTicketEvent appends an event to the stream of a ticket. An actorID
+// of 0 records the system as the actor.
+func appendTicketEvent(tx *gorm.DB, ticket *model.Ticket, eventType model.TicketEventType, actorID uint, status model.TicketStatus, amount int, details string) error {
+	event := model.TicketEvent{
+		TenantID: ticket.TenantID,
+		TicketID: ticket.ID,
+		Type:     eventType,
+		Status:   status,
+		Amount:   amount,
+		Details:  details,
+	}
+	if actorID != 0 {
+		event.ActorID = &actorID
+	}
+	return tx.Create(&event).Error
+}
+
+// recordTicketView appends a viewed event unless the latest event of the
+// ticket already is the same user viewing it
+func recordTicketView(db *gorm.DB, ticket *model.Ticket, userID uint) error {
+	var latest model.TicketEvent
+	err := db.Where("ticket_id = ?", ticket.ID).Order("id DESC").First(&latest).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if err == nil && latest.Type == model.TicketEventViewed && latest.ActorID != nil && *latest.ActorID == userID {
+		return nil
+	}
+	return appendTicketEvent(db, ticket, model.TicketEventViewed, userID, "", 0, "")
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+	"gorm.io/gorm"
+)
+
+func setupTicketHistoryTest(t *testing.T, users int) (*gorm.DB, *LotteryService, *TicketHistoryService, uint, []uint) {
+	db := setupTenantTestDB(t)
+	lotteryTypeID := createOddsHintPool(t, db, 100, 0, nil)
+
+	userIDs := make([]uint, users)
+	for i := range userIDs {
+		user := model.User{LinuxdoID: fmt.Sprintf("history_user_%d", i), Username: fmt.Sprintf("User %d", i), Role: "user"}
+		db.Create(&user)
+		userIDs[i] = user.ID
+	}
+	return db, NewLotteryService(db, testEncryptionKey), NewTicketHistoryService(db), lotteryTypeID, userIDs
+}
+
+// Ticket history: every reassignment continues the chain from the previous
+// holder, and the last holder in the chain owns the ticket.
+func TestTicketHistoryChain(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("history is an unbroken chain of custody", prop.ForAll(
+		func(recipients []int) bool {
+			db, lotteries, history, lotteryTypeID, userIDs := setupTicketHistoryTest(t, 4)
+			ticket, err := lotteries.GenerateTicket(userIDs[0], lotteryTypeID)
+			if err != nil {
+				t.Logf("GenerateTicket failed: %v", err)
+				return false
+			}
+
+			owner := userIDs[0]
+			moves := 0
+			for _, i := range recipients {
+				_, err := history.Reassign(99, ticket.ID, ReassignTicketRequest{UserID: userIDs[i], Reason: "Support case"})
+				if userIDs[i] == owner {
+					if err != ErrInvalidReassignRecipient {
+						t.Logf("Expected ErrInvalidReassignRecipient, got %v", err)
+						return false
+					}
+					continue
+				}
+				if err != nil {
+					t.Logf("Reassign failed: %v", err)
+					return false
+				}
+				owner = userIDs[i]
+				moves++
+			}
+
+			resp, err := history.GetHistory(ticket.ID)
+			if err != nil || len(resp.Entries) != moves+1 || resp.OwnerID != owner {
+				t.Logf("Expected %d entries owned by %d, got %+v (err %v)", moves+1, owner, resp, err)
+				return false
+			}
+			first := resp.Entries[0]
+			if first.Event != model.TicketHistoryPurchase || first.FromUserID != nil || first.ToUserID != userIDs[0] || !first.Recorded {
+				t.Logf("Unexpected purchase entry %+v", first)
+				return false
+			}
+			for i := 1; i < len(resp.Entries); i++ {
+				entry := resp.Entries[i]
+				if entry.Event != model.TicketHistoryReassign || entry.FromUserID == nil ||
+					*entry.FromUserID != resp.Entries[i-1].ToUserID || entry.Reason != "Support case" {
+					t.Logf("Broken chain at %d: %+v", i, entry)
+					return false
+				}
+			}
+			if resp.Entries[len(resp.Entries)-1].ToUserID != owner {
+				return false
+			}
+
+			var logs int64
+			db.Model(&model.AdminLog{}).Where("action = ? AND target_id = ?", "reassign_ticket", ticket.ID).Count(&logs)
+			return logs == int64(moves)
+		},
+		gen.SliceOfN(5, gen.IntRange(0, 3)),
+	))
+
+	properties.TestingRun(t)
+}
+
+func TestTicketHistoryViews(t *testing.T) {
+	db, lotteries, history, lotteryTypeID, userIDs := setupTicketHistoryTest(t, 2)
+
+	gift, err := lotteries.GenerateGiftTicket(userIDs[0], lotteryTypeID, 7)
+	if err != nil {
+		t.Fatalf("GenerateGiftTicket failed: %v", err)
+	}
+	admin := model.User{LinuxdoID: "history_admin", Username: "Admin", Role: "admin"}
+	db.Create(&admin)
+	if _, err := history.Reassign(admin.ID, gift.ID, ReassignTicketRequest{UserID: userIDs[1], Reason: "Wrong account"}); err != nil {
+		t.Fatalf("Reassign failed: %v", err)
+	}
+
+	resp, err := history.GetHistory(gift.ID)
+	if err != nil || len(resp.Entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %+v (err %v)", resp, err)
+	}
+	if resp.Entries[0].Event != model.TicketHistoryGift || resp.Entries[0].ReferenceID != 7 {
+		t.Errorf("Unexpected gift entry %+v", resp.Entries[0])
+	}
+	if reassign := resp.Entries[1]; reassign.ActorName != "Admin" || reassign.FromUsername != "User 0" || reassign.ToUsername != "User 1" {
+		t.Errorf("Unexpected reassign entry %+v", reassign)
+	}
+
+	// Only the current owner sees the trimmed history
+	if _, err := history.GetUserHistory(userIDs[0], gift.ID); err != ErrTicketNotOwned {
+		t.Errorf("Expected ErrTicketNotOwned for the previous owner, got %v", err)
+	}
+	entries, err := history.GetUserHistory(userIDs[1], gift.ID)
+	if err != nil || len(entries) != 2 || entries[1].Event != model.TicketHistoryReassign {
+		t.Errorf("Unexpected user history %+v (err %v)", entries, err)
+	}
+
+	// Scratched tickets, unknown users and other tenants' users are refused
+	db.Model(&model.Ticket{}).Where("id = ?", gift.ID).Update("status", model.TicketStatusScratched)
+	if _, err := history.Reassign(admin.ID, gift.ID, ReassignTicketRequest{UserID: userIDs[0], Reason: "Again"}); err != ErrTicketNotReassignable {
+		t.Errorf("Expected ErrTicketNotReassignable, got %v", err)
+	}
+	ticket, err := lotteries.GenerateTicket(userIDs[0], lotteryTypeID)
+	if err != nil {
+		t.Fatalf("GenerateTicket failed: %v", err)
+	}
+	if _, err := history.Reassign(admin.ID, ticket.ID, ReassignTicketRequest{UserID: 999, Reason: "Unknown"}); err != ErrInvalidReassignRecipient {
+		t.Errorf("Expected ErrInvalidReassignRecipient, got %v", err)
+	}
+	outsider := model.User{TenantID: 2, LinuxdoID: "history_outsider", Username: "Outsider", Role: "user"}
+	db.Create(&outsider)
+	if _, err := history.ForTenant(1).Reassign(admin.ID, ticket.ID, ReassignTicketRequest{UserID: outsider.ID, Reason: "Other tenant"}); err != ErrInvalidReassignRecipient {
+		t.Errorf("Expected ErrInvalidReassignRecipient for another tenant's user, got %v", err)
+	}
+	if _, err := history.ForTenant(2).GetHistory(ticket.ID); err != ErrTicketNotFound {
+		t.Errorf("Expected ErrTicketNotFound in tenant 2, got %v", err)
+	}
+
+	// Tickets from before the history start with an unrecorded purchase
+	db.Where("ticket_id = ?", ticket.ID).Delete(&model.TicketHistory{})
+	if _, err := history.Reassign(admin.ID, ticket.ID, ReassignTicketRequest{UserID: userIDs[1], Reason: "Legacy"}); err != nil {
+		t.Fatalf("Reassign failed: %v", err)
+	}
+	resp, err = history.GetHistory(ticket.ID)
+	if err != nil || len(resp.Entries) != 2 || resp.Entries[0].Recorded || resp.Entries[0].ToUserID != userIDs[0] ||
+		!resp.Entries[0].CreatedAt.Equal(ticket.PurchasedAt) {
+		t.Errorf("Unexpected legacy history %+v (err %v)", resp, err)
+	}
+}
@@ -0,0 +1,262 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTicketNotReassignable    = errors.New("only unscratched tickets can be reassigned")
+	ErrInvalidReassignRecipient = errors.New("invalid reassignment recipient")
+)
+
+// TicketHistoryService keeps the chain of custody of tickets: who held a
+// ticket, since when, and who moved it
+type TicketHistoryService struct {
+	db        *gorm.DB
+	redaction FieldRedaction
+}
+
+// NewTicketHistoryService creates a new ticket history service
+func NewTicketHistoryService(db *gorm.DB) *TicketHistoryService {
+	return &TicketHistoryService{db: db}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *TicketHistoryService) ForTenant(tenantID uint) *TicketHistoryService {
+	return &TicketHistoryService{db: repository.ScopeTenant(s.db, tenantID), redaction: s.redaction}
+}
+
+// WithRedaction returns a copy of the service that redacts its responses
+func (s *TicketHistoryService) WithRedaction(redaction FieldRedaction) *TicketHistoryService {
+	return &TicketHistoryService{db: s.db, redaction: redaction}
+}
+
+// TicketHistoryEntry represents an ownership change in the admin view
+type TicketHistoryEntry struct {
+	Event        model.TicketHistoryEvent `json:"event"`
+	FromUserID   *uint                    `json:"from_user_id,omitempty"`
+	FromUsername string                   `json:"from_username,omitempty"`
+	ToUserID     uint                     `json:"to_user_id"`
+	ToUsername   string                   `json:"to_username"`
+	ActorID      *uint                    `json:"actor_id,omitempty"`
+	ActorName    string                   `json:"actor_name,omitempty"`
+	ReferenceID  uint                     `json:"reference_id,omitempty"`
+	Reason       string                   `json:"reason,omitempty"`
+	Recorded     bool                     `json:"recorded"` // False for the purchase of tickets older than the history
+	CreatedAt    time.Time                `json:"created_at"`
+}
+
+// TicketHistoryResponse represents the chain of custody of a ticket, oldest first
+type TicketHistoryResponse struct {
+	TicketID     uint                 `json:"ticket_id"`
+	SecurityCode string               `json:"security_code"`
+	OwnerID      uint                 `json:"owner_id"`
+	Entries      []TicketHistoryEntry `json:"entries"`
+}
+
+// UserTicketHistoryEntry represents an ownership change in the owner's view,
+// without the identities of other users or admins
+type UserTicketHistoryEntry struct {
+	Event     model.TicketHistoryEvent `json:"event"`
+	CreatedAt time.Time                `json:"created_at"`
+}
+
+// ReassignTicketRequest represents an admin moving a ticket to another user
+type ReassignTicketRequest struct {
+	UserID uint   `json:"user_id" binding:"required"`
+	Reason string `json:"reason" binding:"required,max=256"`
+}
+
+// GetHistory returns the full chain of custody of a ticket
+func (s *TicketHistoryService) GetHistory(ticketID uint) (*TicketHistoryResponse, error) {
+	ticket, err := s.ticket(ticketID)
+	if err != nil {
+		return nil, err
+	}
+	records, err := s.records(ticket)
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]uint, 0, len(records)*2)
+	for _, record := range records {
+		userIDs = append(userIDs, record.ToUserID)
+		if record.FromUserID != nil {
+			userIDs = append(userIDs, *record.FromUserID)
+		}
+		if record.ActorID != nil {
+			userIDs = append(userIDs, *record.ActorID)
+		}
+	}
+	var users []model.User
+	if err := s.db.Unscoped().Select("id", "username").Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	names := make(map[uint]string, len(users))
+	for _, user := range users {
+		names[user.ID] = user.Username
+	}
+
+	entries := make([]TicketHistoryEntry, len(records))
+	for i, record := range records {
+		entries[i] = TicketHistoryEntry{
+			Event:       record.Event,
+			FromUserID:  record.FromUserID,
+			ToUserID:    record.ToUserID,
+			ToUsername:  names[record.ToUserID],
+			ActorID:     record.ActorID,
+			ReferenceID: record.ReferenceID,
+			Reason:      record.Reason,
+			Recorded:    record.ID != 0,
+			CreatedAt:   record.CreatedAt,
+		}
+		if record.FromUserID != nil {
+			entries[i].FromUsername = names[*record.FromUserID]
+		}
+		if record.ActorID != nil {
+			entries[i].ActorName = names[*record.ActorID]
+		}
+	}
+	return &TicketHistoryResponse{
+		TicketID:     ticket.ID,
+		SecurityCode: s.redaction.SecurityCode(ticket.SecurityCode),
+		OwnerID:      ticket.UserID,
+		Entries:      entries,
+	}, nil
+}
+
+// GetUserHistory returns the trimmed chain of custody of a ticket the user
+// currently owns
+func (s *TicketHistoryService) GetUserHistory(userID, ticketID uint) ([]UserTicketHistoryEntry, error) {
+	ticket, err := s.ticket(ticketID)
+	if err != nil {
+		return nil, err
+	}
+	if ticket.UserID != userID {
+		return nil, ErrTicketNotOwned
+	}
+	records, err := s.records(ticket)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]UserTicketHistoryEntry, len(records))
+	for i, record := range records {
+		entries[i] = UserTicketHistoryEntry{Event: record.Event, CreatedAt: record.CreatedAt}
+	}
+	return entries, nil
+}
+
+// Reassign moves an unscratched ticket to another user of the tenant and
+// records the reason in the ticket history and the admin log
+func (s *TicketHistoryService) Reassign(adminID, ticketID uint, req ReassignTicketRequest) (*TicketHistoryResponse, error) {
+	ticket, err := s.ticket(ticketID)
+	if err != nil {
+		return nil, err
+	}
+	if ticket.Status != model.TicketStatusUnscratched {
+		return nil, ErrTicketNotReassignable
+	}
+	if req.UserID == ticket.UserID {
+		return nil, ErrInvalidReassignRecipient
+	}
+	var recipient model.User
+	if err := s.db.Select("id").First(&recipient, req.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidReassignRecipient
+		}
+		return nil, err
+	}
+
+	fromUserID := ticket.UserID
+	err = repository.Transaction(s.db, func(tx *gorm.DB) error {
+		// Guarded against the ticket being scratched or moved meanwhile
+		result := tx.Model(&model.Ticket{}).
+			Where("id = ? AND user_id = ? AND status = ?", ticket.ID, fromUserID, model.TicketStatusUnscratched).
+			Update("user_id", recipient.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTicketNotReassignable
+		}
+		if err := adjustBadge(tx, fromUserID, badgeUnscratchedTickets, -1); err != nil {
+			return err
+		}
+		if err := adjustBadge(tx, recipient.ID, badgeUnscratchedTickets, 1); err != nil {
+			return err
+		}
+
+		history := model.TicketHistory{
+			TicketID:   ticket.ID,
+			Event:      model.TicketHistoryReassign,
+			FromUserID: &fromUserID,
+			ToUserID:   recipient.ID,
+			ActorID:    &adminID,
+			Reason:     req.Reason,
+		}
+		if err := tx.Create(&history).Error; err != nil {
+			return err
+		}
+
+		details, _ := json.Marshal(map[string]interface{}{
+			"from_user_id": fromUserID,
+			"to_user_id":   recipient.ID,
+			"reason":       req.Reason,
+		})
+		adminLog := model.AdminLog{
+			AdminID:    adminID,
+			Action:     "reassign_ticket",
+			TargetType: "ticket",
+			TargetID:   ticket.ID,
+			Details:    string(details),
+		}
+		return tx.Create(&adminLog).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetHistory(ticket.ID)
+}
+
+func (s *TicketHistoryService) ticket(id uint) (*model.Ticket, error) {
+	var ticket model.Ticket
+	if err := s.db.First(&ticket, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTicketNotFound
+		}
+		return nil, err
+	}
+	return &ticket, nil
+}
+
+// records returns the ownership changes of a ticket, oldest first. Tickets
+// issued before the history was kept get an unrecorded purchase by the first
+// known owner at the purchase time.
+func (s *TicketHistoryService) records(ticket *model.Ticket) ([]model.TicketHistory, error) {
+	var records []model.TicketHistory
+	if err := s.db.Where("ticket_id = ?", ticket.ID).Order("created_at ASC, id ASC").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	if len(records) > 0 && records[0].FromUserID == nil {
+		return records, nil
+	}
+
+	purchase := model.TicketHistory{
+		TicketID:  ticket.ID,
+		Event:     model.TicketHistoryPurchase,
+		ToUserID:  ticket.UserID,
+		CreatedAt: ticket.PurchasedAt,
+	}
+	if len(records) > 0 {
+		purchase.ToUserID = *records[0].FromUserID
+	}
+	return append([]model.TicketHistory{purchase}, records...), nil
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"testing"
+
+	"scratch-lottery/internal/model"
+)
+
+// Ticket gifts: an unscratched ticket moves to the recipient with a transfer
+// record, a history entry and a notification; scratched tickets, tickets of
+// other users, unknown recipients and the sender themselves are refused.
+func TestTicketTransfer(t *testing.T) {
+	db, lotteries, history, lotteryTypeID, userIDs := setupTicketHistoryTest(t, 2)
+	if err := db.AutoMigrate(&model.TicketTransfer{}, &model.Notification{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	transfers := NewTicketTransferService(db, NewNotificationService(db))
+
+	ticket, err := lotteries.GenerateTicket(userIDs[0], lotteryTypeID)
+	if err != nil {
+		t.Fatalf("GenerateTicket failed: %v", err)
+	}
+	transfer, err := transfers.GiftTicket(userIDs[0], ticket.ID, GiftTicketRequest{Username: "User 1", Message: "Good luck"})
+	if err != nil {
+		t.Fatalf("GiftTicket failed: %v", err)
+	}
+	if transfer.ToUserID != userIDs[1] || transfer.FromUsername != "User 0" || transfer.Message != "Good luck" {
+		t.Errorf("Unexpected transfer %+v", transfer)
+	}
+
+	var owner model.Ticket
+	db.First(&owner, ticket.ID)
+	if owner.UserID != userIDs[1] {
+		t.Fatalf("Expected the recipient to own the ticket, got user %d", owner.UserID)
+	}
+	resp, err := history.GetHistory(ticket.ID)
+	if err != nil || len(resp.Entries) != 2 || resp.Entries[1].Event != model.TicketHistoryTransfer || resp.Entries[1].ReferenceID != transfer.ID {
+		t.Fatalf("Expected a transfer entry in the history, got %+v (err %v)", resp, err)
+	}
+	var notifications int64
+	db.Model(&model.Notification{}).Where("user_id = ? AND type = ?", userIDs[1], model.NotificationTypeTicketGift).Count(&notifications)
+	if notifications != 1 {
+		t.Errorf("Expected the recipient to be notified, got %d notifications", notifications)
+	}
+
+	received, err := transfers.GetTransfers(userIDs[1], TicketTransferQuery{})
+	if err != nil || received.Total != 1 || received.Transfers[0].LotteryTypeName == "" {
+		t.Errorf("Expected one received gift, got %+v (err %v)", received, err)
+	}
+	if sent, err := transfers.GetTransfers(userIDs[1], TicketTransferQuery{Direction: GiftDirectionSent}); err != nil || sent.Total != 0 {
+		t.Errorf("Expected no sent gifts, got %+v (err %v)", sent, err)
+	}
+
+	// The previous owner no longer holds the ticket
+	if _, err := transfers.GiftTicket(userIDs[0], ticket.ID, GiftTicketRequest{Username: "User 1"}); err != ErrTicketNotOwned {
+		t.Errorf("Expected ErrTicketNotOwned, got %v", err)
+	}
+	if _, err := transfers.GiftTicket(userIDs[1], ticket.ID, GiftTicketRequest{Username: "User 1"}); err != ErrTransferToSelf {
+		t.Errorf("Expected ErrTransferToSelf, got %v", err)
+	}
+	if _, err := transfers.GiftTicket(userIDs[1], ticket.ID, GiftTicketRequest{Username: "Nobody"}); err != ErrTransferRecipientNotFound {
+		t.Errorf("Expected ErrTransferRecipientNotFound, got %v", err)
+	}
+	if _, err := transfers.ForTenant(2).GiftTicket(userIDs[1], ticket.ID, GiftTicketRequest{Username: "User 0"}); err != ErrTicketNotFound {
+		t.Errorf("Expected ErrTicketNotFound across tenants, got %v", err)
+	}
+	for _, status := range []model.TicketStatus{model.TicketStatusScratching, model.TicketStatusScratched, model.TicketStatusClaimed} {
+		db.Model(&model.Ticket{}).Where("id = ?", ticket.ID).Update("status", status)
+		if _, err := transfers.GiftTicket(userIDs[1], ticket.ID, GiftTicketRequest{Username: "User 0"}); err != ErrTicketNotTransferable {
+			t.Errorf("Expected ErrTicketNotTransferable for a %s ticket, got %v", status, err)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTicketNotTransferable     = errors.New("only unscratched tickets can be gifted")
+	ErrTransferRecipientNotFound = errors.New("ticket gift recipient not found")
+	ErrTransferToSelf            = errors.New("cannot gift a ticket to yourself")
+)
+
+// TicketTransferService lets users give unscratched tickets to other users
+// of the tenant
+type TicketTransferService struct {
+	db                  *gorm.DB
+	notificationService *NotificationService
+}
+
+// NewTicketTransferService creates a new ticket transfer service
+func NewTicketTransferService(db *gorm.DB, notificationService *NotificationService) *TicketTransferService {
+	return &TicketTransferService{db: db, notificationService: notificationService}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *TicketTransferService) ForTenant(tenantID uint) *TicketTransferService {
+	return &TicketTransferService{
+		db:                  repository.ScopeTenant(s.db, tenantID),
+		notificationService: s.notificationService.ForTenant(tenantID),
+	}
+}
+
+// GiftTicketRequest represents a request to give a ticket to another user
+type GiftTicketRequest struct {
+	Username string `json:"username" binding:"required"`
+	Message  string `json:"message" binding:"max=256"`
+}
+
+// TicketTransferResponse represents a ticket gift in responses
+type TicketTransferResponse struct {
+	ID              uint      `json:"id"`
+	TicketID        uint      `json:"ticket_id"`
+	LotteryTypeName string    `json:"lottery_type_name"`
+	FromUserID      uint      `json:"from_user_id"`
+	FromUsername    string    `json:"from_username"`
+	ToUserID        uint      `json:"to_user_id"`
+	ToUsername      string    `json:"to_username"`
+	Message         string    `json:"message"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// TicketTransferQuery represents query parameters for listing ticket gifts
+type TicketTransferQuery struct {
+	Direction string `form:"direction"` // received (default) or sent
+	Page      int    `form:"page"`
+	Limit     int    `form:"limit"`
+}
+
+// TicketTransferListResponse represents paginated ticket gifts
+type TicketTransferListResponse struct {
+	Transfers  []TicketTransferResponse `json:"transfers"`
+	Total      int64                    `json:"total"`
+	Page       int                      `json:"page"`
+	Limit      int                      `json:"limit"`
+	TotalPages int                      `json:"total_pages"`
+}
+
+// GiftTicket moves an unscratched ticket of the user to the user with the
+// given username, recording the transfer in the ticket history and notifying
+// the recipient
+func (s *TicketTransferService) GiftTicket(userID, ticketID uint, req GiftTicketRequest) (*TicketTransferResponse, error) {
+	var ticket model.Ticket
+	if err := s.db.Preload("LotteryType", includeDeleted).First(&ticket, ticketID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTicketNotFound
+		}
+		return nil, err
+	}
+	if ticket.UserID != userID {
+		return nil, ErrTicketNotOwned
+	}
+	if ticket.Status != model.TicketStatusUnscratched {
+		return nil, ErrTicketNotTransferable
+	}
+
+	var recipient model.User
+	if err := s.db.Where("username = ?", strings.TrimSpace(req.Username)).First(&recipient).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTransferRecipientNotFound
+		}
+		return nil, err
+	}
+	if recipient.ID == userID {
+		return nil, ErrTransferToSelf
+	}
+	var sender model.User
+	if err := s.db.Select("id", "username").First(&sender, userID).Error; err != nil {
+		return nil, err
+	}
+
+	transfer := model.TicketTransfer{
+		TicketID:   ticket.ID,
+		FromUserID: userID,
+		ToUserID:   recipient.ID,
+		Message:    req.Message,
+	}
+	err := repository.Transaction(s.db, func(tx *gorm.DB) error {
+		transfer.ID = 0
+		// Guarded against the ticket being scratched or moved meanwhile
+		result := tx.Model(&model.Ticket{}).
+			Where("id = ? AND user_id = ? AND status = ?", ticket.ID, userID, model.TicketStatusUnscratched).
+			Update("user_id", recipient.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTicketNotTransferable
+		}
+		if err := adjustBadge(tx, userID, badgeUnscratchedTickets, -1); err != nil {
+			return err
+		}
+		if err := adjustBadge(tx, recipient.ID, badgeUnscratchedTickets, 1); err != nil {
+			return err
+		}
+
+		if err := tx.Create(&transfer).Error; err != nil {
+			return err
+		}
+		history := model.TicketHistory{
+			TicketID:    ticket.ID,
+			Event:       model.TicketHistoryTransfer,
+			FromUserID:  &userID,
+			ToUserID:    recipient.ID,
+			ReferenceID: transfer.ID,
+		}
+		if err := tx.Create(&history).Error; err != nil {
+			return err
+		}
+
+		content := fmt.Sprintf("%s 赠送了一张「%s」给你", sender.Username, ticket.LotteryType.Name)
+		if req.Message != "" {
+			content += "：" + req.Message
+		}
+		_, err := s.notificationService.notify(tx, recipient.ID, model.NotificationTypeTicketGift, "收到彩票赠送", content)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	transfer.Ticket = ticket
+	transfer.FromUser = sender
+	transfer.ToUser = recipient
+	response := toTicketTransferResponse(&transfer)
+	return &response, nil
+}
+
+// GetTransfers returns the ticket gifts received (default) or sent by a user, newest first
+func (s *TicketTransferService) GetTransfers(userID uint, query TicketTransferQuery) (*TicketTransferListResponse, error) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit < 1 || query.Limit > 100 {
+		query.Limit = 20
+	}
+	column := "to_user_id"
+	if query.Direction == GiftDirectionSent {
+		column = "from_user_id"
+	}
+
+	dbQuery := s.db.Model(&model.TicketTransfer{}).Where(column+" = ?", userID)
+	var total int64
+	if err := dbQuery.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	var transfers []model.TicketTransfer
+	if err := s.db.Preload("Ticket.LotteryType", includeDeleted).
+		Preload("FromUser", includeDeleted).
+		Preload("ToUser", includeDeleted).
+		Where(column+" = ?", userID).
+		Order("created_at DESC, id DESC").
+		Offset((query.Page - 1) * query.Limit).
+		Limit(query.Limit).
+		Find(&transfers).Error; err != nil {
+		return nil, err
+	}
+
+	responses := make([]TicketTransferResponse, len(transfers))
+	for i := range transfers {
+		responses[i] = toTicketTransferResponse(&transfers[i])
+	}
+
+	totalPages := int(total) / query.Limit
+	if int(total)%query.Limit > 0 {
+		totalPages++
+	}
+	return &TicketTransferListResponse{
+		Transfers:  responses,
+		Total:      total,
+		Page:       query.Page,
+		Limit:      query.Limit,
+		TotalPages: totalPages,
+	}, nil
+}
+
+func toTicketTransferResponse(transfer *model.TicketTransfer) TicketTransferResponse {
+	return TicketTransferResponse{
+		ID:              transfer.ID,
+		TicketID:        transfer.TicketID,
+		LotteryTypeName: transfer.Ticket.LotteryType.Name,
+		FromUserID:      transfer.FromUserID,
+		FromUsername:    transfer.FromUser.Username,
+		ToUserID:        transfer.ToUserID,
+		ToUsername:      transfer.ToUser.Username,
+		Message:         transfer.Message,
+		CreatedAt:       transfer.CreatedAt,
+	}
+}
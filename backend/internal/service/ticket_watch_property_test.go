@@ -0,0 +1,161 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+	"gorm.io/gorm"
+)
+
+// createWatchedTicket creates an unscratched ticket of a tenant and returns its security code
+func createWatchedTicket(t *testing.T, db *gorm.DB, tenantID uint, n int, prizeAmount int) string {
+	scoped := repository.ScopeTenant(db, tenantID)
+	lotteryType := model.LotteryType{Name: fmt.Sprintf("Watch %d", n), Price: 5}
+	if err := scoped.Create(&lotteryType).Error; err != nil {
+		t.Fatalf("Failed to create lottery type: %v", err)
+	}
+	code := fmt.Sprintf("WATCH%011d", n)
+	ticket := model.Ticket{
+		LotteryTypeID: lotteryType.ID,
+		SecurityCode:  code,
+		PrizeAmount:   prizeAmount,
+		Status:        model.TicketStatusUnscratched,
+		PurchasedAt:   time.Now(),
+	}
+	if err := scoped.Create(&ticket).Error; err != nil {
+		t.Fatalf("Failed to create ticket: %v", err)
+	}
+	return code
+}
+
+// Ticket watch: listeners learn about a ticket once it is scratched, with the
+// same view VerifySecurityCode gives, and never before.
+func TestTicketWatchNotifiesScratched(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("listeners are notified only on scratched/claimed", prop.ForAll(
+		func(prizeAmount int, listeners int) bool {
+			db := setupTenantTestDB(t)
+			lotteryService := NewLotteryService(db, testEncryptionKey)
+			watcher := NewTicketWatchService(db, lotteryService, 10).ForTenant(2)
+			code := createWatchedTicket(t, db, 2, 1, prizeAmount)
+
+			var channels []<-chan *VerifySecurityCodeResponse
+			for i := 0; i < listeners; i++ {
+				current, updates, cancel, err := watcher.Watch(code, fmt.Sprintf("client-%d", i))
+				if err != nil {
+					t.Logf("Watch failed: %v", err)
+					return false
+				}
+				defer cancel()
+				if current.PrizeAmount != nil {
+					t.Logf("Prize of an unscratched ticket was exposed")
+					return false
+				}
+				channels = append(channels, updates)
+			}
+
+			// Intermediate states are not pushed
+			db.Model(&model.Ticket{}).Where("security_code = ?", code).Update("status", model.TicketStatusScratching)
+			if err := watcher.Poll(); err != nil {
+				return false
+			}
+			for _, ch := range channels {
+				select {
+				case view := <-ch:
+					t.Logf("Unexpected notification %+v", view)
+					return false
+				default:
+				}
+			}
+
+			now := time.Now()
+			db.Model(&model.Ticket{}).Where("security_code = ?", code).
+				Updates(map[string]interface{}{"status": model.TicketStatusScratched, "scratched_at": now})
+			if err := watcher.Poll(); err != nil {
+				return false
+			}
+			expected, _ := lotteryService.VerifySecurityCode(code)
+			for _, ch := range channels {
+				select {
+				case view := <-ch:
+					if view.Status != expected.Status || view.PrizeAmount == nil || *view.PrizeAmount != *expected.PrizeAmount {
+						t.Logf("Expected %+v, got %+v", expected, view)
+						return false
+					}
+				default:
+					t.Logf("Listener was not notified")
+					return false
+				}
+			}
+
+			// Polling again without a change sends nothing
+			if err := watcher.Poll(); err != nil {
+				return false
+			}
+			for _, ch := range channels {
+				if len(ch) != 0 {
+					t.Logf("Duplicate notification")
+					return false
+				}
+			}
+			return true
+		},
+		gen.IntRange(0, 1000),
+		gen.IntRange(1, 4),
+	))
+
+	properties.TestingRun(t)
+}
+
+func TestTicketWatchLimits(t *testing.T) {
+	db := setupTenantTestDB(t)
+	watcher := NewTicketWatchService(db, NewLotteryService(db, testEncryptionKey), 2)
+	codes := []string{createWatchedTicket(t, db, 2, 1, 0), createWatchedTicket(t, db, 2, 2, 0), createWatchedTicket(t, db, 2, 3, 0)}
+
+	// Tickets of another tenant cannot be watched
+	if _, _, _, err := watcher.ForTenant(1).Watch(codes[0], "a"); err != ErrTicketNotFound {
+		t.Errorf("Expected ErrTicketNotFound across tenants, got %v", err)
+	}
+
+	var cancels []func()
+	for _, code := range codes[:2] {
+		_, _, cancel, err := watcher.ForTenant(2).Watch(code, "a")
+		if err != nil {
+			t.Fatalf("Watch failed: %v", err)
+		}
+		cancels = append(cancels, cancel)
+	}
+	if _, _, _, err := watcher.ForTenant(2).Watch(codes[2], "a"); err != ErrTooManyTicketWatches {
+		t.Errorf("Expected ErrTooManyTicketWatches, got %v", err)
+	}
+	if _, _, cancel, err := watcher.ForTenant(2).Watch(codes[2], "b"); err != nil {
+		t.Errorf("Other clients must not be limited: %v", err)
+	} else {
+		cancel()
+	}
+
+	// Cancelling frees the slot and stops watching the code; cancelling twice is harmless
+	cancels[0]()
+	cancels[0]()
+	if _, _, cancel, err := watcher.ForTenant(2).Watch(codes[2], "a"); err != nil {
+		t.Errorf("Expected a free slot after cancel, got %v", err)
+	} else {
+		cancel()
+	}
+	cancels[1]()
+	if len(watcher.hub.watches) != 0 || len(watcher.hub.clients) != 0 {
+		t.Errorf("Expected no watches left, got %d codes and %d clients", len(watcher.hub.watches), len(watcher.hub.clients))
+	}
+}
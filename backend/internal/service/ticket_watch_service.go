@@ -0,0 +1,184 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTooManyTicketWatches = errors.New("too many ticket watches from this client")
+)
+
+// ticketWatchHub keeps the watched security codes and their listeners. It is
+// shared by all tenant copies of the service, as security codes are unique.
+type ticketWatchHub struct {
+	watches map[string]*ticketWatch
+	clients map[string]int
+	mutex   sync.Mutex
+}
+
+// ticketWatch is the last known status of a watched ticket and its listeners
+type ticketWatch struct {
+	status    model.TicketStatus
+	listeners map[chan *VerifySecurityCodeResponse]struct{}
+}
+
+// TicketWatchService lets public verification pages follow a ticket by its
+// security code. Watched tickets are polled in one batch query and listeners
+// are notified when a ticket becomes scratched or claimed. Notifications carry
+// the same view as VerifySecurityCode.
+type TicketWatchService struct {
+	db             *gorm.DB
+	lotteryService *LotteryService
+	hub            *ticketWatchHub
+	maxPerClient   int
+}
+
+// NewTicketWatchService creates a new ticket watch service. A client (IP) may
+// watch at most maxPerClient tickets at a time.
+func NewTicketWatchService(db *gorm.DB, lotteryService *LotteryService, maxPerClient int) *TicketWatchService {
+	if maxPerClient < 1 {
+		maxPerClient = 5
+	}
+	return &TicketWatchService{
+		db:             db,
+		lotteryService: lotteryService,
+		hub: &ticketWatchHub{
+			watches: make(map[string]*ticketWatch),
+			clients: make(map[string]int),
+		},
+		maxPerClient: maxPerClient,
+	}
+}
+
+// ForTenant returns a copy of the service that only watches tickets of a tenant
+func (s *TicketWatchService) ForTenant(tenantID uint) *TicketWatchService {
+	return &TicketWatchService{
+		db:             s.db,
+		lotteryService: s.lotteryService.ForTenant(tenantID),
+		hub:            s.hub,
+		maxPerClient:   s.maxPerClient,
+	}
+}
+
+// Watch verifies a security code and starts watching its ticket. It returns
+// the current view of the ticket and a channel receiving later transitions.
+// The returned cancel func must be called when the listener goes away.
+func (s *TicketWatchService) Watch(code, client string) (*VerifySecurityCodeResponse, <-chan *VerifySecurityCodeResponse, func(), error) {
+	current, err := s.lotteryService.VerifySecurityCode(code)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	s.hub.mutex.Lock()
+	defer s.hub.mutex.Unlock()
+
+	if s.hub.clients[client] >= s.maxPerClient {
+		return nil, nil, nil, ErrTooManyTicketWatches
+	}
+	s.hub.clients[client]++
+
+	watch, exists := s.hub.watches[code]
+	if !exists {
+		watch = &ticketWatch{
+			status:    model.TicketStatus(current.Status),
+			listeners: make(map[chan *VerifySecurityCodeResponse]struct{}),
+		}
+		s.hub.watches[code] = watch
+	}
+	ch := make(chan *VerifySecurityCodeResponse, 4)
+	watch.listeners[ch] = struct{}{}
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			s.hub.mutex.Lock()
+			defer s.hub.mutex.Unlock()
+
+			if s.hub.clients[client]--; s.hub.clients[client] <= 0 {
+				delete(s.hub.clients, client)
+			}
+			if watch, ok := s.hub.watches[code]; ok {
+				if _, ok := watch.listeners[ch]; ok {
+					delete(watch.listeners, ch)
+					close(ch)
+				}
+				if len(watch.listeners) == 0 {
+					delete(s.hub.watches, code)
+				}
+			}
+		})
+	}
+	return current, ch, cancel, nil
+}
+
+// Poll checks all watched tickets and notifies listeners of tickets that
+// became scratched or claimed since the last poll
+func (s *TicketWatchService) Poll() error {
+	s.hub.mutex.Lock()
+	codes := make([]string, 0, len(s.hub.watches))
+	for code := range s.hub.watches {
+		codes = append(codes, code)
+	}
+	s.hub.mutex.Unlock()
+
+	if len(codes) == 0 {
+		return nil
+	}
+
+	var tickets []model.Ticket
+	if err := s.db.Preload("LotteryType", includeDeleted).Where("security_code IN ?", codes).Find(&tickets).Error; err != nil {
+		return err
+	}
+
+	s.hub.mutex.Lock()
+	defer s.hub.mutex.Unlock()
+
+	for i := range tickets {
+		ticket := &tickets[i]
+		watch, ok := s.hub.watches[ticket.SecurityCode]
+		if !ok || watch.status == ticket.Status {
+			continue
+		}
+		watch.status = ticket.Status
+		if !ticket.Status.Revealed() {
+			continue
+		}
+
+		view := buildVerifyResponse(ticket)
+		for ch := range watch.listeners {
+			select {
+			case ch <- view:
+			default:
+			}
+		}
+	}
+	return nil
+}
+
+// Start polls the watched tickets every interval in the background.
+// The returned stop func ends polling.
+func (s *TicketWatchService) Start(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := s.Poll(); err != nil {
+					logger.Default().Warn("Ticket watch poll failed: %v", err)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
@@ -0,0 +1,304 @@
+package service
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
+)
+
+// Disputing an exchange freezes its card key until an admin resolves the
+// dispute; a refund credits the charge back and keeps the key frozen
+func TestExchangeDisputeFreezesCardKey(t *testing.T) {
+	db := setupExchangeTestDB(t)
+	if err := db.AutoMigrate(&model.CardKeyReveal{}, &model.Refund{}, &model.AdminLog{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	if err := createTestUserWithBalance(db, 1, 100); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if err := createTestUserWithBalance(db, 2, 100); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if err := createTestProductWithCardKeys(db, 1, 30, 2); err != nil {
+		t.Fatalf("Failed to create product: %v", err)
+	}
+	exchangeService := NewExchangeService(db, NewWalletService(db))
+	disputes := NewTransactionDisputeService(db, 0)
+
+	redeemed, err := exchangeService.Redeem(1, 1)
+	if err != nil {
+		t.Fatalf("Redeem failed: %v", err)
+	}
+	var charge model.Transaction
+	db.Where("type = ? AND amount = ?", model.TransactionTypeExchange, -30).First(&charge)
+
+	// Only the user's own debits can be disputed
+	if _, err := disputes.OpenDispute(2, charge.ID, OpenDisputeRequest{Reason: "not mine"}); err != ErrTransactionNotFound {
+		t.Errorf("Expected ErrTransactionNotFound for another user's transaction, got %v", err)
+	}
+	credit := model.Transaction{WalletID: charge.WalletID, Type: model.TransactionTypeRecharge, Currency: model.CurrencyPoints, Amount: 50}
+	db.Create(&credit)
+	if _, err := disputes.OpenDispute(1, credit.ID, OpenDisputeRequest{Reason: "not mine"}); err != ErrTransactionNotDisputable {
+		t.Errorf("Expected ErrTransactionNotDisputable for a credit, got %v", err)
+	}
+	if _, err := disputes.OpenDispute(1, charge.ID, OpenDisputeRequest{Reason: " "}); err != ErrInvalidDispute {
+		t.Errorf("Expected ErrInvalidDispute without a reason, got %v", err)
+	}
+
+	dispute, err := disputes.OpenDispute(1, charge.ID, OpenDisputeRequest{Reason: "我没有兑换过这个商品"})
+	if err != nil {
+		t.Fatalf("OpenDispute failed: %v", err)
+	}
+	if dispute.ExchangeRecordID != redeemed.RecordID || dispute.Amount != 30 || dispute.FreezesPayouts {
+		t.Fatalf("Unexpected dispute %+v", dispute)
+	}
+	if _, err := disputes.OpenDispute(1, charge.ID, OpenDisputeRequest{Reason: "again"}); err != ErrTransactionAlreadyDisputed {
+		t.Errorf("Expected ErrTransactionAlreadyDisputed, got %v", err)
+	}
+
+	// The card key is frozen
+	if _, err := exchangeService.RevealCardKey(1, redeemed.RecordID, "127.0.0.1", "test"); err != ErrExchangeDisputed {
+		t.Errorf("Expected ErrExchangeDisputed, got %v", err)
+	}
+	record, err := exchangeService.GetExchangeRecordByID(1, redeemed.RecordID)
+	if err != nil || !record.Disputed || record.CardKey != "" {
+		t.Errorf("Expected the record's key hidden, got %+v (err %v)", record, err)
+	}
+
+	queue, err := disputes.GetDisputes(AdminDisputeQuery{})
+	if err != nil || queue.Total != 1 || queue.Disputes[0].ID != dispute.ID {
+		t.Fatalf("Expected the dispute queued, got %+v (err %v)", queue, err)
+	}
+
+	if _, err := disputes.RefundDispute(7, dispute.ID, ResolveDisputeRequest{Resolution: " "}); err != ErrInvalidDispute {
+		t.Errorf("Expected ErrInvalidDispute without a resolution, got %v", err)
+	}
+	refunded, err := disputes.RefundDispute(7, dispute.ID, ResolveDisputeRequest{Resolution: "账户被盗用，退回积分"})
+	if err != nil {
+		t.Fatalf("RefundDispute failed: %v", err)
+	}
+	if refunded.Status != model.TransactionDisputeStatusRefunded || refunded.RefundID == 0 || refunded.ResolvedBy != 7 {
+		t.Errorf("Unexpected resolved dispute %+v", refunded)
+	}
+	if balance := walletBalance(db, 1); balance != 100 {
+		t.Errorf("Expected the charge refunded to 100, got %d", balance)
+	}
+	var refund model.Refund
+	db.First(&refund, refunded.RefundID)
+	if refund.PurchaseID != charge.ID || refund.Amount != 30 || refund.Status != model.RefundStatusRefunded {
+		t.Errorf("Unexpected refund %+v", refund)
+	}
+	if _, err := disputes.UpholdDispute(7, dispute.ID, ResolveDisputeRequest{Resolution: "again"}); err != ErrDisputeNotOpen {
+		t.Errorf("Expected ErrDisputeNotOpen, got %v", err)
+	}
+
+	// The refunded exchange keeps its key frozen
+	if _, err := exchangeService.RevealCardKey(1, redeemed.RecordID, "127.0.0.1", "test"); err != ErrExchangeDisputed {
+		t.Errorf("Expected the refunded key still frozen, got %v", err)
+	}
+	if queue, _ := disputes.GetDisputes(AdminDisputeQuery{}); queue.Total != 0 {
+		t.Errorf("Expected an empty queue, got %d", queue.Total)
+	}
+
+	var logs []model.AdminLog
+	db.Where("target_type = ? AND target_id = ?", "transaction_dispute", dispute.ID).Order("id").Find(&logs)
+	if len(logs) != 2 || logs[0].Action != "open_transaction_dispute" || logs[0].AdminID != 0 ||
+		logs[1].Action != "refund_transaction_dispute" || logs[1].AdminID != 7 {
+		t.Errorf("Expected the dispute audited, got %+v", logs)
+	}
+}
+
+// Disputing a ticket purchase holds the user's prize payouts until the
+// dispute is resolved
+func TestPurchaseDisputeHoldsPayouts(t *testing.T) {
+	db, scratchService, largeWins, userID, ticketIDs := setupLargeWinTest(t, []int{1000})
+	if err := db.AutoMigrate(&model.Refund{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	threshold, required := 500, true
+	if _, err := largeWins.adminService.UpdateSystemSettings(1, UpdateSystemSettingsRequest{
+		LargeWinThreshold:        &threshold,
+		LargeWinIdentityRequired: &required,
+	}); err != nil {
+		t.Fatalf("UpdateSystemSettings failed: %v", err)
+	}
+	if _, err := scratchService.ScratchTicket(userID, ticketIDs[0], ""); err != nil {
+		t.Fatalf("ScratchTicket failed: %v", err)
+	}
+
+	var wallet model.Wallet
+	db.Where("user_id = ?", userID).First(&wallet)
+	charge := model.Transaction{WalletID: wallet.ID, Type: model.TransactionTypePurchase, Currency: model.CurrencyPoints, Amount: -10}
+	db.Create(&charge)
+	balance := walletBalance(db, userID)
+
+	disputes := NewTransactionDisputeService(db, 0)
+	dispute, err := disputes.OpenDispute(userID, charge.ID, OpenDisputeRequest{Reason: "我没有买过这张彩票"})
+	if err != nil || !dispute.FreezesPayouts {
+		t.Fatalf("Expected a dispute holding payouts, got %+v (err %v)", dispute, err)
+	}
+
+	// The claim is held for approval, which waits for the dispute
+	claim, err := largeWins.ClaimPrize(userID, ticketIDs[0], ClaimPrizeRequest{FullName: "张三", IDNumber: "110101199001011234"})
+	if err != nil || claim.Status != model.PrizeClaimStatusSubmitted {
+		t.Fatalf("Expected the claim held, got %+v (err %v)", claim, err)
+	}
+	if walletBalance(db, userID) != balance {
+		t.Error("Expected no payout while disputed")
+	}
+	if _, err := largeWins.ApproveClaim(7, claim.ID); err != ErrPayoutDisputed {
+		t.Errorf("Expected ErrPayoutDisputed, got %v", err)
+	}
+
+	if _, err := disputes.UpholdDispute(7, dispute.ID, ResolveDisputeRequest{Resolution: "购票记录与设备一致"}); err != nil {
+		t.Fatalf("UpholdDispute failed: %v", err)
+	}
+	if _, err := largeWins.ApproveClaim(7, claim.ID); err != nil {
+		t.Fatalf("ApproveClaim failed: %v", err)
+	}
+	if got := walletBalance(db, userID); got != balance+1000 {
+		t.Errorf("Expected balance %d after the payout, got %d", balance+1000, got)
+	}
+}
+
+// Disputing a ticket purchase paid in bonus credits holds payouts like any
+// purchase, and refunding it credits the bonus balance rather than points
+func TestBonusPurchaseDispute(t *testing.T) {
+	db, _, _, userID, _ := setupLargeWinTest(t, []int{1000})
+	if err := db.AutoMigrate(&model.Refund{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	if err := grantBonus(db, userID, 30, "bonus", 0); err != nil {
+		t.Fatalf("grantBonus failed: %v", err)
+	}
+	var wallet model.Wallet
+	db.Where("user_id = ?", userID).First(&wallet)
+	charge := model.Transaction{WalletID: wallet.ID, Type: model.TransactionTypeBonusPurchase, Currency: model.CurrencyBonus, Amount: -10}
+	db.Create(&charge)
+	db.Model(&wallet).Update("bonus_balance", 20)
+	balance := walletBalance(db, userID)
+
+	disputes := NewTransactionDisputeService(db, 0)
+	dispute, err := disputes.OpenDispute(userID, charge.ID, OpenDisputeRequest{Reason: "我没有买过这张彩票"})
+	if err != nil || !dispute.FreezesPayouts {
+		t.Fatalf("Expected a dispute holding payouts, got %+v (err %v)", dispute, err)
+	}
+	if _, err := disputes.RefundDispute(7, dispute.ID, ResolveDisputeRequest{Resolution: "重复扣款"}); err != nil {
+		t.Fatalf("RefundDispute failed: %v", err)
+	}
+
+	db.First(&wallet, wallet.ID)
+	if wallet.BonusBalance != 30 || wallet.Balance != balance {
+		t.Errorf("Expected 30 bonus credits and %d points, got %d and %d", balance, wallet.BonusBalance, wallet.Balance)
+	}
+	var refund model.Transaction
+	db.Where("wallet_id = ? AND type = ?", wallet.ID, model.TransactionTypeRefund).First(&refund)
+	if refund.Currency != model.CurrencyBonus || refund.Amount != 10 {
+		t.Errorf("Expected a bonus refund of 10, got %+v", refund)
+	}
+}
+
+// Refunding a disputed ticket purchase voids its tickets, unless one of them
+// has been scratched
+func TestPurchaseDisputeRefundVoidsTickets(t *testing.T) {
+	db, purchases, _, lotteryTypeID, userID := setupRefundTest(t)
+	var bought []*PurchaseResponse
+	for i := 0; i < 2; i++ {
+		purchase, err := purchases.PurchaseTickets(userID, PurchaseRequest{LotteryTypeID: lotteryTypeID, Quantity: 2})
+		if err != nil {
+			t.Fatalf("PurchaseTickets failed: %v", err)
+		}
+		bought = append(bought, purchase)
+	}
+	var charges []model.Transaction
+	db.Where("type = ?", model.TransactionTypePurchase).Order("id").Find(&charges)
+	scratches := NewScratchService(db, purchases.lotteryService, purchases.walletService, nil, nil)
+	if _, err := scratches.ScratchTicket(userID, bought[1].Tickets[0].ID, ""); err != nil {
+		t.Fatalf("ScratchTicket failed: %v", err)
+	}
+
+	disputes := NewTransactionDisputeService(db, 0)
+	var opened []*model.TransactionDispute
+	for _, charge := range charges {
+		dispute, err := disputes.OpenDispute(userID, charge.ID, OpenDisputeRequest{Reason: "我没有买过这张彩票"})
+		if err != nil {
+			t.Fatalf("OpenDispute failed: %v", err)
+		}
+		opened = append(opened, dispute)
+	}
+	if _, err := disputes.RefundDispute(7, opened[1].ID, ResolveDisputeRequest{Resolution: "账户被盗用"}); err != ErrPurchasePlayed {
+		t.Errorf("Expected ErrPurchasePlayed refunding a scratched purchase, got %v", err)
+	}
+	if _, err := disputes.RefundDispute(7, opened[0].ID, ResolveDisputeRequest{Resolution: "账户被盗用"}); err != nil {
+		t.Fatalf("RefundDispute failed: %v", err)
+	}
+
+	var voided []model.Ticket
+	db.Where("status = ?", model.TicketStatusVoided).Find(&voided)
+	if len(voided) != 2 || voided[0].PurchaseID != charges[0].ID || voided[1].PurchaseID != charges[0].ID {
+		t.Errorf("Expected the tickets of the refunded purchase voided, got %+v", voided)
+	}
+}
+
+// Debits older than the dispute window cannot be disputed
+func TestDisputeWindow(t *testing.T) {
+	db, _, _, _, userID := setupRefundTest(t)
+	var wallet model.Wallet
+	db.Where("user_id = ?", userID).First(&wallet)
+	old := model.Transaction{WalletID: wallet.ID, Type: model.TransactionTypePurchase, Currency: model.CurrencyPoints, Amount: -10}
+	old.CreatedAt = time.Now().AddDate(0, 0, -31)
+	db.Create(&old)
+	recent := model.Transaction{WalletID: wallet.ID, Type: model.TransactionTypePurchase, Currency: model.CurrencyPoints, Amount: -10}
+	recent.CreatedAt = time.Now().AddDate(0, 0, -29)
+	db.Create(&recent)
+
+	disputes := NewTransactionDisputeService(db, 30*24*time.Hour)
+	if _, err := disputes.OpenDispute(userID, old.ID, OpenDisputeRequest{Reason: "我没有买过这张彩票"}); err != ErrDisputeWindowClosed {
+		t.Errorf("Expected ErrDisputeWindowClosed for a debit of 31 days ago, got %v", err)
+	}
+	if _, err := disputes.ForTenant(1).OpenDispute(userID, recent.ID, OpenDisputeRequest{Reason: "我没有买过这张彩票"}); err != nil {
+		t.Errorf("Expected a debit of 29 days ago disputable, got %v", err)
+	}
+	if _, err := NewTransactionDisputeService(db, 0).OpenDispute(userID, old.ID, OpenDisputeRequest{Reason: "我没有买过这张彩票"}); err != nil {
+		t.Errorf("Expected any debit disputable without a window, got %v", err)
+	}
+}
+
+// Concurrent disputes of one transaction open a single dispute
+func TestConcurrentDisputesOpenOnce(t *testing.T) {
+	db, _, _, _, userID := setupRefundTest(t)
+	var wallet model.Wallet
+	db.Where("user_id = ?", userID).First(&wallet)
+	charge := model.Transaction{WalletID: wallet.ID, Type: model.TransactionTypePurchase, Currency: model.CurrencyPoints, Amount: -10}
+	db.Create(&charge)
+
+	disputes := NewTransactionDisputeService(db, 30*24*time.Hour)
+	var wg sync.WaitGroup
+	errs := make([]error, 5)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = disputes.OpenDispute(userID, charge.ID, OpenDisputeRequest{Reason: "我没有买过这张彩票"})
+		}(i)
+	}
+	wg.Wait()
+
+	opened := 0
+	for _, err := range errs {
+		switch err {
+		case nil:
+			opened++
+		case ErrTransactionAlreadyDisputed:
+		default:
+			t.Errorf("Expected ErrTransactionAlreadyDisputed, got %v", err)
+		}
+	}
+	var count int64
+	db.Model(&model.TransactionDispute{}).Where("transaction_id = ? AND status = ?", charge.ID, model.TransactionDisputeStatusOpen).Count(&count)
+	if opened != 1 || count != 1 {
+		t.Errorf("Expected one open dispute, got %d opened and %d stored", opened, count)
+	}
+}
@@ -0,0 +1,409 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// maxDisputeReasonLength is the maximum length of a dispute reason or resolution
+const maxDisputeReasonLength = 255
+
+var (
+	ErrDisputeNotFound            = errors.New("dispute not found")
+	ErrTransactionNotFound        = errors.New("transaction not found")
+	ErrDisputeNotOpen             = errors.New("dispute already resolved")
+	ErrInvalidDispute             = errors.New("invalid dispute")
+	ErrTransactionNotDisputable   = errors.New("transaction cannot be disputed")
+	ErrTransactionAlreadyDisputed = errors.New("transaction already disputed")
+	ErrDisputeWindowClosed        = errors.New("transaction too old to dispute")
+	ErrExchangeDisputed           = errors.New("exchange is disputed")
+	ErrPayoutDisputed             = errors.New("payouts held by an open dispute")
+)
+
+// TransactionDisputeService lets users dispute debits of their wallet they
+// did not make. A dispute freezes what the debit paid for until an admin
+// resolves it: the card key of a disputed exchange cannot be revealed, and
+// the prize payouts of a user disputing a ticket purchase are held. Admins
+// uphold the transaction or refund it; every step is recorded in the admin
+// log.
+type TransactionDisputeService struct {
+	db     *gorm.DB
+	window time.Duration // how long after a debit it can be disputed, 0 for no limit
+}
+
+// NewTransactionDisputeService creates a new transaction dispute service
+// accepting disputes of debits up to window old; 0 accepts any debit
+func NewTransactionDisputeService(db *gorm.DB, window time.Duration) *TransactionDisputeService {
+	return &TransactionDisputeService{db: db, window: window}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *TransactionDisputeService) ForTenant(tenantID uint) *TransactionDisputeService {
+	return &TransactionDisputeService{db: repository.ScopeTenant(s.db, tenantID), window: s.window}
+}
+
+// OpenDisputeRequest represents a user's dispute of a transaction
+type OpenDisputeRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// ResolveDisputeRequest represents an admin's resolution of a dispute
+type ResolveDisputeRequest struct {
+	Resolution string `json:"resolution" binding:"required"`
+}
+
+// AdminDisputeQuery represents query parameters for the admin dispute queue
+type AdminDisputeQuery struct {
+	Status string `form:"status"` // Open disputes when empty, "all" for every status
+	UserID uint   `form:"user_id"`
+	Page   int    `form:"page"`
+	Limit  int    `form:"limit"`
+}
+
+// AdminDisputeListResponse represents a page of disputes
+type AdminDisputeListResponse struct {
+	Disputes   []model.TransactionDispute `json:"disputes"`
+	Total      int64                      `json:"total"`
+	Page       int                        `json:"page"`
+	Limit      int                        `json:"limit"`
+	TotalPages int                        `json:"total_pages"`
+}
+
+// OpenDispute disputes a debit of the user's own wallet and freezes
+// what it paid for. Each transaction can be disputed once, within the
+// dispute window of the service.
+func (s *TransactionDisputeService) OpenDispute(userID, transactionID uint, req OpenDisputeRequest) (*model.TransactionDispute, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" || len([]rune(reason)) > maxDisputeReasonLength {
+		return nil, ErrInvalidDispute
+	}
+
+	var wallet model.Wallet
+	if err := s.db.Where("user_id = ?", userID).First(&wallet).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWalletNotFound
+		}
+		return nil, err
+	}
+	var transaction model.Transaction
+	if err := s.db.Where("wallet_id = ?", wallet.ID).First(&transaction, transactionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTransactionNotFound
+		}
+		return nil, err
+	}
+	// Bonus credits are promotional: only the tickets they paid for can be
+	// disputed, and those are refunded as bonus credits
+	if transaction.Amount >= 0 || (transaction.Currency == model.CurrencyBonus && transaction.Type != model.TransactionTypeBonusPurchase) {
+		return nil, ErrTransactionNotDisputable
+	}
+	if s.window > 0 && time.Since(transaction.CreatedAt) > s.window {
+		return nil, ErrDisputeWindowClosed
+	}
+
+	dispute := &model.TransactionDispute{
+		UserID:          userID,
+		TransactionID:   transaction.ID,
+		TransactionType: transaction.Type,
+		Amount:          -transaction.Amount,
+		Reason:          reason,
+		Status:          model.TransactionDisputeStatusOpen,
+		FreezesPayouts:  transaction.Type == model.TransactionTypePurchase || transaction.Type == model.TransactionTypeBonusPurchase,
+	}
+	if transaction.Type == model.TransactionTypeExchange {
+		record, err := s.exchangeRecordOf(userID, &transaction)
+		if err != nil {
+			return nil, err
+		}
+		if record != nil {
+			dispute.ExchangeRecordID = record.ID
+		}
+	}
+
+	err := repository.Transaction(s.db, func(tx *gorm.DB) error {
+		// The wallet lock serialises disputes of the user's transactions, so
+		// concurrent requests cannot both find the transaction undisputed
+		var locked model.Wallet
+		if err := lockWallet(tx, userID, &locked); err != nil {
+			return err
+		}
+		var existing int64
+		if err := tx.Model(&model.TransactionDispute{}).Where("transaction_id = ?", transaction.ID).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return ErrTransactionAlreadyDisputed
+		}
+		if err := tx.Create(dispute).Error; err != nil {
+			return err
+		}
+		// The freeze is applied by the system, not by an admin
+		return logDispute(tx, 0, dispute, "open_transaction_dispute", reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dispute, nil
+}
+
+// exchangeRecordOf finds the exchange record an exchange debit paid for,
+// which the debit references
+func (s *TransactionDisputeService) exchangeRecordOf(userID uint, transaction *model.Transaction) (*model.ExchangeRecord, error) {
+	var records []model.ExchangeRecord
+	if err := s.db.Where("id = ? AND user_id = ? AND cost = ? AND status <> ?",
+		transaction.ReferenceID, userID, -transaction.Amount, model.ExchangeRecordStatusReleased).
+		Limit(1).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	return &records[0], nil
+}
+
+// GetUserDisputes returns the disputes of a user, newest first
+func (s *TransactionDisputeService) GetUserDisputes(userID uint) ([]model.TransactionDispute, error) {
+	disputes := []model.TransactionDispute{}
+	if err := s.db.Where("user_id = ?", userID).Order("created_at DESC, id DESC").Find(&disputes).Error; err != nil {
+		return nil, err
+	}
+	return disputes, nil
+}
+
+// GetDisputes returns a page of disputes. The queue of open disputes is
+// oldest first; other statuses are newest first.
+func (s *TransactionDisputeService) GetDisputes(query AdminDisputeQuery) (*AdminDisputeListResponse, error) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit < 1 || query.Limit > 100 {
+		query.Limit = 20
+	}
+	if query.Status == "" {
+		query.Status = string(model.TransactionDisputeStatusOpen)
+	}
+
+	dbQuery := s.db.Model(&model.TransactionDispute{})
+	if query.Status != "all" {
+		dbQuery = dbQuery.Where("status = ?", query.Status)
+	}
+	if query.UserID != 0 {
+		dbQuery = dbQuery.Where("user_id = ?", query.UserID)
+	}
+	var total int64
+	if err := dbQuery.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+	order := "created_at DESC, id DESC"
+	if query.Status == string(model.TransactionDisputeStatusOpen) {
+		order = "created_at ASC, id ASC"
+	}
+	disputes := []model.TransactionDispute{}
+	if err := dbQuery.Order(order).
+		Offset((query.Page - 1) * query.Limit).
+		Limit(query.Limit).
+		Find(&disputes).Error; err != nil {
+		return nil, err
+	}
+
+	return &AdminDisputeListResponse{
+		Disputes:   disputes,
+		Total:      total,
+		Page:       query.Page,
+		Limit:      query.Limit,
+		TotalPages: int((total + int64(query.Limit) - 1) / int64(query.Limit)),
+	}, nil
+}
+
+// UpholdDispute closes a dispute with the transaction standing, which lifts
+// its freeze
+func (s *TransactionDisputeService) UpholdDispute(adminID, disputeID uint, req ResolveDisputeRequest) (*model.TransactionDispute, error) {
+	resolution := strings.TrimSpace(req.Resolution)
+	if resolution == "" || len([]rune(resolution)) > maxDisputeReasonLength {
+		return nil, ErrInvalidDispute
+	}
+	dispute, err := s.getOpenDispute(disputeID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	err = repository.Transaction(s.db, func(tx *gorm.DB) error {
+		if err := resolveDispute(tx, dispute, adminID, now, model.TransactionDisputeStatusUpheld, resolution, 0); err != nil {
+			return err
+		}
+		return logDispute(tx, adminID, dispute, "uphold_transaction_dispute", resolution)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dispute, nil
+}
+
+// RefundDispute closes a dispute by refunding the part of the transaction
+// not refunded yet as a refund of it. The card key of a refunded exchange
+// stays frozen, since the user no longer paid for it, and the tickets of a
+// refunded purchase are voided; a purchase with a scratched ticket is not
+// refunded.
+func (s *TransactionDisputeService) RefundDispute(adminID, disputeID uint, req ResolveDisputeRequest) (*model.TransactionDispute, error) {
+	resolution := strings.TrimSpace(req.Resolution)
+	if resolution == "" || len([]rune(resolution)) > maxDisputeReasonLength {
+		return nil, ErrInvalidDispute
+	}
+	dispute, err := s.getOpenDispute(disputeID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	err = repository.Transaction(s.db, func(tx *gorm.DB) error {
+		// The wallet lock serialises refunds of the user's transactions
+		var wallet model.Wallet
+		if err := lockWallet(tx, dispute.UserID, &wallet); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrWalletNotFound
+			}
+			return err
+		}
+		var refunded struct{ Total int }
+		if err := tx.Model(&model.Refund{}).Select("COALESCE(SUM(amount), 0) as total").
+			Where("purchase_id = ? AND status IN ?", dispute.TransactionID, []model.RefundStatus{model.RefundStatusPending, model.RefundStatusRefunded}).
+			Scan(&refunded).Error; err != nil {
+			return err
+		}
+		amount := dispute.Amount - refunded.Total
+		if amount <= 0 {
+			return ErrInvalidRefund
+		}
+
+		currency := model.CurrencyPoints
+		if dispute.TransactionType == model.TransactionTypeBonusPurchase {
+			currency = model.CurrencyBonus
+		}
+		if dispute.TransactionType == model.TransactionTypePurchase || dispute.TransactionType == model.TransactionTypeBonusPurchase {
+			var charge model.Transaction
+			if err := tx.First(&charge, dispute.TransactionID).Error; err != nil {
+				return err
+			}
+			if err := voidPurchaseTickets(tx, charge.ReferenceID, adminID, resolution); err != nil {
+				return err
+			}
+		}
+		refund := &model.Refund{
+			UserID:     dispute.UserID,
+			PurchaseID: dispute.TransactionID,
+			Currency:   currency,
+			Amount:     amount,
+			Status:     model.RefundStatusPending,
+			Reason:     resolution,
+		}
+		if err := tx.Create(refund).Error; err != nil {
+			return err
+		}
+		if err := creditRefund(tx, refund, adminID, now, "交易争议退款"); err != nil {
+			return err
+		}
+		if err := resolveDispute(tx, dispute, adminID, now, model.TransactionDisputeStatusRefunded, resolution, refund.ID); err != nil {
+			return err
+		}
+		return logDispute(tx, adminID, dispute, "refund_transaction_dispute", resolution)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dispute, nil
+}
+
+// getOpenDispute loads a dispute waiting for an admin
+func (s *TransactionDisputeService) getOpenDispute(disputeID uint) (*model.TransactionDispute, error) {
+	var dispute model.TransactionDispute
+	if err := s.db.First(&dispute, disputeID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDisputeNotFound
+		}
+		return nil, err
+	}
+	if dispute.Status != model.TransactionDisputeStatusOpen {
+		return nil, ErrDisputeNotOpen
+	}
+	return &dispute, nil
+}
+
+// resolveDispute moves an open dispute to its resolved status, guarding
+// against a concurrent resolution
+func resolveDispute(tx *gorm.DB, dispute *model.TransactionDispute, adminID uint, now time.Time, status model.TransactionDisputeStatus, resolution string, refundID uint) error {
+	result := tx.Model(&model.TransactionDispute{}).
+		Where("id = ? AND status = ?", dispute.ID, model.TransactionDisputeStatusOpen).
+		Updates(map[string]interface{}{
+			"status":      status,
+			"resolution":  resolution,
+			"refund_id":   refundID,
+			"resolved_by": adminID,
+			"resolved_at": now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrDisputeNotOpen
+	}
+	dispute.Status = status
+	dispute.Resolution = resolution
+	dispute.RefundID = refundID
+	dispute.ResolvedBy = adminID
+	dispute.ResolvedAt = &now
+	return nil
+}
+
+// logDispute records a step of a dispute in the admin log
+func logDispute(tx *gorm.DB, adminID uint, dispute *model.TransactionDispute, action, reason string) error {
+	details, _ := json.Marshal(map[string]interface{}{
+		"user_id":            dispute.UserID,
+		"transaction_id":     dispute.TransactionID,
+		"amount":             dispute.Amount,
+		"exchange_record_id": dispute.ExchangeRecordID,
+		"freezes_payouts":    dispute.FreezesPayouts,
+		"refund_id":          dispute.RefundID,
+		"reason":             reason,
+	})
+	adminLog := model.AdminLog{
+		AdminID:    adminID,
+		Action:     action,
+		TargetType: "transaction_dispute",
+		TargetID:   dispute.ID,
+		Details:    string(details),
+	}
+	return tx.Create(&adminLog).Error
+}
+
+// exchangeRecordFrozen reports whether a dispute freezes the card key of an
+// exchange record: while it is open, and for good once it was refunded
+func exchangeRecordFrozen(db *gorm.DB, recordID uint) (bool, error) {
+	var count int64
+	if err := db.Model(&model.TransactionDispute{}).
+		Where("exchange_record_id = ? AND status IN ?", recordID,
+			[]model.TransactionDisputeStatus{model.TransactionDisputeStatusOpen, model.TransactionDisputeStatusRefunded}).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// payoutsFrozen reports whether an open dispute of a ticket purchase holds
+// the prize payouts of a user
+func payoutsFrozen(db *gorm.DB, userID uint) (bool, error) {
+	var count int64
+	if err := db.Model(&model.TransactionDispute{}).
+		Where("user_id = ? AND freezes_payouts = ? AND status = ?", userID, true, model.TransactionDisputeStatusOpen).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
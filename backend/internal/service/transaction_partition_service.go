@@ -0,0 +1,59 @@
+package service
+
+import (
+	"time"
+
+	"scratch-lottery/internal/repository"
+	"scratch-lottery/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// TransactionPartitionService keeps monthly partitions of the transactions
+// table created ahead of time, so new transactions never fall into the
+// default partition. It does nothing until the table has been partitioned.
+type TransactionPartitionService struct {
+	db          *gorm.DB
+	monthsAhead int
+}
+
+// NewTransactionPartitionService creates a new transaction partition service
+func NewTransactionPartitionService(db *gorm.DB, monthsAhead int) *TransactionPartitionService {
+	return &TransactionPartitionService{db: db, monthsAhead: monthsAhead}
+}
+
+// Maintain creates the partitions of the current month through monthsAhead
+// months ahead that don't exist yet. Returns the names of the partitions
+// created.
+func (s *TransactionPartitionService) Maintain() ([]string, error) {
+	partitioned, err := repository.TransactionsPartitioned(s.db)
+	if err != nil || !partitioned {
+		return nil, err
+	}
+	return repository.EnsureTransactionPartitions(s.db, time.Now(), s.monthsAhead)
+}
+
+// Start maintains the partitions now and then at every interval until stop
+// is called
+func (s *TransactionPartitionService) Start(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			created, err := s.Maintain()
+			if err != nil {
+				logger.Default().Warn("Maintaining transaction partitions failed: %v", err)
+			} else if len(created) > 0 {
+				logger.Default().Info("Created transaction partitions %v", created)
+			}
+
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return func() { close(done) }
+}
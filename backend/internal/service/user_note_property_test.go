@@ -0,0 +1,156 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+	"gorm.io/gorm"
+)
+
+func setupUserNoteTest(t *testing.T) (*gorm.DB, *UserNoteService, uint, []uint) {
+	db := setupTenantTestDB(t)
+	user := model.User{LinuxdoID: "noted", Username: "noted", Role: "user"}
+	db.Create(&user)
+	db.Create(&model.Wallet{UserID: user.ID})
+
+	adminIDs := make([]uint, 2)
+	for i := range adminIDs {
+		admin := model.User{LinuxdoID: fmt.Sprintf("note_admin_%d", i), Username: fmt.Sprintf("Admin %d", i), Role: "admin"}
+		db.Create(&admin)
+		adminIDs[i] = admin.ID
+	}
+	return db, NewUserNoteService(db), user.ID, adminIDs
+}
+
+// User notes: notes are listed pinned first and then newest first, and the
+// user detail carries the first of them.
+func TestUserNoteOrdering(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("notes are pinned first, then newest first", prop.ForAll(
+		func(pinned []bool) bool {
+			db, notes, userID, adminIDs := setupUserNoteTest(t)
+
+			created := make([]*UserNoteResponse, len(pinned))
+			for i, pin := range pinned {
+				note, err := notes.CreateNote(adminIDs[i%2], userID, CreateUserNoteRequest{Content: fmt.Sprintf("note %d", i), Pinned: pin})
+				if err != nil {
+					t.Logf("CreateNote failed: %v", err)
+					return false
+				}
+				created[i] = note
+			}
+			expected := append([]*UserNoteResponse{}, created...)
+			sort.SliceStable(expected, func(i, j int) bool {
+				if expected[i].Pinned != expected[j].Pinned {
+					return expected[i].Pinned
+				}
+				return expected[i].ID > expected[j].ID
+			})
+
+			list, err := notes.GetNotes(userID, 1, 100)
+			if err != nil {
+				t.Logf("GetNotes failed: %v", err)
+				return false
+			}
+			if list.Total != int64(len(pinned)) || len(list.Notes) != len(pinned) {
+				t.Logf("Expected %d notes, got %d of %d", len(pinned), len(list.Notes), list.Total)
+				return false
+			}
+			for i := range expected {
+				if list.Notes[i].ID != expected[i].ID {
+					t.Logf("Note %d is %d, expected %d", i, list.Notes[i].ID, expected[i].ID)
+					return false
+				}
+			}
+
+			detail, err := NewAdminService(db, NewWalletService(db)).GetUserByID(userID)
+			if err != nil {
+				t.Logf("GetUserByID failed: %v", err)
+				return false
+			}
+			want := min(len(expected), recentUserNotes)
+			if len(detail.Notes) != want {
+				t.Logf("Expected %d notes in the user detail, got %d", want, len(detail.Notes))
+				return false
+			}
+			for i := 0; i < want; i++ {
+				if detail.Notes[i].ID != expected[i].ID || detail.Notes[i].AuthorName == "" {
+					t.Logf("Detail note %d is %+v, expected %d", i, detail.Notes[i], expected[i].ID)
+					return false
+				}
+			}
+			return true
+		},
+		gen.SliceOf(gen.Bool()),
+	))
+
+	properties.TestingRun(t)
+}
+
+// User notes: only the author edits or deletes a note, any admin pins it, and
+// notes never leave their tenant.
+func TestUserNotePermissions(t *testing.T) {
+	db, notes, userID, adminIDs := setupUserNoteTest(t)
+	author, other := adminIDs[0], adminIDs[1]
+
+	note, err := notes.CreateNote(author, userID, CreateUserNoteRequest{Content: "refunded 100 pts"})
+	if err != nil {
+		t.Fatalf("CreateNote failed: %v", err)
+	}
+
+	content := "edited by someone else"
+	if _, err := notes.UpdateNote(other, userID, note.ID, UpdateUserNoteRequest{Content: &content}); err != ErrUserNoteNotAuthor {
+		t.Errorf("Expected ErrUserNoteNotAuthor, got %v", err)
+	}
+	if err := notes.DeleteNote(other, userID, note.ID); err != ErrUserNoteNotAuthor {
+		t.Errorf("Expected ErrUserNoteNotAuthor, got %v", err)
+	}
+
+	pin := true
+	updated, err := notes.UpdateNote(other, userID, note.ID, UpdateUserNoteRequest{Pinned: &pin})
+	if err != nil {
+		t.Fatalf("Pinning failed: %v", err)
+	}
+	if !updated.Pinned || updated.Content != "refunded 100 pts" {
+		t.Errorf("Expected a pinned, unchanged note, got %+v", updated)
+	}
+
+	content = "refunded 100 pts on 5/2, ticket #123"
+	updated, err = notes.UpdateNote(author, userID, note.ID, UpdateUserNoteRequest{Content: &content})
+	if err != nil {
+		t.Fatalf("UpdateNote failed: %v", err)
+	}
+	if updated.Content != content {
+		t.Errorf("Expected the new content, got %q", updated.Content)
+	}
+
+	if _, err := notes.ForTenant(2).GetNotes(userID, 1, 20); err != ErrUserNotFound {
+		t.Errorf("Expected the user to be invisible to tenant 2, got %v", err)
+	}
+	if _, err := notes.ForTenant(2).UpdateNote(author, userID, note.ID, UpdateUserNoteRequest{Pinned: &pin}); err != ErrUserNoteNotFound {
+		t.Errorf("Expected the note to be invisible to tenant 2, got %v", err)
+	}
+	if _, err := notes.CreateNote(author, 9999, CreateUserNoteRequest{Content: "nobody"}); err != ErrUserNotFound {
+		t.Errorf("Expected ErrUserNotFound, got %v", err)
+	}
+
+	if err := notes.DeleteNote(author, userID, note.ID); err != nil {
+		t.Fatalf("DeleteNote failed: %v", err)
+	}
+	var count int64
+	db.Model(&model.UserNote{}).Count(&count)
+	if count != 0 {
+		t.Errorf("Expected the note to be deleted, %d left", count)
+	}
+}
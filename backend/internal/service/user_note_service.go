@@ -0,0 +1,242 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// recentUserNotes is the number of notes shown with the admin user detail
+const recentUserNotes = 5
+
+var (
+	ErrUserNoteNotFound  = errors.New("user note not found")
+	ErrUserNoteNotAuthor = errors.New("only the author can edit a note")
+)
+
+// UserNoteService handles the internal notes admins keep on users
+type UserNoteService struct {
+	db *gorm.DB
+}
+
+// NewUserNoteService creates a new user note service
+func NewUserNoteService(db *gorm.DB) *UserNoteService {
+	return &UserNoteService{db: db}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *UserNoteService) ForTenant(tenantID uint) *UserNoteService {
+	return &UserNoteService{db: repository.ScopeTenant(s.db, tenantID)}
+}
+
+// UserNoteResponse represents a note on a user
+type UserNoteResponse struct {
+	ID         uint      `json:"id"`
+	UserID     uint      `json:"user_id"`
+	AuthorID   uint      `json:"author_id"`
+	AuthorName string    `json:"author_name"`
+	Content    string    `json:"content"`
+	Pinned     bool      `json:"pinned"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// UserNoteListResponse represents a paginated list of notes, pinned first
+type UserNoteListResponse struct {
+	Notes      []UserNoteResponse `json:"notes"`
+	Total      int64              `json:"total"`
+	Page       int                `json:"page"`
+	Limit      int                `json:"limit"`
+	TotalPages int                `json:"total_pages"`
+}
+
+// CreateUserNoteRequest represents the request to add a note on a user
+type CreateUserNoteRequest struct {
+	Content string `json:"content" binding:"required,max=2000"`
+	Pinned  bool   `json:"pinned"`
+}
+
+// UpdateUserNoteRequest represents the request to change a note. Only the
+// author may change the content; any admin may pin or unpin.
+type UpdateUserNoteRequest struct {
+	Content *string `json:"content" binding:"omitempty,min=1,max=2000"`
+	Pinned  *bool   `json:"pinned"`
+}
+
+// GetNotes returns a page of the notes on a user, pinned notes first and
+// then newest first
+func (s *UserNoteService) GetNotes(userID uint, page, limit int) (*UserNoteListResponse, error) {
+	if err := s.requireUser(userID); err != nil {
+		return nil, err
+	}
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	var total int64
+	if err := s.db.Model(&model.UserNote{}).Where("user_id = ?", userID).Count(&total).Error; err != nil {
+		return nil, err
+	}
+	notes, err := s.notes(userID, (page-1)*limit, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	totalPages := int(total) / limit
+	if int(total)%limit > 0 {
+		totalPages++
+	}
+	return &UserNoteListResponse{
+		Notes:      notes,
+		Total:      total,
+		Page:       page,
+		Limit:      limit,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// GetRecentNotes returns the notes shown with the admin user detail
+func (s *UserNoteService) GetRecentNotes(userID uint) ([]UserNoteResponse, error) {
+	return s.notes(userID, 0, recentUserNotes)
+}
+
+// CreateNote adds a note by an admin on a user
+func (s *UserNoteService) CreateNote(authorID, userID uint, req CreateUserNoteRequest) (*UserNoteResponse, error) {
+	if err := s.requireUser(userID); err != nil {
+		return nil, err
+	}
+	note := model.UserNote{
+		UserID:   userID,
+		AuthorID: authorID,
+		Content:  req.Content,
+		Pinned:   req.Pinned,
+	}
+	if err := s.db.Create(&note).Error; err != nil {
+		return nil, err
+	}
+	return s.getNote(userID, note.ID)
+}
+
+// UpdateNote changes the content or pinned flag of a note
+func (s *UserNoteService) UpdateNote(adminID, userID, noteID uint, req UpdateUserNoteRequest) (*UserNoteResponse, error) {
+	note, err := s.note(userID, noteID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	if req.Content != nil && *req.Content != note.Content {
+		if note.AuthorID != adminID {
+			return nil, ErrUserNoteNotAuthor
+		}
+		updates["content"] = *req.Content
+	}
+	if req.Pinned != nil {
+		updates["pinned"] = *req.Pinned
+	}
+	if len(updates) > 0 {
+		if err := s.db.Model(note).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+	}
+	return s.getNote(userID, noteID)
+}
+
+// DeleteNote removes a note; only its author may remove it
+func (s *UserNoteService) DeleteNote(adminID, userID, noteID uint) error {
+	note, err := s.note(userID, noteID)
+	if err != nil {
+		return err
+	}
+	if note.AuthorID != adminID {
+		return ErrUserNoteNotAuthor
+	}
+	return s.db.Delete(note).Error
+}
+
+// requireUser checks that the user exists in the tenant
+func (s *UserNoteService) requireUser(userID uint) error {
+	var user model.User
+	if err := s.db.Select("id").First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *UserNoteService) note(userID, noteID uint) (*model.UserNote, error) {
+	var note model.UserNote
+	if err := s.db.Where("id = ? AND user_id = ?", noteID, userID).First(&note).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNoteNotFound
+		}
+		return nil, err
+	}
+	return &note, nil
+}
+
+func (s *UserNoteService) getNote(userID, noteID uint) (*UserNoteResponse, error) {
+	note, err := s.note(userID, noteID)
+	if err != nil {
+		return nil, err
+	}
+	responses, err := s.toResponses([]model.UserNote{*note})
+	if err != nil {
+		return nil, err
+	}
+	return &responses[0], nil
+}
+
+// notes returns a window of the notes on a user, pinned first and then newest first
+func (s *UserNoteService) notes(userID uint, offset, limit int) ([]UserNoteResponse, error) {
+	var notes []model.UserNote
+	if err := s.db.Where("user_id = ?", userID).
+		Order("pinned DESC, created_at DESC, id DESC").
+		Offset(offset).Limit(limit).
+		Find(&notes).Error; err != nil {
+		return nil, err
+	}
+	return s.toResponses(notes)
+}
+
+// toResponses resolves the author names of notes
+func (s *UserNoteService) toResponses(notes []model.UserNote) ([]UserNoteResponse, error) {
+	authorIDs := make([]uint, len(notes))
+	for i, note := range notes {
+		authorIDs[i] = note.AuthorID
+	}
+	var authors []model.User
+	if len(authorIDs) > 0 {
+		if err := s.db.Unscoped().Select("id", "username").Where("id IN ?", authorIDs).Find(&authors).Error; err != nil {
+			return nil, err
+		}
+	}
+	names := make(map[uint]string, len(authors))
+	for _, author := range authors {
+		names[author.ID] = author.Username
+	}
+
+	responses := make([]UserNoteResponse, len(notes))
+	for i, note := range notes {
+		responses[i] = UserNoteResponse{
+			ID:         note.ID,
+			UserID:     note.UserID,
+			AuthorID:   note.AuthorID,
+			AuthorName: names[note.AuthorID],
+			Content:    note.Content,
+			Pinned:     note.Pinned,
+			CreatedAt:  note.CreatedAt,
+			UpdatedAt:  note.UpdatedAt,
+		}
+	}
+	return responses, nil
+}
@@ -4,6 +4,8 @@ import (
 	"time"
 
 	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+	"scratch-lottery/pkg/biztime"
 
 	"gorm.io/gorm"
 )
@@ -12,25 +14,41 @@ import (
 type UserService struct {
 	db            *gorm.DB
 	walletService *WalletService
+	streakService *StreakService
 }
 
-// NewUserService creates a new user service
-func NewUserService(db *gorm.DB, walletService *WalletService) *UserService {
+// NewUserService creates a new user service. streakService may be nil, in
+// which case profiles carry no streak status.
+func NewUserService(db *gorm.DB, walletService *WalletService, streakService *StreakService) *UserService {
 	return &UserService{
 		db:            db,
 		walletService: walletService,
+		streakService: streakService,
 	}
 }
 
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *UserService) ForTenant(tenantID uint) *UserService {
+	scoped := &UserService{
+		db:            repository.ScopeTenant(s.db, tenantID),
+		walletService: s.walletService.ForTenant(tenantID),
+	}
+	if s.streakService != nil {
+		scoped.streakService = s.streakService.ForTenant(tenantID)
+	}
+	return scoped
+}
+
 // UserProfileResponse represents user profile information
 type UserProfileResponse struct {
-	ID        uint      `json:"id"`
-	LinuxdoID string    `json:"linuxdo_id"`
-	Username  string    `json:"username"`
-	Avatar    string    `json:"avatar"`
-	Role      string    `json:"role"`
-	Balance   int       `json:"balance"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        uint          `json:"id"`
+	LinuxdoID string        `json:"linuxdo_id"`
+	Username  string        `json:"username"`
+	Avatar    string        `json:"avatar"`
+	Role      string        `json:"role"`
+	Balance   int           `json:"balance"`
+	Streak    *StreakStatus `json:"streak,omitempty"`
+	CreatedAt time.Time     `json:"created_at"`
 }
 
 // UserStatisticsResponse represents user game statistics
@@ -110,7 +128,7 @@ func (s *UserService) GetUserProfile(userID uint) (*UserProfileResponse, error)
 		balance = 0
 	}
 
-	return &UserProfileResponse{
+	profile := &UserProfileResponse{
 		ID:        user.ID,
 		LinuxdoID: user.LinuxdoID,
 		Username:  user.Username,
@@ -118,7 +136,18 @@ func (s *UserService) GetUserProfile(userID uint) (*UserProfileResponse, error)
 		Role:      user.Role,
 		Balance:   balance,
 		CreatedAt: user.CreatedAt,
-	}, nil
+	}
+
+	// Get scratch streak
+	if s.streakService != nil {
+		streak, err := s.streakService.GetStatus(userID)
+		if err != nil {
+			return nil, err
+		}
+		profile.Streak = streak
+	}
+
+	return profile, nil
 }
 
 // GetUserStatistics retrieves user game statistics
@@ -149,7 +178,7 @@ func (s *UserService) GetUserStatistics(userID uint) (*UserStatisticsResponse, e
 	}
 	s.db.Model(&model.Ticket{}).
 		Select("COUNT(*) as count, COALESCE(SUM(prize_amount), 0) as total, COALESCE(MAX(prize_amount), 0) as max_single").
-		Where("user_id = ? AND status IN ? AND prize_amount > 0", userID, []model.TicketStatus{model.TicketStatusScratched, model.TicketStatusClaimed}).
+		Where("user_id = ? AND status IN ? AND prize_amount > 0", userID, model.RevealedTicketStatuses).
 		Scan(&winStats)
 	stats.TotalWins = int(winStats.Count)
 	stats.TotalWinAmount = winStats.Total
@@ -158,7 +187,7 @@ func (s *UserService) GetUserStatistics(userID uint) (*UserStatisticsResponse, e
 	// Calculate win rate
 	var scratchedCount int64
 	s.db.Model(&model.Ticket{}).
-		Where("user_id = ? AND status IN ?", userID, []model.TicketStatus{model.TicketStatusScratched, model.TicketStatusClaimed}).
+		Where("user_id = ? AND status IN ?", userID, model.RevealedTicketStatuses).
 		Count(&scratchedCount)
 	if scratchedCount > 0 {
 		stats.WinRate = float64(stats.TotalWins) / float64(scratchedCount) * 100
@@ -204,16 +233,16 @@ func (s *UserService) GetUserTickets(userID uint, query TicketRecordQuery) (*Tic
 
 	// Filter by date range
 	if query.StartDate != "" {
-		startTime, err := time.Parse("2006-01-02", query.StartDate)
+		startTime, err := biztime.ParseDate(query.StartDate)
 		if err == nil {
 			dbQuery = dbQuery.Where("tickets.purchased_at >= ?", startTime)
 		}
 	}
 	if query.EndDate != "" {
-		endTime, err := time.Parse("2006-01-02", query.EndDate)
+		endTime, err := biztime.ParseDate(query.EndDate)
 		if err == nil {
 			// Add one day to include the end date
-			endTime = endTime.Add(24 * time.Hour)
+			endTime = endTime.AddDate(0, 0, 1)
 			dbQuery = dbQuery.Where("tickets.purchased_at < ?", endTime)
 		}
 	}
@@ -227,7 +256,7 @@ func (s *UserService) GetUserTickets(userID uint, query TicketRecordQuery) (*Tic
 	// Get paginated results with lottery type info
 	var tickets []model.Ticket
 	offset := (query.Page - 1) * query.Limit
-	if err := s.db.Preload("LotteryType").
+	if err := s.db.Preload("LotteryType", includeDeleted).
 		Where("user_id = ?", userID).
 		Scopes(func(db *gorm.DB) *gorm.DB {
 			if query.LotteryTypeID > 0 {
@@ -237,13 +266,13 @@ func (s *UserService) GetUserTickets(userID uint, query TicketRecordQuery) (*Tic
 				db = db.Where("status = ?", query.Status)
 			}
 			if query.StartDate != "" {
-				if startTime, err := time.Parse("2006-01-02", query.StartDate); err == nil {
+				if startTime, err := biztime.ParseDate(query.StartDate); err == nil {
 					db = db.Where("purchased_at >= ?", startTime)
 				}
 			}
 			if query.EndDate != "" {
-				if endTime, err := time.Parse("2006-01-02", query.EndDate); err == nil {
-					db = db.Where("purchased_at < ?", endTime.Add(24*time.Hour))
+				if endTime, err := biztime.ParseDate(query.EndDate); err == nil {
+					db = db.Where("purchased_at < ?", endTime.AddDate(0, 0, 1))
 				}
 			}
 			return db
@@ -269,7 +298,7 @@ func (s *UserService) GetUserTickets(userID uint, query TicketRecordQuery) (*Tic
 			ScratchedAt:   t.ScratchedAt,
 		}
 		// Only show prize amount if scratched
-		if t.Status == model.TicketStatusScratched || t.Status == model.TicketStatusClaimed {
+		if t.Status.Revealed() {
 			responses[i].PrizeAmount = t.PrizeAmount
 		}
 	}
@@ -302,7 +331,7 @@ func (s *UserService) GetUserWins(userID uint, page, limit int) (*WinRecordListR
 	// Build query for winning tickets only
 	dbQuery := s.db.Model(&model.Ticket{}).
 		Where("user_id = ? AND status IN ? AND prize_amount > 0", userID, 
-			[]model.TicketStatus{model.TicketStatusScratched, model.TicketStatusClaimed})
+			model.RevealedTicketStatuses)
 
 	// Get total count
 	var total int64
@@ -313,9 +342,9 @@ func (s *UserService) GetUserWins(userID uint, page, limit int) (*WinRecordListR
 	// Get paginated results
 	var tickets []model.Ticket
 	offset := (page - 1) * limit
-	if err := s.db.Preload("LotteryType").
+	if err := s.db.Preload("LotteryType", includeDeleted).
 		Where("user_id = ? AND status IN ? AND prize_amount > 0", userID,
-			[]model.TicketStatus{model.TicketStatusScratched, model.TicketStatusClaimed}).
+			model.RevealedTicketStatuses).
 		Order("scratched_at DESC").
 		Offset(offset).
 		Limit(limit).
@@ -354,3 +383,132 @@ func (s *UserService) GetUserWins(userID uint, page, limit int) (*WinRecordListR
 		TotalPages: totalPages,
 	}, nil
 }
+
+// PurchaseSummaryQuery represents query parameters for the purchase summary
+type PurchaseSummaryQuery struct {
+	StartDate string `form:"start_date"` // Format: 2006-01-02, default 29 days before end
+	EndDate   string `form:"end_date"`   // Format: 2006-01-02, default today
+}
+
+// PurchaseSummaryTotals represents aggregated spend and winnings
+type PurchaseSummaryTotals struct {
+	Tickets  int `json:"tickets"`
+	Spent    int `json:"spent"`
+	Winnings int `json:"winnings"` // Only revealed (scratched) tickets count
+	Net      int `json:"net"`      // winnings - spent
+}
+
+// PurchaseDaySummary represents purchases aggregated for a single day
+type PurchaseDaySummary struct {
+	Date string `json:"date"`
+	PurchaseSummaryTotals
+}
+
+// PurchaseGameSummary represents purchases aggregated for a lottery type
+type PurchaseGameSummary struct {
+	LotteryTypeID uint   `json:"lottery_type_id"`
+	LotteryName   string `json:"lottery_name"`
+	PurchaseSummaryTotals
+}
+
+// PurchaseSummaryResponse represents the purchase history summary
+type PurchaseSummaryResponse struct {
+	StartDate     string                `json:"start_date"`
+	EndDate       string                `json:"end_date"`
+	Totals        PurchaseSummaryTotals `json:"totals"`
+	ByDay         []PurchaseDaySummary  `json:"by_day"`
+	ByLotteryType []PurchaseGameSummary `json:"by_lottery_type"`
+}
+
+// maxPurchaseSummaryDays limits the selectable summary range
+const maxPurchaseSummaryDays = 366
+
+// GetPurchaseSummary aggregates the user's spend and winnings by day and by lottery type
+func (s *UserService) GetPurchaseSummary(userID uint, query PurchaseSummaryQuery) (*PurchaseSummaryResponse, error) {
+	endDate := biztime.DayStart(time.Now())
+	if query.EndDate != "" {
+		if t, err := biztime.ParseDate(query.EndDate); err == nil {
+			endDate = t
+		}
+	}
+	startDate := endDate.AddDate(0, 0, -29)
+	if query.StartDate != "" {
+		if t, err := biztime.ParseDate(query.StartDate); err == nil {
+			startDate = t
+		}
+	}
+	if startDate.After(endDate) {
+		startDate, endDate = endDate, startDate
+	}
+	if endDate.Sub(startDate) > maxPurchaseSummaryDays*24*time.Hour {
+		startDate = endDate.AddDate(0, 0, -(maxPurchaseSummaryDays - 1))
+	}
+
+	// Load only the columns needed; grouping by day is done in Go to stay database agnostic
+	type ticketRow struct {
+		LotteryTypeID uint
+		LotteryName   string
+		Price         int
+		Status        model.TicketStatus
+		PrizeAmount   int
+		PurchasedAt   time.Time
+	}
+	var rows []ticketRow
+	if err := s.db.Table("tickets").
+		Select("tickets.lottery_type_id, lottery_types.name as lottery_name, lottery_types.price, tickets.status, tickets.prize_amount, tickets.purchased_at").
+		Joins("LEFT JOIN lottery_types ON lottery_types.id = tickets.lottery_type_id").
+		Where("tickets.user_id = ? AND tickets.deleted_at IS NULL", userID).
+		Where("tickets.purchased_at >= ? AND tickets.purchased_at < ?", startDate, endDate.AddDate(0, 0, 1)).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	resp := &PurchaseSummaryResponse{
+		StartDate:     biztime.Date(startDate),
+		EndDate:       biztime.Date(endDate),
+		ByDay:         []PurchaseDaySummary{},
+		ByLotteryType: []PurchaseGameSummary{},
+	}
+
+	dayIndex := make(map[string]int)
+	for d := startDate; !d.After(endDate); d = d.AddDate(0, 0, 1) {
+		key := biztime.Date(d)
+		dayIndex[key] = len(resp.ByDay)
+		resp.ByDay = append(resp.ByDay, PurchaseDaySummary{Date: key})
+	}
+
+	gameIndex := make(map[uint]int)
+	for _, r := range rows {
+		winnings := 0
+		if r.Status.Revealed() {
+			winnings = r.PrizeAmount
+		}
+
+		addToTotals(&resp.Totals, r.Price, winnings)
+
+		if i, ok := dayIndex[biztime.Date(r.PurchasedAt)]; ok {
+			addToTotals(&resp.ByDay[i].PurchaseSummaryTotals, r.Price, winnings)
+		}
+
+		i, ok := gameIndex[r.LotteryTypeID]
+		if !ok {
+			i = len(resp.ByLotteryType)
+			gameIndex[r.LotteryTypeID] = i
+			resp.ByLotteryType = append(resp.ByLotteryType, PurchaseGameSummary{
+				LotteryTypeID: r.LotteryTypeID,
+				LotteryName:   r.LotteryName,
+			})
+		}
+		addToTotals(&resp.ByLotteryType[i].PurchaseSummaryTotals, r.Price, winnings)
+	}
+
+	return resp, nil
+}
+
+func addToTotals(t *PurchaseSummaryTotals, spent, winnings int) {
+	t.Tickets++
+	t.Spent += spent
+	t.Winnings += winnings
+	t.Net = t.Winnings - t.Spent
+}
+
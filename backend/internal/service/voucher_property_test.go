@@ -0,0 +1,196 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+	"gorm.io/gorm"
+)
+
+// setupVoucherTest creates a tenant test database with a lottery type and
+// claiming users
+func setupVoucherTest(t *testing.T, users int) (*gorm.DB, uint, []uint) {
+	db := setupTenantTestDB(t)
+	if err := db.AutoMigrate(&model.VoucherClaimFailure{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	lotteryTypeID := createOddsHintPool(t, db, 10000, 0, nil)
+	userIDs := make([]uint, users)
+	for i := range userIDs {
+		user := model.User{LinuxdoID: fmt.Sprintf("voucher_user_%d", i), Username: fmt.Sprintf("User %d", i), Role: "user"}
+		if err := db.Create(&user).Error; err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+		userIDs[i] = user.ID
+	}
+	return db, lotteryTypeID, userIDs
+}
+
+// Vouchers: generated vouchers are unassigned, each code is claimed into
+// exactly one account, and claimed vouchers leave the export.
+func TestVoucherClaimOnce(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	db, lotteryTypeID, userIDs := setupVoucherTest(t, 3)
+	vouchers := NewVoucherService(db, NewLotteryService(db, testEncryptionKey), 1000, time.Hour)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("each voucher is claimed once", prop.ForAll(
+		func(quantity int, claimers []int) bool {
+			defer db.Where("1 = 1").Delete(&model.Ticket{})
+
+			batch, err := vouchers.GenerateVouchers(1, lotteryTypeID, GenerateVouchersRequest{Quantity: quantity})
+			if err != nil || batch.Generated != quantity || batch.Unclaimed != int64(quantity) {
+				t.Logf("GenerateVouchers failed: %+v (err %v)", batch, err)
+				return false
+			}
+			var codes []string
+			db.Model(&model.Ticket{}).Where("user_id = 0").Order("id ASC").Pluck("security_code", &codes)
+			if len(codes) != quantity {
+				t.Logf("Expected %d unassigned tickets, got %d", quantity, len(codes))
+				return false
+			}
+
+			// Claimers take the codes in turn, then try them all again
+			owners := make(map[string]uint, len(codes))
+			for i, code := range append(codes, codes...) {
+				userID := userIDs[claimers[i%len(claimers)]]
+				ticket, err := vouchers.ClaimVoucher(userID, ClaimVoucherRequest{SecurityCode: " " + strings.ToLower(code)}, ClientInfo{})
+				if _, claimed := owners[code]; claimed {
+					if err != ErrVoucherNotFound {
+						t.Logf("Expected the claimed code %s to be refused, got %v", code, err)
+						return false
+					}
+					continue
+				}
+				if err != nil || ticket.UserID != userID || ticket.Status != model.TicketStatusUnscratched {
+					t.Logf("Claim of %s failed: %+v (err %v)", code, ticket, err)
+					return false
+				}
+				owners[code] = userID
+			}
+
+			for code, userID := range owners {
+				var ticket model.Ticket
+				db.Where("security_code = ?", code).First(&ticket)
+				var events []model.TicketHistoryEvent
+				db.Model(&model.TicketHistory{}).Where("ticket_id = ?", ticket.ID).Order("id ASC").Pluck("event", &events)
+				if ticket.UserID != userID || len(events) != 2 ||
+					events[0] != model.TicketHistoryVoucher || events[1] != model.TicketHistoryClaim {
+					t.Logf("Unexpected ticket %+v with history %v", ticket, events)
+					return false
+				}
+			}
+
+			csvData, err := vouchers.ExportVouchersCSV(1, lotteryTypeID)
+			if err != nil {
+				t.Logf("ExportVouchersCSV failed: %v", err)
+				return false
+			}
+			return strings.Count(string(csvData), "\n") == 1
+		},
+		gen.IntRange(1, 10),
+		gen.SliceOfN(4, gen.IntRange(0, 2)),
+	))
+
+	properties.TestingRun(t)
+}
+
+// Vouchers: the export lists the unclaimed codes of the lottery type only,
+// and batches beyond the stock are refused whole.
+func TestVoucherGenerateAndExport(t *testing.T) {
+	db, lotteryTypeID, userIDs := setupVoucherTest(t, 1)
+	lotteries := NewLotteryService(db, testEncryptionKey)
+	vouchers := NewVoucherService(db, lotteries, 5, time.Hour)
+
+	if _, err := vouchers.GenerateVouchers(1, lotteryTypeID, GenerateVouchersRequest{Quantity: 3}); err != nil {
+		t.Fatalf("GenerateVouchers failed: %v", err)
+	}
+	if _, err := lotteries.GenerateTicket(userIDs[0], lotteryTypeID); err != nil {
+		t.Fatalf("GenerateTicket failed: %v", err)
+	}
+
+	var codes []string
+	db.Model(&model.Ticket{}).Where("user_id = 0").Pluck("security_code", &codes)
+	if _, err := vouchers.ClaimVoucher(userIDs[0], ClaimVoucherRequest{SecurityCode: codes[0]}, ClientInfo{}); err != nil {
+		t.Fatalf("ClaimVoucher failed: %v", err)
+	}
+
+	csvData, err := vouchers.ExportVouchersCSV(1, lotteryTypeID)
+	if err != nil {
+		t.Fatalf("ExportVouchersCSV failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(csvData)), "\n")
+	if len(lines) != 3 || strings.Contains(string(csvData), codes[0]) ||
+		!strings.Contains(string(csvData), codes[1]) || !strings.Contains(string(csvData), codes[2]) {
+		t.Errorf("Expected the 2 unclaimed codes, got:\n%s", csvData)
+	}
+
+	var pool model.PrizePool
+	db.Where("lottery_type_id = ?", lotteryTypeID).First(&pool)
+	db.Model(&pool).Update("total_tickets", pool.SoldTickets+2)
+	if _, err := vouchers.GenerateVouchers(1, lotteryTypeID, GenerateVouchersRequest{Quantity: 3}); err != ErrLotteryTypeSoldOut {
+		t.Errorf("Expected ErrLotteryTypeSoldOut, got %v", err)
+	}
+	var tickets int64
+	db.Model(&model.Ticket{}).Count(&tickets)
+	if tickets != 4 {
+		t.Errorf("Expected the refused batch to generate nothing, got %d tickets", tickets)
+	}
+
+	var logs int64
+	db.Model(&model.AdminLog{}).Where("action IN ?", []string{"generate_vouchers", "export_vouchers"}).Count(&logs)
+	if logs != 2 {
+		t.Errorf("Expected 2 admin logs, got %d", logs)
+	}
+}
+
+// Vouchers: repeated failed claims lock the user out, even for valid codes,
+// without affecting other users; codes of another tenant cannot be claimed.
+func TestVoucherClaimLockout(t *testing.T) {
+	db, lotteryTypeID, userIDs := setupVoucherTest(t, 2)
+	vouchers := NewVoucherService(db, NewLotteryService(db, testEncryptionKey), 3, time.Hour)
+
+	if _, err := vouchers.GenerateVouchers(1, lotteryTypeID, GenerateVouchersRequest{Quantity: 2}); err != nil {
+		t.Fatalf("GenerateVouchers failed: %v", err)
+	}
+	var codes []string
+	db.Model(&model.Ticket{}).Where("user_id = 0").Pluck("security_code", &codes)
+
+	if _, err := vouchers.ForTenant(2).ClaimVoucher(userIDs[1], ClaimVoucherRequest{SecurityCode: codes[1]}, ClientInfo{}); err != ErrVoucherNotFound {
+		t.Errorf("Expected codes of another tenant to be refused, got %v", err)
+	}
+
+	for _, code := range []string{"short", "AAAAAAAAAAAAAAAA", "BBBBBBBBBBBBBBBB"} {
+		if _, err := vouchers.ClaimVoucher(userIDs[0], ClaimVoucherRequest{SecurityCode: code}, ClientInfo{IP: "10.0.0.1"}); err != ErrVoucherNotFound {
+			t.Fatalf("Expected ErrVoucherNotFound for %s, got %v", code, err)
+		}
+	}
+	if _, err := vouchers.ClaimVoucher(userIDs[0], ClaimVoucherRequest{SecurityCode: codes[0]}, ClientInfo{}); err != ErrVoucherClaimsLocked {
+		t.Errorf("Expected ErrVoucherClaimsLocked, got %v", err)
+	}
+	lockedUntil, err := vouchers.LockedUntil(userIDs[0])
+	if err != nil || lockedUntil == nil || time.Until(*lockedUntil) > time.Hour || time.Until(*lockedUntil) < 59*time.Minute {
+		t.Errorf("Expected a lockout of about an hour, got %v (err %v)", lockedUntil, err)
+	}
+
+	if _, err := vouchers.ClaimVoucher(userIDs[1], ClaimVoucherRequest{SecurityCode: codes[0]}, ClientInfo{}); err != nil {
+		t.Errorf("Expected other users to claim, got %v", err)
+	}
+
+	// Failures age out of the window
+	db.Model(&model.VoucherClaimFailure{}).Where("1 = 1").Update("created_at", time.Now().Add(-2*time.Hour))
+	if _, err := vouchers.ClaimVoucher(userIDs[0], ClaimVoucherRequest{SecurityCode: codes[1]}, ClientInfo{}); err != nil {
+		t.Errorf("Expected the lockout to end, got %v", err)
+	}
+}
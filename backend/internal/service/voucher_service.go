@@ -0,0 +1,265 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// MaxVoucherBatch is the most vouchers generated by one request
+const MaxVoucherBatch = 1000
+
+var (
+	ErrInvalidVoucherQuantity = errors.New("invalid voucher quantity")
+	ErrVoucherNotFound        = errors.New("voucher not found or already claimed")
+	ErrVoucherClaimsLocked    = errors.New("too many failed voucher claims")
+)
+
+// VoucherService handles tickets sold offline as vouchers. Admins generate
+// unassigned tickets and export their security codes for printing; a user
+// claims a ticket into their account by entering its code, after which it is
+// scratched like a bought ticket. Failed claims are counted per user and too
+// many within the window stop the user from claiming, so codes cannot be
+// guessed.
+type VoucherService struct {
+	db             *gorm.DB
+	lotteryService *LotteryService
+	maxFailures    int64
+	window         time.Duration
+}
+
+// NewVoucherService creates a new voucher service. maxFailures failed claims
+// of a user within window stop the user from claiming until they age out.
+func NewVoucherService(db *gorm.DB, lotteryService *LotteryService, maxFailures int, window time.Duration) *VoucherService {
+	if maxFailures < 1 {
+		maxFailures = 5
+	}
+	if window <= 0 {
+		window = time.Hour
+	}
+	return &VoucherService{
+		db:             db,
+		lotteryService: lotteryService,
+		maxFailures:    int64(maxFailures),
+		window:         window,
+	}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *VoucherService) ForTenant(tenantID uint) *VoucherService {
+	return &VoucherService{
+		db:             repository.ScopeTenant(s.db, tenantID),
+		lotteryService: s.lotteryService.ForTenant(tenantID),
+		maxFailures:    s.maxFailures,
+		window:         s.window,
+	}
+}
+
+// GenerateVouchersRequest represents an admin generating voucher tickets
+type GenerateVouchersRequest struct {
+	Quantity int `json:"quantity" binding:"required,min=1,max=1000"`
+}
+
+// VoucherBatchResponse summarizes the vouchers of a lottery type
+type VoucherBatchResponse struct {
+	LotteryTypeID uint  `json:"lottery_type_id"`
+	Generated     int   `json:"generated"` // Vouchers generated by this request
+	Unclaimed     int64 `json:"unclaimed"` // Vouchers of the lottery type not claimed yet
+}
+
+// ClaimVoucherRequest represents a user claiming a voucher ticket
+type ClaimVoucherRequest struct {
+	SecurityCode string `json:"security_code" binding:"required"`
+}
+
+// GenerateVouchers generates unassigned tickets from the active prize pool of
+// a lottery type. The batch is generated in one transaction, so it is either
+// generated whole or not at all.
+func (s *VoucherService) GenerateVouchers(adminID, lotteryTypeID uint, req GenerateVouchersRequest) (*VoucherBatchResponse, error) {
+	if req.Quantity < 1 || req.Quantity > MaxVoucherBatch {
+		return nil, ErrInvalidVoucherQuantity
+	}
+	if _, err := s.lotteryService.GetLotteryTypeByID(lotteryTypeID); err != nil {
+		return nil, err
+	}
+	if availableStock(s.db, lotteryTypeID, 0) < req.Quantity {
+		return nil, ErrLotteryTypeSoldOut
+	}
+
+	err := repository.Transaction(s.db, func(tx *gorm.DB) error {
+		lotteries := s.lotteryService.withDB(tx)
+		for i := 0; i < req.Quantity; i++ {
+			if _, err := lotteries.GenerateVoucherTicket(lotteryTypeID); err != nil {
+				return err
+			}
+		}
+
+		details, _ := json.Marshal(map[string]interface{}{"quantity": req.Quantity})
+		adminLog := model.AdminLog{
+			AdminID:    adminID,
+			Action:     "generate_vouchers",
+			TargetType: "lottery_type",
+			TargetID:   lotteryTypeID,
+			Details:    string(details),
+		}
+		return tx.Create(&adminLog).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var unclaimed int64
+	if err := s.unclaimed(lotteryTypeID).Count(&unclaimed).Error; err != nil {
+		return nil, err
+	}
+	return &VoucherBatchResponse{LotteryTypeID: lotteryTypeID, Generated: req.Quantity, Unclaimed: unclaimed}, nil
+}
+
+// ExportVouchersCSV exports the security codes of the unclaimed vouchers of a
+// lottery type for printing. Every export is recorded in the admin log.
+func (s *VoucherService) ExportVouchersCSV(adminID, lotteryTypeID uint) ([]byte, error) {
+	lotteryType, err := s.lotteryService.GetLotteryTypeByID(lotteryTypeID)
+	if err != nil {
+		return nil, err
+	}
+
+	var tickets []model.Ticket
+	if err := s.unclaimed(lotteryTypeID).Order("id ASC").Find(&tickets).Error; err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	_ = w.Write([]string{"票据ID", "保安码", "彩票类型", "生成时间"})
+	for _, ticket := range tickets {
+		_ = w.Write([]string{
+			strconv.FormatUint(uint64(ticket.ID), 10),
+			ticket.SecurityCode,
+			lotteryType.Name,
+			formatReportTime(&ticket.PurchasedAt),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+
+	details, _ := json.Marshal(map[string]interface{}{"count": len(tickets)})
+	adminLog := model.AdminLog{
+		AdminID:    adminID,
+		Action:     "export_vouchers",
+		TargetType: "lottery_type",
+		TargetID:   lotteryTypeID,
+		Details:    string(details),
+	}
+	if err := s.db.Create(&adminLog).Error; err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ClaimVoucher moves the unclaimed voucher with the given security code into
+// the user's account. Unknown, malformed and already claimed codes all fail
+// the same way and count towards the user's failure limit.
+func (s *VoucherService) ClaimVoucher(userID uint, req ClaimVoucherRequest, client ClientInfo) (*TicketResponse, error) {
+	lockedUntil, err := s.LockedUntil(userID)
+	if err != nil {
+		return nil, err
+	}
+	if lockedUntil != nil {
+		return nil, ErrVoucherClaimsLocked
+	}
+
+	code := strings.ToUpper(strings.TrimSpace(req.SecurityCode))
+	if len(code) != SecurityCodeLength {
+		return nil, s.recordFailure(userID, client)
+	}
+
+	var ticket model.Ticket
+	err = repository.Transaction(s.db, func(tx *gorm.DB) error {
+		ticket = model.Ticket{}
+		if err := tx.Where("security_code = ? AND user_id = 0 AND status = ?", code, model.TicketStatusUnscratched).
+			First(&ticket).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrVoucherNotFound
+			}
+			return err
+		}
+
+		// Guarded against the voucher being claimed by someone else meanwhile
+		result := tx.Model(&model.Ticket{}).
+			Where("id = ? AND user_id = 0 AND status = ?", ticket.ID, model.TicketStatusUnscratched).
+			Update("user_id", userID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVoucherNotFound
+		}
+		if err := adjustBadge(tx, userID, badgeUnscratchedTickets, 1); err != nil {
+			return err
+		}
+
+		history := model.TicketHistory{
+			TicketID: ticket.ID,
+			Event:    model.TicketHistoryClaim,
+			ToUserID: userID,
+		}
+		return tx.Create(&history).Error
+	})
+	if err == ErrVoucherNotFound {
+		return nil, s.recordFailure(userID, client)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	claimed, err := s.lotteryService.GetTicketByID(ticket.ID)
+	if err != nil {
+		return nil, err
+	}
+	resp := s.lotteryService.toTicketResponse(claimed, false)
+	return &resp, nil
+}
+
+// LockedUntil returns when a user may claim vouchers again, or nil if the
+// user is not locked out
+func (s *VoucherService) LockedUntil(userID uint) (*time.Time, error) {
+	since := time.Now().Add(-s.window)
+	var failures []model.VoucherClaimFailure
+	if err := s.db.Where("user_id = ? AND created_at > ?", userID, since).
+		Order("created_at DESC").Limit(int(s.maxFailures)).
+		Find(&failures).Error; err != nil {
+		return nil, err
+	}
+	if int64(len(failures)) < s.maxFailures {
+		return nil, nil
+	}
+	// Locked until the oldest failure counted against the limit ages out
+	until := failures[len(failures)-1].CreatedAt.Add(s.window)
+	return &until, nil
+}
+
+// recordFailure records a failed claim and returns the error to report
+func (s *VoucherService) recordFailure(userID uint, client ClientInfo) error {
+	failure := model.VoucherClaimFailure{UserID: userID, IP: client.IP}
+	if err := s.db.Create(&failure).Error; err != nil {
+		return err
+	}
+	return ErrVoucherNotFound
+}
+
+// unclaimed returns a query for the unclaimed vouchers of a lottery type
+func (s *VoucherService) unclaimed(lotteryTypeID uint) *gorm.DB {
+	return s.db.Model(&model.Ticket{}).
+		Where("lottery_type_id = ? AND user_id = 0 AND status = ?", lotteryTypeID, model.TicketStatusUnscratched)
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+)
+
+// Waiting room: once concurrent purchases exceed the threshold the lottery
+// type is protected, tokenless purchases are rejected and queued users are
+// admitted in join order as purchase slots free up.
+func TestWaitingRoomAdmitsInOrder(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("overflow protects the type and admits the queue in order", prop.ForAll(
+		func(threshold, waiting int) bool {
+			db := setupLotteryTestDB(t)
+			lotteryType := model.LotteryType{Name: "Launch", Price: 10, WaitingRoomThreshold: threshold}
+			if err := db.Create(&lotteryType).Error; err != nil {
+				t.Logf("Failed to create lottery type: %v", err)
+				return false
+			}
+			room := NewWaitingRoomService(db, time.Hour, time.Minute)
+
+			// Fill every purchase slot, then overflow
+			var leaves []func()
+			for i := 0; i < threshold; i++ {
+				leave, err := room.Enter(uint(1000+i), lotteryType.ID, "")
+				if err != nil {
+					t.Logf("Enter within threshold failed: %v", err)
+					return false
+				}
+				leaves = append(leaves, leave)
+			}
+			if _, err := room.Enter(999, lotteryType.ID, ""); err != ErrWaitingRoomRequired {
+				t.Logf("Expected ErrWaitingRoomRequired on overflow, got %v", err)
+				return false
+			}
+
+			statuses := make([]*QueueStatus, waiting)
+			for i := 0; i < waiting; i++ {
+				status, err := room.Join(uint(i+1), lotteryType.ID)
+				if err != nil || status.Admitted || status.Position != i+1 {
+					t.Logf("Join %d: %+v (err %v)", i+1, status, err)
+					return false
+				}
+				statuses[i] = status
+			}
+
+			// Queued but not admitted tokens are rejected, as are tokenless purchases
+			if _, err := room.Enter(1, lotteryType.ID, statuses[0].Token); err != ErrWaitingRoomNotAdmitted {
+				t.Logf("Expected ErrWaitingRoomNotAdmitted, got %v", err)
+				return false
+			}
+
+			// Each finished purchase admits the next user in line
+			for _, leave := range leaves {
+				leave()
+			}
+			for i := 0; i < waiting; i++ {
+				status, err := room.Status(uint(i+1), lotteryType.ID)
+				if err != nil {
+					return false
+				}
+				if status.Admitted != (i < threshold) {
+					t.Logf("User %d admitted=%v with threshold %d", i+1, status.Admitted, threshold)
+					return false
+				}
+			}
+
+			leave, err := room.Enter(1, lotteryType.ID, statuses[0].Token)
+			if err != nil {
+				t.Logf("Admitted user should enter: %v", err)
+				return false
+			}
+			leave()
+
+			// The admission token is single use
+			if _, err := room.Enter(1, lotteryType.ID, statuses[0].Token); err != ErrWaitingRoomRequired {
+				t.Logf("Expected reused token to be rejected, got %v", err)
+				return false
+			}
+			_, err = room.Enter(999, lotteryType.ID, "")
+			return err == ErrWaitingRoomRequired
+		},
+		gen.IntRange(1, 5),
+		gen.IntRange(1, 12),
+	))
+
+	properties.TestingRun(t)
+}
+
+// Lottery types without a threshold never engage the waiting room
+func TestWaitingRoomDisabledWithoutThreshold(t *testing.T) {
+	db := setupLotteryTestDB(t)
+	lotteryType := model.LotteryType{Name: "Regular", Price: 10}
+	if err := db.Create(&lotteryType).Error; err != nil {
+		t.Fatalf("Failed to create lottery type: %v", err)
+	}
+	room := NewWaitingRoomService(db, time.Hour, time.Minute)
+
+	for i := 0; i < 50; i++ {
+		if _, err := room.Enter(uint(i+1), lotteryType.ID, ""); err != nil {
+			t.Fatalf("Enter %d failed: %v", i+1, err)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrWaitingRoomRequired    = errors.New("waiting room admission required")
+	ErrWaitingRoomNotAdmitted = errors.New("waiting room token not admitted yet")
+	ErrWaitingRoomNotQueued   = errors.New("not queued in waiting room")
+)
+
+// defaultPurchaseDuration seeds the ETA estimate before any purchase has been timed
+const defaultPurchaseDuration = 5 * time.Second
+
+// WaitingRoomService is a virtual waiting room for high-demand lottery types.
+// When concurrent purchase attempts for a lottery type exceed its
+// WaitingRoomThreshold, the type becomes protected for a window: users join a
+// queue, are admitted in order as purchase slots free up, and purchases
+// without a valid admission token are rejected until the window ends.
+type WaitingRoomService struct {
+	db           *gorm.DB
+	window       time.Duration
+	admissionTTL time.Duration
+	rooms        map[uint]*waitingRoom
+	mutex        sync.Mutex
+}
+
+type waitingRoom struct {
+	threshold      int
+	inFlight       int
+	protectedUntil time.Time
+	queue          []*queueEntry        // waiting and admitted entries in join order
+	entries        map[uint]*queueEntry // user ID -> entry
+	avgDuration    time.Duration        // moving average of purchase durations
+}
+
+type queueEntry struct {
+	token      string
+	userID     uint
+	admitted   bool
+	expiresAt  time.Time // admission deadline, once admitted
+	lastSeenAt time.Time
+}
+
+// NewWaitingRoomService creates a new waiting room service. window is how long
+// a lottery type stays protected after overflowing, and admissionTTL is how
+// long an admitted user has to complete the purchase.
+func NewWaitingRoomService(db *gorm.DB, window, admissionTTL time.Duration) *WaitingRoomService {
+	if window <= 0 {
+		window = 10 * time.Minute
+	}
+	if admissionTTL <= 0 {
+		admissionTTL = 2 * time.Minute
+	}
+	return &WaitingRoomService{
+		db:           db,
+		window:       window,
+		admissionTTL: admissionTTL,
+		rooms:        make(map[uint]*waitingRoom),
+	}
+}
+
+// QueueStatus represents a user's place in a waiting room
+type QueueStatus struct {
+	LotteryTypeID      uint       `json:"lottery_type_id"`
+	Token              string     `json:"token"`
+	Position           int        `json:"position"`    // 1-based position among waiting users, 0 once admitted
+	ETASeconds         int        `json:"eta_seconds"` // Estimated wait until admission
+	Admitted           bool       `json:"admitted"`
+	AdmissionExpiresAt *time.Time `json:"admission_expires_at,omitempty"`
+	ProtectedUntil     *time.Time `json:"protected_until,omitempty"`
+}
+
+// Join places the user in the waiting room of a lottery type, or returns the
+// existing place if the user already joined
+func (s *WaitingRoomService) Join(userID uint, lotteryTypeID uint) (*QueueStatus, error) {
+	threshold, err := s.getThreshold(lotteryTypeID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	room := s.getRoom(lotteryTypeID, threshold)
+	entry, exists := room.entries[userID]
+	if !exists {
+		entry = &queueEntry{token: uuid.NewString(), userID: userID}
+		room.queue = append(room.queue, entry)
+		room.entries[userID] = entry
+	}
+	entry.lastSeenAt = now
+	s.admit(room, now)
+
+	return s.toQueueStatus(lotteryTypeID, room, entry), nil
+}
+
+// Status returns the user's current place in the waiting room of a lottery type
+func (s *WaitingRoomService) Status(userID uint, lotteryTypeID uint) (*QueueStatus, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	room, exists := s.rooms[lotteryTypeID]
+	if !exists {
+		return nil, ErrWaitingRoomNotQueued
+	}
+	now := time.Now()
+	s.admit(room, now)
+	entry, exists := room.entries[userID]
+	if !exists {
+		s.dropIfIdle(lotteryTypeID, room, now)
+		return nil, ErrWaitingRoomNotQueued
+	}
+	entry.lastSeenAt = now
+
+	return s.toQueueStatus(lotteryTypeID, room, entry), nil
+}
+
+// Enter claims a purchase slot for the user. Outside the protected window a
+// slot is granted while fewer than the threshold purchases are in flight;
+// inside it, a valid admission token is required and is consumed. The
+// returned leave func must be called once the purchase attempt finishes.
+func (s *WaitingRoomService) Enter(userID uint, lotteryTypeID uint, token string) (leave func(), err error) {
+	threshold, err := s.getThreshold(lotteryTypeID)
+	if err != nil {
+		return nil, err
+	}
+	if threshold <= 0 {
+		return func() {}, nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	room := s.getRoom(lotteryTypeID, threshold)
+	s.admit(room, now)
+
+	if now.Before(room.protectedUntil) {
+		entry, exists := room.entries[userID]
+		if !exists || token == "" || entry.token != token {
+			return nil, ErrWaitingRoomRequired
+		}
+		if !entry.admitted {
+			return nil, ErrWaitingRoomNotAdmitted
+		}
+		s.removeEntry(room, entry)
+	} else if room.inFlight >= threshold {
+		// Overflow: protect the lottery type and send users through the queue
+		room.protectedUntil = now.Add(s.window)
+		return nil, ErrWaitingRoomRequired
+	} else if entry, exists := room.entries[userID]; exists {
+		s.removeEntry(room, entry)
+	}
+
+	room.inFlight++
+	started := now
+	var once sync.Once
+	return func() {
+		once.Do(func() { s.leave(lotteryTypeID, time.Since(started)) })
+	}, nil
+}
+
+// leave frees a purchase slot and admits the next waiting user
+func (s *WaitingRoomService) leave(lotteryTypeID uint, took time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	room, exists := s.rooms[lotteryTypeID]
+	if !exists {
+		return
+	}
+	now := time.Now()
+	room.inFlight--
+	room.avgDuration = (room.avgDuration*4 + took) / 5
+	s.admit(room, now)
+	s.dropIfIdle(lotteryTypeID, room, now)
+}
+
+// admit drops expired admissions and abandoned entries, then admits waiting
+// users in join order while purchase slots are free
+func (s *WaitingRoomService) admit(room *waitingRoom, now time.Time) {
+	admitted := 0
+	kept := room.queue[:0]
+	for _, entry := range room.queue {
+		if entry.admitted && now.After(entry.expiresAt) {
+			delete(room.entries, entry.userID)
+			continue
+		}
+		if !entry.admitted && now.Sub(entry.lastSeenAt) > 2*s.admissionTTL {
+			delete(room.entries, entry.userID)
+			continue
+		}
+		if entry.admitted {
+			admitted++
+		}
+		kept = append(kept, entry)
+	}
+	room.queue = kept
+
+	for _, entry := range room.queue {
+		if room.inFlight+admitted >= room.threshold {
+			break
+		}
+		if !entry.admitted {
+			entry.admitted = true
+			entry.expiresAt = now.Add(s.admissionTTL)
+			admitted++
+		}
+	}
+}
+
+// dropIfIdle discards a room with nobody queued or purchasing once its protected window is over
+func (s *WaitingRoomService) dropIfIdle(lotteryTypeID uint, room *waitingRoom, now time.Time) {
+	if len(room.queue) == 0 && room.inFlight == 0 && !now.Before(room.protectedUntil) {
+		delete(s.rooms, lotteryTypeID)
+	}
+}
+
+func (s *WaitingRoomService) removeEntry(room *waitingRoom, entry *queueEntry) {
+	delete(room.entries, entry.userID)
+	for i, e := range room.queue {
+		if e == entry {
+			room.queue = append(room.queue[:i], room.queue[i+1:]...)
+			break
+		}
+	}
+}
+
+func (s *WaitingRoomService) getRoom(lotteryTypeID uint, threshold int) *waitingRoom {
+	room, exists := s.rooms[lotteryTypeID]
+	if !exists {
+		room = &waitingRoom{
+			entries:     make(map[uint]*queueEntry),
+			avgDuration: defaultPurchaseDuration,
+		}
+		s.rooms[lotteryTypeID] = room
+	}
+	if threshold < 1 {
+		threshold = 1
+	}
+	room.threshold = threshold
+	return room
+}
+
+// getThreshold loads the waiting room threshold of a lottery type
+func (s *WaitingRoomService) getThreshold(lotteryTypeID uint) (int, error) {
+	var lotteryType model.LotteryType
+	if err := s.db.Select("id", "waiting_room_threshold").First(&lotteryType, lotteryTypeID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, ErrLotteryTypeNotFound
+		}
+		return 0, err
+	}
+	return lotteryType.WaitingRoomThreshold, nil
+}
+
+func (s *WaitingRoomService) toQueueStatus(lotteryTypeID uint, room *waitingRoom, entry *queueEntry) *QueueStatus {
+	status := &QueueStatus{
+		LotteryTypeID: lotteryTypeID,
+		Token:         entry.token,
+		Admitted:      entry.admitted,
+	}
+	if entry.admitted {
+		expiresAt := entry.expiresAt
+		status.AdmissionExpiresAt = &expiresAt
+	} else {
+		for _, e := range room.queue {
+			if !e.admitted {
+				status.Position++
+			}
+			if e == entry {
+				break
+			}
+		}
+		// Each round of threshold slots takes about one purchase duration
+		rounds := (status.Position + room.threshold - 1) / room.threshold
+		status.ETASeconds = int((time.Duration(rounds) * room.avgDuration).Seconds())
+	}
+	if time.Now().Before(room.protectedUntil) {
+		protectedUntil := room.protectedUntil
+		status.ProtectedUntil = &protectedUntil
+	}
+	return status
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
+)
+
+// Wallet balances: version 1 keeps reporting the wallet balance alone, while
+// version 2 breaks it down into available points, prizes pending their claim
+// and points held by exchange reservations or a frozen wallet.
+func TestWalletBalanceBreakdown(t *testing.T) {
+	db, scratchService, largeWins, userID, ticketIDs := setupLargeWinTest(t, []int{800})
+	threshold, required := 500, true
+	if _, err := largeWins.adminService.UpdateSystemSettings(1, UpdateSystemSettingsRequest{
+		LargeWinThreshold:        &threshold,
+		LargeWinIdentityRequired: &required,
+	}); err != nil {
+		t.Fatalf("UpdateSystemSettings failed: %v", err)
+	}
+	if _, err := scratchService.ScratchTicket(userID, ticketIDs[0], ""); err != nil {
+		t.Fatalf("ScratchTicket failed: %v", err)
+	}
+	db.Create(&model.ExchangeRecord{UserID: userID, ProductID: 1, CardKeyID: 1, Cost: 30, Status: model.ExchangeRecordStatusReserved})
+	db.Create(&model.ExchangeRecord{UserID: userID, ProductID: 1, CardKeyID: 2, Cost: 40, Status: model.ExchangeRecordStatusReleased})
+
+	wallets := NewWalletService(db)
+	var wallet model.Wallet
+	db.Where("user_id = ?", userID).First(&wallet)
+
+	v1, err := wallets.GetWalletByUserID(userID)
+	if err != nil || v1.Balance != wallet.Balance || v1.Balances != nil {
+		t.Fatalf("Expected the plain balance in version 1, got %+v (err %v)", v1, err)
+	}
+	v2, err := wallets.GetWalletVersion(userID, WalletAPIVersion2)
+	if err != nil || v2.Balances == nil {
+		t.Fatalf("GetWalletVersion failed: %+v (err %v)", v2, err)
+	}
+	if *v2.Balances != (WalletBalances{Available: wallet.Balance, Pending: 800, Held: 30}) || v2.Balance != wallet.Balance+30 {
+		t.Errorf("Unexpected breakdown %+v with balance %d", *v2.Balances, v2.Balance)
+	}
+
+	// A frozen wallet holds its whole balance
+	db.Model(&wallet).Update("frozen_at", time.Now())
+	v2, err = wallets.GetWalletVersion(userID, WalletAPIVersion2)
+	if err != nil || v2.Balances.Available != 0 || v2.Balances.Held != wallet.Balance+30 {
+		t.Errorf("Expected the frozen balance to be held, got %+v (err %v)", v2.Balances, err)
+	}
+
+	if _, err := wallets.GetWalletVersion(userID, 3); err != ErrInvalidWalletVersion {
+		t.Errorf("Expected ErrInvalidWalletVersion, got %v", err)
+	}
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"testing"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+)
+
+// Bonus credits: tickets are paid from the balance first in the spend
+// priority and topped up from the other, each currency drawn on is booked as
+// its own transaction, and both ledgers still reconcile.
+func TestBonusCreditsSpendPriority(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("tickets are paid in the spend priority", prop.ForAll(
+		func(points, bonus, quantity int, bonusFirst bool) bool {
+			db, purchases, _, lotteryTypeID, userIDs := setupStockReservationTest(t, 100, 1)
+			if err := db.AutoMigrate(&model.WalletReconciliation{}, &model.Notification{}); err != nil {
+				t.Logf("Failed to migrate: %v", err)
+				return false
+			}
+			userID := userIDs[0]
+			var wallet model.Wallet
+			db.Where("user_id = ?", userID).First(&wallet)
+			db.Model(&wallet).Updates(map[string]interface{}{"balance": points, "bonus_balance": bonus})
+			db.Create(&model.Transaction{WalletID: wallet.ID, Type: model.TransactionTypeInitial, Currency: model.CurrencyPoints, Amount: points})
+			db.Create(&model.Transaction{WalletID: wallet.ID, Type: model.TransactionTypeBonusGrant, Currency: model.CurrencyBonus, Amount: bonus})
+
+			priority := BonusSpendLast
+			if bonusFirst {
+				priority = BonusSpendFirst
+			}
+			purchases.walletService.UseBonusSpendPriority(priority)
+
+			cost := 10 * quantity
+			result, err := purchases.PurchaseTickets(userID, PurchaseRequest{LotteryTypeID: lotteryTypeID, Quantity: quantity})
+			if points+bonus < cost {
+				if err != ErrInsufficientBalance {
+					t.Logf("Expected ErrInsufficientBalance paying %d from %d+%d, got %v", cost, points, bonus, err)
+					return false
+				}
+				return true
+			}
+			if err != nil {
+				t.Logf("PurchaseTickets failed: %v", err)
+				return false
+			}
+
+			bonusSpent := min(bonus, cost)
+			if !bonusFirst {
+				bonusSpent = cost - min(points, cost)
+			}
+			if result.BonusSpent != bonusSpent || result.BonusBalance != bonus-bonusSpent || result.Balance != points-(cost-bonusSpent) {
+				t.Logf("Paying %d from %d+%d (bonus first %v): got %+v", cost, points, bonus, bonusFirst, result)
+				return false
+			}
+
+			// Each currency drawn on is booked once, in its own currency
+			var booked []model.Transaction
+			db.Where("wallet_id = ? AND type IN ?", wallet.ID,
+				[]model.TransactionType{model.TransactionTypePurchase, model.TransactionTypeBonusPurchase}).Find(&booked)
+			spent := map[model.WalletCurrency]int{}
+			for _, transaction := range booked {
+				if (transaction.Type == model.TransactionTypeBonusPurchase) != (transaction.Currency == model.CurrencyBonus) {
+					t.Logf("Transaction %s booked in %s", transaction.Type, transaction.Currency)
+					return false
+				}
+				spent[transaction.Currency] -= transaction.Amount
+			}
+			if spent[model.CurrencyBonus] != bonusSpent || spent[model.CurrencyPoints] != cost-bonusSpent || len(booked) != len(spent) {
+				t.Logf("Expected %d bonus and %d points booked, got %v", bonusSpent, cost-bonusSpent, spent)
+				return false
+			}
+
+			reconciliations := NewWalletReconciliationService(db, NewAdminService(db, purchases.walletService), NewNotificationService(db))
+			if found, err := reconciliations.Reconcile(); err != nil || found != 0 {
+				t.Logf("Expected both ledgers to reconcile, got %d discrepancies (err %v)", found, err)
+				return false
+			}
+			return true
+		},
+		gen.IntRange(0, 60),
+		gen.IntRange(0, 60),
+		gen.IntRange(1, 5),
+		gen.Bool(),
+	))
+
+	properties.TestingRun(t)
+}
+
+// Campaigns reward bonus credits as bonus grants, which exchanges cannot spend
+func TestCampaignBonusReward(t *testing.T) {
+	db, campaigns, userIDs := setupCampaignTest(t, 1)
+	if _, err := campaigns.CreateCampaign(CampaignRequest{
+		Name:         "Check-in bonus",
+		Trigger:      model.CampaignTriggerCheckIn,
+		RewardType:   model.CampaignRewardBonus,
+		RewardPoints: 25,
+	}); err != nil {
+		t.Fatalf("CreateCampaign failed: %v", err)
+	}
+	if _, err := campaignCheckins(db, campaigns).CheckIn(userIDs[0]); err != nil {
+		t.Fatalf("CheckIn failed: %v", err)
+	}
+
+	walletService := NewWalletService(db)
+	points, bonus, err := walletService.GetBalances(userIDs[0])
+	if err != nil || bonus != 25 {
+		t.Fatalf("Expected 25 bonus credits, got %d (err %v)", bonus, err)
+	}
+	var grants int64
+	db.Model(&model.Transaction{}).
+		Where("type = ? AND currency = ? AND amount = ?", model.TransactionTypeBonusGrant, model.CurrencyBonus, 25).Count(&grants)
+	if grants != 1 {
+		t.Errorf("Expected one bonus grant, got %d", grants)
+	}
+
+	// Bonus credits count for tickets only
+	if balance, _ := walletService.GetBalance(userIDs[0]); balance != points {
+		t.Errorf("Expected the points balance %d unchanged, got %d", points, balance)
+	}
+	if tickets, _ := walletService.GetTicketBalance(userIDs[0]); tickets != points+25 {
+		t.Errorf("Expected %d to spend on tickets, got %d", points+25, tickets)
+	}
+}
+
+// Tickets paid with bonus credits count as sales in the admin statistics; a
+// purchase paid from both balances counts once in the sales trend
+func TestBonusPurchasesCountAsSales(t *testing.T) {
+	db, purchases, _, lotteryTypeID, userIDs := setupStockReservationTest(t, 100, 2)
+	if err := db.AutoMigrate(&model.PaymentOrder{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	purchases.walletService.UseBonusSpendPriority(BonusSpendFirst)
+	for i, bonus := range []int{15, 30} {
+		if err := grantBonus(db, userIDs[i], bonus, "bonus", 0); err != nil {
+			t.Fatalf("grantBonus failed: %v", err)
+		}
+	}
+
+	// 15 bonus and 5 points, then 10 bonus
+	for i, quantity := range []int{2, 1} {
+		if _, err := purchases.PurchaseTickets(userIDs[i], PurchaseRequest{LotteryTypeID: lotteryTypeID, Quantity: quantity}); err != nil {
+			t.Fatalf("PurchaseTickets failed: %v", err)
+		}
+	}
+
+	admin := NewAdminService(db, purchases.walletService)
+	dashboard, err := admin.GetDashboardStats()
+	if err != nil {
+		t.Fatalf("GetDashboardStats failed: %v", err)
+	}
+	if dashboard.TotalRevenue != 30 {
+		t.Errorf("Expected revenue 30, got %d", dashboard.TotalRevenue)
+	}
+
+	metrics, err := admin.getCoreMetrics()
+	if err != nil {
+		t.Fatalf("getCoreMetrics failed: %v", err)
+	}
+	if metrics.TotalSalesAmount != 30 {
+		t.Errorf("Expected sales of 30, got %d", metrics.TotalSalesAmount)
+	}
+	// The sales trend formats dates with postgres functions, so its count is
+	// checked on its own
+	var count int64
+	db.Model(&model.Transaction{}).Select("SUM(CASE WHEN "+splitChargeSQL+" THEN 0 ELSE 1 END)").
+		Where("type IN ?", ticketSpendTypes).Scan(&count)
+	if count != 2 {
+		t.Errorf("Expected 2 purchases in the sales trend, got %d", count)
+	}
+	behavior, err := admin.getUserBehaviorStats()
+	if err != nil {
+		t.Fatalf("getUserBehaviorStats failed: %v", err)
+	}
+	var users int64
+	db.Model(&model.User{}).Count(&users)
+	if want := 30 / float64(users); behavior.AvgPurchaseAmount != want {
+		t.Errorf("Expected an average purchase amount of %v, got %v", want, behavior.AvgPurchaseAmount)
+	}
+}
+
+// Purchases of the same tickets booked one after the other are each counted
+// once, whichever balances paid for them
+func TestBackToBackPurchasesCountOnce(t *testing.T) {
+	db, purchases, _, lotteryTypeID, userIDs := setupStockReservationTest(t, 100, 1)
+	purchases.walletService.UseBonusSpendPriority(BonusSpendLast)
+	db.Model(&model.Wallet{}).Where("user_id = ?", userIDs[0]).Update("balance", 10)
+	if err := grantBonus(db, userIDs[0], 15, "bonus", 0); err != nil {
+		t.Fatalf("grantBonus failed: %v", err)
+	}
+
+	// 10 points, then 10 bonus, then 5 points and 5 bonus
+	for i := 0; i < 3; i++ {
+		if i == 2 {
+			db.Model(&model.Wallet{}).Where("user_id = ?", userIDs[0]).Update("balance", 5)
+		}
+		if _, err := purchases.PurchaseTickets(userIDs[0], PurchaseRequest{LotteryTypeID: lotteryTypeID, Quantity: 1}); err != nil {
+			t.Fatalf("PurchaseTickets failed: %v", err)
+		}
+	}
+
+	var charges []model.Transaction
+	db.Where("type IN ?", ticketSpendTypes).Order("id").Find(&charges)
+	if len(charges) != 4 {
+		t.Fatalf("Expected 4 charges, got %d", len(charges))
+	}
+	if charges[2].ReferenceID != charges[2].ID || charges[3].ReferenceID != charges[2].ID {
+		t.Errorf("Expected both halves of the split purchase to reference its first, got %d and %d",
+			charges[2].ReferenceID, charges[3].ReferenceID)
+	}
+	var count int64
+	db.Model(&model.Transaction{}).Select("SUM(CASE WHEN "+splitChargeSQL+" THEN 0 ELSE 1 END)").
+		Where("type IN ?", ticketSpendTypes).Scan(&count)
+	if count != 3 {
+		t.Errorf("Expected 3 purchases in the sales trend, got %d", count)
+	}
+}
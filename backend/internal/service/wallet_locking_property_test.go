@@ -0,0 +1,110 @@
+package service
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Wallet reads that precede a balance change lock the wallet row, so on
+// PostgreSQL a concurrent transaction waits instead of overwriting the balance.
+func TestLockWalletSelectsForUpdate(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost dbname=dry_run"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("Failed to open dry run database: %v", err)
+	}
+
+	var wallet model.Wallet
+	stmt := db.Session(&gorm.Session{DryRun: true})
+	sql := stmt.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		_ = lockWallet(tx, 7, &wallet)
+		return tx
+	})
+	if !strings.Contains(sql, "FOR UPDATE") || !strings.Contains(sql, "user_id = 7") {
+		t.Errorf("Expected a locking read of the wallet, got %s", sql)
+	}
+}
+
+// Concurrent debits, credits and admin adjustments of one wallet: every
+// accepted change is applied exactly once, the balance never goes negative,
+// and it always equals the starting balance plus the ledger.
+func TestConcurrentWalletChangesAreNotLost(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("balance equals the start plus every accepted change", prop.ForAll(
+		func(start int, changes []int) bool {
+			db := setupLotteryTestDB(t)
+			sqlDB, _ := db.DB()
+			sqlDB.SetMaxOpenConns(1) // every :memory: connection is a separate database
+			if err := db.AutoMigrate(&model.AdminLog{}, &model.UserNote{}); err != nil {
+				t.Logf("Failed to migrate: %v", err)
+				return false
+			}
+			user := model.User{LinuxdoID: "locking", Username: "Locking"}
+			db.Create(&user)
+			db.Create(&model.Wallet{UserID: user.ID, Balance: start})
+
+			wallets := NewWalletService(db)
+			admin := NewAdminService(db, wallets)
+
+			var wg sync.WaitGroup
+			var mutex sync.Mutex
+			applied := 0
+			for i, change := range changes {
+				wg.Add(1)
+				go func(i, change int) {
+					defer wg.Done()
+					var err error
+					switch {
+					case i%3 == 2:
+						_, err = admin.AdjustUserPoints(1, user.ID, AdjustUserPointsRequest{Amount: change})
+					case change < 0:
+						err = wallets.Deduct(user.ID, -change, model.TransactionTypePurchase, "purchase", 0)
+					default:
+						err = wallets.Credit(user.ID, change, model.TransactionTypeWin, "win", 0)
+					}
+					if err == nil {
+						mutex.Lock()
+						applied += change
+						mutex.Unlock()
+					} else if err != ErrInsufficientBalance {
+						t.Logf("Change %d failed: %v", change, err)
+					}
+				}(i, change)
+			}
+			wg.Wait()
+
+			var wallet model.Wallet
+			db.Where("user_id = ?", user.ID).First(&wallet)
+			var ledger struct{ Total int }
+			db.Model(&model.Transaction{}).Select("COALESCE(SUM(amount), 0) as total").
+				Where("wallet_id = ?", wallet.ID).Scan(&ledger)
+			if wallet.Balance != start+applied || wallet.Balance < 0 || ledger.Total != applied {
+				t.Logf("start=%d applied=%d balance=%d ledger=%d", start, applied, wallet.Balance, ledger.Total)
+				return false
+			}
+			return true
+		},
+		gen.IntRange(1, 50),
+		gen.SliceOfN(12, gen.IntRange(-30, 30).SuchThat(func(v int) bool { return v != 0 })),
+	))
+
+	properties.TestingRun(t)
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+	"gorm.io/gorm"
+)
+
+// setupWalletReconciliationTest creates an admin and one wallet per given
+// drift, each with a matching initial transaction. A non-zero drift moves the
+// stored balance away from the ledger behind the wallet service's back.
+func setupWalletReconciliationTest(t *testing.T, drifts []int) (*gorm.DB, *WalletReconciliationService, *WalletService, uint, []uint) {
+	db := setupTenantTestDB(t)
+	if err := db.AutoMigrate(&model.WalletReconciliation{}, &model.Notification{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	walletService := NewWalletService(db)
+	adminService := NewAdminService(db, walletService)
+	reconciliations := NewWalletReconciliationService(db, adminService, NewNotificationService(db))
+
+	admin := model.User{LinuxdoID: "reconcile_admin", Username: "Admin", Role: "admin"}
+	db.Create(&admin)
+
+	userIDs := make([]uint, len(drifts))
+	for i, drift := range drifts {
+		user := model.User{LinuxdoID: fmt.Sprintf("reconcile_user_%d", i), Username: fmt.Sprintf("User %d", i), Role: "user"}
+		db.Create(&user)
+		wallet := model.Wallet{UserID: user.ID}
+		db.Create(&wallet)
+		db.Create(&model.Transaction{WalletID: wallet.ID, Type: model.TransactionTypeInitial, Amount: wallet.Balance})
+		if drift != 0 {
+			db.Model(&model.Wallet{}).Where("id = ?", wallet.ID).Update("balance", gorm.Expr("balance + ?", drift))
+		}
+		userIDs[i] = user.ID
+	}
+	return db, reconciliations, walletService, admin.ID, userIDs
+}
+
+// Wallet reconciliation: exactly the wallets whose balance drifted from their
+// ledger get one open reconciliation each, admins are notified once per
+// discrepancy, and frozen wallets refuse debits until resolved.
+func TestWalletReconciliationFindsDrift(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("drifted wallets are recorded, notified and frozen", prop.ForAll(
+		func(drifts []int, freeze bool) bool {
+			db, reconciliations, walletService, adminID, userIDs := setupWalletReconciliationTest(t, drifts)
+			if _, err := reconciliations.adminService.UpdateSystemSettings(adminID, UpdateSystemSettingsRequest{WalletReconcileFreeze: &freeze}); err != nil {
+				t.Logf("UpdateSystemSettings failed: %v", err)
+				return false
+			}
+
+			drifted := 0
+			for _, drift := range drifts {
+				if drift != 0 {
+					drifted++
+				}
+			}
+
+			found, err := reconciliations.Reconcile()
+			if err != nil || found != drifted {
+				t.Logf("Expected %d discrepancies, got %d (err %v)", drifted, found, err)
+				return false
+			}
+			// A second run opens nothing new
+			if found, err := reconciliations.Reconcile(); err != nil || found != 0 {
+				t.Logf("Expected no new discrepancies on a second run, got %d (err %v)", found, err)
+				return false
+			}
+
+			open, err := reconciliations.GetReconciliations(ReconciliationQuery{Limit: 100})
+			if err != nil || int(open.Total) != drifted {
+				t.Logf("Expected %d open reconciliations, got %+v (err %v)", drifted, open, err)
+				return false
+			}
+			for _, record := range open.Reconciliations {
+				if record.Difference == 0 || record.Balance-record.LedgerTotal != record.Difference || record.Frozen != freeze {
+					t.Logf("Unexpected reconciliation %+v", record)
+					return false
+				}
+			}
+
+			var notifications int64
+			db.Model(&model.Notification{}).Where("user_id = ? AND type = ?", adminID, model.NotificationTypeWalletDiscrepancy).Count(&notifications)
+			if int(notifications) != drifted {
+				t.Logf("Expected %d admin notifications, got %d", drifted, notifications)
+				return false
+			}
+
+			// Frozen wallets take credits but refuse debits
+			for i, userID := range userIDs {
+				blocked := freeze && drifts[i] != 0
+				if err := walletService.Credit(userID, 10, model.TransactionTypeWin, "win", 0); err != nil {
+					t.Logf("Credit failed: %v", err)
+					return false
+				}
+				err := walletService.Deduct(userID, 1, model.TransactionTypePurchase, "purchase", 0)
+				if blocked != (err == ErrWalletFrozen) || (!blocked && err != nil) {
+					t.Logf("Wallet %d: expected blocked %v, got %v", i, blocked, err)
+					return false
+				}
+			}
+
+			// Resolving with unfreeze lifts the freeze
+			for _, record := range open.Reconciliations {
+				if _, err := reconciliations.Resolve(adminID, record.ID, ResolveReconciliationRequest{Note: "checked", Unfreeze: true}); err != nil {
+					t.Logf("Resolve failed: %v", err)
+					return false
+				}
+				if _, err := reconciliations.Resolve(adminID, record.ID, ResolveReconciliationRequest{}); err != ErrReconciliationResolved {
+					t.Logf("Expected ErrReconciliationResolved, got %v", err)
+					return false
+				}
+				if err := walletService.Deduct(record.UserID, 1, model.TransactionTypePurchase, "purchase", 0); err != nil {
+					t.Logf("Expected the wallet to be unfrozen, got %v", err)
+					return false
+				}
+			}
+
+			resolved, err := reconciliations.GetReconciliations(ReconciliationQuery{Status: ReconciliationStatusResolved, Limit: 100})
+			return err == nil && int(resolved.Total) == drifted
+		},
+		gen.SliceOfN(5, gen.OneConstOf(0, 0, -7, 25)),
+		gen.Bool(),
+	))
+
+	properties.TestingRun(t)
+}
+
+func TestWalletReconciliationFrozenExchange(t *testing.T) {
+	db, reconciliations, walletService, adminID, userIDs := setupWalletReconciliationTest(t, []int{5})
+	freeze := true
+	if _, err := reconciliations.adminService.UpdateSystemSettings(adminID, UpdateSystemSettingsRequest{WalletReconcileFreeze: &freeze}); err != nil {
+		t.Fatalf("UpdateSystemSettings failed: %v", err)
+	}
+
+	// Reconciliations are kept per tenant
+	if found, err := reconciliations.ForTenant(2).Reconcile(); err != nil || found != 0 {
+		t.Fatalf("Expected no discrepancy in tenant 2, got %d (err %v)", found, err)
+	}
+	if found, err := reconciliations.ForTenant(1).Reconcile(); err != nil || found != 1 {
+		t.Fatalf("Expected one discrepancy in tenant 1, got %d (err %v)", found, err)
+	}
+
+	exchangeService := NewExchangeService(db, walletService)
+	product := model.Product{Name: "Gift Card", Price: 10, Status: model.ProductStatusSoldOut}
+	db.Create(&product)
+	if _, err := exchangeService.ImportCardKeys(product.ID, []string{"KEY-1"}); err != nil {
+		t.Fatalf("Failed to import card keys: %v", err)
+	}
+	if _, err := exchangeService.Redeem(userIDs[0], product.ID); err != ErrWalletFrozen {
+		t.Errorf("Expected ErrWalletFrozen when redeeming from a frozen wallet, got %v", err)
+	}
+
+	open, _ := reconciliations.GetReconciliations(ReconciliationQuery{})
+	if open == nil || open.Total != 1 {
+		t.Fatalf("Expected one open reconciliation, got %+v", open)
+	}
+	if _, err := reconciliations.ForTenant(2).Resolve(adminID, open.Reconciliations[0].ID, ResolveReconciliationRequest{}); err != ErrReconciliationNotFound {
+		t.Errorf("Expected ErrReconciliationNotFound for tenant 2, got %v", err)
+	}
+
+	// Resolving without unfreeze keeps the wallet frozen
+	if _, err := reconciliations.Resolve(adminID, open.Reconciliations[0].ID, ResolveReconciliationRequest{Note: "under investigation"}); err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	var wallet model.Wallet
+	db.Where("user_id = ?", userIDs[0]).First(&wallet)
+	if wallet.FrozenAt == nil {
+		t.Errorf("Expected the wallet to stay frozen")
+	}
+
+	var logs int64
+	db.Model(&model.AdminLog{}).Where("action = ?", "resolve_wallet_reconciliation").Count(&logs)
+	if logs != 1 {
+		t.Errorf("Expected the resolution to be logged, got %d logs", logs)
+	}
+}
+
+// Bonus balances are reconciled against the bonus ledger on their own: bonus
+// grants keep them in line, and a drifted points and bonus balance open one
+// reconciliation each, the wallet staying frozen until both are resolved.
+func TestWalletReconciliationChecksBonus(t *testing.T) {
+	db, reconciliations, walletService, adminID, userIDs := setupWalletReconciliationTest(t, []int{0, 5})
+	freeze := true
+	if _, err := reconciliations.adminService.UpdateSystemSettings(adminID, UpdateSystemSettingsRequest{WalletReconcileFreeze: &freeze}); err != nil {
+		t.Fatalf("UpdateSystemSettings failed: %v", err)
+	}
+	for _, userID := range userIDs {
+		if err := grantBonus(db, userID, 30, "bonus", 0); err != nil {
+			t.Fatalf("grantBonus failed: %v", err)
+		}
+	}
+	db.Model(&model.Wallet{}).Where("user_id = ?", userIDs[1]).Update("bonus_balance", gorm.Expr("bonus_balance - ?", 4))
+
+	if found, err := reconciliations.Reconcile(); err != nil || found != 2 {
+		t.Fatalf("Expected two discrepancies, got %d (err %v)", found, err)
+	}
+	open, err := reconciliations.GetReconciliations(ReconciliationQuery{})
+	if err != nil || open.Total != 2 {
+		t.Fatalf("Expected two open reconciliations, got %+v (err %v)", open, err)
+	}
+	byCurrency := make(map[model.WalletCurrency]ReconciliationResponse)
+	for _, record := range open.Reconciliations {
+		if record.UserID != userIDs[1] {
+			t.Errorf("Expected only the drifted wallet recorded, got %+v", record)
+		}
+		byCurrency[record.Currency] = record
+	}
+	if record := byCurrency[model.CurrencyPoints]; record.Difference != 5 {
+		t.Errorf("Expected a points difference of 5, got %+v", record)
+	}
+	if record := byCurrency[model.CurrencyBonus]; record.Balance != 26 || record.LedgerTotal != 30 || record.Difference != -4 {
+		t.Errorf("Expected a bonus difference of -4, got %+v", record)
+	}
+
+	if _, err := reconciliations.Resolve(adminID, byCurrency[model.CurrencyPoints].ID, ResolveReconciliationRequest{Unfreeze: true}); err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if err := walletService.Deduct(userIDs[1], 1, model.TransactionTypePurchase, "purchase", 0); err != ErrWalletFrozen {
+		t.Errorf("Expected the wallet frozen while the bonus discrepancy is open, got %v", err)
+	}
+	if _, err := reconciliations.Resolve(adminID, byCurrency[model.CurrencyBonus].ID, ResolveReconciliationRequest{Unfreeze: true}); err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if err := walletService.Deduct(userIDs[1], 1, model.TransactionTypePurchase, "purchase", 0); err != nil {
+		t.Errorf("Expected the wallet unfrozen, got %v", err)
+	}
+}
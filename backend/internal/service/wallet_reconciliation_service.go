@@ -0,0 +1,434 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+	"scratch-lottery/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// ConfigKeyWalletReconcileFreeze is the system config key that freezes wallets
+// whose balance disagrees with their ledger until an admin reviews them
+const ConfigKeyWalletReconcileFreeze = "wallet_reconcile_freeze"
+
+// walletReconcileBatch is the number of wallets checked per query
+const walletReconcileBatch = 200
+
+// Statuses of wallet reconciliations
+const (
+	ReconciliationStatusOpen     = "open"
+	ReconciliationStatusResolved = "resolved"
+)
+
+var (
+	ErrReconciliationNotFound = errors.New("wallet reconciliation not found")
+	ErrReconciliationResolved = errors.New("wallet reconciliation already resolved")
+)
+
+// WalletReconciliationService audits wallets against their ledger. A wallet
+// whose stored balance differs from the sum of its transactions gets an open
+// reconciliation, the tenant's admins are notified, and when the tenant asks
+// for it the wallet is frozen for debits until an admin resolves it.
+type WalletReconciliationService struct {
+	db                  *gorm.DB
+	adminService        *AdminService
+	notificationService *NotificationService
+}
+
+// NewWalletReconciliationService creates a new wallet reconciliation service.
+// notificationService may be nil, in which case admins are not notified.
+func NewWalletReconciliationService(db *gorm.DB, adminService *AdminService, notificationService *NotificationService) *WalletReconciliationService {
+	return &WalletReconciliationService{db: db, adminService: adminService, notificationService: notificationService}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *WalletReconciliationService) ForTenant(tenantID uint) *WalletReconciliationService {
+	return &WalletReconciliationService{
+		db:                  repository.ScopeTenant(s.db, tenantID),
+		adminService:        s.adminService.ForTenant(tenantID),
+		notificationService: s.notificationService,
+	}
+}
+
+// ReconciliationQuery represents query parameters for wallet reconciliations
+type ReconciliationQuery struct {
+	Status string `form:"status"` // open (default) or resolved
+	Page   int    `form:"page"`
+	Limit  int    `form:"limit"`
+}
+
+// ReconciliationResponse represents a wallet whose balance disagreed with its ledger
+type ReconciliationResponse struct {
+	ID          uint                 `json:"id"`
+	WalletID    uint                 `json:"wallet_id"`
+	UserID      uint                 `json:"user_id"`
+	Username    string               `json:"username"`
+	Currency    model.WalletCurrency `json:"currency"`
+	Balance     int                  `json:"balance"`
+	LedgerTotal int                  `json:"ledger_total"`
+	Difference  int                  `json:"difference"`
+	Frozen      bool                 `json:"frozen"`
+	ResolvedAt  *time.Time           `json:"resolved_at,omitempty"`
+	ResolvedBy  uint                 `json:"resolved_by,omitempty"`
+	Note        string               `json:"note,omitempty"`
+	CreatedAt   time.Time            `json:"created_at"`
+	UpdatedAt   time.Time            `json:"updated_at"`
+}
+
+// ReconciliationListResponse represents paginated wallet reconciliations
+type ReconciliationListResponse struct {
+	Reconciliations []ReconciliationResponse `json:"reconciliations"`
+	Total           int64                    `json:"total"`
+	Page            int                      `json:"page"`
+	Limit           int                      `json:"limit"`
+	TotalPages      int                      `json:"total_pages"`
+}
+
+// ResolveReconciliationRequest represents a request to close a reconciliation.
+// Unfreeze lifts the wallet's freeze once no other reconciliation is open for it.
+type ResolveReconciliationRequest struct {
+	Note     string `json:"note" binding:"max=512"`
+	Unfreeze bool   `json:"unfreeze"`
+}
+
+// walletLedger is a wallet's stored balances next to the sums of their transactions
+type walletLedger struct {
+	ID               uint
+	TenantID         uint
+	UserID           uint
+	Balance          int
+	BonusBalance     int
+	FrozenAt         *time.Time
+	LedgerTotal      int
+	BonusLedgerTotal int
+}
+
+// walletDiscrepancy is one balance of a wallet that disagrees with its ledger
+type walletDiscrepancy struct {
+	walletLedger
+	Currency model.WalletCurrency
+	Stored   int
+	Ledger   int
+}
+
+// discrepancies returns the balances of the wallet that disagree with their ledger
+func (w walletLedger) discrepancies() []walletDiscrepancy {
+	var found []walletDiscrepancy
+	if w.Balance != w.LedgerTotal {
+		found = append(found, walletDiscrepancy{w, model.CurrencyPoints, w.Balance, w.LedgerTotal})
+	}
+	if w.BonusBalance != w.BonusLedgerTotal {
+		found = append(found, walletDiscrepancy{w, model.CurrencyBonus, w.BonusBalance, w.BonusLedgerTotal})
+	}
+	return found
+}
+
+// Reconcile compares every wallet's points balance with the sum of its
+// points transactions, and its bonus balance with the sum of its bonus
+// transactions, and records the ones that disagree. Returns the number of
+// newly found discrepancies.
+func (s *WalletReconciliationService) Reconcile() (int, error) {
+	freezeByTenant := make(map[uint]bool)
+	found := 0
+	var lastID uint
+	for {
+		// Balance and ledger are read in one statement so that concurrent
+		// transactions can't show up as a discrepancy
+		var wallets []walletLedger
+		if err := s.db.Model(&model.Wallet{}).
+			Select("wallets.id, wallets.tenant_id, wallets.user_id, wallets.balance, wallets.bonus_balance, wallets.frozen_at, "+
+				"(SELECT COALESCE(SUM(amount), 0) FROM transactions WHERE transactions.wallet_id = wallets.id AND transactions.currency = 'points' AND transactions.deleted_at IS NULL) AS ledger_total, "+
+				"(SELECT COALESCE(SUM(amount), 0) FROM transactions WHERE transactions.wallet_id = wallets.id AND transactions.currency = 'bonus' AND transactions.deleted_at IS NULL) AS bonus_ledger_total").
+			Where("wallets.id > ?", lastID).
+			Order("wallets.id ASC").
+			Limit(walletReconcileBatch).
+			Scan(&wallets).Error; err != nil {
+			return found, err
+		}
+		if len(wallets) == 0 {
+			return found, nil
+		}
+		lastID = wallets[len(wallets)-1].ID
+
+		for _, wallet := range wallets {
+			discrepancies := wallet.discrepancies()
+			if len(discrepancies) == 0 {
+				continue
+			}
+
+			freeze, ok := freezeByTenant[wallet.TenantID]
+			if !ok {
+				value, err := s.adminService.ForTenant(wallet.TenantID).GetConfigValue(ConfigKeyWalletReconcileFreeze)
+				if err != nil && !errors.Is(err, ErrConfigNotFound) {
+					return found, err
+				}
+				freeze = value == "true"
+				freezeByTenant[wallet.TenantID] = freeze
+			}
+
+			for _, discrepancy := range discrepancies {
+				created, err := s.recordDiscrepancy(discrepancy, freeze)
+				if err != nil {
+					return found, err
+				}
+				if created {
+					found++
+					s.notifyAdmins(discrepancy, freeze)
+				}
+			}
+		}
+	}
+}
+
+// recordDiscrepancy opens a reconciliation for a wallet's balance, or
+// refreshes the figures of the one already open for it, and freezes the
+// wallet if asked to. Reports whether a new reconciliation was opened.
+func (s *WalletReconciliationService) recordDiscrepancy(wallet walletDiscrepancy, freeze bool) (bool, error) {
+	created := false
+	err := repository.Transaction(s.db, func(tx *gorm.DB) error {
+		created = false
+		frozen := wallet.FrozenAt != nil
+		if freeze && !frozen {
+			if err := tx.Model(&model.Wallet{}).Where("id = ? AND frozen_at IS NULL", wallet.ID).
+				Update("frozen_at", time.Now()).Error; err != nil {
+				return err
+			}
+			frozen = true
+		}
+
+		var open model.WalletReconciliation
+		err := tx.Where("wallet_id = ? AND currency = ? AND resolved_at IS NULL", wallet.ID, wallet.Currency).First(&open).Error
+		if err == nil {
+			return tx.Model(&open).Updates(map[string]interface{}{
+				"balance":      wallet.Stored,
+				"ledger_total": wallet.Ledger,
+				"difference":   wallet.Stored - wallet.Ledger,
+				"frozen":       frozen,
+			}).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		reconciliation := model.WalletReconciliation{
+			TenantID:    wallet.TenantID,
+			WalletID:    wallet.ID,
+			UserID:      wallet.UserID,
+			Currency:    wallet.Currency,
+			Balance:     wallet.Stored,
+			LedgerTotal: wallet.Ledger,
+			Difference:  wallet.Stored - wallet.Ledger,
+			Frozen:      frozen,
+		}
+		created = true
+		return tx.Create(&reconciliation).Error
+	})
+	return created, err
+}
+
+// notifyAdmins tells the admins of the wallet's tenant about a new discrepancy.
+// Failures are logged; the reconciliation itself is already recorded.
+func (s *WalletReconciliationService) notifyAdmins(wallet walletDiscrepancy, frozen bool) {
+	if s.notificationService == nil {
+		return
+	}
+
+	var adminIDs []uint
+	if err := s.db.Model(&model.User{}).Where("tenant_id = ? AND role = ?", wallet.TenantID, "admin").
+		Pluck("id", &adminIDs).Error; err != nil {
+		logger.Default().Warn("Listing admins for wallet %d discrepancy failed: %v", wallet.ID, err)
+		return
+	}
+
+	balanceName := "钱包余额"
+	if wallet.Currency == model.CurrencyBonus {
+		balanceName = "赠金余额"
+	}
+	content := fmt.Sprintf("用户 #%d 的%s为 %d，流水合计为 %d，相差 %d。",
+		wallet.UserID, balanceName, wallet.Stored, wallet.Ledger, wallet.Stored-wallet.Ledger)
+	if frozen {
+		content += "该钱包已冻结，核查后请在对账记录中处理。"
+	}
+	notifications := s.notificationService.ForTenant(wallet.TenantID)
+	for _, adminID := range adminIDs {
+		if _, err := notifications.Notify(adminID, model.NotificationTypeWalletDiscrepancy, "钱包对账异常", content); err != nil {
+			logger.Default().Warn("Notifying admin %d of wallet %d discrepancy failed: %v", adminID, wallet.ID, err)
+		}
+	}
+}
+
+// Start reconciles the wallets every interval until the returned stop func is
+// called
+func (s *WalletReconciliationService) Start(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			found, err := s.Reconcile()
+			if err != nil {
+				logger.Default().Warn("Reconciling wallets failed: %v", err)
+			} else if found > 0 {
+				logger.Default().Warn("Found %d wallets whose balance disagrees with their ledger", found)
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// GetReconciliations lists wallet reconciliations (admin only)
+func (s *WalletReconciliationService) GetReconciliations(query ReconciliationQuery) (*ReconciliationListResponse, error) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit < 1 || query.Limit > 100 {
+		query.Limit = 20
+	}
+
+	dbQuery := s.db.Model(&model.WalletReconciliation{})
+	if query.Status == ReconciliationStatusResolved {
+		dbQuery = dbQuery.Where("resolved_at IS NOT NULL")
+	} else {
+		dbQuery = dbQuery.Where("resolved_at IS NULL")
+	}
+
+	var total int64
+	if err := dbQuery.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	var records []model.WalletReconciliation
+	offset := (query.Page - 1) * query.Limit
+	if err := dbQuery.Preload("User").
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(query.Limit).
+		Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	reconciliations := make([]ReconciliationResponse, len(records))
+	for i := range records {
+		reconciliations[i] = toReconciliationResponse(&records[i])
+	}
+
+	totalPages := int(total) / query.Limit
+	if int(total)%query.Limit > 0 {
+		totalPages++
+	}
+
+	return &ReconciliationListResponse{
+		Reconciliations: reconciliations,
+		Total:           total,
+		Page:            query.Page,
+		Limit:           query.Limit,
+		TotalPages:      totalPages,
+	}, nil
+}
+
+// Resolve closes a reconciliation after an admin reviewed the wallet, and
+// lifts the wallet's freeze when asked to and nothing else is open for it
+func (s *WalletReconciliationService) Resolve(adminID, id uint, req ResolveReconciliationRequest) (*ReconciliationResponse, error) {
+	var record model.WalletReconciliation
+	if err := s.db.First(&record, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReconciliationNotFound
+		}
+		return nil, err
+	}
+	if record.ResolvedAt != nil {
+		return nil, ErrReconciliationResolved
+	}
+
+	unfrozen := false
+	err := repository.Transaction(s.db, func(tx *gorm.DB) error {
+		unfrozen = false
+		// Close the record, guarding against a concurrent resolution
+		result := tx.Model(&model.WalletReconciliation{}).
+			Where("id = ? AND resolved_at IS NULL", record.ID).
+			Updates(map[string]interface{}{
+				"resolved_at": time.Now(),
+				"resolved_by": adminID,
+				"note":        req.Note,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrReconciliationResolved
+		}
+
+		if req.Unfreeze {
+			var open int64
+			if err := tx.Model(&model.WalletReconciliation{}).
+				Where("wallet_id = ? AND resolved_at IS NULL", record.WalletID).
+				Count(&open).Error; err != nil {
+				return err
+			}
+			if open == 0 {
+				result := tx.Model(&model.Wallet{}).Where("id = ? AND frozen_at IS NOT NULL", record.WalletID).
+					Update("frozen_at", nil)
+				if result.Error != nil {
+					return result.Error
+				}
+				unfrozen = result.RowsAffected > 0
+			}
+		}
+
+		details, _ := json.Marshal(map[string]interface{}{
+			"wallet_id":  record.WalletID,
+			"currency":   record.Currency,
+			"difference": record.Difference,
+			"unfrozen":   unfrozen,
+			"note":       req.Note,
+		})
+		adminLog := model.AdminLog{
+			AdminID:    adminID,
+			Action:     "resolve_wallet_reconciliation",
+			TargetType: "wallet",
+			TargetID:   record.WalletID,
+			Details:    string(details),
+		}
+		return tx.Create(&adminLog).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Preload("User").First(&record, record.ID).Error; err != nil {
+		return nil, err
+	}
+	resp := toReconciliationResponse(&record)
+	return &resp, nil
+}
+
+func toReconciliationResponse(record *model.WalletReconciliation) ReconciliationResponse {
+	return ReconciliationResponse{
+		ID:          record.ID,
+		WalletID:    record.WalletID,
+		UserID:      record.UserID,
+		Username:    record.User.Username,
+		Currency:    record.Currency,
+		Balance:     record.Balance,
+		LedgerTotal: record.LedgerTotal,
+		Difference:  record.Difference,
+		Frozen:      record.Frozen,
+		ResolvedAt:  record.ResolvedAt,
+		ResolvedBy:  record.ResolvedBy,
+		Note:        record.Note,
+		CreatedAt:   record.CreatedAt,
+		UpdatedAt:   record.UpdatedAt,
+	}
+}
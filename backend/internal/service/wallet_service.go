@@ -1,28 +1,77 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"time"
 
 	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+	"scratch-lottery/pkg/money"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 var (
 	ErrWalletNotFound       = errors.New("wallet not found")
 	ErrInsufficientBalance  = errors.New("insufficient balance")
 	ErrInvalidAmount        = errors.New("invalid amount")
+	ErrWalletFrozen         = errors.New("wallet frozen")
+	ErrInvalidWalletVersion = errors.New("invalid wallet api version")
+)
+
+// Versions of the wallet response. Version 1, the default, reports the
+// spendable wallet balance alone; version 2 adds the balance breakdown and
+// reports every point the user owns as the balance.
+const (
+	WalletAPIVersion1 = 1
+	WalletAPIVersion2 = 2
+)
+
+// Spend priorities of ticket purchases paid from both balances
+const (
+	BonusSpendFirst = "bonus_first"  // bonus credits, then points
+	BonusSpendLast  = "points_first" // points, then bonus credits
 )
 
 // WalletService handles wallet-related business logic
 type WalletService struct {
-	db *gorm.DB
+	db            *gorm.DB
+	bonusPriority string
 }
 
-// NewWalletService creates a new wallet service
+// NewWalletService creates a new wallet service. Ticket purchases spend
+// bonus credits first unless UseBonusSpendPriority says otherwise.
 func NewWalletService(db *gorm.DB) *WalletService {
-	return &WalletService{db: db}
+	return &WalletService{db: db, bonusPriority: BonusSpendFirst}
+}
+
+// UseBonusSpendPriority sets which balance ticket purchases draw on first;
+// anything but BonusSpendLast spends bonus credits first
+func (s *WalletService) UseBonusSpendPriority(priority string) {
+	if priority != BonusSpendLast {
+		priority = BonusSpendFirst
+	}
+	s.bonusPriority = priority
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *WalletService) ForTenant(tenantID uint) *WalletService {
+	return &WalletService{db: repository.ScopeTenant(s.db, tenantID), bonusPriority: s.bonusPriority}
+}
+
+// WithRequest returns a copy of the service whose logs carry the request id
+// of ctx
+func (s *WalletService) WithRequest(ctx context.Context) *WalletService {
+	scoped := *s
+	scoped.db = repository.ScopeRequest(s.db, ctx)
+	return &scoped
+}
+
+// withDB returns a copy of the service working on db, such as a transaction
+func (s *WalletService) withDB(db *gorm.DB) *WalletService {
+	return &WalletService{db: db, bonusPriority: s.bonusPriority}
 }
 
 // WalletResponse represents the wallet information response
@@ -30,15 +79,29 @@ type WalletResponse struct {
 	ID           uint                 `json:"id"`
 	UserID       uint                 `json:"user_id"`
 	Balance      int                  `json:"balance"`
+	BonusBalance int                  `json:"bonus_balance"` // Bonus credits, spent on tickets only
+	Balances     *WalletBalances      `json:"balances,omitempty"` // Version 2 only
 	Transactions []TransactionResponse `json:"transactions,omitempty"`
+	Sandbox      bool                 `json:"sandbox,omitempty"` // The points are test currency of a sandbox tenant
 	CreatedAt    time.Time            `json:"created_at"`
 	UpdatedAt    time.Time            `json:"updated_at"`
 }
 
+// WalletBalances breaks the points of a wallet down by whether they can be
+// spent. Pending points are not in the wallet yet. Bonus credits are kept
+// apart from the points.
+type WalletBalances struct {
+	Available int `json:"available"` // Spendable now
+	Pending   int `json:"pending"`   // Won prizes waiting for their claim to be confirmed or approved
+	Held      int `json:"held"`      // Reserved for exchanges awaiting confirmation, or frozen for review
+	Bonus     int `json:"bonus"`     // Bonus credits, spent on tickets only
+}
+
 // TransactionResponse represents a transaction in the response
 type TransactionResponse struct {
 	ID          uint                   `json:"id"`
 	Type        model.TransactionType  `json:"type"`
+	Currency    model.WalletCurrency   `json:"currency"`
 	Amount      int                    `json:"amount"`
 	Description string                 `json:"description"`
 	ReferenceID uint                   `json:"reference_id,omitempty"`
@@ -47,9 +110,10 @@ type TransactionResponse struct {
 
 // TransactionQuery represents query parameters for transactions
 type TransactionQuery struct {
-	Type   string `form:"type"`
-	Page   int    `form:"page"`
-	Limit  int    `form:"limit"`
+	Type     string `form:"type"`
+	Currency string `form:"currency"` // points or bonus
+	Page     int    `form:"page"`
+	Limit    int    `form:"limit"`
 }
 
 // TransactionListResponse represents paginated transaction list
@@ -75,13 +139,15 @@ func (s *WalletService) CreateWalletForUser(userID uint) (*model.Wallet, error)
 		Balance: 50, // Initial 50 points as per requirements
 	}
 
-	err := s.db.Transaction(func(tx *gorm.DB) error {
+	err := repository.Transaction(s.db, func(tx *gorm.DB) error {
+		wallet.ID = 0
 		if err := tx.Create(&wallet).Error; err != nil {
 			return err
 		}
 
 		// Create initial transaction record
 		transaction := model.Transaction{
+			TenantID:    wallet.TenantID,
 			WalletID:    wallet.ID,
 			Type:        model.TransactionTypeInitial,
 			Amount:      50,
@@ -99,6 +165,17 @@ func (s *WalletService) CreateWalletForUser(userID uint) (*model.Wallet, error)
 
 // GetWalletByUserID retrieves wallet information for a user
 func (s *WalletService) GetWalletByUserID(userID uint) (*WalletResponse, error) {
+	return s.GetWalletVersion(userID, WalletAPIVersion1)
+}
+
+// GetWalletVersion retrieves wallet information for a user in a version of
+// the wallet response. Version 2 reports the available, pending and held
+// points, and as the balance the available and held points together.
+func (s *WalletService) GetWalletVersion(userID uint, version int) (*WalletResponse, error) {
+	if version != WalletAPIVersion1 && version != WalletAPIVersion2 {
+		return nil, ErrInvalidWalletVersion
+	}
+
 	var wallet model.Wallet
 	if err := s.db.Where("user_id = ?", userID).First(&wallet).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -114,7 +191,52 @@ func (s *WalletService) GetWalletByUserID(userID uint) (*WalletResponse, error)
 		Limit(10).
 		Find(&transactions)
 
-	return s.toWalletResponse(&wallet, transactions), nil
+	resp := s.toWalletResponse(&wallet, transactions)
+	sandbox, err := isSandboxTenant(s.db, wallet.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	resp.Sandbox = sandbox
+	if version == WalletAPIVersion2 {
+		balances, err := s.balances(&wallet)
+		if err != nil {
+			return nil, err
+		}
+		resp.Balances = balances
+		resp.Balance = balances.Available + balances.Held
+	}
+	return resp, nil
+}
+
+// balances breaks the points of a wallet down. Exchange reservations have
+// already been deducted from the wallet balance and are refunded if released;
+// a frozen wallet holds its whole balance until the review.
+func (s *WalletService) balances(wallet *model.Wallet) (*WalletBalances, error) {
+	balances := &WalletBalances{Available: wallet.Balance, Bonus: wallet.BonusBalance}
+	if wallet.FrozenAt != nil {
+		balances.Available, balances.Held = 0, wallet.Balance
+	}
+
+	var reserved int64
+	if err := s.db.Model(&model.ExchangeRecord{}).
+		Where("user_id = ? AND status = ?", wallet.UserID, model.ExchangeRecordStatusReserved).
+		Select("COALESCE(SUM(cost), 0)").
+		Scan(&reserved).Error; err != nil {
+		return nil, err
+	}
+	balances.Held += int(reserved)
+
+	// Product payouts are delivered as products, not points
+	var pending int64
+	if err := s.db.Model(&model.PrizeClaim{}).
+		Where("user_id = ? AND status IN ? AND payout_product_id = 0", wallet.UserID,
+			[]model.PrizeClaimStatus{model.PrizeClaimStatusPending, model.PrizeClaimStatusSubmitted}).
+		Select("COALESCE(SUM(prize_amount), 0)").
+		Scan(&pending).Error; err != nil {
+		return nil, err
+	}
+	balances.Pending = int(pending)
+	return balances, nil
 }
 
 // GetBalance retrieves the current balance for a user
@@ -154,6 +276,9 @@ func (s *WalletService) GetTransactions(userID uint, query TransactionQuery) (*T
 	if query.Type != "" {
 		dbQuery = dbQuery.Where("type = ?", query.Type)
 	}
+	if query.Currency != "" {
+		dbQuery = dbQuery.Where("currency = ?", query.Currency)
+	}
 
 	// Get total count
 	var total int64
@@ -188,30 +313,41 @@ func (s *WalletService) GetTransactions(userID uint, query TransactionQuery) (*T
 
 // AddTransaction adds a transaction and updates the wallet balance
 func (s *WalletService) AddTransaction(userID uint, txType model.TransactionType, amount int, description string, referenceID uint) error {
-	return s.db.Transaction(func(tx *gorm.DB) error {
+	return repository.Transaction(s.db, func(tx *gorm.DB) error {
 		var wallet model.Wallet
-		if err := tx.Where("user_id = ?", userID).First(&wallet).Error; err != nil {
+		if err := lockWallet(tx, userID, &wallet); err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
 				return ErrWalletNotFound
 			}
 			return err
 		}
 
+		// Frozen wallets only take credits until the discrepancy is reviewed
+		if amount < 0 && wallet.FrozenAt != nil {
+			return ErrWalletFrozen
+		}
+
 		// Check balance for debit transactions
-		if amount < 0 && wallet.Balance+amount < 0 {
+		balance, err := money.New(int64(wallet.Balance), money.Points).Add(money.New(int64(amount), money.Points))
+		if err != nil {
+			return ErrInvalidAmount
+		}
+		if amount < 0 && balance.Amount < 0 {
 			return ErrInsufficientBalance
 		}
 
 		// Update balance
-		wallet.Balance += amount
+		wallet.Balance = int(balance.Amount)
 		if err := tx.Save(&wallet).Error; err != nil {
 			return err
 		}
 
 		// Create transaction record
 		transaction := model.Transaction{
+			TenantID:    wallet.TenantID,
 			WalletID:    wallet.ID,
 			Type:        txType,
+			Currency:    model.CurrencyPoints,
 			Amount:      amount,
 			Description: description,
 			ReferenceID: referenceID,
@@ -220,6 +356,15 @@ func (s *WalletService) AddTransaction(userID uint, txType model.TransactionType
 	})
 }
 
+// lockWallet loads the wallet of a user within tx and locks its row until
+// the transaction ends (SELECT ... FOR UPDATE), so concurrent debits and
+// credits of the same wallet apply one after the other instead of
+// overwriting each other's balance. Every read-modify-write of a balance
+// goes through it.
+func lockWallet(tx *gorm.DB, userID uint, wallet *model.Wallet) error {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).First(wallet).Error
+}
+
 // Deduct deducts points from user's wallet (for purchases)
 func (s *WalletService) Deduct(userID uint, amount int, txType model.TransactionType, description string, referenceID uint) error {
 	if amount <= 0 {
@@ -236,6 +381,152 @@ func (s *WalletService) Credit(userID uint, amount int, txType model.Transaction
 	return s.AddTransaction(userID, txType, amount, description, referenceID)
 }
 
+// GetTicketBalance returns what a user can spend on tickets: the points and
+// the bonus credits together
+func (s *WalletService) GetTicketBalance(userID uint) (int, error) {
+	points, bonus, err := s.GetBalances(userID)
+	return points + bonus, err
+}
+
+// GetBalances returns the points and the bonus credits of a user
+func (s *WalletService) GetBalances(userID uint) (points, bonus int, err error) {
+	var wallet model.Wallet
+	if err := s.db.Where("user_id = ?", userID).First(&wallet).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, 0, ErrWalletNotFound
+		}
+		return 0, 0, err
+	}
+	return wallet.Balance, wallet.BonusBalance, nil
+}
+
+// TicketPayment is how a ticket purchase is paid
+type TicketPayment struct {
+	Points     int  `json:"points"`
+	Bonus      int  `json:"bonus"`
+	PurchaseID uint `json:"-"` // Transaction the charges of the purchase reference
+}
+
+// SplitTicketPayment splits the cost of tickets between points and bonus
+// credits in the spend priority of the service. The balances must cover it.
+func (s *WalletService) SplitTicketPayment(points, bonus, amount int) TicketPayment {
+	var payment TicketPayment
+	if s.bonusPriority == BonusSpendLast {
+		payment.Points = min(max(points, 0), amount)
+		payment.Bonus = amount - payment.Points
+	} else {
+		payment.Bonus = min(max(bonus, 0), amount)
+		payment.Points = amount - payment.Bonus
+	}
+	return payment
+}
+
+// ticketSpendTypes are the transaction types booked for ticket purchases.
+// A purchase paid from both balances books one of each.
+var ticketSpendTypes = []model.TransactionType{model.TransactionTypePurchase, model.TransactionTypeBonusPurchase}
+
+// splitChargeSQL matches the second half of a ticket purchase paid from both
+// balances. PayForTickets has both halves reference the transaction of the
+// first, so each purchase is matched once by the row referencing itself.
+const splitChargeSQL = "transactions.reference_id <> 0 AND transactions.reference_id <> transactions.id"
+
+// PayForTickets deducts the cost of tickets from the points and bonus
+// credits of a user, in the spend priority of the service. Each balance
+// drawn on is booked as its own transaction, and both reference the first of
+// them, which is the purchase ID of the returned payment.
+func (s *WalletService) PayForTickets(userID uint, amount int, description string) (*TicketPayment, error) {
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	var payment TicketPayment
+	err := repository.Transaction(s.db, func(tx *gorm.DB) error {
+		var wallet model.Wallet
+		if err := lockWallet(tx, userID, &wallet); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrWalletNotFound
+			}
+			return err
+		}
+		if wallet.FrozenAt != nil {
+			return ErrWalletFrozen
+		}
+		if wallet.Balance+wallet.BonusBalance < amount {
+			return ErrInsufficientBalance
+		}
+
+		payment = s.SplitTicketPayment(wallet.Balance, wallet.BonusBalance, amount)
+		if err := tx.Model(&wallet).Updates(map[string]interface{}{
+			"balance":       wallet.Balance - payment.Points,
+			"bonus_balance": wallet.BonusBalance - payment.Bonus,
+		}).Error; err != nil {
+			return err
+		}
+
+		for _, part := range []struct {
+			txType   model.TransactionType
+			currency model.WalletCurrency
+			amount   int
+		}{
+			{model.TransactionTypePurchase, model.CurrencyPoints, payment.Points},
+			{model.TransactionTypeBonusPurchase, model.CurrencyBonus, payment.Bonus},
+		} {
+			if part.amount == 0 {
+				continue
+			}
+			charge := model.Transaction{
+				TenantID:    wallet.TenantID,
+				WalletID:    wallet.ID,
+				Type:        part.txType,
+				Currency:    part.currency,
+				Amount:      -part.amount,
+				Description: description,
+				ReferenceID: payment.PurchaseID,
+			}
+			if err := tx.Create(&charge).Error; err != nil {
+				return err
+			}
+			if payment.PurchaseID == 0 {
+				payment.PurchaseID = charge.ID
+				if err := tx.Model(&charge).Update("reference_id", charge.ID).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// grantBonus credits bonus credits of a promotion to the wallet of a user
+// within tx
+func grantBonus(tx *gorm.DB, userID uint, amount int, description string, referenceID uint) error {
+	if amount <= 0 {
+		return ErrInvalidAmount
+	}
+	var wallet model.Wallet
+	if err := lockWallet(tx, userID, &wallet); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrWalletNotFound
+		}
+		return err
+	}
+	if err := tx.Model(&wallet).Update("bonus_balance", gorm.Expr("bonus_balance + ?", amount)).Error; err != nil {
+		return err
+	}
+	return tx.Create(&model.Transaction{
+		TenantID:    wallet.TenantID,
+		WalletID:    wallet.ID,
+		Type:        model.TransactionTypeBonusGrant,
+		Currency:    model.CurrencyBonus,
+		Amount:      amount,
+		Description: description,
+		ReferenceID: referenceID,
+	}).Error
+}
+
 // HasSufficientBalance checks if user has enough balance
 func (s *WalletService) HasSufficientBalance(userID uint, amount int) (bool, error) {
 	balance, err := s.GetBalance(userID)
@@ -252,6 +543,7 @@ func (s *WalletService) toWalletResponse(wallet *model.Wallet, transactions []mo
 		ID:           wallet.ID,
 		UserID:       wallet.UserID,
 		Balance:      wallet.Balance,
+		BonusBalance: wallet.BonusBalance,
 		Transactions: s.toTransactionResponses(transactions),
 		CreatedAt:    wallet.CreatedAt,
 		UpdatedAt:    wallet.UpdatedAt,
@@ -264,6 +556,7 @@ func (s *WalletService) toTransactionResponses(transactions []model.Transaction)
 		responses[i] = TransactionResponse{
 			ID:          tx.ID,
 			Type:        tx.Type,
+			Currency:    tx.Currency,
 			Amount:      tx.Amount,
 			Description: tx.Description,
 			ReferenceID: tx.ReferenceID,
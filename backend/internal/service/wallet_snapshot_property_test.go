@@ -0,0 +1,112 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+)
+
+// Balance snapshots: the scheduled capture of a past day and the on-demand
+// ledger recomputation agree on the end-of-day balance, and today is live.
+func TestWalletBalanceHistory(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("scheduled and ledger balances match the transactions", prop.ForAll(
+		func(amounts []int, captureDay int) bool {
+			db := setupTestDB(t)
+			if err := db.AutoMigrate(&model.WalletBalanceSnapshot{}); err != nil {
+				t.Logf("Failed to migrate: %v", err)
+				return false
+			}
+
+			now := time.Now()
+			today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+			days := len(amounts)
+			wallet := model.Wallet{UserID: 1, Balance: 0}
+			wallet.CreatedAt = today.AddDate(0, 0, -days)
+			if err := db.Create(&wallet).Error; err != nil {
+				return false
+			}
+
+			// One transaction at noon of each day, the last one today
+			expected := make(map[string]int)
+			balance := 0
+			for i, amount := range amounts {
+				day := today.AddDate(0, 0, i-days+1)
+				balance += amount
+				expected[day.Format("2006-01-02")] = balance
+				tx := model.Transaction{WalletID: wallet.ID, Type: model.TransactionTypeRecharge, Amount: amount, CreatedAt: day.Add(12 * time.Hour)}
+				if err := db.Create(&tx).Error; err != nil {
+					return false
+				}
+			}
+			db.Model(&wallet).Update("balance", balance)
+
+			snapshots := NewWalletSnapshotService(db)
+			captured := today.AddDate(0, 0, -(captureDay%days)-1)
+			if n, err := snapshots.CaptureDay(captured); err != nil || n != 1 {
+				t.Logf("CaptureDay: %d (err %v)", n, err)
+				return false
+			}
+			if n, _ := snapshots.CaptureDay(captured); n != 0 {
+				t.Logf("Capturing the same day twice should be a no-op")
+				return false
+			}
+
+			history, err := snapshots.GetBalanceHistory(1, BalanceHistoryQuery{
+				StartDate: today.AddDate(0, 0, -days-5).Format("2006-01-02"),
+			})
+			if err != nil {
+				t.Logf("GetBalanceHistory failed: %v", err)
+				return false
+			}
+			// The day the wallet was created has no transaction yet
+			if len(history.Points) != days+1 {
+				t.Logf("Expected %d points, got %d", days+1, len(history.Points))
+				return false
+			}
+			for _, point := range history.Points {
+				want, ok := expected[point.Date]
+				if !ok {
+					want = 0
+				}
+				if point.Balance != want {
+					t.Logf("%s: expected %d, got %d (%s)", point.Date, want, point.Balance, point.Source)
+					return false
+				}
+				switch point.Date {
+				case today.Format("2006-01-02"):
+					if point.Source != BalanceSourceLive {
+						return false
+					}
+				case captured.Format("2006-01-02"):
+					if point.Source != model.BalanceSnapshotSourceScheduled {
+						return false
+					}
+				default:
+					if point.Source != model.BalanceSnapshotSourceLedger {
+						return false
+					}
+				}
+			}
+
+			// Recomputed days are stored; today never is
+			var stored int64
+			db.Model(&model.WalletBalanceSnapshot{}).Count(&stored)
+			return stored == int64(days)
+		},
+		gen.SliceOfN(6, gen.IntRange(-50, 200)).SuchThat(func(v []int) bool { return len(v) > 0 }),
+		gen.IntRange(0, 10),
+	))
+
+	properties.TestingRun(t)
+}
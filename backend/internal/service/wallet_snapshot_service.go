@@ -0,0 +1,273 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/internal/repository"
+	"scratch-lottery/pkg/biztime"
+	"scratch-lottery/pkg/logger"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BalanceSourceLive marks today's balance, which is read from the wallet and never stored
+const BalanceSourceLive = "live"
+
+// maxBalanceHistoryDays limits the selectable balance history range
+const maxBalanceHistoryDays = 366
+
+// WalletSnapshotService captures end-of-day wallet balances and serves the
+// balance history used to settle disputes. Days without a snapshot are
+// recomputed from the transaction ledger on demand.
+type WalletSnapshotService struct {
+	db *gorm.DB
+}
+
+// NewWalletSnapshotService creates a new wallet snapshot service
+func NewWalletSnapshotService(db *gorm.DB) *WalletSnapshotService {
+	return &WalletSnapshotService{db: db}
+}
+
+// ForTenant returns a copy of the service restricted to a tenant
+func (s *WalletSnapshotService) ForTenant(tenantID uint) *WalletSnapshotService {
+	return &WalletSnapshotService{db: repository.ScopeTenant(s.db, tenantID)}
+}
+
+// BalanceHistoryQuery represents query parameters for the balance history
+type BalanceHistoryQuery struct {
+	StartDate string `form:"start_date"` // Format: 2006-01-02, default 29 days before end
+	EndDate   string `form:"end_date"`   // Format: 2006-01-02, default today
+}
+
+// BalancePoint represents the balance at the end of a day
+type BalancePoint struct {
+	Date    string `json:"date"`
+	Balance int    `json:"balance"`
+	Source  string `json:"source"` // scheduled, ledger or live
+}
+
+// BalanceHistoryResponse represents a wallet's daily balance series
+type BalanceHistoryResponse struct {
+	UserID    uint           `json:"user_id"`
+	WalletID  uint           `json:"wallet_id"`
+	StartDate string         `json:"start_date"`
+	EndDate   string         `json:"end_date"`
+	Points    []BalancePoint `json:"points"`
+}
+
+// CaptureDay snapshots the end-of-day balance of every wallet for the given
+// day. Wallets already captured for that day are skipped. Returns the number
+// of snapshots written.
+func (s *WalletSnapshotService) CaptureDay(day time.Time) (int, error) {
+	dayStart := biztime.DayStart(day)
+	dayEnd := dayStart.AddDate(0, 0, 1)
+	date := dayStart.Format("2006-01-02")
+
+	var wallets []model.Wallet
+	if err := s.db.Where("created_at < ?", dayEnd).
+		Where("id NOT IN (?)", s.db.Model(&model.WalletBalanceSnapshot{}).Select("wallet_id").Where("date = ?", date)).
+		Find(&wallets).Error; err != nil {
+		return 0, err
+	}
+	if len(wallets) == 0 {
+		return 0, nil
+	}
+
+	// The balance at the end of the day is the current balance minus everything booked since
+	var later []struct {
+		WalletID uint
+		Total    int
+	}
+	if err := repository.TransactionsBetween(s.db, dayEnd, time.Time{}).
+		Select("wallet_id, COALESCE(SUM(amount), 0) as total").
+		Where("currency = ?", model.CurrencyPoints).
+		Group("wallet_id").
+		Scan(&later).Error; err != nil {
+		return 0, err
+	}
+	laterByWallet := make(map[uint]int, len(later))
+	for _, row := range later {
+		laterByWallet[row.WalletID] = row.Total
+	}
+
+	snapshots := make([]model.WalletBalanceSnapshot, len(wallets))
+	for i, wallet := range wallets {
+		snapshots[i] = model.WalletBalanceSnapshot{
+			WalletID: wallet.ID,
+			Date:     date,
+			Balance:  wallet.Balance - laterByWallet[wallet.ID],
+			Source:   model.BalanceSnapshotSourceScheduled,
+		}
+	}
+	result := s.db.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&snapshots, 200)
+	return int(result.RowsAffected), result.Error
+}
+
+// Start captures yesterday's balances every interval until the returned stop
+// func is called. Capturing is idempotent, so the job only does real work
+// once per day.
+func (s *WalletSnapshotService) Start(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			captured, err := s.CaptureDay(time.Now().AddDate(0, 0, -1))
+			if err != nil {
+				logger.Default().Warn("Capturing wallet balance snapshots failed: %v", err)
+			} else if captured > 0 {
+				logger.Default().Info("Captured %d wallet balance snapshots", captured)
+			}
+
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// GetBalanceHistory returns the user's end-of-day balances for the requested
+// range. Missing snapshots are recomputed from the ledger and stored; today
+// is reported from the live balance.
+func (s *WalletSnapshotService) GetBalanceHistory(userID uint, query BalanceHistoryQuery) (*BalanceHistoryResponse, error) {
+	var wallet model.Wallet
+	if err := s.db.Where("user_id = ?", userID).First(&wallet).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWalletNotFound
+		}
+		return nil, err
+	}
+
+	today := biztime.DayStart(time.Now())
+	endDate := today
+	if query.EndDate != "" {
+		if t, err := biztime.ParseDate(query.EndDate); err == nil {
+			endDate = t
+		}
+	}
+	startDate := endDate.AddDate(0, 0, -29)
+	if query.StartDate != "" {
+		if t, err := biztime.ParseDate(query.StartDate); err == nil {
+			startDate = t
+		}
+	}
+	if startDate.After(endDate) {
+		startDate, endDate = endDate, startDate
+	}
+	if endDate.After(today) {
+		endDate = today
+	}
+	if endDate.Sub(startDate) > maxBalanceHistoryDays*24*time.Hour {
+		startDate = endDate.AddDate(0, 0, -(maxBalanceHistoryDays - 1))
+	}
+
+	resp := &BalanceHistoryResponse{
+		UserID:    userID,
+		WalletID:  wallet.ID,
+		StartDate: startDate.Format("2006-01-02"),
+		EndDate:   endDate.Format("2006-01-02"),
+		Points:    []BalancePoint{},
+	}
+
+	// No balance exists before the wallet was created
+	firstDay := biztime.DayStart(wallet.CreatedAt)
+	if startDate.Before(firstDay) {
+		startDate = firstDay
+	}
+	if startDate.After(endDate) {
+		return resp, nil
+	}
+
+	var snapshots []model.WalletBalanceSnapshot
+	if err := s.db.Where("wallet_id = ? AND date >= ? AND date <= ?",
+		wallet.ID, startDate.Format("2006-01-02"), endDate.Format("2006-01-02")).
+		Find(&snapshots).Error; err != nil {
+		return nil, err
+	}
+	byDate := make(map[string]model.WalletBalanceSnapshot, len(snapshots))
+	for _, snapshot := range snapshots {
+		byDate[snapshot.Date] = snapshot
+	}
+
+	ledger, err := s.newLedgerWalker(wallet.ID, startDate, endDate.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []model.WalletBalanceSnapshot
+	for day := startDate; !day.After(endDate); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		ledgerBalance := ledger.balanceBefore(day.AddDate(0, 0, 1))
+
+		if day.Equal(today) {
+			resp.Points = append(resp.Points, BalancePoint{Date: date, Balance: wallet.Balance, Source: BalanceSourceLive})
+			continue
+		}
+		if snapshot, ok := byDate[date]; ok {
+			resp.Points = append(resp.Points, BalancePoint{Date: date, Balance: snapshot.Balance, Source: snapshot.Source})
+			continue
+		}
+
+		snapshot := model.WalletBalanceSnapshot{
+			WalletID: wallet.ID,
+			Date:     date,
+			Balance:  ledgerBalance,
+			Source:   model.BalanceSnapshotSourceLedger,
+		}
+		missing = append(missing, snapshot)
+		resp.Points = append(resp.Points, BalancePoint{Date: date, Balance: snapshot.Balance, Source: snapshot.Source})
+	}
+
+	if len(missing) > 0 {
+		if err := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&missing).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
+// ledgerWalker replays a wallet's transactions in order to compute the
+// balance at successive points in time
+type ledgerWalker struct {
+	balance      int
+	transactions []model.Transaction
+	next         int
+}
+
+// newLedgerWalker loads the balance before from and the transactions up to until
+func (s *WalletSnapshotService) newLedgerWalker(walletID uint, from, until time.Time) (*ledgerWalker, error) {
+	var opening struct {
+		Total int
+	}
+	if err := s.db.Model(&model.Transaction{}).
+		Select("COALESCE(SUM(amount), 0) as total").
+		Where("wallet_id = ? AND currency = ? AND created_at < ?", walletID, model.CurrencyPoints, from).
+		Scan(&opening).Error; err != nil {
+		return nil, err
+	}
+
+	walker := &ledgerWalker{balance: opening.Total}
+	if err := s.db.Where("wallet_id = ? AND currency = ? AND created_at >= ? AND created_at < ?", walletID, model.CurrencyPoints, from, until).
+		Order("created_at ASC, id ASC").
+		Find(&walker.transactions).Error; err != nil {
+		return nil, err
+	}
+	return walker, nil
+}
+
+// balanceBefore advances the walker and returns the balance before t.
+// Calls must use non-decreasing times.
+func (w *ledgerWalker) balanceBefore(t time.Time) int {
+	for w.next < len(w.transactions) && w.transactions[w.next].CreatedAt.Before(t) {
+		w.balance += w.transactions[w.next].Amount
+		w.next++
+	}
+	return w.balance
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"scratch-lottery/internal/model"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+)
+
+// webhookReceiver records signed deliveries and can be told to fail
+type webhookReceiver struct {
+	mu       sync.Mutex
+	secret   string
+	fail     bool
+	received []WalletWebhookPayload
+	badSig   int
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fail {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	body, _ := io.ReadAll(req.Body)
+	var t, sig string
+	for _, part := range strings.Split(req.Header.Get(WebhookSignatureHeader), ",") {
+		if v, ok := strings.CutPrefix(part, "t="); ok {
+			t = v
+		} else if v, ok := strings.CutPrefix(part, "v1="); ok {
+			sig = v
+		}
+	}
+	if sig != SignWebhookPayload(r.secret, t, body) {
+		r.badSig++
+	}
+	var payload WalletWebhookPayload
+	_ = json.Unmarshal(body, &payload)
+	r.received = append(r.received, payload)
+	w.WriteHeader(http.StatusOK)
+}
+
+// Wallet webhooks: only transactions passing the filters are delivered, each
+// exactly once and correctly signed; failures are retried and replay resends.
+func TestWalletWebhookDelivery(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("filtered, signed, delivered once, replayable", prop.ForAll(
+		func(amounts []int, minAmount int) bool {
+			db := setupLotteryTestDB(t)
+			if err := db.AutoMigrate(&model.WalletWebhook{}, &model.WebhookDelivery{}, &model.Tenant{}); err != nil {
+				t.Logf("Failed to migrate: %v", err)
+				return false
+			}
+
+			receiver := &webhookReceiver{secret: "test-secret"}
+			server := httptest.NewServer(receiver)
+			defer server.Close()
+
+			service := NewWalletWebhookService(db, 2)
+			webhook, err := service.CreateWebhook(CreateWalletWebhookRequest{
+				Name:      "erp",
+				URL:       server.URL,
+				Secret:    "test-secret",
+				Types:     []string{string(model.TransactionTypeRecharge)},
+				MinAmount: minAmount,
+			})
+			if err != nil {
+				t.Logf("CreateWebhook failed: %v", err)
+				return false
+			}
+
+			wallet := model.Wallet{UserID: 7}
+			db.Create(&wallet)
+			past := time.Now().Add(-time.Minute)
+			want := 0
+			for i, amount := range amounts {
+				txType := model.TransactionTypeRecharge
+				if i%3 == 2 {
+					txType = model.TransactionTypePurchase
+				} else if amount >= minAmount {
+					want++
+				}
+				db.Create(&model.Transaction{WalletID: wallet.ID, Type: txType, Amount: amount, CreatedAt: past})
+			}
+
+			// Enqueue twice: the cursor must prevent duplicates
+			service.Enqueue()
+			service.Enqueue()
+			if _, err := service.Dispatch(); err != nil {
+				t.Logf("Dispatch failed: %v", err)
+				return false
+			}
+			service.Dispatch()
+
+			if len(receiver.received) != want || receiver.badSig != 0 {
+				t.Logf("Received %d (want %d), bad signatures %d", len(receiver.received), want, receiver.badSig)
+				return false
+			}
+			for _, p := range receiver.received {
+				if p.UserID != 7 || p.Type != model.TransactionTypeRecharge || p.Amount < minAmount {
+					t.Logf("Unexpected payload: %+v", p)
+					return false
+				}
+			}
+
+			// Receiver goes down: replayed deliveries fail after the max attempts
+			receiver.fail = true
+			replayed, err := service.Replay(webhook.ID, ReplayWebhookRequest{StartTime: past.Add(-time.Second), EndTime: time.Now()})
+			if err != nil || replayed != want {
+				t.Logf("Replay: %d (want %d), err %v", replayed, want, err)
+				return false
+			}
+			service.Dispatch()
+			db.Model(&model.WebhookDelivery{}).Where("1 = 1").Update("next_attempt_at", past)
+			service.Dispatch()
+
+			var failed int64
+			db.Model(&model.WebhookDelivery{}).Where("status = ?", model.WebhookDeliveryStatusFailed).Count(&failed)
+			return failed == int64(want)
+		},
+		gen.SliceOf(gen.IntRange(1, 200)),
+		gen.IntRange(0, 150),
+	))
+
+	properties.TestingRun(t)
+}
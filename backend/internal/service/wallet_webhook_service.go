@@ -0,0 +1,612 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"scratch-lottery/internal/model"
+	"scratch-lottery/pkg/logger"
+	"scratch-lottery/pkg/redact"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrWebhookNotFound        = errors.New("webhook not found")
+	ErrInvalidTransactionType = errors.New("invalid transaction type")
+	ErrInvalidReplayWindow    = errors.New("invalid replay window")
+)
+
+// Wallet webhook request headers
+const (
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookSignatureHeader = "X-Webhook-Signature" // t=<unix>,v1=<hex HMAC-SHA256 of "<t>.<body>">
+)
+
+// WalletWebhookEvent is the event name sent for every mirrored transaction
+const WalletWebhookEvent = "wallet.transaction.created"
+
+const (
+	// webhookEnqueueBatch caps how many transaction ids one enqueue pass scans per webhook
+	webhookEnqueueBatch = 1000
+	// webhookDispatchBatch caps how many deliveries one dispatch pass sends
+	webhookDispatchBatch = 100
+	// webhookSettleDelay skips very recent transactions so rows committed out of
+	// id order by concurrent transactions are not skipped by the cursor
+	webhookSettleDelay = 5 * time.Second
+	webhookRetryBase   = 30 * time.Second
+	webhookRetryMax    = time.Hour
+)
+
+// WalletWebhookService mirrors wallet transactions to external systems.
+// Transactions are copied into a delivery outbox per webhook and then sent
+// by the dispatcher with exponential-backoff retries.
+type WalletWebhookService struct {
+	db          *gorm.DB
+	httpClient  *http.Client
+	maxAttempts int
+}
+
+// NewWalletWebhookService creates a new wallet webhook service.
+// maxAttempts is the number of delivery attempts before a delivery is marked failed.
+func NewWalletWebhookService(db *gorm.DB, maxAttempts int) *WalletWebhookService {
+	if maxAttempts < 1 {
+		maxAttempts = 8
+	}
+	return &WalletWebhookService{
+		db:          db,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+		maxAttempts: maxAttempts,
+	}
+}
+
+// CreateWalletWebhookRequest represents a request to create a wallet webhook
+type CreateWalletWebhookRequest struct {
+	Name      string   `json:"name" binding:"required"`
+	URL       string   `json:"url" binding:"required,url"`
+	Secret    string   `json:"secret"` // Generated when empty
+	Types     []string `json:"types"`
+	MinAmount int      `json:"min_amount" binding:"gte=0"`
+}
+
+// UpdateWalletWebhookRequest represents a request to update a wallet webhook
+type UpdateWalletWebhookRequest struct {
+	Name      *string   `json:"name"`
+	URL       *string   `json:"url" binding:"omitempty,url"`
+	Secret    *string   `json:"secret"`
+	Types     *[]string `json:"types"`
+	MinAmount *int      `json:"min_amount" binding:"omitempty,gte=0"`
+	Enabled   *bool     `json:"enabled"`
+}
+
+// WalletWebhookResponse represents a wallet webhook in the response
+type WalletWebhookResponse struct {
+	ID                uint      `json:"id"`
+	Name              string    `json:"name"`
+	URL               string    `json:"url"`
+	Secret            string    `json:"secret"` // Masked except right after creation
+	Types             []string  `json:"types"`
+	MinAmount         int       `json:"min_amount"`
+	Enabled           bool      `json:"enabled"`
+	LastTransactionID uint      `json:"last_transaction_id"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// WalletWebhookPayload is the JSON body posted for each transaction
+type WalletWebhookPayload struct {
+	Event         string                `json:"event" gorm:"-"`
+	TransactionID uint                  `json:"transaction_id" gorm:"column:id"`
+	WalletID      uint                  `json:"wallet_id"`
+	UserID        uint                  `json:"user_id"`
+	Type          model.TransactionType `json:"type"`
+	Currency      model.WalletCurrency  `json:"currency"`
+	Amount        int                   `json:"amount"`
+	Description   string                `json:"description"`
+	ReferenceID   uint                  `json:"reference_id,omitempty"`
+	CreatedAt     time.Time             `json:"created_at"`
+}
+
+// ReplayWebhookRequest represents a request to resend the transactions of a time window
+type ReplayWebhookRequest struct {
+	StartTime time.Time `json:"start_time" binding:"required"`
+	EndTime   time.Time `json:"end_time" binding:"required"`
+}
+
+// WebhookDeliveryQuery represents query parameters for listing deliveries
+type WebhookDeliveryQuery struct {
+	Status string `form:"status"`
+	Page   int    `form:"page"`
+	Limit  int    `form:"limit"`
+}
+
+// WebhookDeliveryListResponse represents paginated webhook deliveries
+type WebhookDeliveryListResponse struct {
+	Deliveries []model.WebhookDelivery `json:"deliveries"`
+	Total      int64                   `json:"total"`
+	Page       int                     `json:"page"`
+	Limit      int                     `json:"limit"`
+	TotalPages int                     `json:"total_pages"`
+}
+
+// ==================== Webhook Management ====================
+
+// CreateWebhook creates a wallet webhook. Only transactions created after
+// this point are mirrored; use Replay to backfill older ones.
+func (s *WalletWebhookService) CreateWebhook(req CreateWalletWebhookRequest) (*WalletWebhookResponse, error) {
+	types, err := normalizeTransactionTypes(req.Types)
+	if err != nil {
+		return nil, err
+	}
+	secret := req.Secret
+	if secret == "" {
+		if secret, err = generateWebhookSecret(); err != nil {
+			return nil, err
+		}
+	}
+
+	var lastID uint
+	if err := s.db.Model(&model.Transaction{}).Select("COALESCE(MAX(id), 0)").Scan(&lastID).Error; err != nil {
+		return nil, err
+	}
+
+	webhook := model.WalletWebhook{
+		Name:              req.Name,
+		URL:               req.URL,
+		Secret:            secret,
+		Types:             types,
+		MinAmount:         req.MinAmount,
+		Enabled:           true,
+		LastTransactionID: lastID,
+	}
+	if err := s.db.Create(&webhook).Error; err != nil {
+		return nil, err
+	}
+
+	resp := toWalletWebhookResponse(&webhook)
+	resp.Secret = webhook.Secret // Shown once so the receiver can be configured
+	return resp, nil
+}
+
+// GetWebhooks lists all wallet webhooks
+func (s *WalletWebhookService) GetWebhooks() ([]WalletWebhookResponse, error) {
+	var webhooks []model.WalletWebhook
+	if err := s.db.Order("created_at ASC").Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+	responses := make([]WalletWebhookResponse, len(webhooks))
+	for i := range webhooks {
+		responses[i] = *toWalletWebhookResponse(&webhooks[i])
+	}
+	return responses, nil
+}
+
+// UpdateWebhook updates a wallet webhook. A masked secret is ignored.
+func (s *WalletWebhookService) UpdateWebhook(id uint, req UpdateWalletWebhookRequest) (*WalletWebhookResponse, error) {
+	webhook, err := s.getWebhook(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		webhook.Name = *req.Name
+	}
+	if req.URL != nil {
+		webhook.URL = *req.URL
+	}
+	if req.Secret != nil && *req.Secret != "" && !redact.IsMasked(*req.Secret) {
+		webhook.Secret = *req.Secret
+	}
+	if req.Types != nil {
+		types, err := normalizeTransactionTypes(*req.Types)
+		if err != nil {
+			return nil, err
+		}
+		webhook.Types = types
+	}
+	if req.MinAmount != nil {
+		webhook.MinAmount = *req.MinAmount
+	}
+	if req.Enabled != nil {
+		webhook.Enabled = *req.Enabled
+	}
+
+	if err := s.db.Save(webhook).Error; err != nil {
+		return nil, err
+	}
+	return toWalletWebhookResponse(webhook), nil
+}
+
+// DeleteWebhook deletes a wallet webhook (soft delete); its pending deliveries are dropped
+func (s *WalletWebhookService) DeleteWebhook(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&model.WalletWebhook{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrWebhookNotFound
+		}
+		return tx.Where("webhook_id = ? AND status = ?", id, model.WebhookDeliveryStatusPending).
+			Delete(&model.WebhookDelivery{}).Error
+	})
+}
+
+// GetDeliveries lists the deliveries of a webhook, newest first
+func (s *WalletWebhookService) GetDeliveries(webhookID uint, query WebhookDeliveryQuery) (*WebhookDeliveryListResponse, error) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit < 1 || query.Limit > 100 {
+		query.Limit = 20
+	}
+
+	dbQuery := s.db.Model(&model.WebhookDelivery{}).Where("webhook_id = ?", webhookID)
+	if query.Status != "" {
+		dbQuery = dbQuery.Where("status = ?", query.Status)
+	}
+
+	var total int64
+	if err := dbQuery.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	var deliveries []model.WebhookDelivery
+	offset := (query.Page - 1) * query.Limit
+	if err := dbQuery.Order("id DESC").Offset(offset).Limit(query.Limit).Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+
+	totalPages := int(total) / query.Limit
+	if int(total)%query.Limit > 0 {
+		totalPages++
+	}
+
+	return &WebhookDeliveryListResponse{
+		Deliveries: deliveries,
+		Total:      total,
+		Page:       query.Page,
+		Limit:      query.Limit,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// ==================== Outbox and Dispatcher ====================
+
+// Start enqueues and dispatches deliveries every interval until the returned stop func is called
+func (s *WalletWebhookService) Start(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if _, err := s.Enqueue(); err != nil {
+					logger.Default().Warn("Wallet webhook enqueue failed: %v", err)
+				}
+				if _, err := s.Dispatch(); err != nil {
+					logger.Default().Warn("Wallet webhook dispatch failed: %v", err)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// Enqueue copies new transactions matching each enabled webhook's filters
+// into the delivery outbox and advances the webhook cursors
+func (s *WalletWebhookService) Enqueue() (int, error) {
+	var webhooks []model.WalletWebhook
+	if err := s.db.Where("enabled = ?", true).Find(&webhooks).Error; err != nil {
+		return 0, err
+	}
+
+	enqueued := 0
+	for i := range webhooks {
+		webhook := &webhooks[i]
+
+		var highWater uint
+		if err := s.db.Model(&model.Transaction{}).
+			Where("id > ? AND id <= ? AND created_at <= ?", webhook.LastTransactionID,
+				webhook.LastTransactionID+webhookEnqueueBatch, time.Now().Add(-webhookSettleDelay)).
+			Select("COALESCE(MAX(id), 0)").Scan(&highWater).Error; err != nil {
+			return enqueued, err
+		}
+		if highWater <= webhook.LastTransactionID {
+			continue
+		}
+
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			var payloads []WalletWebhookPayload
+			if err := s.matchingTransactions(tx, webhook).
+				Where("transactions.id > ? AND transactions.id <= ?", webhook.LastTransactionID, highWater).
+				Scan(&payloads).Error; err != nil {
+				return err
+			}
+			deliveries, err := newWebhookDeliveries(webhook.ID, payloads)
+			if err != nil {
+				return err
+			}
+			if len(deliveries) > 0 {
+				if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&deliveries).Error; err != nil {
+					return err
+				}
+			}
+			enqueued += len(deliveries)
+			return tx.Model(webhook).Update("last_transaction_id", highWater).Error
+		})
+		if err != nil {
+			return enqueued, err
+		}
+	}
+
+	return enqueued, nil
+}
+
+// Dispatch sends due deliveries, scheduling retries with exponential backoff
+// and marking deliveries failed after the maximum attempts
+func (s *WalletWebhookService) Dispatch() (int, error) {
+	var deliveries []model.WebhookDelivery
+	if err := s.db.Where("status = ? AND next_attempt_at <= ?", model.WebhookDeliveryStatusPending, time.Now()).
+		Order("id ASC").
+		Limit(webhookDispatchBatch).
+		Find(&deliveries).Error; err != nil {
+		return 0, err
+	}
+
+	webhooks := make(map[uint]*model.WalletWebhook)
+	delivered := 0
+	for i := range deliveries {
+		delivery := &deliveries[i]
+
+		webhook, ok := webhooks[delivery.WebhookID]
+		if !ok {
+			var w model.WalletWebhook
+			if err := s.db.First(&w, delivery.WebhookID).Error; err != nil {
+				if !errors.Is(err, gorm.ErrRecordNotFound) {
+					return delivered, err
+				}
+			} else {
+				webhook = &w
+			}
+			webhooks[delivery.WebhookID] = webhook
+		}
+		if webhook == nil || !webhook.Enabled {
+			// Disabled webhooks keep their backlog until re-enabled
+			continue
+		}
+
+		now := time.Now()
+		updates := map[string]interface{}{"attempts": delivery.Attempts + 1}
+		if err := s.send(webhook, delivery); err != nil {
+			updates["last_error"] = truncateString(err.Error(), 512)
+			if delivery.Attempts+1 >= s.maxAttempts {
+				updates["status"] = model.WebhookDeliveryStatusFailed
+			} else {
+				updates["next_attempt_at"] = now.Add(webhookRetryDelay(delivery.Attempts + 1))
+			}
+		} else {
+			updates["status"] = model.WebhookDeliveryStatusDelivered
+			updates["delivered_at"] = now
+			updates["last_error"] = ""
+			delivered++
+		}
+		if err := s.db.Model(delivery).Updates(updates).Error; err != nil {
+			return delivered, err
+		}
+	}
+
+	return delivered, nil
+}
+
+// Replay re-queues every matching transaction created within the window,
+// including ones that were already delivered or failed
+func (s *WalletWebhookService) Replay(webhookID uint, req ReplayWebhookRequest) (int, error) {
+	if !req.EndTime.After(req.StartTime) {
+		return 0, ErrInvalidReplayWindow
+	}
+	webhook, err := s.getWebhook(webhookID)
+	if err != nil {
+		return 0, err
+	}
+
+	replayed := 0
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var payloads []WalletWebhookPayload
+		if err := s.matchingTransactions(tx, webhook).
+			Where("transactions.created_at >= ? AND transactions.created_at < ?", req.StartTime, req.EndTime).
+			Scan(&payloads).Error; err != nil {
+			return err
+		}
+		deliveries, err := newWebhookDeliveries(webhook.ID, payloads)
+		if err != nil {
+			return err
+		}
+		if len(deliveries) == 0 {
+			return nil
+		}
+		replayed = len(deliveries)
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "webhook_id"}, {Name: "transaction_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"payload", "status", "attempts", "next_attempt_at", "last_error", "delivered_at", "updated_at"}),
+		}).CreateInBatches(&deliveries, 200).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return replayed, nil
+}
+
+// matchingTransactions builds the query of transactions passing the webhook
+// filters. The test points of sandbox tenants are never delivered.
+func (s *WalletWebhookService) matchingTransactions(tx *gorm.DB, webhook *model.WalletWebhook) *gorm.DB {
+	query := tx.Table("transactions").
+		Select("transactions.id, transactions.wallet_id, wallets.user_id, transactions.type, transactions.currency, transactions.amount, transactions.description, transactions.reference_id, transactions.created_at").
+		Joins("LEFT JOIN wallets ON wallets.id = transactions.wallet_id").
+		Where("transactions.deleted_at IS NULL").
+		Where("transactions.tenant_id NOT IN (?)", sandboxTenants(tx)).
+		Order("transactions.id ASC")
+	if webhook.Types != "" {
+		query = query.Where("transactions.type IN ?", strings.Split(webhook.Types, ","))
+	}
+	if webhook.MinAmount > 0 {
+		query = query.Where("ABS(transactions.amount) >= ?", webhook.MinAmount)
+	}
+	return query
+}
+
+// send posts a delivery to the webhook URL with an HMAC signature
+func (s *WalletWebhookService) send(webhook *model.WalletWebhook, delivery *model.WebhookDelivery) error {
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, strings.NewReader(delivery.Payload))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, WalletWebhookEvent)
+	req.Header.Set(WebhookDeliveryHeader, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(WebhookSignatureHeader, "t="+timestamp+",v1="+SignWebhookPayload(webhook.Secret, timestamp, []byte(delivery.Payload)))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SignWebhookPayload returns the hex HMAC-SHA256 of "<timestamp>.<body>".
+// Receivers recompute it with the shared secret to verify a delivery.
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ==================== Helper Functions ====================
+
+func (s *WalletWebhookService) getWebhook(id uint) (*model.WalletWebhook, error) {
+	var webhook model.WalletWebhook
+	if err := s.db.First(&webhook, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// newWebhookDeliveries turns transactions into pending deliveries with payload snapshots
+func newWebhookDeliveries(webhookID uint, payloads []WalletWebhookPayload) ([]model.WebhookDelivery, error) {
+	now := time.Now()
+	deliveries := make([]model.WebhookDelivery, 0, len(payloads))
+	for _, payload := range payloads {
+		payload.Event = WalletWebhookEvent
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, model.WebhookDelivery{
+			WebhookID:     webhookID,
+			TransactionID: payload.TransactionID,
+			Payload:       string(body),
+			Status:        model.WebhookDeliveryStatusPending,
+			NextAttemptAt: now,
+		})
+	}
+	return deliveries, nil
+}
+
+func toWalletWebhookResponse(webhook *model.WalletWebhook) *WalletWebhookResponse {
+	types := []string{}
+	if webhook.Types != "" {
+		types = strings.Split(webhook.Types, ",")
+	}
+	return &WalletWebhookResponse{
+		ID:                webhook.ID,
+		Name:              webhook.Name,
+		URL:               webhook.URL,
+		Secret:            redact.Secret(webhook.Secret),
+		Types:             types,
+		MinAmount:         webhook.MinAmount,
+		Enabled:           webhook.Enabled,
+		LastTransactionID: webhook.LastTransactionID,
+		CreatedAt:         webhook.CreatedAt,
+		UpdatedAt:         webhook.UpdatedAt,
+	}
+}
+
+// normalizeTransactionTypes validates transaction types and joins them for storage
+func normalizeTransactionTypes(types []string) (string, error) {
+	valid := map[model.TransactionType]bool{
+		model.TransactionTypeInitial:       true,
+		model.TransactionTypeRecharge:      true,
+		model.TransactionTypePurchase:      true,
+		model.TransactionTypeWin:           true,
+		model.TransactionTypeExchange:      true,
+		model.TransactionTypeStreakBonus:   true,
+		model.TransactionTypeCheckin:       true,
+		model.TransactionTypeOnboarding:    true,
+		model.TransactionTypeRefund:        true,
+		model.TransactionTypeBonusGrant:    true,
+		model.TransactionTypeBonusPurchase: true,
+	}
+	var normalized []string
+	for _, t := range types {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		if !valid[model.TransactionType(t)] {
+			return "", ErrInvalidTransactionType
+		}
+		normalized = append(normalized, t)
+	}
+	return strings.Join(normalized, ","), nil
+}
+
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// webhookRetryDelay returns the backoff before the next attempt
+func webhookRetryDelay(attempts int) time.Duration {
+	delay := webhookRetryBase
+	for i := 1; i < attempts && delay < webhookRetryMax; i++ {
+		delay *= 2
+	}
+	if delay > webhookRetryMax {
+		delay = webhookRetryMax
+	}
+	return delay
+}
+
+func truncateString(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
@@ -28,6 +28,8 @@ type Claims struct {
 	LinuxdoID string    `json:"linuxdo_id"`
 	Username  string    `json:"username"`
 	Role      string    `json:"role"`
+	TenantID  uint      `json:"tenant_id,omitempty"` // 0 for tokens issued before multi-tenancy (default tenant)
+	SessionID string    `json:"sid,omitempty"`       // Login session the token belongs to, empty for tokens without one
 	TokenType TokenType `json:"token_type"`
 	jwt.RegisteredClaims
 }
@@ -50,12 +52,23 @@ func NewJWTManager(secret string, accessExpiryMinutes, refreshExpiryDays int) *J
 
 // GenerateTokenPair generates both access and refresh tokens
 func (m *JWTManager) GenerateTokenPair(userID uint, linuxdoID, username, role string) (accessToken, refreshToken string, err error) {
-	accessToken, err = m.generateToken(userID, linuxdoID, username, role, AccessToken, m.accessExpiry)
+	return m.GenerateTenantTokenPair(0, userID, linuxdoID, username, role)
+}
+
+// GenerateTenantTokenPair generates both access and refresh tokens for a user of a tenant
+func (m *JWTManager) GenerateTenantTokenPair(tenantID, userID uint, linuxdoID, username, role string) (accessToken, refreshToken string, err error) {
+	return m.GenerateSessionTokenPair("", tenantID, userID, linuxdoID, username, role)
+}
+
+// GenerateSessionTokenPair generates both access and refresh tokens for a
+// user of a tenant, bound to a login session
+func (m *JWTManager) GenerateSessionTokenPair(sessionID string, tenantID, userID uint, linuxdoID, username, role string) (accessToken, refreshToken string, err error) {
+	accessToken, err = m.generateToken(sessionID, tenantID, userID, linuxdoID, username, role, AccessToken, m.accessExpiry, jwt.RegisteredClaims{})
 	if err != nil {
 		return "", "", err
 	}
 
-	refreshToken, err = m.generateToken(userID, linuxdoID, username, role, RefreshToken, m.refreshExpiry)
+	refreshToken, err = m.generateToken(sessionID, tenantID, userID, linuxdoID, username, role, RefreshToken, m.refreshExpiry, jwt.RegisteredClaims{})
 	if err != nil {
 		return "", "", err
 	}
@@ -63,20 +76,31 @@ func (m *JWTManager) GenerateTokenPair(userID uint, linuxdoID, username, role st
 	return accessToken, refreshToken, nil
 }
 
-// generateToken creates a JWT token
-func (m *JWTManager) generateToken(userID uint, linuxdoID, username, role string, tokenType TokenType, expiry time.Duration) (string, error) {
+// GenerateAudienceToken generates an access token for a separate realm,
+// stamped with its issuer and audience and valid for expiry
+func (m *JWTManager) GenerateAudienceToken(tenantID, userID uint, linuxdoID, username, role, issuer, audience string, expiry time.Duration) (string, error) {
+	return m.generateToken("", tenantID, userID, linuxdoID, username, role, AccessToken, expiry, jwt.RegisteredClaims{
+		Issuer:   issuer,
+		Audience: jwt.ClaimStrings{audience},
+	})
+}
+
+// generateToken creates a JWT token. The issuer and audience of registered
+// are kept, the timestamps are set here.
+func (m *JWTManager) generateToken(sessionID string, tenantID, userID uint, linuxdoID, username, role string, tokenType TokenType, expiry time.Duration, registered jwt.RegisteredClaims) (string, error) {
 	now := time.Now()
+	registered.ExpiresAt = jwt.NewNumericDate(now.Add(expiry))
+	registered.IssuedAt = jwt.NewNumericDate(now)
+	registered.NotBefore = jwt.NewNumericDate(now)
 	claims := &Claims{
-		UserID:    userID,
-		LinuxdoID: linuxdoID,
-		Username:  username,
-		Role:      role,
-		TokenType: tokenType,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(expiry)),
-			IssuedAt:  jwt.NewNumericDate(now),
-			NotBefore: jwt.NewNumericDate(now),
-		},
+		UserID:           userID,
+		LinuxdoID:        linuxdoID,
+		Username:         username,
+		Role:             role,
+		TenantID:         tenantID,
+		SessionID:        sessionID,
+		TokenType:        tokenType,
+		RegisteredClaims: registered,
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -107,6 +131,19 @@ func (m *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
 	return claims, nil
 }
 
+// HasAudience reports whether the token was issued by issuer for audience
+func (c *Claims) HasAudience(issuer, audience string) bool {
+	if c.Issuer != issuer {
+		return false
+	}
+	for _, aud := range c.Audience {
+		if aud == audience {
+			return true
+		}
+	}
+	return false
+}
+
 // GetAccessExpiry returns the access token expiry duration in seconds
 func (m *JWTManager) GetAccessExpiry() int64 {
 	return int64(m.accessExpiry.Seconds())
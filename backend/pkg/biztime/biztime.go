@@ -0,0 +1,89 @@
+// Package biztime counts days, weeks and months in the business time zone of
+// the deployment.
+//
+// Times are stored and compared in UTC. Whenever a time is bucketed by
+// calendar (daily statistics, check-in days, "today" on the dashboard), it is
+// first converted to the business time zone with the helpers here, so every
+// service agrees on where a day starts regardless of the server's zone.
+package biztime
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// DateLayout is the layout of business dates, such as check-in days
+const DateLayout = "2006-01-02"
+
+var location atomic.Pointer[time.Location]
+
+func init() {
+	location.Store(time.UTC)
+}
+
+// SetLocation sets the business time zone, UTC until set. It is set once at
+// startup.
+func SetLocation(loc *time.Location) {
+	location.Store(loc)
+}
+
+// Location returns the business time zone
+func Location() *time.Location {
+	return location.Load()
+}
+
+// Now returns the current time in the business time zone
+func Now() time.Time {
+	return time.Now().In(Location())
+}
+
+// In returns t in the business time zone
+func In(t time.Time) time.Time {
+	return t.In(Location())
+}
+
+// DayStart returns the start of the business day of t
+func DayStart(t time.Time) time.Time {
+	t = In(t)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// WeekStart returns the start of the ISO week (Monday) of t
+func WeekStart(t time.Time) time.Time {
+	day := DayStart(t)
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+// MonthStart returns the start of the month of t
+func MonthStart(t time.Time) time.Time {
+	t = In(t)
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
+
+// Date returns the business date of t, such as "2024-05-01"
+func Date(t time.Time) string {
+	return In(t).Format(DateLayout)
+}
+
+// WeekKey returns the ISO week of t, such as "2024-W18"
+func WeekKey(t time.Time) string {
+	year, week := In(t).ISOWeek()
+	return fmt.Sprintf("%04d-W%02d", year, week)
+}
+
+// MonthKey returns the month of t, such as "2024-05"
+func MonthKey(t time.Time) string {
+	return In(t).Format("2006-01")
+}
+
+// ParseDate parses a business date and returns the start of that day
+func ParseDate(s string) (time.Time, error) {
+	return time.ParseInLocation(DateLayout, s, Location())
+}
+
+// SQLZone returns the IANA name of the business time zone for SQL
+// conversions, such as PostgreSQL's AT TIME ZONE
+func SQLZone() string {
+	return Location().String()
+}
@@ -0,0 +1,57 @@
+package biztime
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucketsFollowTheBusinessZone(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	SetLocation(shanghai)
+	defer SetLocation(time.UTC)
+
+	// 2024-04-30 17:30 UTC is already May 1st in Shanghai
+	at := time.Date(2024, 4, 30, 17, 30, 0, 0, time.UTC)
+	if got := Date(at); got != "2024-05-01" {
+		t.Errorf("Date: expected 2024-05-01, got %s", got)
+	}
+	if got := DayStart(at); !got.Equal(time.Date(2024, 4, 30, 16, 0, 0, 0, time.UTC)) {
+		t.Errorf("DayStart: expected 16:00 UTC the day before, got %s", got.UTC())
+	}
+	if got := MonthKey(at); got != "2024-05" {
+		t.Errorf("MonthKey: expected 2024-05, got %s", got)
+	}
+	if got := MonthStart(at); !got.Equal(DayStart(at)) {
+		t.Errorf("MonthStart: expected May 1st, got %s", got)
+	}
+	// May 1st 2024 is the Wednesday of ISO week 18
+	if got := WeekKey(at); got != "2024-W18" {
+		t.Errorf("WeekKey: expected 2024-W18, got %s", got)
+	}
+	if got := WeekStart(at); got.Weekday() != time.Monday || Date(got) != "2024-04-29" {
+		t.Errorf("WeekStart: expected Monday 2024-04-29, got %s", got)
+	}
+	if got, err := ParseDate("2024-05-01"); err != nil || !got.Equal(DayStart(at)) {
+		t.Errorf("ParseDate: expected the start of May 1st, got %s (err %v)", got, err)
+	}
+	if SQLZone() != "Asia/Shanghai" {
+		t.Errorf("SQLZone: expected Asia/Shanghai, got %s", SQLZone())
+	}
+}
+
+func TestWeekKeyAtYearBoundaries(t *testing.T) {
+	cases := map[time.Time]string{
+		time.Date(2020, 12, 31, 12, 0, 0, 0, time.UTC): "2020-W53",
+		time.Date(2021, 1, 3, 12, 0, 0, 0, time.UTC):   "2020-W53",
+		time.Date(2021, 1, 4, 12, 0, 0, 0, time.UTC):   "2021-W01",
+		time.Date(2024, 12, 30, 12, 0, 0, 0, time.UTC): "2025-W01",
+	}
+	for at, want := range cases {
+		if got := WeekKey(at); got != want {
+			t.Errorf("WeekKey(%s): expected %s, got %s", at.Format(DateLayout), want, got)
+		}
+	}
+}
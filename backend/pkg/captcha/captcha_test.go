@@ -0,0 +1,56 @@
+package captcha
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewSelectsProvider(t *testing.T) {
+	for _, provider := range []string{"", "none", "NONE"} {
+		c, err := New(provider, "")
+		if err != nil || c.Name() != ProviderNone {
+			t.Errorf("Expected the none provider for %q, got %v (err %v)", provider, c, err)
+		}
+	}
+	c, err := New("Turnstile", "secret")
+	if err != nil || c.Name() != ProviderTurnstile {
+		t.Errorf("Expected the turnstile provider, got %v (err %v)", c, err)
+	}
+	if _, err := New("hcaptcha", ""); err != ErrMissingSecret {
+		t.Errorf("Expected ErrMissingSecret, got %v", err)
+	}
+	if _, err := New("geetest", "secret"); !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("Expected ErrUnknownProvider, got %v", err)
+	}
+}
+
+func TestSiteVerify(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("ParseForm failed: %v", err)
+		}
+		if r.PostForm.Get("secret") != "secret" || r.PostForm.Get("remoteip") != "10.0.0.1" {
+			t.Errorf("Unexpected siteverify form %v", r.PostForm)
+		}
+		if r.PostForm.Get("response") == "good" {
+			w.Write([]byte(`{"success":true}`))
+			return
+		}
+		w.Write([]byte(`{"success":false,"error-codes":["invalid-input-response"]}`))
+	}))
+	defer server.Close()
+
+	c := NewSiteVerify(ProviderTurnstile, server.URL, "secret")
+	if err := c.Verify(context.Background(), "good", "10.0.0.1"); err != nil {
+		t.Errorf("Expected a valid token, got %v", err)
+	}
+	if err := c.Verify(context.Background(), "bad", "10.0.0.1"); !errors.Is(err, ErrVerifyFailed) {
+		t.Errorf("Expected ErrVerifyFailed, got %v", err)
+	}
+	if err := c.Verify(context.Background(), "", "10.0.0.1"); err != ErrMissingToken {
+		t.Errorf("Expected ErrMissingToken, got %v", err)
+	}
+}
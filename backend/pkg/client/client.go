@@ -0,0 +1,147 @@
+// Package client is a thin typed client for the HTTP API, for the Telegram
+// bot and command line tools. Its types and methods are generated into
+// api.gen.go from the OpenAPI document in api/openapi.json, together with the
+// TypeScript client of the frontend; this file only sends the requests and
+// decodes the standard response envelope.
+package client
+
+//go:generate go run ../../cmd/clientgen -spec ../../api/openapi.json -go api.gen.go -ts ../../../frontend/src/api/generated.ts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TenantHeader selects a tenant by slug, as the server's tenant middleware does
+const TenantHeader = "X-Tenant"
+
+// Error is an error response of the API
+type Error struct {
+	StatusCode int
+	Code       int    `json:"code"`
+	Message    string `json:"message"`
+	Details    string `json:"details,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if e.Details != "" {
+		return fmt.Sprintf("api error %d (%d): %s: %s", e.Code, e.StatusCode, e.Message, e.Details)
+	}
+	return fmt.Sprintf("api error %d (%d): %s", e.Code, e.StatusCode, e.Message)
+}
+
+// Client calls the API at a base URL such as https://lottery.example.com/api.
+// It is safe for concurrent use once configured.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	token      string
+	tenant     string
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used for requests
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// WithToken sets the access token sent with every request
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// WithTenant selects the tenant by slug
+func WithTenant(slug string) Option {
+	return func(c *Client) { c.tenant = slug }
+}
+
+// New creates a client for the API at baseURL
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// WithAccessToken returns a copy of the client that sends token, such as the
+// token of the user a bot acts for
+func (c *Client) WithAccessToken(token string) *Client {
+	scoped := *c
+	scoped.token = token
+	return &scoped
+}
+
+// do sends a request and decodes the data of the response envelope into out
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	endpoint := c.baseURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.tenant != "" {
+		req.Header.Set(TenantHeader, c.tenant)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		apiErr := &Error{StatusCode: resp.StatusCode}
+		if err := json.Unmarshal(data, apiErr); err != nil || apiErr.Message == "" {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+		return apiErr
+	}
+
+	envelope := struct {
+		Code    int             `json:"code"`
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
+	}{}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	if out == nil || len(envelope.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(envelope.Data, out)
+}
@@ -0,0 +1,72 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientDecodesEnvelope(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token-1" || r.Header.Get(TenantHeader) != "second" {
+			t.Errorf("Expected the token and tenant headers, got %v", r.Header)
+		}
+		switch r.URL.Path {
+		case "/api/lottery/purchase":
+			var req PurchaseRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.LotteryTypeID != 3 || req.Quantity != 2 {
+				t.Errorf("Unexpected purchase request %+v (err %v)", req, err)
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"code":0,"message":"success","data":{"tickets":[{"id":7,"status":"unscratched"}],"cost":20,"balance":30}}`))
+		case "/api/wallet":
+			if r.URL.Query().Get("version") != "2" {
+				t.Errorf("Expected the version 2 wallet, got %q", r.URL.RawQuery)
+			}
+			w.Write([]byte(`{"code":0,"message":"success","data":{"id":1,"balance":30,"balances":{"available":25,"pending":0,"held":5}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	c := New(server.URL+"/api/", WithToken("token-1"), WithTenant("second"))
+	purchase, err := c.PurchaseTickets(context.Background(), PurchaseRequest{LotteryTypeID: 3, Quantity: 2})
+	if err != nil {
+		t.Fatalf("PurchaseTickets failed: %v", err)
+	}
+	if len(purchase.Tickets) != 1 || purchase.Tickets[0].ID != 7 || purchase.Balance != 30 {
+		t.Errorf("Unexpected purchase %+v", purchase)
+	}
+	wallet, err := c.GetWallet(context.Background(), &GetWalletParams{Version: 2})
+	if err != nil {
+		t.Fatalf("GetWallet failed: %v", err)
+	}
+	if wallet.Balances == nil || wallet.Balances.Held != 5 {
+		t.Errorf("Expected the balance breakdown, got %+v", wallet)
+	}
+}
+
+func TestClientReturnsAPIErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer user-token" {
+			t.Errorf("Expected the scoped token, got %q", r.Header.Get("Authorization"))
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code":3004,"message":"彩票已刮开"}`))
+	}))
+	defer server.Close()
+
+	c := New(server.URL, WithToken("bot-token")).WithAccessToken("user-token")
+	_, err := c.ScratchTicket(context.Background(), 7, ScratchConfirmRequest{})
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected an API error, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusBadRequest || apiErr.Code != 3004 || apiErr.Message != "彩票已刮开" {
+		t.Errorf("Unexpected API error %+v", apiErr)
+	}
+}
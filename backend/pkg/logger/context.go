@@ -0,0 +1,30 @@
+package logger
+
+import "context"
+
+type requestIDContextKey struct{}
+
+// ContextWithRequestID returns a copy of ctx carrying the request id, so it
+// follows the request into the service layer.
+func ContextWithRequestID(ctx context.Context, rid string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, rid)
+}
+
+// RequestIDFromContext returns the request id carried by ctx, or "".
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	rid, _ := ctx.Value(requestIDContextKey{}).(string)
+	return rid
+}
+
+// FromContext returns the default logger, tagging every message with the
+// request id carried by ctx so service logs can be correlated with the
+// access log line of the request.
+func FromContext(ctx context.Context) *Logger {
+	if rid := RequestIDFromContext(ctx); rid != "" {
+		return Default().With(F("request_id", rid))
+	}
+	return Default()
+}
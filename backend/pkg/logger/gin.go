@@ -6,6 +6,7 @@ import (
 	"runtime/debug"
 	"time"
 
+	"scratch-lottery/pkg/redact"
 	"scratch-lottery/pkg/response"
 
 	"github.com/gin-gonic/gin"
@@ -14,7 +15,10 @@ import (
 
 const (
 	RequestIDHeader = "X-Request-Id"
-	requestIDKey    = "requestID"
+	requestIDKey    = response.RequestIDKey
+
+	// maxRequestIDLength bounds request ids taken from the client
+	maxRequestIDLength = 128
 )
 
 // GinRequestID ensures each request has a request id, and mirrors it back via `X-Request-Id`.
@@ -34,14 +38,30 @@ func ensureRequestID(c *gin.Context) string {
 	}
 
 	rid := c.GetHeader(RequestIDHeader)
-	if rid == "" {
+	if !validRequestID(rid) {
 		rid = uuid.NewString()
 	}
 	c.Set(requestIDKey, rid)
+	c.Request = c.Request.WithContext(ContextWithRequestID(c.Request.Context(), rid))
 	c.Header(RequestIDHeader, rid)
 	return rid
 }
 
+// validRequestID reports whether a client supplied request id can be logged
+// and echoed as is. Anything else is replaced, so clients cannot inject
+// line breaks or oversized values into the logs.
+func validRequestID(rid string) bool {
+	if rid == "" || len(rid) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(rid); i++ {
+		if rid[i] < 0x21 || rid[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
 // GinLogger returns a gin middleware for logging requests.
 func GinLogger() gin.HandlerFunc {
 	log := Default().WithPrefix("HTTP")
@@ -52,7 +72,7 @@ func GinLogger() gin.HandlerFunc {
 		rid := ensureRequestID(c)
 
 		path := c.Request.URL.Path
-		raw := c.Request.URL.RawQuery
+		raw := redact.Query(c.Request.URL.RawQuery)
 		if raw != "" {
 			path = path + "?" + raw
 		}
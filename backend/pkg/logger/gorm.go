@@ -50,13 +50,17 @@ func (l *GormLogger) Error(ctx context.Context, msg string, data ...interface{})
 func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
 	elapsed := time.Since(begin)
 	sql, rows := fc()
+	log := l.log
+	if rid := RequestIDFromContext(ctx); rid != "" {
+		log = log.With(F("request_id", rid))
+	}
 
 	// Only log slow queries or errors
 	switch {
 	case err != nil && (!l.IgnoreRecordNotFound || err != logger.ErrRecordNotFound):
-		l.log.Error("Query error: %v | %s | rows=%d | time=%v", err, truncateSQL(sql), rows, elapsed)
+		log.Error("Query error: %v | %s | rows=%d | time=%v", err, truncateSQL(sql), rows, elapsed)
 	case elapsed > l.SlowThreshold && l.SlowThreshold != 0:
-		l.log.Warn("Slow query: %s | rows=%d | time=%v", truncateSQL(sql), rows, elapsed)
+		log.Warn("Slow query: %s | rows=%d | time=%v", truncateSQL(sql), rows, elapsed)
 	// Skip debug logs in production for cleaner output
 	}
 }
@@ -10,6 +10,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"scratch-lottery/pkg/redact"
 )
 
 // Level represents log level
@@ -57,6 +59,8 @@ type core struct {
 	format        Format
 	timeFormat    string
 	includeCaller bool
+	redactor      *redact.Redactor
+	sync          func() error // flushes the log file, nil when logging to stdout or stderr
 }
 
 // Logger is the main logger struct.
@@ -64,6 +68,7 @@ type core struct {
 type Logger struct {
 	core   *core
 	prefix string
+	fields []Field // Attached to every message, e.g. the request id
 }
 
 type Field struct {
@@ -105,6 +110,7 @@ func New() *Logger {
 		format:        TextFormat,
 		timeFormat:    "2006-01-02 15:04:05.000",
 		includeCaller: false,
+		redactor:      redact.Default(),
 	}
 	for _, opt := range optionsFromEnv() {
 		opt(c)
@@ -171,7 +177,7 @@ func parseBoolEnv(v string) (bool, bool) {
 	}
 }
 
-func openLogFile(filePath string) (io.Writer, func() error, error) {
+func openLogFile(filePath string) (*os.File, func() error, error) {
 	if filePath == "" {
 		return nil, nil, fmt.Errorf("LOG_FILE is empty")
 	}
@@ -227,6 +233,12 @@ func optionsFromEnv() []Option {
 		opts = append(opts, func(c *core) { c.colored = false })
 	}
 
+	// Field names containing these words are masked, in addition to the defaults
+	if v := os.Getenv("LOG_REDACT_KEYS"); v != "" {
+		r := redact.WithKeys(redact.ParseKeys(v)...)
+		opts = append(opts, func(c *core) { c.redactor = r })
+	}
+
 	// Outputs
 	output := strings.ToLower(strings.TrimSpace(os.Getenv("LOG_OUTPUT")))
 	logFile := strings.TrimSpace(os.Getenv("LOG_FILE"))
@@ -235,21 +247,21 @@ func optionsFromEnv() []Option {
 	}
 	switch output {
 	case "stderr":
-		opts = append(opts, func(c *core) { c.output = os.Stderr })
+		opts = append(opts, func(c *core) { c.output, c.sync = os.Stderr, nil })
 	case "file":
 		if logFile != "" {
-			if w, _, err := openLogFile(logFile); err == nil {
-				opts = append(opts, func(c *core) { c.output = w })
+			if f, _, err := openLogFile(logFile); err == nil {
+				opts = append(opts, func(c *core) { c.output, c.sync = f, f.Sync })
 			}
 		}
 	case "both":
 		if logFile != "" {
-			if w, _, err := openLogFile(logFile); err == nil {
-				opts = append(opts, func(c *core) { c.output = io.MultiWriter(os.Stdout, w) })
+			if f, _, err := openLogFile(logFile); err == nil {
+				opts = append(opts, func(c *core) { c.output, c.sync = io.MultiWriter(os.Stdout, f), f.Sync })
 			}
 		}
 	default:
-		opts = append(opts, func(c *core) { c.output = os.Stdout })
+		opts = append(opts, func(c *core) { c.output, c.sync = os.Stdout, nil })
 	}
 
 	// JSON logs should never include ANSI colors
@@ -262,6 +274,16 @@ func optionsFromEnv() []Option {
 	return opts
 }
 
+// Sync flushes the log file to disk. Call it before the process exits.
+func (l *Logger) Sync() error {
+	l.core.mu.Lock()
+	defer l.core.mu.Unlock()
+	if l.core.sync == nil {
+		return nil
+	}
+	return l.core.sync()
+}
+
 // SetLevel sets the log level.
 func (l *Logger) SetLevel(level Level) {
 	l.core.mu.Lock()
@@ -274,6 +296,7 @@ func (l *Logger) SetOutput(w io.Writer) {
 	l.core.mu.Lock()
 	defer l.core.mu.Unlock()
 	l.core.output = w
+	l.core.sync = nil
 }
 
 // SetColored enables/disables colored output.
@@ -293,6 +316,19 @@ func (l *Logger) WithPrefix(prefix string) *Logger {
 	return &Logger{
 		core:   l.core,
 		prefix: prefix,
+		fields: l.fields,
+	}
+}
+
+// With returns a logger that attaches the given fields to every message.
+func (l *Logger) With(fields ...Field) *Logger {
+	merged := make([]Field, 0, len(l.fields)+len(fields))
+	merged = append(merged, l.fields...)
+	merged = append(merged, fields...)
+	return &Logger{
+		core:   l.core,
+		prefix: l.prefix,
+		fields: merged,
 	}
 }
 
@@ -316,6 +352,10 @@ func (l *Logger) logw(level Level, msg string, fields []Field) {
 
 	ts := time.Now().Format(l.core.timeFormat)
 	levelStr := levelNames[level]
+	if len(l.fields) > 0 {
+		fields = append(append([]Field{}, l.fields...), fields...)
+	}
+	fields = redactFields(l.core.redactor, fields)
 
 	if l.core.format == JSONFormat {
 		payload := map[string]any{
@@ -342,7 +382,7 @@ func (l *Logger) logw(level Level, msg string, fields []Field) {
 			_, _ = l.core.output.Write([]byte(fmt.Sprintf("%s | %-5s | %s\n", ts, levelStr, msg)))
 		}
 		if level == FatalLevel {
-			os.Exit(1)
+			l.exit()
 		}
 		return
 	}
@@ -355,8 +395,30 @@ func (l *Logger) logw(level Level, msg string, fields []Field) {
 	_, _ = l.core.output.Write([]byte(line))
 
 	if level == FatalLevel {
-		os.Exit(1)
+		l.exit()
+	}
+}
+
+// exit flushes the log file and exits after a fatal message. The caller
+// holds the core lock.
+func (l *Logger) exit() {
+	if l.core.sync != nil {
+		_ = l.core.sync()
+	}
+	os.Exit(1)
+}
+
+// redactFields masks sensitive field values (secrets, tokens, keys, passwords),
+// including ones nested inside maps and structs.
+func redactFields(r *redact.Redactor, fields []Field) []Field {
+	if r == nil || len(fields) == 0 {
+		return fields
+	}
+	out := make([]Field, len(fields))
+	for i, f := range fields {
+		out[i] = Field{Key: f.Key, Value: r.Value(f.Key, f.Value)}
 	}
+	return out
 }
 
 func formatTextLine(ts string, level Level, levelStr string, prefix string, msg string, caller string, colored bool, fields []Field) string {
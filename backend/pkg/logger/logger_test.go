@@ -0,0 +1,166 @@
+package logger
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"scratch-lottery/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newTestLogger(format Format) (*Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	l := New()
+	l.Configure(func(c *core) {
+		c.level = DebugLevel
+		c.output = &buf
+		c.format = format
+		c.colored = false
+		c.includeCaller = false
+	})
+	return l, &buf
+}
+
+type epayConfig struct {
+	MerchantID string `json:"merchant_id"`
+	Secret     string `json:"secret"`
+}
+
+func TestJSONLogsNeverContainSecrets(t *testing.T) {
+	const secret = "super-secret-value"
+	l, buf := newTestLogger(JSONFormat)
+
+	l.Infow("settings updated",
+		F("epay_secret", secret),
+		F("access_token", secret),
+		F("details", map[string]any{
+			"config": epayConfig{MerchantID: "1001", Secret: secret},
+			"nested": []any{map[string]any{"password": secret}},
+		}),
+		F("user_id", 42),
+	)
+
+	out := buf.String()
+	if strings.Contains(out, secret) {
+		t.Fatalf("secret leaked into JSON log: %s", out)
+	}
+	if !strings.Contains(out, `"merchant_id":"1001"`) || !strings.Contains(out, `"user_id":42`) {
+		t.Fatalf("non-sensitive fields should be kept: %s", out)
+	}
+}
+
+func TestTextLogsNeverContainSecrets(t *testing.T) {
+	const secret = "super-secret-value"
+	l, buf := newTestLogger(TextFormat)
+
+	l.Warnw("login", F("refresh_token", secret), F("config", epayConfig{Secret: secret}))
+
+	if out := buf.String(); strings.Contains(out, secret) {
+		t.Fatalf("secret leaked into text log: %s", out)
+	}
+}
+
+func TestRedactKeysFromEnv(t *testing.T) {
+	t.Setenv("LOG_REDACT_KEYS", "state")
+	l, buf := newTestLogger(JSONFormat)
+	l.Configure(optionsFromEnv()...)
+	l.SetOutput(buf)
+
+	l.Infow("oauth", F("oauth_state", "abc-state"))
+
+	if out := buf.String(); strings.Contains(out, "abc-state") {
+		t.Fatalf("configured key should be redacted: %s", out)
+	}
+}
+
+func TestSyncFlushesLogFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "backend.log")
+	t.Setenv("LOG_OUTPUT", "file")
+	t.Setenv("LOG_FILE", path)
+	t.Setenv("LOG_FORMAT", "text")
+
+	l := New()
+	l.Info("shutting down")
+	if err := l.Sync(); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+
+	out, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading log file: %v", err)
+	}
+	if !strings.Contains(string(out), "shutting down") {
+		t.Fatalf("log file should contain the message: %s", out)
+	}
+}
+
+func TestWithAttachesFieldsToEveryMessage(t *testing.T) {
+	l, buf := newTestLogger(JSONFormat)
+
+	tagged := l.With(F("request_id", "req-1")).WithPrefix("PAY")
+	tagged.Warn("callback for order %s failed", "R1")
+	tagged.Infow("order paid", F("order_no", "R1"))
+	l.Info("untagged")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d: %s", len(lines), buf.String())
+	}
+	for _, line := range lines[:2] {
+		if !strings.Contains(line, `"request_id":"req-1"`) {
+			t.Fatalf("tagged message lost its fields: %s", line)
+		}
+	}
+	if !strings.Contains(lines[1], `"order_no":"R1"`) {
+		t.Fatalf("message fields should be kept: %s", lines[1])
+	}
+	if strings.Contains(lines[2], "request_id") {
+		t.Fatalf("With must not change the parent logger: %s", lines[2])
+	}
+}
+
+func TestRequestIDReachesContextAndErrorResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinRequestID())
+	var fromContext string
+	r.GET("/fail", func(c *gin.Context) {
+		fromContext = RequestIDFromContext(c.Request.Context())
+		response.BadRequest(c, "参数错误")
+	})
+
+	cases := map[string]bool{
+		"client-id-123":          true,
+		"":                       false,
+		"evil\nlevel=error":      false,
+		strings.Repeat("x", 129): false,
+	}
+	for incoming, kept := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/fail", nil)
+		if incoming != "" {
+			req.Header.Set(RequestIDHeader, incoming)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		rid := w.Header().Get(RequestIDHeader)
+		if kept && rid != incoming {
+			t.Fatalf("valid request id %q was replaced by %q", incoming, rid)
+		}
+		if !kept && (rid == "" || rid == incoming) {
+			t.Fatalf("invalid request id %q was not replaced: %q", incoming, rid)
+		}
+		if fromContext != rid {
+			t.Fatalf("request context carries %q, header %q", fromContext, rid)
+		}
+		if !strings.Contains(w.Body.String(), `"request_id":"`+rid+`"`) {
+			t.Fatalf("response does not quote the request id: %s", w.Body.String())
+		}
+	}
+}
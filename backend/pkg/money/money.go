@@ -0,0 +1,262 @@
+// Package money represents amounts as integer minor units of a currency, so
+// that fees, percentages and conversions never go through floating point.
+//
+// Rounding is always explicit: operations that can produce a fraction of a
+// minor unit take a Rounding mode and apply it exactly once, on the final
+// result.
+package money
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrCurrencyMismatch = errors.New("money: currency mismatch")
+	ErrOverflow         = errors.New("money: amount out of range")
+	ErrInvalidAmount    = errors.New("money: invalid amount")
+	ErrInvalidRate      = errors.New("money: invalid rate")
+)
+
+// Currency identifies the unit of an amount
+type Currency string
+
+const (
+	// Points are the in-app currency. A point has no subunits, so the integer
+	// point columns already hold minor units.
+	Points Currency = "PTS"
+	// CNY is the currency of recharge payments, in fen
+	CNY Currency = "CNY"
+)
+
+// Exponent returns the number of decimal places of the currency's major unit
+func (c Currency) Exponent() int {
+	switch c {
+	case CNY:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// Rounding selects how a fraction of a minor unit is resolved
+type Rounding int
+
+const (
+	// RoundDown truncates toward zero. Use it when paying out, so the system
+	// never credits more than it received.
+	RoundDown Rounding = iota
+	// RoundUp rounds away from zero. Use it when charging fees.
+	RoundUp
+	// RoundHalfUp rounds to the nearest minor unit, halves away from zero
+	RoundHalfUp
+	// RoundHalfEven rounds to the nearest minor unit, halves to the even one.
+	// Use it for reporting, where rounding bias would add up.
+	RoundHalfEven
+)
+
+// Money is an amount in minor units of a currency
+type Money struct {
+	Amount   int64    `json:"amount"`
+	Currency Currency `json:"currency"`
+}
+
+// New returns an amount of minor units
+func New(minor int64, currency Currency) Money {
+	return Money{Amount: minor, Currency: currency}
+}
+
+// FromMajor returns an amount of whole major units (e.g. yuan)
+func FromMajor(major int64, currency Currency) (Money, error) {
+	minor, ok := mul64(major, pow10(currency.Exponent()))
+	if !ok {
+		return Money{}, ErrOverflow
+	}
+	return New(minor, currency), nil
+}
+
+// Parse reads a decimal amount in major units, such as "12.50". Amounts with
+// more decimal places than the currency has are rejected instead of rounded.
+func Parse(s string, currency Currency) (Money, error) {
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	whole, fraction, _ := strings.Cut(s, ".")
+	exponent := currency.Exponent()
+	if whole == "" || len(fraction) > exponent || !isDigits(whole) || !isDigits(fraction) {
+		return Money{}, ErrInvalidAmount
+	}
+	fraction += strings.Repeat("0", exponent-len(fraction))
+
+	minor, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return Money{}, ErrOverflow
+	}
+	if negative {
+		minor = -minor
+	}
+	return New(minor, currency), nil
+}
+
+// String formats the amount in major units with all of the currency's
+// decimal places, such as "12.50"
+func (m Money) String() string {
+	exponent := m.Currency.Exponent()
+	digits := strconv.FormatUint(abs64(m.Amount), 10)
+	if exponent > 0 {
+		if len(digits) <= exponent {
+			digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+	}
+	if m.Amount < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+// Format formats an amount of minor units, as stored in integer columns
+func Format(minor int64, currency Currency) string {
+	return New(minor, currency).String()
+}
+
+// IsZero reports whether the amount is zero
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// Add returns m + o
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	sum := m.Amount + o.Amount
+	if (o.Amount > 0 && sum < m.Amount) || (o.Amount < 0 && sum > m.Amount) {
+		return Money{}, ErrOverflow
+	}
+	return New(sum, m.Currency), nil
+}
+
+// Sub returns m - o
+func (m Money) Sub(o Money) (Money, error) {
+	if o.Amount == math.MinInt64 {
+		return Money{}, ErrOverflow
+	}
+	return m.Add(New(-o.Amount, o.Currency))
+}
+
+// Mul returns m * n, such as the price of n tickets
+func (m Money) Mul(n int64) (Money, error) {
+	product, ok := mul64(m.Amount, n)
+	if !ok {
+		return Money{}, ErrOverflow
+	}
+	return New(product, m.Currency), nil
+}
+
+// Scale returns m * num / den rounded to a minor unit, such as a percentage
+// fee (num/den = 3/100)
+func (m Money) Scale(num, den int64, rounding Rounding) (Money, error) {
+	if den == 0 {
+		return Money{}, ErrInvalidRate
+	}
+	n := new(big.Int).Mul(big.NewInt(m.Amount), big.NewInt(num))
+	amount, err := divRound(n, big.NewInt(den), rounding)
+	if err != nil {
+		return Money{}, err
+	}
+	return New(amount, m.Currency), nil
+}
+
+// Rate is a conversion ratio: one major unit of the source currency is worth
+// Num/Den major units of the target currency
+type Rate struct {
+	Num int64
+	Den int64
+}
+
+// Convert returns the amount in another currency at the given rate
+func (m Money) Convert(to Currency, rate Rate, rounding Rounding) (Money, error) {
+	if rate.Num <= 0 || rate.Den <= 0 {
+		return Money{}, ErrInvalidRate
+	}
+	n := new(big.Int).Mul(big.NewInt(m.Amount), big.NewInt(rate.Num))
+	n.Mul(n, big.NewInt(pow10(to.Exponent())))
+	d := new(big.Int).Mul(big.NewInt(rate.Den), big.NewInt(pow10(m.Currency.Exponent())))
+	amount, err := divRound(n, d, rounding)
+	if err != nil {
+		return Money{}, err
+	}
+	return New(amount, to), nil
+}
+
+// divRound divides n by a positive or negative d and rounds the quotient
+func divRound(n, d *big.Int, rounding Rounding) (int64, error) {
+	if d.Sign() < 0 {
+		n = new(big.Int).Neg(n)
+		d = new(big.Int).Neg(d)
+	}
+	q, r := new(big.Int).QuoRem(n, d, new(big.Int)) // truncated toward zero
+	if r.Sign() != 0 {
+		away := false
+		switch rounding {
+		case RoundUp:
+			away = true
+		case RoundHalfUp, RoundHalfEven:
+			twice := new(big.Int).Abs(r)
+			twice.Lsh(twice, 1)
+			switch twice.Cmp(d) {
+			case 1:
+				away = true
+			case 0:
+				away = rounding == RoundHalfUp || q.Bit(0) == 1
+			}
+		}
+		if away {
+			q.Add(q, big.NewInt(int64(n.Sign())))
+		}
+	}
+	if !q.IsInt64() {
+		return 0, ErrOverflow
+	}
+	return q.Int64(), nil
+}
+
+func mul64(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	product := a * b
+	if product/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, false
+	}
+	return product, true
+}
+
+func pow10(exponent int) int64 {
+	p := int64(1)
+	for i := 0; i < exponent; i++ {
+		p *= 10
+	}
+	return p
+}
+
+func abs64(v int64) uint64 {
+	if v < 0 {
+		return uint64(-(v + 1)) + 1
+	}
+	return uint64(v)
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,164 @@
+package money
+
+import (
+	"math"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+)
+
+// getMinSuccessfulTests returns the minimum number of successful tests for property testing.
+// Uses GOPTER_MIN_SUCCESSFUL_TESTS env var if set, otherwise defaults to 100.
+func getMinSuccessfulTests() int {
+	if val := os.Getenv("GOPTER_MIN_SUCCESSFUL_TESTS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 100
+}
+
+func TestParseAndString(t *testing.T) {
+	cases := []struct {
+		in       string
+		currency Currency
+		minor    int64
+		out      string
+	}{
+		{"12.50", CNY, 1250, "12.50"},
+		{"12.5", CNY, 1250, "12.50"},
+		{"12", CNY, 1200, "12.00"},
+		{"0.07", CNY, 7, "0.07"},
+		{"-0.07", CNY, -7, "-0.07"},
+		{"100", Points, 100, "100"},
+	}
+	for _, c := range cases {
+		m, err := Parse(c.in, c.currency)
+		if err != nil || m.Amount != c.minor || m.Currency != c.currency {
+			t.Errorf("Parse(%q, %s) = %+v, %v, want %d", c.in, c.currency, m, err, c.minor)
+			continue
+		}
+		if got := m.String(); got != c.out {
+			t.Errorf("String() of %q = %q, want %q", c.in, got, c.out)
+		}
+	}
+
+	for _, in := range []string{"", "abc", "1.234", "1,00", "1e3", ".5", "99999999999999999999"} {
+		if _, err := Parse(in, CNY); err == nil {
+			t.Errorf("Parse(%q) should fail", in)
+		}
+	}
+	if _, err := Parse("1.5", Points); err != ErrInvalidAmount {
+		t.Errorf("Parse of fractional points should fail, got %v", err)
+	}
+}
+
+func TestRounding(t *testing.T) {
+	cases := []struct {
+		amount   int64
+		num, den int64
+		rounding Rounding
+		want     int64
+	}{
+		{25, 1, 10, RoundDown, 2},
+		{25, 1, 10, RoundUp, 3},
+		{25, 1, 10, RoundHalfUp, 3},
+		{25, 1, 10, RoundHalfEven, 2},
+		{35, 1, 10, RoundHalfEven, 4},
+		{26, 1, 10, RoundHalfEven, 3},
+		{-25, 1, 10, RoundDown, -2},
+		{-25, 1, 10, RoundUp, -3},
+		{-25, 1, 10, RoundHalfUp, -3},
+		{-25, 1, 10, RoundHalfEven, -2},
+		{1000, 3, 100, RoundUp, 30},
+		{1001, 3, 100, RoundUp, 31},
+	}
+	for _, c := range cases {
+		got, err := New(c.amount, CNY).Scale(c.num, c.den, c.rounding)
+		if err != nil || got.Amount != c.want {
+			t.Errorf("Scale(%d, %d/%d, %d) = %d, %v, want %d", c.amount, c.num, c.den, c.rounding, got.Amount, err, c.want)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	// 1 yuan = 10 points: 12.34 yuan is 123.4 points, paid out as 123
+	points, err := New(1234, CNY).Convert(Points, Rate{Num: 10, Den: 1}, RoundDown)
+	if err != nil || points != New(123, Points) {
+		t.Errorf("Convert to points = %+v, %v", points, err)
+	}
+	// The other way round: 7 points are 0.70 yuan
+	yuan, err := New(7, Points).Convert(CNY, Rate{Num: 1, Den: 10}, RoundHalfEven)
+	if err != nil || yuan != New(70, CNY) {
+		t.Errorf("Convert to yuan = %+v, %v", yuan, err)
+	}
+	if _, err := New(1, CNY).Convert(Points, Rate{Num: 0, Den: 1}, RoundDown); err != ErrInvalidRate {
+		t.Errorf("Expected ErrInvalidRate, got %v", err)
+	}
+}
+
+func TestArithmeticErrors(t *testing.T) {
+	if _, err := New(1, CNY).Add(New(1, Points)); err != ErrCurrencyMismatch {
+		t.Errorf("Expected ErrCurrencyMismatch, got %v", err)
+	}
+	if _, err := New(math.MaxInt64, Points).Add(New(1, Points)); err != ErrOverflow {
+		t.Errorf("Expected ErrOverflow on Add, got %v", err)
+	}
+	if _, err := New(math.MinInt64, Points).Sub(New(1, Points)); err != ErrOverflow {
+		t.Errorf("Expected ErrOverflow on Sub, got %v", err)
+	}
+	if _, err := New(math.MaxInt64/2+1, Points).Mul(2); err != ErrOverflow {
+		t.Errorf("Expected ErrOverflow on Mul, got %v", err)
+	}
+	if _, err := FromMajor(math.MaxInt64/10, CNY); err != ErrOverflow {
+		t.Errorf("Expected ErrOverflow on FromMajor, got %v", err)
+	}
+}
+
+// Splitting an amount with RoundDown and giving the remainder to the last
+// share never creates or loses a minor unit, and formatting round-trips.
+func TestMoneyProperties(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("String and Parse round-trip", prop.ForAll(
+		func(minor int64) bool {
+			m := New(minor, CNY)
+			parsed, err := Parse(m.String(), CNY)
+			return err == nil && parsed == m
+		},
+		gen.Int64Range(-1e12, 1e12),
+	))
+
+	properties.Property("proportional shares add up to the whole", prop.ForAll(
+		func(total int64, weights []int64) bool {
+			whole := New(total, Points)
+			var weightSum int64
+			for _, w := range weights {
+				weightSum += w
+			}
+			sum := New(0, Points)
+			for _, w := range weights[:len(weights)-1] {
+				share, err := whole.Scale(w, weightSum, RoundDown)
+				if err != nil || share.Amount > total {
+					return false
+				}
+				sum, _ = sum.Add(share)
+			}
+			last, _ := whole.Sub(sum)
+			sum, _ = sum.Add(last)
+			return sum == whole && last.Amount >= 0
+		},
+		gen.Int64Range(0, 1e9),
+		gen.SliceOfN(4, gen.Int64Range(1, 100)),
+	))
+
+	properties.TestingRun(t)
+}
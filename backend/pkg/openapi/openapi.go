@@ -0,0 +1,372 @@
+// Package openapi builds an OpenAPI 3 document from the routes registered on
+// a gin router. Every route is listed; the operations described by the caller
+// add a summary, the security scheme and the request and response schemas,
+// which are derived from Go types by reflection.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Version is the OpenAPI version of the documents built
+const Version = "3.0.3"
+
+// Security is how an operation is authorized
+type Security int
+
+const (
+	// SecurityOptional accepts a bearer token but does not require one. Routes
+	// that are not described are listed with it.
+	SecurityOptional Security = iota
+	SecurityPublic
+	SecurityBearer
+	SecurityAPIKey
+)
+
+// Operation describes a route. Query is a struct bound from the query string
+// by its form tags, Body a JSON request body and Response the data of a
+// successful response, all given as zero values of their types.
+type Operation struct {
+	Summary  string
+	Security Security
+	Query    any
+	Body     any
+	Response any
+}
+
+// Info describes the API
+type Info struct {
+	Title       string
+	Description string
+	Version     string
+}
+
+// Envelope is the name of the schema every JSON response is wrapped in
+const Envelope = "Response"
+
+var (
+	pathParam = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+	nonWord   = regexp.MustCompile(`[^A-Za-z0-9]+`)
+)
+
+// Build returns the OpenAPI document of routes. operations are keyed by
+// method and path as registered, such as "GET /api/wallet".
+func Build(info Info, routes gin.RoutesInfo, operations map[string]Operation) map[string]any {
+	schemas := newSchemas()
+	schemas.components[Envelope] = map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"code":    map[string]any{"type": "integer", "description": "0 on success, otherwise the error code"},
+			"message": map[string]any{"type": "string"},
+			"data":    map[string]any{},
+		},
+		"required": []string{"code", "message"},
+	}
+
+	paths := map[string]any{}
+	operationIDs := map[string]int{}
+	for _, route := range routes {
+		if route.Method == "HEAD" {
+			continue // Documented by the GET of the same path
+		}
+		op := operations[route.Method+" "+route.Path]
+		path := pathParam.ReplaceAllString(route.Path, "{$1}")
+		item, _ := paths[path].(map[string]any)
+		if item == nil {
+			item = map[string]any{}
+			paths[path] = item
+		}
+
+		operationID := handlerName(route.Handler)
+		if operationID == "" {
+			operationID = strings.ToLower(route.Method) + nonWord.ReplaceAllString(route.Path, "_")
+		}
+		operationIDs[operationID]++
+		if n := operationIDs[operationID]; n > 1 {
+			operationID = fmt.Sprintf("%s_%d", operationID, n) // A handler registered on several routes
+		}
+		summary := op.Summary
+		if summary == "" {
+			summary = operationID[strings.LastIndex(operationID, ".")+1:]
+		}
+
+		operation := map[string]any{
+			"operationId": operationID,
+			"summary":     summary,
+			"tags":        []string{tag(route.Path)},
+			"responses": map[string]any{
+				"200":     schemas.response(op.Response),
+				"default": map[string]any{"description": "Error", "content": jsonContent(ref(Envelope))},
+			},
+		}
+		switch op.Security {
+		case SecurityPublic:
+			operation["security"] = []any{}
+		case SecurityBearer:
+			operation["security"] = []any{map[string]any{"bearerAuth": []string{}}}
+		case SecurityAPIKey:
+			operation["security"] = []any{map[string]any{"apiKey": []string{}}}
+		default:
+			operation["security"] = []any{map[string]any{}, map[string]any{"bearerAuth": []string{}}}
+		}
+
+		parameters := []any{}
+		for _, match := range pathParam.FindAllStringSubmatch(route.Path, -1) {
+			parameters = append(parameters, map[string]any{
+				"name":     match[1],
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]any{"type": paramType(match[1])},
+			})
+		}
+		if op.Query != nil {
+			parameters = append(parameters, schemas.queryParameters(reflect.TypeOf(op.Query))...)
+		}
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+		if op.Body != nil {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content":  jsonContent(schemas.schema(reflect.TypeOf(op.Body))),
+			}
+		}
+		item[strings.ToLower(route.Method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": Version,
+		"info": map[string]any{
+			"title":       info.Title,
+			"description": info.Description,
+			"version":     info.Version,
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas.components,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+				"apiKey":     map[string]any{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+			},
+		},
+	}
+}
+
+// handlerName returns the type and method of a handler method, such as
+// "WalletHandler.GetWallet", or "" for other functions
+func handlerName(name string) string {
+	if !strings.HasSuffix(name, "-fm") {
+		return "" // Not a method value
+	}
+	name = strings.TrimSuffix(name[strings.LastIndex(name, "/")+1:], "-fm")
+	parts := strings.Split(strings.NewReplacer("(*", "", ")", "").Replace(name), ".")
+	if len(parts) != 3 {
+		return ""
+	}
+	return parts[1] + "." + parts[2]
+}
+
+// tag groups a path by its first segment after /api, such as "lottery"
+func tag(path string) string {
+	if !strings.HasPrefix(path, "/api/") {
+		return "system"
+	}
+	segments := strings.Split(strings.TrimPrefix(path, "/api"), "/")
+	if segments[1] == "" || strings.HasPrefix(segments[1], ":") {
+		return "system"
+	}
+	return segments[1]
+}
+
+// paramType returns the type of a path parameter; ids and indexes are
+// integers
+func paramType(name string) string {
+	if name == "id" || name == "index" || strings.HasSuffix(name, "Id") || strings.HasSuffix(name, "_id") {
+		return "integer"
+	}
+	return "string"
+}
+
+func ref(name string) map[string]any {
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+func jsonContent(schema map[string]any) map[string]any {
+	return map[string]any{"application/json": map[string]any{"schema": schema}}
+}
+
+// schemas derives JSON schemas from Go types. Named structs become
+// components so they are described once and may refer to themselves.
+type schemas struct {
+	components map[string]any
+	names      map[reflect.Type]string
+}
+
+func newSchemas() *schemas {
+	return &schemas{components: map[string]any{}, names: map[reflect.Type]string{}}
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// response returns the success response of an operation, its data wrapped
+// in the envelope
+func (s *schemas) response(data any) map[string]any {
+	schema := ref(Envelope)
+	if data != nil {
+		schema = map[string]any{"allOf": []any{
+			ref(Envelope),
+			map[string]any{"type": "object", "properties": map[string]any{"data": s.schema(reflect.TypeOf(data))}},
+		}}
+	}
+	return map[string]any{"description": "OK", "content": jsonContent(schema)}
+}
+
+func (s *schemas) schema(t reflect.Type) map[string]any {
+	if t != timeType && t.Kind() != reflect.Pointer && t.Implements(marshalerType) {
+		return map[string]any{} // Encodes itself, such as gorm.DeletedAt
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := s.schema(t.Elem())
+		if _, isRef := schema["$ref"]; isRef {
+			return map[string]any{"allOf": []any{schema}, "nullable": true}
+		}
+		schema["nullable"] = true
+		return schema
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": s.schema(t.Elem())}
+	case reflect.Struct:
+		if t == timeType {
+			return map[string]any{"type": "string", "format": "date-time"}
+		}
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		name, ok := s.names[t]
+		if !ok {
+			name = s.componentName(t)
+			s.names[t] = name
+			s.components[name] = map[string]any{} // Placeholder for self references
+			s.components[name] = s.object(t)
+		}
+		return ref(name)
+	default:
+		return map[string]any{}
+	}
+}
+
+// componentName names a struct after its type, qualified by its package
+// when another package has a type of the same name
+func (s *schemas) componentName(t reflect.Type) string {
+	name := t.Name()
+	if _, taken := s.components[name]; !taken {
+		return name
+	}
+	pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+	return strings.ToUpper(pkg[:1]) + pkg[1:] + name
+}
+
+func (s *schemas) object(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	required := []string{}
+	s.fields(t, func(field reflect.StructField) {
+		name, ok := jsonName(field)
+		if !ok {
+			return
+		}
+		properties[name] = s.schema(field.Type)
+		if isRequired(field) {
+			required = append(required, name)
+		}
+	})
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// fields calls fn for the fields of t, flattening embedded structs without
+// a JSON name, such as gorm.Model
+func (s *schemas) fields(t reflect.Type, fn func(reflect.StructField)) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Anonymous && field.Tag.Get("json") == "" && field.Type.Kind() == reflect.Struct {
+			s.fields(field.Type, fn)
+			continue
+		}
+		fn(field)
+	}
+}
+
+// queryParameters lists the fields of a query struct by their form tags
+func (s *schemas) queryParameters(t reflect.Type) []any {
+	parameters := []any{}
+	s.fields(t, func(field reflect.StructField) {
+		name := strings.Split(field.Tag.Get("form"), ",")[0]
+		if name == "" || name == "-" {
+			return
+		}
+		schema := s.schema(field.Type)
+		delete(schema, "nullable")
+		parameters = append(parameters, map[string]any{
+			"name":     name,
+			"in":       "query",
+			"required": isRequired(field),
+			"schema":   schema,
+		})
+	})
+	return parameters
+}
+
+// jsonName returns the JSON name of a field; ok is false for fields left out
+// of JSON
+func jsonName(field reflect.StructField) (name string, ok bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name = strings.Split(tag, ",")[0]
+	if name == "" {
+		name = field.Name
+	}
+	return name, true
+}
+
+// isRequired reports whether binding requires the field
+func isRequired(field reflect.StructField) bool {
+	for _, rule := range strings.Split(field.Tag.Get("binding"), ",") {
+		if rule == "required" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package openapi
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type testHandler struct{}
+
+func (testHandler) GetItem(c *gin.Context)  {}
+func (testHandler) SaveItem(c *gin.Context) {}
+
+type testItem struct {
+	ID       uint       `json:"id"`
+	Name     string     `json:"name" binding:"required"`
+	Secret   string     `json:"-"`
+	Parent   *testItem  `json:"parent,omitempty"`
+	Tags     []string   `json:"tags"`
+	Created  time.Time  `json:"created_at"`
+	Deleted  *time.Time `json:"deleted_at,omitempty"`
+	internal int
+}
+
+type testQuery struct {
+	Page   int    `form:"page"`
+	Status string `form:"status" binding:"required"`
+}
+
+func testDocument(t *testing.T) map[string]any {
+	gin.SetMode(gin.TestMode)
+	h := &testHandler{}
+	r := gin.New()
+	r.GET("/api/items/:id", h.GetItem)
+	r.HEAD("/api/items/:id", h.GetItem)
+	r.PUT("/api/items/:id", h.SaveItem)
+	r.POST("/api/items/:id/copy", h.SaveItem)
+	r.GET("/health", func(c *gin.Context) {})
+
+	doc := Build(Info{Title: "Test", Version: "1"}, r.Routes(), map[string]Operation{
+		"GET /api/items/:id": {Summary: "Get an item", Security: SecurityPublic, Query: testQuery{}, Response: testItem{}},
+		"PUT /api/items/:id": {Security: SecurityBearer, Body: testItem{}},
+	})
+	// Round trip through JSON, as served
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	return decoded
+}
+
+func TestBuildListsEveryRoute(t *testing.T) {
+	doc := testDocument(t)
+	paths := doc["paths"].(map[string]any)
+	if len(paths) != 3 {
+		t.Fatalf("Expected 3 paths, got %v", paths)
+	}
+	item := paths["/api/items/{id}"].(map[string]any)
+	if _, ok := item["head"]; ok || item["get"] == nil || item["put"] == nil {
+		t.Errorf("Expected GET and PUT without HEAD, got %v", item)
+	}
+
+	get := item["get"].(map[string]any)
+	if get["operationId"] != "testHandler.GetItem" || get["summary"] != "Get an item" || len(get["security"].([]any)) != 0 {
+		t.Errorf("Unexpected GET operation %v", get)
+	}
+	parameters := get["parameters"].([]any)
+	if len(parameters) != 3 {
+		t.Fatalf("Expected the path and query parameters, got %v", parameters)
+	}
+	id := parameters[0].(map[string]any)
+	status := parameters[2].(map[string]any)
+	if id["in"] != "path" || id["schema"].(map[string]any)["type"] != "integer" || status["name"] != "status" || status["required"] != true {
+		t.Errorf("Unexpected parameters %v", parameters)
+	}
+
+	// A handler on two routes gets two operation ids
+	copyOp := paths["/api/items/{id}/copy"].(map[string]any)["post"].(map[string]any)
+	put := item["put"].(map[string]any)
+	if put["operationId"] == copyOp["operationId"] || !strings.HasPrefix(copyOp["operationId"].(string), "testHandler.SaveItem") {
+		t.Errorf("Expected distinct operation ids, got %v and %v", put["operationId"], copyOp["operationId"])
+	}
+	// Undescribed routes take an optional token
+	if security := copyOp["security"].([]any); len(security) != 2 {
+		t.Errorf("Expected optional security, got %v", security)
+	}
+	health := paths["/health"].(map[string]any)["get"].(map[string]any)
+	if health["operationId"] != "get_health" || health["tags"].([]any)[0] != "system" {
+		t.Errorf("Unexpected operation for an anonymous handler %v", health)
+	}
+}
+
+func TestBuildDerivesSchemas(t *testing.T) {
+	doc := testDocument(t)
+	schemas := doc["components"].(map[string]any)["schemas"].(map[string]any)
+	item, ok := schemas["testItem"].(map[string]any)
+	if !ok {
+		t.Fatalf("Expected a testItem component, got %v", schemas)
+	}
+	properties := item["properties"].(map[string]any)
+	if len(properties) != 6 || properties["Secret"] != nil || properties["internal"] != nil {
+		t.Errorf("Expected the JSON fields only, got %v", properties)
+	}
+	if properties["created_at"].(map[string]any)["format"] != "date-time" || properties["tags"].(map[string]any)["type"] != "array" {
+		t.Errorf("Unexpected field schemas %v", properties)
+	}
+	parent := properties["parent"].(map[string]any)
+	if parent["nullable"] != true || parent["allOf"].([]any)[0].(map[string]any)["$ref"] != "#/components/schemas/testItem" {
+		t.Errorf("Expected a nullable self reference, got %v", parent)
+	}
+	if required := item["required"].([]any); len(required) != 1 || required[0] != "name" {
+		t.Errorf("Expected name required by binding, got %v", required)
+	}
+
+	put := doc["paths"].(map[string]any)["/api/items/{id}"].(map[string]any)["put"].(map[string]any)
+	body := put["requestBody"].(map[string]any)["content"].(map[string]any)["application/json"].(map[string]any)["schema"].(map[string]any)
+	if body["$ref"] != "#/components/schemas/testItem" {
+		t.Errorf("Expected the body to refer to testItem, got %v", body)
+	}
+}
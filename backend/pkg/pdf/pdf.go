@@ -0,0 +1,85 @@
+// Package pdf writes single-page text documents, such as receipts, without
+// external dependencies.
+//
+// Text is set in STSong-Light, one of the CJK fonts PDF readers provide
+// themselves, so Chinese text renders without embedding a font. Characters
+// outside the Basic Multilingual Plane cannot be encoded and print as '?'.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf16"
+)
+
+// A4 page size and margins, in points
+const (
+	pageWidth  = 595
+	pageHeight = 842
+	margin     = 56
+)
+
+// Line is a line of text on the page
+type Line struct {
+	Text string
+	Size float64 // font size in points; 0 uses 11
+}
+
+// Render returns a PDF document with title as its document title and lines
+// set top to bottom on one A4 page. Lines past the bottom margin are dropped.
+func Render(title string, lines []Line) []byte {
+	var content bytes.Buffer
+	y := float64(pageHeight - margin)
+	for _, line := range lines {
+		size := line.Size
+		if size <= 0 {
+			size = 11
+		}
+		y -= size * 1.6
+		if y < margin {
+			break
+		}
+		fmt.Fprintf(&content, "BT /F1 %.1f Tf %d %.1f Td <%s> Tj ET\n", size, margin, y, encodeText(line.Text))
+	}
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 5 0 R >> >> /Contents 4 0 R >>", pageWidth, pageHeight),
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+		"<< /Type /Font /Subtype /Type0 /BaseFont /STSong-Light /Encoding /UniGB-UCS2-H /DescendantFonts [6 0 R] >>",
+		// CIDs 1-95 are the half-width ASCII glyphs
+		"<< /Type /Font /Subtype /CIDFontType0 /BaseFont /STSong-Light /CIDSystemInfo << /Registry (Adobe) /Ordering (GB1) /Supplement 2 >> /FontDescriptor 7 0 R /DW 1000 /W [1 95 500] >>",
+		"<< /Type /FontDescriptor /FontName /STSong-Light /Flags 6 /FontBBox [-25 -254 1000 880] /ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 880 /StemV 93 >>",
+		fmt.Sprintf("<< /Title <FEFF%s> /Producer (scratch-lottery) >>", encodeText(title)),
+	}
+
+	var doc bytes.Buffer
+	doc.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = doc.Len()
+		fmt.Fprintf(&doc, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := doc.Len()
+	fmt.Fprintf(&doc, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&doc, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&doc, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, len(objects), xref)
+	return doc.Bytes()
+}
+
+// encodeText encodes s as big-endian UCS-2 in hex, the encoding of both the
+// font and text strings
+func encodeText(s string) string {
+	var buf bytes.Buffer
+	for _, r := range s {
+		if r > 0xFFFF || utf16.IsSurrogate(r) {
+			r = '?'
+		}
+		fmt.Fprintf(&buf, "%04X", r)
+	}
+	return buf.String()
+}
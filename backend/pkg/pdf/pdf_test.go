@@ -0,0 +1,60 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRenderStructure(t *testing.T) {
+	doc := Render("收据", []Line{{Text: "兑换收据", Size: 18}, {Text: "Code: AB12"}})
+	if !bytes.HasPrefix(doc, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(doc, []byte("%%EOF\n")) {
+		t.Fatalf("Expected a PDF header and trailer, got %q", doc)
+	}
+
+	// The xref table points at each object
+	match := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(doc)
+	if match == nil {
+		t.Fatal("Missing startxref")
+	}
+	xref, _ := strconv.Atoi(string(match[1]))
+	if !bytes.HasPrefix(doc[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref %d does not point at the xref table", xref)
+	}
+	entries := strings.Split(string(doc[xref:]), "\n")[3:]
+	for i := 1; i <= 8; i++ {
+		offset, err := strconv.Atoi(entries[i-1][:10])
+		if err != nil {
+			t.Fatalf("Bad xref entry %q", entries[i-1])
+		}
+		if want := fmt.Sprintf("%d 0 obj\n", i); !bytes.HasPrefix(doc[offset:], []byte(want)) {
+			t.Errorf("Object %d is not at offset %d", i, offset)
+		}
+	}
+
+	// Text is UCS-2 in hex
+	if !bytes.Contains(doc, []byte("<515163626536636E>")) || !bytes.Contains(doc, []byte("<0043006F00640065003A00200041004200310032>")) {
+		t.Error("Expected the lines encoded as UCS-2")
+	}
+	if !bytes.Contains(doc, []byte("/Title <FEFF6536636E>")) {
+		t.Error("Expected the document title")
+	}
+}
+
+func TestRenderEdgeCases(t *testing.T) {
+	if got := encodeText("a😀"); got != "0061003F" {
+		t.Errorf("Expected characters outside the BMP replaced, got %s", got)
+	}
+
+	lines := make([]Line, 100)
+	for i := range lines {
+		lines[i] = Line{Text: "line"}
+	}
+	doc := Render("", lines)
+	if n := bytes.Count(doc, []byte(" Tj ET")); n == 0 || n >= 100 {
+		t.Errorf("Expected lines past the page dropped, got %d", n)
+	}
+}
@@ -0,0 +1,217 @@
+package redact
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode"
+)
+
+// Mask is the placeholder written in place of sensitive values.
+const Mask = "****"
+
+// DefaultKeys are the name fragments treated as sensitive by default.
+var DefaultKeys = []string{"secret", "token", "key", "password"}
+
+// Redactor masks values whose field names look sensitive.
+// A field name is sensitive when one of its words (split on `_`, `-`, `.`,
+// spaces and camelCase boundaries) matches one of the configured keys.
+type Redactor struct {
+	keys map[string]struct{}
+}
+
+var defaultRedactor = New(DefaultKeys...)
+
+// New creates a redactor for the given key words (case-insensitive).
+func New(keys ...string) *Redactor {
+	r := &Redactor{keys: make(map[string]struct{}, len(keys))}
+	for _, k := range keys {
+		k = strings.ToLower(strings.TrimSpace(k))
+		if k != "" {
+			r.keys[k] = struct{}{}
+		}
+	}
+	return r
+}
+
+// Default returns the redactor configured with DefaultKeys.
+func Default() *Redactor {
+	return defaultRedactor
+}
+
+// WithKeys returns a new redactor with the default keys plus the given extra keys.
+func WithKeys(extra ...string) *Redactor {
+	return New(append(append([]string{}, DefaultKeys...), extra...)...)
+}
+
+// ParseKeys parses a comma separated key list (e.g. from LOG_REDACT_KEYS).
+func ParseKeys(s string) []string {
+	var keys []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			keys = append(keys, part)
+		}
+	}
+	return keys
+}
+
+// IsSensitive reports whether a field name should be masked.
+func (r *Redactor) IsSensitive(name string) bool {
+	if r == nil || name == "" {
+		return false
+	}
+	for _, word := range splitWords(name) {
+		if _, ok := r.keys[word]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Value returns v with every sensitive field masked.
+// If name itself is sensitive, the whole value is masked. Maps, slices and
+// structs are walked recursively so nested secrets are masked as well.
+func (r *Redactor) Value(name string, v any) any {
+	if r.IsSensitive(name) {
+		return maskValue(v)
+	}
+	return r.walk(v)
+}
+
+func (r *Redactor) walk(v any) any {
+	switch t := v.(type) {
+	case nil, bool, string, int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64, float32, float64:
+		return v
+	case error:
+		return t.Error()
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, val := range t {
+			out[k] = r.Value(k, val)
+		}
+		return out
+	case map[string]string:
+		out := make(map[string]any, len(t))
+		for k, val := range t {
+			out[k] = r.Value(k, val)
+		}
+		return out
+	case []any:
+		out := make([]any, len(t))
+		for i, val := range t {
+			out[i] = r.walk(val)
+		}
+		return out
+	}
+
+	// Structs, pointers and typed collections: go through their JSON form so
+	// json tags decide the field names, exactly as they would appear in output.
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var generic any
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return v
+	}
+	switch generic.(type) {
+	case map[string]any, []any:
+		return r.walk(generic)
+	}
+	return v
+}
+
+// IsSensitive reports whether a field name is sensitive using the default keys.
+func IsSensitive(name string) bool {
+	return defaultRedactor.IsSensitive(name)
+}
+
+// Value masks sensitive fields in v using the default keys.
+func Value(name string, v any) any {
+	return defaultRedactor.Value(name, v)
+}
+
+// Secret masks a secret for display, keeping a short prefix so admins can
+// recognise which secret is configured. Empty input stays empty.
+func Secret(s string) string {
+	if s == "" {
+		return ""
+	}
+	if len(s) > 4 {
+		return s[:4] + Mask
+	}
+	return Mask
+}
+
+// Partial masks the middle of a value, keeping keep characters visible at
+// each end so it can still be matched against what a user reports, e.g.
+// ABCD****WXYZ. Values too short to keep both ends are masked entirely.
+// Empty input stays empty.
+func Partial(s string, keep int) string {
+	if s == "" {
+		return ""
+	}
+	runes := []rune(s)
+	if keep <= 0 || len(runes) <= 2*keep {
+		return Mask
+	}
+	return string(runes[:keep]) + Mask + string(runes[len(runes)-keep:])
+}
+
+// IsMasked reports whether s contains a mask placeholder, i.e. it was produced
+// by Secret and must not be written back as a real value.
+func IsMasked(s string) bool {
+	return strings.Contains(s, "*")
+}
+
+func maskValue(v any) any {
+	if v == nil {
+		return nil
+	}
+	if s, ok := v.(string); ok && s == "" {
+		return ""
+	}
+	return Mask
+}
+
+// splitWords lowercases a field name and splits it into words.
+func splitWords(name string) []string {
+	var words []string
+	var cur []rune
+	flush := func() {
+		if len(cur) > 0 {
+			words = append(words, strings.ToLower(string(cur)))
+			cur = cur[:0]
+		}
+	}
+	runes := []rune(name)
+	for i, c := range runes {
+		switch {
+		case c == '_' || c == '-' || c == '.' || unicode.IsSpace(c):
+			flush()
+		case unicode.IsUpper(c) && i > 0 && unicode.IsLower(runes[i-1]):
+			flush()
+			cur = append(cur, c)
+		default:
+			cur = append(cur, c)
+		}
+	}
+	flush()
+	return words
+}
+
+// Query masks the values of sensitive parameters in a raw URL query string,
+// e.g. "code=1&access_token=abc" becomes "code=1&access_token=****".
+func Query(raw string) string {
+	if raw == "" {
+		return raw
+	}
+	parts := strings.Split(raw, "&")
+	for i, part := range parts {
+		name, _, hasValue := strings.Cut(part, "=")
+		if hasValue && defaultRedactor.IsSensitive(name) {
+			parts[i] = name + "=" + Mask
+		}
+	}
+	return strings.Join(parts, "&")
+}
@@ -0,0 +1,152 @@
+package redact
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+)
+
+// getMinSuccessfulTests returns the minimum number of successful tests for property testing.
+// Uses GOPTER_MIN_SUCCESSFUL_TESTS env var if set, otherwise defaults to 100.
+func getMinSuccessfulTests() int {
+	if val := os.Getenv("GOPTER_MIN_SUCCESSFUL_TESTS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 100
+}
+
+func TestIsSensitive(t *testing.T) {
+	cases := map[string]bool{
+		"epay_secret":   true,
+		"EPaySecret":    true,
+		"access_token":  true,
+		"refreshToken":  true,
+		"api-key":       true,
+		"card_key":      true,
+		"password":      true,
+		"DB_PASSWORD":   true,
+		"user_id":       false,
+		"request_id":    false,
+		"status":        false,
+		"monkey":        false,
+		"tokenizer":     false,
+		"epay_callback": false,
+		"":              false,
+	}
+	for name, want := range cases {
+		if got := IsSensitive(name); got != want {
+			t.Errorf("IsSensitive(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestWithKeys(t *testing.T) {
+	r := WithKeys(ParseKeys(" code , state ")...)
+	if !r.IsSensitive("oauth_code") || !r.IsSensitive("state") {
+		t.Error("extra keys should be sensitive")
+	}
+	if !r.IsSensitive("jwt_secret") {
+		t.Error("default keys should still be sensitive")
+	}
+}
+
+func TestSecret(t *testing.T) {
+	cases := map[string]string{
+		"":            "",
+		"abc":         Mask,
+		"abcd":        Mask,
+		"abcdefgh123": "abcd" + Mask,
+	}
+	for in, want := range cases {
+		if got := Secret(in); got != want {
+			t.Errorf("Secret(%q) = %q, want %q", in, got, want)
+		}
+		if in != "" && !IsMasked(Secret(in)) {
+			t.Errorf("IsMasked(Secret(%q)) should be true", in)
+		}
+	}
+}
+
+func TestPartial(t *testing.T) {
+	cases := map[string]string{
+		"":                 "",
+		"ABCD1234":         Mask,
+		"ABCD1234WXYZ":     "ABCD" + Mask + "WXYZ",
+		"ABCDEFGH2345WXYZ": "ABCD" + Mask + "WXYZ",
+		"卡密内容一二三四五六":       "卡密内容" + Mask + "三四五六",
+	}
+	for in, want := range cases {
+		if got := Partial(in, 4); got != want {
+			t.Errorf("Partial(%q, 4) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	got := Query("page=1&access_token=abc123&api_key=zzz&limit=")
+	want := "page=1&access_token=****&api_key=****&limit="
+	if got != want {
+		t.Errorf("Query() = %q, want %q", got, want)
+	}
+}
+
+type nestedSettings struct {
+	MerchantID string `json:"merchant_id"`
+	Secret     string `json:"secret"`
+	Inner      struct {
+		Password string `json:"password"`
+		Name     string `json:"name"`
+	} `json:"inner"`
+}
+
+// Property: a secret value assigned to a sensitive field never survives redaction,
+// no matter how deeply it is nested, while non-sensitive values are preserved.
+func TestPropertyRedactedValueNeverContainsSecret(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = getMinSuccessfulTests()
+	parameters.Rng.Seed(1234)
+
+	properties := gopter.NewProperties(parameters)
+
+	secretGen := gen.AlphaString().Map(func(s string) string { return "S3CR3T" + s })
+
+	properties.Property("nested secrets are masked in JSON output", prop.ForAll(
+		func(secret, name string) bool {
+			var settings nestedSettings
+			settings.MerchantID = "merchant-" + name
+			settings.Secret = secret
+			settings.Inner.Password = secret
+			settings.Inner.Name = name
+
+			value := map[string]any{
+				"settings": settings,
+				"list":     []any{map[string]string{"api_token": secret}},
+				"pointer":  &settings,
+			}
+
+			data, err := json.Marshal(Value("details", value))
+			if err != nil {
+				t.Logf("marshal failed: %v", err)
+				return false
+			}
+			out := string(data)
+			if strings.Contains(out, secret) {
+				t.Logf("secret leaked: %s", out)
+				return false
+			}
+			return strings.Contains(out, "merchant-"+name)
+		},
+		secretGen,
+		gen.AlphaString(),
+	))
+
+	properties.TestingRun(t)
+}
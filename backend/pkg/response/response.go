@@ -28,17 +28,23 @@ func (e *APIError) Error() string {
 // Error codes
 const (
 	// General errors 1xxx
-	ErrInvalidRequest  = 1001
-	ErrUnauthorized    = 1002
-	ErrForbidden       = 1003
-	ErrNotFound        = 1004
-	ErrInternalServer  = 1005
+	ErrInvalidRequest     = 1001
+	ErrUnauthorized       = 1002
+	ErrForbidden          = 1003
+	ErrNotFound           = 1004
+	ErrInternalServer     = 1005
+	ErrTooManyRequests    = 1006
+	ErrRequestTooLarge    = 1007
+	ErrServiceUnavailable = 1008
+	ErrMethodNotAllowed   = 1009
 
 	// Auth errors 2xxx
-	ErrOAuthFailed    = 2001
-	ErrTokenExpired   = 2002
-	ErrTokenInvalid   = 2003
-	ErrRefreshFailed  = 2004
+	ErrOAuthFailed        = 2001
+	ErrTokenExpired       = 2002
+	ErrTokenInvalid       = 2003
+	ErrRefreshFailed      = 2004
+	ErrAdminIPDenied      = 2005
+	ErrAdminTokenRequired = 2006
 
 	// Lottery errors 3xxx
 	ErrInsufficientBalance = 3001
@@ -46,11 +52,16 @@ const (
 	ErrLotterySoldOut      = 3003
 	ErrAlreadyScratched    = 3004
 	ErrInvalidSecurityCode = 3005
+	ErrWaitingRoom         = 3006
+	ErrScratchNotConfirmed = 3007
+	ErrPurchaseConflict    = 3008
+	ErrScratchTooSoon      = 3009
 
 	// Exchange errors 4xxx
 	ErrProductNotFound    = 4001
 	ErrProductSoldOut     = 4002
 	ErrInsufficientPoints = 4003
+	ErrProductNotDropped  = 4004
 
 	// Payment errors 5xxx
 	ErrPaymentDisabled  = 5001
@@ -65,11 +76,15 @@ type Response struct {
 	Data    interface{} `json:"data,omitempty"`
 }
 
+// RequestIDKey is the gin context key holding the id of the current request
+const RequestIDKey = "requestID"
+
 // ErrorResponse represents an error response
 type ErrorResponse struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
-	Details string `json:"details,omitempty"`
+	Code      int    `json:"code"`
+	Message   string `json:"message"`
+	Details   string `json:"details,omitempty"`
+	RequestID string `json:"request_id,omitempty"` // Quoted by users to correlate incidents with the logs
 }
 
 // Success sends a successful response
@@ -93,8 +108,9 @@ func Created(c *gin.Context, data interface{}) {
 // Error sends an error response
 func Error(c *gin.Context, httpStatus int, code int, message string, details ...string) {
 	resp := ErrorResponse{
-		Code:    code,
-		Message: message,
+		Code:      code,
+		Message:   message,
+		RequestID: c.GetString(RequestIDKey),
 	}
 	if len(details) > 0 {
 		resp.Details = details[0]
@@ -133,3 +149,18 @@ func NotFound(c *gin.Context, message string, details ...string) {
 func InternalError(c *gin.Context, message string, details ...string) {
 	Error(c, http.StatusInternalServerError, ErrInternalServer, message, details...)
 }
+
+// TooManyRequests sends a 429 too many requests response
+func TooManyRequests(c *gin.Context, message string, details ...string) {
+	Error(c, http.StatusTooManyRequests, ErrTooManyRequests, message, details...)
+}
+
+// RequestEntityTooLarge sends a 413 request entity too large response
+func RequestEntityTooLarge(c *gin.Context, message string, details ...string) {
+	Error(c, http.StatusRequestEntityTooLarge, ErrRequestTooLarge, message, details...)
+}
+
+// ServiceUnavailable sends a 503 service unavailable response
+func ServiceUnavailable(c *gin.Context, message string, details ...string) {
+	Error(c, http.StatusServiceUnavailable, ErrServiceUnavailable, message, details...)
+}
@@ -148,6 +148,9 @@ export interface PrizeLevel {
   prize_amount: number;
   quantity: number;
   remaining?: number;
+  image?: string;
+  color?: string;
+  description?: string;
 }
 
 export interface LotteryType {
@@ -310,11 +313,34 @@ export interface CardKey {
   redeemed_at?: string;
 }
 
-export async function getCardKeys(productId: number, status?: string): Promise<{ card_keys: CardKey[]; total: number }> {
-  const params = new URLSearchParams();
-  if (status) params.append('status', status);
-  const queryString = params.toString();
-  return apiClient.get<{ card_keys: CardKey[]; total: number }>(`/admin/exchange/products/${productId}/card-keys${queryString ? `?${queryString}` : ''}`);
+export interface CardKeySummary {
+  available: number;
+  reserved: number;
+  redeemed: number;
+  total: number;
+}
+
+export interface CardKeyListResponse {
+  card_keys: CardKey[];
+  total: number;
+  page: number;
+  limit: number;
+  total_pages: number;
+  summary: CardKeySummary;
+}
+
+export async function getCardKeys(
+  productId: number,
+  params?: { status?: string; redeemed_by?: number; sort?: string; page?: number; limit?: number }
+): Promise<CardKeyListResponse> {
+  const searchParams = new URLSearchParams();
+  if (params?.status) searchParams.append('status', params.status);
+  if (params?.redeemed_by) searchParams.append('redeemed_by', params.redeemed_by.toString());
+  if (params?.sort) searchParams.append('sort', params.sort);
+  if (params?.page) searchParams.append('page', params.page.toString());
+  if (params?.limit) searchParams.append('limit', params.limit.toString());
+  const queryString = searchParams.toString();
+  return apiClient.get<CardKeyListResponse>(`/admin/exchange/products/${productId}/card-keys${queryString ? `?${queryString}` : ''}`);
 }
 
 export async function importCardKeys(productId: number, cardKeys: string[]): Promise<{ imported: number; message: string }> {
@@ -25,6 +25,9 @@ export interface PrizeLevel {
   prize_amount: number;
   quantity: number;
   remaining: number;
+  image?: string;
+  color?: string;
+  description?: string;
 }
 
 export interface LotteryTypeDetail extends LotteryType {
@@ -6,9 +6,11 @@ export interface RechargeRequest {
 
 export interface RechargeResponse {
   order_no: string;
-  payment_url: string;
+  payment_url: string; // Empty for orders paid offline or in the sandbox
   amount: number;
   points: number;
+  gateway?: string;
+  sandbox?: boolean;
 }
 
 export interface PaymentOrder {
@@ -16,7 +18,7 @@ export interface PaymentOrder {
   order_no: string;
   amount: number; // Amount in yuan
   points: number;
-  status: 'pending' | 'paid' | 'failed';
+  status: 'pending' | 'paid' | 'failed' | 'expired';
   payment_type?: string;
   trade_no?: string;
   created_at: string;
@@ -66,6 +68,7 @@ export function getOrderStatusLabel(status: PaymentOrder['status']): string {
     pending: '待支付',
     paid: '已支付',
     failed: '支付失败',
+    expired: '已过期',
   };
   return labels[status] || status;
 }
@@ -76,6 +79,7 @@ export function getOrderStatusColor(status: PaymentOrder['status']): string {
     pending: 'text-yellow-500',
     paid: 'text-green-500',
     failed: 'text-red-500',
+    expired: 'text-gray-500',
   };
   return colors[status] || 'text-gray-500';
 }
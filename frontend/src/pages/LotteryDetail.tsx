@@ -272,7 +272,17 @@ export function LotteryDetail() {
                 {lottery.prize_levels.map((level) => (
                   <tr key={level.id} className="border-t">
                     <td className="px-4 py-3 text-sm">{level.level}等奖</td>
-                    <td className="px-4 py-3 text-sm">{level.name}</td>
+                    <td className="px-4 py-3 text-sm">
+                      <div className="flex items-center gap-2">
+                        {level.image && (
+                          <img src={level.image} alt="" className="w-6 h-6 object-contain" />
+                        )}
+                        <span style={level.color ? { color: level.color } : undefined}>{level.name}</span>
+                      </div>
+                      {level.description && (
+                        <p className="text-xs text-muted-foreground mt-1">{level.description}</p>
+                      )}
+                    </td>
                     <td className="px-4 py-3 text-sm text-right font-medium text-yellow-600">
                       {formatPrize(level.prize_amount)} 积分
                     </td>
@@ -133,6 +133,12 @@ export function Recharge() {
 
     try {
       const result = await createRechargeOrder(amount);
+      if (!result.payment_url) {
+        // Paid offline or in the sandbox: show the order status instead
+        setSubmitting(false);
+        navigate(`/recharge?order_no=${result.order_no}`);
+        return;
+      }
       // Redirect to payment URL
       window.location.href = result.payment_url;
     } catch (err) {
@@ -344,7 +350,8 @@ export function Recharge() {
                 </div>
                 <h3 className="text-xl font-bold mb-2">
                   {orderResult.status === 'paid' ? '充值成功' : 
-                   orderResult.status === 'pending' ? '等待支付' : '支付失败'}
+                   orderResult.status === 'pending' ? '等待支付' :
+                   orderResult.status === 'expired' ? '订单已过期' : '支付失败'}
                 </h3>
                 {orderResult.status === 'paid' && (
                   <p className="text-green-600 mb-4">
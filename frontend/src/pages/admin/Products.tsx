@@ -11,6 +11,7 @@ import {
   type CreateProductRequest,
   type UpdateProductRequest,
   type CardKey,
+  type CardKeySummary,
 } from '../../api/admin';
 import { Button } from '@/components/ui/button';
 import { Input } from '@/components/ui/input';
@@ -40,6 +41,7 @@ export function AdminProducts() {
   const [showImportModal, setShowImportModal] = useState(false);
   const [selectedProduct, setSelectedProduct] = useState<AdminProduct | null>(null);
   const [cardKeys, setCardKeys] = useState<CardKey[]>([]);
+  const [cardKeySummary, setCardKeySummary] = useState<CardKeySummary | null>(null);
   
   // Form states
   const [formData, setFormData] = useState<CreateProductRequest>({
@@ -132,8 +134,9 @@ export function AdminProducts() {
   const handleViewKeys = async (product: AdminProduct) => {
     setSelectedProduct(product);
     try {
-      const data = await getCardKeys(product.id);
+      const data = await getCardKeys(product.id, { limit: 100 });
       setCardKeys(data.card_keys || []);
+      setCardKeySummary(data.summary);
       setShowKeysModal(true);
     } catch (err) {
       setError(err instanceof Error ? err.message : '获取卡密失败');
@@ -149,8 +152,9 @@ export function AdminProducts() {
       setShowImportModal(false);
       setImportText('');
       // Refresh card keys
-      const data = await getCardKeys(selectedProduct.id);
+      const data = await getCardKeys(selectedProduct.id, { limit: 100 });
       setCardKeys(data.card_keys || []);
+      setCardKeySummary(data.summary);
       fetchProducts(page);
       alert(`成功导入 ${result.imported} 个卡密`);
     } catch (err) {
@@ -420,7 +424,7 @@ export function AdminProducts() {
                 <Button size="sm" onClick={() => setShowImportModal(true)}>
                   <Upload className="w-4 h-4 mr-2" /> 导入卡密
                 </Button>
-                <Button variant="ghost" size="icon" onClick={() => { setShowKeysModal(false); setSelectedProduct(null); setCardKeys([]); }}>
+                <Button variant="ghost" size="icon" onClick={() => { setShowKeysModal(false); setSelectedProduct(null); setCardKeys([]); setCardKeySummary(null); }}>
                   <X className="w-4 h-4" />
                 </Button>
               </div>
@@ -428,14 +432,17 @@ export function AdminProducts() {
             <CardContent className="flex-1 overflow-hidden flex flex-col p-0">
               <div className="p-4 bg-muted/30 border-b flex items-center justify-between">
                 <span className="text-sm font-medium">
-                  总计: {cardKeys.length} 个
+                  总计: {cardKeySummary?.total ?? cardKeys.length} 个
+                  {cardKeySummary && cardKeySummary.total > cardKeys.length && (
+                    <span className="ml-2 text-muted-foreground font-normal">（显示最新 {cardKeys.length} 个）</span>
+                  )}
                 </span>
                 <div className="flex items-center gap-3 text-sm">
                   <Badge variant="outline" className="bg-green-50 text-green-700 border-green-200">
-                    可用: {cardKeys.filter(k => k.status === 'available').length}
+                    可用: {cardKeySummary?.available ?? 0}
                   </Badge>
                   <Badge variant="outline" className="bg-muted text-muted-foreground">
-                    已兑换: {cardKeys.filter(k => k.status === 'redeemed').length}
+                    已兑换: {cardKeySummary?.redeemed ?? 0}
                   </Badge>
                 </div>
               </div>